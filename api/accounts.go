@@ -0,0 +1,333 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"nofx/config"
+	"nofx/crypto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 多账户：交易员通过 ai_model_id / exchange_id 引用配置，
+// 纯数字表示配置的自增ID（指定某个账户），否则表示模型/交易所类型（例如 "binance"），匹配该类型的默认账户
+
+// findExchangeAccount 按引用查找交易所配置，找不到返回 nil
+func findExchangeAccount(exchanges []*config.ExchangeConfig, ref string) *config.ExchangeConfig {
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, ex := range exchanges {
+			if ex.ID == id {
+				return ex
+			}
+		}
+		return nil
+	}
+	var first *config.ExchangeConfig
+	for _, ex := range exchanges {
+		if ex.ExchangeID != ref {
+			continue
+		}
+		if ex.DisplayName == "" {
+			return ex
+		}
+		if first == nil {
+			first = ex
+		}
+	}
+	return first
+}
+
+// findAIModelAccount 按引用查找AI模型配置，找不到返回 nil
+func findAIModelAccount(models []*config.AIModelConfig, ref string) *config.AIModelConfig {
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, model := range models {
+			if model.ID == id {
+				return model
+			}
+		}
+		return nil
+	}
+	var first *config.AIModelConfig
+	for _, model := range models {
+		if model.ModelID != ref {
+			continue
+		}
+		if model.DisplayName == "" {
+			return model
+		}
+		if first == nil {
+			first = model
+		}
+	}
+	return first
+}
+
+// normalizeAccountName 校验账户名称（去掉首尾空白，不超过 MaxAccountNameLen 个字符）
+func normalizeAccountName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > config.MaxAccountNameLen {
+		return "", fmt.Errorf("账户名称不能超过 %d 个字符", config.MaxAccountNameLen)
+	}
+	return name, nil
+}
+
+// decryptAccountPayload 解密账户配置请求体（包含密钥，仅支持加密传输），失败时已写入响应
+func (s *Server) decryptAccountPayload(c *gin.Context, userID string, out interface{}) bool {
+	bodyBytes, err := c.GetRawData()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "READ_BODY_FAILED")
+		return false
+	}
+
+	var encryptedPayload crypto.EncryptedPayload
+	if err := json.Unmarshal(bodyBytes, &encryptedPayload); err != nil || encryptedPayload.WrappedKey == "" {
+		log.Printf("❌ 检测到非加密请求 (UserID: %s)", userID)
+		respondError(c, http.StatusBadRequest, "ENCRYPTION_REQUIRED")
+		return false
+	}
+
+	decrypted, err := s.cryptoHandler.cryptoService.DecryptSensitiveData(&encryptedPayload)
+	if err != nil {
+		log.Printf("❌ 解密账户配置失败 (UserID: %s): %v", userID, err)
+		code := "DECRYPT_FAILED"
+		if strings.Contains(err.Error(), "timestamp") {
+			code = "DECRYPT_TIMESTAMP_INVALID"
+		} else if strings.Contains(err.Error(), "unwrap") || strings.Contains(err.Error(), "RSA") {
+			code = "DECRYPT_KEY_FAILED"
+		}
+		respondError(c, http.StatusBadRequest, code)
+		return false
+	}
+
+	if err := json.Unmarshal([]byte(decrypted), out); err != nil {
+		log.Printf("❌ 解析解密数据失败: %v", err)
+		respondError(c, http.StatusBadRequest, "DECRYPT_PAYLOAD_INVALID")
+		return false
+	}
+	return true
+}
+
+// respondAccountError 把账户写入错误映射为 HTTP 状态码
+func respondAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, config.ErrAccountNameTaken):
+		respondError(c, http.StatusConflict, "CONFLICT", err)
+	case errors.Is(err, config.ErrAccountNotFound):
+		respondError(c, http.StatusNotFound, "NOT_FOUND", err)
+	default:
+		respondError(c, http.StatusInternalServerError, "SAVE_ACCOUNT_FAILED", err)
+	}
+}
+
+// ExchangeAccountRequest 创建/更新交易所账户请求（解密后的内容）
+type ExchangeAccountRequest struct {
+	ExchangeID            string `json:"exchange_id"` // 交易所类型，仅创建时使用
+	DisplayName           string `json:"display_name"`
+	Enabled               bool   `json:"enabled"`
+	APIKey                string `json:"api_key"`
+	SecretKey             string `json:"secret_key"`
+	Testnet               bool   `json:"testnet"`
+	HyperliquidWalletAddr string `json:"hyperliquid_wallet_addr"`
+	AsterUser             string `json:"aster_user"`
+	AsterSigner           string `json:"aster_signer"`
+	AsterPrivateKey       string `json:"aster_private_key"`
+	OKXPassphrase         string `json:"okx_passphrase"`
+}
+
+func (r *ExchangeAccountRequest) input(displayName string) *config.ExchangeAccountInput {
+	return &config.ExchangeAccountInput{
+		DisplayName:           displayName,
+		Enabled:               r.Enabled,
+		APIKey:                r.APIKey,
+		SecretKey:             r.SecretKey,
+		Testnet:               r.Testnet,
+		HyperliquidWalletAddr: r.HyperliquidWalletAddr,
+		AsterUser:             r.AsterUser,
+		AsterSigner:           r.AsterSigner,
+		AsterPrivateKey:       r.AsterPrivateKey,
+		OKXPassphrase:         r.OKXPassphrase,
+	}
+}
+
+// ModelAccountRequest 创建/更新AI模型账户请求（解密后的内容）
+type ModelAccountRequest struct {
+	ModelID         string `json:"model_id"` // 模型类型，仅创建时使用
+	DisplayName     string `json:"display_name"`
+	Enabled         bool   `json:"enabled"`
+	APIKey          string `json:"api_key"`
+	CustomAPIURL    string `json:"custom_api_url"`
+	CustomModelName string `json:"custom_model_name"`
+}
+
+func (r *ModelAccountRequest) input(displayName string) *config.AIModelAccountInput {
+	return &config.AIModelAccountInput{
+		DisplayName:     displayName,
+		Enabled:         r.Enabled,
+		APIKey:          r.APIKey,
+		CustomAPIURL:    r.CustomAPIURL,
+		CustomModelName: r.CustomModelName,
+	}
+}
+
+// accountIDParam 解析路径中的账户自增ID
+func accountIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		respondError(c, http.StatusBadRequest, "ACCOUNT_ID_INVALID")
+		return 0, false
+	}
+	return id, true
+}
+
+// handleCreateExchangeAccount 新建交易所账户（同一交易所可有多个账户，以 display_name 区分）
+func (s *Server) handleCreateExchangeAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req ExchangeAccountRequest
+	if !s.decryptAccountPayload(c, userID, &req) {
+		return
+	}
+	exchangeID := strings.TrimSpace(req.ExchangeID)
+	if exchangeID == "" {
+		respondError(c, http.StatusBadRequest, "EXCHANGE_ID_REQUIRED")
+		return
+	}
+	displayName, err := normalizeAccountName(req.DisplayName)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	id, err := s.database.CreateExchangeAccount(userID, exchangeID, req.input(displayName))
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	s.recordAudit(c, userID, auditExchangeCreate, auditResourceExchange, strconv.Itoa(id), map[string]interface{}{
+		"exchange_id":  exchangeID,
+		"display_name": displayName,
+	})
+
+	log.Printf("✓ 交易所账户已创建: %s %q (ID=%d, 用户: %s)", exchangeID, displayName, id, userID)
+	c.JSON(http.StatusCreated, gin.H{
+		"id":           id,
+		"exchange_id":  exchangeID,
+		"display_name": displayName,
+	})
+}
+
+// handleUpdateExchangeAccount 按自增ID更新交易所账户（密钥留空表示不修改）
+func (s *Server) handleUpdateExchangeAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	id, ok := accountIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ExchangeAccountRequest
+	if !s.decryptAccountPayload(c, userID, &req) {
+		return
+	}
+	displayName, err := normalizeAccountName(req.DisplayName)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	before := s.findExchangeByRef(userID, strconv.Itoa(id))
+	if err := s.database.UpdateExchangeAccount(userID, id, req.input(displayName)); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	s.recordAudit(c, userID, auditExchangeUpdate, auditResourceExchange, strconv.Itoa(id), map[string]interface{}{
+		"changed_fields": changedFields(before, s.findExchangeByRef(userID, strconv.Itoa(id))),
+	})
+
+	// 重新加载该用户的所有交易员，使新配置立即生效
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 重新加载用户交易员到内存失败: %v", err)
+	}
+
+	log.Printf("✓ 交易所账户已更新: ID=%d %q (用户: %s)", id, displayName, userID)
+	c.JSON(http.StatusOK, gin.H{"message": "交易所账户已更新"})
+}
+
+// handleCreateModelAccount 新建AI模型账户（例如两个不同 Base URL 的 DeepSeek Key）
+func (s *Server) handleCreateModelAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req ModelAccountRequest
+	if !s.decryptAccountPayload(c, userID, &req) {
+		return
+	}
+	modelID := strings.TrimSpace(req.ModelID)
+	if modelID == "" {
+		respondError(c, http.StatusBadRequest, "MODEL_ID_REQUIRED")
+		return
+	}
+	displayName, err := normalizeAccountName(req.DisplayName)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	id, err := s.database.CreateAIModelAccount(userID, modelID, req.input(displayName))
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	s.recordAudit(c, userID, auditModelCreate, auditResourceModel, strconv.Itoa(id), map[string]interface{}{
+		"model_id":     modelID,
+		"display_name": displayName,
+	})
+
+	log.Printf("✓ AI模型账户已创建: %s %q (ID=%d, 用户: %s)", modelID, displayName, id, userID)
+	c.JSON(http.StatusCreated, gin.H{
+		"id":           id,
+		"model_id":     modelID,
+		"display_name": displayName,
+	})
+}
+
+// handleUpdateModelAccount 按自增ID更新AI模型账户（API Key 留空表示不修改）
+func (s *Server) handleUpdateModelAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	id, ok := accountIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ModelAccountRequest
+	if !s.decryptAccountPayload(c, userID, &req) {
+		return
+	}
+	displayName, err := normalizeAccountName(req.DisplayName)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	before := s.findAIModelByRef(userID, strconv.Itoa(id))
+	if err := s.database.UpdateAIModelAccount(userID, id, req.input(displayName)); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	s.recordAudit(c, userID, auditModelUpdate, auditResourceModel, strconv.Itoa(id), map[string]interface{}{
+		"changed_fields": changedFields(before, s.findAIModelByRef(userID, strconv.Itoa(id))),
+	})
+
+	// 重新加载该用户的所有交易员，使新配置立即生效
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 重新加载用户交易员到内存失败: %v", err)
+	}
+
+	log.Printf("✓ AI模型账户已更新: ID=%d %q (用户: %s)", id, displayName, userID)
+	c.JSON(http.StatusOK, gin.H{"message": "AI模型账户已更新"})
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/config"
+)
+
+func TestFindExchangeAccount(t *testing.T) {
+	exchanges := []*config.ExchangeConfig{
+		{ID: 3, ExchangeID: "binance", DisplayName: "Binance-sub"},
+		{ID: 5, ExchangeID: "binance"},
+		{ID: 7, ExchangeID: "okx", DisplayName: "okx-main"},
+	}
+
+	cases := []struct {
+		ref  string
+		want int
+	}{
+		{"binance", 5}, // 按类型引用优先默认账户
+		{"3", 3},       // 按自增ID引用指定账户
+		{"okx", 7},     // 没有默认账户时使用第一个
+		{"9", 0},
+		{"aster", 0},
+	}
+	for _, tc := range cases {
+		got := findExchangeAccount(exchanges, tc.ref)
+		gotID := 0
+		if got != nil {
+			gotID = got.ID
+		}
+		if gotID != tc.want {
+			t.Errorf("findExchangeAccount(%q) = %d, 期望 %d", tc.ref, gotID, tc.want)
+		}
+	}
+}
+
+func TestChangedFieldsOmitsValues(t *testing.T) {
+	before := &config.ExchangeConfig{ID: 3, ExchangeID: "binance", APIKey: "old-key", SecretKey: "secret", UpdatedAt: "2025-01-01"}
+	after := &config.ExchangeConfig{ID: 3, ExchangeID: "binance", APIKey: "new-key", SecretKey: "secret", Testnet: true, UpdatedAt: "2025-01-02"}
+
+	got := changedFields(before, after)
+	if strings.Join(got, ",") != "apiKey,testnet" {
+		t.Errorf("changedFields = %v, 期望 [apiKey testnet]（忽略时间戳）", got)
+	}
+
+	// 没有旧配置时所有字段都视为变更
+	if got := changedFields((*config.AIModelConfig)(nil), &config.AIModelConfig{ModelID: "deepseek", APIKey: "k"}); len(got) == 0 {
+		t.Error("没有旧配置时应返回变更字段")
+	}
+}
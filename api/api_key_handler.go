@@ -0,0 +1,165 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyAuthScheme API Key 认证使用的 Authorization 方案（Authorization: ApiKey <token>）
+const apiKeyAuthScheme = "ApiKey"
+
+// maxAPIKeyExpiresInDays API Key 有效期上限（天），0 表示永不过期
+const maxAPIKeyExpiresInDays = 3650
+
+// apiKeyAllows 判断 API Key 权限范围是否允许该请求：只读密钥只允许 GET/HEAD/OPTIONS 以及登出
+func apiKeyAllows(scope, method, path string) bool {
+	if scope == config.APIKeyScopeReadWrite {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return path == "/api/logout"
+}
+
+// rejectAPIKeyManagement API Key 只能由登录会话创建和撤销（泄露的密钥不能再签发新密钥），API Key 请求返回 403
+func rejectAPIKeyManagement(c *gin.Context) bool {
+	if c.GetString("api_key_id") != "" {
+		respondError(c, http.StatusForbidden, "API_KEY_CANNOT_MANAGE_KEYS")
+		return true
+	}
+	return false
+}
+
+// authenticateAPIKey 校验 API Key 并写入与 JWT 登录相同的用户上下文，失败时中止请求并返回 false
+func (s *Server) authenticateAPIKey(c *gin.Context, token string) bool {
+	key, err := s.database.GetUserAPIKeyByKey(token)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "API_KEY_CHECK_FAILED")
+		c.Abort()
+		return false
+	}
+	if key == nil || key.IsExpired(time.Now()) {
+		respondError(c, http.StatusUnauthorized, "API_KEY_INVALID")
+		c.Abort()
+		return false
+	}
+
+	// 被管理员禁用的用户，其 API Key 同样无法访问
+	if s.database.IsUserDisabled(key.UserID) {
+		respondError(c, http.StatusForbidden, "ACCOUNT_DISABLED")
+		c.Abort()
+		return false
+	}
+
+	if !apiKeyAllows(key.Scope, c.Request.Method, c.Request.URL.Path) {
+		respondError(c, http.StatusForbidden, "API_KEY_READ_ONLY")
+		c.Abort()
+		return false
+	}
+
+	if err := s.database.TouchUserAPIKey(key.ID); err != nil {
+		log.Printf("⚠️ 更新API Key最后使用时间失败: %v", err)
+	}
+
+	email := ""
+	if user, err := s.database.GetUserByID(key.UserID); err == nil && user != nil {
+		email = user.Email
+	}
+	c.Set("user_id", key.UserID)
+	c.Set("email", email)
+	c.Set("api_key_id", key.ID)
+	return true
+}
+
+// handleListAPIKeys 列出当前用户的 API Key（不含明文）
+func (s *Server) handleListAPIKeys(c *gin.Context) {
+	keys, err := s.database.ListUserAPIKeys(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LIST_API_KEYS_FAILED")
+		return
+	}
+	now := time.Now()
+	items := make([]gin.H, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, gin.H{
+			"id":           key.ID,
+			"name":         key.Name,
+			"key_prefix":   key.KeyPrefix,
+			"scope":        key.Scope,
+			"created_at":   key.CreatedAt,
+			"last_used_at": key.LastUsedAt,
+			"expires_at":   key.ExpiresAt,
+			"expired":      key.IsExpired(now),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": items})
+}
+
+// handleCreateAPIKey 创建 API Key，明文密钥只在本次响应中返回
+func (s *Server) handleCreateAPIKey(c *gin.Context) {
+	if rejectAPIKeyManagement(c) {
+		return
+	}
+	var req struct {
+		Name          string `json:"name" binding:"required"`
+		Scope         string `json:"scope"`
+		ExpiresInDays int    `json:"expires_in_days"` // 0 表示永不过期
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = config.APIKeyScopeRead
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPIKeyExpiresInDays {
+		respondError(c, http.StatusBadRequest, "API_KEY_EXPIRY_INVALID")
+		return
+	}
+	var expiresAt time.Time
+	if req.ExpiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, req.ExpiresInDays)
+	}
+
+	userID := c.GetString("user_id")
+	plaintext, key, err := s.database.CreateUserAPIKey(userID, req.Name, strings.TrimSpace(req.Scope), expiresAt)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	log.Printf("🔑 用户 %s 创建了API Key %s (%s, 权限 %s)", userID, key.ID, key.Name, key.Scope)
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         key.ID,
+		"name":       key.Name,
+		"key":        plaintext,
+		"key_prefix": key.KeyPrefix,
+		"scope":      key.Scope,
+		"created_at": key.CreatedAt,
+		"expires_at": key.ExpiresAt,
+		"message":    "请立即保存该密钥，之后将无法再次查看",
+	})
+}
+
+// handleDeleteAPIKey 撤销 API Key
+func (s *Server) handleDeleteAPIKey(c *gin.Context) {
+	if rejectAPIKeyManagement(c) {
+		return
+	}
+	userID := c.GetString("user_id")
+	id := c.Param("id")
+	if err := s.database.DeleteUserAPIKey(userID, id); err != nil {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", err)
+		return
+	}
+	log.Printf("🔑 用户 %s 撤销了API Key %s", userID, id)
+	c.JSON(http.StatusOK, gin.H{"message": "API Key已撤销"})
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 审计动作
+const (
+	auditLoginSuccess           = "login_success"
+	auditLoginFailure           = "login_failure"
+	auditPasswordReset          = "password_reset"
+	auditPasswordChange         = "password_change"
+	auditLogoutAll              = "logout_all"
+	auditExchangeCreate         = "exchange_create"
+	auditExchangeUpdate         = "exchange_update"
+	auditModelCreate            = "model_create"
+	auditModelUpdate            = "model_update"
+	auditTraderCreate           = "trader_create"
+	auditTraderUpdate           = "trader_update"
+	auditTraderDelete           = "trader_delete"
+	auditTraderStart            = "trader_start"
+	auditTraderStop             = "trader_stop"
+	auditPromptTemplateCreate   = "prompt_template_create"
+	auditPromptTemplateUpdate   = "prompt_template_update"
+	auditPromptTemplateDelete   = "prompt_template_delete"
+	auditPromptTemplateRollback = "prompt_template_rollback"
+	auditTraderTemplateCreate   = "trader_template_create"
+	auditTraderTemplateDelete   = "trader_template_delete"
+)
+
+// 审计资源类型
+const (
+	auditResourceUser           = "user"
+	auditResourceExchange       = "exchange"
+	auditResourceModel          = "model"
+	auditResourceTrader         = "trader"
+	auditResourcePromptTemplate = "prompt_template"
+	auditResourceTraderTemplate = "trader_template"
+)
+
+// maxAuditLogLimit 单次查询审计日志的最大条数
+const maxAuditLogLimit = 500
+
+// auditIgnoredFields 对比配置变更时忽略的字段（标识和时间戳）
+var auditIgnoredFields = map[string]bool{
+	"id": true, "user_id": true, "created_at": true, "updated_at": true, "is_running": true,
+}
+
+// recordAudit 写入一条审计记录（写入失败只记录日志，不影响请求结果）
+func (s *Server) recordAudit(c *gin.Context, userID, action, resourceType, resourceID string, details map[string]interface{}) {
+	entry := &config.AuditLogEntry{
+		UserID:       userID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		IP:           c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		Details:      details,
+	}
+	if err := s.database.RecordAuditLog(entry); err != nil {
+		log.Printf("⚠️ 写入审计日志失败 (%s %s): %v", action, resourceID, err)
+	}
+}
+
+// changedFields 对比两个配置对象（按 JSON 字段名）并返回取值不同的字段名
+// 只返回字段名、不返回取值，密钥等敏感字段不会出现在审计日志中
+func changedFields(before, after interface{}) []string {
+	toMap := func(v interface{}) map[string]interface{} {
+		m := map[string]interface{}{}
+		if data, err := json.Marshal(v); err == nil {
+			_ = json.Unmarshal(data, &m)
+		}
+		return m
+	}
+	b, a := toMap(before), toMap(after)
+
+	changed := make([]string, 0)
+	for key, value := range a {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if !reflect.DeepEqual(b[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// findExchangeByRef 按引用（自增ID或交易所类型）查找交易所配置，用于记录更新前后的变更字段
+func (s *Server) findExchangeByRef(userID, ref string) *config.ExchangeConfig {
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		return nil
+	}
+	return findExchangeAccount(exchanges, ref)
+}
+
+// findAIModelByRef 按引用（自增ID或模型类型）查找AI模型配置，用于记录更新前后的变更字段
+func (s *Server) findAIModelByRef(userID, ref string) *config.AIModelConfig {
+	models, err := s.database.GetAIModels(userID)
+	if err != nil {
+		return nil
+	}
+	return findAIModelAccount(models, ref)
+}
+
+// handleUserAuditLog 当前用户的审计日志（分页，可按动作和时间范围过滤）
+func (s *Server) handleUserAuditLog(c *gin.Context) {
+	s.respondAuditLog(c, c.GetString("user_id"))
+}
+
+// handleAdminAuditLog 所有用户的审计日志（管理员，可用 user_id 过滤）
+func (s *Server) handleAdminAuditLog(c *gin.Context) {
+	s.respondAuditLog(c, strings.TrimSpace(c.Query("user_id")))
+}
+
+// respondAuditLog 解析分页和过滤参数并返回审计日志
+// 查询参数：action、since/until（毫秒时间戳、RFC3339 或 YYYY-MM-DD）、limit（默认 50）、offset
+func (s *Server) respondAuditLog(c *gin.Context, userID string) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > maxAuditLogLimit {
+		respondError(c, http.StatusBadRequest, "LIMIT_OUT_OF_RANGE", maxAuditLogLimit)
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		respondError(c, http.StatusBadRequest, "OFFSET_INVALID")
+		return
+	}
+
+	filter := config.AuditLogFilter{
+		UserID: userID,
+		Action: strings.TrimSpace(c.Query("action")),
+	}
+	if filter.Since, err = parseTimeParam(c.Query("since"), time.Time{}); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	if filter.Until, err = parseTimeParam(c.Query("until"), time.Time{}); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		respondError(c, http.StatusBadRequest, "TIME_RANGE_UNTIL_BEFORE_SINCE")
+		return
+	}
+
+	entries, total, err := s.database.GetAuditLog(filter, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_AUDIT_LOG_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
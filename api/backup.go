@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// backupCheckInterval 定时备份检查间隔
+const backupCheckInterval = time.Minute
+
+// runBackupScheduler 按 backup_interval_hours / backup_time 定时备份数据库，并按保留策略清理旧备份
+func (s *Server) runBackupScheduler() {
+	ticker := time.NewTicker(backupCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runScheduledBackup(time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// runScheduledBackup 最近一个计划时间点之后还没有执行过备份时执行一次（重启后错过的备份会立即补上）
+func (s *Server) runScheduledBackup(now time.Time) {
+	intervalStr, _ := s.database.GetSystemConfig("backup_interval_hours")
+	intervalHours, err := strconv.Atoi(strings.TrimSpace(intervalStr))
+	if err != nil || intervalHours <= 0 {
+		return
+	}
+	startAt, _ := s.database.GetSystemConfig("backup_time")
+
+	slot := lastBackupSlot(now, startAt, time.Duration(intervalHours)*time.Hour)
+	if !s.database.GetBackupStatus().LastRunAt.Before(slot) {
+		return
+	}
+
+	info, err := s.database.CreateBackup("scheduled")
+	if err != nil {
+		log.Printf("❌ 定时备份数据库失败: %v", err)
+		return
+	}
+	log.Printf("💾 已定时备份数据库: %s (%d 字节)", info.Name, info.Size)
+	s.pruneBackups()
+}
+
+// lastBackupSlot 不晚于 now 的最近一个计划备份时间：从当天 startAt（HH:MM，本地时间，格式错误时为 00:00）起每隔 interval 一次
+func lastBackupSlot(now time.Time, startAt string, interval time.Duration) time.Time {
+	anchor := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if t, err := time.Parse("15:04", strings.TrimSpace(startAt)); err == nil {
+		anchor = anchor.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+	}
+
+	steps := now.Sub(anchor) / interval
+	slot := anchor.Add(steps * interval)
+	if slot.After(now) {
+		slot = slot.Add(-interval)
+	}
+	return slot
+}
+
+// pruneBackups 按 backup_keep_count / backup_keep_days 清理旧备份
+func (s *Server) pruneBackups() {
+	keepCountStr, _ := s.database.GetSystemConfig("backup_keep_count")
+	keepDaysStr, _ := s.database.GetSystemConfig("backup_keep_days")
+	keepCount, _ := strconv.Atoi(strings.TrimSpace(keepCountStr))
+	keepDays, _ := strconv.Atoi(strings.TrimSpace(keepDaysStr))
+	if keepCount <= 0 && keepDays <= 0 {
+		return
+	}
+
+	deleted, err := s.database.PruneBackups(keepCount, keepDays)
+	if err != nil {
+		log.Printf("⚠️ 清理旧备份失败: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 已清理 %d 个旧的数据库备份", deleted)
+	}
+}
+
+// handleCreateBackup 立即备份数据库（管理员）
+func (s *Server) handleCreateBackup(c *gin.Context) {
+	info, err := s.database.CreateBackup("manual")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "BACKUP_FAILED", err)
+		return
+	}
+	log.Printf("💾 管理员 %s 手动备份数据库: %s (%d 字节)", c.GetString("user_id"), info.Name, info.Size)
+	s.pruneBackups()
+
+	c.JSON(http.StatusOK, info)
+}
+
+// handleListBackups 列出数据库备份及最近一次备份的执行结果（管理员）
+func (s *Server) handleListBackups(c *gin.Context) {
+	backups, err := s.database.ListBackups()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LIST_BACKUPS_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backups": backups,
+		"status":  s.database.GetBackupStatus(),
+	})
+}
+
+// handleDownloadBackup 下载一个数据库备份文件（管理员，文件名经过校验，只能访问备份目录）
+func (s *Server) handleDownloadBackup(c *gin.Context) {
+	name := c.Param("name")
+	path, err := s.database.BackupPath(name)
+	if errors.Is(err, config.ErrBackupNotFound) {
+		respondError(c, http.StatusNotFound, "BACKUP_NOT_FOUND")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_ERROR", err)
+		return
+	}
+
+	log.Printf("📥 管理员 %s 下载数据库备份: %s", c.GetString("user_id"), name)
+	c.FileAttachment(path, name)
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLastBackupSlot 测试定时备份的计划时间点：从每天 backup_time 起每隔 interval 一次
+func TestLastBackupSlot(t *testing.T) {
+	day := func(d, h, m int) time.Time { return time.Date(2025, 3, d, h, m, 0, 0, time.Local) }
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		startAt  string
+		interval time.Duration
+		want     time.Time
+	}{
+		{"每天03:00_当天已过", day(10, 10, 0), "03:00", 24 * time.Hour, day(10, 3, 0)},
+		{"每天03:00_当天未到", day(10, 2, 59), "03:00", 24 * time.Hour, day(9, 3, 0)},
+		{"每天03:00_正好", day(10, 3, 0), "03:00", 24 * time.Hour, day(10, 3, 0)},
+		{"每6小时", day(10, 20, 30), "03:00", 6 * time.Hour, day(10, 15, 0)},
+		{"每6小时_凌晨", day(10, 1, 0), "03:00", 6 * time.Hour, day(9, 21, 0)},
+		{"格式错误按00:00", day(10, 10, 0), "bad", 24 * time.Hour, day(10, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastBackupSlot(tt.now, tt.startAt, tt.interval); !got.Equal(tt.want) {
+				t.Errorf("lastBackupSlot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/config"
+	"nofx/trader"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// defaultBalanceCacheTTL 余额缓存默认有效期（可通过 balance_cache_ttl 配置）
+	defaultBalanceCacheTTL = 10 * time.Second
+	// balanceThrottleWait 本地限流时最多等待的时间（没有缓存可用时）
+	balanceThrottleWait = 5 * time.Second
+)
+
+// exchangeBalanceLimits 每个交易所的余额查询限流（每秒请求数、突发数），未列出的使用默认值
+var exchangeBalanceLimits = map[string]struct {
+	r rate.Limit
+	b int
+}{
+	"binance":     {rate.Limit(1), 2},
+	"okx":         {rate.Limit(1), 2},
+	"aster":       {rate.Limit(1), 2},
+	"hyperliquid": {rate.Limit(2), 4},
+}
+
+// balanceSnapshot 一次余额查询结果
+type balanceSnapshot struct {
+	TotalEquity      float64
+	WalletBalance    float64
+	UnrealizedProfit float64
+	FetchedAt        time.Time
+	IsStale          bool // 交易所限流时返回的旧缓存
+}
+
+// pooledBalanceTrader 复用的余额查询 trader（凭证变化时重建）
+type pooledBalanceTrader struct {
+	trader      trader.Trader
+	fingerprint string
+}
+
+// balanceService 交易所余额查询服务
+// 按 (userID, exchangeID) 缓存余额、复用 trader 实例，并按交易所限流，避免频繁创建交易员/同步余额时触发交易所请求权重限制（如币安 -1003）
+type balanceService struct {
+	mu       sync.Mutex
+	entries  map[string]*balanceSnapshot
+	traders  map[string]*pooledBalanceTrader
+	keyLocks map[string]*sync.Mutex
+	limiters map[string]*rate.Limiter
+
+	ttl       func() time.Duration
+	newTrader func(userID string, cfg *config.ExchangeConfig) (trader.Trader, error)
+	now       func() time.Time
+}
+
+// newBalanceService 创建余额查询服务
+func newBalanceService(ttl func() time.Duration, newTrader func(userID string, cfg *config.ExchangeConfig) (trader.Trader, error)) *balanceService {
+	return &balanceService{
+		entries:   make(map[string]*balanceSnapshot),
+		traders:   make(map[string]*pooledBalanceTrader),
+		keyLocks:  make(map[string]*sync.Mutex),
+		limiters:  make(map[string]*rate.Limiter),
+		ttl:       ttl,
+		newTrader: newTrader,
+		now:       time.Now,
+	}
+}
+
+// balanceKey 缓存键（同一交易所的多个账户按配置ID区分）
+func balanceKey(userID string, cfg *config.ExchangeConfig) string {
+	return fmt.Sprintf("%s|%s|%d", userID, cfg.ExchangeID, cfg.ID)
+}
+
+// credentialFingerprint 交易所凭证指纹，凭证或代理变化后不再复用旧的 trader 实例
+func credentialFingerprint(cfg *config.ExchangeConfig, proxy string) string {
+	h := sha256.New()
+	for _, field := range []string{
+		cfg.APIKey, cfg.SecretKey, cfg.HyperliquidWalletAddr,
+		cfg.AsterUser, cfg.AsterSigner, cfg.AsterPrivateKey, cfg.OKXPassphrase,
+		strconv.FormatBool(cfg.Testnet), proxy,
+	} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isRateLimitError 判断是否为交易所限流错误
+func isRateLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"-1003", "429", "418", "too many requests", "too much request weight", "rate limit"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockKey 获取缓存键的互斥锁（同一账户的并发查询合并为一次上游请求）
+func (b *balanceService) lockKey(key string) *sync.Mutex {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.keyLocks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		b.keyLocks[key] = l
+	}
+	return l
+}
+
+// limiter 获取交易所的限流器
+func (b *balanceService) limiter(exchangeID string) *rate.Limiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.limiters[exchangeID]
+	if !ok {
+		limit, exists := exchangeBalanceLimits[exchangeID]
+		if !exists {
+			limit.r, limit.b = rate.Limit(2), 4
+		}
+		l = rate.NewLimiter(limit.r, limit.b)
+		b.limiters[exchangeID] = l
+	}
+	return l
+}
+
+// cached 读取缓存（返回副本）
+func (b *balanceService) cached(key string) (balanceSnapshot, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[key]
+	if !ok {
+		return balanceSnapshot{}, false
+	}
+	return *entry, true
+}
+
+// pooledTrader 获取复用的 trader，凭证变化时重建
+func (b *balanceService) pooledTrader(key, userID, fingerprint string, cfg *config.ExchangeConfig) (trader.Trader, error) {
+	b.mu.Lock()
+	pooled, ok := b.traders[key]
+	b.mu.Unlock()
+	if ok && pooled.fingerprint == fingerprint {
+		return pooled.trader, nil
+	}
+
+	t, err := b.newTrader(userID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, fmt.Errorf("tempTrader 為 nil")
+	}
+
+	b.mu.Lock()
+	b.traders[key] = &pooledBalanceTrader{trader: t, fingerprint: fingerprint}
+	b.mu.Unlock()
+	return t, nil
+}
+
+// Get 查询账户总资产：缓存未过期时直接返回，否则经限流后向交易所查询；交易所限流时返回旧缓存并标记 IsStale
+func (b *balanceService) Get(userID string, cfg *config.ExchangeConfig, proxy string) (*balanceSnapshot, error) {
+	key := balanceKey(userID, cfg)
+	fingerprint := credentialFingerprint(cfg, proxy)
+
+	keyLock := b.lockKey(key)
+	keyLock.Lock()
+	defer keyLock.Unlock()
+
+	// 凭证变化时旧缓存不可信
+	b.mu.Lock()
+	if pooled, ok := b.traders[key]; ok && pooled.fingerprint != fingerprint {
+		delete(b.entries, key)
+	}
+	b.mu.Unlock()
+
+	cached, hasCache := b.cached(key)
+	if hasCache && b.now().Sub(cached.FetchedAt) < b.ttl() {
+		return &cached, nil
+	}
+
+	limiter := b.limiter(cfg.ExchangeID)
+	if !limiter.Allow() {
+		if hasCache {
+			cached.IsStale = true
+			return &cached, nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), balanceThrottleWait)
+		err := limiter.Wait(ctx)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("交易所 %s 余额查询过于频繁，请稍后重试", cfg.ExchangeID)
+		}
+	}
+
+	t, err := b.pooledTrader(key, userID, fingerprint, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("創建臨時 trader 失敗: %w", err)
+	}
+
+	balanceInfo, err := t.GetBalance()
+	if err != nil {
+		if isRateLimitError(err) && hasCache {
+			log.Printf("⚠️ 交易所 %s 限流，返回 %s 前缓存的余额: %v", cfg.ExchangeID, b.now().Sub(cached.FetchedAt).Round(time.Second), err)
+			cached.IsStale = true
+			return &cached, nil
+		}
+		return nil, fmt.Errorf("查詢交易所余額失敗: %w", err)
+	}
+
+	totalEquity, success := trader.ParseTotalEquity(balanceInfo, "✓")
+	if !success {
+		return nil, fmt.Errorf("無法從餘額信息中提取總資產")
+	}
+	snapshot := balanceSnapshot{TotalEquity: totalEquity, FetchedAt: b.now()}
+	if wallet, ok := balanceInfo["totalWalletBalance"].(float64); ok {
+		snapshot.WalletBalance = wallet
+	}
+	if unrealized, ok := balanceInfo["totalUnrealizedProfit"].(float64); ok {
+		snapshot.UnrealizedProfit = unrealized
+	}
+
+	b.mu.Lock()
+	b.entries[key] = &snapshot
+	b.mu.Unlock()
+	return &snapshot, nil
+}
+
+// balanceCacheTTL 从系统配置读取余额缓存有效期（balance_cache_ttl 秒，0=不缓存）
+func (s *Server) balanceCacheTTL() time.Duration {
+	ttlStr, _ := s.database.GetSystemConfig("balance_cache_ttl")
+	seconds, err := strconv.Atoi(strings.TrimSpace(ttlStr))
+	if err != nil || seconds < 0 {
+		return defaultBalanceCacheTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// newBalanceTrader 根据交易所类型创建用于查询余额的 trader
+func (s *Server) newBalanceTrader(userID string, exchangeCfg *config.ExchangeConfig) (trader.Trader, error) {
+	switch exchangeCfg.ExchangeID {
+	case "binance":
+		// 使用默认订单策略（查询余额不需要实际下单）
+		return trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.Testnet, userID, "market_only", -0.03, 60, s.userOutboundTransport(userID)), nil
+	case "hyperliquid":
+		return trader.NewHyperliquidTrader(
+			exchangeCfg.APIKey, // private key
+			exchangeCfg.HyperliquidWalletAddr,
+			exchangeCfg.Testnet,
+		)
+	case "aster":
+		return trader.NewAsterTrader(
+			exchangeCfg.AsterUser,
+			exchangeCfg.AsterSigner,
+			exchangeCfg.AsterPrivateKey,
+			s.userOutboundTransport(userID),
+		)
+	case "okx":
+		return trader.NewOKXTrader(
+			exchangeCfg.APIKey,
+			exchangeCfg.SecretKey,
+			exchangeCfg.OKXPassphrase,
+			exchangeCfg.Testnet,
+			s.userOutboundTransport(userID),
+		)
+	case "paper":
+		// 模拟盘没有真实余额，未指定初始资金时使用默认模拟资金
+		return trader.NewPaperTrader("", trader.DefaultPaperBalance, 0, 0, "market_only"), nil
+	default:
+		return nil, fmt.Errorf("不支持的交易所類型: %s", exchangeCfg.ExchangeID)
+	}
+}
+
+// fetchExchangeBalance 通过余额缓存查询交易所总资产
+func (s *Server) fetchExchangeBalance(userID string, exchangeCfg *config.ExchangeConfig) (*balanceSnapshot, error) {
+	proxy, _ := s.database.GetUserOutboundProxy(userID)
+	return s.balances.Get(userID, exchangeCfg, proxy)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"nofx/config"
+	"nofx/trader"
+)
+
+// fakeBalanceTrader 只实现 GetBalance 的测试 trader
+type fakeBalanceTrader struct {
+	trader.Trader
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (f *fakeBalanceTrader) GetBalance() (map[string]interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return map[string]interface{}{
+		"totalWalletBalance":    1000.0,
+		"totalUnrealizedProfit": 25.0,
+	}, nil
+}
+
+func TestBalanceServiceCachesAndPoolsTrader(t *testing.T) {
+	fake := &fakeBalanceTrader{}
+	created := 0
+	svc := newBalanceService(
+		func() time.Duration { return 10 * time.Second },
+		func(userID string, cfg *config.ExchangeConfig) (trader.Trader, error) {
+			created++
+			return fake, nil
+		},
+	)
+	cfg := &config.ExchangeConfig{ExchangeID: "binance", APIKey: "key", SecretKey: "secret"}
+
+	// 连续点击十次同步余额，只应请求交易所一次
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			snapshot, err := svc.Get("user1", cfg, "")
+			if err != nil {
+				t.Errorf("查询余额失败: %v", err)
+				return
+			}
+			if snapshot.TotalEquity != 1025 || snapshot.IsStale {
+				t.Errorf("期望总资产 1025 且不过期, 实际 %.2f stale=%v", snapshot.TotalEquity, snapshot.IsStale)
+			}
+		}()
+	}
+	wg.Wait()
+	if fake.calls != 1 || created != 1 {
+		t.Errorf("期望 1 次上游请求和 1 个 trader 实例, 实际 %d 次请求 %d 个实例", fake.calls, created)
+	}
+
+	// 凭证变化后重建 trader 并重新查询
+	changed := *cfg
+	changed.APIKey = "new-key"
+	if _, err := svc.Get("user1", &changed, ""); err != nil {
+		t.Fatalf("查询余额失败: %v", err)
+	}
+	if fake.calls != 2 || created != 2 {
+		t.Errorf("凭证变化后应重建 trader, 实际 %d 次请求 %d 个实例", fake.calls, created)
+	}
+}
+
+func TestBalanceServiceServesStaleOnRateLimit(t *testing.T) {
+	fake := &fakeBalanceTrader{}
+	now := time.Now()
+	svc := newBalanceService(
+		func() time.Duration { return 10 * time.Second },
+		func(userID string, cfg *config.ExchangeConfig) (trader.Trader, error) { return fake, nil },
+	)
+	svc.now = func() time.Time { return now }
+	cfg := &config.ExchangeConfig{ExchangeID: "binance"}
+
+	if _, err := svc.Get("user1", cfg, ""); err != nil {
+		t.Fatalf("查询余额失败: %v", err)
+	}
+
+	// 缓存过期后交易所返回 -1003，应返回旧值并标记 is_stale
+	now = now.Add(time.Minute)
+	fake.err = errors.New("<APIError> code=-1003, msg=Too many requests")
+	snapshot, err := svc.Get("user1", cfg, "")
+	if err != nil {
+		t.Fatalf("限流时应返回缓存, 实际错误: %v", err)
+	}
+	if !snapshot.IsStale || snapshot.TotalEquity != 1025 {
+		t.Errorf("期望返回过期缓存 1025, 实际 %.2f stale=%v", snapshot.TotalEquity, snapshot.IsStale)
+	}
+
+	// 没有缓存时限流错误直接返回
+	if _, err := svc.Get("user2", cfg, ""); err == nil {
+		t.Error("没有缓存时限流错误应返回错误")
+	}
+
+	// 其他错误不使用缓存
+	okx := &config.ExchangeConfig{ExchangeID: "okx"}
+	fake.err = nil
+	if _, err := svc.Get("user1", okx, ""); err != nil {
+		t.Fatalf("查询余额失败: %v", err)
+	}
+	now = now.Add(time.Minute)
+	fake.err = errors.New("invalid api key")
+	if _, err := svc.Get("user1", okx, ""); err == nil {
+		t.Error("非限流错误不应返回缓存")
+	}
+}
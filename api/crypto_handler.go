@@ -47,30 +47,30 @@ func (h *CryptoHandler) HandleGetPublicKey(c *gin.Context) {
 // HandleDecryptSensitiveData 解密客戶端傳送的加密数据
 func (h *CryptoHandler) HandleDecryptSensitiveData(c *gin.Context) {
 	if !h.allowClientDecrypt {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Decrypt API disabled"})
+		respondError(c, http.StatusForbidden, "DECRYPT_API_DISABLED")
 		return
 	}
 
 	userID := strings.TrimSpace(c.GetString("user_id"))
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, "UNAUTHORIZED")
 		return
 	}
 
 	var payload crypto.EncryptedPayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		respondError(c, http.StatusBadRequest, "BAD_REQUEST")
 		return
 	}
 
 	if strings.TrimSpace(payload.AAD) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing AAD metadata"})
+		respondError(c, http.StatusBadRequest, "AAD_MISSING")
 		return
 	}
 
 	aadBytes, err := base64.RawURLEncoding.DecodeString(payload.AAD)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid AAD encoding"})
+		respondError(c, http.StatusBadRequest, "AAD_ENCODING_INVALID")
 		return
 	}
 
@@ -78,12 +78,12 @@ func (h *CryptoHandler) HandleDecryptSensitiveData(c *gin.Context) {
 		UserID string `json:"userId"`
 	}
 	if err := json.Unmarshal(aadBytes, &aad); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid AAD payload"})
+		respondError(c, http.StatusBadRequest, "AAD_PAYLOAD_INVALID")
 		return
 	}
 
 	if strings.TrimSpace(aad.UserID) == "" || aad.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "AAD mismatch: unauthorized decrypt request"})
+		respondError(c, http.StatusForbidden, "AAD_MISMATCH")
 		return
 	}
 
@@ -91,7 +91,7 @@ func (h *CryptoHandler) HandleDecryptSensitiveData(c *gin.Context) {
 	decrypted, err := h.cryptoService.DecryptSensitiveData(&payload)
 	if err != nil {
 		log.Printf("❌ 解密失敗: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Decryption failed"})
+		respondError(c, http.StatusInternalServerError, "DECRYPTION_FAILED")
 		return
 	}
 
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"nofx/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// decisionLogRootDir 所有交易员决策记录的根目录（每个交易员一个子目录）
+const decisionLogRootDir = "decision_logs"
+
+// handleEncryptDecisionLogs 一次性加密所有交易员历史决策记录中的提示词/思维链字段（管理员）
+// 运行中的交易员使用其自身的记录器，避免与正在写入的记录冲突；已加密的记录跳过，可重复执行
+func (s *Server) handleEncryptDecisionLogs(c *gin.Context) {
+	cs := s.cryptoHandler.cryptoService
+	if cs == nil || !cs.HasDataKey() {
+		respondError(c, http.StatusBadRequest, "ENCRYPTION_KEY_MISSING")
+		return
+	}
+
+	entries, err := os.ReadDir(decisionLogRootDir)
+	if err != nil && !os.IsNotExist(err) {
+		respondError(c, http.StatusInternalServerError, "READ_DECISION_LOG_DIR_FAILED")
+		return
+	}
+
+	results := gin.H{}
+	total := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		traderID := entry.Name()
+
+		var decisionLogger logger.IDecisionLogger
+		if at, err := s.traderManager.GetTrader(traderID); err == nil {
+			decisionLogger = at.GetDecisionLogger()
+		} else {
+			decisionLogger = logger.NewDecisionLogger(traderDecisionLogDir(traderID))
+			decisionLogger.SetCryptoService(cs, true)
+		}
+
+		encrypted, err := decisionLogger.EncryptExistingRecords()
+		total += encrypted
+		if err != nil {
+			log.Printf("⚠️ 加密交易员 %s 的决策记录失败: %v", traderID, err)
+			respondError(c, http.StatusInternalServerError, "ENCRYPT_DECISION_LOGS_FAILED", traderID, err)
+			return
+		}
+		if encrypted > 0 {
+			results[traderID] = encrypted
+		}
+	}
+
+	log.Printf("🔐 管理员 %s 加密了 %d 条历史决策记录", c.GetString("email"), total)
+	c.JSON(http.StatusOK, gin.H{
+		"encrypted_records": total,
+		"traders":           results,
+	})
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nofx/auth"
+	"nofx/config"
+	"nofx/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 邮件令牌有效期
+const (
+	emailVerifyTokenTTL   = 24 * time.Hour
+	passwordResetTokenTTL = 30 * time.Minute
+)
+
+// smtpConfig 当前 SMTP 配置和邮件链接地址
+// 环境变量 SMTP_HOST/SMTP_PORT/SMTP_USERNAME/SMTP_PASSWORD/SMTP_FROM/EMAIL_LINK_BASE_URL 优先于系统配置，链接地址最后回退 FRONTEND_URL
+func (s *Server) smtpConfig() (*notify.SMTPConfig, string) {
+	settings := s.database.GetSMTPSettings()
+	env := func(key, fallback string) string {
+		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	cfg := &notify.SMTPConfig{
+		Host:     env("SMTP_HOST", settings.Host),
+		Port:     settings.Port,
+		Username: env("SMTP_USERNAME", settings.Username),
+		Password: env("SMTP_PASSWORD", settings.Password),
+		From:     env("SMTP_FROM", settings.From),
+	}
+	if port, err := strconv.Atoi(strings.TrimSpace(os.Getenv("SMTP_PORT"))); err == nil {
+		cfg.Port = port
+	}
+
+	baseURL := env("EMAIL_LINK_BASE_URL", settings.LinkBaseURL)
+	if baseURL == "" {
+		baseURL = strings.TrimSpace(os.Getenv("FRONTEND_URL"))
+	}
+	return cfg, strings.TrimRight(baseURL, "/")
+}
+
+// emailEnabled 是否启用邮件功能（SMTP 和邮件链接地址均已配置）
+func (s *Server) emailEnabled() bool {
+	cfg, baseURL := s.smtpConfig()
+	return cfg.Configured() && baseURL != ""
+}
+
+// requireEmailVerification 是否要求验证邮箱后才能登录（未配置邮件服务时不生效）
+func (s *Server) requireEmailVerification() bool {
+	required, _ := s.database.GetSystemConfig("require_email_verification")
+	return strings.EqualFold(strings.TrimSpace(required), "true") && s.emailEnabled()
+}
+
+// sendEmailLink 生成一次性令牌并异步发送邮箱验证/密码重置链接（只入队，不等待 SMTP 响应）
+func (s *Server) sendEmailLink(user *config.User, purpose string) error {
+	cfg, baseURL := s.smtpConfig()
+	if !cfg.Configured() || baseURL == "" {
+		return fmt.Errorf("未配置邮件服务")
+	}
+
+	ttl, path, query := emailVerifyTokenTTL, "/login", "verify_email"
+	if purpose == config.EmailTokenResetPassword {
+		ttl, path, query = passwordResetTokenTTL, "/reset-password", "token"
+	}
+	token, err := s.database.CreateEmailToken(user.ID, purpose, ttl)
+	if err != nil {
+		return fmt.Errorf("生成邮件令牌失败: %w", err)
+	}
+	link := baseURL + path + "?" + url.Values{query: {token}}.Encode()
+
+	email := notify.Email{To: user.Email}
+	if purpose == config.EmailTokenResetPassword {
+		email.Subject = "NOFX 密码重置"
+		email.Body = fmt.Sprintf("您正在重置 NOFX 账户密码，请在 %d 分钟内打开以下链接设置新密码：\n\n%s\n\n如果不是您本人操作，请忽略此邮件，密码不会被修改。",
+			int(ttl.Minutes()), link)
+	} else {
+		email.Subject = "NOFX 邮箱验证"
+		email.Body = fmt.Sprintf("欢迎注册 NOFX！请在 %d 小时内打开以下链接验证邮箱：\n\n%s\n\n如果不是您本人注册，请忽略此邮件。",
+			int(ttl.Hours()), link)
+	}
+	if !s.mailer.Enqueue(cfg, email) {
+		return fmt.Errorf("邮件发送队列不可用")
+	}
+	return nil
+}
+
+// loginTokenResponse 签发 Access/Refresh Token 并构造登录成功响应
+func loginTokenResponse(user *config.User, message string) (gin.H, error) {
+	tokenPair, err := auth.GenerateTokenPair(user.ID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":              tokenPair.AccessToken, // 向後兼容舊版前端
+		"access_token":       tokenPair.AccessToken,
+		"refresh_token":      tokenPair.RefreshToken,
+		"expires_in":         tokenPair.ExpiresIn,
+		"refresh_expires_in": tokenPair.RefreshExpiresIn,
+		"user_id":            user.ID,
+		"email":              user.Email,
+		"message":            message,
+	}, nil
+}
+
+// handleVerifyEmail 通过邮件链接中的令牌验证邮箱（一次性）
+func (s *Server) handleVerifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	userID, err := s.database.ConsumeEmailToken(strings.TrimSpace(req.Token), config.EmailTokenVerifyEmail)
+	if errors.Is(err, config.ErrEmailTokenInvalid) {
+		respondError(c, http.StatusBadRequest, "EMAIL_VERIFY_LINK_INVALID")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "EMAIL_VERIFY_FAILED")
+		return
+	}
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return
+	}
+
+	if err := s.database.SetUserEmailVerified(userID); err != nil {
+		respondError(c, http.StatusInternalServerError, "UPDATE_USER_FAILED")
+		return
+	}
+	// 未绑定 Authenticator 的用户以邮箱验证完成注册
+	if !user.OTPVerified && !user.EmailVerified {
+		if err := s.initUserDefaultConfigs(userID); err != nil {
+			log.Printf("初始化用户默认配置失败: %v", err)
+		}
+	}
+
+	log.Printf("✓ 用户 %s 邮箱已验证", user.Email)
+	c.JSON(http.StatusOK, gin.H{"email": user.Email, "message": "邮箱验证成功，请登录"})
+}
+
+// handleResendVerificationEmail 重新发送邮箱验证邮件（无论邮箱是否存在都返回相同响应，防止枚举注册邮箱）
+func (s *Server) handleResendVerificationEmail(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	if !s.emailEnabled() {
+		respondError(c, http.StatusServiceUnavailable, "EMAIL_NOT_CONFIGURED")
+		return
+	}
+
+	if user, err := s.database.GetUserByEmail(req.Email); err == nil && !user.EmailVerified && !user.Disabled {
+		if err := s.sendEmailLink(user, config.EmailTokenVerifyEmail); err != nil {
+			log.Printf("⚠️ 发送验证邮件失败 (%s): %v", user.Email, err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册且未验证，验证邮件将在几分钟内送达"})
+}
+
+// handleRequestPasswordReset 发送密码重置邮件（无论邮箱是否存在都返回相同响应，防止枚举注册邮箱）
+func (s *Server) handleRequestPasswordReset(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	if !s.emailEnabled() {
+		respondError(c, http.StatusServiceUnavailable, "EMAIL_RESET_NOT_CONFIGURED")
+		return
+	}
+
+	if user, err := s.database.GetUserByEmail(req.Email); err == nil && !user.Disabled {
+		if err := s.sendEmailLink(user, config.EmailTokenResetPassword); err != nil {
+			log.Printf("⚠️ 发送密码重置邮件失败 (%s): %v", user.Email, err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册，密码重置邮件将在几分钟内送达"})
+}
+
+// handleResetPasswordWithToken 通过邮件链接中的令牌重置密码（一次性），此前签发的所有token失效
+func (s *Server) handleResetPasswordWithToken(c *gin.Context) {
+	var req struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required,min=6"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	newPasswordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "PASSWORD_HASH_FAILED")
+		return
+	}
+
+	userID, err := s.database.ConsumeEmailToken(strings.TrimSpace(req.Token), config.EmailTokenResetPassword)
+	if errors.Is(err, config.ErrEmailTokenInvalid) {
+		respondError(c, http.StatusBadRequest, "RESET_LINK_INVALID")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "RESET_LINK_CHECK_FAILED")
+		return
+	}
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return
+	}
+
+	if err := s.database.UpdateUserPassword(user.ID, newPasswordHash); err != nil {
+		respondError(c, http.StatusInternalServerError, "PASSWORD_UPDATE_FAILED")
+		return
+	}
+	s.revokeUserSessions(user)
+	// 能收到重置邮件即证明拥有该邮箱
+	if !user.EmailVerified {
+		if err := s.database.SetUserEmailVerified(user.ID); err != nil {
+			log.Printf("⚠️ 标记邮箱已验证失败 (%s): %v", user.Email, err)
+		}
+	}
+
+	s.recordAudit(c, user.ID, auditPasswordReset, auditResourceUser, user.ID, map[string]interface{}{
+		"method": "email_link",
+	})
+	log.Printf("✓ 用户 %s 通过邮件链接重置密码", user.Email)
+	c.JSON(http.StatusOK, gin.H{"message": "密码重置成功，请使用新密码登录"})
+}
+
+// handleGetSMTPSettings 获取 SMTP 设置（管理员，不返回密码）
+func (s *Server) handleGetSMTPSettings(c *gin.Context) {
+	settings := s.database.GetSMTPSettings()
+	cfg, baseURL := s.smtpConfig()
+	c.JSON(http.StatusOK, gin.H{
+		"settings":     settings,
+		"password_set": settings.Password != "",
+		"enabled":      cfg.Configured() && baseURL != "",
+		// 环境变量优先于系统配置，这里返回实际生效的配置
+		"effective": gin.H{
+			"host":          cfg.Host,
+			"port":          cfg.Port,
+			"username":      cfg.Username,
+			"from":          cfg.From,
+			"link_base_url": baseURL,
+		},
+	})
+}
+
+// handleUpdateSMTPSettings 更新 SMTP 设置（管理员，密码加密保存；未传 password 时保留原密码）
+func (s *Server) handleUpdateSMTPSettings(c *gin.Context) {
+	var req struct {
+		Host        string  `json:"host"`
+		Port        int     `json:"port"`
+		Username    string  `json:"username"`
+		Password    *string `json:"password"`
+		From        string  `json:"from"`
+		LinkBaseURL string  `json:"link_base_url"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	if req.LinkBaseURL != "" {
+		if u, err := url.Parse(req.LinkBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			respondError(c, http.StatusBadRequest, "LINK_BASE_URL_INVALID")
+			return
+		}
+	}
+
+	settings := config.SMTPSettings{
+		Host:        req.Host,
+		Port:        req.Port,
+		Username:    req.Username,
+		From:        req.From,
+		LinkBaseURL: req.LinkBaseURL,
+	}
+	if req.Password != nil {
+		settings.Password = *req.Password
+	}
+	if err := s.database.SetSMTPSettings(settings, req.Password == nil); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	log.Printf("✓ SMTP 设置已更新 (host=%s, from=%s)", settings.Host, settings.From)
+	c.JSON(http.StatusOK, gin.H{"message": "SMTP 设置已更新", "enabled": s.emailEnabled()})
+}
+
+// handleTestSMTP 同步发送一封测试邮件，返回 SMTP 错误方便排查配置（管理员）
+func (s *Server) handleTestSMTP(c *gin.Context) {
+	var req struct {
+		To string `json:"to" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	cfg, _ := s.smtpConfig()
+	email := notify.Email{To: req.To, Subject: "NOFX 邮件测试", Body: "✅ NOFX 邮件测试：SMTP 配置可用"}
+	if err := s.mailer.Send(cfg, email); err != nil {
+		respondError(c, http.StatusBadGateway, "SEND_TEST_EMAIL_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "测试邮件已发送"})
+}
+
+// pruneEmailTokens 清理已使用或已过期的邮件令牌
+func (s *Server) pruneEmailTokens() {
+	deleted, err := s.database.PruneEmailTokens()
+	if err != nil {
+		log.Printf("⚠️ 清理邮件令牌失败: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 已清理 %d 个已使用或过期的邮件令牌", deleted)
+	}
+}
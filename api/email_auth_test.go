@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/auth"
+	"nofx/config"
+	"nofx/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestEmailOnboardingAndPasswordReset 测试配置 SMTP 后：邮件验证完成注册并免 OTP 登录、邮件链接重置密码（一次性）
+func TestEmailOnboardingAndPasswordReset(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	auth.SetJWTSecret("test-secret")
+	auth.SetTokenVersionLookup(db.GetTokenVersion)
+	defer auth.SetTokenVersionLookup(nil)
+
+	sent := make(chan string, 4)
+	server.mailer = notify.NewMailer(func(cfg *notify.SMTPConfig, to string, msg []byte) error {
+		sent <- string(msg)
+		return nil
+	}, 0, time.Millisecond)
+	if err := db.SetSMTPSettings(config.SMTPSettings{Host: "smtp.example.com", Port: 587, From: "noreply@example.com", LinkBaseURL: "https://nofx.example.com"}, false); err != nil {
+		t.Fatalf("保存 SMTP 设置失败: %v", err)
+	}
+	db.SetSystemConfig("require_email_verification", "true")
+
+	router := gin.New()
+	router.POST("/register", server.handleRegister)
+	router.POST("/login", server.handleLogin)
+	router.POST("/verify-email", server.handleVerifyEmail)
+	router.POST("/request-password-reset", server.handleRequestPasswordReset)
+	router.POST("/reset-password-with-token", server.handleResetPasswordWithToken)
+
+	do := func(path string, body interface{}) (int, map[string]interface{}) {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	// linkToken 从邮件正文中取出链接里的令牌
+	linkToken := func(param string) string {
+		select {
+		case msg := <-sent:
+			body := msg[strings.Index(msg, "\r\n\r\n")+4:]
+			decoded, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\r\n", ""))
+			match := regexp.MustCompile(`https://nofx\.example\.com/\S+`).FindString(string(decoded))
+			u, err := url.Parse(match)
+			if err != nil || u.Query().Get(param) == "" {
+				t.Fatalf("邮件中没有有效链接: %q", decoded)
+			}
+			return u.Query().Get(param)
+		case <-time.After(3 * time.Second):
+			t.Fatal("超时未发送邮件")
+		}
+		return ""
+	}
+
+	email := "mail-user@example.com"
+	code, resp := do("/register", gin.H{"email": email, "password": "first-pass"})
+	if code != http.StatusOK || resp["email_verification_sent"] != true {
+		t.Fatalf("注册应发送验证邮件, got %d: %v", code, resp)
+	}
+	verifyToken := linkToken("verify_email")
+
+	// 未验证邮箱时禁止登录
+	if code, resp := do("/login", gin.H{"email": email, "password": "first-pass"}); code != http.StatusForbidden || resp["requires_email_verification"] != true {
+		t.Fatalf("未验证邮箱应禁止登录, got %d: %v", code, resp)
+	}
+
+	if code, resp := do("/verify-email", gin.H{"token": verifyToken}); code != http.StatusOK {
+		t.Fatalf("验证邮箱失败, got %d: %v", code, resp)
+	}
+	if code, _ := do("/verify-email", gin.H{"token": verifyToken}); code != http.StatusBadRequest {
+		t.Errorf("验证链接只能使用一次, got %d", code)
+	}
+
+	// 已验证邮箱、未绑定 Authenticator：密码登录直接签发token
+	code, resp = do("/login", gin.H{"email": email, "password": "first-pass"})
+	if code != http.StatusOK || resp["access_token"] == nil {
+		t.Fatalf("验证邮箱后应可直接登录, got %d: %v", code, resp)
+	}
+	oldToken, _ := resp["access_token"].(string)
+
+	// 不存在的邮箱同样返回成功，但不发送邮件
+	if code, _ := do("/request-password-reset", gin.H{"email": "nobody@example.com"}); code != http.StatusOK {
+		t.Errorf("不存在的邮箱也应返回相同响应, got %d", code)
+	}
+	if code, _ := do("/request-password-reset", gin.H{"email": email}); code != http.StatusOK {
+		t.Fatalf("申请重置密码失败, got %d", code)
+	}
+	resetToken := linkToken("token")
+
+	if code, resp := do("/reset-password-with-token", gin.H{"token": resetToken, "new_password": "second-pass"}); code != http.StatusOK {
+		t.Fatalf("重置密码失败, got %d: %v", code, resp)
+	}
+	if code, _ := do("/reset-password-with-token", gin.H{"token": resetToken, "new_password": "third-pass"}); code != http.StatusBadRequest {
+		t.Errorf("重置链接只能使用一次, got %d", code)
+	}
+	if _, err := auth.ValidateJWT(oldToken); err == nil {
+		t.Error("重置密码前签发的token应失效")
+	}
+	if code, _ := do("/login", gin.H{"email": email, "password": "second-pass"}); code != http.StatusOK {
+		t.Errorf("应能使用新密码登录, got %d", code)
+	}
+}
+
+// TestEmailFlowsDisabledWithoutSMTP 测试未配置 SMTP 时保持原有 OTP 流程
+func TestEmailFlowsDisabledWithoutSMTP(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.POST("/register", server.handleRegister)
+	router.POST("/request-password-reset", server.handleRequestPasswordReset)
+
+	post := func(path string, body interface{}) (int, map[string]interface{}) {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := post("/register", gin.H{"email": "otp-user@example.com", "password": "password"})
+	if code != http.StatusOK || resp["email_verification_sent"] != false || resp["otp_secret"] == "" {
+		t.Fatalf("未配置 SMTP 时应返回 OTP 设置信息, got %d: %v", code, resp)
+	}
+	if code, _ := post("/request-password-reset", gin.H{"email": "otp-user@example.com"}); code != http.StatusServiceUnavailable {
+		t.Errorf("未配置 SMTP 时不应支持邮件重置密码, got %d", code)
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"nofx/i18n"
+	"nofx/logger"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// requestLang 当前请求的响应语言（?lang= 优先，其次 Accept-Language，默认中文）
+func requestLang(c *gin.Context) string {
+	return i18n.RequestLang(c.Request)
+}
+
+// errorResponse 构造带错误码的本地化错误响应体，需要附加字段时在返回值上追加
+func errorResponse(c *gin.Context, code string, args ...interface{}) gin.H {
+	return i18n.ErrorBody(requestLang(c), code, args...)
+}
+
+// respondError 返回带错误码的本地化错误响应（args 填充消息模板中的占位符）
+// 5xx 错误同时写入结构化日志（携带 user_id，交易员相关路由额外携带 trader_id）
+func respondError(c *gin.Context, status int, code string, args ...interface{}) {
+	if status >= http.StatusInternalServerError {
+		logServerError(c, status, code, args...)
+	}
+	c.JSON(status, errorResponse(c, code, args...))
+}
+
+// logServerError 记录服务端错误，带 trader_id 的条目会进入该交易员的运行日志缓冲
+func logServerError(c *gin.Context, status int, code string, args ...interface{}) {
+	fields := logrus.Fields{
+		"user_id": c.GetString("user_id"),
+		"method":  c.Request.Method,
+		"path":    c.FullPath(),
+		"status":  status,
+		"code":    code,
+	}
+	if traderID := c.Query("trader_id"); traderID != "" {
+		fields["trader_id"] = traderID
+	} else if strings.HasPrefix(c.FullPath(), "/api/traders/:id") {
+		fields["trader_id"] = c.Param("id")
+	}
+	logger.Component("api").WithFields(fields).Error(i18n.Message(i18n.LangZH, code, args...))
+}
+
+// abortWithError 中止后续处理并返回带错误码的本地化错误响应（用于中间件）
+func abortWithError(c *gin.Context, status int, code string, args ...interface{}) {
+	c.AbortWithStatusJSON(status, errorResponse(c, code, args...))
+}
+
+// localizeDecisionRecords 按请求语言重新生成决策动作的错误信息
+// 日志中保存的是中文（同时作为AI反馈），带错误码的动作在其他语言下用错误码和参数重新渲染
+func localizeDecisionRecords(records []*logger.DecisionRecord, lang string) {
+	if lang == i18n.DefaultLang {
+		return
+	}
+	for _, record := range records {
+		for i := range record.Decisions {
+			action := &record.Decisions[i]
+			key := action.ErrorKey
+			if key == "" && action.ErrorCode != "EXECUTION_FAILED" {
+				key = action.ErrorCode
+			}
+			if key == "" || !i18n.Has(key) {
+				continue
+			}
+			action.Error = i18n.Message(lang, key, action.ErrorArgs...)
+		}
+	}
+}
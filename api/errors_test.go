@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"nofx/i18n"
+	"nofx/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// templateVerb 消息模板中的格式化占位符（字面 % 如 "0-1%之间" 不算）
+var templateVerb = regexp.MustCompile(`%[\d.]*[a-zA-Z]`)
+
+// TestErrorCodesInCatalog 扫描 api 包源码，每个错误码都必须在消息目录中，且传入的参数个数与模板占位符一致
+func TestErrorCodesInCatalog(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 函数名 -> 错误码参数的位置
+	codeArg := map[string]int{"respondError": 2, "abortWithError": 2, "errorResponse": 1}
+
+	fset := token.NewFileSet()
+	checked := 0
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("解析 %s 失败: %v", file, err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			ident, ok := call.Fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			pos, ok := codeArg[ident.Name]
+			if !ok || len(call.Args) <= pos {
+				return true
+			}
+			lit, ok := call.Args[pos].(*ast.BasicLit)
+			if !ok {
+				return true // 错误码由变量决定（如解密失败原因），由对应的测试覆盖
+			}
+			code, _ := strconv.Unquote(lit.Value)
+			where := fset.Position(call.Pos())
+			checked++
+
+			if !i18n.Has(code) {
+				t.Errorf("%s: 错误码 %s 不在消息目录中", where, code)
+				return true
+			}
+			argc := len(call.Args) - pos - 1
+			for _, lang := range []string{i18n.LangZH, i18n.LangEN} {
+				if argc == 0 {
+					if msg := i18n.Message(lang, code); templateVerb.MatchString(msg) {
+						t.Errorf("%s: 错误码 %s 的 %s 模板需要参数但调用未传入: %q", where, code, lang, msg)
+					}
+					continue
+				}
+				args := make([]interface{}, argc)
+				for i := range args {
+					args[i] = "x"
+				}
+				// 只校验参数个数（EXTRA/MISSING），不校验占位符类型
+				if msg := i18n.Message(lang, code, args...); strings.Contains(msg, "%!(EXTRA") || strings.Contains(msg, "(MISSING)") {
+					t.Errorf("%s: 错误码 %s 的 %s 模板与参数个数 %d 不匹配: %q", where, code, lang, argc, msg)
+				}
+			}
+			return true
+		})
+	}
+	if checked == 0 {
+		t.Fatal("没有扫描到任何错误响应调用")
+	}
+}
+
+// TestErrorResponseLanguage 同一个失败请求：Accept-Language: en 返回英文，否则返回中文，且都带稳定的错误码
+func TestErrorResponseLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{}
+	router := gin.New()
+	router.DELETE("/orders/:orderId", server.handleCancelOpenOrder)
+
+	do := func(path, acceptLanguage string) map[string]interface{} {
+		req := httptest.NewRequest("DELETE", path, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("期望 400, 实际 %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		path           string
+		acceptLanguage string
+		want           string
+	}{
+		{"/orders/abc", "en-US,en;q=0.9", "Invalid order ID"},
+		{"/orders/abc", "", "无效的订单ID"},
+		{"/orders/abc", "zh-CN", "无效的订单ID"},
+		{"/orders/abc?lang=en", "zh-CN", "Invalid order ID"},
+	}
+	for _, tt := range tests {
+		resp := do(tt.path, tt.acceptLanguage)
+		if resp["code"] != "ORDER_ID_INVALID" {
+			t.Errorf("%s (%q): code = %v, want ORDER_ID_INVALID", tt.path, tt.acceptLanguage, resp["code"])
+		}
+		if resp["message"] != tt.want || resp["error"] != tt.want {
+			t.Errorf("%s (%q): message = %v, error = %v, want %q", tt.path, tt.acceptLanguage, resp["message"], resp["error"], tt.want)
+		}
+	}
+}
+
+// TestLocalizeDecisionRecords 决策日志中的中文错误按错误码和参数重新生成为请求语言
+func TestLocalizeDecisionRecords(t *testing.T) {
+	zh := i18n.Message(i18n.LangZH, "POSITION_EXISTS_LONG", "BTCUSDT")
+	newRecords := func() []*logger.DecisionRecord {
+		return []*logger.DecisionRecord{{
+			Decisions: []logger.DecisionAction{
+				{Symbol: "BTCUSDT", Error: zh, ErrorCode: "POSITION_EXISTS", ErrorKey: "POSITION_EXISTS_LONG", ErrorArgs: []interface{}{"BTCUSDT"}},
+				{Symbol: "ETHUSDT", Error: "每日开仓次数已达上限 (5/5)", ErrorCode: "DAILY_TRADE_LIMIT"},
+				{Symbol: "SOLUSDT", Error: "下单失败: timeout", ErrorCode: "EXECUTION_FAILED"},
+				{Symbol: "BNBUSDT", Error: "旧记录没有错误码"},
+			},
+		}}
+	}
+
+	records := newRecords()
+	localizeDecisionRecords(records, i18n.LangEN)
+	actions := records[0].Decisions
+	if want := i18n.Message(i18n.LangEN, "POSITION_EXISTS_LONG", "BTCUSDT"); actions[0].Error != want {
+		t.Errorf("带模板的错误应按参数重新生成: got %q, want %q", actions[0].Error, want)
+	}
+	if want := i18n.Message(i18n.LangEN, "DAILY_TRADE_LIMIT"); actions[1].Error != want {
+		t.Errorf("只有拒绝代码的错误应使用通用消息: got %q, want %q", actions[1].Error, want)
+	}
+	if actions[2].Error != "下单失败: timeout" || actions[3].Error != "旧记录没有错误码" {
+		t.Errorf("交易所错误和旧记录应保持原文: %q, %q", actions[2].Error, actions[3].Error)
+	}
+
+	records = newRecords()
+	localizeDecisionRecords(records, i18n.LangZH)
+	if records[0].Decisions[0].Error != zh || records[0].Decisions[1].Error != "每日开仓次数已达上限 (5/5)" {
+		t.Error("中文请求应保持日志原文")
+	}
+}
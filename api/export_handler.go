@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushRows 导出时每写入多少行刷新一次，避免大文件全部缓冲在内存中
+const exportFlushRows = 500
+
+// decisionExportHeader 决策导出的列（顺序固定，新增列只能追加在末尾）
+var decisionExportHeader = []string{
+	"timestamp", "cycle_number", "ai_model", "action", "symbol", "quantity", "price", "leverage",
+	"order_id", "order_type", "success", "skipped", "dry_run", "error", "total_equity", "total_pnl_pct",
+}
+
+// equityExportHeader 净值历史导出的列
+var equityExportHeader = []string{
+	"timestamp", "cycle_number", "total_equity", "available_balance", "total_pnl", "total_pnl_pct",
+	"position_count", "margin_used_pct",
+}
+
+// equityFromRecord 从决策记录计算账户净值、总盈亏和盈亏百分比
+// 优先使用记录中保存的初始余额，旧记录没有时使用 base
+func equityFromRecord(record *logger.DecisionRecord, base float64) (equity, pnl, pnlPct float64) {
+	// TotalBalance 为钱包余额，TotalUnrealizedProfit 为未实现盈亏
+	equity = record.AccountState.TotalBalance + record.AccountState.TotalUnrealizedProfit
+	if record.AccountState.InitialBalance > 0 {
+		base = record.AccountState.InitialBalance
+	}
+	pnl = equity - base
+	if base > 0 {
+		pnlPct = pnl / base * 100
+	}
+	return equity, pnl, pnlPct
+}
+
+// parseExportRange 解析导出的 from/to 参数（to 为纯日期时包含当天）
+func parseExportRange(c *gin.Context) (from, to time.Time, err error) {
+	if from, err = parseTimeParam(c.Query("from"), time.Time{}); err != nil {
+		return from, to, err
+	}
+	rawTo := strings.TrimSpace(c.Query("to"))
+	if to, err = parseTimeParam(rawTo, time.Time{}); err != nil {
+		return from, to, err
+	}
+	if len(rawTo) == len("2006-01-02") {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !to.After(from) {
+		return from, to, fmt.Errorf("to 必须晚于 from")
+	}
+	return from, to, nil
+}
+
+// prepareExport 校验导出请求并返回交易员ID和时间范围，失败时已写入错误响应
+func (s *Server) prepareExport(c *gin.Context) (traderID string, from, to time.Time, ok bool) {
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		respondError(c, http.StatusBadRequest, "EXPORT_FORMAT_UNSUPPORTED", format)
+		return "", from, to, false
+	}
+
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
+		return "", from, to, false
+	}
+	if _, _, _, err := s.database.GetTraderConfig(c.GetString("user_id"), traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return "", from, to, false
+	}
+
+	if from, to, err = parseExportRange(c); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return "", from, to, false
+	}
+	return traderID, from, to, true
+}
+
+// startCSV 写入 CSV 响应头、UTF-8 BOM（Excel 识别中文）和表头
+func startCSV(c *gin.Context, filename string, header []string) *csv.Writer {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	c.Writer.WriteString("\ufeff")
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(header)
+	return w
+}
+
+// exportFilename 生成导出文件名：<kind>_<traderID>_<日期>.csv
+func exportFilename(kind, traderID string) string {
+	safeID := strings.Map(func(r rune) rune {
+		if r == '"' || r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, traderID)
+	return fmt.Sprintf("%s_%s_%s.csv", kind, safeID, time.Now().Format("20060102"))
+}
+
+// formatExportFloat 按响应小数位格式化浮点数
+func formatExportFloat(v float64, decimals int) string {
+	return strconv.FormatFloat(RoundFloat(v, decimals), 'f', -1, 64)
+}
+
+// handleExportDecisions 导出决策日志为 CSV（每个决策动作一行），支持 from/to 时间范围
+func (s *Server) handleExportDecisions(c *gin.Context) {
+	traderID, from, to, ok := s.prepareExport(c)
+	if !ok {
+		return
+	}
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return
+	}
+	base, _ := trader.GetStatus()["initial_balance"].(float64)
+	decimals := s.responseDecimals(c)
+
+	w := startCSV(c, exportFilename("decisions", traderID), decisionExportHeader)
+	rows := 0
+	err = trader.GetDecisionLogger().IterateRecords(from, to, func(record *logger.DecisionRecord) error {
+		equity, _, pnlPct := equityFromRecord(record, base)
+		for _, action := range record.Decisions {
+			ts := action.Timestamp
+			if ts.IsZero() {
+				ts = record.Timestamp
+			}
+			w.Write([]string{
+				ts.Format("2006-01-02 15:04:05"),
+				strconv.Itoa(record.CycleNumber),
+				record.AIModel,
+				action.Action,
+				action.Symbol,
+				strconv.FormatFloat(action.Quantity, 'f', -1, 64),
+				strconv.FormatFloat(action.Price, 'f', -1, 64),
+				strconv.Itoa(action.Leverage),
+				strconv.FormatInt(action.OrderID, 10),
+				action.OrderType,
+				strconv.FormatBool(action.Success),
+				strconv.FormatBool(action.Skipped),
+				strconv.FormatBool(action.DryRun),
+				action.Error,
+				formatExportFloat(equity, decimals),
+				formatExportFloat(pnlPct, decimals),
+			})
+			rows++
+			if rows%exportFlushRows == 0 {
+				w.Flush()
+				c.Writer.Flush()
+			}
+		}
+		return w.Error()
+	})
+	w.Flush()
+	if err != nil {
+		// 响应头已发送，只能记录日志
+		log.Printf("⚠️ 导出交易员 %s 的决策日志中断: %v", traderID, err)
+	}
+}
+
+// handleExportEquityHistory 导出净值历史为 CSV（每个周期一行），支持 from/to 时间范围
+func (s *Server) handleExportEquityHistory(c *gin.Context) {
+	traderID, from, to, ok := s.prepareExport(c)
+	if !ok {
+		return
+	}
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return
+	}
+	base, _ := trader.GetStatus()["initial_balance"].(float64)
+	decimals := s.responseDecimals(c)
+
+	w := startCSV(c, exportFilename("equity", traderID), equityExportHeader)
+	rows := 0
+	err = trader.GetDecisionLogger().IterateRecords(from, to, func(record *logger.DecisionRecord) error {
+		equity, pnl, pnlPct := equityFromRecord(record, base)
+		w.Write([]string{
+			record.Timestamp.Format("2006-01-02 15:04:05"),
+			strconv.Itoa(record.CycleNumber),
+			formatExportFloat(equity, decimals),
+			formatExportFloat(record.AccountState.AvailableBalance, decimals),
+			formatExportFloat(pnl, decimals),
+			formatExportFloat(pnlPct, decimals),
+			strconv.Itoa(record.AccountState.PositionCount),
+			formatExportFloat(record.AccountState.MarginUsedPct, decimals),
+		})
+		rows++
+		if rows%exportFlushRows == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+		return w.Error()
+	})
+	w.Flush()
+	if err != nil {
+		log.Printf("⚠️ 导出交易员 %s 的净值历史中断: %v", traderID, err)
+	}
+}
@@ -2,15 +2,23 @@ package api
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"nofx/auth"
 	"nofx/config"
+	"nofx/logger"
+	"nofx/stream"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
 // setupTestEnv creates test user and configurations
@@ -685,3 +693,1670 @@ func TestHandleStopTrader(t *testing.T) {
 
 	t.Logf("✅ handleStopTrader test passed")
 }
+
+// TestCompetitionSnapshot tests creating and fetching a competition snapshot
+func TestCompetitionSnapshot(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/competition/snapshot", func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Set("email", c.GetHeader("X-Test-Email"))
+		c.Next()
+	}, server.adminMiddleware(), server.handleCreateCompetitionSnapshot)
+	router.GET("/competition/snapshot/:id", server.handleGetCompetitionSnapshot)
+
+	// Non-admin users are rejected
+	req := httptest.NewRequest("POST", "/competition/snapshot", nil)
+	req.Header.Set("X-Test-User", "regular-user")
+	req.Header.Set("X-Test-Email", "user@example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for non-admin, got %d", w.Code)
+	}
+
+	// Users listed in admin_emails are allowed
+	if err := db.SetSystemConfig("admin_emails", "ops@example.com, user@example.com"); err != nil {
+		t.Fatalf("Failed to set admin_emails: %v", err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var created map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	snapshotID, _ := created["id"].(string)
+	if snapshotID == "" || created["window"] == "" || created["data_as_of"] == "" {
+		t.Fatalf("Snapshot response missing fields: %v", created)
+	}
+
+	// Snapshot can be fetched publicly
+	req = httptest.NewRequest("GET", "/competition/snapshot/"+snapshotID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var fetched map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &fetched); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if fetched["id"] != snapshotID || fetched["data_as_of"] != created["data_as_of"] {
+		t.Errorf("Fetched snapshot mismatch: %v", fetched)
+	}
+	if _, ok := fetched["competition"].(map[string]interface{}); !ok {
+		t.Errorf("Snapshot should include competition data: %v", fetched)
+	}
+
+	// Unknown snapshot returns 404
+	req = httptest.NewRequest("GET", "/competition/snapshot/does-not-exist", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestDefaultTemplateEndpoints(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	}
+	router.GET("/admin/default-template", setUser, server.adminMiddleware(), server.handleGetDefaultTemplate)
+	router.PUT("/admin/default-template", setUser, server.adminMiddleware(), server.handleSetDefaultTemplate)
+
+	put := func(user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/admin/default-template", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Defaults to the built-in template
+	if got := server.defaultPromptTemplate(); got != "default" {
+		t.Fatalf("Expected default template, got %s", got)
+	}
+
+	// Non-admin users are rejected
+	if w := put("regular-user", `{"template":"nof1"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for non-admin, got %d", w.Code)
+	}
+
+	// Unknown templates are rejected
+	if w := put("admin", `{"template":"does-not-exist"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for unknown template, got %d", w.Code)
+	}
+
+	if w := put("admin", `{"template":"nof1"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := server.defaultPromptTemplate(); got != "nof1" {
+		t.Errorf("Expected nof1 as new default, got %s", got)
+	}
+
+	req := httptest.NewRequest("GET", "/admin/default-template", nil)
+	req.Header.Set("X-Test-User", "admin")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if resp["template"] != "nof1" {
+		t.Errorf("Expected template nof1, got %v", resp["template"])
+	}
+
+	// A configured template that was later removed falls back to default
+	if err := db.SetSystemConfig("default_template", "removed-template"); err != nil {
+		t.Fatalf("Failed to set default_template: %v", err)
+	}
+	if got := server.defaultPromptTemplate(); got != "default" {
+		t.Errorf("Expected fallback to default, got %s", got)
+	}
+}
+
+func TestBetaCodeAdminEndpoints(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	}
+	router.POST("/admin/beta-codes/generate", setUser, server.adminMiddleware(), server.handleGenerateBetaCodes)
+	router.GET("/admin/beta-codes", setUser, server.adminMiddleware(), server.handleListBetaCodes)
+	router.DELETE("/admin/beta-codes/:code", setUser, server.adminMiddleware(), server.handleRevokeBetaCode)
+
+	do := func(method, path, user string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	// Non-admin users are rejected
+	if w, _ := do("POST", "/admin/beta-codes/generate?count=3", "regular-user"); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for non-admin, got %d", w.Code)
+	}
+	if w, _ := do("POST", "/admin/beta-codes/generate?count=abc", "admin"); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for invalid count, got %d", w.Code)
+	}
+
+	w, resp := do("POST", "/admin/beta-codes/generate?count=3", "admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	codes, _ := resp["codes"].([]interface{})
+	if len(codes) != 3 {
+		t.Fatalf("Expected 3 generated codes, got %v", resp["codes"])
+	}
+
+	w, resp = do("DELETE", "/admin/beta-codes/"+codes[0].(string), "admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on revoke, got %d: %s", w.Code, w.Body.String())
+	}
+	if w, _ := do("DELETE", "/admin/beta-codes/"+codes[0].(string), "admin"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 when revoking a missing code, got %d", w.Code)
+	}
+
+	w, resp = do("GET", "/admin/beta-codes", "admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on list, got %d", w.Code)
+	}
+	listed, _ := resp["codes"].([]interface{})
+	if len(listed) != 2 || resp["total"] != float64(2) || resp["unused"] != float64(2) {
+		t.Errorf("Expected 2 unused codes after revoke, got %v", resp)
+	}
+}
+
+func TestAdminPositionsBySymbol(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	}
+	router.GET("/admin/positions/by-symbol", setUser, server.adminMiddleware(), server.handleAdminPositionsBySymbol)
+
+	get := func(user, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/positions/by-symbol"+query, nil)
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Non-admin users are rejected
+	if w := get("regular-user", "?symbol=BTCUSDT"); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for non-admin, got %d", w.Code)
+	}
+
+	// Symbol is required
+	if w := get("admin", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 without symbol, got %d", w.Code)
+	}
+
+	// Symbol is normalized; no running traders means no holders
+	w := get("admin", "?symbol=btc")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if resp["symbol"] != "BTCUSDT" {
+		t.Errorf("Expected normalized symbol BTCUSDT, got %v", resp["symbol"])
+	}
+	if resp["count"] != float64(0) {
+		t.Errorf("Expected no holders, got %v", resp["count"])
+	}
+	if holders, ok := resp["holders"].([]interface{}); !ok || len(holders) != 0 {
+		t.Errorf("Expected empty holders list, got %v", resp["holders"])
+	}
+	if failures, ok := resp["failures"].([]interface{}); !ok || len(failures) != 0 {
+		t.Errorf("Expected empty failures list, got %v", resp["failures"])
+	}
+}
+
+func TestAdminUserManagement(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+	auth.SetJWTSecret("test-secret")
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  "admin-managed-trader",
+		UserID:              userID,
+		Name:                "admin-managed-trader",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+		IsRunning:           true,
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	token, err := auth.GenerateJWT(userID, "trader-test@example.com")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	}
+	router.GET("/admin/users", setUser, server.adminMiddleware(), server.handleAdminListUsers)
+	router.POST("/admin/users/:id/disable", setUser, server.adminMiddleware(), server.handleAdminDisableUser)
+	router.POST("/admin/users/:id/enable", setUser, server.adminMiddleware(), server.handleAdminEnableUser)
+	router.DELETE("/admin/users/:id", setUser, server.adminMiddleware(), server.handleAdminDeleteUser)
+	router.GET("/me", server.authMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method, path, user string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	me := func() int {
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Non-admin users are rejected
+	if w, _ := do("GET", "/admin/users", userID); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for non-admin, got %d", w.Code)
+	}
+
+	w, resp := do("GET", "/admin/users", "admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var listed map[string]interface{}
+	users, _ := resp["users"].([]interface{})
+	for _, u := range users {
+		if entry := u.(map[string]interface{}); entry["id"] == userID {
+			listed = entry
+		}
+	}
+	if listed == nil || listed["trader_count"] != float64(1) || listed["running_trader_count"] != float64(1) || listed["disabled"] != false {
+		t.Fatalf("Expected user with 1 running trader in list, got %v", resp["users"])
+	}
+
+	// Admins cannot disable themselves
+	if w, _ := do("POST", "/admin/users/admin/disable", "admin"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 when disabling admin, got %d", w.Code)
+	}
+	if w, _ := do("POST", "/admin/users/missing-user/disable", "admin"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown user, got %d", w.Code)
+	}
+
+	// Disabling blocks existing tokens and marks traders stopped
+	if code := me(); code != http.StatusOK {
+		t.Fatalf("Expected status 200 before disabling, got %d", code)
+	}
+	if w, _ := do("POST", "/admin/users/"+userID+"/disable", "admin"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on disable, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := me(); code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for disabled user, got %d", code)
+	}
+	if traders, _ := db.GetTraders(userID); len(traders) != 1 || traders[0].IsRunning {
+		t.Errorf("Expected trader to be marked stopped after disable, got %+v", traders)
+	}
+
+	if w, _ := do("POST", "/admin/users/"+userID+"/enable", "admin"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on enable, got %d", w.Code)
+	}
+	if code := me(); code != http.StatusOK {
+		t.Errorf("Expected status 200 after re-enabling, got %d", code)
+	}
+
+	// Deleting removes the user together with their traders
+	if w, _ := do("DELETE", "/admin/users/"+userID, "admin"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on delete, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := db.GetUserByID(userID); err == nil {
+		t.Error("Expected user to be deleted")
+	}
+	if traders, _ := db.GetTraders(userID); len(traders) != 0 {
+		t.Errorf("Expected traders to be deleted with the user, got %d", len(traders))
+	}
+}
+
+func TestExportDecisionsCSV(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  "export-trader",
+		UserID:              userID,
+		Name:                "export-trader",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	if err := server.traderManager.LoadUserTraders(db, userID); err != nil {
+		t.Fatalf("Failed to load trader into manager: %v", err)
+	}
+	at, err := server.traderManager.GetTrader("export-trader")
+	if err != nil {
+		t.Fatalf("Trader not loaded: %v", err)
+	}
+	defer os.RemoveAll("decision_logs")
+
+	at.GetDecisionLogger().LogDecision(&logger.DecisionRecord{
+		AccountState: logger.AccountSnapshot{TotalBalance: 1050, TotalUnrealizedProfit: 50, InitialBalance: 1000},
+		Decisions: []logger.DecisionAction{
+			{Action: "open_long", Symbol: "BTCUSDT", Quantity: 0.01, Price: 60000, Leverage: 5, Success: true},
+			{Action: "open_short", Symbol: "ETHUSDT", Error: "insufficient margin, retry"},
+		},
+	})
+	at.GetDecisionLogger().LogDecision(&logger.DecisionRecord{
+		AccountState: logger.AccountSnapshot{TotalBalance: 1000, InitialBalance: 1000},
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}
+	router.GET("/decisions/export", setUser, server.handleExportDecisions)
+	router.GET("/equity-history/export", setUser, server.handleExportEquityHistory)
+
+	get := func(path string) (*httptest.ResponseRecorder, [][]string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		rows, _ := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\ufeff"))).ReadAll()
+		return w, rows
+	}
+
+	if w, _ := get("/decisions/export?trader_id=export-trader&format=xlsx"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unsupported format, got %d", w.Code)
+	}
+	if w, _ := get("/decisions/export?trader_id=export-trader&from=2026-02-01&to=2026-01-01"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for inverted range, got %d", w.Code)
+	}
+
+	// One row per decision action; cycles without actions produce no rows
+	w, rows := get("/decisions/export?trader_id=export-trader&format=csv")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="decisions_export-trader_`) {
+		t.Errorf("Unexpected Content-Disposition: %s", cd)
+	}
+	if len(rows) != 3 || rows[0][0] != "timestamp" || rows[0][3] != "action" {
+		t.Fatalf("Expected header plus 2 action rows, got %v", rows)
+	}
+	if rows[1][3] != "open_long" || rows[1][6] != "60000" || rows[1][10] != "true" || rows[1][14] != "1100" || rows[1][15] != "10" {
+		t.Errorf("Unexpected first action row: %v", rows[1])
+	}
+	if rows[2][3] != "open_short" || rows[2][13] != "insufficient margin, retry" {
+		t.Errorf("Errors containing commas should be quoted into a single field: %v", rows[2])
+	}
+
+	// Equity export has one row per cycle; a future range yields only the header
+	if _, rows := get("/equity-history/export?trader_id=export-trader"); len(rows) != 3 || rows[2][2] != "1000" {
+		t.Errorf("Expected 2 equity rows, got %v", rows)
+	}
+	if _, rows := get("/equity-history/export?trader_id=export-trader&from=2099-01-01"); len(rows) != 1 {
+		t.Errorf("Expected only the header for a future range, got %v", rows)
+	}
+}
+
+func TestDisplayCurrencyPreference(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, _, _ := setupTestEnv(t, db)
+
+	originalPrice := displayPriceFunc
+	displayPriceFunc = func(symbol string) (float64, error) { return 50000, nil }
+	defer func() { displayPriceFunc = originalPrice }()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}
+	router.PUT("/user/display-currency", setUser, server.handleSetDisplayCurrency)
+	router.GET("/account", setUser, func(c *gin.Context) {
+		c.JSON(http.StatusOK, server.withDisplayCurrency(c, map[string]interface{}{"total_equity": 25000.0}))
+	})
+
+	getAccount := func(query string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/account"+query, nil))
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return resp
+	}
+
+	// USD by default: no converted fields
+	if resp := getAccount(""); resp["total_equity_btc"] != nil {
+		t.Errorf("Expected no BTC fields by default, got %v", resp)
+	}
+
+	// Unsupported currencies are rejected
+	req := httptest.NewRequest("PUT", "/user/display-currency", bytes.NewBufferString(`{"currency":"DOGE"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("PUT", "/user/display-currency", bytes.NewBufferString(`{"currency":"btc"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	resp := getAccount("")
+	if resp["total_equity_btc"] != 0.5 || resp["total_equity"] != 25000.0 {
+		t.Errorf("Expected BTC equity alongside USD, got %v", resp)
+	}
+
+	// Query parameter overrides the saved preference
+	if resp := getAccount("?currency=USD"); resp["total_equity_btc"] != nil {
+		t.Errorf("Expected USD override, got %v", resp)
+	}
+}
+
+// TestDeleteUserHistoryValidation tests the confirmation flag and trader scoping of history deletion
+func TestDeleteUserHistoryValidation(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  "history-trader",
+		UserID:              userID,
+		Name:                "History Trader",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000.0,
+		ScanIntervalMinutes: 3,
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	if err := db.RecordTrade("history-trader", userID, "BTCUSDT", "LONG", "OPEN", 0.1, 50000, "", 0, 0, 0, 0); err != nil {
+		t.Fatalf("Failed to record trade: %v", err)
+	}
+
+	router := gin.New()
+	router.DELETE("/user/history", func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		server.handleDeleteUserHistory(c)
+	})
+	do := func(path, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", path, nil)
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("/user/history?trader_id=history-trader", userID); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without confirm flag, got %d", w.Code)
+	}
+
+	// Another user cannot wipe this trader's history
+	if w := do("/user/history?confirm=true&trader_id=history-trader", "other-user"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's trader, got %d", w.Code)
+	}
+	if _, _, err := db.GetLastOpenTrade("history-trader", "BTCUSDT", "LONG"); err != nil {
+		t.Errorf("Trade history should be untouched after rejected requests: %v", err)
+	}
+}
+
+// TestUpdateModelSystemPrompt tests storing model-specific system prompt prefix/suffix
+func TestUpdateModelSystemPrompt(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	userID, _, _ := setupTestEnv(t, db)
+
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	}
+	router.PUT("/models/:id/system-prompt", setUser, server.handleUpdateModelSystemPrompt)
+	router.GET("/models", setUser, server.handleGetModelConfigs)
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"system_prompt_prefix":"  Answer tersely.  ","system_prompt_suffix":"Output JSON only."}`
+	if w := do("PUT", "/models/test-model/system-prompt", userID, body); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", "/models/test-model/system-prompt", "other-user", body); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's model, got %d", w.Code)
+	}
+	tooLong := `{"system_prompt_prefix":"` + strings.Repeat("x", maxModelSystemPromptLength+1) + `"}`
+	if w := do("PUT", "/models/test-model/system-prompt", userID, tooLong); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an oversized prefix, got %d", w.Code)
+	}
+
+	w := do("GET", "/models", userID, "")
+	var models []SafeModelConfig
+	if err := json.Unmarshal(w.Body.Bytes(), &models); err != nil || len(models) != 1 {
+		t.Fatalf("Failed to parse models: %v (%s)", err, w.Body.String())
+	}
+	if models[0].SystemPromptPrefix != "Answer tersely." || models[0].SystemPromptSuffix != "Output JSON only." {
+		t.Errorf("Unexpected stored prefix/suffix: %q / %q", models[0].SystemPromptPrefix, models[0].SystemPromptSuffix)
+	}
+}
+
+func TestTraderTagFilterAndAggregation(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+
+	traders := map[string]string{
+		"trader-mom-1": "momentum",
+		"trader-mom-2": "momentum,btc",
+		"trader-mr":    "mean-reversion",
+		"trader-none":  "",
+	}
+	for id, tags := range traders {
+		if err := db.CreateTrader(&config.TraderRecord{
+			ID:                  id,
+			UserID:              userID,
+			Name:                id,
+			AIModelID:           aiModelIntID,
+			ExchangeID:          exchangeIntID,
+			InitialBalance:      1000,
+			ScanIntervalMinutes: 3,
+			Timeframes:          "4h",
+			Tags:                tags,
+		}); err != nil {
+			t.Fatalf("Failed to create trader %s: %v", id, err)
+		}
+	}
+	if err := db.RecordTrade("trader-mom-1", userID, "BTCUSDT", "LONG", "CLOSE", 0.1, 50000, "", 0, 0, 40, 0); err != nil {
+		t.Fatalf("Failed to record trade: %v", err)
+	}
+	if err := db.RecordTrade("trader-mom-2", userID, "ETHUSDT", "LONG", "CLOSE", 1, 3000, "", 0, 0, -10, 0); err != nil {
+		t.Fatalf("Failed to record trade: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	router.GET("/my-traders", server.handleTraderList)
+	router.GET("/statistics/by-tag", server.handleStatisticsByTag)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d: %s", path, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	// Tag filter is case-insensitive and matches any of a trader's tags
+	var list []map[string]interface{}
+	if err := json.Unmarshal(get("/my-traders?tag=Momentum").Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to parse trader list: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 momentum traders, got %d", len(list))
+	}
+	for _, item := range list {
+		if id := item["trader_id"]; id != "trader-mom-1" && id != "trader-mom-2" {
+			t.Errorf("Unexpected trader in momentum filter: %v", id)
+		}
+	}
+	if err := json.Unmarshal(get("/my-traders").Body.Bytes(), &list); err != nil || len(list) != 4 {
+		t.Errorf("Expected all 4 traders without filter, got %d (%v)", len(list), err)
+	}
+
+	// Aggregation by tag
+	var resp struct {
+		Tags []config.TagSummary `json:"tags"`
+	}
+	if err := json.Unmarshal(get("/statistics/by-tag").Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse tag summary: %v", err)
+	}
+	byTag := make(map[string]config.TagSummary)
+	for _, summary := range resp.Tags {
+		byTag[summary.Tag] = summary
+	}
+	if len(byTag) != 3 {
+		t.Fatalf("Expected 3 tags (untagged traders excluded), got %v", resp.Tags)
+	}
+	momentum := byTag["momentum"]
+	if momentum.TraderCount != 2 || momentum.RealizedPnL != 30 || momentum.ClosedTrades != 2 || momentum.WinRate != 50 {
+		t.Errorf("Unexpected momentum summary: %+v", momentum)
+	}
+	if momentum.ReturnPct != 1.5 {
+		t.Errorf("Expected momentum return 1.5%%, got %v", momentum.ReturnPct)
+	}
+	if mr := byTag["mean-reversion"]; mr.TraderCount != 1 || mr.ClosedTrades != 0 {
+		t.Errorf("Unexpected mean-reversion summary: %+v", mr)
+	}
+
+	if err := json.Unmarshal(get("/statistics/by-tag?tag=btc").Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse filtered tag summary: %v", err)
+	}
+	if len(resp.Tags) != 1 || resp.Tags[0].Tag != "btc" || resp.Tags[0].RealizedPnL != -10 {
+		t.Errorf("Expected only btc tag summary, got %+v", resp.Tags)
+	}
+}
+
+// TestSignalSourceStatus tests the signal source status endpoint reports user URLs without secrets
+func TestSignalSourceStatus(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, _, _ := setupTestEnv(t, db)
+
+	if err := db.CreateUserSignalSource(userID, "https://signals.example.com/ai500?auth=top-secret", ""); err != nil {
+		t.Fatalf("Failed to save signal source: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	router.GET("/signal-sources/status", server.handleSignalSourceStatus)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/signal-sources/status", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "top-secret") {
+		t.Fatalf("Response leaks signal source secret: %s", w.Body.String())
+	}
+
+	var resp struct {
+		Sources []struct {
+			Source     string `json:"source"`
+			URL        string `json:"url"`
+			Origin     string `json:"origin"`
+			Configured bool   `json:"configured"`
+			Fetched    bool   `json:"fetched"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(resp.Sources) != 2 {
+		t.Fatalf("Expected 2 sources, got %d", len(resp.Sources))
+	}
+	ai500 := resp.Sources[0]
+	if ai500.Source != "ai500" || ai500.Origin != "user" || !ai500.Configured || ai500.Fetched {
+		t.Errorf("Unexpected ai500 status: %+v", ai500)
+	}
+	if ai500.URL != "https://signals.example.com/ai500?auth=***" {
+		t.Errorf("Expected redacted URL, got %s", ai500.URL)
+	}
+	if oiTop := resp.Sources[1]; oiTop.Source != "oi_top" || oiTop.Origin != "system" {
+		t.Errorf("Unexpected oi_top status: %+v", oiTop)
+	}
+}
+
+// TestTradeHistoryEndpoint tests trade history pagination, filters and ownership checks
+func TestTradeHistoryEndpoint(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  "history-owner",
+		UserID:              userID,
+		Name:                "history-owner",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+		Timeframes:          "4h",
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	trades := []struct {
+		symbol, action string
+		pnl            float64
+	}{
+		{"BTCUSDT", "OPEN", 0},
+		{"BTCUSDT", "CLOSE", 25},
+		{"ETHUSDT", "OPEN", 0},
+		{"ETHUSDT", "CLOSE", -5},
+		{"BTCUSDT", "OPEN", 0},
+	}
+	for _, tr := range trades {
+		if err := db.RecordTrade("history-owner", userID, tr.symbol, "LONG", tr.action, 1, 100, "test", 90, 120, tr.pnl, tr.pnl); err != nil {
+			t.Fatalf("Failed to record trade: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User"))
+		c.Next()
+	})
+	router.GET("/trade-history", server.handleTradeHistory)
+
+	type historyResponse struct {
+		Trades []config.TradeHistoryRecord `json:"trades"`
+		Total  int                         `json:"total"`
+	}
+	get := func(user, path string) (*httptest.ResponseRecorder, historyResponse) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp historyResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+		}
+		return w, resp
+	}
+
+	w, resp := get(userID, "/trade-history?trader_id=history-owner&limit=2&offset=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Total != 5 || len(resp.Trades) != 2 {
+		t.Fatalf("Expected page of 2 out of 5 trades, got %d/%d", len(resp.Trades), resp.Total)
+	}
+	if resp.Trades[0].Timestamp < resp.Trades[1].Timestamp || resp.Trades[0].StopLoss != 90 || resp.Trades[0].Reason != "test" {
+		t.Errorf("Unexpected trades page: %+v", resp.Trades)
+	}
+
+	_, resp = get(userID, "/trade-history?trader_id=history-owner&symbol=btcusdt&action=close")
+	if resp.Total != 1 || len(resp.Trades) != 1 || resp.Trades[0].PnL != 25 {
+		t.Errorf("Expected the single BTC close trade, got %+v (total %d)", resp.Trades, resp.Total)
+	}
+
+	_, resp = get(userID, "/trade-history?trader_id=history-owner&since=4102444800000")
+	if resp.Total != 0 || len(resp.Trades) != 0 {
+		t.Errorf("Expected no trades in the future range, got %d", resp.Total)
+	}
+
+	if w, _ := get(userID, "/trade-history?trader_id=history-owner&limit=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid limit, got %d", w.Code)
+	}
+	if w, _ := get("someone-else", "/trade-history?trader_id=history-owner"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another user's trader, got %d", w.Code)
+	}
+}
+
+// TestTradeStatsEndpoint tests win rate from paired trade_history rows and period validation
+func TestTradeStatsEndpoint(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  "stats-owner",
+		UserID:              userID,
+		Name:                "stats-owner",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+		Timeframes:          "4h",
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	for _, tr := range []struct {
+		symbol, action string
+		pnl            float64
+	}{
+		{"BTCUSDT", "OPEN", 0},
+		{"BTCUSDT", "CLOSE", 25},
+		{"ETHUSDT", "OPEN", 0},
+		{"ETHUSDT", "AUTO_CLOSE", -5},
+	} {
+		if err := db.RecordTrade("stats-owner", userID, tr.symbol, "LONG", tr.action, 1, 100, "test", 0, 0, tr.pnl, 0); err != nil {
+			t.Fatalf("Failed to record trade: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	router.GET("/trade-stats", server.handleTradeStats)
+
+	req := httptest.NewRequest("GET", "/trade-stats?trader_id=stats-owner&period=7d", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Stats config.TradeStats `json:"stats"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if resp.Stats.Trades != 2 || resp.Stats.WinRate != 50 || resp.Stats.RealizedPnL != 20 {
+		t.Errorf("Expected 2 trades at 50%% win rate and +20 PnL, got %+v", resp.Stats)
+	}
+
+	req = httptest.NewRequest("GET", "/trade-stats?trader_id=stats-owner&period=90d", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unsupported period, got %d", w.Code)
+	}
+}
+
+// TestTraderLogsEndpoint tests ownership, level filtering and limit validation of the runtime logs endpoint
+func TestTraderLogsEndpoint(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  "logs-owner",
+		UserID:              userID,
+		Name:                "logs-owner",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+		Timeframes:          "4h",
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	defer logger.ClearTraderLogs("logs-owner")
+	entry := logger.ForTrader("logs-owner", userID, "")
+	entry.Info("cycle started")
+	entry.Warn("balance low")
+	entry.Error("order failed")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	router.GET("/traders/:id/logs", server.handleTraderLogs)
+
+	req := httptest.NewRequest("GET", "/traders/logs-owner/logs?level=warn&limit=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Logs  []logger.LogEntry `json:"logs"`
+		Count int               `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if resp.Count != 1 || resp.Logs[0].Message != "order failed" {
+		t.Errorf("Expected only the latest warn+ entry, got %+v", resp.Logs)
+	}
+
+	for _, path := range []string{
+		"/traders/logs-owner/logs?level=verbose",
+		"/traders/logs-owner/logs?limit=5000",
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", path, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/traders/someone-else/logs", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another user's trader, got %d", w.Code)
+	}
+}
+
+// TestStreamWebSocket tests JWT auth, ownership checks and event fan-out on the WebSocket endpoint
+func TestStreamWebSocket(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+	auth.SetJWTSecret("test-secret")
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  "stream-trader",
+		UserID:              userID,
+		Name:                "stream-trader",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+		Timeframes:          "4h",
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	token, err := auth.GenerateJWT(userID, "trader-test@example.com")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/api/ws", server.handleStream)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/ws"
+
+	readType := func(conn *websocket.Conn) map[string]interface{} {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		return msg
+	}
+
+	// Invalid token is rejected
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=bad", nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	if msg := readType(conn); msg["type"] != "error" {
+		t.Errorf("Expected error for invalid token, got %v", msg)
+	}
+	conn.Close()
+
+	// Authenticate via first message, then subscribe
+	conn, _, err = websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(map[string]string{"action": "auth", "token": token}); err != nil {
+		t.Fatalf("Failed to send auth: %v", err)
+	}
+	if msg := readType(conn); msg["type"] != "subscribed" {
+		t.Fatalf("Expected subscribed ack, got %v", msg)
+	}
+
+	conn.WriteJSON(map[string]interface{}{"action": "subscribe", "trader_ids": []string{"someone-elses-trader"}})
+	if msg := readType(conn); msg["type"] != "error" {
+		t.Errorf("Expected error when subscribing to a foreign trader, got %v", msg)
+	}
+	conn.WriteJSON(map[string]interface{}{"action": "subscribe", "trader_ids": []string{"stream-trader"}})
+	if msg := readType(conn); msg["type"] != "subscribed" {
+		t.Fatalf("Expected subscribed ack, got %v", msg)
+	}
+
+	stream.Publish(stream.Event{Type: stream.EventDecision, TraderID: "other-trader"})
+	stream.Publish(stream.Event{Type: stream.EventDecision, TraderID: "stream-trader", Data: map[string]int{"cycle_number": 7}})
+	msg := readType(conn)
+	if msg["type"] != stream.EventDecision || msg["trader_id"] != "stream-trader" {
+		t.Fatalf("Expected decision event for stream-trader, got %v", msg)
+	}
+	if data, _ := msg["data"].(map[string]interface{}); data["cycle_number"] != float64(7) {
+		t.Errorf("Unexpected event payload: %v", msg["data"])
+	}
+}
+
+// TestUserNotifications tests saving, masking, token reuse and deletion of Telegram notification settings
+func TestUserNotifications(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, _, _ := setupTestEnv(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	router.GET("/user/notifications", server.handleGetUserNotifications)
+	router.POST("/user/notifications", server.handleSaveUserNotifications)
+	router.DELETE("/user/notifications", server.handleDeleteUserNotifications)
+
+	do := func(method, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, "/user/notifications", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	if code, resp := do(http.MethodGet, ""); code != http.StatusOK || resp["configured"] != false {
+		t.Fatalf("Expected unconfigured notifications, got %d %v", code, resp)
+	}
+	if code, _ := do(http.MethodPost, `{"telegram_chat_id":"12345"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 when bot token is missing on first save, got %d", code)
+	}
+	if code, _ := do(http.MethodPost, `{"telegram_bot_token":"123456:ABCDEFGH","telegram_chat_id":"not-a-chat"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid chat id, got %d", code)
+	}
+	if code, _ := do(http.MethodPost, `{"telegram_bot_token":"123456:ABCDEFGH","telegram_chat_id":"12345","events":["bogus"]}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown event, got %d", code)
+	}
+	if code, resp := do(http.MethodPost, `{"telegram_bot_token":"123456:ABCDEFGH","telegram_chat_id":"12345","events":["open","risk_stop"]}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 on save, got %d %v", code, resp)
+	}
+
+	code, resp := do(http.MethodGet, "")
+	if code != http.StatusOK || resp["configured"] != true || resp["telegram_chat_id"] != "12345" {
+		t.Fatalf("Unexpected notification settings: %d %v", code, resp)
+	}
+	if resp["telegram_bot_token"] != "1234****EFGH" {
+		t.Errorf("Expected masked bot token, got %v", resp["telegram_bot_token"])
+	}
+
+	// Saving without a token keeps the stored one
+	if code, _ := do(http.MethodPost, `{"telegram_chat_id":"-1009876","enabled":false}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 when updating without token, got %d", code)
+	}
+	stored, err := db.GetUserNotification(userID)
+	if err != nil || stored == nil {
+		t.Fatalf("Failed to load notification settings: %v", err)
+	}
+	if stored.TelegramBotToken != "123456:ABCDEFGH" || stored.TelegramChatID != "-1009876" || stored.Enabled || len(stored.Events) != 0 {
+		t.Errorf("Unexpected stored settings: %+v", stored)
+	}
+
+	if code, _ := do(http.MethodDelete, ""); code != http.StatusOK {
+		t.Fatalf("Expected 200 on delete, got %d", code)
+	}
+	if stored, _ := db.GetUserNotification(userID); stored != nil {
+		t.Errorf("Expected settings to be deleted, got %+v", stored)
+	}
+}
+
+// TestTraderArchiveRestorePurge tests that deleting archives a trader with its history, restoring brings it back and purge removes everything
+func TestTraderArchiveRestorePurge(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+
+	traderID := "archive-test-trader"
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  traderID,
+		UserID:              userID,
+		Name:                "Archive Test",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	if err := db.RecordTrade(traderID, userID, "BTCUSDT", "LONG", "CLOSE", 1, 100, "", 0, 0, 25, 25); err != nil {
+		t.Fatalf("Failed to save trade history: %v", err)
+	}
+	logDir := traderDecisionLogDir(traderID)
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		t.Fatalf("Failed to create log dir: %v", err)
+	}
+	defer os.RemoveAll("decision_logs")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	router.GET("/traders/archived", server.handleArchivedTraders)
+	router.DELETE("/traders/:id", server.handleDeleteTrader)
+	router.POST("/traders/:id/restore", server.handleRestoreTrader)
+
+	do := func(method, path string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	if code, _ := do(http.MethodDelete, "/traders/"+traderID); code != http.StatusOK {
+		t.Fatalf("Expected 200 on soft delete, got %d", code)
+	}
+	if traders, _ := db.GetTraders(userID); len(traders) != 0 {
+		t.Errorf("Soft-deleted trader should be hidden from GetTraders, got %d", len(traders))
+	}
+	code, resp := do(http.MethodGet, "/traders/archived")
+	list, _ := resp["traders"].([]interface{})
+	if code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("Expected 1 archived trader, got %d %v", code, resp)
+	}
+	if item := list[0].(map[string]interface{}); item["trader_id"] != traderID || item["realized_pnl"] != 25.0 || item["deleted_at"] == "" {
+		t.Errorf("Unexpected archived trader: %v", item)
+	}
+
+	if code, resp := do(http.MethodPost, "/traders/"+traderID+"/restore"); code != http.StatusOK {
+		t.Fatalf("Expected 200 on restore, got %d %v", code, resp)
+	}
+	if traders, _ := db.GetTraders(userID); len(traders) != 1 || traders[0].Name != "Archive Test" {
+		t.Errorf("Restored trader should be listed again with its config, got %+v", traders)
+	}
+	if _, err := server.traderManager.GetTrader(traderID); err != nil {
+		t.Errorf("Restored trader should be registered in the manager: %v", err)
+	}
+	if code, _ := do(http.MethodPost, "/traders/"+traderID+"/restore"); code != http.StatusNotFound {
+		t.Errorf("Restoring an active trader should return 404, got %d", code)
+	}
+
+	if code, _ := do(http.MethodDelete, "/traders/"+traderID+"?purge=true"); code != http.StatusOK {
+		t.Fatalf("Expected 200 on purge, got %d", code)
+	}
+	if code, resp := do(http.MethodGet, "/traders/archived"); code != http.StatusOK || resp["count"] != 0.0 {
+		t.Errorf("Purged trader should not be archived, got %v", resp)
+	}
+	if _, total, _ := db.GetTradeHistory(traderID, 10, 0, config.TradeHistoryFilter{}); total != 0 {
+		t.Errorf("Purge should remove trade history, got %d rows", total)
+	}
+	if _, err := os.Stat(logDir); !os.IsNotExist(err) {
+		t.Errorf("Purge should remove the decision log directory, stat err: %v", err)
+	}
+}
+
+// TestHandleBatchTraders tests batch request validation, ownership checks and per-trader results
+func TestHandleBatchTraders(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+
+	for _, id := range []string{"batch-trader-1", "batch-trader-2"} {
+		if err := db.CreateTrader(&config.TraderRecord{
+			ID:                  id,
+			UserID:              userID,
+			Name:                id,
+			AIModelID:           aiModelIntID,
+			ExchangeID:          exchangeIntID,
+			InitialBalance:      1000,
+			ScanIntervalMinutes: 3,
+		}); err != nil {
+			t.Fatalf("Failed to create trader: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/traders/batch", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		server.handleBatchTraders(c)
+	})
+	do := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/traders/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	if code, _ := do(`{"action":"restart","all":true}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown action, got %d", code)
+	}
+	if code, _ := do(`{"action":"stop"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without trader_ids or all, got %d", code)
+	}
+
+	code, resp := do(`{"action":"stop","trader_ids":["batch-trader-1","someone-elses-trader"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %v", code, resp)
+	}
+	results, _ := resp["results"].(map[string]interface{})
+	status := func(id string) interface{} {
+		r, _ := results[id].(map[string]interface{})
+		return r["status"]
+	}
+	if status("batch-trader-1") != "already_stopped" || status("someone-elses-trader") != "not_found" || len(results) != 2 {
+		t.Errorf("Unexpected batch results: %v", results)
+	}
+
+	_, resp = do(`{"action":"stop","all":true}`)
+	if summary, _ := resp["summary"].(map[string]interface{}); summary["already_stopped"] != 2.0 {
+		t.Errorf("Expected all 2 traders in the result summary, got %v", resp)
+	}
+}
+
+// TestAPIKeyAuth tests personal API keys: scope enforcement, same user context and revocation
+func TestAPIKeyAuth(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, _, _ := setupTestEnv(t, db)
+
+	readKey, _, err := db.CreateUserAPIKey(userID, "cron", config.APIKeyScopeRead, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to create api key: %v", err)
+	}
+	writeKey, writeRecord, err := db.CreateUserAPIKey(userID, "deploy", config.APIKeyScopeReadWrite, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to create api key: %v", err)
+	}
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "ApiKey "+key)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Read-only key can read as the owning user
+	w := do("GET", "/api/user/api-keys", readKey, "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"cron"`) || strings.Contains(w.Body.String(), readKey) {
+		t.Fatalf("Expected key list without plaintext, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Read-only key cannot write, but may log out
+	if w := do("POST", "/api/user/api-keys", readKey, `{"name":"x"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for read-only key POST, got %d", w.Code)
+	}
+	if w := do("POST", "/api/logout", readKey, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected logout to be allowed for read-only key, got %d", w.Code)
+	}
+
+	// Even a read-write key cannot mint or revoke keys (a leaked key must not outlive its revocation)
+	if w := do("POST", "/api/user/api-keys", writeKey, `{"name":"ci","scope":"read","expires_in_days":30}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when creating a key with an api key, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("DELETE", "/api/user/api-keys/"+writeRecord.ID, writeKey, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when revoking a key with an api key, got %d", w.Code)
+	}
+
+	// Admin endpoints reject api keys even when the owner is an admin
+	if err := db.SetSystemConfig("admin_emails", "trader-test@example.com"); err != nil {
+		t.Fatalf("Failed to set admin_emails: %v", err)
+	}
+	for _, path := range []string{"/api/admin/backups", "/api/admin/backups/config.db/download", "/api/admin/users"} {
+		if w := do("GET", path, readKey, ""); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "API_KEY_ADMIN_FORBIDDEN") {
+			t.Errorf("Expected API_KEY_ADMIN_FORBIDDEN for %s, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	// Unknown and revoked keys are rejected
+	if w := do("GET", "/api/user/api-keys", "nofx_invalid", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for unknown key, got %d", w.Code)
+	}
+	if err := db.DeleteUserAPIKey(userID, writeRecord.ID); err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+	if w := do("GET", "/api/user/api-keys", writeKey, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for revoked key, got %d", w.Code)
+	}
+}
+
+// TestAuditLogEndpoints tests that account activity is recorded and queryable by the user and admins
+func TestAuditLogEndpoints(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  "audited-trader",
+		UserID:              userID,
+		Name:                "audited-trader",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	}
+	router.POST("/login", server.handleLogin)
+	router.DELETE("/traders/:id", setUser, server.handleDeleteTrader)
+	router.GET("/user/audit-log", setUser, server.handleUserAuditLog)
+	router.GET("/admin/audit-log", setUser, server.adminMiddleware(), server.handleAdminAuditLog)
+
+	do := func(method, path, user, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "audit-test")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	if w, _ := do("POST", "/login", "", `{"email":"trader-test@example.com","password":"wrong-password"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 for wrong password, got %d", w.Code)
+	}
+	if w, _ := do("POST", "/login", "", `{"email":"nobody@example.com","password":"whatever"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 for unknown email, got %d", w.Code)
+	}
+	if w, _ := do("DELETE", "/traders/audited-trader", userID, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for delete, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w, resp := do("GET", "/user/audit-log", userID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	entries, _ := resp["entries"].([]interface{})
+	if resp["total"].(float64) != 2 || len(entries) != 2 {
+		t.Fatalf("Expected 2 entries for the user, got %v", resp)
+	}
+	latest := entries[0].(map[string]interface{})
+	if latest["action"] != auditTraderDelete || latest["resource_id"] != "audited-trader" || latest["user_agent"] != "audit-test" {
+		t.Errorf("Unexpected latest entry: %v", latest)
+	}
+
+	_, resp = do("GET", "/user/audit-log?action=login_failure", userID, "")
+	if resp["total"].(float64) != 1 {
+		t.Errorf("Expected 1 login failure for the user, got %v", resp)
+	}
+	if w, _ := do("GET", "/user/audit-log?limit=0", userID, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid limit, got %d", w.Code)
+	}
+	if w, _ := do("GET", "/user/audit-log?since=2025-02-01&until=2025-01-01", userID, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for inverted time range, got %d", w.Code)
+	}
+
+	// Non-admin users are rejected; admins see every user's entries
+	if w, _ := do("GET", "/admin/audit-log", userID, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for non-admin, got %d", w.Code)
+	}
+	if _, resp := do("GET", "/admin/audit-log?action=login_failure", "admin", ""); resp["total"].(float64) != 2 {
+		t.Errorf("Expected 2 login failures across users, got %v", resp)
+	}
+	if _, resp := do("GET", "/admin/audit-log?user_id="+userID, "admin", ""); resp["total"].(float64) != 2 {
+		t.Errorf("Expected 2 entries when filtering by user, got %v", resp)
+	}
+}
+
+func TestLogoutAndLogoutAll(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, _, _ := setupTestEnv(t, db)
+	auth.SetJWTSecret("test-secret")
+	auth.SetRevocationStore(db)
+	auth.SetTokenVersionLookup(db.GetTokenVersion)
+	defer auth.SetRevocationStore(nil)
+	defer auth.SetTokenVersionLookup(nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/logout", server.authMiddleware(), server.handleLogout)
+	router.POST("/user/logout-all", server.authMiddleware(), server.handleLogoutAll)
+	router.POST("/refresh-token", server.handleRefreshToken)
+	router.GET("/me", server.authMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method, path, token, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	refresh := func(pair *auth.TokenPair) int {
+		return do("POST", "/refresh-token", "", `{"refresh_token":"`+pair.RefreshToken+`"}`)
+	}
+
+	// Logout revokes the access token and its paired refresh token in the database
+	session, _ := auth.GenerateTokenPair(userID, "trader-test@example.com")
+	if code := do("POST", "/logout", session.AccessToken, ""); code != http.StatusOK {
+		t.Fatalf("Expected logout to succeed, got %d", code)
+	}
+	if code := do("GET", "/me", session.AccessToken, ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected logged out access token to be rejected, got %d", code)
+	}
+	if code := refresh(session); code != http.StatusUnauthorized {
+		t.Errorf("Expected paired refresh token to be rejected, got %d", code)
+	}
+
+	// Logout-all invalidates every token issued before the call
+	phone, _ := auth.GenerateTokenPair(userID, "trader-test@example.com")
+	laptop, _ := auth.GenerateTokenPair(userID, "trader-test@example.com")
+	if code := do("POST", "/user/logout-all", laptop.AccessToken, ""); code != http.StatusOK {
+		t.Fatalf("Expected logout-all to succeed, got %d", code)
+	}
+	if code := do("GET", "/me", phone.AccessToken, ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected other device's access token to be rejected, got %d", code)
+	}
+	if code := refresh(phone); code != http.StatusUnauthorized {
+		t.Errorf("Expected other device's refresh token to be rejected, got %d", code)
+	}
+	if v, err := db.GetTokenVersion(userID); err != nil || v != 1 {
+		t.Errorf("Expected token version 1, got %d (%v)", v, err)
+	}
+
+	fresh, _ := auth.GenerateTokenPair(userID, "trader-test@example.com")
+	if code := do("GET", "/me", fresh.AccessToken, ""); code != http.StatusOK {
+		t.Errorf("Expected tokens issued after logout-all to work, got %d", code)
+	}
+
+	// A failed token version lookup must not let a possibly revoked token through
+	auth.SetTokenVersionLookup(func(string) (int, error) { return 0, errors.New("database is locked") })
+	if code := do("GET", "/me", fresh.AccessToken, ""); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected token version lookup failure to reject the request, got %d", code)
+	}
+	if code := refresh(fresh); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected token version lookup failure to reject refresh, got %d", code)
+	}
+	auth.SetTokenVersionLookup(db.GetTokenVersion)
+
+	if entries, total, _ := db.GetAuditLog(config.AuditLogFilter{UserID: userID, Action: auditLogoutAll}, 10, 0); total != 1 || len(entries) != 1 {
+		t.Errorf("Expected one logout_all audit entry, got %d", total)
+	}
+}
+
+// TestTraderTemplateEndpoints 测试从交易员保存模板、列出模板、从模板创建交易员
+func TestTraderTemplateEndpoints(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	userID, _, _ := setupTestEnv(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	}
+	router.POST("/traders", setUser, server.handleCreateTrader)
+	router.POST("/traders/from-template/:templateId", setUser, server.handleCreateTraderFromTemplate)
+	router.GET("/trader-templates", setUser, server.handleTraderTemplates)
+	router.POST("/trader-templates", setUser, server.handleCreateTraderTemplate)
+	router.DELETE("/trader-templates/:templateId", setUser, server.handleDeleteTraderTemplate)
+
+	do := func(method, path, user, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	findTrader := func(id string) *config.TraderRecord {
+		traders, err := db.GetTraders(userID)
+		if err != nil {
+			t.Fatalf("Failed to get traders: %v", err)
+		}
+		for _, tr := range traders {
+			if tr.ID == id {
+				return tr
+			}
+		}
+		t.Fatalf("Trader %s not found", id)
+		return nil
+	}
+
+	w, resp := do("POST", "/traders", userID, `{"name":"source","ai_model_id":"test-model","exchange_id":"binance",
+		"initial_balance":1500,"btc_eth_leverage":7,"altcoin_leverage":4,"timeframes":"1h,4h","order_strategy":"limit_only",
+		"trading_symbols":"BTCUSDT,SOLUSDT","max_positions":4,"tags":"core","weekend_trading":false}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	sourceID := resp["trader_id"].(string)
+
+	// 保存模板：不包含名称、余额和绑定
+	w, resp = do("POST", "/trader-templates", userID, `{"trader_id":"`+sourceID+`","name":"standard","description":"my defaults"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	templateID := resp["id"].(string)
+	cfg := resp["config"].(map[string]interface{})
+	for _, key := range traderTemplateExcludedFields {
+		if _, ok := cfg[key]; ok {
+			t.Errorf("Template config should not contain %s", key)
+		}
+	}
+	if cfg["btc_eth_leverage"] != float64(7) || cfg["order_strategy"] != "limit_only" || resp["is_system"] != false {
+		t.Errorf("Template should capture trader settings: %v", resp)
+	}
+
+	if w, _ := do("POST", "/trader-templates", userID, `{"trader_id":"`+sourceID+`","name":"standard"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for duplicate template name, got %d", w.Code)
+	}
+	if w, _ := do("POST", "/trader-templates", userID, `{"trader_id":"missing","name":"other"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown trader, got %d", w.Code)
+	}
+
+	// 列表包含自己的模板和系统模板
+	_, resp = do("GET", "/trader-templates", userID, "")
+	templates := resp["templates"].([]interface{})
+	var system, own int
+	for _, item := range templates {
+		if item.(map[string]interface{})["is_system"] == true {
+			system++
+		} else {
+			own++
+		}
+	}
+	if system == 0 || own != 1 {
+		t.Errorf("Expected system templates and 1 own template, got system=%d own=%d", system, own)
+	}
+	for _, item := range templates {
+		tpl := item.(map[string]interface{})
+		raw, _ := json.Marshal(tpl["config"])
+		var settings CreateTraderRequest
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			t.Fatalf("Failed to parse template %v: %v", tpl["id"], err)
+		}
+		if e := validateTraderSettings(&settings); e != nil {
+			t.Errorf("Template %v fails trader validation: %s %v", tpl["id"], e.code, e.args)
+		}
+	}
+
+	// 从模板创建：只需名称和绑定
+	w, resp = do("POST", "/traders/from-template/"+templateID, userID,
+		`{"name":"copy","ai_model_id":"test-model","exchange_id":"binance","initial_balance":800}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	created := findTrader(resp["trader_id"].(string))
+	if created.Name != "copy" || created.InitialBalance != 800 || created.BTCETHLeverage != 7 || created.AltcoinLeverage != 4 ||
+		created.Timeframes != "1h,4h" || created.OrderStrategy != "limit_only" || created.TradingSymbols != "BTCUSDT,SOLUSDT" ||
+		created.MaxPositions != 4 || created.Tags != "core" || created.WeekendTrading {
+		t.Errorf("Trader created from template should carry the template settings: %+v", created)
+	}
+
+	// 系统模板同样可用
+	w, resp = do("POST", "/traders/from-template/system_conservative", userID,
+		`{"name":"conservative","ai_model_id":"test-model","exchange_id":"binance","initial_balance":500}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if tr := findTrader(resp["trader_id"].(string)); tr.BTCETHLeverage != 3 || tr.Timeframes != "15m,1h,4h" {
+		t.Errorf("Trader created from system template has wrong settings: %+v", tr)
+	}
+
+	// 名称重复、模板配置无效时按创建交易员的规则拒绝
+	if w, _ := do("POST", "/traders/from-template/"+templateID, userID,
+		`{"name":"copy","ai_model_id":"test-model","exchange_id":"binance","initial_balance":800}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for duplicate trader name, got %d", w.Code)
+	}
+	if err := db.CreateTraderTemplate(&config.TraderTemplate{ID: "tpl_bad", UserID: userID, Name: "bad", Config: `{"btc_eth_leverage":99}`}); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	if w, resp := do("POST", "/traders/from-template/tpl_bad", userID,
+		`{"name":"bad","ai_model_id":"test-model","exchange_id":"binance","initial_balance":800}`); w.Code != http.StatusBadRequest || resp["code"] != "LEVERAGE_BTC_ETH_OUT_OF_RANGE" {
+		t.Errorf("Expected LEVERAGE_BTC_ETH_OUT_OF_RANGE, got %d: %v", w.Code, resp)
+	}
+
+	// 其他用户看不到、不能使用该模板
+	if w, _ := do("POST", "/traders/from-template/"+templateID, "another-user",
+		`{"name":"x","ai_model_id":"test-model","exchange_id":"binance"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's template, got %d", w.Code)
+	}
+
+	// 系统模板不能删除，自己的模板可以删除
+	if w, _ := do("DELETE", "/trader-templates/system_conservative", userID, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 when deleting a system template, got %d", w.Code)
+	}
+	if w, _ := do("DELETE", "/trader-templates/"+templateID, userID, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if w, _ := do("DELETE", "/trader-templates/"+templateID, userID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after deletion, got %d", w.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceStatus 当前维护模式状态（GET /api/config 和 /api/health 展示）
+func maintenanceStatus() gin.H {
+	mode := trader.CurrentMaintenanceMode()
+	status := gin.H{
+		"enabled":   mode.Enabled,
+		"message":   mode.Message,
+		"resume_at": nil,
+	}
+	if !mode.Until.IsZero() {
+		status["resume_at"] = mode.Until.UTC().Format(time.RFC3339)
+	}
+	return status
+}
+
+// handleSetMaintenance 开启/关闭系统维护模式（管理员）
+// 开启后所有交易员跳过决策周期，交易员运行状态、持仓和止损单保持不变；resume_at 到达后自动恢复
+func (s *Server) handleSetMaintenance(c *gin.Context) {
+	var req struct {
+		Enabled  bool   `json:"enabled"`
+		Message  string `json:"message"`
+		ResumeAt string `json:"resume_at"` // 自动结束时间（RFC3339，可选）
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	mode := trader.MaintenanceMode{Enabled: req.Enabled}
+	if req.Enabled {
+		mode.Message = strings.TrimSpace(req.Message)
+		if resumeAt := strings.TrimSpace(req.ResumeAt); resumeAt != "" {
+			until, err := time.Parse(time.RFC3339, resumeAt)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "MAINTENANCE_RESUME_AT_INVALID")
+				return
+			}
+			if !until.After(time.Now()) {
+				respondError(c, http.StatusBadRequest, "MAINTENANCE_RESUME_AT_PAST")
+				return
+			}
+			mode.Until = until
+		}
+	}
+
+	until := ""
+	if !mode.Until.IsZero() {
+		until = mode.Until.UTC().Format(time.RFC3339)
+	}
+	settings := []struct{ key, value string }{
+		{"maintenance_mode", strconv.FormatBool(mode.Enabled)},
+		{"maintenance_message", mode.Message},
+		{"maintenance_until", until},
+	}
+	for _, setting := range settings {
+		if err := s.database.SetSystemConfig(setting.key, setting.value); err != nil {
+			respondError(c, http.StatusInternalServerError, "SAVE_MAINTENANCE_FAILED")
+			return
+		}
+	}
+	trader.SetMaintenanceMode(mode)
+
+	if mode.Enabled {
+		log.Printf("🛠 管理员 %s 开启维护模式: %s (自动结束: %s)", c.GetString("email"), mode.Message, until)
+	} else {
+		log.Printf("✅ 管理员 %s 关闭维护模式，交易员将在下一个周期恢复决策", c.GetString("email"))
+	}
+	c.JSON(http.StatusOK, gin.H{"maintenance": maintenanceStatus()})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMaintenanceModeEndpoints 测试管理员切换维护模式，/api/config 和 /api/health 展示维护状态
+func TestMaintenanceModeEndpoints(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	defer trader.SetMaintenanceMode(trader.MaintenanceMode{})
+
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	}
+	router.POST("/admin/maintenance", setUser, server.adminMiddleware(), server.handleSetMaintenance)
+	router.GET("/config", server.handleGetSystemConfig)
+	router.GET("/health", server.handleHealth)
+
+	post := func(user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/maintenance", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	get := func(path string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析 %s 响应失败: %v", path, err)
+		}
+		return resp
+	}
+
+	if w := post("regular-user", `{"enabled":true}`); w.Code != http.StatusForbidden {
+		t.Fatalf("非管理员应返回 403, 实际 %d", w.Code)
+	}
+	if w := post("admin", `{"enabled":true,"resume_at":"2000-01-01T00:00:00Z"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("过去的 resume_at 应返回 400, 实际 %d", w.Code)
+	}
+
+	resumeAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if w := post("admin", `{"enabled":true,"message":"交易所升级","resume_at":"`+resumeAt+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("开启维护模式失败: %d %s", w.Code, w.Body.String())
+	}
+	if !trader.CurrentMaintenanceMode().Enabled {
+		t.Fatal("交易员应进入维护模式")
+	}
+	if value, _ := db.GetSystemConfig("maintenance_mode"); value != "true" {
+		t.Errorf("maintenance_mode 应持久化为 true, 实际 %q", value)
+	}
+
+	maintenance, _ := get("/config")["maintenance"].(map[string]interface{})
+	if maintenance["enabled"] != true || maintenance["message"] != "交易所升级" || maintenance["resume_at"] != resumeAt {
+		t.Errorf("/config 维护状态不正确: %v", maintenance)
+	}
+	if mode := get("/health")["mode"]; mode != "maintenance" {
+		t.Errorf("/health mode 应为 maintenance, 实际 %v", mode)
+	}
+
+	if w := post("admin", `{"enabled":false}`); w.Code != http.StatusOK {
+		t.Fatalf("关闭维护模式失败: %d", w.Code)
+	}
+	if trader.CurrentMaintenanceMode().Enabled {
+		t.Error("关闭后不应处于维护模式")
+	}
+	if mode := get("/health")["mode"]; mode != "normal" {
+		t.Errorf("/health mode 应为 normal, 实际 %v", mode)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// traderForUser 从query参数获取trader，并校验是否属于当前用户（用于修改交易所状态的接口）
+func (s *Server) traderForUser(c *gin.Context) (*trader.AutoTrader, bool) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
+		return nil, false
+	}
+	if _, _, _, err := s.database.GetTraderConfig(c.GetString("user_id"), traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return nil, false
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return nil, false
+	}
+	return at, true
+}
+
+// handleOpenOrders 指定trader在交易所上的未成交挂单（限价单、止损止盈单）
+func (s *Server) handleOpenOrders(c *gin.Context) {
+	at, ok := s.traderForUser(c)
+	if !ok {
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(c.Query("symbol")))
+	orders, err := at.GetOpenOrders(symbol)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_OPEN_ORDERS_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// handleCancelOpenOrder 手动撤销指定trader的一个挂单（?trader_id=xxx&symbol=SYMBOL）
+func (s *Server) handleCancelOpenOrder(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("orderId"), 10, 64)
+	if err != nil || orderID <= 0 {
+		respondError(c, http.StatusBadRequest, "ORDER_ID_INVALID")
+		return
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(c.Query("symbol")))
+	if symbol == "" {
+		respondError(c, http.StatusBadRequest, "SYMBOL_REQUIRED")
+		return
+	}
+
+	at, ok := s.traderForUser(c)
+	if !ok {
+		return
+	}
+
+	err = at.CancelOpenOrder(symbol, orderID)
+	switch {
+	case errors.Is(err, trader.ErrCancelOrderUnsupported):
+		respondError(c, http.StatusBadRequest, "CANCEL_ORDER_UNSUPPORTED")
+	case errors.Is(err, trader.ErrOpenOrderNotFound):
+		respondError(c, http.StatusNotFound, "OPEN_ORDER_NOT_FOUND")
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, "CANCEL_ORDER_FAILED", err)
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "挂单已撤销", "order_id": orderID, "symbol": symbol})
+	}
+}
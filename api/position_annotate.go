@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPositionNoteLength 持仓备注的最大字符数（备注会写入AI提示词）
+const maxPositionNoteLength = 200
+
+// AnnotatePositionRequest 持仓备注/锁定请求（note 为空且 locked 为 false 时清除备注）
+type AnnotatePositionRequest struct {
+	TraderID string `json:"trader_id" binding:"required"`
+	Symbol   string `json:"symbol" binding:"required"`
+	Side     string `json:"side" binding:"required"` // long / short
+	Note     string `json:"note"`
+	Locked   bool   `json:"locked"` // 锁定后AI的平仓决策在执行时被拒绝
+}
+
+// handleAnnotatePosition 为指定trader的持仓设置人工备注和锁定状态，持仓完全平仓后自动清除
+func (s *Server) handleAnnotatePosition(c *gin.Context) {
+	var req AnnotatePositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	side := strings.ToLower(strings.TrimSpace(req.Side))
+	if side != "long" && side != "short" {
+		respondError(c, http.StatusBadRequest, "POSITION_SIDE_INVALID")
+		return
+	}
+	// 备注写入提示词的单行持仓信息，合并换行和多余空白
+	note := strings.Join(strings.Fields(req.Note), " ")
+	if utf8.RuneCountInString(note) > maxPositionNoteLength {
+		respondError(c, http.StatusBadRequest, "POSITION_NOTE_TOO_LONG", maxPositionNoteLength)
+		return
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(c.GetString("user_id"), req.TraderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+	at, err := s.traderManager.GetTrader(req.TraderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	annotation, err := at.AnnotatePosition(symbol, side, note, req.Locked)
+	switch {
+	case errors.Is(err, trader.ErrPositionNotFound):
+		respondError(c, http.StatusNotFound, "POSITION_NOT_FOUND")
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, "ANNOTATE_POSITION_FAILED", err)
+	default:
+		c.JSON(http.StatusOK, annotation)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClosePositionRequest 手动按比例平仓请求
+type ClosePositionRequest struct {
+	TraderID   string  `json:"trader_id" binding:"required"`
+	Symbol     string  `json:"symbol" binding:"required"`
+	Side       string  `json:"side" binding:"required"`       // long / short
+	Percentage float64 `json:"percentage" binding:"required"` // 平仓比例 1-100，100 为全部平仓
+}
+
+// handleClosePosition 手动平掉指定trader持仓的一部分，返回成交价和平仓部分的已实现盈亏
+func (s *Server) handleClosePosition(c *gin.Context) {
+	var req ClosePositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	side := strings.ToLower(strings.TrimSpace(req.Side))
+	if side != "long" && side != "short" {
+		respondError(c, http.StatusBadRequest, "POSITION_SIDE_INVALID")
+		return
+	}
+	if req.Percentage < 1 || req.Percentage > 100 {
+		respondError(c, http.StatusBadRequest, "CLOSE_PERCENT_INVALID")
+		return
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(c.GetString("user_id"), req.TraderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+	at, err := s.traderManager.GetTrader(req.TraderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	result, err := at.ClosePositionPercent(symbol, side, req.Percentage)
+	switch {
+	case errors.Is(err, trader.ErrPositionNotFound):
+		respondError(c, http.StatusNotFound, "POSITION_NOT_FOUND")
+	case errors.Is(err, trader.ErrTraderBusy):
+		respondError(c, http.StatusConflict, "TRADER_BUSY")
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, "CLOSE_POSITION_FAILED", err)
+	default:
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"log"
+
+	"nofx/market"
+)
+
+// fundingLookup 查询币种资金费率（测试中可替换）
+var fundingLookup = market.GetFunding
+
+// annotatePositionFunding 为持仓补充资金费率信息，便于前端展示资金费方向
+// funding_direction: pay=该持仓在下次结算时支付资金费，receive=收取资金费；查询失败的币种不补充
+func annotatePositionFunding(positions []map[string]interface{}) {
+	cache := make(map[string]*market.FundingData)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		if symbol == "" {
+			continue
+		}
+
+		funding, ok := cache[symbol]
+		if !ok {
+			var err error
+			funding, err = fundingLookup(symbol)
+			if err != nil {
+				log.Printf("⚠️ 获取 %s 资金费率失败: %v", symbol, err)
+			}
+			cache[symbol] = funding
+		}
+		if funding == nil {
+			continue
+		}
+
+		pos["funding_rate"] = funding.Rate
+		pos["next_funding_time"] = funding.NextFundingTime
+		pos["funding_direction"] = fundingDirection(side, funding.Rate)
+	}
+}
+
+// fundingDirection 资金费方向：费率为正时多头付给空头，为负时空头付给多头
+func fundingDirection(side string, rate float64) string {
+	switch {
+	case rate == 0:
+		return "none"
+	case (side == "long") == (rate > 0):
+		return "pay"
+	default:
+		return "receive"
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"nofx/market"
+)
+
+func TestAnnotatePositionFunding(t *testing.T) {
+	calls := 0
+	orig := fundingLookup
+	fundingLookup = func(symbol string) (*market.FundingData, error) {
+		calls++
+		if symbol == "XYZUSDT" {
+			return nil, errors.New("unknown symbol")
+		}
+		return &market.FundingData{Rate: 0.0005, NextFundingTime: 1700000000000}, nil
+	}
+	defer func() { fundingLookup = orig }()
+
+	positions := []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long"},
+		{"symbol": "BTCUSDT", "side": "short"},
+		{"symbol": "XYZUSDT", "side": "long"},
+	}
+	annotatePositionFunding(positions)
+
+	if calls != 2 {
+		t.Errorf("同一币种应只查询一次资金费率, 实际查询 %d 次", calls)
+	}
+	if positions[0]["funding_direction"] != "pay" || positions[1]["funding_direction"] != "receive" {
+		t.Errorf("正费率时多头支付、空头收取, 实际 %v / %v", positions[0]["funding_direction"], positions[1]["funding_direction"])
+	}
+	if positions[0]["funding_rate"] != 0.0005 || positions[0]["next_funding_time"] != int64(1700000000000) {
+		t.Errorf("资金费率字段错误: %v", positions[0])
+	}
+	if _, ok := positions[2]["funding_rate"]; ok {
+		t.Error("查询失败的币种不应补充资金费率")
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"nofx/auth"
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifySecondFactor 校验 OTP 或恢复码（恢复码校验成功即作废），返回是否通过以及是否使用了恢复码
+func (s *Server) verifySecondFactor(user *config.User, code string) (ok bool, usedRecoveryCode bool) {
+	code = strings.TrimSpace(code)
+	if auth.VerifyOTP(user.OTPSecret, code) {
+		return true, false
+	}
+	if !config.IsRecoveryCodeFormat(code) {
+		return false, false
+	}
+	used, err := s.database.UseRecoveryCode(user.ID, code)
+	if err != nil {
+		log.Printf("❌ 校验恢复码失败 (UserID: %s): %v", user.ID, err)
+		return false, false
+	}
+	if used {
+		log.Printf("🔑 用户 %s 使用恢复码通过两步验证", user.Email)
+	}
+	return used, used
+}
+
+// recoveryCodesRemaining 剩余可用恢复码数量（查询失败时返回 0）
+func (s *Server) recoveryCodesRemaining(userID string) int {
+	count, err := s.database.CountRecoveryCodes(userID)
+	if err != nil {
+		log.Printf("⚠️ 查询剩余恢复码失败 (UserID: %s): %v", userID, err)
+		return 0
+	}
+	return count
+}
+
+// rejectAPIKeySession 安全设置只允许登录会话修改，API Key 请求返回 403
+func rejectAPIKeySession(c *gin.Context) bool {
+	if c.GetString("api_key_id") != "" {
+		respondError(c, http.StatusForbidden, "API_KEY_CANNOT_CHANGE_2FA")
+		return true
+	}
+	return false
+}
+
+// handleGetUserSecurity 当前用户的两步验证状态和剩余恢复码数量
+func (s *Server) handleGetUserSecurity(c *gin.Context) {
+	userID := c.GetString("user_id")
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"otp_enabled":              user.OTPVerified,
+		"recovery_codes_remaining": s.recoveryCodesRemaining(userID),
+	})
+}
+
+// handleRegenerateRecoveryCodes 重新生成恢复码（需要当前 OTP），旧恢复码全部失效，明文只在本次响应中返回
+func (s *Server) handleRegenerateRecoveryCodes(c *gin.Context) {
+	if rejectAPIKeySession(c) {
+		return
+	}
+	var req struct {
+		OTPCode string `json:"otp_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return
+	}
+	if !auth.VerifyOTP(user.OTPSecret, strings.TrimSpace(req.OTPCode)) {
+		respondError(c, http.StatusBadRequest, "OTP_CODE_INCORRECT")
+		return
+	}
+
+	codes, err := s.database.GenerateRecoveryCodes(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "RECOVERY_CODES_FAILED")
+		return
+	}
+	log.Printf("✓ 用户 %s 已重新生成恢复码", user.Email)
+	c.JSON(http.StatusOK, gin.H{
+		"recovery_codes": codes,
+		"message":        "请妥善保存恢复码，每个只能使用一次，旧恢复码已失效",
+	})
+}
+
+// handleSetupOTP 生成新的 OTP 密钥用于重新绑定 Authenticator（不保存，确认后才生效）
+func (s *Server) handleSetupOTP(c *gin.Context) {
+	if rejectAPIKeySession(c) {
+		return
+	}
+	user, err := s.database.GetUserByID(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return
+	}
+	otpSecret, err := auth.GenerateOTPSecret()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "OTP_SECRET_FAILED")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"otp_secret":  otpSecret,
+		"qr_code_url": auth.GetOTPQRCodeURL(otpSecret, user.Email),
+		"message":     "请使用Google Authenticator扫描二维码，然后提交验证码和密码确认绑定",
+	})
+}
+
+// handleConfirmOTP 确认重新绑定 Authenticator：校验账户密码和新密钥生成的验证码后替换 OTP 密钥
+func (s *Server) handleConfirmOTP(c *gin.Context) {
+	if rejectAPIKeySession(c) {
+		return
+	}
+	var req struct {
+		Password  string `json:"password" binding:"required"`
+		OTPSecret string `json:"otp_secret" binding:"required"`
+		OTPCode   string `json:"otp_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return
+	}
+	if !auth.CheckPassword(req.Password, user.PasswordHash) {
+		respondError(c, http.StatusUnauthorized, "PASSWORD_INCORRECT")
+		return
+	}
+	otpSecret := strings.TrimSpace(req.OTPSecret)
+	if !auth.VerifyOTP(otpSecret, strings.TrimSpace(req.OTPCode)) {
+		respondError(c, http.StatusBadRequest, "OTP_CODE_INVALID")
+		return
+	}
+
+	if err := s.database.UpdateUserOTPSecret(userID, otpSecret); err != nil {
+		respondError(c, http.StatusInternalServerError, "UPDATE_OTP_SECRET_FAILED")
+		return
+	}
+	log.Printf("✓ 用户 %s 已重新绑定 Authenticator", user.Email)
+	c.JSON(http.StatusOK, gin.H{
+		"message":                  "Authenticator 已重新绑定，旧设备上的验证码已失效",
+		"recovery_codes_remaining": s.recoveryCodesRemaining(userID),
+	})
+}
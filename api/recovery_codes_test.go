@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nofx/auth"
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+)
+
+// TestRecoveryCodeLoginAndReenroll 测试丢失 Authenticator 后用恢复码登录并重新绑定 OTP
+func TestRecoveryCodeLoginAndReenroll(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	auth.SetJWTSecret("test-secret")
+
+	oldSecret, _ := auth.GenerateOTPSecret()
+	passwordHash, _ := auth.HashPassword("correct-password")
+	userID := "recovery-user"
+	if err := db.CreateUser(&config.User{ID: userID, Email: "recovery@example.com", PasswordHash: passwordHash, OTPSecret: oldSecret, OTPVerified: true}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	codes, err := db.GenerateRecoveryCodes(userID)
+	if err != nil {
+		t.Fatalf("Failed to generate recovery codes: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/verify-otp", server.handleVerifyOTP)
+	protected := router.Group("/", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	protected.GET("/user/security", server.handleGetUserSecurity)
+	protected.POST("/user/otp/setup", server.handleSetupOTP)
+	protected.PUT("/user/otp", server.handleConfirmOTP)
+
+	do := func(method, path string, body interface{}) (int, map[string]interface{}) {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := do("POST", "/verify-otp", gin.H{"user_id": userID, "otp_code": codes[0]})
+	if code != http.StatusOK || resp["recovery_code_used"] != true || resp["access_token"] == nil {
+		t.Fatalf("Expected login with recovery code, got %d: %v", code, resp)
+	}
+	if code, _ := do("POST", "/verify-otp", gin.H{"user_id": userID, "otp_code": codes[0]}); code != http.StatusBadRequest {
+		t.Errorf("Expected a burned recovery code to be rejected, got %d", code)
+	}
+	if _, resp := do("GET", "/user/security", nil); resp["recovery_codes_remaining"] != float64(config.RecoveryCodeCount-1) {
+		t.Errorf("Expected %d remaining recovery codes, got %v", config.RecoveryCodeCount-1, resp["recovery_codes_remaining"])
+	}
+
+	// 重新绑定 Authenticator
+	_, resp = do("POST", "/user/otp/setup", nil)
+	newSecret, _ := resp["otp_secret"].(string)
+	if newSecret == "" {
+		t.Fatalf("Expected a new OTP secret, got %v", resp)
+	}
+	otpCode, _ := totp.GenerateCode(newSecret, time.Now())
+	if code, _ := do("PUT", "/user/otp", gin.H{"password": "wrong-password", "otp_secret": newSecret, "otp_code": otpCode}); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong password, got %d", code)
+	}
+	if code, resp := do("PUT", "/user/otp", gin.H{"password": "correct-password", "otp_secret": newSecret, "otp_code": otpCode}); code != http.StatusOK {
+		t.Fatalf("Expected OTP re-enrollment to succeed, got %d: %v", code, resp)
+	}
+
+	if code, _ := do("POST", "/verify-otp", gin.H{"user_id": userID, "otp_code": otpCode}); code != http.StatusOK {
+		t.Errorf("Expected login with the new authenticator, got %d", code)
+	}
+	if user, _ := db.GetUserByID(userID); user == nil || user.OTPSecret != newSecret {
+		t.Error("Expected the OTP secret to be replaced")
+	}
+}
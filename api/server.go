@@ -2,24 +2,36 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"nofx/auth"
 	"nofx/config"
 	"nofx/crypto"
 	"nofx/decision"
 	"nofx/hook"
+	"nofx/logger"
 	"nofx/manager"
+	"nofx/market"
+	"nofx/metrics"
 	"nofx/middleware"
+	"nofx/netproxy"
+	"nofx/notify"
+	"nofx/pool"
 	"nofx/trader"
+	"nofx/webhook"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -33,7 +45,10 @@ type Server struct {
 	traderManager *manager.TraderManager
 	database      *config.Database
 	cryptoHandler *CryptoHandler
+	balances      *balanceService // 交易所余额缓存（创建交易员/同步余额时使用）
+	mailer        *notify.Mailer  // 邮件发送器（邮箱验证、密码重置）
 	port          int
+	stopCh        chan struct{} // 通知后台任务停止
 }
 
 // NewServer 创建API服务器
@@ -163,8 +178,11 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 		traderManager: traderManager,
 		database:      database,
 		cryptoHandler: cryptoHandler,
+		mailer:        notify.DefaultMailer,
 		port:          port,
+		stopCh:        make(chan struct{}),
 	}
+	s.balances = newBalanceService(s.balanceCacheTTL, s.newBalanceTrader)
 
 	// 设置路由
 	s.setupRoutes()
@@ -238,13 +256,12 @@ func corsMiddleware(allowedOrigins []string) gin.HandlerFunc {
 				// 生产模式：严格拒绝
 				log.Printf("🚫 [CORS] 生产模式拒绝来源: %s", origin)
 				log.Printf("    配置方法：在 .env 添加 CORS_ALLOWED_ORIGINS=%s", origin)
-				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-					"error":   "Origin not allowed",
-					"origin":  origin,
-					"help":    "請在 .env 文件中添加此來源到 CORS_ALLOWED_ORIGINS",
-					"example": fmt.Sprintf("CORS_ALLOWED_ORIGINS=%s", origin),
-					"docs":    "重啟容器後生效：docker-compose restart",
-				})
+				resp := errorResponse(c, "ORIGIN_NOT_ALLOWED")
+				resp["origin"] = origin
+				resp["help"] = "請在 .env 文件中添加此來源到 CORS_ALLOWED_ORIGINS"
+				resp["example"] = fmt.Sprintf("CORS_ALLOWED_ORIGINS=%s", origin)
+				resp["docs"] = "重啟容器後生效：docker-compose restart"
+				c.AbortWithStatusJSON(http.StatusForbidden, resp)
 				return
 			}
 		}
@@ -318,6 +335,9 @@ func isPrivateNetworkOrigin(origin string) bool {
 
 // setupRoutes 设置路由
 func (s *Server) setupRoutes() {
+	// Prometheus 交易指标（配置 metrics_token 后需携带 Bearer Token）
+	s.router.GET("/metrics", s.handleMetrics)
+
 	// API路由组
 	api := s.router.Group("/api")
 	{
@@ -326,9 +346,10 @@ func (s *Server) setupRoutes() {
 
 		// 管理员登录（管理员模式下使用，公共）
 
-		// 系统支持的模型和交易所（无需认证）
+		// 系统支持的模型、交易所和行情指标（无需认证）
 		api.GET("/supported-models", s.handleGetSupportedModels)
 		api.GET("/supported-exchanges", s.handleGetSupportedExchanges)
+		api.GET("/supported-indicators", s.handleGetSupportedIndicators)
 
 		// 系统配置（无需认证，用于前端判断是否管理员模式/注册是否开启）
 		api.GET("/config", s.handleGetSystemConfig)
@@ -342,15 +363,21 @@ func (s *Server) setupRoutes() {
 		// 系统提示词模板管理（无需认证）
 		api.GET("/prompt-templates", s.handleGetPromptTemplates)
 		api.GET("/prompt-templates/:name", s.handleGetPromptTemplate)
+		api.GET("/prompt-templates/:name/versions", s.handlePromptTemplateVersions)
+		api.GET("/prompt-templates/:name/versions/:version", s.handlePromptTemplateVersion)
 
 		// 公开的竞赛数据（无需认证）
 		api.GET("/traders", s.handlePublicTraderList)
 		api.GET("/competition", s.handlePublicCompetition)
+		api.GET("/competition/snapshot/:id", s.handleGetCompetitionSnapshot)
 		api.GET("/top-traders", s.handleTopTraders)
 		api.GET("/equity-history", s.handleEquityHistory)
 		api.POST("/equity-history-batch", s.handleEquityHistoryBatch)
 		api.GET("/traders/:id/public-config", s.handleGetPublicTraderConfig)
 
+		// 实时推送（WebSocket，握手时自行校验JWT，因此不走 authMiddleware）
+		api.GET("/ws", s.handleStream)
+
 		// 认证相关路由（应用严格速率限制，防止暴力破解）
 		authGroup := api.Group("/", middleware.AuthRateLimitMiddleware())
 		{
@@ -359,14 +386,20 @@ func (s *Server) setupRoutes() {
 			authGroup.POST("/verify-otp", s.handleVerifyOTP)
 			authGroup.POST("/complete-registration", s.handleCompleteRegistration)
 			authGroup.POST("/reset-password", s.handleResetPassword)
+			authGroup.POST("/verify-email", s.handleVerifyEmail)
+			authGroup.POST("/resend-verification-email", s.handleResendVerificationEmail)
+			authGroup.POST("/request-password-reset", s.handleRequestPasswordReset)
+			authGroup.POST("/reset-password-with-token", s.handleResetPasswordWithToken)
 			authGroup.POST("/refresh-token", s.handleRefreshToken)
 		}
 
 		// 需要认证的路由
 		protected := api.Group("/", s.authMiddleware())
 		{
-			// 注销（加入黑名单）
+			// 注销（当前 Access Token 和配对的 Refresh Token 加入撤销列表）
 			protected.POST("/logout", s.handleLogout)
+			// 登出所有设备（此前签发的全部token失效）
+			protected.POST("/user/logout-all", s.handleLogoutAll)
 
 			// 僅在顯式啟用時開放解密端點（需要JWT身份）
 			if s.cryptoHandler.AllowDecryptEndpoint() {
@@ -378,48 +411,191 @@ func (s *Server) setupRoutes() {
 
 			// AI交易员管理
 			protected.GET("/my-traders", s.handleTraderList)
+			protected.GET("/traders/archived", s.handleArchivedTraders)
 			protected.GET("/traders/:id/config", s.handleGetTraderConfig)
+			protected.GET("/traders/:id/effective-config", s.handleGetTraderEffectiveConfig)
+			protected.GET("/traders/:id/exchange-fills", s.handleExchangeFills)
+			protected.GET("/traders/:id/daily-reports", s.handleDailyReports)
+			protected.GET("/traders/:id/logs", s.handleTraderLogs)
 			protected.POST("/traders", s.handleCreateTrader)
+			protected.POST("/traders/from-template/:templateId", s.handleCreateTraderFromTemplate)
+			protected.POST("/traders/batch", s.handleBatchTraders)
 			protected.PUT("/traders/:id", s.handleUpdateTrader)
 			protected.DELETE("/traders/:id", s.handleDeleteTrader)
+			protected.POST("/traders/:id/restore", s.handleRestoreTrader)
 			protected.POST("/traders/:id/start", s.handleStartTrader)
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
+			protected.POST("/traders/:id/resume", s.handleResumeTrader)
+			protected.POST("/traders/:id/dry-run", s.handleTraderDryRun)
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
 
 			// AI模型配置
 			protected.GET("/models", s.handleGetModelConfigs)
 			protected.PUT("/models", s.handleUpdateModelConfigs)
+			protected.POST("/models", s.handleCreateModelAccount)
+			protected.PUT("/models/:id", s.handleUpdateModelAccount)
+			protected.PUT("/models/:id/system-prompt", s.handleUpdateModelSystemPrompt)
 
 			// 交易所配置
 			protected.GET("/exchanges", s.handleGetExchangeConfigs)
 			protected.PUT("/exchanges", s.handleUpdateExchangeConfigs)
+			protected.POST("/exchanges", s.handleCreateExchangeAccount)
+			protected.PUT("/exchanges/:id", s.handleUpdateExchangeAccount)
 
 			// 用户信号源配置
 			protected.GET("/user/signal-sources", s.handleGetUserSignalSource)
 			protected.POST("/user/signal-sources", s.handleSaveUserSignalSource)
+			protected.GET("/signal-sources/status", s.handleSignalSourceStatus)
+
+			// 交易事件 Webhook
+			protected.GET("/user/webhook", s.handleGetUserWebhook)
+			protected.PUT("/user/webhook", s.handleSaveUserWebhook)
+
+			// Telegram 交易通知
+			protected.GET("/user/notifications", s.handleGetUserNotifications)
+			protected.POST("/user/notifications", s.handleSaveUserNotifications)
+			protected.DELETE("/user/notifications", s.handleDeleteUserNotifications)
+			protected.POST("/user/notifications/test", s.handleTestUserNotifications)
+
+			// 金额展示币种（仅影响API展示）
+			protected.GET("/user/display-currency", s.handleGetDisplayCurrency)
+			protected.PUT("/user/display-currency", s.handleSetDisplayCurrency)
+			protected.GET("/user/outbound-proxy", s.handleGetOutboundProxy)
+			protected.PUT("/user/outbound-proxy", s.handleSetOutboundProxy)
+			protected.DELETE("/user/webhook", s.handleDeleteUserWebhook)
+			// 账户操作审计日志
+			protected.GET("/user/audit-log", s.handleUserAuditLog)
+
+			// 个人 API Key（脚本等非浏览器访问）
+			protected.GET("/user/api-keys", s.handleListAPIKeys)
+			protected.POST("/user/api-keys", s.handleCreateAPIKey)
+			protected.DELETE("/user/api-keys/:id", s.handleDeleteAPIKey)
+
+			// 两步验证：恢复码和重新绑定 Authenticator
+			protected.GET("/user/security", s.handleGetUserSecurity)
+			protected.POST("/user/recovery-codes/regenerate", s.handleRegenerateRecoveryCodes)
+			protected.POST("/user/otp/setup", s.handleSetupOTP)
+			protected.PUT("/user/otp", s.handleConfirmOTP)
+
+			// 修改密码（需当前密码 + OTP，修改后所有会话需重新登录；应用认证速率限制）
+			protected.POST("/user/change-password", middleware.AuthRateLimitMiddleware(), s.handleChangePassword)
+
+			// 清空历史数据（决策记录、交易历史），保留交易员配置和持仓
+			protected.DELETE("/user/history", s.handleDeleteUserHistory)
 
 			// 提示词模板管理（需要认证）
+			// 交易员配置模板
+			protected.GET("/trader-templates", s.handleTraderTemplates)
+			protected.POST("/trader-templates", s.handleCreateTraderTemplate)
+			protected.DELETE("/trader-templates/:templateId", s.handleDeleteTraderTemplate)
+
 			protected.POST("/prompt-templates", s.handleCreatePromptTemplate)
 			protected.PUT("/prompt-templates/:name", s.handleUpdatePromptTemplate)
 			protected.DELETE("/prompt-templates/:name", s.handleDeletePromptTemplate)
 			protected.POST("/prompt-templates/reload", s.handleReloadPromptTemplates)
+			protected.POST("/prompt-templates/validate", s.handleValidatePromptTemplate)
+			protected.POST("/prompt-templates/:name/rollback/:version", s.handleRollbackPromptTemplate)
 			// 指定trader的数据（使用query参数 ?trader_id=xxx）
 			protected.GET("/status", s.handleStatus)
 			protected.GET("/account", s.handleAccount)
 			protected.GET("/positions", s.handlePositions)
+			protected.POST("/positions/close", s.handleClosePosition)
+			protected.POST("/positions/annotate", s.handleAnnotatePosition)
+			protected.GET("/orders", s.handleOpenOrders)
+			protected.DELETE("/orders/:orderId", s.handleCancelOpenOrder)
 			protected.GET("/decisions", s.handleDecisions)
 			protected.GET("/decisions/latest", s.handleLatestDecisions)
+			protected.GET("/decisions/export", s.handleExportDecisions)
+			protected.GET("/equity-history/export", s.handleExportEquityHistory)
+			protected.GET("/decisions/success-rate", s.handleDecisionSuccessRate)
+			protected.GET("/rejected-decisions", s.handleRejectedDecisions)
+			protected.GET("/fees", s.handleFees)
+			protected.GET("/trade-history", s.handleTradeHistory)
+			protected.GET("/trade-stats", s.handleTradeStats)
 			protected.GET("/statistics", s.handleStatistics)
+			protected.GET("/statistics/by-tag", s.handleStatisticsByTag)
 			protected.GET("/performance", s.handlePerformance)
+
+			// 管理员接口
+			protected.POST("/competition/snapshot", s.adminMiddleware(), s.handleCreateCompetitionSnapshot)
+			protected.GET("/admin/default-template", s.adminMiddleware(), s.handleGetDefaultTemplate)
+			protected.PUT("/admin/default-template", s.adminMiddleware(), s.handleSetDefaultTemplate)
+			protected.POST("/admin/beta-codes/generate", s.adminMiddleware(), s.handleGenerateBetaCodes)
+			protected.GET("/admin/beta-codes", s.adminMiddleware(), s.handleListBetaCodes)
+			protected.DELETE("/admin/beta-codes/:code", s.adminMiddleware(), s.handleRevokeBetaCode)
+			protected.GET("/admin/positions/by-symbol", s.adminMiddleware(), s.handleAdminPositionsBySymbol)
+			protected.GET("/admin/users", s.adminMiddleware(), s.handleAdminListUsers)
+			protected.POST("/admin/users/:id/disable", s.adminMiddleware(), s.handleAdminDisableUser)
+			protected.POST("/admin/users/:id/enable", s.adminMiddleware(), s.handleAdminEnableUser)
+			protected.DELETE("/admin/users/:id", s.adminMiddleware(), s.handleAdminDeleteUser)
+			protected.POST("/admin/maintenance", s.adminMiddleware(), s.handleSetMaintenance)
+			protected.POST("/admin/decision-logs/encrypt", s.adminMiddleware(), s.handleEncryptDecisionLogs)
+			protected.GET("/admin/smtp", s.adminMiddleware(), s.handleGetSMTPSettings)
+			protected.PUT("/admin/smtp", s.adminMiddleware(), s.handleUpdateSMTPSettings)
+			protected.POST("/admin/smtp/test", s.adminMiddleware(), s.handleTestSMTP)
+			protected.POST("/admin/backup", s.adminMiddleware(), s.handleCreateBackup)
+			protected.GET("/admin/backups", s.adminMiddleware(), s.handleListBackups)
+			protected.GET("/admin/backups/:name/download", s.adminMiddleware(), s.handleDownloadBackup)
+			protected.GET("/admin/audit-log", s.adminMiddleware(), s.handleAdminAuditLog)
+		}
+	}
+}
+
+// handleMetrics 以 Prometheus 文本格式导出各交易员的净值、盈亏、持仓数和回撤
+func (s *Server) handleMetrics(c *gin.Context) {
+	token, _ := s.database.GetSystemConfig("metrics_token")
+	if token != "" {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			respondError(c, http.StatusUnauthorized, "METRICS_TOKEN_INVALID")
+			return
 		}
 	}
+	metrics.Handler().ServeHTTP(c.Writer, c.Request)
 }
 
 // handleHealth 健康检查
 func (s *Server) handleHealth(c *gin.Context) {
+	status := "ok"
+
+	// 汇总处于维护中的交易所（基于各交易员最近一次交易状态检查）
+	maintenanceExchanges := []string{}
+	if s.traderManager != nil {
+		seen := make(map[string]bool)
+		for _, t := range s.traderManager.GetAllTraders() {
+			exchange := t.GetExchange()
+			if t.IsExchangeInMaintenance() && !seen[exchange] {
+				seen[exchange] = true
+				maintenanceExchanges = append(maintenanceExchanges, exchange)
+			}
+		}
+	}
+	if len(maintenanceExchanges) > 0 {
+		status = "degraded"
+	}
+
+	// WebSocket 行情流不可用时行情由 REST 轮询降级提供
+	marketData := market.GetMarketDataStatus()
+	if marketData.RESTFallback {
+		status = "degraded"
+	}
+
+	// 系统维护模式：所有交易员暂停决策
+	mode := "normal"
+	if trader.CurrentMaintenanceMode().Enabled {
+		mode = "maintenance"
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
-		"time":   c.Request.Context().Value("time"),
+		"status":                status,
+		"mode":                  mode,
+		"time":                  c.Request.Context().Value("time"),
+		"exchange_maintenance":  len(maintenanceExchanges) > 0,
+		"maintenance_exchanges": maintenanceExchanges,
+		"maintenance":           maintenanceStatus(),
+		"kline_cache":           market.GetKlineCacheStats(),
+		"market_data":           marketData,
+		"signal_sources":        pool.SystemSourceHealth(),
 	})
 }
 
@@ -479,6 +655,7 @@ func (s *Server) handleGetSystemConfig(c *gin.Context) {
 		"btc_eth_leverage":     btcEthLeverage,
 		"altcoin_leverage":     altcoinLeverage,
 		"registration_enabled": registrationEnabled,
+		"maintenance":          maintenanceStatus(),
 	})
 }
 
@@ -505,7 +682,7 @@ func (s *Server) handleGetServerIP(c *gin.Context) {
 
 	// 如果还是没有获取到，返回错误
 	if publicIP == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法获取公网IP地址"})
+		respondError(c, http.StatusInternalServerError, "PUBLIC_IP_UNAVAILABLE")
 		return
 	}
 
@@ -650,28 +827,139 @@ func (s *Server) getTraderFromQuery(c *gin.Context) (*manager.TraderManager, str
 	return s.traderManager, traderID, nil
 }
 
+// responseDecimals 获取响应中金额/盈亏字段的小数位
+// 优先级：query参数 decimals > 系统配置 response_decimals > 默认值
+func (s *Server) responseDecimals(c *gin.Context) int {
+	if decimalsStr := c.Query("decimals"); decimalsStr != "" {
+		if d, err := strconv.Atoi(decimalsStr); err == nil && d >= 0 && d <= maxResponseDecimals {
+			return d
+		}
+	}
+	if s.database != nil {
+		if decimalsStr, err := s.database.GetSystemConfig("response_decimals"); err == nil && decimalsStr != "" {
+			if d, err := strconv.Atoi(decimalsStr); err == nil && d >= 0 && d <= maxResponseDecimals {
+				return d
+			}
+		}
+	}
+	return defaultResponseDecimals
+}
+
+// displayPriceFunc 获取展示币种换算价格（测试中可替换）
+var displayPriceFunc = market.GetPrice
+
+// displayCurrency 返回本次响应使用的金额展示币种：?currency= 参数优先，其次为登录用户的偏好，默认 USD
+func (s *Server) displayCurrency(c *gin.Context) string {
+	if currency, ok := NormalizeDisplayCurrency(c.Query("currency")); ok {
+		return currency
+	}
+	if userID := c.GetString("user_id"); userID != "" && s.database != nil {
+		if currency, err := s.database.GetUserDisplayCurrency(userID); err == nil {
+			if currency, ok := NormalizeDisplayCurrency(currency); ok {
+				return currency
+			}
+		}
+	}
+	return "USD"
+}
+
+// displayCurrencyPrice 返回展示币种的 USD 价格，USD 或获取价格失败时返回 0（响应只保留 USD 字段）
+func (s *Server) displayCurrencyPrice(c *gin.Context) (string, float64) {
+	currency := s.displayCurrency(c)
+	symbol := displayCurrencySymbols[currency]
+	if symbol == "" {
+		return currency, 0
+	}
+	price, err := displayPriceFunc(symbol)
+	if err != nil {
+		log.Printf("⚠️ 获取展示币种 %s 价格失败，仅返回USD金额: %v", currency, err)
+		return currency, 0
+	}
+	return currency, price
+}
+
+// withDisplayCurrency 为响应追加按展示币种换算的金额字段
+func (s *Server) withDisplayCurrency(c *gin.Context, data map[string]interface{}) map[string]interface{} {
+	currency, price := s.displayCurrencyPrice(c)
+	return ConvertDisplayCurrency(data, currency, price)
+}
+
+// competitionResponse 竞赛数据响应：四舍五入后为每个交易员追加展示币种金额
+func (s *Server) competitionResponse(c *gin.Context, data map[string]interface{}) map[string]interface{} {
+	rounded := roundCompetitionData(data, s.responseDecimals(c))
+	currency, price := s.displayCurrencyPrice(c)
+	if price <= 0 {
+		return rounded
+	}
+	if traders, ok := rounded["traders"].([]map[string]interface{}); ok {
+		converted := make([]map[string]interface{}, len(traders))
+		for i, t := range traders {
+			converted[i] = ConvertDisplayCurrency(t, currency, price)
+		}
+		rounded["traders"] = converted
+	}
+	rounded["display_currency"] = currency
+	rounded["display_price"] = price
+	return rounded
+}
+
 // AI交易员管理相关结构体
 type CreateTraderRequest struct {
-	Name                 string  `json:"name" binding:"required"`
-	AIModelID            string  `json:"ai_model_id" binding:"required"`
-	ExchangeID           string  `json:"exchange_id" binding:"required"`
-	InitialBalance       float64 `json:"initial_balance"`
-	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
-	BTCETHLeverage       int     `json:"btc_eth_leverage"`
-	AltcoinLeverage      int     `json:"altcoin_leverage"`
-	TradingSymbols       string  `json:"trading_symbols"`
-	CustomPrompt         string  `json:"custom_prompt"`
-	OverrideBasePrompt   bool    `json:"override_base_prompt"`
-	SystemPromptTemplate string  `json:"system_prompt_template"` // 系统提示词模板名称
-	IsCrossMargin        *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
-	UseCoinPool          bool    `json:"use_coin_pool"`
-	UseOITop             bool    `json:"use_oi_top"`
-	TakerFeeRate         float64 `json:"taker_fee_rate"`        // Taker fee rate, default 0.0004 (0.04%)
-	MakerFeeRate         float64 `json:"maker_fee_rate"`        // Maker fee rate, default 0.0002 (0.02%)
-	OrderStrategy        string  `json:"order_strategy"`        // Order strategy: market_only, conservative_hybrid, limit_only
-	LimitPriceOffset     float64 `json:"limit_price_offset"`    // Limit price offset percentage, default -0.03 (-0.03%)
-	LimitTimeoutSeconds  int     `json:"limit_timeout_seconds"` // Limit order timeout in seconds, default 60
-	Timeframes           string  `json:"timeframes"`            // 时间线选择 (逗号分隔，例如: "1m,4h,1d")
+	Name                   string  `json:"name" binding:"required"`
+	AIModelID              string  `json:"ai_model_id" binding:"required"`
+	ExchangeID             string  `json:"exchange_id" binding:"required"`
+	InitialBalance         float64 `json:"initial_balance"`
+	ScanIntervalMinutes    int     `json:"scan_interval_minutes"`
+	BTCETHLeverage         int     `json:"btc_eth_leverage"`
+	AltcoinLeverage        int     `json:"altcoin_leverage"`
+	TradingSymbols         string  `json:"trading_symbols"`
+	CustomPrompt           string  `json:"custom_prompt"`
+	OverrideBasePrompt     bool    `json:"override_base_prompt"`
+	SystemPromptTemplate   string  `json:"system_prompt_template"` // 系统提示词模板名称
+	IsCrossMargin          *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
+	UseCoinPool            bool    `json:"use_coin_pool"`
+	UseOITop               bool    `json:"use_oi_top"`
+	TakerFeeRate           float64 `json:"taker_fee_rate"`             // Taker fee rate, default 0.0004 (0.04%)
+	MakerFeeRate           float64 `json:"maker_fee_rate"`             // Maker fee rate, default 0.0002 (0.02%)
+	OrderStrategy          string  `json:"order_strategy"`             // Order strategy: market_only, conservative_hybrid, limit_only
+	LimitPriceOffset       float64 `json:"limit_price_offset"`         // Limit price offset percentage, default -0.03 (-0.03%)
+	LimitTimeoutSeconds    int     `json:"limit_timeout_seconds"`      // Limit order timeout in seconds, default 60
+	Timeframes             string  `json:"timeframes"`                 // 时间线选择 (逗号分隔，例如: "1m,4h,1d")
+	PortfolioGroup         string  `json:"portfolio_group"`            // 组合模式分组名称（同组交易员共用一次AI调用，空=独立决策）
+	MaxTradesPerDay        int     `json:"max_trades_per_day"`         // 每日最多开仓次数（0=不限制）
+	ModelPool              string  `json:"model_pool"`                 // 模型池：AI模型ID列表，逗号分隔（为空则只使用 ai_model_id）
+	ModelPoolMode          string  `json:"model_pool_mode"`            // 模型池选择方式：round_robin（默认）/random
+	FallbackAIModelID      string  `json:"fallback_ai_model_id"`       // 备用AI模型ID（主模型失败时重试一次，空=不启用）
+	LogLevel               string  `json:"log_level"`                  // 交易员日志级别（debug/info/warn/error，空=使用全局 log.level）
+	HoldCachePct           float64 `json:"hold_cache_pct"`             // 持有决策缓存阈值（价格变动百分比，0=关闭）
+	StartPriority          int     `json:"start_priority"`             // 开机自动启动优先级（越大越先启动，默认0）
+	MaxExposureMultiple    float64 `json:"max_exposure_multiple"`      // 最大总敞口倍数（总名义价值/账户净值，0=不限制）
+	RespectSignalBias      bool    `json:"respect_signal_bias"`        // 开仓方向必须与信号源方向偏好一致
+	DryRun                 bool    `json:"dry_run"`                    // 模拟运行（不实际下单）
+	AlertDrawdownPct       float64 `json:"alert_drawdown_pct"`         // 净值较峰值回撤预警阈值（%，0=不启用）
+	AlertDailyLossPct      float64 `json:"alert_daily_loss_pct"`       // 当日亏损预警阈值（%，0=不启用）
+	AIQualityWindow        int     `json:"ai_quality_window"`          // AI质量检测窗口（最近N次调用，0=不启用）
+	AIQualityMaxFailurePct float64 `json:"ai_quality_max_failure_pct"` // AI调用失败率暂停阈值（%）
+	AIQualityPauseMinutes  int     `json:"ai_quality_pause_minutes"`   // AI质量暂停冷却时长（分钟，0=默认30分钟）
+	MaxConsecutiveErrors   int     `json:"max_consecutive_errors"`     // 连续决策周期失败自动暂停阈值（0=不暂停）
+	DailyReport            bool    `json:"daily_report"`               // 生成每日报告
+	UnfundedThreshold      float64 `json:"unfunded_threshold"`         // 未入金判定阈值（USDT，0=默认1）
+	Tags                   string  `json:"tags"`                       // 标签，逗号分隔（小写字母、数字、-、_）
+	MaxAICallsPerDay       int     `json:"max_ai_calls_per_day"`       // 每日AI调用上限（0=不限制）
+	RejectNonCandidates    bool    `json:"reject_non_candidates"`      // 仅允许对候选币种开仓
+	OpenVerifyDelayMs      int     `json:"open_verify_delay_ms"`       // 开仓确认延迟毫秒（0=不确认）
+	ActiveHours            string  `json:"active_hours"`               // 交易时间窗口（UTC，如 13:00-21:00，逗号分隔多段，空=全天）
+	WeekendTrading         *bool   `json:"weekend_trading"`            // 指针类型，nil表示使用默认值true（周末照常交易）
+	FlattenOnWindowClose   bool    `json:"flatten_on_window_close"`    // 交易窗口外平掉所有持仓
+	MaxPositions           int     `json:"max_positions"`              // 最多同时持仓数量（0=不限制）
+	MaxPositionSizeUSD     float64 `json:"max_position_size_usd"`      // 单笔开仓最大名义价值USDT（0=不限制）
+	BlacklistedSymbols     string  `json:"blacklisted_symbols"`        // 禁止开仓的币种，逗号分隔
+	KlineLimit             int     `json:"kline_limit"`                // 每个时间线提供给AI的K线数量（0=默认行情格式）
+	Indicators             string  `json:"indicators"`                 // 行情指标，逗号分隔（例如: "ema50,rsi14,weekly_pivots"，空=默认行情格式）
+	// 交易员级风控阈值，nil表示使用系统配置（max_daily_loss/max_drawdown/stop_trading_minutes）
+	MaxDailyLoss       *float64 `json:"max_daily_loss"`       // 最大日亏损百分比（0=不限制）
+	MaxDrawdown        *float64 `json:"max_drawdown"`         // 最大回撤百分比（0=不限制）
+	StopTradingMinutes *int     `json:"stop_trading_minutes"` // 触发风控后暂停分钟数
 }
 
 type ModelConfig struct {
@@ -686,11 +974,16 @@ type ModelConfig struct {
 // SafeModelConfig 安全的模型配置结构（不包含敏感信息）
 type SafeModelConfig struct {
 	ID              string `json:"id"`
+	AccountID       int    `json:"account_id,omitempty"` // 配置自增ID（多账户时区分同一模型的不同配置）
+	DisplayName     string `json:"display_name"`         // 账户名称（空表示默认账户）
 	Name            string `json:"name"`
 	Provider        string `json:"provider"`
 	Enabled         bool   `json:"enabled"`
 	CustomAPIURL    string `json:"customApiUrl"`    // 自定义API URL（通常不敏感）
 	CustomModelName string `json:"customModelName"` // 自定义模型名（不敏感）
+	// 模型专属 System Prompt 前缀/后缀
+	SystemPromptPrefix string `json:"systemPromptPrefix"`
+	SystemPromptSuffix string `json:"systemPromptSuffix"`
 }
 
 type ExchangeConfig struct {
@@ -706,6 +999,8 @@ type ExchangeConfig struct {
 // SafeExchangeConfig 安全的交易所配置结构（不包含敏感信息）
 type SafeExchangeConfig struct {
 	ID                    string `json:"id"`
+	AccountID             int    `json:"account_id,omitempty"` // 配置自增ID（多账户时区分同一交易所的不同账户）
+	DisplayName           string `json:"display_name"`         // 账户名称（空表示默认账户）
 	Name                  string `json:"name"`
 	Type                  string `json:"type"` // "cex" or "dex"
 	Enabled               bool   `json:"enabled"`
@@ -734,107 +1029,203 @@ type UpdateExchangeConfigRequest struct {
 		AsterUser             string `json:"aster_user"`
 		AsterSigner           string `json:"aster_signer"`
 		AsterPrivateKey       string `json:"aster_private_key"`
+		OKXPassphrase         string `json:"okx_passphrase"`
 	} `json:"exchanges"`
 }
 
-// queryExchangeBalance 查詢交易所實際餘額
-// 根據交易所類型創建臨時 trader 並查詢當前總資產
-func (s *Server) queryExchangeBalance(userID, exchangeID string, exchangeCfg *config.ExchangeConfig) (float64, error) {
-	// 根據交易所類型創建臨時 trader
-	var tempTrader trader.Trader
-	var err error
-
-	switch exchangeID {
-	case "binance":
-		// 使用默认订单策略（查询余额不需要实际下单）
-		tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID, "market_only", -0.03, 60)
-	case "hyperliquid":
-		tempTrader, err = trader.NewHyperliquidTrader(
-			exchangeCfg.APIKey, // private key
-			exchangeCfg.HyperliquidWalletAddr,
-			exchangeCfg.Testnet,
-		)
-	case "aster":
-		tempTrader, err = trader.NewAsterTrader(
-			exchangeCfg.AsterUser,
-			exchangeCfg.AsterSigner,
-			exchangeCfg.AsterPrivateKey,
-		)
-	default:
-		return 0, fmt.Errorf("不支持的交易所類型: %s", exchangeID)
-	}
-
+// ensurePaperExchange 确保用户拥有已启用的模拟盘交易所配置
+func (s *Server) ensurePaperExchange(userID string) error {
+	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
-		return 0, fmt.Errorf("創建臨時 trader 失敗: %w", err)
+		return err
 	}
-
-	if tempTrader == nil {
-		return 0, fmt.Errorf("tempTrader 為 nil")
+	for _, ex := range exchanges {
+		if ex.ExchangeID == "paper" {
+			if ex.Enabled {
+				return nil
+			}
+			return s.database.UpdateExchange(userID, "paper", true, "", "", false, "", "", "", "")
+		}
 	}
+	log.Printf("📄 为用户 %s 开通模拟盘交易所", userID)
+	return s.database.CreateExchange(userID, "paper", "Paper Trading", "paper", true, "", "", false, "", "", "", "")
+}
 
-	// 查詢實際餘額
-	balanceInfo, err := tempTrader.GetBalance()
+// queryExchangeBalance 查詢交易所實際餘額
+// 通過余額緩存查詢當前總資產（複用 trader 實例並按交易所限流）
+func (s *Server) queryExchangeBalance(userID string, exchangeCfg *config.ExchangeConfig) (float64, error) {
+	snapshot, err := s.fetchExchangeBalance(userID, exchangeCfg)
 	if err != nil {
-		return 0, fmt.Errorf("查詢交易所余額失敗: %w", err)
-	}
-
-	// 使用統一的工具函數解析總資產
-	totalEquity, success := trader.ParseTotalEquity(balanceInfo, "✓")
-	if !success {
-		return 0, fmt.Errorf("無法從餘額信息中提取總資產")
+		return 0, err
 	}
+	return snapshot.TotalEquity, nil
+}
 
-	return totalEquity, nil
+// respondTraderNameTaken 返回交易员重名错误（带 TRADER_NAME_TAKEN 错误码，便于前端识别）
+func respondTraderNameTaken(c *gin.Context, name string) {
+	respondError(c, http.StatusBadRequest, "TRADER_NAME_TAKEN", name)
 }
 
 // handleCreateTrader 创建新的AI交易员
 func (s *Server) handleCreateTrader(c *gin.Context) {
-	userID := c.GetString("user_id")
-	var err error // Declare err for later use
 	var req CreateTraderRequest
-	if err = c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
+	s.createTrader(c, c.GetString("user_id"), &req)
+}
+
+// traderSettingsError 交易员配置校验失败（错误码和参数，对应 respondError）
+type traderSettingsError struct {
+	code string
+	args []interface{}
+}
+
+func settingsError(code string, args ...interface{}) *traderSettingsError {
+	return &traderSettingsError{code: code, args: args}
+}
 
+// validateTraderSettings 校验创建交易员的配置项（不依赖数据库的规则，创建交易员和保存模板共用）
+func validateTraderSettings(req *CreateTraderRequest) *traderSettingsError {
 	// 校验杠杆值
 	if req.BTCETHLeverage < 0 || req.BTCETHLeverage > 50 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "BTC/ETH杠杆必须在1-50倍之间"})
-		return
+		return settingsError("LEVERAGE_BTC_ETH_OUT_OF_RANGE")
 	}
 	if req.AltcoinLeverage < 0 || req.AltcoinLeverage > 20 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "山寨币杠杆必须在1-20倍之间"})
-		return
+		return settingsError("LEVERAGE_ALTCOIN_OUT_OF_RANGE")
 	}
 
-	// 校验交易币种格式
-	if req.TradingSymbols != "" {
-		symbols := strings.Split(req.TradingSymbols, ",")
-		for _, symbol := range symbols {
-			symbol = strings.TrimSpace(symbol)
-			if symbol != "" && !strings.HasSuffix(strings.ToUpper(symbol), "USDT") {
-				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的币种格式: %s，必须以USDT结尾", symbol)})
-				return
-			}
-		}
+	// 校验交易币种和黑名单币种格式
+	if err := validateSymbolList(req.TradingSymbols); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+	if err := validateSymbolList(req.BlacklistedSymbols); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+
+	// 费率范围（0 表示使用默认费率）
+	if req.TakerFeeRate < 0 || req.TakerFeeRate > 0.01 {
+		return settingsError("TAKER_FEE_OUT_OF_RANGE")
+	}
+	if req.MakerFeeRate < 0 || req.MakerFeeRate > 0.01 {
+		return settingsError("MAKER_FEE_OUT_OF_RANGE")
+	}
+
+	// 每日开仓上限（0=不限制）
+	if req.MaxTradesPerDay < 0 {
+		return settingsError("MAX_DAILY_TRADES_NEGATIVE")
+	}
+
+	// 持有决策缓存阈值（0=关闭）
+	if req.HoldCachePct < 0 || req.HoldCachePct > trader.MaxHoldCachePct {
+		return settingsError("HOLD_CACHE_PCT_OUT_OF_RANGE", trader.MaxHoldCachePct)
+	}
+
+	if req.MaxExposureMultiple < 0 || req.MaxExposureMultiple > trader.MaxExposureMultipleLimit {
+		return settingsError("EXPOSURE_MULTIPLE_OUT_OF_RANGE", trader.MaxExposureMultipleLimit)
+	}
+
+	// 净值预警阈值（0=不启用）
+	if !validEquityAlertPct(req.AlertDrawdownPct) || !validEquityAlertPct(req.AlertDailyLossPct) {
+		return settingsError("EQUITY_ALERT_PCT_OUT_OF_RANGE")
+	}
+
+	// AI输出质量检测（窗口为0时不启用）
+	if err := validateAIQualityConfig(req.AIQualityWindow, req.AIQualityMaxFailurePct, req.AIQualityPauseMinutes); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+
+	// 连续失败自动暂停（0=不暂停）
+	if err := validateMaxConsecutiveErrors(req.MaxConsecutiveErrors); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+
+	// 未入金判定阈值（0=默认1 USDT）
+	if req.UnfundedThreshold < 0 {
+		return settingsError("UNFUNDED_THRESHOLD_NEGATIVE")
+	}
+
+	// 每日AI调用上限（0=不限制）
+	if req.MaxAICallsPerDay < 0 {
+		return settingsError("DAILY_AI_CALL_LIMIT_NEGATIVE")
+	}
+
+	// 开仓确认延迟（0=不确认）
+	if err := validateOpenVerifyDelay(req.OpenVerifyDelayMs); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+
+	// 行情上下文：K线数量和指标（都为空=默认行情格式）
+	if err := validateKlineLimit(req.KlineLimit); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+	if _, err := normalizeIndicators(req.Indicators); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+
+	// 交易时间窗口（默认全天、周末照常交易）
+	weekendTrading := req.WeekendTrading == nil || *req.WeekendTrading
+	if _, err := trader.ParseTradingWindow(strings.TrimSpace(req.ActiveHours), weekendTrading); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+
+	// 仓位上限（0=不限制）
+	if req.MaxPositions < 0 || req.MaxPositionSizeUSD < 0 {
+		return settingsError("POSITION_LIMITS_NEGATIVE")
+	}
+
+	// 交易员级风控阈值（未设置则使用系统配置）
+	if err := validateRiskLimitOverrides(req.MaxDailyLoss, req.MaxDrawdown, req.StopTradingMinutes); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+
+	// 标签（用于分组筛选和按标签汇总）
+	if _, err := config.NormalizeTags(req.Tags); err != nil {
+		return settingsError("INVALID_PARAMETER", err)
+	}
+
+	// 模型池选择方式（为空表示 round_robin）
+	if req.ModelPoolMode != "" && !trader.IsValidModelPoolMode(req.ModelPoolMode) {
+		return settingsError("MODEL_POOL_STRATEGY_INVALID")
+	}
+
+	// 交易员日志级别（为空表示使用全局级别）
+	if logLevel, ok := normalizeLogLevel(req.LogLevel); !ok {
+		return settingsError("LOG_LEVEL_INVALID", logLevel)
+	}
+	return nil
+}
+
+// createTrader 校验配置并创建交易员（创建接口和从模板创建共用）
+func (s *Server) createTrader(c *gin.Context, userID string, req *CreateTraderRequest) {
+	var err error // Declare err for later use
+	if e := validateTraderSettings(req); e != nil {
+		respondError(c, http.StatusBadRequest, e.code, e.args...)
+		return
 	}
+	blacklistedSymbols := strings.Join(trader.ParseSymbolList(req.BlacklistedSymbols), ",")
 
 	// ✅ 检查交易员名称是否重复
 	existingTraders, err := s.database.GetTraders(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("检查交易员名称失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, "CHECK_TRADER_NAME_FAILED", err)
 		return
 	}
 	for _, existing := range existingTraders {
 		if existing.Name == req.Name {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易员名称 '%s' 已存在，请使用其他名称", req.Name)})
+			respondTraderNameTaken(c, req.Name)
 			return
 		}
 	}
 
-	// 生成交易员ID (使用 UUID 确保唯一性，解决 Issue #893)
-	// 保留前缀以便调试和日志追踪
-	traderID := fmt.Sprintf("%s_%s_%s", req.ExchangeID, req.AIModelID, uuid.New().String())
+	// 📄 模拟盘无需API密钥，首次创建模拟盘交易员时自动开通 paper 交易所
+	if req.ExchangeID == "paper" {
+		if err := s.ensurePaperExchange(userID); err != nil {
+			respondError(c, http.StatusInternalServerError, "CREATE_PAPER_ACCOUNT_FAILED", err)
+			return
+		}
+	}
 
 	// 设置默认值
 	isCrossMargin := true // 默认为全仓模式
@@ -866,8 +1257,8 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		}
 	}
 
-	// 设置系统提示词模板默认值
-	systemPromptTemplate := "default"
+	// 设置系统提示词模板默认值（未指定时使用管理员配置的系统默认模板）
+	systemPromptTemplate := s.defaultPromptTemplate()
 	if req.SystemPromptTemplate != "" {
 		systemPromptTemplate = req.SystemPromptTemplate
 	}
@@ -894,13 +1285,7 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 			actualBalance = 100.0
 		} else {
 			// 查找匹配的交易所配置
-			var exchangeCfg *config.ExchangeConfig
-			for _, ex := range exchanges {
-				if ex.ExchangeID == req.ExchangeID {
-					exchangeCfg = ex
-					break
-				}
-			}
+			exchangeCfg := findExchangeAccount(exchanges, req.ExchangeID)
 
 			if exchangeCfg == nil {
 				log.Printf("⚠️ 未找到交易所 %s 的配置，使用默认值 100 USDT", req.ExchangeID)
@@ -912,7 +1297,7 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 				// 🔧 计算Total Equity = Wallet Balance + Unrealized Profit
 				// 这是账户的真实净值，用作Initial Balance的基准
 				// 使用輔助函數查詢交易所余額
-				balance, queryErr := s.queryExchangeBalance(userID, req.ExchangeID, exchangeCfg)
+				balance, queryErr := s.queryExchangeBalance(userID, exchangeCfg)
 				if queryErr != nil {
 					log.Printf("⚠️ 查詢余額失敗，使用默認值 100 USDT: %v", queryErr)
 					actualBalance = 100.0
@@ -939,16 +1324,6 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		makerFeeRate = 0.0002 // Binance 标准 Maker 费率
 	}
 
-	// 添加费率范围验证
-	if takerFeeRate < 0 || takerFeeRate > 0.01 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Taker费率必须在0-1%之间"})
-		return
-	}
-	if makerFeeRate < 0 || makerFeeRate > 0.01 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Maker费率必须在0-1%之间"})
-		return
-	}
-
 	log.Printf("✓ 费率配置: Taker=%.4f (%.2f%%), Maker=%.4f (%.2f%%)",
 		takerFeeRate, takerFeeRate*100, makerFeeRate, makerFeeRate*100)
 
@@ -958,6 +1333,23 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		timeframes = "4h" // 默认只勾选4小时线
 	}
 
+	// 组合模式分组（空字符串表示独立决策）
+	portfolioGroup := strings.TrimSpace(req.PortfolioGroup)
+
+	// 以下配置已在 validateTraderSettings 中校验
+	maxTradesPerDay := req.MaxTradesPerDay
+	holdCachePct := req.HoldCachePct
+	maxExposureMultiple := req.MaxExposureMultiple
+	indicators, _ := normalizeIndicators(req.Indicators)
+	tags, _ := config.NormalizeTags(req.Tags)
+
+	// 交易时间窗口（默认全天、周末照常交易）
+	activeHours := strings.TrimSpace(req.ActiveHours)
+	weekendTrading := true
+	if req.WeekendTrading != nil {
+		weekendTrading = *req.WeekendTrading
+	}
+
 	// 设置订单策略默认值
 	orderStrategy := req.OrderStrategy
 	if orderStrategy == "" {
@@ -981,95 +1373,144 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	aiModels, err := s.database.GetAIModels(userID)
 	if err != nil {
 		log.Printf("❌ [DEBUG] 查询 AI 模型失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI模型配置失败"})
+		respondError(c, http.StatusInternalServerError, "GET_AI_MODELS_FAILED", err)
 		return
 	}
 	log.Printf("✅ [DEBUG] 找到 %d 个 AI 模型配置", len(aiModels))
 
-	var aiModelIntID int
-	var aiModelFound bool
-	for _, model := range aiModels {
-		log.Printf("🔍 [DEBUG] 检查 AI 模型: ID=%d, ModelID=%s (寻找: %s)", model.ID, model.ModelID, req.AIModelID)
-		if model.ModelID == req.AIModelID {
-			aiModelIntID = model.ID
-			aiModelFound = true
-			log.Printf("✅ [DEBUG] 找到匹配的 AI 模型: ID=%d", aiModelIntID)
-			break
-		}
-	}
-	if !aiModelFound {
+	// ai_model_id 可以是模型类型（默认账户）或配置自增ID（指定账户）
+	aiModelCfg := findAIModelAccount(aiModels, req.AIModelID)
+	if aiModelCfg == nil {
 		log.Printf("❌ [DEBUG] 未找到 AI 模型 '%s'，可用的模型：", req.AIModelID)
 		for _, model := range aiModels {
-			log.Printf("   - ModelID=%s", model.ModelID)
+			log.Printf("   - ID=%d, ModelID=%s, DisplayName=%s", model.ID, model.ModelID, model.DisplayName)
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("AI模型 %s 不存在", req.AIModelID)})
+		respondError(c, http.StatusBadRequest, "AI_MODEL_NOT_FOUND", req.AIModelID)
+		return
+	}
+
+	// 模型池（为空表示只使用 ai_model_id）
+	modelPool, err := normalizeModelPool(req.ModelPool, aiModels)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	modelPoolMode := req.ModelPoolMode
+	if modelPoolMode == "" {
+		modelPoolMode = trader.ModelPoolRoundRobin
+	}
+
+	// 备用模型（为空表示不启用）
+	fallbackModelID, ok := normalizeFallbackModel(req.FallbackAIModelID, aiModels)
+	if !ok {
+		respondError(c, http.StatusBadRequest, "FALLBACK_MODEL_NOT_FOUND", fallbackModelID)
 		return
 	}
 
+	// 交易员日志级别（为空表示使用全局级别，已在 validateTraderSettings 中校验）
+	logLevel, _ := normalizeLogLevel(req.LogLevel)
+
 	log.Printf("🔍 [DEBUG] 步骤8: 查询用户 %s 的交易所配置 (请求的交易所: %s)...", userID, req.ExchangeID)
 	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
 		log.Printf("❌ [DEBUG] 查询交易所失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易所配置失败"})
+		respondError(c, http.StatusInternalServerError, "GET_EXCHANGES_FAILED", err)
 		return
 	}
 	log.Printf("✅ [DEBUG] 找到 %d 个交易所配置", len(exchanges))
 
-	var exchangeIntID int
-	var exchangeFound bool
-	for _, exchange := range exchanges {
-		log.Printf("🔍 [DEBUG] 检查交易所: ID=%d, ExchangeID=%s (寻找: %s)", exchange.ID, exchange.ExchangeID, req.ExchangeID)
-		if exchange.ExchangeID == req.ExchangeID {
-			exchangeIntID = exchange.ID
-			exchangeFound = true
-			log.Printf("✅ [DEBUG] 找到匹配的交易所: ID=%d", exchangeIntID)
-			break
-		}
-	}
-	if !exchangeFound {
+	// exchange_id 可以是交易所类型（默认账户）或配置自增ID（指定账户，例如 Binance 子账户）
+	exchangeCfg := findExchangeAccount(exchanges, req.ExchangeID)
+	if exchangeCfg == nil {
 		log.Printf("❌ [DEBUG] 未找到交易所 '%s'，可用的交易所：", req.ExchangeID)
 		for _, exchange := range exchanges {
-			log.Printf("   - ExchangeID=%s", exchange.ExchangeID)
+			log.Printf("   - ID=%d, ExchangeID=%s, DisplayName=%s", exchange.ID, exchange.ExchangeID, exchange.DisplayName)
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易所 %s 不存在", req.ExchangeID)})
+		respondError(c, http.StatusBadRequest, "EXCHANGE_NOT_FOUND", req.ExchangeID)
 		return
 	}
+	aiModelIntID := aiModelCfg.ID
+	exchangeIntID := exchangeCfg.ID
+	log.Printf("✅ [DEBUG] 使用 AI 模型 ID=%d, 交易所 ID=%d", aiModelIntID, exchangeIntID)
+
+	// 生成交易员ID (使用 UUID 确保唯一性，解决 Issue #893)
+	// 保留前缀以便调试和日志追踪
+	traderID := fmt.Sprintf("%s_%s_%s", exchangeCfg.ExchangeID, aiModelCfg.ModelID, uuid.New().String())
 
 	// 创建交易员配置（数据库实体）
 	log.Printf("🔍 [DEBUG] 步骤9: 构建交易员配置对象...")
 	trader := &config.TraderRecord{
-		ID:                   traderID,
-		UserID:               userID,
-		Name:                 req.Name,
-		AIModelID:            aiModelIntID,  // 使用查询到的自增 ID
-		ExchangeID:           exchangeIntID, // 使用查询到的自增 ID
-		InitialBalance:       actualBalance, // 使用实际查询的余额
-		BTCETHLeverage:       btcEthLeverage,
-		AltcoinLeverage:      altcoinLeverage,
-		TradingSymbols:       req.TradingSymbols,
-		UseCoinPool:          req.UseCoinPool,
-		UseOITop:             req.UseOITop,
-		CustomPrompt:         req.CustomPrompt,
-		OverrideBasePrompt:   req.OverrideBasePrompt,
-		SystemPromptTemplate: systemPromptTemplate,
-		IsCrossMargin:        isCrossMargin,
-		ScanIntervalMinutes:  scanIntervalMinutes,
-		TakerFeeRate:         takerFeeRate,        // 添加 Taker 费率
-		MakerFeeRate:         makerFeeRate,        // 添加 Maker 费率
-		OrderStrategy:        orderStrategy,       // 添加订单策略
-		LimitPriceOffset:     limitPriceOffset,    // 添加限价偏移
-		LimitTimeoutSeconds:  limitTimeoutSeconds, // 添加限价超时
-		Timeframes:           timeframes,          // 添加时间线选择
-		IsRunning:            false,
+		ID:                     traderID,
+		UserID:                 userID,
+		Name:                   req.Name,
+		AIModelID:              aiModelIntID,  // 使用查询到的自增 ID
+		ExchangeID:             exchangeIntID, // 使用查询到的自增 ID
+		InitialBalance:         actualBalance, // 使用实际查询的余额
+		BTCETHLeverage:         btcEthLeverage,
+		AltcoinLeverage:        altcoinLeverage,
+		TradingSymbols:         req.TradingSymbols,
+		UseCoinPool:            req.UseCoinPool,
+		UseOITop:               req.UseOITop,
+		CustomPrompt:           req.CustomPrompt,
+		OverrideBasePrompt:     req.OverrideBasePrompt,
+		SystemPromptTemplate:   systemPromptTemplate,
+		IsCrossMargin:          isCrossMargin,
+		ScanIntervalMinutes:    scanIntervalMinutes,
+		TakerFeeRate:           takerFeeRate,               // 添加 Taker 费率
+		MakerFeeRate:           makerFeeRate,               // 添加 Maker 费率
+		OrderStrategy:          orderStrategy,              // 添加订单策略
+		LimitPriceOffset:       limitPriceOffset,           // 添加限价偏移
+		LimitTimeoutSeconds:    limitTimeoutSeconds,        // 添加限价超时
+		Timeframes:             timeframes,                 // 添加时间线选择
+		PortfolioGroup:         portfolioGroup,             // 组合模式分组
+		MaxTradesPerDay:        maxTradesPerDay,            // 每日开仓上限
+		ModelPool:              modelPool,                  // 模型池
+		ModelPoolMode:          modelPoolMode,              // 模型池选择方式
+		FallbackAIModelID:      fallbackModelID,            // 备用模型
+		LogLevel:               logLevel,                   // 日志级别
+		HoldCachePct:           holdCachePct,               // 持有决策缓存阈值
+		StartPriority:          req.StartPriority,          // 开机自动启动优先级
+		MaxExposureMultiple:    maxExposureMultiple,        // 总敞口倍数上限
+		RespectSignalBias:      req.RespectSignalBias,      // 遵循信号源方向
+		DryRun:                 req.DryRun,                 // 模拟运行
+		AlertDrawdownPct:       req.AlertDrawdownPct,       // 净值回撤预警
+		AlertDailyLossPct:      req.AlertDailyLossPct,      // 当日亏损预警
+		AIQualityWindow:        req.AIQualityWindow,        // AI质量检测窗口
+		AIQualityMaxFailurePct: req.AIQualityMaxFailurePct, // AI失败率阈值
+		AIQualityPauseMinutes:  req.AIQualityPauseMinutes,  // AI质量暂停冷却
+		MaxConsecutiveErrors:   req.MaxConsecutiveErrors,   // 连续失败自动暂停阈值
+		DailyReport:            req.DailyReport,            // 每日报告
+		UnfundedThreshold:      req.UnfundedThreshold,      // 未入金判定阈值
+		Tags:                   tags,                       // 标签
+		MaxAICallsPerDay:       req.MaxAICallsPerDay,       // 每日AI调用上限
+		RejectNonCandidates:    req.RejectNonCandidates,    // 仅交易候选币种
+		OpenVerifyDelayMs:      req.OpenVerifyDelayMs,      // 开仓确认延迟
+		ActiveHours:            activeHours,                // 交易时间窗口
+		WeekendTrading:         weekendTrading,             // 周末交易
+		FlattenOnWindowClose:   req.FlattenOnWindowClose,   // 窗口外平仓
+		MaxPositions:           req.MaxPositions,           // 持仓数量上限
+		MaxPositionSizeUSD:     req.MaxPositionSizeUSD,     // 单笔仓位上限
+		BlacklistedSymbols:     blacklistedSymbols,         // 币种黑名单
+		KlineLimit:             req.KlineLimit,             // 每个时间线的K线数量
+		Indicators:             indicators,                 // 行情指标
+		MaxDailyLoss:           req.MaxDailyLoss,           // 交易员级最大日亏损
+		MaxDrawdown:            req.MaxDrawdown,            // 交易员级最大回撤
+		StopTradingMinutes:     req.StopTradingMinutes,     // 交易员级风控暂停时长
+		IsRunning:              false,
 	}
 	log.Printf("✅ [DEBUG] 交易员配置对象已构建: ID=%s, AIModelID=%d, ExchangeID=%d", traderID, aiModelIntID, exchangeIntID)
 
 	// 保存到数据库
 	log.Printf("🔍 [DEBUG] 步骤10: 保存交易员到数据库...")
 	err = s.database.CreateTrader(trader)
+	if errors.Is(err, config.ErrTraderNameTaken) {
+		// 并发创建同名交易员时由数据库唯一索引兜底
+		respondTraderNameTaken(c, req.Name)
+		return
+	}
 	if err != nil {
 		log.Printf("❌ [DEBUG] 数据库 CreateTrader 失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建交易员失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, "CREATE_TRADER_FAILED", err)
 		return
 	}
 	log.Printf("✅ [DEBUG] 交易员已成功保存到数据库")
@@ -1081,38 +1522,221 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		// 这里不返回错误，因为交易员已经成功创建到数据库
 	}
 
+	s.recordAudit(c, userID, auditTraderCreate, auditResourceTrader, traderID, map[string]interface{}{
+		"name":        req.Name,
+		"ai_model_id": req.AIModelID,
+		"exchange_id": req.ExchangeID,
+	})
 	log.Printf("✓ 创建交易员成功: %s (模型: %s, 交易所: %s)", req.Name, req.AIModelID, req.ExchangeID)
 
 	c.JSON(http.StatusCreated, gin.H{
-		"trader_id":   traderID,
-		"trader_name": req.Name,
-		"ai_model":    req.AIModelID,
-		"is_running":  false,
+		"trader_id":             traderID,
+		"trader_name":           req.Name,
+		"ai_model":              aiModelCfg.ModelID,
+		"ai_model_account_id":   aiModelCfg.ID,
+		"exchange_id":           exchangeCfg.ExchangeID,
+		"exchange_account_id":   exchangeCfg.ID,
+		"exchange_display_name": exchangeCfg.DisplayName,
+		"is_running":            false,
 	})
 }
 
 // UpdateTraderRequest 更新交易员请求
 type UpdateTraderRequest struct {
-	Name                 string  `json:"name" binding:"required"`
-	AIModelID            string  `json:"ai_model_id" binding:"required"`
-	ExchangeID           string  `json:"exchange_id" binding:"required"`
-	InitialBalance       float64 `json:"initial_balance"`
-	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
-	BTCETHLeverage       int     `json:"btc_eth_leverage"`
-	AltcoinLeverage      int     `json:"altcoin_leverage"`
-	TradingSymbols       string  `json:"trading_symbols"`
-	CustomPrompt         string  `json:"custom_prompt"`
-	OverrideBasePrompt   bool    `json:"override_base_prompt"`
-	SystemPromptTemplate string  `json:"system_prompt_template"`
-	IsCrossMargin        *bool   `json:"is_cross_margin"`
-	UseCoinPool          *bool   `json:"use_coin_pool"`
-	UseOITop             *bool   `json:"use_oi_top"`
-	TakerFeeRate         float64 `json:"taker_fee_rate"`        // Taker fee rate
-	MakerFeeRate         float64 `json:"maker_fee_rate"`        // Maker fee rate
-	OrderStrategy        string  `json:"order_strategy"`        // Order strategy
-	LimitPriceOffset     float64 `json:"limit_price_offset"`    // Limit price offset
-	LimitTimeoutSeconds  int     `json:"limit_timeout_seconds"` // Limit timeout in seconds
-	Timeframes           string  `json:"timeframes"`            // Timeframes selection
+	Name                   string   `json:"name" binding:"required"`
+	AIModelID              string   `json:"ai_model_id" binding:"required"`
+	ExchangeID             string   `json:"exchange_id" binding:"required"`
+	InitialBalance         float64  `json:"initial_balance"`
+	ScanIntervalMinutes    int      `json:"scan_interval_minutes"`
+	BTCETHLeverage         int      `json:"btc_eth_leverage"`
+	AltcoinLeverage        int      `json:"altcoin_leverage"`
+	TradingSymbols         string   `json:"trading_symbols"`
+	CustomPrompt           string   `json:"custom_prompt"`
+	OverrideBasePrompt     bool     `json:"override_base_prompt"`
+	SystemPromptTemplate   string   `json:"system_prompt_template"`
+	IsCrossMargin          *bool    `json:"is_cross_margin"`
+	UseCoinPool            *bool    `json:"use_coin_pool"`
+	UseOITop               *bool    `json:"use_oi_top"`
+	TakerFeeRate           float64  `json:"taker_fee_rate"`             // Taker fee rate
+	MakerFeeRate           float64  `json:"maker_fee_rate"`             // Maker fee rate
+	OrderStrategy          string   `json:"order_strategy"`             // Order strategy
+	LimitPriceOffset       float64  `json:"limit_price_offset"`         // Limit price offset
+	LimitTimeoutSeconds    int      `json:"limit_timeout_seconds"`      // Limit timeout in seconds
+	Timeframes             string   `json:"timeframes"`                 // Timeframes selection
+	PortfolioGroup         *string  `json:"portfolio_group"`            // 组合模式分组名称，nil表示保持原值
+	MaxTradesPerDay        *int     `json:"max_trades_per_day"`         // 每日最多开仓次数，nil表示保持原值
+	ModelPool              *string  `json:"model_pool"`                 // 模型池，nil表示保持原值，传空字符串表示关闭模型池
+	ModelPoolMode          *string  `json:"model_pool_mode"`            // 模型池选择方式，nil表示保持原值
+	FallbackAIModelID      *string  `json:"fallback_ai_model_id"`       // 备用AI模型ID，nil表示保持原值，传空字符串表示关闭备用模型
+	LogLevel               *string  `json:"log_level"`                  // 交易员日志级别，nil表示保持原值，传空字符串表示使用全局级别
+	HoldCachePct           *float64 `json:"hold_cache_pct"`             // 持有决策缓存阈值，nil表示保持原值
+	StartPriority          *int     `json:"start_priority"`             // 开机自动启动优先级，nil表示保持原值
+	MaxExposureMultiple    *float64 `json:"max_exposure_multiple"`      // 最大总敞口倍数，nil表示保持原值
+	RespectSignalBias      *bool    `json:"respect_signal_bias"`        // 是否遵循信号源方向，nil表示保持原值
+	DryRun                 *bool    `json:"dry_run"`                    // 是否模拟运行（不实际下单），nil表示保持原值
+	AlertDrawdownPct       *float64 `json:"alert_drawdown_pct"`         // 净值回撤预警阈值，nil表示保持原值
+	AlertDailyLossPct      *float64 `json:"alert_daily_loss_pct"`       // 当日亏损预警阈值，nil表示保持原值
+	AIQualityWindow        *int     `json:"ai_quality_window"`          // AI质量检测窗口，nil表示保持原值
+	AIQualityMaxFailurePct *float64 `json:"ai_quality_max_failure_pct"` // AI调用失败率暂停阈值，nil表示保持原值
+	AIQualityPauseMinutes  *int     `json:"ai_quality_pause_minutes"`   // AI质量暂停冷却时长，nil表示保持原值
+	MaxConsecutiveErrors   *int     `json:"max_consecutive_errors"`     // 连续失败自动暂停阈值，nil表示保持原值
+	DailyReport            *bool    `json:"daily_report"`               // 是否生成每日报告，nil表示保持原值
+	UnfundedThreshold      *float64 `json:"unfunded_threshold"`         // 未入金判定阈值，nil表示保持原值
+	Tags                   *string  `json:"tags"`                       // 标签，nil表示保持原值，传空字符串表示清空
+	MaxAICallsPerDay       *int     `json:"max_ai_calls_per_day"`       // 每日AI调用上限
+	RejectNonCandidates    *bool    `json:"reject_non_candidates"`      // 是否仅允许对候选币种开仓，nil表示保持原值
+	OpenVerifyDelayMs      *int     `json:"open_verify_delay_ms"`       // 开仓确认延迟毫秒，nil表示保持原值
+	ActiveHours            *string  `json:"active_hours"`               // 交易时间窗口，nil表示保持原值
+	WeekendTrading         *bool    `json:"weekend_trading"`            // 周末是否交易，nil表示保持原值
+	FlattenOnWindowClose   *bool    `json:"flatten_on_window_close"`    // 交易窗口外平仓，nil表示保持原值
+	MaxPositions           *int     `json:"max_positions"`              // 最多同时持仓数量，nil表示保持原值
+	MaxPositionSizeUSD     *float64 `json:"max_position_size_usd"`      // 单笔开仓最大名义价值，nil表示保持原值
+	BlacklistedSymbols     *string  `json:"blacklisted_symbols"`        // 禁止开仓的币种，nil表示保持原值，空字符串表示清空
+	KlineLimit             *int     `json:"kline_limit"`                // 每个时间线的K线数量，nil表示保持原值
+	Indicators             *string  `json:"indicators"`                 // 行情指标，nil表示保持原值，空字符串表示恢复默认
+	// 交易员级风控阈值，nil表示保持原值，传负数表示恢复使用系统配置
+	MaxDailyLoss       *float64 `json:"max_daily_loss"`
+	MaxDrawdown        *float64 `json:"max_drawdown"`
+	StopTradingMinutes *int     `json:"stop_trading_minutes"`
+}
+
+// validateSymbolList 校验逗号分隔的币种列表格式（每个币种必须以USDT结尾）
+func validateSymbolList(list string) error {
+	for _, symbol := range strings.Split(list, ",") {
+		symbol = strings.TrimSpace(symbol)
+		if symbol != "" && !strings.HasSuffix(strings.ToUpper(symbol), "USDT") {
+			return fmt.Errorf("无效的币种格式: %s，必须以USDT结尾", symbol)
+		}
+	}
+	return nil
+}
+
+// validEquityAlertPct 净值预警阈值是否合法（0=不启用，百分比不超过100）
+func validEquityAlertPct(pct float64) bool {
+	return pct >= 0 && pct <= 100
+}
+
+// validateAIQualityConfig 校验AI输出质量检测配置：窗口 0-100 次，失败率阈值 0-100%，冷却时长不超过一天
+func validateAIQualityConfig(window int, maxFailurePct float64, pauseMinutes int) error {
+	if window < 0 || window > 100 {
+		return fmt.Errorf("AI质量检测窗口必须在 0-100 次之间")
+	}
+	if maxFailurePct < 0 || maxFailurePct > 100 {
+		return fmt.Errorf("AI调用失败率阈值必须在 0-100%% 之间")
+	}
+	if pauseMinutes < 0 || pauseMinutes > 1440 {
+		return fmt.Errorf("AI质量暂停冷却时长必须在 0-1440 分钟之间")
+	}
+	return nil
+}
+
+// validateKlineLimit 校验每个时间线提供给AI的K线数量：0（默认行情格式）或 1-MaxKlineLimit
+func validateKlineLimit(limit int) error {
+	if limit < 0 || limit > market.MaxKlineLimit {
+		return fmt.Errorf("K线数量必须在 0-%d 之间", market.MaxKlineLimit)
+	}
+	return nil
+}
+
+// normalizeIndicators 校验指标列表并返回标准化的逗号分隔写法
+func normalizeIndicators(raw string) (string, error) {
+	indicators, err := market.ParseIndicators(raw)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(indicators, ","), nil
+}
+
+// validateOpenVerifyDelay 校验开仓确认延迟：0-10000 毫秒（延迟会阻塞本周期后续决策的执行）
+func validateOpenVerifyDelay(delayMs int) error {
+	if delayMs < 0 || delayMs > 10000 {
+		return fmt.Errorf("开仓确认延迟必须在 0-10000 毫秒之间")
+	}
+	return nil
+}
+
+// validateMaxConsecutiveErrors 校验连续失败自动暂停阈值：0（不暂停）或 1-1000 个决策周期
+func validateMaxConsecutiveErrors(limit int) error {
+	if limit < 0 || limit > 1000 {
+		return fmt.Errorf("连续失败自动暂停阈值必须在 0-1000 之间")
+	}
+	return nil
+}
+
+// validateRiskLimitOverrides 校验交易员级风控阈值（nil=使用系统配置）
+func validateRiskLimitOverrides(maxDailyLoss, maxDrawdown *float64, stopTradingMinutes *int) error {
+	if maxDailyLoss != nil && (*maxDailyLoss < 0 || *maxDailyLoss > 100) {
+		return fmt.Errorf("最大日亏损必须在 0-100%% 之间")
+	}
+	if maxDrawdown != nil && (*maxDrawdown < 0 || *maxDrawdown > 100) {
+		return fmt.Errorf("最大回撤必须在 0-100%% 之间")
+	}
+	if stopTradingMinutes != nil && (*stopTradingMinutes < 0 || *stopTradingMinutes > 7*24*60) {
+		return fmt.Errorf("风控暂停时长必须在 0-10080 分钟之间")
+	}
+	return nil
+}
+
+// mergeRiskLimitOverride 合并更新请求中的交易员级风控阈值：nil 保持原值，负数恢复使用系统配置（返回 nil）
+func mergeRiskLimitOverride[T float64 | int](current, requested *T) *T {
+	if requested == nil {
+		return current
+	}
+	if *requested < 0 {
+		return nil
+	}
+	return requested
+}
+
+// tradingWindowDesc 数据库中交易时间窗口配置的描述（与运行中交易员的 trading_window 对比）
+func tradingWindowDesc(record *config.TraderRecord) string {
+	window, err := trader.ParseTradingWindow(record.ActiveHours, record.WeekendTrading)
+	if err != nil {
+		return "全天"
+	}
+	return window.String()
+}
+
+// normalizeModelPool 校验模型池中的模型都已配置，返回去重后逗号分隔的模型ID
+func normalizeModelPool(raw string, aiModels []*config.AIModelConfig) (string, error) {
+	ids := trader.ParseModelPool(raw)
+	for _, id := range ids {
+		found := false
+		for _, model := range aiModels {
+			if model.ModelID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("模型池中的AI模型 %s 不存在", id)
+		}
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// normalizeFallbackModel 校验备用模型已配置，返回去掉空白的模型ID（空字符串表示不启用备用模型）
+func normalizeFallbackModel(raw string, aiModels []*config.AIModelConfig) (string, bool) {
+	id := strings.TrimSpace(raw)
+	if id == "" {
+		return "", true
+	}
+	for _, model := range aiModels {
+		if model.ModelID == id {
+			return id, true
+		}
+	}
+	return id, false
+}
+
+// normalizeLogLevel 校验交易员日志级别，返回小写的级别（空字符串表示使用全局级别）
+func normalizeLogLevel(raw string) (string, bool) {
+	level := strings.ToLower(strings.TrimSpace(raw))
+	switch level {
+	case "", "debug", "info", "warn", "error":
+		return level, true
+	}
+	return level, false
 }
 
 // handleUpdateTrader 更新交易员配置
@@ -1128,14 +1752,14 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 
 	var req UpdateTraderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
 	// 检查交易员是否存在且属于当前用户
 	traders, err := s.database.GetTraders(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易员列表失败"})
+		respondError(c, http.StatusInternalServerError, "LIST_TRADERS_FAILED", err)
 		return
 	}
 
@@ -1148,7 +1772,7 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 	}
 
 	if existingTrader == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
@@ -1215,11 +1839,11 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 
 	// 验证费率范围
 	if takerFeeRate < 0 || takerFeeRate > 0.01 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Taker费率必须在0-1%之间"})
+		respondError(c, http.StatusBadRequest, "TAKER_FEE_OUT_OF_RANGE")
 		return
 	}
 	if makerFeeRate < 0 || makerFeeRate > 0.01 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Maker费率必须在0-1%之间"})
+		respondError(c, http.StatusBadRequest, "MAKER_FEE_OUT_OF_RANGE")
 		return
 	}
 
@@ -1270,91 +1894,372 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		}
 	}
 
-	// 查询 AI Model 和 Exchange 的自增 ID
-	aiModels, err := s.database.GetAIModels(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI模型配置失败"})
-		return
+	// 设置组合模式分组，未提供则保持原值，传空字符串表示退出组合
+	portfolioGroup := existingTrader.PortfolioGroup
+	if req.PortfolioGroup != nil {
+		portfolioGroup = strings.TrimSpace(*req.PortfolioGroup)
 	}
 
-	var aiModelIntID int
-	var aiModelFound bool
-	for _, model := range aiModels {
-		if model.ModelID == req.AIModelID {
-			aiModelIntID = model.ID
-			aiModelFound = true
-			break
+	// 设置每日开仓上限，未提供则保持原值
+	maxTradesPerDay := existingTrader.MaxTradesPerDay
+	if req.MaxTradesPerDay != nil {
+		if *req.MaxTradesPerDay < 0 {
+			respondError(c, http.StatusBadRequest, "MAX_DAILY_TRADES_NEGATIVE")
+			return
 		}
-	}
-	if !aiModelFound {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("AI模型 %s 不存在", req.AIModelID)})
-		return
+		maxTradesPerDay = *req.MaxTradesPerDay
 	}
 
-	exchanges, err := s.database.GetExchanges(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易所配置失败"})
-		return
+	// 设置开机自动启动优先级，未提供则保持原值
+	startPriority := existingTrader.StartPriority
+	if req.StartPriority != nil {
+		startPriority = *req.StartPriority
 	}
 
-	var exchangeIntID int
-	var exchangeFound bool
-	for _, exchange := range exchanges {
-		if exchange.ExchangeID == req.ExchangeID {
-			exchangeIntID = exchange.ID
-			exchangeFound = true
-			break
+	// 设置持有决策缓存阈值，未提供则保持原值
+	holdCachePct := existingTrader.HoldCachePct
+	if req.HoldCachePct != nil {
+		if *req.HoldCachePct < 0 || *req.HoldCachePct > trader.MaxHoldCachePct {
+			respondError(c, http.StatusBadRequest, "HOLD_CACHE_PCT_OUT_OF_RANGE", trader.MaxHoldCachePct)
+			return
 		}
+		holdCachePct = *req.HoldCachePct
 	}
-	if !exchangeFound {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易所 %s 不存在", req.ExchangeID)})
-		return
+
+	maxExposureMultiple := existingTrader.MaxExposureMultiple
+	if req.MaxExposureMultiple != nil {
+		if *req.MaxExposureMultiple < 0 || *req.MaxExposureMultiple > trader.MaxExposureMultipleLimit {
+			respondError(c, http.StatusBadRequest, "EXPOSURE_MULTIPLE_OUT_OF_RANGE", trader.MaxExposureMultipleLimit)
+			return
+		}
+		maxExposureMultiple = *req.MaxExposureMultiple
 	}
 
-	// 更新交易员配置
-	trader := &config.TraderRecord{
-		ID:                   traderID,
-		UserID:               userID,
-		Name:                 req.Name,
-		AIModelID:            aiModelIntID,  // 使用查询到的自增 ID
-		ExchangeID:           exchangeIntID, // 使用查询到的自增 ID
-		InitialBalance:       req.InitialBalance,
-		BTCETHLeverage:       btcEthLeverage,
-		AltcoinLeverage:      altcoinLeverage,
-		TradingSymbols:       req.TradingSymbols,
-		UseCoinPool:          useCoinPool,
-		UseOITop:             useOITop,
-		CustomPrompt:         req.CustomPrompt,
-		OverrideBasePrompt:   req.OverrideBasePrompt,
-		SystemPromptTemplate: systemPromptTemplate,
-		IsCrossMargin:        isCrossMargin,
-		ScanIntervalMinutes:  scanIntervalMinutes,
-		TakerFeeRate:         takerFeeRate,             // 添加 Taker 费率
-		MakerFeeRate:         makerFeeRate,             // 添加 Maker 费率
-		OrderStrategy:        orderStrategy,            // 添加订单策略
-		LimitPriceOffset:     limitPriceOffset,         // 添加限价偏移
-		LimitTimeoutSeconds:  limitTimeoutSeconds,      // 添加限价超时
-		Timeframes:           timeframes,               // 添加时间线选择
-		IsRunning:            existingTrader.IsRunning, // 保持原值
+	respectSignalBias := existingTrader.RespectSignalBias
+	if req.RespectSignalBias != nil {
+		respectSignalBias = *req.RespectSignalBias
+	}
+	dryRun := existingTrader.DryRun
+	if req.DryRun != nil {
+		dryRun = *req.DryRun
 	}
 
-	// 更新数据库
-	err = s.database.UpdateTrader(trader)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易员失败: %v", err)})
+	alertDrawdownPct := existingTrader.AlertDrawdownPct
+	if req.AlertDrawdownPct != nil {
+		alertDrawdownPct = *req.AlertDrawdownPct
+	}
+	alertDailyLossPct := existingTrader.AlertDailyLossPct
+	if req.AlertDailyLossPct != nil {
+		alertDailyLossPct = *req.AlertDailyLossPct
+	}
+	if !validEquityAlertPct(alertDrawdownPct) || !validEquityAlertPct(alertDailyLossPct) {
+		respondError(c, http.StatusBadRequest, "EQUITY_ALERT_PCT_OUT_OF_RANGE")
 		return
 	}
 
-	// 如果请求中包含initial_balance且与现有值不同，单独更新它
-	// UpdateTrader不会更新initial_balance，需要使用专门的方法
-	if req.InitialBalance > 0 && math.Abs(req.InitialBalance-existingTrader.InitialBalance) > 0.1 {
-		err = s.database.UpdateTraderInitialBalance(userID, traderID, req.InitialBalance)
-		if err != nil {
-			log.Printf("⚠️ 更新初始余额失败: %v", err)
-			// 不返回错误，因为主要配置已更新成功
-		} else {
-			log.Printf("✓ 初始余额已更新: %.2f -> %.2f", existingTrader.InitialBalance, req.InitialBalance)
-		}
+	aiQualityWindow := existingTrader.AIQualityWindow
+	if req.AIQualityWindow != nil {
+		aiQualityWindow = *req.AIQualityWindow
+	}
+	aiQualityMaxFailurePct := existingTrader.AIQualityMaxFailurePct
+	if req.AIQualityMaxFailurePct != nil {
+		aiQualityMaxFailurePct = *req.AIQualityMaxFailurePct
+	}
+	aiQualityPauseMinutes := existingTrader.AIQualityPauseMinutes
+	if req.AIQualityPauseMinutes != nil {
+		aiQualityPauseMinutes = *req.AIQualityPauseMinutes
+	}
+	if err := validateAIQualityConfig(aiQualityWindow, aiQualityMaxFailurePct, aiQualityPauseMinutes); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	maxConsecutiveErrors := existingTrader.MaxConsecutiveErrors
+	if req.MaxConsecutiveErrors != nil {
+		if err := validateMaxConsecutiveErrors(*req.MaxConsecutiveErrors); err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+			return
+		}
+		maxConsecutiveErrors = *req.MaxConsecutiveErrors
+	}
+
+	dailyReport := existingTrader.DailyReport
+	if req.DailyReport != nil {
+		dailyReport = *req.DailyReport
+	}
+
+	unfundedThreshold := existingTrader.UnfundedThreshold
+	if req.UnfundedThreshold != nil {
+		if *req.UnfundedThreshold < 0 {
+			respondError(c, http.StatusBadRequest, "UNFUNDED_THRESHOLD_NEGATIVE")
+			return
+		}
+		unfundedThreshold = *req.UnfundedThreshold
+	}
+
+	maxAICallsPerDay := existingTrader.MaxAICallsPerDay
+	if req.MaxAICallsPerDay != nil {
+		if *req.MaxAICallsPerDay < 0 {
+			respondError(c, http.StatusBadRequest, "DAILY_AI_CALL_LIMIT_NEGATIVE")
+			return
+		}
+		maxAICallsPerDay = *req.MaxAICallsPerDay
+	}
+
+	rejectNonCandidates := existingTrader.RejectNonCandidates
+	if req.RejectNonCandidates != nil {
+		rejectNonCandidates = *req.RejectNonCandidates
+	}
+
+	openVerifyDelayMs := existingTrader.OpenVerifyDelayMs
+	if req.OpenVerifyDelayMs != nil {
+		if err := validateOpenVerifyDelay(*req.OpenVerifyDelayMs); err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+			return
+		}
+		openVerifyDelayMs = *req.OpenVerifyDelayMs
+	}
+
+	// 行情上下文，未提供则保持原值
+	klineLimit := existingTrader.KlineLimit
+	if req.KlineLimit != nil {
+		if err := validateKlineLimit(*req.KlineLimit); err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+			return
+		}
+		klineLimit = *req.KlineLimit
+	}
+	indicators := existingTrader.Indicators
+	if req.Indicators != nil {
+		if indicators, err = normalizeIndicators(*req.Indicators); err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+			return
+		}
+	}
+
+	// 交易时间窗口，未提供的字段保持原值
+	activeHours := existingTrader.ActiveHours
+	if req.ActiveHours != nil {
+		activeHours = strings.TrimSpace(*req.ActiveHours)
+	}
+	weekendTrading := existingTrader.WeekendTrading
+	if req.WeekendTrading != nil {
+		weekendTrading = *req.WeekendTrading
+	}
+	if _, err := trader.ParseTradingWindow(activeHours, weekendTrading); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	flattenOnWindowClose := existingTrader.FlattenOnWindowClose
+	if req.FlattenOnWindowClose != nil {
+		flattenOnWindowClose = *req.FlattenOnWindowClose
+	}
+
+	// 仓位上限，未提供则保持原值（0=不限制）
+	maxPositions := existingTrader.MaxPositions
+	if req.MaxPositions != nil {
+		if *req.MaxPositions < 0 {
+			respondError(c, http.StatusBadRequest, "MAX_POSITIONS_NEGATIVE")
+			return
+		}
+		maxPositions = *req.MaxPositions
+	}
+	maxPositionSizeUSD := existingTrader.MaxPositionSizeUSD
+	if req.MaxPositionSizeUSD != nil {
+		if *req.MaxPositionSizeUSD < 0 {
+			respondError(c, http.StatusBadRequest, "MAX_POSITION_SIZE_NEGATIVE")
+			return
+		}
+		maxPositionSizeUSD = *req.MaxPositionSizeUSD
+	}
+
+	// 交易员级风控阈值，未提供则保持原值，传负数表示恢复使用系统配置
+	maxDailyLoss := mergeRiskLimitOverride(existingTrader.MaxDailyLoss, req.MaxDailyLoss)
+	maxDrawdown := mergeRiskLimitOverride(existingTrader.MaxDrawdown, req.MaxDrawdown)
+	stopTradingMinutes := mergeRiskLimitOverride(existingTrader.StopTradingMinutes, req.StopTradingMinutes)
+	if err := validateRiskLimitOverrides(maxDailyLoss, maxDrawdown, stopTradingMinutes); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	// 币种黑名单，未提供则保持原值，传空字符串表示清空
+	blacklistedSymbols := existingTrader.BlacklistedSymbols
+	if req.BlacklistedSymbols != nil {
+		if err := validateSymbolList(*req.BlacklistedSymbols); err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+			return
+		}
+		blacklistedSymbols = strings.Join(trader.ParseSymbolList(*req.BlacklistedSymbols), ",")
+	}
+
+	// 设置标签，未提供则保持原值，传空字符串表示清空
+	tags := existingTrader.Tags
+	if req.Tags != nil {
+		normalized, err := config.NormalizeTags(*req.Tags)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+			return
+		}
+		tags = normalized
+	}
+
+	// 查询 AI Model 和 Exchange 的自增 ID
+	aiModels, err := s.database.GetAIModels(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_AI_MODELS_FAILED", err)
+		return
+	}
+
+	// 按类型引用且与当前账户类型相同时保持交易员当前使用的账户，避免被切换到默认账户
+	aiModelCfg := findAIModelAccount(aiModels, strconv.Itoa(existingTrader.AIModelID))
+	if aiModelCfg == nil || aiModelCfg.ModelID != req.AIModelID {
+		aiModelCfg = findAIModelAccount(aiModels, req.AIModelID)
+	}
+	if aiModelCfg == nil {
+		respondError(c, http.StatusBadRequest, "AI_MODEL_NOT_FOUND", req.AIModelID)
+		return
+	}
+	aiModelIntID := aiModelCfg.ID
+
+	// 设置模型池，未提供则保持原值，传空字符串表示关闭模型池
+	modelPool := existingTrader.ModelPool
+	if req.ModelPool != nil {
+		modelPool, err = normalizeModelPool(*req.ModelPool, aiModels)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+			return
+		}
+	}
+	modelPoolMode := existingTrader.ModelPoolMode
+	if req.ModelPoolMode != nil {
+		modelPoolMode = *req.ModelPoolMode
+		if modelPoolMode == "" {
+			modelPoolMode = trader.ModelPoolRoundRobin
+		}
+		if !trader.IsValidModelPoolMode(modelPoolMode) {
+			respondError(c, http.StatusBadRequest, "MODEL_POOL_STRATEGY_INVALID")
+			return
+		}
+	}
+
+	// 设置备用模型，未提供则保持原值，传空字符串表示关闭备用模型
+	fallbackModelID := existingTrader.FallbackAIModelID
+	if req.FallbackAIModelID != nil {
+		var ok bool
+		if fallbackModelID, ok = normalizeFallbackModel(*req.FallbackAIModelID, aiModels); !ok {
+			respondError(c, http.StatusBadRequest, "FALLBACK_MODEL_NOT_FOUND", fallbackModelID)
+			return
+		}
+	}
+
+	// 设置日志级别，未提供则保持原值，传空字符串表示使用全局级别
+	logLevel := existingTrader.LogLevel
+	if req.LogLevel != nil {
+		var ok bool
+		if logLevel, ok = normalizeLogLevel(*req.LogLevel); !ok {
+			respondError(c, http.StatusBadRequest, "LOG_LEVEL_INVALID", logLevel)
+			return
+		}
+	}
+
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_EXCHANGES_FAILED", err)
+		return
+	}
+
+	exchangeCfg := findExchangeAccount(exchanges, strconv.Itoa(existingTrader.ExchangeID))
+	if exchangeCfg == nil || exchangeCfg.ExchangeID != req.ExchangeID {
+		exchangeCfg = findExchangeAccount(exchanges, req.ExchangeID)
+	}
+	if exchangeCfg == nil {
+		respondError(c, http.StatusBadRequest, "EXCHANGE_NOT_FOUND", req.ExchangeID)
+		return
+	}
+	exchangeIntID := exchangeCfg.ID
+
+	// 更新交易员配置
+	trader := &config.TraderRecord{
+		ID:                     traderID,
+		UserID:                 userID,
+		Name:                   req.Name,
+		AIModelID:              aiModelIntID,  // 使用查询到的自增 ID
+		ExchangeID:             exchangeIntID, // 使用查询到的自增 ID
+		InitialBalance:         req.InitialBalance,
+		BTCETHLeverage:         btcEthLeverage,
+		AltcoinLeverage:        altcoinLeverage,
+		TradingSymbols:         req.TradingSymbols,
+		UseCoinPool:            useCoinPool,
+		UseOITop:               useOITop,
+		CustomPrompt:           req.CustomPrompt,
+		OverrideBasePrompt:     req.OverrideBasePrompt,
+		SystemPromptTemplate:   systemPromptTemplate,
+		IsCrossMargin:          isCrossMargin,
+		ScanIntervalMinutes:    scanIntervalMinutes,
+		TakerFeeRate:           takerFeeRate,             // 添加 Taker 费率
+		MakerFeeRate:           makerFeeRate,             // 添加 Maker 费率
+		OrderStrategy:          orderStrategy,            // 添加订单策略
+		LimitPriceOffset:       limitPriceOffset,         // 添加限价偏移
+		LimitTimeoutSeconds:    limitTimeoutSeconds,      // 添加限价超时
+		Timeframes:             timeframes,               // 添加时间线选择
+		PortfolioGroup:         portfolioGroup,           // 组合模式分组
+		MaxTradesPerDay:        maxTradesPerDay,          // 每日开仓上限
+		ModelPool:              modelPool,                // 模型池
+		ModelPoolMode:          modelPoolMode,            // 模型池选择方式
+		FallbackAIModelID:      fallbackModelID,          // 备用模型
+		LogLevel:               logLevel,                 // 日志级别
+		HoldCachePct:           holdCachePct,             // 持有决策缓存阈值
+		StartPriority:          startPriority,            // 开机自动启动优先级
+		MaxExposureMultiple:    maxExposureMultiple,      // 总敞口倍数上限
+		RespectSignalBias:      respectSignalBias,        // 遵循信号源方向
+		DryRun:                 dryRun,                   // 模拟运行
+		AlertDrawdownPct:       alertDrawdownPct,         // 净值回撤预警
+		AlertDailyLossPct:      alertDailyLossPct,        // 当日亏损预警
+		AIQualityWindow:        aiQualityWindow,          // AI质量检测窗口
+		AIQualityMaxFailurePct: aiQualityMaxFailurePct,   // AI失败率阈值
+		AIQualityPauseMinutes:  aiQualityPauseMinutes,    // AI质量暂停冷却
+		MaxConsecutiveErrors:   maxConsecutiveErrors,     // 连续失败自动暂停阈值
+		DailyReport:            dailyReport,              // 每日报告
+		UnfundedThreshold:      unfundedThreshold,        // 未入金判定阈值
+		Tags:                   tags,                     // 标签
+		MaxAICallsPerDay:       maxAICallsPerDay,         // 每日AI调用上限
+		RejectNonCandidates:    rejectNonCandidates,      // 仅交易候选币种
+		OpenVerifyDelayMs:      openVerifyDelayMs,        // 开仓确认延迟
+		ActiveHours:            activeHours,              // 交易时间窗口
+		WeekendTrading:         weekendTrading,           // 周末交易
+		FlattenOnWindowClose:   flattenOnWindowClose,     // 窗口外平仓
+		MaxPositions:           maxPositions,             // 持仓数量上限
+		MaxPositionSizeUSD:     maxPositionSizeUSD,       // 单笔仓位上限
+		BlacklistedSymbols:     blacklistedSymbols,       // 币种黑名单
+		KlineLimit:             klineLimit,               // 每个时间线的K线数量
+		Indicators:             indicators,               // 行情指标
+		MaxDailyLoss:           maxDailyLoss,             // 交易员级最大日亏损
+		MaxDrawdown:            maxDrawdown,              // 交易员级最大回撤
+		StopTradingMinutes:     stopTradingMinutes,       // 交易员级风控暂停时长
+		IsRunning:              existingTrader.IsRunning, // 保持原值
+	}
+
+	// 更新数据库
+	err = s.database.UpdateTrader(trader)
+	if errors.Is(err, config.ErrTraderNameTaken) {
+		respondTraderNameTaken(c, req.Name)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "UPDATE_TRADER_FAILED", err)
+		return
+	}
+
+	// 如果请求中包含initial_balance且与现有值不同，单独更新它
+	// UpdateTrader不会更新initial_balance，需要使用专门的方法
+	if req.InitialBalance > 0 && math.Abs(req.InitialBalance-existingTrader.InitialBalance) > 0.1 {
+		err = s.database.UpdateTraderInitialBalance(userID, traderID, req.InitialBalance)
+		if err != nil {
+			log.Printf("⚠️ 更新初始余额失败: %v", err)
+			// 不返回错误，因为主要配置已更新成功
+		} else {
+			log.Printf("✓ 初始余额已更新: %.2f -> %.2f", existingTrader.InitialBalance, req.InitialBalance)
+		}
 	}
 
 	// 🔄 从内存中移除旧的trader实例，以便重新加载最新配置
@@ -1366,6 +2271,13 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		log.Printf("⚠️ 重新加载交易员到内存失败: %v", err)
 	}
 
+	changed := make([]string, 0)
+	if updated, _, _, err := s.database.GetTraderConfig(userID, traderID); err == nil {
+		changed = changedFields(existingTrader, updated)
+	}
+	s.recordAudit(c, userID, auditTraderUpdate, auditResourceTrader, traderID, map[string]interface{}{
+		"changed_fields": changed,
+	})
 	log.Printf("✓ 更新交易员成功: %s (模型: %s, 交易所: %s)", req.Name, req.AIModelID, req.ExchangeID)
 
 	c.JSON(http.StatusOK, gin.H{
@@ -1377,9 +2289,12 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 }
 
 // handleDeleteTrader 删除交易员
+// 默认软删除：停止并移出内存，配置和历史数据保留在归档列表中，可恢复
+// purge=true 时永久删除交易员、交易历史、状态和决策日志目录
 func (s *Server) handleDeleteTrader(c *gin.Context) {
 	userID := c.GetString("user_id")
 	traderID := c.Param("id")
+	purge := c.Query("purge") == "true"
 
 	// 确保用户的交易员已加载到内存中
 	err := s.traderManager.LoadUserTraders(s.database, userID)
@@ -1393,15 +2308,91 @@ func (s *Server) handleDeleteTrader(c *gin.Context) {
 		log.Printf("⚠️ 从内存中移除交易员时出现警告: %v", removeErr)
 	}
 
-	// ✅ 步骤2：最后才从数据库删除
+	if purge {
+		if err := s.database.PurgeTrader(userID, traderID); err != nil {
+			respondError(c, http.StatusNotFound, "PURGE_TRADER_FAILED", err)
+			return
+		}
+		if err := os.RemoveAll(traderDecisionLogDir(traderID)); err != nil {
+			log.Printf("⚠️ 删除交易员 %s 的决策日志目录失败: %v", traderID, err)
+		}
+		logger.ClearTraderLogs(traderID)
+		s.recordAudit(c, userID, auditTraderDelete, auditResourceTrader, traderID, map[string]interface{}{
+			"purge": true,
+		})
+		log.Printf("🗑️ 交易员已永久删除（含交易历史和决策日志）: %s", traderID)
+		c.JSON(http.StatusOK, gin.H{"message": "交易员已永久删除"})
+		return
+	}
+
+	// ✅ 步骤2：最后才在数据库中标记删除
 	err = s.database.DeleteTrader(userID, traderID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除交易员失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, "DELETE_TRADER_FAILED", err)
+		return
+	}
+
+	s.recordAudit(c, userID, auditTraderDelete, auditResourceTrader, traderID, map[string]interface{}{
+		"purge": false,
+	})
+	log.Printf("✓ 交易员已删除（已归档，可恢复）: %s", traderID)
+	c.JSON(http.StatusOK, gin.H{"message": "交易员已删除，可在归档列表中查看或恢复"})
+}
+
+// handleArchivedTraders 已删除（归档）的交易员列表，附带历史交易统计
+func (s *Server) handleArchivedTraders(c *gin.Context) {
+	archived, err := s.database.GetArchivedTraders(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LIST_ARCHIVED_TRADERS_FAILED", err)
+		return
+	}
+
+	result := make([]gin.H, 0, len(archived))
+	for _, t := range archived {
+		item := gin.H{
+			"trader_id":       t.ID,
+			"trader_name":     t.Name,
+			"ai_model_id":     t.AIModelID,
+			"exchange_id":     t.ExchangeID,
+			"initial_balance": t.InitialBalance,
+			"tags":            t.Tags,
+			"created_at":      t.CreatedAt,
+			"deleted_at":      t.DeletedAt,
+		}
+		if stats, err := s.database.SummarizeTrades(t.ID, time.Unix(0, 0), time.Now().Add(time.Minute)); err == nil {
+			item["closed_trades"] = stats.ClosedTrades
+			item["win_rate"] = stats.WinRate
+			item["realized_pnl"] = stats.RealizedPnL
+		} else {
+			log.Printf("⚠️ 统计归档交易员 %s 的交易历史失败: %v", t.ID, err)
+		}
+		result = append(result, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"traders": result, "count": len(result)})
+}
+
+// handleRestoreTrader 恢复归档的交易员并重新加载到内存（恢复后为停止状态，需手动启动）
+func (s *Server) handleRestoreTrader(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if err := s.database.RestoreTrader(userID, traderID); err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, config.ErrTraderNameTaken) {
+			status = http.StatusConflict
+		}
+		respondError(c, status, "RESTORE_TRADER_FAILED", err)
 		return
 	}
 
-	log.Printf("✓ 交易员已完全删除: %s", traderID)
-	c.JSON(http.StatusOK, gin.H{"message": "交易员已删除"})
+	resp := gin.H{"message": "交易员已恢复", "trader_id": traderID}
+	if err := s.traderManager.LoadTraderByID(s.database, userID, traderID); err != nil {
+		// 已恢复到列表中，但依赖的模型或交易所配置可能已被删除，需修改配置后才能启动
+		log.Printf("⚠️ 恢复的交易员 %s 加载失败: %v", traderID, err)
+		resp["warning"] = fmt.Sprintf("交易员已恢复但加载失败，请检查模型和交易所配置: %v", err)
+	}
+	log.Printf("♻️ 交易员已恢复: %s", traderID)
+	c.JSON(http.StatusOK, resp)
 }
 
 // handleStartTrader 启动交易员
@@ -1418,89 +2409,233 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 	// 校验交易员是否属于当前用户
 	traderRecord, _, _, err := s.database.GetTraderConfig(userID, traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
 		return
 	}
 
-	// 获取模板名称
-	templateName := traderRecord.SystemPromptTemplate
+	// 启动交易员（重新加载系统提示词模板、更新数据库运行状态，与批量启动共用同一流程）
+	result := s.traderManager.StartTraders([]string{traderID}, s.database)[traderID]
+	switch result.Status {
+	case manager.BatchNotFound:
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+	case manager.BatchAlreadyRunning:
+		respondError(c, http.StatusBadRequest, "TRADER_ALREADY_RUNNING")
+	case manager.BatchStarted:
+		s.recordAudit(c, userID, auditTraderStart, auditResourceTrader, traderID, nil)
+		log.Printf("✓ 已使用系统提示词模板 [%s] 启动交易员 %s", traderRecord.SystemPromptTemplate, traderID)
+		c.JSON(http.StatusOK, gin.H{"message": "交易员已启动"})
+	default:
+		respondError(c, http.StatusInternalServerError, "START_TRADER_FAILED", result.Error)
+	}
+}
 
-	trader, err := s.traderManager.GetTrader(traderID)
+// handleStopTrader 停止交易员
+func (s *Server) handleStopTrader(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 确保用户的交易员已加载到内存中
+	err := s.traderManager.LoadUserTraders(s.database, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
-		return
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
 	}
 
-	// 检查交易员是否已经在运行
-	status := trader.GetStatus()
-	if isRunning, ok := status["is_running"].(bool); ok && isRunning {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员已在运行中"})
+	// 校验交易员是否属于当前用户
+	_, _, _, err = s.database.GetTraderConfig(userID, traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
 		return
 	}
 
-	// 重新加载系统提示词模板（确保使用最新的硬盘文件）
-	s.reloadPromptTemplatesWithLog(templateName)
+	// close_positions 可通过查询参数或 JSON 请求体传入：停止后平掉全部持仓并撤销挂单
+	closePositions := c.Query("close_positions") == "true"
+	var req struct {
+		ClosePositions bool `json:"close_positions"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+			return
+		}
+		closePositions = closePositions || req.ClosePositions
+	}
 
-	// 启动交易员
-	go func() {
-		log.Printf("▶️  启动交易员 %s (%s)", traderID, trader.GetName())
-		if err := trader.Run(); err != nil {
-			log.Printf("❌ 交易员 %s 运行错误: %v", trader.GetName(), err)
+	// 停止交易员并更新数据库运行状态（与批量停止共用同一流程）
+	opts := manager.StopOptions{ClosePositions: closePositions}
+	result := s.traderManager.StopTradersWithOptions([]string{traderID}, s.database, opts)[traderID]
+	if result.Status == manager.BatchStopped || (result.Status == manager.BatchAlreadyStopped && closePositions) {
+		s.recordAudit(c, userID, auditTraderStop, auditResourceTrader, traderID, map[string]interface{}{
+			"close_positions": closePositions,
+		})
+	}
+	switch result.Status {
+	case manager.BatchNotFound:
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+	case manager.BatchAlreadyStopped:
+		if closePositions {
+			// 已停止的交易员仍可平掉遗留持仓
+			c.JSON(http.StatusOK, gin.H{
+				"message":          "交易员已停止，已平掉遗留持仓",
+				"closed_positions": result.ClosedPositions,
+				"close_failures":   result.CloseFailures,
+			})
+			return
+		}
+		respondError(c, http.StatusBadRequest, "TRADER_ALREADY_STOPPED")
+	case manager.BatchStopped:
+		if closePositions {
+			c.JSON(http.StatusOK, gin.H{
+				"message":          "交易员已停止并平仓",
+				"closed_positions": result.ClosedPositions,
+				"close_failures":   result.CloseFailures,
+			})
+			return
 		}
-	}()
+		c.JSON(http.StatusOK, gin.H{"message": "交易员已停止"})
+	default:
+		respondError(c, http.StatusInternalServerError, "STOP_TRADER_FAILED", result.Error)
+	}
+}
+
+// handleBatchTraders 批量启动或停止交易员
+// 请求体 {"action":"start"|"stop","trader_ids":[...]} 或 {"action":"stop","all":true}
+// 停止时可设置 "close_positions":true 平掉全部持仓
+// 逐个校验归属（不属于当前用户的ID记为 not_found），返回每个交易员的结果
+func (s *Server) handleBatchTraders(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		Action         string   `json:"action" binding:"required"`
+		TraderIDs      []string `json:"trader_ids"`
+		All            bool     `json:"all"`
+		ClosePositions bool     `json:"close_positions"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	if req.Action != "start" && req.Action != "stop" {
+		respondError(c, http.StatusBadRequest, "BULK_ACTION_UNSUPPORTED", req.Action)
+		return
+	}
+	if !req.All && len(req.TraderIDs) == 0 {
+		respondError(c, http.StatusBadRequest, "BULK_TARGET_REQUIRED")
+		return
+	}
 
-	// 更新数据库中的运行状态
-	err = s.database.UpdateTraderStatus(userID, traderID, true)
+	// 确保用户的交易员已加载到内存中
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+	}
+	traders, err := s.database.GetTraders(userID)
 	if err != nil {
-		log.Printf("⚠️  更新交易员状态失败: %v", err)
+		respondError(c, http.StatusInternalServerError, "LIST_TRADERS_FAILED", err)
+		return
+	}
+	owned := make(map[string]bool, len(traders))
+	for _, t := range traders {
+		owned[t.ID] = true
+	}
+
+	results := make(map[string]manager.BatchResult)
+	var ids []string
+	if req.All {
+		for _, t := range traders {
+			ids = append(ids, t.ID)
+		}
+	} else {
+		for _, id := range req.TraderIDs {
+			if owned[id] {
+				ids = append(ids, id)
+			} else {
+				results[id] = manager.BatchResult{Status: manager.BatchNotFound}
+			}
+		}
+	}
+
+	var batch map[string]manager.BatchResult
+	if req.Action == "start" {
+		batch = s.traderManager.StartTraders(ids, s.database)
+	} else {
+		batch = s.traderManager.StopTradersWithOptions(ids, s.database, manager.StopOptions{ClosePositions: req.ClosePositions})
+	}
+	summary := make(map[string]int)
+	for id, result := range batch {
+		results[id] = result
+		switch {
+		case req.Action == "start" && result.Status == manager.BatchStarted:
+			s.recordAudit(c, userID, auditTraderStart, auditResourceTrader, id, map[string]interface{}{"batch": true})
+		case req.Action == "stop" && result.Status == manager.BatchStopped:
+			s.recordAudit(c, userID, auditTraderStop, auditResourceTrader, id, map[string]interface{}{
+				"batch":           true,
+				"close_positions": req.ClosePositions,
+			})
+		}
+	}
+	for _, result := range results {
+		summary[result.Status]++
 	}
 
-	log.Printf("✓ 交易员 %s 已启动", trader.GetName())
-	c.JSON(http.StatusOK, gin.H{"message": "交易员已启动"})
+	log.Printf("📦 用户 %s 批量%s交易员: %v", userID, map[string]string{"start": "启动", "stop": "停止"}[req.Action], summary)
+	c.JSON(http.StatusOK, gin.H{
+		"action":  req.Action,
+		"results": results,
+		"summary": summary,
+	})
 }
 
-// handleStopTrader 停止交易员
-func (s *Server) handleStopTrader(c *gin.Context) {
+// handleResumeTrader 手动解除交易员的暂停状态（风控暂停或AI输出质量暂停），无需等待冷却结束
+func (s *Server) handleResumeTrader(c *gin.Context) {
 	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
-	// 确保用户的交易员已加载到内存中
-	err := s.traderManager.LoadUserTraders(s.database, userID)
+	// 校验交易员是否属于当前用户
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_RUNNING")
+		return
+	}
+
+	wasPaused := trader.ResumeTrading()
+	c.JSON(http.StatusOK, gin.H{"success": true, "was_paused": wasPaused})
+}
+
+// handleTraderDryRun 决策预演：用当前提示词跑一次完整决策周期，返回提示词、思维链和决策校验结果，不执行任何订单
+func (s *Server) handleTraderDryRun(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 确保用户的交易员已加载到内存中（已停止的交易员也可预演）
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
 		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
 	}
 
 	// 校验交易员是否属于当前用户
-	_, _, _, err = s.database.GetTraderConfig(userID, traderID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
 		return
 	}
 
-	trader, err := s.traderManager.GetTrader(traderID)
+	at, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
-	// 检查交易员是否正在运行
-	status := trader.GetStatus()
-	if isRunning, ok := status["is_running"].(bool); ok && !isRunning {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员已停止"})
+	result, err := at.RunDryRun()
+	if errors.Is(err, trader.ErrDryRunBusy) {
+		respondError(c, http.StatusConflict, "DRY_RUN_BUSY")
 		return
 	}
-
-	// 停止交易员
-	trader.Stop()
-
-	// 更新数据库中的运行状态
-	err = s.database.UpdateTraderStatus(userID, traderID, false)
 	if err != nil {
-		log.Printf("⚠️  更新交易员状态失败: %v", err)
+		respondError(c, http.StatusInternalServerError, "INTERNAL_ERROR", err)
+		return
 	}
-
-	log.Printf("⏹  交易员 %s 已停止", trader.GetName())
-	c.JSON(http.StatusOK, gin.H{"message": "交易员已停止"})
+	c.JSON(http.StatusOK, result)
 }
 
 // handleUpdateTraderPrompt 更新交易员自定义Prompt
@@ -1520,16 +2655,22 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 	}
 
 	if bindErr := c.ShouldBindJSON(&req); bindErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": bindErr.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", bindErr)
 		return
 	}
 
 	// 更新数据库
+	before, _, _, _ := s.database.GetTraderConfig(userID, traderID)
 	err = s.database.UpdateTraderCustomPrompt(userID, traderID, req.CustomPrompt, req.OverrideBasePrompt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新自定义prompt失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, "UPDATE_PROMPT_FAILED", err)
 		return
 	}
+	if after, _, _, err := s.database.GetTraderConfig(userID, traderID); err == nil {
+		s.recordAudit(c, userID, auditTraderUpdate, auditResourceTrader, traderID, map[string]interface{}{
+			"changed_fields": changedFields(before, after),
+		})
+	}
 
 	// 如果trader在内存中，更新其custom prompt和override设置
 	trader, err := s.traderManager.GetTrader(traderID)
@@ -1558,77 +2699,32 @@ func (s *Server) handleSyncBalance(c *gin.Context) {
 	// 从数据库获取交易员配置（包含交易所信息）
 	traderConfig, _, exchangeCfg, err := s.database.GetTraderConfig(userID, traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
 	if exchangeCfg == nil || !exchangeCfg.Enabled {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易所未配置或未启用"})
-		return
-	}
-
-	// 创建临时 trader 查询余额
-	var tempTrader trader.Trader
-	var createErr error
-
-	switch exchangeCfg.ExchangeID {
-	case "binance":
-		// 使用默认订单策略（查询余额不需要实际下单）
-		tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID, "market_only", -0.03, 60)
-	case "hyperliquid":
-		tempTrader, createErr = trader.NewHyperliquidTrader(
-			exchangeCfg.APIKey,
-			exchangeCfg.HyperliquidWalletAddr,
-			exchangeCfg.Testnet,
-		)
-	case "aster":
-		tempTrader, createErr = trader.NewAsterTrader(
-			exchangeCfg.AsterUser,
-			exchangeCfg.AsterSigner,
-			exchangeCfg.AsterPrivateKey,
-		)
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的交易所类型"})
+		respondError(c, http.StatusBadRequest, "EXCHANGE_NOT_CONFIGURED")
 		return
 	}
 
-	if createErr != nil {
-		log.Printf("⚠️ 创建临时 trader 失败: %v", createErr)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("连接交易所失败: %v", createErr)})
+	if exchangeCfg.ExchangeID == "paper" {
+		respondError(c, http.StatusBadRequest, "PAPER_TRADER_NO_SYNC")
 		return
 	}
 
-	// 查询实际余额
-	balanceInfo, balanceErr := tempTrader.GetBalance()
+	// 查询实际余额（短时间内重复同步使用缓存，交易所限流时返回旧值并标记 is_stale）
+	// ✅ 使用总资产（total equity）而不是可用余额
+	// 总资产 = 钱包余额 + 未实现盈亏，这样才能正确计算总盈亏
+	snapshot, balanceErr := s.fetchExchangeBalance(userID, exchangeCfg)
 	if balanceErr != nil {
 		log.Printf("⚠️ 查询交易所余额失败: %v", balanceErr)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("查询余额失败: %v", balanceErr)})
+		respondError(c, http.StatusInternalServerError, "QUERY_BALANCE_FAILED", balanceErr)
 		return
 	}
-
-	// ✅ 使用总资产（total equity）而不是可用余额
-	// 总资产 = 钱包余额 + 未实现盈亏，这样才能正确计算总盈亏
-	var actualBalance float64
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-
-	if wallet, ok := balanceInfo["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balanceInfo["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
-	}
-
-	// 总资产 = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
-
-	if totalEquity > 0 {
-		actualBalance = totalEquity
-		log.Printf("✓ 查询到交易所总资产余额: %.2f USDT (钱包: %.2f + 未实现: %.2f)",
-			actualBalance, totalWalletBalance, totalUnrealizedProfit)
-	} else {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法获取总资产余额"})
-		return
+	actualBalance := snapshot.TotalEquity
+	if snapshot.IsStale {
+		log.Printf("⚠️ 交易所限流，使用 %s 缓存的余额: %.2f USDT", snapshot.FetchedAt.Format(time.RFC3339), actualBalance)
 	}
 
 	oldBalance := traderConfig.InitialBalance
@@ -1647,7 +2743,7 @@ func (s *Server) handleSyncBalance(c *gin.Context) {
 	err = s.database.UpdateTraderInitialBalance(userID, traderID, actualBalance)
 	if err != nil {
 		log.Printf("❌ 更新initial_balance失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新余额失败"})
+		respondError(c, http.StatusInternalServerError, "UPDATE_BALANCE_FAILED")
 		return
 	}
 
@@ -1665,6 +2761,8 @@ func (s *Server) handleSyncBalance(c *gin.Context) {
 		"new_balance":    actualBalance,
 		"change_percent": changePercent,
 		"change_type":    changeType,
+		"is_stale":       snapshot.IsStale,
+		"fetched_at":     snapshot.FetchedAt,
 	})
 }
 
@@ -1675,7 +2773,7 @@ func (s *Server) handleGetModelConfigs(c *gin.Context) {
 	models, err := s.database.GetAIModels(userID)
 	if err != nil {
 		log.Printf("❌ 获取AI模型配置失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取AI模型配置失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, "GET_AI_MODELS_FAILED", err)
 		return
 	}
 	log.Printf("✅ 找到 %d 个AI模型配置", len(models))
@@ -1684,12 +2782,16 @@ func (s *Server) handleGetModelConfigs(c *gin.Context) {
 	safeModels := make([]SafeModelConfig, len(models))
 	for i, model := range models {
 		safeModels[i] = SafeModelConfig{
-			ID:              model.ModelID, // 返回 model_id（例如 "deepseek"）而不是自增 ID
-			Name:            model.Name,
-			Provider:        model.Provider,
-			Enabled:         model.Enabled,
-			CustomAPIURL:    model.CustomAPIURL,
-			CustomModelName: model.CustomModelName,
+			ID:                 model.ModelID, // 返回 model_id（例如 "deepseek"）而不是自增 ID
+			AccountID:          model.ID,
+			DisplayName:        model.DisplayName,
+			Name:               model.Name,
+			Provider:           model.Provider,
+			Enabled:            model.Enabled,
+			CustomAPIURL:       model.CustomAPIURL,
+			CustomModelName:    model.CustomModelName,
+			SystemPromptPrefix: model.SystemPromptPrefix,
+			SystemPromptSuffix: model.SystemPromptSuffix,
 		}
 	}
 
@@ -1703,7 +2805,7 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	// 读取原始请求体
 	bodyBytes, err := c.GetRawData()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+		respondError(c, http.StatusBadRequest, "READ_BODY_FAILED")
 		return
 	}
 
@@ -1711,18 +2813,14 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	var encryptedPayload crypto.EncryptedPayload
 	if err := json.Unmarshal(bodyBytes, &encryptedPayload); err != nil {
 		log.Printf("❌ 解析加密载荷失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误，必须使用加密传输"})
+		respondError(c, http.StatusBadRequest, "ENCRYPTED_PAYLOAD_INVALID")
 		return
 	}
 
 	// 验证是否为加密数据
 	if encryptedPayload.WrappedKey == "" {
 		log.Printf("❌ 检测到非加密请求 (UserID: %s)", userID)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "此接口仅支持加密传输，请使用加密客户端",
-			"code":    "ENCRYPTION_REQUIRED",
-			"message": "Encrypted transmission is required for security reasons",
-		})
+		respondError(c, http.StatusBadRequest, "ENCRYPTION_REQUIRED")
 		return
 	}
 
@@ -1731,13 +2829,13 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	if err != nil {
 		log.Printf("❌ 解密模型配置失败 (UserID: %s): %v", userID, err)
 		// 根据错误类型提供更具体的错误信息
-		errMsg := "解密数据失败"
+		code := "DECRYPT_FAILED"
 		if strings.Contains(err.Error(), "timestamp") {
-			errMsg = "时间戳验证失败：请检查系统时间是否正确"
+			code = "DECRYPT_TIMESTAMP_INVALID"
 		} else if strings.Contains(err.Error(), "unwrap") || strings.Contains(err.Error(), "RSA") {
-			errMsg = "密钥解密失败：请刷新页面重试"
+			code = "DECRYPT_KEY_FAILED"
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		respondError(c, http.StatusBadRequest, code)
 		return
 	}
 
@@ -1745,18 +2843,22 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	var req UpdateModelConfigRequest
 	if err := json.Unmarshal([]byte(decrypted), &req); err != nil {
 		log.Printf("❌ 解析解密数据失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "解析解密数据失败"})
+		respondError(c, http.StatusBadRequest, "DECRYPT_PAYLOAD_INVALID")
 		return
 	}
 	log.Printf("🔓 已解密模型配置数据 (UserID: %s)", userID)
 
 	// 更新每个模型的配置
 	for modelID, modelData := range req.Models {
+		before := s.findAIModelByRef(userID, modelID)
 		err := s.database.UpdateAIModel(userID, modelID, modelData.Enabled, modelData.APIKey, modelData.CustomAPIURL, modelData.CustomModelName)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新模型 %s 失败: %v", modelID, err)})
+			respondError(c, http.StatusInternalServerError, "UPDATE_AI_MODEL_FAILED", modelID, err)
 			return
 		}
+		s.recordAudit(c, userID, auditModelUpdate, auditResourceModel, modelID, map[string]interface{}{
+			"changed_fields": changedFields(before, s.findAIModelByRef(userID, modelID)),
+		})
 	}
 
 	// 重新加载该用户的所有交易员，使新配置立即生效
@@ -1770,6 +2872,56 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "模型配置已更新"})
 }
 
+// maxModelSystemPromptLength 模型专属 System Prompt 前缀/后缀的最大长度（字符数）
+const maxModelSystemPromptLength = 4000
+
+// handleUpdateModelSystemPrompt 更新模型专属的 System Prompt 前缀/后缀（不含敏感信息，无需加密传输）
+// 使用该模型的交易员在每次决策时把前缀/后缀加到所选模板前后，留空表示不添加
+func (s *Server) handleUpdateModelSystemPrompt(c *gin.Context) {
+	userID := c.GetString("user_id")
+	modelID := c.Param("id")
+
+	var req struct {
+		Prefix string `json:"system_prompt_prefix"`
+		Suffix string `json:"system_prompt_suffix"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	prefix := strings.TrimSpace(req.Prefix)
+	suffix := strings.TrimSpace(req.Suffix)
+	if utf8.RuneCountInString(prefix) > maxModelSystemPromptLength || utf8.RuneCountInString(suffix) > maxModelSystemPromptLength {
+		respondError(c, http.StatusBadRequest, "PROMPT_AFFIX_TOO_LONG", maxModelSystemPromptLength)
+		return
+	}
+
+	before := s.findAIModelByRef(userID, modelID)
+	if err := s.database.UpdateAIModelSystemPrompt(userID, modelID, prefix, suffix); err != nil {
+		if errors.Is(err, config.ErrAIModelNotFound) {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", err)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "UPDATE_MODEL_PROMPT_FAILED", modelID, err)
+		return
+	}
+	s.recordAudit(c, userID, auditModelUpdate, auditResourceModel, modelID, map[string]interface{}{
+		"changed_fields": changedFields(before, s.findAIModelByRef(userID, modelID)),
+	})
+
+	// 重新加载该用户的所有交易员，使新配置立即生效
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 重新加载用户交易员到内存失败: %v", err)
+	}
+
+	log.Printf("✓ 模型 %s 的提示词前缀/后缀已更新 (用户: %s, 前缀%d字, 后缀%d字)", modelID, userID, utf8.RuneCountInString(prefix), utf8.RuneCountInString(suffix))
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "模型提示词前缀/后缀已更新",
+		"systemPromptPrefix": prefix,
+		"systemPromptSuffix": suffix,
+	})
+}
+
 // handleGetExchangeConfigs 获取交易所配置
 func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -1777,7 +2929,7 @@ func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
 		log.Printf("❌ 获取交易所配置失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易所配置失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, "GET_EXCHANGES_FAILED", err)
 		return
 	}
 	log.Printf("✅ 找到 %d 个交易所配置", len(exchanges))
@@ -1787,6 +2939,8 @@ func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 	for i, exchange := range exchanges {
 		safeExchanges[i] = SafeExchangeConfig{
 			ID:                    exchange.ExchangeID, // 返回 exchange_id（例如 "binance"）
+			AccountID:             exchange.ID,
+			DisplayName:           exchange.DisplayName,
 			Name:                  exchange.Name,
 			Type:                  exchange.Type,
 			Enabled:               exchange.Enabled,
@@ -1807,7 +2961,7 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 	// 读取原始请求体
 	bodyBytes, err := c.GetRawData()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+		respondError(c, http.StatusBadRequest, "READ_BODY_FAILED")
 		return
 	}
 
@@ -1815,18 +2969,14 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 	var encryptedPayload crypto.EncryptedPayload
 	if err := json.Unmarshal(bodyBytes, &encryptedPayload); err != nil {
 		log.Printf("❌ 解析加密载荷失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误，必须使用加密传输"})
+		respondError(c, http.StatusBadRequest, "ENCRYPTED_PAYLOAD_INVALID")
 		return
 	}
 
 	// 验证是否为加密数据
 	if encryptedPayload.WrappedKey == "" {
 		log.Printf("❌ 检测到非加密请求 (UserID: %s)", userID)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "此接口仅支持加密传输，请使用加密客户端",
-			"code":    "ENCRYPTION_REQUIRED",
-			"message": "Encrypted transmission is required for security reasons",
-		})
+		respondError(c, http.StatusBadRequest, "ENCRYPTION_REQUIRED")
 		return
 	}
 
@@ -1835,13 +2985,13 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 	if err != nil {
 		log.Printf("❌ 解密交易所配置失败 (UserID: %s): %v", userID, err)
 		// 根据错误类型提供更具体的错误信息
-		errMsg := "解密数据失败"
+		code := "DECRYPT_FAILED"
 		if strings.Contains(err.Error(), "timestamp") {
-			errMsg = "时间戳验证失败：请检查系统时间是否正确"
+			code = "DECRYPT_TIMESTAMP_INVALID"
 		} else if strings.Contains(err.Error(), "unwrap") || strings.Contains(err.Error(), "RSA") {
-			errMsg = "密钥解密失败：请刷新页面重试"
+			code = "DECRYPT_KEY_FAILED"
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		respondError(c, http.StatusBadRequest, code)
 		return
 	}
 
@@ -1849,18 +2999,26 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 	var req UpdateExchangeConfigRequest
 	if err := json.Unmarshal([]byte(decrypted), &req); err != nil {
 		log.Printf("❌ 解析解密数据失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "解析解密数据失败"})
+		respondError(c, http.StatusBadRequest, "DECRYPT_PAYLOAD_INVALID")
 		return
 	}
 	log.Printf("🔓 已解密交易所配置数据 (UserID: %s)", userID)
 
 	// 更新每个交易所的配置
 	for exchangeID, exchangeData := range req.Exchanges {
+		before := s.findExchangeByRef(userID, exchangeID)
 		err := s.database.UpdateExchange(userID, exchangeID, exchangeData.Enabled, exchangeData.APIKey, exchangeData.SecretKey, exchangeData.Testnet, exchangeData.HyperliquidWalletAddr, exchangeData.AsterUser, exchangeData.AsterSigner, exchangeData.AsterPrivateKey)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易所 %s 失败: %v", exchangeID, err)})
+			respondError(c, http.StatusInternalServerError, "UPDATE_EXCHANGE_FAILED", exchangeID, err)
 			return
 		}
+		if err := s.database.UpdateExchangePassphrase(userID, exchangeID, exchangeData.OKXPassphrase); err != nil {
+			respondError(c, http.StatusInternalServerError, "UPDATE_EXCHANGE_FAILED", exchangeID, err)
+			return
+		}
+		s.recordAudit(c, userID, auditExchangeUpdate, auditResourceExchange, exchangeID, map[string]interface{}{
+			"changed_fields": changedFields(before, s.findExchangeByRef(userID, exchangeID)),
+		})
 	}
 
 	// 重新加载该用户的所有交易员，使新配置立即生效
@@ -1893,6 +3051,35 @@ func (s *Server) handleGetUserSignalSource(c *gin.Context) {
 	})
 }
 
+// signalSourceStatus 信号源状态，origin 表示使用的是用户配置(user)还是系统默认(system)的 URL
+type signalSourceStatus struct {
+	pool.SourceStatus
+	Origin string `json:"origin"`
+}
+
+// handleSignalSourceStatus 获取用户信号源的健康状态（最近拉取时间、结果、HTTP状态码、币种数量、是否使用兜底数据）
+func (s *Server) handleSignalSourceStatus(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var coinPoolURL, oiTopURL string
+	if source, err := s.database.GetUserSignalSource(userID); err == nil && source != nil {
+		coinPoolURL, oiTopURL = source.CoinPoolURL, source.OITopURL
+	}
+
+	sources := []signalSourceStatus{}
+	for _, item := range []struct{ name, url string }{
+		{pool.SourceAI500, coinPoolURL},
+		{pool.SourceOITop, oiTopURL},
+	} {
+		origin := "user"
+		if strings.TrimSpace(item.url) == "" {
+			origin = "system"
+		}
+		sources = append(sources, signalSourceStatus{SourceStatus: pool.GetSourceStatus(item.name, item.url), Origin: origin})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sources": sources})
+}
+
 // handleSaveUserSignalSource 保存用户信号源配置
 func (s *Server) handleSaveUserSignalSource(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -1902,13 +3089,13 @@ func (s *Server) handleSaveUserSignalSource(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
 	err := s.database.CreateUserSignalSource(userID, req.CoinPoolURL, req.OITopURL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存用户信号源配置失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, "SAVE_SIGNAL_SOURCE_FAILED", err)
 		return
 	}
 
@@ -1916,99 +3103,599 @@ func (s *Server) handleSaveUserSignalSource(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "用户信号源配置已保存"})
 }
 
+// handleGetDisplayCurrency 获取用户的金额展示币种
+func (s *Server) handleGetDisplayCurrency(c *gin.Context) {
+	currency, err := s.database.GetUserDisplayCurrency(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_DISPLAY_CURRENCY_FAILED", err)
+		return
+	}
+
+	supported := make([]string, 0, len(displayCurrencySymbols))
+	for cur := range displayCurrencySymbols {
+		supported = append(supported, cur)
+	}
+	slices.Sort(supported)
+	c.JSON(http.StatusOK, gin.H{
+		"display_currency": currency,
+		"supported":        supported,
+	})
+}
+
+// handleSetDisplayCurrency 设置用户的金额展示币种（账户/竞赛接口在 USD 金额之外追加换算后的金额）
+func (s *Server) handleSetDisplayCurrency(c *gin.Context) {
+	var req struct {
+		Currency string `json:"currency" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	currency, ok := NormalizeDisplayCurrency(req.Currency)
+	if !ok {
+		respondError(c, http.StatusBadRequest, "DISPLAY_CURRENCY_UNSUPPORTED", req.Currency)
+		return
+	}
+	if err := s.database.SetUserDisplayCurrency(c.GetString("user_id"), currency); err != nil {
+		respondError(c, http.StatusInternalServerError, "SAVE_DISPLAY_CURRENCY_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "display_currency": currency})
+}
+
+// userOutboundTransport 用户的出站代理传输（用户配置优先，否则 OUTBOUND_PROXY 环境变量），未配置或无效时返回 nil 直连
+func (s *Server) userOutboundTransport(userID string) *http.Transport {
+	userProxy, _ := s.database.GetUserOutboundProxy(userID)
+	transport, err := netproxy.NewTransport(netproxy.Resolve(userProxy))
+	if err != nil {
+		log.Printf("⚠️ 用户 %s 的出站代理配置无效，使用直连: %v", userID, err)
+		return nil
+	}
+	return transport
+}
+
+// handleGetOutboundProxy 获取用户的出站代理配置（密码脱敏）
+func (s *Server) handleGetOutboundProxy(c *gin.Context) {
+	userProxy, err := s.database.GetUserOutboundProxy(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_PROXY_FAILED", err)
+		return
+	}
+
+	source := "none"
+	effective := netproxy.Resolve(userProxy)
+	if userProxy != "" {
+		source = "user"
+	} else if effective != "" {
+		source = "env"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"outbound_proxy":  netproxy.Redact(userProxy),
+		"effective_proxy": netproxy.Redact(effective),
+		"source":          source,
+	})
+}
+
+// handleSetOutboundProxy 设置用户的出站代理（空字符串清除，重启交易员后生效）
+func (s *Server) handleSetOutboundProxy(c *gin.Context) {
+	var req struct {
+		Proxy string `json:"proxy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	proxy := strings.TrimSpace(req.Proxy)
+	if proxy != "" {
+		if _, err := netproxy.Parse(proxy); err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+			return
+		}
+	}
+	if err := s.database.SetUserOutboundProxy(c.GetString("user_id"), proxy); err != nil {
+		respondError(c, http.StatusInternalServerError, "SAVE_PROXY_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "outbound_proxy": netproxy.Redact(proxy)})
+}
+
+// handleGetUserWebhook 获取用户 Webhook 配置（不返回签名密钥）
+func (s *Server) handleGetUserWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	hook, err := s.database.GetUserWebhook(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_WEBHOOK_FAILED", err)
+		return
+	}
+	if hook == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"configured":       false,
+			"available_events": webhook.AllEvents,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"configured":       true,
+		"url":              hook.URL,
+		"events":           hook.Events,
+		"enabled":          hook.Enabled,
+		"has_secret":       hook.Secret != "",
+		"updated_at":       hook.UpdatedAt,
+		"available_events": webhook.AllEvents,
+	})
+}
+
+// handleSaveUserWebhook 保存用户 Webhook 配置
+// 未提供密钥时：首次配置自动生成，之后沿用原密钥；新生成或新设置的密钥仅在本次响应中返回
+func (s *Server) handleSaveUserWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		URL              string   `json:"url" binding:"required"`
+		Secret           string   `json:"secret"`
+		Events           []string `json:"events"`
+		Enabled          *bool    `json:"enabled"`
+		RegenerateSecret bool     `json:"regenerate_secret"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	// 回调地址不能指向本机或内网（防止 SSRF），投递时还会按实际连接的 IP 再校验
+	rawURL := strings.TrimSpace(req.URL)
+	if err := webhook.ValidateURL(rawURL); err != nil {
+		if errors.Is(err, webhook.ErrForbiddenTarget) {
+			respondError(c, http.StatusBadRequest, "WEBHOOK_URL_FORBIDDEN")
+		} else {
+			respondError(c, http.StatusBadRequest, "WEBHOOK_URL_INVALID")
+		}
+		return
+	}
+	parsed, _ := url.Parse(rawURL)
+
+	events := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if !webhook.IsValidEvent(e) {
+			respondError(c, http.StatusBadRequest, "EVENT_TYPE_UNSUPPORTED", e)
+			return
+		}
+		if !slices.Contains(events, e) {
+			events = append(events, e)
+		}
+	}
+
+	existing, err := s.database.GetUserWebhook(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_WEBHOOK_FAILED", err)
+		return
+	}
+
+	secret := req.Secret
+	revealSecret := secret != ""
+	if secret == "" {
+		if existing != nil && existing.Secret != "" && !req.RegenerateSecret {
+			secret = existing.Secret
+		} else {
+			secret = webhook.GenerateSecret()
+			revealSecret = true
+		}
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	hook := &config.UserWebhook{
+		UserID:  userID,
+		URL:     parsed.String(),
+		Secret:  secret,
+		Events:  events,
+		Enabled: enabled,
+	}
+	if err := s.database.SaveUserWebhook(hook); err != nil {
+		respondError(c, http.StatusInternalServerError, "SAVE_WEBHOOK_FAILED", err)
+		return
+	}
+
+	log.Printf("✓ 用户Webhook配置已保存: user=%s, url=%s, events=%v", userID, hook.URL, events)
+	resp := gin.H{
+		"message": "Webhook配置已保存",
+		"url":     hook.URL,
+		"events":  events,
+		"enabled": enabled,
+	}
+	if revealSecret {
+		resp["secret"] = secret
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleDeleteUserWebhook 删除用户 Webhook 配置
+func (s *Server) handleDeleteUserWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if err := s.database.DeleteUserWebhook(userID); err != nil {
+		respondError(c, http.StatusInternalServerError, "DELETE_WEBHOOK_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook配置已删除"})
+}
+
+// handleGetUserNotifications 获取用户 Telegram 通知配置（机器人令牌脱敏）
+func (s *Server) handleGetUserNotifications(c *gin.Context) {
+	n, err := s.database.GetUserNotification(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_NOTIFICATIONS_FAILED", err)
+		return
+	}
+	if n == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"configured":       false,
+			"available_events": webhook.AllEvents,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"configured":         true,
+		"telegram_bot_token": MaskSensitiveString(n.TelegramBotToken),
+		"telegram_chat_id":   n.TelegramChatID,
+		"events":             n.Events,
+		"enabled":            n.Enabled,
+		"updated_at":         n.UpdatedAt,
+		"available_events":   webhook.AllEvents,
+	})
+}
+
+// handleSaveUserNotifications 保存用户 Telegram 通知配置
+// 已配置过时可不传机器人令牌，沿用原令牌（前端展示的是脱敏值）
+func (s *Server) handleSaveUserNotifications(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		TelegramBotToken string   `json:"telegram_bot_token"`
+		TelegramChatID   string   `json:"telegram_chat_id" binding:"required"`
+		Events           []string `json:"events"`
+		Enabled          *bool    `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	chatID := strings.TrimSpace(req.TelegramChatID)
+	if err := notify.ValidateChatID(chatID); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	events := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if !webhook.IsValidEvent(e) {
+			respondError(c, http.StatusBadRequest, "EVENT_TYPE_UNSUPPORTED", e)
+			return
+		}
+		if !slices.Contains(events, e) {
+			events = append(events, e)
+		}
+	}
+
+	existing, err := s.database.GetUserNotification(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_NOTIFICATIONS_FAILED", err)
+		return
+	}
+
+	token := strings.TrimSpace(req.TelegramBotToken)
+	if token == "" && existing != nil {
+		token = existing.TelegramBotToken
+	}
+	if !strings.Contains(token, ":") {
+		respondError(c, http.StatusBadRequest, "TELEGRAM_TOKEN_INVALID")
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	n := &config.UserNotification{
+		UserID:           userID,
+		TelegramBotToken: token,
+		TelegramChatID:   chatID,
+		Events:           events,
+		Enabled:          enabled,
+	}
+	if err := s.database.SaveUserNotification(n); err != nil {
+		respondError(c, http.StatusInternalServerError, "SAVE_NOTIFICATIONS_FAILED", err)
+		return
+	}
+
+	log.Printf("✓ 用户通知配置已保存: user=%s, chat=%s, events=%v", userID, chatID, events)
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "通知配置已保存",
+		"telegram_chat_id": chatID,
+		"events":           events,
+		"enabled":          enabled,
+	})
+}
+
+// handleDeleteUserNotifications 删除用户 Telegram 通知配置
+func (s *Server) handleDeleteUserNotifications(c *gin.Context) {
+	if err := s.database.DeleteUserNotification(c.GetString("user_id")); err != nil {
+		respondError(c, http.StatusInternalServerError, "DELETE_NOTIFICATIONS_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "通知配置已删除"})
+}
+
+// handleTestUserNotifications 同步发送一条测试消息，用于确认机器人令牌和 Chat ID 可用
+func (s *Server) handleTestUserNotifications(c *gin.Context) {
+	n, err := s.database.GetUserNotification(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_NOTIFICATIONS_FAILED", err)
+		return
+	}
+	if n == nil {
+		respondError(c, http.StatusBadRequest, "TELEGRAM_NOT_CONFIGURED")
+		return
+	}
+
+	cfg := &notify.TelegramConfig{BotToken: n.TelegramBotToken, ChatID: n.TelegramChatID, Events: n.Events, Enabled: n.Enabled}
+	if err := notify.DefaultNotifier.Send(cfg, "✅ NOFX 通知测试：Telegram 配置可用"); err != nil {
+		respondError(c, http.StatusBadGateway, "SEND_TEST_MESSAGE_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "测试消息已发送"})
+}
+
+// handleDeleteUserHistory 删除用户交易员的决策记录和交易历史（trader_id 为空表示全部交易员）
+// 需要 confirm=true；交易员配置和交易所持仓不受影响，删除后收益曲线和统计从零开始
+func (s *Server) handleDeleteUserHistory(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if c.Query("confirm") != "true" {
+		respondError(c, http.StatusBadRequest, "HISTORY_DELETE_CONFIRM_REQUIRED")
+		return
+	}
+
+	traders, err := s.database.GetTraders(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LIST_TRADERS_FAILED", err)
+		return
+	}
+
+	traderID := strings.TrimSpace(c.Query("trader_id"))
+	var traderIDs []string
+	for _, t := range traders {
+		if traderID == "" || t.ID == traderID {
+			traderIDs = append(traderIDs, t.ID)
+		}
+	}
+	if traderID != "" && len(traderIDs) == 0 {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+
+	tradeRows, rejectedRows, err := s.database.DeleteTradingHistory(userID, traderID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "DELETE_TRADE_HISTORY_FAILED", err)
+		return
+	}
+
+	// 决策记录保存在文件中，优先通过内存中的交易员清理（同时重置周期编号）
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+	}
+	decisionRecords := 0
+	for _, id := range traderIDs {
+		var decisionLogger logger.IDecisionLogger
+		if at, err := s.traderManager.GetTrader(id); err == nil {
+			decisionLogger = at.GetDecisionLogger()
+		} else if _, err := os.Stat(traderDecisionLogDir(id)); err == nil {
+			decisionLogger = logger.NewDecisionLogger(traderDecisionLogDir(id))
+		} else {
+			continue
+		}
+		removed, err := decisionLogger.ClearRecords()
+		decisionRecords += removed
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "DELETE_DECISION_LOGS_FAILED", id, err)
+			return
+		}
+	}
+
+	log.Printf("🗑️ 用户 %s 已清空历史数据: 交易员%d个, 决策记录%d条, 交易历史%d条, 被拒绝决策%d条",
+		userID, len(traderIDs), decisionRecords, tradeRows, rejectedRows)
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "历史数据已删除",
+		"trader_ids":         traderIDs,
+		"decision_records":   decisionRecords,
+		"trade_records":      tradeRows,
+		"rejected_decisions": rejectedRows,
+	})
+}
+
+// traderDecisionLogDir 交易员决策记录目录（与 AutoTrader 创建决策日志时使用的路径一致）
+func traderDecisionLogDir(traderID string) string {
+	return fmt.Sprintf("decision_logs/%s", traderID)
+}
+
 // handleTraderList trader列表
 func (s *Server) handleTraderList(c *gin.Context) {
 	userID := c.GetString("user_id")
 	traders, err := s.database.GetTraders(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员列表失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, "LIST_TRADERS_FAILED", err)
 		return
 	}
 
+	// 按标签筛选（?tag=momentum）
+	if tag := strings.TrimSpace(c.Query("tag")); tag != "" {
+		filtered := make([]*config.TraderRecord, 0, len(traders))
+		for _, t := range traders {
+			if t.HasTag(tag) {
+				filtered = append(filtered, t)
+			}
+		}
+		traders = filtered
+	}
+
 	// 获取用户的所有 AI 模型和交易所配置，用于将整数 ID 映射到字符串 ID
 	aiModels, err := s.database.GetAIModels(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI模型配置失败"})
+		respondError(c, http.StatusInternalServerError, "GET_AI_MODELS_FAILED", err)
 		return
 	}
 
 	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易所配置失败"})
+		respondError(c, http.StatusInternalServerError, "GET_EXCHANGES_FAILED", err)
 		return
 	}
 
-	// 创建映射：整数 ID -> 字符串 ModelID/ExchangeID
-	aiModelMap := make(map[int]string)
+	// 创建映射：整数 ID -> 模型/交易所配置（多账户时同一类型可能有多个配置）
+	aiModelMap := make(map[int]*config.AIModelConfig)
 	for _, model := range aiModels {
-		aiModelMap[model.ID] = model.ModelID
+		aiModelMap[model.ID] = model
 	}
 
-	exchangeMap := make(map[int]string)
+	exchangeMap := make(map[int]*config.ExchangeConfig)
 	for _, exchange := range exchanges {
-		exchangeMap[exchange.ID] = exchange.ExchangeID
+		exchangeMap[exchange.ID] = exchange
 	}
 
 	result := make([]map[string]interface{}, 0, len(traders))
 	for _, trader := range traders {
-		// 获取实时运行状态
+		// 获取实时运行状态和决策周期健康状态（未加载到内存的交易员视为健康）
 		isRunning := trader.IsRunning
+		health := defaultTraderHealth()
 		if at, err := s.traderManager.GetTrader(trader.ID); err == nil {
 			status := at.GetStatus()
 			if running, ok := status["is_running"].(bool); ok {
 				isRunning = running
 			}
+			for key := range health {
+				health[key] = status[key]
+			}
 		}
 
 		// 返回 AI 模型的 ModelID（如 "deepseek", "qwen-chat"），而不是整数 ID
 		// 前端需要使用 .includes() 方法来检查模型类型
-		aiModelID := aiModelMap[trader.AIModelID]
-		if aiModelID == "" {
-			aiModelID = "unknown" // 如果找不到，返回默认值
+		aiModelID := "unknown" // 如果找不到，返回默认值
+		aiModelDisplayName := ""
+		if model := aiModelMap[trader.AIModelID]; model != nil {
+			aiModelID = model.ModelID
+			aiModelDisplayName = model.DisplayName
 		}
 
 		// 返回交易所的 ExchangeID（如 "binance", "hyperliquid"），而不是整数 ID
-		exchangeID := exchangeMap[trader.ExchangeID]
-		if exchangeID == "" {
-			exchangeID = "unknown" // 如果找不到，返回默认值
+		// 账户自增 ID 和名称单独返回，用于区分同一交易所的多个账户
+		exchangeID := "unknown" // 如果找不到，返回默认值
+		exchangeDisplayName := ""
+		testnet := false
+		if exchange := exchangeMap[trader.ExchangeID]; exchange != nil {
+			exchangeID = exchange.ExchangeID
+			exchangeDisplayName = exchange.DisplayName
+			testnet = exchange.Testnet
 		}
 
-		result = append(result, map[string]interface{}{
-			"trader_id":              trader.ID,
-			"trader_name":            trader.Name,
-			"ai_model":               aiModelID,
-			"exchange_id":            exchangeID,
-			"is_running":             isRunning,
-			"initial_balance":        trader.InitialBalance,
-			"system_prompt_template": trader.SystemPromptTemplate,
-			"scan_interval_minutes":  trader.ScanIntervalMinutes,
-			"btc_eth_leverage":       trader.BTCETHLeverage,
-			"altcoin_leverage":       trader.AltcoinLeverage,
-			"trading_symbols":        trader.TradingSymbols,
-			"custom_prompt":          trader.CustomPrompt,
-			"override_base_prompt":   trader.OverrideBasePrompt,
-			"is_cross_margin":        trader.IsCrossMargin,
-			"use_coin_pool":          trader.UseCoinPool,
-			"use_oi_top":             trader.UseOITop,
-			"taker_fee_rate":         trader.TakerFeeRate,
-			"maker_fee_rate":         trader.MakerFeeRate,
-			"order_strategy":         trader.OrderStrategy,
-			"limit_price_offset":     trader.LimitPriceOffset,
-			"limit_timeout_seconds":  trader.LimitTimeoutSeconds,
-			"timeframes":             trader.Timeframes,
-		})
+		entry := map[string]interface{}{
+			"trader_id":                  trader.ID,
+			"trader_name":                trader.Name,
+			"ai_model":                   aiModelID,
+			"ai_model_account_id":        trader.AIModelID,
+			"ai_model_display_name":      aiModelDisplayName,
+			"exchange_id":                exchangeID,
+			"exchange_account_id":        trader.ExchangeID,
+			"exchange_display_name":      exchangeDisplayName,
+			"testnet":                    testnet,
+			"is_running":                 isRunning,
+			"initial_balance":            trader.InitialBalance,
+			"system_prompt_template":     trader.SystemPromptTemplate,
+			"scan_interval_minutes":      trader.ScanIntervalMinutes,
+			"btc_eth_leverage":           trader.BTCETHLeverage,
+			"altcoin_leverage":           trader.AltcoinLeverage,
+			"trading_symbols":            trader.TradingSymbols,
+			"custom_prompt":              trader.CustomPrompt,
+			"override_base_prompt":       trader.OverrideBasePrompt,
+			"is_cross_margin":            trader.IsCrossMargin,
+			"use_coin_pool":              trader.UseCoinPool,
+			"use_oi_top":                 trader.UseOITop,
+			"taker_fee_rate":             trader.TakerFeeRate,
+			"maker_fee_rate":             trader.MakerFeeRate,
+			"order_strategy":             trader.OrderStrategy,
+			"limit_price_offset":         trader.LimitPriceOffset,
+			"limit_timeout_seconds":      trader.LimitTimeoutSeconds,
+			"timeframes":                 trader.Timeframes,
+			"portfolio_group":            trader.PortfolioGroup,
+			"max_trades_per_day":         trader.MaxTradesPerDay,
+			"model_pool":                 trader.ModelPool,
+			"model_pool_mode":            trader.ModelPoolMode,
+			"fallback_ai_model_id":       trader.FallbackAIModelID,
+			"log_level":                  trader.LogLevel,
+			"hold_cache_pct":             trader.HoldCachePct,
+			"start_priority":             trader.StartPriority,
+			"max_exposure_multiple":      trader.MaxExposureMultiple,
+			"respect_signal_bias":        trader.RespectSignalBias,
+			"dry_run":                    trader.DryRun,
+			"alert_drawdown_pct":         trader.AlertDrawdownPct,
+			"alert_daily_loss_pct":       trader.AlertDailyLossPct,
+			"ai_quality_window":          trader.AIQualityWindow,
+			"ai_quality_max_failure_pct": trader.AIQualityMaxFailurePct,
+			"ai_quality_pause_minutes":   trader.AIQualityPauseMinutes,
+			"max_consecutive_errors":     trader.MaxConsecutiveErrors,
+			"daily_report":               trader.DailyReport,
+			"unfunded_threshold":         trader.UnfundedThreshold,
+			"tags":                       trader.Tags,
+			"max_ai_calls_per_day":       trader.MaxAICallsPerDay,
+			"reject_non_candidates":      trader.RejectNonCandidates,
+			"open_verify_delay_ms":       trader.OpenVerifyDelayMs,
+			"active_hours":               trader.ActiveHours,
+			"weekend_trading":            trader.WeekendTrading,
+			"flatten_on_window_close":    trader.FlattenOnWindowClose,
+			"max_positions":              trader.MaxPositions,
+			"max_position_size_usd":      trader.MaxPositionSizeUSD,
+			"blacklisted_symbols":        trader.BlacklistedSymbols,
+			"kline_limit":                trader.KlineLimit,
+			"indicators":                 trader.Indicators,
+			"max_daily_loss":             trader.MaxDailyLoss,
+			"max_drawdown":               trader.MaxDrawdown,
+			"stop_trading_minutes":       trader.StopTradingMinutes,
+		}
+		for key, value := range health {
+			entry[key] = value
+		}
+		result = append(result, entry)
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
+// defaultTraderHealth 未加载到内存（未运行过）的交易员的健康状态字段，与 AutoTrader.GetStatus 的字段一致
+func defaultTraderHealth() map[string]interface{} {
+	return map[string]interface{}{
+		"health":             trader.HealthOK,
+		"health_reason":      "",
+		"consecutive_errors": 0,
+		"last_error":         "",
+		"last_error_time":    "",
+		"auto_paused":        false,
+		"auto_pause_reason":  "",
+	}
+}
+
 // handleGetTraderConfig 获取交易员详细配置
 func (s *Server) handleGetTraderConfig(c *gin.Context) {
 	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
 	if traderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员ID不能为空"})
+		respondError(c, http.StatusBadRequest, "TRADER_ID_REQUIRED")
 		return
 	}
 
@@ -2020,7 +3707,7 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 
 	traderConfig, aiModel, exchange, err := s.database.GetTraderConfig(userID, traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("获取交易员配置失败: %v", err)})
+		respondError(c, http.StatusNotFound, "GET_TRADER_CONFIG_FAILED", err)
 		return
 	}
 
@@ -2041,44 +3728,286 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 	exchangeID := exchange.ExchangeID
 
 	result := map[string]interface{}{
-		"trader_id":              traderConfig.ID,
-		"trader_name":            traderConfig.Name,
-		"ai_model":               aiModelID,
-		"exchange_id":            exchangeID,
-		"initial_balance":        traderConfig.InitialBalance,
-		"scan_interval_minutes":  traderConfig.ScanIntervalMinutes,
-		"btc_eth_leverage":       traderConfig.BTCETHLeverage,
-		"altcoin_leverage":       traderConfig.AltcoinLeverage,
-		"trading_symbols":        traderConfig.TradingSymbols,
-		"custom_prompt":          traderConfig.CustomPrompt,
-		"override_base_prompt":   traderConfig.OverrideBasePrompt,
-		"system_prompt_template": traderConfig.SystemPromptTemplate,
-		"is_cross_margin":        traderConfig.IsCrossMargin,
-		"use_coin_pool":          traderConfig.UseCoinPool,
-		"use_oi_top":             traderConfig.UseOITop,
-		"is_running":             isRunning,
-		"taker_fee_rate":         traderConfig.TakerFeeRate,
-		"maker_fee_rate":         traderConfig.MakerFeeRate,
-		"order_strategy":         traderConfig.OrderStrategy,
-		"limit_price_offset":     traderConfig.LimitPriceOffset,
-		"limit_timeout_seconds":  traderConfig.LimitTimeoutSeconds,
-		"timeframes":             traderConfig.Timeframes,
+		"trader_id":                  traderConfig.ID,
+		"trader_name":                traderConfig.Name,
+		"ai_model":                   aiModelID,
+		"ai_model_account_id":        aiModel.ID,
+		"ai_model_display_name":      aiModel.DisplayName,
+		"exchange_id":                exchangeID,
+		"exchange_account_id":        exchange.ID,
+		"exchange_display_name":      exchange.DisplayName,
+		"initial_balance":            traderConfig.InitialBalance,
+		"scan_interval_minutes":      traderConfig.ScanIntervalMinutes,
+		"btc_eth_leverage":           traderConfig.BTCETHLeverage,
+		"altcoin_leverage":           traderConfig.AltcoinLeverage,
+		"trading_symbols":            traderConfig.TradingSymbols,
+		"custom_prompt":              traderConfig.CustomPrompt,
+		"override_base_prompt":       traderConfig.OverrideBasePrompt,
+		"system_prompt_template":     traderConfig.SystemPromptTemplate,
+		"is_cross_margin":            traderConfig.IsCrossMargin,
+		"use_coin_pool":              traderConfig.UseCoinPool,
+		"use_oi_top":                 traderConfig.UseOITop,
+		"is_running":                 isRunning,
+		"taker_fee_rate":             traderConfig.TakerFeeRate,
+		"maker_fee_rate":             traderConfig.MakerFeeRate,
+		"order_strategy":             traderConfig.OrderStrategy,
+		"limit_price_offset":         traderConfig.LimitPriceOffset,
+		"limit_timeout_seconds":      traderConfig.LimitTimeoutSeconds,
+		"timeframes":                 traderConfig.Timeframes,
+		"portfolio_group":            traderConfig.PortfolioGroup,
+		"max_trades_per_day":         traderConfig.MaxTradesPerDay,
+		"model_pool":                 traderConfig.ModelPool,
+		"model_pool_mode":            traderConfig.ModelPoolMode,
+		"fallback_ai_model_id":       traderConfig.FallbackAIModelID,
+		"log_level":                  traderConfig.LogLevel,
+		"hold_cache_pct":             traderConfig.HoldCachePct,
+		"start_priority":             traderConfig.StartPriority,
+		"max_exposure_multiple":      traderConfig.MaxExposureMultiple,
+		"respect_signal_bias":        traderConfig.RespectSignalBias,
+		"dry_run":                    traderConfig.DryRun,
+		"alert_drawdown_pct":         traderConfig.AlertDrawdownPct,
+		"alert_daily_loss_pct":       traderConfig.AlertDailyLossPct,
+		"ai_quality_window":          traderConfig.AIQualityWindow,
+		"ai_quality_max_failure_pct": traderConfig.AIQualityMaxFailurePct,
+		"ai_quality_pause_minutes":   traderConfig.AIQualityPauseMinutes,
+		"max_consecutive_errors":     traderConfig.MaxConsecutiveErrors,
+		"daily_report":               traderConfig.DailyReport,
+		"unfunded_threshold":         traderConfig.UnfundedThreshold,
+		"tags":                       traderConfig.Tags,
+		"max_ai_calls_per_day":       traderConfig.MaxAICallsPerDay,
+		"reject_non_candidates":      traderConfig.RejectNonCandidates,
+		"open_verify_delay_ms":       traderConfig.OpenVerifyDelayMs,
+		"active_hours":               traderConfig.ActiveHours,
+		"weekend_trading":            traderConfig.WeekendTrading,
+		"flatten_on_window_close":    traderConfig.FlattenOnWindowClose,
+		"max_positions":              traderConfig.MaxPositions,
+		"max_position_size_usd":      traderConfig.MaxPositionSizeUSD,
+		"blacklisted_symbols":        traderConfig.BlacklistedSymbols,
+		"kline_limit":                traderConfig.KlineLimit,
+		"indicators":                 traderConfig.Indicators,
+		"max_daily_loss":             traderConfig.MaxDailyLoss,
+		"max_drawdown":               traderConfig.MaxDrawdown,
+		"stop_trading_minutes":       traderConfig.StopTradingMinutes,
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
+// handleGetTraderEffectiveConfig 获取运行中交易员实际使用的配置（内存中的配置，密钥已脱敏）
+// 同时列出与数据库配置不一致的字段，便于排查部分热更新后行为与配置不符的问题
+func (s *Server) handleGetTraderEffectiveConfig(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 确保用户的交易员已加载到内存中
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+	}
+
+	// 校验交易员是否属于当前用户
+	traderRecord, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_LOADED")
+		return
+	}
+
+	effective := at.GetEffectiveConfig()
+	status := at.GetStatus()
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":     traderID,
+		"is_running":    status["is_running"],
+		"config":        effective,
+		"db_mismatches": effectiveConfigMismatches(traderRecord, effective),
+	})
+}
+
+// maxExchangeFillsRange 成交记录对账的最大查询跨度
+const maxExchangeFillsRange = 30 * 24 * time.Hour
+
+// parseTimeParam 解析时间查询参数（毫秒时间戳、RFC3339 或 2006-01-02），为空时返回默认值
+func parseTimeParam(raw string, def time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return def, nil
+	}
+	if ms, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s（支持毫秒时间戳、RFC3339 或 YYYY-MM-DD）", raw)
+}
+
+// handleDailyReports 获取交易员的每日绩效报告（按日期倒序，limit 默认30、最多365）
+func (s *Server) handleDailyReports(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+
+	limit := 30
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 365 {
+			respondError(c, http.StatusBadRequest, "LIMIT_OUT_OF_RANGE_365")
+			return
+		}
+		limit = parsed
+	}
+
+	reports, err := s.database.GetDailyReports(traderID, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_DAILY_REPORTS_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports, "count": len(reports)})
+}
+
+// handleExchangeFills 获取交易所实际成交记录，用于与 trade_history 对账
+// 查询参数：symbol（Binance/Aster 必填）、from/to（默认最近24小时，跨度不超过30天）
+func (s *Server) handleExchangeFills(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+
+	now := time.Now()
+	from, err := parseTimeParam(c.Query("from"), now.Add(-24*time.Hour))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	to, err := parseTimeParam(c.Query("to"), now)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	if !from.Before(to) {
+		respondError(c, http.StatusBadRequest, "TIME_RANGE_FROM_AFTER_TO")
+		return
+	}
+	if to.Sub(from) > maxExchangeFillsRange {
+		respondError(c, http.StatusBadRequest, "TIME_RANGE_TOO_LONG")
+		return
+	}
+
+	// 确保用户的交易员已加载到内存中
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_LOADED")
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(c.Query("symbol")))
+	fills, err := at.GetExchangeFills(symbol, from, to)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, "GET_EXCHANGE_FILLS_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"symbol":    symbol,
+		"from":      from.UnixMilli(),
+		"to":        to.UnixMilli(),
+		"count":     len(fills),
+		"fills":     fills,
+	})
+}
+
+// effectiveConfigMismatches 对比数据库配置与内存配置，返回不一致的字段
+func effectiveConfigMismatches(record *config.TraderRecord, effective map[string]interface{}) []gin.H {
+	timeframes, _ := effective["timeframes"].([]string)
+	indicators, _ := effective["indicators"].([]string)
+	dbTemplate := record.SystemPromptTemplate
+	if dbTemplate == "" {
+		dbTemplate = "default"
+	}
+	runtimeTemplate, _ := effective["system_prompt_template"].(string)
+	if runtimeTemplate == "" {
+		runtimeTemplate = "default"
+	}
+
+	checks := []struct {
+		field   string
+		db      interface{}
+		runtime interface{}
+	}{
+		{"name", record.Name, effective["name"]},
+		{"btc_eth_leverage", record.BTCETHLeverage, effective["btc_eth_leverage"]},
+		{"altcoin_leverage", record.AltcoinLeverage, effective["altcoin_leverage"]},
+		{"scan_interval_minutes", record.ScanIntervalMinutes, effective["scan_interval_minutes"]},
+		{"is_cross_margin", record.IsCrossMargin, effective["is_cross_margin"]},
+		{"system_prompt_template", dbTemplate, runtimeTemplate},
+		{"custom_prompt", record.CustomPrompt, effective["custom_prompt"]},
+		{"override_base_prompt", record.OverrideBasePrompt, effective["override_base_prompt"]},
+		{"order_strategy", record.OrderStrategy, effective["order_strategy"]},
+		{"timeframes", record.Timeframes, strings.Join(timeframes, ",")},
+		{"kline_limit", record.KlineLimit, effective["kline_limit"]},
+		{"indicators", record.Indicators, strings.Join(indicators, ",")},
+		{"max_trades_per_day", record.MaxTradesPerDay, effective["max_trades_per_day"]},
+		{"hold_cache_pct", record.HoldCachePct, effective["hold_cache_pct"]},
+		{"max_exposure_multiple", record.MaxExposureMultiple, effective["max_exposure_multiple"]},
+		{"respect_signal_bias", record.RespectSignalBias, effective["respect_signal_bias"]},
+		{"dry_run", record.DryRun, effective["dry_run"]},
+		{"alert_drawdown_pct", record.AlertDrawdownPct, effective["alert_drawdown_pct"]},
+		{"alert_daily_loss_pct", record.AlertDailyLossPct, effective["alert_daily_loss_pct"]},
+		{"ai_quality_window", record.AIQualityWindow, effective["ai_quality_window"]},
+		{"ai_quality_max_failure_pct", record.AIQualityMaxFailurePct, effective["ai_quality_max_failure_pct"]},
+		{"ai_quality_pause_minutes", record.AIQualityPauseMinutes, effective["ai_quality_pause_minutes"]},
+		{"max_consecutive_errors", record.MaxConsecutiveErrors, effective["max_consecutive_errors"]},
+		{"daily_report", record.DailyReport, effective["daily_report"]},
+		{"unfunded_threshold", record.UnfundedThreshold, effective["unfunded_threshold"]},
+		{"max_ai_calls_per_day", record.MaxAICallsPerDay, effective["max_ai_calls_per_day"]},
+		{"reject_non_candidates", record.RejectNonCandidates, effective["reject_non_candidates"]},
+		{"open_verify_delay_ms", record.OpenVerifyDelayMs, effective["open_verify_delay_ms"]},
+		{"trading_window", tradingWindowDesc(record), effective["trading_window"]},
+		{"flatten_on_window_close", record.FlattenOnWindowClose, effective["flatten_on_window_close"]},
+		{"max_positions", record.MaxPositions, effective["max_positions"]},
+		{"max_position_size_usd", record.MaxPositionSizeUSD, effective["max_position_size_usd"]},
+		{"portfolio_group", strings.TrimSpace(record.PortfolioGroup), effective["portfolio_group"]},
+	}
+
+	mismatches := make([]gin.H, 0)
+	for _, check := range checks {
+		if fmt.Sprint(check.db) != fmt.Sprint(check.runtime) {
+			mismatches = append(mismatches, gin.H{"field": check.field, "db": check.db, "runtime": check.runtime})
+		}
+	}
+	return mismatches
+}
+
 // handleStatus 系统状态
 func (s *Server) handleStatus(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
 		return
 	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
@@ -2090,13 +4019,13 @@ func (s *Server) handleStatus(c *gin.Context) {
 func (s *Server) handleAccount(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
 		return
 	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
@@ -2104,9 +4033,7 @@ func (s *Server) handleAccount(c *gin.Context) {
 	account, err := trader.GetAccountInfo()
 	if err != nil {
 		log.Printf("❌ 获取账户信息失败 [%s]: %v", trader.GetName(), err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取账户信息失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "GET_ACCOUNT_FAILED", err)
 		return
 	}
 
@@ -2116,71 +4043,275 @@ func (s *Server) handleAccount(c *gin.Context) {
 		account["available_balance"],
 		account["total_pnl"],
 		account["total_pnl_pct"])
-	c.JSON(http.StatusOK, account)
+	c.JSON(http.StatusOK, s.withDisplayCurrency(c, RoundResponseFields(account, s.responseDecimals(c))))
+}
+
+// handlePositions 持仓列表
+func (s *Server) handlePositions(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return
+	}
+
+	positions, err := trader.GetPositions()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_POSITIONS_FAILED", err)
+		return
+	}
+
+	// 补充资金费率，便于前端展示持仓的资金费方向
+	annotatePositionFunding(positions)
+
+	c.JSON(http.StatusOK, RoundResponseList(positions, s.responseDecimals(c)))
+}
+
+// handleDecisions 决策日志列表
+func (s *Server) handleDecisions(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return
+	}
+
+	// 获取所有历史决策记录（无限制）
+	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_DECISIONS_FAILED", err)
+		return
+	}
+
+	localizeDecisionRecords(records, requestLang(c))
+	c.JSON(http.StatusOK, records)
+}
+
+// handleRejectedDecisions 被守卫检查拒绝的决策（最新的在前），可按 reason 过滤
+func (s *Server) handleRejectedDecisions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
+		return
+	}
+
+	// 校验交易员是否属于当前用户
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+
+	// 从 query 参数读取 limit，默认 100，最大 1000
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+
+	records, err := s.database.GetRejectedDecisions(traderID, strings.TrimSpace(c.Query("reason")), limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_REJECTED_DECISIONS_FAILED", err)
+		return
+	}
+	counts, err := s.database.CountRejectedDecisions(traderID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "COUNT_REJECTED_DECISIONS_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"records":   records,
+		"by_reason": counts,
+	})
+}
+
+// handleFees 交易员手续费汇总（按 trade_history 估算，含按币种拆分和手续费/盈亏比）
+// 查询参数：trader_id、from/to（默认全部历史）；限价单策略按 maker 费率估算，其余按 taker 费率
+func (s *Server) handleFees(c *gin.Context) {
+	userID := c.GetString("user_id")
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
+		return
+	}
+
+	traderRecord, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+
+	now := time.Now()
+	from, err := parseTimeParam(c.Query("from"), time.UnixMilli(0))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	to, err := parseTimeParam(c.Query("to"), now)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	if !to.After(from) {
+		respondError(c, http.StatusBadRequest, "TIME_RANGE_TO_BEFORE_FROM")
+		return
+	}
+
+	feeRate := traderRecord.TakerFeeRate
+	feeType := "taker"
+	if traderRecord.OrderStrategy == "limit_only" {
+		feeRate = traderRecord.MakerFeeRate
+		feeType = "maker"
+	}
+
+	summary, err := s.database.SummarizeFees(traderID, from, to, feeRate)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_FEES_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":  traderID,
+		"from":       from.UTC().Format(time.RFC3339),
+		"to":         to.UTC().Format(time.RFC3339),
+		"fee_source": "estimated",
+		"fee_type":   feeType,
+		"fee_rate":   feeRate,
+		"summary":    summary,
+	})
 }
 
-// handlePositions 持仓列表
-func (s *Server) handlePositions(c *gin.Context) {
+// maxTradeHistoryLimit 交易历史单页最大条数
+const maxTradeHistoryLimit = 1000
+
+// handleTradeHistory 分页查询交易员的开平仓记录（trade_history，按时间倒序）
+// 查询参数：trader_id、limit（默认100）、offset、symbol、action、since/until（毫秒时间戳、RFC3339 或 YYYY-MM-DD）
+func (s *Server) handleTradeHistory(c *gin.Context) {
+	userID := c.GetString("user_id")
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
 		return
 	}
 
-	trader, err := s.traderManager.GetTrader(traderID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
 		return
 	}
 
-	positions, err := trader.GetPositions()
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > maxTradeHistoryLimit {
+		respondError(c, http.StatusBadRequest, "LIMIT_OUT_OF_RANGE", maxTradeHistoryLimit)
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		respondError(c, http.StatusBadRequest, "OFFSET_INVALID")
+		return
+	}
+
+	filter := config.TradeHistoryFilter{
+		Symbol: strings.ToUpper(strings.TrimSpace(c.Query("symbol"))),
+		Action: strings.ToUpper(strings.TrimSpace(c.Query("action"))),
+	}
+	if filter.Since, err = parseTimeParam(c.Query("since"), time.Time{}); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	if filter.Until, err = parseTimeParam(c.Query("until"), time.Time{}); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		respondError(c, http.StatusBadRequest, "TIME_RANGE_UNTIL_BEFORE_SINCE")
+		return
+	}
+
+	trades, total, err := s.database.GetTradeHistory(traderID, limit, offset, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取持仓列表失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "GET_TRADE_HISTORY_FAILED", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, positions)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"trades":    trades,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// tradeStatsPeriods 交易统计支持的时间范围（all 表示全部历史）
+var tradeStatsPeriods = map[string]time.Duration{
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"all": 0,
 }
 
-// handleDecisions 决策日志列表
-func (s *Server) handleDecisions(c *gin.Context) {
+// handleTradeStats 基于 trade_history 实际成交的交易统计（胜率、盈亏比、最大连续亏损、按币种拆分）
+// 查询参数：trader_id、period（7d/30d/all，默认 30d）
+func (s *Server) handleTradeStats(c *gin.Context) {
+	userID := c.GetString("user_id")
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
 		return
 	}
 
-	trader, err := s.traderManager.GetTrader(traderID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
 		return
 	}
 
-	// 获取所有历史决策记录（无限制）
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	period := strings.ToLower(strings.TrimSpace(c.DefaultQuery("period", "30d")))
+	window, ok := tradeStatsPeriods[period]
+	if !ok {
+		respondError(c, http.StatusBadRequest, "PERIOD_INVALID", period)
+		return
+	}
+	var since time.Time
+	if window > 0 {
+		since = time.Now().Add(-window)
+	}
+
+	stats, err := s.database.GetTradeStats(traderID, since)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取决策日志失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "GET_TRADE_STATS_FAILED", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, records)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"period":    period,
+		"stats":     stats,
+	})
 }
 
 // handleLatestDecisions 最新决策日志（最近5条，最新的在前）
 func (s *Server) handleLatestDecisions(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
 		return
 	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
@@ -2194,9 +4325,7 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 
 	records, err := trader.GetDecisionLogger().GetLatestRecords(limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取决策日志失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "GET_DECISIONS_FAILED", err)
 		return
 	}
 
@@ -2206,6 +4335,7 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 		records[i], records[j] = records[j], records[i]
 	}
 
+	localizeDecisionRecords(records, requestLang(c))
 	c.JSON(http.StatusOK, records)
 }
 
@@ -2213,27 +4343,81 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 func (s *Server) handleStatistics(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
 		return
 	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
 	stats, err := trader.GetDecisionLogger().GetStatistics()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取统计信息失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "GET_STATISTICS_FAILED", err)
 		return
 	}
+	stats.AIUsage.ApplyPrices(s.tokenPrices())
 
 	c.JSON(http.StatusOK, stats)
 }
 
+// tokenPrices 读取系统配置 ai_token_prices（每个模型每 1000 token 的价格，JSON），配置无效时忽略
+func (s *Server) tokenPrices() logger.TokenPrices {
+	raw, _ := s.database.GetSystemConfig("ai_token_prices")
+	prices, err := logger.ParseTokenPrices(raw)
+	if err != nil {
+		log.Printf("⚠️ 忽略无效的 ai_token_prices 配置: %v", err)
+		return nil
+	}
+	return prices
+}
+
+// handleStatisticsByTag 按标签汇总当前用户交易员的已实现盈亏、交易次数和胜率（?tag= 只看单个标签）
+func (s *Server) handleStatisticsByTag(c *gin.Context) {
+	userID := c.GetString("user_id")
+	summaries, err := s.database.SummarizeByTag(userID, c.Query("tag"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_TAG_STATS_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": summaries})
+}
+
+// handleDecisionSuccessRate 按时间段统计决策执行成功率（bucket: hour/day/week，默认 day）
+func (s *Server) handleDecisionSuccessRate(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
+		return
+	}
+
+	bucket := c.DefaultQuery("bucket", logger.SuccessRateBucketDay)
+	if !logger.ValidSuccessRateBucket(bucket) {
+		respondError(c, http.StatusBadRequest, "BUCKET_INVALID")
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return
+	}
+
+	buckets, err := trader.GetDecisionLogger().GetSuccessRate(bucket)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_SUCCESS_RATE_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"bucket":    bucket,
+		"buckets":   buckets,
+	})
+}
+
 // handleCompetition 竞赛总览（对比所有trader）
 func (s *Server) handleCompetition(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -2246,49 +4430,114 @@ func (s *Server) handleCompetition(c *gin.Context) {
 
 	competition, err := s.traderManager.GetCompetitionData()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取竞赛数据失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "GET_COMPETITION_FAILED", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, competition)
+	c.JSON(http.StatusOK, s.competitionResponse(c, competition))
+}
+
+// roundCompetitionData 对竞赛数据中的 traders 列表做四舍五入（返回副本，不影响竞赛缓存）
+func roundCompetitionData(data map[string]interface{}, decimals int) map[string]interface{} {
+	rounded := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		rounded[k] = v
+	}
+	if traders, ok := data["traders"].([]map[string]interface{}); ok {
+		rounded["traders"] = RoundResponseList(traders, decimals)
+	}
+	return rounded
+}
+
+const (
+	equityHistoryLimit      = 10000 // 单个交易员收益率曲线最多返回的数据点
+	equityHistoryBatchLimit = 500   // 批量对比时每个交易员最多返回的数据点
+)
+
+// EquityPoint 收益率历史数据点
+type EquityPoint struct {
+	Timestamp        string  `json:"timestamp"`
+	TotalEquity      float64 `json:"total_equity"`      // 账户净值（wallet + unrealized）
+	AvailableBalance float64 `json:"available_balance"` // 可用余额
+	TotalPnL         float64 `json:"total_pnl"`         // 总盈亏（相对初始余额）
+	TotalPnLPct      float64 `json:"total_pnl_pct"`     // 总盈亏百分比
+	PositionCount    int     `json:"position_count"`    // 持仓数量
+	MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
+	CycleNumber      int     `json:"cycle_number"`
+}
+
+// parseEquityInterval 解析收益率曲线的降采样间隔（interval=15m/1h，为空表示不降采样）
+func parseEquityInterval(c *gin.Context) (time.Duration, error) {
+	raw := strings.TrimSpace(c.Query("interval"))
+	if raw == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval < time.Minute {
+		return 0, fmt.Errorf("无效的 interval: %s（示例: 15m、1h，最小 1m）", raw)
+	}
+	return interval, nil
+}
+
+// equityPointsFromSnapshots 将净值快照转换为收益率历史数据点
+func equityPointsFromSnapshots(snapshots []*config.EquitySnapshot, decimals int) []EquityPoint {
+	history := make([]EquityPoint, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		pnlPct := 0.0
+		if snapshot.InitialBalance > 0 {
+			pnlPct = snapshot.TotalPnL / snapshot.InitialBalance * 100
+		}
+		history = append(history, EquityPoint{
+			Timestamp:        snapshot.Timestamp.Format("2006-01-02 15:04:05"),
+			TotalEquity:      RoundFloat(snapshot.TotalEquity, decimals),
+			AvailableBalance: RoundFloat(snapshot.AvailableBalance, decimals),
+			TotalPnL:         RoundFloat(snapshot.TotalPnL, decimals),
+			TotalPnLPct:      RoundFloat(pnlPct, decimals),
+			PositionCount:    snapshot.PositionCount,
+			MarginUsedPct:    RoundFloat(snapshot.MarginUsedPct, decimals),
+			CycleNumber:      snapshot.CycleNumber,
+		})
+	}
+	return history
 }
 
 // handleEquityHistory 收益率历史数据
 func (s *Server) handleEquityHistory(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
 		return
 	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
-	// 获取尽可能多的历史数据（几天的数据）
-	// 每3分钟一个周期：10000条 = 约20天的数据
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	interval, err := parseEquityInterval(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取历史数据失败: %v", err),
-		})
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
+	// 优先查询净值快照表；迁移前创建的交易员没有快照，回退到决策日志
+	decimals := s.responseDecimals(c)
+	snapshots, err := s.database.GetEquitySnapshots(traderID, interval, equityHistoryLimit)
+	if err != nil {
+		log.Printf("⚠️ 查询净值快照失败，回退到决策日志: %v", err)
+	}
+	if len(snapshots) > 0 {
+		c.JSON(http.StatusOK, equityPointsFromSnapshots(snapshots, decimals))
 		return
 	}
 
-	// 构建收益率历史数据点
-	type EquityPoint struct {
-		Timestamp        string  `json:"timestamp"`
-		TotalEquity      float64 `json:"total_equity"`      // 账户净值（wallet + unrealized）
-		AvailableBalance float64 `json:"available_balance"` // 可用余额
-		TotalPnL         float64 `json:"total_pnl"`         // 总盈亏（相对初始余额）
-		TotalPnLPct      float64 `json:"total_pnl_pct"`     // 总盈亏百分比
-		PositionCount    int     `json:"position_count"`    // 持仓数量
-		MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
-		CycleNumber      int     `json:"cycle_number"`
+	// 获取尽可能多的历史数据（几天的数据）
+	// 每3分钟一个周期：10000条 = 约20天的数据
+	records, err := trader.GetDecisionLogger().GetLatestRecords(equityHistoryLimit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_HISTORY_FAILED", err)
+		return
 	}
 
 	// 从AutoTrader获取当前初始余额（用作旧数据的fallback）
@@ -2301,43 +4550,27 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 
 	// 如果还是无法获取，返回错误
 	if base == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "无法获取初始余额",
-		})
+		respondError(c, http.StatusInternalServerError, "INITIAL_BALANCE_UNAVAILABLE")
 		return
 	}
 
 	var history []EquityPoint
 	for _, record := range records {
-		// TotalBalance字段实际存储的是TotalEquity
-		// totalEquity := record.AccountState.TotalBalance
-		// TotalUnrealizedProfit字段实际存储的是TotalPnL（相对初始余额）
-		// totalPnL := record.AccountState.TotalUnrealizedProfit
-		walletBalance := record.AccountState.TotalBalance
-		unrealizedPnL := record.AccountState.TotalUnrealizedProfit
-		totalEquity := walletBalance + unrealizedPnL
-
 		// 🔄 使用历史记录中保存的initial_balance（如果有）
 		// 这样可以保持历史PNL%的准确性，即使用户后来更新了initial_balance
 		if record.AccountState.InitialBalance > 0 {
 			base = record.AccountState.InitialBalance
 		}
-
-		totalPnL := totalEquity - base
-		// 计算盈亏百分比
-		totalPnLPct := 0.0
-		if base > 0 {
-			totalPnLPct = (totalPnL / base) * 100
-		}
+		totalEquity, totalPnL, totalPnLPct := equityFromRecord(record, base)
 
 		history = append(history, EquityPoint{
 			Timestamp:        record.Timestamp.Format("2006-01-02 15:04:05"),
-			TotalEquity:      totalEquity,
-			AvailableBalance: record.AccountState.AvailableBalance,
-			TotalPnL:         totalPnL,
-			TotalPnLPct:      totalPnLPct,
+			TotalEquity:      RoundFloat(totalEquity, decimals),
+			AvailableBalance: RoundFloat(record.AccountState.AvailableBalance, decimals),
+			TotalPnL:         RoundFloat(totalPnL, decimals),
+			TotalPnLPct:      RoundFloat(totalPnLPct, decimals),
 			PositionCount:    record.AccountState.PositionCount,
-			MarginUsedPct:    record.AccountState.MarginUsedPct,
+			MarginUsedPct:    RoundFloat(record.AccountState.MarginUsedPct, decimals),
 			CycleNumber:      record.CycleNumber,
 		})
 	}
@@ -2349,13 +4582,13 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 func (s *Server) handlePerformance(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "NO_TRADER_AVAILABLE")
 		return
 	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
@@ -2363,46 +4596,71 @@ func (s *Server) handlePerformance(c *gin.Context) {
 	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
 	performance, err := trader.GetDecisionLogger().AnalyzePerformance(100)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("分析历史表现失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "ANALYZE_PERFORMANCE_FAILED", err)
 		return
 	}
 
+	// 附带该交易员的 AI token 用量（按模型拆分）
+	if stats, err := trader.GetDecisionLogger().GetStatistics(); err == nil {
+		stats.AIUsage.ApplyPrices(s.tokenPrices())
+		performance.AIUsage = &stats.AIUsage
+	}
+
 	c.JSON(http.StatusOK, performance)
 }
 
+// validateToken 校验JWT（含黑名单检查），供认证中间件和 WebSocket 握手使用
+func validateToken(tokenString string) (*auth.Claims, error) {
+	if auth.IsTokenBlacklisted(tokenString) {
+		return nil, fmt.Errorf("token已失效，请重新登录")
+	}
+	claims, err := auth.ValidateJWT(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("无效的token: %w", err)
+	}
+	return claims, nil
+}
+
 // authMiddleware JWT认证中间件
 func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少Authorization头"})
+			respondError(c, http.StatusUnauthorized, "AUTH_HEADER_MISSING")
 			c.Abort()
 			return
 		}
 
-		// 检查Bearer token格式
+		// 检查Bearer token / ApiKey 格式
 		tokenParts := strings.Split(authHeader, " ")
+		if len(tokenParts) == 2 && tokenParts[0] == apiKeyAuthScheme {
+			if s.authenticateAPIKey(c, tokenParts[1]) {
+				c.Next()
+			}
+			return
+		}
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Authorization格式"})
+			respondError(c, http.StatusUnauthorized, "AUTH_HEADER_INVALID")
 			c.Abort()
 			return
 		}
 
-		tokenString := tokenParts[1]
-
-		// 黑名单检查
-		if auth.IsTokenBlacklisted(tokenString) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token已失效，请重新登录"})
+		claims, err := validateToken(tokenParts[1])
+		if errors.Is(err, auth.ErrTokenVersionUnavailable) {
+			// 无法确认token是否已被撤销（如刚修改密码），拒绝请求而不是放行
+			respondError(c, http.StatusServiceUnavailable, "AUTH_CHECK_UNAVAILABLE")
 			c.Abort()
 			return
 		}
-
-		// 验证JWT token
-		claims, err := auth.ValidateJWT(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token: " + err.Error()})
+			respondError(c, http.StatusUnauthorized, "INVALID_TOKEN")
+			c.Abort()
+			return
+		}
+
+		// 被管理员禁用的用户即使持有未过期的token也无法访问
+		if s.database.IsUserDisabled(claims.UserID) {
+			respondError(c, http.StatusForbidden, "ACCOUNT_DISABLED")
 			c.Abort()
 			return
 		}
@@ -2414,34 +4672,84 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 	}
 }
 
+// isAdmin 判断当前用户是否为管理员（admin 用户，或邮箱在 admin_emails 系统配置中）
+func (s *Server) isAdmin(c *gin.Context) bool {
+	if c.GetString("user_id") == "admin" {
+		return true
+	}
+
+	email := strings.TrimSpace(c.GetString("email"))
+	if email == "" {
+		return false
+	}
+	adminEmails, _ := s.database.GetSystemConfig("admin_emails")
+	for _, e := range strings.Split(adminEmails, ",") {
+		if strings.EqualFold(strings.TrimSpace(e), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminMiddleware 管理员权限中间件（需在 authMiddleware 之后使用）
+// API Key 即使属于管理员也不能访问（备份下载包含整个数据库）
+func (s *Server) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("api_key_id") != "" {
+			respondError(c, http.StatusForbidden, "API_KEY_ADMIN_FORBIDDEN")
+			c.Abort()
+			return
+		}
+		if !s.isAdmin(c) {
+			respondError(c, http.StatusForbidden, "ADMIN_REQUIRED")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // handleLogout 将当前token加入黑名单
 func (s *Server) handleLogout(c *gin.Context) {
+	// API Key 没有会话，停用需撤销密钥
+	if c.GetString("api_key_id") != "" {
+		c.JSON(http.StatusOK, gin.H{"message": "API Key 无需登出，如需停用请撤销该密钥"})
+		return
+	}
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少Authorization头"})
+		respondError(c, http.StatusUnauthorized, "AUTH_HEADER_MISSING")
 		return
 	}
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Authorization格式"})
+		respondError(c, http.StatusUnauthorized, "AUTH_HEADER_INVALID")
 		return
 	}
 	tokenString := parts[1]
 	claims, err := auth.ValidateJWT(tokenString)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token"})
+		respondError(c, http.StatusUnauthorized, "INVALID_TOKEN")
 		return
 	}
-	var exp time.Time
-	if claims.ExpiresAt != nil {
-		exp = claims.ExpiresAt.Time
-	} else {
-		exp = time.Now().Add(24 * time.Hour)
-	}
-	auth.BlacklistToken(tokenString, exp)
+	auth.RevokeSession(tokenString, claims)
 	c.JSON(http.StatusOK, gin.H{"message": "已登出"})
 }
 
+// handleLogoutAll 登出所有设备：递增用户的token版本号，此前签发的 Access/Refresh Token 全部失效
+func (s *Server) handleLogoutAll(c *gin.Context) {
+	userID := c.GetString("user_id")
+	version, err := s.database.IncrementTokenVersion(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LOGOUT_ALL_FAILED", err)
+		return
+	}
+
+	s.recordAudit(c, userID, auditLogoutAll, auditResourceUser, userID, nil)
+	log.Printf("🚪 用户 %s 已登出所有设备（token 版本号 %d）", userID, version)
+	c.JSON(http.StatusOK, gin.H{"message": "已登出所有设备，请重新登录"})
+}
+
 // handleRegister 处理用户注册请求
 func (s *Server) handleRegister(c *gin.Context) {
 	clientIP := c.ClientIP()
@@ -2454,7 +4762,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 	}
 	if !regEnabled {
 		log.Printf("⚠️ [Register] 注册已关闭 (IP: %s)", clientIP)
-		c.JSON(http.StatusForbidden, gin.H{"error": "注册已关闭"})
+		respondError(c, http.StatusForbidden, "REGISTRATION_CLOSED")
 		return
 	}
 
@@ -2466,7 +4774,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("❌ [Register] 请求格式错误 (IP: %s): %v", clientIP, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
@@ -2479,18 +4787,18 @@ func (s *Server) handleRegister(c *gin.Context) {
 		// 内测模式下必须提供有效的内测码
 		if req.BetaCode == "" {
 			log.Printf("⚠️ [Register] 内测模式但未提供内测码 (Email: %s)", req.Email)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "内测期间，注册需要提供内测码"})
+			respondError(c, http.StatusBadRequest, "BETA_CODE_REQUIRED")
 			return
 		}
 
 		// 验证内测码
 		isValid, err := s.database.ValidateBetaCode(req.BetaCode)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "验证内测码失败"})
+			respondError(c, http.StatusInternalServerError, "BETA_CODE_CHECK_FAILED")
 			return
 		}
 		if !isValid {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "内测码无效或已被使用"})
+			respondError(c, http.StatusBadRequest, "BETA_CODE_INVALID")
 			return
 		}
 	}
@@ -2498,8 +4806,8 @@ func (s *Server) handleRegister(c *gin.Context) {
 	// 检查邮箱是否已存在
 	existingUser, err := s.database.GetUserByEmail(req.Email)
 	if err == nil {
-		// 如果用户未完成OTP验证，允许重新获取OTP（支持中断后恢复注册）
-		if !existingUser.OTPVerified {
+		// 如果用户未完成OTP验证（也未通过邮件验证），允许重新获取OTP（支持中断后恢复注册）
+		if !existingUser.OTPVerified && !existingUser.EmailVerified {
 			qrCodeURL := auth.GetOTPQRCodeURL(existingUser.OTPSecret, req.Email)
 			c.JSON(http.StatusOK, gin.H{
 				"user_id":     existingUser.ID,
@@ -2511,21 +4819,21 @@ func (s *Server) handleRegister(c *gin.Context) {
 			return
 		}
 		// 用户已完成验证，拒绝重复注册
-		c.JSON(http.StatusConflict, gin.H{"error": "邮箱已被注册"})
+		respondError(c, http.StatusConflict, "EMAIL_ALREADY_REGISTERED")
 		return
 	}
 
 	// 生成密码哈希
 	passwordHash, err := auth.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码处理失败"})
+		respondError(c, http.StatusInternalServerError, "PASSWORD_HASH_FAILED")
 		return
 	}
 
 	// 生成OTP密钥
 	otpSecret, err := auth.GenerateOTPSecret()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "OTP密钥生成失败"})
+		respondError(c, http.StatusInternalServerError, "OTP_SECRET_FAILED")
 		return
 	}
 
@@ -2541,7 +4849,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 
 	err = s.database.CreateUser(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户失败: " + err.Error()})
+		respondError(c, http.StatusInternalServerError, "CREATE_USER_FAILED", err)
 		return
 	}
 
@@ -2557,14 +4865,29 @@ func (s *Server) handleRegister(c *gin.Context) {
 		}
 	}
 
+	// 配置了邮件服务时发送验证链接：验证邮箱后可直接用密码登录，无需绑定 Authenticator
+	emailSent := false
+	if s.emailEnabled() {
+		if err := s.sendEmailLink(user, config.EmailTokenVerifyEmail); err != nil {
+			log.Printf("⚠️ 发送验证邮件失败 (%s): %v", req.Email, err)
+		} else {
+			emailSent = true
+		}
+	}
+
 	// 返回OTP设置信息
 	qrCodeURL := auth.GetOTPQRCodeURL(otpSecret, req.Email)
+	message := "请使用Google Authenticator扫描二维码并验证OTP"
+	if emailSent {
+		message = "验证邮件已发送，点击邮件中的链接即可完成注册（也可扫描二维码绑定 Google Authenticator）"
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"user_id":     userID,
-		"email":       req.Email,
-		"otp_secret":  otpSecret,
-		"qr_code_url": qrCodeURL,
-		"message":     "请使用Google Authenticator扫描二维码并验证OTP",
+		"user_id":                 userID,
+		"email":                   req.Email,
+		"otp_secret":              otpSecret,
+		"qr_code_url":             qrCodeURL,
+		"email_verification_sent": emailSent,
+		"message":                 message,
 	})
 }
 
@@ -2576,34 +4899,43 @@ func (s *Server) handleCompleteRegistration(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
 	// 获取用户信息
 	user, err := s.database.GetUserByID(req.UserID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
 		return
 	}
 
 	// 验证OTP
 	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "OTP验证码错误"})
+		respondError(c, http.StatusBadRequest, "OTP_CODE_INVALID")
 		return
 	}
 
 	// 更新用户OTP验证状态
 	err = s.database.UpdateUserOTPVerified(req.UserID, true)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户状态失败"})
+		respondError(c, http.StatusInternalServerError, "UPDATE_USER_FAILED")
 		return
 	}
 
+	// 首次完成注册时生成恢复码（丢失 Authenticator 时代替 OTP），明文只返回这一次
+	var recoveryCodes []string
+	if !user.OTPVerified {
+		recoveryCodes, err = s.database.GenerateRecoveryCodes(user.ID)
+		if err != nil {
+			log.Printf("⚠️ 生成恢复码失败 (UserID: %s): %v", user.ID, err)
+		}
+	}
+
 	// 生成 Access/Refresh Token
 	tokenPair, err := auth.GenerateTokenPair(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		respondError(c, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED")
 		return
 	}
 
@@ -2621,6 +4953,7 @@ func (s *Server) handleCompleteRegistration(c *gin.Context) {
 		"refresh_expires_in": tokenPair.RefreshExpiresIn,
 		"user_id":            user.ID,
 		"email":              user.Email,
+		"recovery_codes":     recoveryCodes,
 		"message":            "注册完成",
 	})
 }
@@ -2633,30 +4966,63 @@ func (s *Server) handleLogin(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
 	// 获取用户信息
 	user, err := s.database.GetUserByEmail(req.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "邮箱或密码错误"})
+		s.recordAudit(c, "", auditLoginFailure, auditResourceUser, "", map[string]interface{}{
+			"email":  req.Email,
+			"reason": "user_not_found",
+		})
+		respondError(c, http.StatusUnauthorized, "INVALID_CREDENTIALS")
 		return
 	}
 
 	// 验证密码
 	if !auth.CheckPassword(req.Password, user.PasswordHash) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "邮箱或密码错误"})
+		s.recordAudit(c, user.ID, auditLoginFailure, auditResourceUser, user.ID, map[string]interface{}{
+			"reason": "invalid_password",
+		})
+		respondError(c, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+		return
+	}
+
+	if user.Disabled {
+		respondError(c, http.StatusForbidden, "ACCOUNT_DISABLED")
+		return
+	}
+
+	if s.requireEmailVerification() && !user.EmailVerified {
+		resp := errorResponse(c, "EMAIL_NOT_VERIFIED")
+		resp["email"] = user.Email
+		resp["requires_email_verification"] = true
+		c.JSON(http.StatusForbidden, resp)
+		return
+	}
+
+	// 已验证邮箱但未绑定 Authenticator：密码登录即完成（邮箱作为账户恢复方式）
+	if !user.OTPVerified && user.EmailVerified {
+		resp, err := loginTokenResponse(user, "登录成功")
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED")
+			return
+		}
+		s.recordAudit(c, user.ID, auditLoginSuccess, auditResourceUser, user.ID, map[string]interface{}{
+			"method": "password",
+		})
+		c.JSON(http.StatusOK, resp)
 		return
 	}
 
 	// 检查OTP是否已验证
 	if !user.OTPVerified {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":              "账户未完成OTP设置",
-			"user_id":            user.ID,
-			"requires_otp_setup": true,
-		})
+		resp := errorResponse(c, "OTP_SETUP_INCOMPLETE")
+		resp["user_id"] = user.ID
+		resp["requires_otp_setup"] = true
+		c.JSON(http.StatusUnauthorized, resp)
 		return
 	}
 
@@ -2677,31 +5043,39 @@ func (s *Server) handleVerifyOTP(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
 	// 获取用户信息
 	user, err := s.database.GetUserByID(req.UserID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return
+	}
+	if user.Disabled {
+		respondError(c, http.StatusForbidden, "ACCOUNT_DISABLED")
 		return
 	}
 
-	// 验证OTP
-	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+	// 验证OTP（丢失 Authenticator 时可使用恢复码，使用后作废）
+	ok, usedRecoveryCode := s.verifySecondFactor(user, req.OTPCode)
+	if !ok {
+		s.recordAudit(c, user.ID, auditLoginFailure, auditResourceUser, user.ID, map[string]interface{}{
+			"reason": "invalid_otp",
+		})
+		respondError(c, http.StatusBadRequest, "VERIFICATION_CODE_INVALID")
 		return
 	}
 
 	// 生成新的 Token Pair（Access + Refresh）
 	tokenPair, err := auth.GenerateTokenPair(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		respondError(c, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"token":              tokenPair.AccessToken, // 向後兼容舊版前端
 		"access_token":       tokenPair.AccessToken,
 		"refresh_token":      tokenPair.RefreshToken,
@@ -2710,7 +5084,20 @@ func (s *Server) handleVerifyOTP(c *gin.Context) {
 		"user_id":            user.ID,
 		"email":              user.Email,
 		"message":            "登录成功",
+	}
+	if usedRecoveryCode {
+		resp["recovery_code_used"] = true
+		resp["recovery_codes_remaining"] = s.recoveryCodesRemaining(user.ID)
+		resp["message"] = "登录成功（已使用恢复码，请尽快重新绑定 Authenticator）"
+	}
+	method := "otp"
+	if usedRecoveryCode {
+		method = "recovery_code"
+	}
+	s.recordAudit(c, user.ID, auditLoginSuccess, auditResourceUser, user.ID, map[string]interface{}{
+		"method": method,
 	})
+	c.JSON(http.StatusOK, resp)
 }
 
 // handleRefreshToken 刷新访问令牌（使用 Refresh Token 获取新的 Token Pair）
@@ -2720,15 +5107,19 @@ func (s *Server) handleRefreshToken(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 refresh_token 参数"})
+		respondError(c, http.StatusBadRequest, "REFRESH_TOKEN_MISSING")
 		return
 	}
 
 	// 调用 auth.RefreshAccessToken 刷新令牌（自动进行 Token Rotation）
 	tokenPair, err := auth.RefreshAccessToken(req.RefreshToken)
+	if errors.Is(err, auth.ErrTokenVersionUnavailable) {
+		respondError(c, http.StatusServiceUnavailable, "AUTH_CHECK_UNAVAILABLE")
+		return
+	}
 	if err != nil {
 		log.Printf("❌ [AUTH] Refresh Token 刷新失败: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh Token 无效或已过期"})
+		respondError(c, http.StatusUnauthorized, "REFRESH_TOKEN_INVALID")
 		return
 	}
 
@@ -2753,41 +5144,110 @@ func (s *Server) handleResetPassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
 	// 查询用户
 	user, err := s.database.GetUserByEmail(req.Email)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "邮箱不存在"})
+		respondError(c, http.StatusNotFound, "EMAIL_NOT_FOUND")
 		return
 	}
 
-	// 验证 OTP
-	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Google Authenticator 验证码错误"})
+	// 验证 OTP（也可使用恢复码，使用后作废）
+	if ok, _ := s.verifySecondFactor(user, req.OTPCode); !ok {
+		respondError(c, http.StatusBadRequest, "OTP_OR_RECOVERY_CODE_INVALID")
 		return
 	}
 
 	// 生成新密码哈希
 	newPasswordHash, err := auth.HashPassword(req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码处理失败"})
+		respondError(c, http.StatusInternalServerError, "PASSWORD_HASH_FAILED")
 		return
 	}
 
 	// 更新密码
 	err = s.database.UpdateUserPassword(user.ID, newPasswordHash)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码更新失败"})
+		respondError(c, http.StatusInternalServerError, "PASSWORD_UPDATE_FAILED")
 		return
 	}
+	s.revokeUserSessions(user)
 
+	s.recordAudit(c, user.ID, auditPasswordReset, auditResourceUser, user.ID, map[string]interface{}{
+		"method": "otp",
+	})
 	log.Printf("✓ 用户 %s 密码已重置", user.Email)
 	c.JSON(http.StatusOK, gin.H{"message": "密码重置成功，请使用新密码登录"})
 }
 
+// handleChangePassword 已登录用户修改密码（验证当前密码 + OTP）
+// 修改成功后递增token版本号，包括当前会话在内此前签发的所有 Access/Refresh Token 失效（所有设备强制重新登录）
+func (s *Server) handleChangePassword(c *gin.Context) {
+	if c.GetString("api_key_id") != "" {
+		respondError(c, http.StatusForbidden, "API_KEY_CANNOT_CHANGE_PASSWORD")
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required,min=6"`
+		OTPCode         string `json:"otp_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return
+	}
+
+	if !auth.CheckPassword(req.CurrentPassword, user.PasswordHash) {
+		respondError(c, http.StatusUnauthorized, "CURRENT_PASSWORD_INCORRECT")
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		respondError(c, http.StatusBadRequest, "PASSWORD_UNCHANGED")
+		return
+	}
+
+	// 验证 OTP（也可使用恢复码，使用后作废）
+	if ok, _ := s.verifySecondFactor(user, req.OTPCode); !ok {
+		respondError(c, http.StatusBadRequest, "OTP_OR_RECOVERY_CODE_INVALID")
+		return
+	}
+
+	newPasswordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "PASSWORD_HASH_FAILED")
+		return
+	}
+
+	if err := s.database.UpdateUserPassword(user.ID, newPasswordHash); err != nil {
+		respondError(c, http.StatusInternalServerError, "PASSWORD_UPDATE_FAILED")
+		return
+	}
+	s.revokeUserSessions(user)
+
+	s.recordAudit(c, user.ID, auditPasswordChange, auditResourceUser, user.ID, nil)
+	log.Printf("🔑 用户 %s 已修改密码，所有会话需重新登录", user.Email)
+	c.JSON(http.StatusOK, gin.H{"message": "密码修改成功，请使用新密码重新登录"})
+}
+
+// revokeUserSessions 修改/重置密码后递增用户的token版本号，此前签发的 Access/Refresh Token 全部失效
+// 密码已更新成功，递增失败只记录日志（用户仍可通过登出所有设备再次撤销）
+func (s *Server) revokeUserSessions(user *config.User) {
+	if _, err := s.database.IncrementTokenVersion(user.ID); err != nil {
+		log.Printf("⚠️ 用户 %s 修改密码后撤销旧会话失败: %v", user.Email, err)
+	}
+}
+
 // initUserDefaultConfigs 为新用户初始化默认的模型和交易所配置
 func (s *Server) initUserDefaultConfigs(userID string) error {
 	// 注释掉自动创建默认配置，让用户手动添加
@@ -2802,7 +5262,7 @@ func (s *Server) handleGetSupportedModels(c *gin.Context) {
 	models, err := s.database.GetAIModels("default")
 	if err != nil {
 		log.Printf("❌ 获取支持的AI模型失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取支持的AI模型失败"})
+		respondError(c, http.StatusInternalServerError, "GET_SUPPORTED_MODELS_FAILED")
 		return
 	}
 
@@ -2828,7 +5288,7 @@ func (s *Server) handleGetSupportedExchanges(c *gin.Context) {
 	exchanges, err := s.database.GetExchanges("default")
 	if err != nil {
 		log.Printf("❌ 获取支持的交易所失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取支持的交易所失败"})
+		respondError(c, http.StatusInternalServerError, "GET_SUPPORTED_EXCHANGES_FAILED")
 		return
 	}
 
@@ -2850,6 +5310,16 @@ func (s *Server) handleGetSupportedExchanges(c *gin.Context) {
 	c.JSON(http.StatusOK, safeExchanges)
 }
 
+// handleGetSupportedIndicators 获取交易员 indicators 配置支持的行情指标
+func (s *Server) handleGetSupportedIndicators(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"indicators":          market.SupportedIndicators(),
+		"default_indicators":  market.DefaultIndicators,
+		"default_kline_limit": market.DefaultKlineLimit,
+		"max_kline_limit":     market.MaxKlineLimit,
+	})
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
@@ -2858,27 +5328,61 @@ func (s *Server) Start() error {
 	log.Printf("  • GET  /api/health           - 健康检查")
 	log.Printf("  • GET  /api/traders          - 公开的AI交易员排行榜前50名（无需认证）")
 	log.Printf("  • GET  /api/competition      - 公开的竞赛数据（无需认证）")
+	log.Printf("  • GET  /api/competition/snapshot/:id - 竞赛排行榜快照（无需认证）")
+	log.Printf("  • POST /api/competition/snapshot - 创建竞赛排行榜快照（管理员）")
+	log.Printf("  • POST /api/admin/maintenance - 开启/关闭系统维护模式，暂停所有交易员决策（管理员）")
+	log.Printf("  • POST /api/admin/decision-logs/encrypt - 加密历史决策记录中的提示词字段（管理员）")
 	log.Printf("  • GET  /api/top-traders      - 前5名交易员数据（无需认证，表现对比用）")
 	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 公开的收益率历史数据（无需认证，竞赛用）")
 	log.Printf("  • GET  /api/equity-history-batch?trader_ids=a,b,c - 批量获取历史数据（无需认证，表现对比优化）")
 	log.Printf("  • GET  /api/traders/:id/public-config - 公开的交易员配置（无需认证，不含敏感信息）")
+	log.Printf("  • GET  /api/ws?token=xxx&trader_id=a,b - 实时推送决策周期、账户和持仓变化（WebSocket）")
 	log.Printf("  • POST /api/traders          - 创建新的AI交易员")
 	log.Printf("  • DELETE /api/traders/:id    - 删除AI交易员")
 	log.Printf("  • POST /api/traders/:id/start - 启动AI交易员")
 	log.Printf("  • POST /api/traders/:id/stop  - 停止AI交易员")
+	log.Printf("  • GET  /api/traders/:id/logs?level=warn&limit=200 - 交易员最近运行日志（内存环形缓冲）")
 	log.Printf("  • GET  /api/models           - 获取AI模型配置")
 	log.Printf("  • PUT  /api/models           - 更新AI模型配置")
+	log.Printf("  • POST /api/models           - 新建AI模型账户（多账户）")
+	log.Printf("  • PUT  /api/models/:id       - 按ID更新AI模型账户")
 	log.Printf("  • GET  /api/exchanges        - 获取交易所配置")
 	log.Printf("  • PUT  /api/exchanges        - 更新交易所配置")
+	log.Printf("  • POST /api/exchanges        - 新建交易所账户（多账户）")
+	log.Printf("  • PUT  /api/exchanges/:id    - 按ID更新交易所账户")
 	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
 	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
+	log.Printf("  • POST /api/positions/close          - 手动按比例平仓（trader_id/symbol/side/percentage）")
+	log.Printf("  • POST /api/positions/annotate       - 持仓备注/锁定（trader_id/symbol/side/note/locked）")
+	log.Printf("  • GET  /api/orders?trader_id=xxx     - 指定trader的未成交挂单")
+	log.Printf("  • DELETE /api/orders/:orderId?trader_id=xxx&symbol=SYMBOL - 手动撤销挂单")
 	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
+	log.Printf("  • GET  /api/decisions/success-rate?trader_id=xxx&bucket=day - 指定trader的决策执行成功率趋势")
+	log.Printf("  • DELETE /api/user/history?confirm=true[&trader_id=xxx] - 清空决策记录和交易历史")
+	log.Printf("  • GET  /api/user/security    - 两步验证状态和剩余恢复码数量")
+	log.Printf("  • POST /api/user/recovery-codes/regenerate - 重新生成恢复码（需要当前OTP）")
+	log.Printf("  • POST /api/user/otp/setup   - 生成新的OTP密钥（重新绑定 Authenticator）")
+	log.Printf("  • PUT  /api/user/otp         - 确认重新绑定 Authenticator（需要密码）")
+	log.Printf("  • POST /api/verify-email     - 通过邮件链接验证邮箱（需配置 SMTP）")
+	log.Printf("  • POST /api/request-password-reset - 发送密码重置邮件（需配置 SMTP）")
+	log.Printf("  • POST /api/reset-password-with-token - 通过邮件链接重置密码")
+	log.Printf("  • PUT  /api/admin/smtp       - 配置 SMTP 发件服务（管理员）")
+	log.Printf("  • POST /api/admin/backup     - 立即备份数据库（管理员）")
+	log.Printf("  • GET  /api/admin/backups    - 数据库备份列表及最近一次备份结果（管理员）")
+	log.Printf("  • GET  /api/admin/backups/:name/download - 下载数据库备份（管理员）")
+	log.Printf("  • POST /api/user/logout-all  - 登出所有设备（此前签发的token全部失效）")
+	log.Printf("  • GET  /api/user/audit-log?action=&since=&until= - 账户操作审计日志（登录、配置变更等）")
+	log.Printf("  • GET  /api/admin/audit-log?user_id= - 所有用户的审计日志（管理员）")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
 	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
 	log.Println()
 
+	// 启动后台清理任务
+	go s.runSnapshotPruner()
+	go s.runBackupScheduler()
+
 	// 创建 http.Server 以支持 graceful shutdown
 	s.httpServer = &http.Server{
 		Addr:    addr,
@@ -2890,6 +5394,12 @@ func (s *Server) Start() error {
 
 // Shutdown 优雅关闭 API 服务器
 func (s *Server) Shutdown() error {
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+
 	if s.httpServer == nil {
 		return nil
 	}
@@ -2927,7 +5437,7 @@ func (s *Server) handleGetPromptTemplate(c *gin.Context) {
 
 	template, err := decision.GetPromptTemplate(templateName)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("模板不存在: %s", templateName)})
+		respondError(c, http.StatusNotFound, "TEMPLATE_NOT_FOUND", templateName)
 		return
 	}
 
@@ -2937,29 +5447,41 @@ func (s *Server) handleGetPromptTemplate(c *gin.Context) {
 	})
 }
 
-// handlePublicTraderList 获取公开的交易员列表（无需认证）
+// handlePublicTraderList 获取公开的交易员排行榜（无需认证）
+// 查询参数：sort（total_pnl_pct/total_equity/win_rate/running_days）、order（desc/asc）、
+// period（24h/7d/30d/all，周期收益率基于周期起点的净值快照）、page、page_size（最大 100）
 func (s *Server) handlePublicTraderList(c *gin.Context) {
-	// 从所有用户获取交易员信息
-	competition, err := s.traderManager.GetCompetitionData()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取交易员列表失败: %v", err),
-		})
+	query := manager.LeaderboardQuery{
+		Sort:   strings.TrimSpace(c.Query("sort")),
+		Order:  strings.ToLower(strings.TrimSpace(c.Query("order"))),
+		Period: strings.TrimSpace(c.Query("period")),
+	}
+	for param, target := range map[string]*int{"page": &query.Page, "page_size": &query.PageSize} {
+		raw := strings.TrimSpace(c.Query(param))
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			respondError(c, http.StatusBadRequest, "PARAM_INVALID", param, raw)
+			return
+		}
+		*target = value
+	}
+	if err := query.Normalize(); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
 		return
 	}
 
-	// 获取traders数组
-	tradersData, exists := competition["traders"]
-	if !exists {
-		c.JSON(http.StatusOK, []map[string]interface{}{})
+	leaderboard, err := s.traderManager.GetLeaderboard(query)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LIST_TRADERS_FAILED", err)
 		return
 	}
 
-	traders, ok := tradersData.([]map[string]interface{})
+	traders, ok := leaderboard["traders"].([]map[string]interface{})
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "交易员数据格式错误",
-		})
+		respondError(c, http.StatusInternalServerError, "TRADER_DATA_INVALID")
 		return
 	}
 
@@ -2967,6 +5489,7 @@ func (s *Server) handlePublicTraderList(c *gin.Context) {
 	result := make([]map[string]interface{}, 0, len(traders))
 	for _, trader := range traders {
 		result = append(result, map[string]interface{}{
+			"rank":                   trader["rank"],
 			"trader_id":              trader["trader_id"],
 			"trader_name":            trader["trader_name"],
 			"ai_model":               trader["ai_model"],
@@ -2978,36 +5501,176 @@ func (s *Server) handlePublicTraderList(c *gin.Context) {
 			"position_count":         trader["position_count"],
 			"margin_used_pct":        trader["margin_used_pct"],
 			"system_prompt_template": trader["system_prompt_template"],
+			"period_pnl_pct":         trader["period_pnl_pct"],
+			"win_rate":               trader["win_rate"],
+			"closed_trades":          trader["closed_trades"],
+			"running_days":           trader["running_days"],
 		})
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, gin.H{
+		"traders":     RoundResponseList(result, s.responseDecimals(c)),
+		"count":       leaderboard["count"],
+		"total_count": leaderboard["total_count"],
+		"page":        leaderboard["page"],
+		"page_size":   leaderboard["page_size"],
+		"total_pages": leaderboard["total_pages"],
+		"sort":        leaderboard["sort"],
+		"order":       leaderboard["order"],
+		"period":      leaderboard["period"],
+	})
 }
 
 // handlePublicCompetition 获取公开的竞赛数据（无需认证）
 func (s *Server) handlePublicCompetition(c *gin.Context) {
 	competition, err := s.traderManager.GetCompetitionData()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取竞赛数据失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "GET_COMPETITION_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, s.competitionResponse(c, competition))
+}
+
+// handleCreateCompetitionSnapshot 保存当前竞赛排行榜快照（管理员）
+func (s *Server) handleCreateCompetitionSnapshot(c *gin.Context) {
+	competition, err := s.traderManager.GetCompetitionData()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_COMPETITION_FAILED", err)
+		return
+	}
+
+	// 快照按当前小数位配置冻结
+	data, err := json.Marshal(roundCompetitionData(competition, s.responseDecimals(c)))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "SERIALIZE_COMPETITION_FAILED", err)
+		return
+	}
+
+	dataAsOf := s.traderManager.CompetitionDataAsOf()
+	if dataAsOf.IsZero() {
+		dataAsOf = time.Now()
+	}
+
+	snapshot := &config.CompetitionSnapshot{
+		ID:        uuid.New().String(),
+		Data:      string(data),
+		Window:    manager.CompetitionWindow,
+		DataAsOf:  dataAsOf.UTC().Format(time.RFC3339),
+		CreatedBy: c.GetString("user_id"),
+	}
+	if err := s.database.CreateCompetitionSnapshot(snapshot); err != nil {
+		respondError(c, http.StatusInternalServerError, "SAVE_SNAPSHOT_FAILED", err)
+		return
+	}
+
+	log.Printf("📸 已创建竞赛快照 %s (by %s)", snapshot.ID, snapshot.CreatedBy)
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         snapshot.ID,
+		"window":     snapshot.Window,
+		"data_as_of": snapshot.DataAsOf,
+	})
+}
+
+// handleGetCompetitionSnapshot 获取竞赛排行榜快照（无需认证）
+func (s *Server) handleGetCompetitionSnapshot(c *gin.Context) {
+	snapshot, err := s.database.GetCompetitionSnapshot(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, "SNAPSHOT_NOT_FOUND")
+		return
+	}
+
+	var competition map[string]interface{}
+	if err := json.Unmarshal([]byte(snapshot.Data), &competition); err != nil {
+		respondError(c, http.StatusInternalServerError, "PARSE_SNAPSHOT_FAILED", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, competition)
+	c.JSON(http.StatusOK, gin.H{
+		"id":          snapshot.ID,
+		"window":      snapshot.Window,
+		"data_as_of":  snapshot.DataAsOf,
+		"created_at":  snapshot.CreatedAt,
+		"competition": competition,
+	})
+}
+
+// runSnapshotPruner 定期清理过期的竞赛快照和被拒绝决策记录（保留天数由 snapshot_keep_days / reject_keep_days 配置）
+func (s *Server) runSnapshotPruner() {
+	ticker := time.NewTicker(6 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		s.pruneCompetitionSnapshots()
+		s.pruneRejectedDecisions()
+		s.pruneEmailTokens()
+		s.pruneRevokedTokens()
+
+		select {
+		case <-ticker.C:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// pruneCompetitionSnapshots 清理一次过期的竞赛快照
+func (s *Server) pruneCompetitionSnapshots() {
+	keepDaysStr, _ := s.database.GetSystemConfig("snapshot_keep_days")
+	keepDays, err := strconv.Atoi(strings.TrimSpace(keepDaysStr))
+	if err != nil || keepDays <= 0 {
+		return
+	}
+
+	deleted, err := s.database.PruneCompetitionSnapshots(keepDays)
+	if err != nil {
+		log.Printf("⚠️ 清理竞赛快照失败: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 已清理 %d 个超过 %d 天的竞赛快照", deleted, keepDays)
+	}
+}
+
+// pruneRejectedDecisions 清理一次过期的被拒绝决策记录
+func (s *Server) pruneRejectedDecisions() {
+	keepDaysStr, _ := s.database.GetSystemConfig("reject_keep_days")
+	keepDays, err := strconv.Atoi(strings.TrimSpace(keepDaysStr))
+	if err != nil || keepDays <= 0 {
+		return
+	}
+
+	deleted, err := s.database.PruneRejectedDecisions(keepDays)
+	if err != nil {
+		log.Printf("⚠️ 清理被拒绝的决策记录失败: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 已清理 %d 条超过 %d 天的被拒绝决策记录", deleted, keepDays)
+	}
+}
+
+// pruneRevokedTokens 清理已过期的token撤销记录
+func (s *Server) pruneRevokedTokens() {
+	deleted, err := s.database.PruneRevokedTokens()
+	if err != nil {
+		log.Printf("⚠️ 清理token撤销记录失败: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 已清理 %d 条过期的token撤销记录", deleted)
+	}
 }
 
 // handleTopTraders 获取前5名交易员数据（无需认证，用于表现对比）
 func (s *Server) handleTopTraders(c *gin.Context) {
 	topTraders, err := s.traderManager.GetTopTradersData()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取前10名交易员数据失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "GET_TOP_TRADERS_FAILED", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, topTraders)
+	c.JSON(http.StatusOK, s.competitionResponse(c, topTraders))
 }
 
 // handleEquityHistoryBatch 批量获取多个交易员的收益率历史数据（无需认证，用于表现对比）
@@ -3016,6 +5679,12 @@ func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 		TraderIDs []string `json:"trader_ids"`
 	}
 
+	interval, err := parseEquityInterval(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+
 	// 尝试解析POST请求的JSON body
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		// 如果JSON解析失败，尝试从query参数获取（兼容GET请求）
@@ -3024,15 +5693,13 @@ func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 			// 如果没有指定trader_ids，则返回前5名的历史数据
 			topTraders, err := s.traderManager.GetTopTradersData()
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": fmt.Sprintf("获取前5名交易员失败: %v", err),
-				})
+				respondError(c, http.StatusInternalServerError, "GET_TOP5_TRADERS_FAILED", err)
 				return
 			}
 
 			traders, ok := topTraders["traders"].([]map[string]interface{})
 			if !ok {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "交易员数据格式错误"})
+				respondError(c, http.StatusInternalServerError, "TRADER_DATA_INVALID")
 				return
 			}
 
@@ -3044,7 +5711,7 @@ func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 				}
 			}
 
-			result := s.getEquityHistoryForTraders(traderIDs)
+			result := s.getEquityHistoryForTraders(traderIDs, interval, s.responseDecimals(c))
 			c.JSON(http.StatusOK, result)
 			return
 		}
@@ -3061,12 +5728,12 @@ func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 		requestBody.TraderIDs = requestBody.TraderIDs[:20]
 	}
 
-	result := s.getEquityHistoryForTraders(requestBody.TraderIDs)
+	result := s.getEquityHistoryForTraders(requestBody.TraderIDs, interval, s.responseDecimals(c))
 	c.JSON(http.StatusOK, result)
 }
 
-// getEquityHistoryForTraders 获取多个交易员的历史数据
-func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]interface{} {
+// getEquityHistoryForTraders 获取多个交易员的历史数据（优先查询净值快照表，没有快照时回退到决策日志）
+func (s *Server) getEquityHistoryForTraders(traderIDs []string, interval time.Duration, decimals int) map[string]interface{} {
 	result := make(map[string]interface{})
 	histories := make(map[string]interface{})
 	errors := make(map[string]string)
@@ -3082,8 +5749,26 @@ func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]inter
 			continue
 		}
 
+		snapshots, err := s.database.GetEquitySnapshots(traderID, interval, equityHistoryBatchLimit)
+		if err != nil {
+			log.Printf("⚠️ 查询交易员 %s 的净值快照失败，回退到决策日志: %v", traderID, err)
+		}
+		if len(snapshots) > 0 {
+			history := make([]map[string]interface{}, 0, len(snapshots))
+			for _, snapshot := range snapshots {
+				history = append(history, map[string]interface{}{
+					"timestamp":    snapshot.Timestamp,
+					"total_equity": RoundFloat(snapshot.TotalEquity, decimals),
+					"total_pnl":    RoundFloat(snapshot.UnrealizedPnL, decimals),
+					"balance":      RoundFloat(snapshot.TotalEquity-snapshot.UnrealizedPnL, decimals),
+				})
+			}
+			histories[traderID] = history
+			continue
+		}
+
 		// 获取历史数据（用于对比展示，限制数据量）
-		records, err := trader.GetDecisionLogger().GetLatestRecords(500)
+		records, err := trader.GetDecisionLogger().GetLatestRecords(equityHistoryBatchLimit)
 		if err != nil {
 			errors[traderID] = fmt.Sprintf("获取历史数据失败: %v", err)
 			continue
@@ -3097,9 +5782,9 @@ func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]inter
 
 			history = append(history, map[string]interface{}{
 				"timestamp":    record.Timestamp,
-				"total_equity": totalEquity,
-				"total_pnl":    record.AccountState.TotalUnrealizedProfit,
-				"balance":      record.AccountState.TotalBalance,
+				"total_equity": RoundFloat(totalEquity, decimals),
+				"total_pnl":    RoundFloat(record.AccountState.TotalUnrealizedProfit, decimals),
+				"balance":      RoundFloat(record.AccountState.TotalBalance, decimals),
 			})
 		}
 
@@ -3119,13 +5804,13 @@ func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]inter
 func (s *Server) handleGetPublicTraderConfig(c *gin.Context) {
 	traderID := c.Param("id")
 	if traderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员ID不能为空"})
+		respondError(c, http.StatusBadRequest, "TRADER_ID_REQUIRED")
 		return
 	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
 		return
 	}
 
@@ -3146,80 +5831,179 @@ func (s *Server) handleGetPublicTraderConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// reloadPromptTemplatesWithLog 重新加载提示词模板并记录日志
-func (s *Server) reloadPromptTemplatesWithLog(templateName string) {
-	if err := decision.ReloadPromptTemplates(); err != nil {
-		log.Printf("⚠️  重新加载提示词模板失败: %v", err)
-		return
-	}
-
-	if templateName == "" {
-		log.Printf("✓ 已重新加载系统提示词模板 [当前使用: default (未指定，使用默认)]")
-	} else {
-		log.Printf("✓ 已重新加载系统提示词模板 [当前使用: %s]", templateName)
-	}
-}
-
 // handleCreatePromptTemplate 创建新的提示词模板
 func (s *Server) handleCreatePromptTemplate(c *gin.Context) {
 	var req struct {
 		Name    string `json:"name" binding:"required"`
 		Content string `json:"content" binding:"required"`
+		Note    string `json:"note"` // 版本说明（可选）
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
 	// 检查模板是否已存在
 	if decision.TemplateExists(req.Name) {
-		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("模板已存在: %s", req.Name)})
+		respondError(c, http.StatusConflict, "TEMPLATE_EXISTS", req.Name)
 		return
 	}
 
 	// 保存模板
-	if err := decision.SavePromptTemplate(req.Name, req.Content); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建模板失败: %v", err)})
+	version, err := decision.SavePromptTemplateWithNote(req.Name, req.Content, req.Note)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "CREATE_TEMPLATE_FAILED", err)
 		return
 	}
+	s.recordAudit(c, c.GetString("user_id"), auditPromptTemplateCreate, auditResourcePromptTemplate, req.Name, map[string]interface{}{
+		"version": version,
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "模板创建成功",
 		"name":    req.Name,
+		"version": version,
 	})
 }
 
+// handleValidatePromptTemplate 校验提示词模板：用示例上下文渲染完整提示词并返回模板错误（不调用 AI）
+func (s *Server) handleValidatePromptTemplate(c *gin.Context) {
+	var req struct {
+		Content string `json:"content"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, decision.ValidatePromptTemplate(req.Content))
+}
+
 // handleUpdatePromptTemplate 更新提示词模板
 func (s *Server) handleUpdatePromptTemplate(c *gin.Context) {
 	templateName := c.Param("name")
 
 	var req struct {
 		Content string `json:"content" binding:"required"`
+		Note    string `json:"note"` // 版本说明（可选）
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
 		return
 	}
 
 	// 检查模板是否存在
 	if !decision.TemplateExists(templateName) {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("模板不存在: %s", templateName)})
+		respondError(c, http.StatusNotFound, "TEMPLATE_NOT_FOUND", templateName)
 		return
 	}
 
-	// 更新模板
-	if err := decision.SavePromptTemplate(templateName, req.Content); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新模板失败: %v", err)})
+	// 更新模板（保存为新版本）
+	version, err := decision.SavePromptTemplateWithNote(templateName, req.Content, req.Note)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "UPDATE_TEMPLATE_FAILED", err)
 		return
 	}
+	s.recordAudit(c, c.GetString("user_id"), auditPromptTemplateUpdate, auditResourcePromptTemplate, templateName, map[string]interface{}{
+		"version": version,
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "模板更新成功",
 		"name":    templateName,
+		"version": version,
+	})
+}
+
+// parsePromptVersionParam 解析路径中的版本号（支持 3 或 v3）
+func parsePromptVersionParam(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(strings.TrimPrefix(c.Param("version"), "v"))
+	if err != nil || version <= 0 {
+		respondError(c, http.StatusBadRequest, "TEMPLATE_VERSION_INVALID", c.Param("version"))
+		return 0, false
+	}
+	return version, true
+}
+
+// handlePromptTemplateVersions 获取提示词模板的版本历史
+func (s *Server) handlePromptTemplateVersions(c *gin.Context) {
+	templateName := c.Param("name")
+
+	versions, current, err := decision.ListPromptVersions(templateName)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		return
+	}
+	if len(versions) == 0 && !decision.TemplateExists(templateName) {
+		respondError(c, http.StatusNotFound, "TEMPLATE_NOT_FOUND", templateName)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":     templateName,
+		"current":  current,
+		"versions": versions,
+	})
+}
+
+// handlePromptTemplateVersion 获取提示词模板指定版本的内容
+func (s *Server) handlePromptTemplateVersion(c *gin.Context) {
+	templateName := c.Param("name")
+	version, ok := parsePromptVersionParam(c)
+	if !ok {
+		return
+	}
+
+	content, meta, err := decision.GetPromptVersion(templateName, version)
+	if err != nil {
+		if errors.Is(err, decision.ErrPromptVersionNotFound) {
+			respondError(c, http.StatusNotFound, "TEMPLATE_VERSION_NOT_FOUND", templateName, version)
+		} else {
+			respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":       templateName,
+		"version":    meta.Version,
+		"created_at": meta.CreatedAt,
+		"note":       meta.Note,
+		"content":    content,
+	})
+}
+
+// handleRollbackPromptTemplate 将提示词模板回滚到指定版本并重新加载
+func (s *Server) handleRollbackPromptTemplate(c *gin.Context) {
+	templateName := c.Param("name")
+	version, ok := parsePromptVersionParam(c)
+	if !ok {
+		return
+	}
+
+	if err := decision.RollbackPromptTemplate(templateName, version); err != nil {
+		if errors.Is(err, decision.ErrPromptVersionNotFound) {
+			respondError(c, http.StatusNotFound, "TEMPLATE_VERSION_NOT_FOUND", templateName, version)
+		} else {
+			respondError(c, http.StatusInternalServerError, "ROLLBACK_TEMPLATE_FAILED", err)
+		}
+		return
+	}
+
+	s.recordAudit(c, c.GetString("user_id"), auditPromptTemplateRollback, auditResourcePromptTemplate, templateName, map[string]interface{}{
+		"version": version,
+	})
+	log.Printf("✓ 用户 %s 将提示词模板 %s 回滚到 v%d", c.GetString("user_id"), templateName, version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("模板已回滚到 v%d", version),
+		"name":    templateName,
+		"version": version,
 	})
 }
 
@@ -3230,14 +6014,15 @@ func (s *Server) handleDeletePromptTemplate(c *gin.Context) {
 	// 删除模板
 	if err := decision.DeletePromptTemplate(templateName); err != nil {
 		if strings.Contains(err.Error(), "不能删除系统模板") {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			respondError(c, http.StatusForbidden, "FORBIDDEN", err)
 		} else if strings.Contains(err.Error(), "模板不存在") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respondError(c, http.StatusNotFound, "NOT_FOUND", err)
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除模板失败: %v", err)})
+			respondError(c, http.StatusInternalServerError, "DELETE_TEMPLATE_FAILED", err)
 		}
 		return
 	}
+	s.recordAudit(c, c.GetString("user_id"), auditPromptTemplateDelete, auditResourcePromptTemplate, templateName, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -3245,12 +6030,62 @@ func (s *Server) handleDeletePromptTemplate(c *gin.Context) {
 	})
 }
 
+// defaultPromptTemplate 返回新建交易员使用的系统默认提示词模板，未配置或模板已被删除时回退到 default
+func (s *Server) defaultPromptTemplate() string {
+	name, _ := s.database.GetSystemConfig("default_template")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "default"
+	}
+	if !decision.TemplateExists(name) {
+		log.Printf("⚠️ 系统默认提示词模板 %s 不存在，回退到 default", name)
+		return "default"
+	}
+	return name
+}
+
+// handleGetDefaultTemplate 获取新建交易员使用的系统默认提示词模板（管理员）
+func (s *Server) handleGetDefaultTemplate(c *gin.Context) {
+	configured, _ := s.database.GetSystemConfig("default_template")
+	c.JSON(http.StatusOK, gin.H{
+		"template":   s.defaultPromptTemplate(),
+		"configured": configured,
+	})
+}
+
+// handleSetDefaultTemplate 设置新建交易员使用的系统默认提示词模板（管理员），只影响之后创建的交易员
+func (s *Server) handleSetDefaultTemplate(c *gin.Context) {
+	var req struct {
+		Template string `json:"template" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Template)
+	if !decision.TemplateExists(name) {
+		respondError(c, http.StatusBadRequest, "TEMPLATE_NOT_FOUND", name)
+		return
+	}
+
+	if err := s.database.SetSystemConfig("default_template", name); err != nil {
+		respondError(c, http.StatusInternalServerError, "SAVE_DEFAULT_TEMPLATE_FAILED", err)
+		return
+	}
+
+	log.Printf("✓ 管理员 %s 将系统默认提示词模板设置为: %s", c.GetString("user_id"), name)
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"template": name,
+		"message":  "系统默认模板已更新（仅影响之后新建的交易员）",
+	})
+}
+
 // handleReloadPromptTemplates 重新加载所有提示词模板
 func (s *Server) handleReloadPromptTemplates(c *gin.Context) {
 	if err := decision.ReloadPromptTemplates(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("重新加载失败: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, "RELOAD_TEMPLATES_FAILED", err)
 		return
 	}
 
@@ -3261,3 +6096,190 @@ func (s *Server) handleReloadPromptTemplates(c *gin.Context) {
 		"count":   len(templates),
 	})
 }
+
+// handleGenerateBetaCodes 生成内测码（管理员），count 默认 1，返回新生成的内测码
+func (s *Server) handleGenerateBetaCodes(c *gin.Context) {
+	count, err := strconv.Atoi(c.DefaultQuery("count", "1"))
+	if err != nil || count <= 0 || count > config.MaxBetaCodesBatch {
+		respondError(c, http.StatusBadRequest, "BETA_CODE_COUNT_INVALID", config.MaxBetaCodesBatch)
+		return
+	}
+
+	codes, err := s.database.GenerateBetaCodes(count)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GENERATE_BETA_CODES_FAILED", err)
+		return
+	}
+
+	log.Printf("🎟️ 管理员 %s 生成了 %d 个内测码", c.GetString("user_id"), len(codes))
+	c.JSON(http.StatusOK, gin.H{"codes": codes, "count": len(codes)})
+}
+
+// handleListBetaCodes 列出内测码及使用情况（管理员）
+func (s *Server) handleListBetaCodes(c *gin.Context) {
+	codes, err := s.database.ListBetaCodes()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LIST_BETA_CODES_FAILED", err)
+		return
+	}
+	total, used, err := s.database.GetBetaCodeStats()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_BETA_CODE_STATS_FAILED", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"codes":  codes,
+		"total":  total,
+		"used":   used,
+		"unused": total - used,
+	})
+}
+
+// handleAdminPositionsBySymbol 查询全站运行中的交易员在指定币种上的持仓（管理员）
+func (s *Server) handleAdminPositionsBySymbol(c *gin.Context) {
+	symbol := strings.TrimSpace(c.Query("symbol"))
+	if symbol == "" {
+		respondError(c, http.StatusBadRequest, "SYMBOL_REQUIRED")
+		return
+	}
+	symbol = market.Normalize(symbol)
+
+	holders, failures := s.traderManager.GetSymbolHolders(symbol)
+	longCount, shortCount := 0, 0
+	for _, h := range holders {
+		if h["side"] == "long" {
+			longCount++
+		} else if h["side"] == "short" {
+			shortCount++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":      symbol,
+		"holders":     holders,
+		"count":       len(holders),
+		"long_count":  longCount,
+		"short_count": shortCount,
+		"failures":    failures,
+	})
+}
+
+// handleAdminListUsers 列出所有用户及其交易员数量（管理员）
+func (s *Server) handleAdminListUsers(c *gin.Context) {
+	users, err := s.database.ListUsersWithStats()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LIST_USERS_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"users": users, "total": len(users)})
+}
+
+// adminTargetUser 校验管理员操作的目标用户（不能操作系统 admin 用户和自己）
+func (s *Server) adminTargetUser(c *gin.Context) (string, bool) {
+	targetID := c.Param("id")
+	if targetID == "admin" || targetID == c.GetString("user_id") {
+		respondError(c, http.StatusBadRequest, "CANNOT_MODIFY_SELF")
+		return "", false
+	}
+	if _, err := s.database.GetUserByID(targetID); err != nil {
+		respondError(c, http.StatusNotFound, "USER_NOT_FOUND")
+		return "", false
+	}
+	return targetID, true
+}
+
+// stopUserTraders 停止用户所有运行中的交易员，remove 为 true 时同时从内存中移除
+// 返回停止的交易员数量
+func (s *Server) stopUserTraders(userID string, remove bool) int {
+	traders, err := s.database.GetTraders(userID)
+	if err != nil {
+		log.Printf("⚠️ 获取用户 %s 的交易员失败: %v", userID, err)
+		return 0
+	}
+
+	stopped := 0
+	for _, record := range traders {
+		if at, err := s.traderManager.GetTrader(record.ID); err == nil {
+			if running, ok := at.GetStatus()["is_running"].(bool); ok && running {
+				at.Stop()
+				stopped++
+			}
+		}
+		if remove {
+			if err := s.traderManager.RemoveTrader(record.ID); err != nil {
+				log.Printf("⚠️ 从内存中移除交易员时出现警告: %v", err)
+			}
+			continue
+		}
+		if record.IsRunning {
+			if err := s.database.UpdateTraderStatus(userID, record.ID, false); err != nil {
+				log.Printf("⚠️  更新交易员状态失败: %v", err)
+			}
+		}
+	}
+	return stopped
+}
+
+// handleAdminDisableUser 禁用用户并停止其所有交易员（管理员）
+func (s *Server) handleAdminDisableUser(c *gin.Context) {
+	userID, ok := s.adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := s.database.SetUserDisabled(userID, true); err != nil {
+		respondError(c, http.StatusInternalServerError, "DISABLE_USER_FAILED", err)
+		return
+	}
+	stopped := s.stopUserTraders(userID, false)
+
+	log.Printf("🚫 管理员 %s 禁用了用户 %s（停止 %d 个交易员）", c.GetString("user_id"), userID, stopped)
+	c.JSON(http.StatusOK, gin.H{"message": "用户已禁用", "stopped_traders": stopped})
+}
+
+// handleAdminEnableUser 重新启用被禁用的用户（管理员），交易员需用户自行启动
+func (s *Server) handleAdminEnableUser(c *gin.Context) {
+	userID, ok := s.adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := s.database.SetUserDisabled(userID, false); err != nil {
+		respondError(c, http.StatusInternalServerError, "ENABLE_USER_FAILED", err)
+		return
+	}
+
+	log.Printf("✅ 管理员 %s 重新启用了用户 %s", c.GetString("user_id"), userID)
+	c.JSON(http.StatusOK, gin.H{"message": "用户已启用"})
+}
+
+// handleAdminDeleteUser 删除用户及其全部数据（管理员）
+// 先停止并从内存中移除交易员，再级联删除数据库记录
+func (s *Server) handleAdminDeleteUser(c *gin.Context) {
+	userID, ok := s.adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	stopped := s.stopUserTraders(userID, true)
+	if err := s.database.DeleteUser(userID); err != nil {
+		respondError(c, http.StatusInternalServerError, "DELETE_USER_FAILED", err)
+		return
+	}
+
+	log.Printf("🗑️ 管理员 %s 删除了用户 %s（停止 %d 个交易员）", c.GetString("user_id"), userID, stopped)
+	c.JSON(http.StatusOK, gin.H{"message": "用户已删除", "stopped_traders": stopped})
+}
+
+// handleRevokeBetaCode 作废未使用的内测码（管理员）
+func (s *Server) handleRevokeBetaCode(c *gin.Context) {
+	code := strings.TrimSpace(c.Param("code"))
+	if err := s.database.RevokeBetaCode(code); err != nil {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", err)
+		return
+	}
+
+	log.Printf("🎟️ 管理员 %s 作废了内测码 %s", c.GetString("user_id"), code)
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
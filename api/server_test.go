@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"nofx/config"
 )
@@ -372,3 +373,110 @@ func getDecryptionErrorMessage(errMsg string) string {
 	}
 	return "解密数据失败"
 }
+
+// TestEffectiveConfigMismatches 测试数据库配置与运行配置的差异检测
+func TestEffectiveConfigMismatches(t *testing.T) {
+	record := &config.TraderRecord{
+		Name:                "trader",
+		BTCETHLeverage:      5,
+		AltcoinLeverage:     3,
+		ScanIntervalMinutes: 3,
+		IsCrossMargin:       true,
+		OrderStrategy:       "market_only",
+		Timeframes:          "15m,4h",
+		WeekendTrading:      true,
+	}
+	effective := map[string]interface{}{
+		"name":                       "trader",
+		"btc_eth_leverage":           10, // 数据库已更新但内存未重载
+		"altcoin_leverage":           3,
+		"scan_interval_minutes":      3,
+		"is_cross_margin":            true,
+		"system_prompt_template":     "",
+		"custom_prompt":              "",
+		"override_base_prompt":       false,
+		"order_strategy":             "market_only",
+		"timeframes":                 []string{"15m", "4h"},
+		"kline_limit":                0,
+		"indicators":                 []string{},
+		"max_trades_per_day":         0,
+		"hold_cache_pct":             0.0,
+		"max_exposure_multiple":      0.0,
+		"respect_signal_bias":        false,
+		"dry_run":                    false,
+		"alert_drawdown_pct":         0.0,
+		"alert_daily_loss_pct":       0.0,
+		"ai_quality_window":          0,
+		"ai_quality_max_failure_pct": 0.0,
+		"ai_quality_pause_minutes":   0,
+		"max_consecutive_errors":     0,
+		"daily_report":               false,
+		"unfunded_threshold":         0.0,
+		"max_ai_calls_per_day":       0,
+		"reject_non_candidates":      false,
+		"open_verify_delay_ms":       0,
+		"trading_window":             "全天",
+		"flatten_on_window_close":    false,
+		"max_positions":              0,
+		"max_position_size_usd":      0.0,
+		"portfolio_group":            "",
+	}
+
+	mismatches := effectiveConfigMismatches(record, effective)
+	if len(mismatches) != 1 {
+		t.Fatalf("期望 1 个不一致字段，实际 %d: %v", len(mismatches), mismatches)
+	}
+	if mismatches[0]["field"] != "btc_eth_leverage" || mismatches[0]["db"] != 5 || mismatches[0]["runtime"] != 10 {
+		t.Errorf("不一致字段内容不正确: %v", mismatches[0])
+	}
+
+	// 时间线在内存中解析为切片，顺序或内容不同应被检测到
+	effective["btc_eth_leverage"] = 5
+	effective["timeframes"] = []string{"4h"}
+	mismatches = effectiveConfigMismatches(record, effective)
+	if len(mismatches) != 1 || mismatches[0]["field"] != "timeframes" {
+		t.Errorf("应检测到时间线不一致: %v", mismatches)
+	}
+}
+
+// TestNormalizeMarketDataConfig 测试交易员K线数量和指标配置的校验
+func TestNormalizeMarketDataConfig(t *testing.T) {
+	for _, limit := range []int{0, 1, 500} {
+		if err := validateKlineLimit(limit); err != nil {
+			t.Errorf("validateKlineLimit(%d) 不应报错: %v", limit, err)
+		}
+	}
+	for _, limit := range []int{-1, 501} {
+		if err := validateKlineLimit(limit); err == nil {
+			t.Errorf("validateKlineLimit(%d) 应报错", limit)
+		}
+	}
+
+	got, err := normalizeIndicators("EMA50, rsi14 ,ema50")
+	if err != nil || got != "ema50,rsi14" {
+		t.Errorf("normalizeIndicators = %q (%v), 期望 \"ema50,rsi14\"", got, err)
+	}
+	if _, err := normalizeIndicators("ema50,supertrend"); err == nil {
+		t.Error("未知指标应报错")
+	}
+}
+
+// TestParseTimeParam 测试对账接口的时间参数解析
+func TestParseTimeParam(t *testing.T) {
+	def := time.UnixMilli(42)
+	if got, err := parseTimeParam("", def); err != nil || !got.Equal(def) {
+		t.Errorf("空参数应返回默认值: %v %v", got, err)
+	}
+	if got, err := parseTimeParam("1700000000000", def); err != nil || got.UnixMilli() != 1700000000000 {
+		t.Errorf("毫秒时间戳解析错误: %v %v", got, err)
+	}
+	if got, err := parseTimeParam("2024-01-02T03:04:05Z", def); err != nil || got.Unix() != 1704164645 {
+		t.Errorf("RFC3339 解析错误: %v %v", got, err)
+	}
+	if got, err := parseTimeParam("2024-01-02", def); err != nil || got.Day() != 2 {
+		t.Errorf("日期解析错误: %v %v", got, err)
+	}
+	if _, err := parseTimeParam("yesterday", def); err == nil {
+		t.Error("无法识别的格式应返回错误")
+	}
+}
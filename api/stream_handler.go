@@ -0,0 +1,178 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/stream"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsMaxTraders 单个连接最多订阅的交易员数量
+	wsMaxTraders = 50
+	// wsAuthTimeout 未在 URL 中携带 token 时，等待首条认证消息的时间
+	wsAuthTimeout = 10 * time.Second
+	// wsPingInterval 心跳间隔，超过 wsPongWait 未收到 pong 视为断开
+	wsPingInterval = 30 * time.Second
+	wsPongWait     = 60 * time.Second
+	wsWriteWait    = 10 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// 连接必须携带有效JWT，跨域页面拿不到用户的 token，因此不限制 Origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClientMessage 客户端发送的消息
+// {"action":"auth","token":"..."} 认证（URL 未携带 token 时必须是第一条消息）
+// {"action":"subscribe","trader_ids":["..."]} 替换订阅的交易员
+type wsClientMessage struct {
+	Action    string   `json:"action"`
+	Token     string   `json:"token"`
+	TraderIDs []string `json:"trader_ids"`
+}
+
+// handleStream 实时推送交易员事件的 WebSocket 接口
+// 认证：?token=JWT 或连接后首条 auth 消息；订阅：?trader_id=a,b 或 subscribe 消息（只能订阅自己的交易员）
+// 推送：每个决策周期结束后的 decision（决策摘要）、account（账户快照）和持仓变化时的 positions
+func (s *Server) handleStream(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("⚠️ WebSocket 升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	token := c.Query("token")
+	if token == "" {
+		conn.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+		var msg wsClientMessage
+		if err := conn.ReadJSON(&msg); err != nil || msg.Action != "auth" {
+			writeWSError(conn, "缺少认证信息：请在 URL 中携带 token 或首条消息发送 auth")
+			return
+		}
+		token = msg.Token
+	}
+	claims, err := validateToken(token)
+	if err != nil {
+		writeWSError(conn, err.Error())
+		return
+	}
+	userID := claims.UserID
+
+	var initial []string
+	for _, id := range strings.Split(c.Query("trader_id"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			initial = append(initial, id)
+		}
+	}
+	if err := s.checkStreamTraders(userID, initial); err != nil {
+		writeWSError(conn, err.Error())
+		return
+	}
+
+	sub := stream.Subscribe(initial, stream.DefaultBufferSize)
+	defer sub.Close()
+	log.Printf("🔌 用户 %s 建立实时推送连接（订阅 %d 个交易员）", userID, len(initial))
+
+	// 所有写操作都在写协程中完成（gorilla/websocket 不支持并发写）
+	replies := make(chan interface{}, 8)
+	done := make(chan struct{})
+	go s.streamReader(conn, userID, sub, replies, done)
+
+	replies <- gin.H{"type": "subscribed", "trader_ids": initial}
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			err = writeWSJSON(conn, ev)
+		case reply := <-replies:
+			err = writeWSJSON(conn, reply)
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		}
+		if err != nil {
+			log.Printf("🔌 用户 %s 的实时推送连接已断开: %v", userID, err)
+			return
+		}
+	}
+}
+
+// streamReader 读取客户端消息（订阅变更、心跳），连接断开时关闭 done
+func (s *Server) streamReader(conn *websocket.Conn, userID string, sub *stream.Subscription, replies chan<- interface{}, done chan<- struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(64 * 1024)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg wsClientMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var reply interface{}
+		switch msg.Action {
+		case "subscribe":
+			if err := s.checkStreamTraders(userID, msg.TraderIDs); err != nil {
+				reply = gin.H{"type": "error", "error": err.Error()}
+			} else {
+				sub.SetTraders(msg.TraderIDs)
+				reply = gin.H{"type": "subscribed", "trader_ids": msg.TraderIDs}
+			}
+		case "ping":
+			reply = gin.H{"type": "pong"}
+		default:
+			reply = gin.H{"type": "error", "error": fmt.Sprintf("未知的 action: %s", msg.Action)}
+		}
+		select {
+		case replies <- reply:
+		default:
+			// 客户端发送过快时丢弃回复，不阻塞读取
+		}
+	}
+}
+
+// checkStreamTraders 校验订阅的交易员都属于当前用户
+func (s *Server) checkStreamTraders(userID string, traderIDs []string) error {
+	if len(traderIDs) > wsMaxTraders {
+		return fmt.Errorf("单个连接最多订阅 %d 个交易员", wsMaxTraders)
+	}
+	for _, id := range traderIDs {
+		if _, _, _, err := s.database.GetTraderConfig(userID, id); err != nil {
+			return fmt.Errorf("交易员 %s 不存在或无访问权限", id)
+		}
+	}
+	return nil
+}
+
+func writeWSJSON(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(v)
+}
+
+// writeWSError 发送错误后关闭连接
+func writeWSError(conn *websocket.Conn, message string) {
+	writeWSJSON(conn, gin.H{"type": "error", "error": message})
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, message))
+}
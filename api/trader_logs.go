@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"nofx/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxTraderLogLimit 单次查询运行日志的最大条数
+const maxTraderLogLimit = 1000
+
+// handleTraderLogs 获取交易员最近的运行日志（内存缓冲区，重启后清空）
+// 查询参数：level（最低级别，默认 debug 即全部）、limit（默认 200）
+func (s *Server) handleTraderLogs(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_ACCESSIBLE")
+		return
+	}
+
+	minLevel := logrus.DebugLevel
+	if raw := c.Query("level"); raw != "" {
+		parsed, err := logrus.ParseLevel(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "LOG_LEVEL_INVALID", raw)
+			return
+		}
+		minLevel = parsed
+	}
+
+	limit := 200
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxTraderLogLimit {
+			respondError(c, http.StatusBadRequest, "LIMIT_OUT_OF_RANGE", maxTraderLogLimit)
+			return
+		}
+		limit = parsed
+	}
+
+	logs := logger.RecentTraderLogs(traderID, minLevel, limit)
+	c.JSON(http.StatusOK, gin.H{"trader_id": traderID, "logs": logs, "count": len(logs)})
+}
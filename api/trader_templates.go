@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// traderTemplateExcludedFields 模板不保存的字段：交易员标识、初始余额、交易所/模型绑定
+var traderTemplateExcludedFields = []string{
+	"name", "ai_model_id", "exchange_id", "initial_balance", "model_pool", "fallback_ai_model_id",
+}
+
+// CreateTraderTemplateRequest 从已有交易员保存模板
+type CreateTraderTemplateRequest struct {
+	TraderID    string `json:"trader_id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// CreateTraderFromTemplateRequest 从模板创建交易员（只需填写名称和绑定）
+type CreateTraderFromTemplateRequest struct {
+	Name           string  `json:"name" binding:"required"`
+	AIModelID      string  `json:"ai_model_id" binding:"required"`
+	ExchangeID     string  `json:"exchange_id" binding:"required"`
+	InitialBalance float64 `json:"initial_balance"` // 0=从交易所查询
+}
+
+// traderSettingsFromRecord 把交易员记录转换为创建请求（只包含配置项，不含标识、余额和绑定）
+func traderSettingsFromRecord(t *config.TraderRecord) *CreateTraderRequest {
+	isCrossMargin := t.IsCrossMargin
+	weekendTrading := t.WeekendTrading
+	return &CreateTraderRequest{
+		ScanIntervalMinutes:    t.ScanIntervalMinutes,
+		BTCETHLeverage:         t.BTCETHLeverage,
+		AltcoinLeverage:        t.AltcoinLeverage,
+		TradingSymbols:         t.TradingSymbols,
+		CustomPrompt:           t.CustomPrompt,
+		OverrideBasePrompt:     t.OverrideBasePrompt,
+		SystemPromptTemplate:   t.SystemPromptTemplate,
+		IsCrossMargin:          &isCrossMargin,
+		UseCoinPool:            t.UseCoinPool,
+		UseOITop:               t.UseOITop,
+		TakerFeeRate:           t.TakerFeeRate,
+		MakerFeeRate:           t.MakerFeeRate,
+		OrderStrategy:          t.OrderStrategy,
+		LimitPriceOffset:       t.LimitPriceOffset,
+		LimitTimeoutSeconds:    t.LimitTimeoutSeconds,
+		Timeframes:             t.Timeframes,
+		PortfolioGroup:         t.PortfolioGroup,
+		MaxTradesPerDay:        t.MaxTradesPerDay,
+		ModelPoolMode:          t.ModelPoolMode,
+		LogLevel:               t.LogLevel,
+		HoldCachePct:           t.HoldCachePct,
+		StartPriority:          t.StartPriority,
+		MaxExposureMultiple:    t.MaxExposureMultiple,
+		RespectSignalBias:      t.RespectSignalBias,
+		DryRun:                 t.DryRun,
+		AlertDrawdownPct:       t.AlertDrawdownPct,
+		AlertDailyLossPct:      t.AlertDailyLossPct,
+		AIQualityWindow:        t.AIQualityWindow,
+		AIQualityMaxFailurePct: t.AIQualityMaxFailurePct,
+		AIQualityPauseMinutes:  t.AIQualityPauseMinutes,
+		MaxConsecutiveErrors:   t.MaxConsecutiveErrors,
+		DailyReport:            t.DailyReport,
+		UnfundedThreshold:      t.UnfundedThreshold,
+		Tags:                   t.Tags,
+		MaxAICallsPerDay:       t.MaxAICallsPerDay,
+		RejectNonCandidates:    t.RejectNonCandidates,
+		OpenVerifyDelayMs:      t.OpenVerifyDelayMs,
+		ActiveHours:            t.ActiveHours,
+		WeekendTrading:         &weekendTrading,
+		FlattenOnWindowClose:   t.FlattenOnWindowClose,
+		MaxPositions:           t.MaxPositions,
+		MaxPositionSizeUSD:     t.MaxPositionSizeUSD,
+		BlacklistedSymbols:     t.BlacklistedSymbols,
+		KlineLimit:             t.KlineLimit,
+		Indicators:             t.Indicators,
+		MaxDailyLoss:           t.MaxDailyLoss,
+		MaxDrawdown:            t.MaxDrawdown,
+		StopTradingMinutes:     t.StopTradingMinutes,
+	}
+}
+
+// encodeTemplateConfig 把配置序列化为模板 JSON（去掉标识、余额和绑定字段）
+func encodeTemplateConfig(req *CreateTraderRequest) (string, error) {
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return "", err
+	}
+	for _, key := range traderTemplateExcludedFields {
+		delete(fields, key)
+	}
+	raw, err = json.Marshal(fields)
+	return string(raw), err
+}
+
+// traderTemplateResponse 模板的接口返回格式（config 以 JSON 对象返回）
+func traderTemplateResponse(t *config.TraderTemplate) gin.H {
+	return gin.H{
+		"id":               t.ID,
+		"name":             t.Name,
+		"description":      t.Description,
+		"config":           json.RawMessage(t.Config),
+		"is_system":        t.IsSystem(),
+		"source_trader_id": t.SourceTraderID,
+		"created_at":       t.CreatedAt,
+	}
+}
+
+// handleTraderTemplates 获取当前用户的交易员模板和系统模板
+func (s *Server) handleTraderTemplates(c *gin.Context) {
+	templates, err := s.database.GetTraderTemplates(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_TRADER_TEMPLATES_FAILED", err)
+		return
+	}
+	result := make([]gin.H, 0, len(templates))
+	for _, t := range templates {
+		result = append(result, traderTemplateResponse(t))
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": result})
+}
+
+// handleCreateTraderTemplate 把已有交易员的配置保存为模板（不含名称、余额和交易所/模型绑定）
+func (s *Server) handleCreateTraderTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req CreateTraderTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		respondError(c, http.StatusBadRequest, "INVALID_PARAMETER", "name 不能为空")
+		return
+	}
+
+	traderCfg, _, _, err := s.database.GetTraderConfig(userID, req.TraderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "TRADER_NOT_FOUND")
+		return
+	}
+
+	settings := traderSettingsFromRecord(traderCfg)
+	if e := validateTraderSettings(settings); e != nil {
+		respondError(c, http.StatusBadRequest, e.code, e.args...)
+		return
+	}
+	cfg, err := encodeTemplateConfig(settings)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "CREATE_TRADER_TEMPLATE_FAILED", err)
+		return
+	}
+
+	template := &config.TraderTemplate{
+		ID:             "tpl_" + uuid.New().String(),
+		UserID:         userID,
+		Name:           name,
+		Description:    strings.TrimSpace(req.Description),
+		Config:         cfg,
+		SourceTraderID: traderCfg.ID,
+	}
+	if err := s.database.CreateTraderTemplate(template); err != nil {
+		if errors.Is(err, config.ErrTraderTemplateNameTaken) {
+			respondError(c, http.StatusBadRequest, "TRADER_TEMPLATE_NAME_TAKEN", name)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "CREATE_TRADER_TEMPLATE_FAILED", err)
+		return
+	}
+	s.recordAudit(c, userID, auditTraderTemplateCreate, auditResourceTraderTemplate, template.ID, map[string]interface{}{
+		"name":      name,
+		"trader_id": traderCfg.ID,
+	})
+
+	created, err := s.database.GetTraderTemplate(userID, template.ID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_TRADER_TEMPLATES_FAILED", err)
+		return
+	}
+	c.JSON(http.StatusCreated, traderTemplateResponse(created))
+}
+
+// handleDeleteTraderTemplate 删除自己的交易员模板（系统模板不能删除）
+func (s *Server) handleDeleteTraderTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	templateID := c.Param("templateId")
+
+	template, err := s.database.GetTraderTemplate(userID, templateID)
+	if errors.Is(err, config.ErrTraderTemplateNotFound) {
+		respondError(c, http.StatusNotFound, "TRADER_TEMPLATE_NOT_FOUND")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "DELETE_TRADER_TEMPLATE_FAILED", err)
+		return
+	}
+	if template.IsSystem() {
+		respondError(c, http.StatusForbidden, "TRADER_TEMPLATE_READONLY")
+		return
+	}
+	if err := s.database.DeleteTraderTemplate(userID, templateID); err != nil {
+		respondError(c, http.StatusInternalServerError, "DELETE_TRADER_TEMPLATE_FAILED", err)
+		return
+	}
+	s.recordAudit(c, userID, auditTraderTemplateDelete, auditResourceTraderTemplate, templateID, map[string]interface{}{
+		"name": template.Name,
+	})
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// handleCreateTraderFromTemplate 使用模板配置创建交易员（与创建接口走同一流程）
+func (s *Server) handleCreateTraderFromTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req CreateTraderFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", err)
+		return
+	}
+
+	template, err := s.database.GetTraderTemplate(userID, c.Param("templateId"))
+	if errors.Is(err, config.ErrTraderTemplateNotFound) {
+		respondError(c, http.StatusNotFound, "TRADER_TEMPLATE_NOT_FOUND")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "GET_TRADER_TEMPLATES_FAILED", err)
+		return
+	}
+
+	// 模板配置由 createTrader 按创建交易员的规则重新校验
+	var createReq CreateTraderRequest
+	if err := json.Unmarshal([]byte(template.Config), &createReq); err != nil {
+		respondError(c, http.StatusUnprocessableEntity, "TRADER_TEMPLATE_INVALID", err)
+		return
+	}
+	createReq.Name = req.Name
+	createReq.AIModelID = req.AIModelID
+	createReq.ExchangeID = req.ExchangeID
+	createReq.InitialBalance = req.InitialBalance
+
+	s.createTrader(c, userID, &createReq)
+}
@@ -1,6 +1,9 @@
 package api
 
-import "strings"
+import (
+	"math"
+	"strings"
+)
 
 // MaskSensitiveString 脱敏敏感字符串，只显示前4位和后4位
 // 用于脱敏 API Key、Secret Key、Private Key 等敏感信息
@@ -44,6 +47,7 @@ func SanitizeExchangeConfigForLog(exchanges map[string]struct {
 	AsterUser             string `json:"aster_user"`
 	AsterSigner           string `json:"aster_signer"`
 	AsterPrivateKey       string `json:"aster_private_key"`
+	OKXPassphrase         string `json:"okx_passphrase"`
 }) map[string]interface{} {
 	safe := make(map[string]interface{})
 	for exchangeID, cfg := range exchanges {
@@ -62,6 +66,9 @@ func SanitizeExchangeConfigForLog(exchanges map[string]struct {
 		if cfg.AsterPrivateKey != "" {
 			safeExchange["aster_private_key"] = MaskSensitiveString(cfg.AsterPrivateKey)
 		}
+		if cfg.OKXPassphrase != "" {
+			safeExchange["okx_passphrase"] = MaskSensitiveString(cfg.OKXPassphrase)
+		}
 
 		// 非敏感字段直接添加
 		if cfg.HyperliquidWalletAddr != "" {
@@ -95,3 +102,110 @@ func MaskEmail(email string) string {
 	}
 	return username[:2] + "****@" + domain
 }
+
+// defaultResponseDecimals 响应中金额/盈亏字段默认保留的小数位（USD 计价保留 2 位）
+const defaultResponseDecimals = 2
+
+// maxResponseDecimals 允许配置的最大小数位
+const maxResponseDecimals = 8
+
+// roundedResponseFields 在响应序列化前需要四舍五入的金额/盈亏字段
+// 注意：价格、数量类字段（entry_price、quantity 等）不在此列，小币种价格需要保留完整精度
+var roundedResponseFields = []string{
+	"total_equity",
+	"wallet_balance",
+	"unrealized_profit",
+	"available_balance",
+	"total_pnl",
+	"total_pnl_pct",
+	"initial_balance",
+	"daily_pnl",
+	"margin_used",
+	"margin_used_pct",
+	"unrealized_pnl",
+	"unrealized_pnl_pct",
+	"balance",
+}
+
+// RoundFloat 将浮点数四舍五入到指定小数位（NaN/Inf 原样返回）
+func RoundFloat(v float64, decimals int) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+	pow := math.Pow(10, float64(decimals))
+	return math.Round(v*pow) / pow
+}
+
+// RoundResponseFields 返回 data 的浅拷贝，并对其中的金额/盈亏字段做四舍五入
+// 只在响应边界调用，不修改原 map（竞赛数据等可能来自共享缓存），内部计算保持完整精度
+func RoundResponseFields(data map[string]interface{}, decimals int) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	rounded := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		rounded[k] = v
+	}
+	for _, key := range roundedResponseFields {
+		if v, ok := rounded[key].(float64); ok {
+			rounded[key] = RoundFloat(v, decimals)
+		}
+	}
+	return rounded
+}
+
+// RoundResponseList 对列表中每一项调用 RoundResponseFields
+func RoundResponseList(list []map[string]interface{}, decimals int) []map[string]interface{} {
+	if list == nil {
+		return nil
+	}
+	rounded := make([]map[string]interface{}, len(list))
+	for i, item := range list {
+		rounded[i] = RoundResponseFields(item, decimals)
+	}
+	return rounded
+}
+
+// displayCurrencySymbols 支持的金额展示币种及换算使用的 USDT 交易对（USD 不换算）
+var displayCurrencySymbols = map[string]string{
+	"USD": "",
+	"BTC": "BTCUSDT",
+	"ETH": "ETHUSDT",
+}
+
+// displayCurrencyFields 按展示币种换算的金额字段（百分比字段与币种无关，不换算）
+var displayCurrencyFields = []string{
+	"total_equity", "wallet_balance", "unrealized_profit", "available_balance",
+	"total_pnl", "initial_balance", "daily_pnl", "margin_used",
+}
+
+// NormalizeDisplayCurrency 规范化展示币种，不支持的币种返回 false
+func NormalizeDisplayCurrency(currency string) (string, bool) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	_, ok := displayCurrencySymbols[currency]
+	return currency, ok
+}
+
+// ConvertDisplayCurrency 返回 data 的浅拷贝，追加按展示币种换算的金额字段（如 total_equity_btc）
+// price 为 1 单位展示币种的 USD 价格；原 USD 字段保持不变，内部记账不受影响
+func ConvertDisplayCurrency(data map[string]interface{}, currency string, price float64) map[string]interface{} {
+	if data == nil || price <= 0 {
+		return data
+	}
+	converted := make(map[string]interface{}, len(data)+len(displayCurrencyFields)+2)
+	for k, v := range data {
+		converted[k] = v
+	}
+	suffix := "_" + strings.ToLower(currency)
+	for _, key := range displayCurrencyFields {
+		if v, ok := converted[key].(float64); ok {
+			converted[key+suffix] = RoundFloat(v/price, 8)
+		}
+	}
+	converted["display_currency"] = currency
+	converted["display_price"] = price
+	return converted
+}
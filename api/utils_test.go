@@ -97,6 +97,7 @@ func TestSanitizeExchangeConfigForLog(t *testing.T) {
 		AsterUser             string `json:"aster_user"`
 		AsterSigner           string `json:"aster_signer"`
 		AsterPrivateKey       string `json:"aster_private_key"`
+		OKXPassphrase         string `json:"okx_passphrase"`
 	}{
 		"binance": {
 			Enabled:   true,
@@ -191,3 +192,100 @@ func TestMaskEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestRoundFloat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    float64
+		decimals int
+		expected float64
+	}{
+		{name: "浮点误差", input: 100.00000000001, decimals: 2, expected: 100},
+		{name: "四舍五入进位", input: 12.345, decimals: 2, expected: 12.35},
+		{name: "负数", input: -3.14159, decimals: 3, expected: -3.142},
+		{name: "零位小数", input: 99.5, decimals: 0, expected: 100},
+		{name: "负小数位按0处理", input: 1.6, decimals: -1, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RoundFloat(tt.input, tt.decimals)
+			if result != tt.expected {
+				t.Errorf("RoundFloat(%v, %d) = %v, want %v", tt.input, tt.decimals, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRoundResponseFields(t *testing.T) {
+	original := map[string]interface{}{
+		"total_equity":  100.00000000001,
+		"total_pnl_pct": 1.23456,
+		"entry_price":   0.000012345,
+		"symbol":        "BTCUSDT",
+	}
+
+	rounded := RoundResponseFields(original, 2)
+
+	if rounded["total_equity"] != 100.0 {
+		t.Errorf("total_equity = %v, want 100", rounded["total_equity"])
+	}
+	if rounded["total_pnl_pct"] != 1.23 {
+		t.Errorf("total_pnl_pct = %v, want 1.23", rounded["total_pnl_pct"])
+	}
+	// 价格字段保持完整精度
+	if rounded["entry_price"] != 0.000012345 {
+		t.Errorf("entry_price 不应被四舍五入, got %v", rounded["entry_price"])
+	}
+	if rounded["symbol"] != "BTCUSDT" {
+		t.Errorf("symbol = %v, want BTCUSDT", rounded["symbol"])
+	}
+	// 原 map 不应被修改（内部计算保持完整精度）
+	if original["total_equity"] != 100.00000000001 {
+		t.Errorf("原始数据被修改: %v", original["total_equity"])
+	}
+}
+
+func TestConvertDisplayCurrency(t *testing.T) {
+	original := map[string]interface{}{
+		"total_equity":  12500.0,
+		"total_pnl":     -250.0,
+		"total_pnl_pct": -2.0,
+	}
+
+	// BTC = 50000 USD
+	converted := ConvertDisplayCurrency(original, "BTC", 50000)
+
+	if converted["total_equity_btc"] != 0.25 {
+		t.Errorf("total_equity_btc = %v, want 0.25", converted["total_equity_btc"])
+	}
+	if converted["total_pnl_btc"] != -0.005 {
+		t.Errorf("total_pnl_btc = %v, want -0.005", converted["total_pnl_btc"])
+	}
+	// USD 字段和百分比字段保持不变
+	if converted["total_equity"] != 12500.0 {
+		t.Errorf("total_equity = %v, want 12500", converted["total_equity"])
+	}
+	if _, ok := converted["total_pnl_pct_btc"]; ok {
+		t.Error("百分比字段不应换算")
+	}
+	if converted["display_currency"] != "BTC" || converted["display_price"] != 50000.0 {
+		t.Errorf("display fields = %v/%v", converted["display_currency"], converted["display_price"])
+	}
+	// 原 map 不应被修改
+	if _, ok := original["total_equity_btc"]; ok {
+		t.Error("原始数据被修改")
+	}
+
+	// 价格不可用时原样返回
+	if unchanged := ConvertDisplayCurrency(original, "BTC", 0); len(unchanged) != len(original) {
+		t.Errorf("价格无效时不应追加字段: %v", unchanged)
+	}
+
+	if currency, ok := NormalizeDisplayCurrency(" btc "); !ok || currency != "BTC" {
+		t.Errorf("NormalizeDisplayCurrency(btc) = %s, %v", currency, ok)
+	}
+	if _, ok := NormalizeDisplayCurrency("DOGE"); ok {
+		t.Error("不支持的币种应返回 false")
+	}
+}
@@ -2,6 +2,9 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -16,17 +19,46 @@ import (
 // JWTSecret JWT密钥，将从配置中动态设置
 var JWTSecret []byte
 
-// tokenBlacklist 用于登出后的token黑名单（仅内存，按过期时间清理）
-var tokenBlacklist = struct {
+// revocationList 已撤销token的内存列表（键为 tokenIdentity，值为过期时间，过期自动清理）
+type revocationList struct {
 	sync.RWMutex
 	items map[string]time.Time
-}{items: make(map[string]time.Time)}
+}
+
+// tokenBlacklist 用于登出后的token黑名单
+var tokenBlacklist = revocationList{items: make(map[string]time.Time)}
 
 // refreshTokenBlacklist Refresh Token 黑名单
-var refreshTokenBlacklist = struct {
-	sync.RWMutex
-	items map[string]time.Time
-}{items: make(map[string]time.Time)}
+var refreshTokenBlacklist = revocationList{items: make(map[string]time.Time)}
+
+// Token 类型
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// 為新手用戶優化：延長 Token 有效期以改善用戶體驗
+// Access Token: 7 天（用戶 7 天免登錄）
+// Refresh Token: 30 天（提供更長的自動刷新窗口）
+const (
+	accessTokenExpiry  = 7 * 24 * time.Hour
+	refreshTokenExpiry = 30 * 24 * time.Hour
+)
+
+// RevocationStore 持久化的token撤销列表，由上层注入
+// 未设置时撤销只保存在内存中，服务重启后已登出的token会重新生效
+type RevocationStore interface {
+	RevokeToken(tokenID, tokenType, userID string, expiresAt time.Time) error
+	IsTokenRevoked(tokenID string) (bool, error)
+}
+
+var revocationStore RevocationStore
+
+// tokenVersionLookup 查询用户当前的token版本号（登出所有设备时递增），由上层注入
+var tokenVersionLookup func(userID string) (int, error)
+
+// ErrTokenVersionUnavailable 无法查询用户当前的token版本号，token可能已被撤销，不能放行
+var ErrTokenVersionUnavailable = errors.New("无法校验token版本号")
 
 // maxBlacklistEntries 黑名单最大容量阈值
 const maxBlacklistEntries = 100_000
@@ -39,84 +71,165 @@ func SetJWTSecret(secret string) {
 	JWTSecret = []byte(secret)
 }
 
-// BlacklistToken 将token加入黑名单直到过期
-func BlacklistToken(token string, exp time.Time) {
-	tokenBlacklist.Lock()
-	defer tokenBlacklist.Unlock()
-	tokenBlacklist.items[token] = exp
+// SetRevocationStore 设置持久化的token撤销列表
+func SetRevocationStore(store RevocationStore) {
+	revocationStore = store
+}
+
+// SetTokenVersionLookup 设置token版本号查询函数（登出所有设备、修改/重置密码时递增版本号）
+// 设置后，签发时携带的版本号低于用户当前版本号的 Access Token / Refresh Token 均视为失效
+func SetTokenVersionLookup(lookup func(userID string) (int, error)) {
+	tokenVersionLookup = lookup
+}
+
+// currentTokenVersion 用户当前的token版本号（未设置查询函数时为 0，查询失败时返回 ErrTokenVersionUnavailable）
+func currentTokenVersion(userID string) (int, error) {
+	if tokenVersionLookup == nil {
+		return 0, nil
+	}
+	version, err := tokenVersionLookup(userID)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrTokenVersionUnavailable, err)
+	}
+	return version, nil
+}
+
+// checkTokenVersion 检查token签发时的版本号是否已被“登出所有设备”作废
+// 查询失败时按失效处理（返回 ErrTokenVersionUnavailable），不放行可能已被撤销的token
+func checkTokenVersion(userID string, version int) error {
+	current, err := currentTokenVersion(userID)
+	if err != nil {
+		return err
+	}
+	if version < current {
+		return fmt.Errorf("已在所有设备登出，请重新登录")
+	}
+	return nil
+}
+
+// tokenIdentity 不校验签名解析token，得到撤销列表中的标识和所属用户
+// 优先使用 jti；没有 jti 的旧token（或无法解析的字符串）使用 SHA-256 哈希，撤销列表中不保存token明文
+func tokenIdentity(token string) (tokenID, userID string) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err == nil {
+		userID, _ = claims["user_id"].(string)
+		if jti, _ := claims["jti"].(string); jti != "" {
+			return jti, userID
+		}
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:]), userID
+}
+
+// revoke 将token加入内存撤销列表并写入持久化存储（写入失败只记录日志，内存中仍然生效）
+func (l *revocationList) revoke(tokenID, tokenType, userID string, exp time.Time) {
+	l.Lock()
+	l.items[tokenID] = exp
 
 	// 如果超过容量阈值，则进行一次过期清理；若仍超限，记录警告日志
-	if len(tokenBlacklist.items) > maxBlacklistEntries {
+	if len(l.items) > maxBlacklistEntries {
 		now := time.Now()
-		for t, e := range tokenBlacklist.items {
+		for t, e := range l.items {
 			if now.After(e) {
-				delete(tokenBlacklist.items, t)
+				delete(l.items, t)
 			}
 		}
-		if len(tokenBlacklist.items) > maxBlacklistEntries {
-			log.Printf("auth: token blacklist size (%d) exceeds limit (%d) after sweep; consider reducing JWT TTL or using a shared persistent store",
-				len(tokenBlacklist.items), maxBlacklistEntries)
+		if len(l.items) > maxBlacklistEntries {
+			log.Printf("auth: %s token blacklist size (%d) exceeds limit (%d) after sweep; consider reducing JWT TTL",
+				tokenType, len(l.items), maxBlacklistEntries)
 		}
 	}
-}
+	l.Unlock()
 
-// IsTokenBlacklisted 检查token是否在黑名单中（过期自动清理）
-func IsTokenBlacklisted(token string) bool {
-	tokenBlacklist.Lock()
-	defer tokenBlacklist.Unlock()
-	if exp, ok := tokenBlacklist.items[token]; ok {
-		if time.Now().After(exp) {
-			delete(tokenBlacklist.items, token)
-			return false
+	if revocationStore != nil {
+		if err := revocationStore.RevokeToken(tokenID, tokenType, userID, exp); err != nil {
+			log.Printf("⚠️ [AUTH] 持久化撤销 %s token 失败（仅在内存中生效）: %v", tokenType, err)
 		}
+	}
+}
+
+// contains 检查token是否已撤销：先查内存，未命中时查询持久化存储（服务重启后内存列表为空）
+// 持久化存储查询失败时不拒绝请求，只记录日志
+func (l *revocationList) contains(tokenID string) bool {
+	l.Lock()
+	exp, ok := l.items[tokenID]
+	if ok && time.Now().After(exp) {
+		delete(l.items, tokenID)
+		ok = false
+	}
+	l.Unlock()
+	if ok {
 		return true
 	}
-	return false
+
+	if revocationStore == nil {
+		return false
+	}
+	revoked, err := revocationStore.IsTokenRevoked(tokenID)
+	if err != nil {
+		log.Printf("⚠️ [AUTH] 查询token撤销列表失败: %v", err)
+		return false
+	}
+	return revoked
+}
+
+// BlacklistToken 将token加入黑名单直到过期
+func BlacklistToken(token string, exp time.Time) {
+	tokenID, userID := tokenIdentity(token)
+	tokenBlacklist.revoke(tokenID, TokenTypeAccess, userID, exp)
+}
+
+// IsTokenBlacklisted 检查token是否在黑名单中（过期自动清理）
+func IsTokenBlacklisted(token string) bool {
+	tokenID, _ := tokenIdentity(token)
+	return tokenBlacklist.contains(tokenID)
 }
 
 // BlacklistRefreshToken 将 Refresh Token 加入黑名单
 func BlacklistRefreshToken(token string, exp time.Time) {
-	refreshTokenBlacklist.Lock()
-	defer refreshTokenBlacklist.Unlock()
-	refreshTokenBlacklist.items[token] = exp
-
-	// 清理过期条目
-	if len(refreshTokenBlacklist.items) > maxBlacklistEntries {
-		now := time.Now()
-		for t, e := range refreshTokenBlacklist.items {
-			if now.After(e) {
-				delete(refreshTokenBlacklist.items, t)
-			}
-		}
-	}
+	tokenID, userID := tokenIdentity(token)
+	refreshTokenBlacklist.revoke(tokenID, TokenTypeRefresh, userID, exp)
 }
 
 // IsRefreshTokenBlacklisted 检查 Refresh Token 是否在黑名单中
 func IsRefreshTokenBlacklisted(token string) bool {
-	refreshTokenBlacklist.Lock()
-	defer refreshTokenBlacklist.Unlock()
-	if exp, ok := refreshTokenBlacklist.items[token]; ok {
-		if time.Now().After(exp) {
-			delete(refreshTokenBlacklist.items, token)
-			return false
+	tokenID, _ := tokenIdentity(token)
+	return refreshTokenBlacklist.contains(tokenID)
+}
+
+// RevokeSession 登出：撤销 Access Token 以及与其同时签发的 Refresh Token
+func RevokeSession(accessToken string, claims *Claims) {
+	exp := time.Now().Add(accessTokenExpiry)
+	if claims.ExpiresAt != nil {
+		exp = claims.ExpiresAt.Time
+	}
+	BlacklistToken(accessToken, exp)
+
+	// 旧版本签发的 Access Token 没有 rid，无法定位配对的 Refresh Token
+	if claims.RefreshID != "" {
+		refreshExp := time.Now().Add(refreshTokenExpiry)
+		if claims.IssuedAt != nil {
+			refreshExp = claims.IssuedAt.Time.Add(refreshTokenExpiry)
 		}
-		return true
+		refreshTokenBlacklist.revoke(claims.RefreshID, TokenTypeRefresh, claims.UserID, refreshExp)
 	}
-	return false
 }
 
 // Claims JWT声明（Access Token）
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID       string `json:"user_id"`
+	Email        string `json:"email"`
+	TokenVersion int    `json:"tv,omitempty"`  // 签发时用户的token版本号
+	RefreshID    string `json:"rid,omitempty"` // 同时签发的 Refresh Token 的 jti（登出时一并撤销）
 	jwt.RegisteredClaims
 }
 
 // RefreshClaims Refresh Token 声明
 type RefreshClaims struct {
-	UserID    string `json:"user_id"`
-	Email     string `json:"email"`
-	TokenType string `json:"token_type"` // 固定为 "refresh"
+	UserID       string `json:"user_id"`
+	Email        string `json:"email"`
+	TokenType    string `json:"token_type"`   // 固定为 "refresh"
+	TokenVersion int    `json:"tv,omitempty"` // 签发时用户的token版本号
 	jwt.RegisteredClaims
 }
 
@@ -171,9 +284,15 @@ func GenerateJWT(userID, email string) (string, error) {
 		return "", fmt.Errorf("JWT密钥未设置，无法生成token")
 	}
 
+	tokenVersion, err := currentTokenVersion(userID)
+	if err != nil {
+		return "", err
+	}
+
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:       userID,
+		Email:        email,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24小时过期
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -196,16 +315,18 @@ func GenerateTokenPair(userID, email string) (*TokenPair, error) {
 	}
 
 	now := time.Now()
-	// 為新手用戶優化：延長 Token 有效期以改善用戶體驗
-	// Access Token: 7 天（用戶 7 天免登錄）
-	// Refresh Token: 30 天（提供更長的自動刷新窗口）
-	accessTokenExpiry := 7 * 24 * time.Hour
-	refreshTokenExpiry := 30 * 24 * time.Hour
+	tokenVersion, err := currentTokenVersion(userID)
+	if err != nil {
+		return nil, err
+	}
+	refreshID := uuid.New().String()
 
 	// 生成 Access Token
 	accessClaims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:       userID,
+		Email:        email,
+		TokenVersion: tokenVersion,
+		RefreshID:    refreshID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -223,15 +344,16 @@ func GenerateTokenPair(userID, email string) (*TokenPair, error) {
 
 	// 生成 Refresh Token
 	refreshClaims := RefreshClaims{
-		UserID:    userID,
-		Email:     email,
-		TokenType: "refresh",
+		UserID:       userID,
+		Email:        email,
+		TokenType:    TokenTypeRefresh,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(refreshTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "nofxAI",
-			ID:        uuid.New().String(),
+			ID:        refreshID,
 		},
 	}
 
@@ -269,9 +391,13 @@ func ValidateRefreshToken(tokenString string) (*RefreshClaims, error) {
 
 	if claims, ok := token.Claims.(*RefreshClaims); ok && token.Valid {
 		// 验证 token_type
-		if claims.TokenType != "refresh" {
+		if claims.TokenType != TokenTypeRefresh {
 			return nil, fmt.Errorf("无效的 Token 类型")
 		}
+		// 登出所有设备或修改密码前签发的 Refresh Token 全部失效
+		if err := checkTokenVersion(claims.UserID, claims.TokenVersion); err != nil {
+			return nil, err
+		}
 		return claims, nil
 	}
 
@@ -317,6 +443,10 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		// 登出所有设备或修改密码前签发的 Access Token 全部失效
+		if err := checkTokenVersion(claims.UserID, claims.TokenVersion); err != nil {
+			return nil, err
+		}
 		return claims, nil
 	}
 
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -229,3 +230,105 @@ func BenchmarkRefreshAccessToken(b *testing.B) {
 		_, _ = RefreshAccessToken(tokenPair.RefreshToken)
 	}
 }
+
+// memoryRevocationStore 模拟持久化撤销列表（跨“重启”保留）
+type memoryRevocationStore struct {
+	items map[string]time.Time
+}
+
+func (m *memoryRevocationStore) RevokeToken(tokenID, tokenType, userID string, expiresAt time.Time) error {
+	m.items[tokenID] = expiresAt
+	return nil
+}
+
+func (m *memoryRevocationStore) IsTokenRevoked(tokenID string) (bool, error) {
+	exp, ok := m.items[tokenID]
+	return ok && time.Now().Before(exp), nil
+}
+
+// clearRevocationMemory 清空内存中的黑名单（模拟服务重启）
+func clearRevocationMemory() {
+	for _, list := range []*revocationList{&tokenBlacklist, &refreshTokenBlacklist} {
+		list.Lock()
+		list.items = make(map[string]time.Time)
+		list.Unlock()
+	}
+}
+
+// TestLogoutRevocationSurvivesRestart 测试登出撤销配对的 Refresh Token，且重启后仍然有效
+func TestLogoutRevocationSurvivesRestart(t *testing.T) {
+	store := &memoryRevocationStore{items: make(map[string]time.Time)}
+	SetRevocationStore(store)
+	defer SetRevocationStore(nil)
+
+	pair, err := GenerateTokenPair("logout-user", "logout@example.com")
+	assert.NoError(t, err)
+	other, err := GenerateTokenPair("logout-user", "logout@example.com")
+	assert.NoError(t, err)
+
+	claims, err := ValidateJWT(pair.AccessToken)
+	assert.NoError(t, err)
+	RevokeSession(pair.AccessToken, claims)
+	assert.Len(t, store.items, 2, "Access Token 和配对的 Refresh Token 都应写入持久化撤销列表")
+
+	clearRevocationMemory()
+
+	assert.True(t, IsTokenBlacklisted(pair.AccessToken), "重启后已登出的 Access Token 仍应失效")
+	_, err = RefreshAccessToken(pair.RefreshToken)
+	assert.Error(t, err, "重启后已登出的 Refresh Token 不能再刷新")
+
+	// 其他设备的会话不受影响
+	assert.False(t, IsTokenBlacklisted(other.AccessToken))
+	_, err = RefreshAccessToken(other.RefreshToken)
+	assert.NoError(t, err, "未登出会话的 Refresh Token 应可刷新")
+
+	// 轮换后的旧 Refresh Token 在重启后同样不能重放
+	clearRevocationMemory()
+	_, err = RefreshAccessToken(other.RefreshToken)
+	assert.Error(t, err, "已轮换的 Refresh Token 重启后仍应失效")
+}
+
+// TestTokenVersionInvalidatesTokens 测试登出所有设备后此前签发的 Access/Refresh Token 失效
+func TestTokenVersionInvalidatesTokens(t *testing.T) {
+	version := 0
+	var lookupErr error
+	SetTokenVersionLookup(func(userID string) (int, error) {
+		if lookupErr != nil {
+			return 0, lookupErr
+		}
+		if userID == "version-user" {
+			return version, nil
+		}
+		return 0, nil
+	})
+	defer SetTokenVersionLookup(nil)
+
+	oldPair, err := GenerateTokenPair("version-user", "version@example.com")
+	assert.NoError(t, err)
+	otherPair, err := GenerateTokenPair("other-user", "other@example.com")
+	assert.NoError(t, err)
+
+	version = 1
+	_, err = ValidateJWT(oldPair.AccessToken)
+	assert.Error(t, err, "登出所有设备前签发的 Access Token 应失效")
+	_, err = ValidateRefreshToken(oldPair.RefreshToken)
+	assert.Error(t, err, "登出所有设备前签发的 Refresh Token 应失效")
+	_, err = ValidateJWT(otherPair.AccessToken)
+	assert.NoError(t, err, "其他用户不受影响")
+
+	newPair, err := GenerateTokenPair("version-user", "version@example.com")
+	assert.NoError(t, err)
+	_, err = ValidateJWT(newPair.AccessToken)
+	assert.NoError(t, err, "之后签发的 Access Token 应有效")
+	_, err = ValidateRefreshToken(newPair.RefreshToken)
+	assert.NoError(t, err, "之后签发的 Refresh Token 应有效")
+
+	// 版本号查询失败时不能放行（token可能刚被撤销）
+	lookupErr = errors.New("database is locked")
+	_, err = ValidateJWT(newPair.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenVersionUnavailable, "版本号查询失败时 Access Token 应被拒绝")
+	_, err = ValidateRefreshToken(newPair.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenVersionUnavailable, "版本号查询失败时 Refresh Token 应被拒绝")
+	_, err = GenerateTokenPair("version-user", "version@example.com")
+	assert.Error(t, err, "版本号查询失败时不应签发token")
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 多账户：同一用户可以为同一种交易所/AI模型创建多个配置（例如 Binance 主账户和子账户），
+// 以 display_name 区分，由 (user_id, exchange_id, display_name) / (user_id, model_id, display_name) 唯一索引保证不重名。
+// display_name 为空的配置是默认账户，旧版 PUT /api/exchanges、/api/models 只作用于默认账户。
+
+// ErrAccountNameTaken 同一用户同一类型下账户名称已存在
+var ErrAccountNameTaken = errors.New("账户名称已存在")
+
+// ErrAccountNotFound 账户配置不存在（或不属于该用户）
+var ErrAccountNotFound = errors.New("账户配置不存在")
+
+// MaxAccountNameLen 账户名称最大长度
+const MaxAccountNameLen = 64
+
+// ExchangeAccountInput 创建/更新交易所账户的参数
+// 🔒 更新时敏感字段（APIKey/SecretKey/AsterPrivateKey/OKXPassphrase）为空表示保留现有值
+type ExchangeAccountInput struct {
+	DisplayName           string
+	Enabled               bool
+	APIKey                string
+	SecretKey             string
+	Testnet               bool
+	HyperliquidWalletAddr string
+	AsterUser             string
+	AsterSigner           string
+	AsterPrivateKey       string
+	OKXPassphrase         string
+}
+
+// AIModelAccountInput 创建/更新AI模型账户的参数（更新时 APIKey 为空表示保留现有值）
+type AIModelAccountInput struct {
+	DisplayName     string
+	Enabled         bool
+	APIKey          string
+	CustomAPIURL    string
+	CustomModelName string
+}
+
+// isAccountNameConflict 判断错误是否由账户唯一索引冲突引起
+func isAccountNameConflict(err error, table string) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: "+table+".user_id")
+}
+
+// defaultExchangeMeta 交易所类型的默认名称和分类
+func defaultExchangeMeta(exchangeID string) (name, typ string) {
+	switch exchangeID {
+	case "binance":
+		return "Binance Futures", "cex"
+	case "hyperliquid":
+		return "Hyperliquid", "dex"
+	case "aster":
+		return "Aster DEX", "dex"
+	case "okx":
+		return "OKX Futures", "cex"
+	case "paper":
+		return "Paper Trading", "paper"
+	default:
+		return exchangeID + " Exchange", "cex"
+	}
+}
+
+// defaultModelMeta AI模型类型的默认名称和 provider（兼容 "user123_deepseek" 格式）
+func defaultModelMeta(modelID string) (name, provider string) {
+	provider = modelID
+	if strings.Contains(modelID, "_") {
+		parts := strings.Split(modelID, "_")
+		provider = parts[len(parts)-1]
+	}
+	switch provider {
+	case "deepseek":
+		return "DeepSeek AI", provider
+	case "qwen":
+		return "Qwen AI", provider
+	default:
+		return provider + " AI", provider
+	}
+}
+
+// CreateExchangeAccount 为用户新建一个交易所账户，返回自增ID
+func (d *Database) CreateExchangeAccount(userID, exchangeID string, in *ExchangeAccountInput) (int, error) {
+	name, typ := defaultExchangeMeta(exchangeID)
+	result, err := d.db.Exec(`
+		INSERT INTO exchanges (exchange_id, user_id, display_name, name, type, enabled, api_key, secret_key, testnet,
+		                       hyperliquid_wallet_addr, aster_user, aster_signer, aster_private_key, okx_passphrase,
+		                       created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+	`, exchangeID, userID, in.DisplayName, name, typ, in.Enabled,
+		d.encryptSensitiveData(in.APIKey), d.encryptSensitiveData(in.SecretKey), in.Testnet,
+		in.HyperliquidWalletAddr, in.AsterUser, in.AsterSigner,
+		d.encryptSensitiveData(in.AsterPrivateKey), d.encryptSensitiveData(in.OKXPassphrase))
+	if isAccountNameConflict(err, "exchanges") {
+		return 0, fmt.Errorf("%w: %s %q", ErrAccountNameTaken, exchangeID, in.DisplayName)
+	}
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// UpdateExchangeAccount 按自增ID更新交易所账户（包括账户名称）
+func (d *Database) UpdateExchangeAccount(userID string, id int, in *ExchangeAccountInput) error {
+	setClauses := []string{
+		"display_name = ?",
+		"enabled = ?",
+		"testnet = ?",
+		"hyperliquid_wallet_addr = ?",
+		"aster_user = ?",
+		"aster_signer = ?",
+		"updated_at = datetime('now')",
+	}
+	args := []interface{}{in.DisplayName, in.Enabled, in.Testnet, in.HyperliquidWalletAddr, in.AsterUser, in.AsterSigner}
+
+	// 🔒 敏感字段：只在非空时更新
+	for _, field := range []struct{ column, value string }{
+		{"api_key", in.APIKey},
+		{"secret_key", in.SecretKey},
+		{"aster_private_key", in.AsterPrivateKey},
+		{"okx_passphrase", in.OKXPassphrase},
+	} {
+		if field.value != "" {
+			setClauses = append(setClauses, field.column+" = ?")
+			args = append(args, d.encryptSensitiveData(field.value))
+		}
+	}
+	args = append(args, id, userID)
+
+	result, err := d.db.Exec(fmt.Sprintf(`UPDATE exchanges SET %s WHERE id = ? AND user_id = ?`, strings.Join(setClauses, ", ")), args...)
+	if isAccountNameConflict(err, "exchanges") {
+		return fmt.Errorf("%w: %q", ErrAccountNameTaken, in.DisplayName)
+	}
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// CreateAIModelAccount 为用户新建一个AI模型账户（例如两个不同 Base URL 的 DeepSeek Key），返回自增ID
+func (d *Database) CreateAIModelAccount(userID, modelID string, in *AIModelAccountInput) (int, error) {
+	name, provider := defaultModelMeta(modelID)
+	result, err := d.db.Exec(`
+		INSERT INTO ai_models (model_id, user_id, display_name, name, provider, enabled, api_key, custom_api_url, custom_model_name, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+	`, modelID, userID, in.DisplayName, name, provider, in.Enabled,
+		d.encryptSensitiveData(in.APIKey), in.CustomAPIURL, in.CustomModelName)
+	if isAccountNameConflict(err, "ai_models") {
+		return 0, fmt.Errorf("%w: %s %q", ErrAccountNameTaken, modelID, in.DisplayName)
+	}
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// UpdateAIModelAccount 按自增ID更新AI模型账户（包括账户名称）
+func (d *Database) UpdateAIModelAccount(userID string, id int, in *AIModelAccountInput) error {
+	setClauses := []string{
+		"display_name = ?",
+		"enabled = ?",
+		"custom_api_url = ?",
+		"custom_model_name = ?",
+		"updated_at = datetime('now')",
+	}
+	args := []interface{}{in.DisplayName, in.Enabled, in.CustomAPIURL, in.CustomModelName}
+	if in.APIKey != "" {
+		setClauses = append(setClauses, "api_key = ?")
+		args = append(args, d.encryptSensitiveData(in.APIKey))
+	}
+	args = append(args, id, userID)
+
+	result, err := d.db.Exec(fmt.Sprintf(`UPDATE ai_models SET %s WHERE id = ? AND user_id = ?`, strings.Join(setClauses, ", ")), args...)
+	if isAccountNameConflict(err, "ai_models") {
+		return fmt.Errorf("%w: %q", ErrAccountNameTaken, in.DisplayName)
+	}
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
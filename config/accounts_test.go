@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestMultipleExchangeAccounts 测试同一用户可以为同一交易所创建多个账户，交易员分别引用
+func TestMultipleExchangeAccounts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-009"
+	if err := db.UpdateExchange(userID, "binance", true, "main-key", "main-secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("创建默认账户失败: %v", err)
+	}
+	subID, err := db.CreateExchangeAccount(userID, "binance", &ExchangeAccountInput{
+		DisplayName: "Binance-sub",
+		Enabled:     true,
+		APIKey:      "sub-key",
+		SecretKey:   "sub-secret",
+	})
+	if err != nil {
+		t.Fatalf("创建子账户失败: %v", err)
+	}
+	if _, err := db.CreateExchangeAccount(userID, "binance", &ExchangeAccountInput{DisplayName: "Binance-sub"}); !errors.Is(err, ErrAccountNameTaken) {
+		t.Fatalf("重复的账户名称应返回 ErrAccountNameTaken, 实际: %v", err)
+	}
+
+	// 旧版按类型更新只作用于默认账户
+	if err := db.UpdateExchange(userID, "binance", true, "main-key-2", "", false, "", "", "", ""); err != nil {
+		t.Fatalf("更新默认账户失败: %v", err)
+	}
+	exchanges, err := db.GetExchanges(userID)
+	if err != nil {
+		t.Fatalf("获取交易所失败: %v", err)
+	}
+	if len(exchanges) != 2 {
+		t.Fatalf("期望 2 个 binance 账户, 实际 %d", len(exchanges))
+	}
+	var mainID int
+	for _, ex := range exchanges {
+		switch ex.DisplayName {
+		case "":
+			mainID = ex.ID
+			if ex.APIKey != "main-key-2" {
+				t.Errorf("默认账户 API Key 应为 main-key-2, 实际 %s", ex.APIKey)
+			}
+		case "Binance-sub":
+			if ex.APIKey != "sub-key" || ex.SecretKey != "sub-secret" {
+				t.Errorf("子账户密钥不应被默认账户的更新覆盖: %s/%s", ex.APIKey, ex.SecretKey)
+			}
+		}
+	}
+
+	// 按ID更新：密钥留空保留原值
+	if err := db.UpdateExchangeAccount(userID, subID, &ExchangeAccountInput{DisplayName: "Binance-sub", Enabled: true, Testnet: true}); err != nil {
+		t.Fatalf("按ID更新子账户失败: %v", err)
+	}
+	if err := db.UpdateExchangeAccount("test-user-008", subID, &ExchangeAccountInput{}); !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("更新其他用户的账户应返回 ErrAccountNotFound, 实际: %v", err)
+	}
+
+	modelID := createTestAIModel(t, db, userID, "deepseek")
+	for _, tr := range []*TraderRecord{
+		{ID: "trader-main", UserID: userID, Name: "TraderA", AIModelID: modelID, ExchangeID: mainID, InitialBalance: 1000, ScanIntervalMinutes: 3, Timeframes: "4h"},
+		{ID: "trader-sub", UserID: userID, Name: "TraderB", AIModelID: modelID, ExchangeID: subID, InitialBalance: 1000, ScanIntervalMinutes: 3, Timeframes: "4h"},
+	} {
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("创建交易员 %s 失败: %v", tr.ID, err)
+		}
+	}
+
+	_, _, mainExchange, err := db.GetTraderConfig(userID, "trader-main")
+	if err != nil {
+		t.Fatalf("获取交易员配置失败: %v", err)
+	}
+	_, _, subExchange, err := db.GetTraderConfig(userID, "trader-sub")
+	if err != nil {
+		t.Fatalf("获取交易员配置失败: %v", err)
+	}
+	if mainExchange.APIKey != "main-key-2" || mainExchange.DisplayName != "" {
+		t.Errorf("TraderA 应使用默认账户, 实际 %q (%s)", mainExchange.DisplayName, mainExchange.APIKey)
+	}
+	if subExchange.APIKey != "sub-key" || subExchange.DisplayName != "Binance-sub" || !subExchange.Testnet {
+		t.Errorf("TraderB 应使用子账户, 实际 %q (%s, testnet=%v)", subExchange.DisplayName, subExchange.APIKey, subExchange.Testnet)
+	}
+}
+
+// TestMultipleAIModelAccounts 测试同一模型可以配置多个不同 Base URL 的 Key
+func TestMultipleAIModelAccounts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-009"
+	if err := db.UpdateAIModel(userID, "deepseek", true, "key-1", "https://api.deepseek.com/v1", ""); err != nil {
+		t.Fatalf("创建默认模型配置失败: %v", err)
+	}
+	id, err := db.CreateAIModelAccount(userID, "deepseek", &AIModelAccountInput{
+		DisplayName:  "proxy",
+		Enabled:      true,
+		APIKey:       "key-2",
+		CustomAPIURL: "https://proxy.example.com/v1",
+	})
+	if err != nil {
+		t.Fatalf("创建第二个模型配置失败: %v", err)
+	}
+
+	models, err := db.GetAIModels(userID)
+	if err != nil {
+		t.Fatalf("获取模型配置失败: %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("期望 2 个 deepseek 配置, 实际 %d", len(models))
+	}
+	for _, m := range models {
+		if m.ID == id && (m.DisplayName != "proxy" || m.APIKey != "key-2" || m.Provider != "deepseek" || m.Name != "DeepSeek AI") {
+			t.Errorf("第二个配置内容不正确: %+v", m)
+		}
+		if m.ID != id && m.APIKey != "key-1" {
+			t.Errorf("默认配置不应被修改: %+v", m)
+		}
+	}
+
+	if err := db.UpdateAIModelAccount(userID, id, &AIModelAccountInput{DisplayName: "", Enabled: true}); !errors.Is(err, ErrAccountNameTaken) {
+		t.Fatalf("改名为已存在的默认账户应返回 ErrAccountNameTaken, 实际: %v", err)
+	}
+}
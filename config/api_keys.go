@@ -0,0 +1,203 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API Key 权限范围
+const (
+	APIKeyScopeRead      = "read"       // 只读：只允许 GET/HEAD 请求（以及登出）
+	APIKeyScopeReadWrite = "read_write" // 读写：与登录用户权限相同
+)
+
+// API Key 限制
+const (
+	MaxUserAPIKeys     = 20
+	MaxAPIKeyNameLen   = 64
+	apiKeyPrefix       = "nofx_"
+	apiKeyRandomBytes  = 24
+	apiKeyDisplayChars = 12 // 列表中展示的密钥前缀长度（含 nofx_）
+)
+
+// sqliteTimeLayout 与 CURRENT_TIMESTAMP 相同的时间格式（UTC），便于在 SQL 中直接比较
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// UserAPIKey 用户的个人访问令牌（数据库只保存哈希，明文仅在创建时返回一次）
+type UserAPIKey struct {
+	ID         string `json:"id"`
+	UserID     string `json:"user_id"`
+	Name       string `json:"name"`
+	KeyHash    string `json:"-"`
+	KeyPrefix  string `json:"key_prefix"`
+	Scope      string `json:"scope"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+}
+
+// IsValidAPIKeyScope 是否为支持的权限范围
+func IsValidAPIKeyScope(scope string) bool {
+	return scope == APIKeyScopeRead || scope == APIKeyScopeReadWrite
+}
+
+// HashAPIKey 计算 API Key 的存储哈希（密钥为高熵随机串，SHA-256 即可，无需慢哈希）
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsExpired API Key 是否已过期
+func (k *UserAPIKey) IsExpired(now time.Time) bool {
+	if k.ExpiresAt == "" {
+		return false
+	}
+	// 驱动读取 DATETIME 列时返回 RFC3339 格式，兼容直接写入的 SQLite 格式
+	for _, layout := range []string{time.RFC3339, sqliteTimeLayout} {
+		if expiresAt, err := time.Parse(layout, k.ExpiresAt); err == nil {
+			return !now.UTC().Before(expiresAt)
+		}
+	}
+	return true
+}
+
+// randomHex 生成 n 字节随机数的十六进制串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// CreateUserAPIKey 为用户创建 API Key，返回明文密钥（只在此时可见）和记录
+// expiresAt 为零值表示永不过期
+func (d *Database) CreateUserAPIKey(userID, name, scope string, expiresAt time.Time) (string, *UserAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > MaxAPIKeyNameLen {
+		return "", nil, fmt.Errorf("名称不能为空且不超过 %d 个字符", MaxAPIKeyNameLen)
+	}
+	if !IsValidAPIKeyScope(scope) {
+		return "", nil, fmt.Errorf("不支持的权限范围: %s（可选 %s / %s）", scope, APIKeyScopeRead, APIKeyScopeReadWrite)
+	}
+
+	var count int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM user_api_keys WHERE user_id = ?`, userID).Scan(&count); err != nil {
+		return "", nil, fmt.Errorf("查询 API Key 数量失败: %w", err)
+	}
+	if count >= MaxUserAPIKeys {
+		return "", nil, fmt.Errorf("每个用户最多 %d 个 API Key，请先撤销不再使用的密钥", MaxUserAPIKeys)
+	}
+
+	secret, err := randomHex(apiKeyRandomBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+	plaintext := apiKeyPrefix + secret
+
+	key := &UserAPIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		KeyHash:   HashAPIKey(plaintext),
+		KeyPrefix: plaintext[:apiKeyDisplayChars],
+		Scope:     scope,
+	}
+	// 以 SQLite 格式写入（便于 SQL 中比较），返回值与读取时一致使用 RFC3339
+	now := time.Now().UTC().Truncate(time.Second)
+	key.CreatedAt = now.Format(time.RFC3339)
+	var expires interface{}
+	if !expiresAt.IsZero() {
+		expiresAt = expiresAt.UTC().Truncate(time.Second)
+		key.ExpiresAt = expiresAt.Format(time.RFC3339)
+		expires = expiresAt.Format(sqliteTimeLayout)
+	}
+
+	_, err = d.db.Exec(`
+		INSERT INTO user_api_keys (id, user_id, name, key_hash, key_prefix, scope, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, key.ID, key.UserID, key.Name, key.KeyHash, key.KeyPrefix, key.Scope, now.Format(sqliteTimeLayout), expires)
+	if err != nil {
+		return "", nil, fmt.Errorf("保存 API Key 失败: %w", err)
+	}
+	return plaintext, key, nil
+}
+
+// scanUserAPIKey 扫描一行 API Key 记录
+func scanUserAPIKey(scanner interface{ Scan(...interface{}) error }) (*UserAPIKey, error) {
+	var key UserAPIKey
+	var lastUsedAt, expiresAt sql.NullString
+	if err := scanner.Scan(&key.ID, &key.UserID, &key.Name, &key.KeyHash, &key.KeyPrefix, &key.Scope,
+		&key.CreatedAt, &lastUsedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	key.LastUsedAt = lastUsedAt.String
+	key.ExpiresAt = expiresAt.String
+	return &key, nil
+}
+
+// ListUserAPIKeys 列出用户的 API Key（按创建时间倒序）
+func (d *Database) ListUserAPIKeys(userID string) ([]*UserAPIKey, error) {
+	rows, err := d.db.Query(`
+		SELECT id, user_id, name, key_hash, key_prefix, scope, created_at, last_used_at, expires_at
+		FROM user_api_keys WHERE user_id = ?
+		ORDER BY created_at DESC, rowid DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*UserAPIKey, 0)
+	for rows.Next() {
+		key, err := scanUserAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetUserAPIKeyByKey 根据明文密钥查找 API Key（不存在时返回 nil, nil）
+func (d *Database) GetUserAPIKeyByKey(plaintext string) (*UserAPIKey, error) {
+	row := d.db.QueryRow(`
+		SELECT id, user_id, name, key_hash, key_prefix, scope, created_at, last_used_at, expires_at
+		FROM user_api_keys WHERE key_hash = ?
+	`, HashAPIKey(plaintext))
+	key, err := scanUserAPIKey(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// TouchUserAPIKey 更新 API Key 最后使用时间（一分钟内只写一次，避免每个请求都写库）
+func (d *Database) TouchUserAPIKey(id string) error {
+	_, err := d.db.Exec(`
+		UPDATE user_api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at < datetime('now', '-1 minute'))
+	`, id)
+	return err
+}
+
+// DeleteUserAPIKey 撤销用户的 API Key
+func (d *Database) DeleteUserAPIKey(userID, id string) error {
+	result, err := d.db.Exec(`DELETE FROM user_api_keys WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("API Key 不存在: %s", id)
+	}
+	return nil
+}
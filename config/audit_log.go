@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditLogEntry 一条账户操作审计记录
+type AuditLogEntry struct {
+	ID           int64                  `json:"id"`
+	UserID       string                 `json:"user_id"`
+	Action       string                 `json:"action"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   string                 `json:"resource_id"`
+	IP           string                 `json:"ip"`
+	UserAgent    string                 `json:"user_agent"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+	CreatedAt    string                 `json:"created_at"`
+}
+
+// AuditLogFilter 审计日志查询条件（零值表示不过滤）
+type AuditLogFilter struct {
+	UserID string
+	Action string
+	Since  time.Time // 包含
+	Until  time.Time // 不包含
+}
+
+// RecordAuditLog 写入一条审计记录
+func (d *Database) RecordAuditLog(entry *AuditLogEntry) error {
+	details := ""
+	if len(entry.Details) > 0 {
+		data, err := json.Marshal(entry.Details)
+		if err != nil {
+			return fmt.Errorf("序列化审计详情失败: %w", err)
+		}
+		details = string(data)
+	}
+
+	_, err := d.db.Exec(`
+		INSERT INTO audit_log (user_id, action, resource_type, resource_id, ip, user_agent, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.UserID, entry.Action, entry.ResourceType, entry.ResourceID, entry.IP, entry.UserAgent, details,
+		time.Now().UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return fmt.Errorf("写入审计日志失败: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLog 分页查询审计日志（按时间倒序），同时返回满足条件的总条数
+func (d *Database) GetAuditLog(filter AuditLogFilter, limit, offset int) ([]*AuditLogEntry, int, error) {
+	where := `WHERE 1 = 1`
+	args := []interface{}{}
+	if filter.UserID != "" {
+		where += ` AND user_id = ?`
+		args = append(args, filter.UserID)
+	}
+	if filter.Action != "" {
+		where += ` AND action = ?`
+		args = append(args, filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		where += ` AND created_at >= ?`
+		args = append(args, filter.Since.UTC().Format(sqliteTimeLayout))
+	}
+	if !filter.Until.IsZero() {
+		where += ` AND created_at < ?`
+		args = append(args, filter.Until.UTC().Format(sqliteTimeLayout))
+	}
+
+	var total int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM audit_log `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := d.db.Query(`
+		SELECT id, COALESCE(user_id, ''), action, COALESCE(resource_type, ''), COALESCE(resource_id, ''),
+		       COALESCE(ip, ''), COALESCE(user_agent, ''), COALESCE(details, ''), created_at
+		FROM audit_log `+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditLogEntry, 0)
+	for rows.Next() {
+		var e AuditLogEntry
+		var details string
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Action, &e.ResourceType, &e.ResourceID,
+			&e.IP, &e.UserAgent, &details, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if details != "" {
+			_ = json.Unmarshal([]byte(details), &e.Details)
+		}
+		entries = append(entries, &e)
+	}
+	return entries, total, rows.Err()
+}
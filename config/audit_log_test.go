@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAuditLog 测试审计日志的写入、按用户/动作/时间过滤和分页
+func TestAuditLog(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	entries := []*AuditLogEntry{
+		{UserID: "u1", Action: "login_success", ResourceType: "user", ResourceID: "u1", IP: "10.0.0.1", UserAgent: "curl/8"},
+		{UserID: "u1", Action: "exchange_update", ResourceType: "exchange", ResourceID: "binance",
+			IP: "10.0.0.2", Details: map[string]interface{}{"changed_fields": []string{"api_key", "secret_key"}}},
+		{UserID: "u2", Action: "trader_delete", ResourceType: "trader", ResourceID: "t9"},
+	}
+	for _, e := range entries {
+		if err := db.RecordAuditLog(e); err != nil {
+			t.Fatalf("写入审计日志失败: %v", err)
+		}
+	}
+
+	logs, total, err := db.GetAuditLog(AuditLogFilter{UserID: "u1"}, 10, 0)
+	if err != nil {
+		t.Fatalf("查询审计日志失败: %v", err)
+	}
+	if total != 2 || len(logs) != 2 {
+		t.Fatalf("u1 应有 2 条记录, 实际 total=%d len=%d", total, len(logs))
+	}
+	latest := logs[0]
+	if latest.Action != "exchange_update" || latest.IP != "10.0.0.2" || latest.CreatedAt == "" {
+		t.Errorf("应按时间倒序返回最新记录: %+v", latest)
+	}
+	if fields, ok := latest.Details["changed_fields"].([]interface{}); !ok || len(fields) != 2 {
+		t.Errorf("details 应还原为 JSON 对象: %+v", latest.Details)
+	}
+
+	if logs, total, _ := db.GetAuditLog(AuditLogFilter{Action: "trader_delete"}, 10, 0); total != 1 || logs[0].UserID != "u2" {
+		t.Errorf("按动作过滤应只返回 u2 的删除记录: total=%d %+v", total, logs)
+	}
+	if logs, total, _ := db.GetAuditLog(AuditLogFilter{}, 1, 1); total != 3 || len(logs) != 1 {
+		t.Errorf("分页应返回第 2 条且总数为 3: total=%d len=%d", total, len(logs))
+	}
+	if _, total, _ := db.GetAuditLog(AuditLogFilter{Since: time.Now().Add(time.Hour)}, 10, 0); total != 0 {
+		t.Errorf("时间范围之后不应有记录, 实际 %d", total)
+	}
+	if _, total, _ := db.GetAuditLog(AuditLogFilter{Since: time.Now().Add(-time.Hour), Until: time.Now().Add(time.Hour)}, 10, 0); total != 3 {
+		t.Errorf("时间范围内应有 3 条记录, 实际 %d", total)
+	}
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"nofx/security"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BackupDirName 数据库备份目录（位于数据库文件所在目录下）
+const BackupDirName = "backups"
+
+// backupMu 同一时间只执行一个备份（定时备份与手动备份可能同时触发）
+var backupMu sync.Mutex
+
+// ErrBackupNotFound 备份文件不存在或文件名不合法
+var ErrBackupNotFound = errors.New("备份文件不存在")
+
+// backupNamePattern 备份文件名格式：backup_<时间>_<原因>.db，只有符合该格式的文件才会被列出、下载和清理
+var backupNamePattern = regexp.MustCompile(`^backup_\d{8}_\d{6}_[a-z0-9_]+\.db$`)
+
+// BackupInfo 一个数据库备份文件
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupStatus 最近一次备份的执行结果（保存在 system_config 中，重启后保留）
+type BackupStatus struct {
+	LastRunAt time.Time `json:"last_run_at"`          // 最近一次执行时间（成功或失败）
+	LastFile  string    `json:"last_file,omitempty"`  // 最近一次成功的备份文件名
+	LastSize  int64     `json:"last_size,omitempty"`  // 最近一次成功的备份大小（字节）
+	LastError string    `json:"last_error,omitempty"` // 最近一次失败的原因（成功后清空）
+}
+
+// BackupDir 备份目录的路径
+func (d *Database) BackupDir() string {
+	dbFile, _, _ := strings.Cut(d.dbPath, "?")
+	return filepath.Join(filepath.Dir(dbFile), BackupDirName)
+}
+
+// CreateBackup 使用 VACUUM INTO 在备份目录生成一份完整的数据库快照，并记录执行结果
+// WAL 模式下 VACUUM INTO 只读取一致的快照，交易员运行期间也可以执行
+func (d *Database) CreateBackup(reason string) (*BackupInfo, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	info, err := d.createBackupFile(reason)
+	d.recordBackupStatus(info, err)
+	return info, err
+}
+
+func (d *Database) createBackupFile(reason string) (*BackupInfo, error) {
+	guard := security.NewSQLGuard()
+	if err := guard.ValidateIdentifier(reason); err != nil {
+		return nil, fmt.Errorf("备份原因不合法: %w", err)
+	}
+
+	dir := d.BackupDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建备份目录失败: %w", err)
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("backup_%s_%s.db", now.Format("20060102_150405"), strings.ToLower(reason))
+	if !backupNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("备份文件名不合法: %s", name)
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("备份文件已存在: %s", name)
+	}
+
+	// VACUUM INTO 不支持参数化查询，路径中不能包含引号等字符
+	if strings.ContainsAny(path, "';\"") {
+		return nil, fmt.Errorf("备份路径包含非法字符")
+	}
+	if _, err := d.db.Exec(fmt.Sprintf("VACUUM INTO '%s'", path)); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("备份数据库失败: %w", err)
+	}
+	// 备份中包含加密的 API 密钥，只允许当前用户读取
+	os.Chmod(path, 0600)
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取备份文件失败: %w", err)
+	}
+	return &BackupInfo{Name: name, Size: stat.Size(), CreatedAt: now}, nil
+}
+
+// recordBackupStatus 记录备份执行结果
+func (d *Database) recordBackupStatus(info *BackupInfo, backupErr error) {
+	d.SetSystemConfig("backup_last_run_at", time.Now().UTC().Format(time.RFC3339))
+	if backupErr != nil {
+		d.SetSystemConfig("backup_last_error", backupErr.Error())
+		return
+	}
+	d.SetSystemConfig("backup_last_error", "")
+	d.SetSystemConfig("backup_last_file", info.Name)
+	d.SetSystemConfig("backup_last_size", strconv.FormatInt(info.Size, 10))
+}
+
+// GetBackupStatus 最近一次备份的执行结果（从未执行过时 LastRunAt 为零值）
+func (d *Database) GetBackupStatus() BackupStatus {
+	var status BackupStatus
+	if value, _ := d.GetSystemConfig("backup_last_run_at"); value != "" {
+		status.LastRunAt, _ = time.Parse(time.RFC3339, value)
+	}
+	status.LastFile, _ = d.GetSystemConfig("backup_last_file")
+	status.LastError, _ = d.GetSystemConfig("backup_last_error")
+	if value, _ := d.GetSystemConfig("backup_last_size"); value != "" {
+		status.LastSize, _ = strconv.ParseInt(value, 10, 64)
+	}
+	return status
+}
+
+// ListBackups 列出备份目录中的备份文件（最新的在前）
+func (d *Database) ListBackups() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(d.BackupDir())
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取备份目录失败: %w", err)
+	}
+
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !backupNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{Name: entry.Name(), Size: stat.Size(), CreatedAt: stat.ModTime()})
+	}
+	// 文件名以时间开头，按名称倒序即最新在前
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
+	return backups, nil
+}
+
+// BackupPath 校验备份文件名并返回文件路径（防止路径遍历，只允许访问备份目录中的备份文件）
+func (d *Database) BackupPath(name string) (string, error) {
+	guard := security.NewSQLGuard()
+	clean, err := guard.SanitizeFilePath(name)
+	if err != nil || clean != name || filepath.Base(name) != name || !backupNamePattern.MatchString(name) {
+		return "", ErrBackupNotFound
+	}
+
+	path := filepath.Join(d.BackupDir(), name)
+	stat, err := os.Stat(path)
+	if err != nil || !stat.Mode().IsRegular() {
+		return "", ErrBackupNotFound
+	}
+	return path, nil
+}
+
+// PruneBackups 清理超出保留数量或保留天数的备份，返回删除的文件数（keepCount/keepDays 为 0 表示不按该条件清理）
+func (d *Database) PruneBackups(keepCount, keepDays int) (int, error) {
+	backups, err := d.ListBackups()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -keepDays)
+	deleted := 0
+	for i, backup := range backups {
+		expired := keepDays > 0 && backup.CreatedAt.Before(cutoff)
+		overCount := keepCount > 0 && i >= keepCount
+		if !expired && !overCount {
+			continue
+		}
+		if err := os.Remove(filepath.Join(d.BackupDir(), backup.Name)); err != nil {
+			return deleted, fmt.Errorf("删除备份 %s 失败: %w", backup.Name, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDatabaseBackup 测试备份生成可打开的数据库副本、记录执行结果，下载路径只允许备份目录中的备份文件
+func TestDatabaseBackup(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetSystemConfig("backup_marker", "before"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := db.CreateBackup("manual")
+	if err != nil {
+		t.Fatalf("备份失败: %v", err)
+	}
+	if info.Size <= 0 || !backupNamePattern.MatchString(info.Name) {
+		t.Fatalf("备份信息不正确: %+v", info)
+	}
+
+	status := db.GetBackupStatus()
+	if status.LastFile != info.Name || status.LastSize != info.Size || status.LastError != "" || status.LastRunAt.IsZero() {
+		t.Errorf("备份执行结果记录不正确: %+v", status)
+	}
+
+	path, err := db.BackupPath(info.Name)
+	if err != nil {
+		t.Fatalf("备份文件应可下载: %v", err)
+	}
+	restored, err := NewDatabase(path)
+	if err != nil {
+		t.Fatalf("打开备份失败: %v", err)
+	}
+	defer restored.Close()
+	if value, _ := restored.GetSystemConfig("backup_marker"); value != "before" {
+		t.Errorf("备份内容不完整: backup_marker = %q", value)
+	}
+
+	// 备份目录外的文件、目录遍历和不符合命名格式的文件都不能下载
+	os.WriteFile(filepath.Join(db.BackupDir(), "notes.txt"), []byte("x"), 0600)
+	for _, name := range []string{
+		"../test.db",
+		"..%2Ftest.db",
+		"/etc/passwd",
+		"notes.txt",
+		"backup_20250101_000000_manual.db",
+		filepath.Join("sub", info.Name),
+	} {
+		if _, err := db.BackupPath(name); err != ErrBackupNotFound {
+			t.Errorf("BackupPath(%q) 应返回 ErrBackupNotFound, 实际 %v", name, err)
+		}
+	}
+
+	backups, err := db.ListBackups()
+	if err != nil || len(backups) != 1 || backups[0].Name != info.Name {
+		t.Errorf("备份列表应只包含备份文件: %+v, %v", backups, err)
+	}
+}
+
+// TestPruneBackups 测试按保留数量和保留天数清理旧备份，且不删除非备份文件
+func TestPruneBackups(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := db.BackupDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	names := []string{}
+	for i := 0; i < 5; i++ {
+		created := now.AddDate(0, 0, -i*10)
+		name := "backup_" + created.Format("20060102_150405") + "_scheduled.db"
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte("backup"), 0600)
+		os.Chtimes(path, created, created)
+		names = append(names, name)
+	}
+	os.WriteFile(filepath.Join(dir, "keep.txt"), []byte("x"), 0600)
+
+	// 按天数：保留 25 天内的 3 个（0、10、20 天前）
+	deleted, err := db.PruneBackups(0, 25)
+	if err != nil || deleted != 2 {
+		t.Fatalf("按天数应删除 2 个备份, 实际 %d, %v", deleted, err)
+	}
+	// 按数量：只保留最新的 1 个
+	deleted, err = db.PruneBackups(1, 0)
+	if err != nil || deleted != 2 {
+		t.Fatalf("按数量应删除 2 个备份, 实际 %d, %v", deleted, err)
+	}
+
+	backups, _ := db.ListBackups()
+	if len(backups) != 1 || backups[0].Name != names[0] {
+		t.Errorf("应只保留最新的备份: %+v", backups)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "keep.txt")); err != nil {
+		t.Error("非备份文件不应被清理")
+	}
+}
@@ -15,8 +15,10 @@ type LeverageConfig struct {
 
 // LogConfig 日志配置
 type LogConfig struct {
-	Level    string          `json:"level"`    // 日志级别: debug, info, warn, error (默认: info)
-	Telegram *TelegramConfig `json:"telegram"` // Telegram推送配置（可选）
+	Level      string          `json:"level"`       // 日志级别: debug, info, warn, error (默认: info)，交易员可单独覆盖
+	Format     string          `json:"format"`      // 输出格式: text（彩色文本，默认）, json（一行一个 JSON 对象，便于日志采集）
+	BufferSize int             `json:"buffer_size"` // 每个交易员在内存中保留的运行日志条数，供 API 查询（默认: 500）
+	Telegram   *TelegramConfig `json:"telegram"`    // Telegram推送配置（可选）
 }
 
 // TelegramConfig Telegram推送配置（简化版，只保留必需字段）
@@ -27,21 +29,30 @@ type TelegramConfig struct {
 	MinLevel string `json:"min_level"` // 最低日志级别，该级别及以上的日志会推送到Telegram（可选，默认: error）
 }
 
+// DatabaseConfig 数据库连接配置（交易员数量多、并发请求高时调整，未配置的字段使用默认值）
+type DatabaseConfig struct {
+	MaxOpenConns       int `json:"max_open_conns"`        // 最大打开连接数（默认: 8）
+	MaxIdleConns       int `json:"max_idle_conns"`        // 最大空闲连接数（默认: 4）
+	BusyTimeoutMs      int `json:"busy_timeout_ms"`       // 数据库被锁时的最长等待时间，避免 database is locked（默认: 5000）
+	TraderStateFlushMs int `json:"trader_state_flush_ms"` // 交易员状态合并写入间隔（默认: 1000，-1 表示每次直接写入）
+}
+
 // Config 总配置
 type Config struct {
-	BetaMode           bool           `json:"beta_mode"`
-	APIServerPort      int            `json:"api_server_port"`
-	UseDefaultCoins    bool           `json:"use_default_coins"`
-	DefaultCoins       []string       `json:"default_coins"`
-	CoinPoolAPIURL     string         `json:"coin_pool_api_url"`
-	OITopAPIURL        string         `json:"oi_top_api_url"`
-	MaxDailyLoss       float64        `json:"max_daily_loss"`
-	MaxDrawdown        float64        `json:"max_drawdown"`
-	StopTradingMinutes int            `json:"stop_trading_minutes"`
-	Leverage           LeverageConfig `json:"leverage"`
-	JWTSecret          string         `json:"jwt_secret"`
-	DataKLineTime      string         `json:"data_k_line_time"`
-	Log                *LogConfig     `json:"log"` // 日志配置
+	BetaMode           bool            `json:"beta_mode"`
+	APIServerPort      int             `json:"api_server_port"`
+	UseDefaultCoins    bool            `json:"use_default_coins"`
+	DefaultCoins       []string        `json:"default_coins"`
+	CoinPoolAPIURL     string          `json:"coin_pool_api_url"`
+	OITopAPIURL        string          `json:"oi_top_api_url"`
+	MaxDailyLoss       float64         `json:"max_daily_loss"`
+	MaxDrawdown        float64         `json:"max_drawdown"`
+	StopTradingMinutes int             `json:"stop_trading_minutes"`
+	Leverage           LeverageConfig  `json:"leverage"`
+	JWTSecret          string          `json:"jwt_secret"`
+	DataKLineTime      string          `json:"data_k_line_time"`
+	Log                *LogConfig      `json:"log"`      // 日志配置
+	Database           *DatabaseConfig `json:"database"` // 数据库连接配置
 }
 
 // LoadConfig 从文件加载配置
@@ -5,19 +5,28 @@ import (
 	"database/sql"
 	"encoding/base32"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"nofx/crypto"
 	"nofx/market"
 	"nofx/security"
 	"os"
 	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// ErrTraderNameTaken 同一用户下交易员名称已存在（由 traders(user_id, name) 唯一索引保证）
+var ErrTraderNameTaken = errors.New("交易员名称已存在")
+
+// ErrAIModelNotFound 用户没有该 AI 模型配置
+var ErrAIModelNotFound = errors.New("模型不存在")
+
 // DatabaseInterface 定义了数据库实现需要提供的方法集合
 type DatabaseInterface interface {
 	SetCryptoService(cs *crypto.CryptoService)
@@ -28,8 +37,10 @@ type DatabaseInterface interface {
 	UpdateUserOTPVerified(userID string, verified bool) error
 	GetAIModels(userID string) ([]*AIModelConfig, error)
 	UpdateAIModel(userID, id string, enabled bool, apiKey, customAPIURL, customModelName string) error
+	UpdateAIModelSystemPrompt(userID, modelID, prefix, suffix string) error
 	GetExchanges(userID string) ([]*ExchangeConfig, error)
 	UpdateExchange(userID, id string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error
+	UpdateExchangePassphrase(userID, id, passphrase string) error
 	CreateAIModel(userID, id, name, provider string, enabled bool, apiKey, customAPIURL string) error
 	CreateExchange(userID, id, name, typ string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error
 	CreateTrader(trader *TraderRecord) error
@@ -39,6 +50,7 @@ type DatabaseInterface interface {
 	UpdateTraderInitialBalance(userID, id string, newBalance float64) error
 	UpdateTraderCustomPrompt(userID, id string, customPrompt string, overrideBase bool) error
 	DeleteTrader(userID, id string) error
+	DeleteTradingHistory(userID, traderID string) (tradeRows, rejectedRows int64, err error)
 	GetTraderConfig(userID, traderID string) (*TraderRecord, *AIModelConfig, *ExchangeConfig, error)
 	GetSystemConfig(key string) (string, error)
 	SetSystemConfig(key, value string) error
@@ -51,6 +63,9 @@ type DatabaseInterface interface {
 	ValidateBetaCode(code string) (bool, error)
 	UseBetaCode(code, userEmail string) error
 	GetBetaCodeStats() (total, used int, err error)
+	GenerateBetaCodes(count int) ([]string, error)
+	ListBetaCodes() ([]*BetaCode, error)
+	RevokeBetaCode(code string) error
 	Close() error
 }
 
@@ -59,14 +74,65 @@ type Database struct {
 	db            *sql.DB
 	dbPath        string // 數據庫文件路徑（用於備份等操作）
 	cryptoService *crypto.CryptoService
+	stateBatcher  *traderStateBatcher // trader_state 合并写入（nil 表示每次直接写入）
+
+	uncleanShutdown bool // 上次运行是否未经正常退出流程（由 MarkSessionStarted 检测）
 }
 
-// NewDatabase 创建配置数据库
+// 数据库连接默认配置
+const (
+	defaultDBMaxOpenConns       = 8
+	defaultDBMaxIdleConns       = 4
+	defaultDBBusyTimeoutMs      = 5000
+	defaultTraderStateFlushMs   = 1000
+	traderStateFlushDisabledVal = -1
+)
+
+// NewDatabase 创建配置数据库（使用默认连接配置）
 func NewDatabase(dbPath string) (*Database, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	return NewDatabaseWithConfig(dbPath, nil)
+}
+
+// sqliteDSN 为每个连接附加 PRAGMA 参数
+// busy_timeout、foreign_keys、synchronous 都是连接级设置，只对单个连接执行 PRAGMA 时连接池中的其他连接不会生效；
+// _txlock=immediate 让事务开始时就获取写锁，避免两个事务同时由读升级为写时直接返回 SQLITE_BUSY
+func sqliteDSN(dbPath string, busyTimeoutMs int) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)&_pragma=synchronous(FULL)&_txlock=immediate",
+		dbPath, sep, busyTimeoutMs)
+}
+
+// NewDatabaseWithConfig 创建配置数据库，cfg 为 nil 或字段为0时使用默认连接配置
+func NewDatabaseWithConfig(dbPath string, cfg *DatabaseConfig) (*Database, error) {
+	opts := DatabaseConfig{}
+	if cfg != nil {
+		opts = *cfg
+	}
+	if opts.MaxOpenConns <= 0 {
+		opts.MaxOpenConns = defaultDBMaxOpenConns
+	}
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = defaultDBMaxIdleConns
+	}
+	if opts.MaxIdleConns > opts.MaxOpenConns {
+		opts.MaxIdleConns = opts.MaxOpenConns
+	}
+	if opts.BusyTimeoutMs <= 0 {
+		opts.BusyTimeoutMs = defaultDBBusyTimeoutMs
+	}
+	if opts.TraderStateFlushMs == 0 {
+		opts.TraderStateFlushMs = defaultTraderStateFlushMs
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath, opts.BusyTimeoutMs))
 	if err != nil {
 		return nil, fmt.Errorf("打开数据库失败: %w", err)
 	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
 
 	// 🔒 启用 WAL 模式,提高并发性能和崩溃恢复能力
 	// WAL (Write-Ahead Logging) 模式的优势:
@@ -117,7 +183,12 @@ func NewDatabase(dbPath string) (*Database, error) {
 		return nil, fmt.Errorf("初始化默认数据失败: %w", err)
 	}
 
+	if opts.TraderStateFlushMs != traderStateFlushDisabledVal && opts.TraderStateFlushMs > 0 {
+		database.stateBatcher = newTraderStateBatcher(database, time.Duration(opts.TraderStateFlushMs)*time.Millisecond)
+	}
+
 	log.Printf("✅ 数据库已启用 WAL 模式、FULL 同步和外键约束,数据完整性得到保证")
+	log.Printf("🗄️  数据库连接池: 最大连接 %d / 空闲 %d | busy_timeout %dms", opts.MaxOpenConns, opts.MaxIdleConns, opts.BusyTimeoutMs)
 	return database, nil
 }
 
@@ -136,6 +207,8 @@ func (d *Database) createTables() error {
 			api_key TEXT DEFAULT '',
 			custom_api_url TEXT DEFAULT '',
 			custom_model_name TEXT DEFAULT '',
+			system_prompt_prefix TEXT DEFAULT '',
+			system_prompt_suffix TEXT DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -159,6 +232,8 @@ func (d *Database) createTables() error {
 			aster_user TEXT DEFAULT '',
 			aster_signer TEXT DEFAULT '',
 			aster_private_key TEXT DEFAULT '',
+			-- OKX 特定字段
+			okx_passphrase TEXT DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -201,6 +276,41 @@ func (d *Database) createTables() error {
 			limit_price_offset REAL DEFAULT -0.03,
 			limit_timeout_seconds INTEGER DEFAULT 60,
 			timeframes TEXT DEFAULT '4h',
+			portfolio_group TEXT DEFAULT '',
+			max_trades_per_day INTEGER DEFAULT 0,
+			model_pool TEXT DEFAULT '',
+			model_pool_mode TEXT DEFAULT 'round_robin',
+			fallback_ai_model_id TEXT DEFAULT '',
+			log_level TEXT DEFAULT '',
+			hold_cache_pct REAL DEFAULT 0,
+			start_priority INTEGER DEFAULT 0,
+			max_exposure_multiple REAL DEFAULT 0,
+			respect_signal_bias BOOLEAN DEFAULT 0,
+			dry_run BOOLEAN DEFAULT 0,
+			alert_drawdown_pct REAL DEFAULT 0,
+			alert_daily_loss_pct REAL DEFAULT 0,
+			ai_quality_window INTEGER DEFAULT 0,
+			ai_quality_max_failure_pct REAL DEFAULT 0,
+			ai_quality_pause_minutes INTEGER DEFAULT 0,
+			daily_report BOOLEAN DEFAULT 0,
+			unfunded_threshold REAL DEFAULT 0,
+			tags TEXT DEFAULT '',
+			max_ai_calls_per_day INTEGER DEFAULT 0,
+			reject_non_candidates BOOLEAN DEFAULT 0,
+			open_verify_delay_ms INTEGER DEFAULT 0,
+			active_hours TEXT DEFAULT '',
+			weekend_trading BOOLEAN DEFAULT 1,
+			flatten_on_window_close BOOLEAN DEFAULT 0,
+			max_positions INTEGER DEFAULT 0,
+			max_position_size_usd REAL DEFAULT 0,
+			blacklisted_symbols TEXT DEFAULT '',
+			kline_limit INTEGER DEFAULT 0,
+			indicators TEXT DEFAULT '',
+			max_consecutive_errors INTEGER DEFAULT 0,
+			max_daily_loss REAL DEFAULT NULL,
+			max_drawdown REAL DEFAULT NULL,
+			stop_trading_minutes INTEGER DEFAULT NULL,
+			deleted_at DATETIME DEFAULT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
@@ -215,6 +325,11 @@ func (d *Database) createTables() error {
 			password_hash TEXT NOT NULL,
 			otp_secret TEXT,
 			otp_verified BOOLEAN DEFAULT 0,
+			display_currency TEXT DEFAULT 'USD',
+			outbound_proxy TEXT DEFAULT '',
+			disabled BOOLEAN DEFAULT 0,
+			email_verified BOOLEAN DEFAULT 0,
+			token_version INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
@@ -270,6 +385,177 @@ func (d *Database) createTables() error {
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 
+		// 竞赛快照表（冻结某一时刻的排行榜，用于分享）
+		`CREATE TABLE IF NOT EXISTS competition_snapshots (
+			id TEXT PRIMARY KEY,
+			data TEXT NOT NULL,                     -- GetCompetitionData 的 JSON 序列化
+			time_window TEXT DEFAULT '',            -- 排行榜统计窗口
+			data_as_of DATETIME,                    -- 排行榜数据的生成时间
+			created_by TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_competition_snapshots_created_at ON competition_snapshots(created_at)`,
+
+		// 用户 Webhook 配置表（交易事件签名回调）
+		`CREATE TABLE IF NOT EXISTS user_webhooks (
+			user_id TEXT PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,                   -- HMAC 签名密钥（加密存储）
+			events TEXT DEFAULT '',                 -- 订阅的事件，逗号分隔，为空表示全部
+			enabled BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		// 用户通知配置表（Telegram 机器人推送交易事件）
+		`CREATE TABLE IF NOT EXISTS user_notifications (
+			user_id TEXT PRIMARY KEY,
+			telegram_bot_token TEXT DEFAULT '',     -- 机器人令牌（加密存储）
+			telegram_chat_id TEXT DEFAULT '',       -- 接收消息的 Chat ID（加密存储）
+			events TEXT DEFAULT '',                 -- 订阅的事件，逗号分隔，为空表示全部
+			enabled BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		// 用户 API Key（个人访问令牌，用于脚本等非浏览器访问，只保存哈希）
+		`CREATE TABLE IF NOT EXISTS user_api_keys (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,          -- SHA-256(明文密钥)
+			key_prefix TEXT DEFAULT '',             -- 明文前缀，仅用于列表中识别
+			scope TEXT NOT NULL DEFAULT 'read',     -- read | read_write
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_used_at DATETIME DEFAULT NULL,
+			expires_at DATETIME DEFAULT NULL,       -- 为空表示永不过期
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_api_keys_user ON user_api_keys(user_id)`,
+
+		// 两步验证恢复码（丢失 Authenticator 时代替 OTP 使用，一次性，只保存哈希）
+		`CREATE TABLE IF NOT EXISTS user_recovery_codes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			code_hash TEXT NOT NULL,                -- SHA-256(user_id:规范化后的恢复码)
+			used_at DATETIME DEFAULT NULL,          -- 使用时间，为空表示未使用
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user ON user_recovery_codes(user_id)`,
+
+		// 邮件令牌（邮箱验证链接、密码重置链接；一次性、有过期时间，只保存哈希）
+		`CREATE TABLE IF NOT EXISTS user_email_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			purpose TEXT NOT NULL,                  -- verify_email / reset_password
+			token_hash TEXT NOT NULL UNIQUE,        -- SHA-256(令牌)
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME DEFAULT NULL,          -- 使用时间，为空表示未使用
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_email_tokens_user ON user_email_tokens(user_id, purpose)`,
+
+		// 被拒绝的决策记录（AI想执行但被守卫检查拦截的操作）
+		`CREATE TABLE IF NOT EXISTS rejected_decisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			user_id TEXT DEFAULT '',
+			symbol TEXT DEFAULT '',
+			action TEXT DEFAULT '',                 -- 尝试执行的动作（open_long/open_short...）
+			reason_code TEXT NOT NULL,              -- 结构化原因代码（daily_trade_limit/position_exists...）
+			reason TEXT DEFAULT '',                 -- 原始拒绝信息
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rejected_decisions_trader ON rejected_decisions(trader_id, created_at)`,
+
+		// 每日绩效报告（交易员跨日重置时生成，按交易员+日期唯一）
+		`CREATE TABLE IF NOT EXISTS daily_reports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			user_id TEXT DEFAULT '',
+			report_date TEXT NOT NULL,              -- YYYY-MM-DD（交易员本地时区）
+			opened_trades INTEGER DEFAULT 0,
+			closed_trades INTEGER DEFAULT 0,        -- 平仓次数（含部分平仓、紧急平仓、被动平仓）
+			winning_trades INTEGER DEFAULT 0,
+			losing_trades INTEGER DEFAULT 0,
+			win_rate REAL DEFAULT 0,                -- 胜率（%）
+			realized_pnl REAL DEFAULT 0,
+			starting_equity REAL DEFAULT 0,
+			ending_equity REAL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(trader_id, report_date)
+		)`,
+
+		// 净值快照（每个决策周期写入一条，收益率曲线直接查询，不再读取决策日志）
+		`CREATE TABLE IF NOT EXISTS equity_snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			timestamp INTEGER NOT NULL,             -- Unix timestamp (milliseconds)
+			total_equity REAL DEFAULT 0,            -- 账户净值（钱包余额 + 未实现盈亏）
+			available_balance REAL DEFAULT 0,
+			unrealized_pnl REAL DEFAULT 0,
+			total_pnl REAL DEFAULT 0,               -- 相对 initial_balance 的总盈亏
+			position_count INTEGER DEFAULT 0,
+			margin_used_pct REAL DEFAULT 0,
+			initial_balance REAL DEFAULT 0,         -- 当时的初始余额基准
+			cycle_number INTEGER DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_equity_snapshots_trader_time ON equity_snapshots(trader_id, timestamp)`,
+
+		// 持仓的人工备注和锁定（按交易员+币种+方向唯一，持仓完全平仓后自动删除）
+		`CREATE TABLE IF NOT EXISTS position_annotations (
+			trader_id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,                     -- long/short
+			note TEXT DEFAULT '',
+			locked BOOLEAN DEFAULT 0,               -- 锁定后执行层拒绝 AI 的平仓决策
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (trader_id, symbol, side)
+		)`,
+
+		// 账户操作审计日志（登录、凭证/模型/交易员/提示词变更、密码重置）
+		// 不设外键：用户被删除后审计记录仍保留；details 只记录变更了哪些字段，不记录密钥明文
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT DEFAULT '',                -- 操作人（登录失败且邮箱不存在时为空）
+			action TEXT NOT NULL,                   -- login_success / exchange_update / trader_delete ...
+			resource_type TEXT DEFAULT '',          -- user / exchange / model / trader / prompt_template
+			resource_id TEXT DEFAULT '',
+			ip TEXT DEFAULT '',
+			user_agent TEXT DEFAULT '',
+			details TEXT DEFAULT '',                -- JSON
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_user_time ON audit_log(user_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_action_time ON audit_log(action, created_at)`,
+
+		// 交易员配置模板（不含名称、余额和交易所/模型绑定；user_id='default' 为系统模板）
+		`CREATE TABLE IF NOT EXISTS trader_templates (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT DEFAULT '',
+			config TEXT NOT NULL DEFAULT '{}',      -- JSON，字段与创建交易员请求一致
+			source_trader_id TEXT DEFAULT '',       -- 从哪个交易员保存（系统模板为空）
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		// 已撤销的 Access/Refresh Token（登出、刷新轮换、修改密码），服务重启后仍然生效，过期后定期清理
+		`CREATE TABLE IF NOT EXISTS revoked_tokens (
+			token_id TEXT PRIMARY KEY,              -- JWT 的 jti（没有 jti 的旧token为 SHA-256 哈希）
+			token_type TEXT NOT NULL,               -- access / refresh
+			user_id TEXT DEFAULT '',
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires ON revoked_tokens(expires_at)`,
+
 		// 触发器：自动更新 updated_at
 		`CREATE TRIGGER IF NOT EXISTS update_users_updated_at
 			AFTER UPDATE ON users
@@ -326,6 +612,7 @@ func (d *Database) createTables() error {
 		`ALTER TABLE exchanges ADD COLUMN aster_user TEXT DEFAULT ''`,
 		`ALTER TABLE exchanges ADD COLUMN aster_signer TEXT DEFAULT ''`,
 		`ALTER TABLE exchanges ADD COLUMN aster_private_key TEXT DEFAULT ''`,
+		`ALTER TABLE exchanges ADD COLUMN okx_passphrase TEXT DEFAULT ''`,
 		`ALTER TABLE traders ADD COLUMN custom_prompt TEXT DEFAULT ''`,
 		`ALTER TABLE traders ADD COLUMN override_base_prompt BOOLEAN DEFAULT 0`,
 		`ALTER TABLE traders ADD COLUMN is_cross_margin BOOLEAN DEFAULT 1`,                 // 默认为全仓模式
@@ -343,8 +630,50 @@ func (d *Database) createTables() error {
 		`ALTER TABLE traders ADD COLUMN limit_price_offset REAL DEFAULT -0.03`,             // Limit order price offset percentage (e.g., -0.03 for -0.03%)
 		`ALTER TABLE traders ADD COLUMN limit_timeout_seconds INTEGER DEFAULT 60`,          // Timeout in seconds before converting to market order
 		`ALTER TABLE traders ADD COLUMN timeframes TEXT DEFAULT '4h'`,                      // 时间线选择 (逗号分隔，例如: "1m,4h,1d")
+		`ALTER TABLE traders ADD COLUMN portfolio_group TEXT DEFAULT ''`,                   // 组合模式分组名称（空=独立决策）
+		`ALTER TABLE traders ADD COLUMN max_trades_per_day INTEGER DEFAULT 0`,              // 每日最多开仓次数（0=不限制）
+		`ALTER TABLE traders ADD COLUMN model_pool TEXT DEFAULT ''`,                        // 模型池：AI模型ID列表，逗号分隔（为空则只使用 ai_model_id）
+		`ALTER TABLE traders ADD COLUMN model_pool_mode TEXT DEFAULT 'round_robin'`,        // 模型池选择方式：round_robin/random
+		`ALTER TABLE traders ADD COLUMN fallback_ai_model_id TEXT DEFAULT ''`,              // 备用AI模型ID（主模型调用失败时重试一次，空=不启用）
+		`ALTER TABLE traders ADD COLUMN log_level TEXT DEFAULT ''`,                         // 交易员日志级别（debug/info/warn/error，空=使用全局 log.level）
+		`ALTER TABLE traders ADD COLUMN hold_cache_pct REAL DEFAULT 0`,                     // 重复持有决策缓存的价格变动阈值（百分比，0=关闭）
+		`ALTER TABLE traders ADD COLUMN start_priority INTEGER DEFAULT 0`,                  // 开机自动启动优先级（越大越先启动）
+		`ALTER TABLE traders ADD COLUMN max_exposure_multiple REAL DEFAULT 0`,              // 最大总敞口倍数（总名义价值/账户净值，0=不限制）
+		`ALTER TABLE traders ADD COLUMN respect_signal_bias BOOLEAN DEFAULT 0`,             // 开仓方向必须与信号源方向偏好一致
+		`ALTER TABLE traders ADD COLUMN dry_run BOOLEAN DEFAULT 0`,                         // 模拟运行（不实际下单）
+		`ALTER TABLE traders ADD COLUMN alert_drawdown_pct REAL DEFAULT 0`,                 // 净值回撤预警阈值（%，0=不启用）
+		`ALTER TABLE traders ADD COLUMN alert_daily_loss_pct REAL DEFAULT 0`,               // 当日亏损预警阈值（%，0=不启用）
+		`ALTER TABLE traders ADD COLUMN ai_quality_window INTEGER DEFAULT 0`,               // AI质量检测窗口（最近N次调用，0=不启用）
+		`ALTER TABLE traders ADD COLUMN ai_quality_max_failure_pct REAL DEFAULT 0`,         // AI调用失败率暂停阈值（%）
+		`ALTER TABLE traders ADD COLUMN ai_quality_pause_minutes INTEGER DEFAULT 0`,        // AI质量暂停冷却时长（分钟，0=默认30分钟）
+		`ALTER TABLE traders ADD COLUMN daily_report BOOLEAN DEFAULT 0`,                    // 每日报告（跨日时生成并推送）
+		`ALTER TABLE traders ADD COLUMN unfunded_threshold REAL DEFAULT 0`,                 // 未入金判定阈值（USDT，0=默认1）
+		`ALTER TABLE traders ADD COLUMN tags TEXT DEFAULT ''`,                              // 标签（逗号分隔，用于分组筛选和按标签汇总）
+		`ALTER TABLE traders ADD COLUMN max_ai_calls_per_day INTEGER DEFAULT 0`,            // 每日AI调用上限（0=不限制）
+		`ALTER TABLE traders ADD COLUMN reject_non_candidates BOOLEAN DEFAULT 0`,           // 仅允许对候选币种开仓
+		`ALTER TABLE traders ADD COLUMN open_verify_delay_ms INTEGER DEFAULT 0`,            // 开仓确认延迟毫秒（0=不确认）
+		`ALTER TABLE traders ADD COLUMN active_hours TEXT DEFAULT ''`,                      // 交易时间窗口（UTC，如 13:00-21:00，逗号分隔多段，空=全天）
+		`ALTER TABLE traders ADD COLUMN weekend_trading BOOLEAN DEFAULT 1`,                 // 周末（UTC 周六、周日）是否交易
+		`ALTER TABLE traders ADD COLUMN flatten_on_window_close BOOLEAN DEFAULT 0`,         // 交易窗口外平掉所有持仓
+		`ALTER TABLE traders ADD COLUMN max_positions INTEGER DEFAULT 0`,                   // 最多同时持仓数量（0=不限制）
+		`ALTER TABLE traders ADD COLUMN max_position_size_usd REAL DEFAULT 0`,              // 单笔开仓最大名义价值USDT（0=不限制）
+		`ALTER TABLE traders ADD COLUMN blacklisted_symbols TEXT DEFAULT ''`,               // 禁止开仓的币种，逗号分隔（执行时强制拒绝）
+		`ALTER TABLE traders ADD COLUMN kline_limit INTEGER DEFAULT 0`,                     // 每个时间线提供给AI的K线数量（0=默认）
+		`ALTER TABLE traders ADD COLUMN indicators TEXT DEFAULT ''`,                        // 提示词中的技术指标，逗号分隔（空=默认指标集）
+		`ALTER TABLE traders ADD COLUMN max_consecutive_errors INTEGER DEFAULT 0`,          // 连续决策周期失败达到该次数时自动暂停（0=不暂停）
+		`ALTER TABLE traders ADD COLUMN max_daily_loss REAL DEFAULT NULL`,                  // 交易员级最大日亏损百分比（NULL=使用系统配置）
+		`ALTER TABLE traders ADD COLUMN max_drawdown REAL DEFAULT NULL`,                    // 交易员级最大回撤百分比（NULL=使用系统配置）
+		`ALTER TABLE traders ADD COLUMN stop_trading_minutes INTEGER DEFAULT NULL`,         // 交易员级风控暂停分钟数（NULL=使用系统配置）
+		`ALTER TABLE traders ADD COLUMN deleted_at DATETIME DEFAULT NULL`,                  // 软删除时间（NULL=未删除）
 		`ALTER TABLE ai_models ADD COLUMN custom_api_url TEXT DEFAULT ''`,                  // 自定义API地址
 		`ALTER TABLE ai_models ADD COLUMN custom_model_name TEXT DEFAULT ''`,               // 自定义模型名称
+		`ALTER TABLE ai_models ADD COLUMN system_prompt_prefix TEXT DEFAULT ''`,            // 模型专属 System Prompt 前缀
+		`ALTER TABLE ai_models ADD COLUMN system_prompt_suffix TEXT DEFAULT ''`,            // 模型专属 System Prompt 后缀
+		`ALTER TABLE users ADD COLUMN display_currency TEXT DEFAULT 'USD'`,                 // 金额展示币种（USD/BTC/ETH，仅影响API展示）
+		`ALTER TABLE users ADD COLUMN outbound_proxy TEXT DEFAULT ''`,                      // 出站代理地址（访问交易所和AI API）
+		`ALTER TABLE users ADD COLUMN disabled BOOLEAN DEFAULT 0`,                          // 是否被管理员禁用（禁止登录和访问API）
+		`ALTER TABLE users ADD COLUMN email_verified BOOLEAN DEFAULT 0`,                    // 是否已通过邮件链接验证邮箱
+		`ALTER TABLE users ADD COLUMN token_version INTEGER DEFAULT 0`,                     // token版本号（登出所有设备时递增，此前签发的token失效）
 	}
 
 	for _, query := range alterQueries {
@@ -364,15 +693,49 @@ func (d *Database) createTables() error {
 		log.Printf("⚠️ 迁移自增ID失败: %v", err)
 	}
 
+	// 同一用户下的重名交易员加后缀，之后才能创建 (user_id, name) 唯一索引
+	if err := d.dedupeTraderNames(); err != nil {
+		log.Printf("⚠️ 处理重名交易员失败: %v", err)
+	}
+
 	// 🔒 添加 UNIQUE 約束防止重複配置
 	uniqueConstraints := []string{
-		// ai_models: 同一用戶不能有重複的 model_id
+		// ai_models: 同一用戶同一 model_id 下的賬戶名稱（display_name）不能重複
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_ai_models_user_model
-		 ON ai_models(user_id, model_id)`,
+		 ON ai_models(user_id, model_id, display_name)`,
 
-		// exchanges: 同一用戶不能有重複的 exchange_id
+		// exchanges: 同一用戶同一 exchange_id 下的賬戶名稱（display_name）不能重複
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_exchanges_user_exchange
-		 ON exchanges(user_id, exchange_id)`,
+		 ON exchanges(user_id, exchange_id, display_name)`,
+
+		// traders: 同一用戶不能有重名的交易員（並發創建時由數據庫保證唯一，已軟刪除的交易員不佔用名稱）
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_traders_user_name
+		 ON traders(user_id, name) WHERE deleted_at IS NULL`,
+	}
+
+	// 舊版索引不含軟刪除條件，刪除後按新定義重建
+	var traderNameIndex string
+	if err := d.db.QueryRow(`SELECT COALESCE(sql, '') FROM sqlite_master WHERE type = 'index' AND name = 'idx_traders_user_name'`).Scan(&traderNameIndex); err == nil && !strings.Contains(traderNameIndex, "deleted_at") {
+		if _, err := d.db.Exec(`DROP INDEX idx_traders_user_name`); err != nil {
+			log.Printf("⚠️ 刪除舊版交易員名稱索引失敗: %v", err)
+		}
+	}
+
+	// 舊版索引每種交易所/模型只允許一個配置，刪除後按 (類型, 賬戶名稱) 重建以支持多賬戶
+	for _, idx := range []struct{ table, name string }{
+		{"ai_models", "idx_ai_models_user_model"},
+		{"exchanges", "idx_exchanges_user_exchange"},
+	} {
+		// NULL 在唯一索引中互不相等，統一為空字符串（默認賬戶）
+		if _, err := d.db.Exec(fmt.Sprintf(`UPDATE %s SET display_name = '' WHERE display_name IS NULL`, idx.table)); err != nil {
+			log.Printf("⚠️ 規範化 %s.display_name 失敗: %v", idx.table, err)
+		}
+		var indexSQL string
+		if err := d.db.QueryRow(`SELECT COALESCE(sql, '') FROM sqlite_master WHERE type = 'index' AND name = ?`, idx.name).Scan(&indexSQL); err == nil && !strings.Contains(indexSQL, "display_name") {
+			if _, err := d.db.Exec(fmt.Sprintf(`DROP INDEX %s`, idx.name)); err != nil {
+				log.Printf("⚠️ 刪除舊版索引 %s 失敗: %v", idx.name, err)
+			}
+		}
 	}
 
 	for _, query := range uniqueConstraints {
@@ -385,6 +748,65 @@ func (d *Database) createTables() error {
 	return nil
 }
 
+// dedupeTraderNames 为同一用户下重名的交易员追加后缀（保留最早创建的名称不变），例如 "BTC趋势 (2)"
+func (d *Database) dedupeTraderNames() error {
+	rows, err := d.db.Query(`
+		SELECT id, user_id, name FROM traders
+		WHERE (user_id, name) IN (
+			SELECT user_id, name FROM traders GROUP BY user_id, name HAVING COUNT(*) > 1
+		)
+		ORDER BY user_id, name, created_at, id
+	`)
+	if err != nil {
+		return err
+	}
+
+	type traderName struct{ id, userID, name string }
+	var duplicates []traderName
+	for rows.Next() {
+		var t traderName
+		if err := rows.Scan(&t.id, &t.userID, &t.name); err != nil {
+			rows.Close()
+			return err
+		}
+		duplicates = append(duplicates, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range duplicates {
+		key := t.userID + "\x00" + t.name
+		if !seen[key] {
+			seen[key] = true // 第一个保留原名
+			continue
+		}
+		for i := 2; ; i++ {
+			candidate := fmt.Sprintf("%s (%d)", t.name, i)
+			var exists int
+			if err := d.db.QueryRow(`SELECT COUNT(*) FROM traders WHERE user_id = ? AND name = ?`, t.userID, candidate).Scan(&exists); err != nil {
+				return err
+			}
+			if exists > 0 {
+				continue
+			}
+			if _, err := d.db.Exec(`UPDATE traders SET name = ? WHERE id = ?`, candidate, t.id); err != nil {
+				return err
+			}
+			log.Printf("⚠️ 交易员 %s 名称与同用户的其他交易员重复，已重命名为: %s", t.id, candidate)
+			break
+		}
+	}
+	return nil
+}
+
+// isTraderNameConflict 判断错误是否由 traders(user_id, name) 唯一索引冲突引起
+func isTraderNameConflict(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: traders.user_id, traders.name")
+}
+
 // initDefaultData 初始化默认数据
 func (d *Database) initDefaultData() error {
 	// 确保 default 用户存在（后续 AI 模型、交易所都依赖此外键）
@@ -475,6 +897,8 @@ func (d *Database) initDefaultData() error {
 		{"binance", "Binance Futures", "binance"},
 		{"hyperliquid", "Hyperliquid", "hyperliquid"},
 		{"aster", "Aster DEX", "aster"},
+		{"okx", "OKX Futures", "okx"},
+		{"paper", "Paper Trading", "paper"},
 	}
 
 	// 檢查表結構，判斷是否已遷移到自增ID結構
@@ -544,6 +968,70 @@ func (d *Database) initDefaultData() error {
 		"altcoin_leverage":     "5",                                                                                   // 山寨币杠杆倍数
 		"jwt_secret":           "",                                                                                    // JWT密钥，默认为空，由config.json或系统生成
 		"registration_enabled": "true",                                                                                // 默认允许注册
+		"response_decimals":    "2",                                                                                   // API响应中金额/盈亏字段保留的小数位
+		"admin_emails":         "",                                                                                    // 管理员邮箱（逗号分隔），admin 用户始终为管理员
+		"snapshot_keep_days":   "30",                                                                                  // 竞赛快照保留天数（0=永久保留）
+		"max_symbol_traders":   "0",                                                                                   // 同一币种最多同时持仓的交易员数（全实例，0=不限制）
+		"log_compact_days":     "7",                                                                                   // 决策记录超过N天后压缩提示词/思维链（0=不压缩）
+		"log_compact_mode":     "gzip",                                                                                // 决策记录压缩方式：gzip（可还原）/ strip（直接清空）
+		"log_retention_days":   "0",                                                                                   // 决策记录保留天数，更早的记录归档或删除（0=不限制）
+		"log_max_records":      "0",                                                                                   // 每个交易员最多保留的决策记录条数（0=不限制）
+		"log_retention_mode":   "archive",                                                                             // 过期决策记录处理方式：archive（gzip 归档到 archive/ 子目录）/ delete（直接删除）
+		"ai_token_prices":      "{}",                                                                                  // 每个模型每1000 token的价格（USD，JSON，如 {"deepseek":0.0014,"default":0.002}），用于估算AI费用
+		"sl_tp_dedup_pct":      "0.01",                                                                                // 止损/止盈调整去重容差（百分比，负数=关闭去重）
+		"strict_price_usd":     "0",                                                                                   // 开仓金额达到该值(USDT)时要求至少两个数据源价格一致（0=不启用）
+		"reject_log_enabled":   "true",                                                                                // 是否记录被守卫检查拒绝的决策
+		"reject_keep_days":     "30",                                                                                  // 被拒绝决策记录保留天数（0=永久保留）
+		"reject_feedback":      "true",                                                                                // 是否把执行失败的决策（交易所拒单等）在下一周期反馈给AI
+		"safety_stop_pct":      "5",                                                                                   // 持仓没有止损单时自动设置的兜底止损距离（入场价的百分比，0=不启用）
+		"autostart_max":        "0",                                                                                   // 开机最多自动启动的交易员数量（0=不限制），超出的保持停止等待手动启动
+		"autostart_interval":   "0",                                                                                   // 开机自动启动交易员的间隔秒数（0=同时启动）
+		"stagger_start":        "true",                                                                                // 开机自动启动的交易员把首次决策周期均匀错开在一个扫描间隔内（false=启动后立即执行）
+		"scan_jitter_pct":      "0",                                                                                   // 扫描间隔随机抖动百分比（例如 10 表示 ±10%，0=不抖动），避免多个交易员同时请求
+		"default_template":     "default",                                                                             // 新建交易员未指定提示词模板时使用的系统默认模板
+		"metrics_token":        "",                                                                                    // Prometheus 指标接口 /metrics 的访问令牌（为空=无需认证）
+		"balance_cache_ttl":    "10",                                                                                  // 交易所余额查询缓存秒数（创建交易员/同步余额，0=不缓存）
+		"flatten_on_shutdown":  "false",                                                                               // 服务关闭时是否平掉所有交易员的持仓（true=平仓，默认保留持仓）
+		"maintenance_mode":     "false",                                                                               // 维护模式：暂停所有交易员的AI决策（持仓和止损单保持不动，通过 POST /api/admin/maintenance 切换）
+		"maintenance_message":  "",                                                                                    // 维护模式提示信息（显示在前端横幅）
+		"maintenance_until":    "",                                                                                    // 维护模式自动结束时间（RFC3339，为空=手动关闭）
+
+		// 决策记录中的提示词/思维链加密存储（需配置 DATA_ENCRYPTION_KEY，历史记录通过 POST /api/admin/decision-logs/encrypt 迁移）
+		"encrypt_decision_logs": "false",
+
+		// K线缓存过期倍数：WebSocket 缓存的数据年龄超过 N 个时间线周期时回退 REST（0=固定 5 分钟）
+		"kline_cache_stale_factor": "2",
+
+		// 全局禁止开仓的币种（逗号分隔，对所有交易员生效，如 PEPEUSDT,1000SHIBUSDT），执行时强制拒绝
+		"global_symbol_blacklist": "",
+
+		// 所有交易员共用的全局并发上限（0=不限制）：同时进行的AI请求数、交易所 REST 请求数（Hyperliquid 不受限制）
+		"max_concurrent_ai_calls":       "0",
+		"max_concurrent_exchange_calls": "0",
+
+		// 单次AI请求超时（秒）：超时视为服务商错误，配置了备用模型的交易员会改用备用模型重试（0=默认 120 秒）
+		"ai_request_timeout_seconds": "120",
+
+		// 信号源（AI500 币种池 / OI Top）快照：后台每隔 signal_cache_ttl_seconds 秒统一刷新，交易员只读取快照
+		// 刷新失败时继续使用上一次成功的快照；signal_fetch_timeout_seconds 为单次 HTTP 请求超时
+		"signal_cache_ttl_seconds":     "300",
+		"signal_fetch_timeout_seconds": "10",
+
+		// 邮件（SMTP）：配置后注册发送邮箱验证链接、支持邮件重置密码；环境变量 SMTP_* 优先，密码通过 PUT /api/admin/smtp 加密保存
+		"smtp_host":                  "",
+		"smtp_port":                  "587",
+		"smtp_username":              "",
+		"smtp_password":              "",
+		"smtp_from":                  "",
+		"email_link_base_url":        "", // 邮件链接的前端地址（如 https://nofx.example.com，为空时使用 FRONTEND_URL）
+		"require_email_verification": "false",
+
+		// 数据库自动备份（VACUUM INTO 到数据库目录下的 backups/，管理员可通过 /api/admin/backups 下载）
+		// 从每天 backup_time 起每隔 backup_interval_hours 小时执行一次（0=关闭自动备份）
+		"backup_interval_hours": "24",
+		"backup_time":           "03:00",
+		"backup_keep_count":     "7",  // 最多保留的备份数量（0=不限制）
+		"backup_keep_days":      "30", // 备份保留天数（0=不限制）
 	}
 
 	for key, value := range systemConfigs {
@@ -556,6 +1044,17 @@ func (d *Database) initDefaultData() error {
 		}
 	}
 
+	// 初始化系统交易员模板（已存在则保留，管理员可直接修改数据库中的配置）
+	for _, tpl := range systemTraderTemplates {
+		_, err := d.db.Exec(`
+			INSERT OR IGNORE INTO trader_templates (id, user_id, name, description, config)
+			VALUES (?, 'default', ?, ?, ?)
+		`, tpl.ID, tpl.Name, tpl.Description, tpl.Config)
+		if err != nil {
+			return fmt.Errorf("初始化系统交易员模板失败: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -938,6 +1437,8 @@ func (d *Database) migrateAIModelsTable() error {
 			api_key TEXT DEFAULT '',
 			custom_api_url TEXT DEFAULT '',
 			custom_model_name TEXT DEFAULT '',
+			system_prompt_prefix TEXT DEFAULT '',
+			system_prompt_suffix TEXT DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -1060,6 +1561,7 @@ func (d *Database) migrateExchangesTableToAutoIncrement() error {
 			aster_user TEXT DEFAULT '',
 			aster_signer TEXT DEFAULT '',
 			aster_private_key TEXT DEFAULT '',
+			okx_passphrase TEXT DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -1160,11 +1662,13 @@ func (d *Database) migrateExchangesTableToAutoIncrement() error {
 
 // User 用户配置
 type User struct {
-	ID           string `json:"id"`
-	Email        string `json:"email"`
-	PasswordHash string `json:"-"` // 不返回到前端
-	OTPSecret    string `json:"-"` // 不返回到前端
-	OTPVerified  bool   `json:"otp_verified"`
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	PasswordHash  string `json:"-"` // 不返回到前端
+	OTPSecret     string `json:"-"` // 不返回到前端
+	OTPVerified   bool   `json:"otp_verified"`
+	EmailVerified bool   `json:"email_verified"` // 已通过邮件链接验证邮箱
+	Disabled      bool   `json:"disabled"`       // 被管理员禁用
 	// 使用 string 類型來避免 SQLite 時間解析問題
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
@@ -1182,6 +1686,9 @@ type AIModelConfig struct {
 	APIKey          string `json:"apiKey"`
 	CustomAPIURL    string `json:"customApiUrl"`
 	CustomModelName string `json:"customModelName"`
+	// 模型专属 System Prompt 前缀/后缀（为空表示不添加）
+	SystemPromptPrefix string `json:"systemPromptPrefix"`
+	SystemPromptSuffix string `json:"systemPromptSuffix"`
 	// 使用 string 類型來避免 SQLite 時間解析問題
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
@@ -1206,6 +1713,8 @@ type ExchangeConfig struct {
 	AsterUser       string `json:"asterUser"`
 	AsterSigner     string `json:"asterSigner"`
 	AsterPrivateKey string `json:"asterPrivateKey"`
+	// OKX 特定字段（API Key/Secret 复用 APIKey/SecretKey）
+	OKXPassphrase string `json:"okxPassphrase"`
 	// 使用 string 類型來避免 SQLite 時間解析問題
 	// SQLite 存儲時間為 TEXT，直接 Scan 到 time.Time 可能失敗
 	CreatedAt string `json:"created_at"`
@@ -1237,6 +1746,41 @@ type TraderRecord struct {
 	LimitPriceOffset     float64 `json:"limit_price_offset"`     // Limit order price offset percentage (e.g., -0.03 for -0.03%)
 	LimitTimeoutSeconds  int     `json:"limit_timeout_seconds"`  // Timeout in seconds before converting to market order (default: 60)
 	Timeframes           string  `json:"timeframes"`             // 时间线选择 (逗号分隔，例如: "1m,4h,1d")
+	PortfolioGroup       string  `json:"portfolio_group"`        // 组合模式分组名称（同组交易员共用一次AI调用，空=独立决策）
+	MaxTradesPerDay      int     `json:"max_trades_per_day"`     // 每日最多开仓次数（0=不限制）
+	ModelPool            string  `json:"model_pool"`             // 模型池：AI模型ID列表，逗号分隔（为空则只使用 ai_model_id）
+	ModelPoolMode        string  `json:"model_pool_mode"`        // 模型池选择方式：round_robin/random
+	FallbackAIModelID    string  `json:"fallback_ai_model_id"`   // 备用AI模型ID（主模型调用失败时重试一次，空=不启用）
+	LogLevel             string  `json:"log_level"`              // 交易员日志级别（debug/info/warn/error，空=使用全局 log.level）
+	HoldCachePct         float64 `json:"hold_cache_pct"`         // 重复持有决策缓存的价格变动阈值（百分比，0=关闭）
+	StartPriority        int     `json:"start_priority"`         // 开机自动启动优先级（越大越先启动）
+	MaxExposureMultiple  float64 `json:"max_exposure_multiple"`  // 最大总敞口倍数（总名义价值/账户净值，0=不限制）
+	RespectSignalBias    bool    `json:"respect_signal_bias"`    // 开仓方向必须与信号源方向偏好一致
+	DryRun               bool    `json:"dry_run"`                // 模拟运行（不实际下单）
+	AlertDrawdownPct     float64 `json:"alert_drawdown_pct"`     // 净值回撤预警阈值（%，0=不启用）
+	AlertDailyLossPct    float64 `json:"alert_daily_loss_pct"`   // 当日亏损预警阈值（%，0=不启用）
+	AIQualityWindow      int     `json:"ai_quality_window"`      // AI质量检测窗口（最近N次调用，0=不启用）
+	AIQualityMaxFailurePct float64 `json:"ai_quality_max_failure_pct"` // AI调用失败率暂停阈值（%）
+	AIQualityPauseMinutes  int     `json:"ai_quality_pause_minutes"`   // AI质量暂停冷却时长（分钟，0=默认30分钟）
+	DailyReport            bool    `json:"daily_report"`               // 每日报告（跨日时生成并推送）
+	UnfundedThreshold      float64 `json:"unfunded_threshold"`         // 未入金判定阈值（USDT，0=默认1）
+	Tags                   string  `json:"tags"`                       // 标签（逗号分隔，用于分组筛选和按标签汇总）
+	MaxAICallsPerDay       int     `json:"max_ai_calls_per_day"`       // 每日AI调用上限（0=不限制）
+	RejectNonCandidates    bool    `json:"reject_non_candidates"`      // 仅允许对候选币种开仓
+	OpenVerifyDelayMs      int     `json:"open_verify_delay_ms"`       // 开仓确认延迟毫秒（0=不确认）
+	ActiveHours            string  `json:"active_hours"`               // 交易时间窗口（UTC，如 13:00-21:00，逗号分隔多段，空=全天）
+	WeekendTrading         bool    `json:"weekend_trading"`            // 周末（UTC 周六、周日）是否交易
+	FlattenOnWindowClose   bool    `json:"flatten_on_window_close"`    // 交易窗口外平掉所有持仓
+	MaxPositions           int     `json:"max_positions"`              // 最多同时持仓数量（0=不限制）
+	MaxPositionSizeUSD     float64 `json:"max_position_size_usd"`      // 单笔开仓最大名义价值USDT（0=不限制）
+	BlacklistedSymbols     string  `json:"blacklisted_symbols"`        // 禁止开仓的币种，逗号分隔（执行时强制拒绝）
+	KlineLimit             int     `json:"kline_limit"`                // 每个时间线提供给AI的K线数量（0=默认10根）
+	Indicators             string  `json:"indicators"`                 // 提示词中的技术指标，逗号分隔（如 ema20,rsi14,atr14，空=默认指标集）
+	MaxConsecutiveErrors   int     `json:"max_consecutive_errors"`     // 连续决策周期失败达到该次数时自动暂停（0=不暂停）
+	// 交易员级风控阈值（nil=使用系统配置 max_daily_loss/max_drawdown/stop_trading_minutes）
+	MaxDailyLoss       *float64 `json:"max_daily_loss"`       // 最大日亏损百分比
+	MaxDrawdown        *float64 `json:"max_drawdown"`         // 最大回撤百分比
+	StopTradingMinutes *int     `json:"stop_trading_minutes"` // 触发风控后暂停分钟数
 	// 使用 string 類型來避免 SQLite 時間解析問題
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
@@ -1302,11 +1846,11 @@ func (d *Database) EnsureAdminUser() error {
 func (d *Database) GetUserByEmail(email string) (*User, error) {
 	var user User
 	err := d.db.QueryRow(`
-		SELECT id, email, password_hash, otp_secret, otp_verified, created_at, updated_at
+		SELECT id, email, password_hash, otp_secret, otp_verified, COALESCE(email_verified, 0), COALESCE(disabled, 0), created_at, updated_at
 		FROM users WHERE email = ?
 	`, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.OTPSecret,
-		&user.OTPVerified, &user.CreatedAt, &user.UpdatedAt,
+		&user.OTPVerified, &user.EmailVerified, &user.Disabled, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -1318,11 +1862,11 @@ func (d *Database) GetUserByEmail(email string) (*User, error) {
 func (d *Database) GetUserByID(userID string) (*User, error) {
 	var user User
 	err := d.db.QueryRow(`
-		SELECT id, email, password_hash, otp_secret, otp_verified, created_at, updated_at
+		SELECT id, email, password_hash, otp_secret, otp_verified, COALESCE(email_verified, 0), COALESCE(disabled, 0), created_at, updated_at
 		FROM users WHERE id = ?
 	`, userID).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.OTPSecret,
-		&user.OTPVerified, &user.CreatedAt, &user.UpdatedAt,
+		&user.OTPVerified, &user.EmailVerified, &user.Disabled, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -1349,12 +1893,122 @@ func (d *Database) GetAllUsers() ([]string, error) {
 	return userIDs, nil
 }
 
+// UserSummary 管理员用户列表项（含交易员数量统计）
+type UserSummary struct {
+	ID                 string `json:"id"`
+	Email              string `json:"email"`
+	OTPVerified        bool   `json:"otp_verified"`
+	Disabled           bool   `json:"disabled"`
+	TraderCount        int    `json:"trader_count"`
+	RunningTraderCount int    `json:"running_trader_count"`
+	CreatedAt          string `json:"created_at"`
+}
+
+// ListUsersWithStats 获取所有用户及其交易员数量（管理员接口使用）
+func (d *Database) ListUsersWithStats() ([]*UserSummary, error) {
+	rows, err := d.db.Query(`
+		SELECT u.id, u.email, u.otp_verified, COALESCE(u.disabled, 0), u.created_at,
+		       COUNT(t.id), COALESCE(SUM(CASE WHEN t.is_running = 1 THEN 1 ELSE 0 END), 0)
+		FROM users u
+		LEFT JOIN traders t ON t.user_id = u.id AND t.deleted_at IS NULL
+		GROUP BY u.id
+		ORDER BY u.created_at, u.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*UserSummary, 0)
+	for rows.Next() {
+		var u UserSummary
+		if err := rows.Scan(&u.ID, &u.Email, &u.OTPVerified, &u.Disabled, &u.CreatedAt,
+			&u.TraderCount, &u.RunningTraderCount); err != nil {
+			return nil, err
+		}
+		users = append(users, &u)
+	}
+	return users, rows.Err()
+}
+
+// IsUserDisabled 判断用户是否被禁用（用户不存在时返回 false，由其他逻辑处理）
+func (d *Database) IsUserDisabled(userID string) bool {
+	var disabled bool
+	err := d.db.QueryRow(`SELECT COALESCE(disabled, 0) FROM users WHERE id = ?`, userID).Scan(&disabled)
+	return err == nil && disabled
+}
+
+// SetUserDisabled 禁用或启用用户
+func (d *Database) SetUserDisabled(userID string, disabled bool) error {
+	result, err := d.db.Exec(`UPDATE users SET disabled = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, disabled, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("用户不存在: %s", userID)
+	}
+	return nil
+}
+
+// DeleteUser 在同一事务中删除用户及其全部数据
+// 交易员、交易所、AI模型、信号源和 Webhook 通过外键级联删除；交易历史等无外键的表显式删除
+func (d *Database) DeleteUser(userID string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"trade_history", "trader_state", "rejected_decisions", "daily_reports"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("删除 %s 失败: %w", table, err)
+		}
+	}
+	// 净值快照和持仓备注只记录 trader_id，需在删除交易员之前按交易员清理
+	for _, table := range []string{"equity_snapshots", "position_annotations"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE trader_id IN (SELECT id FROM traders WHERE user_id = ?)`, userID); err != nil {
+			return fmt.Errorf("删除 %s 失败: %w", table, err)
+		}
+	}
+	// 兼容外键未启用的旧库：显式删除级联表
+	for _, table := range []string{"traders", "exchanges", "ai_models", "user_signal_sources", "user_webhooks", "user_notifications", "user_api_keys", "user_recovery_codes", "user_email_tokens"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("删除 %s 失败: %w", table, err)
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("删除用户失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("用户不存在: %s", userID)
+	}
+	return tx.Commit()
+}
+
 // UpdateUserOTPVerified 更新用户OTP验证状态
 func (d *Database) UpdateUserOTPVerified(userID string, verified bool) error {
 	_, err := d.db.Exec(`UPDATE users SET otp_verified = ? WHERE id = ?`, verified, userID)
 	return err
 }
 
+// SetUserEmailVerified 标记用户邮箱已验证
+func (d *Database) SetUserEmailVerified(userID string) error {
+	_, err := d.db.Exec(`UPDATE users SET email_verified = 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, userID)
+	return err
+}
+
+// UpdateUserOTPSecret 更换用户的 OTP 密钥（重新绑定 Authenticator）
+func (d *Database) UpdateUserOTPSecret(userID, otpSecret string) error {
+	_, err := d.db.Exec(`
+		UPDATE users
+		SET otp_secret = ?, otp_verified = 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, otpSecret, userID)
+	return err
+}
+
 // UpdateUserPassword 更新用户密码
 func (d *Database) UpdateUserPassword(userID, passwordHash string) error {
 	_, err := d.db.Exec(`
@@ -1365,6 +2019,61 @@ func (d *Database) UpdateUserPassword(userID, passwordHash string) error {
 	return err
 }
 
+// GetUserDisplayCurrency 获取用户的金额展示币种（未设置时返回 USD）
+func (d *Database) GetUserDisplayCurrency(userID string) (string, error) {
+	var currency string
+	err := d.db.QueryRow(`SELECT COALESCE(display_currency, 'USD') FROM users WHERE id = ?`, userID).Scan(&currency)
+	if err == sql.ErrNoRows || currency == "" {
+		return "USD", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
+// SetUserDisplayCurrency 设置用户的金额展示币种
+func (d *Database) SetUserDisplayCurrency(userID, currency string) error {
+	result, err := d.db.Exec(`
+		UPDATE users
+		SET display_currency = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, currency, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("用户不存在: %s", userID)
+	}
+	return nil
+}
+
+// GetUserOutboundProxy 获取用户的出站代理地址（未设置时返回空字符串）
+func (d *Database) GetUserOutboundProxy(userID string) (string, error) {
+	var proxy string
+	err := d.db.QueryRow(`SELECT COALESCE(outbound_proxy, '') FROM users WHERE id = ?`, userID).Scan(&proxy)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return proxy, err
+}
+
+// SetUserOutboundProxy 设置用户的出站代理地址（空字符串表示不使用代理）
+func (d *Database) SetUserOutboundProxy(userID, proxy string) error {
+	result, err := d.db.Exec(`
+		UPDATE users
+		SET outbound_proxy = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, proxy, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("用户不存在: %s", userID)
+	}
+	return nil
+}
+
 // GetAIModels 获取用户的AI模型配置
 func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 	// 檢查表結構，判斷是否已遷移到自增ID結構
@@ -1381,9 +2090,11 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 	if hasModelIDColumn > 0 {
 		// 新結構：有 model_id 列
 		rows, err = d.db.Query(`
-			SELECT id, model_id, user_id, name, provider, enabled, api_key,
+			SELECT id, model_id, user_id, COALESCE(display_name, '') as display_name, name, provider, enabled, api_key,
 			       COALESCE(custom_api_url, '') as custom_api_url,
 			       COALESCE(custom_model_name, '') as custom_model_name,
+			       COALESCE(system_prompt_prefix, '') as system_prompt_prefix,
+			       COALESCE(system_prompt_suffix, '') as system_prompt_suffix,
 			       created_at, updated_at
 			FROM ai_models WHERE user_id = ? ORDER BY id
 		`, userID)
@@ -1409,8 +2120,9 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 		if hasModelIDColumn > 0 {
 			// 新結構：掃描包含 model_id
 			err = rows.Scan(
-				&model.ID, &model.ModelID, &model.UserID, &model.Name, &model.Provider,
+				&model.ID, &model.ModelID, &model.UserID, &model.DisplayName, &model.Name, &model.Provider,
 				&model.Enabled, &model.APIKey, &model.CustomAPIURL, &model.CustomModelName,
+				&model.SystemPromptPrefix, &model.SystemPromptSuffix,
 				&model.CreatedAt, &model.UpdatedAt,
 			)
 		} else {
@@ -1436,7 +2148,23 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 	return models, nil
 }
 
+// UpdateAIModelSystemPrompt 更新模型专属的 System Prompt 前缀/后缀（空字符串表示不添加）
+func (d *Database) UpdateAIModelSystemPrompt(userID, modelID, prefix, suffix string) error {
+	result, err := d.db.Exec(`
+		UPDATE ai_models SET system_prompt_prefix = ?, system_prompt_suffix = ?, updated_at = datetime('now')
+		WHERE user_id = ? AND model_id = ?
+	`, prefix, suffix, userID, modelID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrAIModelNotFound
+	}
+	return nil
+}
+
 // UpdateAIModel 更新AI模型配置，如果不存在则创建用户特定配置
+// 只作用于默认账户（display_name 为空），其他账户通过 UpdateAIModelAccount 按 ID 更新
 func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, customAPIURL, customModelName string) error {
 	log.Printf("🔧 [AI Model] UpdateAIModel 開始: userID=%s, id=%s, enabled=%v, apiKeyLen=%d, customURL=%s, customModelName=%s",
 		userID, id, enabled, len(apiKey), customAPIURL, customModelName)
@@ -1464,7 +2192,7 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 		// 先尝试精确匹配 model_id
 		var existingModelID string
 		err = d.db.QueryRow(`
-			SELECT model_id FROM ai_models WHERE user_id = ? AND model_id = ? LIMIT 1
+			SELECT model_id FROM ai_models WHERE user_id = ? AND model_id = ? AND display_name = '' LIMIT 1
 		`, userID, id).Scan(&existingModelID)
 
 		if err == nil {
@@ -1472,7 +2200,7 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 			log.Printf("✓ [AI Model] 找到現有配置（model_id匹配）: %s, 執行更新", existingModelID)
 			result, err := d.db.Exec(`
 				UPDATE ai_models SET enabled = ?, api_key = ?, custom_api_url = ?, custom_model_name = ?, updated_at = datetime('now')
-				WHERE model_id = ? AND user_id = ?
+				WHERE model_id = ? AND user_id = ? AND display_name = ''
 			`, enabled, encryptedAPIKey, customAPIURL, customModelName, existingModelID, userID)
 			if err != nil {
 				log.Printf("❌ [AI Model] 更新失敗: %v", err)
@@ -1487,7 +2215,7 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 		// model_id 不存在，尝试通过 provider 查找（兼容舊邏輯）
 		provider := id
 		err = d.db.QueryRow(`
-			SELECT model_id FROM ai_models WHERE user_id = ? AND provider = ? LIMIT 1
+			SELECT model_id FROM ai_models WHERE user_id = ? AND provider = ? AND display_name = '' LIMIT 1
 		`, userID, provider).Scan(&existingModelID)
 
 		if err == nil {
@@ -1496,7 +2224,7 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 			log.Printf("⚠️  使用旧版 provider 匹配更新模型: %s -> %s，同時修正 model_id 為: %s", provider, existingModelID, id)
 			_, err = d.db.Exec(`
 				UPDATE ai_models SET model_id = ?, enabled = ?, api_key = ?, custom_api_url = ?, custom_model_name = ?, updated_at = datetime('now')
-				WHERE model_id = ? AND user_id = ?
+				WHERE model_id = ? AND user_id = ? AND display_name = ''
 			`, id, enabled, encryptedAPIKey, customAPIURL, customModelName, existingModelID, userID)
 			if err != nil {
 				log.Printf("❌ [AI Model] 更新並修正 model_id 失敗: %v", err)
@@ -1607,11 +2335,12 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 	if hasExchangeIDColumn > 0 {
 		// 新結構：有 exchange_id 列
 		rows, err = d.db.Query(`
-			SELECT id, exchange_id, user_id, name, type, enabled, api_key, secret_key, testnet,
+			SELECT id, exchange_id, user_id, COALESCE(display_name, '') as display_name, name, type, enabled, api_key, secret_key, testnet,
 			       COALESCE(hyperliquid_wallet_addr, '') as hyperliquid_wallet_addr,
 			       COALESCE(aster_user, '') as aster_user,
 			       COALESCE(aster_signer, '') as aster_signer,
 			       COALESCE(aster_private_key, '') as aster_private_key,
+			       COALESCE(okx_passphrase, '') as okx_passphrase,
 			       created_at, updated_at
 			FROM exchanges WHERE user_id = ? ORDER BY id
 		`, userID)
@@ -1623,6 +2352,7 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 			       COALESCE(aster_user, '') as aster_user,
 			       COALESCE(aster_signer, '') as aster_signer,
 			       COALESCE(aster_private_key, '') as aster_private_key,
+			       COALESCE(okx_passphrase, '') as okx_passphrase,
 			       created_at, updated_at
 			FROM exchanges WHERE user_id = ? ORDER BY id
 		`, userID)
@@ -1639,10 +2369,10 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 		if hasExchangeIDColumn > 0 {
 			// 新結構：掃描包含 exchange_id
 			err = rows.Scan(
-				&exchange.ID, &exchange.ExchangeID, &exchange.UserID, &exchange.Name, &exchange.Type,
+				&exchange.ID, &exchange.ExchangeID, &exchange.UserID, &exchange.DisplayName, &exchange.Name, &exchange.Type,
 				&exchange.Enabled, &exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
 				&exchange.HyperliquidWalletAddr, &exchange.AsterUser,
-				&exchange.AsterSigner, &exchange.AsterPrivateKey,
+				&exchange.AsterSigner, &exchange.AsterPrivateKey, &exchange.OKXPassphrase,
 				&exchange.CreatedAt, &exchange.UpdatedAt,
 			)
 		} else {
@@ -1652,7 +2382,7 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 				&idValue, &exchange.UserID, &exchange.Name, &exchange.Type,
 				&exchange.Enabled, &exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
 				&exchange.HyperliquidWalletAddr, &exchange.AsterUser,
-				&exchange.AsterSigner, &exchange.AsterPrivateKey,
+				&exchange.AsterSigner, &exchange.AsterPrivateKey, &exchange.OKXPassphrase,
 				&exchange.CreatedAt, &exchange.UpdatedAt,
 			)
 			// 舊結構中 id 是文本，直接用作業務邏輯 ID
@@ -1667,6 +2397,7 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 		exchange.APIKey = d.decryptSensitiveData(exchange.APIKey)
 		exchange.SecretKey = d.decryptSensitiveData(exchange.SecretKey)
 		exchange.AsterPrivateKey = d.decryptSensitiveData(exchange.AsterPrivateKey)
+		exchange.OKXPassphrase = d.decryptSensitiveData(exchange.OKXPassphrase)
 
 		exchanges = append(exchanges, &exchange)
 	}
@@ -1674,7 +2405,41 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 	return exchanges, nil
 }
 
+// GetEnabledTestnetExchanges 所有用户中已启用且使用测试网的交易所ID（去重，如 binance、hyperliquid）
+// 用于启动时决定是否额外加载测试网行情数据源
+func (d *Database) GetEnabledTestnetExchanges() ([]string, error) {
+	var hasExchangeIDColumn int
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('exchanges')
+		WHERE name = 'exchange_id'
+	`).Scan(&hasExchangeIDColumn)
+	if err != nil {
+		return nil, fmt.Errorf("检查exchanges表结构失败: %w", err)
+	}
+	idColumn := "id"
+	if hasExchangeIDColumn > 0 {
+		idColumn = "exchange_id"
+	}
+
+	rows, err := d.db.Query(`SELECT DISTINCT ` + idColumn + ` FROM exchanges WHERE enabled = 1 AND testnet = 1 ORDER BY ` + idColumn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exchangeIDs := make([]string, 0)
+	for rows.Next() {
+		var exchangeID string
+		if err := rows.Scan(&exchangeID); err != nil {
+			return nil, err
+		}
+		exchangeIDs = append(exchangeIDs, exchangeID)
+	}
+	return exchangeIDs, rows.Err()
+}
+
 // UpdateExchange 更新交易所配置，如果不存在则创建用户特定配置
+// 只作用于默认账户（display_name 为空），其他账户通过 UpdateExchangeAccount 按 ID 更新
 // 🔒 安全特性：空值不会覆盖现有的敏感字段（api_key, secret_key, aster_private_key）
 func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error {
 	log.Printf("🔧 UpdateExchange: userID=%s, id=%s, enabled=%v", userID, id, enabled)
@@ -1725,10 +2490,10 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 
 	var query string
 	if hasExchangeIDColumn > 0 {
-		// 新結構：使用 exchange_id
+		// 新結構：使用 exchange_id（只更新默認賬戶）
 		query = fmt.Sprintf(`
 			UPDATE exchanges SET %s
-			WHERE exchange_id = ? AND user_id = ?
+			WHERE exchange_id = ? AND user_id = ? AND display_name = ''
 		`, strings.Join(setClauses, ", "))
 	} else {
 		// 舊結構：使用 id
@@ -1769,6 +2534,12 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 		} else if id == "aster" {
 			name = "Aster DEX"
 			typ = "dex"
+		} else if id == "okx" {
+			name = "OKX Futures"
+			typ = "cex"
+		} else if id == "paper" {
+			name = "Paper Trading"
+			typ = "paper"
 		} else {
 			name = id + " Exchange"
 			typ = "cex"
@@ -1810,19 +2581,45 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 	return nil
 }
 
-// CreateAIModel 创建AI模型配置
-func (d *Database) CreateAIModel(userID, id, name, provider string, enabled bool, apiKey, customAPIURL string) error {
-	_, err := d.db.Exec(`
-		INSERT OR IGNORE INTO ai_models (model_id, user_id, name, provider, enabled, api_key, custom_api_url)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, id, userID, name, provider, enabled, apiKey, customAPIURL)
-	return err
-}
+// UpdateExchangePassphrase 更新 OKX API Passphrase（加密存储，空值不覆盖现有数据）
+func (d *Database) UpdateExchangePassphrase(userID, id, passphrase string) error {
+	if passphrase == "" {
+		return nil
+	}
 
-// CreateExchange 创建交易所配置
-func (d *Database) CreateExchange(userID, id, name, typ string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error {
-	// 加密敏感字段
-	encryptedAPIKey := d.encryptSensitiveData(apiKey)
+	var hasExchangeIDColumn int
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('exchanges')
+		WHERE name = 'exchange_id'
+	`).Scan(&hasExchangeIDColumn)
+	if err != nil {
+		return fmt.Errorf("检查exchanges表结构失败: %w", err)
+	}
+
+	where := "id = ?"
+	if hasExchangeIDColumn > 0 {
+		where = "exchange_id = ? AND display_name = ''"
+	}
+	_, err = d.db.Exec(fmt.Sprintf(`
+		UPDATE exchanges SET okx_passphrase = ?, updated_at = datetime('now')
+		WHERE %s AND user_id = ?
+	`, where), d.encryptSensitiveData(passphrase), id, userID)
+	return err
+}
+
+// CreateAIModel 创建AI模型配置
+func (d *Database) CreateAIModel(userID, id, name, provider string, enabled bool, apiKey, customAPIURL string) error {
+	_, err := d.db.Exec(`
+		INSERT OR IGNORE INTO ai_models (model_id, user_id, name, provider, enabled, api_key, custom_api_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, id, userID, name, provider, enabled, apiKey, customAPIURL)
+	return err
+}
+
+// CreateExchange 创建交易所配置
+func (d *Database) CreateExchange(userID, id, name, typ string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error {
+	// 加密敏感字段
+	encryptedAPIKey := d.encryptSensitiveData(apiKey)
 	encryptedSecretKey := d.encryptSensitiveData(secretKey)
 	encryptedAsterPrivateKey := d.encryptSensitiveData(asterPrivateKey)
 
@@ -1836,9 +2633,12 @@ func (d *Database) CreateExchange(userID, id, name, typ string, enabled bool, ap
 // CreateTrader 创建交易员
 func (d *Database) CreateTrader(trader *TraderRecord) error {
 	_, err := d.db.Exec(`
-		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, taker_fee_rate, maker_fee_rate, order_strategy, limit_price_offset, limit_timeout_seconds, timeframes)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, trader.TakerFeeRate, trader.MakerFeeRate, trader.OrderStrategy, trader.LimitPriceOffset, trader.LimitTimeoutSeconds, trader.Timeframes)
+		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, taker_fee_rate, maker_fee_rate, order_strategy, limit_price_offset, limit_timeout_seconds, timeframes, portfolio_group, max_trades_per_day, model_pool, model_pool_mode, fallback_ai_model_id, log_level, hold_cache_pct, start_priority, max_exposure_multiple, respect_signal_bias, dry_run, alert_drawdown_pct, alert_daily_loss_pct, ai_quality_window, ai_quality_max_failure_pct, ai_quality_pause_minutes, daily_report, unfunded_threshold, tags, max_ai_calls_per_day, reject_non_candidates, open_verify_delay_ms, active_hours, weekend_trading, flatten_on_window_close, max_positions, max_position_size_usd, blacklisted_symbols, kline_limit, indicators, max_consecutive_errors, max_daily_loss, max_drawdown, stop_trading_minutes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, trader.TakerFeeRate, trader.MakerFeeRate, trader.OrderStrategy, trader.LimitPriceOffset, trader.LimitTimeoutSeconds, trader.Timeframes, trader.PortfolioGroup, trader.MaxTradesPerDay, trader.ModelPool, trader.ModelPoolMode, trader.FallbackAIModelID, trader.LogLevel, trader.HoldCachePct, trader.StartPriority, trader.MaxExposureMultiple, trader.RespectSignalBias, trader.DryRun, trader.AlertDrawdownPct, trader.AlertDailyLossPct, trader.AIQualityWindow, trader.AIQualityMaxFailurePct, trader.AIQualityPauseMinutes, trader.DailyReport, trader.UnfundedThreshold, trader.Tags, trader.MaxAICallsPerDay, trader.RejectNonCandidates, trader.OpenVerifyDelayMs, trader.ActiveHours, trader.WeekendTrading, trader.FlattenOnWindowClose, trader.MaxPositions, trader.MaxPositionSizeUSD, trader.BlacklistedSymbols, trader.KlineLimit, trader.Indicators, trader.MaxConsecutiveErrors, trader.MaxDailyLoss, trader.MaxDrawdown, trader.StopTradingMinutes)
+	if isTraderNameConflict(err) {
+		return fmt.Errorf("%w: %s", ErrTraderNameTaken, trader.Name)
+	}
 	return err
 }
 
@@ -1857,8 +2657,40 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 		       COALESCE(limit_price_offset, -0.03) as limit_price_offset,
 		       COALESCE(limit_timeout_seconds, 60) as limit_timeout_seconds,
 		       COALESCE(timeframes, '4h') as timeframes,
+		       COALESCE(portfolio_group, '') as portfolio_group,
+		       COALESCE(max_trades_per_day, 0) as max_trades_per_day,
+		       COALESCE(model_pool, '') as model_pool,
+		       COALESCE(model_pool_mode, 'round_robin') as model_pool_mode,
+		       COALESCE(fallback_ai_model_id, '') as fallback_ai_model_id,
+		       COALESCE(log_level, '') as log_level,
+		       COALESCE(hold_cache_pct, 0) as hold_cache_pct,
+		       COALESCE(start_priority, 0) as start_priority,
+		       COALESCE(max_exposure_multiple, 0) as max_exposure_multiple,
+		       COALESCE(respect_signal_bias, 0) as respect_signal_bias,
+		       COALESCE(dry_run, 0) as dry_run,
+		       COALESCE(alert_drawdown_pct, 0) as alert_drawdown_pct,
+		       COALESCE(alert_daily_loss_pct, 0) as alert_daily_loss_pct,
+		       COALESCE(ai_quality_window, 0) as ai_quality_window,
+		       COALESCE(ai_quality_max_failure_pct, 0) as ai_quality_max_failure_pct,
+		       COALESCE(ai_quality_pause_minutes, 0) as ai_quality_pause_minutes,
+		       COALESCE(daily_report, 0) as daily_report,
+		       COALESCE(unfunded_threshold, 0) as unfunded_threshold,
+		       COALESCE(tags, '') as tags,
+		       COALESCE(max_ai_calls_per_day, 0) as max_ai_calls_per_day,
+		       COALESCE(reject_non_candidates, 0) as reject_non_candidates,
+		       COALESCE(open_verify_delay_ms, 0) as open_verify_delay_ms,
+		       COALESCE(active_hours, '') as active_hours,
+		       COALESCE(weekend_trading, 1) as weekend_trading,
+		       COALESCE(flatten_on_window_close, 0) as flatten_on_window_close,
+		       COALESCE(max_positions, 0) as max_positions,
+		       COALESCE(max_position_size_usd, 0) as max_position_size_usd,
+		       COALESCE(blacklisted_symbols, '') as blacklisted_symbols,
+		       COALESCE(kline_limit, 0) as kline_limit,
+		       COALESCE(indicators, '') as indicators,
+		       COALESCE(max_consecutive_errors, 0) as max_consecutive_errors,
+		       max_daily_loss, max_drawdown, stop_trading_minutes,
 		       created_at, updated_at
-		FROM traders WHERE user_id = ? ORDER BY created_at DESC
+		FROM traders WHERE user_id = ? AND deleted_at IS NULL ORDER BY created_at DESC
 	`, userID)
 	if err != nil {
 		return nil, err
@@ -1877,7 +2709,8 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 			&trader.IsCrossMargin,
 			&trader.TakerFeeRate, &trader.MakerFeeRate,
 			&trader.OrderStrategy, &trader.LimitPriceOffset, &trader.LimitTimeoutSeconds,
-			&trader.Timeframes,
+			&trader.Timeframes, &trader.PortfolioGroup, &trader.MaxTradesPerDay, &trader.ModelPool, &trader.ModelPoolMode, &trader.FallbackAIModelID, &trader.LogLevel, &trader.HoldCachePct, &trader.StartPriority, &trader.MaxExposureMultiple, &trader.RespectSignalBias, &trader.DryRun, &trader.AlertDrawdownPct, &trader.AlertDailyLossPct, &trader.AIQualityWindow, &trader.AIQualityMaxFailurePct, &trader.AIQualityPauseMinutes, &trader.DailyReport, &trader.UnfundedThreshold, &trader.Tags, &trader.MaxAICallsPerDay, &trader.RejectNonCandidates, &trader.OpenVerifyDelayMs, &trader.ActiveHours, &trader.WeekendTrading, &trader.FlattenOnWindowClose, &trader.MaxPositions, &trader.MaxPositionSizeUSD, &trader.BlacklistedSymbols, &trader.KlineLimit, &trader.Indicators, &trader.MaxConsecutiveErrors,
+			&trader.MaxDailyLoss, &trader.MaxDrawdown, &trader.StopTradingMinutes,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -1904,14 +2737,17 @@ func (d *Database) UpdateTrader(trader *TraderRecord) error {
 			trading_symbols = ?, use_coin_pool = ?, use_oi_top = ?, custom_prompt = ?, override_base_prompt = ?,
 			system_prompt_template = ?, is_cross_margin = ?, taker_fee_rate = ?, maker_fee_rate = ?,
 			order_strategy = ?, limit_price_offset = ?, limit_timeout_seconds = ?, timeframes = ?,
-			updated_at = CURRENT_TIMESTAMP
+			portfolio_group = ?, max_trades_per_day = ?, model_pool = ?, model_pool_mode = ?, fallback_ai_model_id = ?, log_level = ?, hold_cache_pct = ?, start_priority = ?, max_exposure_multiple = ?, respect_signal_bias = ?, dry_run = ?, alert_drawdown_pct = ?, alert_daily_loss_pct = ?, ai_quality_window = ?, ai_quality_max_failure_pct = ?, ai_quality_pause_minutes = ?, daily_report = ?, unfunded_threshold = ?, tags = ?, max_ai_calls_per_day = ?, reject_non_candidates = ?, open_verify_delay_ms = ?, active_hours = ?, weekend_trading = ?, flatten_on_window_close = ?, max_positions = ?, max_position_size_usd = ?, blacklisted_symbols = ?, kline_limit = ?, indicators = ?, max_consecutive_errors = ?, max_daily_loss = ?, max_drawdown = ?, stop_trading_minutes = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ? AND user_id = ?
 	`, trader.Name, trader.AIModelID, trader.ExchangeID,
 		trader.ScanIntervalMinutes, trader.BTCETHLeverage, trader.AltcoinLeverage,
 		trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt,
 		trader.SystemPromptTemplate, trader.IsCrossMargin, trader.TakerFeeRate, trader.MakerFeeRate,
 		trader.OrderStrategy, trader.LimitPriceOffset, trader.LimitTimeoutSeconds, trader.Timeframes,
-		trader.ID, trader.UserID)
+		trader.PortfolioGroup, trader.MaxTradesPerDay, trader.ModelPool, trader.ModelPoolMode, trader.FallbackAIModelID, trader.LogLevel, trader.HoldCachePct, trader.StartPriority, trader.MaxExposureMultiple, trader.RespectSignalBias, trader.DryRun, trader.AlertDrawdownPct, trader.AlertDailyLossPct, trader.AIQualityWindow, trader.AIQualityMaxFailurePct, trader.AIQualityPauseMinutes, trader.DailyReport, trader.UnfundedThreshold, trader.Tags, trader.MaxAICallsPerDay, trader.RejectNonCandidates, trader.OpenVerifyDelayMs, trader.ActiveHours, trader.WeekendTrading, trader.FlattenOnWindowClose, trader.MaxPositions, trader.MaxPositionSizeUSD, trader.BlacklistedSymbols, trader.KlineLimit, trader.Indicators, trader.MaxConsecutiveErrors, trader.MaxDailyLoss, trader.MaxDrawdown, trader.StopTradingMinutes, trader.ID, trader.UserID)
+	if isTraderNameConflict(err) {
+		return fmt.Errorf("%w: %s", ErrTraderNameTaken, trader.Name)
+	}
 	return err
 }
 
@@ -1928,12 +2764,135 @@ func (d *Database) UpdateTraderInitialBalance(userID, id string, newBalance floa
 	return err
 }
 
-// DeleteTrader 删除交易员
+// DeleteTrader 软删除交易员（保留配置和历史数据，可在归档列表中查看或恢复）
 func (d *Database) DeleteTrader(userID, id string) error {
-	_, err := d.db.Exec(`DELETE FROM traders WHERE id = ? AND user_id = ?`, id, userID)
+	_, err := d.db.Exec(`
+		UPDATE traders SET deleted_at = CURRENT_TIMESTAMP, is_running = 0
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, id, userID)
 	return err
 }
 
+// ArchivedTrader 已软删除的交易员
+type ArchivedTrader struct {
+	ID             string  `json:"trader_id"`
+	Name           string  `json:"trader_name"`
+	AIModelID      int     `json:"ai_model_id"`
+	ExchangeID     int     `json:"exchange_id"`
+	InitialBalance float64 `json:"initial_balance"`
+	Tags           string  `json:"tags"`
+	CreatedAt      string  `json:"created_at"`
+	DeletedAt      string  `json:"deleted_at"`
+}
+
+// GetArchivedTraders 获取用户已软删除的交易员（按删除时间倒序）
+func (d *Database) GetArchivedTraders(userID string) ([]*ArchivedTrader, error) {
+	rows, err := d.db.Query(`
+		SELECT id, name, ai_model_id, exchange_id, initial_balance, COALESCE(tags, ''), created_at, deleted_at
+		FROM traders WHERE user_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	traders := make([]*ArchivedTrader, 0)
+	for rows.Next() {
+		var t ArchivedTrader
+		if err := rows.Scan(&t.ID, &t.Name, &t.AIModelID, &t.ExchangeID, &t.InitialBalance, &t.Tags, &t.CreatedAt, &t.DeletedAt); err != nil {
+			return nil, err
+		}
+		traders = append(traders, &t)
+	}
+	return traders, rows.Err()
+}
+
+// RestoreTrader 恢复已软删除的交易员（恢复后为停止状态）
+// 已有同名的未删除交易员时返回 ErrTraderNameTaken
+func (d *Database) RestoreTrader(userID, id string) error {
+	result, err := d.db.Exec(`
+		UPDATE traders SET deleted_at = NULL, is_running = 0
+		WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL
+	`, id, userID)
+	if isTraderNameConflict(err) {
+		return fmt.Errorf("%w: 请先重命名同名交易员", ErrTraderNameTaken)
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("归档交易员不存在: %s", id)
+	}
+	return nil
+}
+
+// PurgeTrader 永久删除交易员及其交易历史、状态、被拒绝决策和每日报告（不可恢复）
+// 已软删除和未删除的交易员都可以清除
+func (d *Database) PurgeTrader(userID, id string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM traders WHERE id = ? AND user_id = ?`, id, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return fmt.Errorf("交易员不存在: %s", id)
+	}
+
+	for _, table := range []string{"trade_history", "trader_state", "rejected_decisions", "daily_reports", "equity_snapshots", "position_annotations"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE trader_id = ?`, id); err != nil {
+			return fmt.Errorf("删除 %s 失败: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM traders WHERE id = ? AND user_id = ?`, id, userID); err != nil {
+		return fmt.Errorf("删除交易员失败: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeleteTradingHistory 在同一事务中删除用户的交易历史和被拒绝决策记录（traderID 为空表示该用户全部交易员）
+// 只删除历史数据，交易员配置和状态不受影响
+func (d *Database) DeleteTradingHistory(userID, traderID string) (tradeRows, rejectedRows int64, err error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	tradeQuery := `DELETE FROM trade_history WHERE user_id = ?`
+	rejectedQuery := `DELETE FROM rejected_decisions WHERE (user_id = ? OR trader_id IN (SELECT id FROM traders WHERE user_id = ?))`
+	tradeArgs := []interface{}{userID}
+	rejectedArgs := []interface{}{userID, userID}
+	if traderID != "" {
+		tradeQuery += ` AND trader_id = ?`
+		rejectedQuery += ` AND trader_id = ?`
+		tradeArgs = append(tradeArgs, traderID)
+		rejectedArgs = append(rejectedArgs, traderID)
+	}
+
+	result, err := tx.Exec(tradeQuery, tradeArgs...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("删除交易历史失败: %w", err)
+	}
+	tradeRows, _ = result.RowsAffected()
+
+	result, err = tx.Exec(rejectedQuery, rejectedArgs...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("删除被拒绝决策记录失败: %w", err)
+	}
+	rejectedRows, _ = result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return tradeRows, rejectedRows, nil
+}
+
 // GetTraderConfig 获取交易员完整配置（包含AI模型和交易所信息）
 func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIModelConfig, *ExchangeConfig, error) {
 	var trader TraderRecord
@@ -1958,21 +2917,56 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 			COALESCE(t.limit_price_offset, -0.03) as limit_price_offset,
 			COALESCE(t.limit_timeout_seconds, 60) as limit_timeout_seconds,
 			COALESCE(t.timeframes, '4h') as timeframes,
+			COALESCE(t.portfolio_group, '') as portfolio_group,
+			COALESCE(t.max_trades_per_day, 0) as max_trades_per_day,
+			COALESCE(t.model_pool, '') as model_pool,
+			COALESCE(t.model_pool_mode, 'round_robin') as model_pool_mode,
+			COALESCE(t.fallback_ai_model_id, '') as fallback_ai_model_id,
+			COALESCE(t.log_level, '') as log_level,
+			COALESCE(t.hold_cache_pct, 0) as hold_cache_pct,
+			COALESCE(t.start_priority, 0) as start_priority,
+			COALESCE(t.max_exposure_multiple, 0) as max_exposure_multiple,
+			COALESCE(t.respect_signal_bias, 0) as respect_signal_bias,
+			COALESCE(t.dry_run, 0) as dry_run,
+			COALESCE(t.alert_drawdown_pct, 0) as alert_drawdown_pct,
+			COALESCE(t.alert_daily_loss_pct, 0) as alert_daily_loss_pct,
+			COALESCE(t.ai_quality_window, 0) as ai_quality_window,
+			COALESCE(t.ai_quality_max_failure_pct, 0) as ai_quality_max_failure_pct,
+			COALESCE(t.ai_quality_pause_minutes, 0) as ai_quality_pause_minutes,
+			COALESCE(t.daily_report, 0) as daily_report,
+			COALESCE(t.unfunded_threshold, 0) as unfunded_threshold,
+			COALESCE(t.tags, '') as tags,
+			COALESCE(t.max_ai_calls_per_day, 0) as max_ai_calls_per_day,
+			COALESCE(t.reject_non_candidates, 0) as reject_non_candidates,
+			COALESCE(t.open_verify_delay_ms, 0) as open_verify_delay_ms,
+			COALESCE(t.active_hours, '') as active_hours,
+			COALESCE(t.weekend_trading, 1) as weekend_trading,
+			COALESCE(t.flatten_on_window_close, 0) as flatten_on_window_close,
+			COALESCE(t.max_positions, 0) as max_positions,
+			COALESCE(t.max_position_size_usd, 0) as max_position_size_usd,
+			COALESCE(t.blacklisted_symbols, '') as blacklisted_symbols,
+			COALESCE(t.kline_limit, 0) as kline_limit,
+			COALESCE(t.indicators, '') as indicators,
+			COALESCE(t.max_consecutive_errors, 0) as max_consecutive_errors,
+			t.max_daily_loss, t.max_drawdown, t.stop_trading_minutes,
 			t.created_at, t.updated_at,
-			a.id, a.model_id, a.user_id, a.name, a.provider, a.enabled, a.api_key,
+			a.id, a.model_id, a.user_id, COALESCE(a.display_name, '') as model_display_name, a.name, a.provider, a.enabled, a.api_key,
 			COALESCE(a.custom_api_url, '') as custom_api_url,
 			COALESCE(a.custom_model_name, '') as custom_model_name,
+			COALESCE(a.system_prompt_prefix, '') as system_prompt_prefix,
+			COALESCE(a.system_prompt_suffix, '') as system_prompt_suffix,
 			a.created_at, a.updated_at,
-			e.id, e.exchange_id, e.user_id, e.name, e.type, e.enabled, e.api_key, e.secret_key, e.testnet,
+			e.id, e.exchange_id, e.user_id, COALESCE(e.display_name, '') as exchange_display_name, e.name, e.type, e.enabled, e.api_key, e.secret_key, e.testnet,
 			COALESCE(e.hyperliquid_wallet_addr, '') as hyperliquid_wallet_addr,
 			COALESCE(e.aster_user, '') as aster_user,
 			COALESCE(e.aster_signer, '') as aster_signer,
 			COALESCE(e.aster_private_key, '') as aster_private_key,
+			COALESCE(e.okx_passphrase, '') as okx_passphrase,
 			e.created_at, e.updated_at
 		FROM traders t
 		JOIN ai_models a ON t.ai_model_id = a.id
 		JOIN exchanges e ON t.exchange_id = e.id
-		WHERE t.id = ? AND t.user_id = ?
+		WHERE t.id = ? AND t.user_id = ? AND t.deleted_at IS NULL
 	`, traderID, userID).Scan(
 		&trader.ID, &trader.UserID, &trader.Name, &trader.AIModelID, &trader.ExchangeID,
 		&trader.InitialBalance, &trader.ScanIntervalMinutes, &trader.IsRunning,
@@ -1982,14 +2976,16 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 		&trader.IsCrossMargin,
 		&trader.TakerFeeRate, &trader.MakerFeeRate,
 		&trader.OrderStrategy, &trader.LimitPriceOffset, &trader.LimitTimeoutSeconds,
-		&trader.Timeframes,
+		&trader.Timeframes, &trader.PortfolioGroup, &trader.MaxTradesPerDay, &trader.ModelPool, &trader.ModelPoolMode, &trader.FallbackAIModelID, &trader.LogLevel, &trader.HoldCachePct, &trader.StartPriority, &trader.MaxExposureMultiple, &trader.RespectSignalBias, &trader.DryRun, &trader.AlertDrawdownPct, &trader.AlertDailyLossPct, &trader.AIQualityWindow, &trader.AIQualityMaxFailurePct, &trader.AIQualityPauseMinutes, &trader.DailyReport, &trader.UnfundedThreshold, &trader.Tags, &trader.MaxAICallsPerDay, &trader.RejectNonCandidates, &trader.OpenVerifyDelayMs, &trader.ActiveHours, &trader.WeekendTrading, &trader.FlattenOnWindowClose, &trader.MaxPositions, &trader.MaxPositionSizeUSD, &trader.BlacklistedSymbols, &trader.KlineLimit, &trader.Indicators, &trader.MaxConsecutiveErrors,
+		&trader.MaxDailyLoss, &trader.MaxDrawdown, &trader.StopTradingMinutes,
 		&trader.CreatedAt, &trader.UpdatedAt,
-		&aiModel.ID, &aiModel.ModelID, &aiModel.UserID, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey,
+		&aiModel.ID, &aiModel.ModelID, &aiModel.UserID, &aiModel.DisplayName, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey,
 		&aiModel.CustomAPIURL, &aiModel.CustomModelName,
+		&aiModel.SystemPromptPrefix, &aiModel.SystemPromptSuffix,
 		&aiModel.CreatedAt, &aiModel.UpdatedAt,
-		&exchange.ID, &exchange.ExchangeID, &exchange.UserID, &exchange.Name, &exchange.Type, &exchange.Enabled,
+		&exchange.ID, &exchange.ExchangeID, &exchange.UserID, &exchange.DisplayName, &exchange.Name, &exchange.Type, &exchange.Enabled,
 		&exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
-		&exchange.HyperliquidWalletAddr, &exchange.AsterUser, &exchange.AsterSigner, &exchange.AsterPrivateKey,
+		&exchange.HyperliquidWalletAddr, &exchange.AsterUser, &exchange.AsterSigner, &exchange.AsterPrivateKey, &exchange.OKXPassphrase,
 		&exchange.CreatedAt, &exchange.UpdatedAt,
 	)
 
@@ -2002,6 +2998,7 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 	exchange.APIKey = d.decryptSensitiveData(exchange.APIKey)
 	exchange.SecretKey = d.decryptSensitiveData(exchange.SecretKey)
 	exchange.AsterPrivateKey = d.decryptSensitiveData(exchange.AsterPrivateKey)
+	exchange.OKXPassphrase = d.decryptSensitiveData(exchange.OKXPassphrase)
 
 	return &trader, &aiModel, &exchange, nil
 }
@@ -2021,6 +3018,24 @@ func (d *Database) SetSystemConfig(key, value string) error {
 	return err
 }
 
+// MarkSessionStarted 记录本次进程已启动（clean_shutdown=false），并检测上次运行是否正常退出
+// 上次运行的标记仍为 false 说明进程被强制终止（OOM、断电等），未走 MarkCleanShutdown；首次启动视为正常
+func (d *Database) MarkSessionStarted() (uncleanShutdown bool, err error) {
+	previous, _ := d.GetSystemConfig("clean_shutdown")
+	d.uncleanShutdown = previous == "false"
+	return d.uncleanShutdown, d.SetSystemConfig("clean_shutdown", "false")
+}
+
+// MarkCleanShutdown 正常退出时调用（所有交易员已停止、状态已保存）
+func (d *Database) MarkCleanShutdown() error {
+	return d.SetSystemConfig("clean_shutdown", "true")
+}
+
+// UncleanShutdownDetected 本次启动时是否检测到上次运行非正常退出
+func (d *Database) UncleanShutdownDetected() bool {
+	return d.uncleanShutdown
+}
+
 // CreateUserSignalSource 创建用户信号源配置
 func (d *Database) CreateUserSignalSource(userID, coinPoolURL, oiTopURL string) error {
 	_, err := d.db.Exec(`
@@ -2161,6 +3176,9 @@ func (d *Database) GetAllTimeframes() []string {
 
 // Close 关闭数据库连接
 func (d *Database) Close() error {
+	if d.stateBatcher != nil {
+		d.stateBatcher.stop()
+	}
 	return d.db.Close()
 }
 
@@ -2266,11 +3284,131 @@ func (d *Database) GetBetaCodeStats() (total, used int, err error) {
 	return total, used, nil
 }
 
+const (
+	betaCodeCharset   = "23456789abcdefghjkmnpqrstuvwxyz" // 与 generate_beta_code.sh 一致，避免易混淆字符（0/O, 1/I/l）
+	betaCodeLength    = 6
+	MaxBetaCodesBatch = 500 // 单次最多生成的内测码数量
+)
+
+// BetaCode 内测码及使用状态
+type BetaCode struct {
+	Code      string `json:"code"`
+	Used      bool   `json:"used"`
+	UsedBy    string `json:"used_by"`
+	UsedAt    string `json:"used_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+// randomBetaCode 使用 crypto/rand 生成随机内测码（拒绝采样，保证字符分布均匀）
+func randomBetaCode() (string, error) {
+	const limit = 256 - 256%len(betaCodeCharset)
+	code := make([]byte, 0, betaCodeLength)
+	buf := make([]byte, betaCodeLength*2)
+	for len(code) < betaCodeLength {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("生成随机数失败: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			code = append(code, betaCodeCharset[int(b)%len(betaCodeCharset)])
+			if len(code) == betaCodeLength {
+				break
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// GenerateBetaCodes 生成 count 个随机且不重复的内测码写入数据库，返回新生成的内测码
+func (d *Database) GenerateBetaCodes(count int) ([]string, error) {
+	if count <= 0 || count > MaxBetaCodesBatch {
+		return nil, fmt.Errorf("生成数量必须在 1-%d 之间", MaxBetaCodesBatch)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO beta_codes (code) VALUES (?)`)
+	if err != nil {
+		return nil, fmt.Errorf("准备语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	codes := make([]string, 0, count)
+	// 与已有内测码冲突时重新生成（INSERT OR IGNORE 不插入），限制尝试次数防止码空间耗尽时死循环
+	for attempts := 0; len(codes) < count && attempts < count*10; attempts++ {
+		code, err := randomBetaCode()
+		if err != nil {
+			return nil, err
+		}
+		result, err := stmt.Exec(code)
+		if err != nil {
+			return nil, fmt.Errorf("插入内测码失败: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) < count {
+		return nil, fmt.Errorf("生成不重复的内测码失败（已生成 %d/%d）", len(codes), count)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return codes, nil
+}
+
+// ListBetaCodes 列出所有内测码及使用情况（未使用的排在前面，按创建时间倒序）
+func (d *Database) ListBetaCodes() ([]*BetaCode, error) {
+	rows, err := d.db.Query(`
+		SELECT code, COALESCE(used, 0), COALESCE(used_by, ''), COALESCE(used_at, ''), COALESCE(created_at, '')
+		FROM beta_codes
+		ORDER BY used ASC, created_at DESC, code ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := make([]*BetaCode, 0)
+	for rows.Next() {
+		code := &BetaCode{}
+		if err := rows.Scan(&code.Code, &code.Used, &code.UsedBy, &code.UsedAt, &code.CreatedAt); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+// RevokeBetaCode 作废未使用的内测码（已使用的内测码保留用于追溯）
+func (d *Database) RevokeBetaCode(code string) error {
+	result, err := d.db.Exec(`DELETE FROM beta_codes WHERE code = ? AND used = 0`, code)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("内测码不存在或已被使用")
+	}
+	return nil
+}
+
 // SetCryptoService 设置加密服务
 func (d *Database) SetCryptoService(cs *crypto.CryptoService) {
 	d.cryptoService = cs
 }
 
+// GetCryptoService 获取加密服务（未设置时返回 nil）
+func (d *Database) GetCryptoService() *crypto.CryptoService {
+	return d.cryptoService
+}
+
 // encryptSensitiveData 加密敏感数据用于存储
 func (d *Database) encryptSensitiveData(plaintext string) string {
 	if d.cryptoService == nil || plaintext == "" {
@@ -2373,6 +3511,41 @@ func (d *Database) cleanupLegacyColumns() error {
 			limit_price_offset REAL DEFAULT -0.03,
 			limit_timeout_seconds INTEGER DEFAULT 60,
 			timeframes TEXT DEFAULT '4h',
+			portfolio_group TEXT DEFAULT '',
+			max_trades_per_day INTEGER DEFAULT 0,
+			model_pool TEXT DEFAULT '',
+			model_pool_mode TEXT DEFAULT 'round_robin',
+			fallback_ai_model_id TEXT DEFAULT '',
+			log_level TEXT DEFAULT '',
+			hold_cache_pct REAL DEFAULT 0,
+			start_priority INTEGER DEFAULT 0,
+			max_exposure_multiple REAL DEFAULT 0,
+			respect_signal_bias BOOLEAN DEFAULT 0,
+			dry_run BOOLEAN DEFAULT 0,
+			alert_drawdown_pct REAL DEFAULT 0,
+			alert_daily_loss_pct REAL DEFAULT 0,
+			ai_quality_window INTEGER DEFAULT 0,
+			ai_quality_max_failure_pct REAL DEFAULT 0,
+			ai_quality_pause_minutes INTEGER DEFAULT 0,
+			daily_report BOOLEAN DEFAULT 0,
+			unfunded_threshold REAL DEFAULT 0,
+			tags TEXT DEFAULT '',
+			max_ai_calls_per_day INTEGER DEFAULT 0,
+			reject_non_candidates BOOLEAN DEFAULT 0,
+			open_verify_delay_ms INTEGER DEFAULT 0,
+			active_hours TEXT DEFAULT '',
+			weekend_trading BOOLEAN DEFAULT 1,
+			flatten_on_window_close BOOLEAN DEFAULT 0,
+			max_positions INTEGER DEFAULT 0,
+			max_position_size_usd REAL DEFAULT 0,
+			blacklisted_symbols TEXT DEFAULT '',
+			kline_limit INTEGER DEFAULT 0,
+			indicators TEXT DEFAULT '',
+			max_consecutive_errors INTEGER DEFAULT 0,
+			max_daily_loss REAL DEFAULT NULL,
+			max_drawdown REAL DEFAULT NULL,
+			stop_trading_minutes INTEGER DEFAULT NULL,
+			deleted_at DATETIME DEFAULT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
@@ -2395,7 +3568,7 @@ func (d *Database) cleanupLegacyColumns() error {
 			is_cross_margin, use_default_coins, custom_coins,
 			taker_fee_rate, maker_fee_rate, order_strategy,
 			limit_price_offset, limit_timeout_seconds, timeframes,
-			created_at, updated_at
+			portfolio_group, max_trades_per_day, model_pool, model_pool_mode, fallback_ai_model_id, log_level, hold_cache_pct, start_priority, max_exposure_multiple, respect_signal_bias, dry_run, alert_drawdown_pct, alert_daily_loss_pct, ai_quality_window, ai_quality_max_failure_pct, ai_quality_pause_minutes, daily_report, unfunded_threshold, tags, max_ai_calls_per_day, reject_non_candidates, open_verify_delay_ms, active_hours, weekend_trading, flatten_on_window_close, max_positions, max_position_size_usd, blacklisted_symbols, kline_limit, indicators, max_consecutive_errors, max_daily_loss, max_drawdown, stop_trading_minutes, deleted_at, created_at, updated_at
 		)
 		SELECT
 			id, user_id, name, ai_model_id, exchange_id,
@@ -2406,7 +3579,7 @@ func (d *Database) cleanupLegacyColumns() error {
 			COALESCE(is_cross_margin, 1), COALESCE(use_default_coins, 1), COALESCE(custom_coins, ''),
 			COALESCE(taker_fee_rate, 0.0004), COALESCE(maker_fee_rate, 0.0002), COALESCE(order_strategy, 'conservative_hybrid'),
 			COALESCE(limit_price_offset, -0.03), COALESCE(limit_timeout_seconds, 60), COALESCE(timeframes, '4h'),
-			created_at, updated_at
+			COALESCE(portfolio_group, ''), COALESCE(max_trades_per_day, 0), COALESCE(model_pool, ''), COALESCE(model_pool_mode, 'round_robin'), COALESCE(fallback_ai_model_id, ''), COALESCE(log_level, ''), COALESCE(hold_cache_pct, 0), COALESCE(start_priority, 0), COALESCE(max_exposure_multiple, 0), COALESCE(respect_signal_bias, 0), COALESCE(dry_run, 0), COALESCE(alert_drawdown_pct, 0), COALESCE(alert_daily_loss_pct, 0), COALESCE(ai_quality_window, 0), COALESCE(ai_quality_max_failure_pct, 0), COALESCE(ai_quality_pause_minutes, 0), COALESCE(daily_report, 0), COALESCE(unfunded_threshold, 0), COALESCE(tags, ''), COALESCE(max_ai_calls_per_day, 0), COALESCE(reject_non_candidates, 0), COALESCE(open_verify_delay_ms, 0), COALESCE(active_hours, ''), COALESCE(weekend_trading, 1), COALESCE(flatten_on_window_close, 0), COALESCE(max_positions, 0), COALESCE(max_position_size_usd, 0), COALESCE(blacklisted_symbols, ''), COALESCE(kline_limit, 0), COALESCE(indicators, ''), COALESCE(max_consecutive_errors, 0), max_daily_loss, max_drawdown, stop_trading_minutes, deleted_at, created_at, updated_at
 		FROM traders
 	`)
 	if err != nil {
@@ -2435,7 +3608,7 @@ func (d *Database) cleanupLegacyColumns() error {
 }
 
 // checkDataIntegrity 檢查數據庫完整性（外鍵約束）
-// 這個函數在啟動時執行，檢測並報告孤立的記錄
+// 這個函數在啟動時執行，檢測並報告孤立的記錄（已軟刪除的交易員不參與檢查）
 // 不會中斷啟動，只記錄警告信息
 func (d *Database) checkDataIntegrity() error {
 	log.Printf("🔍 [啟動檢查] 開始數據庫完整性檢查...")
@@ -2447,7 +3620,7 @@ func (d *Database) checkDataIntegrity() error {
 	err := d.db.QueryRow(`
 		SELECT COUNT(*)
 		FROM traders t
-		WHERE NOT EXISTS (
+		WHERE t.deleted_at IS NULL AND NOT EXISTS (
 			SELECT 1 FROM exchanges e WHERE e.id = t.exchange_id
 		)
 	`).Scan(&orphanedTradersCount)
@@ -2461,7 +3634,7 @@ func (d *Database) checkDataIntegrity() error {
 		rows, err := d.db.Query(`
 			SELECT t.id, t.name, t.exchange_id
 			FROM traders t
-			WHERE NOT EXISTS (
+			WHERE t.deleted_at IS NULL AND NOT EXISTS (
 				SELECT 1 FROM exchanges e WHERE e.id = t.exchange_id
 			)
 			LIMIT 5
@@ -2486,7 +3659,7 @@ func (d *Database) checkDataIntegrity() error {
 	err = d.db.QueryRow(`
 		SELECT COUNT(*)
 		FROM traders t
-		WHERE NOT EXISTS (
+		WHERE t.deleted_at IS NULL AND NOT EXISTS (
 			SELECT 1 FROM ai_models a WHERE a.id = t.ai_model_id
 		)
 	`).Scan(&orphanedTradersAICount)
@@ -2499,7 +3672,7 @@ func (d *Database) checkDataIntegrity() error {
 		rows, err := d.db.Query(`
 			SELECT t.id, t.name, t.ai_model_id
 			FROM traders t
-			WHERE NOT EXISTS (
+			WHERE t.deleted_at IS NULL AND NOT EXISTS (
 				SELECT 1 FROM ai_models a WHERE a.id = t.ai_model_id
 			)
 			LIMIT 5
@@ -2585,22 +3758,57 @@ func (db *Database) RecordTrade(traderID, userID, symbol, side, action string, q
 }
 
 // SaveTraderState 保存交易員狀態到數據庫
+// 啟用合併寫入時只放入待寫隊列（同一交易員只保留最新狀態），由後台按間隔在單個事務中批量落盤
 func (db *Database) SaveTraderState(traderID, userID string, callCount int, peakEquity float64, lastResetTime int64, stateJSON string) error {
-	query := `INSERT OR REPLACE INTO trader_state 
-		(trader_id, user_id, call_count, peak_equity, last_reset_time, state_json) 
-		VALUES (?, ?, ?, ?, ?, ?)`
+	state := traderStateWrite{
+		traderID:      traderID,
+		userID:        userID,
+		callCount:     callCount,
+		peakEquity:    peakEquity,
+		lastResetTime: lastResetTime,
+		stateJSON:     stateJSON,
+	}
+	if db.stateBatcher != nil {
+		db.stateBatcher.queue(state)
+		return nil
+	}
 
-	_, err := db.db.Exec(query, traderID, userID, callCount, peakEquity, lastResetTime, stateJSON)
-	if err != nil {
+	if err := db.writeTraderStates([]traderStateWrite{state}); err != nil {
 		log.Printf("❌ 保存交易員狀態失敗: %v", err)
 		return err
 	}
-
 	return nil
 }
 
+// writeTraderStates 在單個事務中寫入一批交易員狀態
+func (db *Database) writeTraderStates(states []traderStateWrite) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO trader_state 
+		(trader_id, user_id, call_count, peak_equity, last_reset_time, state_json) 
+		VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, st := range states {
+		if _, err := stmt.Exec(st.traderID, st.userID, st.callCount, st.peakEquity, st.lastResetTime, st.stateJSON); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // LoadTraderState 從數據庫恢復交易員狀態
 func (db *Database) LoadTraderState(traderID string) (callCount int, peakEquity float64, lastResetTime int64, stateJSON string, err error) {
+	if db.stateBatcher != nil {
+		db.stateBatcher.flush()
+	}
 	query := `SELECT call_count, peak_equity, last_reset_time, state_json FROM trader_state WHERE trader_id = ?`
 
 	err = db.db.QueryRow(query, traderID).Scan(&callCount, &peakEquity, &lastResetTime, &stateJSON)
@@ -2617,16 +3825,39 @@ func (db *Database) LoadTraderState(traderID string) (callCount int, peakEquity
 	return callCount, peakEquity, lastResetTime, stateJSON, nil
 }
 
+// GetTraderLastActivity 獲取各交易員最後一次保存運行狀態的時間（trader_id -> updated_at）
+func (db *Database) GetTraderLastActivity() (map[string]string, error) {
+	if db.stateBatcher != nil {
+		db.stateBatcher.flush()
+	}
+	rows, err := db.db.Query(`SELECT trader_id, COALESCE(updated_at, '') FROM trader_state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := make(map[string]string)
+	for rows.Next() {
+		var traderID, updatedAt string
+		if err := rows.Scan(&traderID, &updatedAt); err != nil {
+			return nil, err
+		}
+		activity[traderID] = updatedAt
+	}
+	return activity, rows.Err()
+}
+
 // GetOpenPositionsFromHistory 從交易歷史中獲取當前未平倉的持倉
 // 通過分析 OPEN 和 CLOSE 事件來重建持倉狀態
 func (db *Database) GetOpenPositionsFromHistory(traderID string) (map[string]map[string]interface{}, error) {
 	query := `
 		SELECT symbol, side, 
-			   SUM(CASE WHEN action = 'OPEN' THEN quantity ELSE -quantity END) as net_quantity,
-			   AVG(CASE WHEN action = 'OPEN' THEN price ELSE NULL END) as avg_entry_price,
-			   MAX(CASE WHEN action = 'OPEN' THEN stop_loss ELSE 0 END) as stop_loss,
-			   MAX(CASE WHEN action = 'OPEN' THEN take_profit ELSE 0 END) as take_profit,
-			   MIN(CASE WHEN action = 'OPEN' THEN timestamp ELSE NULL END) as first_seen_time
+			   SUM(CASE WHEN action IN (` + tradeOpenActions + `) THEN quantity ELSE -quantity END) as net_quantity,
+			   AVG(CASE WHEN action IN (` + tradeOpenActions + `) THEN price ELSE NULL END) as avg_entry_price,
+			   MAX(CASE WHEN action IN (` + tradeOpenActions + `) THEN stop_loss ELSE 0 END) as stop_loss,
+			   MAX(CASE WHEN action IN (` + tradeOpenActions + `) THEN take_profit ELSE 0 END) as take_profit,
+			   MIN(CASE WHEN action IN (` + tradeOpenActions + `) THEN timestamp ELSE NULL END) as first_seen_time,
+			   MAX(CASE WHEN action = 'EXTERNAL_OPEN' THEN 1 ELSE 0 END) as external
 		FROM trade_history 
 		WHERE trader_id = ? 
 		GROUP BY symbol, side
@@ -2645,8 +3876,9 @@ func (db *Database) GetOpenPositionsFromHistory(traderID string) (map[string]map
 		var symbol, side string
 		var netQuantity, avgPrice, stopLoss, takeProfit float64
 		var firstSeenTime int64
+		var external int
 
-		err = rows.Scan(&symbol, &side, &netQuantity, &avgPrice, &stopLoss, &takeProfit, &firstSeenTime)
+		err = rows.Scan(&symbol, &side, &netQuantity, &avgPrice, &stopLoss, &takeProfit, &firstSeenTime, &external)
 		if err != nil {
 			continue
 		}
@@ -2660,6 +3892,7 @@ func (db *Database) GetOpenPositionsFromHistory(traderID string) (map[string]map
 			"stop_loss":       stopLoss,
 			"take_profit":     takeProfit,
 			"first_seen_time": firstSeenTime,
+			"external":        external == 1, // 对账接管的外部持仓（非交易员开仓）
 		}
 	}
 
@@ -2679,7 +3912,7 @@ func (db *Database) GetLastOpenTrade(traderID, symbol, side string) (entryPrice,
 		WHERE trader_id = ?
 		  AND symbol = ?
 		  AND side = ?
-		  AND action = 'OPEN'
+		  AND action IN (` + tradeOpenActions + `)
 		  AND id NOT IN (
 			  -- 排除已配對的開倉記錄
 			  SELECT open_id FROM (
@@ -2691,12 +3924,12 @@ func (db *Database) GetLastOpenTrade(traderID, symbol, side string) (entryPrice,
 					  ON c.trader_id = o.trader_id
 					  AND c.symbol = o.symbol
 					  AND c.side = o.side
-					  AND c.action IN ('CLOSE', 'PARTIAL_CLOSE', 'EMERGENCY_CLOSE', 'AUTO_CLOSE')
+					  AND c.action IN (` + tradeCloseActions + `)
 					  AND c.timestamp > o.timestamp
 				  WHERE o.trader_id = ?
 					AND o.symbol = ?
 					AND o.side = ?
-					AND o.action = 'OPEN'
+					AND o.action IN (` + tradeOpenActions + `)
 					AND c.id IS NOT NULL
 			  )
 		  )
@@ -2722,7 +3955,7 @@ func (db *Database) GetOpenPositions(traderID string) ([]string, error) {
 		SELECT DISTINCT symbol || '_' || side as position_key
 		FROM trade_history
 		WHERE trader_id = ?
-		  AND action = 'OPEN'
+		  AND action IN (` + tradeOpenActions + `)
 		  AND id NOT IN (
 			  -- 排除已配對的開倉記錄
 			  SELECT open_id FROM (
@@ -2733,10 +3966,10 @@ func (db *Database) GetOpenPositions(traderID string) ([]string, error) {
 					  ON c.trader_id = o.trader_id
 					  AND c.symbol = o.symbol
 					  AND c.side = o.side
-					  AND c.action IN ('CLOSE', 'PARTIAL_CLOSE', 'EMERGENCY_CLOSE', 'AUTO_CLOSE')
+					  AND c.action IN (` + tradeCloseActions + `)
 					  AND c.timestamp > o.timestamp
 				  WHERE o.trader_id = ?
-					AND o.action = 'OPEN'
+					AND o.action IN (` + tradeOpenActions + `)
 			  )
 		  )
 	`
@@ -2758,3 +3991,574 @@ func (db *Database) GetOpenPositions(traderID string) ([]string, error) {
 
 	return keys, nil
 }
+
+// TradeHistoryRecord trade_history 中的一条开平仓记录
+type TradeHistoryRecord struct {
+	ID         int64   `json:"id"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`   // LONG / SHORT
+	Action     string  `json:"action"` // OPEN / CLOSE / PARTIAL_CLOSE / EMERGENCY_CLOSE / AUTO_CLOSE / MANUAL_CLOSE
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"`
+	Timestamp  int64   `json:"timestamp"` // 毫秒
+	Reason     string  `json:"reason"`
+	StopLoss   float64 `json:"stop_loss"`
+	TakeProfit float64 `json:"take_profit"`
+	PnL        float64 `json:"pnl"`
+	PnLPercent float64 `json:"pnl_percent"`
+}
+
+// TradeHistoryFilter 交易历史查询条件（零值表示不过滤）
+type TradeHistoryFilter struct {
+	Symbol string
+	Action string
+	Since  time.Time // 包含
+	Until  time.Time // 不包含
+}
+
+// GetTradeHistory 分页查询交易员的交易历史（按时间倒序），同时返回满足条件的总条数
+func (db *Database) GetTradeHistory(traderID string, limit, offset int, filter TradeHistoryFilter) ([]*TradeHistoryRecord, int, error) {
+	where := `WHERE trader_id = ?`
+	args := []interface{}{traderID}
+	if filter.Symbol != "" {
+		where += ` AND symbol = ?`
+		args = append(args, filter.Symbol)
+	}
+	if filter.Action != "" {
+		where += ` AND action = ?`
+		args = append(args, filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		where += ` AND timestamp >= ?`
+		args = append(args, filter.Since.UnixMilli())
+	}
+	if !filter.Until.IsZero() {
+		where += ` AND timestamp < ?`
+		args = append(args, filter.Until.UnixMilli())
+	}
+
+	var total int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM trade_history `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.db.Query(`
+		SELECT id, symbol, side, action, quantity, price, timestamp, COALESCE(reason, ''),
+		       COALESCE(stop_loss, 0), COALESCE(take_profit, 0), COALESCE(pnl, 0), COALESCE(pnl_percent, 0)
+		FROM trade_history `+where+`
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	records := make([]*TradeHistoryRecord, 0)
+	for rows.Next() {
+		r := &TradeHistoryRecord{}
+		if err := rows.Scan(&r.ID, &r.Symbol, &r.Side, &r.Action, &r.Quantity, &r.Price, &r.Timestamp, &r.Reason,
+			&r.StopLoss, &r.TakeProfit, &r.PnL, &r.PnLPercent); err != nil {
+			return nil, 0, err
+		}
+		records = append(records, r)
+	}
+	return records, total, rows.Err()
+}
+
+// CompetitionSnapshot 竞赛排行榜快照
+type CompetitionSnapshot struct {
+	ID        string `json:"id"`
+	Data      string `json:"-"`          // 排行榜数据（JSON）
+	Window    string `json:"window"`     // 统计窗口
+	DataAsOf  string `json:"data_as_of"` // 排行榜数据的生成时间
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+}
+
+// CreateCompetitionSnapshot 保存竞赛快照
+func (d *Database) CreateCompetitionSnapshot(snapshot *CompetitionSnapshot) error {
+	_, err := d.db.Exec(`
+		INSERT INTO competition_snapshots (id, data, time_window, data_as_of, created_by)
+		VALUES (?, ?, ?, ?, ?)
+	`, snapshot.ID, snapshot.Data, snapshot.Window, snapshot.DataAsOf, snapshot.CreatedBy)
+	return err
+}
+
+// GetCompetitionSnapshot 获取竞赛快照
+func (d *Database) GetCompetitionSnapshot(id string) (*CompetitionSnapshot, error) {
+	var snapshot CompetitionSnapshot
+	err := d.db.QueryRow(`
+		SELECT id, data, COALESCE(time_window, ''), COALESCE(data_as_of, ''), COALESCE(created_by, ''), created_at
+		FROM competition_snapshots WHERE id = ?
+	`, id).Scan(&snapshot.ID, &snapshot.Data, &snapshot.Window, &snapshot.DataAsOf, &snapshot.CreatedBy, &snapshot.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// PruneCompetitionSnapshots 删除早于 keepDays 天的竞赛快照，返回删除数量
+func (d *Database) PruneCompetitionSnapshots(keepDays int) (int64, error) {
+	if keepDays <= 0 {
+		return 0, nil
+	}
+	result, err := d.db.Exec(`
+		DELETE FROM competition_snapshots WHERE created_at < datetime('now', ?)
+	`, fmt.Sprintf("-%d days", keepDays))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// UserWebhook 用户的交易事件 Webhook 配置
+type UserWebhook struct {
+	UserID    string   `json:"user_id"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"-"`
+	Events    []string `json:"events"`
+	Enabled   bool     `json:"enabled"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// GetUserWebhook 获取用户的 Webhook 配置（未配置时返回 nil, nil）
+func (d *Database) GetUserWebhook(userID string) (*UserWebhook, error) {
+	var hook UserWebhook
+	var events string
+	err := d.db.QueryRow(`
+		SELECT user_id, url, secret, COALESCE(events, ''), enabled, created_at, updated_at
+		FROM user_webhooks WHERE user_id = ?
+	`, userID).Scan(&hook.UserID, &hook.URL, &hook.Secret, &events, &hook.Enabled, &hook.CreatedAt, &hook.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hook.Secret = d.decryptSensitiveData(hook.Secret)
+	hook.Events = []string{}
+	for _, e := range strings.Split(events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			hook.Events = append(hook.Events, e)
+		}
+	}
+	return &hook, nil
+}
+
+// SaveUserWebhook 保存用户的 Webhook 配置（存在则覆盖）
+func (d *Database) SaveUserWebhook(hook *UserWebhook) error {
+	_, err := d.db.Exec(`
+		INSERT INTO user_webhooks (user_id, url, secret, events, enabled)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			url = excluded.url,
+			secret = excluded.secret,
+			events = excluded.events,
+			enabled = excluded.enabled,
+			updated_at = CURRENT_TIMESTAMP
+	`, hook.UserID, hook.URL, d.encryptSensitiveData(hook.Secret), strings.Join(hook.Events, ","), hook.Enabled)
+	return err
+}
+
+// DeleteUserWebhook 删除用户的 Webhook 配置
+func (d *Database) DeleteUserWebhook(userID string) error {
+	_, err := d.db.Exec(`DELETE FROM user_webhooks WHERE user_id = ?`, userID)
+	return err
+}
+
+// UserNotification 用户的 Telegram 通知配置
+type UserNotification struct {
+	UserID           string   `json:"user_id"`
+	TelegramBotToken string   `json:"-"`
+	TelegramChatID   string   `json:"telegram_chat_id"`
+	Events           []string `json:"events"`
+	Enabled          bool     `json:"enabled"`
+	CreatedAt        string   `json:"created_at"`
+	UpdatedAt        string   `json:"updated_at"`
+}
+
+// GetUserNotification 获取用户的通知配置（未配置时返回 nil, nil）
+func (d *Database) GetUserNotification(userID string) (*UserNotification, error) {
+	var n UserNotification
+	var events string
+	err := d.db.QueryRow(`
+		SELECT user_id, COALESCE(telegram_bot_token, ''), COALESCE(telegram_chat_id, ''), COALESCE(events, ''),
+		       enabled, created_at, updated_at
+		FROM user_notifications WHERE user_id = ?
+	`, userID).Scan(&n.UserID, &n.TelegramBotToken, &n.TelegramChatID, &events, &n.Enabled, &n.CreatedAt, &n.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n.TelegramBotToken = d.decryptSensitiveData(n.TelegramBotToken)
+	n.TelegramChatID = d.decryptSensitiveData(n.TelegramChatID)
+	n.Events = []string{}
+	for _, e := range strings.Split(events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			n.Events = append(n.Events, e)
+		}
+	}
+	return &n, nil
+}
+
+// SaveUserNotification 保存用户的通知配置（存在则覆盖）
+func (d *Database) SaveUserNotification(n *UserNotification) error {
+	_, err := d.db.Exec(`
+		INSERT INTO user_notifications (user_id, telegram_bot_token, telegram_chat_id, events, enabled)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			telegram_bot_token = excluded.telegram_bot_token,
+			telegram_chat_id = excluded.telegram_chat_id,
+			events = excluded.events,
+			enabled = excluded.enabled,
+			updated_at = CURRENT_TIMESTAMP
+	`, n.UserID, d.encryptSensitiveData(n.TelegramBotToken), d.encryptSensitiveData(n.TelegramChatID),
+		strings.Join(n.Events, ","), n.Enabled)
+	return err
+}
+
+// DeleteUserNotification 删除用户的通知配置
+func (d *Database) DeleteUserNotification(userID string) error {
+	_, err := d.db.Exec(`DELETE FROM user_notifications WHERE user_id = ?`, userID)
+	return err
+}
+
+// RejectedDecision 被守卫检查拒绝的决策记录
+type RejectedDecision struct {
+	ID         int64  `json:"id"`
+	TraderID   string `json:"trader_id"`
+	Symbol     string `json:"symbol"`
+	Action     string `json:"action"`
+	ReasonCode string `json:"reason_code"`
+	Reason     string `json:"reason"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// RecordRejectedDecision 记录一条被拒绝的决策
+func (d *Database) RecordRejectedDecision(traderID, userID, symbol, action, reasonCode, reason string) error {
+	_, err := d.db.Exec(`
+		INSERT INTO rejected_decisions (trader_id, user_id, symbol, action, reason_code, reason)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, traderID, userID, symbol, action, reasonCode, reason)
+	return err
+}
+
+// GetRejectedDecisions 获取交易员最近被拒绝的决策（按时间倒序），reasonCode 为空表示全部
+func (d *Database) GetRejectedDecisions(traderID, reasonCode string, limit int) ([]*RejectedDecision, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := `
+		SELECT id, trader_id, COALESCE(symbol, ''), COALESCE(action, ''), reason_code, COALESCE(reason, ''), created_at
+		FROM rejected_decisions WHERE trader_id = ?`
+	args := []interface{}{traderID}
+	if reasonCode != "" {
+		query += ` AND reason_code = ?`
+		args = append(args, reasonCode)
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []*RejectedDecision{}
+	for rows.Next() {
+		var r RejectedDecision
+		if err := rows.Scan(&r.ID, &r.TraderID, &r.Symbol, &r.Action, &r.ReasonCode, &r.Reason, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, &r)
+	}
+	return records, rows.Err()
+}
+
+// CountRejectedDecisions 按原因代码统计交易员被拒绝的决策数量
+func (d *Database) CountRejectedDecisions(traderID string) (map[string]int, error) {
+	rows, err := d.db.Query(`
+		SELECT reason_code, COUNT(*) FROM rejected_decisions WHERE trader_id = ? GROUP BY reason_code
+	`, traderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var code string
+		var count int
+		if err := rows.Scan(&code, &count); err != nil {
+			return nil, err
+		}
+		counts[code] = count
+	}
+	return counts, rows.Err()
+}
+
+// PruneRejectedDecisions 删除早于 keepDays 天的被拒绝决策记录，返回删除数量
+func (d *Database) PruneRejectedDecisions(keepDays int) (int64, error) {
+	if keepDays <= 0 {
+		return 0, nil
+	}
+	result, err := d.db.Exec(`
+		DELETE FROM rejected_decisions WHERE created_at < datetime('now', ?)
+	`, fmt.Sprintf("-%d days", keepDays))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SymbolFeeSummary 单个币种的手续费汇总
+type SymbolFeeSummary struct {
+	Symbol      string  `json:"symbol"`
+	Trades      int     `json:"trades"`       // 成交笔数（开仓和平仓各计一笔）
+	Notional    float64 `json:"notional"`     // 成交名义价值（数量×价格）
+	Fees        float64 `json:"fees"`         // 估算手续费
+	RealizedPnL float64 `json:"realized_pnl"` // 已实现盈亏（平仓记录的盈亏之和）
+}
+
+// FeeSummary 交易员在时间范围内的手续费汇总
+type FeeSummary struct {
+	Trades      int                 `json:"trades"`
+	Notional    float64             `json:"notional"`
+	TotalFees   float64             `json:"total_fees"`
+	RealizedPnL float64             `json:"realized_pnl"`
+	NetPnL      float64             `json:"net_pnl"`          // 已实现盈亏扣除手续费
+	FeeToPnL    *float64            `json:"fee_to_pnl_ratio"` // 手续费/|已实现盈亏|，无盈亏时为 null
+	BySymbol    []*SymbolFeeSummary `json:"by_symbol"`        // 按手续费从高到低排序
+}
+
+// SummarizeFees 按 trade_history 估算交易员在 [from, to) 内的手续费（每笔开平仓按 名义价值×费率 计算）
+// trade_history 不记录实际手续费，因此结果为估算值；没有交易时返回全零汇总
+func (d *Database) SummarizeFees(traderID string, from, to time.Time, feeRate float64) (*FeeSummary, error) {
+	rows, err := d.db.Query(`
+		SELECT symbol, COUNT(*), COALESCE(SUM(quantity * price), 0),
+		       COALESCE(SUM(CASE WHEN action NOT IN (`+tradeOpenActions+`) THEN pnl ELSE 0 END), 0)
+		FROM trade_history
+		WHERE trader_id = ? AND timestamp >= ? AND timestamp < ?
+		GROUP BY symbol
+	`, traderID, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &FeeSummary{BySymbol: make([]*SymbolFeeSummary, 0)}
+	for rows.Next() {
+		item := &SymbolFeeSummary{}
+		if err := rows.Scan(&item.Symbol, &item.Trades, &item.Notional, &item.RealizedPnL); err != nil {
+			return nil, err
+		}
+		item.Fees = item.Notional * feeRate
+		summary.Trades += item.Trades
+		summary.Notional += item.Notional
+		summary.TotalFees += item.Fees
+		summary.RealizedPnL += item.RealizedPnL
+		summary.BySymbol = append(summary.BySymbol, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(summary.BySymbol, func(i, j int) bool {
+		return summary.BySymbol[i].Fees > summary.BySymbol[j].Fees
+	})
+	summary.NetPnL = summary.RealizedPnL - summary.TotalFees
+	if summary.RealizedPnL != 0 {
+		ratio := summary.TotalFees / math.Abs(summary.RealizedPnL)
+		summary.FeeToPnL = &ratio
+	}
+	return summary, nil
+}
+
+// DailyReport 交易员每日绩效报告
+type DailyReport struct {
+	TraderID       string  `json:"trader_id"`
+	UserID         string  `json:"-"`
+	Date           string  `json:"date"` // YYYY-MM-DD
+	OpenedTrades   int     `json:"opened_trades"`
+	ClosedTrades   int     `json:"closed_trades"`
+	WinningTrades  int     `json:"winning_trades"`
+	LosingTrades   int     `json:"losing_trades"`
+	WinRate        float64 `json:"win_rate"` // 胜率（%）
+	RealizedPnL    float64 `json:"realized_pnl"`
+	StartingEquity float64 `json:"starting_equity"`
+	EndingEquity   float64 `json:"ending_equity"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+// SummarizeTrades 统计交易员在 [start, end) 内的开平仓次数、已实现盈亏和胜率（基于 trade_history）
+func (d *Database) SummarizeTrades(traderID string, start, end time.Time) (*DailyReport, error) {
+	report := &DailyReport{TraderID: traderID}
+	err := d.db.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN action IN (`+tradeOpenActions+`) THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN action NOT IN (`+tradeOpenActions+`) THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN action NOT IN (`+tradeOpenActions+`) AND pnl > 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN action NOT IN (`+tradeOpenActions+`) AND pnl < 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN action NOT IN (`+tradeOpenActions+`) THEN pnl ELSE 0 END), 0)
+		FROM trade_history
+		WHERE trader_id = ? AND timestamp >= ? AND timestamp < ?
+	`, traderID, start.UnixMilli(), end.UnixMilli()).Scan(
+		&report.OpenedTrades, &report.ClosedTrades, &report.WinningTrades, &report.LosingTrades, &report.RealizedPnL)
+	if err != nil {
+		return nil, err
+	}
+
+	if decided := report.WinningTrades + report.LosingTrades; decided > 0 {
+		report.WinRate = float64(report.WinningTrades) / float64(decided) * 100
+	}
+	return report, nil
+}
+
+// SaveDailyReport 保存每日报告（同一交易员同一天重复生成时覆盖）
+func (d *Database) SaveDailyReport(report *DailyReport) error {
+	_, err := d.db.Exec(`
+		INSERT OR REPLACE INTO daily_reports
+			(trader_id, user_id, report_date, opened_trades, closed_trades, winning_trades, losing_trades,
+			 win_rate, realized_pnl, starting_equity, ending_equity)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, report.TraderID, report.UserID, report.Date, report.OpenedTrades, report.ClosedTrades, report.WinningTrades,
+		report.LosingTrades, report.WinRate, report.RealizedPnL, report.StartingEquity, report.EndingEquity)
+	return err
+}
+
+// GetDailyReports 获取交易员最近的每日报告（按日期倒序）
+func (d *Database) GetDailyReports(traderID string, limit int) ([]*DailyReport, error) {
+	rows, err := d.db.Query(`
+		SELECT trader_id, COALESCE(user_id, ''), report_date, opened_trades, closed_trades, winning_trades,
+		       losing_trades, win_rate, realized_pnl, starting_equity, ending_equity, created_at
+		FROM daily_reports
+		WHERE trader_id = ?
+		ORDER BY report_date DESC
+		LIMIT ?
+	`, traderID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]*DailyReport, 0)
+	for rows.Next() {
+		var r DailyReport
+		if err := rows.Scan(&r.TraderID, &r.UserID, &r.Date, &r.OpenedTrades, &r.ClosedTrades, &r.WinningTrades,
+			&r.LosingTrades, &r.WinRate, &r.RealizedPnL, &r.StartingEquity, &r.EndingEquity, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, &r)
+	}
+	return reports, rows.Err()
+}
+
+// EquitySnapshot 交易员某个决策周期的净值快照
+type EquitySnapshot struct {
+	TraderID         string    `json:"trader_id"`
+	Timestamp        time.Time `json:"timestamp"`
+	TotalEquity      float64   `json:"total_equity"`
+	AvailableBalance float64   `json:"available_balance"`
+	UnrealizedPnL    float64   `json:"unrealized_pnl"`
+	TotalPnL         float64   `json:"total_pnl"`
+	PositionCount    int       `json:"position_count"`
+	MarginUsedPct    float64   `json:"margin_used_pct"`
+	InitialBalance   float64   `json:"initial_balance"`
+	CycleNumber      int       `json:"cycle_number"`
+}
+
+// SaveEquitySnapshot 写入一条净值快照
+func (d *Database) SaveEquitySnapshot(snapshot *EquitySnapshot) error {
+	_, err := d.db.Exec(`
+		INSERT INTO equity_snapshots
+			(trader_id, timestamp, total_equity, available_balance, unrealized_pnl, total_pnl,
+			 position_count, margin_used_pct, initial_balance, cycle_number)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, snapshot.TraderID, snapshot.Timestamp.UnixMilli(), snapshot.TotalEquity, snapshot.AvailableBalance,
+		snapshot.UnrealizedPnL, snapshot.TotalPnL, snapshot.PositionCount, snapshot.MarginUsedPct,
+		snapshot.InitialBalance, snapshot.CycleNumber)
+	return err
+}
+
+// GetEquitySnapshots 获取交易员最近的净值快照（按时间升序，最多 limit 条）
+// interval > 0 时按时间桶降采样，每个桶保留最后一条快照
+func (d *Database) GetEquitySnapshots(traderID string, interval time.Duration, limit int) ([]*EquitySnapshot, error) {
+	query := `
+		SELECT trader_id, timestamp, total_equity, available_balance, unrealized_pnl, total_pnl,
+		       position_count, margin_used_pct, initial_balance, cycle_number
+		FROM equity_snapshots
+		WHERE trader_id = ?
+		ORDER BY timestamp DESC
+		LIMIT ?`
+	args := []interface{}{traderID, limit}
+	if bucket := interval.Milliseconds(); bucket > 0 {
+		query = `
+		SELECT trader_id, timestamp, total_equity, available_balance, unrealized_pnl, total_pnl,
+		       position_count, margin_used_pct, initial_balance, cycle_number
+		FROM equity_snapshots
+		WHERE id IN (
+			SELECT MAX(id) FROM equity_snapshots WHERE trader_id = ? GROUP BY timestamp / ?
+		)
+		ORDER BY timestamp DESC
+		LIMIT ?`
+		args = []interface{}{traderID, bucket, limit}
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]*EquitySnapshot, 0)
+	for rows.Next() {
+		var s EquitySnapshot
+		var ts int64
+		if err := rows.Scan(&s.TraderID, &ts, &s.TotalEquity, &s.AvailableBalance, &s.UnrealizedPnL, &s.TotalPnL,
+			&s.PositionCount, &s.MarginUsedPct, &s.InitialBalance, &s.CycleNumber); err != nil {
+			return nil, err
+		}
+		s.Timestamp = time.UnixMilli(ts)
+		snapshots = append(snapshots, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 倒序查询最近的数据，返回时恢复为时间升序
+	for i, j := 0, len(snapshots)-1; i < j; i, j = i+1, j-1 {
+		snapshots[i], snapshots[j] = snapshots[j], snapshots[i]
+	}
+	return snapshots, nil
+}
+
+// GetFirstEquitySnapshotSince 获取交易员 since 之后（含）的第一条净值快照，没有快照时返回 nil
+// since 为零值时返回最早的快照（用于计算排行榜周期收益率和运行天数）
+func (d *Database) GetFirstEquitySnapshotSince(traderID string, since time.Time) (*EquitySnapshot, error) {
+	var s EquitySnapshot
+	var ts int64
+	err := d.db.QueryRow(`
+		SELECT trader_id, timestamp, total_equity, available_balance, unrealized_pnl, total_pnl,
+		       position_count, margin_used_pct, initial_balance, cycle_number
+		FROM equity_snapshots
+		WHERE trader_id = ? AND timestamp >= ?
+		ORDER BY timestamp ASC
+		LIMIT 1
+	`, traderID, since.UnixMilli()).Scan(&s.TraderID, &ts, &s.TotalEquity, &s.AvailableBalance, &s.UnrealizedPnL,
+		&s.TotalPnL, &s.PositionCount, &s.MarginUsedPct, &s.InitialBalance, &s.CycleNumber)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Timestamp = time.UnixMilli(ts)
+	return &s, nil
+}
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -148,6 +153,58 @@ func TestUpdateExchange_AsterEmptyValuesShouldNotOverwrite(t *testing.T) {
 	}
 }
 
+// TestUpdateExchangePassphrase_OKX 测试 OKX Passphrase 的保存、读取以及空值不覆盖
+func TestUpdateExchangePassphrase_OKX(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-009"
+	if err := db.UpdateExchange(userID, "okx", true, "okx-key", "okx-secret", true, "", "", "", ""); err != nil {
+		t.Fatalf("初始化 OKX 失败: %v", err)
+	}
+	if err := db.UpdateExchangePassphrase(userID, "okx", "okx-pass"); err != nil {
+		t.Fatalf("保存 Passphrase 失败: %v", err)
+	}
+	if err := db.UpdateExchangePassphrase(userID, "okx", ""); err != nil {
+		t.Fatalf("空 Passphrase 更新失败: %v", err)
+	}
+
+	exchanges, err := db.GetExchanges(userID)
+	if err != nil {
+		t.Fatalf("获取配置失败: %v", err)
+	}
+	if len(exchanges) != 1 || exchanges[0].Name != "OKX Futures" || exchanges[0].OKXPassphrase != "okx-pass" {
+		t.Errorf("OKX 配置不正确: %+v", exchanges)
+	}
+}
+
+// TestGetEnabledTestnetExchanges 测试只返回已启用且使用测试网的交易所（跨用户去重）
+func TestGetEnabledTestnetExchanges(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.UpdateExchange("test-user-001", "binance", true, "key", "secret", true, "", "", "", ""); err != nil {
+		t.Fatalf("初始化 binance 失败: %v", err)
+	}
+	if err := db.UpdateExchange("test-user-002", "binance", true, "key", "secret", true, "", "", "", ""); err != nil {
+		t.Fatalf("初始化 binance 失败: %v", err)
+	}
+	if err := db.UpdateExchange("test-user-001", "hyperliquid", false, "key", "", true, "0xabc", "", "", ""); err != nil {
+		t.Fatalf("初始化 hyperliquid 失败: %v", err)
+	}
+	if err := db.UpdateExchange("test-user-002", "okx", true, "key", "secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("初始化 okx 失败: %v", err)
+	}
+
+	exchangeIDs, err := db.GetEnabledTestnetExchanges()
+	if err != nil {
+		t.Fatalf("查询测试网交易所失败: %v", err)
+	}
+	if len(exchangeIDs) != 1 || exchangeIDs[0] != "binance" {
+		t.Errorf("期望只有 [binance], 实际 %v", exchangeIDs)
+	}
+}
+
 // TestUpdateExchange_NonEmptyValuesShouldUpdate 测试非空值应该正常更新
 func TestUpdateExchange_NonEmptyValuesShouldUpdate(t *testing.T) {
 	db, cleanup := setupTestDB(t)
@@ -944,3 +1001,801 @@ func TestConcurrentWritesWithWAL(t *testing.T) {
 		t.Errorf("并发写入失败次数过多: %d", errorCount)
 	}
 }
+
+// TestPruneCompetitionSnapshots 測試過期競賽快照清理
+func TestPruneCompetitionSnapshots(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, id := range []string{"snap-old", "snap-new"} {
+		if err := db.CreateCompetitionSnapshot(&CompetitionSnapshot{ID: id, Data: `{"traders":[]}`, Window: "all_time_top50"}); err != nil {
+			t.Fatalf("創建快照失敗: %v", err)
+		}
+	}
+	if _, err := db.db.Exec(`UPDATE competition_snapshots SET created_at = datetime('now', '-40 days') WHERE id = 'snap-old'`); err != nil {
+		t.Fatalf("更新快照時間失敗: %v", err)
+	}
+
+	deleted, err := db.PruneCompetitionSnapshots(30)
+	if err != nil {
+		t.Fatalf("清理快照失敗: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("應清理 1 個快照，實際 %d", deleted)
+	}
+
+	if _, err := db.GetCompetitionSnapshot("snap-old"); err == nil {
+		t.Error("過期快照應已刪除")
+	}
+	snapshot, err := db.GetCompetitionSnapshot("snap-new")
+	if err != nil {
+		t.Fatalf("未過期快照不應刪除: %v", err)
+	}
+	if snapshot.Window != "all_time_top50" || snapshot.Data != `{"traders":[]}` {
+		t.Errorf("快照內容不正確: %+v", snapshot)
+	}
+
+	// keepDays <= 0 表示永久保留
+	if deleted, _ := db.PruneCompetitionSnapshots(0); deleted != 0 {
+		t.Errorf("keepDays=0 不應刪除快照，實際 %d", deleted)
+	}
+}
+
+// TestUserWebhook 測試用戶 Webhook 配置的保存、覆蓋與刪除
+func TestUserWebhook(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	hook, err := db.GetUserWebhook("test-user-001")
+	if err != nil || hook != nil {
+		t.Fatalf("未配置時應返回 nil: hook=%v err=%v", hook, err)
+	}
+
+	if err := db.SaveUserWebhook(&UserWebhook{
+		UserID: "test-user-001", URL: "https://example.com/hook", Secret: "whsec_test",
+		Events: []string{"open", "close"}, Enabled: true,
+	}); err != nil {
+		t.Fatalf("保存Webhook失敗: %v", err)
+	}
+
+	hook, err = db.GetUserWebhook("test-user-001")
+	if err != nil || hook == nil {
+		t.Fatalf("讀取Webhook失敗: %v", err)
+	}
+	if hook.Secret != "whsec_test" || len(hook.Events) != 2 || !hook.Enabled {
+		t.Errorf("Webhook內容不正確: %+v", hook)
+	}
+
+	// 再次保存覆蓋原配置
+	if err := db.SaveUserWebhook(&UserWebhook{UserID: "test-user-001", URL: "https://example.com/v2", Secret: "whsec_test", Enabled: false}); err != nil {
+		t.Fatalf("覆蓋Webhook失敗: %v", err)
+	}
+	hook, _ = db.GetUserWebhook("test-user-001")
+	if hook.URL != "https://example.com/v2" || hook.Enabled || len(hook.Events) != 0 {
+		t.Errorf("覆蓋後內容不正確: %+v", hook)
+	}
+
+	if err := db.DeleteUserWebhook("test-user-001"); err != nil {
+		t.Fatalf("刪除Webhook失敗: %v", err)
+	}
+	if hook, _ := db.GetUserWebhook("test-user-001"); hook != nil {
+		t.Error("刪除後應返回 nil")
+	}
+}
+
+func TestRejectedDecisions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	records := []struct{ symbol, action, code string }{
+		{"BTCUSDT", "open_long", "daily_trade_limit"},
+		{"ETHUSDT", "open_short", "price_check"},
+		{"SOLUSDT", "open_long", "daily_trade_limit"},
+	}
+	for _, r := range records {
+		if err := db.RecordRejectedDecision("trader-1", "test-user-001", r.symbol, r.action, r.code, "拒絕原因"); err != nil {
+			t.Fatalf("記錄被拒絕決策失敗: %v", err)
+		}
+	}
+	if err := db.RecordRejectedDecision("trader-2", "test-user-001", "BTCUSDT", "open_long", "symbol_cap", "其他交易員"); err != nil {
+		t.Fatalf("記錄被拒絕決策失敗: %v", err)
+	}
+
+	all, err := db.GetRejectedDecisions("trader-1", "", 0)
+	if err != nil {
+		t.Fatalf("查詢被拒絕決策失敗: %v", err)
+	}
+	if len(all) != 3 || all[0].Symbol != "SOLUSDT" {
+		t.Errorf("應按時間倒序返回 trader-1 的3條記錄: %+v", all)
+	}
+
+	filtered, _ := db.GetRejectedDecisions("trader-1", "daily_trade_limit", 1)
+	if len(filtered) != 1 || filtered[0].ReasonCode != "daily_trade_limit" {
+		t.Errorf("按原因過濾並限制數量不正確: %+v", filtered)
+	}
+
+	counts, err := db.CountRejectedDecisions("trader-1")
+	if err != nil {
+		t.Fatalf("統計被拒絕決策失敗: %v", err)
+	}
+	if counts["daily_trade_limit"] != 2 || counts["price_check"] != 1 || counts["symbol_cap"] != 0 {
+		t.Errorf("按原因統計不正確: %v", counts)
+	}
+
+	// 剛寫入的記錄不應被清理
+	if n, err := db.PruneRejectedDecisions(30); err != nil || n != 0 {
+		t.Errorf("清理結果不正確: n=%d err=%v", n, err)
+	}
+}
+
+// TestTraderNameUniqueUnderConcurrency 测试并发创建同名交易员时只有一个成功，其余返回 ErrTraderNameTaken
+func TestTraderNameUniqueUnderConcurrency(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-008"
+	modelID := createTestAIModel(t, db, userID, "test-model")
+	exchangeID := createTestExchange(t, db, userID, "binance-unique")
+
+	newTrader := func(id, name string) *TraderRecord {
+		return &TraderRecord{
+			ID:                  id,
+			UserID:              userID,
+			Name:                name,
+			AIModelID:           modelID,
+			ExchangeID:          exchangeID,
+			InitialBalance:      1000,
+			ScanIntervalMinutes: 3,
+			Timeframes:          "4h",
+		}
+	}
+
+	const workers = 5
+	results := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			// SQLite 写锁竞争（SQLITE_BUSY）与唯一性无关，重试直到拿到确定结果
+			var err error
+			for attempt := 0; attempt < 50; attempt++ {
+				err = db.CreateTrader(newTrader(fmt.Sprintf("trader-dup-%d", i), "同名交易员"))
+				if err == nil || !strings.Contains(err.Error(), "SQLITE_BUSY") {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			results <- err
+		}(i)
+	}
+
+	created := 0
+	for i := 0; i < workers; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, ErrTraderNameTaken):
+		default:
+			t.Errorf("并发创建返回了非重名错误: %v", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("并发创建同名交易员应只有1个成功, 实际 %d", created)
+	}
+
+	// 其他用户可以使用相同名称
+	other := newTrader("trader-other-user", "同名交易员")
+	other.UserID = "test-user-009"
+	other.AIModelID = createTestAIModel(t, db, other.UserID, "test-model")
+	other.ExchangeID = createTestExchange(t, db, other.UserID, "binance-unique")
+	if err := db.CreateTrader(other); err != nil {
+		t.Fatalf("其他用户使用相同名称应成功: %v", err)
+	}
+
+	// 改名为已存在的名称同样被拒绝
+	second := newTrader("trader-second", "另一个交易员")
+	if err := db.CreateTrader(second); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+	second.Name = "同名交易员"
+	if err := db.UpdateTrader(second); !errors.Is(err, ErrTraderNameTaken) {
+		t.Errorf("改名为已存在的名称应返回 ErrTraderNameTaken, 实际 %v", err)
+	}
+}
+
+// TestDedupeTraderNames 测试迁移前已存在的重名交易员被加后缀，之后可以建立唯一索引
+func TestDedupeTraderNames(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-008"
+	modelID := createTestAIModel(t, db, userID, "test-model")
+	exchangeID := createTestExchange(t, db, userID, "binance-dedupe")
+
+	// 模拟旧数据库：没有唯一索引时插入了重名交易员
+	if _, err := db.db.Exec(`DROP INDEX IF EXISTS idx_traders_user_name`); err != nil {
+		t.Fatalf("删除唯一索引失败: %v", err)
+	}
+	for i, created := range []string{"2024-01-01 00:00:00", "2024-01-02 00:00:00", "2024-01-03 00:00:00"} {
+		if _, err := db.db.Exec(`INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, created_at) VALUES (?, ?, ?, ?, ?, 1000, 3, ?)`,
+			fmt.Sprintf("old-%d", i), userID, "趋势", modelID, exchangeID, created); err != nil {
+			t.Fatalf("插入旧交易员失败: %v", err)
+		}
+	}
+	if _, err := db.db.Exec(`INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes) VALUES (?, ?, ?, ?, ?, 1000, 3)`,
+		"taken-suffix", userID, "趋势 (2)", modelID, exchangeID); err != nil {
+		t.Fatalf("插入交易员失败: %v", err)
+	}
+
+	if err := db.dedupeTraderNames(); err != nil {
+		t.Fatalf("处理重名交易员失败: %v", err)
+	}
+	if _, err := db.db.Exec(`CREATE UNIQUE INDEX idx_traders_user_name ON traders(user_id, name)`); err != nil {
+		t.Fatalf("去重后应能创建唯一索引: %v", err)
+	}
+
+	names := map[string]string{}
+	for _, id := range []string{"old-0", "old-1", "old-2"} {
+		var name string
+		if err := db.db.QueryRow(`SELECT name FROM traders WHERE id = ?`, id).Scan(&name); err != nil {
+			t.Fatalf("查询交易员失败: %v", err)
+		}
+		names[id] = name
+	}
+	if names["old-0"] != "趋势" || names["old-1"] != "趋势 (3)" || names["old-2"] != "趋势 (4)" {
+		t.Errorf("重名交易员重命名不正确: %v", names)
+	}
+}
+
+// TestSoftDeleteTraderNameReuse 测试软删除的交易员不占用名称，同名交易员存在时恢复返回名称冲突
+func TestSoftDeleteTraderNameReuse(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-009"
+	modelID := createTestAIModel(t, db, userID, "test-model")
+	exchangeID := createTestExchange(t, db, userID, "binance-archive")
+	newTrader := func(id string) *TraderRecord {
+		return &TraderRecord{ID: id, UserID: userID, Name: "趋势", AIModelID: modelID, ExchangeID: exchangeID, InitialBalance: 1000, ScanIntervalMinutes: 3}
+	}
+
+	if err := db.CreateTrader(newTrader("archived-1")); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+	if err := db.DeleteTrader(userID, "archived-1"); err != nil {
+		t.Fatalf("软删除失败: %v", err)
+	}
+	if _, _, _, err := db.GetTraderConfig(userID, "archived-1"); err == nil {
+		t.Error("软删除的交易员不应能读取配置")
+	}
+	if err := db.CreateTrader(newTrader("active-1")); err != nil {
+		t.Fatalf("软删除后应能创建同名交易员: %v", err)
+	}
+
+	if err := db.RestoreTrader(userID, "archived-1"); !errors.Is(err, ErrTraderNameTaken) {
+		t.Errorf("存在同名交易员时恢复应返回名称冲突, 实际 %v", err)
+	}
+	if err := db.PurgeTrader(userID, "active-1"); err != nil {
+		t.Fatalf("永久删除失败: %v", err)
+	}
+	if err := db.RestoreTrader(userID, "archived-1"); err != nil {
+		t.Fatalf("恢复交易员失败: %v", err)
+	}
+	if archived, _ := db.GetArchivedTraders(userID); len(archived) != 0 {
+		t.Errorf("恢复后归档列表应为空, 实际 %d", len(archived))
+	}
+	if err := db.PurgeTrader("other-user", "archived-1"); err == nil {
+		t.Error("不能永久删除其他用户的交易员")
+	}
+}
+
+// TestGenerateAndRevokeBetaCodes 测试内测码生成（随机、唯一、字符集）、使用状态列表和作废
+func TestGenerateAndRevokeBetaCodes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	codes, err := db.GenerateBetaCodes(50)
+	if err != nil {
+		t.Fatalf("生成内测码失败: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != betaCodeLength || strings.Trim(code, betaCodeCharset) != "" {
+			t.Errorf("内测码格式不正确: %q", code)
+		}
+		if seen[code] {
+			t.Errorf("内测码重复: %s", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) != 50 {
+		t.Fatalf("期望生成 50 个不重复内测码，实际 %d", len(seen))
+	}
+
+	if _, err := db.GenerateBetaCodes(0); err == nil {
+		t.Error("生成数量为 0 应返回错误")
+	}
+	if _, err := db.GenerateBetaCodes(MaxBetaCodesBatch + 1); err == nil {
+		t.Error("超过单次上限应返回错误")
+	}
+
+	if err := db.UseBetaCode(codes[0], "alice@example.com"); err != nil {
+		t.Fatalf("使用内测码失败: %v", err)
+	}
+	list, err := db.ListBetaCodes()
+	if err != nil || len(list) != 50 {
+		t.Fatalf("列出内测码失败: %d, %v", len(list), err)
+	}
+	last := list[len(list)-1]
+	if last.Code != codes[0] || !last.Used || last.UsedBy != "alice@example.com" || last.UsedAt == "" {
+		t.Errorf("已使用的内测码应排在最后并记录使用者: %+v", last)
+	}
+
+	// 已使用的内测码不能作废，未使用的作废后失效
+	if err := db.RevokeBetaCode(codes[0]); err == nil {
+		t.Error("已使用的内测码不应被作废")
+	}
+	if err := db.RevokeBetaCode(codes[1]); err != nil {
+		t.Fatalf("作废内测码失败: %v", err)
+	}
+	if valid, _ := db.ValidateBetaCode(codes[1]); valid {
+		t.Error("作废后的内测码不应再有效")
+	}
+	if total, used, _ := db.GetBetaCodeStats(); total != 49 || used != 1 {
+		t.Errorf("统计不正确: total=%d used=%d", total, used)
+	}
+}
+
+// TestDeleteTradingHistoryScope 测试清空历史数据只影响指定用户（及指定交易员），不删除交易员配置
+func TestDeleteTradingHistoryScope(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	newTrader := func(id, userID string) *TraderRecord {
+		return &TraderRecord{
+			ID:                  id,
+			UserID:              userID,
+			Name:                id,
+			AIModelID:           createTestAIModel(t, db, userID, "model-"+id),
+			ExchangeID:          createTestExchange(t, db, userID, "binance-"+id),
+			InitialBalance:      1000,
+			ScanIntervalMinutes: 3,
+			Timeframes:          "4h",
+		}
+	}
+	owned := map[string]string{"trader-a1": "test-user-001", "trader-a2": "test-user-001", "trader-b1": "test-user-002"}
+	for id, userID := range owned {
+		if err := db.CreateTrader(newTrader(id, userID)); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+		if err := db.RecordTrade(id, userID, "BTCUSDT", "LONG", "OPEN", 0.1, 50000, "", 49000, 52000, 0, 0); err != nil {
+			t.Fatalf("记录交易失败: %v", err)
+		}
+		if err := db.RecordRejectedDecision(id, userID, "BTCUSDT", "open_long", "position_exists", "已有多仓"); err != nil {
+			t.Fatalf("记录被拒绝决策失败: %v", err)
+		}
+	}
+
+	count := func(table, traderID string) int {
+		var n int
+		if err := db.db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE trader_id = ?`, traderID).Scan(&n); err != nil {
+			t.Fatalf("统计 %s 失败: %v", table, err)
+		}
+		return n
+	}
+
+	// 只删除指定交易员
+	tradeRows, rejectedRows, err := db.DeleteTradingHistory("test-user-001", "trader-a1")
+	if err != nil || tradeRows != 1 || rejectedRows != 1 {
+		t.Fatalf("删除指定交易员历史结果不正确: trade=%d rejected=%d err=%v", tradeRows, rejectedRows, err)
+	}
+	if count("trade_history", "trader-a2") != 1 || count("trade_history", "trader-b1") != 1 {
+		t.Error("删除指定交易员时不应影响其他交易员")
+	}
+
+	// 不能删除其他用户的交易员历史
+	if tradeRows, rejectedRows, _ := db.DeleteTradingHistory("test-user-001", "trader-b1"); tradeRows != 0 || rejectedRows != 0 {
+		t.Errorf("不应删除其他用户的数据: trade=%d rejected=%d", tradeRows, rejectedRows)
+	}
+
+	// 删除用户全部交易员历史
+	if _, _, err := db.DeleteTradingHistory("test-user-001", ""); err != nil {
+		t.Fatalf("删除全部历史失败: %v", err)
+	}
+	if count("trade_history", "trader-a2") != 0 || count("rejected_decisions", "trader-a2") != 0 {
+		t.Error("用户全部交易员的历史应被删除")
+	}
+	if count("trade_history", "trader-b1") != 1 || count("rejected_decisions", "trader-b1") != 1 {
+		t.Error("其他用户的历史不应受影响")
+	}
+
+	traders, err := db.GetTraders("test-user-001")
+	if err != nil || len(traders) != 2 {
+		t.Errorf("交易员配置应保留: %d, %v", len(traders), err)
+	}
+}
+
+func TestDailyReportSummaryAndStorage(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	trades := []struct {
+		action string
+		pnl    float64
+	}{
+		{"OPEN", 0},
+		{"OPEN", 0},
+		{"CLOSE", 25},
+		{"CLOSE", -10},
+		{"PARTIAL_CLOSE", 5},
+	}
+	for _, tr := range trades {
+		if err := db.RecordTrade("trader-report", "test-user-001", "BTCUSDT", "LONG", tr.action, 0.1, 50000, "", 0, 0, tr.pnl, 0); err != nil {
+			t.Fatalf("记录交易失败: %v", err)
+		}
+	}
+	// 前一天的交易和其他交易员的交易不应计入
+	yesterday := time.Now().Add(-36 * time.Hour).UnixMilli()
+	if _, err := db.db.Exec(`INSERT INTO trade_history (trader_id, user_id, symbol, side, action, quantity, price, timestamp, pnl)
+		VALUES ('trader-report', 'test-user-001', 'ETHUSDT', 'SHORT', 'CLOSE', 1, 3000, ?, 100)`, yesterday); err != nil {
+		t.Fatalf("插入历史交易失败: %v", err)
+	}
+	if err := db.RecordTrade("trader-other", "test-user-001", "BTCUSDT", "LONG", "CLOSE", 0.1, 50000, "", 0, 0, 999, 0); err != nil {
+		t.Fatalf("记录交易失败: %v", err)
+	}
+
+	now := time.Now()
+	report, err := db.SummarizeTrades("trader-report", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("统计交易失败: %v", err)
+	}
+	if report.OpenedTrades != 2 || report.ClosedTrades != 3 || report.WinningTrades != 2 || report.LosingTrades != 1 {
+		t.Errorf("交易次数统计不正确: %+v", report)
+	}
+	if report.RealizedPnL != 20 {
+		t.Errorf("已实现盈亏应为 20，实际 %.2f", report.RealizedPnL)
+	}
+	if report.WinRate < 66.6 || report.WinRate > 66.7 {
+		t.Errorf("胜率应约为 66.67%%，实际 %.2f", report.WinRate)
+	}
+
+	empty, err := db.SummarizeTrades("trader-report", now.Add(-72*time.Hour), now.Add(-48*time.Hour))
+	if err != nil || empty.ClosedTrades != 0 || empty.WinRate != 0 {
+		t.Errorf("无交易的时间段应返回空报告: %+v, %v", empty, err)
+	}
+
+	// 同一天重复保存应覆盖
+	report.UserID = "test-user-001"
+	report.Date = "2026-01-02"
+	if err := db.SaveDailyReport(report); err != nil {
+		t.Fatalf("保存报告失败: %v", err)
+	}
+	report.EndingEquity = 1020
+	if err := db.SaveDailyReport(report); err != nil {
+		t.Fatalf("覆盖报告失败: %v", err)
+	}
+	if err := db.SaveDailyReport(&DailyReport{TraderID: "trader-report", Date: "2026-01-01"}); err != nil {
+		t.Fatalf("保存报告失败: %v", err)
+	}
+
+	reports, err := db.GetDailyReports("trader-report", 30)
+	if err != nil {
+		t.Fatalf("获取报告失败: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("应有 2 份报告，实际 %d", len(reports))
+	}
+	if reports[0].Date != "2026-01-02" || reports[0].EndingEquity != 1020 || reports[0].ClosedTrades != 3 {
+		t.Errorf("报告应按日期倒序且被覆盖更新: %+v", reports[0])
+	}
+	if limited, _ := db.GetDailyReports("trader-report", 1); len(limited) != 1 {
+		t.Errorf("limit 应生效，实际 %d", len(limited))
+	}
+}
+
+func TestEquitySnapshots(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// 每 3 分钟一条快照，共 1 小时
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		err := db.SaveEquitySnapshot(&EquitySnapshot{
+			TraderID:       "trader-equity",
+			Timestamp:      start.Add(time.Duration(i) * 3 * time.Minute),
+			TotalEquity:    1000 + float64(i),
+			TotalPnL:       float64(i),
+			InitialBalance: 1000,
+			CycleNumber:    i + 1,
+		})
+		if err != nil {
+			t.Fatalf("保存净值快照失败: %v", err)
+		}
+	}
+	db.SaveEquitySnapshot(&EquitySnapshot{TraderID: "other-trader", Timestamp: start, TotalEquity: 1})
+
+	all, err := db.GetEquitySnapshots("trader-equity", 0, 100)
+	if err != nil {
+		t.Fatalf("查询净值快照失败: %v", err)
+	}
+	if len(all) != 20 || all[0].CycleNumber != 1 || all[19].TotalEquity != 1019 {
+		t.Fatalf("应按时间升序返回 20 条快照，实际 %d 条", len(all))
+	}
+	if !all[0].Timestamp.Equal(start) {
+		t.Errorf("时间戳应保持不变，实际 %v", all[0].Timestamp)
+	}
+
+	// limit 保留最近的数据
+	latest, _ := db.GetEquitySnapshots("trader-equity", 0, 5)
+	if len(latest) != 5 || latest[0].CycleNumber != 16 || latest[4].CycleNumber != 20 {
+		t.Errorf("limit 应返回最近 5 条，实际 %d 条", len(latest))
+	}
+
+	// 15 分钟降采样：4 个桶，每个桶保留最后一条
+	sampled, err := db.GetEquitySnapshots("trader-equity", 15*time.Minute, 100)
+	if err != nil {
+		t.Fatalf("降采样查询失败: %v", err)
+	}
+	if len(sampled) != 4 {
+		t.Fatalf("15m 降采样应返回 4 个数据点，实际 %d", len(sampled))
+	}
+	if sampled[0].CycleNumber != 5 || sampled[3].CycleNumber != 20 {
+		t.Errorf("每个时间桶应保留最后一条快照，实际 %d, %d", sampled[0].CycleNumber, sampled[3].CycleNumber)
+	}
+}
+
+func TestSummarizeFees(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	empty, err := db.SummarizeFees("trader-fees", now.Add(-time.Hour), now.Add(time.Hour), 0.0004)
+	if err != nil {
+		t.Fatalf("统计手续费失败: %v", err)
+	}
+	if empty.Trades != 0 || empty.TotalFees != 0 || empty.FeeToPnL != nil || len(empty.BySymbol) != 0 {
+		t.Errorf("没有交易时应返回全零汇总: %+v", empty)
+	}
+
+	trades := []struct {
+		symbol, action  string
+		quantity, price float64
+		pnl             float64
+	}{
+		{"BTCUSDT", "OPEN", 0.1, 50000, 0},
+		{"BTCUSDT", "CLOSE", 0.1, 51000, 100},
+		{"ETHUSDT", "OPEN", 1, 3000, 0},
+		{"ETHUSDT", "CLOSE", 1, 2950, -50},
+	}
+	for _, tr := range trades {
+		if err := db.RecordTrade("trader-fees", "test-user-001", tr.symbol, "LONG", tr.action, tr.quantity, tr.price, "", 0, 0, tr.pnl, 0); err != nil {
+			t.Fatalf("记录交易失败: %v", err)
+		}
+	}
+
+	summary, err := db.SummarizeFees("trader-fees", now.Add(-time.Hour), now.Add(time.Hour), 0.0004)
+	if err != nil {
+		t.Fatalf("统计手续费失败: %v", err)
+	}
+	// 名义价值 5000+5100+3000+2950 = 16050，手续费 = 16050×0.0004 = 6.42
+	if summary.Trades != 4 || math.Abs(summary.Notional-16050) > 1e-6 || math.Abs(summary.TotalFees-6.42) > 1e-6 {
+		t.Errorf("汇总不正确: %+v", summary)
+	}
+	if summary.RealizedPnL != 50 || math.Abs(summary.NetPnL-43.58) > 1e-6 {
+		t.Errorf("盈亏不正确: realized=%.2f net=%.2f", summary.RealizedPnL, summary.NetPnL)
+	}
+	if summary.FeeToPnL == nil || math.Abs(*summary.FeeToPnL-0.1284) > 1e-6 {
+		t.Errorf("手续费/盈亏比不正确: %v", summary.FeeToPnL)
+	}
+	if len(summary.BySymbol) != 2 || summary.BySymbol[0].Symbol != "BTCUSDT" || summary.BySymbol[1].RealizedPnL != -50 {
+		t.Errorf("按币种拆分应按手续费从高到低排序: %+v, %+v", summary.BySymbol[0], summary.BySymbol[1])
+	}
+
+	// 时间范围之外的交易不计入
+	old, err := db.SummarizeFees("trader-fees", now.Add(-48*time.Hour), now.Add(-24*time.Hour), 0.0004)
+	if err != nil || old.Trades != 0 {
+		t.Errorf("范围外不应有交易: %+v, %v", old, err)
+	}
+}
+
+// TestConcurrentAccessWithoutBusyErrors 多个交易员并发写状态/交易记录、同时有API读请求时不应出现 SQLITE_BUSY
+func TestConcurrentAccessWithoutBusyErrors(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	const workers = 16
+	const iterations = 25
+	var wg sync.WaitGroup
+	errCh := make(chan error, workers*iterations*3)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			traderID := fmt.Sprintf("trader-load-%d", w)
+			for i := 0; i < iterations; i++ {
+				if err := db.SaveTraderState(traderID, "test-user-001", i, float64(1000+i), 0, "{}"); err != nil {
+					errCh <- err
+				}
+				if err := db.RecordTrade(traderID, "test-user-001", "BTCUSDT", "LONG", "OPEN", 0.1, 50000, "", 0, 0, 0, 0); err != nil {
+					errCh <- err
+				}
+				if _, err := db.SummarizeTrades(traderID, time.Now().Add(-time.Hour), time.Now().Add(time.Hour)); err != nil {
+					errCh <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Errorf("并发访问出错: %v", err)
+	}
+
+	// 合并写入的状态在读取前会落盘，且保留每个交易员的最新状态
+	for w := 0; w < workers; w++ {
+		callCount, peakEquity, _, _, err := db.LoadTraderState(fmt.Sprintf("trader-load-%d", w))
+		if err != nil || callCount != iterations-1 || peakEquity != float64(1000+iterations-1) {
+			t.Errorf("交易员 %d 状态不正确: call=%d peak=%.0f err=%v", w, callCount, peakEquity, err)
+		}
+	}
+	var trades int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM trade_history WHERE trader_id LIKE 'trader-load-%'`).Scan(&trades); err != nil || trades != workers*iterations {
+		t.Errorf("交易记录数不正确: %d, %v", trades, err)
+	}
+}
+
+// TestConnectionPragmasOnEveryConnection 连接池中每个连接都应启用 busy_timeout 和外键约束
+func TestConnectionPragmasOnEveryConnection(t *testing.T) {
+	db, err := NewDatabaseWithConfig(t.TempDir()+"/pool.db", &DatabaseConfig{MaxOpenConns: 3, BusyTimeoutMs: 1234, TraderStateFlushMs: -1})
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	if db.stateBatcher != nil {
+		t.Error("TraderStateFlushMs=-1 时应直接写入")
+	}
+
+	// 同时占用多个连接，逐个检查连接级设置
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := db.db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("获取连接失败: %v", err)
+		}
+		defer conn.Close()
+
+		var busyTimeout, foreignKeys int
+		if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+			t.Fatalf("查询 busy_timeout 失败: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			t.Fatalf("查询 foreign_keys 失败: %v", err)
+		}
+		if busyTimeout != 1234 || foreignKeys != 1 {
+			t.Errorf("连接 %d 设置不正确: busy_timeout=%d foreign_keys=%d", i, busyTimeout, foreignKeys)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{" Momentum, mean-reversion ,momentum,", "momentum,mean-reversion", false},
+		{"btc_only,v2", "btc_only,v2", false},
+		{"-leading", "", true},
+		{"has space", "", true},
+		{"中文", "", true},
+		{strings.Repeat("a", MaxTraderTagLen+1), "", true},
+		{"a,b,c,d,e,f,g,h,i,j,k", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeTags(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeTags(%q) 错误不符合预期: %v", tt.raw, err)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("NormalizeTags(%q) = %q，期望 %q", tt.raw, got, tt.want)
+		}
+	}
+
+	trader := &TraderRecord{Tags: "momentum,btc"}
+	if !trader.HasTag("BTC") || trader.HasTag("eth") {
+		t.Error("HasTag 判断不正确")
+	}
+}
+
+func TestCleanShutdownMarker(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// 首次启动视为正常
+	unclean, err := db.MarkSessionStarted()
+	if err != nil || unclean || db.UncleanShutdownDetected() {
+		t.Fatalf("首次启动不应视为非正常退出: %v, %v", unclean, err)
+	}
+
+	// 未调用 MarkCleanShutdown 再次启动（模拟进程被强制终止）
+	if unclean, _ := db.MarkSessionStarted(); !unclean || !db.UncleanShutdownDetected() {
+		t.Error("上次未正常退出时应检测到")
+	}
+
+	// 正常退出后再启动
+	if err := db.MarkCleanShutdown(); err != nil {
+		t.Fatalf("记录正常退出失败: %v", err)
+	}
+	if unclean, _ := db.MarkSessionStarted(); unclean {
+		t.Error("正常退出后不应视为非正常退出")
+	}
+}
+
+func TestUserAPIKeys(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, _, err := db.CreateUserAPIKey("test-user-001", "cron", "admin", time.Time{}); err == nil {
+		t.Error("不支持的权限范围應返回錯誤")
+	}
+
+	plaintext, key, err := db.CreateUserAPIKey("test-user-001", "cron", APIKeyScopeRead, time.Time{})
+	if err != nil {
+		t.Fatalf("創建API Key失敗: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, "nofx_") || key.KeyHash == plaintext || !strings.HasPrefix(plaintext, key.KeyPrefix) {
+		t.Errorf("API Key 格式不正確: %s %+v", plaintext, key)
+	}
+
+	found, err := db.GetUserAPIKeyByKey(plaintext)
+	if err != nil || found == nil {
+		t.Fatalf("按明文查找API Key失敗: %v", err)
+	}
+	if found.UserID != "test-user-001" || found.Scope != APIKeyScopeRead || found.IsExpired(time.Now()) {
+		t.Errorf("API Key 內容不正確: %+v", found)
+	}
+	if missing, err := db.GetUserAPIKeyByKey("nofx_unknown"); err != nil || missing != nil {
+		t.Errorf("未知密鑰應返回 nil: %v %v", missing, err)
+	}
+
+	if err := db.TouchUserAPIKey(key.ID); err != nil {
+		t.Fatalf("更新最後使用時間失敗: %v", err)
+	}
+	keys, err := db.ListUserAPIKeys("test-user-001")
+	if err != nil || len(keys) != 1 || keys[0].LastUsedAt == "" {
+		t.Fatalf("列出API Key失敗: %v %+v", err, keys)
+	}
+
+	// 未到期和已過期的密鑰
+	_, future, err := db.CreateUserAPIKey("test-user-001", "ci", APIKeyScopeReadWrite, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("創建API Key失敗: %v", err)
+	}
+	_, expired, err := db.CreateUserAPIKey("test-user-001", "old", APIKeyScopeReadWrite, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("創建API Key失敗: %v", err)
+	}
+	keys, _ = db.ListUserAPIKeys("test-user-001")
+	for _, k := range keys {
+		if k.ID == expired.ID && !k.IsExpired(time.Now()) {
+			t.Errorf("過期的API Key應判定為過期: %+v", k)
+		}
+		if k.ID == future.ID && k.IsExpired(time.Now()) {
+			t.Errorf("未到期的API Key不應判定為過期: %+v", k)
+		}
+	}
+
+	if err := db.DeleteUserAPIKey("other-user", key.ID); err == nil {
+		t.Error("不能撤銷其他用戶的API Key")
+	}
+	if err := db.DeleteUserAPIKey("test-user-001", key.ID); err != nil {
+		t.Fatalf("撤銷API Key失敗: %v", err)
+	}
+	if found, _ := db.GetUserAPIKeyByKey(plaintext); found != nil {
+		t.Error("撤銷後不應再能查到API Key")
+	}
+}
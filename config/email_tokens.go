@@ -0,0 +1,103 @@
+package config
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 邮件令牌用途
+const (
+	EmailTokenVerifyEmail   = "verify_email"   // 注册后验证邮箱
+	EmailTokenResetPassword = "reset_password" // 忘记密码时通过邮件重置
+)
+
+// emailTokenBytes 邮件令牌的随机字节数（64 位十六进制，放在链接中）
+const emailTokenBytes = 32
+
+// ErrEmailTokenInvalid 令牌不存在、已使用或已过期
+var ErrEmailTokenInvalid = errors.New("链接无效或已过期")
+
+// hashEmailToken 计算邮件令牌的存储哈希
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateEmailToken 为用户生成一次性邮件令牌，同一用途的旧令牌全部作废，返回明文（只在邮件中出现）
+func (d *Database) CreateEmailToken(userID, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomHex(emailTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE user_email_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND purpose = ? AND used_at IS NULL
+	`, userID, purpose); err != nil {
+		return "", fmt.Errorf("作废旧令牌失败: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO user_email_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES (?, ?, ?, datetime('now', ?))
+	`, userID, purpose, hashEmailToken(token), fmt.Sprintf("+%d seconds", int(ttl.Seconds()))); err != nil {
+		return "", fmt.Errorf("保存令牌失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeEmailToken 校验并作废一个邮件令牌，返回所属用户ID；令牌无效、已使用或已过期时返回 ErrEmailTokenInvalid
+func (d *Database) ConsumeEmailToken(token, purpose string) (string, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var userID string
+	err = tx.QueryRow(`
+		SELECT id, user_id FROM user_email_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > datetime('now')
+	`, hashEmailToken(token), purpose).Scan(&id, &userID)
+	if err == sql.ErrNoRows {
+		return "", ErrEmailTokenInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// 条件更新保证并发请求中只有一个能使用该令牌
+	result, err := tx.Exec(`UPDATE user_email_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = ? AND used_at IS NULL`, id)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return "", ErrEmailTokenInvalid
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// PruneEmailTokens 删除已使用或已过期的邮件令牌
+func (d *Database) PruneEmailTokens() (int64, error) {
+	result, err := d.db.Exec(`DELETE FROM user_email_tokens WHERE used_at IS NOT NULL OR expires_at <= datetime('now')`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
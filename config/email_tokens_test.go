@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEmailTokens 测试邮件令牌一次性使用、用途隔离、过期和重新签发后旧令牌失效
+func TestEmailTokens(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "email-user"
+	if err := db.CreateUser(&User{ID: userID, Email: "email@example.com", PasswordHash: "hash", OTPSecret: "SECRET"}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	token, err := db.CreateEmailToken(userID, EmailTokenResetPassword, time.Hour)
+	if err != nil {
+		t.Fatalf("生成令牌失败: %v", err)
+	}
+	if _, err := db.ConsumeEmailToken(token, EmailTokenVerifyEmail); err != ErrEmailTokenInvalid {
+		t.Errorf("令牌不能用于其他用途, 实际 %v", err)
+	}
+	if got, err := db.ConsumeEmailToken(token, EmailTokenResetPassword); err != nil || got != userID {
+		t.Fatalf("令牌应校验通过: %q, %v", got, err)
+	}
+	if _, err := db.ConsumeEmailToken(token, EmailTokenResetPassword); err != ErrEmailTokenInvalid {
+		t.Errorf("已使用的令牌不能再次使用, 实际 %v", err)
+	}
+
+	// 重新签发后旧令牌失效
+	first, _ := db.CreateEmailToken(userID, EmailTokenVerifyEmail, time.Hour)
+	second, _ := db.CreateEmailToken(userID, EmailTokenVerifyEmail, time.Hour)
+	if _, err := db.ConsumeEmailToken(first, EmailTokenVerifyEmail); err != ErrEmailTokenInvalid {
+		t.Errorf("重新签发后旧令牌应失效, 实际 %v", err)
+	}
+	if _, err := db.ConsumeEmailToken(second, EmailTokenVerifyEmail); err != nil {
+		t.Errorf("新令牌应校验通过: %v", err)
+	}
+
+	// 过期令牌
+	expired, _ := db.CreateEmailToken(userID, EmailTokenResetPassword, 0)
+	if _, err := db.ConsumeEmailToken(expired, EmailTokenResetPassword); err != ErrEmailTokenInvalid {
+		t.Errorf("过期令牌不能使用, 实际 %v", err)
+	}
+
+	if pruned, err := db.PruneEmailTokens(); err != nil || pruned != 4 {
+		t.Errorf("应清理 4 个已使用或过期的令牌, 实际 %d, %v", pruned, err)
+	}
+
+	if err := db.SetUserEmailVerified(userID); err != nil {
+		t.Fatalf("标记邮箱已验证失败: %v", err)
+	}
+	if user, _ := db.GetUserByID(userID); !user.EmailVerified {
+		t.Error("用户邮箱应已验证")
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import "fmt"
+
+// PositionAnnotation 持仓的人工备注和锁定状态（按交易员+币种+方向唯一）
+type PositionAnnotation struct {
+	TraderID  string `json:"trader_id"`
+	Symbol    string `json:"symbol"`
+	Side      string `json:"side"` // long / short
+	Note      string `json:"note"`
+	Locked    bool   `json:"locked"` // 锁定后执行层拒绝 AI 的平仓决策
+	UpdatedAt string `json:"updated_at"`
+}
+
+// SavePositionAnnotation 写入或覆盖一条持仓备注；备注为空且未锁定时删除该记录
+func (d *Database) SavePositionAnnotation(a *PositionAnnotation) error {
+	if a.Note == "" && !a.Locked {
+		return d.DeletePositionAnnotation(a.TraderID, a.Symbol, a.Side)
+	}
+	_, err := d.db.Exec(`
+		INSERT INTO position_annotations (trader_id, symbol, side, note, locked, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(trader_id, symbol, side) DO UPDATE SET
+			note = excluded.note,
+			locked = excluded.locked,
+			updated_at = CURRENT_TIMESTAMP
+	`, a.TraderID, a.Symbol, a.Side, a.Note, a.Locked)
+	if err != nil {
+		return fmt.Errorf("保存持仓备注失败: %w", err)
+	}
+	return nil
+}
+
+// GetPositionAnnotations 获取交易员的全部持仓备注
+func (d *Database) GetPositionAnnotations(traderID string) ([]*PositionAnnotation, error) {
+	rows, err := d.db.Query(`
+		SELECT trader_id, symbol, side, note, locked, updated_at
+		FROM position_annotations WHERE trader_id = ? ORDER BY symbol, side
+	`, traderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	annotations := make([]*PositionAnnotation, 0)
+	for rows.Next() {
+		var a PositionAnnotation
+		if err := rows.Scan(&a.TraderID, &a.Symbol, &a.Side, &a.Note, &a.Locked, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, &a)
+	}
+	return annotations, rows.Err()
+}
+
+// DeletePositionAnnotation 删除一条持仓备注（持仓完全平仓后调用，记录不存在时不报错）
+func (d *Database) DeletePositionAnnotation(traderID, symbol, side string) error {
+	if _, err := d.db.Exec(`DELETE FROM position_annotations WHERE trader_id = ? AND symbol = ? AND side = ?`,
+		traderID, symbol, side); err != nil {
+		return fmt.Errorf("删除持仓备注失败: %w", err)
+	}
+	return nil
+}
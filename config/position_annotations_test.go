@@ -0,0 +1,47 @@
+package config
+
+import "testing"
+
+// TestPositionAnnotations 测试持仓备注的写入、覆盖、清空即删除和按交易员删除
+func TestPositionAnnotations(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SavePositionAnnotation(&PositionAnnotation{TraderID: "t1", Symbol: "BTCUSDT", Side: "long", Note: "长线底仓"}); err != nil {
+		t.Fatalf("保存备注失败: %v", err)
+	}
+	if err := db.SavePositionAnnotation(&PositionAnnotation{TraderID: "t1", Symbol: "BTCUSDT", Side: "long", Note: "长线底仓，勿动", Locked: true}); err != nil {
+		t.Fatalf("覆盖备注失败: %v", err)
+	}
+	if err := db.SavePositionAnnotation(&PositionAnnotation{TraderID: "t1", Symbol: "ETHUSDT", Side: "short", Locked: true}); err != nil {
+		t.Fatalf("保存锁定失败: %v", err)
+	}
+	if err := db.SavePositionAnnotation(&PositionAnnotation{TraderID: "t2", Symbol: "BTCUSDT", Side: "long", Note: "其他交易员"}); err != nil {
+		t.Fatalf("保存备注失败: %v", err)
+	}
+
+	annotations, err := db.GetPositionAnnotations("t1")
+	if err != nil {
+		t.Fatalf("查询备注失败: %v", err)
+	}
+	if len(annotations) != 2 {
+		t.Fatalf("t1 应有 2 条备注, 实际 %d", len(annotations))
+	}
+	if a := annotations[0]; a.Symbol != "BTCUSDT" || a.Note != "长线底仓，勿动" || !a.Locked {
+		t.Errorf("备注应被覆盖: %+v", a)
+	}
+
+	// 备注为空且未锁定等同于删除
+	if err := db.SavePositionAnnotation(&PositionAnnotation{TraderID: "t1", Symbol: "ETHUSDT", Side: "short"}); err != nil {
+		t.Fatalf("清空备注失败: %v", err)
+	}
+	if err := db.DeletePositionAnnotation("t1", "BTCUSDT", "long"); err != nil {
+		t.Fatalf("删除备注失败: %v", err)
+	}
+	if annotations, _ := db.GetPositionAnnotations("t1"); len(annotations) != 0 {
+		t.Errorf("t1 的备注应全部删除, 实际 %d", len(annotations))
+	}
+	if annotations, _ := db.GetPositionAnnotations("t2"); len(annotations) != 1 {
+		t.Errorf("t2 的备注不应受影响, 实际 %d", len(annotations))
+	}
+}
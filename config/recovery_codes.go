@@ -0,0 +1,100 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// RecoveryCodeCount 每次生成的恢复码数量
+const RecoveryCodeCount = 10
+
+// recoveryCodeBytes 每个恢复码的随机字节数（16 位十六进制，按 4 位一组显示）
+const recoveryCodeBytes = 8
+
+// NormalizeRecoveryCode 规范化用户输入的恢复码：去掉分隔符和空白，转为小写
+func NormalizeRecoveryCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(code) {
+		if r == '-' || r == ' ' || r == '\t' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// IsRecoveryCodeFormat 输入是否为恢复码格式（用于区分 6 位 OTP 和恢复码）
+func IsRecoveryCodeFormat(code string) bool {
+	normalized := NormalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeBytes*2 {
+		return false
+	}
+	_, err := hex.DecodeString(normalized)
+	return err == nil
+}
+
+// hashRecoveryCode 计算恢复码的存储哈希（加入用户ID，同一恢复码在不同用户下哈希不同）
+func hashRecoveryCode(userID, code string) string {
+	sum := sha256.Sum256([]byte(userID + ":" + NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateRecoveryCodes 为用户生成一组新的恢复码并使旧的全部失效，返回明文（只在此时可见）
+func (d *Database) GenerateRecoveryCodes(userID string) ([]string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		raw, err := randomHex(recoveryCodeBytes)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return nil, fmt.Errorf("清除旧恢复码失败: %w", err)
+	}
+	for _, code := range codes {
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)`,
+			userID, hashRecoveryCode(userID, code)); err != nil {
+			return nil, fmt.Errorf("保存恢复码失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode 校验并作废一个恢复码，恢复码不存在或已使用时返回 false
+func (d *Database) UseRecoveryCode(userID, code string) (bool, error) {
+	if !IsRecoveryCodeFormat(code) {
+		return false, nil
+	}
+	result, err := d.db.Exec(`
+		UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`, userID, hashRecoveryCode(userID, code))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// CountRecoveryCodes 用户剩余可用的恢复码数量
+func (d *Database) CountRecoveryCodes(userID string) (int, error) {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
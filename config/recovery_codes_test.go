@@ -0,0 +1,50 @@
+package config
+
+import "testing"
+
+// TestRecoveryCodes 测试恢复码生成、一次性使用和重新生成后旧码失效
+func TestRecoveryCodes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "recovery-user"
+	if err := db.CreateUser(&User{ID: userID, Email: "recovery@example.com", PasswordHash: "hash", OTPSecret: "SECRET", OTPVerified: true}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	codes, err := db.GenerateRecoveryCodes(userID)
+	if err != nil {
+		t.Fatalf("生成恢复码失败: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount || !IsRecoveryCodeFormat(codes[0]) {
+		t.Fatalf("期望 %d 个格式正确的恢复码, 实际 %v", RecoveryCodeCount, codes)
+	}
+
+	// 输入时忽略大小写和分隔符；使用后作废
+	if ok, err := db.UseRecoveryCode(userID, " "+NormalizeRecoveryCode(codes[0])+" "); err != nil || !ok {
+		t.Fatalf("恢复码应校验通过: %v, %v", ok, err)
+	}
+	if ok, _ := db.UseRecoveryCode(userID, codes[0]); ok {
+		t.Error("已使用的恢复码不能再次使用")
+	}
+	if ok, _ := db.UseRecoveryCode("other-user", codes[1]); ok {
+		t.Error("恢复码不能被其他用户使用")
+	}
+	if ok, _ := db.UseRecoveryCode(userID, "123456"); ok {
+		t.Error("OTP 格式的输入不应作为恢复码通过")
+	}
+	if remaining, _ := db.CountRecoveryCodes(userID); remaining != RecoveryCodeCount-1 {
+		t.Errorf("剩余恢复码应为 %d, 实际 %d", RecoveryCodeCount-1, remaining)
+	}
+
+	// 重新生成后旧恢复码全部失效
+	if _, err := db.GenerateRecoveryCodes(userID); err != nil {
+		t.Fatalf("重新生成恢复码失败: %v", err)
+	}
+	if ok, _ := db.UseRecoveryCode(userID, codes[1]); ok {
+		t.Error("重新生成后旧恢复码应失效")
+	}
+	if remaining, _ := db.CountRecoveryCodes(userID); remaining != RecoveryCodeCount {
+		t.Errorf("重新生成后应有 %d 个可用恢复码, 实际 %d", RecoveryCodeCount, remaining)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RevokeToken 记录一个被撤销的token，直到 expiresAt 之前都视为无效（重复撤销时更新过期时间）
+func (d *Database) RevokeToken(tokenID, tokenType, userID string, expiresAt time.Time) error {
+	_, err := d.db.Exec(`
+		INSERT OR REPLACE INTO revoked_tokens (token_id, token_type, user_id, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, tokenID, tokenType, userID, expiresAt.UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return fmt.Errorf("记录撤销token失败: %w", err)
+	}
+	return nil
+}
+
+// IsTokenRevoked 检查token是否已被撤销（已过期的记录不再生效）
+func (d *Database) IsTokenRevoked(tokenID string) (bool, error) {
+	var exists int
+	err := d.db.QueryRow(`
+		SELECT 1 FROM revoked_tokens WHERE token_id = ? AND expires_at > ?
+	`, tokenID, time.Now().UTC().Format(sqliteTimeLayout)).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PruneRevokedTokens 删除已过期的撤销记录（token本身已过期，无需再拦截）
+func (d *Database) PruneRevokedTokens() (int64, error) {
+	result, err := d.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at <= ?`, time.Now().UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetTokenVersion 获取用户当前的token版本号（用户不存在时返回 0）
+// 查询失败时返回错误，调用方不能据此放行可能已被撤销的token
+func (d *Database) GetTokenVersion(userID string) (int, error) {
+	var version sql.NullInt64
+	err := d.db.QueryRow(`SELECT token_version FROM users WHERE id = ?`, userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询token版本号失败: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// IncrementTokenVersion 递增用户的token版本号并返回新版本号，此前签发的token全部失效
+func (d *Database) IncrementTokenVersion(userID string) (int, error) {
+	result, err := d.db.Exec(`UPDATE users SET token_version = COALESCE(token_version, 0) + 1 WHERE id = ?`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("更新token版本号失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, fmt.Errorf("用户不存在")
+	}
+	return d.GetTokenVersion(userID)
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRevokedTokens 测试撤销记录的写入、过期失效和清理
+func TestRevokedTokens(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.RevokeToken("jti-active", "refresh", "u1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("记录撤销token失败: %v", err)
+	}
+	if err := db.RevokeToken("jti-expired", "access", "u1", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("记录撤销token失败: %v", err)
+	}
+
+	if revoked, err := db.IsTokenRevoked("jti-active"); err != nil || !revoked {
+		t.Errorf("未过期的撤销记录应生效: %v, %v", revoked, err)
+	}
+	if revoked, _ := db.IsTokenRevoked("jti-expired"); revoked {
+		t.Error("已过期的撤销记录不应生效")
+	}
+	if revoked, _ := db.IsTokenRevoked("jti-unknown"); revoked {
+		t.Error("未撤销的token不应被拦截")
+	}
+
+	// 重复撤销更新过期时间
+	if err := db.RevokeToken("jti-expired", "access", "u1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("重复撤销失败: %v", err)
+	}
+	if revoked, _ := db.IsTokenRevoked("jti-expired"); !revoked {
+		t.Error("重复撤销后应按新的过期时间生效")
+	}
+
+	db.RevokeToken("jti-old", "access", "u1", time.Now().Add(-time.Minute))
+	if deleted, err := db.PruneRevokedTokens(); err != nil || deleted != 1 {
+		t.Errorf("应清理 1 条过期记录, 实际 %d (%v)", deleted, err)
+	}
+}
+
+// TestTokenVersion 测试登出所有设备时递增token版本号
+func TestTokenVersion(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if v, err := db.GetTokenVersion("test-user-001"); err != nil || v != 0 {
+		t.Errorf("初始版本号应为 0, 实际 %d (%v)", v, err)
+	}
+	for want := 1; want <= 2; want++ {
+		if v, err := db.IncrementTokenVersion("test-user-001"); err != nil || v != want {
+			t.Errorf("版本号应为 %d, 实际 %d (%v)", want, v, err)
+		}
+	}
+	if v, err := db.GetTokenVersion("test-user-002"); err != nil || v != 0 {
+		t.Errorf("其他用户版本号不受影响, 实际 %d (%v)", v, err)
+	}
+	if v, err := db.GetTokenVersion("missing-user"); err != nil || v != 0 {
+		t.Errorf("用户不存在时版本号应为 0, 实际 %d (%v)", v, err)
+	}
+	if _, err := db.IncrementTokenVersion("missing-user"); err == nil {
+		t.Error("用户不存在时应返回错误")
+	}
+}
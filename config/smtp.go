@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SMTPSettings 系统配置中的 SMTP 发件设置
+type SMTPSettings struct {
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	Username    string `json:"username"`
+	Password    string `json:"-"` // 加密存储，不返回到前端
+	From        string `json:"from"`
+	LinkBaseURL string `json:"link_base_url"` // 邮件链接的前端地址
+}
+
+// GetSMTPSettings 读取 SMTP 设置（密码已解密）
+func (d *Database) GetSMTPSettings() SMTPSettings {
+	get := func(key string) string {
+		value, _ := d.GetSystemConfig(key)
+		return strings.TrimSpace(value)
+	}
+	settings := SMTPSettings{
+		Host:        get("smtp_host"),
+		Username:    get("smtp_username"),
+		Password:    d.decryptSensitiveData(get("smtp_password")),
+		From:        get("smtp_from"),
+		LinkBaseURL: get("email_link_base_url"),
+	}
+	settings.Port, _ = strconv.Atoi(get("smtp_port"))
+	return settings
+}
+
+// SetSMTPSettings 保存 SMTP 设置；keepPassword 为 true 时保留已保存的密码
+func (d *Database) SetSMTPSettings(settings SMTPSettings, keepPassword bool) error {
+	if settings.Port < 0 || settings.Port > 65535 {
+		return fmt.Errorf("无效的 SMTP 端口: %d", settings.Port)
+	}
+	values := map[string]string{
+		"smtp_host":           strings.TrimSpace(settings.Host),
+		"smtp_port":           strconv.Itoa(settings.Port),
+		"smtp_username":       strings.TrimSpace(settings.Username),
+		"smtp_from":           strings.TrimSpace(settings.From),
+		"email_link_base_url": strings.TrimRight(strings.TrimSpace(settings.LinkBaseURL), "/"),
+	}
+	if !keepPassword {
+		values["smtp_password"] = d.encryptSensitiveData(settings.Password)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+	for key, value := range values {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO system_config (key, value) VALUES (?, ?)`, key, value); err != nil {
+			return fmt.Errorf("保存 %s 失败: %w", key, err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 交易员标签限制
+const (
+	MaxTraderTags   = 10
+	MaxTraderTagLen = 32
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ParseTags 解析逗号分隔的标签（去空白、转小写、去重，不做格式校验）
+func ParseTags(raw string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		tag := strings.ToLower(strings.TrimSpace(part))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// NormalizeTags 校验并规范化标签，返回逗号分隔的标签字符串
+// 标签只能包含小写字母、数字、-、_（以字母或数字开头），每个不超过32个字符，最多10个
+func NormalizeTags(raw string) (string, error) {
+	tags := ParseTags(raw)
+	if len(tags) > MaxTraderTags {
+		return "", fmt.Errorf("标签最多 %d 个", MaxTraderTags)
+	}
+	for _, tag := range tags {
+		if len(tag) > MaxTraderTagLen {
+			return "", fmt.Errorf("标签 %s 超过 %d 个字符", tag, MaxTraderTagLen)
+		}
+		if !tagPattern.MatchString(tag) {
+			return "", fmt.Errorf("标签 %s 格式无效（只能包含小写字母、数字、-、_，且以字母或数字开头）", tag)
+		}
+	}
+	return strings.Join(tags, ","), nil
+}
+
+// HasTag 交易员是否带有指定标签（大小写不敏感）
+func (t *TraderRecord) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, existing := range ParseTags(t.Tags) {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TagSummary 同一标签下所有交易员的汇总指标（基于 trade_history）
+type TagSummary struct {
+	Tag            string   `json:"tag"`
+	TraderIDs      []string `json:"trader_ids"`
+	TraderCount    int      `json:"trader_count"`
+	InitialBalance float64  `json:"initial_balance"`
+	OpenedTrades   int      `json:"opened_trades"`
+	ClosedTrades   int      `json:"closed_trades"`
+	WinningTrades  int      `json:"winning_trades"`
+	LosingTrades   int      `json:"losing_trades"`
+	WinRate        float64  `json:"win_rate"` // 胜率（%）
+	RealizedPnL    float64  `json:"realized_pnl"`
+	ReturnPct      float64  `json:"return_pct"` // 已实现盈亏 / 初始余额合计（%）
+}
+
+// SummarizeByTag 按标签汇总用户交易员的历史交易表现（一个交易员有多个标签时分别计入每个标签）
+// filterTag 非空时只返回该标签；没有标签的交易员不计入
+func (d *Database) SummarizeByTag(userID, filterTag string) ([]*TagSummary, error) {
+	traders, err := d.GetTraders(userID)
+	if err != nil {
+		return nil, err
+	}
+	filterTag = strings.ToLower(strings.TrimSpace(filterTag))
+
+	end := time.Now().Add(time.Second)
+	byTag := make(map[string]*TagSummary)
+	for _, trader := range traders {
+		tags := ParseTags(trader.Tags)
+		if len(tags) == 0 || (filterTag != "" && !trader.HasTag(filterTag)) {
+			continue
+		}
+
+		trades, err := d.SummarizeTrades(trader.ID, time.UnixMilli(0), end)
+		if err != nil {
+			return nil, fmt.Errorf("统计交易员 %s 失败: %w", trader.ID, err)
+		}
+		for _, tag := range tags {
+			if filterTag != "" && tag != filterTag {
+				continue
+			}
+			summary := byTag[tag]
+			if summary == nil {
+				summary = &TagSummary{Tag: tag, TraderIDs: make([]string, 0)}
+				byTag[tag] = summary
+			}
+			summary.TraderIDs = append(summary.TraderIDs, trader.ID)
+			summary.TraderCount++
+			summary.InitialBalance += trader.InitialBalance
+			summary.OpenedTrades += trades.OpenedTrades
+			summary.ClosedTrades += trades.ClosedTrades
+			summary.WinningTrades += trades.WinningTrades
+			summary.LosingTrades += trades.LosingTrades
+			summary.RealizedPnL += trades.RealizedPnL
+		}
+	}
+
+	result := make([]*TagSummary, 0, len(byTag))
+	for _, summary := range byTag {
+		if decided := summary.WinningTrades + summary.LosingTrades; decided > 0 {
+			summary.WinRate = float64(summary.WinningTrades) / float64(decided) * 100
+		}
+		if summary.InitialBalance > 0 {
+			summary.ReturnPct = summary.RealizedPnL / summary.InitialBalance * 100
+		}
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tag < result[j].Tag })
+	return result, nil
+}
@@ -361,6 +361,41 @@ func setupTestDBForTimeframes(t *testing.T) (*Database, func()) {
 			limit_price_offset REAL DEFAULT -0.03,
 			limit_timeout_seconds INTEGER DEFAULT 60,
 			timeframes TEXT DEFAULT '4h',
+			portfolio_group TEXT DEFAULT '',
+			max_trades_per_day INTEGER DEFAULT 0,
+			model_pool TEXT DEFAULT '',
+			model_pool_mode TEXT DEFAULT 'round_robin',
+			fallback_ai_model_id TEXT DEFAULT '',
+			log_level TEXT DEFAULT '',
+			hold_cache_pct REAL DEFAULT 0,
+			start_priority INTEGER DEFAULT 0,
+			max_exposure_multiple REAL DEFAULT 0,
+			respect_signal_bias BOOLEAN DEFAULT 0,
+			dry_run BOOLEAN DEFAULT 0,
+			alert_drawdown_pct REAL DEFAULT 0,
+			alert_daily_loss_pct REAL DEFAULT 0,
+			ai_quality_window INTEGER DEFAULT 0,
+			ai_quality_max_failure_pct REAL DEFAULT 0,
+			ai_quality_pause_minutes INTEGER DEFAULT 0,
+			daily_report BOOLEAN DEFAULT 0,
+			unfunded_threshold REAL DEFAULT 0,
+			tags TEXT DEFAULT '',
+			max_ai_calls_per_day INTEGER DEFAULT 0,
+			reject_non_candidates BOOLEAN DEFAULT 0,
+			open_verify_delay_ms INTEGER DEFAULT 0,
+			active_hours TEXT DEFAULT '',
+			weekend_trading BOOLEAN DEFAULT 1,
+			flatten_on_window_close BOOLEAN DEFAULT 0,
+			max_positions INTEGER DEFAULT 0,
+			max_position_size_usd REAL DEFAULT 0,
+			blacklisted_symbols TEXT DEFAULT '',
+			kline_limit INTEGER DEFAULT 0,
+			indicators TEXT DEFAULT '',
+			max_consecutive_errors INTEGER DEFAULT 0,
+			max_daily_loss REAL DEFAULT NULL,
+			max_drawdown REAL DEFAULT NULL,
+			stop_trading_minutes INTEGER DEFAULT NULL,
+			deleted_at DATETIME DEFAULT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
@@ -373,6 +408,36 @@ func setupTestDBForTimeframes(t *testing.T) (*Database, func()) {
 		       custom_prompt, override_base_prompt, system_prompt_template,
 		       is_cross_margin, taker_fee_rate, maker_fee_rate, order_strategy,
 		       limit_price_offset, limit_timeout_seconds, timeframes,
+		       COALESCE(portfolio_group, ''),
+		       COALESCE(max_trades_per_day, 0),
+		       COALESCE(model_pool, ''),
+		       COALESCE(model_pool_mode, 'round_robin'),
+		       COALESCE(fallback_ai_model_id, ''),
+		       COALESCE(log_level, ''),
+		       COALESCE(hold_cache_pct, 0),
+		       COALESCE(start_priority, 0),
+		       COALESCE(max_exposure_multiple, 0),
+		       COALESCE(respect_signal_bias, 0),
+		       COALESCE(dry_run, 0),
+		       COALESCE(alert_drawdown_pct, 0),
+		       COALESCE(alert_daily_loss_pct, 0),
+		       COALESCE(ai_quality_window, 0),
+		       COALESCE(ai_quality_max_failure_pct, 0),
+		       COALESCE(ai_quality_pause_minutes, 0),
+		       COALESCE(daily_report, 0),
+		       COALESCE(unfunded_threshold, 0),
+		       COALESCE(tags, ''),
+		       COALESCE(max_ai_calls_per_day, 0),
+		       COALESCE(reject_non_candidates, 0),
+		       COALESCE(open_verify_delay_ms, 0),
+		       COALESCE(active_hours, ''),
+		       COALESCE(weekend_trading, 1),
+		       COALESCE(flatten_on_window_close, 0),
+		       COALESCE(max_positions, 0),
+		       COALESCE(max_position_size_usd, 0),
+		       COALESCE(blacklisted_symbols, ''),
+		       max_daily_loss, max_drawdown, stop_trading_minutes,
+		       deleted_at,
 		       COALESCE(created_at, CURRENT_TIMESTAMP), COALESCE(updated_at, CURRENT_TIMESTAMP)
 		FROM traders;
 		DROP TABLE traders;
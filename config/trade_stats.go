@@ -0,0 +1,169 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// tradeCloseActions trade_history 中表示平仓的动作（与开仓配对计算已实现盈亏）
+const tradeCloseActions = `'CLOSE', 'PARTIAL_CLOSE', 'EMERGENCY_CLOSE', 'AUTO_CLOSE', 'MANUAL_CLOSE'`
+
+// tradeOpenActions trade_history 中表示开仓的动作（EXTERNAL_OPEN 为对账时接管的交易所外部持仓）
+const tradeOpenActions = `'OPEN', 'EXTERNAL_OPEN'`
+
+// closedQtyEpsilon 剩余数量小于该值视为已全部平仓
+const closedQtyEpsilon = 1e-9
+
+// SymbolTradeStats 单个币种的已平仓交易统计
+type SymbolTradeStats struct {
+	Symbol      string  `json:"symbol"`
+	Trades      int     `json:"trades"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	WinRate     float64 `json:"win_rate"` // 胜率（%）
+	RealizedPnL float64 `json:"realized_pnl"`
+}
+
+// TradeStats 基于 trade_history 实际成交计算的已平仓交易统计
+// 一笔交易 = 一次开仓到完全平仓（分批平仓合并为一笔，交易所自动平仓同样计入）
+type TradeStats struct {
+	Since                time.Time           `json:"since,omitempty"` // 统计起点（零值表示全部历史）
+	Trades               int                 `json:"trades"`          // 已完全平仓的交易笔数
+	Wins                 int                 `json:"wins"`
+	Losses               int                 `json:"losses"`
+	Breakeven            int                 `json:"breakeven"`
+	WinRate              float64             `json:"win_rate"` // 胜率（%）= 盈利笔数 / (盈利 + 亏损笔数)，不含持平
+	RealizedPnL          float64             `json:"realized_pnl"`
+	GrossProfit          float64             `json:"gross_profit"`
+	GrossLoss            float64             `json:"gross_loss"` // 亏损合计（正数）
+	AvgWin               float64             `json:"avg_win"`
+	AvgLoss              float64             `json:"avg_loss"`      // 平均亏损（正数）
+	ProfitFactor         *float64            `json:"profit_factor"` // 盈利合计/亏损合计，没有亏损时为 null
+	MaxConsecutiveLosses int                 `json:"max_consecutive_losses"`
+	OpenPositions        int                 `json:"open_positions"` // 尚未完全平仓的持仓数（不计入统计）
+	BySymbol             []*SymbolTradeStats `json:"by_symbol"`      // 按已实现盈亏从高到低排序
+}
+
+// roundTrip 一次开仓到平仓的完整交易
+type roundTrip struct {
+	symbol    string
+	remaining float64
+	pnl       float64
+	closedAt  int64
+}
+
+// GetTradeStats 统计交易员自 since 以来平仓的交易（since 为零值时统计全部历史）
+// 配对方式与 GetLastOpenTrade 一致：同一币种同一方向，平仓记录配对此前的开仓记录；
+// PARTIAL_CLOSE 按数量递减，其余平仓动作视为全部平仓。since 之前开仓、之后平仓的交易也计入
+func (d *Database) GetTradeStats(traderID string, since time.Time) (*TradeStats, error) {
+	rows, err := d.db.Query(`
+		SELECT symbol, side, action, quantity, COALESCE(pnl, 0), timestamp
+		FROM trade_history
+		WHERE trader_id = ? AND action IN (`+tradeOpenActions+`, `+tradeCloseActions+`)
+		ORDER BY timestamp, id
+	`, traderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	open := make(map[string]*roundTrip) // symbol_side -> 未平仓的交易
+	var closed []*roundTrip
+	for rows.Next() {
+		var symbol, side, action string
+		var quantity, pnl float64
+		var timestamp int64
+		if err := rows.Scan(&symbol, &side, &action, &quantity, &pnl, &timestamp); err != nil {
+			return nil, err
+		}
+		key := symbol + "_" + strings.ToUpper(side)
+		trip := open[key]
+
+		if action == "OPEN" || action == "EXTERNAL_OPEN" {
+			if trip == nil {
+				trip = &roundTrip{symbol: symbol}
+				open[key] = trip
+			}
+			trip.remaining += quantity
+			continue
+		}
+
+		// 没有配对开仓的平仓记录（例如开仓记录早于 trade_history 启用）单独计为一笔
+		if trip == nil {
+			trip = &roundTrip{symbol: symbol}
+		}
+		trip.pnl += pnl
+		trip.remaining -= quantity
+		if action != "PARTIAL_CLOSE" || trip.remaining <= closedQtyEpsilon {
+			trip.closedAt = timestamp
+			closed = append(closed, trip)
+			delete(open, key)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := &TradeStats{Since: since, OpenPositions: len(open), BySymbol: make([]*SymbolTradeStats, 0)}
+	bySymbol := make(map[string]*SymbolTradeStats)
+	consecutiveLosses := 0
+	for _, trip := range closed {
+		if !since.IsZero() && trip.closedAt < since.UnixMilli() {
+			continue
+		}
+		item := bySymbol[trip.symbol]
+		if item == nil {
+			item = &SymbolTradeStats{Symbol: trip.symbol}
+			bySymbol[trip.symbol] = item
+			stats.BySymbol = append(stats.BySymbol, item)
+		}
+		stats.Trades++
+		item.Trades++
+		stats.RealizedPnL += trip.pnl
+		item.RealizedPnL += trip.pnl
+
+		switch {
+		case trip.pnl > 0:
+			stats.Wins++
+			item.Wins++
+			stats.GrossProfit += trip.pnl
+			consecutiveLosses = 0
+		case trip.pnl < 0:
+			stats.Losses++
+			item.Losses++
+			stats.GrossLoss -= trip.pnl
+			consecutiveLosses++
+			if consecutiveLosses > stats.MaxConsecutiveLosses {
+				stats.MaxConsecutiveLosses = consecutiveLosses
+			}
+		default:
+			stats.Breakeven++
+		}
+	}
+
+	stats.WinRate = winRate(stats.Wins, stats.Losses)
+	if stats.Wins > 0 {
+		stats.AvgWin = stats.GrossProfit / float64(stats.Wins)
+	}
+	if stats.Losses > 0 {
+		stats.AvgLoss = stats.GrossLoss / float64(stats.Losses)
+		factor := stats.GrossProfit / stats.GrossLoss
+		stats.ProfitFactor = &factor
+	}
+	for _, item := range stats.BySymbol {
+		item.WinRate = winRate(item.Wins, item.Losses)
+	}
+	sort.SliceStable(stats.BySymbol, func(i, j int) bool {
+		return stats.BySymbol[i].RealizedPnL > stats.BySymbol[j].RealizedPnL
+	})
+	return stats, nil
+}
+
+// winRate 胜率（%），与 SummarizeTrades 一致不计入持平的交易
+func winRate(wins, losses int) float64 {
+	if wins+losses == 0 {
+		return 0
+	}
+	return float64(wins) / float64(wins+losses) * 100
+}
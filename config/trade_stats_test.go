@@ -0,0 +1,107 @@
+package config
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestGetTradeStats 测试按开平仓配对统计胜率、盈亏比和连续亏损（分批平仓合并为一笔）
+func TestGetTradeStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	insert := func(minutes int, symbol, side, action string, qty, pnl float64) {
+		t.Helper()
+		_, err := db.db.Exec(`INSERT INTO trade_history (trader_id, user_id, symbol, side, action, quantity, price, timestamp, pnl)
+			VALUES ('trader-1', 'user-1', ?, ?, ?, ?, 100, ?, ?)`,
+			symbol, side, action, qty, base.Add(time.Duration(minutes)*time.Minute).UnixMilli(), pnl)
+		if err != nil {
+			t.Fatalf("写入交易记录失败: %v", err)
+		}
+	}
+
+	insert(0, "BTCUSDT", "LONG", "OPEN", 1, 0)
+	insert(10, "BTCUSDT", "LONG", "CLOSE", 1, 50) // 盈利
+	insert(20, "ETHUSDT", "SHORT", "OPEN", 2, 0)
+	insert(30, "ETHUSDT", "SHORT", "PARTIAL_CLOSE", 1, -10)
+	insert(40, "ETHUSDT", "SHORT", "AUTO_CLOSE", 1, -20) // 分批平仓合并为一笔亏损 -30
+	insert(50, "BTCUSDT", "SHORT", "OPEN", 1, 0)
+	insert(60, "BTCUSDT", "SHORT", "EMERGENCY_CLOSE", 1, -10) // 连续第二笔亏损
+	insert(70, "SOLUSDT", "LONG", "OPEN", 5, 0)
+	insert(80, "SOLUSDT", "LONG", "PARTIAL_CLOSE", 2, 5) // 未完全平仓，不计入
+
+	stats, err := db.GetTradeStats("trader-1", time.Time{})
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	if stats.Trades != 3 || stats.Wins != 1 || stats.Losses != 2 || stats.OpenPositions != 1 {
+		t.Fatalf("期望 3 笔交易(1胜2负, 1个持仓), 实际 %d 笔(%d胜%d负, %d个持仓)", stats.Trades, stats.Wins, stats.Losses, stats.OpenPositions)
+	}
+	if math.Abs(stats.WinRate-100.0/3) > 1e-9 || stats.RealizedPnL != 10 {
+		t.Errorf("胜率/已实现盈亏不正确: %.4f%% / %.2f", stats.WinRate, stats.RealizedPnL)
+	}
+	if stats.AvgWin != 50 || stats.AvgLoss != 20 || stats.ProfitFactor == nil || *stats.ProfitFactor != 1.25 {
+		t.Errorf("平均盈亏/盈亏比不正确: %.2f / %.2f / %v", stats.AvgWin, stats.AvgLoss, stats.ProfitFactor)
+	}
+	if stats.MaxConsecutiveLosses != 2 {
+		t.Errorf("最大连续亏损应为 2, 实际 %d", stats.MaxConsecutiveLosses)
+	}
+	if len(stats.BySymbol) != 2 || stats.BySymbol[0].Symbol != "BTCUSDT" || stats.BySymbol[0].RealizedPnL != 40 {
+		t.Errorf("按币种统计不正确: %+v", stats.BySymbol)
+	}
+
+	// since 之后平仓的交易才计入
+	recent, err := db.GetTradeStats("trader-1", base.Add(35*time.Minute))
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	if recent.Trades != 2 || recent.Wins != 0 || recent.ProfitFactor == nil || *recent.ProfitFactor != 0 {
+		t.Errorf("since 过滤后应只有 2 笔亏损交易, 实际 %+v", recent)
+	}
+}
+
+// TestExternalOpenCountsAsOpen 测试对账接管的 EXTERNAL_OPEN 与普通开仓一样参与持仓重建和平仓配对
+func TestExternalOpenCountsAsOpen(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.RecordTrade("trader-1", "user-1", "ETHUSDT", "SHORT", "EXTERNAL_OPEN", 2, 3000, "对账接管", 0, 0, 0, 0); err != nil {
+		t.Fatalf("写入外部开仓失败: %v", err)
+	}
+	if err := db.RecordTrade("trader-1", "user-1", "BTCUSDT", "LONG", "OPEN", 1, 50000, "", 0, 0, 0, 0); err != nil {
+		t.Fatalf("写入开仓失败: %v", err)
+	}
+
+	positions, err := db.GetOpenPositionsFromHistory("trader-1")
+	if err != nil {
+		t.Fatalf("重建持仓失败: %v", err)
+	}
+	eth := positions["ETHUSDT_SHORT"]
+	if eth == nil || eth["quantity"] != 2.0 || eth["entry_price"] != 3000.0 || eth["external"] != true {
+		t.Fatalf("外部持仓未正确重建: %v", eth)
+	}
+	if btc := positions["BTCUSDT_LONG"]; btc == nil || btc["external"] != false {
+		t.Errorf("普通持仓不应标记为外部持仓: %v", btc)
+	}
+
+	time.Sleep(2 * time.Millisecond) // 平仓配对要求平仓时间晚于开仓
+	if err := db.RecordTrade("trader-1", "user-1", "ETHUSDT", "SHORT", "CLOSE", 2, 2900, "", 0, 0, 200, 3.33); err != nil {
+		t.Fatalf("写入平仓失败: %v", err)
+	}
+	keys, err := db.GetOpenPositions("trader-1")
+	if err != nil {
+		t.Fatalf("获取未平仓失败: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "BTCUSDT_LONG" {
+		t.Errorf("外部持仓平仓后应只剩 BTCUSDT_LONG, 实际 %v", keys)
+	}
+	stats, err := db.GetTradeStats("trader-1", time.Time{})
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	if stats.Trades != 1 || stats.Wins != 1 || stats.OpenPositions != 1 {
+		t.Errorf("外部持仓平仓应计为一笔盈利交易: %+v", stats)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// traderStateWrite 一次待写入的交易员运行状态
+type traderStateWrite struct {
+	traderID      string
+	userID        string
+	callCount     int
+	peakEquity    float64
+	lastResetTime int64
+	stateJSON     string
+}
+
+// traderStateBatcher 合并高频的 trader_state 写入
+// 每个交易员每个周期都会保存状态，交易员多时大量小事务争抢 SQLite 写锁；
+// 这里同一交易员只保留最新状态，按固定间隔在单个事务中批量落盘
+type traderStateBatcher struct {
+	db       *Database
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]traderStateWrite
+
+	flushMu  sync.Mutex // 保证批次按顺序落盘，避免旧状态覆盖新状态
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// newTraderStateBatcher 创建并启动合并写入
+func newTraderStateBatcher(db *Database, interval time.Duration) *traderStateBatcher {
+	b := &traderStateBatcher{
+		db:       db,
+		interval: interval,
+		pending:  make(map[string]traderStateWrite),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// queue 放入待写队列（同一交易员覆盖旧状态）
+func (b *traderStateBatcher) queue(state traderStateWrite) {
+	b.mu.Lock()
+	b.pending[state.traderID] = state
+	b.mu.Unlock()
+}
+
+func (b *traderStateBatcher) loop() {
+	defer close(b.doneCh)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.stopCh:
+			b.flush()
+			return
+		}
+	}
+}
+
+// flush 立即写入所有待写状态；失败时放回队列（期间已有更新状态的交易员除外），下次重试
+func (b *traderStateBatcher) flush() {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	if len(b.pending) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	batch := make([]traderStateWrite, 0, len(b.pending))
+	for _, state := range b.pending {
+		batch = append(batch, state)
+	}
+	b.pending = make(map[string]traderStateWrite)
+	b.mu.Unlock()
+
+	if err := b.db.writeTraderStates(batch); err != nil {
+		log.Printf("❌ 批量保存交易員狀態失敗（%d 個，稍後重試）: %v", len(batch), err)
+		b.mu.Lock()
+		for _, state := range batch {
+			if _, newer := b.pending[state.traderID]; !newer {
+				b.pending[state.traderID] = state
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// stop 停止后台写入并落盘剩余状态
+func (b *traderStateBatcher) stop() {
+	b.stopOnce.Do(func() {
+		close(b.stopCh)
+		<-b.doneCh
+	})
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTraderTemplateNotFound 模板不存在（或不属于该用户）
+var ErrTraderTemplateNotFound = errors.New("交易员模板不存在")
+
+// ErrTraderTemplateNameTaken 同一用户下模板名称已存在（由 trader_templates(user_id, name) 唯一约束保证）
+var ErrTraderTemplateNameTaken = errors.New("交易员模板名称已存在")
+
+// TraderTemplate 交易员配置模板
+// Config 为 JSON，字段与创建交易员请求一致，不含名称、初始余额和交易所/模型绑定
+type TraderTemplate struct {
+	ID             string `json:"id"`
+	UserID         string `json:"user_id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Config         string `json:"config"`
+	SourceTraderID string `json:"source_trader_id"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// IsSystem 是否为系统模板（所有用户可见，不能删除）
+func (t *TraderTemplate) IsSystem() bool {
+	return t.UserID == "default"
+}
+
+// systemTraderTemplates 初始化时写入的系统模板
+var systemTraderTemplates = []TraderTemplate{
+	{
+		ID:          "system_conservative",
+		Name:        "Conservative",
+		Description: "低杠杆、限价优先、多时间线确认，适合大部分币种的稳健配置",
+		Config: `{"btc_eth_leverage":3,"altcoin_leverage":2,"scan_interval_minutes":5,"order_strategy":"conservative_hybrid",` +
+			`"timeframes":"15m,1h,4h","max_positions":3,"max_trades_per_day":6,"max_consecutive_errors":5}`,
+	},
+	{
+		ID:          "system_momentum",
+		Name:        "Momentum",
+		Description: "较高杠杆、市价成交、短周期扫描，跟随信号源方向",
+		Config: `{"btc_eth_leverage":10,"altcoin_leverage":5,"scan_interval_minutes":3,"order_strategy":"market_only",` +
+			`"timeframes":"5m,15m,1h","use_oi_top":true,"respect_signal_bias":true,"max_positions":5,"max_consecutive_errors":5}`,
+	},
+}
+
+// CreateTraderTemplate 保存交易员模板，同名时返回 ErrTraderTemplateNameTaken
+func (d *Database) CreateTraderTemplate(t *TraderTemplate) error {
+	_, err := d.db.Exec(`
+		INSERT INTO trader_templates (id, user_id, name, description, config, source_trader_id)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, t.ID, t.UserID, t.Name, t.Description, t.Config, t.SourceTraderID)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: trader_templates.user_id, trader_templates.name") {
+		return fmt.Errorf("%w: %s", ErrTraderTemplateNameTaken, t.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("保存交易员模板失败: %w", err)
+	}
+	return nil
+}
+
+// GetTraderTemplates 获取用户可用的交易员模板（系统模板在前，其余按创建时间排序）
+func (d *Database) GetTraderTemplates(userID string) ([]*TraderTemplate, error) {
+	rows, err := d.db.Query(`
+		SELECT id, user_id, name, description, config, source_trader_id, created_at
+		FROM trader_templates WHERE user_id IN (?, 'default')
+		ORDER BY user_id = 'default' DESC, created_at, name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]*TraderTemplate, 0)
+	for rows.Next() {
+		var t TraderTemplate
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Description, &t.Config, &t.SourceTraderID, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, &t)
+	}
+	return templates, rows.Err()
+}
+
+// GetTraderTemplate 获取用户自己的或系统的交易员模板
+func (d *Database) GetTraderTemplate(userID, id string) (*TraderTemplate, error) {
+	var t TraderTemplate
+	err := d.db.QueryRow(`
+		SELECT id, user_id, name, description, config, source_trader_id, created_at
+		FROM trader_templates WHERE id = ? AND user_id IN (?, 'default')
+	`, id, userID).Scan(&t.ID, &t.UserID, &t.Name, &t.Description, &t.Config, &t.SourceTraderID, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTraderTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// DeleteTraderTemplate 删除用户自己的交易员模板（系统模板不能通过该方法删除）
+func (d *Database) DeleteTraderTemplate(userID, id string) error {
+	result, err := d.db.Exec(`DELETE FROM trader_templates WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("删除交易员模板失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTraderTemplateNotFound
+	}
+	return nil
+}
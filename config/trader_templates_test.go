@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestTraderTemplates 测试交易员模板的保存、按用户可见性查询和删除
+func TestTraderTemplates(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// 系统模板在初始化时写入，配置为合法 JSON
+	templates, err := db.GetTraderTemplates("test-user-001")
+	if err != nil {
+		t.Fatalf("查询模板失败: %v", err)
+	}
+	if len(templates) != len(systemTraderTemplates) {
+		t.Fatalf("应只有 %d 个系统模板, 实际 %d", len(systemTraderTemplates), len(templates))
+	}
+	for _, tpl := range templates {
+		var cfg map[string]interface{}
+		if !tpl.IsSystem() || json.Unmarshal([]byte(tpl.Config), &cfg) != nil {
+			t.Errorf("系统模板无效: %+v", tpl)
+		}
+	}
+
+	tpl := &TraderTemplate{ID: "tpl_1", UserID: "test-user-001", Name: "standard", Config: `{"btc_eth_leverage":7}`, SourceTraderID: "t1"}
+	if err := db.CreateTraderTemplate(tpl); err != nil {
+		t.Fatalf("保存模板失败: %v", err)
+	}
+	dup := &TraderTemplate{ID: "tpl_2", UserID: "test-user-001", Name: "standard", Config: `{}`}
+	if err := db.CreateTraderTemplate(dup); !errors.Is(err, ErrTraderTemplateNameTaken) {
+		t.Errorf("同名模板应返回 ErrTraderTemplateNameTaken, 实际 %v", err)
+	}
+	// 不同用户可以使用相同名称
+	dup.UserID = "test-user-002"
+	if err := db.CreateTraderTemplate(dup); err != nil {
+		t.Errorf("其他用户保存同名模板失败: %v", err)
+	}
+
+	templates, _ = db.GetTraderTemplates("test-user-001")
+	if len(templates) != len(systemTraderTemplates)+1 || !templates[0].IsSystem() || templates[len(templates)-1].ID != "tpl_1" {
+		t.Errorf("应返回系统模板和自己的模板（系统模板在前）: %d", len(templates))
+	}
+
+	got, err := db.GetTraderTemplate("test-user-001", "tpl_1")
+	if err != nil || got.Config != tpl.Config || got.SourceTraderID != "t1" || got.CreatedAt == "" {
+		t.Errorf("读取模板不正确: %+v, err=%v", got, err)
+	}
+	if _, err := db.GetTraderTemplate("test-user-002", "tpl_1"); !errors.Is(err, ErrTraderTemplateNotFound) {
+		t.Errorf("其他用户不应读取到该模板, 实际 %v", err)
+	}
+	if _, err := db.GetTraderTemplate("test-user-002", "system_conservative"); err != nil {
+		t.Errorf("所有用户都应能读取系统模板: %v", err)
+	}
+
+	if err := db.DeleteTraderTemplate("test-user-002", "tpl_1"); !errors.Is(err, ErrTraderTemplateNotFound) {
+		t.Errorf("不能删除其他用户的模板, 实际 %v", err)
+	}
+	if err := db.DeleteTraderTemplate("test-user-001", "system_conservative"); !errors.Is(err, ErrTraderTemplateNotFound) {
+		t.Errorf("不能删除系统模板, 实际 %v", err)
+	}
+	if err := db.DeleteTraderTemplate("test-user-001", "tpl_1"); err != nil {
+		t.Errorf("删除模板失败: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package decision
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -31,6 +32,12 @@ var (
 	reDecisionTag  = regexp.MustCompile(`(?s)<decision>(.*?)</decision>`)
 )
 
+// 追踪止损回撤比例范围（%）
+const (
+	MinTrailingDistancePct = 0.1
+	MaxTrailingDistancePct = 20.0
+)
+
 // PositionInfo 持仓信息
 type PositionInfo struct {
 	Symbol           string  `json:"symbol"`
@@ -44,9 +51,14 @@ type PositionInfo struct {
 	PeakPnLPct       float64 `json:"peak_pnl_pct"` // 历史最高收益率（百分比）
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
-	UpdateTime       int64   `json:"update_time"`           // 持仓更新时间戳（毫秒）
-	StopLoss         float64 `json:"stop_loss,omitempty"`   // 止损价格（用于推断平仓原因）
-	TakeProfit       float64 `json:"take_profit,omitempty"` // 止盈价格（用于推断平仓原因）
+	UpdateTime       int64   `json:"update_time"`                 // 持仓更新时间戳（毫秒）
+	StopLoss         float64 `json:"stop_loss,omitempty"`         // 止损价格（用于推断平仓原因）
+	TakeProfit       float64 `json:"take_profit,omitempty"`       // 止盈价格（用于推断平仓原因）
+	TrailingStopPct  float64 `json:"trailing_stop_pct,omitempty"` // 追踪止损回撤比例（%，0=未设置）
+	TrailingStop     float64 `json:"trailing_stop,omitempty"`     // 追踪止损当前止损线
+	External         bool    `json:"external,omitempty"`          // 外部持仓（手动/其他程序开仓，对账时接管）
+	Note             string  `json:"note,omitempty"`              // 用户的人工备注（写入提示词供AI参考）
+	Locked           bool    `json:"locked,omitempty"`            // 用户锁定：执行层拒绝 close_long/close_short/partial_close
 }
 
 // OpenOrderInfo represents an open order for AI decision context
@@ -59,6 +71,33 @@ type OpenOrderInfo struct {
 	Quantity     float64 `json:"quantity"`      // Order quantity
 	Price        float64 `json:"price"`         // Limit order price (for limit orders)
 	StopPrice    float64 `json:"stop_price"`    // Trigger price (for stop-loss/take-profit orders)
+	ReduceOnly   bool    `json:"reduce_only"`   // Reduce-only order
+	CreatedAt    int64   `json:"created_at"`    // Order creation time (ms), 0 if unknown
+}
+
+// EntrySide 限价开仓单对应的持仓方向（long/short），止损/止盈单和平仓挂单返回空
+func (o OpenOrderInfo) EntrySide() string {
+	if !strings.EqualFold(o.Type, "LIMIT") {
+		return ""
+	}
+	side := strings.ToUpper(o.Side)
+	positionSide := strings.ToUpper(o.PositionSide)
+	switch {
+	case side == "BUY" && positionSide != "SHORT":
+		return "long"
+	case side == "SELL" && positionSide != "LONG":
+		return "short"
+	}
+	return ""
+}
+
+// OrderRejection 上一周期执行失败（交易所拒单或守卫拒绝）的决策，反馈给AI用于调整
+type OrderRejection struct {
+	Symbol     string `json:"symbol"`
+	Action     string `json:"action"`
+	Code       string `json:"code,omitempty"` // 守卫拒绝原因代码，交易所拒单为空
+	Reason     string `json:"reason"`         // 错误信息
+	MinutesAgo int    `json:"minutes_ago"`    // 距今分钟数
 }
 
 // AccountInfo 账户信息
@@ -75,8 +114,11 @@ type AccountInfo struct {
 
 // CandidateCoin 候选币种（来自币种池）
 type CandidateCoin struct {
-	Symbol  string   `json:"symbol"`
-	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"
+	Symbol           string   `json:"symbol"`
+	Sources          []string `json:"sources"`                      // 来源: "ai500" 和/或 "oi_top"
+	Bias             string   `json:"bias,omitempty"`               // 信号源方向偏好: "long"/"short"，空表示无偏好
+	SignalAgeSeconds int64    `json:"signal_age_seconds,omitempty"` // 信号源快照的数据年龄（多个来源取最旧的）
+	SignalStale      bool     `json:"signal_stale,omitempty"`       // 信号源刷新失败，使用的是旧快照
 }
 
 // OITopData 持仓量增长Top数据（用于AI决策参考）
@@ -91,42 +133,73 @@ type OITopData struct {
 
 // Context 交易上下文（传递给AI的完整信息）
 type Context struct {
-	CurrentTime     string                  `json:"current_time"`
-	RuntimeMinutes  int                     `json:"runtime_minutes"`
-	CallCount       int                     `json:"call_count"`
-	Account         AccountInfo             `json:"account"`
-	Positions       []PositionInfo          `json:"positions"`
-	OpenOrders      []OpenOrderInfo         `json:"open_orders"` // List of open orders for AI context
-	CandidateCoins  []CandidateCoin         `json:"candidate_coins"`
-	MarketDataMap   map[string]*market.Data `json:"-"` // 不序列化，但内部使用
-	OITopDataMap    map[string]*OITopData   `json:"-"` // OI Top数据映射
-	Performance     interface{}             `json:"-"` // 历史表现分析（logger.PerformanceAnalysis，包含 RecentTrades）
-	BTCETHLeverage  int                     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
-	AltcoinLeverage int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
-	TakerFeeRate    float64                 `json:"-"` // Taker fee rate (from config, default 0.0004)
-	MakerFeeRate    float64                 `json:"-"` // Maker fee rate (from config, default 0.0002)
-	Timeframes      []string                `json:"-"` // K线时间线配置（从trader配置读取）
+	CurrentTime      string                  `json:"current_time"`
+	RuntimeMinutes   int                     `json:"runtime_minutes"`
+	CallCount        int                     `json:"call_count"`
+	Account          AccountInfo             `json:"account"`
+	Positions        []PositionInfo          `json:"positions"`
+	OpenOrders       []OpenOrderInfo         `json:"open_orders"`                 // List of open orders for AI context
+	RecentRejections []OrderRejection        `json:"recent_rejections,omitempty"` // 上一周期执行失败的决策（反馈给AI调整）
+	CandidateCoins   []CandidateCoin         `json:"candidate_coins"`
+	MarketDataMap    map[string]*market.Data `json:"-"` // 不序列化，但内部使用
+	OITopDataMap     map[string]*OITopData   `json:"-"` // OI Top数据映射
+	Performance      interface{}             `json:"-"` // 历史表现分析（logger.PerformanceAnalysis，包含 RecentTrades）
+	TradeStats       *TradeStatsSummary      `json:"-"` // 基于实际成交（trade_history）的交易统计，nil 表示不显示
+	BTCETHLeverage   int                     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
+	AltcoinLeverage  int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
+	TakerFeeRate     float64                 `json:"-"` // Taker fee rate (from config, default 0.0004)
+	MakerFeeRate     float64                 `json:"-"` // Maker fee rate (from config, default 0.0002)
+	Timeframes       []string                `json:"-"` // K线时间线配置（从trader配置读取）
+	KlineLimit       int                     `json:"-"` // 每个时间线提供给AI的K线数量（0=默认行情格式）
+	Indicators       []string                `json:"-"` // 行情中计算的指标（空=默认行情格式）
+
+	// 交易员的硬性仓位上限（0=不限制），写入提示词，超出的开仓决策会被执行层拒绝
+	MaxPositions       int     `json:"-"` // 最多同时持仓数量
+	MaxPositionSizeUSD float64 `json:"-"` // 单笔开仓最大名义价值（USDT）
+
+	// 本周期所用AI模型的专属 System Prompt 前缀/后缀（为空表示不添加）
+	SystemPromptPrefix string `json:"-"`
+	SystemPromptSuffix string `json:"-"`
 
 	// ⚡ 新增：全局市場情緒數據（VIX 恐慌指數 + 美股狀態）
 	GlobalSentiment *market.MarketSentiment `json:"-"` // 全局風險情緒（免費來源：Yahoo Finance + Alpha Vantage）
 }
 
+// TradeStatsSummary 按实际成交统计的历史表现（分批平仓合并为一笔，包含交易所自动平仓）
+type TradeStatsSummary struct {
+	Period               string   // 统计范围描述，例如 "近30天"
+	Trades               int      // 已平仓交易笔数
+	Wins                 int      // 盈利笔数
+	Losses               int      // 亏损笔数
+	WinRate              float64  // 胜率（%）
+	RealizedPnL          float64  // 已实现盈亏（USDT）
+	AvgWin               float64  // 平均盈利（USDT）
+	AvgLoss              float64  // 平均亏损（USDT，正数）
+	ProfitFactor         *float64 // 盈亏比（盈利合计/亏损合计），没有亏损时为 nil
+	MaxConsecutiveLosses int      // 最大连续亏损笔数
+}
+
 // Decision AI的交易决策
 type Decision struct {
 	Symbol string `json:"symbol"`
-	Action string `json:"action"` // "open_long", "open_short", "close_long", "close_short", "update_stop_loss", "update_take_profit", "partial_close", "hold", "wait"
+	Action string `json:"action"` // "open_long", "open_short", "close_long", "close_short", "update_stop_loss", "update_take_profit", "set_trailing_stop", "partial_close", "cancel_order", "hold", "wait"
 
 	// 开仓参数
 	Leverage        int     `json:"leverage,omitempty"`
 	PositionSizeUSD float64 `json:"position_size_usd,omitempty"`
 	StopLoss        float64 `json:"stop_loss,omitempty"`
 	TakeProfit      float64 `json:"take_profit,omitempty"`
+	OrderType       string  `json:"order_type,omitempty"`  // "market" | "limit"，为空时使用交易员配置的下单策略
+	LimitPrice      float64 `json:"limit_price,omitempty"` // order_type=limit 时的挂单价格
 
 	// 调整参数（新增）
 	NewStopLoss     float64 `json:"new_stop_loss,omitempty"`    // 用于 update_stop_loss
 	NewTakeProfit   float64 `json:"new_take_profit,omitempty"`  // 用于 update_take_profit
 	ClosePercentage float64 `json:"close_percentage,omitempty"` // 用于 partial_close (0-100)
 
+	TrailingDistancePct float64 `json:"trailing_distance_pct,omitempty"` // 用于 set_trailing_stop：从最优价回撤的百分比
+	OrderID             int64   `json:"order_id,omitempty"`              // 用于 cancel_order：要撤销的限价开仓单（为空时撤销该币种全部未成交限价开仓单）
+
 	// 通用参数
 	Confidence int     `json:"confidence,omitempty"` // 信心度 (0-100)
 	RiskUSD    float64 `json:"risk_usd,omitempty"`   // 最大美元风险
@@ -142,8 +215,25 @@ type FullDecision struct {
 	Timestamp    time.Time  `json:"timestamp"`
 	// AIRequestDurationMs 记录 AI API 调用耗时（毫秒）方便排查延迟问题
 	AIRequestDurationMs int64 `json:"ai_request_duration_ms,omitempty"`
+	// PromptTemplate/PromptVersion 本次使用的提示词模板及其版本（版本为 0 表示未纳入版本管理）
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	PromptVersion  int    `json:"prompt_version,omitempty"`
+	// Usage 本次 AI 调用的 token 用量（服务商未返回时为 nil）
+	Usage *mcp.Usage `json:"usage,omitempty"`
 }
 
+// ErrAIUnavailable AI调用失败（服务商错误、超时）或返回了无法解析的响应，换用其他模型重试可能成功
+// 获取市场数据等与模型无关的失败不属于此类
+var ErrAIUnavailable = errors.New("AI未返回可用决策")
+
+// aiUnavailableError 保持原错误信息不变，同时满足 errors.Is(err, ErrAIUnavailable)
+type aiUnavailableError struct {
+	err error
+}
+
+func (e *aiUnavailableError) Error() string   { return e.err.Error() }
+func (e *aiUnavailableError) Unwrap() []error { return []error{e.err, ErrAIUnavailable} }
+
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
 func GetFullDecision(ctx *Context, mcpClient mcp.AIClient) (*FullDecision, error) {
 	return GetFullDecisionWithCustomPrompt(ctx, mcpClient, "", false, "")
@@ -170,15 +260,17 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, custo
 	}
 
 	// 2. 构建 System Prompt（固定规则）和 User Prompt（动态数据）
+	promptTemplate, promptVersion := resolvePromptTemplateVersion(templateName, overrideBase && customPrompt != "")
 	systemPrompt := buildSystemPromptWithCustom(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, customPrompt, overrideBase, templateName)
+	systemPrompt = applySystemPromptAffixes(systemPrompt, ctx.SystemPromptPrefix, ctx.SystemPromptSuffix)
 	userPrompt := buildUserPrompt(ctx)
 
 	// 3. 调用AI API（使用 system + user prompt）
 	aiCallStart := time.Now()
-	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
+	aiResponse, usage, err := mcpClient.CallWithUsage(systemPrompt, userPrompt)
 	aiCallDuration := time.Since(aiCallStart)
 	if err != nil {
-		return nil, fmt.Errorf("调用AI API失败: %w", err)
+		return nil, &aiUnavailableError{fmt.Errorf("调用AI API失败: %w", err)}
 	}
 
 	// 4. 解析AI响应
@@ -190,10 +282,13 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, custo
 		decision.SystemPrompt = systemPrompt // 保存系统prompt
 		decision.UserPrompt = userPrompt     // 保存输入prompt
 		decision.AIRequestDurationMs = aiCallDuration.Milliseconds()
+		decision.PromptTemplate = promptTemplate
+		decision.PromptVersion = promptVersion
+		decision.Usage = usage
 	}
 
 	if err != nil {
-		return decision, fmt.Errorf("解析AI响应失败: %w", err)
+		return decision, &aiUnavailableError{fmt.Errorf("解析AI响应失败: %w", err)}
 	}
 
 	decision.Timestamp = time.Now()
@@ -245,7 +340,10 @@ func fetchMarketDataForContext(ctx *Context) error {
 		wg.Add(1)
 		go func(sym string) {
 			defer wg.Done()
-			data, err := market.Get(sym, ctx.Timeframes)
+			data, err := market.GetWithOptions(sym, ctx.Timeframes, market.DataOptions{
+				KlineLimit: ctx.KlineLimit,
+				Indicators: ctx.Indicators,
+			})
 			resultChan <- marketDataResult{symbol: sym, data: data, err: err}
 		}(symbol)
 	}
@@ -379,15 +477,43 @@ func buildSystemPromptWithCustom(accountEquity float64, btcEthLeverage, altcoinL
 	return sb.String()
 }
 
+// applySystemPromptAffixes 在 System Prompt 前后添加模型专属的前缀/后缀（用于针对不同模型微调措辞）
+func applySystemPromptAffixes(systemPrompt, prefix, suffix string) string {
+	if prefix = strings.TrimSpace(prefix); prefix != "" {
+		systemPrompt = prefix + "\n\n" + systemPrompt
+	}
+	if suffix = strings.TrimSpace(suffix); suffix != "" {
+		systemPrompt = systemPrompt + "\n\n" + suffix
+	}
+	return systemPrompt
+}
+
+// resolvePromptTemplateVersion 返回实际使用的提示词模板名称和版本号（与 buildSystemPrompt 的回退规则一致）
+// 自定义提示词完全覆盖模板时不使用模板，返回空
+func resolvePromptTemplateVersion(templateName string, customOnly bool) (string, int) {
+	if customOnly {
+		return "", 0
+	}
+	if templateName == "" {
+		templateName = "default"
+	}
+	template, err := GetPromptTemplate(templateName)
+	if err != nil {
+		if template, err = GetPromptTemplate("default"); err != nil {
+			return "", 0
+		}
+	}
+	return template.Name, template.Version
+}
+
 // buildSystemPrompt 构建 System Prompt（使用模板+动态部分）
 func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int, templateName string) string {
-	var sb strings.Builder
-
 	// 1. 加载提示词模板（核心交易策略部分）
 	if templateName == "" {
 		templateName = "default" // 默认使用 default 模板
 	}
 
+	var templateContent string
 	template, err := GetPromptTemplate(templateName)
 	if err != nil {
 		// 如果模板不存在，记录错误并使用 default
@@ -396,16 +522,23 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 		if err != nil {
 			// 如果连 default 都不存在，使用内置的简化版本
 			log.Printf("❌ 无法加载任何提示词模板，使用内置简化版本")
-			sb.WriteString("你是专业的加密货币交易AI。请根据市场数据做出交易决策。\n\n")
+			templateContent = "你是专业的加密货币交易AI。请根据市场数据做出交易决策。"
 		} else {
-			sb.WriteString(template.Content)
-			sb.WriteString("\n\n")
+			templateContent = template.Content
 		}
 	} else {
-		sb.WriteString(template.Content)
-		sb.WriteString("\n\n")
+		templateContent = template.Content
 	}
 
+	return renderSystemPrompt(templateContent, accountEquity, btcEthLeverage, altcoinLeverage)
+}
+
+// renderSystemPrompt 用模板内容渲染 System Prompt（模板 + 动态生成的风控约束和输出格式）
+func renderSystemPrompt(templateContent string, accountEquity float64, btcEthLeverage, altcoinLeverage int) string {
+	var sb strings.Builder
+	sb.WriteString(templateContent)
+	sb.WriteString("\n\n")
+
 	// 2. 硬约束（风险控制）- 动态生成
 	sb.WriteString("# 硬约束（风险控制）\n\n")
 	sb.WriteString("1. 风险回报比: 必须 ≥ 1:3（冒1%风险，赚3%+收益）\n")
@@ -459,12 +592,17 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("]\n```\n")
 	sb.WriteString("</decision>\n\n")
 	sb.WriteString("## 字段说明\n\n")
-	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | update_stop_loss | update_take_profit | partial_close | hold | wait\n")
+	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | update_stop_loss | update_take_profit | set_trailing_stop | partial_close | cancel_order | hold | wait\n")
 	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
 	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
+	sb.WriteString("- 开仓时可选: order_type (market 立即成交 | limit 挂单等待)，limit 时必填 limit_price（须在止损和止盈之间，且接近当前价）；不填则使用系统默认下单策略\n")
+	sb.WriteString("- limit 挂单未成交时会保留在交易所（成交后自动设置止损止盈），下个周期显示在「未成交限价开仓单」中；行情变化不再适合入场时用 cancel_order 撤单\n")
+	sb.WriteString("- cancel_order 时可选: order_id（不填则撤销该币种全部未成交限价开仓单）\n")
 	sb.WriteString("- update_stop_loss 时必填: new_stop_loss (注意是 new_stop_loss，不是 stop_loss)\n")
 	sb.WriteString("- update_take_profit 时必填: new_take_profit (注意是 new_take_profit，不是 take_profit)\n")
-	sb.WriteString("- partial_close 时必填: close_percentage (0-100)\n\n")
+	sb.WriteString(fmt.Sprintf("- set_trailing_stop 时必填: trailing_distance_pct（%.1f-%.0f，如 2 表示价格从最优点回撤2%%时止损；止损线只会朝有利方向移动，替换原有止损）\n", MinTrailingDistancePct, MaxTrailingDistancePct))
+	sb.WriteString("- partial_close 时必填: close_percentage (0-100，占当前持仓数量的百分比，如 50 表示平掉一半；剩余仓位价值不足 10 USDT 时自动全部平仓)\n")
+	sb.WriteString("- partial_close 时建议同时给出 new_stop_loss / new_take_profit：平仓后系统按剩余数量重新设置止损止盈\n\n")
 	sb.WriteString("## 🛡️ 未成交挂单提醒\n\n")
 	sb.WriteString("在「当前持仓」部分，你会看到每个持仓的挂单状态：\n\n")
 	sb.WriteString("- 🛡️ **止损单**: 表示该持仓已有止损保护\n")
@@ -564,6 +702,16 @@ func buildUserPrompt(ctx *Context) string {
 				pos.EntryPrice, pos.MarkPrice, pos.Quantity, positionValue, pos.UnrealizedPnLPct, pos.UnrealizedPnL, pos.PeakPnLPct,
 				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
 
+			if pos.External {
+				sb.WriteString("   🧷 外部持仓：非你开仓（手动或其他程序下单，已接管），入场价为交易所记录的持仓均价\n")
+			}
+			if pos.Note != "" {
+				sb.WriteString(fmt.Sprintf("   📝 用户备注：%s\n", pos.Note))
+			}
+			if pos.Locked {
+				sb.WriteString("   🔒 用户已锁定：不要对该持仓给出 close/partial_close 决策（执行时会被拒绝），可以调整止损止盈\n")
+			}
+
 			// Display stop-loss/take-profit orders for this position to prevent duplicate orders
 			hasStopLoss := false
 
@@ -577,9 +725,16 @@ func buildUserPrompt(ctx *Context) string {
 					hasStopLoss = true
 				} else if order.Type == "TAKE_PROFIT_MARKET" || order.Type == "TAKE_PROFIT" {
 					sb.WriteString(fmt.Sprintf("   🎯 止盈单: %.4f (%s)\n", order.StopPrice, order.Side))
+				} else if order.Type == "TRAILING_STOP_MARKET" {
+					hasStopLoss = true
 				}
 			}
 
+			if pos.TrailingStopPct > 0 {
+				sb.WriteString(fmt.Sprintf("   🪢 追踪止损: 回撤%.2f%% | 当前止损线%.4f（随价格自动移动，无需重复设置）\n", pos.TrailingStopPct, pos.TrailingStop))
+				hasStopLoss = true
+			}
+
 			if !hasStopLoss {
 				sb.WriteString("   ⚠️ **该持仓没有止损保护！**\n")
 			}
@@ -596,6 +751,47 @@ func buildUserPrompt(ctx *Context) string {
 		sb.WriteString("当前持仓: 无\n\n")
 	}
 
+	// 未成交的限价开仓单（AI 上一周期挂出的回调入场单，可用 cancel_order 撤销）
+	var pendingEntries []OpenOrderInfo
+	for _, order := range ctx.OpenOrders {
+		if order.EntrySide() != "" {
+			pendingEntries = append(pendingEntries, order)
+		}
+	}
+	if len(pendingEntries) > 0 {
+		sb.WriteString("## ⏳ 未成交限价开仓单\n\n")
+		for _, order := range pendingEntries {
+			sb.WriteString(fmt.Sprintf("- %s %s 限价%.4f 数量%.4f（order_id: %d）\n",
+				order.Symbol, strings.ToUpper(order.EntrySide()), order.Price, order.Quantity, order.OrderID))
+		}
+		sb.WriteString("  → 入场理由已失效时请用 cancel_order 撤单；对同一币种重新开仓会替换原挂单\n\n")
+	}
+
+	// 硬性仓位上限（执行层强制，超出的开仓决策会被拒绝）
+	if ctx.MaxPositions > 0 || ctx.MaxPositionSizeUSD > 0 {
+		sb.WriteString("## 🚧 仓位上限（硬性限制）\n")
+		if ctx.MaxPositions > 0 {
+			sb.WriteString(fmt.Sprintf("- 最多同时持仓 %d 个（当前 %d 个）", ctx.MaxPositions, len(ctx.Positions)))
+			if len(ctx.Positions) >= ctx.MaxPositions {
+				sb.WriteString("，已满，不要开新仓")
+			}
+			sb.WriteString("\n")
+		}
+		if ctx.MaxPositionSizeUSD > 0 {
+			sb.WriteString(fmt.Sprintf("- 单笔开仓 position_size_usd 不得超过 %.2f USDT\n", ctx.MaxPositionSizeUSD))
+		}
+		sb.WriteString("\n")
+	}
+
+	// 上一周期执行失败的决策（让AI根据失败原因调整仓位/止损等参数）
+	if len(ctx.RecentRejections) > 0 {
+		sb.WriteString("## ⚠️ 最近执行失败的决策\n\n")
+		for _, r := range ctx.RecentRejections {
+			sb.WriteString(fmt.Sprintf("- %s %s（%d分钟前）: %s\n", r.Symbol, r.Action, r.MinutesAgo, r.Reason))
+		}
+		sb.WriteString("  → 请根据失败原因调整（如减小仓位、设置有效的止损价），不要原样重复相同的决策\n\n")
+	}
+
 	// 候选币种（完整市场数据）
 	sb.WriteString(fmt.Sprintf("## 候选币种 (%d个)\n\n", len(ctx.MarketDataMap)))
 	displayedCount := 0
@@ -612,6 +808,15 @@ func buildUserPrompt(ctx *Context) string {
 		} else if len(coin.Sources) == 1 && coin.Sources[0] == "oi_top" {
 			sourceTags = " (OI_Top持仓增长)"
 		}
+		switch coin.Bias {
+		case "long":
+			sourceTags += " [信号偏多]"
+		case "short":
+			sourceTags += " [信号偏空]"
+		}
+		if coin.SignalStale {
+			sourceTags += fmt.Sprintf(" [⚠️ 信号数据已过期：%d分钟前]", coin.SignalAgeSeconds/60)
+		}
 
 		// 使用FormatMarketData输出完整市场数据
 		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
@@ -620,6 +825,19 @@ func buildUserPrompt(ctx *Context) string {
 	}
 	sb.WriteString("\n")
 
+	// 历史表现（实际成交）
+	if ts := ctx.TradeStats; ts != nil && ts.Trades > 0 {
+		profitFactor := "∞（无亏损）"
+		if ts.ProfitFactor != nil {
+			profitFactor = fmt.Sprintf("%.2f", *ts.ProfitFactor)
+		}
+		sb.WriteString(fmt.Sprintf("## 📈 历史表现（%s实际成交）\n\n", ts.Period))
+		sb.WriteString(fmt.Sprintf("已平仓 %d 笔 | 胜率 %.1f%% (%d胜/%d负) | 已实现盈亏 %+.2f USDT\n",
+			ts.Trades, ts.WinRate, ts.Wins, ts.Losses, ts.RealizedPnL))
+		sb.WriteString(fmt.Sprintf("平均盈利 %+.2f | 平均亏损 %.2f | 盈亏比 %s | 最大连续亏损 %d 笔\n\n",
+			ts.AvgWin, -ts.AvgLoss, profitFactor, ts.MaxConsecutiveLosses))
+	}
+
 	// 夏普比率（直接传值，不要复杂格式化）
 	if ctx.Performance != nil {
 		// 直接从interface{}中提取SharpeRatio
@@ -1027,6 +1245,28 @@ func calculateMinPositionSize(symbol string, accountEquity float64) float64 {
 	return absoluteMinimum
 }
 
+// validateOrderType 验证开仓决策指定的订单类型（限价单的挂单价必须在止损和止盈之间）
+func validateOrderType(d *Decision) error {
+	switch d.OrderType {
+	case "", "market":
+		return nil
+	case "limit":
+	default:
+		return fmt.Errorf("无效的order_type: %s（仅支持 market 或 limit）", d.OrderType)
+	}
+
+	if d.LimitPrice <= 0 {
+		return fmt.Errorf("order_type=limit 时 limit_price 必须大于0")
+	}
+	if d.Action == "open_long" && (d.LimitPrice <= d.StopLoss || d.LimitPrice >= d.TakeProfit) {
+		return fmt.Errorf("做多限价 %.4f 必须在止损价 %.4f 和止盈价 %.4f 之间", d.LimitPrice, d.StopLoss, d.TakeProfit)
+	}
+	if d.Action == "open_short" && (d.LimitPrice >= d.StopLoss || d.LimitPrice <= d.TakeProfit) {
+		return fmt.Errorf("做空限价 %.4f 必须在止盈价 %.4f 和止损价 %.4f 之间", d.LimitPrice, d.TakeProfit, d.StopLoss)
+	}
+	return nil
+}
+
 // validateDecision 验证单个决策的有效性
 func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
 	// 验证action
@@ -1037,7 +1277,9 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		"close_short":        true,
 		"update_stop_loss":   true,
 		"update_take_profit": true,
+		"set_trailing_stop":  true,
 		"partial_close":      true,
+		"cancel_order":       true,
 		"hold":               true,
 		"wait":               true,
 	}
@@ -1095,6 +1337,9 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		if d.StopLoss <= 0 || d.TakeProfit <= 0 {
 			return fmt.Errorf("止损和止盈必须大于0")
 		}
+		if err := validateOrderType(d); err != nil {
+			return err
+		}
 
 		// 验证止损止盈的合理性
 		if d.Action == "open_long" {
@@ -1154,6 +1399,13 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		}
 	}
 
+	// 追踪止损验证
+	if d.Action == "set_trailing_stop" {
+		if d.TrailingDistancePct < MinTrailingDistancePct || d.TrailingDistancePct > MaxTrailingDistancePct {
+			return fmt.Errorf("追踪止损回撤比例必须在 %.1f%%-%.0f%% 之间: %.2f%%", MinTrailingDistancePct, MaxTrailingDistancePct, d.TrailingDistancePct)
+		}
+	}
+
 	// 部分平仓验证
 	if d.Action == "partial_close" {
 		if d.ClosePercentage <= 0 || d.ClosePercentage > 100 {
@@ -0,0 +1,239 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// PortfolioMember 组合模式中的单个成员（交易员ID + 该交易员的交易上下文）
+type PortfolioMember struct {
+	TraderID string
+	Context  *Context
+}
+
+// BuildPortfolioContext 将多个交易员的上下文合并为一个组合账户上下文
+// 账户数据求和（总敞口按合并账户计算），持仓/挂单合并，候选币种取并集，杠杆取最保守值
+func BuildPortfolioContext(members []PortfolioMember) (*Context, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("组合成员为空")
+	}
+
+	leader := members[0].Context
+	merged := &Context{
+		CurrentTime:     leader.CurrentTime,
+		RuntimeMinutes:  leader.RuntimeMinutes,
+		CallCount:       leader.CallCount,
+		Performance:     leader.Performance,
+		TradeStats:      leader.TradeStats,
+		BTCETHLeverage:  leader.BTCETHLeverage,
+		AltcoinLeverage: leader.AltcoinLeverage,
+		TakerFeeRate:    leader.TakerFeeRate,
+		MakerFeeRate:    leader.MakerFeeRate,
+		Timeframes:      leader.Timeframes,
+		KlineLimit:      leader.KlineLimit,
+		Indicators:      leader.Indicators,
+	}
+
+	seenCoins := make(map[string]int)
+	for _, m := range members {
+		ctx := m.Context
+		if ctx == nil {
+			return nil, fmt.Errorf("组合成员 %s 上下文为空", m.TraderID)
+		}
+
+		merged.Account.TotalEquity += ctx.Account.TotalEquity
+		merged.Account.AvailableBalance += ctx.Account.AvailableBalance
+		merged.Account.UnrealizedPnL += ctx.Account.UnrealizedPnL
+		merged.Account.TotalPnL += ctx.Account.TotalPnL
+		merged.Account.MarginUsed += ctx.Account.MarginUsed
+		merged.Account.PositionCount += ctx.Account.PositionCount
+
+		merged.Positions = append(merged.Positions, ctx.Positions...)
+		merged.OpenOrders = append(merged.OpenOrders, ctx.OpenOrders...)
+
+		// 候选币种取并集（合并来源）
+		for _, coin := range ctx.CandidateCoins {
+			if idx, ok := seenCoins[coin.Symbol]; ok {
+				existing := &merged.CandidateCoins[idx]
+				existing.Sources = mergeSources(existing.Sources, coin.Sources)
+				if coin.SignalAgeSeconds > existing.SignalAgeSeconds {
+					existing.SignalAgeSeconds = coin.SignalAgeSeconds
+				}
+				existing.SignalStale = existing.SignalStale || coin.SignalStale
+				continue
+			}
+			seenCoins[coin.Symbol] = len(merged.CandidateCoins)
+			merged.CandidateCoins = append(merged.CandidateCoins, CandidateCoin{
+				Symbol:           coin.Symbol,
+				Sources:          append([]string{}, coin.Sources...),
+				SignalAgeSeconds: coin.SignalAgeSeconds,
+				SignalStale:      coin.SignalStale,
+			})
+		}
+
+		// 杠杆取最保守值，手续费取最高值
+		if ctx.BTCETHLeverage > 0 && ctx.BTCETHLeverage < merged.BTCETHLeverage {
+			merged.BTCETHLeverage = ctx.BTCETHLeverage
+		}
+		if ctx.AltcoinLeverage > 0 && ctx.AltcoinLeverage < merged.AltcoinLeverage {
+			merged.AltcoinLeverage = ctx.AltcoinLeverage
+		}
+		if ctx.TakerFeeRate > merged.TakerFeeRate {
+			merged.TakerFeeRate = ctx.TakerFeeRate
+		}
+		if ctx.MakerFeeRate > merged.MakerFeeRate {
+			merged.MakerFeeRate = ctx.MakerFeeRate
+		}
+	}
+
+	if merged.Account.TotalEquity > 0 {
+		merged.Account.MarginUsedPct = merged.Account.MarginUsed / merged.Account.TotalEquity * 100
+	}
+	if base := merged.Account.TotalEquity - merged.Account.TotalPnL; base > 0 {
+		merged.Account.TotalPnLPct = merged.Account.TotalPnL / base * 100
+	}
+
+	return merged, nil
+}
+
+// mergeSources 合并候选币种来源（去重）
+func mergeSources(a, b []string) []string {
+	for _, src := range b {
+		found := false
+		for _, existing := range a {
+			if existing == src {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, src)
+		}
+	}
+	return a
+}
+
+// AssignPortfolioDecisions 将组合决策分配到各成员交易员
+// - 平仓/调整止损止盈/部分平仓：分配给持有该币种该方向仓位的所有成员
+// - 开仓：组合内已有该币种仓位（任意方向）则拒绝，避免成员间互相对冲；否则分配给可用余额最多的成员
+// - hold/wait：分配给第一个成员（组长）用于记录
+// 返回分配结果（traderID -> 决策列表）以及被拒绝的决策说明
+func AssignPortfolioDecisions(decisions []Decision, members []PortfolioMember) (map[string][]Decision, []string) {
+	assigned := make(map[string][]Decision)
+	var rejected []string
+	if len(members) == 0 {
+		return assigned, rejected
+	}
+
+	// 持仓归属：symbol -> side -> 成员ID列表
+	holders := make(map[string]map[string][]string)
+	available := make(map[string]float64, len(members))
+	for _, m := range members {
+		available[m.TraderID] = m.Context.Account.AvailableBalance
+		for _, pos := range m.Context.Positions {
+			if holders[pos.Symbol] == nil {
+				holders[pos.Symbol] = make(map[string][]string)
+			}
+			holders[pos.Symbol][pos.Side] = append(holders[pos.Symbol][pos.Side], m.TraderID)
+		}
+	}
+
+	for _, d := range decisions {
+		switch d.Action {
+		case "close_long", "close_short", "update_stop_loss", "update_take_profit", "set_trailing_stop", "partial_close":
+			side := "long"
+			if d.Action == "close_short" {
+				side = "short"
+			}
+			var owners []string
+			if d.Action == "close_long" || d.Action == "close_short" {
+				owners = holders[d.Symbol][side]
+			} else {
+				// 调整类决策未指定方向，分配给持有该币种的所有成员
+				owners = append(append([]string{}, holders[d.Symbol]["long"]...), holders[d.Symbol]["short"]...)
+			}
+			if len(owners) == 0 {
+				rejected = append(rejected, fmt.Sprintf("%s %s: 组合内没有对应持仓", d.Symbol, d.Action))
+				continue
+			}
+			for _, id := range owners {
+				assigned[id] = append(assigned[id], d)
+			}
+
+		case "cancel_order":
+			// 撤单分配给挂有该币种限价开仓单的成员
+			var owners []string
+			for _, m := range members {
+				for _, order := range m.Context.OpenOrders {
+					if order.Symbol == d.Symbol && order.EntrySide() != "" && (d.OrderID == 0 || order.OrderID == d.OrderID) {
+						owners = append(owners, m.TraderID)
+						break
+					}
+				}
+			}
+			if len(owners) == 0 {
+				rejected = append(rejected, fmt.Sprintf("%s %s: 组合内没有对应的未成交限价开仓单", d.Symbol, d.Action))
+				continue
+			}
+			for _, id := range owners {
+				assigned[id] = append(assigned[id], d)
+			}
+
+		case "open_long", "open_short":
+			if len(holders[d.Symbol]) > 0 {
+				rejected = append(rejected, fmt.Sprintf("%s %s: 组合内已有该币种仓位，拒绝重复/对冲开仓", d.Symbol, d.Action))
+				continue
+			}
+			target := pickMemberWithMostBalance(members, available)
+			if target == "" {
+				rejected = append(rejected, fmt.Sprintf("%s %s: 没有可分配的成员", d.Symbol, d.Action))
+				continue
+			}
+			assigned[target] = append(assigned[target], d)
+
+			// 预扣保证金，后续开仓分配给其他成员；同时登记持仓归属防止同周期重复开仓
+			leverage := d.Leverage
+			if leverage <= 0 {
+				leverage = 1
+			}
+			available[target] -= d.PositionSizeUSD / float64(leverage)
+			side := "long"
+			if d.Action == "open_short" {
+				side = "short"
+			}
+			if holders[d.Symbol] == nil {
+				holders[d.Symbol] = make(map[string][]string)
+			}
+			holders[d.Symbol][side] = append(holders[d.Symbol][side], target)
+
+		default:
+			leaderID := members[0].TraderID
+			assigned[leaderID] = append(assigned[leaderID], d)
+		}
+	}
+
+	for _, reason := range rejected {
+		log.Printf("🧩 组合决策未分配: %s", reason)
+	}
+	return assigned, rejected
+}
+
+// pickMemberWithMostBalance 选择可用余额最多的成员（余额相同时按ID排序保证稳定）
+func pickMemberWithMostBalance(members []PortfolioMember, available map[string]float64) string {
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.TraderID)
+	}
+	sort.Strings(ids)
+
+	best := ""
+	bestBalance := 0.0
+	for _, id := range ids {
+		if available[id] > bestBalance {
+			best = id
+			bestBalance = available[id]
+		}
+	}
+	return best
+}
@@ -0,0 +1,157 @@
+package decision
+
+import (
+	"testing"
+)
+
+func newPortfolioTestMembers() []PortfolioMember {
+	return []PortfolioMember{
+		{
+			TraderID: "trader_a",
+			Context: &Context{
+				Account: AccountInfo{TotalEquity: 1000, AvailableBalance: 600, MarginUsed: 400, PositionCount: 1},
+				Positions: []PositionInfo{
+					{Symbol: "BTCUSDT", Side: "long", MarginUsed: 400},
+				},
+				CandidateCoins:  []CandidateCoin{{Symbol: "BTCUSDT", Sources: []string{"ai500"}}},
+				BTCETHLeverage:  10,
+				AltcoinLeverage: 5,
+				TakerFeeRate:    0.0004,
+				MakerFeeRate:    0.0002,
+			},
+		},
+		{
+			TraderID: "trader_b",
+			Context: &Context{
+				Account: AccountInfo{TotalEquity: 2000, AvailableBalance: 1800, MarginUsed: 200, PositionCount: 1},
+				Positions: []PositionInfo{
+					{Symbol: "ETHUSDT", Side: "short", MarginUsed: 200},
+				},
+				CandidateCoins: []CandidateCoin{
+					{Symbol: "BTCUSDT", Sources: []string{"oi_top"}},
+					{Symbol: "SOLUSDT", Sources: []string{"ai500"}},
+				},
+				BTCETHLeverage:  5,
+				AltcoinLeverage: 3,
+				TakerFeeRate:    0.0005,
+				MakerFeeRate:    0.0002,
+			},
+		},
+	}
+}
+
+// TestBuildPortfolioContext 测试组合上下文合并
+func TestBuildPortfolioContext(t *testing.T) {
+	merged, err := BuildPortfolioContext(newPortfolioTestMembers())
+	if err != nil {
+		t.Fatalf("合并失败: %v", err)
+	}
+
+	if merged.Account.TotalEquity != 3000 || merged.Account.AvailableBalance != 2400 {
+		t.Errorf("账户汇总错误: equity=%.2f available=%.2f", merged.Account.TotalEquity, merged.Account.AvailableBalance)
+	}
+	if merged.Account.MarginUsed != 600 || merged.Account.MarginUsedPct != 20 {
+		t.Errorf("保证金汇总错误: used=%.2f pct=%.2f", merged.Account.MarginUsed, merged.Account.MarginUsedPct)
+	}
+	if len(merged.Positions) != 2 || merged.Account.PositionCount != 2 {
+		t.Errorf("持仓合并错误: %d", len(merged.Positions))
+	}
+
+	if len(merged.CandidateCoins) != 2 {
+		t.Fatalf("候选币种应去重为2个，实际 %d", len(merged.CandidateCoins))
+	}
+	if got := merged.CandidateCoins[0].Sources; len(got) != 2 {
+		t.Errorf("BTCUSDT 来源应合并为2个，实际 %v", got)
+	}
+
+	// 杠杆取最保守值，手续费取最高值
+	if merged.BTCETHLeverage != 5 || merged.AltcoinLeverage != 3 {
+		t.Errorf("杠杆应取最小值: btc=%d alt=%d", merged.BTCETHLeverage, merged.AltcoinLeverage)
+	}
+	if merged.TakerFeeRate != 0.0005 {
+		t.Errorf("Taker费率应取最高值: %f", merged.TakerFeeRate)
+	}
+
+	if _, err := BuildPortfolioContext(nil); err == nil {
+		t.Error("空成员应返回错误")
+	}
+}
+
+// TestAssignPortfolioDecisions 测试组合决策分配
+func TestAssignPortfolioDecisions(t *testing.T) {
+	members := newPortfolioTestMembers()
+
+	tests := []struct {
+		name         string
+		decisions    []Decision
+		wantAssigned map[string][]string // traderID -> actions
+		wantRejected int
+	}{
+		{
+			name:         "平仓分配给持仓成员",
+			decisions:    []Decision{{Symbol: "ETHUSDT", Action: "close_short"}},
+			wantAssigned: map[string][]string{"trader_b": {"close_short"}},
+		},
+		{
+			name:         "平仓方向不匹配被拒绝",
+			decisions:    []Decision{{Symbol: "ETHUSDT", Action: "close_long"}},
+			wantRejected: 1,
+		},
+		{
+			name:         "已有仓位时拒绝反向开仓",
+			decisions:    []Decision{{Symbol: "BTCUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 100}},
+			wantRejected: 1,
+		},
+		{
+			name:         "开仓分配给可用余额最多的成员",
+			decisions:    []Decision{{Symbol: "SOLUSDT", Action: "open_long", Leverage: 2, PositionSizeUSD: 200}},
+			wantAssigned: map[string][]string{"trader_b": {"open_long"}},
+		},
+		{
+			name: "预扣保证金后分配给下一个成员",
+			decisions: []Decision{
+				{Symbol: "SOLUSDT", Action: "open_long", Leverage: 1, PositionSizeUSD: 1500},
+				{Symbol: "XRPUSDT", Action: "open_long", Leverage: 1, PositionSizeUSD: 100},
+			},
+			wantAssigned: map[string][]string{"trader_b": {"open_long"}, "trader_a": {"open_long"}},
+		},
+		{
+			name: "同周期重复开仓被拒绝",
+			decisions: []Decision{
+				{Symbol: "SOLUSDT", Action: "open_long", Leverage: 2, PositionSizeUSD: 100},
+				{Symbol: "SOLUSDT", Action: "open_short", Leverage: 2, PositionSizeUSD: 100},
+			},
+			wantAssigned: map[string][]string{"trader_b": {"open_long"}},
+			wantRejected: 1,
+		},
+		{
+			name:         "hold/wait分配给组长",
+			decisions:    []Decision{{Symbol: "ALL", Action: "wait"}},
+			wantAssigned: map[string][]string{"trader_a": {"wait"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assigned, rejected := AssignPortfolioDecisions(tt.decisions, members)
+
+			if len(rejected) != tt.wantRejected {
+				t.Errorf("拒绝数量 = %d, 期望 %d (%v)", len(rejected), tt.wantRejected, rejected)
+			}
+			if len(assigned) != len(tt.wantAssigned) {
+				t.Fatalf("分配结果 = %v, 期望 %v", assigned, tt.wantAssigned)
+			}
+			for id, actions := range tt.wantAssigned {
+				got := assigned[id]
+				if len(got) != len(actions) {
+					t.Fatalf("%s 分配 %d 个决策, 期望 %d", id, len(got), len(actions))
+				}
+				for i, action := range actions {
+					if got[i].Action != action {
+						t.Errorf("%s 第%d个决策 = %s, 期望 %s", id, i, got[i].Action, action)
+					}
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package decision
 
 import (
+	"nofx/market"
 	"strings"
 	"testing"
 )
@@ -20,6 +21,8 @@ func TestPromptContainsAllValidActions(t *testing.T) {
 		"update_stop_loss",   // Issue #982: This was missing
 		"update_take_profit", // Issue #982: This was missing
 		"partial_close",      // Issue #982: This was missing
+		"set_trailing_stop",
+		"cancel_order",
 		"hold",
 		"wait",
 	}
@@ -33,7 +36,7 @@ func TestPromptContainsAllValidActions(t *testing.T) {
 	}
 
 	// Verify the action list appears in the field description
-	actionListPattern := "open_long | open_short | close_long | close_short | update_stop_loss | update_take_profit | partial_close | hold | wait"
+	actionListPattern := "open_long | open_short | close_long | close_short | update_stop_loss | update_take_profit | set_trailing_stop | partial_close | cancel_order | hold | wait"
 	if !strings.Contains(prompt, actionListPattern) {
 		t.Errorf("❌ Prompt does not contain the complete action list")
 		t.Logf("Expected pattern: %s", actionListPattern)
@@ -61,6 +64,7 @@ func TestValidateDecisionAcceptsAllActions(t *testing.T) {
 		"close_short",
 		"update_stop_loss",
 		"update_take_profit",
+		"set_trailing_stop",
 		"partial_close",
 		"hold",
 		"wait",
@@ -101,6 +105,9 @@ func TestValidateDecisionAcceptsAllActions(t *testing.T) {
 			if action == "partial_close" {
 				decision.ClosePercentage = 50
 			}
+			if action == "set_trailing_stop" {
+				decision.TrailingDistancePct = 2
+			}
 
 			err := validateDecision(&decision, 100.0, 5, 5)
 			if err != nil {
@@ -124,6 +131,7 @@ func TestPromptAndValidationInSync(t *testing.T) {
 		"close_short",
 		"update_stop_loss",
 		"update_take_profit",
+		"set_trailing_stop",
 		"partial_close",
 		"hold",
 		"wait",
@@ -143,3 +151,104 @@ func TestPromptAndValidationInSync(t *testing.T) {
 		t.Logf("✅ Prompt and validation are in sync with all %d actions", len(expectedActions))
 	}
 }
+
+// TestApplySystemPromptAffixes tests that model-specific prefix/suffix wrap the system prompt
+func TestApplySystemPromptAffixes(t *testing.T) {
+	base := buildSystemPrompt(100.0, 5, 5, "default")
+
+	if got := applySystemPromptAffixes(base, "", "  "); got != base {
+		t.Errorf("Empty affixes should leave the prompt unchanged")
+	}
+
+	got := applySystemPromptAffixes(base, "Respond in concise English.", "Think step by step.")
+	if !strings.HasPrefix(got, "Respond in concise English.\n\n") {
+		t.Errorf("Prefix should be prepended to the template")
+	}
+	if !strings.HasSuffix(got, "\n\nThink step by step.") {
+		t.Errorf("Suffix should be appended after the template")
+	}
+	if !strings.Contains(got, base) {
+		t.Errorf("Original prompt should be preserved between prefix and suffix")
+	}
+}
+
+// TestPromptListsPendingLimitEntries 测试未成交的限价开仓单出现在下一周期的 user prompt 中（止损止盈单不列出）
+func TestPromptListsPendingLimitEntries(t *testing.T) {
+	ctx := &Context{
+		OpenOrders: []OpenOrderInfo{
+			{Symbol: "ETHUSDT", OrderID: 42, Type: "LIMIT", Side: "BUY", PositionSide: "LONG", Price: 2950, Quantity: 0.5},
+			{Symbol: "ETHUSDT", OrderID: 43, Type: "STOP_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 2800},
+		},
+	}
+	prompt := buildUserPrompt(ctx)
+	if !strings.Contains(prompt, "未成交限价开仓单") || !strings.Contains(prompt, "order_id: 42") {
+		t.Errorf("user prompt 应列出未成交的限价开仓单:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "order_id: 43") {
+		t.Error("止损单不应作为限价开仓单列出")
+	}
+
+	if side := (OpenOrderInfo{Type: "LIMIT", Side: "SELL", PositionSide: "LONG"}).EntrySide(); side != "" {
+		t.Errorf("多仓的限价平仓单不是开仓单, 实际 %q", side)
+	}
+	if side := (OpenOrderInfo{Type: "LIMIT", Side: "SELL", PositionSide: "BOTH"}).EntrySide(); side != "short" {
+		t.Errorf("单向持仓模式的限价卖单应视为开空, 实际 %q", side)
+	}
+}
+
+// TestPromptIncludesTradeStats 测试实际成交统计写入 user prompt 的历史表现部分
+func TestPromptIncludesTradeStats(t *testing.T) {
+	factor := 1.25
+	ctx := &Context{TradeStats: &TradeStatsSummary{
+		Period: "近30天", Trades: 3, Wins: 1, Losses: 2, WinRate: 100.0 / 3, RealizedPnL: 10,
+		AvgWin: 50, AvgLoss: 20, ProfitFactor: &factor, MaxConsecutiveLosses: 2,
+	}}
+	prompt := buildUserPrompt(ctx)
+	for _, want := range []string{"历史表现（近30天实际成交）", "胜率 33.3% (1胜/2负)", "盈亏比 1.25", "最大连续亏损 2 笔"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("user prompt 缺少 %q:\n%s", want, prompt)
+		}
+	}
+
+	// 没有已平仓交易时不显示
+	ctx.TradeStats = &TradeStatsSummary{Period: "近30天"}
+	if strings.Contains(buildUserPrompt(ctx), "历史表现") {
+		t.Error("没有已平仓交易时不应显示历史表现")
+	}
+}
+
+// TestPromptIncludesPositionAnnotations 测试持仓的用户备注和锁定状态写入 user prompt
+func TestPromptIncludesPositionAnnotations(t *testing.T) {
+	ctx := &Context{Positions: []PositionInfo{
+		{Symbol: "BTCUSDT", Side: "long", EntryPrice: 60000, MarkPrice: 61000, Quantity: 0.1, Leverage: 5, Note: "长线底仓", Locked: true},
+		{Symbol: "ETHUSDT", Side: "short", EntryPrice: 3000, MarkPrice: 2950, Quantity: 1, Leverage: 5},
+	}}
+	prompt := buildUserPrompt(ctx)
+	if !strings.Contains(prompt, "📝 用户备注：长线底仓") || !strings.Contains(prompt, "🔒 用户已锁定") {
+		t.Errorf("user prompt 应包含持仓备注和锁定状态:\n%s", prompt)
+	}
+	if strings.Count(prompt, "🔒") != 1 {
+		t.Errorf("只有锁定的持仓显示锁定标记:\n%s", prompt)
+	}
+}
+
+// TestPromptMarksStaleSignals 测试信号源刷新失败时候选币种标注信号数据年龄
+func TestPromptMarksStaleSignals(t *testing.T) {
+	ctx := &Context{
+		CandidateCoins: []CandidateCoin{
+			{Symbol: "BTCUSDT", Sources: []string{"ai500"}, SignalAgeSeconds: 1500, SignalStale: true},
+			{Symbol: "ETHUSDT", Sources: []string{"ai500"}, SignalAgeSeconds: 60},
+		},
+		MarketDataMap: map[string]*market.Data{
+			"BTCUSDT": {Symbol: "BTCUSDT", CurrentPrice: 60000},
+			"ETHUSDT": {Symbol: "ETHUSDT", CurrentPrice: 3000},
+		},
+	}
+	prompt := buildUserPrompt(ctx)
+	if !strings.Contains(prompt, "BTCUSDT [⚠️ 信号数据已过期：25分钟前]") {
+		t.Errorf("过期信号应标注数据年龄:\n%s", prompt)
+	}
+	if strings.Count(prompt, "信号数据已过期") != 1 {
+		t.Errorf("只有过期的信号需要标注:\n%s", prompt)
+	}
+}
@@ -16,6 +16,7 @@ type PromptTemplate struct {
 	Content     string            // 模板内容
 	DisplayName map[string]string // 显示名称（多语言）{"zh": "中文名", "en": "English Name"}
 	Description map[string]string // 描述（多语言）
+	Version     int               // 当前版本号（0 表示未纳入版本管理或文件被手动修改过）
 }
 
 // TemplateMetadata 模板元数据配置
@@ -62,8 +63,9 @@ func findPromptsDir() string {
 // init 包初始化时加载所有提示词模板
 func init() {
 	globalPromptManager = NewPromptManager()
-	actualPromptsDir := findPromptsDir()
-	if err := globalPromptManager.LoadTemplates(actualPromptsDir); err != nil {
+	// 重新加载/保存模板时使用同一目录（测试环境下 prompts/ 位于上级目录）
+	promptsDir = findPromptsDir()
+	if err := globalPromptManager.LoadTemplates(promptsDir); err != nil {
 		log.Printf("⚠️  加载提示词模板失败: %v", err)
 	} else {
 		log.Printf("✓ 已加载 %d 个系统提示词模板", len(globalPromptManager.templates))
@@ -130,6 +132,7 @@ func (pm *PromptManager) LoadTemplates(dir string) error {
 		template := &PromptTemplate{
 			Name:    templateName,
 			Content: string(content),
+			Version: currentTemplateVersion(dir, templateName, string(content)),
 		}
 
 		// 如果有配置元数据，填充显示名称和描述
@@ -227,31 +230,13 @@ func ReloadPromptTemplates() error {
 	return globalPromptManager.ReloadTemplates(promptsDir)
 }
 
-// SavePromptTemplate 保存提示词模板到文件并重新加载
+// SavePromptTemplate 保存提示词模板到文件（记录为新版本）并重新加载
 func SavePromptTemplate(name, content string) error {
-	if name == "" {
-		return fmt.Errorf("模板名称不能为空")
-	}
-	if content == "" {
-		return fmt.Errorf("模板内容不能为空")
-	}
-
-	// 保存到文件
-	filePath := filepath.Join(promptsDir, name+".txt")
-	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("保存模板文件失败: %w", err)
-	}
-
-	// 重新加载模板
-	if err := ReloadPromptTemplates(); err != nil {
-		log.Printf("⚠️  保存成功但重新加载失败: %v", err)
-	}
-
-	log.Printf("✓ 已保存提示词模板: %s", name)
-	return nil
+	_, err := SavePromptTemplateWithNote(name, content, "")
+	return err
 }
 
-// DeletePromptTemplate 删除提示词模板文件并重新加载
+// DeletePromptTemplate 删除提示词模板文件并重新加载（保留版本历史，同名模板重建后可回滚）
 func DeletePromptTemplate(name string) error {
 	if name == "" {
 		return fmt.Errorf("模板名称不能为空")
@@ -0,0 +1,173 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPromptTemplateLength 模板内容建议的最大长度（字符），过长会挤占市场数据的上下文窗口
+const maxPromptTemplateLength = 20000
+
+var (
+	// 模板是纯文本，渲染时不会替换任何占位符，出现以下写法会被原样发给 AI
+	goPlaceholderPattern    = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+	shellPlaceholderPattern = regexp.MustCompile(`\$\{[^{}]*\}`)
+)
+
+// TemplateValidationResult 提示词模板校验结果
+type TemplateValidationResult struct {
+	Valid        bool     `json:"valid"`
+	Errors       []string `json:"errors"`
+	Warnings     []string `json:"warnings"`
+	SystemPrompt string   `json:"system_prompt"` // 渲染后的 System Prompt
+	UserPrompt   string   `json:"user_prompt"`   // 基于示例上下文渲染的 User Prompt
+}
+
+// ValidatePromptTemplate 校验模板内容，并用示例上下文渲染完整提示词（不调用 AI）
+func ValidatePromptTemplate(content string) *TemplateValidationResult {
+	result := &TemplateValidationResult{
+		Errors:   []string{},
+		Warnings: []string{},
+	}
+
+	switch {
+	case strings.TrimSpace(content) == "":
+		result.Errors = append(result.Errors, "模板内容不能为空")
+	case !utf8.ValidString(content):
+		result.Errors = append(result.Errors, "模板内容不是有效的 UTF-8 文本")
+	}
+
+	for _, pattern := range []*regexp.Regexp{goPlaceholderPattern, shellPlaceholderPattern} {
+		for _, placeholder := range uniqueMatches(pattern, content) {
+			result.Errors = append(result.Errors, fmt.Sprintf("包含无法替换的占位符 %s（模板为纯文本，账户/行情数据会自动附加在 User Prompt 中）", placeholder))
+		}
+	}
+	if openCount, closeCount := strings.Count(content, "{{"), strings.Count(content, "}}"); openCount != closeCount {
+		result.Errors = append(result.Errors, fmt.Sprintf("花括号不匹配: {{ 出现 %d 次，}} 出现 %d 次", openCount, closeCount))
+	}
+
+	// 输出解析依赖 <reasoning>/<decision> 标签，模板中出现不成对的标签会误导 AI 的输出格式
+	for _, tag := range []string{"reasoning", "decision"} {
+		openCount := strings.Count(content, "<"+tag+">")
+		closeCount := strings.Count(content, "</"+tag+">")
+		if openCount != closeCount {
+			result.Errors = append(result.Errors, fmt.Sprintf("<%s> 标签不成对（开始 %d 个，结束 %d 个）", tag, openCount, closeCount))
+		}
+	}
+
+	if length := utf8.RuneCountInString(content); length > maxPromptTemplateLength {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("模板长度 %d 字符，超过建议上限 %d，可能挤占市场数据的上下文", length, maxPromptTemplateLength))
+	}
+
+	ctx := NewSampleContext()
+	if err := renderSamplePrompts(content, ctx, result); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// renderSamplePrompts 走与交易周期相同的渲染路径生成提示词
+func renderSamplePrompts(content string, ctx *Context, result *TemplateValidationResult) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("渲染提示词失败: %v", r)
+		}
+	}()
+
+	result.SystemPrompt = renderSystemPrompt(content, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	result.UserPrompt = buildUserPrompt(ctx)
+	return nil
+}
+
+// NewSampleContext 构建用于模板预览的示例上下文（静态数据，不请求行情）
+func NewSampleContext() *Context {
+	btc := &market.Data{
+		Symbol:        "BTCUSDT",
+		CurrentPrice:  95000,
+		PriceChange1h: 0.35,
+		PriceChange4h: -1.2,
+		CurrentEMA20:  94800,
+		CurrentMACD:   120.5,
+		CurrentRSI7:   55.3,
+		FundingRate:   0.0001,
+		Funding: &market.FundingData{
+			Source:          "Binance",
+			Rate:            0.0001,
+			NextFundingTime: time.Now().Add(3 * time.Hour).UnixMilli(),
+			IntervalHours:   8,
+			History:         []market.FundingSnapshot{{Rate: 0.00008}, {Rate: 0.0001}},
+		},
+	}
+	sol := &market.Data{
+		Symbol:        "SOLUSDT",
+		CurrentPrice:  180,
+		PriceChange1h: 1.1,
+		PriceChange4h: 2.4,
+		CurrentEMA20:  176.5,
+		CurrentMACD:   0.85,
+		CurrentRSI7:   63.2,
+		FundingRate:   0.00012,
+	}
+
+	return &Context{
+		CurrentTime:    time.Now().Format("2006-01-02 15:04:05"),
+		RuntimeMinutes: 120,
+		CallCount:      40,
+		Account: AccountInfo{
+			TotalEquity:      1000,
+			AvailableBalance: 800,
+			TotalPnL:         50,
+			TotalPnLPct:      5,
+			MarginUsed:       200,
+			MarginUsedPct:    20,
+			PositionCount:    1,
+		},
+		Positions: []PositionInfo{
+			{
+				Symbol:           "BTCUSDT",
+				Side:             "long",
+				EntryPrice:       94000,
+				MarkPrice:        95000,
+				Quantity:         0.01,
+				Leverage:         5,
+				UnrealizedPnL:    10,
+				UnrealizedPnLPct: 5.32,
+				PeakPnLPct:       6.1,
+				LiquidationPrice: 76000,
+				MarginUsed:       190,
+				UpdateTime:       time.Now().Add(-90 * time.Minute).UnixMilli(),
+			},
+		},
+		OpenOrders: []OpenOrderInfo{
+			{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: 92000},
+		},
+		CandidateCoins: []CandidateCoin{
+			{Symbol: "BTCUSDT", Sources: []string{"ai500"}},
+			{Symbol: "SOLUSDT", Sources: []string{"ai500", "oi_top"}},
+		},
+		MarketDataMap:   map[string]*market.Data{"BTCUSDT": btc, "SOLUSDT": sol},
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		TakerFeeRate:    0.0004,
+		MakerFeeRate:    0.0002,
+	}
+}
+
+// uniqueMatches 返回去重后的正则匹配结果（保持出现顺序）
+func uniqueMatches(pattern *regexp.Regexp, content string) []string {
+	seen := make(map[string]bool)
+	var matches []string
+	for _, m := range pattern.FindAllString(content, -1) {
+		if !seen[m] {
+			seen[m] = true
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
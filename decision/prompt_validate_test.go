@@ -0,0 +1,89 @@
+package decision
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidatePromptTemplate 测试模板校验与示例渲染
+func TestValidatePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantValid  bool
+		wantErrSub string
+	}{
+		{
+			name:      "正常模板",
+			content:   "你是专业的加密货币交易员，只在趋势明确时开仓。",
+			wantValid: true,
+		},
+		{
+			name:       "空模板",
+			content:    "   \n",
+			wantErrSub: "不能为空",
+		},
+		{
+			name:       "Go模板占位符不会被替换",
+			content:    "当前净值 {{.Account.TotalEquity}}，请谨慎交易",
+			wantErrSub: "{{.Account.TotalEquity}}",
+		},
+		{
+			name:       "Shell风格占位符",
+			content:    "最大杠杆 ${leverage}",
+			wantErrSub: "${leverage}",
+		},
+		{
+			name:       "花括号不匹配",
+			content:    "当前净值 {{ equity",
+			wantErrSub: "花括号不匹配",
+		},
+		{
+			name:       "输出标签不成对",
+			content:    "请先输出 <reasoning> 再输出决策",
+			wantErrSub: "<reasoning> 标签不成对",
+		},
+		{
+			name:      "成对的输出标签",
+			content:   "在 <reasoning></reasoning> 中写分析，在 <decision></decision> 中写JSON",
+			wantValid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ValidatePromptTemplate(tt.content)
+
+			if result.Valid != tt.wantValid {
+				t.Errorf("Valid = %v, 期望 %v (errors=%v)", result.Valid, tt.wantValid, result.Errors)
+			}
+			if tt.wantErrSub != "" && !strings.Contains(strings.Join(result.Errors, "\n"), tt.wantErrSub) {
+				t.Errorf("错误信息应包含 %q，实际 %v", tt.wantErrSub, result.Errors)
+			}
+
+			// 无论是否有错误，都应返回渲染结果供预览
+			if !strings.Contains(result.SystemPrompt, "# 硬约束（风险控制）") || !strings.Contains(result.SystemPrompt, "<decision>") {
+				t.Error("System Prompt 应包含动态生成的风控约束和输出格式")
+			}
+			if !strings.Contains(result.UserPrompt, "BTCUSDT") || !strings.Contains(result.UserPrompt, "## 候选币种") {
+				t.Error("User Prompt 应基于示例上下文渲染")
+			}
+		})
+	}
+}
+
+// TestValidatePromptTemplateRendersContent 测试渲染结果以模板内容开头
+func TestValidatePromptTemplateRendersContent(t *testing.T) {
+	content := "自定义策略：只做BTC"
+	result := ValidatePromptTemplate(content)
+
+	if !strings.HasPrefix(result.SystemPrompt, content+"\n\n") {
+		t.Errorf("System Prompt 应以模板内容开头: %q", result.SystemPrompt[:50])
+	}
+
+	// 与交易周期使用同一渲染路径
+	expected := renderSystemPrompt(content, 1000, 5, 5)
+	if result.SystemPrompt != expected {
+		t.Error("校验渲染结果应与交易周期的渲染结果一致")
+	}
+}
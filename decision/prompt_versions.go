@@ -0,0 +1,290 @@
+package decision
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionsDirName 版本历史目录（位于 prompts/ 下，每个模板一个子目录：versions/<name>/v3.txt + index.json）
+const versionsDirName = "versions"
+
+// ErrPromptVersionNotFound 模板版本不存在
+var ErrPromptVersionNotFound = errors.New("模板版本不存在")
+
+// PromptVersion 提示词模板的一个历史版本
+type PromptVersion struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Note      string    `json:"note,omitempty"` // 修改说明
+	Size      int       `json:"size"`           // 内容字节数
+}
+
+// promptVersionIndex 模板版本索引（index.json）
+type promptVersionIndex struct {
+	Current  int             `json:"current"` // 当前生效的版本（回滚后不一定是最新版本）
+	Versions []PromptVersion `json:"versions"`
+}
+
+// versionsMu 串行化版本历史的读写，避免并发保存分配到相同的版本号
+var versionsMu sync.Mutex
+
+// validateTemplateName 校验模板名称可以安全地用作文件名
+func validateTemplateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("模板名称不能为空")
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("模板名称包含非法字符: %s", name)
+	}
+	return nil
+}
+
+// templateVersionsDir 模板的版本历史目录
+func templateVersionsDir(dir, name string) string {
+	return filepath.Join(dir, versionsDirName, name)
+}
+
+// versionFile 版本内容文件路径
+func versionFile(dir, name string, version int) string {
+	return filepath.Join(templateVersionsDir(dir, name), fmt.Sprintf("v%d.txt", version))
+}
+
+// loadVersionIndex 读取模板的版本索引（没有历史时返回空索引）
+func loadVersionIndex(dir, name string) (*promptVersionIndex, error) {
+	data, err := os.ReadFile(filepath.Join(templateVersionsDir(dir, name), "index.json"))
+	if os.IsNotExist(err) {
+		return &promptVersionIndex{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取版本索引失败: %w", err)
+	}
+	var index promptVersionIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("解析版本索引失败: %w", err)
+	}
+	return &index, nil
+}
+
+// saveVersionIndex 写入模板的版本索引（先写临时文件再重命名，避免写一半时崩溃损坏索引）
+func saveVersionIndex(dir, name string, index *promptVersionIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化版本索引失败: %w", err)
+	}
+	path := filepath.Join(templateVersionsDir(dir, name), "index.json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入版本索引失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("写入版本索引失败: %w", err)
+	}
+	return nil
+}
+
+// latestVersion 索引中最大的版本号
+func (idx *promptVersionIndex) latestVersion() int {
+	latest := 0
+	for _, v := range idx.Versions {
+		latest = max(latest, v.Version)
+	}
+	return latest
+}
+
+// find 查找指定版本
+func (idx *promptVersionIndex) find(version int) (PromptVersion, bool) {
+	for _, v := range idx.Versions {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return PromptVersion{}, false
+}
+
+// readVersionContent 读取版本内容
+func readVersionContent(dir, name string, version int) (string, error) {
+	data, err := os.ReadFile(versionFile(dir, name, version))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrPromptVersionNotFound
+		}
+		return "", fmt.Errorf("读取版本内容失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// appendVersion 写入一个新版本并设为当前版本（调用方持有 versionsMu）
+func appendVersion(dir, name string, index *promptVersionIndex, content, note string) (int, error) {
+	if err := os.MkdirAll(templateVersionsDir(dir, name), 0755); err != nil {
+		return 0, fmt.Errorf("创建版本目录失败: %w", err)
+	}
+	version := index.latestVersion() + 1
+	if err := os.WriteFile(versionFile(dir, name, version), []byte(content), 0644); err != nil {
+		return 0, fmt.Errorf("保存版本文件失败: %w", err)
+	}
+	index.Versions = append(index.Versions, PromptVersion{
+		Version:   version,
+		CreatedAt: time.Now(),
+		Note:      note,
+		Size:      len(content),
+	})
+	index.Current = version
+	return version, nil
+}
+
+// currentTemplateVersion 模板文件对应的版本号
+// 文件内容与索引记录的当前版本不一致（例如被手动修改过）时返回 0，避免把决策追溯到错误的版本
+func currentTemplateVersion(dir, name, content string) int {
+	index, err := loadVersionIndex(dir, name)
+	if err != nil || index.Current == 0 {
+		return 0
+	}
+	stored, err := readVersionContent(dir, name, index.Current)
+	if err != nil || stored != content {
+		return 0
+	}
+	return index.Current
+}
+
+// SavePromptTemplateWithNote 保存提示词模板为新版本并重新加载，返回新版本号
+// 首次纳入版本管理（或文件被手动修改过）时，先把磁盘上的现有内容保存为一个版本，保证可以回滚；
+// 内容与当前版本相同时不产生新版本
+func SavePromptTemplateWithNote(name, content, note string) (int, error) {
+	if err := validateTemplateName(name); err != nil {
+		return 0, err
+	}
+	if content == "" {
+		return 0, fmt.Errorf("模板内容不能为空")
+	}
+
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	index, err := loadVersionIndex(promptsDir, name)
+	if err != nil {
+		return 0, err
+	}
+
+	filePath := filepath.Join(promptsDir, name+".txt")
+	existing, readErr := os.ReadFile(filePath)
+	fileVersion := 0
+	if readErr == nil {
+		fileVersion = currentTemplateVersion(promptsDir, name, string(existing))
+	}
+	if readErr == nil && fileVersion == 0 && string(existing) != content {
+		if _, err := appendVersion(promptsDir, name, index, string(existing), "保存前的原有内容"); err != nil {
+			return 0, err
+		}
+	}
+
+	version := fileVersion
+	if readErr != nil || string(existing) != content || fileVersion == 0 {
+		if version, err = appendVersion(promptsDir, name, index, content, strings.TrimSpace(note)); err != nil {
+			return 0, err
+		}
+	}
+	if err := saveVersionIndex(promptsDir, name, index); err != nil {
+		return 0, err
+	}
+
+	// 保存到文件
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		return 0, fmt.Errorf("保存模板文件失败: %w", err)
+	}
+
+	// 重新加载模板
+	if err := ReloadPromptTemplates(); err != nil {
+		log.Printf("⚠️  保存成功但重新加载失败: %v", err)
+	}
+
+	log.Printf("✓ 已保存提示词模板: %s (v%d)", name, version)
+	return version, nil
+}
+
+// ListPromptVersions 获取模板的版本历史（按版本号升序）和当前版本号
+func ListPromptVersions(name string) ([]PromptVersion, int, error) {
+	if err := validateTemplateName(name); err != nil {
+		return nil, 0, err
+	}
+
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	index, err := loadVersionIndex(promptsDir, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(index.Versions, func(i, j int) bool {
+		return index.Versions[i].Version < index.Versions[j].Version
+	})
+	return index.Versions, index.Current, nil
+}
+
+// GetPromptVersion 获取模板指定版本的内容
+func GetPromptVersion(name string, version int) (string, *PromptVersion, error) {
+	if err := validateTemplateName(name); err != nil {
+		return "", nil, err
+	}
+
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	index, err := loadVersionIndex(promptsDir, name)
+	if err != nil {
+		return "", nil, err
+	}
+	meta, ok := index.find(version)
+	if !ok {
+		return "", nil, ErrPromptVersionNotFound
+	}
+	content, err := readVersionContent(promptsDir, name, version)
+	if err != nil {
+		return "", nil, err
+	}
+	return content, &meta, nil
+}
+
+// RollbackPromptTemplate 将模板回滚到指定版本（该版本成为当前版本，不删除之后的版本）并重新加载
+func RollbackPromptTemplate(name string, version int) error {
+	if err := validateTemplateName(name); err != nil {
+		return err
+	}
+
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	index, err := loadVersionIndex(promptsDir, name)
+	if err != nil {
+		return err
+	}
+	if _, ok := index.find(version); !ok {
+		return ErrPromptVersionNotFound
+	}
+	content, err := readVersionContent(promptsDir, name, version)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(promptsDir, name+".txt"), []byte(content), 0644); err != nil {
+		return fmt.Errorf("保存模板文件失败: %w", err)
+	}
+	index.Current = version
+	if err := saveVersionIndex(promptsDir, name, index); err != nil {
+		return err
+	}
+
+	if err := ReloadPromptTemplates(); err != nil {
+		log.Printf("⚠️  回滚成功但重新加载失败: %v", err)
+	}
+
+	log.Printf("✓ 提示词模板 %s 已回滚到 v%d", name, version)
+	return nil
+}
@@ -0,0 +1,94 @@
+package decision
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPromptTemplateVersioning 测试保存产生新版本、已有内容首次保存时被保留、回滚和决策引擎使用的版本号
+func TestPromptTemplateVersioning(t *testing.T) {
+	originalDir := promptsDir
+	defer func() {
+		promptsDir = originalDir
+		globalPromptManager.ReloadTemplates(originalDir)
+	}()
+
+	tempDir := t.TempDir()
+	promptsDir = tempDir
+
+	// 版本管理之前已存在的模板
+	if err := os.WriteFile(filepath.Join(tempDir, "aggressive.txt"), []byte("原始策略"), 0644); err != nil {
+		t.Fatalf("创建模板文件失败: %v", err)
+	}
+	if err := ReloadPromptTemplates(); err != nil {
+		t.Fatalf("加载模板失败: %v", err)
+	}
+	if tmpl, _ := GetPromptTemplate("aggressive"); tmpl.Version != 0 {
+		t.Errorf("未纳入版本管理的模板版本应为 0, 实际 %d", tmpl.Version)
+	}
+
+	v, err := SavePromptTemplateWithNote("aggressive", "激进策略 A", "提高仓位")
+	if err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	if v != 2 {
+		t.Errorf("原有内容应保存为 v1，新内容为 v2, 实际 v%d", v)
+	}
+	if v, _ = SavePromptTemplateWithNote("aggressive", "激进策略 A", ""); v != 2 {
+		t.Errorf("内容未变化时不应产生新版本, 实际 v%d", v)
+	}
+	if v, _ = SavePromptTemplateWithNote("aggressive", "激进策略 B", "实验"); v != 3 {
+		t.Errorf("期望 v3, 实际 v%d", v)
+	}
+
+	versions, current, err := ListPromptVersions("aggressive")
+	if err != nil || len(versions) != 3 || current != 3 {
+		t.Fatalf("期望 3 个版本且当前为 v3, 实际 %d 个, 当前 v%d, err=%v", len(versions), current, err)
+	}
+	if versions[1].Note != "提高仓位" {
+		t.Errorf("v2 的修改说明不正确: %q", versions[1].Note)
+	}
+	content, _, err := GetPromptVersion("aggressive", 1)
+	if err != nil || content != "原始策略" {
+		t.Errorf("v1 应为原有内容, 实际 %q, err=%v", content, err)
+	}
+
+	// 回滚到 v2：文件内容、加载的模板和决策引擎使用的版本号都应更新
+	if err := RollbackPromptTemplate("aggressive", 2); err != nil {
+		t.Fatalf("回滚失败: %v", err)
+	}
+	tmpl, _ := GetPromptTemplate("aggressive")
+	if tmpl.Content != "激进策略 A" || tmpl.Version != 2 {
+		t.Errorf("回滚后模板应为 v2, 实际 v%d %q", tmpl.Version, tmpl.Content)
+	}
+	if name, version := resolvePromptTemplateVersion("aggressive", false); name != "aggressive" || version != 2 {
+		t.Errorf("决策应记录 aggressive v2, 实际 %s v%d", name, version)
+	}
+	if _, version := resolvePromptTemplateVersion("aggressive", true); version != 0 {
+		t.Errorf("自定义提示词覆盖模板时不应记录版本, 实际 v%d", version)
+	}
+
+	// 回滚后再次保存，版本号继续递增
+	if v, _ = SavePromptTemplateWithNote("aggressive", "激进策略 C", ""); v != 4 {
+		t.Errorf("回滚后保存应为 v4, 实际 v%d", v)
+	}
+
+	// 手动修改文件后版本号失效，下次保存前先保留手动修改的内容
+	os.WriteFile(filepath.Join(tempDir, "aggressive.txt"), []byte("手动修改"), 0644)
+	ReloadPromptTemplates()
+	if tmpl, _ := GetPromptTemplate("aggressive"); tmpl.Version != 0 {
+		t.Errorf("手动修改后版本应为 0, 实际 %d", tmpl.Version)
+	}
+	if v, _ = SavePromptTemplateWithNote("aggressive", "激进策略 D", ""); v != 6 {
+		t.Errorf("手动修改的内容应保存为 v5，新内容为 v6, 实际 v%d", v)
+	}
+
+	if err := RollbackPromptTemplate("aggressive", 99); !errors.Is(err, ErrPromptVersionNotFound) {
+		t.Errorf("回滚到不存在的版本应返回 ErrPromptVersionNotFound, 实际 %v", err)
+	}
+	if _, err := SavePromptTemplateWithNote("../evil", "x", ""); err == nil {
+		t.Error("包含路径分隔符的模板名称应被拒绝")
+	}
+}
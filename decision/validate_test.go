@@ -1,6 +1,8 @@
 package decision
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -426,3 +428,49 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+// TestValidateOrderType 测试开仓决策的订单类型和限价校验
+func TestValidateOrderType(t *testing.T) {
+	tests := []struct {
+		name      string
+		decision  Decision
+		wantError bool
+	}{
+		{"未指定_使用默认策略", Decision{Action: "open_long", StopLoss: 90, TakeProfit: 120}, false},
+		{"市价单", Decision{Action: "open_long", StopLoss: 90, TakeProfit: 120, OrderType: "market"}, false},
+		{"多单限价在止损止盈之间", Decision{Action: "open_long", StopLoss: 90, TakeProfit: 120, OrderType: "limit", LimitPrice: 99}, false},
+		{"空单限价在止盈止损之间", Decision{Action: "open_short", StopLoss: 110, TakeProfit: 80, OrderType: "limit", LimitPrice: 101}, false},
+		{"限价单缺少价格", Decision{Action: "open_long", StopLoss: 90, TakeProfit: 120, OrderType: "limit"}, true},
+		{"多单限价低于止损", Decision{Action: "open_long", StopLoss: 90, TakeProfit: 120, OrderType: "limit", LimitPrice: 85}, true},
+		{"空单限价低于止盈", Decision{Action: "open_short", StopLoss: 110, TakeProfit: 80, OrderType: "limit", LimitPrice: 75}, true},
+		{"无效订单类型", Decision{Action: "open_long", StopLoss: 90, TakeProfit: 120, OrderType: "stop"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOrderType(&tt.decision)
+			if (err != nil) != tt.wantError {
+				t.Errorf("validateOrderType() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+// TestAIUnavailableError 测试AI调用/解析失败的错误可识别为 ErrAIUnavailable，且错误信息保持不变
+func TestAIUnavailableError(t *testing.T) {
+	cause := errors.New("连接超时")
+	err := &aiUnavailableError{fmt.Errorf("调用AI API失败: %w", cause)}
+
+	if !errors.Is(err, ErrAIUnavailable) {
+		t.Error("应识别为 ErrAIUnavailable")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("应保留原始错误")
+	}
+	if err.Error() != "调用AI API失败: 连接超时" {
+		t.Errorf("错误信息不应改变, 实际 %q", err.Error())
+	}
+	if errors.Is(fmt.Errorf("获取市场数据失败: %w", cause), ErrAIUnavailable) {
+		t.Error("与模型无关的失败不应识别为 ErrAIUnavailable")
+	}
+}
@@ -0,0 +1,108 @@
+// Package i18n API 返回给用户的错误信息多语言支持
+//
+// 错误以稳定的错误码（如 TRADER_NOT_FOUND）标识，消息文本按请求语言从 messages 目录中选择。
+// 语言优先取 ?lang= 参数，其次是 Accept-Language 请求头，都不支持时使用中文。
+package i18n
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+// DefaultLang 未指定或不支持的语言时使用的默认语言
+const DefaultLang = LangZH
+
+// normalizeLang 把语言标签（如 en-US、zh_CN）归一为支持的语言，不支持时返回空字符串
+func normalizeLang(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	switch tag {
+	case LangZH, LangEN:
+		return tag
+	}
+	return ""
+}
+
+// ResolveLang 选择响应语言：override（?lang=）优先，其次按 Accept-Language 的 q 值选择第一个支持的语言
+func ResolveLang(override, acceptLanguage string) string {
+	if lang := normalizeLang(override); lang != "" {
+		return lang
+	}
+
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		lang := normalizeLang(fields[0])
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return DefaultLang
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// RequestLang 从 HTTP 请求中选择响应语言
+func RequestLang(r *http.Request) string {
+	return ResolveLang(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+}
+
+// Has 错误码是否在消息目录中
+func Has(code string) bool {
+	_, ok := messages[code]
+	return ok
+}
+
+// Message 返回错误码在指定语言下的消息，缺少该语言时回退到默认语言，未知错误码原样返回
+func Message(lang, code string, args ...interface{}) string {
+	templates, ok := messages[code]
+	if !ok {
+		return code
+	}
+	template, ok := templates[lang]
+	if !ok {
+		template = templates[DefaultLang]
+	}
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// ErrorBody 构造错误响应体：code 为稳定的错误码，message 为本地化消息，error 与 message 相同（兼容只读取 error 的客户端）
+func ErrorBody(lang, code string, args ...interface{}) map[string]interface{} {
+	message := Message(lang, code, args...)
+	return map[string]interface{}{
+		"error":   message,
+		"code":    code,
+		"message": message,
+	}
+}
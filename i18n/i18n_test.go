@@ -0,0 +1,95 @@
+package i18n
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestResolveLang 测试 ?lang= 优先、Accept-Language 按 q 值选择、不支持时回退中文
+func TestResolveLang(t *testing.T) {
+	tests := []struct {
+		override string
+		accept   string
+		want     string
+	}{
+		{"", "", LangZH},
+		{"", "en-US,en;q=0.9", LangEN},
+		{"", "zh-CN,zh;q=0.9,en;q=0.8", LangZH},
+		{"", "fr-FR,en;q=0.5", LangEN},
+		{"", "zh;q=0.3,en;q=0.7", LangEN},
+		{"", "en;q=0,zh;q=0.5", LangZH},
+		{"", "fr,de", LangZH},
+		{"en", "zh-CN", LangEN},
+		{"EN_us", "", LangEN},
+		{"fr", "en", LangEN},
+	}
+	for _, tt := range tests {
+		if got := ResolveLang(tt.override, tt.accept); got != tt.want {
+			t.Errorf("ResolveLang(%q, %q) = %q, want %q", tt.override, tt.accept, got, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/x?lang=zh", nil)
+	req.Header.Set("Accept-Language", "en")
+	if got := RequestLang(req); got != LangZH {
+		t.Errorf("?lang= 应优先于 Accept-Language, got %q", got)
+	}
+}
+
+// TestMessage 测试模板填充、未知错误码原样返回
+func TestMessage(t *testing.T) {
+	if got := Message(LangEN, "INSUFFICIENT_MARGIN_DETAIL", 110.0, 100.0, 10.0, 50.0); !strings.Contains(got, "110.00 USDT required") {
+		t.Errorf("英文模板填充错误: %q", got)
+	}
+	if got := Message(LangZH, "INSUFFICIENT_MARGIN_DETAIL", 110.0, 100.0, 10.0, 50.0); got != "❌ 保证金不足: 需要 110.00 USDT（保证金 100.00 + 手续费 10.00），可用 50.00 USDT" {
+		t.Errorf("中文模板填充错误: %q", got)
+	}
+	if got := Message("fr", "TRADER_NOT_FOUND"); got != messages["TRADER_NOT_FOUND"][LangZH] {
+		t.Errorf("不支持的语言应回退中文, got %q", got)
+	}
+	if got := Message(LangEN, "NO_SUCH_CODE"); got != "NO_SUCH_CODE" {
+		t.Errorf("未知错误码应原样返回, got %q", got)
+	}
+
+	body := ErrorBody(LangEN, "TRADER_NOT_FOUND")
+	if body["code"] != "TRADER_NOT_FOUND" || body["error"] != body["message"] || body["message"] == "" {
+		t.Errorf("ErrorBody 字段错误: %v", body)
+	}
+}
+
+// TestMessagesComplete 每个错误码都必须同时有中英文，且两种语言的占位符一致
+func TestMessagesComplete(t *testing.T) {
+	for code, templates := range messages {
+		if code != strings.ToUpper(code) {
+			t.Errorf("错误码 %s 必须全部大写", code)
+		}
+		zh, en := templates[LangZH], templates[LangEN]
+		if zh == "" || en == "" {
+			t.Errorf("错误码 %s 缺少中文或英文消息", code)
+			continue
+		}
+		if verbs(zh) != verbs(en) {
+			t.Errorf("错误码 %s 中英文占位符不一致: %q vs %q", code, verbs(zh), verbs(en))
+		}
+	}
+}
+
+// verbs 提取格式化模板中的占位符序列（忽略 %% 和不带动词的字面 %）
+func verbs(template string) string {
+	var out []string
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' || i+1 >= len(template) {
+			continue
+		}
+		j := i + 1
+		for j < len(template) && strings.ContainsRune(".0123456789", rune(template[j])) {
+			j++
+		}
+		if j < len(template) && (template[j] >= 'a' && template[j] <= 'z' || template[j] >= 'A' && template[j] <= 'Z') {
+			out = append(out, template[i:j+1])
+		}
+		i = j
+	}
+	return strings.Join(out, ",")
+}
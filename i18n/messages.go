@@ -0,0 +1,315 @@
+package i18n
+
+// messages 错误码 → 各语言的消息模板（fmt 格式），新增错误码时需同时提供中英文
+var messages = map[string]map[string]string{
+	// 通用
+	"INVALID_REQUEST":           {LangZH: "请求参数错误: %v", LangEN: "Invalid request: %v"},
+	"INVALID_PARAMETER":         {LangZH: "%v", LangEN: "Invalid parameter: %v"},
+	"INTERNAL_ERROR":            {LangZH: "%v", LangEN: "Internal error: %v"},
+	"NOT_FOUND":                 {LangZH: "%v", LangEN: "Not found: %v"},
+	"CONFLICT":                  {LangZH: "%v", LangEN: "Conflict: %v"},
+	"FORBIDDEN":                 {LangZH: "%v", LangEN: "Forbidden: %v"},
+	"READ_BODY_FAILED":          {LangZH: "读取请求体失败", LangEN: "Failed to read request body"},
+	"ENCRYPTED_PAYLOAD_INVALID": {LangZH: "请求格式错误，必须使用加密传输", LangEN: "Invalid request format: encrypted transport is required"},
+	"ENCRYPTION_REQUIRED":       {LangZH: "此接口仅支持加密传输，请使用加密客户端", LangEN: "This endpoint only accepts encrypted requests, please use an encrypted client"},
+	"DECRYPT_FAILED":            {LangZH: "解密数据失败", LangEN: "Failed to decrypt data"},
+	"DECRYPT_TIMESTAMP_INVALID": {LangZH: "时间戳验证失败：请检查系统时间是否正确", LangEN: "Timestamp verification failed: please check that your system clock is correct"},
+	"DECRYPT_KEY_FAILED":        {LangZH: "密钥解密失败：请刷新页面重试", LangEN: "Failed to unwrap the encryption key: please refresh the page and retry"},
+	"DECRYPT_PAYLOAD_INVALID":   {LangZH: "解析解密数据失败", LangEN: "Failed to parse decrypted payload"},
+	"ORIGIN_NOT_ALLOWED":        {LangZH: "不允许的来源", LangEN: "Origin not allowed"},
+
+	// 认证
+	"AUTH_HEADER_MISSING":            {LangZH: "缺少Authorization头", LangEN: "Missing Authorization header"},
+	"AUTH_HEADER_INVALID":            {LangZH: "无效的Authorization格式", LangEN: "Invalid Authorization header format"},
+	"INVALID_TOKEN":                  {LangZH: "无效的token", LangEN: "Invalid token"},
+	"AUTH_CHECK_UNAVAILABLE":         {LangZH: "暂时无法校验登录状态，请稍后重试", LangEN: "Unable to verify the session right now, please retry later"},
+	"ACCOUNT_DISABLED":               {LangZH: "账户已被禁用", LangEN: "Account is disabled"},
+	"ADMIN_REQUIRED":                 {LangZH: "需要管理员权限", LangEN: "Administrator privileges required"},
+	"METRICS_TOKEN_INVALID":          {LangZH: "无效的指标访问令牌", LangEN: "Invalid metrics access token"},
+	"REGISTRATION_CLOSED":            {LangZH: "注册已关闭", LangEN: "Registration is closed"},
+	"BETA_CODE_REQUIRED":             {LangZH: "内测期间，注册需要提供内测码", LangEN: "A beta code is required to register during the beta"},
+	"BETA_CODE_CHECK_FAILED":         {LangZH: "验证内测码失败", LangEN: "Failed to verify beta code"},
+	"BETA_CODE_INVALID":              {LangZH: "内测码无效或已被使用", LangEN: "Beta code is invalid or already used"},
+	"EMAIL_ALREADY_REGISTERED":       {LangZH: "邮箱已被注册", LangEN: "Email is already registered"},
+	"PASSWORD_HASH_FAILED":           {LangZH: "密码处理失败", LangEN: "Failed to process password"},
+	"OTP_SECRET_FAILED":              {LangZH: "OTP密钥生成失败", LangEN: "Failed to generate OTP secret"},
+	"CREATE_USER_FAILED":             {LangZH: "创建用户失败: %v", LangEN: "Failed to create user: %v"},
+	"USER_NOT_FOUND":                 {LangZH: "用户不存在", LangEN: "User not found"},
+	"OTP_CODE_INVALID":               {LangZH: "OTP验证码错误", LangEN: "Invalid OTP code"},
+	"VERIFICATION_CODE_INVALID":      {LangZH: "验证码错误", LangEN: "Invalid verification code"},
+	"UPDATE_USER_FAILED":             {LangZH: "更新用户状态失败", LangEN: "Failed to update user status"},
+	"TOKEN_GENERATION_FAILED":        {LangZH: "生成token失败", LangEN: "Failed to generate token"},
+	"INVALID_CREDENTIALS":            {LangZH: "邮箱或密码错误", LangEN: "Incorrect email or password"},
+	"EMAIL_NOT_VERIFIED":             {LangZH: "邮箱未验证，请点击验证邮件中的链接", LangEN: "Email not verified, please click the link in the verification email"},
+	"OTP_SETUP_INCOMPLETE":           {LangZH: "账户未完成OTP设置", LangEN: "OTP setup is not complete for this account"},
+	"REFRESH_TOKEN_MISSING":          {LangZH: "缺少 refresh_token 参数", LangEN: "Missing refresh_token parameter"},
+	"REFRESH_TOKEN_INVALID":          {LangZH: "Refresh Token 无效或已过期", LangEN: "Refresh token is invalid or expired"},
+	"EMAIL_NOT_FOUND":                {LangZH: "邮箱不存在", LangEN: "Email not found"},
+	"OTP_OR_RECOVERY_CODE_INVALID":   {LangZH: "Google Authenticator 验证码或恢复码错误", LangEN: "Invalid Google Authenticator code or recovery code"},
+	"OTP_CODE_INCORRECT":             {LangZH: "Google Authenticator 验证码错误", LangEN: "Invalid Google Authenticator code"},
+	"PASSWORD_UPDATE_FAILED":         {LangZH: "密码更新失败", LangEN: "Failed to update password"},
+	"LOGOUT_ALL_FAILED":              {LangZH: "登出所有设备失败: %v", LangEN: "Failed to log out all devices: %v"},
+	"API_KEY_CANNOT_CHANGE_PASSWORD": {LangZH: "API Key 不能修改密码，请登录后操作", LangEN: "API keys cannot change the password, please sign in first"},
+	"API_KEY_CANNOT_CHANGE_2FA":      {LangZH: "API Key 不能修改两步验证设置，请登录后操作", LangEN: "API keys cannot change two-factor settings, please sign in first"},
+	"API_KEY_CANNOT_MANAGE_KEYS":     {LangZH: "API Key 不能创建或撤销 API Key，请登录后操作", LangEN: "API keys cannot create or revoke API keys, please sign in first"},
+	"API_KEY_ADMIN_FORBIDDEN":        {LangZH: "API Key 不能访问管理员接口，请登录后操作", LangEN: "API keys cannot access admin endpoints, please sign in first"},
+	"CURRENT_PASSWORD_INCORRECT":     {LangZH: "当前密码错误", LangEN: "Current password is incorrect"},
+	"PASSWORD_INCORRECT":             {LangZH: "密码错误", LangEN: "Incorrect password"},
+	"PASSWORD_UNCHANGED":             {LangZH: "新密码不能与当前密码相同", LangEN: "The new password must differ from the current password"},
+	"RECOVERY_CODES_FAILED":          {LangZH: "生成恢复码失败", LangEN: "Failed to generate recovery codes"},
+	"UPDATE_OTP_SECRET_FAILED":       {LangZH: "更新OTP密钥失败", LangEN: "Failed to update OTP secret"},
+	"API_KEY_CHECK_FAILED":           {LangZH: "校验API Key失败", LangEN: "Failed to verify API key"},
+	"API_KEY_INVALID":                {LangZH: "API Key无效或已过期", LangEN: "API key is invalid or expired"},
+	"API_KEY_READ_ONLY":              {LangZH: "只读API Key不允许修改操作", LangEN: "Read-only API keys cannot perform write operations"},
+	"LIST_API_KEYS_FAILED":           {LangZH: "获取API Key列表失败", LangEN: "Failed to list API keys"},
+	"API_KEY_EXPIRY_INVALID":         {LangZH: "expires_in_days 必须在 0-3650 之间（0 表示永不过期）", LangEN: "expires_in_days must be between 0 and 3650 (0 means never expires)"},
+
+	// 邮件
+	"EMAIL_VERIFY_LINK_INVALID":  {LangZH: "验证链接无效或已过期，请重新发送验证邮件", LangEN: "The verification link is invalid or expired, please request a new one"},
+	"EMAIL_VERIFY_FAILED":        {LangZH: "验证邮箱失败", LangEN: "Failed to verify email"},
+	"EMAIL_NOT_CONFIGURED":       {LangZH: "未配置邮件服务", LangEN: "Email service is not configured"},
+	"EMAIL_RESET_NOT_CONFIGURED": {LangZH: "未配置邮件服务，请使用 Google Authenticator 验证码重置密码", LangEN: "Email service is not configured, please reset your password with a Google Authenticator code"},
+	"RESET_LINK_INVALID":         {LangZH: "重置链接无效或已过期，请重新申请", LangEN: "The reset link is invalid or expired, please request a new one"},
+	"RESET_LINK_CHECK_FAILED":    {LangZH: "校验重置链接失败", LangEN: "Failed to verify reset link"},
+	"LINK_BASE_URL_INVALID":      {LangZH: "link_base_url 必须是 http(s) 地址", LangEN: "link_base_url must be an http(s) URL"},
+	"SEND_TEST_EMAIL_FAILED":     {LangZH: "发送测试邮件失败: %v", LangEN: "Failed to send test email: %v"},
+
+	// 交易员
+	"TRADER_NOT_FOUND":             {LangZH: "交易员不存在", LangEN: "Trader not found"},
+	"TRADER_NOT_ACCESSIBLE":        {LangZH: "交易员不存在或无访问权限", LangEN: "Trader not found or access denied"},
+	"NO_TRADER_AVAILABLE":          {LangZH: "没有可用的trader", LangEN: "No trader available"},
+	"TRADER_ID_REQUIRED":           {LangZH: "交易员ID不能为空", LangEN: "Trader ID is required"},
+	"TRADER_NOT_LOADED":            {LangZH: "交易员未加载到内存", LangEN: "Trader is not loaded"},
+	"TRADER_NOT_RUNNING":           {LangZH: "交易员未运行", LangEN: "Trader is not running"},
+	"TRADER_ALREADY_RUNNING":       {LangZH: "交易员已在运行中", LangEN: "Trader is already running"},
+	"TRADER_ALREADY_STOPPED":       {LangZH: "交易员已停止", LangEN: "Trader is already stopped"},
+	"TRADER_NAME_TAKEN":            {LangZH: "交易员名称 '%s' 已存在，请使用其他名称", LangEN: "Trader name '%s' already exists, please choose another name"},
+	"CHECK_TRADER_NAME_FAILED":     {LangZH: "检查交易员名称失败: %v", LangEN: "Failed to check trader name: %v"},
+	"CREATE_PAPER_ACCOUNT_FAILED":  {LangZH: "开通模拟盘失败: %v", LangEN: "Failed to create paper trading account: %v"},
+	"CREATE_TRADER_FAILED":         {LangZH: "创建交易员失败: %v", LangEN: "Failed to create trader: %v"},
+	"UPDATE_TRADER_FAILED":         {LangZH: "更新交易员失败: %v", LangEN: "Failed to update trader: %v"},
+	"DELETE_TRADER_FAILED":         {LangZH: "删除交易员失败: %v", LangEN: "Failed to delete trader: %v"},
+	"PURGE_TRADER_FAILED":          {LangZH: "永久删除交易员失败: %v", LangEN: "Failed to permanently delete trader: %v"},
+	"LIST_ARCHIVED_TRADERS_FAILED": {LangZH: "获取归档交易员失败: %v", LangEN: "Failed to list archived traders: %v"},
+	"RESTORE_TRADER_FAILED":        {LangZH: "恢复交易员失败: %v", LangEN: "Failed to restore trader: %v"},
+	"START_TRADER_FAILED":          {LangZH: "启动交易员失败: %s", LangEN: "Failed to start trader: %s"},
+	"STOP_TRADER_FAILED":           {LangZH: "停止交易员失败: %s", LangEN: "Failed to stop trader: %s"},
+	"BULK_ACTION_UNSUPPORTED":      {LangZH: "不支持的操作: %s（仅支持 start/stop）", LangEN: "Unsupported action: %s (only start/stop are supported)"},
+	"BULK_TARGET_REQUIRED":         {LangZH: "请提供 trader_ids 或设置 all=true", LangEN: "Provide trader_ids or set all=true"},
+	"LIST_TRADERS_FAILED":          {LangZH: "获取交易员列表失败: %v", LangEN: "Failed to list traders: %v"},
+	"TRADER_DATA_INVALID":          {LangZH: "交易员数据格式错误", LangEN: "Invalid trader data format"},
+	"GET_TRADER_CONFIG_FAILED":     {LangZH: "获取交易员配置失败: %v", LangEN: "Failed to load trader configuration: %v"},
+	"UPDATE_PROMPT_FAILED":         {LangZH: "更新自定义prompt失败: %v", LangEN: "Failed to update custom prompt: %v"},
+	"DRY_RUN_BUSY":                 {LangZH: "交易员正在执行决策周期，请稍后重试", LangEN: "The trader is running a decision cycle, please retry later"},
+
+	// 交易员参数校验
+	"LEVERAGE_BTC_ETH_OUT_OF_RANGE":  {LangZH: "BTC/ETH杠杆必须在1-50倍之间", LangEN: "BTC/ETH leverage must be between 1x and 50x"},
+	"LEVERAGE_ALTCOIN_OUT_OF_RANGE":  {LangZH: "山寨币杠杆必须在1-20倍之间", LangEN: "Altcoin leverage must be between 1x and 20x"},
+	"TAKER_FEE_OUT_OF_RANGE":         {LangZH: "Taker费率必须在0-1%之间", LangEN: "Taker fee rate must be between 0% and 1%"},
+	"MAKER_FEE_OUT_OF_RANGE":         {LangZH: "Maker费率必须在0-1%之间", LangEN: "Maker fee rate must be between 0% and 1%"},
+	"MAX_DAILY_TRADES_NEGATIVE":      {LangZH: "每日最多开仓次数不能为负数", LangEN: "Max daily trades cannot be negative"},
+	"HOLD_CACHE_PCT_OUT_OF_RANGE":    {LangZH: "持有决策缓存阈值必须在 0-%.0f%% 之间", LangEN: "Hold decision cache threshold must be between 0 and %.0f%%"},
+	"EXPOSURE_MULTIPLE_OUT_OF_RANGE": {LangZH: "总敞口倍数上限必须在 0-%.0f 之间", LangEN: "Max exposure multiple must be between 0 and %.0f"},
+	"EQUITY_ALERT_PCT_OUT_OF_RANGE":  {LangZH: "净值预警阈值必须在 0-100% 之间", LangEN: "Equity alert threshold must be between 0% and 100%"},
+	"UNFUNDED_THRESHOLD_NEGATIVE":    {LangZH: "未入金判定阈值不能为负数", LangEN: "Unfunded balance threshold cannot be negative"},
+	"DAILY_AI_CALL_LIMIT_NEGATIVE":   {LangZH: "每日AI调用上限不能为负数", LangEN: "Daily AI call limit cannot be negative"},
+	"POSITION_LIMITS_NEGATIVE":       {LangZH: "持仓数量上限和单笔仓位上限不能为负数", LangEN: "Max positions and max position size cannot be negative"},
+	"MAX_POSITIONS_NEGATIVE":         {LangZH: "持仓数量上限不能为负数", LangEN: "Max positions cannot be negative"},
+	"MAX_POSITION_SIZE_NEGATIVE":     {LangZH: "单笔仓位上限不能为负数", LangEN: "Max position size cannot be negative"},
+	"MODEL_POOL_STRATEGY_INVALID":    {LangZH: "模型池选择方式只支持 round_robin 或 random", LangEN: "Model pool strategy must be round_robin or random"},
+	"FALLBACK_MODEL_NOT_FOUND":       {LangZH: "备用AI模型 %s 不存在", LangEN: "Fallback AI model %s does not exist"},
+	"LOG_LEVEL_INVALID":              {LangZH: "无效的日志级别 %s（可选 debug/info/warn/error）", LangEN: "Invalid log level %s (expected debug/info/warn/error)"},
+
+	// AI 模型 / 交易所
+	"GET_AI_MODELS_FAILED":           {LangZH: "获取AI模型配置失败: %v", LangEN: "Failed to load AI model configuration: %v"},
+	"AI_MODEL_NOT_FOUND":             {LangZH: "AI模型 %s 不存在", LangEN: "AI model %s does not exist"},
+	"UPDATE_AI_MODEL_FAILED":         {LangZH: "更新模型 %s 失败: %v", LangEN: "Failed to update model %s: %v"},
+	"PROMPT_AFFIX_TOO_LONG":          {LangZH: "前缀/后缀长度不能超过 %d 个字符", LangEN: "Prefix/suffix cannot exceed %d characters"},
+	"UPDATE_MODEL_PROMPT_FAILED":     {LangZH: "更新模型 %s 的提示词前缀/后缀失败: %v", LangEN: "Failed to update prompt prefix/suffix for model %s: %v"},
+	"GET_SUPPORTED_MODELS_FAILED":    {LangZH: "获取支持的AI模型失败", LangEN: "Failed to load supported AI models"},
+	"GET_EXCHANGES_FAILED":           {LangZH: "获取交易所配置失败: %v", LangEN: "Failed to load exchange configuration: %v"},
+	"EXCHANGE_NOT_FOUND":             {LangZH: "交易所 %s 不存在", LangEN: "Exchange %s does not exist"},
+	"UPDATE_EXCHANGE_FAILED":         {LangZH: "更新交易所 %s 失败: %v", LangEN: "Failed to update exchange %s: %v"},
+	"GET_SUPPORTED_EXCHANGES_FAILED": {LangZH: "获取支持的交易所失败", LangEN: "Failed to load supported exchanges"},
+	"EXCHANGE_NOT_CONFIGURED":        {LangZH: "交易所未配置或未启用", LangEN: "Exchange is not configured or not enabled"},
+	"PAPER_TRADER_NO_SYNC":           {LangZH: "模拟盘交易员无需同步余额", LangEN: "Paper traders do not need balance sync"},
+	"QUERY_BALANCE_FAILED":           {LangZH: "查询余额失败: %v", LangEN: "Failed to query balance: %v"},
+	"UPDATE_BALANCE_FAILED":          {LangZH: "更新余额失败", LangEN: "Failed to update balance"},
+	"SAVE_ACCOUNT_FAILED":            {LangZH: "保存账户配置失败: %v", LangEN: "Failed to save account configuration: %v"},
+	"ACCOUNT_ID_INVALID":             {LangZH: "无效的账户ID", LangEN: "Invalid account ID"},
+	"EXCHANGE_ID_REQUIRED":           {LangZH: "exchange_id 不能为空", LangEN: "exchange_id is required"},
+	"MODEL_ID_REQUIRED":              {LangZH: "model_id 不能为空", LangEN: "model_id is required"},
+
+	// 用户设置
+	"SAVE_SIGNAL_SOURCE_FAILED":       {LangZH: "保存用户信号源配置失败: %v", LangEN: "Failed to save signal source configuration: %v"},
+	"GET_DISPLAY_CURRENCY_FAILED":     {LangZH: "获取展示币种失败: %v", LangEN: "Failed to load display currency: %v"},
+	"DISPLAY_CURRENCY_UNSUPPORTED":    {LangZH: "不支持的展示币种: %s", LangEN: "Unsupported display currency: %s"},
+	"SAVE_DISPLAY_CURRENCY_FAILED":    {LangZH: "保存展示币种失败: %v", LangEN: "Failed to save display currency: %v"},
+	"GET_PROXY_FAILED":                {LangZH: "获取出站代理失败: %v", LangEN: "Failed to load outbound proxy: %v"},
+	"SAVE_PROXY_FAILED":               {LangZH: "保存出站代理失败: %v", LangEN: "Failed to save outbound proxy: %v"},
+	"GET_WEBHOOK_FAILED":              {LangZH: "获取Webhook配置失败: %v", LangEN: "Failed to load webhook configuration: %v"},
+	"WEBHOOK_URL_INVALID":             {LangZH: "Webhook地址必须是有效的 http/https URL", LangEN: "Webhook URL must be a valid http/https URL"},
+	"WEBHOOK_URL_FORBIDDEN":           {LangZH: "Webhook地址不能指向本机或内网地址", LangEN: "Webhook URL must not point to a loopback or private network address"},
+	"EVENT_TYPE_UNSUPPORTED":          {LangZH: "不支持的事件类型: %s", LangEN: "Unsupported event type: %s"},
+	"SAVE_WEBHOOK_FAILED":             {LangZH: "保存Webhook配置失败: %v", LangEN: "Failed to save webhook configuration: %v"},
+	"DELETE_WEBHOOK_FAILED":           {LangZH: "删除Webhook配置失败: %v", LangEN: "Failed to delete webhook configuration: %v"},
+	"GET_NOTIFICATIONS_FAILED":        {LangZH: "获取通知配置失败: %v", LangEN: "Failed to load notification settings: %v"},
+	"TELEGRAM_TOKEN_INVALID":          {LangZH: "请提供有效的 Telegram 机器人令牌（格式: 123456:ABC...）", LangEN: "Please provide a valid Telegram bot token (format: 123456:ABC...)"},
+	"SAVE_NOTIFICATIONS_FAILED":       {LangZH: "保存通知配置失败: %v", LangEN: "Failed to save notification settings: %v"},
+	"DELETE_NOTIFICATIONS_FAILED":     {LangZH: "删除通知配置失败: %v", LangEN: "Failed to delete notification settings: %v"},
+	"TELEGRAM_NOT_CONFIGURED":         {LangZH: "尚未配置 Telegram 通知", LangEN: "Telegram notifications are not configured"},
+	"SEND_TEST_MESSAGE_FAILED":        {LangZH: "发送测试消息失败: %v", LangEN: "Failed to send test message: %v"},
+	"HISTORY_DELETE_CONFIRM_REQUIRED": {LangZH: "删除历史数据不可恢复，请添加参数 confirm=true 确认", LangEN: "Deleting history cannot be undone, add confirm=true to proceed"},
+	"DELETE_TRADE_HISTORY_FAILED":     {LangZH: "删除交易历史失败: %v", LangEN: "Failed to delete trade history: %v"},
+	"DELETE_DECISION_LOGS_FAILED":     {LangZH: "删除交易员 %s 的决策记录失败: %v", LangEN: "Failed to delete decision logs for trader %s: %v"},
+	"PUBLIC_IP_UNAVAILABLE":           {LangZH: "无法获取公网IP地址", LangEN: "Unable to determine the public IP address"},
+
+	// 查询参数
+	"LIMIT_OUT_OF_RANGE_365":        {LangZH: "limit 必须在 1-365 之间", LangEN: "limit must be between 1 and 365"},
+	"LIMIT_OUT_OF_RANGE":            {LangZH: "limit 必须在 1-%d 之间", LangEN: "limit must be between 1 and %d"},
+	"OFFSET_INVALID":                {LangZH: "offset 必须是非负整数", LangEN: "offset must be a non-negative integer"},
+	"TIME_RANGE_FROM_AFTER_TO":      {LangZH: "from 必须早于 to", LangEN: "from must be earlier than to"},
+	"TIME_RANGE_TO_BEFORE_FROM":     {LangZH: "to 必须晚于 from", LangEN: "to must be later than from"},
+	"TIME_RANGE_UNTIL_BEFORE_SINCE": {LangZH: "until 必须晚于 since", LangEN: "until must be later than since"},
+	"TIME_RANGE_TOO_LONG":           {LangZH: "查询跨度不能超过30天", LangEN: "The query range cannot exceed 30 days"},
+	"PERIOD_INVALID":                {LangZH: "无效的 period: %s（可选 7d、30d、all）", LangEN: "Invalid period: %s (allowed: 7d, 30d, all)"},
+	"BUCKET_INVALID":                {LangZH: "bucket 参数无效，可选 hour/day/week", LangEN: "Invalid bucket, allowed: hour/day/week"},
+	"PARAM_INVALID":                 {LangZH: "无效的 %s: %s", LangEN: "Invalid %s: %s"},
+	"SYMBOL_REQUIRED":               {LangZH: "缺少 symbol 参数", LangEN: "Missing symbol parameter"},
+	"EXPORT_FORMAT_UNSUPPORTED":     {LangZH: "不支持的导出格式: %s（仅支持 csv）", LangEN: "Unsupported export format: %s (only csv is supported)"},
+
+	// 统计 / 日志
+	"GET_ACCOUNT_FAILED":              {LangZH: "获取账户信息失败: %v", LangEN: "Failed to load account info: %v"},
+	"GET_POSITIONS_FAILED":            {LangZH: "获取持仓列表失败: %v", LangEN: "Failed to load positions: %v"},
+	"GET_DECISIONS_FAILED":            {LangZH: "获取决策日志失败: %v", LangEN: "Failed to load decision logs: %v"},
+	"GET_STATISTICS_FAILED":           {LangZH: "获取统计信息失败: %v", LangEN: "Failed to load statistics: %v"},
+	"GET_SUCCESS_RATE_FAILED":         {LangZH: "获取决策成功率失败: %v", LangEN: "Failed to load decision success rate: %v"},
+	"GET_COMPETITION_FAILED":          {LangZH: "获取竞赛数据失败: %v", LangEN: "Failed to load competition data: %v"},
+	"GET_HISTORY_FAILED":              {LangZH: "获取历史数据失败: %v", LangEN: "Failed to load history: %v"},
+	"INITIAL_BALANCE_UNAVAILABLE":     {LangZH: "无法获取初始余额", LangEN: "Unable to determine the initial balance"},
+	"ANALYZE_PERFORMANCE_FAILED":      {LangZH: "分析历史表现失败: %v", LangEN: "Failed to analyze performance: %v"},
+	"GET_TOP_TRADERS_FAILED":          {LangZH: "获取前10名交易员数据失败: %v", LangEN: "Failed to load top 10 traders: %v"},
+	"GET_TOP5_TRADERS_FAILED":         {LangZH: "获取前5名交易员失败: %v", LangEN: "Failed to load top 5 traders: %v"},
+	"GET_DAILY_REPORTS_FAILED":        {LangZH: "获取每日报告失败: %v", LangEN: "Failed to load daily reports: %v"},
+	"GET_EXCHANGE_FILLS_FAILED":       {LangZH: "获取交易所成交记录失败: %v", LangEN: "Failed to load exchange fills: %v"},
+	"GET_REJECTED_DECISIONS_FAILED":   {LangZH: "获取被拒绝的决策失败: %v", LangEN: "Failed to load rejected decisions: %v"},
+	"COUNT_REJECTED_DECISIONS_FAILED": {LangZH: "统计被拒绝的决策失败: %v", LangEN: "Failed to summarize rejected decisions: %v"},
+	"GET_FEES_FAILED":                 {LangZH: "统计手续费失败: %v", LangEN: "Failed to summarize fees: %v"},
+	"GET_TRADE_HISTORY_FAILED":        {LangZH: "获取交易历史失败: %v", LangEN: "Failed to load trade history: %v"},
+	"GET_AUDIT_LOG_FAILED":            {LangZH: "获取审计日志失败: %v", LangEN: "Failed to load audit log: %v"},
+	"GET_TRADE_STATS_FAILED":          {LangZH: "统计交易失败: %v", LangEN: "Failed to compute trade statistics: %v"},
+	"GET_TAG_STATS_FAILED":            {LangZH: "按标签统计失败: %v", LangEN: "Failed to compute statistics by tag: %v"},
+	"SERIALIZE_COMPETITION_FAILED":    {LangZH: "序列化竞赛数据失败: %v", LangEN: "Failed to serialize competition data: %v"},
+	"SAVE_SNAPSHOT_FAILED":            {LangZH: "保存竞赛快照失败: %v", LangEN: "Failed to save competition snapshot: %v"},
+	"SNAPSHOT_NOT_FOUND":              {LangZH: "快照不存在", LangEN: "Snapshot not found"},
+	"PARSE_SNAPSHOT_FAILED":           {LangZH: "解析快照数据失败: %v", LangEN: "Failed to parse snapshot data: %v"},
+	"ENCRYPTION_KEY_MISSING":          {LangZH: "未配置数据加密密钥（DATA_ENCRYPTION_KEY），无法加密决策记录", LangEN: "No data encryption key (DATA_ENCRYPTION_KEY) configured, decision logs cannot be encrypted"},
+	"READ_DECISION_LOG_DIR_FAILED":    {LangZH: "读取决策记录目录失败", LangEN: "Failed to read the decision log directory"},
+	"ENCRYPT_DECISION_LOGS_FAILED":    {LangZH: "加密交易员 %s 的决策记录失败: %v", LangEN: "Failed to encrypt decision logs for trader %s: %v"},
+
+	// 提示词模板
+	"TEMPLATE_NOT_FOUND":           {LangZH: "模板不存在: %s", LangEN: "Template not found: %s"},
+	"TEMPLATE_EXISTS":              {LangZH: "模板已存在: %s", LangEN: "Template already exists: %s"},
+	"CREATE_TEMPLATE_FAILED":       {LangZH: "创建模板失败: %v", LangEN: "Failed to create template: %v"},
+	"UPDATE_TEMPLATE_FAILED":       {LangZH: "更新模板失败: %v", LangEN: "Failed to update template: %v"},
+	"TEMPLATE_VERSION_INVALID":     {LangZH: "无效的版本号: %s", LangEN: "Invalid version: %s"},
+	"TEMPLATE_VERSION_NOT_FOUND":   {LangZH: "模板 %s 不存在版本 v%d", LangEN: "Template %s has no version v%d"},
+	"ROLLBACK_TEMPLATE_FAILED":     {LangZH: "回滚模板失败: %v", LangEN: "Failed to roll back template: %v"},
+	"DELETE_TEMPLATE_FAILED":       {LangZH: "删除模板失败: %v", LangEN: "Failed to delete template: %v"},
+	"SAVE_DEFAULT_TEMPLATE_FAILED": {LangZH: "保存系统默认模板失败: %v", LangEN: "Failed to save the system default template: %v"},
+	"GET_OPEN_ORDERS_FAILED":       {LangZH: "获取挂单列表失败: %v", LangEN: "Failed to load open orders: %v"},
+	"RELOAD_TEMPLATES_FAILED":      {LangZH: "重新加载失败: %v", LangEN: "Failed to reload: %v"},
+
+	// 交易员配置模板
+	"TRADER_TEMPLATE_NOT_FOUND":     {LangZH: "交易员模板不存在", LangEN: "Trader template not found"},
+	"TRADER_TEMPLATE_NAME_TAKEN":    {LangZH: "交易员模板名称 '%s' 已存在", LangEN: "Trader template name '%s' already exists"},
+	"TRADER_TEMPLATE_READONLY":      {LangZH: "系统模板不能删除", LangEN: "System templates cannot be deleted"},
+	"TRADER_TEMPLATE_INVALID":       {LangZH: "交易员模板配置无效: %v", LangEN: "Invalid trader template configuration: %v"},
+	"GET_TRADER_TEMPLATES_FAILED":   {LangZH: "获取交易员模板失败: %v", LangEN: "Failed to load trader templates: %v"},
+	"CREATE_TRADER_TEMPLATE_FAILED": {LangZH: "保存交易员模板失败: %v", LangEN: "Failed to save trader template: %v"},
+	"DELETE_TRADER_TEMPLATE_FAILED": {LangZH: "删除交易员模板失败: %v", LangEN: "Failed to delete trader template: %v"},
+
+	// 管理员
+	"BETA_CODE_COUNT_INVALID":       {LangZH: "count 必须是 1-%d 之间的整数", LangEN: "count must be an integer between 1 and %d"},
+	"GENERATE_BETA_CODES_FAILED":    {LangZH: "生成内测码失败: %v", LangEN: "Failed to generate beta codes: %v"},
+	"LIST_BETA_CODES_FAILED":        {LangZH: "获取内测码失败: %v", LangEN: "Failed to list beta codes: %v"},
+	"GET_BETA_CODE_STATS_FAILED":    {LangZH: "获取内测码统计失败: %v", LangEN: "Failed to load beta code statistics: %v"},
+	"LIST_USERS_FAILED":             {LangZH: "获取用户列表失败: %v", LangEN: "Failed to list users: %v"},
+	"CANNOT_MODIFY_SELF":            {LangZH: "不能禁用或删除管理员自身账户", LangEN: "You cannot disable or delete your own admin account"},
+	"DISABLE_USER_FAILED":           {LangZH: "禁用用户失败: %v", LangEN: "Failed to disable user: %v"},
+	"ENABLE_USER_FAILED":            {LangZH: "启用用户失败: %v", LangEN: "Failed to enable user: %v"},
+	"DELETE_USER_FAILED":            {LangZH: "删除用户失败: %v", LangEN: "Failed to delete user: %v"},
+	"MAINTENANCE_RESUME_AT_INVALID": {LangZH: "resume_at 格式错误，需要 RFC3339 时间（如 2025-01-01T08:00:00Z）", LangEN: "resume_at must be an RFC3339 time (e.g. 2025-01-01T08:00:00Z)"},
+	"MAINTENANCE_RESUME_AT_PAST":    {LangZH: "resume_at 必须晚于当前时间", LangEN: "resume_at must be in the future"},
+	"SAVE_MAINTENANCE_FAILED":       {LangZH: "保存维护模式配置失败", LangEN: "Failed to save maintenance mode settings"},
+	"BACKUP_FAILED":                 {LangZH: "备份数据库失败: %v", LangEN: "Failed to back up the database: %v"},
+	"LIST_BACKUPS_FAILED":           {LangZH: "获取备份列表失败: %v", LangEN: "Failed to list backups: %v"},
+	"BACKUP_NOT_FOUND":              {LangZH: "备份文件不存在", LangEN: "Backup not found"},
+
+	// 挂单
+	"ORDER_ID_INVALID":         {LangZH: "无效的订单ID", LangEN: "Invalid order ID"},
+	"CANCEL_ORDER_FAILED":      {LangZH: "撤单失败: %v", LangEN: "Failed to cancel order: %v"},
+	"CANCEL_ORDER_UNSUPPORTED": {LangZH: "该交易所不支持按订单ID撤单", LangEN: "This exchange does not support cancelling orders by ID"},
+	"OPEN_ORDER_NOT_FOUND":     {LangZH: "挂单不存在或已成交", LangEN: "The order does not exist or has already been filled"},
+
+	// 手动平仓
+	"POSITION_SIDE_INVALID": {LangZH: "side 必须是 long 或 short", LangEN: "side must be long or short"},
+	"CLOSE_PERCENT_INVALID": {LangZH: "平仓百分比必须在 1-100 之间", LangEN: "percentage must be between 1 and 100"},
+	"POSITION_NOT_FOUND":    {LangZH: "持仓不存在或已平仓", LangEN: "The position does not exist or has already been closed"},
+	"TRADER_BUSY":           {LangZH: "交易员正在执行决策周期，请稍后重试", LangEN: "The trader is running a decision cycle, please retry later"},
+	"CLOSE_POSITION_FAILED": {LangZH: "平仓失败: %v", LangEN: "Failed to close the position: %v"},
+
+	// 持仓备注
+	"POSITION_NOTE_TOO_LONG":   {LangZH: "备注不能超过 %d 个字符", LangEN: "The note must not exceed %d characters"},
+	"ANNOTATE_POSITION_FAILED": {LangZH: "保存持仓备注失败: %v", LangEN: "Failed to save the position note: %v"},
+
+	// 解密接口
+	"DECRYPT_API_DISABLED": {LangZH: "解密接口已禁用", LangEN: "Decrypt API disabled"},
+	"UNAUTHORIZED":         {LangZH: "未授权", LangEN: "Unauthorized"},
+	"BAD_REQUEST":          {LangZH: "无效的请求", LangEN: "Invalid request"},
+	"AAD_MISSING":          {LangZH: "缺少 AAD 元数据", LangEN: "Missing AAD metadata"},
+	"AAD_ENCODING_INVALID": {LangZH: "AAD 编码无效", LangEN: "Invalid AAD encoding"},
+	"AAD_PAYLOAD_INVALID":  {LangZH: "AAD 数据无效", LangEN: "Invalid AAD payload"},
+	"AAD_MISMATCH":         {LangZH: "AAD 不匹配，拒绝解密请求", LangEN: "AAD mismatch: unauthorized decrypt request"},
+	"DECRYPTION_FAILED":    {LangZH: "解密失败", LangEN: "Decryption failed"},
+
+	// 中间件
+	"RATE_LIMITED":        {LangZH: "请求过于频繁，请稍后再试", LangEN: "Too many requests, please try again later"},
+	"AUTH_RATE_LIMITED":   {LangZH: "登录尝试次数过多，请 30 秒后重试", LangEN: "Too many login attempts, please retry in 30 seconds"},
+	"STRICT_RATE_LIMITED": {LangZH: "操作过于频繁，请稍后再试", LangEN: "Too many operations, please try again later"},
+	"CSRF_COOKIE_MISSING": {LangZH: "CSRF Cookie 缺失", LangEN: "CSRF token missing in cookie"},
+	"CSRF_HEADER_MISSING": {LangZH: "CSRF 请求头缺失", LangEN: "CSRF token missing in header"},
+	"CSRF_TOKEN_MISMATCH": {LangZH: "CSRF Token 不匹配", LangEN: "CSRF token mismatch"},
+
+	// 决策执行：守卫拒绝原因（trader 拒绝原因代码的大写形式）
+	"DAILY_TRADE_LIMIT":   {LangZH: "每日开仓次数已达上限", LangEN: "Daily open-trade limit reached"},
+	"POSITION_EXISTS":     {LangZH: "已有同币种同方向持仓", LangEN: "A position in the same symbol and direction already exists"},
+	"SYMBOL_HALTED":       {LangZH: "币种暂停交易或交易所维护", LangEN: "The symbol is halted or the exchange is under maintenance"},
+	"SYMBOL_CAP":          {LangZH: "全实例币种持仓上限已满", LangEN: "The instance-wide per-symbol position cap has been reached"},
+	"PRICE_CHECK":         {LangZH: "多数据源价格校验未通过", LangEN: "Cross-source price verification failed"},
+	"INSUFFICIENT_MARGIN": {LangZH: "保证金不足", LangEN: "Insufficient margin"},
+	"INVALID_STOPS":       {LangZH: "止损/止盈价格不合理", LangEN: "Invalid stop-loss/take-profit prices"},
+	"INVALID_LIMIT_PRICE": {LangZH: "限价不合理", LangEN: "Invalid limit price"},
+	"EXPOSURE_LIMIT":      {LangZH: "总敞口超过账户净值的上限倍数", LangEN: "Total exposure exceeds the allowed multiple of account equity"},
+	"SIGNAL_BIAS":         {LangZH: "开仓方向与信号源方向偏好相反", LangEN: "The position direction conflicts with the signal source bias"},
+	"UNFUNDED":            {LangZH: "账户未入金", LangEN: "The account is not funded"},
+	"NON_CANDIDATE":       {LangZH: "开仓币种不在本周期候选列表中", LangEN: "The symbol is not in this cycle's candidate list"},
+	"POSITION_LIMIT":      {LangZH: "持仓数量或单笔仓位超过交易员上限", LangEN: "Position count or position size exceeds the trader limit"},
+	"BLACKLISTED":         {LangZH: "开仓币种在黑名单中", LangEN: "The symbol is blacklisted"},
+	"POSITION_LOCKED":     {LangZH: "持仓已被人工锁定，拒绝平仓", LangEN: "The position is locked by the user; closing rejected"},
+	"EXECUTION_FAILED":    {LangZH: "决策执行失败", LangEN: "Decision execution failed"},
+
+	// 决策执行：开仓校验的详细信息（中文同时作为AI反馈；日志 JSON 往返后数字参数均为 float64，只能使用 %s/%v/%.2f）
+	"POSITION_EXISTS_LONG":          {LangZH: "❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", LangEN: "❌ %s already has a long position; opening rejected to avoid stacking positions. Issue close_long first to switch"},
+	"POSITION_EXISTS_SHORT":         {LangZH: "❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", LangEN: "❌ %s already has a short position; opening rejected to avoid stacking positions. Issue close_short first to switch"},
+	"PENDING_ENTRY_EXISTS_LONG":     {LangZH: "❌ %s 已有挂单中的限价开多单，拒绝重复开仓。如需调整入场价，请先给出 cancel_order 决策", LangEN: "❌ %s already has a resting limit long entry; opening rejected to avoid duplicate entries. Issue cancel_order first to reprice"},
+	"PENDING_ENTRY_EXISTS_SHORT":    {LangZH: "❌ %s 已有挂单中的限价开空单，拒绝重复开仓。如需调整入场价，请先给出 cancel_order 决策", LangEN: "❌ %s already has a resting limit short entry; opening rejected to avoid duplicate entries. Issue cancel_order first to reprice"},
+	"INSUFFICIENT_MARGIN_DETAIL":    {LangZH: "❌ 保证金不足: 需要 %.2f USDT（保证金 %.2f + 手续费 %.2f），可用 %.2f USDT", LangEN: "❌ Insufficient margin: %.2f USDT required (margin %.2f + fee %.2f), %.2f USDT available"},
+	"STOPS_REQUIRED_LONG":           {LangZH: "❌ 多单开仓失败：止损价 %.2f 和止盈价 %.2f 必须大于 0。建议：AI 必须为每个开仓决策设置合理的止损和止盈价格", LangEN: "❌ Long entry failed: stop loss %.2f and take profit %.2f must be greater than 0. Every entry decision must set a reasonable stop loss and take profit"},
+	"STOPS_REQUIRED_SHORT":          {LangZH: "❌ 空单开仓失败：止损价 %.2f 和止盈价 %.2f 必须大于 0。建议：AI 必须为每个开仓决策设置合理的止损和止盈价格", LangEN: "❌ Short entry failed: stop loss %.2f and take profit %.2f must be greater than 0. Every entry decision must set a reasonable stop loss and take profit"},
+	"LONG_STOP_LOSS_ABOVE_PRICE":    {LangZH: "❌ 多单止损价不合理：止损价 %.2f 必须低于当前价 %.2f (当前高出 %.2f%%)。建议：AI 应设置低于当前价的止损价，例如 %.2f", LangEN: "❌ Invalid long stop loss: %.2f must be below the current price %.2f (currently %.2f%% above). Suggested stop loss, e.g. %.2f"},
+	"LONG_TAKE_PROFIT_BELOW_PRICE":  {LangZH: "❌ 多单止盈价不合理：止盈价 %.2f 必须高于当前价 %.2f (当前低于 %.2f%%)。建议：AI 应设置高于当前价的止盈价，例如 %.2f", LangEN: "❌ Invalid long take profit: %.2f must be above the current price %.2f (currently %.2f%% below). Suggested take profit, e.g. %.2f"},
+	"SHORT_STOP_LOSS_BELOW_PRICE":   {LangZH: "❌ 空单止损价不合理：止损价 %.2f 必须高于当前价 %.2f (当前低于 %.2f%%)。建议：AI 应设置高于当前价的止损价，例如 %.2f", LangEN: "❌ Invalid short stop loss: %.2f must be above the current price %.2f (currently %.2f%% below). Suggested stop loss, e.g. %.2f"},
+	"SHORT_TAKE_PROFIT_ABOVE_PRICE": {LangZH: "❌ 空单止盈价不合理：止盈价 %.2f 必须低于当前价 %.2f (当前高出 %.2f%%)。建议：AI 应设置低于当前价的止盈价，例如 %.2f", LangEN: "❌ Invalid short take profit: %.2f must be below the current price %.2f (currently %.2f%% above). Suggested take profit, e.g. %.2f"},
+	"POSITION_LOCKED_DETAIL":        {LangZH: "🔒 %s %s 持仓已被人工锁定，拒绝平仓。可以调整止损止盈，解锁后才能平仓", LangEN: "🔒 The %s %s position is locked by the user; closing rejected. Stops can still be adjusted; unlock it before closing"},
+}
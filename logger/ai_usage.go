@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenPriceDefault 价格表中的兜底项，未单独配置价格的模型使用该价格
+const TokenPriceDefault = "default"
+
+// TokenPrices 每个模型每 1000 token 的价格（USD），key 为决策记录中的模型标识（如 "deepseek"、"custom/gpt-4o"）
+type TokenPrices map[string]float64
+
+// ParseTokenPrices 解析 JSON 格式的价格表，例如 {"deepseek": 0.0014, "qwen": 0.004, "default": 0.002}
+func ParseTokenPrices(raw string) (TokenPrices, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return TokenPrices{}, nil
+	}
+	var prices TokenPrices
+	if err := json.Unmarshal([]byte(raw), &prices); err != nil {
+		return nil, fmt.Errorf("token 价格配置格式错误: %w", err)
+	}
+	for model, price := range prices {
+		if price < 0 {
+			return nil, fmt.Errorf("模型 %s 的 token 价格不能为负数", model)
+		}
+	}
+	return prices, nil
+}
+
+// Lookup 查找模型的价格：先精确匹配，再匹配 "/" 前的模型ID，最后使用 default
+func (p TokenPrices) Lookup(model string) (float64, bool) {
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+	if i := strings.Index(model, "/"); i > 0 {
+		if price, ok := p[model[:i]]; ok {
+			return price, true
+		}
+	}
+	price, ok := p[TokenPriceDefault]
+	return price, ok
+}
+
+// ModelUsage 单个模型的 token 用量
+type ModelUsage struct {
+	AICalls          int     `json:"ai_calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	ReportedCost     float64 `json:"reported_cost"`            // 服务商返回的费用合计（USD）
+	PricePer1K       float64 `json:"price_per_1k,omitempty"`   // 配置的每 1000 token 价格
+	EstimatedCost    float64 `json:"estimated_cost,omitempty"` // 估算费用（USD）
+}
+
+// AIUsageStats AI token 用量统计（只统计实际调用了 AI 且服务商返回了用量的周期）
+type AIUsageStats struct {
+	AICalls              int                    `json:"ai_calls"`
+	PromptTokens         int64                  `json:"prompt_tokens"`
+	CompletionTokens     int64                  `json:"completion_tokens"`
+	TotalTokens          int64                  `json:"total_tokens"`
+	TokensPerCycle       float64                `json:"tokens_per_cycle"` // 每次 AI 调用的平均 token 数
+	ReportedCost         float64                `json:"reported_cost"`
+	EstimatedCost        float64                `json:"estimated_cost"`         // 自开始记录以来的估算费用（USD）
+	EstimatedMonthlyCost float64                `json:"estimated_monthly_cost"` // 按当前消耗速度折算的 30 天费用（USD）
+	Since                time.Time              `json:"since,omitempty"`        // 第一条有用量的记录时间
+	Until                time.Time              `json:"until,omitempty"`        // 最后一条有用量的记录时间
+	ByModel              map[string]*ModelUsage `json:"by_model,omitempty"`
+}
+
+// add 累加一条决策记录的用量
+func (u *AIUsageStats) add(record *DecisionRecord) {
+	if record.CachedDecision || record.TotalTokens <= 0 {
+		return
+	}
+	u.AICalls++
+	u.PromptTokens += int64(record.PromptTokens)
+	u.CompletionTokens += int64(record.CompletionTokens)
+	u.TotalTokens += int64(record.TotalTokens)
+	u.ReportedCost += record.AICost
+	if u.Since.IsZero() || record.Timestamp.Before(u.Since) {
+		u.Since = record.Timestamp
+	}
+	if record.Timestamp.After(u.Until) {
+		u.Until = record.Timestamp
+	}
+
+	model := record.ModelUsed
+	if model == "" {
+		model = record.AIModel
+	}
+	if model == "" {
+		model = "unknown"
+	}
+	if u.ByModel == nil {
+		u.ByModel = make(map[string]*ModelUsage)
+	}
+	m := u.ByModel[model]
+	if m == nil {
+		m = &ModelUsage{}
+		u.ByModel[model] = m
+	}
+	m.AICalls++
+	m.PromptTokens += int64(record.PromptTokens)
+	m.CompletionTokens += int64(record.CompletionTokens)
+	m.TotalTokens += int64(record.TotalTokens)
+	m.ReportedCost += record.AICost
+}
+
+// finish 计算平均值，未配置价格时以服务商返回的费用作为估算
+func (u *AIUsageStats) finish() {
+	if u.AICalls > 0 {
+		u.TokensPerCycle = float64(u.TotalTokens) / float64(u.AICalls)
+	}
+	u.ApplyPrices(nil)
+}
+
+// ApplyPrices 按价格表估算费用：配置了价格的模型按 token 数计算，其余使用服务商返回的费用
+// 月度费用按第一条到最后一条记录的消耗速度折算（不足 1 小时按 1 小时计）
+func (u *AIUsageStats) ApplyPrices(prices TokenPrices) {
+	u.EstimatedCost = 0
+	for model, m := range u.ByModel {
+		if price, ok := prices.Lookup(model); ok {
+			m.PricePer1K = price
+			m.EstimatedCost = float64(m.TotalTokens) / 1000 * price
+		} else {
+			m.PricePer1K = 0
+			m.EstimatedCost = m.ReportedCost
+		}
+		u.EstimatedCost += m.EstimatedCost
+	}
+
+	u.EstimatedMonthlyCost = 0
+	if u.AICalls > 0 {
+		elapsed := u.Until.Sub(u.Since)
+		if elapsed < time.Hour {
+			elapsed = time.Hour
+		}
+		u.EstimatedMonthlyCost = u.EstimatedCost / elapsed.Hours() * 24 * 30
+	}
+}
@@ -6,8 +6,10 @@ import (
 
 // Config 日志配置（简化版）
 type Config struct {
-	Level    string          `json:"level"`    // 日志级别: debug, info, warn, error (默认: info)
-	Telegram *TelegramConfig `json:"telegram"` // Telegram推送配置（可选）
+	Level      string          `json:"level"`       // 日志级别: debug, info, warn, error (默认: info)
+	Format     string          `json:"format"`      // 输出格式: text（彩色文本，默认）, json（一行一个 JSON 对象）
+	BufferSize int             `json:"buffer_size"` // 每个交易员在内存中保留的运行日志条数（默认: 500）
+	Telegram   *TelegramConfig `json:"telegram"`    // Telegram推送配置（可选）
 }
 
 // TelegramConfig Telegram推送配置（简化版，高级参数使用默认值）
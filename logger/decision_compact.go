@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 决策记录压缩方式
+const (
+	CompactModeGzip  = "gzip"  // gzip 压缩大文本字段，读取时自动解压
+	CompactModeStrip = "strip" // 直接清空大文本字段（不可恢复）
+)
+
+// compactedText 被压缩的大文本字段
+type compactedText struct {
+	SystemPrompt string `json:"system_prompt"`
+	InputPrompt  string `json:"input_prompt"`
+	CoTTrace     string `json:"cot_trace"`
+}
+
+// readRecordFile 读取决策记录文件，expand=true 时自动解压被压缩的大文本字段
+func readRecordFile(path string, expand bool) (*DecisionRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var record DecisionRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	if expand {
+		if err := expandRecord(&record); err != nil {
+			// 解压失败时仍返回结构化数据，大文本字段保持为空
+			fmt.Printf("⚠ 解压决策记录失败 %s: %v\n", filepath.Base(path), err)
+		}
+	}
+	return &record, nil
+}
+
+// compactRecord 压缩记录中的大文本字段（SystemPrompt/InputPrompt/CoTTrace），结构化数据保持不变
+func compactRecord(record *DecisionRecord, mode string) error {
+	switch mode {
+	case CompactModeStrip:
+		record.CompressedText = ""
+	case CompactModeGzip:
+		raw, err := json.Marshal(compactedText{
+			SystemPrompt: record.SystemPrompt,
+			InputPrompt:  record.InputPrompt,
+			CoTTrace:     record.CoTTrace,
+		})
+		if err != nil {
+			return fmt.Errorf("序列化大文本字段失败: %w", err)
+		}
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(raw); err != nil {
+			return fmt.Errorf("压缩大文本字段失败: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("压缩大文本字段失败: %w", err)
+		}
+		record.CompressedText = base64.StdEncoding.EncodeToString(buf.Bytes())
+	default:
+		return fmt.Errorf("不支持的压缩方式: %s", mode)
+	}
+
+	record.Compression = mode
+	record.SystemPrompt = ""
+	record.InputPrompt = ""
+	record.CoTTrace = ""
+	return nil
+}
+
+// expandRecord 还原 gzip 压缩的大文本字段（strip 方式无法还原，保持为空）
+func expandRecord(record *DecisionRecord) error {
+	if record.Compression != CompactModeGzip || record.CompressedText == "" {
+		return nil
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(record.CompressedText)
+	if err != nil {
+		return fmt.Errorf("解码压缩数据失败: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return fmt.Errorf("读取压缩数据失败: %w", err)
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("解压数据失败: %w", err)
+	}
+
+	var text compactedText
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return fmt.Errorf("解析解压数据失败: %w", err)
+	}
+
+	record.SystemPrompt = text.SystemPrompt
+	record.InputPrompt = text.InputPrompt
+	record.CoTTrace = text.CoTTrace
+	record.CompressedText = ""
+	return nil
+}
+
+// CompactOldRecords 压缩早于 olderThan 的决策记录中的大文本字段，返回本次压缩的记录数
+// 已压缩的记录跳过；重写文件后保留原修改时间，不影响按时间排序和 CleanOldRecords 清理
+func (l *DecisionLogger) CompactOldRecords(olderThan time.Duration, mode string) (int, error) {
+	if olderThan <= 0 {
+		return 0, nil
+	}
+	if mode == "" {
+		mode = CompactModeGzip
+	}
+	if mode != CompactModeGzip && mode != CompactModeStrip {
+		return 0, fmt.Errorf("不支持的压缩方式: %s", mode)
+	}
+
+	files, err := os.ReadDir(l.logDir)
+	if err != nil {
+		return 0, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	cutoffTime := time.Now().Add(-olderThan)
+	compacted := 0
+	var savedBytes int64
+	for _, entry := range files {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoffTime) {
+			continue
+		}
+
+		path := filepath.Join(l.logDir, entry.Name())
+		record, err := readRecordFile(path, false)
+		if err != nil || record.Compression != "" {
+			continue
+		}
+		if record.SystemPrompt == "" && record.InputPrompt == "" && record.CoTTrace == "" {
+			continue
+		}
+
+		if err := compactRecord(record, mode); err != nil {
+			return compacted, err
+		}
+		size, err := rewriteRecordFile(path, record, info.ModTime())
+		if err != nil {
+			fmt.Printf("⚠ 压缩决策记录失败 %s: %v\n", entry.Name(), err)
+			continue
+		}
+		savedBytes += info.Size() - size
+		compacted++
+	}
+
+	if compacted > 0 {
+		fmt.Printf("🗜️ 已压缩 %d 条旧决策记录（%s，节省 %.1f KB）\n", compacted, mode, float64(savedBytes)/1024)
+	}
+	return compacted, nil
+}
+
+// rewriteRecordFile 原子重写记录文件并恢复修改时间，返回新文件大小
+func rewriteRecordFile(path string, record *DecisionRecord, modTime time.Time) (int64, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return 0, fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return 0, fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("替换记录文件失败: %w", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		return 0, fmt.Errorf("恢复修改时间失败: %w", err)
+	}
+	return int64(len(data)), nil
+}
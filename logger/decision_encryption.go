@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"nofx/crypto"
+)
+
+// decisionLogAAD 决策记录字段加密时使用的附加认证数据前缀（与字段名组合，防止密文在字段之间互换）
+const decisionLogAAD = "decision_log"
+
+// SetCryptoService 设置决策记录加密服务
+// 设置后读取时自动解密 SystemPrompt/InputPrompt/CoTTrace；encrypt=true 时新记录写入前加密这些字段
+func (l *DecisionLogger) SetCryptoService(cs *crypto.CryptoService, encrypt bool) {
+	l.cryptoService = cs
+	l.encryptRecords = encrypt && cs != nil && cs.HasDataKey()
+}
+
+// encryptedFields 返回记录中需要加密的大文本字段（字段名 → 字段指针）
+func encryptedFields(record *DecisionRecord) map[string]*string {
+	return map[string]*string{
+		"system_prompt": &record.SystemPrompt,
+		"input_prompt":  &record.InputPrompt,
+		"cot_trace":     &record.CoTTrace,
+	}
+}
+
+// encryptRecord 加密记录中的大文本字段，已加密的字段保持不变
+func (l *DecisionLogger) encryptRecord(record *DecisionRecord) error {
+	for name, field := range encryptedFields(record) {
+		encrypted, err := l.cryptoService.EncryptForStorage(*field, decisionLogAAD, name)
+		if err != nil {
+			return fmt.Errorf("加密字段 %s 失败: %w", name, err)
+		}
+		*field = encrypted
+	}
+	return nil
+}
+
+// decryptRecord 解密记录中的大文本字段，明文（加密功能启用前写入的记录）原样保留
+func (l *DecisionLogger) decryptRecord(record *DecisionRecord) error {
+	if l.cryptoService == nil {
+		return nil
+	}
+	for name, field := range encryptedFields(record) {
+		if !l.cryptoService.IsEncryptedStorageValue(*field) {
+			continue
+		}
+		decrypted, err := l.cryptoService.DecryptFromStorage(*field, decisionLogAAD, name)
+		if err != nil {
+			// 解密失败时清空字段，避免把密文当作提示词返回
+			*field = ""
+			return fmt.Errorf("解密字段 %s 失败: %w", name, err)
+		}
+		*field = decrypted
+	}
+	return nil
+}
+
+// readRecord 读取决策记录文件，expand=true 时解压并解密大文本字段
+func (l *DecisionLogger) readRecord(path string, expand bool) (*DecisionRecord, error) {
+	record, err := readRecordFile(path, expand)
+	if err != nil || !expand {
+		return record, err
+	}
+	if err := l.decryptRecord(record); err != nil {
+		fmt.Printf("⚠ 解密决策记录失败 %s: %v\n", filepath.Base(path), err)
+	}
+	return record, nil
+}
+
+// EncryptExistingRecords 加密目录中历史决策记录的大文本字段（一次性迁移），返回本次加密的记录数
+// 已加密或已清空（strip）的记录跳过，gzip 压缩的记录加密后保持压缩；重写文件后保留原修改时间
+func (l *DecisionLogger) EncryptExistingRecords() (int, error) {
+	if l.cryptoService == nil || !l.cryptoService.HasDataKey() {
+		return 0, fmt.Errorf("未配置数据加密密钥，无法加密决策记录")
+	}
+
+	files, err := os.ReadDir(l.logDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	encrypted := 0
+	for _, entry := range files {
+		if entry.IsDir() || !isRecordFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(l.logDir, entry.Name())
+		record, err := readRecordFile(path, false)
+		if err != nil || record.Compression == CompactModeStrip {
+			continue
+		}
+		// gzip 压缩的记录先解压，加密后再重新压缩
+		compression := record.Compression
+		if err := expandRecord(record); err != nil {
+			fmt.Printf("⚠ 解压决策记录失败 %s: %v\n", entry.Name(), err)
+			continue
+		}
+		if !l.hasPlaintextFields(record) {
+			continue
+		}
+
+		if err := l.encryptRecord(record); err != nil {
+			return encrypted, err
+		}
+		if compression != "" {
+			if err := compactRecord(record, compression); err != nil {
+				return encrypted, err
+			}
+		}
+		if _, err := rewriteRecordFile(path, record, info.ModTime()); err != nil {
+			fmt.Printf("⚠ 加密决策记录失败 %s: %v\n", entry.Name(), err)
+			continue
+		}
+		encrypted++
+	}
+
+	if encrypted > 0 {
+		fmt.Printf("🔐 已加密 %d 条历史决策记录: %s\n", encrypted, l.logDir)
+	}
+	return encrypted, nil
+}
+
+// hasPlaintextFields 记录中是否还有未加密的大文本字段
+func (l *DecisionLogger) hasPlaintextFields(record *DecisionRecord) bool {
+	for _, field := range encryptedFields(record) {
+		if *field != "" && !l.cryptoService.IsEncryptedStorageValue(*field) {
+			return true
+		}
+	}
+	return false
+}
@@ -7,7 +7,11 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+
+	"nofx/crypto"
 )
 
 // DecisionRecord 决策记录
@@ -28,6 +32,26 @@ type DecisionRecord struct {
 	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
 	// AIRequestDurationMs 记录 AI API 调用耗时（毫秒），方便评估调用性能
 	AIRequestDurationMs int64 `json:"ai_request_duration_ms,omitempty"`
+	// AIModel 本周期选择的AI模型（启用模型池时每个周期可能不同）
+	AIModel string `json:"ai_model,omitempty"`
+	// ModelUsed 实际产生决策的AI模型；FallbackUsed 主模型失败后改用了备用模型（此时 ModelUsed 为备用模型）
+	ModelUsed    string `json:"model_used,omitempty"`
+	FallbackUsed bool   `json:"fallback_used,omitempty"`
+	// PromptTemplate/PromptVersion 生成 SystemPrompt 的提示词模板及其版本，用于追溯到具体的模板修订
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	PromptVersion  int    `json:"prompt_version,omitempty"`
+	// CachedDecision 市场变化很小时复用了上一次的持有决策，本周期未调用AI
+	CachedDecision bool `json:"cached_decision,omitempty"`
+	// DryRun 决策预演：只调用AI并校验决策，未执行任何订单（Decisions 为空，校验结果见 ExecutionLog）
+	DryRun bool `json:"dry_run,omitempty"`
+	// PromptTokens/CompletionTokens/TotalTokens 本次 AI 调用的 token 用量，AICost 为服务商返回的费用（USD，未返回为 0）
+	PromptTokens     int     `json:"prompt_tokens,omitempty"`
+	CompletionTokens int     `json:"completion_tokens,omitempty"`
+	TotalTokens      int     `json:"total_tokens,omitempty"`
+	AICost           float64 `json:"ai_cost,omitempty"`
+	// Compression 大文本字段的压缩方式（gzip/strip，空表示未压缩），CompressedText 为 gzip+base64 后的大文本
+	Compression    string `json:"compression,omitempty"`
+	CompressedText string `json:"compressed_text,omitempty"`
 }
 
 // AccountSnapshot 账户状态快照
@@ -54,15 +78,25 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
-	Symbol    string    `json:"symbol"`    // 币种
-	Quantity  float64   `json:"quantity"`  // 数量（部分平仓时使用）
-	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
-	Timestamp time.Time `json:"timestamp"` // 执行时间
-	Success   bool      `json:"success"`   // 是否成功
-	Error     string    `json:"error"`     // 错误信息
+	Action    string    `json:"action"`               // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
+	Symbol    string    `json:"symbol"`               // 币种
+	Quantity  float64   `json:"quantity"`             // 数量（部分平仓时使用）
+	Leverage  int       `json:"leverage"`             // 杠杆（开仓时）
+	Price     float64   `json:"price"`                // 执行价格
+	OrderID   int64     `json:"order_id"`             // 订单ID
+	Timestamp time.Time `json:"timestamp"`            // 执行时间
+	Success   bool      `json:"success"`              // 是否成功
+	Error     string    `json:"error"`                // 错误信息
+	Skipped   bool      `json:"skipped,omitempty"`    // 无需执行（如止损/止盈与当前挂单相同），未调用交易所
+	OrderType string    `json:"order_type,omitempty"` // 开仓订单类型（market/limit，AI 指定时记录）
+	DryRun    bool      `json:"dry_run,omitempty"`    // 模拟运行：只记录本应执行的订单，未向交易所下单
+	// ClosePercentage 部分平仓的比例（1-100，AI partial_close 或手动平仓时记录）
+	ClosePercentage float64 `json:"close_percentage,omitempty"`
+	// ErrorCode 稳定的错误码（如 INSUFFICIENT_MARGIN），前端据此判断失败原因
+	ErrorCode string `json:"error_code,omitempty"`
+	// ErrorKey/ErrorArgs 错误信息的 i18n 消息模板及参数，API 按请求语言重新生成 Error
+	ErrorKey  string        `json:"error_key,omitempty"`
+	ErrorArgs []interface{} `json:"error_args,omitempty"`
 }
 
 // IDecisionLogger 决策日志记录器接口
@@ -73,22 +107,49 @@ type IDecisionLogger interface {
 	GetLatestRecords(n int) ([]*DecisionRecord, error)
 	// GetRecordByDate 获取指定日期的所有记录
 	GetRecordByDate(date time.Time) ([]*DecisionRecord, error)
+	// IterateRecords 按时间正序逐条遍历 [from, to) 内的记录（零值表示不限），fn 返回错误时停止
+	IterateRecords(from, to time.Time, fn func(*DecisionRecord) error) error
 	// CleanOldRecords 清理N天前的旧记录
 	CleanOldRecords(days int) error
+	// ClearRecords 删除全部决策记录并重置周期编号，返回删除的记录数
+	ClearRecords() (int, error)
 	// GetStatistics 获取统计信息
 	GetStatistics() (*Statistics, error)
 	// AnalyzePerformance 分析最近N个周期的交易表现
 	AnalyzePerformance(lookbackCycles int) (*PerformanceAnalysis, error)
+	// CompactOldRecords 压缩早于 olderThan 的记录中的大文本字段
+	CompactOldRecords(olderThan time.Duration, mode string) (int, error)
+	// GetSuccessRate 按时间段（hour/day/week）统计决策执行成功率
+	GetSuccessRate(bucket string) ([]SuccessRateBucket, error)
+	// ApplyRetention 按保留策略归档或删除过期记录
+	ApplyRetention() (int, error)
+	// SetCryptoService 设置加密服务，encrypt=true 时新记录的提示词字段加密存储
+	SetCryptoService(cs *crypto.CryptoService, encrypt bool)
+	// EncryptExistingRecords 加密历史记录中的提示词字段，返回加密的记录数
+	EncryptExistingRecords() (int, error)
 }
 
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
 	cycleNumber int
+	retention   RetentionPolicy
+
+	mu          sync.Mutex // 保护记录文件索引
+	index       []string   // 记录文件名（按时间正序），首次查询时加载
+	indexLoaded bool
+
+	cryptoService  *crypto.CryptoService // 提示词字段加密服务（为空时按明文读写）
+	encryptRecords bool                  // 新记录写入前加密 SystemPrompt/InputPrompt/CoTTrace
 }
 
-// NewDecisionLogger 创建决策日志记录器
+// NewDecisionLogger 创建决策日志记录器（不限制保留期限）
 func NewDecisionLogger(logDir string) IDecisionLogger {
+	return NewDecisionLoggerWithRetention(logDir, RetentionPolicy{})
+}
+
+// NewDecisionLoggerWithRetention 创建带保留策略的决策日志记录器，过期记录由 ApplyRetention 处理
+func NewDecisionLoggerWithRetention(logDir string, retention RetentionPolicy) IDecisionLogger {
 	if logDir == "" {
 		logDir = "decision_logs"
 	}
@@ -106,13 +167,17 @@ func NewDecisionLogger(logDir string) IDecisionLogger {
 	return &DecisionLogger{
 		logDir:      logDir,
 		cycleNumber: 0,
+		retention:   retention,
 	}
 }
 
 // LogDecision 记录决策
 func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
+	// 决策预演可能与正常决策周期并发写入，周期编号在锁内递增
+	l.mu.Lock()
 	l.cycleNumber++
 	record.CycleNumber = l.cycleNumber
+	l.mu.Unlock()
 	record.Timestamp = time.Now()
 
 	// 生成文件名：decision_YYYYMMDD_HHMMSS_cycleN.json
@@ -122,8 +187,18 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 
 	filepath := filepath.Join(l.logDir, filename)
 
+	// 启用加密时只加密写入磁盘的副本，调用方持有的记录保持明文
+	stored := record
+	if l.encryptRecords {
+		encrypted := *record
+		if err := l.encryptRecord(&encrypted); err != nil {
+			return fmt.Errorf("加密决策记录失败: %w", err)
+		}
+		stored = &encrypted
+	}
+
 	// 序列化为JSON（带缩进，方便阅读）
-	data, err := json.MarshalIndent(record, "", "  ")
+	data, err := json.MarshalIndent(stored, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化决策记录失败: %w", err)
 	}
@@ -132,45 +207,34 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	if err := ioutil.WriteFile(filepath, data, 0600); err != nil {
 		return fmt.Errorf("写入决策记录失败: %w", err)
 	}
+	l.mu.Lock()
+	l.appendIndexLocked(filename)
+	l.mu.Unlock()
 
 	fmt.Printf("📝 决策记录已保存: %s\n", filename)
 	return nil
 }
 
 // GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
+// 通过记录文件索引直接定位最后N个文件，不扫描整个目录
 func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
-	files, err := ioutil.ReadDir(l.logDir)
-	if err != nil {
+	if _, err := os.Stat(l.logDir); err != nil {
 		return nil, fmt.Errorf("读取日志目录失败: %w", err)
 	}
 
-	// 先按修改时间倒序收集（最新的在前）
 	var records []*DecisionRecord
-	count := 0
-	for i := len(files) - 1; i >= 0 && count < n; i-- {
-		file := files[i]
-		if file.IsDir() {
-			continue
-		}
-
-		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
+	for _, name := range l.latestRecordNames(n) {
+		record, err := l.readRecord(filepath.Join(l.logDir, name), true)
 		if err != nil {
+			if os.IsNotExist(err) {
+				// 文件已被外部删除，下次查询时重新核对索引
+				l.mu.Lock()
+				l.invalidateIndexLocked()
+				l.mu.Unlock()
+			}
 			continue
 		}
-
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
-		}
-
-		records = append(records, &record)
-		count++
-	}
-
-	// 反转数组，让时间从旧到新排列（用于图表显示）
-	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
-		records[i], records[j] = records[j], records[i]
+		records = append(records, record)
 	}
 
 	return records, nil
@@ -187,21 +251,52 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 	}
 
 	var records []*DecisionRecord
-	for _, filepath := range files {
-		data, err := ioutil.ReadFile(filepath)
+	for _, path := range files {
+		record, err := l.readRecord(path, true)
 		if err != nil {
 			continue
 		}
 
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// IterateRecords 按时间正序逐条遍历 [from, to) 内的记录（零值表示不限），fn 返回错误时停止
+// 每次只读取一个文件，适合导出大量历史记录；大文本字段不解压
+func (l *DecisionLogger) IterateRecords(from, to time.Time, fn func(*DecisionRecord) error) error {
+	files, err := ioutil.ReadDir(l.logDir)
+	if err != nil {
+		return fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), "decision_") {
 			continue
 		}
 
-		records = append(records, &record)
-	}
+		// 文件名中带有记录时间（精确到秒），先按文件名过滤，避免读取范围外的文件
+		if len(file.Name()) >= len("decision_20060102_150405") {
+			if ts, err := time.ParseInLocation("20060102_150405", file.Name()[len("decision_"):len("decision_20060102_150405")], time.Local); err == nil {
+				if (!from.IsZero() && ts.Before(from.Truncate(time.Second))) || (!to.IsZero() && !ts.Before(to)) {
+					continue
+				}
+			}
+		}
 
-	return records, nil
+		record, err := readRecordFile(filepath.Join(l.logDir, file.Name()), false)
+		if err != nil {
+			continue
+		}
+		if (!from.IsZero() && record.Timestamp.Before(from)) || (!to.IsZero() && !record.Timestamp.Before(to)) {
+			continue
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // CleanOldRecords 清理N天前的旧记录
@@ -215,7 +310,7 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 
 	removedCount := 0
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || !isRecordFile(file.Name()) {
 			continue
 		}
 
@@ -230,12 +325,45 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 	}
 
 	if removedCount > 0 {
+		l.mu.Lock()
+		l.invalidateIndexLocked()
+		l.mu.Unlock()
 		fmt.Printf("🗑️ 已清理 %d 条旧记录（%d天前）\n", removedCount, days)
 	}
 
 	return nil
 }
 
+// ClearRecords 删除全部决策记录（decision_*.json）并重置周期编号，目录中的其他文件保留
+func (l *DecisionLogger) ClearRecords() (int, error) {
+	files, err := ioutil.ReadDir(l.logDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	removedCount := 0
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, "decision_") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(l.logDir, name)); err != nil {
+			return removedCount, fmt.Errorf("删除决策记录 %s 失败: %w", name, err)
+		}
+		removedCount++
+	}
+	l.cycleNumber = 0
+	l.mu.Lock()
+	l.invalidateIndexLocked()
+	l.mu.Unlock()
+
+	fmt.Printf("🗑️ 已删除全部决策记录: %d 条\n", removedCount)
+	return removedCount, nil
+}
+
 // GetStatistics 获取统计信息
 func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 	files, err := ioutil.ReadDir(l.logDir)
@@ -250,17 +378,12 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 			continue
 		}
 
-		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
+		// 统计只用到结构化字段，无需解压大文本
+		record, err := readRecordFile(filepath.Join(l.logDir, file.Name()), false)
 		if err != nil {
 			continue
 		}
 
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
-		}
-
 		stats.TotalCycles++
 
 		for _, action := range record.Decisions {
@@ -277,23 +400,32 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 			}
 		}
 
+		if record.FallbackUsed {
+			stats.FallbackCycles++
+		}
+
 		if record.Success {
 			stats.SuccessfulCycles++
 		} else {
 			stats.FailedCycles++
 		}
+
+		stats.AIUsage.add(record)
 	}
+	stats.AIUsage.finish()
 
 	return stats, nil
 }
 
 // Statistics 统计信息
 type Statistics struct {
-	TotalCycles         int `json:"total_cycles"`
-	SuccessfulCycles    int `json:"successful_cycles"`
-	FailedCycles        int `json:"failed_cycles"`
-	TotalOpenPositions  int `json:"total_open_positions"`
-	TotalClosePositions int `json:"total_close_positions"`
+	TotalCycles         int          `json:"total_cycles"`
+	SuccessfulCycles    int          `json:"successful_cycles"`
+	FailedCycles        int          `json:"failed_cycles"`
+	TotalOpenPositions  int          `json:"total_open_positions"`
+	TotalClosePositions int          `json:"total_close_positions"`
+	FallbackCycles      int          `json:"fallback_cycles"` // 主模型失败、改用备用模型的周期数
+	AIUsage             AIUsageStats `json:"ai_usage"`        // AI token 用量和费用
 }
 
 // TradeOutcome 单笔交易结果
@@ -328,6 +460,8 @@ type PerformanceAnalysis struct {
 	SymbolStats   map[string]*SymbolPerformance `json:"symbol_stats"`   // 各币种表现
 	BestSymbol    string                        `json:"best_symbol"`    // 表现最好的币种
 	WorstSymbol   string                        `json:"worst_symbol"`   // 表现最差的币种
+	// AIUsage 交易员自开始记录以来的 AI token 用量和估算费用（按模型拆分，由 API 层填充）
+	AIUsage *AIUsageStats `json:"ai_usage,omitempty"`
 }
 
 // SymbolPerformance 币种表现统计
@@ -346,13 +480,14 @@ type SymbolPerformance struct {
 // - Aster: Maker 0.010%, Taker 0.035%
 // - Hyperliquid: Maker 0.015%, Taker 0.045%
 // - Binance Futures: Maker 0.020%, Taker 0.050% (默认费率)
+// - OKX: Maker 0.020%, Taker 0.050%
 func getTakerFeeRate(exchange string) float64 {
 	switch exchange {
 	case "aster":
 		return 0.00035 // 0.035%
 	case "hyperliquid":
 		return 0.00045 // 0.045%
-	case "binance":
+	case "binance", "okx":
 		return 0.0005 // 0.050%
 	default:
 		// 对于未知交易所，使用保守估计（Binance费率）
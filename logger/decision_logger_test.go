@@ -1,8 +1,15 @@
 package logger
 
 import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"nofx/crypto"
 )
 
 // TestGetTakerFeeRate tests the getTakerFeeRate function for all supported exchanges
@@ -474,3 +481,441 @@ func TestFeeImpactOnPerformanceMetrics(t *testing.T) {
 		}
 	}
 }
+
+// TestCompactOldRecords tests that old records are compacted and transparently expanded when fetched
+func TestCompactOldRecords(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDecisionLogger(dir).(*DecisionLogger)
+
+	longText := strings.Repeat("market data and chain of thought ", 200)
+	for i := 0; i < 2; i++ {
+		if err := l.LogDecision(&DecisionRecord{
+			SystemPrompt: "system " + longText,
+			InputPrompt:  "input " + longText,
+			CoTTrace:     "cot " + longText,
+			DecisionJSON: `[{"symbol":"BTCUSDT","action":"hold"}]`,
+			AccountState: AccountSnapshot{TotalBalance: 1000, PositionCount: 1},
+			Decisions:    []DecisionAction{{Action: "open_long", Symbol: "BTCUSDT", Price: 50000, Success: true}},
+			Success:      true,
+		}); err != nil {
+			t.Fatalf("Failed to log decision: %v", err)
+		}
+	}
+
+	// Age the first record only
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 record files, got %d", len(files))
+	}
+	oldTime := time.Now().Add(-10 * 24 * time.Hour)
+	os.Chtimes(files[0], oldTime, oldTime)
+	before, _ := os.Stat(files[0])
+
+	n, err := l.CompactOldRecords(7*24*time.Hour, CompactModeGzip)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 compacted record, got %d (err=%v)", n, err)
+	}
+
+	after, _ := os.Stat(files[0])
+	if after.Size() >= before.Size() {
+		t.Errorf("Compacted file should be smaller: %d -> %d", before.Size(), after.Size())
+	}
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("Compaction should keep the original modification time")
+	}
+
+	// Stored file no longer holds the raw text but keeps structured data
+	raw, _ := readRecordFile(files[0], false)
+	if raw.Compression != CompactModeGzip || raw.CoTTrace != "" || raw.CompressedText == "" {
+		t.Errorf("Stored record should be compressed: compression=%q", raw.Compression)
+	}
+	if raw.AccountState.TotalBalance != 1000 || len(raw.Decisions) != 1 || raw.Decisions[0].Price != 50000 {
+		t.Errorf("Structured data should be untouched: %+v", raw.AccountState)
+	}
+
+	// Round trip: fetched records expose the original text
+	records, err := l.GetLatestRecords(10)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Failed to fetch records: %v", err)
+	}
+	if records[0].SystemPrompt != "system "+longText || records[0].InputPrompt != "input "+longText || records[0].CoTTrace != "cot "+longText {
+		t.Error("Compressed fields should be restored when fetched")
+	}
+	if records[0].CompressedText != "" {
+		t.Error("Expanded record should not expose the compressed blob")
+	}
+	if records[1].Compression != "" {
+		t.Error("Recent record should not be compacted")
+	}
+
+	// Already compacted records are skipped
+	if n, _ := l.CompactOldRecords(7*24*time.Hour, CompactModeGzip); n != 0 {
+		t.Errorf("Expected no records to compact again, got %d", n)
+	}
+
+	// Strip mode drops the text permanently
+	os.Chtimes(files[1], oldTime, oldTime)
+	if n, _ := l.CompactOldRecords(7*24*time.Hour, CompactModeStrip); n != 1 {
+		t.Fatalf("Expected 1 stripped record, got %d", n)
+	}
+	stripped, _ := readRecordFile(files[1], true)
+	if stripped.Compression != CompactModeStrip || stripped.CoTTrace != "" || !stripped.Success {
+		t.Errorf("Stripped record is incorrect: %+v", stripped)
+	}
+
+	if _, err := l.CompactOldRecords(time.Hour, "zip"); err == nil {
+		t.Error("Unsupported mode should return an error")
+	}
+}
+
+// TestAggregateSuccessRate tests time-bucketed cycle and action success counts
+func TestAggregateSuccessRate(t *testing.T) {
+	day1 := time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC) // Monday
+	day2 := time.Date(2025, 1, 7, 15, 0, 0, 0, time.UTC)
+	records := []*DecisionRecord{
+		{Timestamp: day2, Success: false, Decisions: []DecisionAction{{Action: "open_long", Success: false}}},
+		{Timestamp: day1, Success: true, Decisions: []DecisionAction{
+			{Action: "open_long", Success: true},
+			{Action: "close_short", Success: false},
+			{Action: "update_stop_loss", Success: true, Skipped: true},
+		}},
+		{Timestamp: day1.Add(2 * time.Hour), Success: true},
+	}
+
+	daily := aggregateSuccessRate(records, SuccessRateBucketDay)
+	if len(daily) != 2 {
+		t.Fatalf("Expected 2 daily buckets, got %d", len(daily))
+	}
+	first := daily[0]
+	if !first.BucketStart.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Buckets should be sorted ascending, first starts at %v", first.BucketStart)
+	}
+	if first.TotalCycles != 2 || first.SuccessfulCycles != 2 || first.CycleSuccessRate != 100 {
+		t.Errorf("Unexpected cycle counts for day 1: %+v", first)
+	}
+	if first.TotalActions != 2 || first.SuccessfulActions != 1 || first.FailedActions != 1 || first.ActionSuccessRate != 50 {
+		t.Errorf("Skipped actions should be excluded from action counts: %+v", first)
+	}
+	if second := daily[1]; second.FailedCycles != 1 || second.CycleSuccessRate != 0 || second.FailedActions != 1 {
+		t.Errorf("Unexpected counts for day 2: %+v", second)
+	}
+
+	weekly := aggregateSuccessRate(records, SuccessRateBucketWeek)
+	if len(weekly) != 1 || weekly[0].TotalCycles != 3 || !weekly[0].BucketStart.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a single week bucket starting Monday: %+v", weekly)
+	}
+
+	if hourly := aggregateSuccessRate(records, SuccessRateBucketHour); len(hourly) != 3 {
+		t.Errorf("Expected 3 hourly buckets, got %d", len(hourly))
+	}
+}
+
+// TestGetSuccessRateRejectsUnknownBucket tests reading stored records and bucket validation
+func TestGetSuccessRateRejectsUnknownBucket(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+		t.Fatalf("Failed to log decision: %v", err)
+	}
+
+	buckets, err := l.GetSuccessRate(SuccessRateBucketDay)
+	if err != nil || len(buckets) != 1 || buckets[0].SuccessfulCycles != 1 {
+		t.Fatalf("Expected one successful cycle, got %+v (err=%v)", buckets, err)
+	}
+	if _, err := l.GetSuccessRate("month"); err == nil {
+		t.Error("Unknown bucket should be rejected")
+	}
+}
+
+func TestClearRecords(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDecisionLogger(dir).(*DecisionLogger)
+
+	for i := 0; i < 3; i++ {
+		if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+			t.Fatalf("Failed to log decision: %v", err)
+		}
+	}
+	// Unrelated files in the log directory must survive
+	other := filepath.Join(dir, "notes.txt")
+	os.WriteFile(other, []byte("keep"), 0600)
+
+	n, err := l.ClearRecords()
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 cleared records, got %d (err=%v)", n, err)
+	}
+	if records, _ := l.GetLatestRecords(10); len(records) != 0 {
+		t.Errorf("Expected no records after clearing, got %d", len(records))
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Non-record file should be kept: %v", err)
+	}
+
+	// Cycle numbering restarts after clearing
+	record := &DecisionRecord{Success: true}
+	l.LogDecision(record)
+	if record.CycleNumber != 1 {
+		t.Errorf("Expected cycle number to restart at 1, got %d", record.CycleNumber)
+	}
+}
+
+// TestIterateRecords tests chronological iteration over a [from, to) range
+func TestIterateRecords(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDecisionLogger(dir).(*DecisionLogger)
+
+	var logged []*DecisionRecord
+	for i := 0; i < 3; i++ {
+		record := &DecisionRecord{Success: true}
+		if err := l.LogDecision(record); err != nil {
+			t.Fatalf("Failed to log decision: %v", err)
+		}
+		logged = append(logged, record)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("skip"), 0600)
+
+	collect := func(from, to time.Time) []int {
+		var cycles []int
+		if err := l.IterateRecords(from, to, func(r *DecisionRecord) error {
+			cycles = append(cycles, r.CycleNumber)
+			return nil
+		}); err != nil {
+			t.Fatalf("IterateRecords failed: %v", err)
+		}
+		return cycles
+	}
+
+	if cycles := collect(time.Time{}, time.Time{}); len(cycles) != 3 || cycles[0] != 1 || cycles[2] != 3 {
+		t.Errorf("Expected all cycles in chronological order, got %v", cycles)
+	}
+	if cycles := collect(logged[1].Timestamp, time.Time{}); len(cycles) != 2 || cycles[0] != 2 {
+		t.Errorf("Expected cycles from the second record on, got %v", cycles)
+	}
+	if cycles := collect(time.Time{}, logged[1].Timestamp); len(cycles) != 1 || cycles[0] != 1 {
+		t.Errorf("Expected the upper bound to be exclusive, got %v", cycles)
+	}
+
+	// Callback errors stop the iteration
+	visited := 0
+	stop := errors.New("stop")
+	if err := l.IterateRecords(time.Time{}, time.Time{}, func(*DecisionRecord) error {
+		visited++
+		return stop
+	}); err != stop || visited != 1 {
+		t.Errorf("Expected iteration to stop after the first error, visited %d (err=%v)", visited, err)
+	}
+}
+
+// TestApplyRetentionArchivesOldest tests that records beyond MaxRecords are archived and dropped from the index
+func TestApplyRetentionArchivesOldest(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDecisionLoggerWithRetention(dir, RetentionPolicy{MaxRecords: 2}).(*DecisionLogger)
+	for i := 0; i < 5; i++ {
+		if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+			t.Fatalf("Failed to log decision: %v", err)
+		}
+	}
+	// Load the index before retention so later appends go through it
+	if records, _ := l.GetLatestRecords(10); len(records) != 5 {
+		t.Fatalf("Expected 5 records, got %d", len(records))
+	}
+
+	n, err := l.ApplyRetention()
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 archived records, got %d (err=%v)", n, err)
+	}
+	records, _ := l.GetLatestRecords(10)
+	if len(records) != 2 || records[0].CycleNumber != 4 || records[1].CycleNumber != 5 {
+		t.Errorf("Expected cycles 4 and 5 to remain, got %d records", len(records))
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "decision_*.json"))
+	if len(files) != 2 {
+		t.Errorf("Expected 2 record files on disk, got %d", len(files))
+	}
+	archives, _ := filepath.Glob(filepath.Join(dir, decisionArchiveDir, "*.jsonl.gz"))
+	if len(archives) != 1 {
+		t.Errorf("Expected 1 archive bundle, got %d", len(archives))
+	}
+
+	// A fresh logger trusts the persisted index and sees the same records
+	l.LogDecision(&DecisionRecord{Success: true})
+	fresh := NewDecisionLogger(dir)
+	if records, _ := fresh.GetLatestRecords(1); len(records) != 1 || records[0].CycleNumber != 6 {
+		t.Errorf("Expected the newest record from the index, got %+v", records)
+	}
+}
+
+// TestApplyRetentionDeletesExpired tests age-based deletion and index rebuild after external changes
+func TestApplyRetentionDeletesExpired(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDecisionLoggerWithRetention(dir, RetentionPolicy{MaxAgeDays: 7, Mode: RetentionModeDelete})
+	for i := 0; i < 3; i++ {
+		l.LogDecision(&DecisionRecord{Success: true})
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "decision_*.json"))
+	oldTime := time.Now().Add(-10 * 24 * time.Hour)
+	os.Chtimes(files[0], oldTime, oldTime)
+
+	n, err := l.ApplyRetention()
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 deleted record, got %d (err=%v)", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, decisionArchiveDir)); !os.IsNotExist(err) {
+		t.Errorf("Delete mode should not create archives")
+	}
+
+	// Files removed behind the logger's back are dropped from the index
+	os.Remove(files[1])
+	l.GetLatestRecords(10)
+	if records, _ := l.GetLatestRecords(10); len(records) != 1 {
+		t.Errorf("Expected 1 remaining record, got %d", len(records))
+	}
+}
+
+// TestGetStatisticsAggregatesAIUsage tests token usage aggregation and cost estimation per model
+func TestGetStatisticsAggregatesAIUsage(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	records := []*DecisionRecord{
+		{Success: true, AIModel: "deepseek", PromptTokens: 1500, CompletionTokens: 500, TotalTokens: 2000},
+		{Success: true, AIModel: "deepseek", PromptTokens: 800, CompletionTokens: 200, TotalTokens: 1000},
+		{Success: true, AIModel: "custom/gpt-4o", PromptTokens: 900, CompletionTokens: 100, TotalTokens: 1000, AICost: 0.01},
+		{Success: true, AIModel: "deepseek", CachedDecision: true, TotalTokens: 2000}, // cached cycles did not call the AI
+	}
+	for _, r := range records {
+		if err := l.LogDecision(r); err != nil {
+			t.Fatalf("Failed to log decision: %v", err)
+		}
+	}
+
+	stats, err := l.GetStatistics()
+	if err != nil {
+		t.Fatalf("GetStatistics failed: %v", err)
+	}
+	usage := stats.AIUsage
+	if usage.AICalls != 3 || usage.TotalTokens != 4000 || usage.PromptTokens != 3200 || usage.CompletionTokens != 800 {
+		t.Fatalf("Unexpected usage totals: %+v", usage)
+	}
+	if usage.TokensPerCycle < 1333 || usage.TokensPerCycle > 1334 {
+		t.Errorf("Expected ~1333 tokens per cycle, got %.2f", usage.TokensPerCycle)
+	}
+	// Without prices only the provider-reported cost is counted
+	if math.Abs(usage.EstimatedCost-0.01) > 1e-9 {
+		t.Errorf("Expected estimated cost 0.01 from reported cost, got %f", usage.EstimatedCost)
+	}
+
+	prices, err := ParseTokenPrices(`{"deepseek": 0.002, "custom": 0.005}`)
+	if err != nil {
+		t.Fatalf("ParseTokenPrices failed: %v", err)
+	}
+	usage.ApplyPrices(prices)
+	if got := usage.ByModel["deepseek"].EstimatedCost; math.Abs(got-0.006) > 1e-9 {
+		t.Errorf("Expected deepseek cost 0.006, got %f", got)
+	}
+	if got := usage.ByModel["custom/gpt-4o"].EstimatedCost; math.Abs(got-0.005) > 1e-9 {
+		t.Errorf("Expected custom/gpt-4o cost 0.005 via model prefix, got %f", got)
+	}
+	// All records fall within one hour, so the monthly projection uses a one-hour window
+	if want := 0.011 * 24 * 30; math.Abs(usage.EstimatedMonthlyCost-want) > 1e-6 {
+		t.Errorf("Expected monthly cost %f, got %f", want, usage.EstimatedMonthlyCost)
+	}
+
+	if _, err := ParseTokenPrices(`{"deepseek": -1}`); err == nil {
+		t.Error("Expected negative prices to be rejected")
+	}
+}
+
+// TestDecisionLogEncryption tests that prompt fields are encrypted on disk and decrypted when fetched
+func TestDecisionLogEncryption(t *testing.T) {
+	os.Setenv("DATA_ENCRYPTION_KEY", "test-key-32-bytes-long-for-aes")
+	defer os.Unsetenv("DATA_ENCRYPTION_KEY")
+	cs, err := crypto.NewCryptoService(filepath.Join(t.TempDir(), "rsa_key"))
+	if err != nil {
+		t.Fatalf("Failed to create crypto service: %v", err)
+	}
+
+	dir := t.TempDir()
+	l := NewDecisionLogger(dir).(*DecisionLogger)
+
+	// A plaintext record written before encryption was enabled
+	if err := l.LogDecision(&DecisionRecord{SystemPrompt: "legacy system", InputPrompt: "legacy input", CoTTrace: "legacy cot", Success: true}); err != nil {
+		t.Fatalf("Failed to log decision: %v", err)
+	}
+
+	l.SetCryptoService(cs, true)
+	record := &DecisionRecord{SystemPrompt: "balance 1000 USDT", InputPrompt: "BTCUSDT long 0.1", CoTTrace: "thinking", Success: true}
+	if err := l.LogDecision(record); err != nil {
+		t.Fatalf("Failed to log decision: %v", err)
+	}
+	if record.SystemPrompt != "balance 1000 USDT" {
+		t.Error("Caller's record should stay in plaintext")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "decision_*.json"))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 record files, got %d", len(files))
+	}
+	data, _ := os.ReadFile(files[1])
+	if strings.Contains(string(data), "balance 1000 USDT") || strings.Contains(string(data), "thinking") {
+		t.Error("Prompt fields should not be stored in plaintext")
+	}
+	stored, _ := readRecordFile(files[1], false)
+	if !cs.IsEncryptedStorageValue(stored.SystemPrompt) || !cs.IsEncryptedStorageValue(stored.CoTTrace) {
+		t.Errorf("Stored prompt fields should be encrypted: %q", stored.SystemPrompt)
+	}
+
+	// Both legacy plaintext and encrypted records read back unchanged
+	records, err := l.GetLatestRecords(10)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Failed to fetch records: %v", err)
+	}
+	if records[0].SystemPrompt != "legacy system" || records[0].CoTTrace != "legacy cot" {
+		t.Errorf("Legacy record should read as plaintext: %+v", records[0])
+	}
+	if records[1].SystemPrompt != "balance 1000 USDT" || records[1].InputPrompt != "BTCUSDT long 0.1" || records[1].CoTTrace != "thinking" {
+		t.Errorf("Encrypted record should be decrypted when fetched: %+v", records[1])
+	}
+
+	// Migration encrypts the legacy record once
+	if n, err := l.EncryptExistingRecords(); err != nil || n != 1 {
+		t.Fatalf("Expected 1 migrated record, got %d (err=%v)", n, err)
+	}
+	if n, _ := l.EncryptExistingRecords(); n != 0 {
+		t.Errorf("Expected no records to encrypt again, got %d", n)
+	}
+	migrated, _ := readRecordFile(files[0], false)
+	if !cs.IsEncryptedStorageValue(migrated.InputPrompt) {
+		t.Error("Legacy record should be encrypted after migration")
+	}
+	records, _ = l.GetLatestRecords(10)
+	if records[0].InputPrompt != "legacy input" {
+		t.Errorf("Migrated record should be decrypted when fetched: %q", records[0].InputPrompt)
+	}
+}
+
+// TestGetStatisticsCountsFallback tests that fallback cycles are counted and usage goes to the model that answered
+func TestGetStatisticsCountsFallback(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	records := []*DecisionRecord{
+		{Success: true, AIModel: "deepseek", ModelUsed: "deepseek", TotalTokens: 1000},
+		{Success: true, AIModel: "deepseek", ModelUsed: "qwen", FallbackUsed: true, TotalTokens: 1200},
+		{Success: false, AIModel: "deepseek", ModelUsed: "qwen", FallbackUsed: true},
+	}
+	for _, r := range records {
+		if err := l.LogDecision(r); err != nil {
+			t.Fatalf("Failed to log decision: %v", err)
+		}
+	}
+
+	stats, err := l.GetStatistics()
+	if err != nil {
+		t.Fatalf("GetStatistics failed: %v", err)
+	}
+	if stats.FallbackCycles != 2 {
+		t.Errorf("Expected 2 fallback cycles, got %d", stats.FallbackCycles)
+	}
+	if m := stats.AIUsage.ByModel["qwen"]; m == nil || m.TotalTokens != 1200 {
+		t.Errorf("Expected fallback usage attributed to qwen, got %+v", stats.AIUsage.ByModel)
+	}
+	if m := stats.AIUsage.ByModel["deepseek"]; m == nil || m.TotalTokens != 1000 {
+		t.Errorf("Expected primary usage attributed to deepseek, got %+v", stats.AIUsage.ByModel)
+	}
+}
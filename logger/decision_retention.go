@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 决策记录过期后的处理方式
+const (
+	RetentionModeArchive = "archive" // 归档为 gzip 压缩包（archive/ 子目录）后删除原文件
+	RetentionModeDelete  = "delete"  // 直接删除
+)
+
+const (
+	// decisionIndexFile 记录文件索引（每行一个文件名，按时间正序），查询最近N条时无需扫描整个目录
+	decisionIndexFile = "index.txt"
+	// decisionArchiveDir 归档压缩包目录
+	decisionArchiveDir = "archive"
+)
+
+// RetentionPolicy 决策记录保留策略（MaxAgeDays/MaxRecords 为 0 表示不限制）
+type RetentionPolicy struct {
+	MaxAgeDays int    // 保留最近N天的记录
+	MaxRecords int    // 最多保留N条记录
+	Mode       string // 过期记录处理方式：archive（默认）/ delete
+}
+
+// Enabled 是否启用了保留限制
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAgeDays > 0 || p.MaxRecords > 0
+}
+
+// isRecordFile 是否为决策记录文件（decision_*.json）
+func isRecordFile(name string) bool {
+	return strings.HasPrefix(name, "decision_") && strings.HasSuffix(name, ".json")
+}
+
+// loadIndexLocked 加载记录文件索引（调用方持有 l.mu）
+// 首次使用时与目录中的文件名核对，索引缺失或不一致时按文件名重建
+func (l *DecisionLogger) loadIndexLocked() []string {
+	if l.indexLoaded {
+		return l.index
+	}
+
+	entries, err := os.ReadDir(l.logDir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isRecordFile(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	index := readIndexFile(filepath.Join(l.logDir, decisionIndexFile))
+	if !sameRecordSet(index, names) {
+		index = names
+		if err := l.writeIndexLocked(index); err != nil {
+			fmt.Printf("⚠ 重建决策记录索引失败: %v\n", err)
+		}
+	}
+
+	l.index = index
+	l.indexLoaded = true
+	return l.index
+}
+
+// readIndexFile 读取索引文件，不存在时返回 nil
+func readIndexFile(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sameRecordSet 索引中的文件与目录中的文件是否一致（顺序可以不同）
+func sameRecordSet(index, names []string) bool {
+	if len(index) != len(names) {
+		return false
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	for _, name := range index {
+		if !set[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeIndexLocked 原子重写索引文件（调用方持有 l.mu）
+func (l *DecisionLogger) writeIndexLocked(names []string) error {
+	var buf bytes.Buffer
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+	}
+	path := filepath.Join(l.logDir, decisionIndexFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// appendIndexLocked 新记录追加到索引（调用方持有 l.mu）
+func (l *DecisionLogger) appendIndexLocked(name string) {
+	if !l.indexLoaded {
+		// 索引尚未加载，首次查询时会从目录重建
+		return
+	}
+	l.index = append(l.index, name)
+
+	f, err := os.OpenFile(filepath.Join(l.logDir, decisionIndexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		l.invalidateIndexLocked()
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(name + "\n"); err != nil {
+		l.invalidateIndexLocked()
+	}
+}
+
+// invalidateIndexLocked 丢弃内存中的索引，下次查询时重新核对（调用方持有 l.mu）
+func (l *DecisionLogger) invalidateIndexLocked() {
+	l.index = nil
+	l.indexLoaded = false
+}
+
+// latestRecordNames 最近 n 条记录的文件名（按时间正序）
+func (l *DecisionLogger) latestRecordNames(n int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	index := l.loadIndexLocked()
+	if n > len(index) {
+		n = len(index)
+	}
+	if n <= 0 {
+		return nil
+	}
+	names := make([]string, n)
+	copy(names, index[len(index)-n:])
+	return names
+}
+
+// ApplyRetention 按保留策略处理过期记录（超过 MaxAgeDays 或超出 MaxRecords 的最旧记录），返回处理的记录数
+// archive 模式把过期记录逐行写入 archive/decisions_<起>_<止>.jsonl.gz 后删除原文件
+func (l *DecisionLogger) ApplyRetention() (int, error) {
+	policy := l.retention
+	if !policy.Enabled() {
+		return 0, nil
+	}
+	mode := policy.Mode
+	if mode == "" {
+		mode = RetentionModeArchive
+	}
+	if mode != RetentionModeArchive && mode != RetentionModeDelete {
+		return 0, fmt.Errorf("不支持的保留处理方式: %s", mode)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	index := l.loadIndexLocked()
+	expired := 0
+	if policy.MaxRecords > 0 && len(index) > policy.MaxRecords {
+		expired = len(index) - policy.MaxRecords
+	}
+	if policy.MaxAgeDays > 0 {
+		cutoffTime := time.Now().AddDate(0, 0, -policy.MaxAgeDays)
+		for expired < len(index) {
+			info, err := os.Stat(filepath.Join(l.logDir, index[expired]))
+			if err == nil && !info.ModTime().Before(cutoffTime) {
+				break
+			}
+			expired++
+		}
+	}
+	if expired == 0 {
+		return 0, nil
+	}
+
+	names := index[:expired]
+	if mode == RetentionModeArchive {
+		if err := l.archiveRecords(names); err != nil {
+			return 0, err
+		}
+	}
+
+	removed := 0
+	for _, name := range names {
+		if err := os.Remove(filepath.Join(l.logDir, name)); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("⚠ 删除过期决策记录失败 %s: %v\n", name, err)
+			continue
+		}
+		removed++
+	}
+
+	remaining := append([]string(nil), index[expired:]...)
+	if err := l.writeIndexLocked(remaining); err != nil {
+		l.invalidateIndexLocked()
+		return removed, fmt.Errorf("更新决策记录索引失败: %w", err)
+	}
+	l.index = remaining
+
+	fmt.Printf("🗄️ 已处理 %d 条过期决策记录（%s）\n", removed, mode)
+	return removed, nil
+}
+
+// archiveRecords 把记录文件逐行写入一个 gzip 压缩包（JSON Lines）
+func (l *DecisionLogger) archiveRecords(names []string) error {
+	archiveDir := filepath.Join(l.logDir, decisionArchiveDir)
+	if err := os.MkdirAll(archiveDir, 0700); err != nil {
+		return fmt.Errorf("创建归档目录失败: %w", err)
+	}
+
+	archiveName := fmt.Sprintf("decisions_%s_%s.jsonl.gz", recordFileStamp(names[0]), recordFileStamp(names[len(names)-1]))
+	path := filepath.Join(archiveDir, archiveName)
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("创建归档文件失败: %w", err)
+	}
+
+	zw := gzip.NewWriter(f)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(l.logDir, name))
+		if err != nil {
+			continue
+		}
+		var line bytes.Buffer
+		if err := json.Compact(&line, data); err != nil {
+			continue
+		}
+		line.WriteByte('\n')
+		if _, err := zw.Write(line.Bytes()); err != nil {
+			zw.Close()
+			f.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("写入归档文件失败: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入归档文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入归档文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存归档文件失败: %w", err)
+	}
+	return nil
+}
+
+// recordFileStamp 从记录文件名中取出时间和周期部分（decision_20060102_150405_cycleN.json -> 20060102_150405_cycleN）
+func recordFileStamp(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, "decision_"), ".json")
+}
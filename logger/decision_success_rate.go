@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 成功率统计支持的时间粒度
+const (
+	SuccessRateBucketHour = "hour"
+	SuccessRateBucketDay  = "day"
+	SuccessRateBucketWeek = "week"
+)
+
+// SuccessRateBucket 单个时间段内的决策执行成功率
+type SuccessRateBucket struct {
+	BucketStart       time.Time `json:"bucket_start"`        // 时间段起点
+	TotalCycles       int       `json:"total_cycles"`        // 周期数
+	SuccessfulCycles  int       `json:"successful_cycles"`   // 成功周期数
+	FailedCycles      int       `json:"failed_cycles"`       // 失败周期数
+	CycleSuccessRate  float64   `json:"cycle_success_rate"`  // 周期成功率（%）
+	TotalActions      int       `json:"total_actions"`       // 实际执行的决策动作数（不含跳过的动作）
+	SuccessfulActions int       `json:"successful_actions"`  // 执行成功的动作数
+	FailedActions     int       `json:"failed_actions"`      // 执行失败的动作数
+	ActionSuccessRate float64   `json:"action_success_rate"` // 动作执行成功率（%）
+}
+
+// ValidSuccessRateBucket 检查时间粒度是否受支持
+func ValidSuccessRateBucket(bucket string) bool {
+	switch bucket {
+	case SuccessRateBucketHour, SuccessRateBucketDay, SuccessRateBucketWeek:
+		return true
+	}
+	return false
+}
+
+// truncateToBucket 把时间截断到所在时间段的起点（按记录自身时区，周从周一开始）
+func truncateToBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case SuccessRateBucketHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case SuccessRateBucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// aggregateSuccessRate 按时间段统计周期成功率和动作执行成功率，结果按时间升序
+func aggregateSuccessRate(records []*DecisionRecord, bucket string) []SuccessRateBucket {
+	buckets := make(map[time.Time]*SuccessRateBucket)
+	for _, record := range records {
+		start := truncateToBucket(record.Timestamp, bucket)
+		b, ok := buckets[start]
+		if !ok {
+			b = &SuccessRateBucket{BucketStart: start}
+			buckets[start] = b
+		}
+
+		b.TotalCycles++
+		if record.Success {
+			b.SuccessfulCycles++
+		} else {
+			b.FailedCycles++
+		}
+
+		for _, action := range record.Decisions {
+			if action.Skipped {
+				continue
+			}
+			b.TotalActions++
+			if action.Success {
+				b.SuccessfulActions++
+			} else {
+				b.FailedActions++
+			}
+		}
+	}
+
+	result := make([]SuccessRateBucket, 0, len(buckets))
+	for _, b := range buckets {
+		if b.TotalCycles > 0 {
+			b.CycleSuccessRate = float64(b.SuccessfulCycles) / float64(b.TotalCycles) * 100
+		}
+		if b.TotalActions > 0 {
+			b.ActionSuccessRate = float64(b.SuccessfulActions) / float64(b.TotalActions) * 100
+		}
+		result = append(result, *b)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].BucketStart.Before(result[j].BucketStart)
+	})
+	return result
+}
+
+// GetSuccessRate 按时间段统计决策执行成功率（用于观察交易所连接和AI输出质量的变化趋势）
+func (l *DecisionLogger) GetSuccessRate(bucket string) ([]SuccessRateBucket, error) {
+	if !ValidSuccessRateBucket(bucket) {
+		return nil, fmt.Errorf("不支持的时间粒度: %s（可选 hour/day/week）", bucket)
+	}
+
+	files, err := ioutil.ReadDir(l.logDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	var records []*DecisionRecord
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		// 只用到结构化字段，无需解压大文本
+		record, err := readRecordFile(filepath.Join(l.logDir, file.Name()), false)
+		if err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return aggregateSuccessRate(records, bucket), nil
+}
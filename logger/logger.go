@@ -3,13 +3,14 @@ package logger
 import (
 	"nofx/config"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 var (
-	// Log 全局logger实例
-	Log *logrus.Logger
+	// Log 全局logger实例（调用 Init 之前为默认的 info 级别文本 logger）
+	Log = newLogger(logrus.InfoLevel, &logrus.TextFormatter{FullTimestamp: true, TimestampFormat: "2006-01-02 15:04:05"})
 
 	// telegramHook 保存hook引用，用于优雅关闭
 	telegramHook *TelegramHook
@@ -19,11 +20,19 @@ var (
 // 初始化函数
 // ============================================================================
 
+// newLogger 创建输出到 stdout 的 logger，并挂上按交易员保存运行日志的缓冲区
+func newLogger(level logrus.Level, formatter logrus.Formatter) *logrus.Logger {
+	l := logrus.New()
+	l.SetLevel(level)
+	l.SetFormatter(formatter)
+	l.SetOutput(os.Stdout)
+	l.AddHook(runtimeLogs)
+	return l
+}
+
 // Init 初始化全局logger
 // 如果config为nil，使用默认配置（console输出，info级别）
 func Init(cfg *Config) error {
-	Log = logrus.New()
-
 	// 如果没有配置，使用默认值
 	if cfg == nil {
 		cfg = &Config{Level: "info"}
@@ -37,20 +46,26 @@ func Init(cfg *Config) error {
 	if err != nil {
 		level = logrus.InfoLevel
 	}
-	Log.SetLevel(level)
 
-	// 设置格式化器（固定使用彩色文本格式）
-	Log.SetFormatter(&logrus.TextFormatter{
+	// 设置格式化器：json 输出一行一个 JSON 对象（便于 Loki 等采集），否则为彩色文本
+	var formatter logrus.Formatter = &logrus.TextFormatter{
 		FullTimestamp:   true,
 		TimestampFormat: "2006-01-02 15:04:05",
 		ForceColors:     true,
-	})
+	}
+	if cfg.Format == "json" {
+		formatter = &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	}
 
-	// 设置输出目标（默认stdout）
-	Log.SetOutput(os.Stdout)
+	runtimeLogs.setSize(cfg.BufferSize)
+	Log = newLogger(level, formatter)
 
-	// 启用调用位置信息
-	Log.SetReportCaller(true)
+	// 文本格式启用调用位置信息；JSON 格式下标准库 log 的输出也转为 JSON，保证 stdout 可解析
+	if cfg.Format == "json" {
+		redirectStdlibLog()
+	} else {
+		Log.SetReportCaller(true)
+	}
 
 	// 添加Telegram Hook（可选）
 	if cfg.Telegram != nil && cfg.Telegram.Enabled {
@@ -100,7 +115,9 @@ func InitFromLogConfig(logConfig *config.LogConfig) error {
 	}
 
 	cfg := &Config{
-		Level: logConfig.Level,
+		Level:      logConfig.Level,
+		Format:     logConfig.Format,
+		BufferSize: logConfig.BufferSize,
 	}
 
 	if cfg.Level == "" {
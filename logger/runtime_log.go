@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultLogBufferSize 每个交易员在内存中保留的运行日志条数（默认值）
+const DefaultLogBufferSize = 500
+
+// LogEntry 一条运行日志（按交易员保存在内存环形缓冲区中，供 API 查询）
+type LogEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// traderLogRing 单个交易员的日志环形缓冲区
+type traderLogRing struct {
+	entries []LogEntry
+	levels  []logrus.Level
+	next    int
+	full    bool
+}
+
+// runtimeLogBuffer 按 trader_id 分组保存最近的运行日志（作为 logrus Hook 挂在全局 logger 上）
+type runtimeLogBuffer struct {
+	mu    sync.Mutex
+	size  int
+	rings map[string]*traderLogRing
+}
+
+// runtimeLogs 全局运行日志缓冲区
+var runtimeLogs = &runtimeLogBuffer{size: DefaultLogBufferSize, rings: make(map[string]*traderLogRing)}
+
+func (b *runtimeLogBuffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 保存带 trader_id 字段的日志，其他日志忽略
+func (b *runtimeLogBuffer) Fire(entry *logrus.Entry) error {
+	traderID, _ := entry.Data["trader_id"].(string)
+	if traderID == "" {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(entry.Data))
+	for k, v := range entry.Data {
+		if k == "trader_id" {
+			continue
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ring := b.rings[traderID]
+	if ring == nil {
+		ring = &traderLogRing{entries: make([]LogEntry, b.size), levels: make([]logrus.Level, b.size)}
+		b.rings[traderID] = ring
+	}
+	ring.entries[ring.next] = LogEntry{Time: entry.Time, Level: entry.Level.String(), Message: entry.Message, Fields: fields}
+	ring.levels[ring.next] = entry.Level
+	ring.next = (ring.next + 1) % len(ring.entries)
+	if ring.next == 0 {
+		ring.full = true
+	}
+	return nil
+}
+
+// setSize 调整每个交易员保留的日志条数（已有的缓冲区会被清空）
+func (b *runtimeLogBuffer) setSize(size int) {
+	if size <= 0 {
+		size = DefaultLogBufferSize
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if size != b.size {
+		b.size = size
+		b.rings = make(map[string]*traderLogRing)
+	}
+}
+
+// RecentTraderLogs 获取交易员最近的运行日志（按时间升序，只保留 minLevel 及以上级别，最多 limit 条）
+func RecentTraderLogs(traderID string, minLevel logrus.Level, limit int) []LogEntry {
+	runtimeLogs.mu.Lock()
+	defer runtimeLogs.mu.Unlock()
+
+	result := make([]LogEntry, 0)
+	ring := runtimeLogs.rings[traderID]
+	if ring == nil || limit <= 0 {
+		return result
+	}
+
+	// 从最新一条往前找，找够 limit 条后翻转为时间升序
+	count := ring.next
+	if ring.full {
+		count = len(ring.entries)
+	}
+	for i := 0; i < count && len(result) < limit; i++ {
+		idx := (ring.next - 1 - i + len(ring.entries)) % len(ring.entries)
+		if ring.levels[idx] <= minLevel {
+			result = append(result, ring.entries[idx])
+		}
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// ClearTraderLogs 删除交易员的运行日志缓冲区（交易员被删除时调用）
+func ClearTraderLogs(traderID string) {
+	runtimeLogs.mu.Lock()
+	defer runtimeLogs.mu.Unlock()
+	delete(runtimeLogs.rings, traderID)
+}
+
+// ForTrader 创建交易员专用的 logger：附带 trader_id/user_id/component 字段
+// level 非空时覆盖全局日志级别（只影响该交易员的日志）
+func ForTrader(traderID, userID, level string) *logrus.Entry {
+	base := Log
+	if lvl, err := logrus.ParseLevel(level); level != "" && err == nil && lvl != Log.GetLevel() {
+		base = &logrus.Logger{
+			Out:          Log.Out,
+			Formatter:    Log.Formatter,
+			Hooks:        Log.Hooks,
+			ReportCaller: Log.ReportCaller,
+			Level:        lvl,
+		}
+	}
+	return base.WithFields(logrus.Fields{
+		"trader_id": traderID,
+		"user_id":   userID,
+		"component": "trader",
+	})
+}
+
+// Component 创建带 component 字段的 logger（api、manager 等模块使用）
+func Component(name string) *logrus.Entry {
+	return Log.WithField("component", name)
+}
+
+// stdlibBridge 把标准库 log 的输出转为结构化日志（JSON 格式时所有输出保持可解析）
+// 按消息开头的图标推断级别：❌ 为 error，⚠️/🚨/⛔ 为 warn，其余为 info
+type stdlibBridge struct{}
+
+func (stdlibBridge) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	Log.WithField("component", "stdlib").Log(levelFromMessage(msg), msg)
+	return len(p), nil
+}
+
+// levelFromMessage 按日志消息开头的图标推断日志级别
+func levelFromMessage(msg string) logrus.Level {
+	trimmed := strings.TrimSpace(msg)
+	// 跳过 "[trader] " 之类的前缀
+	if strings.HasPrefix(trimmed, "[") {
+		if end := strings.Index(trimmed, "]"); end > 0 {
+			trimmed = strings.TrimSpace(trimmed[end+1:])
+		}
+	}
+	switch {
+	case strings.HasPrefix(trimmed, "❌"):
+		return logrus.ErrorLevel
+	case strings.HasPrefix(trimmed, "⚠"), strings.HasPrefix(trimmed, "🚨"), strings.HasPrefix(trimmed, "⛔"):
+		return logrus.WarnLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
+
+// redirectStdlibLog 把标准库 log 的输出接入结构化 logger
+func redirectStdlibLog() {
+	log.SetFlags(0)
+	log.SetOutput(stdlibBridge{})
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestRecentTraderLogs 测试按交易员保存日志、级别过滤、条数限制和环形覆盖
+func TestRecentTraderLogs(t *testing.T) {
+	if err := Init(&Config{Level: "info", BufferSize: 3}); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer Init(nil)
+	Log.SetOutput(io.Discard)
+
+	entry := ForTrader("t1", "u1", "")
+	entry.Info("cycle 1")
+	entry.Warn("cycle 2")
+	entry.WithField("cycle", 3).Error("cycle 3")
+	entry.Info("cycle 4")
+	ForTrader("t2", "u1", "").Info("other trader")
+	Log.Info("no trader id")
+
+	logs := RecentTraderLogs("t1", logrus.DebugLevel, 10)
+	if len(logs) != 3 {
+		t.Fatalf("缓冲区容量为 3，应返回 3 条, 实际 %d", len(logs))
+	}
+	if logs[0].Message != "cycle 2" || logs[2].Message != "cycle 4" {
+		t.Errorf("应按时间升序返回最近 3 条: %+v", logs)
+	}
+	if logs[1].Level != "error" || fmt.Sprint(logs[1].Fields["cycle"]) != "3" || logs[1].Fields["user_id"] != "u1" {
+		t.Errorf("日志级别或字段不正确: %+v", logs[1])
+	}
+
+	warns := RecentTraderLogs("t1", logrus.WarnLevel, 10)
+	if len(warns) != 2 || warns[0].Message != "cycle 2" || warns[1].Message != "cycle 3" {
+		t.Errorf("level=warn 应只返回 warn 及以上: %+v", warns)
+	}
+	if latest := RecentTraderLogs("t1", logrus.DebugLevel, 1); len(latest) != 1 || latest[0].Message != "cycle 4" {
+		t.Errorf("limit=1 应返回最新一条: %+v", latest)
+	}
+	if other := RecentTraderLogs("t2", logrus.DebugLevel, 10); len(other) != 1 {
+		t.Errorf("t2 应有 1 条日志, 实际 %d", len(other))
+	}
+
+	ClearTraderLogs("t1")
+	if logs := RecentTraderLogs("t1", logrus.DebugLevel, 10); len(logs) != 0 {
+		t.Errorf("清除后不应再有日志, 实际 %d", len(logs))
+	}
+}
+
+// TestForTraderLevelOverride 测试交易员级别的日志级别覆盖不影响全局 logger
+func TestForTraderLevelOverride(t *testing.T) {
+	if err := Init(&Config{Level: "info", Format: "json"}); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer func() {
+		Init(nil)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+	var buf bytes.Buffer
+	Log.SetOutput(&buf)
+
+	ForTrader("verbose", "u1", "debug").WithField("cycle", 7).Debug("debug detail")
+	ForTrader("quiet", "u1", "error").Warn("suppressed warning")
+	ForTrader("default", "u1", "").Debug("suppressed debug")
+
+	if logs := RecentTraderLogs("verbose", logrus.DebugLevel, 10); len(logs) != 1 {
+		t.Errorf("debug 覆盖的交易员应记录 debug 日志, 实际 %d", len(logs))
+	}
+	if logs := RecentTraderLogs("quiet", logrus.DebugLevel, 10); len(logs) != 0 {
+		t.Errorf("error 覆盖的交易员不应记录 warn 日志, 实际 %d", len(logs))
+	}
+	if logs := RecentTraderLogs("default", logrus.DebugLevel, 10); len(logs) != 0 {
+		t.Errorf("未覆盖的交易员应沿用全局 info 级别, 实际 %d", len(logs))
+	}
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &line); err != nil {
+		t.Fatalf("JSON 格式输出应可解析: %v (%s)", err, buf.String())
+	}
+	if line["trader_id"] != "verbose" || line["component"] != "trader" || line["cycle"] != float64(7) {
+		t.Errorf("结构化字段不正确: %v", line)
+	}
+}
+
+// TestLevelFromMessage 测试标准库日志按图标推断级别
+func TestLevelFromMessage(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want logrus.Level
+	}{
+		{"❌ 下单失败", logrus.ErrorLevel},
+		{"⚠️ 余额不足", logrus.WarnLevel},
+		{"[trader] 🚨 强平风险", logrus.WarnLevel},
+		{"⛔ 已暂停", logrus.WarnLevel},
+		{"✓ 开仓成功", logrus.InfoLevel},
+		{"[x] 普通消息", logrus.InfoLevel},
+	}
+	for _, tt := range tests {
+		if got := levelFromMessage(tt.msg); got != tt.want {
+			t.Errorf("levelFromMessage(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"nofx/auth"
 	"nofx/config"
 	"nofx/crypto"
+	"nofx/logger"
 	"nofx/manager"
 	"nofx/market"
 	"nofx/pool"
@@ -26,19 +27,20 @@ import (
 // ConfigFile 配置文件结构，只包含需要同步到数据库的字段
 // TODO 现在与config.Config相同，未来会被替换， 现在为了兼容性不得不保留当前文件
 type ConfigFile struct {
-	BetaMode           bool                  `json:"beta_mode"`
-	APIServerPort      int                   `json:"api_server_port"`
-	UseDefaultCoins    bool                  `json:"use_default_coins"`
-	DefaultCoins       []string              `json:"default_coins"`
-	CoinPoolAPIURL     string                `json:"coin_pool_api_url"`
-	OITopAPIURL        string                `json:"oi_top_api_url"`
-	MaxDailyLoss       float64               `json:"max_daily_loss"`
-	MaxDrawdown        float64               `json:"max_drawdown"`
-	StopTradingMinutes int                   `json:"stop_trading_minutes"`
-	Leverage           config.LeverageConfig `json:"leverage"`
-	JWTSecret          string                `json:"jwt_secret"`
-	DataKLineTime      string                `json:"data_k_line_time"`
-	Log                *config.LogConfig     `json:"log"` // 日志配置
+	BetaMode           bool                   `json:"beta_mode"`
+	APIServerPort      int                    `json:"api_server_port"`
+	UseDefaultCoins    bool                   `json:"use_default_coins"`
+	DefaultCoins       []string               `json:"default_coins"`
+	CoinPoolAPIURL     string                 `json:"coin_pool_api_url"`
+	OITopAPIURL        string                 `json:"oi_top_api_url"`
+	MaxDailyLoss       float64                `json:"max_daily_loss"`
+	MaxDrawdown        float64                `json:"max_drawdown"`
+	StopTradingMinutes int                    `json:"stop_trading_minutes"`
+	Leverage           config.LeverageConfig  `json:"leverage"`
+	JWTSecret          string                 `json:"jwt_secret"`
+	DataKLineTime      string                 `json:"data_k_line_time"`
+	Log                *config.LogConfig      `json:"log"`      // 日志配置
+	Database           *config.DatabaseConfig `json:"database"` // 数据库连接配置
 }
 
 // loadConfigFile 读取并解析config.json文件
@@ -202,13 +204,34 @@ func main() {
 		log.Fatalf("❌ 读取config.json失败: %v", err)
 	}
 
+	// 初始化结构化日志（log.format=json 时 stdout 输出一行一个 JSON 对象）
+	var logConfig *config.LogConfig
+	if configFile != nil {
+		logConfig = configFile.Log
+	}
+	if err := logger.InitFromLogConfig(logConfig); err != nil {
+		log.Printf("⚠️  初始化日志失败: %v", err)
+	}
+	defer logger.Shutdown()
+
 	log.Printf("📋 初始化配置数据库: %s", dbPath)
-	database, err := config.NewDatabase(dbPath)
+	var dbConfig *config.DatabaseConfig
+	if configFile != nil {
+		dbConfig = configFile.Database
+	}
+	database, err := config.NewDatabaseWithConfig(dbPath, dbConfig)
 	if err != nil {
 		log.Fatalf("❌ 初始化数据库失败: %v", err)
 	}
 	defer database.Close()
 
+	// 检测上次运行是否非正常退出（自动启动交易员前据此执行崩溃恢复检查）
+	if unclean, err := database.MarkSessionStarted(); err != nil {
+		log.Printf("⚠️  记录启动状态失败: %v", err)
+	} else if unclean {
+		log.Printf("⚠️  上次运行未正常退出（可能被强制终止），自动启动的交易员将先执行崩溃恢复检查")
+	}
+
 	// 初始化加密服务
 	log.Printf("🔐 初始化加密服务...")
 	cryptoService, err := crypto.NewCryptoService("secrets/rsa_key")
@@ -272,6 +295,9 @@ func main() {
 		log.Printf("🔑 使用环境变量 JWT 密钥（优先级最高）")
 	}
 	auth.SetJWTSecret(jwtSecret)
+	// 登出/刷新撤销的token持久化到数据库（重启后仍然有效）；登出所有设备或修改密码后此前签发的token失效
+	auth.SetRevocationStore(database)
+	auth.SetTokenVersionLookup(database.GetTokenVersion)
 
 	// 获取管理员模式配置（用於自動啟動功能）
 	// 默認為 true，除非顯式設置為 "false"
@@ -325,6 +351,23 @@ func main() {
 		log.Printf("✓ 已配置OI Top API")
 	}
 
+	// 信号源快照：后台统一按有效期刷新，交易员决策周期不再等待信号源 HTTP 请求
+	if ttlStr, _ := database.GetSystemConfig("signal_cache_ttl_seconds"); ttlStr != "" {
+		if ttl, err := strconv.Atoi(ttlStr); err == nil && ttl > 0 {
+			pool.SetSnapshotTTL(time.Duration(ttl) * time.Second)
+		} else {
+			log.Printf("⚠️ signal_cache_ttl_seconds 配置无效: %s，使用默认值 %v", ttlStr, pool.DefaultSnapshotTTL)
+		}
+	}
+	if timeoutStr, _ := database.GetSystemConfig("signal_fetch_timeout_seconds"); timeoutStr != "" {
+		if timeout, err := strconv.Atoi(timeoutStr); err == nil && timeout > 0 {
+			pool.SetFetchTimeout(time.Duration(timeout) * time.Second)
+		} else {
+			log.Printf("⚠️ signal_fetch_timeout_seconds 配置无效: %s，使用默认值 %v", timeoutStr, pool.DefaultFetchTimeout)
+		}
+	}
+	pool.StartSnapshotRefresher()
+
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
 
@@ -424,9 +467,23 @@ func main() {
 	dataSourceManager.Start()
 	log.Printf("✅ 数据源管理器已启动，包含 %d 个数据源", 2)
 
+	// 存在启用测试网的交易所时，额外启动测试网数据源（测试网交易员的价格校验使用测试网行情）
+	testnetSourceManager := newTestnetDataSourceManager(database)
+	if testnetSourceManager != nil {
+		market.SetTestnetDSManager(testnetSourceManager)
+	}
+
 	// 启动流行情数据 - 默认使用所有交易员设置的币种 如果没有设置币种 则优先使用系统默认
 	// 获取所有活跃 trader 的时间线配置（合并后的并集）
 	timeframes := database.GetAllTimeframes()
+	// K线缓存过期倍数：数据年龄超过 N 个时间线周期时回退 REST 刷新
+	if factorStr, _ := database.GetSystemConfig("kline_cache_stale_factor"); factorStr != "" {
+		if factor, err := strconv.ParseFloat(factorStr, 64); err == nil {
+			market.SetKlineStaleFactor(factor)
+		} else {
+			log.Printf("⚠️ kline_cache_stale_factor 配置无效: %s，使用默认值 %.0f", factorStr, market.DefaultKlineStaleFactor)
+		}
+	}
 	go market.NewWSMonitor(150, timeframes, dataSourceManager).Start(database.GetCustomCoins())
 	//go market.NewWSMonitor(150, timeframes).Start([]string{}) //这里是一个使用方式 传入空的话 则使用market市场的所有币种
 	// 设置优雅退出
@@ -447,9 +504,17 @@ func main() {
 	log.Println("📛 收到退出信号，正在优雅关闭...")
 
 	// 步骤 1: 停止所有交易员
+	// flatten_on_shutdown=true 时停止后平掉全部持仓，避免服务下线期间持仓无人管理
 	log.Println("⏸️  停止所有交易员...")
-	traderManager.StopAll()
+	flattenOnShutdown, _ := database.GetSystemConfig("flatten_on_shutdown")
+	if flattenOnShutdown == "true" {
+		log.Println("🧹 flatten_on_shutdown 已启用，停止后将平掉全部持仓")
+	}
+	traderManager.StopAllWithOptions(manager.StopOptions{ClosePositions: flattenOnShutdown == "true"})
 	log.Println("✅ 所有交易员已停止")
+	if err := database.MarkCleanShutdown(); err != nil {
+		log.Printf("⚠️  记录正常退出状态失败: %v", err)
+	}
 
 	// 步骤 2: 关闭 API 服务器
 	log.Println("🛑 停止 API 服务器...")
@@ -462,6 +527,9 @@ func main() {
 	// 步骤 2.5: 停止数据源管理器
 	log.Println("🌐 停止数据源管理器...")
 	dataSourceManager.Stop()
+	if testnetSourceManager != nil {
+		testnetSourceManager.Stop()
+	}
 	log.Println("✅ 数据源管理器已停止")
 
 	// 步骤 3: 关闭数据库连接 (确保所有写入完成)
@@ -475,3 +543,33 @@ func main() {
 	fmt.Println()
 	fmt.Println("👋 感谢使用AI交易系统！")
 }
+
+// newTestnetDataSourceManager 根据启用测试网的交易所创建测试网数据源管理器，没有测试网交易所时返回 nil
+func newTestnetDataSourceManager(database *config.Database) *market.DataSourceManager {
+	exchangeIDs, err := database.GetEnabledTestnetExchanges()
+	if err != nil {
+		log.Printf("⚠️  查询测试网交易所失败，跳过测试网数据源: %v", err)
+		return nil
+	}
+
+	var sources []market.DataSource
+	for _, exchangeID := range exchangeIDs {
+		switch exchangeID {
+		case "binance":
+			sources = append(sources, market.NewBinanceTestnetDataSource())
+		case "hyperliquid":
+			sources = append(sources, market.NewHyperliquidDataSource(true))
+		}
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	dsm := market.NewDataSourceManager(60 * time.Second)
+	for _, source := range sources {
+		dsm.AddSource(source)
+	}
+	dsm.Start()
+	log.Printf("🧪 测试网数据源管理器已启动，包含 %d 个数据源", len(sources))
+	return dsm
+}
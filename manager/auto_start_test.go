@@ -5,6 +5,7 @@ import (
 	"nofx/config"
 	"os"
 	"testing"
+	"time"
 )
 
 // setupTestDatabase creates a temporary test database
@@ -102,6 +103,7 @@ func TestStartRunningTraders_NoRunningTraders(t *testing.T) {
 
 // TestStartRunningTraders_WithRunningTraders tests starting traders marked as running
 func TestStartRunningTraders_WithRunningTraders(t *testing.T) {
+	useTempWorkDir(t)
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
@@ -176,6 +178,7 @@ func TestStartRunningTraders_WithRunningTraders(t *testing.T) {
 
 // TestStartRunningTraders_MultipleUsers tests starting traders for multiple users
 func TestStartRunningTraders_MultipleUsers(t *testing.T) {
+	useTempWorkDir(t)
 	db, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
@@ -247,3 +250,75 @@ func TestStartRunningTraders_MultipleUsers(t *testing.T) {
 
 	t.Logf("✅ StartRunningTraders correctly handled multiple users")
 }
+
+// TestSelectAutoStartTraders tests priority ordering and the auto-start cap
+func TestSelectAutoStartTraders(t *testing.T) {
+	traders := []*config.TraderRecord{
+		{ID: "idle", Name: "idle"},
+		{ID: "vip", Name: "vip", StartPriority: 10},
+		{ID: "active", Name: "active"},
+		{ID: "low", Name: "low", StartPriority: -1},
+	}
+	lastActivity := map[string]string{
+		"idle":   "2024-01-01 00:00:00",
+		"active": "2024-06-01 00:00:00",
+		"low":    "2024-12-01 00:00:00",
+	}
+
+	toStart, deferred := selectAutoStartTraders(traders, lastActivity, 2)
+	if len(toStart) != 2 || toStart[0].ID != "vip" || toStart[1].ID != "active" {
+		t.Errorf("expected [vip active] to start, got %v", traderIDs(toStart))
+	}
+	if len(deferred) != 2 || deferred[0].ID != "idle" || deferred[1].ID != "low" {
+		t.Errorf("expected [idle low] deferred, got %v", traderIDs(deferred))
+	}
+
+	// 0 means unlimited
+	toStart, deferred = selectAutoStartTraders(traders, lastActivity, 0)
+	if len(toStart) != 4 || len(deferred) != 0 {
+		t.Errorf("expected all traders to start without a cap, got %d started / %d deferred", len(toStart), len(deferred))
+	}
+}
+
+// TestAutoStartConfig tests reading the auto-start cap and interval from system config
+func TestAutoStartConfig(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	if maxStart, interval := autoStartConfig(db); maxStart != 0 || interval != 0 {
+		t.Errorf("expected defaults (0, 0), got (%d, %v)", maxStart, interval)
+	}
+
+	db.SetSystemConfig("autostart_max", "5")
+	db.SetSystemConfig("autostart_interval", "3")
+	if maxStart, interval := autoStartConfig(db); maxStart != 5 || interval != 3*time.Second {
+		t.Errorf("expected (5, 3s), got (%d, %v)", maxStart, interval)
+	}
+}
+
+// TestStaggerOffset tests that first cycles are spread evenly across one scan interval
+func TestStaggerOffset(t *testing.T) {
+	cases := []struct {
+		minutes, i, n int
+		want          time.Duration
+	}{
+		{3, 0, 4, 0},
+		{3, 1, 4, 45 * time.Second},
+		{3, 3, 4, 135 * time.Second},
+		{3, 0, 1, 0}, // a single trader starts immediately
+		{0, 2, 4, 0},
+	}
+	for _, tc := range cases {
+		if got := staggerOffset(tc.minutes, tc.i, tc.n); got != tc.want {
+			t.Errorf("staggerOffset(%d, %d, %d) = %v, want %v", tc.minutes, tc.i, tc.n, got, tc.want)
+		}
+	}
+}
+
+func traderIDs(traders []*config.TraderRecord) []string {
+	ids := make([]string, 0, len(traders))
+	for _, tr := range traders {
+		ids = append(ids, tr.ID)
+	}
+	return ids
+}
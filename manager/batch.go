@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/trader"
+	"sync"
+)
+
+// 批量启停中单个交易员的结果状态
+const (
+	BatchStarted        = "started"
+	BatchStopped        = "stopped"
+	BatchAlreadyRunning = "already_running"
+	BatchAlreadyStopped = "already_stopped"
+	BatchNotFound       = "not_found"
+	BatchError          = "error"
+)
+
+// batchWorkers 批量启停的最大并发数（停止需等待监控协程退出，避免串行等待过久）
+const batchWorkers = 4
+
+// BatchResult 单个交易员的批量操作结果
+type BatchResult struct {
+	Status          string   `json:"status"`
+	Error           string   `json:"error,omitempty"`
+	ClosedPositions int      `json:"closed_positions,omitempty"` // 停止时平掉的持仓数
+	CloseFailures   []string `json:"close_failures,omitempty"`   // 停止时平仓/撤单失败的说明
+}
+
+// StopOptions 停止交易员的选项
+type StopOptions struct {
+	ClosePositions bool // 停止后平掉全部持仓并撤销挂单
+}
+
+// TraderStatusStore 持久化交易员运行状态（为 nil 时只启停内存中的交易员）
+type TraderStatusStore interface {
+	UpdateTraderStatus(userID, id string, isRunning bool) error
+}
+
+// StartTraders 批量启动交易员：启动前重新加载提示词模板，启动后更新数据库中的运行状态
+// 返回每个交易员的结果，key 为交易员ID
+func (tm *TraderManager) StartTraders(ids []string, store TraderStatusStore) map[string]BatchResult {
+	if err := decision.ReloadPromptTemplates(); err != nil {
+		log.Printf("⚠️  重新加载提示词模板失败: %v", err)
+	}
+
+	return tm.runBatch(ids, func(at *trader.AutoTrader) BatchResult {
+		if isTraderRunning(at) {
+			return BatchResult{Status: BatchAlreadyRunning}
+		}
+
+		go func() {
+			log.Printf("▶️  启动交易员 %s (%s)", at.GetID(), at.GetName())
+			if err := at.Run(); err != nil {
+				log.Printf("❌ 交易员 %s 运行错误: %v", at.GetName(), err)
+			}
+		}()
+
+		if store != nil {
+			if err := store.UpdateTraderStatus(at.GetUserID(), at.GetID(), true); err != nil {
+				log.Printf("⚠️  更新交易员状态失败: %v", err)
+			}
+		}
+		log.Printf("✓ 交易员 %s 已启动", at.GetName())
+		return BatchResult{Status: BatchStarted}
+	})
+}
+
+// StopTraders 批量停止交易员，停止后更新数据库中的运行状态（保留持仓）
+// 返回每个交易员的结果，key 为交易员ID
+func (tm *TraderManager) StopTraders(ids []string, store TraderStatusStore) map[string]BatchResult {
+	return tm.StopTradersWithOptions(ids, store, StopOptions{})
+}
+
+// StopTradersWithOptions 批量停止交易员，opts.ClosePositions 为 true 时停止后平掉全部持仓
+// 已停止的交易员也会平仓（停止时遗留的持仓可以之后再平掉）
+func (tm *TraderManager) StopTradersWithOptions(ids []string, store TraderStatusStore, opts StopOptions) map[string]BatchResult {
+	return tm.runBatch(ids, func(at *trader.AutoTrader) BatchResult {
+		if !isTraderRunning(at) {
+			result := BatchResult{Status: BatchAlreadyStopped}
+			if opts.ClosePositions {
+				result.withFlatten(at.StopAndFlatten())
+			}
+			return result
+		}
+
+		result := BatchResult{Status: BatchStopped}
+		if opts.ClosePositions {
+			result.withFlatten(at.StopAndFlatten())
+		} else {
+			at.Stop()
+		}
+
+		if store != nil {
+			if err := store.UpdateTraderStatus(at.GetUserID(), at.GetID(), false); err != nil {
+				log.Printf("⚠️  更新交易员状态失败: %v", err)
+			}
+		}
+		log.Printf("⏹  交易员 %s 已停止", at.GetName())
+		return result
+	})
+}
+
+// withFlatten 记录停止时的平仓结果
+func (r *BatchResult) withFlatten(f trader.FlattenResult) {
+	r.ClosedPositions = f.Closed
+	r.CloseFailures = f.Failures
+}
+
+// runBatch 以有界并发对交易员执行操作（重复ID只执行一次，单个交易员 panic 记为 error 不影响其他交易员）
+func (tm *TraderManager) runBatch(ids []string, op func(at *trader.AutoTrader) BatchResult) map[string]BatchResult {
+	results := make(map[string]BatchResult, len(ids))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchWorkers)
+	seen := make(map[string]bool, len(ids))
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		at, err := tm.GetTrader(id)
+		if err != nil {
+			mu.Lock()
+			results[id] = BatchResult{Status: BatchNotFound}
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string, at *trader.AutoTrader) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := func() (result BatchResult) {
+				defer func() {
+					if r := recover(); r != nil {
+						log.Printf("❌ 批量操作交易员 %s 失败: %v", id, r)
+						result = BatchResult{Status: BatchError, Error: fmt.Sprintf("%v", r)}
+					}
+				}()
+				return op(at)
+			}()
+
+			mu.Lock()
+			results[id] = result
+			mu.Unlock()
+		}(id, at)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// isTraderRunning 交易员是否正在运行
+func isTraderRunning(at *trader.AutoTrader) bool {
+	running, _ := at.GetStatus()["is_running"].(bool)
+	return running
+}
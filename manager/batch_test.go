@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"nofx/trader"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingStatusStore records the running status written by batch operations
+type recordingStatusStore struct {
+	mu      sync.Mutex
+	updates map[string]bool
+}
+
+func (s *recordingStatusStore) UpdateTraderStatus(userID, id string, isRunning bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updates[userID+"/"+id] = isRunning
+	return nil
+}
+
+// TestStartStopTraders tests per-trader results of batch start/stop, duplicate ids and status persistence
+func TestStartStopTraders(t *testing.T) {
+	useTempWorkDir(t)
+	tm := NewTraderManager()
+	for _, id := range []string{"batch-a", "batch-b"} {
+		at, err := trader.NewAutoTrader(trader.AutoTraderConfig{
+			ID:             id,
+			Name:           id,
+			AIModel:        "deepseek",
+			Exchange:       "binance",
+			InitialBalance: 1000.0,
+			ScanInterval:   time.Hour,
+		}, nil, "batch-user")
+		if err != nil {
+			t.Fatalf("Failed to create trader %s: %v", id, err)
+		}
+		tm.traders[id] = at
+	}
+	store := &recordingStatusStore{updates: make(map[string]bool)}
+
+	results := tm.StartTraders([]string{"batch-a", "batch-a", "missing"}, store)
+	if len(results) != 2 || results["batch-a"].Status != BatchStarted || results["missing"].Status != BatchNotFound {
+		t.Fatalf("Unexpected start results: %+v", results)
+	}
+	at, _ := tm.GetTrader("batch-a")
+	deadline := time.Now().Add(5 * time.Second)
+	for !isTraderRunning(at) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := tm.StartTraders([]string{"batch-a"}, store)["batch-a"].Status; got != BatchAlreadyRunning {
+		t.Errorf("Expected already_running, got %s", got)
+	}
+
+	results = tm.StopTraders([]string{"batch-a", "batch-b"}, store)
+	if results["batch-a"].Status != BatchStopped || results["batch-b"].Status != BatchAlreadyStopped {
+		t.Errorf("Unexpected stop results: %+v", results)
+	}
+	if running, ok := store.updates["batch-user/batch-a"]; !ok || running {
+		t.Errorf("Expected batch-a to be persisted as stopped, got %v", store.updates)
+	}
+	if _, ok := store.updates["batch-user/batch-b"]; ok {
+		t.Error("Already stopped trader should not update the store")
+	}
+}
@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/trader"
+	"sort"
+	"time"
+)
+
+// 排行榜分页参数
+const (
+	LeaderboardDefaultPageSize = 20
+	LeaderboardMaxPageSize     = 100
+)
+
+// 排行榜排序字段
+const (
+	LeaderboardSortPnLPct      = "total_pnl_pct" // 收益率（选择统计周期时按周期收益率）
+	LeaderboardSortEquity      = "total_equity"
+	LeaderboardSortWinRate     = "win_rate" // 统计周期内的胜率
+	LeaderboardSortRunningDays = "running_days"
+)
+
+var leaderboardSortFields = map[string]bool{
+	LeaderboardSortPnLPct:      true,
+	LeaderboardSortEquity:      true,
+	LeaderboardSortWinRate:     true,
+	LeaderboardSortRunningDays: true,
+}
+
+// LeaderboardQuery 公开排行榜查询参数
+type LeaderboardQuery struct {
+	Sort     string // total_pnl_pct / total_equity / win_rate / running_days，默认 total_pnl_pct
+	Order    string // desc / asc，默认 desc
+	Period   string // 24h / 7d / 30d / all，默认 all
+	Page     int    // 从 1 开始
+	PageSize int    // 默认 20，最大 100
+}
+
+// Normalize 填充默认值并校验参数（page_size 超过上限时截断）
+func (q *LeaderboardQuery) Normalize() error {
+	if q.Sort == "" {
+		q.Sort = LeaderboardSortPnLPct
+	}
+	if !leaderboardSortFields[q.Sort] {
+		return fmt.Errorf("无效的排序字段: %s（可选 total_pnl_pct、total_equity、win_rate、running_days）", q.Sort)
+	}
+	if q.Order == "" {
+		q.Order = "desc"
+	}
+	if q.Order != "desc" && q.Order != "asc" {
+		return fmt.Errorf("无效的排序方向: %s（可选 asc、desc）", q.Order)
+	}
+	if q.Period == "" {
+		q.Period = trader.LeaderboardPeriodAll
+	}
+	if _, ok := trader.LeaderboardPeriods[q.Period]; !ok {
+		return fmt.Errorf("无效的统计周期: %s（可选 24h、7d、30d、all）", q.Period)
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = LeaderboardDefaultPageSize
+	}
+	if q.PageSize > LeaderboardMaxPageSize {
+		q.PageSize = LeaderboardMaxPageSize
+	}
+	return nil
+}
+
+// leaderboardEntry 竞赛数据缓存中的单个交易员（账户数据 + 排行榜衍生指标）
+type leaderboardEntry struct {
+	data    map[string]interface{}
+	metrics *trader.LeaderboardMetrics
+}
+
+// buildLeaderboardEntries 为每个交易员计算排行榜衍生指标（data 与 traders 按下标一一对应）
+func buildLeaderboardEntries(traders []*trader.AutoTrader, data []map[string]interface{}, now time.Time) []*leaderboardEntry {
+	entries := make([]*leaderboardEntry, len(traders))
+	for i, t := range traders {
+		entries[i] = &leaderboardEntry{
+			data:    data[i],
+			metrics: t.LeaderboardMetrics(toFloat(data[i]["total_equity"]), toFloat(data[i]["total_pnl_pct"]), now),
+		}
+	}
+	return entries
+}
+
+// sortValue 排行榜排序值
+func (e *leaderboardEntry) sortValue(field, period string) float64 {
+	switch field {
+	case LeaderboardSortEquity:
+		return toFloat(e.data["total_equity"])
+	case LeaderboardSortWinRate:
+		return e.metrics.Periods[period].WinRate
+	case LeaderboardSortRunningDays:
+		return e.metrics.RunningDays
+	default:
+		return e.metrics.Periods[period].PnLPct
+	}
+}
+
+// GetLeaderboard 获取公开排行榜（支持统计周期、排序和分页）
+// 复用竞赛数据缓存（30秒），缓存中已预先计算各周期收益率/胜率和运行天数
+func (tm *TraderManager) GetLeaderboard(q LeaderboardQuery) (map[string]interface{}, error) {
+	if err := q.Normalize(); err != nil {
+		return nil, err
+	}
+
+	tm.competitionCache.mu.RLock()
+	entries := tm.competitionCache.entries
+	fresh := time.Since(tm.competitionCache.timestamp) < 30*time.Second && entries != nil
+	tm.competitionCache.mu.RUnlock()
+	if !fresh {
+		_, entries = tm.refreshCompetitionCache()
+	}
+
+	// 复制后排序，避免修改缓存中的顺序；相同值按交易员ID排序，保证翻页稳定
+	sorted := make([]*leaderboardEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		vi, vj := sorted[i].sortValue(q.Sort, q.Period), sorted[j].sortValue(q.Sort, q.Period)
+		if vi != vj {
+			if q.Order == "asc" {
+				return vi < vj
+			}
+			return vi > vj
+		}
+		return fmt.Sprint(sorted[i].data["trader_id"]) < fmt.Sprint(sorted[j].data["trader_id"])
+	})
+
+	total := len(sorted)
+	start := (q.Page - 1) * q.PageSize
+	if start > total {
+		start = total
+	}
+	end := start + q.PageSize
+	if end > total {
+		end = total
+	}
+
+	traders := make([]map[string]interface{}, 0, end-start)
+	for i := start; i < end; i++ {
+		entry := sorted[i]
+		stats := entry.metrics.Periods[q.Period]
+
+		item := make(map[string]interface{}, len(entry.data)+5)
+		for k, v := range entry.data {
+			item[k] = v
+		}
+		item["rank"] = i + 1
+		item["period_pnl_pct"] = stats.PnLPct
+		item["win_rate"] = stats.WinRate
+		item["closed_trades"] = stats.Trades
+		item["running_days"] = entry.metrics.RunningDays
+		traders = append(traders, item)
+	}
+
+	totalPages := (total + q.PageSize - 1) / q.PageSize
+	return map[string]interface{}{
+		"traders":     traders,
+		"count":       len(traders),
+		"total_count": total,
+		"page":        q.Page,
+		"page_size":   q.PageSize,
+		"total_pages": totalPages,
+		"sort":        q.Sort,
+		"order":       q.Order,
+		"period":      q.Period,
+	}, nil
+}
+
+// toFloat 将竞赛数据中的数值字段转换为 float64（缺失或类型不符时为 0）
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
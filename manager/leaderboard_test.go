@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/trader"
+	"testing"
+	"time"
+)
+
+// leaderboardTestEntry 构造带排行榜指标的缓存条目
+func leaderboardTestEntry(id string, equity, totalPnLPct, pnl7d, winRate7d, runningDays float64) *leaderboardEntry {
+	return &leaderboardEntry{
+		data: map[string]interface{}{
+			"trader_id":     id,
+			"total_equity":  equity,
+			"total_pnl_pct": totalPnLPct,
+		},
+		metrics: &trader.LeaderboardMetrics{
+			RunningDays: runningDays,
+			Periods: map[string]trader.LeaderboardPeriodStats{
+				"24h": {},
+				"7d":  {PnLPct: pnl7d, WinRate: winRate7d, Trades: 3},
+				"30d": {},
+				"all": {PnLPct: totalPnLPct},
+			},
+		},
+	}
+}
+
+func TestGetLeaderboard_SortPeriodAndPaging(t *testing.T) {
+	tm := NewTraderManager()
+
+	entries := make([]*leaderboardEntry, 0, 45)
+	for i := 0; i < 45; i++ {
+		// 总收益率与7天收益率排序相反
+		entries = append(entries, leaderboardTestEntry(fmt.Sprintf("t%02d", i), 1000+float64(i), float64(i), float64(-i), float64(i%5), float64(i)))
+	}
+	tm.competitionCache.mu.Lock()
+	tm.competitionCache.entries = entries
+	tm.competitionCache.timestamp = time.Now()
+	tm.competitionCache.mu.Unlock()
+
+	data, err := tm.GetLeaderboard(LeaderboardQuery{Period: "7d", Page: 1, PageSize: 20})
+	if err != nil {
+		t.Fatalf("GetLeaderboard failed: %v", err)
+	}
+	traders := data["traders"].([]map[string]interface{})
+	if len(traders) != 20 || data["total_count"] != 45 || data["total_pages"] != 3 {
+		t.Fatalf("分页错误: count=%d total=%v pages=%v", len(traders), data["total_count"], data["total_pages"])
+	}
+	if traders[0]["trader_id"] != "t00" || traders[0]["rank"] != 1 || traders[0]["period_pnl_pct"] != 0.0 {
+		t.Errorf("7天收益率第一名应为 t00，实际 %v (rank=%v)", traders[0]["trader_id"], traders[0]["rank"])
+	}
+
+	// 第3页只有5条，名次接续
+	data, _ = tm.GetLeaderboard(LeaderboardQuery{Period: "7d", Page: 3, PageSize: 20})
+	traders = data["traders"].([]map[string]interface{})
+	if len(traders) != 5 || traders[0]["rank"] != 41 {
+		t.Errorf("第3页应有5条且从第41名开始，实际 %d 条，首条 rank=%v", len(traders), traders[0]["rank"])
+	}
+
+	// 全部周期按总收益率降序
+	data, _ = tm.GetLeaderboard(LeaderboardQuery{})
+	traders = data["traders"].([]map[string]interface{})
+	if traders[0]["trader_id"] != "t44" || data["page_size"] != LeaderboardDefaultPageSize {
+		t.Errorf("默认排行榜第一名应为 t44，实际 %v", traders[0]["trader_id"])
+	}
+
+	// 运行天数升序
+	data, _ = tm.GetLeaderboard(LeaderboardQuery{Sort: LeaderboardSortRunningDays, Order: "asc"})
+	traders = data["traders"].([]map[string]interface{})
+	if traders[0]["trader_id"] != "t00" {
+		t.Errorf("运行天数升序第一名应为 t00，实际 %v", traders[0]["trader_id"])
+	}
+
+	// 缓存顺序不受排序影响
+	if entries[0].data["trader_id"] != "t00" {
+		t.Error("GetLeaderboard 不应修改缓存条目顺序")
+	}
+}
+
+func TestLeaderboardQueryNormalize(t *testing.T) {
+	q := LeaderboardQuery{PageSize: 1000}
+	if err := q.Normalize(); err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if q.Sort != LeaderboardSortPnLPct || q.Order != "desc" || q.Period != "all" || q.Page != 1 || q.PageSize != LeaderboardMaxPageSize {
+		t.Errorf("默认值错误: %+v", q)
+	}
+
+	for _, invalid := range []LeaderboardQuery{{Sort: "name"}, {Order: "up"}, {Period: "1y"}} {
+		if err := invalid.Normalize(); err == nil {
+			t.Errorf("无效参数应返回错误: %+v", invalid)
+		}
+	}
+}
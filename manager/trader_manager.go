@@ -6,24 +6,35 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/crypto"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/mcp"
+	"nofx/metrics"
+	"nofx/netproxy"
 	"nofx/trader"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 // CompetitionCache 竞赛数据缓存
 type CompetitionCache struct {
-	data      map[string]interface{}
+	data      map[string]interface{} // 竞赛数据（前50名）
+	entries   []*leaderboardEntry    // 全部交易员及排行榜衍生指标（按总收益率降序）
 	timestamp time.Time
 	mu        sync.RWMutex
 }
 
 // TraderManager 管理多个trader实例
 type TraderManager struct {
-	traders          map[string]*trader.AutoTrader // key: trader ID
+	traders          map[string]*trader.AutoTrader     // key: trader ID
+	portfolioGroups  map[string]*trader.PortfolioGroup // key: userID + "/" + 分组名称
+	symbolRegistry   *trader.SymbolPositionRegistry    // 全实例币种持仓登记表（跨交易员限制同币种持仓数）
 	competitionCache *CompetitionCache
 	mu               sync.RWMutex
 }
@@ -31,7 +42,9 @@ type TraderManager struct {
 // NewTraderManager 创建trader管理器
 func NewTraderManager() *TraderManager {
 	return &TraderManager{
-		traders: make(map[string]*trader.AutoTrader),
+		traders:         make(map[string]*trader.AutoTrader),
+		portfolioGroups: make(map[string]*trader.PortfolioGroup),
+		symbolRegistry:  trader.NewSymbolPositionRegistry(0),
 		competitionCache: &CompetitionCache{
 			data: make(map[string]interface{}),
 		},
@@ -43,6 +56,9 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
+	// 重启后保持维护模式（交易员启动后第一个周期就会跳过决策）
+	applyMaintenanceMode(database)
+
 	// 获取所有用户
 	userIDs, err := database.GetAllUsers()
 	if err != nil {
@@ -70,6 +86,7 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
 	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
 	defaultCoinsStr, _ := database.GetSystemConfig("default_coins")
+	tm.refreshSymbolPositionLimit(database)
 
 	// 解析配置
 	maxDailyLoss := 10.0 // 默认值
@@ -114,12 +131,12 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 			}
 		}
 		if aiModelCfg == nil {
-			log.Printf("⚠️  交易员 %s 的AI模型 %d 不存在，跳过", traderCfg.Name, traderCfg.AIModelID)
+			traderLog(traderCfg).Warnf("⚠️  交易员 %s 的AI模型 %d 不存在，跳过", traderCfg.Name, traderCfg.AIModelID)
 			continue
 		}
 
 		if !aiModelCfg.Enabled {
-			log.Printf("⚠️  交易员 %s 的AI模型 %d 未启用，跳过", traderCfg.Name, traderCfg.AIModelID)
+			traderLog(traderCfg).Warnf("⚠️  交易员 %s 的AI模型 %d 未启用，跳过", traderCfg.Name, traderCfg.AIModelID)
 			continue
 		}
 
@@ -139,12 +156,12 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 		}
 
 		if exchangeCfg == nil {
-			log.Printf("⚠️  交易员 %s 的交易所 %d 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			traderLog(traderCfg).Warnf("⚠️  交易员 %s 的交易所 %d 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
 			continue
 		}
 
 		if !exchangeCfg.Enabled {
-			log.Printf("⚠️  交易员 %s 的交易所 %d 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			traderLog(traderCfg).Warnf("⚠️  交易员 %s 的交易所 %d 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
 			continue
 		}
 
@@ -161,7 +178,7 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 		// 添加到TraderManager
 		err = tm.addTraderFromDB(traderCfg, aiModelCfg, exchangeCfg, coinPoolURL, oiTopURL, maxDailyLoss, maxDrawdown, stopTradingMinutes, defaultCoins, database, traderCfg.UserID)
 		if err != nil {
-			log.Printf("❌ 添加交易员 %s 失败: %v", traderCfg.Name, err)
+			traderLog(traderCfg).Errorf("❌ 添加交易员 %s 失败: %v", traderCfg.Name, err)
 			continue
 		}
 	}
@@ -198,56 +215,96 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 	var effectiveCoinPoolURL string
 	if traderCfg.UseCoinPool && coinPoolURL != "" {
 		effectiveCoinPoolURL = coinPoolURL
-		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
+		traderLog(traderCfg).Infof("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
 
 	var effectiveOITopURL string
 	if traderCfg.UseOITop && oiTopURL != "" {
 		effectiveOITopURL = oiTopURL
-		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+		traderLog(traderCfg).Infof("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
 	}
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
-		ID:                    traderCfg.ID,
-		Name:                  traderCfg.Name,
-		AIModel:               aiModelCfg.Provider,    // 使用provider作为模型标识
-		Exchange:              exchangeCfg.ExchangeID, // 使用exchange ID
-		BinanceAPIKey:         "",
-		BinanceSecretKey:      "",
-		HyperliquidPrivateKey: "",
-		HyperliquidTestnet:    exchangeCfg.Testnet,
-		CoinPoolAPIURL:        effectiveCoinPoolURL,
-		OITopAPIURL:           effectiveOITopURL,
-		UseQwen:               aiModelCfg.Provider == "qwen",
-		DeepSeekKey:           "",
-		QwenKey:               "",
-		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
-		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		InitialBalance:        traderCfg.InitialBalance,
-		BTCETHLeverage:        traderCfg.BTCETHLeverage,
-		AltcoinLeverage:       traderCfg.AltcoinLeverage,
-		TakerFeeRate:          traderCfg.TakerFeeRate, // Taker fee rate from config
-		MakerFeeRate:          traderCfg.MakerFeeRate, // Maker fee rate from config
-		MaxDailyLoss:          maxDailyLoss,
-		MaxDrawdown:           maxDrawdown,
-		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:         traderCfg.IsCrossMargin,
-		DefaultCoins:          defaultCoins,
-		TradingCoins:          tradingCoins,
-		UseCoinPool:           traderCfg.UseCoinPool,          // 币种池信号源配置
-		UseOITop:              traderCfg.UseOITop,             // OI Top 信号源配置
-		SystemPromptTemplate:  traderCfg.SystemPromptTemplate, // 系统提示词模板
-		OrderStrategy:         traderCfg.OrderStrategy,        // 订单策略
-		LimitPriceOffset:      traderCfg.LimitPriceOffset,     // 限价偏移
-		LimitTimeoutSeconds:   traderCfg.LimitTimeoutSeconds,  // 限价超时
-	}
+		ID:                     traderCfg.ID,
+		Name:                   traderCfg.Name,
+		AIModel:                aiModelCfg.Provider,    // 使用provider作为模型标识
+		Exchange:               exchangeCfg.ExchangeID, // 使用exchange ID
+		BinanceAPIKey:          "",
+		BinanceSecretKey:       "",
+		HyperliquidPrivateKey:  "",
+		HyperliquidTestnet:     exchangeCfg.Testnet,
+		CoinPoolAPIURL:         effectiveCoinPoolURL,
+		OITopAPIURL:            effectiveOITopURL,
+		UseQwen:                aiModelCfg.Provider == "qwen",
+		DeepSeekKey:            "",
+		QwenKey:                "",
+		CustomAPIURL:           aiModelCfg.CustomAPIURL,       // 自定义API URL
+		CustomModelName:        aiModelCfg.CustomModelName,    // 自定义模型名称
+		SystemPromptPrefix:     aiModelCfg.SystemPromptPrefix, // 模型专属 System Prompt 前缀
+		SystemPromptSuffix:     aiModelCfg.SystemPromptSuffix, // 模型专属 System Prompt 后缀
+		ScanInterval:           time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		InitialBalance:         traderCfg.InitialBalance,
+		BTCETHLeverage:         traderCfg.BTCETHLeverage,
+		AltcoinLeverage:        traderCfg.AltcoinLeverage,
+		TakerFeeRate:           traderCfg.TakerFeeRate, // Taker fee rate from config
+		MakerFeeRate:           traderCfg.MakerFeeRate, // Maker fee rate from config
+		MaxDailyLoss:           maxDailyLoss,
+		MaxDrawdown:            maxDrawdown,
+		StopTradingTime:        time.Duration(stopTradingMinutes) * time.Minute,
+		IsCrossMargin:          traderCfg.IsCrossMargin,
+		DefaultCoins:           defaultCoins,
+		TradingCoins:           tradingCoins,
+		UseCoinPool:            traderCfg.UseCoinPool,                                        // 币种池信号源配置
+		UseOITop:               traderCfg.UseOITop,                                           // OI Top 信号源配置
+		SystemPromptTemplate:   traderCfg.SystemPromptTemplate,                               // 系统提示词模板
+		OrderStrategy:          traderCfg.OrderStrategy,                                      // 订单策略
+		LimitPriceOffset:       traderCfg.LimitPriceOffset,                                   // 限价偏移
+		LimitTimeoutSeconds:    traderCfg.LimitTimeoutSeconds,                                // 限价超时
+		MaxTradesPerDay:        traderCfg.MaxTradesPerDay,                                    // 每日开仓上限
+		HoldCachePct:           traderCfg.HoldCachePct,                                       // 持有决策缓存阈值
+		MaxExposureMultiple:    traderCfg.MaxExposureMultiple,                                // 总敞口倍数上限
+		RespectSignalBias:      traderCfg.RespectSignalBias,                                  // 遵循信号源方向
+		DryRun:                 traderCfg.DryRun,                                             // 模拟运行
+		AlertDrawdownPct:       traderCfg.AlertDrawdownPct,                                   // 净值回撤预警
+		AlertDailyLossPct:      traderCfg.AlertDailyLossPct,                                  // 当日亏损预警
+		AIQualityWindow:        traderCfg.AIQualityWindow,                                    // AI质量检测窗口
+		AIQualityMaxFailurePct: traderCfg.AIQualityMaxFailurePct,                             // AI失败率阈值
+		AIQualityPause:         time.Duration(traderCfg.AIQualityPauseMinutes) * time.Minute, // AI质量暂停冷却
+		MaxConsecutiveErrors:   traderCfg.MaxConsecutiveErrors,                               // 连续失败自动暂停阈值
+		DailyReport:            traderCfg.DailyReport,                                        // 每日报告
+		UnfundedThreshold:      traderCfg.UnfundedThreshold,                                  // 未入金判定阈值
+		MaxAICallsPerDay:       traderCfg.MaxAICallsPerDay,                                   // 每日AI调用上限
+		RejectNonCandidates:    traderCfg.RejectNonCandidates,                                // 仅交易候选币种
+		OpenVerifyDelay:        time.Duration(traderCfg.OpenVerifyDelayMs) * time.Millisecond,
+		LogLevel:               traderCfg.LogLevel,
+	}
+
+	traderConfig.DecisionCompactAfter, traderConfig.DecisionCompactMode = decisionCompactConfig(database)
+	applyRiskLimitOverrides(&traderConfig, traderCfg)
+	traderConfig.DecisionRetention = decisionRetentionConfig(database)
+	traderConfig.DecisionLogCrypto, traderConfig.EncryptDecisionLogs = decisionLogEncryptionConfig(database)
+	traderConfig.ModelPool, traderConfig.ModelPoolMode = modelPoolConfig(database, traderCfg)
+	traderConfig.FallbackModel = fallbackModelConfig(database, traderCfg)
+	traderConfig.StopUpdateTolerancePct = stopUpdateTolerance(database)
+	traderConfig.StrictPriceCheckNotional = strictPriceCheckNotional(database)
+	traderConfig.RecordRejections = rejectionLogEnabled(database)
+	traderConfig.RejectionFeedback = rejectionFeedbackEnabled(database)
+	traderConfig.SafetyStopPct = safetyStopPct(database)
+	traderConfig.OutboundProxy = outboundProxy(database, traderCfg.UserID)
+	traderConfig.TradingWindow = tradingWindowConfig(traderCfg)
+	traderConfig.FlattenOnWindowClose = traderCfg.FlattenOnWindowClose
+	traderConfig.MaxPositions = traderCfg.MaxPositions
+	traderConfig.MaxPositionSizeUSD = traderCfg.MaxPositionSizeUSD
+	traderConfig.BlacklistedSymbols = symbolBlacklist(database, traderCfg)
+	traderConfig.KlineLimit, traderConfig.Indicators = marketDataConfig(traderCfg)
+	traderConfig.ScanJitterPct = scanJitterPct(database)
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ExchangeID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceTestnet = exchangeCfg.Testnet
 	} else if exchangeCfg.ExchangeID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -255,6 +312,11 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.AsterUser = exchangeCfg.AsterUser
 		traderConfig.AsterSigner = exchangeCfg.AsterSigner
 		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
+	} else if exchangeCfg.ExchangeID == "okx" {
+		traderConfig.OKXAPIKey = exchangeCfg.APIKey
+		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
+		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
+		traderConfig.OKXTestnet = exchangeCfg.Testnet
 	}
 
 	// 根据AI模型设置API密钥
@@ -282,7 +344,9 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 	}
 
 	tm.traders[traderCfg.ID] = at
-	log.Printf("✓ Trader '%s' (%s + %s) 已加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ExchangeID)
+	tm.joinPortfolioGroup(userID, traderCfg.PortfolioGroup, at)
+	at.SetSymbolRegistry(tm.symbolRegistry)
+	traderLog(traderCfg).Infof("✓ Trader '%s' (%s + %s) 已加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ExchangeID)
 	return nil
 }
 
@@ -319,49 +383,89 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 	var effectiveCoinPoolURL string
 	if traderCfg.UseCoinPool && coinPoolURL != "" {
 		effectiveCoinPoolURL = coinPoolURL
-		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
+		traderLog(traderCfg).Infof("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
-		ID:                    traderCfg.ID,
-		Name:                  traderCfg.Name,
-		AIModel:               aiModelCfg.Provider,    // 使用provider作为模型标识
-		Exchange:              exchangeCfg.ExchangeID, // 使用exchange ID
-		BinanceAPIKey:         "",
-		BinanceSecretKey:      "",
-		HyperliquidPrivateKey: "",
-		HyperliquidTestnet:    exchangeCfg.Testnet,
-		CoinPoolAPIURL:        effectiveCoinPoolURL,
-		UseQwen:               aiModelCfg.Provider == "qwen",
-		DeepSeekKey:           "",
-		QwenKey:               "",
-		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
-		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		InitialBalance:        traderCfg.InitialBalance,
-		BTCETHLeverage:        traderCfg.BTCETHLeverage,
-		AltcoinLeverage:       traderCfg.AltcoinLeverage,
-		TakerFeeRate:          traderCfg.TakerFeeRate, // Taker fee rate from config
-		MakerFeeRate:          traderCfg.MakerFeeRate, // Maker fee rate from config
-		MaxDailyLoss:          maxDailyLoss,
-		MaxDrawdown:           maxDrawdown,
-		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:         traderCfg.IsCrossMargin,
-		DefaultCoins:          defaultCoins,
-		TradingCoins:          tradingCoins,
-		UseCoinPool:           traderCfg.UseCoinPool,          // 币种池信号源配置
-		UseOITop:              traderCfg.UseOITop,             // OI Top 信号源配置
-		SystemPromptTemplate:  traderCfg.SystemPromptTemplate, // 系统提示词模板
-		OrderStrategy:         traderCfg.OrderStrategy,        // 订单策略
-		LimitPriceOffset:      traderCfg.LimitPriceOffset,     // 限价偏移
-		LimitTimeoutSeconds:   traderCfg.LimitTimeoutSeconds,  // 限价超时
-	}
+		ID:                     traderCfg.ID,
+		Name:                   traderCfg.Name,
+		AIModel:                aiModelCfg.Provider,    // 使用provider作为模型标识
+		Exchange:               exchangeCfg.ExchangeID, // 使用exchange ID
+		BinanceAPIKey:          "",
+		BinanceSecretKey:       "",
+		HyperliquidPrivateKey:  "",
+		HyperliquidTestnet:     exchangeCfg.Testnet,
+		CoinPoolAPIURL:         effectiveCoinPoolURL,
+		UseQwen:                aiModelCfg.Provider == "qwen",
+		DeepSeekKey:            "",
+		QwenKey:                "",
+		CustomAPIURL:           aiModelCfg.CustomAPIURL,       // 自定义API URL
+		CustomModelName:        aiModelCfg.CustomModelName,    // 自定义模型名称
+		SystemPromptPrefix:     aiModelCfg.SystemPromptPrefix, // 模型专属 System Prompt 前缀
+		SystemPromptSuffix:     aiModelCfg.SystemPromptSuffix, // 模型专属 System Prompt 后缀
+		ScanInterval:           time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		InitialBalance:         traderCfg.InitialBalance,
+		BTCETHLeverage:         traderCfg.BTCETHLeverage,
+		AltcoinLeverage:        traderCfg.AltcoinLeverage,
+		TakerFeeRate:           traderCfg.TakerFeeRate, // Taker fee rate from config
+		MakerFeeRate:           traderCfg.MakerFeeRate, // Maker fee rate from config
+		MaxDailyLoss:           maxDailyLoss,
+		MaxDrawdown:            maxDrawdown,
+		StopTradingTime:        time.Duration(stopTradingMinutes) * time.Minute,
+		IsCrossMargin:          traderCfg.IsCrossMargin,
+		DefaultCoins:           defaultCoins,
+		TradingCoins:           tradingCoins,
+		UseCoinPool:            traderCfg.UseCoinPool,                                        // 币种池信号源配置
+		UseOITop:               traderCfg.UseOITop,                                           // OI Top 信号源配置
+		SystemPromptTemplate:   traderCfg.SystemPromptTemplate,                               // 系统提示词模板
+		OrderStrategy:          traderCfg.OrderStrategy,                                      // 订单策略
+		LimitPriceOffset:       traderCfg.LimitPriceOffset,                                   // 限价偏移
+		LimitTimeoutSeconds:    traderCfg.LimitTimeoutSeconds,                                // 限价超时
+		MaxTradesPerDay:        traderCfg.MaxTradesPerDay,                                    // 每日开仓上限
+		HoldCachePct:           traderCfg.HoldCachePct,                                       // 持有决策缓存阈值
+		MaxExposureMultiple:    traderCfg.MaxExposureMultiple,                                // 总敞口倍数上限
+		RespectSignalBias:      traderCfg.RespectSignalBias,                                  // 遵循信号源方向
+		DryRun:                 traderCfg.DryRun,                                             // 模拟运行
+		AlertDrawdownPct:       traderCfg.AlertDrawdownPct,                                   // 净值回撤预警
+		AlertDailyLossPct:      traderCfg.AlertDailyLossPct,                                  // 当日亏损预警
+		AIQualityWindow:        traderCfg.AIQualityWindow,                                    // AI质量检测窗口
+		AIQualityMaxFailurePct: traderCfg.AIQualityMaxFailurePct,                             // AI失败率阈值
+		AIQualityPause:         time.Duration(traderCfg.AIQualityPauseMinutes) * time.Minute, // AI质量暂停冷却
+		MaxConsecutiveErrors:   traderCfg.MaxConsecutiveErrors,                               // 连续失败自动暂停阈值
+		DailyReport:            traderCfg.DailyReport,                                        // 每日报告
+		UnfundedThreshold:      traderCfg.UnfundedThreshold,                                  // 未入金判定阈值
+		MaxAICallsPerDay:       traderCfg.MaxAICallsPerDay,                                   // 每日AI调用上限
+		RejectNonCandidates:    traderCfg.RejectNonCandidates,                                // 仅交易候选币种
+		OpenVerifyDelay:        time.Duration(traderCfg.OpenVerifyDelayMs) * time.Millisecond,
+		LogLevel:               traderCfg.LogLevel,
+	}
+
+	traderConfig.DecisionCompactAfter, traderConfig.DecisionCompactMode = decisionCompactConfig(database)
+	applyRiskLimitOverrides(&traderConfig, traderCfg)
+	traderConfig.DecisionRetention = decisionRetentionConfig(database)
+	traderConfig.DecisionLogCrypto, traderConfig.EncryptDecisionLogs = decisionLogEncryptionConfig(database)
+	traderConfig.ModelPool, traderConfig.ModelPoolMode = modelPoolConfig(database, traderCfg)
+	traderConfig.FallbackModel = fallbackModelConfig(database, traderCfg)
+	traderConfig.StopUpdateTolerancePct = stopUpdateTolerance(database)
+	traderConfig.StrictPriceCheckNotional = strictPriceCheckNotional(database)
+	traderConfig.RecordRejections = rejectionLogEnabled(database)
+	traderConfig.RejectionFeedback = rejectionFeedbackEnabled(database)
+	traderConfig.SafetyStopPct = safetyStopPct(database)
+	traderConfig.OutboundProxy = outboundProxy(database, traderCfg.UserID)
+	traderConfig.TradingWindow = tradingWindowConfig(traderCfg)
+	traderConfig.FlattenOnWindowClose = traderCfg.FlattenOnWindowClose
+	traderConfig.MaxPositions = traderCfg.MaxPositions
+	traderConfig.MaxPositionSizeUSD = traderCfg.MaxPositionSizeUSD
+	traderConfig.BlacklistedSymbols = symbolBlacklist(database, traderCfg)
+	traderConfig.KlineLimit, traderConfig.Indicators = marketDataConfig(traderCfg)
+	traderConfig.ScanJitterPct = scanJitterPct(database)
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ExchangeID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceTestnet = exchangeCfg.Testnet
 	} else if exchangeCfg.ExchangeID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -369,6 +473,11 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.AsterUser = exchangeCfg.AsterUser
 		traderConfig.AsterSigner = exchangeCfg.AsterSigner
 		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
+	} else if exchangeCfg.ExchangeID == "okx" {
+		traderConfig.OKXAPIKey = exchangeCfg.APIKey
+		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
+		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
+		traderConfig.OKXTestnet = exchangeCfg.Testnet
 	}
 
 	// 根据AI模型设置API密钥
@@ -396,7 +505,9 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 	}
 
 	tm.traders[traderCfg.ID] = at
-	log.Printf("✓ Trader '%s' (%s + %s) 已添加", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ExchangeID)
+	tm.joinPortfolioGroup(userID, traderCfg.PortfolioGroup, at)
+	at.SetSymbolRegistry(tm.symbolRegistry)
+	traderLog(traderCfg).Infof("✓ Trader '%s' (%s + %s) 已添加", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ExchangeID)
 	return nil
 }
 
@@ -466,6 +577,19 @@ func (tm *TraderManager) RemoveTrader(traderID string) error {
 		}
 	}
 
+	// 退出组合分组
+	if trader != nil {
+		if group := trader.GetPortfolioGroup(); group != nil {
+			group.Leave(traderID)
+		}
+	}
+
+	// 释放全局币种持仓登记
+	tm.symbolRegistry.RemoveTrader(traderID)
+
+	// 停止导出该交易员的 Prometheus 指标
+	metrics.RemoveTrader(traderID)
+
 	// 从map中删除
 	delete(tm.traders, traderID)
 	log.Printf("✅ 已从内存中移除交易员: %s", traderID)
@@ -473,6 +597,7 @@ func (tm *TraderManager) RemoveTrader(traderID string) error {
 	// 清除竞赛缓存，强制下次重新计算
 	tm.competitionCache.mu.Lock()
 	tm.competitionCache.data = nil
+	tm.competitionCache.entries = nil
 	tm.competitionCache.timestamp = time.Time{}
 	tm.competitionCache.mu.Unlock()
 	log.Printf("🔄 已清除竞赛缓存")
@@ -480,6 +605,371 @@ func (tm *TraderManager) RemoveTrader(traderID string) error {
 	return nil
 }
 
+// joinPortfolioGroup 将交易员加入组合分组（调用方需持有 tm.mu 写锁）
+// 分组按用户隔离，只有同一用户显式设置相同分组名称的交易员才会共用AI调用
+func (tm *TraderManager) joinPortfolioGroup(userID, groupName string, at *trader.AutoTrader) {
+	groupName = strings.TrimSpace(groupName)
+	if groupName == "" || at == nil {
+		return
+	}
+
+	key := userID + "/" + groupName
+	group, exists := tm.portfolioGroups[key]
+	if !exists {
+		group = trader.NewPortfolioGroup(groupName, at.GetExchange())
+		tm.portfolioGroups[key] = group
+	}
+
+	if err := group.Join(at); err != nil {
+		at.Logger().Warnf("⚠️ 交易员 %s 加入组合失败，将独立决策: %v", at.GetName(), err)
+	}
+}
+
+// symbolPositionLimit 读取系统配置中同一币种最多持仓交易员数（max_symbol_traders，0=不限制）
+func symbolPositionLimit(database *config.Database) int {
+	limitStr, _ := database.GetSystemConfig("max_symbol_traders")
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// refreshSymbolPositionLimit 从系统配置刷新同一币种最多持仓交易员数
+// 登记表在每次开仓前重新读取该配置，修改 max_symbol_traders 后无需重新加载交易员
+func (tm *TraderManager) refreshSymbolPositionLimit(database *config.Database) {
+	limit := symbolPositionLimit(database)
+	if limit != tm.symbolRegistry.Limit() {
+		log.Printf("🌐 全局币种持仓上限: 每个币种最多 %d 个交易员同时持仓（0=不限制）", limit)
+	}
+	tm.symbolRegistry.SetLimit(limit)
+	tm.symbolRegistry.SetLimitSource(func() int { return symbolPositionLimit(database) })
+}
+
+// applyRiskLimitOverrides 用交易员级风控阈值覆盖系统配置（max_daily_loss/max_drawdown/stop_trading_minutes），并记录每项阈值的来源
+func applyRiskLimitOverrides(traderConfig *trader.AutoTraderConfig, traderCfg *config.TraderRecord) {
+	traderConfig.MaxDailyLossSource = trader.RiskLimitSourceSystem
+	traderConfig.MaxDrawdownSource = trader.RiskLimitSourceSystem
+	traderConfig.StopTradingTimeSource = trader.RiskLimitSourceSystem
+	if traderCfg.MaxDailyLoss != nil {
+		traderConfig.MaxDailyLoss = *traderCfg.MaxDailyLoss
+		traderConfig.MaxDailyLossSource = trader.RiskLimitSourceTrader
+	}
+	if traderCfg.MaxDrawdown != nil {
+		traderConfig.MaxDrawdown = *traderCfg.MaxDrawdown
+		traderConfig.MaxDrawdownSource = trader.RiskLimitSourceTrader
+	}
+	if traderCfg.StopTradingMinutes != nil {
+		traderConfig.StopTradingTime = time.Duration(*traderCfg.StopTradingMinutes) * time.Minute
+		traderConfig.StopTradingTimeSource = trader.RiskLimitSourceTrader
+	}
+}
+
+// decisionCompactConfig 从系统配置读取决策记录压缩设置（log_compact_days，0=不压缩；log_compact_mode: gzip/strip）
+func decisionCompactConfig(database *config.Database) (time.Duration, string) {
+	daysStr, _ := database.GetSystemConfig("log_compact_days")
+	days, err := strconv.Atoi(strings.TrimSpace(daysStr))
+	if err != nil || days < 0 {
+		days = 0
+	}
+
+	mode, _ := database.GetSystemConfig("log_compact_mode")
+	mode = strings.TrimSpace(mode)
+	if mode != logger.CompactModeStrip {
+		mode = logger.CompactModeGzip
+	}
+	return time.Duration(days) * 24 * time.Hour, mode
+}
+
+// decisionRetentionConfig 从系统配置读取决策记录保留策略（log_retention_days/log_max_records，0=不限制；log_retention_mode: archive/delete）
+func decisionRetentionConfig(database *config.Database) logger.RetentionPolicy {
+	var policy logger.RetentionPolicy
+	daysStr, _ := database.GetSystemConfig("log_retention_days")
+	if days, err := strconv.Atoi(strings.TrimSpace(daysStr)); err == nil && days > 0 {
+		policy.MaxAgeDays = days
+	}
+	maxStr, _ := database.GetSystemConfig("log_max_records")
+	if maxRecords, err := strconv.Atoi(strings.TrimSpace(maxStr)); err == nil && maxRecords > 0 {
+		policy.MaxRecords = maxRecords
+	}
+	mode, _ := database.GetSystemConfig("log_retention_mode")
+	policy.Mode = strings.TrimSpace(mode)
+	if policy.Mode != logger.RetentionModeDelete {
+		policy.Mode = logger.RetentionModeArchive
+	}
+	return policy
+}
+
+// decisionLogEncryptionConfig 返回决策记录加密服务和是否加密新记录（系统配置 encrypt_decision_logs）
+// 未配置数据加密密钥时不加密，但仍返回加密服务以便读取之前加密的记录
+func decisionLogEncryptionConfig(database *config.Database) (*crypto.CryptoService, bool) {
+	cs := database.GetCryptoService()
+	if cs == nil {
+		return nil, false
+	}
+	value, _ := database.GetSystemConfig("encrypt_decision_logs")
+	encrypt := strings.TrimSpace(strings.ToLower(value)) == "true"
+	if encrypt && !cs.HasDataKey() {
+		log.Printf("⚠️ 已开启 encrypt_decision_logs 但未配置 DATA_ENCRYPTION_KEY，决策记录仍以明文保存")
+		encrypt = false
+	}
+	return cs, encrypt
+}
+
+// stopUpdateTolerance 从系统配置读取止损/止盈去重容差（sl_tp_dedup_pct，百分比；未配置时使用交易器默认值）
+func stopUpdateTolerance(database *config.Database) float64 {
+	valueStr, _ := database.GetSystemConfig("sl_tp_dedup_pct")
+	value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// strictPriceCheckNotional 从系统配置读取大额开仓严格价格校验阈值（strict_price_usd，USDT，0=不启用）
+func strictPriceCheckNotional(database *config.Database) float64 {
+	valueStr, _ := database.GetSystemConfig("strict_price_usd")
+	value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
+
+// outboundProxy 交易员使用的出站代理：用户配置优先，否则使用 OUTBOUND_PROXY 环境变量
+func outboundProxy(database *config.Database, userID string) string {
+	userProxy, err := database.GetUserOutboundProxy(userID)
+	if err != nil {
+		log.Printf("⚠️ 读取用户 %s 的出站代理配置失败: %v", userID, err)
+	}
+	return netproxy.Resolve(userProxy)
+}
+
+// safetyStopPct 从系统配置读取无止损持仓的兜底止损距离（safety_stop_pct，百分比，0=不启用）
+func safetyStopPct(database *config.Database) float64 {
+	valueStr, _ := database.GetSystemConfig("safety_stop_pct")
+	value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
+
+// scanJitterPct 从系统配置读取扫描间隔抖动百分比（scan_jitter_pct，例如 10 表示 ±10%，0=不抖动）
+func scanJitterPct(database *config.Database) float64 {
+	valueStr, _ := database.GetSystemConfig("scan_jitter_pct")
+	value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
+
+// rejectionLogEnabled 从系统配置读取是否记录被拒绝的决策（reject_log_enabled，默认开启）
+func rejectionLogEnabled(database *config.Database) bool {
+	enabled, _ := database.GetSystemConfig("reject_log_enabled")
+	return strings.TrimSpace(enabled) != "false"
+}
+
+// rejectionFeedbackEnabled 从系统配置读取是否把执行失败的决策反馈给AI（reject_feedback，默认开启）
+func rejectionFeedbackEnabled(database *config.Database) bool {
+	enabled, _ := database.GetSystemConfig("reject_feedback")
+	return strings.TrimSpace(enabled) != "false"
+}
+
+// symbolBlacklist 合并交易员黑名单和系统全局黑名单（global_symbol_blacklist，逗号分隔）
+func symbolBlacklist(database *config.Database, traderCfg *config.TraderRecord) []string {
+	global, _ := database.GetSystemConfig("global_symbol_blacklist")
+	return trader.ParseSymbolList(traderCfg.BlacklistedSymbols + "," + global)
+}
+
+// marketDataConfig 解析交易员的K线数量和指标配置，指标配置无效时使用默认行情格式（创建/更新时已校验）
+func marketDataConfig(traderCfg *config.TraderRecord) (int, []string) {
+	indicators, err := market.ParseIndicators(traderCfg.Indicators)
+	if err != nil {
+		traderLog(traderCfg).Warnf("⚠️ 交易员 %s 指标配置无效，使用默认指标: %v", traderCfg.Name, err)
+		indicators = nil
+	}
+	return traderCfg.KlineLimit, indicators
+}
+
+// tradingWindowConfig 解析交易员的交易时间窗口，配置无效时不限制（创建/更新时已校验）
+func tradingWindowConfig(traderCfg *config.TraderRecord) *trader.TradingWindow {
+	window, err := trader.ParseTradingWindow(traderCfg.ActiveHours, traderCfg.WeekendTrading)
+	if err != nil {
+		traderLog(traderCfg).Warnf("⚠️ 交易员 %s 的交易时间窗口无效，按全天交易: %v", traderCfg.Name, err)
+		return nil
+	}
+	if window != nil {
+		traderLog(traderCfg).Infof("🕒 交易员 %s 交易时间窗口: %s", traderCfg.Name, window)
+	}
+	return window
+}
+
+// modelPoolConfig 解析交易员的模型池配置，未配置、未启用或找不到的模型跳过
+func modelPoolConfig(database *config.Database, traderCfg *config.TraderRecord) ([]trader.ModelPoolEntry, string) {
+	ids := trader.ParseModelPool(traderCfg.ModelPool)
+	if len(ids) == 0 {
+		return nil, ""
+	}
+
+	aiModels, err := database.GetAIModels(traderCfg.UserID)
+	if err != nil {
+		traderLog(traderCfg).Warnf("⚠️ 交易员 %s 读取模型池配置失败，只使用绑定模型: %v", traderCfg.Name, err)
+		return nil, ""
+	}
+
+	entries := make([]trader.ModelPoolEntry, 0, len(ids))
+	for _, id := range ids {
+		var modelCfg *config.AIModelConfig
+		for _, model := range aiModels {
+			if model.ModelID == id {
+				modelCfg = model
+				break
+			}
+		}
+		if modelCfg == nil || !modelCfg.Enabled {
+			traderLog(traderCfg).Warnf("⚠️ 交易员 %s 的模型池成员 %s 不存在或未启用，跳过", traderCfg.Name, id)
+			continue
+		}
+		entries = append(entries, modelPoolEntry(modelCfg))
+	}
+	return entries, traderCfg.ModelPoolMode
+}
+
+// modelPoolEntry 把AI模型配置转换为交易员使用的模型配置
+func modelPoolEntry(modelCfg *config.AIModelConfig) trader.ModelPoolEntry {
+	return trader.ModelPoolEntry{
+		ModelID:            modelCfg.ModelID,
+		Provider:           modelCfg.Provider,
+		APIKey:             modelCfg.APIKey,
+		CustomAPIURL:       modelCfg.CustomAPIURL,
+		CustomModelName:    modelCfg.CustomModelName,
+		SystemPromptPrefix: modelCfg.SystemPromptPrefix,
+		SystemPromptSuffix: modelCfg.SystemPromptSuffix,
+	}
+}
+
+// traderLog 与交易员相关的管理日志（写入该交易员的运行日志缓冲区，可通过 API 查看）
+func traderLog(traderCfg *config.TraderRecord) *logrus.Entry {
+	return logger.ForTrader(traderCfg.ID, traderCfg.UserID, traderCfg.LogLevel).WithField("component", "manager")
+}
+
+// fallbackModelConfig 解析交易员的备用AI模型，未配置、未启用或找不到时返回 nil（不启用备用模型）
+func fallbackModelConfig(database *config.Database, traderCfg *config.TraderRecord) *trader.ModelPoolEntry {
+	id := strings.TrimSpace(traderCfg.FallbackAIModelID)
+	if id == "" {
+		return nil
+	}
+
+	aiModels, err := database.GetAIModels(traderCfg.UserID)
+	if err != nil {
+		traderLog(traderCfg).Warnf("⚠️ 交易员 %s 读取备用模型配置失败，不启用备用模型: %v", traderCfg.Name, err)
+		return nil
+	}
+	for _, model := range aiModels {
+		if model.ModelID != id {
+			continue
+		}
+		if !model.Enabled {
+			break
+		}
+		entry := modelPoolEntry(model)
+		return &entry
+	}
+	traderLog(traderCfg).Warnf("⚠️ 交易员 %s 的备用模型 %s 不存在或未启用，不启用备用模型", traderCfg.Name, id)
+	return nil
+}
+
+// GetSymbolPositionCounts 获取各币种当前持仓的交易员数
+func (tm *TraderManager) GetSymbolPositionCounts() map[string]int {
+	return tm.symbolRegistry.Snapshot()
+}
+
+// GetSymbolHolders 查询所有运行中的交易员在指定币种上的持仓（按交易员ID排序）
+// 各交易员并发查询交易所，查询失败或返回异常数据的交易员记入 failures，不影响其他交易员
+func (tm *TraderManager) GetSymbolHolders(symbol string) (holders, failures []map[string]interface{}) {
+	tm.mu.RLock()
+	running := make([]*trader.AutoTrader, 0, len(tm.traders))
+	for _, t := range tm.traders {
+		if isRunning, _ := t.GetStatus()["is_running"].(bool); isRunning {
+			running = append(running, t)
+		}
+	}
+	tm.mu.RUnlock()
+
+	holders = make([]map[string]interface{}, 0)
+	failures = make([]map[string]interface{}, 0)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, t := range running {
+		wg.Add(1)
+		go func(at *trader.AutoTrader) {
+			defer wg.Done()
+			positions, err := symbolPositions(at, symbol)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				at.Logger().Warnf("⚠️ [%s] 查询 %s 持仓失败: %v", at.GetName(), symbol, err)
+				failures = append(failures, map[string]interface{}{
+					"trader_id":   at.GetID(),
+					"trader_name": at.GetName(),
+					"error":       err.Error(),
+				})
+				return
+			}
+			for _, pos := range positions {
+				holders = append(holders, map[string]interface{}{
+					"trader_id":          at.GetID(),
+					"trader_name":        at.GetName(),
+					"exchange":           at.GetExchange(),
+					"side":               pos["side"],
+					"quantity":           pos["quantity"],
+					"entry_price":        pos["entry_price"],
+					"mark_price":         pos["mark_price"],
+					"leverage":           pos["leverage"],
+					"unrealized_pnl":     pos["unrealized_pnl"],
+					"unrealized_pnl_pct": pos["unrealized_pnl_pct"],
+				})
+			}
+		}(t)
+	}
+	wg.Wait()
+
+	sort.Slice(holders, func(i, j int) bool {
+		if holders[i]["trader_id"] != holders[j]["trader_id"] {
+			return holders[i]["trader_id"].(string) < holders[j]["trader_id"].(string)
+		}
+		return fmt.Sprint(holders[i]["side"]) < fmt.Sprint(holders[j]["side"])
+	})
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i]["trader_id"].(string) < failures[j]["trader_id"].(string)
+	})
+	return holders, failures
+}
+
+// symbolPositions 获取交易员在指定币种上的持仓，交易所返回字段缺失导致的 panic 转为错误
+func symbolPositions(at *trader.AutoTrader, symbol string) (result []map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("持仓数据异常: %v", r)
+		}
+	}()
+
+	positions, err := at.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+	for _, pos := range positions {
+		if pos["symbol"] == symbol {
+			result = append(result, pos)
+		}
+	}
+	return result, nil
+}
+
 // StartAll 启动所有trader
 func (tm *TraderManager) StartAll() {
 	tm.mu.RLock()
@@ -488,9 +978,9 @@ func (tm *TraderManager) StartAll() {
 	log.Println("🚀 启动所有Trader...")
 	for id, t := range tm.traders {
 		go func(traderID string, at *trader.AutoTrader) {
-			log.Printf("▶️  启动 %s...", at.GetName())
+			at.Logger().Infof("▶️  启动 %s...", at.GetName())
 			if err := at.Run(); err != nil {
-				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+				at.Logger().Errorf("❌ %s 运行错误: %v", at.GetName(), err)
 			}
 		}(id, t)
 	}
@@ -527,32 +1017,174 @@ func (tm *TraderManager) StartRunningTraders(database *config.Database) error {
 		return nil
 	}
 
-	log.Printf("🚀 自动启动 %d 个标记为运行状态的交易员...", len(runningTraders))
-	for _, traderCfg := range runningTraders {
+	// 🧯 防止崩溃重启后同时拉起大量交易员：按优先级排序，超出上限的保持停止
+	lastActivity, err := database.GetTraderLastActivity()
+	if err != nil {
+		log.Printf("⚠️ 获取交易员最后活动时间失败，仅按优先级排序: %v", err)
+	}
+	maxStart, interval := autoStartConfig(database)
+	toStart, deferred := selectAutoStartTraders(runningTraders, lastActivity, maxStart)
+
+	// 🩺 上次非正常退出：启动前先以交易所为准核对持仓和止损止盈（crash_recovery=false 可关闭）
+	recoverFromCrash := database.UncleanShutdownDetected() && crashRecoveryEnabled(database)
+	if recoverFromCrash {
+		log.Println("🩺 检测到上次运行非正常退出，启动交易员前执行崩溃恢复检查")
+	}
+
+	for _, traderCfg := range deferred {
+		log.Printf("⏸️  已达自动启动上限 (%d)，交易员 %s (ID: %s, 优先级: %d) 保持停止，请手动启动",
+			maxStart, traderCfg.Name, traderCfg.ID, traderCfg.StartPriority)
+		if err := database.UpdateTraderStatus(traderCfg.UserID, traderCfg.ID, false); err != nil {
+			traderLog(traderCfg).Warnf("⚠️ 更新交易员 %s 运行状态失败: %v", traderCfg.Name, err)
+		}
+	}
+
+	applyConcurrencyLimits(database)
+	applyAIRequestTimeout(database)
+	stagger := staggerStartEnabled(database)
+
+	log.Printf("🚀 自动启动 %d 个标记为运行状态的交易员...", len(toStart))
+	delay := time.Duration(0)
+	for i, traderCfg := range toStart {
 		if t, exists := tm.traders[traderCfg.ID]; exists {
-			go func(at *trader.AutoTrader, name string) {
-				log.Printf("▶️  启动 %s...", name)
+			if stagger {
+				t.SetFirstCycleDelay(staggerOffset(traderCfg.ScanIntervalMinutes, i, len(toStart)))
+			}
+			go func(at *trader.AutoTrader, name string, delay time.Duration) {
+				if delay > 0 {
+					time.Sleep(delay)
+				}
+				if recoverFromCrash {
+					if _, err := at.RecoverAfterCrash(); err != nil {
+						at.Logger().Warnf("⚠️ %s 崩溃恢复检查失败，按已保存状态启动: %v", name, err)
+					}
+				}
+				at.Logger().Infof("▶️  启动 %s...", name)
 				if err := at.Run(); err != nil {
-					log.Printf("❌ %s 运行错误: %v", name, err)
+					at.Logger().Errorf("❌ %s 运行错误: %v", name, err)
 				}
-			}(t, traderCfg.Name)
+			}(t, traderCfg.Name, delay)
+			delay += interval
 		} else {
-			log.Printf("⚠️  交易员 %s (ID: %s) 未加载到内存，跳过", traderCfg.Name, traderCfg.ID)
+			traderLog(traderCfg).Warnf("⚠️  交易员 %s (ID: %s) 未加载到内存，跳过", traderCfg.Name, traderCfg.ID)
 		}
 	}
 
 	return nil
 }
 
+// autoStartConfig 从系统配置读取开机自动启动上限（autostart_max，0=不限制）和启动间隔（autostart_interval，秒）
+func autoStartConfig(database *config.Database) (int, time.Duration) {
+	maxStr, _ := database.GetSystemConfig("autostart_max")
+	maxStart, err := strconv.Atoi(strings.TrimSpace(maxStr))
+	if err != nil || maxStart < 0 {
+		maxStart = 0
+	}
+
+	intervalStr, _ := database.GetSystemConfig("autostart_interval")
+	seconds, err := strconv.Atoi(strings.TrimSpace(intervalStr))
+	if err != nil || seconds < 0 {
+		seconds = 0
+	}
+	return maxStart, time.Duration(seconds) * time.Second
+}
+
+// staggerStartEnabled 是否错开自动启动交易员的首次决策周期（系统配置 stagger_start，默认开启）
+func staggerStartEnabled(database *config.Database) bool {
+	value, _ := database.GetSystemConfig("stagger_start")
+	return strings.TrimSpace(strings.ToLower(value)) != "false"
+}
+
+// staggerOffset 第 i 个（共 n 个）交易员的首次周期延迟：把首次扫描均匀分布在一个扫描间隔内
+func staggerOffset(scanIntervalMinutes, i, n int) time.Duration {
+	if scanIntervalMinutes <= 0 || n <= 1 {
+		return 0
+	}
+	return time.Duration(scanIntervalMinutes) * time.Minute * time.Duration(i) / time.Duration(n)
+}
+
+// applyConcurrencyLimits 从系统配置读取AI请求和交易所请求的全局并发上限
+// （max_concurrent_ai_calls / max_concurrent_exchange_calls，0=不限制）
+func applyConcurrencyLimits(database *config.Database) {
+	readLimit := func(key string) int {
+		valueStr, _ := database.GetSystemConfig(key)
+		value, err := strconv.Atoi(strings.TrimSpace(valueStr))
+		if err != nil || value < 0 {
+			return 0
+		}
+		return value
+	}
+	aiLimit := readLimit("max_concurrent_ai_calls")
+	exchangeLimit := readLimit("max_concurrent_exchange_calls")
+	mcp.SetMaxConcurrentCalls(aiLimit)
+	trader.SetMaxConcurrentExchangeCalls(exchangeLimit)
+	if aiLimit > 0 || exchangeLimit > 0 {
+		log.Printf("🚦 全局并发上限: AI请求 %d, 交易所请求 %d (0=不限制)", aiLimit, exchangeLimit)
+	}
+}
+
+// applyAIRequestTimeout 从系统配置读取单次AI请求超时（ai_request_timeout_seconds，0=客户端默认超时）
+func applyAIRequestTimeout(database *config.Database) {
+	valueStr, _ := database.GetSystemConfig("ai_request_timeout_seconds")
+	seconds, err := strconv.Atoi(strings.TrimSpace(valueStr))
+	if err != nil || seconds <= 0 {
+		mcp.SetRequestTimeout(0)
+		return
+	}
+	mcp.SetRequestTimeout(time.Duration(seconds) * time.Second)
+	log.Printf("⏱️ 单次AI请求超时: %d 秒", seconds)
+}
+
+// applyMaintenanceMode 从系统配置恢复维护模式（maintenance_mode / maintenance_message / maintenance_until）
+func applyMaintenanceMode(database *config.Database) {
+	enabled, _ := database.GetSystemConfig("maintenance_mode")
+	message, _ := database.GetSystemConfig("maintenance_message")
+	untilStr, _ := database.GetSystemConfig("maintenance_until")
+
+	mode := trader.MaintenanceMode{Enabled: strings.TrimSpace(enabled) == "true", Message: message}
+	if until, err := time.Parse(time.RFC3339, strings.TrimSpace(untilStr)); err == nil {
+		mode.Until = until
+	}
+	trader.SetMaintenanceMode(mode)
+	if mode.Active(time.Now()) {
+		log.Printf("🛠 系统处于维护模式，所有交易员暂停决策: %s", mode.Message)
+	}
+}
+
+// crashRecoveryEnabled 是否在非正常退出后执行崩溃恢复检查（系统配置 crash_recovery，默认开启）
+func crashRecoveryEnabled(database *config.Database) bool {
+	value, _ := database.GetSystemConfig("crash_recovery")
+	return strings.TrimSpace(strings.ToLower(value)) != "false"
+}
+
+// selectAutoStartTraders 按启动优先级（高→低）、最后活动时间（近→远）排序，返回需要启动和延后的交易员
+// maxStart 为 0 时全部启动
+func selectAutoStartTraders(traders []*config.TraderRecord, lastActivity map[string]string, maxStart int) (toStart, deferred []*config.TraderRecord) {
+	sorted := make([]*config.TraderRecord, len(traders))
+	copy(sorted, traders)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].StartPriority != sorted[j].StartPriority {
+			return sorted[i].StartPriority > sorted[j].StartPriority
+		}
+		return lastActivity[sorted[i].ID] > lastActivity[sorted[j].ID]
+	})
+
+	if maxStart <= 0 || len(sorted) <= maxStart {
+		return sorted, nil
+	}
+	return sorted[:maxStart], sorted[maxStart:]
+}
+
 // StopAll 停止所有trader
+// 关闭服务时使用，不更新数据库中的运行状态，重启后仍会自动启动
 func (tm *TraderManager) StopAll() {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	tm.StopAllWithOptions(StopOptions{})
+}
 
+// StopAllWithOptions 停止所有trader，opts.ClosePositions 为 true 时停止后平掉全部持仓（flatten_on_shutdown 配置）
+func (tm *TraderManager) StopAllWithOptions(opts StopOptions) {
 	log.Println("⏹  停止所有Trader...")
-	for _, t := range tm.traders {
-		t.Stop()
-	}
+	tm.StopTradersWithOptions(tm.GetTraderIDs(), nil, opts)
 }
 
 // GetComparisonData 获取对比数据
@@ -592,6 +1224,16 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 	return comparison, nil
 }
 
+// CompetitionWindow 竞赛排行榜统计窗口（按创建以来累计收益率排序，取前50名）
+const CompetitionWindow = "all_time_top50"
+
+// CompetitionDataAsOf 获取当前竞赛数据（缓存）的生成时间
+func (tm *TraderManager) CompetitionDataAsOf() time.Time {
+	tm.competitionCache.mu.RLock()
+	defer tm.competitionCache.mu.RUnlock()
+	return tm.competitionCache.timestamp
+}
+
 // GetCompetitionData 获取竞赛数据（全平台所有交易员）
 func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
 	// 检查缓存是否有效（30秒内）
@@ -608,6 +1250,13 @@ func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
 	}
 	tm.competitionCache.mu.RUnlock()
 
+	comparison, _ := tm.refreshCompetitionCache()
+	return comparison, nil
+}
+
+// refreshCompetitionCache 重新获取全部交易员数据并计算排行榜衍生指标，更新竞赛数据缓存
+// 返回竞赛数据（前50名）和全部交易员条目（按总收益率降序）
+func (tm *TraderManager) refreshCompetitionCache() (map[string]interface{}, []*leaderboardEntry) {
 	tm.mu.RLock()
 
 	// 获取所有交易员列表
@@ -619,21 +1268,17 @@ func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
 
 	log.Printf("🔄 重新获取竞赛数据，交易员数量: %d", len(allTraders))
 
-	// 并发获取交易员数据
+	// 并发获取交易员数据，并计算周期收益率/胜率/运行天数
 	traders := tm.getConcurrentTraderData(allTraders)
+	entries := buildLeaderboardEntries(allTraders, traders, time.Now())
 
 	// 按收益率排序（降序）
-	sort.Slice(traders, func(i, j int) bool {
-		pnlPctI, okI := traders[i]["total_pnl_pct"].(float64)
-		pnlPctJ, okJ := traders[j]["total_pnl_pct"].(float64)
-		if !okI {
-			pnlPctI = 0
-		}
-		if !okJ {
-			pnlPctJ = 0
-		}
-		return pnlPctI > pnlPctJ
+	sort.SliceStable(entries, func(i, j int) bool {
+		return toFloat(entries[i].data["total_pnl_pct"]) > toFloat(entries[j].data["total_pnl_pct"])
 	})
+	for i, entry := range entries {
+		traders[i] = entry.data
+	}
 
 	// 限制返回前50名
 	totalCount := len(traders)
@@ -650,10 +1295,11 @@ func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
 	// 更新缓存
 	tm.competitionCache.mu.Lock()
 	tm.competitionCache.data = comparison
+	tm.competitionCache.entries = entries
 	tm.competitionCache.timestamp = time.Now()
 	tm.competitionCache.mu.Unlock()
 
-	return comparison, nil
+	return comparison, entries
 }
 
 // getConcurrentTraderData 并发获取多个交易员的数据
@@ -837,6 +1483,7 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
 	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
 	defaultCoinsStr, _ := database.GetSystemConfig("default_coins")
+	tm.refreshSymbolPositionLimit(database)
 
 	// 获取用户信号源配置
 	var coinPoolURL, oiTopURL string
@@ -890,7 +1537,7 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 	for _, traderCfg := range traders {
 		// 如果已经存在，跳过
 		if tm.isTraderLoaded(traderCfg.ID) {
-			log.Printf("⚠️ 交易员 %s 已经加载，跳过", traderCfg.Name)
+			traderLog(traderCfg).Warnf("⚠️ 交易员 %s 已经加载，跳过", traderCfg.Name)
 			continue
 		}
 
@@ -903,12 +1550,12 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 			}
 		}
 		if aiModelCfg == nil {
-			log.Printf("⚠️ 交易员 %s 的AI模型 %d 不存在，跳过", traderCfg.Name, traderCfg.AIModelID)
+			traderLog(traderCfg).Warnf("⚠️ 交易员 %s 的AI模型 %d 不存在，跳过", traderCfg.Name, traderCfg.AIModelID)
 			continue
 		}
 
 		if !aiModelCfg.Enabled {
-			log.Printf("⚠️ 交易员 %s 的AI模型 %d 未启用，跳过", traderCfg.Name, traderCfg.AIModelID)
+			traderLog(traderCfg).Warnf("⚠️ 交易员 %s 的AI模型 %d 未启用，跳过", traderCfg.Name, traderCfg.AIModelID)
 			continue
 		}
 
@@ -922,18 +1569,18 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 		}
 
 		if exchangeCfg == nil {
-			log.Printf("⚠️ 交易员 %s 的交易所 %d 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			traderLog(traderCfg).Warnf("⚠️ 交易员 %s 的交易所 %d 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
 			continue
 		}
 
 		if !exchangeCfg.Enabled {
-			log.Printf("⚠️ 交易员 %s 的交易所 %d 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			traderLog(traderCfg).Warnf("⚠️ 交易员 %s 的交易所 %d 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
 			continue
 		}
 
 		at, err := tm.loadSingleTrader(traderCfg, aiModelCfg, exchangeCfg, coinPoolURL, oiTopURL, maxDailyLoss, maxDrawdown, stopTradingMinutes, defaultCoins, database, userID)
 		if err != nil {
-			log.Printf("⚠️ 加载交易员 %s 失败: %v", traderCfg.Name, err)
+			traderLog(traderCfg).Warnf("⚠️ 加载交易员 %s 失败: %v", traderCfg.Name, err)
 			continue
 		}
 
@@ -943,8 +1590,10 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 			continue
 		}
 		tm.traders[traderCfg.ID] = at
+		tm.joinPortfolioGroup(userID, traderCfg.PortfolioGroup, at)
+		at.SetSymbolRegistry(tm.symbolRegistry)
 		tm.mu.Unlock()
-		log.Printf("✓ Trader '%s' (%s + %s) 已为用户加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ExchangeID)
+		traderLog(traderCfg).Infof("✓ Trader '%s' (%s + %s) 已为用户加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ExchangeID)
 	}
 
 	return nil
@@ -1032,6 +1681,7 @@ func (tm *TraderManager) LoadTraderByID(database *config.Database, userID, trade
 	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
 	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
 	defaultCoinsStr, _ := database.GetSystemConfig("default_coins")
+	tm.refreshSymbolPositionLimit(database)
 
 	// 6. 查询用户信号源配置
 	var coinPoolURL, oiTopURL string
@@ -1069,7 +1719,7 @@ func (tm *TraderManager) LoadTraderByID(database *config.Database, userID, trade
 	}
 
 	// 8. 调用私有方法加载交易员
-	log.Printf("📋 加载单个交易员: %s (%s)", traderCfg.Name, traderID)
+	traderLog(traderCfg).Infof("📋 加载单个交易员: %s (%s)", traderCfg.Name, traderID)
 	at, err := tm.loadSingleTrader(
 		traderCfg,
 		aiModelCfg,
@@ -1091,7 +1741,9 @@ func (tm *TraderManager) LoadTraderByID(database *config.Database, userID, trade
 	tm.mu.Lock()
 	if _, exists := tm.traders[traderID]; !exists {
 		tm.traders[traderID] = at
-		log.Printf("✓ Trader '%s' (%s + %s) 已为用户加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ExchangeID)
+		tm.joinPortfolioGroup(userID, traderCfg.PortfolioGroup, at)
+		at.SetSymbolRegistry(tm.symbolRegistry)
+		traderLog(traderCfg).Infof("✓ Trader '%s' (%s + %s) 已为用户加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ExchangeID)
 	}
 	tm.mu.Unlock()
 	return nil
@@ -1121,13 +1773,13 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 	var effectiveCoinPoolURL string
 	if traderCfg.UseCoinPool && coinPoolURL != "" {
 		effectiveCoinPoolURL = coinPoolURL
-		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
+		traderLog(traderCfg).Infof("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
 
 	var effectiveOITopURL string
 	if traderCfg.UseOITop && oiTopURL != "" {
 		effectiveOITopURL = oiTopURL
-		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+		traderLog(traderCfg).Infof("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
 	}
 
 	// 处理时间线配置
@@ -1141,44 +1793,84 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 				timeframes = append(timeframes, tf)
 			}
 		}
-		log.Printf("✓ 交易员 %s 配置时间线: %v", traderCfg.Name, timeframes)
+		traderLog(traderCfg).Infof("✓ 交易员 %s 配置时间线: %v", traderCfg.Name, timeframes)
 	}
 	// 如果为空，将使用 NewAutoTrader 中的默认值 ["15m", "1h", "4h"]
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
-		ID:                   traderCfg.ID,
-		Name:                 traderCfg.Name,
-		AIModel:              aiModelCfg.Provider,    // 使用provider作为模型标识
-		Exchange:             exchangeCfg.ExchangeID, // 使用exchange ID
-		InitialBalance:       traderCfg.InitialBalance,
-		BTCETHLeverage:       traderCfg.BTCETHLeverage,
-		AltcoinLeverage:      traderCfg.AltcoinLeverage,
-		TakerFeeRate:         traderCfg.TakerFeeRate, // Taker fee rate from config
-		MakerFeeRate:         traderCfg.MakerFeeRate, // Maker fee rate from config
-		ScanInterval:         time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		CoinPoolAPIURL:       effectiveCoinPoolURL,
-		OITopAPIURL:          effectiveOITopURL,
-		CustomAPIURL:         aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:      aiModelCfg.CustomModelName, // 自定义模型名称
-		UseQwen:              aiModelCfg.Provider == "qwen",
-		MaxDailyLoss:         maxDailyLoss,
-		MaxDrawdown:          maxDrawdown,
-		StopTradingTime:      time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:        traderCfg.IsCrossMargin,
-		DefaultCoins:         defaultCoins,
-		TradingCoins:         tradingCoins,
-		SystemPromptTemplate: traderCfg.SystemPromptTemplate, // 系统提示词模板
-		OrderStrategy:        traderCfg.OrderStrategy,        // 订单策略
-		LimitPriceOffset:     traderCfg.LimitPriceOffset,     // 限价偏移
-		LimitTimeoutSeconds:  traderCfg.LimitTimeoutSeconds,  // 限价超时
-		HyperliquidTestnet:   exchangeCfg.Testnet,            // Hyperliquid测试网
-		Timeframes:           timeframes,                     // K线时间线配置
-	}
+		ID:                     traderCfg.ID,
+		Name:                   traderCfg.Name,
+		AIModel:                aiModelCfg.Provider,    // 使用provider作为模型标识
+		Exchange:               exchangeCfg.ExchangeID, // 使用exchange ID
+		InitialBalance:         traderCfg.InitialBalance,
+		BTCETHLeverage:         traderCfg.BTCETHLeverage,
+		AltcoinLeverage:        traderCfg.AltcoinLeverage,
+		TakerFeeRate:           traderCfg.TakerFeeRate, // Taker fee rate from config
+		MakerFeeRate:           traderCfg.MakerFeeRate, // Maker fee rate from config
+		ScanInterval:           time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		CoinPoolAPIURL:         effectiveCoinPoolURL,
+		OITopAPIURL:            effectiveOITopURL,
+		CustomAPIURL:           aiModelCfg.CustomAPIURL,       // 自定义API URL
+		CustomModelName:        aiModelCfg.CustomModelName,    // 自定义模型名称
+		SystemPromptPrefix:     aiModelCfg.SystemPromptPrefix, // 模型专属 System Prompt 前缀
+		SystemPromptSuffix:     aiModelCfg.SystemPromptSuffix, // 模型专属 System Prompt 后缀
+		UseQwen:                aiModelCfg.Provider == "qwen",
+		MaxDailyLoss:           maxDailyLoss,
+		MaxDrawdown:            maxDrawdown,
+		StopTradingTime:        time.Duration(stopTradingMinutes) * time.Minute,
+		IsCrossMargin:          traderCfg.IsCrossMargin,
+		DefaultCoins:           defaultCoins,
+		TradingCoins:           tradingCoins,
+		SystemPromptTemplate:   traderCfg.SystemPromptTemplate,                               // 系统提示词模板
+		OrderStrategy:          traderCfg.OrderStrategy,                                      // 订单策略
+		LimitPriceOffset:       traderCfg.LimitPriceOffset,                                   // 限价偏移
+		LimitTimeoutSeconds:    traderCfg.LimitTimeoutSeconds,                                // 限价超时
+		MaxTradesPerDay:        traderCfg.MaxTradesPerDay,                                    // 每日开仓上限
+		HoldCachePct:           traderCfg.HoldCachePct,                                       // 持有决策缓存阈值
+		MaxExposureMultiple:    traderCfg.MaxExposureMultiple,                                // 总敞口倍数上限
+		RespectSignalBias:      traderCfg.RespectSignalBias,                                  // 遵循信号源方向
+		DryRun:                 traderCfg.DryRun,                                             // 模拟运行
+		AlertDrawdownPct:       traderCfg.AlertDrawdownPct,                                   // 净值回撤预警
+		AlertDailyLossPct:      traderCfg.AlertDailyLossPct,                                  // 当日亏损预警
+		AIQualityWindow:        traderCfg.AIQualityWindow,                                    // AI质量检测窗口
+		AIQualityMaxFailurePct: traderCfg.AIQualityMaxFailurePct,                             // AI失败率阈值
+		AIQualityPause:         time.Duration(traderCfg.AIQualityPauseMinutes) * time.Minute, // AI质量暂停冷却
+		MaxConsecutiveErrors:   traderCfg.MaxConsecutiveErrors,                               // 连续失败自动暂停阈值
+		DailyReport:            traderCfg.DailyReport,                                        // 每日报告
+		UnfundedThreshold:      traderCfg.UnfundedThreshold,                                  // 未入金判定阈值
+		MaxAICallsPerDay:       traderCfg.MaxAICallsPerDay,                                   // 每日AI调用上限
+		RejectNonCandidates:    traderCfg.RejectNonCandidates,                                // 仅交易候选币种
+		HyperliquidTestnet:     exchangeCfg.Testnet,                                          // Hyperliquid测试网
+		Timeframes:             timeframes,                                                   // K线时间线配置
+		OpenVerifyDelay:        time.Duration(traderCfg.OpenVerifyDelayMs) * time.Millisecond,
+		LogLevel:               traderCfg.LogLevel,
+	}
+
+	traderConfig.DecisionCompactAfter, traderConfig.DecisionCompactMode = decisionCompactConfig(database)
+	applyRiskLimitOverrides(&traderConfig, traderCfg)
+	traderConfig.DecisionRetention = decisionRetentionConfig(database)
+	traderConfig.DecisionLogCrypto, traderConfig.EncryptDecisionLogs = decisionLogEncryptionConfig(database)
+	traderConfig.ModelPool, traderConfig.ModelPoolMode = modelPoolConfig(database, traderCfg)
+	traderConfig.FallbackModel = fallbackModelConfig(database, traderCfg)
+	traderConfig.StopUpdateTolerancePct = stopUpdateTolerance(database)
+	traderConfig.StrictPriceCheckNotional = strictPriceCheckNotional(database)
+	traderConfig.RecordRejections = rejectionLogEnabled(database)
+	traderConfig.RejectionFeedback = rejectionFeedbackEnabled(database)
+	traderConfig.SafetyStopPct = safetyStopPct(database)
+	traderConfig.OutboundProxy = outboundProxy(database, traderCfg.UserID)
+	traderConfig.TradingWindow = tradingWindowConfig(traderCfg)
+	traderConfig.FlattenOnWindowClose = traderCfg.FlattenOnWindowClose
+	traderConfig.MaxPositions = traderCfg.MaxPositions
+	traderConfig.MaxPositionSizeUSD = traderCfg.MaxPositionSizeUSD
+	traderConfig.BlacklistedSymbols = symbolBlacklist(database, traderCfg)
+	traderConfig.KlineLimit, traderConfig.Indicators = marketDataConfig(traderCfg)
+	traderConfig.ScanJitterPct = scanJitterPct(database)
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ExchangeID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceTestnet = exchangeCfg.Testnet
 	} else if exchangeCfg.ExchangeID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -1186,6 +1878,11 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		traderConfig.AsterUser = exchangeCfg.AsterUser
 		traderConfig.AsterSigner = exchangeCfg.AsterSigner
 		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
+	} else if exchangeCfg.ExchangeID == "okx" {
+		traderConfig.OKXAPIKey = exchangeCfg.APIKey
+		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
+		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
+		traderConfig.OKXTestnet = exchangeCfg.Testnet
 	}
 
 	// 根据AI模型设置API密钥
@@ -1,12 +1,20 @@
 package manager
 
 import (
+	"nofx/config"
 	"nofx/trader"
 	"sync"
 	"testing"
 	"time"
 )
 
+// useTempWorkDir switches the working directory to a per-test temp dir so that
+// decision logs written by running traders (relative paths) stay out of the source tree
+func useTempWorkDir(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+}
+
 // TestNewTraderManager tests the creation of a new TraderManager
 func TestNewTraderManager(t *testing.T) {
 	tm := NewTraderManager()
@@ -521,6 +529,7 @@ func TestRemoveTraderClearCache(t *testing.T) {
 	// Set cache data
 	tm.competitionCache.mu.Lock()
 	tm.competitionCache.data = map[string]interface{}{"count": 1}
+	tm.competitionCache.entries = []*leaderboardEntry{leaderboardTestEntry("cache-clear-test", 1000, 1, 1, 1, 1)}
 	tm.competitionCache.timestamp = time.Now()
 	tm.competitionCache.mu.Unlock()
 
@@ -532,7 +541,7 @@ func TestRemoveTraderClearCache(t *testing.T) {
 
 	// Verify cache is cleared
 	tm.competitionCache.mu.RLock()
-	cacheEmpty := tm.competitionCache.data == nil
+	cacheEmpty := tm.competitionCache.data == nil && tm.competitionCache.entries == nil
 	timestampZero := tm.competitionCache.timestamp.IsZero()
 	tm.competitionCache.mu.RUnlock()
 
@@ -648,6 +657,7 @@ func TestGetTrader_AfterRemove(t *testing.T) {
 
 // TestStartAll tests starting all traders
 func TestStartAll(t *testing.T) {
+	useTempWorkDir(t)
 	tm := NewTraderManager()
 
 	// Add multiple traders
@@ -688,6 +698,7 @@ func TestStartAll(t *testing.T) {
 
 // TestStopAll tests stopping all traders
 func TestStopAll(t *testing.T) {
+	useTempWorkDir(t)
 	tm := NewTraderManager()
 
 	// Add multiple traders
@@ -774,3 +785,20 @@ func TestGetTopTradersData(t *testing.T) {
 
 	t.Logf("✅ GetTopTradersData returned valid data structure")
 }
+
+// TestApplyRiskLimitOverrides tests that per-trader risk limits replace the system values and record their source
+func TestApplyRiskLimitOverrides(t *testing.T) {
+	dailyLoss, drawdown := 5.0, 10.0
+	traderConfig := trader.AutoTraderConfig{MaxDailyLoss: 15, MaxDrawdown: 30, StopTradingTime: 60 * time.Minute}
+	applyRiskLimitOverrides(&traderConfig, &config.TraderRecord{MaxDailyLoss: &dailyLoss, MaxDrawdown: &drawdown})
+
+	if traderConfig.MaxDailyLoss != 5 || traderConfig.MaxDailyLossSource != trader.RiskLimitSourceTrader {
+		t.Errorf("Expected trader-level daily loss 5, got %v (%s)", traderConfig.MaxDailyLoss, traderConfig.MaxDailyLossSource)
+	}
+	if traderConfig.MaxDrawdown != 10 || traderConfig.MaxDrawdownSource != trader.RiskLimitSourceTrader {
+		t.Errorf("Expected trader-level drawdown 10, got %v (%s)", traderConfig.MaxDrawdown, traderConfig.MaxDrawdownSource)
+	}
+	if traderConfig.StopTradingTime != 60*time.Minute || traderConfig.StopTradingTimeSource != trader.RiskLimitSourceSystem {
+		t.Errorf("Unset pause should fall back to the system value, got %v (%s)", traderConfig.StopTradingTime, traderConfig.StopTradingTimeSource)
+	}
+}
@@ -13,12 +13,14 @@ import (
 
 const (
 	defaultBaseURL = "https://fapi.binance.com"
+	testnetBaseURL = "https://testnet.binancefuture.com"
 )
 
 var baseURL = defaultBaseURL
 
 type APIClient struct {
-	client *http.Client
+	client  *http.Client
+	baseURL string // 为空时使用包级 baseURL
 }
 
 func NewAPIClient() *APIClient {
@@ -37,8 +39,23 @@ func NewAPIClient() *APIClient {
 	}
 }
 
+// NewTestnetAPIClient 创建连接币安合约测试网的行情客户端
+func NewTestnetAPIClient() *APIClient {
+	c := NewAPIClient()
+	c.baseURL = testnetBaseURL
+	return c
+}
+
+// endpoint 当前客户端使用的 API 地址
+func (c *APIClient) endpoint() string {
+	if c.baseURL != "" {
+		return c.baseURL
+	}
+	return baseURL
+}
+
 func (c *APIClient) GetExchangeInfo() (*ExchangeInfo, error) {
-	url := fmt.Sprintf("%s/fapi/v1/exchangeInfo", baseURL)
+	url := fmt.Sprintf("%s/fapi/v1/exchangeInfo", c.endpoint())
 	resp, err := c.client.Get(url)
 	if err != nil {
 		return nil, err
@@ -92,7 +109,7 @@ func (c *APIClient) GetKlines(symbol, interval string, limit int) ([]Kline, erro
 }
 
 func (c *APIClient) getKlinesAttempt(symbol, interval string, limit int, attempt int) ([]Kline, error) {
-	url := fmt.Sprintf("%s/fapi/v1/klines", baseURL)
+	url := fmt.Sprintf("%s/fapi/v1/klines", c.endpoint())
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request failed: %w", err)
@@ -195,7 +212,7 @@ func parseKline(kr KlineResponse) (Kline, error) {
 }
 
 func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
-	url := fmt.Sprintf("%s/fapi/v1/ticker/price", baseURL)
+	url := fmt.Sprintf("%s/fapi/v1/ticker/price", c.endpoint())
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return 0, err
@@ -232,7 +249,7 @@ func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
 
 // GetOpenInterest 获取持仓量（P0修复：用于OI历史数据采集）
 func (c *APIClient) GetOpenInterest(symbol string) (*OIData, error) {
-	url := fmt.Sprintf("%s/fapi/v1/openInterest?symbol=%s", baseURL, symbol)
+	url := fmt.Sprintf("%s/fapi/v1/openInterest?symbol=%s", c.endpoint(), symbol)
 
 	resp, err := c.client.Get(url)
 	if err != nil {
@@ -290,7 +307,7 @@ func (c *APIClient) GetOpenInterestHistory(symbol string, period string, limit i
 }
 
 func (c *APIClient) getOpenInterestHistoryAttempt(symbol string, period string, limit int, attempt int) ([]OISnapshot, error) {
-	url := fmt.Sprintf("%s/futures/data/openInterestHist", baseURL)
+	url := fmt.Sprintf("%s/futures/data/openInterestHist", c.endpoint())
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request failed: %w", err)
@@ -355,3 +372,91 @@ func (c *APIClient) getOpenInterestHistoryAttempt(symbol string, period string,
 
 	return snapshots, nil
 }
+
+// GetFunding 获取资金费率（premiumIndex）和最近 historyLimit 次已结算的资金费率
+func (c *APIClient) GetFunding(symbol string, historyLimit int) (*FundingData, error) {
+	resp, err := c.client.Get(fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", c.endpoint(), symbol))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var binanceErr BinanceErrorResponse
+	if json.Unmarshal(body, &binanceErr) == nil && binanceErr.Code != 0 {
+		return nil, &binanceErr
+	}
+
+	var index struct {
+		LastFundingRate string `json:"lastFundingRate"`
+		NextFundingTime int64  `json:"nextFundingTime"`
+	}
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("parse premiumIndex JSON failed: %w", err)
+	}
+	rate, err := strconv.ParseFloat(index.LastFundingRate, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse funding rate failed: %w", err)
+	}
+
+	funding := &FundingData{
+		Source:          "Binance",
+		Rate:            rate,
+		NextFundingTime: index.NextFundingTime,
+		IntervalHours:   8,
+	}
+	if historyLimit <= 0 {
+		return funding, nil
+	}
+
+	// 历史资金费率获取失败不影响当前费率
+	history, err := c.getFundingHistory(symbol, historyLimit)
+	if err != nil {
+		log.Printf("⚠️  获取 %s 历史资金费率失败: %v", symbol, err)
+		return funding, nil
+	}
+	funding.History = history
+	// 部分币种结算周期不是 8 小时（如 4h），按最近两次结算时间推算
+	if n := len(history); n >= 2 {
+		if hours := int((history[n-1].Time - history[n-2].Time + 30*60*1000) / (60 * 60 * 1000)); hours > 0 {
+			funding.IntervalHours = hours
+		}
+	}
+	return funding, nil
+}
+
+// getFundingHistory 获取最近 limit 次已结算的资金费率（旧 → 新）
+func (c *APIClient) getFundingHistory(symbol string, limit int) ([]FundingSnapshot, error) {
+	resp, err := c.client.Get(fmt.Sprintf("%s/fapi/v1/fundingRate?symbol=%s&limit=%d", c.endpoint(), symbol, limit))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		FundingRate string `json:"fundingRate"`
+		FundingTime int64  `json:"fundingTime"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("parse funding history JSON failed: %w", err)
+	}
+
+	history := make([]FundingSnapshot, 0, len(items))
+	for _, item := range items {
+		rate, err := strconv.ParseFloat(item.FundingRate, 64)
+		if err != nil {
+			continue
+		}
+		history = append(history, FundingSnapshot{Rate: rate, Time: item.FundingTime})
+	}
+	return history, nil
+}
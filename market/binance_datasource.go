@@ -20,6 +20,14 @@ func NewBinanceDataSource() *BinanceDataSource {
 	}
 }
 
+// NewBinanceTestnetDataSource 创建 Binance 合约测试网数据源实例（测试网交易员使用）
+func NewBinanceTestnetDataSource() *BinanceDataSource {
+	return &BinanceDataSource{
+		client: NewTestnetAPIClient(),
+		name:   "Binance Testnet",
+	}
+}
+
 // GetName 获取数据源名称
 func (b *BinanceDataSource) GetName() string {
 	return b.name
@@ -55,6 +63,16 @@ func (b *BinanceDataSource) GetTicker(symbol string) (*Ticker, error) {
 	return ticker, nil
 }
 
+// GetFunding 获取资金费率（premiumIndex + 最近几次结算历史）
+func (b *BinanceDataSource) GetFunding(symbol string) (*FundingData, error) {
+	funding, err := b.client.GetFunding(symbol, fundingHistoryLimit)
+	if err != nil {
+		log.Printf("⚠️  Binance GetFunding 失败 [%s]: %v", symbol, err)
+		return nil, fmt.Errorf("binance GetFunding failed: %w", err)
+	}
+	return funding, nil
+}
+
 // HealthCheck 健康检查
 func (b *BinanceDataSource) HealthCheck() error {
 	_, err := b.client.GetExchangeInfo()
@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -19,6 +20,9 @@ type CombinedStreamsClient struct {
 	done        chan struct{}
 	batchSize   int // 每批订阅的流数量
 
+	connectedAt   atomic.Int64 // 最近一次连接成功的时间（UnixNano）
+	lastMessageAt atomic.Int64 // 最近一次收到消息的时间（UnixNano，0=从未收到）
+
 	// 测试用 hook（生产环境为 nil）
 	// 重连时调用，传入需要重新订阅的流列表
 	onReconnectSubscribeFunc func(streams []string)
@@ -47,6 +51,7 @@ func (c *CombinedStreamsClient) Connect() error {
 	c.mu.Lock()
 	c.conn = conn
 	c.mu.Unlock()
+	c.connectedAt.Store(time.Now().UnixNano())
 
 	log.Println("组合流WebSocket连接成功")
 	go c.readMessages()
@@ -142,6 +147,8 @@ func (c *CombinedStreamsClient) readMessages() {
 }
 
 func (c *CombinedStreamsClient) handleCombinedMessage(message []byte) {
+	c.lastMessageAt.Store(time.Now().UnixNano())
+
 	var combinedMsg struct {
 		Stream string          `json:"stream"`
 		Data   json.RawMessage `json:"data"`
@@ -165,6 +172,27 @@ func (c *CombinedStreamsClient) handleCombinedMessage(message []byte) {
 	}
 }
 
+// Healthy 组合流是否健康：已连接，且有订阅时 maxSilence 内收到过消息
+// （连接断开后 conn 不会清空，读取失败到重连成功之间依靠消息静默时长判断）
+func (c *CombinedStreamsClient) Healthy(maxSilence time.Duration) bool {
+	c.mu.RLock()
+	connected := c.conn != nil
+	subscribed := len(c.subscribers) > 0
+	c.mu.RUnlock()
+
+	if !connected {
+		return false
+	}
+	if !subscribed {
+		return true
+	}
+	lastActivity := c.lastMessageAt.Load()
+	if connectedAt := c.connectedAt.Load(); connectedAt > lastActivity {
+		lastActivity = connectedAt
+	}
+	return time.Since(time.Unix(0, lastActivity)) <= maxSilence
+}
+
 func (c *CombinedStreamsClient) AddSubscriber(stream string, bufferSize int) <-chan []byte {
 	ch := make(chan []byte, bufferSize)
 	c.mu.Lock()
@@ -13,22 +13,34 @@ import (
 	"time"
 )
 
-// ✅ 优化2：Funding Rate 缓存机制（节省 95% API 调用）
-// Binance Funding Rate 每 8 小时才更新一次，使用 1 小时缓存完全合理
+// FundingRateCache 资金费率缓存（按币种）
+// 资金费率按结算周期才更新，但 premiumIndex 的预测费率会随行情变化，使用短缓存兼顾时效和 API 调用量
 type FundingRateCache struct {
-	Rate      float64
+	Data      *FundingData
 	UpdatedAt time.Time
 }
 
+// fundingHistoryLimit 提供给 AI 的历史资金费率条数
+const fundingHistoryLimit = 6
+
 var (
 	fundingRateMap sync.Map // map[string]*FundingRateCache
-	frCacheTTL     = 1 * time.Hour
+	frCacheTTL     = 5 * time.Minute
 )
 
 // Get 获取指定代币的市场数据（支持动态时间线选择）
 // timeframes: 可选参数，指定需要获取的时间线列表，如 []string{"1m", "15m", "1h", "4h"}
 // 如果为空或nil，默认使用 ["15m", "1h", "4h"]
+//
+// 禁止内联：交易员测试通过 gomonkey 替换 Get，内联后替换会失效
+//
+//go:noinline
 func Get(symbol string, timeframes []string) (*Data, error) {
+	return GetWithOptions(symbol, timeframes, DataOptions{})
+}
+
+// GetWithOptions 按交易员配置的K线数量和指标获取市场数据（opts 为零值时与 Get 相同）
+func GetWithOptions(symbol string, timeframes []string, opts DataOptions) (*Data, error) {
 	var klines1m, klines3m, klines5m, klines15m, klines1h, klines4h, klines1d []Kline
 	var err error
 	// 标准化symbol
@@ -64,6 +76,7 @@ func Get(symbol string, timeframes []string) (*Data, error) {
 
 	// 获取短期K线数据（用于当前价格和指标计算）
 	var shortKlines []Kline
+	shortKlineTF := shortestTF
 	switch shortestTF {
 	case "1m":
 		klines1m, err = WSMonitorCli.GetCurrentKlines(symbol, "1m")
@@ -90,6 +103,7 @@ func Get(symbol string, timeframes []string) (*Data, error) {
 			return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
 		}
 		shortKlines = klines3m
+		shortKlineTF = "3m"
 	}
 
 	// Data staleness detection: Prevent DOGEUSDT-style price freeze issues (PR #800)
@@ -179,61 +193,68 @@ func Get(symbol string, timeframes []string) (*Data, error) {
 		log.Printf("⚠️  %s 獲取多空比數據失敗: %v", symbol, err)
 	}
 
-	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
+	// 获取Funding Rate（失败不影响整体）
+	var fundingRate float64
+	funding, err := GetFunding(symbol)
+	if err != nil {
+		log.Printf("⚠️  %s 获取资金费率失败: %v", symbol, err)
+	} else {
+		fundingRate = funding.Rate
+	}
 
-	// ✅ 条件性计算时间线数据（只计算用户选择的时间线）
-	var intradayData *IntradayData
-	var midTermData15m *MidTermData15m
-	var midTermData1h *MidTermData1h
-	var longerTermData *LongerTermData
-	var dailyData *DailyData
+	data := &Data{
+		Symbol:        symbol,
+		CurrentPrice:  currentPrice,
+		PriceChange1h: priceChange1h,
+		PriceChange4h: priceChange4h,
+		CurrentEMA20:  currentEMA20,
+		CurrentMACD:   currentMACD,
+		CurrentRSI7:   currentRSI7,
+		OpenInterest:  oiData,
+		FundingRate:   fundingRate,
+		Funding:       funding,
+	}
+	data.Source, data.UpdatedAt = WSMonitorCli.klineSource(symbol, shortKlineTF)
+
+	// 交易员配置了K线数量或指标：只计算请求的指标
+	if opts.custom() {
+		if err := fillCustomSeries(data, symbol, timeframes, opts); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
 
+	// ✅ 条件性计算时间线数据（只计算用户选择的时间线）
 	// 计算日内系列数据 (1m/3m/5m)
 	if len(klines1m) > 0 {
-		intradayData = calculateIntradaySeries(klines1m)
+		data.IntradaySeries = calculateIntradaySeries(klines1m)
 	} else if len(klines3m) > 0 {
-		intradayData = calculateIntradaySeries(klines3m)
+		data.IntradaySeries = calculateIntradaySeries(klines3m)
 	} else if len(klines5m) > 0 {
-		intradayData = calculateIntradaySeries(klines5m)
+		data.IntradaySeries = calculateIntradaySeries(klines5m)
 	}
 
 	// 计算15分钟系列数据（如果用户选择了15m）
 	if len(klines15m) > 0 {
-		midTermData15m = calculateMidTermSeries15m(klines15m)
+		data.MidTermSeries15m = calculateMidTermSeries15m(klines15m)
 	}
 
 	// 计算1小时系列数据（如果用户选择了1h）
 	if len(klines1h) > 0 {
-		midTermData1h = calculateMidTermSeries1h(klines1h)
+		data.MidTermSeries1h = calculateMidTermSeries1h(klines1h)
 	}
 
 	// 计算长期数据 (4小时，如果用户选择了4h)
 	if len(klines4h) > 0 {
-		longerTermData = calculateLongerTermData(klines4h)
+		data.LongerTermContext = calculateLongerTermData(klines4h)
 	}
 
 	// 计算日线数据（如果用户选择了1d）
 	if len(klines1d) > 0 {
-		dailyData = calculateDailyData(klines1d)
-	}
-
-	return &Data{
-		Symbol:            symbol,
-		CurrentPrice:      currentPrice,
-		PriceChange1h:     priceChange1h,
-		PriceChange4h:     priceChange4h,
-		CurrentEMA20:      currentEMA20,
-		CurrentMACD:       currentMACD,
-		CurrentRSI7:       currentRSI7,
-		OpenInterest:      oiData,
-		FundingRate:       fundingRate,
-		IntradaySeries:    intradayData,
-		MidTermSeries15m:  midTermData15m,
-		MidTermSeries1h:   midTermData1h,
-		LongerTermContext: longerTermData,
-		DailyContext:      dailyData,
-	}, nil
+		data.DailyContext = calculateDailyData(klines1d)
+	}
+
+	return data, nil
 }
 
 // calculateEMA 计算EMA
@@ -670,58 +691,80 @@ func getOpenInterestData(symbol string) (*OIData, error) {
 	}, nil
 }
 
-// getFundingRate 获取资金费率（优化：使用 1 小时缓存）
-func getFundingRate(symbol string) (float64, error) {
+// GetFunding 获取资金费率（当前费率、下次结算时间、近期历史），按币种短时间缓存
+// 优先通过多数据源管理器获取（Binance 失败时转移到 Hyperliquid），未启动时直接查询 Binance
+func GetFunding(symbol string) (*FundingData, error) {
 	// ✅ 修复：统一symbol格式（确保大小写一致）
 	symbol = Normalize(symbol)
 
-	// ✅ 优化2：检查缓存（有效期 1 小时）
-	// Funding Rate 每 8 小时才更新，1 小时缓存非常合理
 	if cached, ok := fundingRateMap.Load(symbol); ok {
 		cache := cached.(*FundingRateCache)
 		if time.Since(cache.UpdatedAt) < frCacheTTL {
 			// 缓存命中，直接返回
-			return cache.Rate, nil
+			return cache.Data, nil
 		}
 	}
 
-	// ⚠️ 缓存过期或不存在，调用 API
-	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", symbol)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return 0, err
+	var funding *FundingData
+	var err error
+	if WSMonitorCli != nil && WSMonitorCli.dsManager != nil {
+		funding, err = WSMonitorCli.dsManager.GetFundingWithFallback(symbol)
+	} else {
+		funding, err = NewAPIClient().GetFunding(symbol, fundingHistoryLimit)
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return 0, err
-	}
-
-	var result struct {
-		Symbol          string `json:"symbol"`
-		MarkPrice       string `json:"markPrice"`
-		IndexPrice      string `json:"indexPrice"`
-		LastFundingRate string `json:"lastFundingRate"`
-		NextFundingTime int64  `json:"nextFundingTime"`
-		InterestRate    string `json:"interestRate"`
-		Time            int64  `json:"time"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	rate, _ := strconv.ParseFloat(result.LastFundingRate, 64)
-
 	// ✅ 更新缓存
 	fundingRateMap.Store(symbol, &FundingRateCache{
-		Rate:      rate,
+		Data:      funding,
 		UpdatedAt: time.Now(),
 	})
 
-	return rate, nil
+	return funding, nil
+}
+
+// formatFunding 格式化资金费率（当前费率、下次结算时间、近期历史）
+func formatFunding(data *Data) string {
+	if data.Funding == nil {
+		return fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate)
+	}
+
+	f := data.Funding
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Funding Rate: %.4f%% per %dh (%s)", f.Rate*100, f.IntervalHours, f.Source))
+	if f.NextFundingTime > 0 {
+		sb.WriteString(fmt.Sprintf(" | Next funding in %s", formatFundingCountdown(time.Until(time.UnixMilli(f.NextFundingTime)))))
+	}
+	switch {
+	case f.Rate > 0:
+		sb.WriteString(" | longs pay shorts")
+	case f.Rate < 0:
+		sb.WriteString(" | shorts pay longs")
+	}
+	sb.WriteString("\n")
+	if len(f.History) > 0 {
+		rates := make([]string, 0, len(f.History))
+		for _, h := range f.History {
+			rates = append(rates, fmt.Sprintf("%.4f%%", h.Rate*100))
+		}
+		sb.WriteString(fmt.Sprintf("Funding history (oldest → latest): [%s]\n", strings.Join(rates, ", ")))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// formatFundingCountdown 格式化距离下次结算的时间
+func formatFundingCountdown(d time.Duration) string {
+	if d <= 0 {
+		return "0m"
+	}
+	d = d.Round(time.Minute)
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
 }
 
 // Format 格式化输出市场数据
@@ -730,8 +773,16 @@ func Format(data *Data) string {
 
 	// 使用动态精度格式化价格
 	priceStr := formatPriceWithDynamicPrecision(data.CurrentPrice)
-	sb.WriteString(fmt.Sprintf("current_price = %s, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
-		priceStr, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
+	if data.Source == SourceRESTFallback {
+		sb.WriteString(fmt.Sprintf("Note: real-time streams are unavailable; this data comes from REST polling (updated %ds ago) and may lag the market.\n\n",
+			int(time.Since(data.UpdatedAt).Seconds())))
+	}
+	if len(data.Series) > 0 {
+		sb.WriteString(formatCustomHeader(data))
+	} else {
+		sb.WriteString(fmt.Sprintf("current_price = %s, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
+			priceStr, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
+	}
 
 	sb.WriteString(fmt.Sprintf("In addition, here is the latest %s open interest and funding rate for perps:\n\n",
 		data.Symbol))
@@ -796,7 +847,13 @@ func Format(data *Data) string {
 		}
 	}
 
-	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
+	sb.WriteString(formatFunding(data))
+
+	// 自定义格式只输出交易员请求的时间线序列和指标
+	if len(data.Series) > 0 {
+		sb.WriteString(formatTimeframeSeries(data))
+		return sb.String()
+	}
 
 	if data.IntradaySeries != nil {
 		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
@@ -1,9 +1,11 @@
 package market
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,6 +18,14 @@ type DataSource interface {
 	GetLatency() time.Duration                                     // 获取延迟
 }
 
+// ErrInsufficientPriceSources 健康数据源少于两个，无法验证价格一致性
+var ErrInsufficientPriceSources = errors.New("数据源不足，无法验证价格一致性")
+
+// FundingSource 支持查询资金费率的数据源（可选实现）
+type FundingSource interface {
+	GetFunding(symbol string) (*FundingData, error)
+}
+
 // DataSourceStatus 数据源状态
 type DataSourceStatus struct {
 	Name          string        // 数据源名称
@@ -37,6 +47,20 @@ type DataSourceManager struct {
 	checkInterval time.Duration                // 健康检查间隔
 }
 
+// testnetDSManager 测试网行情数据源管理器（只在存在启用测试网的交易所时创建）
+// 测试网价格与主网不同，单独管理，避免混入主网数据源的价格一致性校验
+var testnetDSManager atomic.Pointer[DataSourceManager]
+
+// SetTestnetDSManager 注册测试网数据源管理器（传 nil 取消注册）
+func SetTestnetDSManager(dsm *DataSourceManager) {
+	testnetDSManager.Store(dsm)
+}
+
+// GetTestnetDSManager 获取测试网数据源管理器，未启用时返回 nil
+func GetTestnetDSManager() *DataSourceManager {
+	return testnetDSManager.Load()
+}
+
 // NewDataSourceManager 创建数据源管理器
 func NewDataSourceManager(checkInterval time.Duration) *DataSourceManager {
 	if checkInterval <= 0 {
@@ -242,6 +266,48 @@ func (dsm *DataSourceManager) GetTickerWithFallback(symbol string) (*Ticker, err
 	return nil, fmt.Errorf("所有数据源都失败: %w", lastErr)
 }
 
+// GetFundingWithFallback 获取资金费率（带故障转移，跳过不支持资金费率的数据源）
+func (dsm *DataSourceManager) GetFundingWithFallback(symbol string) (*FundingData, error) {
+	dsm.mu.Lock()
+	sources := make([]DataSource, len(dsm.sources))
+	copy(sources, dsm.sources)
+	dsm.mu.Unlock()
+
+	lastErr := fmt.Errorf("没有支持资金费率的数据源")
+
+	for _, source := range sources {
+		fundingSource, ok := source.(FundingSource)
+		if !ok {
+			continue
+		}
+
+		dsm.mu.RLock()
+		status := dsm.statuses[source.GetName()]
+		healthy := status.Healthy
+		dsm.mu.RUnlock()
+
+		if !healthy {
+			continue
+		}
+
+		funding, err := fundingSource.GetFunding(symbol)
+
+		dsm.mu.Lock()
+		status.TotalRequests++
+		dsm.mu.Unlock()
+
+		if err == nil && funding != nil {
+			return funding, nil
+		}
+
+		lastErr = err
+		log.Printf("⚠️  从 %s 获取 %s 资金费率失败: %v，尝试下一个数据源...",
+			source.GetName(), symbol, err)
+	}
+
+	return nil, fmt.Errorf("所有数据源都失败: %w", lastErr)
+}
+
 // GetStatus 获取所有数据源的状态
 func (dsm *DataSourceManager) GetStatus() map[string]*DataSourceStatus {
 	dsm.mu.RLock()
@@ -290,7 +356,7 @@ func (dsm *DataSourceManager) VerifyPriceConsistency(symbol string, maxDeviation
 	}
 
 	if len(prices) < 2 {
-		return true, prices, fmt.Errorf("数据源不足，无法验证价格一致性")
+		return true, prices, ErrInsufficientPriceSources
 	}
 
 	// 计算平均价格
@@ -494,3 +494,42 @@ func TestStartStop(t *testing.T) {
 
 	t.Logf("✅ Start/Stop cycle completed successfully")
 }
+
+// mockFundingSource 支持资金费率的 mock 数据源
+type mockFundingSource struct {
+	MockDataSource
+	funding     *FundingData
+	failFunding bool
+}
+
+func (m *mockFundingSource) GetFunding(symbol string) (*FundingData, error) {
+	if m.failFunding {
+		return nil, fmt.Errorf("mock funding error")
+	}
+	return m.funding, nil
+}
+
+// TestGetFundingWithFallback tests funding failover and skipping sources without funding support
+func TestGetFundingWithFallback(t *testing.T) {
+	dsm := NewDataSourceManager(10 * time.Second)
+	dsm.AddSource(&MockDataSource{name: "NoFunding", healthy: true})
+	dsm.AddSource(&mockFundingSource{MockDataSource: MockDataSource{name: "Primary", healthy: true}, failFunding: true})
+	dsm.AddSource(&mockFundingSource{
+		MockDataSource: MockDataSource{name: "Backup", healthy: true},
+		funding:        &FundingData{Source: "Backup", Rate: 0.0003, IntervalHours: 1},
+	})
+
+	funding, err := dsm.GetFundingWithFallback("BTCUSDT")
+	if err != nil {
+		t.Fatalf("Expected failover to succeed, got %v", err)
+	}
+	if funding.Source != "Backup" || funding.Rate != 0.0003 {
+		t.Errorf("Expected funding from Backup, got %+v", funding)
+	}
+
+	empty := NewDataSourceManager(10 * time.Second)
+	empty.AddSource(&MockDataSource{name: "NoFunding", healthy: true})
+	if _, err := empty.GetFundingWithFallback("BTCUSDT"); err == nil {
+		t.Error("Expected error when no source supports funding")
+	}
+}
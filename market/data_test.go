@@ -2,7 +2,9 @@ package market
 
 import (
 	"math"
+	"strings"
 	"testing"
+	"time"
 )
 
 // generateTestKlines 生成测试用的 K线数据
@@ -500,3 +502,30 @@ func TestIsStaleData_EmptyKlines(t *testing.T) {
 		t.Error("Expected false for empty klines, got true")
 	}
 }
+
+// TestFormatFunding 测试资金费率（下次结算时间、历史）写入市场数据
+func TestFormatFunding(t *testing.T) {
+	data := &Data{
+		Symbol:      "BTCUSDT",
+		FundingRate: 0.0012,
+		Funding: &FundingData{
+			Source:          "Binance",
+			Rate:            0.0012,
+			NextFundingTime: time.Now().Add(2*time.Hour + 30*time.Minute).UnixMilli(),
+			IntervalHours:   8,
+			History:         []FundingSnapshot{{Rate: 0.0008}, {Rate: 0.001}},
+		},
+	}
+	out := Format(data)
+	for _, want := range []string{"Funding Rate: 0.1200% per 8h (Binance)", "Next funding in 2h30m", "longs pay shorts", "[0.0800%, 0.1000%]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("格式化结果缺少 %q:\n%s", want, out)
+		}
+	}
+
+	// 没有资金费率详情时只输出费率
+	data.Funding = nil
+	if out := Format(data); !strings.Contains(out, "Funding Rate: 1.20e-03") {
+		t.Errorf("缺少资金费率:\n%s", out)
+	}
+}
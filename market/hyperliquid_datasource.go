@@ -22,8 +22,10 @@ type HyperliquidDataSource struct {
 func NewHyperliquidDataSource(testnet bool) *HyperliquidDataSource {
 	// 选择 API URL
 	baseURL := hyperliquid.MainnetAPIURL
+	name := "Hyperliquid"
 	if testnet {
 		baseURL = hyperliquid.TestnetAPIURL
+		name = "Hyperliquid Testnet" // 与主网数据源区分（数据源状态按名称记录）
 	}
 
 	ctx := context.Background()
@@ -36,7 +38,7 @@ func NewHyperliquidDataSource(testnet bool) *HyperliquidDataSource {
 	return &HyperliquidDataSource{
 		info: info,
 		ctx:  ctx,
-		name: "Hyperliquid",
+		name: name,
 	}
 }
 
@@ -118,6 +120,60 @@ func (h *HyperliquidDataSource) GetTicker(symbol string) (*Ticker, error) {
 	return ticker, nil
 }
 
+// GetFunding 获取资金费率（meta 中的当前费率 + fundingHistory），Hyperliquid 每小时结算一次
+func (h *HyperliquidDataSource) GetFunding(symbol string) (*FundingData, error) {
+	coin := convertSymbolToHyperliquid(symbol)
+
+	metaCtxs, err := h.info.MetaAndAssetCtxs(h.ctx)
+	if err != nil {
+		log.Printf("⚠️  Hyperliquid GetFunding 失败 [%s]: %v", symbol, err)
+		return nil, fmt.Errorf("hyperliquid GetFunding failed: %w", err)
+	}
+
+	idx := -1
+	for i, asset := range metaCtxs.Universe {
+		if asset.Name == coin {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 || idx >= len(metaCtxs.Ctxs) {
+		return nil, fmt.Errorf("funding not found for %s (%s)", symbol, coin)
+	}
+
+	rate, err := strconv.ParseFloat(metaCtxs.Ctxs[idx].Funding, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse funding failed: %w", err)
+	}
+
+	now := time.Now()
+	funding := &FundingData{
+		Source:          h.name,
+		Rate:            rate,
+		NextFundingTime: now.Truncate(time.Hour).Add(time.Hour).UnixMilli(),
+		IntervalHours:   1,
+	}
+
+	// 历史资金费率获取失败不影响当前费率
+	startTime := now.Add(-time.Duration(fundingHistoryLimit+1) * time.Hour).UnixMilli()
+	history, err := h.info.FundingHistory(h.ctx, coin, startTime, nil)
+	if err != nil {
+		log.Printf("⚠️  Hyperliquid 获取 %s 历史资金费率失败: %v", symbol, err)
+		return funding, nil
+	}
+	for _, item := range history {
+		r, err := strconv.ParseFloat(item.FundingRate, 64)
+		if err != nil {
+			continue
+		}
+		funding.History = append(funding.History, FundingSnapshot{Rate: r, Time: item.Time})
+	}
+	if len(funding.History) > fundingHistoryLimit {
+		funding.History = funding.History[len(funding.History)-fundingHistoryLimit:]
+	}
+	return funding, nil
+}
+
 // HealthCheck 健康检查
 func (h *HyperliquidDataSource) HealthCheck() error {
 	// 尝试获取 AllMids 作为健康检查
@@ -0,0 +1,418 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 交易员自定义行情上下文：kline_limit 控制每个时间线提供给 AI 的K线数量，
+// indicators 控制计算并写入提示词的技术指标。两者都未配置时保持默认的行情格式。
+
+const (
+	DefaultKlineLimit  = 10   // 配置了指标但未配置K线数量时每个时间线的K线数量
+	MaxKlineLimit      = 500  // 每个时间线最多提供给 AI 的K线数量
+	maxIndicatorPeriod = 200  // 指标周期上限（例如 ema200）
+	maxKlineFetch      = 1500 // 单次 REST 请求的K线数量上限（Binance 限制）
+)
+
+// DefaultIndicators 只配置了K线数量时使用的指标集（与默认行情格式一致）
+var DefaultIndicators = []string{"ema20", "macd", "rsi7", "rsi14", "atr14", "volume"}
+
+// IndicatorInfo 支持的指标说明
+type IndicatorInfo struct {
+	Name        string `json:"name"`        // 指标名称（带周期的写法如 ema<N>）
+	Description string `json:"description"` // 说明
+}
+
+// indicatorRegistry 支持的指标（带周期的指标周期范围 2~200）
+var indicatorRegistry = []struct {
+	base     string
+	periodic bool
+	info     IndicatorInfo
+}{
+	{"ema", true, IndicatorInfo{"ema<N>", "指数移动平均，例如 ema20、ema50、ema200"}},
+	{"sma", true, IndicatorInfo{"sma<N>", "简单移动平均，例如 sma20"}},
+	{"rsi", true, IndicatorInfo{"rsi<N>", "相对强弱指数（Wilder 平滑），例如 rsi7、rsi14"}},
+	{"atr", true, IndicatorInfo{"atr<N>", "平均真实波幅（Wilder 平滑），例如 atr14"}},
+	{"macd", false, IndicatorInfo{"macd", "MACD（EMA12 - EMA26）"}},
+	{"volume", false, IndicatorInfo{"volume", "成交量"}},
+	{"pivots", false, IndicatorInfo{"pivots", "经典枢轴点（基于每个时间线上一根已收盘K线的 P/R1/S1/R2/S2）"}},
+	{"weekly_pivots", false, IndicatorInfo{"weekly_pivots", "周线枢轴点（由日线聚合上一个完整周，UTC 周一开始）"}},
+}
+
+var indicatorNamePattern = regexp.MustCompile(`^([a-z_]+?)(\d*)$`)
+
+// SupportedIndicators 返回支持的指标列表
+func SupportedIndicators() []IndicatorInfo {
+	list := make([]IndicatorInfo, 0, len(indicatorRegistry))
+	for _, spec := range indicatorRegistry {
+		list = append(list, spec.info)
+	}
+	return list
+}
+
+// supportedIndicatorNames 支持的指标写法（用于错误提示）
+func supportedIndicatorNames() string {
+	names := make([]string, 0, len(indicatorRegistry))
+	for _, spec := range indicatorRegistry {
+		names = append(names, spec.info.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// indicator 解析后的指标
+type indicator struct {
+	name   string // 标准化名称，例如 ema20
+	base   string // 指标类型，例如 ema
+	period int    // 周期（无周期指标为 0）
+}
+
+// parseIndicator 解析并校验单个指标名称
+func parseIndicator(raw string) (indicator, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	m := indicatorNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return indicator{}, fmt.Errorf("不支持的指标 %q（支持: %s）", raw, supportedIndicatorNames())
+	}
+	base, digits := m[1], m[2]
+	for _, spec := range indicatorRegistry {
+		if spec.base != base {
+			continue
+		}
+		if !spec.periodic {
+			if digits != "" {
+				return indicator{}, fmt.Errorf("指标 %s 不需要周期参数: %q", base, raw)
+			}
+			return indicator{name: base, base: base}, nil
+		}
+		period, err := strconv.Atoi(digits)
+		if err != nil || period < 2 || period > maxIndicatorPeriod {
+			return indicator{}, fmt.Errorf("指标 %q 需要 2~%d 的周期，例如 %s20", raw, maxIndicatorPeriod, base)
+		}
+		return indicator{name: fmt.Sprintf("%s%d", base, period), base: base, period: period}, nil
+	}
+	return indicator{}, fmt.Errorf("不支持的指标 %q（支持: %s）", raw, supportedIndicatorNames())
+}
+
+// ParseIndicators 解析逗号分隔的指标列表（去重、转小写），返回标准化后的名称
+func ParseIndicators(raw string) ([]string, error) {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		ind, err := parseIndicator(part)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[ind.name] {
+			seen[ind.name] = true
+			names = append(names, ind.name)
+		}
+	}
+	return names, nil
+}
+
+// DataOptions 交易员级别的行情数据配置（零值表示使用默认行情格式）
+type DataOptions struct {
+	KlineLimit int      // 每个时间线提供给 AI 的K线数量（0=默认）
+	Indicators []string // 需要计算的指标（空=DefaultIndicators）
+}
+
+// custom 是否使用自定义行情格式
+func (o DataOptions) custom() bool {
+	return o.KlineLimit > 0 || len(o.Indicators) > 0
+}
+
+// resolve 返回实际使用的K线数量和指标（无法识别的指标已在保存配置时校验，这里直接跳过）
+func (o DataOptions) resolve() (int, []indicator) {
+	limit := o.KlineLimit
+	if limit <= 0 {
+		limit = DefaultKlineLimit
+	}
+	if limit > MaxKlineLimit {
+		limit = MaxKlineLimit
+	}
+	names := o.Indicators
+	if len(names) == 0 {
+		names = DefaultIndicators
+	}
+	inds := make([]indicator, 0, len(names))
+	for _, name := range names {
+		if ind, err := parseIndicator(name); err == nil {
+			inds = append(inds, ind)
+		}
+	}
+	return limit, inds
+}
+
+// warmup 计算指标需要的额外K线数量
+func (ind indicator) warmup() int {
+	switch ind.base {
+	case "ema", "sma":
+		return ind.period
+	case "rsi", "atr":
+		return ind.period + 1
+	case "macd":
+		return 26
+	}
+	return 1
+}
+
+// valueAt 计算指标在 klines 最后一根K线上的值（数据不足时返回 false）
+func (ind indicator) valueAt(klines []Kline) (float64, bool) {
+	n := len(klines)
+	switch ind.base {
+	case "ema":
+		return calculateEMA(klines, ind.period), n >= ind.period
+	case "sma":
+		if n < ind.period {
+			return 0, false
+		}
+		sum := 0.0
+		for _, k := range klines[n-ind.period:] {
+			sum += k.Close
+		}
+		return sum / float64(ind.period), true
+	case "rsi":
+		return calculateRSI(klines, ind.period), n > ind.period
+	case "atr":
+		return calculateATR(klines, ind.period), n > ind.period
+	case "macd":
+		return calculateMACD(klines), n >= 26
+	case "volume":
+		return klines[n-1].Volume, n > 0
+	}
+	return 0, false
+}
+
+// label 指标在提示词中的名称
+func (ind indicator) label() string {
+	switch ind.base {
+	case "ema":
+		return fmt.Sprintf("EMA (%d‑period)", ind.period)
+	case "sma":
+		return fmt.Sprintf("SMA (%d‑period)", ind.period)
+	case "rsi":
+		return fmt.Sprintf("RSI (%d‑period)", ind.period)
+	case "atr":
+		return fmt.Sprintf("ATR (%d‑period)", ind.period)
+	case "macd":
+		return "MACD (12/26)"
+	case "volume":
+		return "Volume"
+	}
+	return ind.name
+}
+
+// PivotLevels 经典枢轴点
+type PivotLevels struct {
+	P, R1, S1, R2, S2 float64
+}
+
+// IndicatorSeries 单个指标的序列（旧 → 新）
+type IndicatorSeries struct {
+	Name   string
+	Label  string
+	Values []float64
+}
+
+// TimeframeSeries 单个时间线按交易员配置计算的行情序列
+type TimeframeSeries struct {
+	Timeframe  string
+	Closes     []float64 // 收盘价（旧 → 新）
+	Indicators []IndicatorSeries
+	Pivots     *PivotLevels // 请求了 pivots 时基于上一根已收盘K线计算
+}
+
+// klineFetchCount 计算指标需要获取的K线数量（展示数量 + 最长预热）
+func klineFetchCount(limit int, inds []indicator) int {
+	warmup := 0
+	for _, ind := range inds {
+		if w := ind.warmup(); w > warmup {
+			warmup = w
+		}
+	}
+	if count := limit + warmup; count < maxKlineFetch {
+		return count
+	}
+	return maxKlineFetch
+}
+
+// buildTimeframeSeries 计算单个时间线最近 limit 根K线的收盘价和请求的指标
+func buildTimeframeSeries(timeframe string, klines []Kline, limit int, inds []indicator) *TimeframeSeries {
+	series := &TimeframeSeries{Timeframe: timeframe}
+	start := len(klines) - limit
+	if start < 0 {
+		start = 0
+	}
+	for _, k := range klines[start:] {
+		series.Closes = append(series.Closes, k.Close)
+	}
+
+	for _, ind := range inds {
+		switch ind.base {
+		case "pivots":
+			if len(klines) >= 2 {
+				prev := klines[len(klines)-2]
+				series.Pivots = pivotLevels(prev.High, prev.Low, prev.Close)
+			}
+			continue
+		case "weekly_pivots":
+			continue
+		}
+		values := make([]float64, 0, len(klines)-start)
+		for i := start; i < len(klines); i++ {
+			if v, ok := ind.valueAt(klines[:i+1]); ok {
+				values = append(values, v)
+			}
+		}
+		series.Indicators = append(series.Indicators, IndicatorSeries{Name: ind.name, Label: ind.label(), Values: values})
+	}
+	return series
+}
+
+// pivotLevels 经典枢轴点：P=(H+L+C)/3，R1=2P-L，S1=2P-H，R2=P+(H-L)，S2=P-(H-L)
+func pivotLevels(high, low, close float64) *PivotLevels {
+	p := (high + low + close) / 3
+	return &PivotLevels{
+		P:  p,
+		R1: 2*p - low,
+		S1: 2*p - high,
+		R2: p + (high - low),
+		S2: p - (high - low),
+	}
+}
+
+// calculateWeeklyPivots 用日线聚合上一个完整周（UTC 周一开始）计算枢轴点，数据不足时返回 nil
+func calculateWeeklyPivots(daily []Kline) *PivotLevels {
+	if len(daily) == 0 {
+		return nil
+	}
+	weekStart := func(openTime int64) time.Time {
+		t := time.UnixMilli(openTime).UTC()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	prevWeek := weekStart(daily[len(daily)-1].OpenTime).AddDate(0, 0, -7)
+
+	high, low, close := 0.0, math.MaxFloat64, 0.0
+	found := false
+	for _, k := range daily {
+		if !weekStart(k.OpenTime).Equal(prevWeek) {
+			continue
+		}
+		found = true
+		high = math.Max(high, k.High)
+		low = math.Min(low, k.Low)
+		close = k.Close
+	}
+	if !found {
+		return nil
+	}
+	return pivotLevels(high, low, close)
+}
+
+// hasIndicator 是否请求了指定类型的指标
+func hasIndicator(inds []indicator, base string) bool {
+	for _, ind := range inds {
+		if ind.base == base {
+			return true
+		}
+	}
+	return false
+}
+
+// fillCustomSeries 按交易员配置获取每个时间线的K线并计算请求的指标
+func fillCustomSeries(data *Data, symbol string, timeframes []string, opts DataOptions) error {
+	limit, inds := opts.resolve()
+	count := klineFetchCount(limit, inds)
+
+	ordered := append([]string(nil), timeframes...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return timeframeDuration(ordered[i]) < timeframeDuration(ordered[j])
+	})
+	for _, tf := range ordered {
+		if timeframeDuration(tf) == 0 {
+			continue
+		}
+		klines, err := WSMonitorCli.GetKlinesWithLimit(symbol, tf, count)
+		if err != nil {
+			return fmt.Errorf("获取%s K线失败: %v", tf, err)
+		}
+		if len(klines) == 0 {
+			continue
+		}
+		data.Series = append(data.Series, buildTimeframeSeries(tf, klines, limit, inds))
+	}
+
+	if hasIndicator(inds, "weekly_pivots") {
+		// 上一个完整周最多需要 14 根日线
+		if daily, err := WSMonitorCli.GetKlinesWithLimit(symbol, "1d", 14); err == nil {
+			data.WeeklyPivots = calculateWeeklyPivots(daily)
+		}
+	}
+	return nil
+}
+
+// timeframeLabel 时间线在提示词中的名称
+func timeframeLabel(tf string) string {
+	switch tf {
+	case "1m", "3m", "5m", "15m":
+		return strings.TrimSuffix(tf, "m") + "‑minute"
+	case "1h", "4h":
+		return strings.TrimSuffix(tf, "h") + "‑hour"
+	case "1d":
+		return "1‑day"
+	}
+	return tf
+}
+
+// formatPivots 格式化枢轴点
+func formatPivots(p *PivotLevels) string {
+	return fmt.Sprintf("P=%s, R1=%s, S1=%s, R2=%s, S2=%s",
+		formatPriceWithDynamicPrecision(p.P), formatPriceWithDynamicPrecision(p.R1), formatPriceWithDynamicPrecision(p.S1),
+		formatPriceWithDynamicPrecision(p.R2), formatPriceWithDynamicPrecision(p.S2))
+}
+
+// formatCustomHeader 自定义格式的当前值（最短时间线上各指标的最新值）
+func formatCustomHeader(data *Data) string {
+	parts := []string{"current_price = " + formatPriceWithDynamicPrecision(data.CurrentPrice)}
+	if len(data.Series) > 0 {
+		for _, ind := range data.Series[0].Indicators {
+			if ind.Name == "volume" || len(ind.Values) == 0 {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("current_%s = %.3f", ind.Name, ind.Values[len(ind.Values)-1]))
+		}
+	}
+	return strings.Join(parts, ", ") + "\n\n"
+}
+
+// formatTimeframeSeries 自定义格式的各时间线序列
+func formatTimeframeSeries(data *Data) string {
+	var sb strings.Builder
+	for _, series := range data.Series {
+		sb.WriteString(fmt.Sprintf("%s series (last %d bars, oldest → latest):\n\n", timeframeLabel(series.Timeframe), len(series.Closes)))
+		sb.WriteString(fmt.Sprintf("Close prices: %s\n\n", formatFloatSlice(series.Closes)))
+		for _, ind := range series.Indicators {
+			if len(ind.Values) == 0 {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%s: %s\n\n", ind.Label, formatFloatSlice(ind.Values)))
+		}
+		if series.Pivots != nil {
+			sb.WriteString(fmt.Sprintf("Pivot points (previous %s bar): %s\n\n", series.Timeframe, formatPivots(series.Pivots)))
+		}
+	}
+	if data.WeeklyPivots != nil {
+		sb.WriteString(fmt.Sprintf("Weekly pivot points (previous week): %s\n\n", formatPivots(data.WeeklyPivots)))
+	}
+	return sb.String()
+}
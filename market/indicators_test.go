@@ -0,0 +1,128 @@
+package market
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseIndicators 测试指标名称的校验和标准化
+func TestParseIndicators(t *testing.T) {
+	got, err := ParseIndicators(" EMA50, rsi14,ema50,,weekly_pivots ")
+	if err != nil {
+		t.Fatalf("ParseIndicators 返回错误: %v", err)
+	}
+	if strings.Join(got, ",") != "ema50,rsi14,weekly_pivots" {
+		t.Errorf("标准化结果 = %v, 期望 [ema50 rsi14 weekly_pivots]", got)
+	}
+
+	if got, err := ParseIndicators(""); err != nil || len(got) != 0 {
+		t.Errorf("空配置应返回空列表, got %v (%v)", got, err)
+	}
+
+	for _, raw := range []string{"bollinger", "ema", "ema1", "ema201", "macd12", "rsi-14"} {
+		if _, err := ParseIndicators(raw); err == nil {
+			t.Errorf("ParseIndicators(%q) 应返回错误", raw)
+		}
+	}
+}
+
+// TestBuildTimeframeSeries 测试只计算请求的指标，且序列长度等于K线数量
+func TestBuildTimeframeSeries(t *testing.T) {
+	klines := generateTestKlines(120)
+	limit, inds := DataOptions{KlineLimit: 30, Indicators: []string{"ema50", "sma20", "atr14", "pivots"}}.resolve()
+	series := buildTimeframeSeries("15m", klines, limit, inds)
+
+	if len(series.Closes) != 30 {
+		t.Fatalf("收盘价数量 = %d, 期望 30", len(series.Closes))
+	}
+	if len(series.Indicators) != 3 {
+		t.Fatalf("指标数量 = %d, 期望 3 (ema50/sma20/atr14)", len(series.Indicators))
+	}
+	for _, ind := range series.Indicators {
+		if len(ind.Values) != 30 {
+			t.Errorf("%s 序列长度 = %d, 期望 30", ind.Name, len(ind.Values))
+		}
+	}
+	if series.Pivots == nil {
+		t.Fatal("请求了 pivots 时应计算枢轴点")
+	}
+	prev := klines[len(klines)-2]
+	if want := (prev.High + prev.Low + prev.Close) / 3; series.Pivots.P != want {
+		t.Errorf("P = %.4f, 期望 %.4f", series.Pivots.P, want)
+	}
+
+	// 预热数据不足时只输出可计算的值
+	short := buildTimeframeSeries("15m", generateTestKlines(40), 30, inds)
+	if got := len(short.Indicators[0].Values); got != 0 {
+		t.Errorf("数据不足时 ema50 不应输出值, got %d", got)
+	}
+}
+
+// TestFormatDiffersPerTrader 测试同一币种按不同交易员配置输出不同的行情段落
+func TestFormatDiffersPerTrader(t *testing.T) {
+	klines := generateTestKlines(200)
+	build := func(opts DataOptions) string {
+		limit, inds := opts.resolve()
+		data := &Data{Symbol: "BTCUSDT", CurrentPrice: klines[len(klines)-1].Close}
+		data.Series = append(data.Series, buildTimeframeSeries("15m", klines, limit, inds))
+		return Format(data)
+	}
+
+	trend := build(DataOptions{KlineLimit: 50, Indicators: []string{"ema50", "ema200"}})
+	momentum := build(DataOptions{KlineLimit: 5, Indicators: []string{"rsi14", "macd"}})
+
+	if trend == momentum {
+		t.Fatal("不同配置应输出不同的行情段落")
+	}
+	for _, want := range []string{"last 50 bars", "EMA (50‑period)", "EMA (200‑period)", "current_ema200"} {
+		if !strings.Contains(trend, want) {
+			t.Errorf("趋势配置缺少 %q:\n%s", want, trend)
+		}
+	}
+	for _, unwanted := range []string{"RSI", "MACD"} {
+		if strings.Contains(trend, unwanted) {
+			t.Errorf("趋势配置不应包含未请求的 %s:\n%s", unwanted, trend)
+		}
+	}
+	for _, want := range []string{"last 5 bars", "RSI (14‑period)", "MACD (12/26)"} {
+		if !strings.Contains(momentum, want) {
+			t.Errorf("动量配置缺少 %q:\n%s", want, momentum)
+		}
+	}
+	if strings.Contains(momentum, "EMA") {
+		t.Errorf("动量配置不应包含未请求的 EMA:\n%s", momentum)
+	}
+}
+
+// TestCalculateWeeklyPivots 测试用上一个完整周的日线计算周线枢轴点
+func TestCalculateWeeklyPivots(t *testing.T) {
+	// 2024-01-01 是周一：01-01~01-07 为上一周，01-08~01-09 为本周
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	daily := make([]Kline, 0, 9)
+	for i := 0; i < 9; i++ {
+		price := 100.0 + float64(i)
+		daily = append(daily, Kline{
+			OpenTime: start.AddDate(0, 0, i).UnixMilli(),
+			High:     price + 5,
+			Low:      price - 5,
+			Close:    price,
+		})
+	}
+
+	pivots := calculateWeeklyPivots(daily)
+	if pivots == nil {
+		t.Fatal("应计算出周线枢轴点")
+	}
+	// 上一周: High=106+5, Low=100-5, Close=106
+	if want := (111.0 + 95.0 + 106.0) / 3; pivots.P != want {
+		t.Errorf("P = %.4f, 期望 %.4f", pivots.P, want)
+	}
+	if want := pivots.P + (111.0 - 95.0); pivots.R2 != want {
+		t.Errorf("R2 = %.4f, 期望 %.4f", pivots.R2, want)
+	}
+
+	if calculateWeeklyPivots(daily[7:]) != nil {
+		t.Error("没有上一周的日线时应返回 nil")
+	}
+}
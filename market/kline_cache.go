@@ -0,0 +1,97 @@
+package market
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// defaultKlineMaxAge 未配置过期倍数时K线缓存的最大数据年龄
+const defaultKlineMaxAge = 5 * time.Minute
+
+// DefaultKlineStaleFactor 默认K线缓存过期倍数（数据年龄超过 2 个时间线周期视为过期）
+const DefaultKlineStaleFactor = 2.0
+
+// klineStaleFactorBits K线缓存过期倍数（float64 位模式，0=使用固定的 5 分钟）
+var klineStaleFactorBits atomic.Uint64
+
+func init() {
+	klineStaleFactorBits.Store(math.Float64bits(DefaultKlineStaleFactor))
+}
+
+// K线缓存命中统计（进程级，所有交易员共用）
+var (
+	klineCacheHits       atomic.Uint64 // 直接使用 WebSocket 维护的缓存
+	klineCacheMisses     atomic.Uint64 // 未订阅，回退 REST 并动态订阅
+	klineCacheStale      atomic.Uint64 // 缓存过期，回退 REST 刷新
+	klineCacheRESTErrors atomic.Uint64 // 回退 REST 失败
+)
+
+// KlineCacheStats K线缓存命中统计
+type KlineCacheStats struct {
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	Stale       uint64  `json:"stale"`
+	RESTErrors  uint64  `json:"rest_errors"`
+	HitRate     float64 `json:"hit_rate"`     // 命中率（%）
+	StaleFactor float64 `json:"stale_factor"` // 过期倍数（0=固定 5 分钟）
+}
+
+// SetKlineStaleFactor 设置K线缓存过期倍数：数据年龄超过 factor 个时间线周期时回退 REST（<=0 使用固定的 5 分钟）
+func SetKlineStaleFactor(factor float64) {
+	if factor < 0 || math.IsNaN(factor) || math.IsInf(factor, 0) {
+		factor = 0
+	}
+	klineStaleFactorBits.Store(math.Float64bits(factor))
+}
+
+// klineStaleFactor 当前K线缓存过期倍数
+func klineStaleFactor() float64 {
+	return math.Float64frombits(klineStaleFactorBits.Load())
+}
+
+// klineMaxAge 指定时间线的缓存最大数据年龄
+func klineMaxAge(timeframe string) time.Duration {
+	factor := klineStaleFactor()
+	period := timeframeDuration(timeframe)
+	if factor <= 0 || period <= 0 {
+		return defaultKlineMaxAge
+	}
+	return time.Duration(factor * float64(period))
+}
+
+// timeframeDuration K线时间线对应的周期长度，未知时间线返回 0
+func timeframeDuration(timeframe string) time.Duration {
+	switch timeframe {
+	case "1m":
+		return time.Minute
+	case "3m":
+		return 3 * time.Minute
+	case "5m":
+		return 5 * time.Minute
+	case "15m":
+		return 15 * time.Minute
+	case "1h":
+		return time.Hour
+	case "4h":
+		return 4 * time.Hour
+	case "1d":
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// GetKlineCacheStats 获取K线缓存命中统计
+func GetKlineCacheStats() KlineCacheStats {
+	stats := KlineCacheStats{
+		Hits:        klineCacheHits.Load(),
+		Misses:      klineCacheMisses.Load(),
+		Stale:       klineCacheStale.Load(),
+		RESTErrors:  klineCacheRESTErrors.Load(),
+		StaleFactor: klineStaleFactor(),
+	}
+	if total := stats.Hits + stats.Misses + stats.Stale; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total) * 100
+	}
+	return stats
+}
@@ -39,6 +39,8 @@ type WSMonitor struct {
 	symbolStats     sync.Map           // 存储币种统计信息
 	FilterSymbol    []string           //经过筛选的币种
 	dsManager       *DataSourceManager // 多数据源管理器（用于故障转移）
+	fallback        restFallback       // WebSocket 不可用时的 REST 轮询降级
+	healthStopChan  chan struct{}      // 流健康检查停止信号通道
 }
 type SymbolStats struct {
 	LastActiveTime   time.Time
@@ -53,6 +55,7 @@ type SymbolStats struct {
 type KlineCacheEntry struct {
 	Klines     []Kline   // K线数据
 	ReceivedAt time.Time // 数据接收时间
+	Source     string    // 数据来源（SourceWebSocket / SourceREST / SourceRESTFallback）
 }
 
 var WSMonitorCli *WSMonitor
@@ -196,6 +199,7 @@ func (m *WSMonitor) initializeHistoricalData() error {
 					entry := &KlineCacheEntry{
 						Klines:     klines,
 						ReceivedAt: time.Now(),
+						Source:     SourceREST,
 					}
 					klineDataMap.Store(s, entry)
 					log.Printf("✅ 已加载 %s 的历史K线数据-%s: %d 条", s, tf, len(klines))
@@ -255,18 +259,25 @@ func (m *WSMonitor) Start(coins []string) {
 
 	err = m.combinedClient.Connect()
 	if err != nil {
-		log.Printf("❌ 批量订阅流失败: %v", err)
-		return
-	}
-	// 订阅所有交易对
-	err = m.subscribeAll()
-	if err != nil {
+		// WSS 被拦截或区域故障：先注册订阅（重连成功后自动重新订阅），行情由 REST 轮询降级提供
+		log.Printf("❌ 批量订阅流失败: %v，行情降级为 REST 轮询，后台持续重连", err)
+		for _, symbol := range m.symbols {
+			for _, st := range m.timeframes {
+				m.subscribeSymbol(symbol, st)
+			}
+		}
+		go m.combinedClient.handleReconnect()
+	} else if err = m.subscribeAll(); err != nil {
+		// 订阅所有交易对
 		log.Printf("❌ 订阅币种交易对失败: %v", err)
 		return
 	}
 
 	// P0修复：启动OI定期监控（每15分钟采样，用于计算4小时变化率）
 	m.StartOIMonitoring()
+
+	// 流健康检查：没有健康的流时启动 REST 轮询降级，流恢复后停止
+	m.startStreamHealthWatch()
 }
 
 // subscribeSymbol 注册监听
@@ -390,18 +401,37 @@ func (m *WSMonitor) processKlineUpdate(symbol string, wsData KlineWSData, _time
 	entry := &KlineCacheEntry{
 		Klines:     klines,
 		ReceivedAt: time.Now(),
+		Source:     SourceWebSocket,
 	}
 	klineDataMap.Store(symbol, entry)
 }
 
+// defaultKlineHistory GetCurrentKlines 返回的K线数量（与缓存初始化时拉取的数量一致）
+const defaultKlineHistory = 100
+
+// GetCurrentKlines 获取最近 100 根K线（缓存被 GetKlinesWithLimit 加长后只返回末尾部分，默认行情格式不受影响）
 func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, error) {
+	klines, err := m.loadKlines(symbol, duration)
+	if err != nil {
+		return nil, err
+	}
+	if len(klines) > defaultKlineHistory {
+		klines = klines[len(klines)-defaultKlineHistory:]
+	}
+	return klines, nil
+}
+
+// loadKlines 获取缓存中的全部K线：已订阅且未过期时直接使用 WebSocket 维护的缓存，否则回退 REST
+func (m *WSMonitor) loadKlines(symbol string, duration string) ([]Kline, error) {
 	// 对每一个进来的symbol检测是否存在内类 是否的话就订阅它
 	value, exists := m.getKlineDataMap(duration).Load(symbol)
 	if !exists {
+		klineCacheMisses.Add(1)
 		// 如果Ws数据未初始化完成时,单独使用api获取 - 兼容性代码 (防止在未初始化完成是,已经有交易员运行)
 		apiClient := NewAPIClient()
 		klines, err := apiClient.GetKlines(symbol, duration, 100)
 		if err != nil {
+			klineCacheRESTErrors.Add(1)
 			return nil, fmt.Errorf("获取%v分钟K线失败: %v", duration, err)
 		}
 
@@ -409,6 +439,7 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 		entry := &KlineCacheEntry{
 			Klines:     klines,
 			ReceivedAt: time.Now(),
+			Source:     SourceREST,
 		}
 		m.getKlineDataMap(duration).Store(strings.ToUpper(symbol), entry)
 
@@ -430,14 +461,13 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 	entry := value.(*KlineCacheEntry)
 
 	// ✅ 检查数据新鲜度（防止使用过期数据）
-	// 🔧 P0修复：縮短閾值至 5 分鐘，快速檢測 WebSocket 數據停止
-	// - 3m K线：5分钟 = 不到 2个周期，及时检测问题
-	// - 4h K线：虽然新 K线 4小时才生成，但当前 K线是实时更新的（每秒更新）
-	// 如果 5 分钟內沒有任何更新，WebSocket 很可能已停止工作
+	// 已订阅的K线由 WebSocket 持续更新（当前 K线每秒更新），数据年龄超过阈值说明 WebSocket 可能已停止工作
+	// 阈值 = 过期倍数 × 时间线周期（SetKlineStaleFactor，默认 2 倍；设为 0 时固定 5 分钟）
 	dataAge := time.Since(entry.ReceivedAt)
-	maxAge := 5 * time.Minute
+	maxAge := klineMaxAge(duration)
 
 	if dataAge > maxAge {
+		klineCacheStale.Add(1)
 		// ⚠️ 数据过期，记录警告并尝试 API fallback
 		log.Printf("⚠️ %s 的 %s K线数据已过期 (%.1f 分钟)，WebSocket 可能停止工作，尝试 API fallback",
 			symbol, duration, dataAge.Minutes())
@@ -446,6 +476,7 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 		apiClient := NewAPIClient()
 		freshKlines, err := apiClient.GetKlines(symbol, duration, 100)
 		if err != nil {
+			klineCacheRESTErrors.Add(1)
 			return nil, fmt.Errorf("%s 的 %s K线数据已过期且 API fallback 失败: %v", symbol, duration, err)
 		}
 
@@ -453,6 +484,7 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 		freshEntry := &KlineCacheEntry{
 			Klines:     freshKlines,
 			ReceivedAt: time.Now(),
+			Source:     SourceREST,
 		}
 		m.getKlineDataMap(duration).Store(strings.ToUpper(symbol), freshEntry)
 		log.Printf("✅ %s %s API fallback 成功，已更新緩存 (%d 條數據)", symbol, duration, len(freshKlines))
@@ -463,17 +495,53 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 	}
 
 	// 数据新鲜，返回缓存数据（深拷贝）
+	klineCacheHits.Add(1)
 	klines := entry.Klines
 	result := make([]Kline, len(klines))
 	copy(result, klines)
 	return result, nil
 }
 
+// GetKlinesWithLimit 获取最近 limit 根K线：缓存足够时直接截取，不足时通过 REST 拉取更长的历史并写入缓存
+// （WebSocket 更新时只移除最旧的一根，写入后缓存会保持该长度）
+func (m *WSMonitor) GetKlinesWithLimit(symbol string, duration string, limit int) ([]Kline, error) {
+	klines, err := m.loadKlines(symbol, duration)
+	if err != nil {
+		return nil, err
+	}
+	if len(klines) >= limit {
+		return klines[len(klines)-limit:], nil
+	}
+	if limit > maxKlineFetch {
+		limit = maxKlineFetch
+	}
+
+	longer, err := NewAPIClient().GetKlines(symbol, duration, limit)
+	if err != nil || len(longer) <= len(klines) {
+		if err != nil {
+			log.Printf("⚠️ 获取 %s %s 的 %d 根K线失败，使用缓存中的 %d 根: %v", symbol, duration, limit, len(klines), err)
+		}
+		return klines, nil
+	}
+	m.getKlineDataMap(duration).Store(strings.ToUpper(symbol), &KlineCacheEntry{
+		Klines:     longer,
+		ReceivedAt: time.Now(),
+		Source:     SourceREST,
+	})
+	result := make([]Kline, len(longer))
+	copy(result, longer)
+	return result, nil
+}
+
 func (m *WSMonitor) Close() {
 	// P0修复：停止OI监控goroutine
 	if m.oiStopChan != nil {
 		close(m.oiStopChan)
 	}
+	if m.healthStopChan != nil {
+		close(m.healthStopChan)
+	}
+	m.stopRESTFallback()
 
 	m.wsClient.Close()
 	close(m.alertsChan)
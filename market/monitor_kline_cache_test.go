@@ -411,3 +411,63 @@ func TestWSMonitor_KlineCache_Integration(t *testing.T) {
 		}
 	})
 }
+
+// TestKlineMaxAge 测试K线缓存过期阈值（过期倍数 × 时间线周期）
+func TestKlineMaxAge(t *testing.T) {
+	defer SetKlineStaleFactor(DefaultKlineStaleFactor)
+
+	SetKlineStaleFactor(2)
+	tests := map[string]time.Duration{
+		"3m":  6 * time.Minute,
+		"15m": 30 * time.Minute,
+		"4h":  8 * time.Hour,
+		"2h":  defaultKlineMaxAge, // 未知时间线使用默认阈值
+	}
+	for tf, want := range tests {
+		if got := klineMaxAge(tf); got != want {
+			t.Errorf("klineMaxAge(%s) = %v, 期望 %v", tf, got, want)
+		}
+	}
+
+	// 过期倍数 <= 0 时固定 5 分钟
+	SetKlineStaleFactor(0)
+	if got := klineMaxAge("4h"); got != defaultKlineMaxAge {
+		t.Errorf("过期倍数为 0 时 klineMaxAge(4h) = %v, 期望 %v", got, defaultKlineMaxAge)
+	}
+	SetKlineStaleFactor(-1)
+	if got := GetKlineCacheStats().StaleFactor; got != 0 {
+		t.Errorf("负数过期倍数应被归零，实际 %v", got)
+	}
+}
+
+// TestWSMonitor_GetCurrentKlines_CacheHit 测试新鲜缓存直接命中（不访问 REST）
+func TestWSMonitor_GetCurrentKlines_CacheHit(t *testing.T) {
+	defer SetKlineStaleFactor(DefaultKlineStaleFactor)
+	SetKlineStaleFactor(2)
+
+	m := &WSMonitor{}
+	m.getKlineDataMap("15m").Store("BTCUSDT", &KlineCacheEntry{
+		Klines:     []Kline{{Close: 100}, {Close: 101}},
+		ReceivedAt: time.Now().Add(-20 * time.Minute), // 小于 2 × 15m
+	})
+
+	before := GetKlineCacheStats()
+	klines, err := m.GetCurrentKlines("BTCUSDT", "15m")
+	if err != nil {
+		t.Fatalf("GetCurrentKlines 失败: %v", err)
+	}
+	if len(klines) != 2 || klines[1].Close != 101 {
+		t.Fatalf("返回的K线与缓存不一致: %+v", klines)
+	}
+
+	after := GetKlineCacheStats()
+	if after.Hits != before.Hits+1 {
+		t.Errorf("命中次数 = %d, 期望 %d", after.Hits, before.Hits+1)
+	}
+	if after.Misses != before.Misses || after.Stale != before.Stale {
+		t.Errorf("缓存命中不应计入未命中/过期: %+v → %+v", before, after)
+	}
+	if after.HitRate <= 0 {
+		t.Errorf("命中率应大于 0，实际 %.2f", after.HitRate)
+	}
+}
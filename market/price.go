@@ -0,0 +1,46 @@
+package market
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// priceCacheEntry 最新价缓存项
+type priceCacheEntry struct {
+	Price     float64
+	UpdatedAt time.Time
+}
+
+var (
+	priceCacheMap sync.Map // map[string]*priceCacheEntry
+	priceCacheTTL = 30 * time.Second
+
+	// fetchCurrentPrice 获取最新成交价（测试中可替换）
+	fetchCurrentPrice = func(symbol string) (float64, error) {
+		return NewAPIClient().GetCurrentPrice(symbol)
+	}
+)
+
+// GetPrice 获取交易对最新价（30秒缓存，用于展示换算等不要求实时的场景）
+func GetPrice(symbol string) (float64, error) {
+	symbol = Normalize(symbol)
+
+	if cached, ok := priceCacheMap.Load(symbol); ok {
+		entry := cached.(*priceCacheEntry)
+		if time.Since(entry.UpdatedAt) < priceCacheTTL {
+			return entry.Price, nil
+		}
+	}
+
+	price, err := fetchCurrentPrice(symbol)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s 最新价失败: %w", symbol, err)
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("%s 最新价无效: %.8f", symbol, price)
+	}
+
+	priceCacheMap.Store(symbol, &priceCacheEntry{Price: price, UpdatedAt: time.Now()})
+	return price, nil
+}
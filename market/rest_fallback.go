@@ -0,0 +1,302 @@
+package market
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// K线缓存的数据来源
+const (
+	SourceWebSocket    = "websocket"     // WebSocket 实时推送
+	SourceREST         = "rest"          // 初始化、未订阅或过期时的单次 REST 请求
+	SourceRESTFallback = "rest-fallback" // WebSocket 不可用时的 REST 轮询降级
+)
+
+const (
+	// streamSilenceThreshold 有订阅时超过该时长没有收到消息，视为流不健康
+	streamSilenceThreshold = 90 * time.Second
+	// streamHealthCheckInterval 流健康检查间隔
+	streamHealthCheckInterval = 30 * time.Second
+	// restFallbackBars 轮询时每个序列拉取的最新K线数量（容忍一次轮询失败）
+	restFallbackBars = 5
+	// restFallbackConcurrency 轮询并发数（保守，避免触发 REST 权重限制）
+	restFallbackConcurrency = 3
+)
+
+var (
+	// restFallbackInterval REST 轮询降级的刷新间隔（测试中可替换）
+	restFallbackInterval = time.Minute
+
+	// fallbackKlineFetcher 轮询降级使用的K线接口（测试中可替换）
+	fallbackKlineFetcher = func(symbol, interval string, limit int) ([]Kline, error) {
+		return NewAPIClient().GetKlines(symbol, interval, limit)
+	}
+)
+
+// cachedTimeframes K线缓存支持的全部时间线
+var cachedTimeframes = []string{"1m", "3m", "5m", "15m", "1h", "4h", "1d"}
+
+// restFallback REST 轮询降级状态
+type restFallback struct {
+	mu       sync.Mutex
+	active   bool
+	since    time.Time
+	lastPoll time.Time
+	series   int // 最近一次轮询成功刷新的序列数
+	stopCh   chan struct{}
+	doneCh   chan struct{} // 轮询协程退出时关闭
+}
+
+// MarketDataStatus 行情数据来源状态（健康检查接口）
+type MarketDataStatus struct {
+	StreamHealthy bool   `json:"stream_healthy"`
+	RESTFallback  bool   `json:"rest_fallback"`
+	FallbackSince string `json:"fallback_since,omitempty"`
+	LastPollAt    string `json:"last_poll_at,omitempty"`
+	PolledSeries  int    `json:"polled_series"`
+}
+
+// StreamHealthy WebSocket 行情流是否健康
+func (m *WSMonitor) StreamHealthy() bool {
+	return m.combinedClient != nil && m.combinedClient.Healthy(streamSilenceThreshold)
+}
+
+// startStreamHealthWatch 定期检查流健康状态（立即检查一次，连接失败时马上进入降级）
+func (m *WSMonitor) startStreamHealthWatch() {
+	m.healthStopChan = make(chan struct{})
+	m.checkStreamHealth(m.StreamHealthy())
+
+	go func() {
+		ticker := time.NewTicker(streamHealthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.checkStreamHealth(m.StreamHealthy())
+			case <-m.healthStopChan:
+				return
+			}
+		}
+	}()
+}
+
+// checkStreamHealth 没有健康的流时启动 REST 轮询降级，流恢复后停止
+func (m *WSMonitor) checkStreamHealth(healthy bool) {
+	if healthy {
+		m.stopRESTFallback()
+	} else {
+		m.startRESTFallback()
+	}
+}
+
+// startRESTFallback 启动 REST 轮询（已启动时忽略）
+func (m *WSMonitor) startRESTFallback() {
+	f := &m.fallback
+	f.mu.Lock()
+	if f.active {
+		f.mu.Unlock()
+		return
+	}
+	f.active = true
+	f.since = time.Now()
+	f.stopCh = make(chan struct{})
+	f.doneCh = make(chan struct{})
+	stopCh, doneCh := f.stopCh, f.doneCh
+	interval := restFallbackInterval // 在锁内取值，轮询协程不再读取可变的包级变量
+	f.mu.Unlock()
+
+	log.Printf("⚠️  没有健康的 WebSocket 行情流，启动 REST 轮询降级（每 %v 刷新一次已缓存的K线）", interval)
+
+	go func() {
+		defer close(doneCh)
+		m.pollRESTFallback()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.pollRESTFallback()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// stopRESTFallback 停止 REST 轮询并等待轮询协程退出（未启动时忽略）
+func (m *WSMonitor) stopRESTFallback() {
+	f := &m.fallback
+	f.mu.Lock()
+	if !f.active {
+		f.mu.Unlock()
+		return
+	}
+	close(f.stopCh)
+	f.active = false
+	doneCh := f.doneCh
+	since := f.since
+	f.mu.Unlock()
+
+	// 轮询结束时需要获取 f.mu 更新状态，必须释放锁后再等待
+	<-doneCh
+	log.Printf("✅ WebSocket 行情流已恢复，停止 REST 轮询降级（持续 %v）", time.Since(since).Round(time.Second))
+}
+
+// pollRESTFallback 通过 REST 刷新所有已缓存（即已订阅）的K线序列
+func (m *WSMonitor) pollRESTFallback() {
+	type series struct {
+		symbol, timeframe string
+		klines            []Kline
+	}
+	var all []series
+	for _, tf := range cachedTimeframes {
+		m.getKlineDataMap(tf).Range(func(key, value interface{}) bool {
+			if entry, ok := value.(*KlineCacheEntry); ok {
+				all = append(all, series{symbol: key.(string), timeframe: tf, klines: entry.Klines})
+			}
+			return true
+		})
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	refreshed := 0
+	semaphore := make(chan struct{}, restFallbackConcurrency)
+	for _, s := range all {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(s series) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if err := m.pollSeries(s.symbol, s.timeframe, s.klines); err != nil {
+				log.Printf("⚠️  REST 轮询 %s %s 失败: %v", s.symbol, s.timeframe, err)
+				return
+			}
+			mu.Lock()
+			refreshed++
+			mu.Unlock()
+		}(s)
+	}
+	wg.Wait()
+
+	f := &m.fallback
+	f.mu.Lock()
+	f.lastPoll = time.Now()
+	f.series = refreshed
+	f.mu.Unlock()
+	log.Printf("🔁 REST 轮询降级：已刷新 %d/%d 个K线序列", refreshed, len(all))
+}
+
+// pollSeries 拉取最新几根K线合并进缓存，出现缺口时重新拉取完整历史
+func (m *WSMonitor) pollSeries(symbol, timeframe string, existing []Kline) error {
+	limit := restFallbackBars
+	if len(existing) == 0 {
+		limit = defaultKlineHistory
+	}
+	fresh, err := fallbackKlineFetcher(symbol, timeframe, limit)
+	if err != nil {
+		return err
+	}
+
+	klines, ok := mergeKlines(existing, fresh, timeframeDuration(timeframe))
+	if !ok {
+		if klines, err = fallbackKlineFetcher(symbol, timeframe, defaultKlineHistory); err != nil {
+			return err
+		}
+	}
+
+	m.getKlineDataMap(timeframe).Store(strings.ToUpper(symbol), &KlineCacheEntry{
+		Klines:     klines,
+		ReceivedAt: time.Now(),
+		Source:     SourceRESTFallback,
+	})
+	return nil
+}
+
+// mergeKlines 把最新K线合并进已有序列（更新未收盘的K线、追加新K线，保持原有长度）
+// 新数据与已有序列之间有缺口时返回 false
+func mergeKlines(existing, fresh []Kline, period time.Duration) ([]Kline, bool) {
+	if len(existing) == 0 {
+		return fresh, true
+	}
+	if len(fresh) == 0 {
+		return existing, true
+	}
+	last := existing[len(existing)-1].OpenTime
+	if period > 0 && fresh[0].OpenTime > last+period.Milliseconds() {
+		return nil, false
+	}
+
+	merged := make([]Kline, len(existing), len(existing)+len(fresh))
+	copy(merged, existing)
+	for _, k := range fresh {
+		switch n := len(merged); {
+		case k.OpenTime == merged[n-1].OpenTime:
+			merged[n-1] = k
+		case k.OpenTime > merged[n-1].OpenTime:
+			merged = append(merged, k)
+		}
+	}
+
+	keep := len(existing)
+	if keep < defaultKlineHistory {
+		keep = defaultKlineHistory
+	}
+	if len(merged) > keep {
+		merged = merged[len(merged)-keep:]
+	}
+	return merged, true
+}
+
+// klineSource 缓存中K线的数据来源和接收时间（未缓存时返回空）
+func (m *WSMonitor) klineSource(symbol, timeframe string) (string, time.Time) {
+	value, ok := m.getKlineDataMap(timeframe).Load(strings.ToUpper(symbol))
+	if !ok {
+		return "", time.Time{}
+	}
+	entry, ok := value.(*KlineCacheEntry)
+	if !ok {
+		return "", time.Time{}
+	}
+	return entry.Source, entry.ReceivedAt
+}
+
+// restFallbackStatus REST 轮询降级状态
+func (m *WSMonitor) restFallbackStatus() MarketDataStatus {
+	f := &m.fallback
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := MarketDataStatus{RESTFallback: f.active, PolledSeries: f.series}
+	if f.active {
+		status.FallbackSince = f.since.UTC().Format(time.RFC3339)
+		if !f.lastPoll.IsZero() {
+			status.LastPollAt = f.lastPoll.UTC().Format(time.RFC3339)
+		}
+	}
+	return status
+}
+
+// RESTFallbackActive 行情当前是否由 REST 轮询降级提供（单一数据源，无法做多源价格校验）
+func RESTFallbackActive() bool {
+	m := WSMonitorCli
+	if m == nil {
+		return false
+	}
+	m.fallback.mu.Lock()
+	defer m.fallback.mu.Unlock()
+	return m.fallback.active
+}
+
+// GetMarketDataStatus 获取行情数据来源状态（未启动行情监控时返回零值）
+func GetMarketDataStatus() MarketDataStatus {
+	m := WSMonitorCli
+	if m == nil {
+		return MarketDataStatus{}
+	}
+	status := m.restFallbackStatus()
+	status.StreamHealthy = m.StreamHealthy()
+	return status
+}
@@ -0,0 +1,123 @@
+package market
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testKline(openTime int64, close float64) Kline {
+	return Kline{OpenTime: openTime, Open: close, High: close, Low: close, Close: close}
+}
+
+// TestMergeKlines 测试轮询数据合并：更新未收盘K线、追加新K线、保持长度、检测缺口
+func TestMergeKlines(t *testing.T) {
+	period := 3 * time.Minute
+	step := period.Milliseconds()
+	existing := []Kline{testKline(0, 1), testKline(step, 2), testKline(2*step, 3)}
+
+	merged, ok := mergeKlines(existing, []Kline{testKline(step, 2), testKline(2*step, 3.5), testKline(3*step, 4)}, period)
+	if !ok {
+		t.Fatal("连续的数据不应判定为缺口")
+	}
+	if len(merged) != 4 || merged[2].Close != 3.5 || merged[3].Close != 4 {
+		t.Errorf("应更新最后一根K线并追加新K线: %+v", merged)
+	}
+	if existing[2].Close != 3 {
+		t.Error("合并不应修改原有序列")
+	}
+
+	// 超过保留长度时丢弃最旧的K线
+	long := make([]Kline, defaultKlineHistory)
+	for i := range long {
+		long[i] = testKline(int64(i)*step, float64(i))
+	}
+	merged, _ = mergeKlines(long, []Kline{testKline(int64(defaultKlineHistory)*step, 999)}, period)
+	if len(merged) != defaultKlineHistory || merged[0].OpenTime != step || merged[len(merged)-1].Close != 999 {
+		t.Errorf("应保持 %d 根K线, 实际 %d", defaultKlineHistory, len(merged))
+	}
+
+	if _, ok := mergeKlines(existing, []Kline{testKline(5*step, 6)}, period); ok {
+		t.Error("新数据与已有序列之间有缺口时应返回 false")
+	}
+}
+
+// TestRESTFallbackLifecycle 测试流不健康时启动轮询并写入缓存，流恢复后停止
+func TestRESTFallbackLifecycle(t *testing.T) {
+	origFetcher, origInterval, origCli := fallbackKlineFetcher, restFallbackInterval, WSMonitorCli
+	defer func() {
+		fallbackKlineFetcher, restFallbackInterval, WSMonitorCli = origFetcher, origInterval, origCli
+	}()
+
+	var calls atomic.Int32
+	fallbackKlineFetcher = func(symbol, interval string, limit int) ([]Kline, error) {
+		calls.Add(1)
+		return []Kline{testKline(time.Now().Truncate(3*time.Minute).UnixMilli(), 101)}, nil
+	}
+	restFallbackInterval = time.Hour
+
+	m := &WSMonitor{combinedClient: NewCombinedStreamsClient(10)}
+	WSMonitorCli = m
+	defer m.stopRESTFallback() // 先于恢复全局变量执行，等待轮询协程退出
+	m.getKlineDataMap("3m").Store("BTCUSDT", &KlineCacheEntry{
+		Klines:     []Kline{testKline(time.Now().Truncate(3*time.Minute).UnixMilli(), 100)},
+		ReceivedAt: time.Now().Add(-10 * time.Minute),
+		Source:     SourceWebSocket,
+	})
+
+	if RESTFallbackActive() {
+		t.Fatal("未检测到流异常前不应处于降级状态")
+	}
+
+	m.checkStreamHealth(false)
+	m.checkStreamHealth(false) // 重复检测不应重复启动
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if source, _ := m.klineSource("BTCUSDT", "3m"); source == SourceRESTFallback {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("轮询后缓存应标记为 rest-fallback")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	klines, err := m.GetCurrentKlines("BTCUSDT", "3m")
+	if err != nil || len(klines) != 1 || klines[0].Close != 101 {
+		t.Errorf("market 读取的缓存应为轮询数据: %+v, err=%v", klines, err)
+	}
+
+	status := GetMarketDataStatus()
+	if !RESTFallbackActive() || !status.RESTFallback || status.StreamHealthy || status.FallbackSince == "" {
+		t.Errorf("降级状态不正确: %+v", status)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("应只启动一个轮询协程, 实际请求 %d 次", calls.Load())
+	}
+
+	m.checkStreamHealth(true)
+	if RESTFallbackActive() || GetMarketDataStatus().RESTFallback {
+		t.Error("流恢复后应停止轮询降级")
+	}
+}
+
+// TestCombinedStreamsHealthy 测试未连接的流不健康
+func TestCombinedStreamsHealthy(t *testing.T) {
+	if NewCombinedStreamsClient(10).Healthy(time.Minute) {
+		t.Error("未连接的流不应视为健康")
+	}
+}
+
+// TestFormatRESTFallback 测试轮询降级数据在市场数据中注明来源
+func TestFormatRESTFallback(t *testing.T) {
+	data := &Data{Symbol: "BTCUSDT", Source: SourceRESTFallback, UpdatedAt: time.Now().Add(-30 * time.Second)}
+	if out := Format(data); !strings.Contains(out, "REST polling") {
+		t.Errorf("降级数据应注明来源:\n%s", out)
+	}
+
+	data.Source = SourceWebSocket
+	if out := Format(data); strings.Contains(out, "REST polling") {
+		t.Errorf("实时数据不应注明降级:\n%s", out)
+	}
+}
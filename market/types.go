@@ -16,14 +16,23 @@ type Data struct {
 	CurrentRSI7       float64
 	OpenInterest      *OIData
 	FundingRate       float64
+	Funding           *FundingData    // 资金费率详情（下次结算时间、近期历史），获取失败时为 nil
 	IntradaySeries    *IntradayData   // 3分钟数据 - 实时价格
 	MidTermSeries15m  *MidTermData15m // 15分钟数据 - 短期趋势
 	MidTermSeries1h   *MidTermData1h  // 1小时数据 - 中期趋势
 	LongerTermContext *LongerTermData // 4小时数据 - 长期趋势
 	DailyContext      *DailyData      // 日线数据 - 长期趋势和极端位置判断
 
+	// 交易员配置了 kline_limit / indicators 时使用自定义序列（此时不计算上面的默认序列）
+	Series       []*TimeframeSeries // 各时间线的收盘价和请求的指标（短 → 长）
+	WeeklyPivots *PivotLevels       // 请求了 weekly_pivots 时的周线枢轴点
+
 	// ⚡ 新增：宏觀市場情緒（免費來源：Yahoo Finance API、Alpha Vantage）
 	MarketSentiment *MarketSentiment // VIX 恐慌指數、美股狀態等
+
+	// 当前价格所用K线的数据来源（SourceWebSocket / SourceREST / SourceRESTFallback）和接收时间（新鲜度）
+	Source    string
+	UpdatedAt time.Time
 }
 
 // OIData Open Interest数据
@@ -40,6 +49,21 @@ type OIData struct {
 	Sentiment               string  // 市場情緒簡化標籤："bullish", "bearish", "neutral"
 }
 
+// FundingData 资金费率数据
+type FundingData struct {
+	Source          string            // 数据来源（Binance / Hyperliquid）
+	Rate            float64           // 当前资金费率（每个结算周期，正数表示多头付给空头）
+	NextFundingTime int64             // 下次结算时间（毫秒时间戳）
+	IntervalHours   int               // 结算周期（小时）
+	History         []FundingSnapshot // 最近几次已结算的资金费率（旧 → 新）
+}
+
+// FundingSnapshot 已结算的资金费率
+type FundingSnapshot struct {
+	Rate float64 // 资金费率
+	Time int64   // 结算时间（毫秒时间戳）
+}
+
 // OISnapshot OI历史快照
 type OISnapshot struct {
 	Value     float64   // OI值
@@ -7,9 +7,11 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"nofx/netproxy"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 )
@@ -22,6 +24,25 @@ var (
 	DefaultTimeout = 120 * time.Second
 )
 
+// callLimiter 所有交易员共用的AI请求并发限制（默认不限制）
+var callLimiter netproxy.Limiter
+
+// SetMaxConcurrentCalls 设置同时进行的AI请求数上限（<=0 表示不限制），避免多个交易员同时调用触发服务商限流
+func SetMaxConcurrentCalls(n int) {
+	callLimiter.SetLimit(n)
+}
+
+// requestTimeout 所有AI请求统一的单次请求超时（纳秒，0 表示使用客户端自身的 Timeout）
+var requestTimeout atomic.Int64
+
+// SetRequestTimeout 设置单次AI请求的超时时间（<=0 恢复客户端默认超时），超时按可重试的网络错误处理
+func SetRequestTimeout(timeout time.Duration) {
+	if timeout < 0 {
+		timeout = 0
+	}
+	requestTimeout.Store(int64(timeout))
+}
+
 // Client AI API配置
 type Client struct {
 	Provider   string
@@ -29,8 +50,9 @@ type Client struct {
 	BaseURL    string
 	Model      string
 	Timeout    time.Duration
-	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
-	MaxTokens  int  // AI响应的最大token数
+	UseFullURL bool              // 是否使用完整URL（不添加/chat/completions）
+	MaxTokens  int               // AI响应的最大token数
+	Transport  http.RoundTripper // 出站代理传输（nil 使用默认传输）
 }
 
 func New() AIClient {
@@ -73,10 +95,29 @@ func (client *Client) SetAPIKey(apiKey, apiURL, customModel string) {
 	client.Timeout = 120 * time.Second
 }
 
+// SetTransport 设置发送请求使用的传输（用于出站代理），nil 恢复默认传输
+func (client *Client) SetTransport(transport http.RoundTripper) {
+	client.Transport = transport
+}
+
+// Usage AI API 返回的 token 用量（OpenAI 兼容格式的 usage 字段）
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost,omitempty"` // 部分服务商（如 OpenRouter）直接返回的本次费用（USD）
+}
+
 // CallWithMessages 使用 system + user prompt 调用AI API（推荐）
 func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	result, _, err := client.CallWithUsage(systemPrompt, userPrompt)
+	return result, err
+}
+
+// CallWithUsage 调用AI API并返回 token 用量（只统计最终成功的那次请求）
+func (client *Client) CallWithUsage(systemPrompt, userPrompt string) (string, *Usage, error) {
 	if client.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
+		return "", nil, fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
 	}
 
 	// Token 限制檢查（第一次調用時檢查）
@@ -91,18 +132,20 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 			fmt.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...\n", attempt, maxRetries)
 		}
 
-		result, err := client.callOnce(systemPrompt, userPrompt)
+		release := callLimiter.Acquire()
+		result, usage, err := client.callOnce(systemPrompt, userPrompt)
+		release()
 		if err == nil {
 			if attempt > 1 {
 				fmt.Printf("✓ AI API重试成功\n")
 			}
-			return result, nil
+			return result, usage, nil
 		}
 
 		lastErr = err
 		// 如果不是网络错误，不重试
 		if !isRetryableError(err) {
-			return "", err
+			return "", nil, err
 		}
 
 		// 重试前等待
@@ -113,7 +156,15 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 		}
 	}
 
-	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+	return "", nil, fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+}
+
+// effectiveTimeout 单次请求的超时：优先使用 SetRequestTimeout 的全局设置
+func (client *Client) effectiveTimeout() time.Duration {
+	if timeout := time.Duration(requestTimeout.Load()); timeout > 0 {
+		return timeout
+	}
+	return client.Timeout
 }
 
 func (client *Client) setAuthHeader(reqHeader http.Header) {
@@ -121,7 +172,7 @@ func (client *Client) setAuthHeader(reqHeader http.Header) {
 }
 
 // callOnce 单次调用AI API（内部使用）
-func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
+func (client *Client) callOnce(systemPrompt, userPrompt string) (string, *Usage, error) {
 	// 打印当前 AI 配置
 	log.Printf("📡 [MCP] AI 请求配置:")
 	log.Printf("   Provider: %s", client.Provider)
@@ -162,7 +213,7 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return "", nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
 	// 创建HTTP请求
@@ -178,7 +229,7 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return "", nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -186,21 +237,21 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 	client.setAuthHeader(req.Header)
 
 	// 发送请求
-	httpClient := &http.Client{Timeout: client.Timeout}
+	httpClient := &http.Client{Timeout: client.effectiveTimeout(), Transport: client.Transport}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return "", nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return "", nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	// 解析响应
@@ -210,17 +261,21 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage *Usage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+		return "", nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API返回空响应")
+		return "", nil, fmt.Errorf("API返回空响应")
 	}
 
-	return result.Choices[0].Message.Content, nil
+	if result.Usage != nil && result.Usage.TotalTokens == 0 {
+		result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	}
+	return result.Choices[0].Message.Content, result.Usage, nil
 }
 
 // isRetryableError 判断错误是否可重试
@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"nofx/netproxy"
 	"os"
 	"strings"
 	"testing"
@@ -341,6 +342,30 @@ func TestCallWithMessages_Success(t *testing.T) {
 	}
 }
 
+// TestCallWithUsage_ParsesUsage 测试解析响应中的 token 用量和服务商返回的费用
+func TestCallWithUsage_ParsesUsage(t *testing.T) {
+	mockServer := startMCPTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":1200,"completion_tokens":300,"cost":0.0021}}`))
+	}))
+	defer mockServer.Close()
+
+	client := &Client{APIKey: "test-key", BaseURL: mockServer.URL, Model: "test-model", Timeout: 5 * time.Second}
+	result, usage, err := client.CallWithUsage("system", "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "ok" {
+		t.Errorf("expected 'ok', got %q", result)
+	}
+	if usage == nil {
+		t.Fatal("expected usage to be parsed")
+	}
+	if usage.PromptTokens != 1200 || usage.CompletionTokens != 300 || usage.TotalTokens != 1500 || usage.Cost != 0.0021 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
 // =============================================================================
 // Test 6: AI API Call (Missing API Key)
 // =============================================================================
@@ -397,6 +422,25 @@ func TestCallWithMessages_Timeout(t *testing.T) {
 	}
 }
 
+func TestSetRequestTimeout(t *testing.T) {
+	defer SetRequestTimeout(0)
+
+	client := &Client{Timeout: 120 * time.Second}
+	if got := client.effectiveTimeout(); got != 120*time.Second {
+		t.Errorf("expected client timeout 120s without global setting, got %v", got)
+	}
+
+	SetRequestTimeout(60 * time.Second)
+	if got := client.effectiveTimeout(); got != 60*time.Second {
+		t.Errorf("expected global timeout 60s, got %v", got)
+	}
+
+	SetRequestTimeout(-1)
+	if got := client.effectiveTimeout(); got != 120*time.Second {
+		t.Errorf("expected non-positive timeout to restore client timeout, got %v", got)
+	}
+}
+
 // =============================================================================
 // Test 8: AI API Call (HTTP Error Status)
 // =============================================================================
@@ -697,6 +741,50 @@ func TestCallWithMessages_EmptyPrompts(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// Test 14: Outbound Proxy
+// =============================================================================
+
+func TestCallWithMessages_UsesProxyTransport(t *testing.T) {
+	var proxiedHost string
+	// A forward proxy receives the absolute target URL; answer on behalf of the AI API
+	proxyServer := startMCPTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{"content": "via proxy"}},
+			},
+		})
+	}))
+	defer proxyServer.Close()
+
+	transport, err := netproxy.NewTransport(proxyServer.URL)
+	if err != nil {
+		t.Fatalf("failed to create proxy transport: %v", err)
+	}
+
+	client := &Client{
+		Provider:  ProviderDeepSeek,
+		APIKey:    "test-key-1234567890",
+		BaseURL:   "http://api.deepseek.invalid/v1",
+		Model:     "test-model",
+		Timeout:   5 * time.Second,
+		MaxTokens: 2000,
+	}
+	client.SetTransport(transport)
+
+	result, err := client.CallWithMessages("system", "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "via proxy" {
+		t.Errorf("expected response from proxy, got: %s", result)
+	}
+	if proxiedHost != "api.deepseek.invalid" {
+		t.Errorf("expected request for api.deepseek.invalid to go through proxy, got host %q", proxiedHost)
+	}
+}
+
 // =============================================================================
 // Benchmark Tests
 // =============================================================================
@@ -7,6 +7,10 @@ type AIClient interface {
 	SetAPIKey(apiKey string, customURL string, customModel string)
 	// CallWithMessages 使用 system + user prompt 调用AI API
 	CallWithMessages(systemPrompt, userPrompt string) (string, error)
+	// CallWithUsage 与 CallWithMessages 相同，同时返回本次调用的 token 用量（服务端未返回时为 nil）
+	CallWithUsage(systemPrompt, userPrompt string) (string, *Usage, error)
+	// SetTransport 设置发送请求使用的传输（用于出站代理）
+	SetTransport(transport http.RoundTripper)
 
 	setAuthHeader(reqHeaders http.Header)
 }
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxTraderSeries 最多导出的交易员数量，超出后新交易员不再导出，避免标签基数无限增长
+const MaxTraderSeries = 1000
+
+// ContentType Prometheus 文本格式
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// TraderGauges 单个交易员的交易指标（每个交易周期更新一次）
+type TraderGauges struct {
+	TraderID      string
+	UserID        string
+	Equity        float64 // 账户净值（USDT）
+	TotalPnL      float64 // 总盈亏（USDT，相对初始余额）
+	TotalPnLPct   float64 // 总盈亏百分比
+	PositionCount int     // 当前持仓数
+	DrawdownPct   float64 // 相对峰值净值的回撤百分比
+	UpdatedAt     time.Time
+}
+
+// gauge 一个导出的指标定义
+type gauge struct {
+	name  string
+	help  string
+	value func(g TraderGauges) float64
+}
+
+var gauges = []gauge{
+	{"nofx_trader_equity_usdt", "Total account equity in USDT.", func(g TraderGauges) float64 { return g.Equity }},
+	{"nofx_trader_pnl_usdt", "Total PnL in USDT relative to the initial balance.", func(g TraderGauges) float64 { return g.TotalPnL }},
+	{"nofx_trader_pnl_percent", "Total PnL as a percentage of the initial balance.", func(g TraderGauges) float64 { return g.TotalPnLPct }},
+	{"nofx_trader_open_positions", "Number of open positions.", func(g TraderGauges) float64 { return float64(g.PositionCount) }},
+	{"nofx_trader_drawdown_percent", "Drawdown from peak equity in percent.", func(g TraderGauges) float64 { return g.DrawdownPct }},
+	{"nofx_trader_last_update_timestamp_seconds", "Unix time of the last trading cycle that updated these gauges.", func(g TraderGauges) float64 { return float64(g.UpdatedAt.Unix()) }},
+}
+
+var registry = struct {
+	mu       sync.RWMutex
+	traders  map[string]TraderGauges
+	warnedAt time.Time
+}{traders: make(map[string]TraderGauges)}
+
+// SetTrader 更新交易员指标；交易员数量达到上限时忽略新交易员
+func SetTrader(g TraderGauges) {
+	if g.TraderID == "" {
+		return
+	}
+	if g.UpdatedAt.IsZero() {
+		g.UpdatedAt = time.Now()
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, exists := registry.traders[g.TraderID]; !exists && len(registry.traders) >= MaxTraderSeries {
+		// 限频告警，避免每个周期刷屏
+		if time.Since(registry.warnedAt) > time.Hour {
+			registry.warnedAt = time.Now()
+			log.Printf("⚠️ 交易指标已达到 %d 个交易员上限，忽略交易员 %s", MaxTraderSeries, g.TraderID)
+		}
+		return
+	}
+	registry.traders[g.TraderID] = g
+}
+
+// RemoveTrader 删除交易员的指标（交易员被删除时调用）
+func RemoveTrader(traderID string) {
+	registry.mu.Lock()
+	delete(registry.traders, traderID)
+	registry.mu.Unlock()
+}
+
+// WriteText 以 Prometheus 文本格式输出全部交易员指标
+func WriteText(w io.Writer) error {
+	registry.mu.RLock()
+	snapshot := make([]TraderGauges, 0, len(registry.traders))
+	for _, g := range registry.traders {
+		snapshot = append(snapshot, g)
+	}
+	registry.mu.RUnlock()
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].TraderID < snapshot[j].TraderID })
+
+	bw := bufio.NewWriter(w)
+	for _, m := range gauges {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, g := range snapshot {
+			fmt.Fprintf(bw, "%s{trader_id=\"%s\",user_id=\"%s\"} %s\n",
+				m.name, escapeLabel(g.TraderID), escapeLabel(g.UserID), strconv.FormatFloat(m.value(g), 'g', -1, 64))
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler 返回 Prometheus 抓取接口
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := WriteText(w); err != nil {
+			log.Printf("⚠️ 输出交易指标失败: %v", err)
+		}
+	})
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号和换行
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
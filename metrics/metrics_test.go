@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestScrapeTraderGauges 测试通过 HTTP 抓取交易员指标
+func TestScrapeTraderGauges(t *testing.T) {
+	defer RemoveTrader("trader_a")
+	defer RemoveTrader(`trader"b`)
+
+	SetTrader(TraderGauges{TraderID: "trader_a", UserID: "user1", Equity: 1050.5, TotalPnL: 50.5, TotalPnLPct: 5.05, PositionCount: 2, DrawdownPct: 1.25, UpdatedAt: time.Unix(1700000000, 0)})
+	SetTrader(TraderGauges{TraderID: `trader"b`, UserID: "user2", Equity: 900})
+
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("抓取失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type 不正确: %s", ct)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	body := string(raw)
+
+	for _, want := range []string{
+		"# TYPE nofx_trader_equity_usdt gauge",
+		`nofx_trader_equity_usdt{trader_id="trader_a",user_id="user1"} 1050.5`,
+		`nofx_trader_pnl_usdt{trader_id="trader_a",user_id="user1"} 50.5`,
+		`nofx_trader_pnl_percent{trader_id="trader_a",user_id="user1"} 5.05`,
+		`nofx_trader_open_positions{trader_id="trader_a",user_id="user1"} 2`,
+		`nofx_trader_drawdown_percent{trader_id="trader_a",user_id="user1"} 1.25`,
+		`nofx_trader_last_update_timestamp_seconds{trader_id="trader_a",user_id="user1"} 1.7e+09`,
+		`nofx_trader_equity_usdt{trader_id="trader\"b",user_id="user2"} 900`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("缺少指标行 %q\n%s", want, body)
+		}
+	}
+
+	// 删除后不再导出
+	RemoveTrader("trader_a")
+	var sb strings.Builder
+	if err := WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sb.String(), `trader_id="trader_a"`) {
+		t.Error("删除后不应再导出 trader_a")
+	}
+}
+
+// TestTraderSeriesBounded 测试交易员数量达到上限后忽略新交易员，已有交易员仍可更新
+func TestTraderSeriesBounded(t *testing.T) {
+	ids := make([]string, 0, MaxTraderSeries)
+	defer func() {
+		for _, id := range ids {
+			RemoveTrader(id)
+		}
+	}()
+	for i := 0; i < MaxTraderSeries; i++ {
+		id := fmt.Sprintf("bounded_%d", i)
+		ids = append(ids, id)
+		SetTrader(TraderGauges{TraderID: id, Equity: 1})
+	}
+
+	SetTrader(TraderGauges{TraderID: "overflow", Equity: 1})
+	SetTrader(TraderGauges{TraderID: "bounded_0", Equity: 2})
+
+	registry.mu.RLock()
+	_, overflow := registry.traders["overflow"]
+	updated := registry.traders["bounded_0"].Equity
+	registry.mu.RUnlock()
+	if overflow {
+		t.Error("超出上限的交易员不应导出")
+	}
+	if updated != 2 {
+		t.Errorf("已有交易员应继续更新, 实际 %.0f", updated)
+	}
+}
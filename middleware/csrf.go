@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"strings"
 
+	"nofx/i18n"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -118,10 +120,7 @@ func CSRFMiddleware(config CSRFConfig) gin.HandlerFunc {
 		cookieToken, err := c.Cookie(config.CookieName)
 		if err != nil {
 			log.Printf("🚨 [CSRF] IP %s 缺少 CSRF Cookie (路径: %s)", c.ClientIP(), path)
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "CSRF token missing in cookie",
-			})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusForbidden, i18n.ErrorBody(i18n.RequestLang(c.Request), "CSRF_COOKIE_MISSING"))
 			return
 		}
 
@@ -129,20 +128,14 @@ func CSRFMiddleware(config CSRFConfig) gin.HandlerFunc {
 		headerToken := c.GetHeader(config.HeaderName)
 		if headerToken == "" {
 			log.Printf("🚨 [CSRF] IP %s 缺少 CSRF Header (路径: %s)", c.ClientIP(), path)
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "CSRF token missing in header",
-			})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusForbidden, i18n.ErrorBody(i18n.RequestLang(c.Request), "CSRF_HEADER_MISSING"))
 			return
 		}
 
 		// 验证 Token 是否一致
 		if cookieToken != headerToken {
 			log.Printf("🚨 [CSRF] IP %s Token 不匹配 (路径: %s)", c.ClientIP(), path)
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "CSRF token mismatch",
-			})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusForbidden, i18n.ErrorBody(i18n.RequestLang(c.Request), "CSRF_TOKEN_MISMATCH"))
 			return
 		}
 
@@ -72,11 +72,13 @@ func TestCSRFMiddleware_POSTWithoutToken(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/api/test", nil)
+	req.Header.Set("Accept-Language", "en")
 	router.ServeHTTP(w, req)
 
 	// 没有 Token 的 POST 请求应该被拒绝
 	assert.Equal(t, http.StatusForbidden, w.Code, "没有 Token 应该返回 403")
 	assert.Contains(t, w.Body.String(), "CSRF token missing", "应该返回 CSRF 错误")
+	assert.Contains(t, w.Body.String(), `"code":"CSRF_COOKIE_MISSING"`, "应该返回稳定的错误码")
 }
 
 // TestCSRFMiddleware_POSTWithValidToken 测试带有有效 Token 的 POST 请求
@@ -124,6 +126,7 @@ func TestCSRFMiddleware_POSTWithMismatchedToken(t *testing.T) {
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/api/test", nil)
 	req.Header.Set(config.HeaderName, "wrong-token-in-header")
+	req.Header.Set("Accept-Language", "en")
 	req.AddCookie(&http.Cookie{
 		Name:  config.CookieName,
 		Value: "different-token-in-cookie",
@@ -133,6 +136,7 @@ func TestCSRFMiddleware_POSTWithMismatchedToken(t *testing.T) {
 	// 应该被拒绝
 	assert.Equal(t, http.StatusForbidden, w.Code, "Token 不匹配应该返回 403")
 	assert.Contains(t, w.Body.String(), "mismatch", "应该返回 Token 不匹配错误")
+	assert.Contains(t, w.Body.String(), `"code":"CSRF_TOKEN_MISMATCH"`, "应该返回稳定的错误码")
 }
 
 // TestCSRFMiddleware_ExemptPaths 测试豁免路径
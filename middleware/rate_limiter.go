@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"nofx/i18n"
+
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
@@ -71,11 +73,7 @@ func RateLimitMiddleware(limiter *IPRateLimiter) gin.HandlerFunc {
 		l := limiter.GetLimiter(ip)
 		if !l.Allow() {
 			log.Printf("⚠️ [RATE_LIMIT] IP %s 请求过于频繁 (全局限制)", ip)
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       "请求过于频繁，请稍后再试",
-				"retry_after": 60,
-			})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody(c, "RATE_LIMITED", 60))
 			return
 		}
 
@@ -97,11 +95,7 @@ func AuthRateLimitMiddleware() gin.HandlerFunc {
 		l := limiter.GetLimiter(ip)
 		if !l.Allow() {
 			log.Printf("🚨 [RATE_LIMIT] IP %s 登录尝试频率过高 (认证限制)", ip)
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       "登录尝试次数过多，请 30 秒后重试",
-				"retry_after": 30,
-			})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody(c, "AUTH_RATE_LIMITED", 30))
 			return
 		}
 
@@ -121,14 +115,17 @@ func StrictRateLimitMiddleware(seconds int, maxRequests int) gin.HandlerFunc {
 		l := limiter.GetLimiter(ip)
 		if !l.Allow() {
 			log.Printf("⚠️ [RATE_LIMIT] IP %s 触发严格限制 (%d 秒 %d 次)", ip, seconds, maxRequests)
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       "操作过于频繁，请稍后再试",
-				"retry_after": seconds,
-			})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody(c, "STRICT_RATE_LIMITED", seconds))
 			return
 		}
 
 		c.Next()
 	}
 }
+
+// rateLimitedBody 限流响应体：本地化错误信息 + 建议的重试等待秒数
+func rateLimitedBody(c *gin.Context, code string, retryAfter int) gin.H {
+	body := gin.H(i18n.ErrorBody(i18n.RequestLang(c.Request), code))
+	body["retry_after"] = retryAfter
+	return body
+}
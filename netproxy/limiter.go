@@ -0,0 +1,85 @@
+package netproxy
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Limiter 并发请求限制（信号量），限制为 0 表示不限制
+// 多个交易员共用同一个 Limiter，避免同一时刻集中请求交易所/AI API 触发限流
+type Limiter struct {
+	mu  sync.Mutex
+	sem chan struct{}
+}
+
+// SetLimit 设置最大并发数（<=0 表示不限制），已占用的名额在释放前仍计入旧的限制
+func (l *Limiter) SetLimit(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n <= 0 {
+		l.sem = nil
+		return
+	}
+	if l.sem != nil && cap(l.sem) == n {
+		return
+	}
+	l.sem = make(chan struct{}, n)
+}
+
+// Limit 当前最大并发数（0 表示不限制）
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return cap(l.sem)
+}
+
+// Acquire 占用一个名额（没有空闲名额时阻塞），返回释放函数（可重复调用）
+func (l *Limiter) Acquire() (release func()) {
+	l.mu.Lock()
+	sem := l.sem
+	l.mu.Unlock()
+	if sem == nil {
+		return func() {}
+	}
+	sem <- struct{}{}
+	var once sync.Once
+	return func() { once.Do(func() { <-sem }) }
+}
+
+// LimitTransport 包装 RoundTripper：每个请求在响应体关闭前占用 Limiter 的一个名额
+// base 为 nil 时使用 http.DefaultTransport
+func LimitTransport(base http.RoundTripper, l *Limiter) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &limitedTransport{base: base, limiter: l}
+}
+
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *Limiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release := t.limiter.Acquire()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody 响应体关闭时释放名额
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,75 @@
+// Package netproxy 出站代理配置（访问交易所和AI API时使用，与入站的 TRUST_PROXY 无关）
+package netproxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// EnvOutboundProxy 全局出站代理环境变量（用户未单独配置时使用）
+const EnvOutboundProxy = "OUTBOUND_PROXY"
+
+// supportedSchemes 支持的代理协议
+var supportedSchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// Parse 校验并解析代理地址，只接受 http/https/socks5/socks5h 且必须包含主机和端口
+func Parse(rawURL string) (*url.URL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址格式错误: %w", err)
+	}
+	if !supportedSchemes[strings.ToLower(u.Scheme)] {
+		return nil, fmt.Errorf("不支持的代理协议: %q（可选 http/https/socks5/socks5h）", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("代理地址必须包含主机和端口，例如 socks5://127.0.0.1:1080")
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("代理地址不能包含路径: %s", u.Path)
+	}
+	return u, nil
+}
+
+// Resolve 返回实际使用的代理地址：用户配置优先，否则使用 OUTBOUND_PROXY 环境变量
+func Resolve(userProxy string) string {
+	if proxy := strings.TrimSpace(userProxy); proxy != "" {
+		return proxy
+	}
+	return strings.TrimSpace(os.Getenv(EnvOutboundProxy))
+}
+
+// NewTransport 创建走代理的 http.Transport（基于默认 Transport 的连接池和超时配置）
+// 代理地址为空时返回 nil，调用方保持原有的默认传输（仍遵循标准的 HTTP(S)_PROXY 环境变量）
+func NewTransport(proxyURL string) (*http.Transport, error) {
+	if strings.TrimSpace(proxyURL) == "" {
+		return nil, nil
+	}
+	u, err := Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(u)
+	return transport, nil
+}
+
+// Redact 隐藏代理地址中的密码，用于日志和接口返回
+func Redact(proxyURL string) string {
+	u, err := url.Parse(strings.TrimSpace(proxyURL))
+	if err != nil || u.User == nil {
+		return proxyURL
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), "****")
+	}
+	return u.String()
+}
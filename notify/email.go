@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 邮件发送配置
+const (
+	defaultMailQueueSize = 100
+	defaultSMTPTimeout   = 15 * time.Second
+	smtpImplicitTLSPort  = 465 // SMTPS：连接即 TLS；其他端口在服务器支持时使用 STARTTLS
+)
+
+// SMTPConfig SMTP 发件配置
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // 为空时不认证
+	Password string
+	From     string // 发件人地址（可带显示名，如 "NOFX <noreply@example.com>"）
+}
+
+// Configured 是否已配置可用的 SMTP 服务器
+func (c *SMTPConfig) Configured() bool {
+	return c != nil && c.Host != "" && c.From != ""
+}
+
+// addr SMTP 服务器地址（未配置端口时使用 587）
+func (c *SMTPConfig) addr() string {
+	port := c.Port
+	if port <= 0 {
+		port = 587
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// Email 一封纯文本邮件
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type mailJob struct {
+	cfg   SMTPConfig
+	email Email
+}
+
+// sendFunc 发送一封已编码的邮件（测试中替换为内存实现）
+type sendFunc func(cfg *SMTPConfig, to string, msg []byte) error
+
+// Mailer 异步发送邮件
+// 有界队列 + 单个发送协程，失败按指数退避重试；慢速 SMTP 服务器不会阻塞认证接口
+type Mailer struct {
+	send           sendFunc
+	maxRetries     int
+	initialBackoff time.Duration
+
+	queue     chan mailJob
+	startOnce sync.Once
+}
+
+// NewMailer 创建邮件发送器（send 为空时通过 SMTP 发送）
+func NewMailer(send sendFunc, maxRetries int, initialBackoff time.Duration) *Mailer {
+	if send == nil {
+		send = sendSMTP
+	}
+	if maxRetries < 0 {
+		maxRetries = defaultMaxRetries
+	}
+	if initialBackoff <= 0 {
+		initialBackoff = defaultInitialBackoff
+	}
+	return &Mailer{
+		send:           send,
+		maxRetries:     maxRetries,
+		initialBackoff: initialBackoff,
+		queue:          make(chan mailJob, defaultMailQueueSize),
+	}
+}
+
+// DefaultMailer 默认邮件发送器
+var DefaultMailer = NewMailer(nil, defaultMaxRetries, defaultInitialBackoff)
+
+// Enqueue 将邮件放入发送队列（非阻塞），未配置 SMTP、收件人无效或队列已满时返回 false
+func (m *Mailer) Enqueue(cfg *SMTPConfig, email Email) bool {
+	if !cfg.Configured() {
+		return false
+	}
+	if err := validateHeaderValue(email.To); err != nil {
+		log.Printf("⚠️ 邮件收件人无效: %v", err)
+		return false
+	}
+	m.startOnce.Do(m.start)
+
+	select {
+	case m.queue <- mailJob{cfg: *cfg, email: email}:
+		return true
+	default:
+		log.Printf("⚠️ 邮件发送队列已满，丢弃邮件 [%s]", email.Subject)
+		return false
+	}
+}
+
+// start 启动发送协程
+func (m *Mailer) start() {
+	go func() {
+		for j := range m.queue {
+			if err := m.Send(&j.cfg, j.email); err != nil {
+				log.Printf("⚠️ 邮件发送失败 [%s]: %v", j.email.To, err)
+			}
+		}
+	}()
+}
+
+// Send 同步发送邮件，失败时按指数退避重试
+func (m *Mailer) Send(cfg *SMTPConfig, email Email) error {
+	if !cfg.Configured() {
+		return fmt.Errorf("未配置 SMTP 服务器")
+	}
+	msg, err := buildMessage(cfg.From, email)
+	if err != nil {
+		return err
+	}
+
+	backoff := m.initialBackoff
+	var lastErr error
+	for attempt := 0; attempt <= m.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if lastErr = m.send(cfg, email.To, msg); lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("重试 %d 次后仍失败: %w", m.maxRetries, lastErr)
+}
+
+// validateHeaderValue 拒绝包含换行的邮件头（防止邮件头注入）
+func validateHeaderValue(v string) error {
+	if v == "" || strings.ContainsAny(v, "\r\n") {
+		return fmt.Errorf("无效的邮件头: %q", v)
+	}
+	return nil
+}
+
+// buildMessage 编码邮件（UTF-8 纯文本，主题按 RFC 2047 编码，正文 base64）
+func buildMessage(from string, email Email) ([]byte, error) {
+	for _, v := range []string{from, email.To} {
+		if err := validateHeaderValue(v); err != nil {
+			return nil, err
+		}
+	}
+	if strings.ContainsAny(email.Subject, "\r\n") {
+		return nil, fmt.Errorf("邮件主题不能包含换行")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", email.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", email.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(email.Body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes(), nil
+}
+
+// sendSMTP 通过 SMTP 发送一封邮件（465 端口使用 TLS 直连，其他端口在服务器支持时升级 STARTTLS）
+func sendSMTP(cfg *SMTPConfig, to string, msg []byte) error {
+	dialer := &net.Dialer{Timeout: defaultSMTPTimeout}
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	var conn net.Conn
+	var err error
+	if cfg.Port == smtpImplicitTLSPort {
+		conn, err = tls.DialWithDialer(dialer, "tcp", cfg.addr(), tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", cfg.addr())
+	}
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(defaultSMTPTimeout))
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP 握手失败: %w", err)
+	}
+	defer client.Close()
+
+	if cfg.Port != smtpImplicitTLSPort {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("STARTTLS 失败: %w", err)
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %w", err)
+		}
+	}
+
+	from := cfg.From
+	if addr, err := parseAddress(cfg.From); err == nil {
+		from = addr
+	}
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("MAIL FROM 失败: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("RCPT TO 失败: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA 失败: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("写入邮件内容失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("提交邮件失败: %w", err)
+	}
+	return client.Quit()
+}
+
+// parseAddress 从 "显示名 <地址>" 中取出邮箱地址
+func parseAddress(from string) (string, error) {
+	start, end := strings.LastIndex(from, "<"), strings.LastIndex(from, ">")
+	if start < 0 || end <= start+1 {
+		return "", fmt.Errorf("无显示名")
+	}
+	return from[start+1 : end], nil
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMailerEnqueueRetries 测试邮件异步发送：失败后重试，未配置 SMTP 或收件人含换行时不入队
+func TestMailerEnqueueRetries(t *testing.T) {
+	var attempts int32
+	delivered := make(chan []byte, 1)
+	m := NewMailer(func(cfg *SMTPConfig, to string, msg []byte) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return errors.New("421 服务暂不可用")
+		}
+		delivered <- msg
+		return nil
+	}, 2, time.Millisecond)
+	cfg := &SMTPConfig{Host: "smtp.example.com", Port: 587, From: "NOFX <noreply@example.com>"}
+
+	if m.Enqueue(&SMTPConfig{Host: "smtp.example.com"}, Email{To: "a@example.com"}) {
+		t.Error("未配置发件人时不应入队")
+	}
+	if m.Enqueue(cfg, Email{To: "a@example.com\r\nBcc: b@example.com"}) {
+		t.Error("收件人包含换行时不应入队")
+	}
+	if !m.Enqueue(cfg, Email{To: "a@example.com", Subject: "验证邮箱", Body: "点击链接完成验证"}) {
+		t.Fatal("已配置 SMTP 时应入队")
+	}
+
+	select {
+	case msg := <-delivered:
+		text := string(msg)
+		if !strings.Contains(text, "To: a@example.com\r\n") || !strings.Contains(text, "Subject: =?UTF-8?q?") {
+			t.Errorf("邮件头不正确:\n%s", text)
+		}
+		body := text[strings.Index(text, "\r\n\r\n")+4:]
+		decoded, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\r\n", ""))
+		if string(decoded) != "点击链接完成验证" {
+			t.Errorf("邮件正文不正确: %q", decoded)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("超时未发送邮件")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("失败后应重试一次, 实际发送 %d 次", got)
+	}
+}
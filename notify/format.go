@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"fmt"
+	"nofx/webhook"
+	"sort"
+	"strings"
+)
+
+// maxReasonRunes 消息中AI理由最多保留的字符数
+const maxReasonRunes = 200
+
+// Excerpt 截取文本前 n 个字符（按 rune 截断，超出时追加省略号）
+func Excerpt(s string, n int) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// closeReasonNames 被动平仓原因的中文名称
+var closeReasonNames = map[string]string{
+	"stop_loss":   "止损",
+	"take_profit": "止盈",
+	"liquidation": "强平",
+	"unknown":     "未知",
+}
+
+// FormatEvent 将交易事件格式化为 Telegram 纯文本消息
+func FormatEvent(event *webhook.Event) string {
+	d := event.Data
+	traderName, _ := d["trader_name"].(string)
+	if traderName == "" {
+		traderName = event.TraderID
+	}
+
+	var b strings.Builder
+	switch event.Type {
+	case webhook.EventOpen:
+		fmt.Fprintf(&b, "🟢 [%s] 开%s %s\n", traderName, sideName(d["side"]), d["symbol"])
+		fmt.Fprintf(&b, "数量: %s @ %s | 杠杆: %vx\n", num(d["quantity"]), num(d["price"]), d["leverage"])
+		fmt.Fprintf(&b, "止损: %s | 止盈: %s", num(d["stop_loss"]), num(d["take_profit"]))
+		writeReason(&b, d["reasoning"])
+
+	case webhook.EventClose:
+		title := "平"
+		if partial, _ := d["partial"].(bool); partial {
+			title = "部分平"
+		}
+		reason, _ := d["reason"].(string)
+		if passive, _ := d["passive"].(bool); passive {
+			name := closeReasonNames[reason]
+			if name == "" {
+				name = reason
+			}
+			title = "被动平仓（" + name + "）"
+			reason = ""
+		}
+		icon := "🔴"
+		if pnl, ok := d["pnl"].(float64); ok && pnl > 0 {
+			icon = "✅"
+		}
+		fmt.Fprintf(&b, "%s [%s] %s%s %s\n", icon, traderName, title, sideName(d["side"]), d["symbol"])
+		fmt.Fprintf(&b, "数量: %s | 开仓: %s → 平仓: %s", num(d["quantity"]), num(d["entry_price"]), num(d["exit_price"]))
+		if lev, ok := d["leverage"]; ok {
+			fmt.Fprintf(&b, " | 杠杆: %vx", lev)
+		}
+		fmt.Fprintf(&b, "\n盈亏: %+.2f USDT", toFloat(d["pnl"]))
+		writeReason(&b, reason)
+
+	case webhook.EventRiskStop:
+		fmt.Fprintf(&b, "⛔ [%s] 触发风控暂停\n原因: %v\n暂停: %v | 恢复时间: %v", traderName, d["reason"], d["pause"], d["resume_at"])
+
+	default:
+		fmt.Fprintf(&b, "📣 [%s] %s", traderName, event.Type)
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			if k != "trader_name" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "\n%s: %s", k, num(d[k]))
+		}
+	}
+	return b.String()
+}
+
+// writeReason 追加AI理由摘要
+func writeReason(b *strings.Builder, reason interface{}) {
+	if s, _ := reason.(string); strings.TrimSpace(s) != "" {
+		fmt.Fprintf(b, "\n理由: %s", Excerpt(s, maxReasonRunes))
+	}
+}
+
+// sideName 方向的中文名称
+func sideName(side interface{}) string {
+	switch side {
+	case "long":
+		return "多"
+	case "short":
+		return "空"
+	}
+	return fmt.Sprint(side)
+}
+
+// toFloat 将数值字段转为 float64（不是数值时返回 0）
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
+
+// num 格式化数值字段（浮点数去掉多余的 0，非数值原样输出）
+func num(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.6f", f), "0"), ".")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,264 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// 默认发送配置
+const (
+	defaultBaseURL        = "https://api.telegram.org"
+	defaultQueueSize      = 100
+	defaultWorkers        = 4
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = 2 * time.Second
+	defaultTimeout        = 10 * time.Second
+	defaultChatInterval   = time.Second      // Telegram 限制同一聊天约每秒 1 条
+	maxRetryAfter         = 30 * time.Second // 429 时最多等待多久
+	maxMessageRunes       = 4000             // Telegram 单条消息上限 4096 字符
+)
+
+// TelegramConfig 用户的 Telegram 通知配置
+type TelegramConfig struct {
+	BotToken string
+	ChatID   string
+	Events   []string // 订阅的事件，为空表示全部
+	Enabled  bool
+}
+
+// Subscribes 是否订阅了该事件（未配置机器人或已禁用时不发送）
+func (c *TelegramConfig) Subscribes(eventType string) bool {
+	if c == nil || !c.Enabled || c.BotToken == "" || c.ChatID == "" {
+		return false
+	}
+	if len(c.Events) == 0 {
+		return true
+	}
+	for _, e := range c.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateChatID 校验 Chat ID（数字ID，或 @ 开头的频道用户名）
+func ValidateChatID(chatID string) error {
+	chatID = strings.TrimSpace(chatID)
+	if strings.HasPrefix(chatID, "@") && len(chatID) > 1 {
+		return nil
+	}
+	if _, err := strconv.ParseInt(chatID, 10, 64); err != nil {
+		return fmt.Errorf("无效的 Telegram Chat ID: %q（应为数字ID或 @频道名）", chatID)
+	}
+	return nil
+}
+
+// apiError Telegram API 返回的错误
+type apiError struct {
+	Code       int
+	Desc       string
+	RetryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Telegram API 错误 %d: %s", e.Code, e.Desc)
+}
+
+// retryable 是否值得重试（限流和服务端错误可重试，令牌或 Chat ID 错误重试无意义）
+func (e *apiError) retryable() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= 500
+}
+
+type job struct {
+	cfg  TelegramConfig
+	text string
+}
+
+// Notifier 异步发送 Telegram 消息
+// 有界队列 + 按聊天分配的发送协程，同一聊天按顺序发送并限速，失败按指数退避重试，不阻塞交易流程
+type Notifier struct {
+	client         *http.Client
+	baseURL        string
+	maxRetries     int
+	initialBackoff time.Duration
+	chatInterval   time.Duration
+
+	queues    []chan job
+	startOnce sync.Once
+
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
+}
+
+// NewNotifier 创建发送器（baseURL 为空时使用官方 API 地址）
+func NewNotifier(client *http.Client, baseURL string, maxRetries int, initialBackoff, chatInterval time.Duration) *Notifier {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	if maxRetries < 0 {
+		maxRetries = defaultMaxRetries
+	}
+	if initialBackoff <= 0 {
+		initialBackoff = defaultInitialBackoff
+	}
+	if chatInterval <= 0 {
+		chatInterval = defaultChatInterval
+	}
+
+	n := &Notifier{
+		client:         client,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		maxRetries:     maxRetries,
+		initialBackoff: initialBackoff,
+		chatInterval:   chatInterval,
+		queues:         make([]chan job, defaultWorkers),
+		limiters:       make(map[string]*rate.Limiter),
+	}
+	for i := range n.queues {
+		n.queues[i] = make(chan job, defaultQueueSize/defaultWorkers)
+	}
+	return n
+}
+
+// DefaultNotifier 默认发送器
+var DefaultNotifier = NewNotifier(nil, "", defaultMaxRetries, defaultInitialBackoff, defaultChatInterval)
+
+// Enqueue 将消息放入发送队列（非阻塞），未订阅该事件或队列已满时返回 false
+func (n *Notifier) Enqueue(cfg *TelegramConfig, eventType, text string) bool {
+	if !cfg.Subscribes(eventType) {
+		return false
+	}
+	n.startOnce.Do(n.start)
+
+	select {
+	case n.queues[n.shard(cfg.ChatID)] <- job{cfg: *cfg, text: text}:
+		return true
+	default:
+		log.Printf("⚠️ Telegram 通知队列已满，丢弃消息 [%s]", eventType)
+		return false
+	}
+}
+
+// start 启动发送协程
+func (n *Notifier) start() {
+	for _, queue := range n.queues {
+		go func(queue chan job) {
+			for j := range queue {
+				if err := n.Send(&j.cfg, j.text); err != nil {
+					log.Printf("⚠️ Telegram 通知发送失败 [chat %s]: %v", j.cfg.ChatID, err)
+				}
+			}
+		}(queue)
+	}
+}
+
+// shard 同一聊天固定分配到同一个发送协程，保证消息顺序
+func (n *Notifier) shard(chatID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(chatID))
+	return int(h.Sum32() % uint32(len(n.queues)))
+}
+
+// limiter 获取聊天的限速器
+func (n *Notifier) limiter(chatID string) *rate.Limiter {
+	n.limitersMu.Lock()
+	defer n.limitersMu.Unlock()
+	l, ok := n.limiters[chatID]
+	if !ok {
+		l = rate.NewLimiter(rate.Every(n.chatInterval), 1)
+		n.limiters[chatID] = l
+	}
+	return l
+}
+
+// Send 同步发送消息（受限速约束），失败时按指数退避重试；429 时按 Telegram 要求的时间等待
+func (n *Notifier) Send(cfg *TelegramConfig, text string) error {
+	if cfg == nil || cfg.BotToken == "" || cfg.ChatID == "" {
+		return fmt.Errorf("未配置 Telegram 机器人")
+	}
+	if runes := []rune(text); len(runes) > maxMessageRunes {
+		text = string(runes[:maxMessageRunes]) + "…"
+	}
+
+	backoff := n.initialBackoff
+	var lastErr error
+	for attempt := 0; attempt <= n.maxRetries; attempt++ {
+		if attempt > 0 {
+			wait := backoff
+			if apiErr, ok := lastErr.(*apiError); ok && apiErr.RetryAfter > 0 {
+				wait = min(apiErr.RetryAfter, maxRetryAfter)
+			}
+			time.Sleep(wait)
+			backoff *= 2
+		}
+		n.limiter(cfg.ChatID).Wait(context.Background())
+
+		if lastErr = n.post(cfg, text); lastErr == nil {
+			return nil
+		}
+		if apiErr, ok := lastErr.(*apiError); ok && !apiErr.retryable() {
+			return lastErr
+		}
+	}
+	return fmt.Errorf("重试 %d 次后仍失败: %w", n.maxRetries, lastErr)
+}
+
+// post 调用一次 sendMessage（纯文本，避免AI理由中的特殊字符破坏 Markdown 解析）
+func (n *Notifier) post(cfg *TelegramConfig, text string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  cfg.ChatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.baseURL+"/bot"+cfg.BotToken+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		// 错误信息中可能包含令牌，不原样返回
+		return fmt.Errorf("创建请求失败")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %s", strings.ReplaceAll(err.Error(), cfg.BotToken, "***"))
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		ErrorCode   int    `json:"error_code"`
+		Description string `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return &apiError{Code: resp.StatusCode, Desc: fmt.Sprintf("无法解析响应: %v", err)}
+	}
+	if !result.OK {
+		code := result.ErrorCode
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return &apiError{Code: code, Desc: result.Description, RetryAfter: time.Duration(result.Parameters.RetryAfter) * time.Second}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"nofx/webhook"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEnqueueSendsWithRetry 测试异步发送：限流(429)后按 retry_after 重试，未配置或未订阅时不发送
+func TestEnqueueSendsWithRetry(t *testing.T) {
+	var attempts int32
+	received := make(chan map[string]interface{}, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot123:abc/sendMessage" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+			return
+		}
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests","parameters":{"retry_after":0}}`))
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer server.Close()
+
+	n := NewNotifier(server.Client(), server.URL, 2, time.Millisecond, time.Millisecond)
+	cfg := &TelegramConfig{BotToken: "123:abc", ChatID: "42", Events: []string{webhook.EventOpen}, Enabled: true}
+
+	if n.Enqueue(cfg, webhook.EventClose, "未订阅") {
+		t.Error("未订阅的事件不应入队")
+	}
+	if n.Enqueue(&TelegramConfig{Enabled: true}, webhook.EventOpen, "未配置") {
+		t.Error("未配置机器人时不应入队")
+	}
+	if !n.Enqueue(cfg, webhook.EventOpen, "开仓通知") {
+		t.Fatal("已订阅的事件应入队")
+	}
+
+	select {
+	case body := <-received:
+		if body["chat_id"] != "42" || body["text"] != "开仓通知" {
+			t.Errorf("消息内容不正确: %+v", body)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("超时未收到消息")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("限流后应重试一次, 实际请求 %d 次", got)
+	}
+}
+
+// TestSendStopsOnPermanentError 测试令牌或 Chat ID 错误时不重试，且错误信息不包含令牌
+func TestSendStopsOnPermanentError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+	}))
+	defer server.Close()
+
+	n := NewNotifier(server.Client(), server.URL, 3, time.Millisecond, time.Millisecond)
+	err := n.Send(&TelegramConfig{BotToken: "secret-token", ChatID: "1", Enabled: true}, "hi")
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Fatalf("应返回 Telegram 的错误描述, 实际 %v", err)
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("错误信息不应包含令牌: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("永久错误不应重试, 实际请求 %d 次", got)
+	}
+}
+
+// TestSendRateLimitedPerChat 测试同一聊天的消息按间隔限速发送
+func TestSendRateLimitedPerChat(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer server.Close()
+
+	interval := 50 * time.Millisecond
+	n := NewNotifier(server.Client(), server.URL, 0, time.Millisecond, interval)
+	cfg := &TelegramConfig{BotToken: "t", ChatID: "7", Enabled: true}
+	for i := 0; i < 3; i++ {
+		if err := n.Send(cfg, "msg"); err != nil {
+			t.Fatalf("发送失败: %v", err)
+		}
+	}
+	if elapsed := times[2].Sub(times[0]); elapsed < 2*interval-10*time.Millisecond {
+		t.Errorf("3 条消息应至少间隔 %v, 实际 %v", 2*interval, elapsed)
+	}
+}
+
+// TestFormatEvent 测试开仓、被动平仓和风控暂停消息的格式
+func TestFormatEvent(t *testing.T) {
+	open := FormatEvent(webhook.NewEvent(webhook.EventOpen, "t1", "u1", map[string]interface{}{
+		"trader_name": "Alpha", "symbol": "BTCUSDT", "side": "long", "quantity": 0.015, "price": 60000.0,
+		"leverage": 10, "stop_loss": 58000.0, "take_profit": 65000.0, "reasoning": strings.Repeat("趋势", 150),
+	}))
+	for _, want := range []string{"[Alpha] 开多 BTCUSDT", "数量: 0.015 @ 60000", "杠杆: 10x", "理由: 趋势"} {
+		if !strings.Contains(open, want) {
+			t.Errorf("开仓消息应包含 %q:\n%s", want, open)
+		}
+	}
+	if !strings.HasSuffix(open, "…") {
+		t.Errorf("过长的理由应被截断:\n%s", open)
+	}
+
+	closed := FormatEvent(webhook.NewEvent(webhook.EventClose, "t1", "u1", map[string]interface{}{
+		"symbol": "ETHUSDT", "side": "short", "quantity": 1.0, "entry_price": 3000.0, "exit_price": 3100.0,
+		"pnl": -100.0, "reason": "stop_loss", "passive": true, "leverage": 5,
+	}))
+	if !strings.Contains(closed, "被动平仓（止损）空 ETHUSDT") || !strings.Contains(closed, "盈亏: -100.00 USDT") {
+		t.Errorf("被动平仓消息不正确:\n%s", closed)
+	}
+
+	risk := FormatEvent(webhook.NewEvent(webhook.EventRiskStop, "t1", "u1", map[string]interface{}{
+		"reason": "触发当日最大亏损", "pause": "1h0m0s", "resume_at": "2026-10-16T12:00:00Z",
+	}))
+	if !strings.Contains(risk, "触发风控暂停") || !strings.Contains(risk, "触发当日最大亏损") {
+		t.Errorf("风控暂停消息不正确:\n%s", risk)
+	}
+}
+
+// TestValidateChatID 测试 Chat ID 校验
+func TestValidateChatID(t *testing.T) {
+	for _, valid := range []string{"123456", "-1001234567890", "@my_channel"} {
+		if err := ValidateChatID(valid); err != nil {
+			t.Errorf("%q 应为有效 Chat ID: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", "@", "abc"} {
+		if ValidateChatID(invalid) == nil {
+			t.Errorf("%q 应为无效 Chat ID", invalid)
+		}
+	}
+}
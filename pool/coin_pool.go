@@ -34,7 +34,7 @@ type CoinPoolConfig struct {
 
 var coinPoolConfig = CoinPoolConfig{
 	APIURL:          "",
-	Timeout:         30 * time.Second, // 增加到30秒
+	Timeout:         DefaultFetchTimeout,
 	CacheDir:        "coin_pool_cache",
 	UseDefaultCoins: false, // 默认不使用
 }
@@ -56,6 +56,7 @@ type CoinInfo struct {
 	MaxScore        float64 `json:"max_score"`        // 最高评分
 	MaxPrice        float64 `json:"max_price"`        // 最高价格
 	IncreasePercent float64 `json:"increase_percent"` // 涨幅百分比
+	Bias            string  `json:"bias,omitempty"`   // 信号方向偏好（long/short，可选）
 	IsAvailable     bool    `json:"-"`                // 是否可交易（内部使用）
 }
 
@@ -91,7 +92,7 @@ func SetDefaultCoins(coins []string) {
 	}
 }
 
-// GetCoinPool 获取币种池列表（带重试和缓存机制）
+// GetCoinPool 获取币种池列表（读取内存快照，拉取失败时依次使用历史缓存文件和默认主流币种）
 func GetCoinPool() ([]CoinInfo, error) {
 	// 优先检查是否启用默认币种列表
 	if coinPoolConfig.UseDefaultCoins {
@@ -100,71 +101,53 @@ func GetCoinPool() ([]CoinInfo, error) {
 	}
 
 	// 检查API URL是否配置
-	if strings.TrimSpace(coinPoolConfig.APIURL) == "" {
+	apiURL := strings.TrimSpace(coinPoolConfig.APIURL)
+	if apiURL == "" {
 		log.Printf("⚠️  未配置币种池API URL，使用默认主流币种列表")
 		return convertSymbolsToCoins(defaultMainstreamCoins), nil
 	}
 
-	maxRetries := 3
-	var lastErr error
-
-	// 尝试从API获取
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			log.Printf("⚠️  第%d次重试获取币种池（共%d次）...", attempt, maxRetries)
-			time.Sleep(2 * time.Second) // 重试前等待2秒
-		}
-
-		coins, err := fetchCoinPool(coinPoolConfig.APIURL, coinPoolConfig.Timeout)
-		if err == nil {
-			if attempt > 1 {
-				log.Printf("✓ 第%d次重试成功", attempt)
-			}
-			// 成功获取后保存到缓存
-			if err := saveCoinPoolCache(coins); err != nil {
-				log.Printf("⚠️  保存币种池缓存失败: %v", err)
-			}
-			return coins, nil
-		}
-
-		lastErr = err
-		log.Printf("❌ 第%d次请求失败: %v", attempt, err)
+	coins, lastErr := cachedCoinPool(apiURL)
+	if lastErr == nil {
+		return coins, nil
 	}
 
-	// API获取失败，尝试使用缓存
-	log.Printf("⚠️  API请求全部失败，尝试使用历史缓存数据...")
-	cachedCoins, err := loadCoinPoolCache()
+	// 还没有成功拉取过且本次请求失败，尝试使用缓存文件（作为过期快照，后台恢复前不再同步等待）
+	log.Printf("⚠️  币种池API请求失败，尝试使用历史缓存数据...")
+	cache, err := loadCoinPoolCacheFile()
 	if err == nil {
-		log.Printf("✓ 使用历史缓存数据（共%d个币种）", len(cachedCoins))
-		return cachedCoins, nil
+		log.Printf("✓ 使用历史缓存数据（共%d个币种）", len(cache.Coins))
+		getSnapshot(SourceAI500, apiURL).seed(cache.Coins, nil, cache.FetchedAt)
+		recordSourceFallback(SourceAI500, apiURL, FallbackCache, len(cache.Coins))
+		return cache.Coins, nil
 	}
 
 	// 缓存也失败，使用默认主流币种
 	log.Printf("⚠️  无法加载缓存数据（最后错误: %v），使用默认主流币种列表", lastErr)
+	recordSourceFallback(SourceAI500, apiURL, FallbackDefault, len(defaultMainstreamCoins))
 	return convertSymbolsToCoins(defaultMainstreamCoins), nil
 }
 
-// GetCoinPoolWithURL 使用自定义 API URL 获取币种池（不影响全局配置）
+// GetCoinPoolWithURL 使用自定义 API URL 获取币种池（不影响全局配置，同样读取快照）
 func GetCoinPoolWithURL(apiURL string) ([]CoinInfo, error) {
 	apiURL = strings.TrimSpace(apiURL)
 	if apiURL == "" {
 		return GetCoinPool()
 	}
-
-	coins, err := fetchCoinPool(apiURL, coinPoolConfig.Timeout)
-	if err != nil {
-		return nil, err
-	}
-	return coins, nil
+	return cachedCoinPool(apiURL)
 }
 
 // fetchCoinPool 实际执行币种池请求
-func fetchCoinPool(apiURL string, timeout time.Duration) ([]CoinInfo, error) {
+func fetchCoinPool(apiURL string, timeout time.Duration) (coins []CoinInfo, err error) {
 	apiURL = strings.TrimSpace(apiURL)
 	if apiURL == "" {
 		return nil, fmt.Errorf("未配置币种池API URL")
 	}
 
+	// 记录信号源状态（供 /signal-sources/status 排查）
+	httpStatus := 0
+	defer func() { recordSourceFetch(SourceAI500, apiURL, httpStatus, len(coins), err) }()
+
 	log.Printf("🔄 正在请求AI500币种池: %s", apiURL)
 
 	client := &http.Client{
@@ -176,6 +159,7 @@ func fetchCoinPool(apiURL string, timeout time.Duration) ([]CoinInfo, error) {
 		return nil, fmt.Errorf("请求币种池API失败: %w", err)
 	}
 	defer resp.Body.Close()
+	httpStatus = resp.StatusCode
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -201,7 +185,7 @@ func fetchCoinPool(apiURL string, timeout time.Duration) ([]CoinInfo, error) {
 	}
 
 	// 设置IsAvailable标志
-	coins := response.Data.Coins
+	coins = response.Data.Coins
 	for i := range coins {
 		coins[i].IsAvailable = true
 	}
@@ -239,6 +223,15 @@ func saveCoinPoolCache(coins []CoinInfo) error {
 
 // loadCoinPoolCache 从缓存文件加载币种池
 func loadCoinPoolCache() ([]CoinInfo, error) {
+	cache, err := loadCoinPoolCacheFile()
+	if err != nil {
+		return nil, err
+	}
+	return cache.Coins, nil
+}
+
+// loadCoinPoolCacheFile 读取币种池缓存文件（包含拉取时间）
+func loadCoinPoolCacheFile() (*CoinPoolCache, error) {
 	cachePath := filepath.Join(coinPoolConfig.CacheDir, "latest.json")
 
 	// 检查文件是否存在
@@ -266,7 +259,7 @@ func loadCoinPoolCache() ([]CoinInfo, error) {
 			cacheAge.Minutes())
 	}
 
-	return cache.Coins, nil
+	return &cache, nil
 }
 
 // GetAvailableCoins 获取可用的币种列表（过滤不可用的）
@@ -294,59 +287,38 @@ func GetAvailableCoins() ([]string, error) {
 
 // GetTopRatedCoins 获取评分最高的N个币种（按评分从大到小排序）
 func GetTopRatedCoins(limit int) ([]string, error) {
-	coins, err := GetCoinPool()
+	return GetTopRatedCoinsWithURL(limit, "")
+}
+
+// GetTopRatedCoinsWithURL 使用自定义币种池 API 获取高评分币种
+func GetTopRatedCoinsWithURL(limit int, apiURL string) ([]string, error) {
+	coins, err := GetTopRatedCoinInfosWithURL(limit, apiURL)
 	if err != nil {
 		return nil, err
 	}
 
-	// 过滤可用的币种
-	var availableCoins []CoinInfo
-	for _, coin := range coins {
-		if coin.IsAvailable {
-			availableCoins = append(availableCoins, coin)
-		}
-	}
-
-	if len(availableCoins) == 0 {
-		return nil, fmt.Errorf("没有可用的币种")
-	}
-
-	// 按Score降序排序（冒泡排序）
-	for i := 0; i < len(availableCoins); i++ {
-		for j := i + 1; j < len(availableCoins); j++ {
-			if availableCoins[i].Score < availableCoins[j].Score {
-				availableCoins[i], availableCoins[j] = availableCoins[j], availableCoins[i]
-			}
-		}
-	}
-
-	// 取前N个
-	maxCount := limit
-	if len(availableCoins) < maxCount {
-		maxCount = len(availableCoins)
-	}
-
 	var symbols []string
-	for i := 0; i < maxCount; i++ {
-		symbol := normalizeSymbol(availableCoins[i].Pair)
-		symbols = append(symbols, symbol)
+	for _, coin := range coins {
+		symbols = append(symbols, normalizeSymbol(coin.Pair))
 	}
 
 	return symbols, nil
 }
 
-// GetTopRatedCoinsWithURL 使用自定义币种池 API 获取高评分币种
-func GetTopRatedCoinsWithURL(limit int, apiURL string) ([]string, error) {
-	apiURL = strings.TrimSpace(apiURL)
-	if apiURL == "" {
-		return GetTopRatedCoins(limit)
+// GetTopRatedCoinInfosWithURL 获取评分最高的N个币种完整信息（保留信号方向偏好等字段）
+func GetTopRatedCoinInfosWithURL(limit int, apiURL string) ([]CoinInfo, error) {
+	var coins []CoinInfo
+	var err error
+	if apiURL = strings.TrimSpace(apiURL); apiURL != "" {
+		coins, err = GetCoinPoolWithURL(apiURL)
+	} else {
+		coins, err = GetCoinPool()
 	}
-
-	coins, err := GetCoinPoolWithURL(apiURL)
 	if err != nil {
 		return nil, err
 	}
 
+	// 过滤可用的币种
 	var availableCoins []CoinInfo
 	for _, coin := range coins {
 		if coin.IsAvailable {
@@ -358,6 +330,7 @@ func GetTopRatedCoinsWithURL(limit int, apiURL string) ([]string, error) {
 		return nil, fmt.Errorf("没有可用的币种")
 	}
 
+	// 按Score降序排序（冒泡排序）
 	for i := 0; i < len(availableCoins); i++ {
 		for j := i + 1; j < len(availableCoins); j++ {
 			if availableCoins[i].Score < availableCoins[j].Score {
@@ -366,18 +339,39 @@ func GetTopRatedCoinsWithURL(limit int, apiURL string) ([]string, error) {
 		}
 	}
 
+	// 取前N个
 	maxCount := limit
 	if len(availableCoins) < maxCount {
 		maxCount = len(availableCoins)
 	}
 
-	var symbols []string
-	for i := 0; i < maxCount; i++ {
-		symbol := normalizeSymbol(availableCoins[i].Pair)
-		symbols = append(symbols, symbol)
+	return availableCoins[:maxCount], nil
+}
+
+// NormalizeBias 标准化信号方向偏好，无法识别时返回空字符串
+func NormalizeBias(bias string) string {
+	switch strings.ToLower(strings.TrimSpace(bias)) {
+	case "long", "buy", "bullish":
+		return "long"
+	case "short", "sell", "bearish":
+		return "short"
+	default:
+		return ""
 	}
+}
 
-	return symbols, nil
+// mergeSymbolBias 合并同一币种的方向偏好，多个信号源方向冲突时视为无偏好
+func mergeSymbolBias(biases map[string]string, conflicts map[string]bool, symbol, bias string) {
+	bias = NormalizeBias(bias)
+	if bias == "" || conflicts[symbol] {
+		return
+	}
+	if existing, ok := biases[symbol]; ok && existing != bias {
+		delete(biases, symbol)
+		conflicts[symbol] = true
+		return
+	}
+	biases[symbol] = bias
 }
 
 // normalizeSymbol 标准化币种符号
@@ -452,6 +446,7 @@ type OIPosition struct {
 	PriceDeltaPercent float64 `json:"price_delta_percent"` // 价格变化百分比
 	NetLong           float64 `json:"net_long"`            // 净多仓
 	NetShort          float64 `json:"net_short"`           // 净空仓
+	Bias              string  `json:"bias,omitempty"`      // 信号方向偏好（long/short，可选）
 }
 
 // OITopAPIResponse OI Top API返回的数据结构
@@ -478,50 +473,32 @@ var oiTopConfig = struct {
 	CacheDir string
 }{
 	APIURL:   "",
-	Timeout:  30 * time.Second,
+	Timeout:  DefaultFetchTimeout,
 	CacheDir: "coin_pool_cache",
 }
 
-// GetOITopPositions 获取持仓量增长Top20数据（带重试和缓存）
+// GetOITopPositions 获取持仓量增长Top20数据（读取内存快照，拉取失败时使用历史缓存文件）
 func GetOITopPositions() ([]OIPosition, error) {
 	// 检查API URL是否配置
-	if strings.TrimSpace(oiTopConfig.APIURL) == "" {
+	apiURL := strings.TrimSpace(oiTopConfig.APIURL)
+	if apiURL == "" {
 		log.Printf("⚠️  未配置OI Top API URL，跳过OI Top数据获取")
 		return []OIPosition{}, nil // 返回空列表，不是错误
 	}
 
-	maxRetries := 3
-	var lastErr error
-
-	// 尝试从API获取
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			log.Printf("⚠️  第%d次重试获取OI Top数据（共%d次）...", attempt, maxRetries)
-			time.Sleep(2 * time.Second)
-		}
-
-		positions, err := fetchOITop(oiTopConfig.APIURL, oiTopConfig.Timeout)
-		if err == nil {
-			if attempt > 1 {
-				log.Printf("✓ 第%d次重试成功", attempt)
-			}
-			// 成功获取后保存到缓存
-			if err := saveOITopCache(positions); err != nil {
-				log.Printf("⚠️  保存OI Top缓存失败: %v", err)
-			}
-			return positions, nil
-		}
-
-		lastErr = err
-		log.Printf("❌ 第%d次请求OI Top失败: %v", attempt, err)
+	positions, lastErr := cachedOITop(apiURL)
+	if lastErr == nil {
+		return positions, nil
 	}
 
-	// API获取失败，尝试使用缓存
-	log.Printf("⚠️  OI Top API请求全部失败，尝试使用历史缓存数据...")
-	cachedPositions, err := loadOITopCache()
+	// 还没有成功拉取过且本次请求失败，尝试使用缓存文件
+	log.Printf("⚠️  OI Top API请求失败，尝试使用历史缓存数据...")
+	cache, err := loadOITopCacheFile()
 	if err == nil {
-		log.Printf("✓ 使用历史OI Top缓存数据（共%d个币种）", len(cachedPositions))
-		return cachedPositions, nil
+		log.Printf("✓ 使用历史OI Top缓存数据（共%d个币种）", len(cache.Positions))
+		getSnapshot(SourceOITop, apiURL).seed(nil, cache.Positions, cache.FetchedAt)
+		recordSourceFallback(SourceOITop, apiURL, FallbackCache, len(cache.Positions))
+		return cache.Positions, nil
 	}
 
 	// 缓存也失败，返回空列表（OI Top是可选的）
@@ -529,27 +506,26 @@ func GetOITopPositions() ([]OIPosition, error) {
 	return []OIPosition{}, nil
 }
 
-// GetOITopPositionsWithURL 使用自定义 URL 获取 OI Top 数据
+// GetOITopPositionsWithURL 使用自定义 URL 获取 OI Top 数据（同样读取快照）
 func GetOITopPositionsWithURL(apiURL string) ([]OIPosition, error) {
 	apiURL = strings.TrimSpace(apiURL)
 	if apiURL == "" {
 		return GetOITopPositions()
 	}
-
-	positions, err := fetchOITop(apiURL, oiTopConfig.Timeout)
-	if err != nil {
-		return nil, err
-	}
-	return positions, nil
+	return cachedOITop(apiURL)
 }
 
 // fetchOITop 实际执行OI Top请求
-func fetchOITop(apiURL string, timeout time.Duration) ([]OIPosition, error) {
+func fetchOITop(apiURL string, timeout time.Duration) (positions []OIPosition, err error) {
 	apiURL = strings.TrimSpace(apiURL)
 	if apiURL == "" {
 		return nil, fmt.Errorf("未配置OI Top API URL")
 	}
 
+	// 记录信号源状态（供 /signal-sources/status 排查）
+	httpStatus := 0
+	defer func() { recordSourceFetch(SourceOITop, apiURL, httpStatus, len(positions), err) }()
+
 	log.Printf("🔄 正在请求OI Top数据: %s", apiURL)
 
 	client := &http.Client{
@@ -561,6 +537,7 @@ func fetchOITop(apiURL string, timeout time.Duration) ([]OIPosition, error) {
 		return nil, fmt.Errorf("请求OI Top API失败: %w", err)
 	}
 	defer resp.Body.Close()
+	httpStatus = resp.StatusCode
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -618,6 +595,15 @@ func saveOITopCache(positions []OIPosition) error {
 
 // loadOITopCache 从缓存加载OI Top数据
 func loadOITopCache() ([]OIPosition, error) {
+	cache, err := loadOITopCacheFile()
+	if err != nil {
+		return nil, err
+	}
+	return cache.Positions, nil
+}
+
+// loadOITopCacheFile 读取OI Top缓存文件（包含拉取时间）
+func loadOITopCacheFile() (*OITopCache, error) {
 	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
 
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
@@ -643,7 +629,7 @@ func loadOITopCache() ([]OIPosition, error) {
 			cacheAge.Minutes())
 	}
 
-	return cache.Positions, nil
+	return &cache, nil
 }
 
 // GetOITopSymbols 获取OI Top的币种符号列表
@@ -684,6 +670,7 @@ type MergedCoinPool struct {
 	OITopCoins    []OIPosition        // 持仓量增长Top20
 	AllSymbols    []string            // 所有不重复的币种符号
 	SymbolSources map[string][]string // 每个币种的来源（"ai500"/"oi_top"）
+	SymbolBias    map[string]string   // 每个币种的信号方向偏好（"long"/"short"，无偏好或冲突时不存在）
 }
 
 // GetMergedCoinPool 获取合并后的币种池（AI500 + OI Top，去重）
@@ -754,11 +741,26 @@ func GetMergedCoinPoolWithOverride(ai500Limit int, coinPoolURL, oiTopURL string)
 		oiTopPositions, _ = GetOITopPositions()
 	}
 
+	// 汇总候选币种的信号方向偏好（只统计进入候选列表的币种）
+	symbolBias := make(map[string]string)
+	biasConflicts := make(map[string]bool)
+	for _, coin := range ai500Coins {
+		if symbol := normalizeSymbol(coin.Pair); symbolSet[symbol] {
+			mergeSymbolBias(symbolBias, biasConflicts, symbol, coin.Bias)
+		}
+	}
+	for _, pos := range oiTopPositions {
+		if symbol := normalizeSymbol(pos.Symbol); symbolSet[symbol] {
+			mergeSymbolBias(symbolBias, biasConflicts, symbol, pos.Bias)
+		}
+	}
+
 	merged := &MergedCoinPool{
 		AI500Coins:    ai500Coins,
 		OITopCoins:    oiTopPositions,
 		AllSymbols:    allSymbols,
 		SymbolSources: symbolSources,
+		SymbolBias:    symbolBias,
 	}
 
 	log.Printf("📊 币种池合并完成: AI500=%d, OI_Top=%d, 总计(去重)=%d",
@@ -0,0 +1,307 @@
+package pool
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 信号源快照缓存：每个信号源（按 URL 区分）最近一次成功拉取的结果保存在内存中，
+// 由后台刷新协程按 TTL 统一拉取，交易员的决策周期只读取快照、不等待 HTTP 请求。
+// 刷新失败时继续使用上一次成功的快照（标记为过期），还没有快照时才同步拉取一次。
+
+const (
+	DefaultSnapshotTTL  = 5 * time.Minute  // 快照有效期（到期后由后台刷新）
+	DefaultFetchTimeout = 10 * time.Second // 单次 HTTP 请求的超时
+	snapshotIdleExpiry  = 24 * time.Hour   // 超过该时间没有被读取的信号源停止刷新（如交易员改用其他 URL）
+)
+
+// SnapshotInfo 信号源快照的数据时间
+type SnapshotInfo struct {
+	FetchedAt  time.Time `json:"fetched_at"`
+	AgeSeconds int64     `json:"age_seconds"`
+	Stale      bool      `json:"stale"` // 最近一次刷新失败或超过两倍有效期未刷新，正在使用旧快照
+}
+
+// sourceSnapshot 单个信号源的快照
+type sourceSnapshot struct {
+	source string
+	apiURL string
+
+	fetchMu sync.Mutex // 同一信号源同一时刻只有一个请求（多个交易员共享同一次拉取）
+
+	mu        sync.RWMutex
+	coins     []CoinInfo
+	positions []OIPosition
+	fetchedAt time.Time
+	hasData   bool
+	stale     bool
+	lastRead  time.Time
+}
+
+var snapshots = struct {
+	sync.Mutex
+	ttl     time.Duration
+	running bool
+	byKey   map[string]*sourceSnapshot
+}{ttl: DefaultSnapshotTTL, byKey: make(map[string]*sourceSnapshot)}
+
+// SetSnapshotTTL 设置信号源快照有效期（<=0 时使用默认 5 分钟）
+func SetSnapshotTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultSnapshotTTL
+	}
+	snapshots.Lock()
+	snapshots.ttl = ttl
+	snapshots.Unlock()
+}
+
+// SetFetchTimeout 设置信号源 HTTP 请求超时（<=0 时使用默认 10 秒）
+func SetFetchTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultFetchTimeout
+	}
+	coinPoolConfig.Timeout = timeout
+	oiTopConfig.Timeout = timeout
+}
+
+func snapshotTTL() time.Duration {
+	snapshots.Lock()
+	defer snapshots.Unlock()
+	return snapshots.ttl
+}
+
+// getSnapshot 获取（或创建）信号源快照
+func getSnapshot(source, apiURL string) *sourceSnapshot {
+	apiURL = strings.TrimSpace(apiURL)
+	key := sourceStatusKey(source, apiURL)
+	snapshots.Lock()
+	defer snapshots.Unlock()
+	s, ok := snapshots.byKey[key]
+	if !ok {
+		s = &sourceSnapshot{source: source, apiURL: apiURL, lastRead: time.Now()}
+		snapshots.byKey[key] = s
+	}
+	return s
+}
+
+// load 确保快照可用：已有快照时直接返回（到期且后台刷新未启动时异步刷新），
+// 没有快照时同步拉取一次
+func (s *sourceSnapshot) load() error {
+	s.mu.Lock()
+	s.lastRead = time.Now()
+	hasData, fetchedAt := s.hasData, s.fetchedAt
+	s.mu.Unlock()
+
+	if hasData {
+		if time.Since(fetchedAt) >= snapshotTTL() && !refresherRunning() {
+			go s.tryRefresh()
+		}
+		return nil
+	}
+
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+	// 等锁期间其他交易员可能已经拉取成功
+	s.mu.RLock()
+	hasData = s.hasData
+	s.mu.RUnlock()
+	if hasData {
+		return nil
+	}
+	return s.fetchLocked()
+}
+
+// tryRefresh 刷新快照（已有请求在进行时跳过）
+func (s *sourceSnapshot) tryRefresh() {
+	if !s.fetchMu.TryLock() {
+		return
+	}
+	defer s.fetchMu.Unlock()
+	s.fetchLocked()
+}
+
+// fetchLocked 拉取信号源并更新快照（调用方持有 fetchMu）；失败时保留上一次成功的快照
+func (s *sourceSnapshot) fetchLocked() error {
+	var coins []CoinInfo
+	var positions []OIPosition
+	var err error
+	switch s.source {
+	case SourceAI500:
+		coins, err = fetchCoinPool(s.apiURL, coinPoolConfig.Timeout)
+	case SourceOITop:
+		positions, err = fetchOITop(s.apiURL, oiTopConfig.Timeout)
+	}
+
+	s.mu.Lock()
+	if err != nil {
+		hasData, count := s.hasData, len(s.coins)+len(s.positions)
+		if hasData {
+			s.stale = true
+		}
+		s.mu.Unlock()
+		if hasData {
+			log.Printf("⚠️  刷新%s信号源失败，继续使用 %s 的快照: %v", s.source, s.fetchedAt.Format("15:04:05"), err)
+			recordSourceFallback(s.source, s.apiURL, FallbackSnapshot, count)
+		}
+		return err
+	}
+	s.coins, s.positions = coins, positions
+	s.fetchedAt = time.Now()
+	s.hasData, s.stale = true, false
+	s.mu.Unlock()
+
+	// 系统默认信号源同时写入缓存文件（重启后 API 不可用时兜底）
+	switch {
+	case s.source == SourceAI500 && s.apiURL == strings.TrimSpace(coinPoolConfig.APIURL):
+		if err := saveCoinPoolCache(coins); err != nil {
+			log.Printf("⚠️  保存币种池缓存失败: %v", err)
+		}
+	case s.source == SourceOITop && s.apiURL == strings.TrimSpace(oiTopConfig.APIURL):
+		if err := saveOITopCache(positions); err != nil {
+			log.Printf("⚠️  保存OI Top缓存失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// seed 用缓存文件中的数据作为快照（标记为过期），避免信号源不可用时每个周期都同步等待
+func (s *sourceSnapshot) seed(coins []CoinInfo, positions []OIPosition, fetchedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hasData {
+		return
+	}
+	s.coins, s.positions = coins, positions
+	s.fetchedAt = fetchedAt
+	s.hasData, s.stale = true, true
+}
+
+// cachedCoinPool 读取币种池快照（返回副本）
+func cachedCoinPool(apiURL string) ([]CoinInfo, error) {
+	s := getSnapshot(SourceAI500, apiURL)
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]CoinInfo(nil), s.coins...), nil
+}
+
+// cachedOITop 读取 OI Top 快照（返回副本）
+func cachedOITop(apiURL string) ([]OIPosition, error) {
+	s := getSnapshot(SourceOITop, apiURL)
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]OIPosition(nil), s.positions...), nil
+}
+
+// GetSnapshotInfo 获取信号源快照的数据时间；apiURL 为空时查询系统默认配置的 URL，没有快照时返回零值
+func GetSnapshotInfo(source, apiURL string) SnapshotInfo {
+	apiURL = resolveSourceURL(source, apiURL)
+	snapshots.Lock()
+	s, ok := snapshots.byKey[sourceStatusKey(source, apiURL)]
+	ttl := snapshots.ttl
+	snapshots.Unlock()
+	if !ok {
+		return SnapshotInfo{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasData {
+		return SnapshotInfo{}
+	}
+	age := time.Since(s.fetchedAt)
+	return SnapshotInfo{
+		FetchedAt:  s.fetchedAt,
+		AgeSeconds: int64(age.Seconds()),
+		Stale:      s.stale || age > 2*ttl,
+	}
+}
+
+// resolveSourceURL apiURL 为空时返回系统默认配置的信号源 URL
+func resolveSourceURL(source, apiURL string) string {
+	apiURL = strings.TrimSpace(apiURL)
+	if apiURL != "" {
+		return apiURL
+	}
+	switch source {
+	case SourceAI500:
+		return strings.TrimSpace(coinPoolConfig.APIURL)
+	case SourceOITop:
+		return strings.TrimSpace(oiTopConfig.APIURL)
+	}
+	return ""
+}
+
+func refresherRunning() bool {
+	snapshots.Lock()
+	defer snapshots.Unlock()
+	return snapshots.running
+}
+
+// StartSnapshotRefresher 启动后台刷新协程（重复调用只启动一次）：
+// 立即拉取已配置的系统信号源，之后定期刷新所有被读取过且已到期的信号源
+func StartSnapshotRefresher() {
+	snapshots.Lock()
+	if snapshots.running {
+		snapshots.Unlock()
+		return
+	}
+	snapshots.running = true
+	snapshots.Unlock()
+
+	go func() {
+		for {
+			refreshDueSnapshots()
+			time.Sleep(refreshInterval())
+		}
+	}()
+	log.Printf("🔄 信号源快照后台刷新已启动（有效期 %v，请求超时 %v）", snapshotTTL(), coinPoolConfig.Timeout)
+}
+
+// refreshInterval 检查快照是否到期的间隔（有效期的 1/5，最少 10 秒）
+func refreshInterval() time.Duration {
+	interval := snapshotTTL() / 5
+	if interval < 10*time.Second {
+		interval = 10 * time.Second
+	}
+	return interval
+}
+
+// refreshDueSnapshots 依次刷新没有数据或已到期的信号源，并移除长期未被读取的信号源
+func refreshDueSnapshots() {
+	if url := strings.TrimSpace(coinPoolConfig.APIURL); url != "" && !coinPoolConfig.UseDefaultCoins {
+		getSnapshot(SourceAI500, url)
+	}
+	if url := strings.TrimSpace(oiTopConfig.APIURL); url != "" {
+		getSnapshot(SourceOITop, url)
+	}
+
+	ttl := snapshotTTL()
+	snapshots.Lock()
+	due := make([]*sourceSnapshot, 0, len(snapshots.byKey))
+	for key, s := range snapshots.byKey {
+		s.mu.RLock()
+		idle := time.Since(s.lastRead) > snapshotIdleExpiry
+		expired := !s.hasData || time.Since(s.fetchedAt) >= ttl
+		s.mu.RUnlock()
+		if idle && s.apiURL != resolveSourceURL(s.source, "") {
+			delete(snapshots.byKey, key)
+			continue
+		}
+		if expired {
+			due = append(due, s)
+		}
+	}
+	snapshots.Unlock()
+
+	for _, s := range due {
+		s.tryRefresh()
+	}
+}
@@ -0,0 +1,129 @@
+package pool
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSnapshotServesLastGoodOnFailure tests that an outage keeps serving the last good snapshot without waiting
+func TestSnapshotServesLastGoodOnFailure(t *testing.T) {
+	var down atomic.Bool
+	server := startPoolTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			time.Sleep(300 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"success":true,"data":{"coins":[{"pair":"BTCUSDT","score":90},{"pair":"ETHUSDT","score":80}]}}`)
+	}))
+	defer server.Close()
+	apiURL := server.URL + "/snapshot"
+
+	SetSnapshotTTL(time.Millisecond)
+	defer SetSnapshotTTL(0)
+
+	if coins, err := GetCoinPoolWithURL(apiURL); err != nil || len(coins) != 2 {
+		t.Fatalf("Expected 2 coins on first fetch, got %d (%v)", len(coins), err)
+	}
+	if info := GetSnapshotInfo(SourceAI500, apiURL); info.FetchedAt.IsZero() || info.Stale {
+		t.Fatalf("Expected fresh snapshot, got %+v", info)
+	}
+
+	down.Store(true)
+	time.Sleep(5 * time.Millisecond)
+	start := time.Now()
+	coins, err := GetCoinPoolWithURL(apiURL)
+	if err != nil || len(coins) != 2 {
+		t.Fatalf("Expected last good snapshot during outage, got %d (%v)", len(coins), err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Reading a snapshot must not wait for the failing source, took %v", elapsed)
+	}
+
+	// 等待异步刷新结束后再同步刷新一次，确认失败被记录
+	s := getSnapshot(SourceAI500, apiURL)
+	s.fetchMu.Lock()
+	s.fetchLocked()
+	s.fetchMu.Unlock()
+
+	if info := GetSnapshotInfo(SourceAI500, apiURL); !info.Stale {
+		t.Errorf("Expected snapshot to be marked stale after failed refresh, got %+v", info)
+	}
+	status := GetSourceStatus(SourceAI500, apiURL)
+	if status.ConsecutiveFailures == 0 || status.Fallback != FallbackSnapshot || status.Snapshot == nil || !status.Snapshot.Stale {
+		t.Errorf("Unexpected status during outage: %+v", status)
+	}
+
+	down.Store(false)
+	s.tryRefresh()
+	if info := GetSnapshotInfo(SourceAI500, apiURL); info.Stale {
+		t.Errorf("Expected fresh snapshot after recovery, got %+v", info)
+	}
+}
+
+// TestSnapshotColdFetchIsShared tests that concurrent readers without a snapshot share one request
+func TestSnapshotColdFetchIsShared(t *testing.T) {
+	var hits atomic.Int32
+	server := startPoolTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, `{"success":true,"data":{"positions":[{"symbol":"BTCUSDT","rank":1}],"count":1}}`)
+	}))
+	defer server.Close()
+	apiURL := server.URL + "/shared"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if positions, err := GetOITopPositionsWithURL(apiURL); err != nil || len(positions) != 1 {
+				t.Errorf("Expected 1 position, got %d (%v)", len(positions), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("Expected a single upstream request, got %d", n)
+	}
+}
+
+// TestCoinPoolSeedsSnapshotFromCacheFile tests that a failing system source falls back to the cache file only once
+func TestCoinPoolSeedsSnapshotFromCacheFile(t *testing.T) {
+	var hits atomic.Int32
+	server := startPoolTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	original := coinPoolConfig
+	defer func() { coinPoolConfig = original }()
+	coinPoolConfig.APIURL = server.URL + "/seed"
+	coinPoolConfig.UseDefaultCoins = false
+	coinPoolConfig.CacheDir = t.TempDir()
+	if err := saveCoinPoolCache([]CoinInfo{{Pair: "SOLUSDT", Score: 70, IsAvailable: true}}); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		coins, err := GetCoinPool()
+		if err != nil || len(coins) != 1 || coins[0].Pair != "SOLUSDT" {
+			t.Fatalf("Expected cached SOLUSDT, got %+v (%v)", coins, err)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("Expected the cache file to seed the snapshot after one failed request, got %d requests", n)
+	}
+	if info := GetSnapshotInfo(SourceAI500, ""); !info.Stale {
+		t.Errorf("Expected seeded snapshot to be stale, got %+v", info)
+	}
+	if health := SystemSourceHealth(); len(health) == 0 || health[0].Source != SourceAI500 || health[0].ConsecutiveFailures != 1 {
+		t.Errorf("Unexpected system source health: %+v", health)
+	}
+}
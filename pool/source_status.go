@@ -0,0 +1,140 @@
+package pool
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 信号源名称
+const (
+	SourceAI500 = "ai500"
+	SourceOITop = "oi_top"
+)
+
+// 拉取失败后的兜底方式
+const (
+	FallbackCache    = "cache"    // 使用历史缓存
+	FallbackDefault  = "default"  // 使用默认主流币种
+	FallbackSnapshot = "snapshot" // 刷新失败，继续使用内存中上一次成功的快照
+)
+
+// SourceStatus 信号源最近一次拉取状态（URL 已脱敏）
+type SourceStatus struct {
+	Source              string        `json:"source"`
+	URL                 string        `json:"url"`
+	Configured          bool          `json:"configured"`
+	Fetched             bool          `json:"fetched"` // 本次进程启动后是否拉取过
+	LastFetchAt         time.Time     `json:"last_fetch_at"`
+	LastSuccessAt       time.Time     `json:"last_success_at"`
+	Success             bool          `json:"success"`
+	HTTPStatus          int           `json:"http_status"` // 0 表示未收到响应（超时/连接失败）
+	SymbolCount         int           `json:"symbol_count"`
+	Error               string        `json:"error,omitempty"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	Fallback            string        `json:"fallback,omitempty"` // 拉取失败后正在使用的兜底数据（cache/default/snapshot）
+	Snapshot            *SnapshotInfo `json:"snapshot,omitempty"` // 交易员当前读取的快照（数据时间和是否过期）
+}
+
+var sourceStatuses = struct {
+	sync.RWMutex
+	byKey map[string]*SourceStatus
+}{byKey: make(map[string]*SourceStatus)}
+
+func sourceStatusKey(source, apiURL string) string {
+	return source + "|" + strings.TrimSpace(apiURL)
+}
+
+// recordSourceFetch 记录一次信号源请求结果
+func recordSourceFetch(source, apiURL string, httpStatus, symbolCount int, err error) {
+	now := time.Now()
+	sourceStatuses.Lock()
+	defer sourceStatuses.Unlock()
+
+	key := sourceStatusKey(source, apiURL)
+	status, ok := sourceStatuses.byKey[key]
+	if !ok {
+		status = &SourceStatus{Source: source}
+		sourceStatuses.byKey[key] = status
+	}
+	status.LastFetchAt = now
+	status.HTTPStatus = httpStatus
+	status.Success = err == nil
+	if err != nil {
+		status.Error = err.Error()
+		status.ConsecutiveFailures++
+		return
+	}
+	status.Error = ""
+	status.ConsecutiveFailures = 0
+	status.LastSuccessAt = now
+	status.SymbolCount = symbolCount
+	status.Fallback = ""
+}
+
+// recordSourceFallback 记录信号源拉取失败后使用的兜底数据
+func recordSourceFallback(source, apiURL, fallback string, symbolCount int) {
+	sourceStatuses.Lock()
+	defer sourceStatuses.Unlock()
+	if status, ok := sourceStatuses.byKey[sourceStatusKey(source, apiURL)]; ok {
+		status.Fallback = fallback
+		status.SymbolCount = symbolCount
+	}
+}
+
+// GetSourceStatus 获取信号源状态；apiURL 为空时查询系统默认配置的 URL
+func GetSourceStatus(source, apiURL string) SourceStatus {
+	apiURL = resolveSourceURL(source, apiURL)
+
+	result := SourceStatus{Source: source}
+	sourceStatuses.RLock()
+	if status, ok := sourceStatuses.byKey[sourceStatusKey(source, apiURL)]; ok {
+		result = *status
+		result.Fetched = true
+	}
+	sourceStatuses.RUnlock()
+
+	result.URL = RedactURL(apiURL)
+	result.Configured = apiURL != ""
+	if info := GetSnapshotInfo(source, apiURL); !info.FetchedAt.IsZero() {
+		result.Snapshot = &info
+	}
+	return result
+}
+
+// SystemSourceHealth 系统默认配置的信号源状态（未配置的信号源不返回，供健康检查使用）
+func SystemSourceHealth() []SourceStatus {
+	statuses := make([]SourceStatus, 0, 2)
+	if !coinPoolConfig.UseDefaultCoins {
+		if status := GetSourceStatus(SourceAI500, ""); status.Configured {
+			statuses = append(statuses, status)
+		}
+	}
+	if status := GetSourceStatus(SourceOITop, ""); status.Configured {
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// RedactURL 隐藏 URL 中的凭证：去掉用户名密码，查询参数只保留参数名
+func RedactURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "***"
+	}
+	u.User = nil
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, "***")
+		}
+		u.RawQuery = strings.ReplaceAll(query.Encode(), "%2A%2A%2A", "***")
+	}
+	u.Fragment = ""
+	return u.String()
+}
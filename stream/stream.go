@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventDecision  = "decision"  // 决策周期完成（决策记录摘要）
+	EventAccount   = "account"   // 账户快照
+	EventPositions = "positions" // 持仓发生变化
+)
+
+// DefaultBufferSize 每个订阅者的默认缓冲事件数，写满后丢弃最旧的事件
+const DefaultBufferSize = 64
+
+// Event 推送给订阅者的交易员事件
+type Event struct {
+	Type     string      `json:"type"`
+	TraderID string      `json:"trader_id"`
+	Time     time.Time   `json:"time"`
+	Data     interface{} `json:"data"`
+}
+
+// Subscription 一个订阅者（例如一个 WebSocket 连接）
+type Subscription struct {
+	ch      chan Event
+	traders map[string]bool
+	dropped int64
+	closed  bool
+}
+
+var hub = struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}{subs: make(map[*Subscription]struct{})}
+
+// Subscribe 订阅指定交易员的事件；buffer<=0 时使用 DefaultBufferSize
+func Subscribe(traderIDs []string, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+	sub := &Subscription{ch: make(chan Event, buffer), traders: make(map[string]bool)}
+	for _, id := range traderIDs {
+		sub.traders[id] = true
+	}
+
+	hub.mu.Lock()
+	hub.subs[sub] = struct{}{}
+	hub.mu.Unlock()
+	return sub
+}
+
+// Events 事件通道，取消订阅后关闭
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// SetTraders 替换订阅的交易员列表
+func (s *Subscription) SetTraders(traderIDs []string) {
+	traders := make(map[string]bool, len(traderIDs))
+	for _, id := range traderIDs {
+		traders[id] = true
+	}
+	hub.mu.Lock()
+	s.traders = traders
+	hub.mu.Unlock()
+}
+
+// Dropped 因订阅者消费过慢被丢弃的事件数
+func (s *Subscription) Dropped() int64 {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return s.dropped
+}
+
+// Close 取消订阅并关闭事件通道（可重复调用）
+func (s *Subscription) Close() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(hub.subs, s)
+	close(s.ch)
+}
+
+// Publish 将事件推送给订阅了该交易员的所有订阅者，不会阻塞交易周期
+// 订阅者缓冲已满时丢弃其最旧的事件（慢消费者只会丢失旧事件，不影响其他订阅者）
+func Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for sub := range hub.subs {
+		if !sub.traders[ev.TraderID] {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+			continue
+		default:
+		}
+		// 缓冲已满：丢弃最旧的事件后重试
+		select {
+		case <-sub.ch:
+			sub.dropped++
+		default:
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// SubscriberCount 当前订阅者数量
+func SubscriberCount() int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return len(hub.subs)
+}
@@ -0,0 +1,56 @@
+package stream
+
+import "testing"
+
+// TestPublishFiltersByTrader 测试只推送订阅的交易员事件，取消订阅后不再推送
+func TestPublishFiltersByTrader(t *testing.T) {
+	sub := Subscribe([]string{"trader_a"}, 4)
+
+	Publish(Event{Type: EventDecision, TraderID: "trader_b"})
+	Publish(Event{Type: EventDecision, TraderID: "trader_a", Data: 1})
+
+	select {
+	case ev := <-sub.Events():
+		if ev.TraderID != "trader_a" || ev.Time.IsZero() {
+			t.Errorf("收到的事件不正确: %+v", ev)
+		}
+	default:
+		t.Fatal("应收到 trader_a 的事件")
+	}
+	select {
+	case ev := <-sub.Events():
+		t.Fatalf("不应收到未订阅交易员的事件: %+v", ev)
+	default:
+	}
+
+	sub.SetTraders([]string{"trader_b"})
+	Publish(Event{Type: EventAccount, TraderID: "trader_b"})
+	if ev := <-sub.Events(); ev.TraderID != "trader_b" {
+		t.Errorf("切换订阅后应收到 trader_b 的事件: %+v", ev)
+	}
+
+	sub.Close()
+	sub.Close()
+	Publish(Event{Type: EventAccount, TraderID: "trader_b"})
+	if _, ok := <-sub.Events(); ok {
+		t.Error("取消订阅后事件通道应已关闭")
+	}
+}
+
+// TestPublishDropsOldestWhenFull 测试订阅者缓冲写满时丢弃最旧事件，且不阻塞发布
+func TestPublishDropsOldestWhenFull(t *testing.T) {
+	sub := Subscribe([]string{"slow"}, 2)
+	defer sub.Close()
+
+	for i := 1; i <= 5; i++ {
+		Publish(Event{Type: EventDecision, TraderID: "slow", Data: i})
+	}
+
+	if dropped := sub.Dropped(); dropped != 3 {
+		t.Errorf("应丢弃3个事件, 实际 %d", dropped)
+	}
+	first, second := <-sub.Events(), <-sub.Events()
+	if first.Data != 4 || second.Data != 5 {
+		t.Errorf("应保留最新的两个事件(4,5), 实际 %v,%v", first.Data, second.Data)
+	}
+}
@@ -0,0 +1,45 @@
+package trader
+
+import (
+	"nofx/webhook"
+)
+
+// aiBudgetExhausted 当日AI调用次数是否已用完预算（MaxAICallsPerDay=0 表示不限制）
+func (at *AutoTrader) aiBudgetExhausted() bool {
+	budget := at.config.MaxAICallsPerDay
+	return budget > 0 && at.dailyAICallCount.Load() >= int64(budget)
+}
+
+// recordAICall 实际调用AI后累加当日次数，刚好用完预算时推送通知（每天只通知一次）
+func (at *AutoTrader) recordAICall() {
+	calls := int(at.dailyAICallCount.Add(1))
+	budget := at.config.MaxAICallsPerDay
+	if budget <= 0 || calls != budget {
+		return
+	}
+
+	at.log().Infof("💰 [%s] 今日AI调用预算已用完 (%d/%d)，今日剩余时间不再调用AI、不开新仓，只维护止损", at.name, calls, budget)
+	at.emitWebhook(webhook.EventAIBudget, map[string]interface{}{
+		"calls":  calls,
+		"budget": budget,
+	})
+}
+
+// resetDailyAICalls 每日重置AI调用次数
+func (at *AutoTrader) resetDailyAICalls() {
+	at.dailyAICallCount.Store(0)
+}
+
+// GetAIBudget 获取当日已用AI调用次数和剩余次数（不限制时剩余为 -1）
+func (at *AutoTrader) GetAIBudget() (used, remaining int) {
+	used = int(at.dailyAICallCount.Load())
+	budget := at.config.MaxAICallsPerDay
+	if budget <= 0 {
+		return used, -1
+	}
+	remaining = budget - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return used, remaining
+}
@@ -0,0 +1,61 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAIBudgetCutoffAndDailyReset 测试AI调用达到每日预算后停止调用，跨日重置后恢复
+func TestAIBudgetCutoffAndDailyReset(t *testing.T) {
+	at := &AutoTrader{name: "budget", config: AutoTraderConfig{MaxAICallsPerDay: 3}, lastResetTime: time.Now()}
+
+	calls := 0
+	for cycle := 0; cycle < 5; cycle++ {
+		if at.aiBudgetExhausted() {
+			continue
+		}
+		calls++
+		at.recordAICall()
+	}
+	if calls != 3 {
+		t.Fatalf("预算为3时应只调用3次AI, 实际 %d", calls)
+	}
+	if used, remaining := at.GetAIBudget(); used != 3 || remaining != 0 {
+		t.Errorf("预算用完后 used=3 remaining=0, 实际 used=%d remaining=%d", used, remaining)
+	}
+
+	// 重启后次数随状态恢复，不会重新获得当日额度
+	restored := &AutoTrader{name: "restored", config: at.config, lastResetTime: time.Now()}
+	restored.restoreStateJSON(at.buildStateJSON())
+	if !restored.aiBudgetExhausted() {
+		t.Fatal("重启后应保持预算用完状态")
+	}
+
+	// 同一天内不重置
+	restored.maybeResetDailyMetrics()
+	if !restored.aiBudgetExhausted() {
+		t.Fatal("同一天内不应重置AI调用次数")
+	}
+
+	// 跨日重置后恢复调用
+	restored.lastResetTime = time.Now().AddDate(0, 0, -1)
+	restored.maybeResetDailyMetrics()
+	if restored.aiBudgetExhausted() {
+		t.Fatal("跨日后应恢复AI调用")
+	}
+	if used, remaining := restored.GetAIBudget(); used != 0 || remaining != 3 {
+		t.Errorf("重置后 used=0 remaining=3, 实际 used=%d remaining=%d", used, remaining)
+	}
+
+	// 0 表示不限制
+	unlimited := &AutoTrader{name: "unlimited"}
+	for i := 0; i < 100; i++ {
+		unlimited.recordAICall()
+	}
+	if unlimited.aiBudgetExhausted() {
+		t.Error("未设置预算时不应限制AI调用")
+	}
+	if _, remaining := unlimited.GetAIBudget(); remaining != -1 {
+		t.Errorf("不限制时 remaining 应为 -1, 实际 %d", remaining)
+	}
+}
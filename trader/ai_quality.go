@@ -0,0 +1,92 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/webhook"
+	"time"
+)
+
+// defaultAIQualityPause AI输出质量过低自动暂停的默认冷却时长
+const defaultAIQualityPause = 30 * time.Minute
+
+// recordAIQuality 记录一次AI调用结果（failed=无法解析或决策校验失败），并在滑动窗口失败率超过阈值时自动暂停交易
+// 窗口未填满前不做判断；触发暂停后清空窗口，冷却结束后重新统计。返回是否触发了暂停
+func (at *AutoTrader) recordAIQuality(failed bool) bool {
+	window := at.config.AIQualityWindow
+	if window <= 0 || at.config.AIQualityMaxFailurePct <= 0 {
+		return false
+	}
+
+	at.aiQualityMu.Lock()
+	at.aiQualityResults = append(at.aiQualityResults, failed)
+	if len(at.aiQualityResults) > window {
+		at.aiQualityResults = at.aiQualityResults[len(at.aiQualityResults)-window:]
+	}
+	failureRate, samples := aiFailureRate(at.aiQualityResults)
+	if samples < window || failureRate < at.config.AIQualityMaxFailurePct {
+		at.aiQualityMu.Unlock()
+		return false
+	}
+	at.aiQualityResults = nil
+	at.aiQualityMu.Unlock()
+
+	pause := at.config.AIQualityPause
+	if pause <= 0 {
+		pause = defaultAIQualityPause
+	}
+	resumeAt := time.Now().Add(pause)
+	at.setStopUntil(resumeAt)
+
+	reason := fmt.Sprintf("AI输出质量下降：最近 %d 次调用失败率 %.0f%% ≥ %.0f%%", samples, failureRate, at.config.AIQualityMaxFailurePct)
+	at.log().Warnf("⛔ [%s] %s，自动暂停 %v，恢复时间: %s", at.name, reason, pause, resumeAt.Format(time.RFC3339))
+
+	at.emitWebhook(webhook.EventRiskStop, map[string]interface{}{
+		"reason":           reason,
+		"trigger":          "ai_quality",
+		"pause":            pause.String(),
+		"resume_at":        resumeAt.UTC().Format(time.RFC3339),
+		"failure_rate_pct": failureRate,
+		"window":           samples,
+	})
+	return true
+}
+
+// aiFailureRate 计算失败率（百分比）和样本数
+func aiFailureRate(results []bool) (float64, int) {
+	if len(results) == 0 {
+		return 0, 0
+	}
+	failures := 0
+	for _, failed := range results {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(results)) * 100, len(results)
+}
+
+// GetAIQuality 获取滑动窗口内的AI调用失败率（百分比）和样本数
+func (at *AutoTrader) GetAIQuality() (float64, int) {
+	at.aiQualityMu.Lock()
+	defer at.aiQualityMu.Unlock()
+	return aiFailureRate(at.aiQualityResults)
+}
+
+// ResumeTrading 手动解除暂停（风控暂停或AI质量暂停），并清空AI质量统计窗口
+// 返回解除前是否处于暂停状态
+func (at *AutoTrader) ResumeTrading() bool {
+	at.cycleMutex.Lock()
+	defer at.cycleMutex.Unlock()
+
+	paused := time.Now().Before(at.getStopUntil())
+	at.setStopUntil(time.Time{})
+
+	at.aiQualityMu.Lock()
+	at.aiQualityResults = nil
+	at.aiQualityMu.Unlock()
+
+	if paused {
+		at.log().Infof("▶️ [%s] 已手动解除交易暂停", at.name)
+	}
+	return paused
+}
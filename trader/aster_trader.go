@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"nofx/decision"
 	"nofx/hook"
+	"nofx/netproxy"
 	"sort"
 	"strconv"
 	"strings"
@@ -38,6 +39,11 @@ type AsterTrader struct {
 	// 缓存交易对精度信息
 	symbolPrecision map[string]SymbolPrecision
 	mu              sync.RWMutex
+
+	// 交易对最大杠杆缓存
+	maxLeverage maxLeverageCache
+	// 交易对状态缓存（开仓前检查）
+	symbolStatus symbolStatusCache
 }
 
 // SymbolPrecision 交易对精度信息
@@ -52,19 +58,24 @@ type SymbolPrecision struct {
 // user: 主钱包地址 (登录地址)
 // signer: API钱包地址 (从 https://www.asterdex.com/en/api-wallet 获取)
 // privateKey: API钱包私钥 (从 https://www.asterdex.com/en/api-wallet 获取)
-func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
+// transport: 出站代理传输（nil 表示直连）
+func NewAsterTrader(user, signer, privateKeyHex string, transport *http.Transport) (*AsterTrader, error) {
 	// 解析私钥
 	privKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
 	if err != nil {
 		return nil, fmt.Errorf("解析私钥失败: %w", err)
 	}
+	if transport == nil {
+		transport = &http.Transport{}
+	} else {
+		transport = transport.Clone()
+	}
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	transport.ResponseHeaderTimeout = 10 * time.Second
+	transport.IdleConnTimeout = 90 * time.Second
 	client := &http.Client{
-		Timeout: 30 * time.Second, // 增加到30秒
-		Transport: &http.Transport{
-			TLSHandshakeTimeout:   10 * time.Second,
-			ResponseHeaderTimeout: 10 * time.Second,
-			IdleConnTimeout:       90 * time.Second,
-		},
+		Timeout:   30 * time.Second, // 增加到30秒
+		Transport: netproxy.LimitTransport(transport, &exchangeCallLimiter),
 	}
 	res := hook.HookExec[hook.NewAsterTraderResult](hook.NEW_ASTER_TRADER, user, client)
 	if res != nil && res.Error() == nil {
@@ -151,17 +162,13 @@ func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
 	return SymbolPrecision{}, fmt.Errorf("未找到交易对 %s 的精度信息", symbol)
 }
 
-// roundToTickSize 将价格/数量四舍五入到tick size/step size的整数倍
-func roundToTickSize(value float64, tickSize float64) float64 {
-	if tickSize <= 0 {
-		return value
-	}
-	// 计算有多少个tick size
-	steps := value / tickSize
-	// 四舍五入到最近的整数
-	roundedSteps := math.Round(steps)
-	// 乘回tick size
-	return roundedSteps * tickSize
+// GetPriceTick 获取交易对的价格步进值
+func (t *AsterTrader) GetPriceTick(symbol string) (float64, error) {
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return prec.TickSize, nil
 }
 
 // formatPrice 格式化价格到正确精度和tick size
@@ -1213,6 +1220,19 @@ func (t *AsterTrader) CancelAllOrders(symbol string) error {
 	return err
 }
 
+// CancelOrder 按订单ID取消挂单
+func (t *AsterTrader) CancelOrder(symbol string, orderID int64) error {
+	params := map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	}
+
+	if _, err := t.request("DELETE", "/fapi/v3/order", params); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+	return nil
+}
+
 // CancelAllOrdersWithRetry 取消所有掛單（帶重試機制）
 func (t *AsterTrader) CancelAllOrdersWithRetry(symbol string, maxRetries int) error {
 	var lastErr error
@@ -1304,6 +1324,128 @@ func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, e
 	return fmt.Sprintf("%v", formatted), nil
 }
 
+// GetSymbolTradingStatus 获取交易对交易状态（基于 exchangeInfo 的 status 字段，全部交易对一次拉取后短时缓存）
+func (t *AsterTrader) GetSymbolTradingStatus(symbol string) (*SymbolTradingStatus, error) {
+	return t.symbolStatus.lookup(symbol, func() (map[string]string, error) {
+		resp, err := t.client.Get(t.baseURL + "/fapi/v3/exchangeInfo")
+		if err != nil {
+			return nil, fmt.Errorf("获取交易规则失败: %w", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("读取交易规则失败: %w", err)
+		}
+
+		var info struct {
+			Symbols []struct {
+				Symbol string `json:"symbol"`
+				Status string `json:"status"`
+			} `json:"symbols"`
+		}
+		if err := json.Unmarshal(body, &info); err != nil {
+			return nil, fmt.Errorf("解析交易规则失败: %w", err)
+		}
+
+		statuses := make(map[string]string, len(info.Symbols))
+		for _, s := range info.Symbols {
+			statuses[s.Symbol] = s.Status
+		}
+		return statuses, nil
+	})
+}
+
+// GetMaxLeverage 获取交易对允许的最大杠杆（接口与 Binance 一致，全部交易对一次拉取后缓存）
+func (t *AsterTrader) GetMaxLeverage(symbol string) (int, error) {
+	return t.maxLeverage.lookup(symbol, func() (map[string]int, error) {
+		body, err := t.request("GET", "/fapi/v3/leverageBracket", map[string]interface{}{})
+		if err != nil {
+			return nil, fmt.Errorf("获取杠杆分层失败: %w", err)
+		}
+
+		var brackets []struct {
+			Symbol   string `json:"symbol"`
+			Brackets []struct {
+				InitialLeverage int `json:"initialLeverage"`
+			} `json:"brackets"`
+		}
+		if err := json.Unmarshal(body, &brackets); err != nil {
+			return nil, fmt.Errorf("解析杠杆分层失败: %w", err)
+		}
+
+		values := make(map[string]int, len(brackets))
+		for _, b := range brackets {
+			for _, bracket := range b.Brackets {
+				if bracket.InitialLeverage > values[b.Symbol] {
+					values[b.Symbol] = bracket.InitialLeverage
+				}
+			}
+		}
+		return values, nil
+	})
+}
+
+// GetUserTrades 获取账户成交记录（接口与 Binance 一致：必须指定交易对，单次查询不超过7天）
+func (t *AsterTrader) GetUserTrades(symbol string, startTime, endTime time.Time) ([]UserTrade, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("查询成交记录必须指定交易对")
+	}
+
+	return collectUserTrades(startTime, endTime, userTradesPageLimit, func(startMs, endMs int64) ([]UserTrade, error) {
+		body, err := t.request("GET", "/fapi/v3/userTrades", map[string]interface{}{
+			"symbol":    symbol,
+			"startTime": startMs,
+			"endTime":   endMs,
+			"limit":     userTradesPageLimit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取成交记录失败: %w", err)
+		}
+
+		var trades []struct {
+			ID              int64  `json:"id"`
+			OrderID         int64  `json:"orderId"`
+			Symbol          string `json:"symbol"`
+			Side            string `json:"side"`
+			PositionSide    string `json:"positionSide"`
+			Price           string `json:"price"`
+			Qty             string `json:"qty"`
+			RealizedPnl     string `json:"realizedPnl"`
+			Commission      string `json:"commission"`
+			CommissionAsset string `json:"commissionAsset"`
+			Maker           bool   `json:"maker"`
+			Time            int64  `json:"time"`
+		}
+		if err := json.Unmarshal(body, &trades); err != nil {
+			return nil, fmt.Errorf("解析成交记录失败: %w", err)
+		}
+
+		page := make([]UserTrade, 0, len(trades))
+		for _, trade := range trades {
+			price, _ := strconv.ParseFloat(trade.Price, 64)
+			quantity, _ := strconv.ParseFloat(trade.Qty, 64)
+			realizedPnL, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+			fee, _ := strconv.ParseFloat(trade.Commission, 64)
+			page = append(page, UserTrade{
+				ID:           trade.ID,
+				OrderID:      trade.OrderID,
+				Symbol:       trade.Symbol,
+				Side:         trade.Side,
+				PositionSide: trade.PositionSide,
+				Price:        price,
+				Quantity:     quantity,
+				RealizedPnL:  realizedPnL,
+				Fee:          fee,
+				FeeAsset:     trade.CommissionAsset,
+				Maker:        trade.Maker,
+				Time:         trade.Time,
+			})
+		}
+		return page, nil
+	})
+}
+
 // GetOpenOrders retrieves open orders for AI decision context
 // Returns all orders if symbol is empty, otherwise returns orders for the specified symbol
 func (t *AsterTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
@@ -1359,6 +1501,16 @@ func (t *AsterTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, er
 			orderInfo.StopPrice, _ = strconv.ParseFloat(stopPriceStr, 64)
 		}
 
+		if reduceOnly, ok := order["reduceOnly"].(bool); ok {
+			orderInfo.ReduceOnly = reduceOnly
+		}
+		if closePosition, ok := order["closePosition"].(bool); ok && closePosition {
+			orderInfo.ReduceOnly = true
+		}
+		if createdAt, ok := order["time"].(float64); ok {
+			orderInfo.CreatedAt = int64(createdAt)
+		}
+
 		result = append(result, orderInfo)
 	}
 
@@ -283,7 +283,7 @@ func TestNewAsterTrader(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			trader, err := NewAsterTrader(tt.user, tt.signer, tt.privateKeyHex)
+			trader, err := NewAsterTrader(tt.user, tt.signer, tt.privateKeyHex, nil)
 
 			if tt.wantError {
 				assert.Error(t, err)
@@ -333,6 +333,7 @@ func TestCancelAllOrdersWithRetry_Success_FirstAttempt(t *testing.T) {
 		"0x1234567890123456789012345678901234567890",
 		"0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
 		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		nil,
 	)
 	trader.baseURL = mockServer.URL
 
@@ -378,6 +379,7 @@ func TestCancelAllOrdersWithRetry_Success_SecondAttempt(t *testing.T) {
 		"0x1234567890123456789012345678901234567890",
 		"0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
 		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		nil,
 	)
 	trader.baseURL = mockServer.URL
 
@@ -426,6 +428,7 @@ func TestCancelAllOrdersWithRetry_Success_ThirdAttempt(t *testing.T) {
 		"0x1234567890123456789012345678901234567890",
 		"0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
 		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		nil,
 	)
 	trader.baseURL = mockServer.URL
 
@@ -463,6 +466,7 @@ func TestCancelAllOrdersWithRetry_AllFailed(t *testing.T) {
 		"0x1234567890123456789012345678901234567890",
 		"0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
 		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		nil,
 	)
 	trader.baseURL = mockServer.URL
 
@@ -501,6 +505,7 @@ func TestCancelAllOrdersWithRetry_RetryIntervals(t *testing.T) {
 		"0x1234567890123456789012345678901234567890",
 		"0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
 		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		nil,
 	)
 	trader.baseURL = mockServer.URL
 
@@ -574,6 +579,7 @@ func TestCancelAllOrdersWithRetry_MaxRetries(t *testing.T) {
 				"0x1234567890123456789012345678901234567890",
 				"0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
 				"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+				nil,
 			)
 			trader.baseURL = mockServer.URL
 
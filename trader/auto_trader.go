@@ -5,15 +5,23 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"net/http"
 	"nofx/config"
+	"nofx/crypto"
 	"nofx/decision"
 	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
+	"nofx/netproxy"
 	"nofx/pool"
+	"nofx/webhook"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 // AutoTraderConfig 自动交易配置（简化版 - AI全权决策）
@@ -24,11 +32,12 @@ type AutoTraderConfig struct {
 	AIModel string // AI模型: "qwen" 或 "deepseek"
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid" 或 "aster"
+	Exchange string // "binance", "hyperliquid", "aster", "okx" 或 "paper"（模拟盘）
 
 	// 币安API配置
 	BinanceAPIKey    string
 	BinanceSecretKey string
+	BinanceTestnet   bool // 使用币安合约测试网
 
 	// Hyperliquid配置
 	HyperliquidPrivateKey string
@@ -40,6 +49,12 @@ type AutoTraderConfig struct {
 	AsterSigner     string // Aster API钱包地址
 	AsterPrivateKey string // Aster API钱包私钥
 
+	// OKX配置
+	OKXAPIKey     string
+	OKXSecretKey  string
+	OKXPassphrase string
+	OKXTestnet    bool // 使用OKX模拟盘
+
 	CoinPoolAPIURL string
 	OITopAPIURL    string
 
@@ -53,8 +68,13 @@ type AutoTraderConfig struct {
 	CustomAPIKey    string
 	CustomModelName string
 
+	// 模型专属 System Prompt 前缀/后缀（来自AI模型配置，为空表示不添加）
+	SystemPromptPrefix string
+	SystemPromptSuffix string
+
 	// 扫描配置
-	ScanInterval time.Duration // 扫描间隔（建议3分钟）
+	ScanInterval  time.Duration // 扫描间隔（建议3分钟）
+	ScanJitterPct float64       // 扫描间隔随机抖动百分比（例如 10 表示 ±10%，0=不抖动），避免多个交易员长期同时请求
 
 	// 账户配置
 	InitialBalance float64 // 初始金额（用于计算盈亏，需手动设置）
@@ -72,6 +92,11 @@ type AutoTraderConfig struct {
 	MaxDrawdown     float64       // 最大回撤百分比（提示）
 	StopTradingTime time.Duration // 触发风控后暂停时长
 
+	// 风控阈值来源（RiskLimitSourceTrader/RiskLimitSourceSystem，空=系统配置），写入风控暂停日志和决策记录
+	MaxDailyLossSource    string
+	MaxDrawdownSource     string
+	StopTradingTimeSource string
+
 	// 仓位模式
 	IsCrossMargin bool // true=全仓模式, false=逐仓模式
 
@@ -93,6 +118,97 @@ type AutoTraderConfig struct {
 
 	// K线时间线配置
 	Timeframes []string // K线时间线选择，例如: ["1m", "15m", "1h", "4h"]
+	KlineLimit int      // 每个时间线提供给AI的K线数量（0=默认行情格式）
+	Indicators []string // 行情中计算的指标，例如: ["ema50", "rsi14", "weekly_pivots"]（空=默认行情格式）
+
+	// 交易频率限制
+	MaxTradesPerDay int // 每日最多开仓次数（0=不限制），达到后当日只允许平仓和风控操作
+
+	// 总敞口上限：所有持仓名义价值之和 / 账户净值不得超过该倍数（0=不限制），不受AI单笔杠杆选择影响
+	MaxExposureMultiple float64
+
+	// 仓位上限：最多同时持仓数量、单笔开仓最大名义价值 USDT（0=不限制），超出的开仓决策直接拒绝
+	MaxPositions       int
+	MaxPositionSizeUSD float64
+
+	// 币种黑名单（交易员黑名单 + 系统全局黑名单，已统一为大写 USDT 交易对）：从候选币种中移除，执行时拒绝开仓
+	BlacklistedSymbols []string
+
+	// 持有决策缓存：价格变动不超过该百分比且持仓/挂单未变时，复用上一次全部持有的决策，跳过AI调用（0=关闭）
+	HoldCachePct float64
+
+	// 决策记录压缩配置
+	DecisionCompactAfter time.Duration          // 早于该时长的决策记录压缩大文本字段（0=不压缩）
+	DecisionCompactMode  string                 // 压缩方式：gzip（默认，可还原）/ strip（直接清空）
+	DecisionRetention    logger.RetentionPolicy // 决策记录保留策略（超过天数/条数的旧记录归档或删除）
+	DecisionLogCrypto    *crypto.CryptoService  // 决策记录加密服务（读取时解密已加密的提示词字段）
+	EncryptDecisionLogs  bool                   // 新决策记录的提示词/思维链字段加密存储
+
+	// 模型池配置（同一交易员在多个AI模型间切换，用于对比模型表现）
+	ModelPool     []ModelPoolEntry // 模型池成员（少于2个时不启用）
+	ModelPoolMode string           // 选择方式：round_robin（默认）/ random
+	FallbackModel *ModelPoolEntry  // 备用AI模型：主模型调用失败、超时或响应无法解析时重试一次（nil=不启用）
+
+	// 交易员日志级别（debug/info/warn/error，空=使用全局 log.level）
+	LogLevel string
+
+	// 止损/止盈去重：新价格与当前跟踪值偏差在容差内时跳过调整
+	StopUpdateTolerancePct float64 // 容差百分比（0=默认0.01%，负数=关闭去重）
+
+	// 大额开仓价格严格校验：开仓金额达到该值时必须有至少两个数据源价格一致（0=不启用）
+	StrictPriceCheckNotional float64
+
+	// 被守卫检查拒绝的决策写入 rejected_decisions（观察AI想做但未执行的操作）
+	RecordRejections bool
+
+	// 执行失败的决策（交易所拒单、守卫拒绝）在下一周期写入 decision.Context.RecentRejections
+	RejectionFeedback bool
+
+	// 兜底止损：持仓在交易所没有止损单且未跟踪到止损价时，按入场价的该百分比自动设置止损（0=不启用）
+	SafetyStopPct float64
+
+	// 出站代理（访问交易所和AI API，支持 http/https/socks5），为空时直连
+	OutboundProxy string
+
+	// 遵循信号源方向：候选币种带有方向偏好时，拒绝与之相反的开仓（偏多币种不开空，偏空币种不开多）
+	RespectSignalBias bool
+
+	// 模拟运行：使用真实账户的余额和持仓完整跑决策流程，但不向交易所下单，只记录本应执行的订单（上线前验证用）
+	DryRun bool
+
+	// 净值预警（只推送通知，不暂停交易）：净值较峰值回撤超过该百分比时预警（0=不启用）
+	AlertDrawdownPct float64
+
+	// 净值预警：当日亏损超过日初基准净值的该百分比时预警（0=不启用）
+	AlertDailyLossPct float64
+
+	// AI输出质量检测：统计最近 N 次AI调用（0=不启用），失败率达到阈值（%）时自动暂停交易
+	AIQualityWindow        int
+	AIQualityMaxFailurePct float64
+	AIQualityPause         time.Duration // 自动暂停的冷却时长（0=默认30分钟），也可手动解除
+
+	// 连续决策周期失败达到该次数时自动暂停交易员（0=不暂停），通过启动接口恢复
+	MaxConsecutiveErrors int
+
+	// 每日报告：跨日重置时汇总前一交易日的交易、已实现盈亏、胜率和期末净值，保存并推送通知
+	DailyReport bool
+
+	// 未入金判定阈值（USDT）：无持仓且可用余额不超过该值时不调用AI、不尝试开仓，资金到账后自动恢复（0=默认1 USDT）
+	UnfundedThreshold float64
+
+	// 每日AI调用预算（0=不限制）：用完后当日不再调用AI、不开新仓，只维护止损，每日重置
+	MaxAICallsPerDay int
+
+	// 仅允许对本周期候选币种开仓：AI 对候选列表之外的币种开仓时拒绝（默认允许，兼容旧行为）
+	RejectNonCandidates bool
+
+	// 开仓确认延迟（0=不确认）：下单后等待该时长重新读取持仓/挂单，确认开仓生效后才记为成功
+	OpenVerifyDelay time.Duration
+
+	// 交易时间窗口（nil=全天候）：窗口外不开新仓，只允许平仓和调整止盈止损
+	TradingWindow *TradingWindow
+	// 交易窗口外平掉所有持仓（默认保留持仓）
+	FlattenOnWindowClose bool
 }
 
 // AutoTrader 自动交易器
@@ -104,36 +220,71 @@ type AutoTrader struct {
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
 	mcpClient             mcp.AIClient
+	modelPool             []pooledModel          // 模型池（为空表示只使用 mcpClient）
+	outboundTransport     http.RoundTripper      // 出站代理传输（nil 表示直连，模型池客户端共用）
+	modelPoolMode         string                 // 模型池选择方式
+	modelPoolIndex        int                    // 轮换模式下的下一个模型下标
+	fallbackModel         *pooledModel           // 备用模型（nil 表示不启用）
 	decisionLogger        logger.IDecisionLogger // 决策日志记录器
 	initialBalance        float64
 	dailyPnL              float64
 	dailyPnLBase          float64
 	needsDailyBaseline    bool
-	customPrompt          string   // 自定义交易策略prompt
-	overrideBasePrompt    bool     // 是否覆盖基础prompt
-	systemPromptTemplate  string   // 系统提示词模板名称
-	timeframes            []string // K线时间线配置
-	defaultCoins          []string // 默认币种列表（从数据库获取）
-	tradingCoins          []string // 实际交易币种列表
-	useCoinPool           bool     // 是否使用 AI500 Coin Pool 信号源
-	useOITop              bool     // 是否使用 OI Top 增长信号源
+	dailyTradeCount       int                // 当日已开仓次数（用于 MaxTradesPerDay）
+	dailyAICallCount      atomic.Int64       // 当日已调用AI次数（用于 MaxAICallsPerDay，组长选举时并发读取）
+	holdCache             *holdDecisionCache // 上一次全部持有的决策缓存（用于 HoldCachePct）
+	fillsCache            exchangeFillsCache // 交易所成交记录短时缓存（对账接口）
+	rejectionFeedback     rejectionFeedback  // 执行失败的决策，下一周期反馈给AI
+	signalBias            map[string]string  // 本周期候选币种的信号方向偏好 (symbol -> long/short)
+	candidateSymbols      map[string]bool    // 本周期候选币种集合（用于 RejectNonCandidates）
+	streamedPositions     string             // 上次推送给订阅者的持仓签名（变化时才推送持仓事件）
+	customPrompt          string             // 自定义交易策略prompt
+	overrideBasePrompt    bool               // 是否覆盖基础prompt
+	systemPromptTemplate  string             // 系统提示词模板名称
+	timeframes            []string           // K线时间线配置
+	defaultCoins          []string           // 默认币种列表（从数据库获取）
+	tradingCoins          []string           // 实际交易币种列表
+	useCoinPool           bool               // 是否使用 AI500 Coin Pool 信号源
+	useOITop              bool               // 是否使用 OI Top 增长信号源
 	coinPoolAPIURL        string
 	oiTopAPIURL           string
 	lastResetTime         time.Time
-	stopUntil             time.Time
-	isRunning             bool
+	stopUntil             time.Time                        // 风控/AI质量暂停的恢复时间（组长读取成员状态，经 stopUntilMutex 访问）
+	stopUntilMutex        sync.RWMutex                     // 保护 stopUntil
+	isRunning             atomic.Bool                      // 是否运行中（组长并发读取成员状态）
 	startTime             time.Time                        // 系统启动时间
+	firstCycleDelay       time.Duration                    // 首次决策周期延迟（错开多个交易员的首次扫描）
 	callCount             int                              // AI调用次数
+	logCycle              atomic.Int64                     // 当前决策周期编号（日志字段，监控协程并发读取）
+	runtimeLog            *logrus.Entry                    // 结构化 logger（trader_id/user_id/component 字段）
 	positionFirstSeenTime map[string]int64                 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	pendingEntries        map[string]*pendingEntry         // 挂单中尚未成交的限价开仓单 (symbol_side -> 挂单)
 	lastPositions         map[string]decision.PositionInfo // 上一次周期的持仓快照 (用于检测被动平仓)
 	positionStopLoss      map[string]float64               // 持仓止损价格 (symbol_side -> stop_loss_price)
 	positionTakeProfit    map[string]float64               // 持仓止盈价格 (symbol_side -> take_profit_price)
+	externalPositions     map[string]bool                  // 对账接管的外部持仓 (symbol_side -> true，非本交易员开仓)
 	stopMonitorCh         chan struct{}                    // 用于停止监控goroutine
 	monitorWg             sync.WaitGroup                   // 用于等待监控goroutine结束
 	peakPnLCache          map[string]float64               // 最高收益缓存 (symbol -> 峰值盈亏百分比)
 	peakPnLCacheMutex     sync.RWMutex                     // 缓存读写锁
+	trailingStops         map[string]TrailingStop          // 追踪止损状态 (symbol_side -> 状态)
+	trailingStopsMutex    sync.RWMutex                     // 追踪止损读写锁（回撤监控协程并发访问）
+	annotations           map[string]PositionAnnotation    // 持仓人工备注和锁定 (symbol_side -> 备注)
+	annotationsMutex      sync.RWMutex                     // 持仓备注读写锁（API 并发修改）
 	peakEquity            float64                          // 账户峰值净值，用于回撤计算
+	equityAlertActive     map[string]bool                  // 已触发、尚未重新布防的净值预警 (类型 -> true)
 	lastBalanceSyncTime   time.Time                        // 上次余额同步时间
+	tradingStatusMutex    sync.RWMutex                     // 交易状态读写锁
+	exchangeMaintenance   bool                             // 交易所是否处于维护中（最近一次检查结果）
+	haltedSymbols         map[string]string                // 暂停交易的币种 (symbol -> 原因)
+	unfunded              bool                             // 账户是否未入金（最近一次周期检查的结果）
+	cycleMutex            sync.Mutex                       // 决策周期锁（组合模式下组长代成员执行时使用）
+	aiQualityResults      []bool                           // 最近AI调用结果滑动窗口（true=失败）
+	aiQualityMu           sync.Mutex                       // 保护 aiQualityResults（状态接口并发读取）
+	cycleHealth           cycleHealth                      // 连续失败的决策周期统计（列表/状态接口的健康状态）
+	portfolio             atomic.Pointer[PortfolioGroup]   // 组合模式分组（nil 表示独立决策；Join/Leave 与周期并发修改）
+	persistQueue          *persistRetryQueue               // 持久化写入重试队列（交易记录/状态）
+	symbolRegistry        *SymbolPositionRegistry          // 全实例币种持仓登记表（由 TraderManager 注入，nil 表示不限制）
 	database              interface{}                      // 数据库引用（用于自动更新余额）
 	userID                string                           // 用户ID
 }
@@ -198,29 +349,60 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	}
 	log.Printf("📊 [%s] 仓位模式: %s", config.Name, marginModeStr)
 
+	// 🌐 出站代理：AI 和交易所请求走同一个代理
+	outboundTransport, err := netproxy.NewTransport(config.OutboundProxy)
+	if err != nil {
+		return nil, fmt.Errorf("出站代理配置无效: %w", err)
+	}
+	if outboundTransport != nil {
+		mcpClient.SetTransport(outboundTransport)
+		log.Printf("🌐 [%s] 出站请求使用代理: %s", config.Name, netproxy.Redact(config.OutboundProxy))
+	}
+
 	switch config.Exchange {
 	case "binance":
 		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
 		trader = NewFuturesTrader(
 			config.BinanceAPIKey,
 			config.BinanceSecretKey,
+			config.BinanceTestnet,
 			userID,
 			config.OrderStrategy,
 			config.LimitPriceOffset,
 			config.LimitTimeoutSeconds,
+			outboundTransport,
 		)
 	case "hyperliquid":
 		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
+		if outboundTransport != nil {
+			// Hyperliquid SDK 不支持自定义 HTTP 客户端，只能通过标准的 HTTPS_PROXY 环境变量走代理
+			log.Printf("⚠️ [%s] Hyperliquid 交易器不支持单独配置代理，请设置 HTTPS_PROXY 环境变量", config.Name)
+		}
 		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
 		if err != nil {
 			return nil, fmt.Errorf("初始化Hyperliquid交易器失败: %w", err)
 		}
 	case "aster":
 		log.Printf("🏦 [%s] 使用Aster交易", config.Name)
-		trader, err = NewAsterTrader(config.AsterUser, config.AsterSigner, config.AsterPrivateKey)
+		trader, err = NewAsterTrader(config.AsterUser, config.AsterSigner, config.AsterPrivateKey, outboundTransport)
 		if err != nil {
 			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
 		}
+	case "okx":
+		log.Printf("🏦 [%s] 使用OKX合约交易", config.Name)
+		trader, err = NewOKXTrader(config.OKXAPIKey, config.OKXSecretKey, config.OKXPassphrase, config.OKXTestnet, outboundTransport)
+		if err != nil {
+			return nil, fmt.Errorf("初始化OKX交易器失败: %w", err)
+		}
+	case "paper":
+		log.Printf("📄 [%s] 使用模拟盘交易（实时行情，不向交易所下单）", config.Name)
+		trader = NewPaperTrader(
+			filepath.Join(defaultPaperStateDir, config.ID+".json"),
+			config.InitialBalance,
+			config.TakerFeeRate,
+			config.MakerFeeRate,
+			config.OrderStrategy,
+		)
 	default:
 		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
@@ -232,7 +414,13 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
 	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
-	decisionLogger := logger.NewDecisionLogger(logDir)
+	decisionLogger := logger.NewDecisionLoggerWithRetention(logDir, config.DecisionRetention)
+	if config.DecisionLogCrypto != nil {
+		decisionLogger.SetCryptoService(config.DecisionLogCrypto, config.EncryptDecisionLogs)
+	}
+
+	// 持久化重试队列（写入失败的交易记录/状态，最终失败写入死信文件）
+	persistQueue := newPersistRetryQueue(defaultPersistMaxRetries, fmt.Sprintf("%s/%s.jsonl", defaultDeadLetterDir, config.ID))
 
 	// 设置默认系统提示词模板
 	systemPromptTemplate := config.SystemPromptTemplate
@@ -245,14 +433,16 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	restoredCallCount := 0
 	restoredPeakEquity := config.InitialBalance
 	restoredLastResetTime := time.Now()
+	restoredStateJSON := ""
 
 	if db, ok := database.(interface {
 		LoadTraderState(string) (int, float64, int64, string, error)
 		GetOpenPositionsFromHistory(string) (map[string]map[string]interface{}, error)
 	}); ok {
 		// 恢復狀態
-		callCount, peakEquity, lastResetTimeUnix, _, err := db.LoadTraderState(config.ID)
+		callCount, peakEquity, lastResetTimeUnix, stateJSON, err := db.LoadTraderState(config.ID)
 		if err == nil {
+			restoredStateJSON = stateJSON
 			restoredCallCount = callCount
 			if peakEquity > 0 {
 				restoredPeakEquity = peakEquity
@@ -286,21 +476,36 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		peakEquity:            restoredPeakEquity,
 		startTime:             time.Now(),
 		callCount:             restoredCallCount,
-		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
 		lastPositions:         make(map[string]decision.PositionInfo),
 		positionStopLoss:      make(map[string]float64),
 		positionTakeProfit:    make(map[string]float64),
+		externalPositions:     make(map[string]bool),
 		stopMonitorCh:         make(chan struct{}),
 		monitorWg:             sync.WaitGroup{},
 		peakPnLCache:          make(map[string]float64),
 		peakPnLCacheMutex:     sync.RWMutex{},
+		trailingStops:         make(map[string]TrailingStop),
+		annotations:           make(map[string]PositionAnnotation),
 		lastBalanceSyncTime:   time.Now(), // 初始化为当前时间
+		haltedSymbols:         make(map[string]string),
+		persistQueue:          persistQueue,
 		database:              database,
 		userID:                userID,
 		coinPoolAPIURL:        strings.TrimSpace(config.CoinPoolAPIURL),
 		oiTopAPIURL:           strings.TrimSpace(config.OITopAPIURL),
+		runtimeLog:            logger.ForTrader(config.ID, userID, config.LogLevel),
+	}
+
+	// 恢復擴展狀態（當日開倉次數、持倉快照等）
+	at.restoreStateJSON(restoredStateJSON)
+
+	// 初始化模型池（每个周期从池中选择一个模型）
+	if outboundTransport != nil {
+		at.outboundTransport = outboundTransport
 	}
+	at.initModelPool(config.ModelPool, config.ModelPoolMode)
+	at.initFallbackModel(config.FallbackModel)
 
 	// 🔧 P0修復：恢復持倉記錄（從交易歷史重建）
 	if db, ok := database.(interface {
@@ -318,46 +523,79 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 				if takeProfit, ok := pos["take_profit"].(float64); ok && takeProfit > 0 {
 					at.positionTakeProfit[key] = takeProfit
 				}
+				if external, _ := pos["external"].(bool); external {
+					symbol, _ := pos["symbol"].(string)
+					side, _ := pos["side"].(string)
+					at.externalPositions[symbol+"_"+strings.ToLower(side)] = true
+				}
 			}
 			log.Printf("✅ [%s] 從數據庫恢復 %d 個持倉記錄", config.Name, len(positions))
 		}
 	}
 
+	// 恢复持仓的人工备注和锁定状态
+	at.loadPositionAnnotations()
+
 	return at, nil
 }
 
 // Run 运行自动交易主循环
 func (at *AutoTrader) Run() error {
-	at.isRunning = true
+	at.isRunning.Store(true)
 	at.stopMonitorCh = make(chan struct{})
 	at.startTime = time.Now()
+	at.resetCycleHealth()
 
-	log.Println("🚀 AI驱动自动交易系统启动")
-	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
-	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
-	log.Println("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
+	at.log().Infoln("🚀 AI驱动自动交易系统启动")
+	at.log().Infof("💰 初始余额: %.2f USDT", at.initialBalance)
+	at.log().Infof("⚙️  扫描间隔: %v", at.config.ScanInterval)
+	if at.config.ScanJitterPct > 0 {
+		at.log().Infof("⚙️  扫描间隔抖动: ±%.1f%%", at.config.ScanJitterPct)
+	}
+	at.log().Infoln("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
+	if at.config.DryRun {
+		at.log().Infoln("🧪 模拟运行模式：使用真实账户数据决策，但不会向交易所下单")
+	}
 	at.monitorWg.Add(1)
 	defer at.monitorWg.Done()
 
 	// 启动回撤监控
 	at.startDrawdownMonitor()
 
-	ticker := time.NewTicker(at.config.ScanInterval)
-	defer ticker.Stop()
+	// 启动持久化重试
+	at.startPersistRetryWorker()
+
+	// 启动决策记录压缩
+	at.startDecisionCompactWorker()
 
-	// 首次立即执行
-	if err := at.runCycle(); err != nil {
-		log.Printf("❌ 执行失败: %v", err)
+	// 首次执行：未设置延迟时立即执行，否则等待延迟（错开多个交易员的首次扫描）
+	wait := at.firstCycleDelay
+	if wait > 0 {
+		at.log().Infof("[%s] ⏱ 首次决策周期延迟 %v", at.name, wait)
 	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
 
-	for at.isRunning {
+	for at.isRunning.Load() {
 		select {
-		case <-ticker.C:
-			if err := at.runCycle(); err != nil {
-				log.Printf("❌ 执行失败: %v", err)
+		case <-timer.C:
+			// 间隔从周期开始计算（与固定 ticker 一致），每次重新计算抖动
+			cycleStart := time.Now()
+			err := at.runCycle()
+			if err != nil {
+				at.log().Errorf("❌ 执行失败: %v", err)
 			}
+			if at.recordCycleResult(err) {
+				at.haltOnErrors()
+				return nil
+			}
+			next := at.nextScanInterval() - time.Since(cycleStart)
+			if next < 0 {
+				next = 0
+			}
+			timer.Reset(next)
 		case <-at.stopMonitorCh:
-			log.Printf("[%s] ⏹ 收到停止信号，退出自动交易主循环", at.name)
+			at.log().Infof("[%s] ⏹ 收到停止信号，退出自动交易主循环", at.name)
 			return nil
 		}
 	}
@@ -365,23 +603,41 @@ func (at *AutoTrader) Run() error {
 	return nil
 }
 
+// getStopUntil 风控/AI质量暂停的恢复时间（零值表示未暂停）
+func (at *AutoTrader) getStopUntil() time.Time {
+	at.stopUntilMutex.RLock()
+	defer at.stopUntilMutex.RUnlock()
+	return at.stopUntil
+}
+
+// setStopUntil 设置暂停恢复时间（零值解除暂停）
+func (at *AutoTrader) setStopUntil(t time.Time) {
+	at.stopUntilMutex.Lock()
+	defer at.stopUntilMutex.Unlock()
+	at.stopUntil = t
+}
+
 // Stop 停止自动交易
 func (at *AutoTrader) Stop() {
-	if !at.isRunning {
+	if !at.isRunning.CompareAndSwap(true, false) {
 		return
 	}
-	at.isRunning = false
 	close(at.stopMonitorCh) // 通知监控goroutine停止
 	at.monitorWg.Wait()     // 等待监控goroutine结束
-	log.Println("⏹ 自动交易系统停止")
+	at.flushPersistQueue()  // 清空持久化重试队列
+	at.log().Infoln("⏹ 自动交易系统停止")
 }
 
 // runCycle 运行一个交易周期（使用AI全权决策）
 func (at *AutoTrader) runCycle() error {
+	at.cycleMutex.Lock()
+	defer at.cycleMutex.Unlock()
+
 	at.callCount++
+	at.logCycle.Store(int64(at.callCount))
 
 	log.Print("\n" + strings.Repeat("=", 70) + "\n")
-	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
+	at.log().Infof("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
 	log.Println(strings.Repeat("=", 70))
 
 	// 创建决策记录
@@ -390,11 +646,25 @@ func (at *AutoTrader) runCycle() error {
 		ExecutionLog: []string{},
 		Success:      true,
 	}
+	// 周期结束（所有分支都已保存决策记录）后推送给实时订阅者
+	defer at.publishCycle(record)
+
+	// 系统维护模式：跳过整个决策周期，持仓和止损止盈单保持不变
+	if m := CurrentMaintenanceMode(); m.Enabled {
+		at.log().Infof("🛠 [%s] 系统维护模式（maintenance mode），跳过本周期决策", at.name)
+		record.Success = false
+		record.ErrorMessage = "系统维护模式（maintenance mode）"
+		if m.Message != "" {
+			record.ErrorMessage += ": " + m.Message
+		}
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
 
 	// 1. 检查是否需要停止交易
-	if time.Now().Before(at.stopUntil) {
-		remaining := at.stopUntil.Sub(time.Now())
-		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
+	if stopUntil := at.getStopUntil(); time.Now().Before(stopUntil) {
+		remaining := time.Until(stopUntil)
+		at.log().Infof("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
 		at.decisionLogger.LogDecision(record)
@@ -404,12 +674,18 @@ func (at *AutoTrader) runCycle() error {
 	// 2. 重置日盈亏基线（每天一次）
 	at.maybeResetDailyMetrics()
 
+	// 挂单中的限价开仓单：已成交的补记开仓并设置止损止盈，已撤销/过期的释放币种名额
+	record.ExecutionLog = append(record.ExecutionLog, at.resolvePendingEntries()...)
+
 	// 🔧 階段1修復#4: 同步交易所自動平倉（檢測數據庫與交易所不一致）
 	if err := at.syncAutoClosedPositions(); err != nil {
-		log.Printf("⚠️ 同步交易所狀態失敗: %v", err)
+		at.log().Warnf("⚠️ 同步交易所狀態失敗: %v", err)
 		// 不返回錯誤，繼續執行交易週期
 	}
 
+	// 接管交易所上的外部持仓（手动/其他程序开仓），补记开仓记录
+	record.ExecutionLog = append(record.ExecutionLog, at.reconcileExternalPositions()...)
+
 	// 4. 收集交易上下文
 	ctx, err := at.buildTradingContext()
 	if err != nil {
@@ -444,11 +720,14 @@ func (at *AutoTrader) runCycle() error {
 	}
 
 	// 更新盈亏指标并执行账户级风控
-	if reason, triggered := at.enforceRiskLimits(ctx.Account.TotalEquity); triggered {
+	reason, triggered := at.enforceRiskLimits(ctx.Account.TotalEquity)
+	at.publishMetrics(ctx.Account) // Prometheus 交易指标
+	at.recordEquitySnapshot(ctx.Account)
+	if triggered {
 		record.Success = false
 		record.ErrorMessage = reason
 		at.decisionLogger.LogDecision(record)
-		log.Printf("⛔ 风险控制触发，暂停交易：%s | 恢复时间: %s", reason, at.stopUntil.Format(time.RFC3339))
+		at.log().Warnf("⛔ 风险控制触发，暂停交易：%s | 恢复时间: %s", reason, at.getStopUntil().Format(time.RFC3339))
 		return nil
 	}
 
@@ -457,7 +736,7 @@ func (at *AutoTrader) runCycle() error {
 	if len(closedPositions) > 0 {
 		autoCloseActions := at.generateAutoCloseActions(closedPositions)
 		record.Decisions = append(record.Decisions, autoCloseActions...)
-		log.Printf("🔔 检测到 %d 个被动平仓", len(closedPositions))
+		at.log().Infof("🔔 检测到 %d 个被动平仓", len(closedPositions))
 		for i, closed := range closedPositions {
 			action := autoCloseActions[i]
 			pnl := closed.Quantity * (closed.MarkPrice - closed.EntryPrice)
@@ -478,13 +757,14 @@ func (at *AutoTrader) runCycle() error {
 				reasonCN = action.Error
 			}
 
-			log.Printf("   └─ %s %s | 开仓: %.4f → 平仓: %.4f | 盈亏: %+.2f%% | 原因: %s",
+			at.log().Infof("   └─ %s %s | 开仓: %.4f → 平仓: %.4f | 盈亏: %+.2f%% | 原因: %s",
 				closed.Symbol,
 				closed.Side,
 				closed.EntryPrice,
 				action.Price, // 使用推断的平仓价格
 				pnlPct,
 				reasonCN)
+			at.emitPassiveCloseEvent(closed, action.Price, action.Error)
 		}
 	}
 
@@ -493,23 +773,86 @@ func (at *AutoTrader) runCycle() error {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
 	}
 
-	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
+	at.log().Infof("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
+	unfunded := at.checkFunding(ctx.Account.AvailableBalance, ctx.Account.PositionCount)
+
+	// 🧩 组合模式：非组长成员不单独调用AI，由组长合并账户后统一决策并分配执行
+	group := at.portfolio.Load()
+	if group != nil && !group.IsLeader(at) {
+		at.log().Infof("🧩 组合模式 [%s]：由组长统一决策，本周期跳过独立AI调用", group.Name())
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("组合模式 [%s]：由组长统一决策", group.Name()))
+		at.updatePositionSnapshot(ctx.Positions)
+		if err := at.decisionLogger.LogDecision(record); err != nil {
+			at.log().Warnf("⚠ 保存决策记录失败: %v", err)
+		}
+		at.saveTraderState()
+		return nil
+	}
+
+	// 💸 未入金：没有持仓也没有可用资金，跳过AI调用，等待资金到账后自动恢复（组长仍需为组合成员决策）
+	if unfunded && group == nil {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("账户未入金：可用余额 %.2f ≤ %.2f USDT，等待资金到账", ctx.Account.AvailableBalance, at.unfundedThreshold())
+		at.updatePositionSnapshot(ctx.Positions)
+		if err := at.decisionLogger.LogDecision(record); err != nil {
+			at.log().Warnf("⚠ 保存决策记录失败: %v", err)
+		}
+		at.saveTraderState()
+		return nil
+	}
+
+	// 🕒 交易时间窗口外：不开新仓，没有持仓时不调用AI，进入窗口后自动恢复
+	outsideWindow := at.outsideTradingWindow()
+	if outsideWindow && at.skipOutsideWindow(ctx.Positions, record) {
+		return nil
+	}
+
+	// 💰 AI调用预算用完：当日不再调用AI（不开新仓），继续维护止损，每日重置后恢复
+	if at.aiBudgetExhausted() {
+		used, _ := at.GetAIBudget()
+		at.log().Infof("💰 [%s] 今日AI调用预算已用完 (%d/%d)，跳过AI决策，只维护止损", at.name, used, at.config.MaxAICallsPerDay)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("💰 今日AI调用预算已用完 (%d/%d)，跳过AI决策", used, at.config.MaxAICallsPerDay))
+		at.appendSafetyStops(record)
+		at.updatePositionSnapshot(ctx.Positions)
+		if err := at.decisionLogger.LogDecision(record); err != nil {
+			at.log().Warnf("⚠ 保存决策记录失败: %v", err)
+		}
+		at.saveTraderState()
+		return nil
+	}
+
 	// 5. 调用AI获取完整决策
-	log.Printf("🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
-	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	at.log().Infof("🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
+	decision, ownDecisions, err := at.requestDecision(ctx, record)
+	if !record.CachedDecision {
+		at.recordAICall()
+		if at.recordAIQuality(err != nil) {
+			record.ExecutionLog = append(record.ExecutionLog, "⛔ AI输出质量下降，已自动暂停交易")
+		}
+	}
 
 	if decision != nil && decision.AIRequestDurationMs > 0 {
 		record.AIRequestDurationMs = decision.AIRequestDurationMs
-		log.Printf("⏱️ AI调用耗时: %.2f 秒", float64(record.AIRequestDurationMs)/1000)
+		at.log().Infof("⏱️ AI调用耗时: %.2f 秒", float64(record.AIRequestDurationMs)/1000)
 		record.ExecutionLog = append(record.ExecutionLog,
 			fmt.Sprintf("AI调用耗时: %d ms", record.AIRequestDurationMs))
 	}
+	if decision != nil && decision.Usage != nil {
+		record.PromptTokens = decision.Usage.PromptTokens
+		record.CompletionTokens = decision.Usage.CompletionTokens
+		record.TotalTokens = decision.Usage.TotalTokens
+		record.AICost = decision.Usage.Cost
+		record.ExecutionLog = append(record.ExecutionLog,
+			fmt.Sprintf("AI token用量: 输入 %d / 输出 %d", record.PromptTokens, record.CompletionTokens))
+	}
 
 	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
 	if decision != nil {
 		record.SystemPrompt = decision.SystemPrompt // 保存系统提示词
+		record.PromptTemplate = decision.PromptTemplate
+		record.PromptVersion = decision.PromptVersion
 		record.InputPrompt = decision.UserPrompt
 		record.CoTTrace = decision.CoTTrace
 		if len(decision.Decisions) > 0 {
@@ -525,16 +868,16 @@ func (at *AutoTrader) runCycle() error {
 		// 打印系统提示词和AI思维链（即使有错误，也要输出以便调试）
 		if decision != nil {
 			log.Print("\n" + strings.Repeat("=", 70) + "\n")
-			log.Printf("📋 系统提示词 [模板: %s] (错误情况)", at.systemPromptTemplate)
+			at.log().Infof("📋 系统提示词 [模板: %s] (错误情况)", at.systemPromptTemplate)
 			log.Println(strings.Repeat("=", 70))
-			log.Println(decision.SystemPrompt)
+			at.log().Infoln(decision.SystemPrompt)
 			log.Println(strings.Repeat("=", 70))
 
 			if decision.CoTTrace != "" {
 				log.Print("\n" + strings.Repeat("-", 70) + "\n")
-				log.Println("💭 AI思维链分析（错误情况）:")
+				at.log().Infoln("💭 AI思维链分析（错误情况）:")
 				log.Println(strings.Repeat("-", 70))
-				log.Println(decision.CoTTrace)
+				at.log().Infoln(decision.CoTTrace)
 				log.Println(strings.Repeat("-", 70))
 			}
 		}
@@ -566,19 +909,22 @@ func (at *AutoTrader) runCycle() error {
 	//           d.Leverage, d.PositionSizeUSD, d.StopLoss, d.TakeProfit)
 	//     }
 	// }
-	log.Println()
+	at.log().Infoln()
 	log.Print(strings.Repeat("-", 70))
 	// 8. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
 	log.Print(strings.Repeat("-", 70))
 
 	// 8. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
-	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
+	if outsideWindow {
+		ownDecisions = at.dropOpenDecisions(ownDecisions, record)
+	}
+	sortedDecisions := sortDecisionsByPriority(ownDecisions)
 
-	log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
+	at.log().Infoln("🔄 执行顺序（已优化）: 先平仓→后开仓")
 	for i, d := range sortedDecisions {
-		log.Printf("  [%d] %s %s", i+1, d.Symbol, d.Action)
+		at.log().Infof("  [%d] %s %s", i+1, d.Symbol, d.Action)
 	}
-	log.Println()
+	at.log().Infoln()
 
 	// 执行决策并记录结果
 	for _, d := range sortedDecisions {
@@ -593,9 +939,17 @@ func (at *AutoTrader) runCycle() error {
 		}
 
 		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
-			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
-			actionRecord.Error = err.Error()
+			at.log().Errorf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+			recordActionError(&actionRecord, err)
+			at.recordRejection(&d, err)
+			at.captureRejectionFeedback(&d, err)
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+		} else if actionRecord.Skipped {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏭ %s %s 跳过: 与当前挂单价格相同", d.Symbol, d.Action))
+		} else if actionRecord.DryRun {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧪 %s %s 模拟运行: 未向交易所下单", d.Symbol, d.Action))
 		} else {
 			actionRecord.Success = true
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
@@ -606,54 +960,80 @@ func (at *AutoTrader) runCycle() error {
 		record.Decisions = append(record.Decisions, actionRecord)
 	}
 
+	// 兜底止损：确保每个持仓在交易所都有止损单
+	at.appendSafetyStops(record)
+
 	// 9. 更新持仓快照（用于下一周期检测被动平仓）
 	at.updatePositionSnapshot(ctx.Positions)
 
 	// 10. 保存决策记录
 	if err := at.decisionLogger.LogDecision(record); err != nil {
-		log.Printf("⚠ 保存决策记录失败: %v", err)
+		at.log().Warnf("⚠ 保存决策记录失败: %v", err)
 	}
 
 	// 🔧 P0修復：每個週期結束後保存狀態到數據庫
-	if db, ok := at.database.(interface {
-		SaveTraderState(string, string, int, float64, int64, string) error
-	}); ok {
-		stateJSON := "{}" // 預留給未來擴展
-		if err := db.SaveTraderState(
-			at.config.ID,
-			at.userID,
-			at.callCount,
-			at.peakEquity,
-			at.lastResetTime.UnixMilli(),
-			stateJSON,
-		); err != nil {
-			log.Printf("⚠️ 保存狀態到數據庫失敗: %v", err)
-		}
-	}
+	at.saveTraderState()
 
 	return nil
 }
 
+// saveTraderState 保存交易员运行状态到数据库
+func (at *AutoTrader) saveTraderState() {
+	if db, ok := at.database.(traderStateSaver); ok {
+		stateJSON := at.buildStateJSON()
+		if err := at.saveTraderStateWithRetry(db, traderStatePayload{
+			TraderID:      at.config.ID,
+			UserID:        at.userID,
+			CallCount:     at.callCount,
+			PeakEquity:    at.peakEquity,
+			LastResetTime: at.lastResetTime.UnixMilli(),
+			StateJSON:     stateJSON,
+		}); err != nil {
+			at.log().Warnf("⚠️ 保存狀態到數據庫失敗: %v", err)
+		}
+	}
+}
+
 // 每日重置盈亏基线
 func (at *AutoTrader) maybeResetDailyMetrics() {
 	now := time.Now()
 	if at.lastResetTime.IsZero() || !sameDay(at.lastResetTime, now) {
+		if at.config.DailyReport && !at.lastResetTime.IsZero() {
+			at.generateDailyReport(at.lastResetTime)
+		}
 		at.dailyPnL = 0
 		at.dailyPnLBase = 0
 		at.needsDailyBaseline = true
 		at.lastResetTime = now
-		log.Println("📅 日盈亏已重置，等待新的基准净值")
+		at.resetDailyTradeCount()
+		at.resetDailyAICalls()
+		at.log().Infoln("📅 日盈亏已重置，等待新的基准净值")
 	}
 }
 
+// 风控阈值来源
+const (
+	RiskLimitSourceTrader = "trader" // 交易员级覆盖
+	RiskLimitSourceSystem = "system" // 系统配置
+)
+
+// riskLimitLevel 风控阈值来源的展示名称
+func riskLimitLevel(source string) string {
+	if source == RiskLimitSourceTrader {
+		return "交易员级"
+	}
+	return "系统级"
+}
+
 func (at *AutoTrader) enforceRiskLimits(currentEquity float64) (string, bool) {
 	at.updatePnLMetrics(currentEquity)
+	at.checkEquityAlerts(currentEquity)
 
 	if limit := at.config.MaxDailyLoss; limit > 0 && at.dailyPnLBase > 0 {
 		maxLoss := -at.dailyPnLBase * limit / 100
 		if at.dailyPnL <= maxLoss {
-			reason := fmt.Sprintf("触发当日最大亏损 %.2f%% (盈亏 %.2f / 基准 %.2f USDT)", limit, at.dailyPnL, at.dailyPnLBase)
-			at.activateRiskStop()
+			reason := fmt.Sprintf("触发%s当日最大亏损 %.2f%% (盈亏 %.2f / 基准 %.2f USDT)", riskLimitLevel(at.config.MaxDailyLossSource), limit, at.dailyPnL, at.dailyPnLBase)
+			at.activateRiskStop(reason)
 			return reason, true
 		}
 	}
@@ -661,8 +1041,8 @@ func (at *AutoTrader) enforceRiskLimits(currentEquity float64) (string, bool) {
 	if dd := at.config.MaxDrawdown; dd > 0 && at.peakEquity > 0 {
 		drawdownPct := (at.peakEquity - currentEquity) / at.peakEquity * 100
 		if drawdownPct >= dd {
-			reason := fmt.Sprintf("触发账户回撤 %.2f%% (峰值 %.2f → 当前 %.2f)", drawdownPct, at.peakEquity, currentEquity)
-			at.activateRiskStop()
+			reason := fmt.Sprintf("触发%s账户回撤上限 %.2f%%: 回撤 %.2f%% (峰值 %.2f → 当前 %.2f)", riskLimitLevel(at.config.MaxDrawdownSource), dd, drawdownPct, at.peakEquity, currentEquity)
+			at.activateRiskStop(reason)
 			return reason, true
 		}
 	}
@@ -675,7 +1055,7 @@ func (at *AutoTrader) updatePnLMetrics(currentEquity float64) {
 		at.dailyPnLBase = currentEquity
 		at.dailyPnL = 0
 		at.needsDailyBaseline = false
-		log.Printf("📊 日盈亏基准同步：%.2f USDT", currentEquity)
+		at.log().Infof("📊 日盈亏基准同步：%.2f USDT", currentEquity)
 	} else {
 		at.dailyPnL = currentEquity - at.dailyPnLBase
 	}
@@ -685,17 +1065,33 @@ func (at *AutoTrader) updatePnLMetrics(currentEquity float64) {
 	}
 }
 
-func (at *AutoTrader) activateRiskStop() {
+func (at *AutoTrader) activateRiskStop(reason string) {
 	pause := at.config.StopTradingTime
 	if pause <= 0 {
 		pause = 60 * time.Minute
 	}
-	at.stopUntil = time.Now().Add(pause)
-	log.Printf("⚠️ 触发风险暂停，暂停时长: %v，恢复时间: %s", pause, at.stopUntil.Format(time.RFC3339))
+	resumeAt := time.Now().Add(pause)
+	at.setStopUntil(resumeAt)
+	at.log().Warnf("⚠️ 触发风险暂停，暂停时长: %v（%s），恢复时间: %s", pause, riskLimitLevel(at.config.StopTradingTimeSource), resumeAt.Format(time.RFC3339))
+
+	at.emitWebhook(webhook.EventRiskStop, map[string]interface{}{
+		"reason":      reason,
+		"pause":       pause.String(),
+		"resume_at":   resumeAt.UTC().Format(time.RFC3339),
+		"daily_pnl":   at.dailyPnL,
+		"peak_equity": at.peakEquity,
+	})
 }
 
 // buildTradingContext 构建交易上下文
 func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
+	return at.collectTradingContext(false)
+}
+
+// collectTradingContext 构建交易上下文
+// readOnly=true 时（决策预演）不修改任何交易员状态：不记录持仓首次出现时间、不清理已平仓记录、
+// 不同步币种持仓登记、不更新信号偏好/候选集合，失败决策反馈只读取不清空
+func (at *AutoTrader) collectTradingContext(readOnly bool) (*decision.Context, error) {
 	// 1. 获取账户信息
 	balance, err := at.trader.GetBalance()
 	if err != nil {
@@ -735,31 +1131,31 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	for _, pos := range positions {
 		symbol, err := SafeString(pos, "symbol")
 		if err != nil {
-			log.Printf("⚠️ 无法解析 symbol: %v", err)
+			at.log().Warnf("⚠️ 无法解析 symbol: %v", err)
 			continue
 		}
 
 		side, err := SafeString(pos, "side")
 		if err != nil {
-			log.Printf("⚠️ 无法解析 side: %v", err)
+			at.log().Warnf("⚠️ 无法解析 side: %v", err)
 			continue
 		}
 
 		entryPrice, err := SafeFloat64(pos, "entryPrice")
 		if err != nil {
-			log.Printf("⚠️ 无法解析 entryPrice: %v", err)
+			at.log().Warnf("⚠️ 无法解析 entryPrice: %v", err)
 			continue
 		}
 
 		markPrice, err := SafeFloat64(pos, "markPrice")
 		if err != nil {
-			log.Printf("⚠️ 无法解析 markPrice: %v", err)
+			at.log().Warnf("⚠️ 无法解析 markPrice: %v", err)
 			continue
 		}
 
 		quantity, err := SafeFloat64(pos, "positionAmt")
 		if err != nil {
-			log.Printf("⚠️ 无法解析 positionAmt: %v", err)
+			at.log().Warnf("⚠️ 无法解析 positionAmt: %v", err)
 			continue
 		}
 		if quantity < 0 {
@@ -773,13 +1169,13 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 		unrealizedPnl, err := SafeFloat64(pos, "unRealizedProfit")
 		if err != nil {
-			log.Printf("⚠️ 无法解析 unRealizedProfit: %v", err)
+			at.log().Warnf("⚠️ 无法解析 unRealizedProfit: %v", err)
 			continue
 		}
 
 		liquidationPrice, err := SafeFloat64(pos, "liquidationPrice")
 		if err != nil {
-			log.Printf("⚠️ 无法解析 liquidationPrice: %v", err)
+			at.log().Warnf("⚠️ 无法解析 liquidationPrice: %v", err)
 			continue
 		}
 
@@ -797,11 +1193,14 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		// 跟踪持仓首次出现时间
 		posKey := symbol + "_" + side
 		currentPositionKeys[posKey] = true
-		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
+		updateTime, exists := at.positionFirstSeenTime[posKey]
+		if !exists {
 			// 新持仓，记录当前时间
-			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+			updateTime = time.Now().UnixMilli()
+			if !readOnly {
+				at.positionFirstSeenTime[posKey] = updateTime
+			}
 		}
-		updateTime := at.positionFirstSeenTime[posKey]
 
 		// 获取该持仓的历史最高收益率
 		at.peakPnLCacheMutex.RLock()
@@ -811,6 +1210,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		// 获取止损止盈价格（用于后续推断平仓原因）
 		stopLoss := at.positionStopLoss[posKey]
 		takeProfit := at.positionTakeProfit[posKey]
+		trailing, _ := at.getTrailingStop(posKey)
+		annotation := at.getPositionAnnotation(posKey)
 
 		positionInfos = append(positionInfos, decision.PositionInfo{
 			Symbol:           symbol,
@@ -827,16 +1228,30 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			UpdateTime:       updateTime,
 			StopLoss:         stopLoss,
 			TakeProfit:       takeProfit,
+			TrailingStopPct:  trailing.DistancePct,
+			TrailingStop:     trailing.StopPrice,
+			External:         at.externalPositions[posKey],
+			Note:             annotation.Note,
+			Locked:           annotation.Locked,
 		})
 	}
 
-	// 清理已平仓的持仓记录（包括止损止盈记录）
-	for key := range at.positionFirstSeenTime {
-		if !currentPositionKeys[key] {
-			delete(at.positionFirstSeenTime, key)
-			delete(at.positionStopLoss, key)
-			delete(at.positionTakeProfit, key)
+	if !readOnly {
+		// 清理已平仓的持仓记录（包括止损止盈记录）
+		for key := range at.positionFirstSeenTime {
+			if !currentPositionKeys[key] {
+				delete(at.positionFirstSeenTime, key)
+				delete(at.positionStopLoss, key)
+				delete(at.positionTakeProfit, key)
+				delete(at.externalPositions, key)
+			}
 		}
+		at.prunePeakPnLCache(currentPositionKeys)
+		at.pruneTrailingStops(currentPositionKeys)
+		at.prunePositionAnnotations(currentPositionKeys)
+
+		// 同步全实例币种持仓登记（交易所侧止损/强平后释放名额）
+		at.syncSymbolSlots(positionInfos)
 	}
 
 	// 3. 获取交易员的候选币种池
@@ -844,6 +1259,10 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	if err != nil {
 		return nil, fmt.Errorf("获取候选币种失败: %w", err)
 	}
+	if !readOnly {
+		at.signalBias = candidateSignalBias(candidateCoins)
+		at.candidateSymbols = candidateSymbolSet(candidateCoins)
+	}
 
 	// 4. 计算总盈亏
 	totalPnL := totalEquity - at.initialBalance
@@ -861,7 +1280,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
 	performance, err := at.decisionLogger.AnalyzePerformance(100)
 	if err != nil {
-		log.Printf("⚠️  分析历史表现失败: %v", err)
+		at.log().Warnf("⚠️  分析历史表现失败: %v", err)
 		// 不影响主流程，继续执行（但设置performance为nil以避免传递错误数据）
 		performance = nil
 	}
@@ -869,11 +1288,11 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 6. Fetch open orders for AI decision context to prevent duplicate orders
 	openOrders, err := at.trader.GetOpenOrders("")
 	if err != nil {
-		log.Printf("⚠️  Failed to fetch open orders: %v (continuing execution, but AI won't see order status)", err)
+		at.log().Warnf("⚠️  Failed to fetch open orders: %v (continuing execution, but AI won't see order status)", err)
 		// Don't block main flow, use empty list
 		openOrders = []decision.OpenOrderInfo{}
 	} else {
-		log.Printf("  ✓ Fetched %d open orders", len(openOrders))
+		at.log().Infof("  ✓ Fetched %d open orders", len(openOrders))
 	}
 
 	// 7. Build context
@@ -886,6 +1305,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		TakerFeeRate:    at.config.TakerFeeRate,    // Use configured taker fee rate
 		MakerFeeRate:    at.config.MakerFeeRate,    // Use configured maker fee rate
 		Timeframes:      at.timeframes,             // K线时间线配置
+		KlineLimit:      at.config.KlineLimit,      // 每个时间线的K线数量
+		Indicators:      at.config.Indicators,      // 行情指标
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -901,6 +1322,14 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		CandidateCoins: candidateCoins,
 		Performance:    performance, // 添加历史表现分析（包含 RecentTrades 用于 AI 学习）
 	}
+	ctx.TradeStats = at.tradeStatsSummary() // 实际成交统计（胜率/盈亏比以交易所成交为准，不依赖决策日志推断）
+	if readOnly {
+		ctx.RecentRejections = at.rejectionFeedback.peek()
+	} else {
+		ctx.RecentRejections = at.rejectionFeedback.drain()
+	}
+	ctx.MaxPositions = at.config.MaxPositions
+	ctx.MaxPositionSizeUSD = at.config.MaxPositionSizeUSD
 
 	return ctx, nil
 }
@@ -909,19 +1338,51 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	switch decision.Action {
 	case "open_long":
+		if err := at.checkSymbolBlacklist(decision.Symbol); err != nil {
+			return rejectDecision(RejectBlacklisted, err)
+		}
+		if err := at.checkPositionLimits(decision); err != nil {
+			return rejectDecision(RejectPositionLimit, err)
+		}
 		return at.executeOpenLongWithRecord(decision, actionRecord)
 	case "open_short":
+		if err := at.checkSymbolBlacklist(decision.Symbol); err != nil {
+			return rejectDecision(RejectBlacklisted, err)
+		}
+		if err := at.checkPositionLimits(decision); err != nil {
+			return rejectDecision(RejectPositionLimit, err)
+		}
 		return at.executeOpenShortWithRecord(decision, actionRecord)
 	case "close_long":
+		if err := at.checkPositionLocked(decision.Action, decision.Symbol); err != nil {
+			return err
+		}
 		return at.executeCloseLongWithRecord(decision, actionRecord)
 	case "close_short":
+		if err := at.checkPositionLocked(decision.Action, decision.Symbol); err != nil {
+			return err
+		}
 		return at.executeCloseShortWithRecord(decision, actionRecord)
 	case "update_stop_loss":
-		return at.executeUpdateStopLossWithRecord(decision, actionRecord)
+		if err := at.executeUpdateStopLossWithRecord(decision, actionRecord); err != nil {
+			return err
+		}
+		// 固定止损替换被调整方向的追踪止损
+		if !actionRecord.Skipped && !actionRecord.DryRun {
+			at.clearTrailingStop(decision.Symbol, at.stopLossSide(decision.Symbol, decision.NewStopLoss))
+		}
+		return nil
+	case "set_trailing_stop":
+		return at.executeSetTrailingStopWithRecord(decision, actionRecord)
 	case "update_take_profit":
 		return at.executeUpdateTakeProfitWithRecord(decision, actionRecord)
 	case "partial_close":
+		if err := at.checkPositionLocked(decision.Action, decision.Symbol); err != nil {
+			return err
+		}
 		return at.executePartialCloseWithRecord(decision, actionRecord)
+	case "cancel_order":
+		return at.executeCancelOrderWithRecord(decision, actionRecord)
 	case "hold", "wait":
 		// 无需执行，仅记录
 		return nil
@@ -930,19 +1391,109 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 	}
 }
 
+// checkSymbolTradable 检查币种当前是否可交易，并记录交易所维护/暂停交易状态
+// 查询状态失败时不阻断开仓（由下单接口兜底）
+func (at *AutoTrader) checkSymbolTradable(symbol string) error {
+	status, err := at.trader.GetSymbolTradingStatus(symbol)
+	if err != nil {
+		at.log().Warnf("⚠️  %s 交易状态查询失败，继续交易: %v", symbol, err)
+		return nil
+	}
+
+	at.tradingStatusMutex.Lock()
+	at.exchangeMaintenance = status.Maintenance
+	if at.haltedSymbols == nil {
+		at.haltedSymbols = make(map[string]string)
+	}
+	if status.Tradeable {
+		delete(at.haltedSymbols, symbol)
+	} else {
+		at.haltedSymbols[symbol] = status.Reason
+	}
+	at.tradingStatusMutex.Unlock()
+
+	if !status.Tradeable {
+		return fmt.Errorf("⏸️ %s 当前不可交易（%s），跳过开仓", symbol, status.Reason)
+	}
+	return nil
+}
+
+// IsExchangeInMaintenance 交易所是否处于维护中（最近一次交易状态检查的结果）
+func (at *AutoTrader) IsExchangeInMaintenance() bool {
+	at.tradingStatusMutex.RLock()
+	defer at.tradingStatusMutex.RUnlock()
+	return at.exchangeMaintenance
+}
+
+// GetHaltedSymbols 获取当前暂停交易的币种及原因
+func (at *AutoTrader) GetHaltedSymbols() map[string]string {
+	at.tradingStatusMutex.RLock()
+	defer at.tradingStatusMutex.RUnlock()
+
+	halted := make(map[string]string, len(at.haltedSymbols))
+	for symbol, reason := range at.haltedSymbols {
+		halted[symbol] = reason
+	}
+	return halted
+}
+
 // executeOpenLongWithRecord 执行开多仓并记录详细信息
 func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  📈 开多仓: %s", decision.Symbol)
+	at.log().Infof("  📈 开多仓: %s", decision.Symbol)
+
+	// 💸 未入金账户不尝试开仓（否则每次都会收到保证金不足错误）
+	if err := at.checkFundedForOpen(); err != nil {
+		return rejectDecision(RejectUnfunded, err)
+	}
+
+	// 🔢 每日开仓上限：达到后当日不再开新仓（平仓和风控不受影响）
+	if err := at.checkDailyTradeLimit(); err != nil {
+		return rejectDecision(RejectDailyTradeLimit, err)
+	}
+
+	// 🧭 信号方向一致性：信号源对该币种有方向偏好时，不允许逆向开仓
+	if err := at.checkSignalBias(decision.Symbol, "long"); err != nil {
+		return rejectDecision(RejectSignalBias, err)
+	}
+
+	// 🎯 候选范围：只允许对本周期提供给AI的候选币种开仓
+	if err := at.checkCandidateSymbol(decision.Symbol); err != nil {
+		return rejectDecision(RejectNonCandidate, err)
+	}
 
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
 	positions, err := at.trader.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
 			if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
-				return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
+				return rejectDecisionf(RejectPositionExists, "POSITION_EXISTS_LONG", decision.Symbol)
 			}
 		}
 	}
+	if at.hasPendingEntry(decision.Symbol, "long") {
+		return rejectDecisionf(RejectPositionExists, "PENDING_ENTRY_EXISTS_LONG", decision.Symbol)
+	}
+
+	// ⏸️ 交易状态检查：暂停交易/维护中的币种直接跳过，避免交易所返回含糊的下单错误
+	if err := at.checkSymbolTradable(decision.Symbol); err != nil {
+		return rejectDecision(RejectSymbolHalted, err)
+	}
+
+	// 🎚️ 杠杆不超过交易所对该币种的上限（同一配置在不同交易所间切换时避免下单被拒）
+	decision.Leverage = at.clampLeverage(decision.Symbol, decision.Leverage)
+	actionRecord.Leverage = decision.Leverage
+
+	// 🌐 全实例币种持仓上限：先登记名额，开仓失败时释放
+	slotAcquired, err := at.acquireSymbolSlot(decision.Symbol)
+	if err != nil {
+		return rejectDecision(RejectSymbolCap, err)
+	}
+	opened := false
+	defer func() {
+		if slotAcquired && !opened {
+			at.symbolRegistry.Release(decision.Symbol, at.id)
+		}
+	}()
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol, at.timeframes)
@@ -950,27 +1501,22 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		return err
 	}
 
-	// 🔍 价格一致性验证（防止单交易所价格异常导致误判）
-	if market.WSMonitorCli != nil && market.WSMonitorCli.GetDSManager() != nil {
-		consistent, prices, err := market.WSMonitorCli.GetDSManager().VerifyPriceConsistency(decision.Symbol, 0.02) // 2% 偏差阈值
-		if err != nil {
-			log.Printf("⚠️  %s 价格验证失败（数据源不足），继续交易: %v", decision.Symbol, err)
-		} else if !consistent {
-			priceDetails := ""
-			for source, price := range prices {
-				priceDetails += fmt.Sprintf("%s: %.2f, ", source, price)
-			}
-			return fmt.Errorf("❌ 价格异常：%s 在多个数据源间偏差过大（>2%%），拒绝开仓以防止误判。价格: %s",
-				decision.Symbol, priceDetails)
-		} else {
-			log.Printf("✅ %s 价格验证通过（多数据源一致性检查）", decision.Symbol)
-		}
+	// 🔍 价格一致性验证（防止单交易所价格异常导致误判，大额开仓严格校验）
+	if err := at.verifyEntryPrice(decision.Symbol, decision.PositionSizeUSD); err != nil {
+		return rejectDecision(RejectPriceCheck, err)
+	}
+
+	// 入场价：AI 指定限价单时按限价计算数量
+	entryPrice, err := at.entryPriceFor(decision, "long", marketData.CurrentPrice)
+	if err != nil {
+		return rejectDecision(RejectInvalidLimitPrice, err)
 	}
 
 	// 计算数量
-	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
+	quantity := decision.PositionSizeUSD / entryPrice
 	actionRecord.Quantity = quantity
-	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Price = entryPrice
+	actionRecord.OrderType = decision.OrderType
 
 	// ⚠️ 保证金验证：防止保证金不足错误（code=-2019）
 	requiredMargin := decision.PositionSizeUSD / float64(decision.Leverage)
@@ -989,110 +1535,131 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	totalRequired := requiredMargin + estimatedFee
 
 	if totalRequired > availableBalance {
-		return fmt.Errorf("❌ 保证金不足: 需要 %.2f USDT（保证金 %.2f + 手续费 %.2f），可用 %.2f USDT",
+		return rejectDecisionf(RejectInsufficientMargin, "INSUFFICIENT_MARGIN_DETAIL",
 			totalRequired, requiredMargin, estimatedFee, availableBalance)
 	}
 
+	// 📊 总敞口上限：开仓后总名义价值不得超过账户净值的 MaxExposureMultiple 倍
+	if err := at.checkExposureLimit(decision.Symbol, decision.PositionSizeUSD, balance); err != nil {
+		return rejectDecision(RejectExposureLimit, err)
+	}
+
 	// ⚡ 严格验证止损/止盈价格（防止开仓后无法设置保护，导致仓位风险）
 	// 修复 Issue: 开仓成功但止损/止盈设置失败，仓位失去保护
 	if decision.StopLoss <= 0 || decision.TakeProfit <= 0 {
-		return fmt.Errorf("❌ 多单开仓失败：止损价 %.2f 和止盈价 %.2f 必须大于 0。"+
-			"建议：AI 必须为每个开仓决策设置合理的止损和止盈价格",
-			decision.StopLoss, decision.TakeProfit)
+		return rejectDecisionf(RejectInvalidStops, "STOPS_REQUIRED_LONG", decision.StopLoss, decision.TakeProfit)
 	}
 
 	// 多单：止损必须 < 当前价，止盈必须 > 当前价
 	if decision.StopLoss >= marketData.CurrentPrice {
 		priceGapPct := ((decision.StopLoss - marketData.CurrentPrice) / marketData.CurrentPrice) * 100
-		return fmt.Errorf("❌ 多单止损价不合理：止损价 %.2f 必须低于当前价 %.2f (当前高出 %.2f%%)。"+
-			"建议：AI 应设置低于当前价的止损价，例如 %.2f",
+		return rejectDecisionf(RejectInvalidStops, "LONG_STOP_LOSS_ABOVE_PRICE",
 			decision.StopLoss, marketData.CurrentPrice, priceGapPct, marketData.CurrentPrice*0.98)
 	}
 
 	if decision.TakeProfit <= marketData.CurrentPrice {
 		priceGapPct := ((marketData.CurrentPrice - decision.TakeProfit) / marketData.CurrentPrice) * 100
-		return fmt.Errorf("❌ 多单止盈价不合理：止盈价 %.2f 必须高于当前价 %.2f (当前低于 %.2f%%)。"+
-			"建议：AI 应设置高于当前价的止盈价，例如 %.2f",
+		return rejectDecisionf(RejectInvalidStops, "LONG_TAKE_PROFIT_BELOW_PRICE",
 			decision.TakeProfit, marketData.CurrentPrice, priceGapPct, marketData.CurrentPrice*1.02)
 	}
 
+	// 模拟运行：风控校验已全部通过，只记录本应下的订单
+	if at.dryRunSkip(actionRecord, "开多仓 %s 数量 %.4f @ %.4f, 杠杆 %dx, 止损 %.4f, 止盈 %.4f",
+		decision.Symbol, quantity, entryPrice, decision.Leverage, decision.StopLoss, decision.TakeProfit) {
+		return nil
+	}
+
 	// 设置仓位模式
 	if err := at.trader.SetMarginMode(decision.Symbol, at.config.IsCrossMargin); err != nil {
-		log.Printf("  ⚠️ 设置仓位模式失败: %v", err)
+		at.log().Warnf("  ⚠️ 设置仓位模式失败: %v", err)
 		// 继续执行，不影响交易
 	}
 
 	// 开仓
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
+	order, err := at.openPosition(decision, "long", quantity)
 	if err != nil {
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
-
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
-
-	// 🔧 P0修復：持久化開倉記錄到數據庫
-	if db, ok := at.database.(interface {
-		RecordTrade(string, string, string, string, string, float64, float64, string, float64, float64, float64, float64) error
-	}); ok {
-		reason := decision.Reasoning
-		if len(reason) > 500 {
-			reason = reason[:500] // 限制長度
-		}
-		if err := db.RecordTrade(
-			at.config.ID,
-			at.userID,
-			decision.Symbol,
-			"LONG",
-			"OPEN",
-			quantity,
-			marketData.CurrentPrice,
-			reason,
-			decision.StopLoss,
-			decision.TakeProfit,
-			0, // 開倉時 PnL 為 0
-			0, // 開倉時 PnL% 為 0
-		); err != nil {
-			log.Printf("  ⚠️ 記錄開倉到數據庫失敗: %v", err)
-		}
+	// 🔎 开仓确认：重新读取持仓/挂单，确认订单确实生效后再记录成功
+	resting, err := at.confirmOpen(decision.Symbol, "long", at.mayRestUnfilled(decision))
+	if err != nil {
+		return err
 	}
+	opened = true
 
-	// 记录开仓时间
-	posKey := decision.Symbol + "_long"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	// 记录订单ID
+	orderID, _ := order["orderId"].(int64)
+	actionRecord.OrderID = orderID
 
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	} else {
-		at.positionStopLoss[posKey] = decision.StopLoss // 记录止损价格
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
-	} else {
-		at.positionTakeProfit[posKey] = decision.TakeProfit // 记录止盈价格
+	// 限价单尚未成交：登记为挂单中，成交后再记录开仓、设置止损止盈并计入当日开仓次数
+	if resting {
+		at.trackPendingEntry(decision, "long", orderID, quantity, entryPrice)
+		return nil
 	}
 
+	at.log().Infof("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	at.recordOpenedPosition(decision.Symbol, "long", quantity, entryPrice, decision.Leverage, decision.StopLoss, decision.TakeProfit, decision.Reasoning)
 	return nil
 }
 
 // executeOpenShortWithRecord 执行开空仓并记录详细信息
 func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  📉 开空仓: %s", decision.Symbol)
+	at.log().Infof("  📉 开空仓: %s", decision.Symbol)
+
+	// 💸 未入金账户不尝试开仓（否则每次都会收到保证金不足错误）
+	if err := at.checkFundedForOpen(); err != nil {
+		return rejectDecision(RejectUnfunded, err)
+	}
+
+	// 🔢 每日开仓上限：达到后当日不再开新仓（平仓和风控不受影响）
+	if err := at.checkDailyTradeLimit(); err != nil {
+		return rejectDecision(RejectDailyTradeLimit, err)
+	}
+
+	// 🧭 信号方向一致性：信号源对该币种有方向偏好时，不允许逆向开仓
+	if err := at.checkSignalBias(decision.Symbol, "short"); err != nil {
+		return rejectDecision(RejectSignalBias, err)
+	}
+
+	// 🎯 候选范围：只允许对本周期提供给AI的候选币种开仓
+	if err := at.checkCandidateSymbol(decision.Symbol); err != nil {
+		return rejectDecision(RejectNonCandidate, err)
+	}
 
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
 	positions, err := at.trader.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
 			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
-				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
+				return rejectDecisionf(RejectPositionExists, "POSITION_EXISTS_SHORT", decision.Symbol)
 			}
 		}
 	}
+	if at.hasPendingEntry(decision.Symbol, "short") {
+		return rejectDecisionf(RejectPositionExists, "PENDING_ENTRY_EXISTS_SHORT", decision.Symbol)
+	}
+
+	// ⏸️ 交易状态检查：暂停交易/维护中的币种直接跳过，避免交易所返回含糊的下单错误
+	if err := at.checkSymbolTradable(decision.Symbol); err != nil {
+		return rejectDecision(RejectSymbolHalted, err)
+	}
+
+	// 🎚️ 杠杆不超过交易所对该币种的上限（同一配置在不同交易所间切换时避免下单被拒）
+	decision.Leverage = at.clampLeverage(decision.Symbol, decision.Leverage)
+	actionRecord.Leverage = decision.Leverage
+
+	// 🌐 全实例币种持仓上限：先登记名额，开仓失败时释放
+	slotAcquired, err := at.acquireSymbolSlot(decision.Symbol)
+	if err != nil {
+		return rejectDecision(RejectSymbolCap, err)
+	}
+	opened := false
+	defer func() {
+		if slotAcquired && !opened {
+			at.symbolRegistry.Release(decision.Symbol, at.id)
+		}
+	}()
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol, at.timeframes)
@@ -1100,27 +1667,22 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		return err
 	}
 
-	// 🔍 价格一致性验证（防止单交易所价格异常导致误判）
-	if market.WSMonitorCli != nil && market.WSMonitorCli.GetDSManager() != nil {
-		consistent, prices, err := market.WSMonitorCli.GetDSManager().VerifyPriceConsistency(decision.Symbol, 0.02) // 2% 偏差阈值
-		if err != nil {
-			log.Printf("⚠️  %s 价格验证失败（数据源不足），继续交易: %v", decision.Symbol, err)
-		} else if !consistent {
-			priceDetails := ""
-			for source, price := range prices {
-				priceDetails += fmt.Sprintf("%s: %.2f, ", source, price)
-			}
-			return fmt.Errorf("❌ 价格异常：%s 在多个数据源间偏差过大（>2%%），拒绝开仓以防止误判。价格: %s",
-				decision.Symbol, priceDetails)
-		} else {
-			log.Printf("✅ %s 价格验证通过（多数据源一致性检查）", decision.Symbol)
-		}
+	// 🔍 价格一致性验证（防止单交易所价格异常导致误判，大额开仓严格校验）
+	if err := at.verifyEntryPrice(decision.Symbol, decision.PositionSizeUSD); err != nil {
+		return rejectDecision(RejectPriceCheck, err)
+	}
+
+	// 入场价：AI 指定限价单时按限价计算数量
+	entryPrice, err := at.entryPriceFor(decision, "short", marketData.CurrentPrice)
+	if err != nil {
+		return rejectDecision(RejectInvalidLimitPrice, err)
 	}
 
 	// 计算数量
-	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
+	quantity := decision.PositionSizeUSD / entryPrice
 	actionRecord.Quantity = quantity
-	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Price = entryPrice
+	actionRecord.OrderType = decision.OrderType
 
 	// ⚠️ 保证金验证：防止保证金不足错误（code=-2019）
 	requiredMargin := decision.PositionSizeUSD / float64(decision.Leverage)
@@ -1139,100 +1701,130 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	totalRequired := requiredMargin + estimatedFee
 
 	if totalRequired > availableBalance {
-		return fmt.Errorf("❌ 保证金不足: 需要 %.2f USDT（保证金 %.2f + 手续费 %.2f），可用 %.2f USDT",
+		return rejectDecisionf(RejectInsufficientMargin, "INSUFFICIENT_MARGIN_DETAIL",
 			totalRequired, requiredMargin, estimatedFee, availableBalance)
 	}
 
+	// 📊 总敞口上限：开仓后总名义价值不得超过账户净值的 MaxExposureMultiple 倍
+	if err := at.checkExposureLimit(decision.Symbol, decision.PositionSizeUSD, balance); err != nil {
+		return rejectDecision(RejectExposureLimit, err)
+	}
+
 	// ⚡ 严格验证止损/止盈价格（防止开仓后无法设置保护，导致仓位风险）
 	// 修复 Issue: 开仓成功但止损/止盈设置失败，仓位失去保护
 	if decision.StopLoss <= 0 || decision.TakeProfit <= 0 {
-		return fmt.Errorf("❌ 空单开仓失败：止损价 %.2f 和止盈价 %.2f 必须大于 0。"+
-			"建议：AI 必须为每个开仓决策设置合理的止损和止盈价格",
-			decision.StopLoss, decision.TakeProfit)
+		return rejectDecisionf(RejectInvalidStops, "STOPS_REQUIRED_SHORT", decision.StopLoss, decision.TakeProfit)
 	}
 
 	// 空单：止损必须 > 当前价，止盈必须 < 当前价
 	if decision.StopLoss <= marketData.CurrentPrice {
 		priceGapPct := ((marketData.CurrentPrice - decision.StopLoss) / marketData.CurrentPrice) * 100
-		return fmt.Errorf("❌ 空单止损价不合理：止损价 %.2f 必须高于当前价 %.2f (当前低于 %.2f%%)。"+
-			"建议：AI 应设置高于当前价的止损价，例如 %.2f",
+		return rejectDecisionf(RejectInvalidStops, "SHORT_STOP_LOSS_BELOW_PRICE",
 			decision.StopLoss, marketData.CurrentPrice, priceGapPct, marketData.CurrentPrice*1.02)
 	}
 
 	if decision.TakeProfit >= marketData.CurrentPrice {
 		priceGapPct := ((decision.TakeProfit - marketData.CurrentPrice) / marketData.CurrentPrice) * 100
-		return fmt.Errorf("❌ 空单止盈价不合理：止盈价 %.2f 必须低于当前价 %.2f (当前高出 %.2f%%)。"+
-			"建议：AI 应设置低于当前价的止盈价，例如 %.2f",
+		return rejectDecisionf(RejectInvalidStops, "SHORT_TAKE_PROFIT_ABOVE_PRICE",
 			decision.TakeProfit, marketData.CurrentPrice, priceGapPct, marketData.CurrentPrice*0.98)
 	}
 
+	// 模拟运行：风控校验已全部通过，只记录本应下的订单
+	if at.dryRunSkip(actionRecord, "开空仓 %s 数量 %.4f @ %.4f, 杠杆 %dx, 止损 %.4f, 止盈 %.4f",
+		decision.Symbol, quantity, entryPrice, decision.Leverage, decision.StopLoss, decision.TakeProfit) {
+		return nil
+	}
+
 	// 设置仓位模式
 	if err := at.trader.SetMarginMode(decision.Symbol, at.config.IsCrossMargin); err != nil {
-		log.Printf("  ⚠️ 设置仓位模式失败: %v", err)
+		at.log().Warnf("  ⚠️ 设置仓位模式失败: %v", err)
 		// 继续执行，不影响交易
 	}
 
 	// 开仓
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
+	order, err := at.openPosition(decision, "short", quantity)
 	if err != nil {
 		return err
 	}
 
+	// 🔎 开仓确认：重新读取持仓/挂单，确认订单确实生效后再记录成功
+	resting, err := at.confirmOpen(decision.Symbol, "short", at.mayRestUnfilled(decision))
+	if err != nil {
+		return err
+	}
+	opened = true
+
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
+	orderID, _ := order["orderId"].(int64)
+	actionRecord.OrderID = orderID
+
+	// 限价单尚未成交：登记为挂单中，成交后再记录开仓、设置止损止盈并计入当日开仓次数
+	if resting {
+		at.trackPendingEntry(decision, "short", orderID, quantity, entryPrice)
+		return nil
 	}
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	at.log().Infof("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	at.recordOpenedPosition(decision.Symbol, "short", quantity, entryPrice, decision.Leverage, decision.StopLoss, decision.TakeProfit, decision.Reasoning)
+	return nil
+}
+
+// recordOpenedPosition 开仓成交后的记录：计入当日开仓次数、推送开仓事件、持久化开仓记录并设置止损止盈
+// 市价单在下单后立即调用；限价单在 resolvePendingEntries 确认成交后调用
+func (at *AutoTrader) recordOpenedPosition(symbol, side string, quantity, entryPrice float64, leverage int, stopLoss, takeProfit float64, reasoning string) {
+	at.recordDailyTrade()
+	at.emitOpenEvent(symbol, side, quantity, entryPrice, leverage, stopLoss, takeProfit, reasoning)
+
+	positionSide := strings.ToUpper(side)
 
 	// 🔧 P0修復：持久化開倉記錄到數據庫
 	if db, ok := at.database.(interface {
 		RecordTrade(string, string, string, string, string, float64, float64, string, float64, float64, float64, float64) error
 	}); ok {
-		reason := decision.Reasoning
+		reason := reasoning
 		if len(reason) > 500 {
 			reason = reason[:500] // 限制長度
 		}
-		if err := db.RecordTrade(
+		if err := at.recordTradeWithRetry(db,
 			at.config.ID,
 			at.userID,
-			decision.Symbol,
-			"SHORT",
+			symbol,
+			positionSide,
 			"OPEN",
 			quantity,
-			marketData.CurrentPrice,
+			entryPrice,
 			reason,
-			decision.StopLoss,
-			decision.TakeProfit,
+			stopLoss,
+			takeProfit,
 			0, // 開倉時 PnL 為 0
 			0, // 開倉時 PnL% 為 0
 		); err != nil {
-			log.Printf("  ⚠️ 記錄開倉到數據庫失敗: %v", err)
+			at.log().Warnf("  ⚠️ 記錄開倉到數據庫失敗: %v", err)
 		}
 	}
 
 	// 记录开仓时间
-	posKey := decision.Symbol + "_short"
+	posKey := symbol + "_" + side
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
+	// 设置止损止盈（按交易对价格步进值对齐）
+	stopLoss = at.alignStopPrice(symbol, positionSide, true, stopLoss)
+	takeProfit = at.alignStopPrice(symbol, positionSide, false, takeProfit)
+	if err := at.trader.SetStopLoss(symbol, positionSide, quantity, stopLoss); err != nil {
+		at.log().Warnf("  ⚠ 设置止损失败: %v", err)
 	} else {
-		at.positionStopLoss[posKey] = decision.StopLoss // 记录止损价格
+		at.positionStopLoss[posKey] = stopLoss // 记录止损价格
 	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	if err := at.trader.SetTakeProfit(symbol, positionSide, quantity, takeProfit); err != nil {
+		at.log().Warnf("  ⚠ 设置止盈失败: %v", err)
 	} else {
-		at.positionTakeProfit[posKey] = decision.TakeProfit // 记录止盈价格
+		at.positionTakeProfit[posKey] = takeProfit // 记录止盈价格
 	}
-
-	return nil
 }
 
 // executeCloseLongWithRecord 执行平多仓并记录详细信息
 func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🔄 平多仓: %s", decision.Symbol)
+	at.log().Infof("  🔄 平多仓: %s", decision.Symbol)
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol, at.timeframes)
@@ -1254,9 +1846,9 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 		if err == nil {
 			entryPrice = dbEntryPrice
 			quantity = dbQuantity
-			log.Printf("  📊 從數據庫獲取入場價: %.2f, 數量: %.4f", entryPrice, quantity)
+			at.log().Infof("  📊 從數據庫獲取入場價: %.2f, 數量: %.4f", entryPrice, quantity)
 		} else {
-			log.Printf("  ⚠️ 數據庫查詢失敗，嘗試內存備份: %v", err)
+			at.log().Warnf("  ⚠️ 數據庫查詢失敗，嘗試內存備份: %v", err)
 		}
 	}
 
@@ -1265,12 +1857,16 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 		if lastPos, exists := at.lastPositions[posKey]; exists {
 			entryPrice = lastPos.EntryPrice
 			quantity = lastPos.Quantity
-			log.Printf("  📊 從內存獲取入場價: %.2f, 數量: %.4f", entryPrice, quantity)
+			at.log().Infof("  📊 從內存獲取入場價: %.2f, 數量: %.4f", entryPrice, quantity)
 		} else {
-			log.Printf("  ⚠️ 無法獲取入場價，PnL 將設為 0")
+			at.log().Warnf("  ⚠️ 無法獲取入場價，PnL 將設為 0")
 		}
 	}
 
+	if at.dryRunSkip(actionRecord, "平多仓 %s（全部持仓）", decision.Symbol) {
+		return nil
+	}
+
 	// 平仓
 	order, err := at.trader.CloseLong(decision.Symbol, 0) // 0 = 全部平仓
 	if err != nil {
@@ -1282,7 +1878,10 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 		actionRecord.OrderID = orderID
 	}
 
-	log.Printf("  ✓ 平仓成功")
+	at.log().Infof("  ✓ 平仓成功")
+	at.releaseSymbolSlot(decision.Symbol, "long")
+	at.clearPositionAnnotation(decision.Symbol, "long")
+	at.emitCloseEvent(decision.Symbol, "long", quantity, entryPrice, marketData.CurrentPrice, false, decision.Reasoning)
 
 	// 🔧 P0修復：持久化平倉記錄到數據庫（含 PnL）
 	if db, ok := at.database.(interface {
@@ -1301,7 +1900,7 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 			reason = reason[:500]
 		}
 
-		if err := db.RecordTrade(
+		if err := at.recordTradeWithRetry(db,
 			at.config.ID,
 			at.userID,
 			decision.Symbol,
@@ -1315,9 +1914,9 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 			pnl,
 			pnlPercent,
 		); err != nil {
-			log.Printf("  ⚠️ 記錄平倉到數據庫失敗: %v", err)
+			at.log().Warnf("  ⚠️ 記錄平倉到數據庫失敗: %v", err)
 		} else if pnl != 0 {
-			log.Printf("  💰 PnL: %.2f USDT (%.2f%%)", pnl, pnlPercent)
+			at.log().Infof("  💰 PnL: %.2f USDT (%.2f%%)", pnl, pnlPercent)
 		}
 	}
 
@@ -1326,7 +1925,7 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 
 // executeCloseShortWithRecord 执行平空仓并记录详细信息
 func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🔄 平空仓: %s", decision.Symbol)
+	at.log().Infof("  🔄 平空仓: %s", decision.Symbol)
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol, at.timeframes)
@@ -1348,9 +1947,9 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 		if err == nil {
 			entryPrice = dbEntryPrice
 			quantity = dbQuantity
-			log.Printf("  📊 從數據庫獲取入場價: %.2f, 數量: %.4f", entryPrice, quantity)
+			at.log().Infof("  📊 從數據庫獲取入場價: %.2f, 數量: %.4f", entryPrice, quantity)
 		} else {
-			log.Printf("  ⚠️ 數據庫查詢失敗，嘗試內存備份: %v", err)
+			at.log().Warnf("  ⚠️ 數據庫查詢失敗，嘗試內存備份: %v", err)
 		}
 	}
 
@@ -1359,12 +1958,16 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 		if lastPos, exists := at.lastPositions[posKey]; exists {
 			entryPrice = lastPos.EntryPrice
 			quantity = lastPos.Quantity
-			log.Printf("  📊 從內存獲取入場價: %.2f, 數量: %.4f", entryPrice, quantity)
+			at.log().Infof("  📊 從內存獲取入場價: %.2f, 數量: %.4f", entryPrice, quantity)
 		} else {
-			log.Printf("  ⚠️ 無法獲取入場價，PnL 將設為 0")
+			at.log().Warnf("  ⚠️ 無法獲取入場價，PnL 將設為 0")
 		}
 	}
 
+	if at.dryRunSkip(actionRecord, "平空仓 %s（全部持仓）", decision.Symbol) {
+		return nil
+	}
+
 	// 平仓
 	order, err := at.trader.CloseShort(decision.Symbol, 0) // 0 = 全部平仓
 	if err != nil {
@@ -1376,7 +1979,10 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 		actionRecord.OrderID = orderID
 	}
 
-	log.Printf("  ✓ 平仓成功")
+	at.log().Infof("  ✓ 平仓成功")
+	at.releaseSymbolSlot(decision.Symbol, "short")
+	at.clearPositionAnnotation(decision.Symbol, "short")
+	at.emitCloseEvent(decision.Symbol, "short", quantity, entryPrice, marketData.CurrentPrice, false, decision.Reasoning)
 
 	// 🔧 P0修復：持久化平倉記錄到數據庫（含 PnL）
 	if db, ok := at.database.(interface {
@@ -1395,7 +2001,7 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 			reason = reason[:500]
 		}
 
-		if err := db.RecordTrade(
+		if err := at.recordTradeWithRetry(db,
 			at.config.ID,
 			at.userID,
 			decision.Symbol,
@@ -1409,9 +2015,9 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 			pnl,
 			pnlPercent,
 		); err != nil {
-			log.Printf("  ⚠️ 記錄平倉到數據庫失敗: %v", err)
+			at.log().Warnf("  ⚠️ 記錄平倉到數據庫失敗: %v", err)
 		} else if pnl != 0 {
-			log.Printf("  💰 PnL: %.2f USDT (%.2f%%)", pnl, pnlPercent)
+			at.log().Infof("  💰 PnL: %.2f USDT (%.2f%%)", pnl, pnlPercent)
 		}
 	}
 
@@ -1420,7 +2026,7 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 
 // executeUpdateStopLossWithRecord 执行调整止损并记录详细信息
 func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🎯 调整止损: %s → %.2f", decision.Symbol, decision.NewStopLoss)
+	at.log().Infof("  🎯 调整止损: %s → %.2f", decision.Symbol, decision.NewStopLoss)
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol, at.timeframes)
@@ -1459,8 +2065,8 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 
 		if wasRecentlyOpen {
 			// 持仓刚刚消失，很可能是止损单已触发
-			log.Printf("  ℹ️  %s 持仓已平仓（止损单可能已触发），跳过止损调整", decision.Symbol)
-			log.Printf("  💡 提示：市价 %.2f，目标止损 %.2f - 交易所可能已在两次AI周期间执行止损",
+			at.log().Infof("  ℹ️  %s 持仓已平仓（止损单可能已触发），跳过止损调整", decision.Symbol)
+			at.log().Infof("  💡 提示：市价 %.2f，目标止损 %.2f - 交易所可能已在两次AI周期间执行止损",
 				marketData.CurrentPrice, decision.NewStopLoss)
 			return nil // 优雅返回，不抛错误
 		}
@@ -1507,10 +2113,21 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	}
 
 	if hasOppositePosition {
-		log.Printf("  🚨 警告：检测到 %s 存在双向持仓（%s + %s），这违反了策略规则",
+		at.log().Warnf("  🚨 警告：检测到 %s 存在双向持仓（%s + %s），这违反了策略规则",
 			decision.Symbol, positionSide, oppositeSide)
-		log.Printf("  🚨 取消止损单将影响两个方向的订单，请检查是否为用户手动操作导致")
-		log.Printf("  🚨 建议：手动平掉其中一个方向的持仓，或检查系统是否有BUG")
+		at.log().Warnf("  🚨 取消止损单将影响两个方向的订单，请检查是否为用户手动操作导致")
+		at.log().Warnf("  🚨 建议：手动平掉其中一个方向的持仓，或检查系统是否有BUG")
+	}
+
+	// 按交易对价格步进值对齐后再与当前止损比较，相同则跳过，避免重复撤单挂单
+	decision.NewStopLoss = at.alignStopPrice(decision.Symbol, positionSide, true, decision.NewStopLoss)
+	posKey := decision.Symbol + "_" + strings.ToLower(positionSide)
+	if at.skipRedundantStopUpdate(at.positionStopLoss, posKey, "止损", decision.NewStopLoss, actionRecord) {
+		return nil
+	}
+
+	if at.dryRunSkip(actionRecord, "调整止损 %s %s → %.4f", decision.Symbol, positionSide, decision.NewStopLoss) {
+		return nil
 	}
 
 	// 取消旧的止损单（只删除止损单，不影响止盈单）
@@ -1520,7 +2137,7 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 		return fmt.Errorf("取消舊止損單失敗，中止操作以防止重複掛單 (Issue #998): %w", err)
 	}
 
-	log.Printf("  ✓ 已取消舊止損單，準備設置新止損")
+	at.log().Infof("  ✓ 已取消舊止損單，準備設置新止損")
 
 	// 调用交易所 API 修改止损
 	quantity := math.Abs(positionAmt)
@@ -1530,10 +2147,9 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	}
 
 	// 更新内存中的止损价格
-	posKey := decision.Symbol + "_" + strings.ToLower(positionSide)
 	at.positionStopLoss[posKey] = decision.NewStopLoss
 
-	log.Printf("  ✓ 止损已调整: %.2f (当前价格: %.2f)", decision.NewStopLoss, marketData.CurrentPrice)
+	at.log().Infof("  ✓ 止损已调整: %.2f (当前价格: %.2f)", decision.NewStopLoss, marketData.CurrentPrice)
 
 	// ✅ 修复 Hyperliquid 止盈止损问题：
 	// Hyperliquid 无法区分止盈/止损单，CancelStopLossOrders 会取消所有挂单
@@ -1548,14 +2164,14 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 		}
 
 		if isValidTP {
-			log.Printf("  → 恢复止盈单: %.2f (Hyperliquid 兼容性修复)", takeProfit)
+			at.log().Infof("  → 恢复止盈单: %.2f (Hyperliquid 兼容性修复)", takeProfit)
 			if err := at.trader.SetTakeProfit(decision.Symbol, positionSide, quantity, takeProfit); err != nil {
-				log.Printf("  ⚠️ 恢复止盈单失败: %v (止损已设置成功)", err)
+				at.log().Warnf("  ⚠️ 恢复止盈单失败: %v (止损已设置成功)", err)
 			} else {
-				log.Printf("  ✓ 止盈单已恢复: %.2f", takeProfit)
+				at.log().Infof("  ✓ 止盈单已恢复: %.2f", takeProfit)
 			}
 		} else {
-			log.Printf("  ⚠️ 原止盈价 %.2f 已失效（%s仓位需%s当前价 %.2f），跳过恢复",
+			at.log().Warnf("  ⚠️ 原止盈价 %.2f 已失效（%s仓位需%s当前价 %.2f），跳过恢复",
 				takeProfit, positionSide, map[string]string{"LONG": "高于", "SHORT": "低于"}[positionSide], marketData.CurrentPrice)
 		}
 	}
@@ -1565,7 +2181,7 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 
 // executeUpdateTakeProfitWithRecord 执行调整止盈并记录详细信息
 func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🎯 调整止盈: %s → %.2f", decision.Symbol, decision.NewTakeProfit)
+	at.log().Infof("  🎯 调整止盈: %s → %.2f", decision.Symbol, decision.NewTakeProfit)
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol, at.timeframes)
@@ -1604,8 +2220,8 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 
 		if wasRecentlyOpen {
 			// 持仓刚刚消失，很可能是止盈单已触发
-			log.Printf("  ℹ️  %s 持仓已平仓（止盈单可能已触发），跳过止盈调整", decision.Symbol)
-			log.Printf("  💡 提示：市价 %.2f，目标止盈 %.2f - 交易所可能已在两次AI周期间执行止盈",
+			at.log().Infof("  ℹ️  %s 持仓已平仓（止盈单可能已触发），跳过止盈调整", decision.Symbol)
+			at.log().Infof("  💡 提示：市价 %.2f，目标止盈 %.2f - 交易所可能已在两次AI周期间执行止盈",
 				marketData.CurrentPrice, decision.NewTakeProfit)
 			return nil // 优雅返回，不抛错误
 		}
@@ -1652,10 +2268,21 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	}
 
 	if hasOppositePosition {
-		log.Printf("  🚨 警告：检测到 %s 存在双向持仓（%s + %s），这违反了策略规则",
+		at.log().Warnf("  🚨 警告：检测到 %s 存在双向持仓（%s + %s），这违反了策略规则",
 			decision.Symbol, positionSide, oppositeSide)
-		log.Printf("  🚨 取消止盈单将影响两个方向的订单，请检查是否为用户手动操作导致")
-		log.Printf("  🚨 建议：手动平掉其中一个方向的持仓，或检查系统是否有BUG")
+		at.log().Warnf("  🚨 取消止盈单将影响两个方向的订单，请检查是否为用户手动操作导致")
+		at.log().Warnf("  🚨 建议：手动平掉其中一个方向的持仓，或检查系统是否有BUG")
+	}
+
+	// 按交易对价格步进值对齐后再与当前止盈比较，相同则跳过，避免重复撤单挂单
+	decision.NewTakeProfit = at.alignStopPrice(decision.Symbol, positionSide, false, decision.NewTakeProfit)
+	trackKey := decision.Symbol + "_" + strings.ToLower(positionSide)
+	if at.skipRedundantStopUpdate(at.positionTakeProfit, trackKey, "止盈", decision.NewTakeProfit, actionRecord) {
+		return nil
+	}
+
+	if at.dryRunSkip(actionRecord, "调整止盈 %s %s → %.4f", decision.Symbol, positionSide, decision.NewTakeProfit) {
+		return nil
 	}
 
 	// 取消旧的止盈单（只删除止盈单，不影响止损单）
@@ -1665,7 +2292,7 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 		return fmt.Errorf("取消舊止盈單失敗，中止操作以防止重複掛單 (Issue #998): %w", err)
 	}
 
-	log.Printf("  ✓ 已取消舊止盈單，準備設置新止盈")
+	at.log().Infof("  ✓ 已取消舊止盈單，準備設置新止盈")
 
 	// 调用交易所 API 修改止盈
 	quantity := math.Abs(positionAmt)
@@ -1674,7 +2301,10 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 		return fmt.Errorf("修改止盈失败: %w", err)
 	}
 
-	log.Printf("  ✓ 止盈已调整: %.2f (当前价格: %.2f)", decision.NewTakeProfit, marketData.CurrentPrice)
+	// 更新内存中的止盈价格
+	at.positionTakeProfit[trackKey] = decision.NewTakeProfit
+
+	at.log().Infof("  ✓ 止盈已调整: %.2f (当前价格: %.2f)", decision.NewTakeProfit, marketData.CurrentPrice)
 
 	// ✅ 修复 Hyperliquid 止盈止损问题：
 	// Hyperliquid 无法区分止盈/止损单，CancelTakeProfitOrders 会取消所有挂单
@@ -1690,9 +2320,9 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 		}
 
 		if isValidSL {
-			log.Printf("  → 恢复止损单: %.2f (Hyperliquid 兼容性修复)", stopLoss)
+			at.log().Infof("  → 恢复止损单: %.2f (Hyperliquid 兼容性修复)", stopLoss)
 			if err := at.trader.SetStopLoss(decision.Symbol, positionSide, quantity, stopLoss); err != nil {
-				log.Printf("  ⚠️ 恢复止损单失败: %v (止盈已设置成功)", err)
+				at.log().Warnf("  ⚠️ 恢复止损单失败: %v (止盈已设置成功)", err)
 			}
 		}
 	}
@@ -1702,7 +2332,7 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 
 // executePartialCloseWithRecord 执行部分平仓并记录详细信息
 func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  📊 部分平仓: %s %.1f%%", decision.Symbol, decision.ClosePercentage)
+	at.log().Infof("  📊 部分平仓: %s %.1f%%", decision.Symbol, decision.ClosePercentage)
 
 	// 验证百分比范围
 	if decision.ClosePercentage <= 0 || decision.ClosePercentage > 100 {
@@ -1746,8 +2376,8 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 
 		if wasRecentlyOpen {
 			// 持仓刚刚消失，很可能是止损/止盈单已触发全部平仓
-			log.Printf("  ℹ️  %s 持仓已完全平仓（止损/止盈可能已触发），跳过部分平仓", decision.Symbol)
-			log.Printf("  💡 提示：市价 %.2f - 交易所可能已在两次AI周期间自动平仓",
+			at.log().Infof("  ℹ️  %s 持仓已完全平仓（止损/止盈可能已触发），跳过部分平仓", decision.Symbol)
+			at.log().Infof("  💡 提示：市价 %.2f - 交易所可能已在两次AI周期间自动平仓",
 				marketData.CurrentPrice)
 			return nil // 优雅返回，不抛错误
 		}
@@ -1765,6 +2395,7 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	totalQuantity := math.Abs(positionAmt)
 	closeQuantity := totalQuantity * (decision.ClosePercentage / 100.0)
 	actionRecord.Quantity = closeQuantity
+	actionRecord.ClosePercentage = decision.ClosePercentage
 
 	// ✅ Layer 2: 最小仓位检查（防止产生小额剩余）
 	markPrice, ok := targetPosition["markPrice"].(float64)
@@ -1779,24 +2410,28 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	const MIN_POSITION_VALUE = 10.0 // 最小持仓价值 10 USDT（對齊交易所底线，小仓位建议直接全平）
 
 	if remainingValue > 0 && remainingValue <= MIN_POSITION_VALUE {
-		log.Printf("⚠️ 检测到 partial_close 后剩余仓位 %.2f USDT < %.0f USDT",
+		at.log().Warnf("⚠️ 检测到 partial_close 后剩余仓位 %.2f USDT < %.0f USDT",
 			remainingValue, MIN_POSITION_VALUE)
-		log.Printf("  → 当前仓位价值: %.2f USDT, 平仓 %.1f%%, 剩余: %.2f USDT",
+		at.log().Infof("  → 当前仓位价值: %.2f USDT, 平仓 %.1f%%, 剩余: %.2f USDT",
 			currentPositionValue, decision.ClosePercentage, remainingValue)
-		log.Printf("  → 自动修正为全部平仓，避免产生无法平仓的小额剩余")
+		at.log().Infof("  → 自动修正为全部平仓，避免产生无法平仓的小额剩余")
 
 		// 🔄 自动修正为全部平仓
 		if positionSide == "LONG" {
 			decision.Action = "close_long"
-			log.Printf("  ✓ 已修正为: close_long")
+			at.log().Infof("  ✓ 已修正为: close_long")
 			return at.executeCloseLongWithRecord(decision, actionRecord)
 		} else {
 			decision.Action = "close_short"
-			log.Printf("  ✓ 已修正为: close_short")
+			at.log().Infof("  ✓ 已修正为: close_short")
 			return at.executeCloseShortWithRecord(decision, actionRecord)
 		}
 	}
 
+	if at.dryRunSkip(actionRecord, "部分平仓 %s %s %.1f%% 数量 %.4f", decision.Symbol, positionSide, decision.ClosePercentage, closeQuantity) {
+		return nil
+	}
+
 	// 执行平仓
 	var order map[string]interface{}
 	if positionSide == "LONG" {
@@ -1814,8 +2449,10 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 		actionRecord.OrderID = orderID
 	}
 
-	log.Printf("  ✓ 部分平仓成功: 平仓 %.4f (%.1f%%), 剩余 %.4f",
+	at.log().Infof("  ✓ 部分平仓成功: 平仓 %.4f (%.1f%%), 剩余 %.4f",
 		closeQuantity, decision.ClosePercentage, remainingQuantity)
+	positionEntryPrice, _ := targetPosition["entryPrice"].(float64)
+	at.emitCloseEvent(decision.Symbol, strings.ToLower(positionSide), closeQuantity, positionEntryPrice, marketData.CurrentPrice, true, decision.Reasoning)
 
 	// 🔧 階段1修復#2: 記錄部分平倉到數據庫
 	if db, ok := at.database.(interface {
@@ -1853,7 +2490,7 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 			reason = fmt.Sprintf("部分平倉 %.1f%%", decision.ClosePercentage)
 		}
 
-		if err := db.RecordTrade(
+		if err := at.recordTradeWithRetry(db,
 			at.config.ID, at.userID, decision.Symbol,
 			positionSide, "PARTIAL_CLOSE",
 			closeQuantity, marketData.CurrentPrice,
@@ -1861,9 +2498,9 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 			decision.NewStopLoss, decision.NewTakeProfit,
 			partialPnL, partialPnLPct,
 		); err != nil {
-			log.Printf("  ⚠️ 記錄部分平倉到數據庫失敗: %v", err)
+			at.log().Warnf("  ⚠️ 記錄部分平倉到數據庫失敗: %v", err)
 		} else if partialPnL != 0 {
-			log.Printf("  💰 部分平倉 PnL: %.2f USDT (%.2f%%)", partialPnL, partialPnLPct)
+			at.log().Infof("  💰 部分平倉 PnL: %.2f USDT (%.2f%%)", partialPnL, partialPnLPct)
 		}
 	}
 
@@ -1880,16 +2517,17 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 		}
 
 		if isValidStopLoss {
-			log.Printf("  → Restoring stop-loss for remaining position %.4f: %.2f", remainingQuantity, decision.NewStopLoss)
+			decision.NewStopLoss = at.alignStopPrice(decision.Symbol, positionSide, true, decision.NewStopLoss)
+			at.log().Infof("  → Restoring stop-loss for remaining position %.4f: %.2f", remainingQuantity, decision.NewStopLoss)
 			err = at.trader.SetStopLoss(decision.Symbol, positionSide, remainingQuantity, decision.NewStopLoss)
 			if err != nil {
-				log.Printf("  ⚠️ Failed to restore stop-loss: %v (doesn't affect close result)", err)
+				at.log().Warnf("  ⚠️ Failed to restore stop-loss: %v (doesn't affect close result)", err)
 			}
 		} else {
 			priceGapPct := math.Abs((decision.NewStopLoss-marketData.CurrentPrice)/marketData.CurrentPrice) * 100
-			log.Printf("  ⚠️⚠️ 跳过设置止损：价格不合理 (止损 %.2f, 当前 %.2f, 差距 %.2f%%)",
+			at.log().Warnf("  ⚠️⚠️ 跳过设置止损：价格不合理 (止损 %.2f, 当前 %.2f, 差距 %.2f%%)",
 				decision.NewStopLoss, marketData.CurrentPrice, priceGapPct)
-			log.Printf("  → %s仓位的止损必须%s当前价，剩余仓位目前没有止损保护",
+			at.log().Infof("  → %s仓位的止损必须%s当前价，剩余仓位目前没有止损保护",
 				positionSide, map[string]string{"LONG": "低于", "SHORT": "高于"}[positionSide])
 		}
 	}
@@ -1904,25 +2542,26 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 		}
 
 		if isValidTakeProfit {
-			log.Printf("  → Restoring take-profit for remaining position %.4f: %.2f", remainingQuantity, decision.NewTakeProfit)
+			decision.NewTakeProfit = at.alignStopPrice(decision.Symbol, positionSide, false, decision.NewTakeProfit)
+			at.log().Infof("  → Restoring take-profit for remaining position %.4f: %.2f", remainingQuantity, decision.NewTakeProfit)
 			err = at.trader.SetTakeProfit(decision.Symbol, positionSide, remainingQuantity, decision.NewTakeProfit)
 			if err != nil {
-				log.Printf("  ⚠️ Failed to restore take-profit: %v (doesn't affect close result)", err)
+				at.log().Warnf("  ⚠️ Failed to restore take-profit: %v (doesn't affect close result)", err)
 			}
 		} else {
 			priceGapPct := math.Abs((decision.NewTakeProfit-marketData.CurrentPrice)/marketData.CurrentPrice) * 100
-			log.Printf("  ⚠️⚠️ 跳过设置止盈：价格不合理 (止盈 %.2f, 当前 %.2f, 差距 %.2f%%)",
+			at.log().Warnf("  ⚠️⚠️ 跳过设置止盈：价格不合理 (止盈 %.2f, 当前 %.2f, 差距 %.2f%%)",
 				decision.NewTakeProfit, marketData.CurrentPrice, priceGapPct)
-			log.Printf("  → %s仓位的止盈必须%s当前价，剩余仓位目前没有止盈保护",
+			at.log().Infof("  → %s仓位的止盈必须%s当前价，剩余仓位目前没有止盈保护",
 				positionSide, map[string]string{"LONG": "高于", "SHORT": "低于"}[positionSide])
 		}
 	}
 
 	// 如果 AI 没有提供新的止盈止损，记录警告
 	if decision.NewStopLoss <= 0 && decision.NewTakeProfit <= 0 {
-		log.Printf("  ⚠️⚠️⚠️ 警告: 部分平仓后AI未提供新的止盈止损价格")
-		log.Printf("  → 剩余仓位 %.4f (价值 %.2f USDT) 目前没有止盈止损保护", remainingQuantity, remainingValue)
-		log.Printf("  → 建议: 在 partial_close 决策中包含 new_stop_loss 和 new_take_profit 字段")
+		at.log().Warnf("  ⚠️⚠️⚠️ 警告: 部分平仓后AI未提供新的止盈止损价格")
+		at.log().Infof("  → 剩余仓位 %.4f (价值 %.2f USDT) 目前没有止盈止损保护", remainingQuantity, remainingValue)
+		at.log().Infof("  → 建议: 在 partial_close 决策中包含 new_stop_loss 和 new_take_profit 字段")
 	}
 
 	return nil
@@ -1933,6 +2572,11 @@ func (at *AutoTrader) GetID() string {
 	return at.id
 }
 
+// GetUserID 获取trader所属用户ID
+func (at *AutoTrader) GetUserID() string {
+	return at.userID
+}
+
 // GetName 获取trader名称
 func (at *AutoTrader) GetName() string {
 	return at.name
@@ -1979,21 +2623,53 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 	if at.config.UseQwen {
 		aiProvider = "Qwen"
 	}
+	aiFailureRatePct, aiQualitySamples := at.GetAIQuality()
+	aiCallsToday, aiCallsRemaining := at.GetAIBudget()
+	health := at.GetCycleHealth()
+	lastErrorTime := ""
+	if !health.LastErrorTime.IsZero() {
+		lastErrorTime = health.LastErrorTime.Format(time.RFC3339)
+	}
 
 	return map[string]interface{}{
 		"trader_id":       at.id,
 		"trader_name":     at.name,
 		"ai_model":        at.aiModel,
 		"exchange":        at.exchange,
-		"is_running":      at.isRunning,
+		"is_running":      at.isRunning.Load(),
 		"start_time":      at.startTime.Format(time.RFC3339),
 		"runtime_minutes": int(time.Since(at.startTime).Minutes()),
 		"call_count":      at.callCount,
 		"initial_balance": at.initialBalance,
 		"scan_interval":   at.config.ScanInterval.String(),
-		"stop_until":      at.stopUntil.Format(time.RFC3339),
+		"stop_until":      at.getStopUntil().Format(time.RFC3339),
 		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
 		"ai_provider":     aiProvider,
+
+		"exchange_maintenance": at.IsExchangeInMaintenance(),
+		"unfunded":             at.IsUnfunded(),
+		"unfunded_threshold":   at.unfundedThreshold(),
+		"halted_symbols":       at.GetHaltedSymbols(),
+		"portfolio_group":      portfolioGroupName(at.portfolio.Load()),
+		"daily_trade_count":    at.dailyTradeCount,
+		"max_trades_per_day":   at.config.MaxTradesPerDay,
+		"model_pool_size":      len(at.modelPool),
+		"fallback_model":       at.fallbackModelLabel(),
+		"ai_failure_rate_pct":  aiFailureRatePct,
+		"ai_quality_samples":   aiQualitySamples,
+		"ai_quality_window":    at.config.AIQualityWindow,
+		"ai_calls_today":       aiCallsToday,
+		"max_ai_calls_per_day": at.config.MaxAICallsPerDay,
+		"ai_calls_remaining":   aiCallsRemaining, // -1 表示不限制
+
+		"health":                 health.Health, // ok / degraded / failing
+		"health_reason":          health.Reason,
+		"consecutive_errors":     health.ConsecutiveErrors,
+		"last_error":             health.LastError,
+		"last_error_time":        lastErrorTime,
+		"max_consecutive_errors": at.config.MaxConsecutiveErrors,
+		"auto_paused":            health.AutoPaused,
+		"auto_pause_reason":      health.AutoPauseReason,
 	}
 }
 
@@ -2050,7 +2726,7 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 	// 验证未实现盈亏的一致性（API值 vs 从持仓计算）
 	diff := math.Abs(totalUnrealizedProfit - totalUnrealizedPnLCalculated)
 	if diff > 0.1 { // 允许0.01 USDT的误差
-		log.Printf("⚠️ 未实现盈亏不一致: API=%.4f, 计算=%.4f, 差异=%.4f",
+		at.log().Warnf("⚠️ 未实现盈亏不一致: API=%.4f, 计算=%.4f, 差异=%.4f",
 			totalUnrealizedProfit, totalUnrealizedPnLCalculated, diff)
 	}
 
@@ -2059,7 +2735,7 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 	if at.initialBalance > 0 {
 		totalPnLPct = (totalPnL / at.initialBalance) * 100
 	} else {
-		log.Printf("⚠️ Initial Balance异常: %.2f，无法计算PNL百分比", at.initialBalance)
+		at.log().Warnf("⚠️ Initial Balance异常: %.2f，无法计算PNL百分比", at.initialBalance)
 	}
 
 	marginUsedPct := 0.0
@@ -2123,6 +2799,7 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 
 		// 计算盈亏百分比（基于保证金）
 		pnlPct := calculatePnLPercentage(unrealizedPnl, marginUsed)
+		annotation := at.getPositionAnnotation(symbol + "_" + side)
 
 		result = append(result, map[string]interface{}{
 			"symbol":             symbol,
@@ -2135,6 +2812,8 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 			"unrealized_pnl_pct": pnlPct,
 			"liquidation_price":  liquidationPrice,
 			"margin_used":        marginUsed,
+			"note":               annotation.Note,
+			"locked":             annotation.Locked,
 		})
 	}
 
@@ -2162,8 +2841,8 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 		switch action {
 		case "close_long", "close_short", "partial_close":
 			return 1 // 最高优先级：先平仓（包括部分平仓）
-		case "update_stop_loss", "update_take_profit":
-			return 2 // 调整持仓止盈止损
+		case "update_stop_loss", "update_take_profit", "set_trailing_stop", "cancel_order":
+			return 2 // 调整持仓止盈止损、撤销未成交挂单
 		case "open_long", "open_short":
 			return 3 // 次优先级：后开仓
 		case "hold", "wait":
@@ -2189,8 +2868,17 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 	return sorted
 }
 
-// getCandidateCoins 获取交易员的候选币种列表
+// getCandidateCoins 获取交易员的候选币种列表（已移除黑名单币种）
 func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
+	coins, err := at.collectCandidateCoins()
+	if err != nil {
+		return nil, err
+	}
+	return at.filterBlacklistedCoins(coins), nil
+}
+
+// collectCandidateCoins 按优先级收集候选币种（自定义币种 > 信号源扩展 > 系统默认）
+func (at *AutoTrader) collectCandidateCoins() ([]decision.CandidateCoin, error) {
 	// 优先级 1: 自定义币种列表（最高优先级）
 	if len(at.tradingCoins) > 0 {
 		var candidateCoins []decision.CandidateCoin
@@ -2201,7 +2889,7 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 				Sources: []string{"custom"},
 			})
 		}
-		log.Printf("📋 [%s] 使用自定义币种: %d个币种 %v",
+		at.log().Infof("📋 [%s] 使用自定义币种: %d个币种 %v",
 			at.name, len(candidateCoins), at.tradingCoins)
 		return candidateCoins, nil
 	}
@@ -2209,6 +2897,7 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 	// 优先级 2: 信号源扩展模式（合并系统默认 + 信号源）
 	if at.useCoinPool || at.useOITop {
 		symbolMap := make(map[string][]string) // symbol -> sources
+		symbolBias := make(map[string]string)  // symbol -> 信号方向偏好
 		coinPoolURL := strings.TrimSpace(at.coinPoolAPIURL)
 		oiTopURL := strings.TrimSpace(at.oiTopAPIURL)
 
@@ -2238,30 +2927,31 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 						symbolMap[symbol] = sources
 						signalSourceCount++
 					}
+					if bias, ok := mergedPool.SymbolBias[symbol]; ok {
+						symbolBias[symbol] = bias
+					}
 				}
 			} else if err != nil {
-				log.Printf("⚠️  [%s] 获取合并信号源失败: %v", at.name, err)
+				at.log().Warnf("⚠️  [%s] 获取合并信号源失败: %v", at.name, err)
 			}
 		} else if at.useCoinPool {
 			// 只使用 AI500
-			var ai500Pool []string
-			var err error
-			if coinPoolURL != "" {
-				ai500Pool, err = pool.GetTopRatedCoinsWithURL(ai500Limit, coinPoolURL)
-			} else {
-				ai500Pool, err = pool.GetTopRatedCoins(ai500Limit)
-			}
+			ai500Pool, err := pool.GetTopRatedCoinInfosWithURL(ai500Limit, coinPoolURL)
 			if err == nil {
-				for _, symbol := range ai500Pool {
+				for _, coin := range ai500Pool {
+					symbol := normalizeSymbol(coin.Pair)
 					if existingSources, exists := symbolMap[symbol]; exists {
 						symbolMap[symbol] = append(existingSources, "ai500")
 					} else {
 						symbolMap[symbol] = []string{"ai500"}
 						signalSourceCount++
 					}
+					if bias := pool.NormalizeBias(coin.Bias); bias != "" {
+						symbolBias[symbol] = bias
+					}
 				}
 			} else if err != nil {
-				log.Printf("⚠️  [%s] 获取 AI500 信号失败: %v", at.name, err)
+				at.log().Warnf("⚠️  [%s] 获取 AI500 信号失败: %v", at.name, err)
 			}
 		} else if at.useOITop {
 			// 只使用 OI Top
@@ -2285,22 +2975,44 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 						symbolMap[symbol] = []string{"oi_top"}
 						signalSourceCount++
 					}
+					if bias := pool.NormalizeBias(oiTopPool[i].Bias); bias != "" {
+						symbolBias[symbol] = bias
+					}
 				}
 			} else if err != nil {
-				log.Printf("⚠️  [%s] 获取 OI Top 信号失败: %v", at.name, err)
+				at.log().Warnf("⚠️  [%s] 获取 OI Top 信号失败: %v", at.name, err)
 			}
 		}
 
-		// 2.3 构建候选币种列表
+		// 2.3 构建候选币种列表（标注信号源快照的数据年龄，刷新失败时标记为过期）
+		snapshots := make(map[string]pool.SnapshotInfo)
+		if at.useCoinPool {
+			snapshots["ai500"] = pool.GetSnapshotInfo(pool.SourceAI500, coinPoolURL)
+		}
+		if at.useOITop {
+			snapshots["oi_top"] = pool.GetSnapshotInfo(pool.SourceOITop, oiTopURL)
+		}
 		var candidateCoins []decision.CandidateCoin
 		for symbol, sources := range symbolMap {
-			candidateCoins = append(candidateCoins, decision.CandidateCoin{
+			coin := decision.CandidateCoin{
 				Symbol:  symbol,
 				Sources: sources,
-			})
+				Bias:    symbolBias[symbol],
+			}
+			for _, source := range sources {
+				info, ok := snapshots[source]
+				if !ok || info.FetchedAt.IsZero() {
+					continue
+				}
+				if info.AgeSeconds > coin.SignalAgeSeconds {
+					coin.SignalAgeSeconds = info.AgeSeconds
+				}
+				coin.SignalStale = coin.SignalStale || info.Stale
+			}
+			candidateCoins = append(candidateCoins, coin)
 		}
 
-		log.Printf("📋 [%s] 信号源扩展模式: 系统默认%d + 信号源新增%d = 总计%d个候选币种",
+		at.log().Infof("📋 [%s] 信号源扩展模式: 系统默认%d + 信号源新增%d = 总计%d个候选币种",
 			at.name, defaultCount, signalSourceCount, len(candidateCoins))
 		return candidateCoins, nil
 	}
@@ -2315,13 +3027,13 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 				Sources: []string{"default"},
 			})
 		}
-		log.Printf("📋 [%s] 使用系统默认币种: %d个币种 %v",
+		at.log().Infof("📋 [%s] 使用系统默认币种: %d个币种 %v",
 			at.name, len(candidateCoins), at.defaultCoins)
 		return candidateCoins, nil
 	}
 
 	// 优先级 4: 都没有配置 - 返回空列表（AI 只管理现有持仓）
-	log.Printf("⚠️  [%s] 无任何币种来源，AI 将只管理现有持仓（不开新仓）", at.name)
+	at.log().Warnf("⚠️  [%s] 无任何币种来源，AI 将只管理现有持仓（不开新仓）", at.name)
 	return []decision.CandidateCoin{}, nil
 }
 
@@ -2347,14 +3059,14 @@ func (at *AutoTrader) startDrawdownMonitor() {
 		ticker := time.NewTicker(1 * time.Minute) // 每分钟检查一次
 		defer ticker.Stop()
 
-		log.Println("📊 启动持仓回撤监控（每分钟检查一次）")
+		at.log().Infoln("📊 启动持仓回撤监控（每分钟检查一次）")
 
 		for {
 			select {
 			case <-ticker.C:
 				at.checkPositionDrawdown()
 			case <-at.stopMonitorCh:
-				log.Println("⏹ 停止持仓回撤监控")
+				at.log().Infoln("⏹ 停止持仓回撤监控")
 				return
 			}
 		}
@@ -2366,7 +3078,7 @@ func (at *AutoTrader) checkPositionDrawdown() {
 	// 获取当前持仓
 	positions, err := at.trader.GetPositions()
 	if err != nil {
-		log.Printf("❌ 回撤监控：获取持仓失败: %v", err)
+		at.log().Errorf("❌ 回撤监控：获取持仓失败: %v", err)
 		return
 	}
 
@@ -2393,23 +3105,14 @@ func (at *AutoTrader) checkPositionDrawdown() {
 			currentPnLPct = ((entryPrice - markPrice) / entryPrice) * float64(leverage) * 100
 		}
 
-		// 构造持仓唯一标识（区分多空）
-		posKey := symbol + "_" + side
-
-		// 获取该持仓的历史最高收益
-		at.peakPnLCacheMutex.RLock()
-		peakPnLPct, exists := at.peakPnLCache[posKey]
-		at.peakPnLCacheMutex.RUnlock()
-
-		if !exists {
-			// 如果没有历史最高记录，使用当前盈亏作为初始值
-			peakPnLPct = currentPnLPct
-			at.UpdatePeakPnL(symbol, side, currentPnLPct)
-		} else {
-			// 更新峰值缓存
-			at.UpdatePeakPnL(symbol, side, currentPnLPct)
+		// 追踪止损：随价格移动止损线，越过止损线时平仓
+		if at.ratchetTrailingStop(symbol, side, markPrice) {
+			continue
 		}
 
+		// 获取该持仓的历史最高收益并更新峰值缓存（没有历史记录时使用当前盈亏作为初始值）
+		peakPnLPct := at.observePeakPnL(symbol, side, currentPnLPct)
+
 		// 计算回撤（从最高点下跌的幅度）
 		var drawdownPct float64
 		if peakPnLPct > 0 && currentPnLPct < peakPnLPct {
@@ -2418,20 +3121,20 @@ func (at *AutoTrader) checkPositionDrawdown() {
 
 		// 检查平仓条件：收益大于5%且回撤超过40%
 		if currentPnLPct > 5.0 && drawdownPct >= 40.0 {
-			log.Printf("🚨 触发回撤平仓条件: %s %s | 当前收益: %.2f%% | 最高收益: %.2f%% | 回撤: %.2f%%",
+			at.log().Warnf("🚨 触发回撤平仓条件: %s %s | 当前收益: %.2f%% | 最高收益: %.2f%% | 回撤: %.2f%%",
 				symbol, side, currentPnLPct, peakPnLPct, drawdownPct)
 
 			// 执行平仓
 			if err := at.emergencyClosePosition(symbol, side); err != nil {
-				log.Printf("❌ 回撤平仓失败 (%s %s): %v", symbol, side, err)
+				at.log().Errorf("❌ 回撤平仓失败 (%s %s): %v", symbol, side, err)
 			} else {
-				log.Printf("✅ 回撤平仓成功: %s %s", symbol, side)
+				at.log().Infof("✅ 回撤平仓成功: %s %s", symbol, side)
 				// 平仓后清理该持仓的缓存
 				at.ClearPeakPnLCache(symbol, side)
 			}
 		} else if currentPnLPct > 5.0 {
 			// 记录接近平仓条件的情况（用于调试）
-			log.Printf("📊 回撤监控: %s %s | 收益: %.2f%% | 最高: %.2f%% | 回撤: %.2f%%",
+			at.log().Infof("📊 回撤监控: %s %s | 收益: %.2f%% | 最高: %.2f%% | 回撤: %.2f%%",
 				symbol, side, currentPnLPct, peakPnLPct, drawdownPct)
 		}
 	}
@@ -2453,17 +3156,23 @@ func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
 	// 獲取當前價格
 	marketData, err := market.Get(symbol, at.timeframes)
 	if err != nil {
-		log.Printf("⚠️ 獲取市場數據失敗: %v", err)
+		at.log().Warnf("⚠️ 獲取市場數據失敗: %v", err)
 	}
 	currentPrice := marketData.CurrentPrice
 
+	if at.dryRunSkip(nil, "回撤紧急平仓 %s %s 数量 %.4f", symbol, side, quantity) {
+		return nil
+	}
+
 	switch side {
 	case "long":
 		order, err := at.trader.CloseLong(symbol, 0) // 0 = 全部平仓
 		if err != nil {
 			return err
 		}
-		log.Printf("✅ 紧急平多仓成功，订单ID: %v", order["orderId"])
+		at.log().Infof("✅ 紧急平多仓成功，订单ID: %v", order["orderId"])
+		at.releaseSymbolSlot(symbol, "long")
+		at.emitCloseEvent(symbol, "long", quantity, entryPrice, currentPrice, false, "回撤觸發緊急平倉")
 
 		// 🔧 記錄緊急平倉到數據庫
 		if db, ok := at.database.(interface {
@@ -2472,7 +3181,7 @@ func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
 			pnl := (currentPrice - entryPrice) * quantity
 			pnlPct := ((currentPrice - entryPrice) / entryPrice) * 100
 
-			db.RecordTrade(
+			at.recordTradeWithRetry(db,
 				at.config.ID, at.userID, symbol, "LONG", "EMERGENCY_CLOSE",
 				quantity, currentPrice, "回撤觸發緊急平倉",
 				0, 0, pnl, pnlPct,
@@ -2484,7 +3193,9 @@ func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
 		if err != nil {
 			return err
 		}
-		log.Printf("✅ 紧急平空仓成功，订单ID: %v", order["orderId"])
+		at.log().Infof("✅ 紧急平空仓成功，订单ID: %v", order["orderId"])
+		at.releaseSymbolSlot(symbol, "short")
+		at.emitCloseEvent(symbol, "short", quantity, entryPrice, currentPrice, false, "回撤觸發緊急平倉")
 
 		// 🔧 記錄緊急平倉到數據庫
 		if db, ok := at.database.(interface {
@@ -2493,7 +3204,7 @@ func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
 			pnl := (entryPrice - currentPrice) * quantity
 			pnlPct := ((entryPrice - currentPrice) / entryPrice) * 100
 
-			db.RecordTrade(
+			at.recordTradeWithRetry(db,
 				at.config.ID, at.userID, symbol, "SHORT", "EMERGENCY_CLOSE",
 				quantity, currentPrice, "回撤觸發緊急平倉",
 				0, 0, pnl, pnlPct,
@@ -2537,6 +3248,35 @@ func (at *AutoTrader) UpdatePeakPnL(symbol, side string, currentPnLPct float64)
 	}
 }
 
+// observePeakPnL 返回持仓此前的最高收益并用当前收益更新峰值（读取与更新在同一把锁内完成）
+// 没有历史记录时返回当前收益
+func (at *AutoTrader) observePeakPnL(symbol, side string, currentPnLPct float64) float64 {
+	at.peakPnLCacheMutex.Lock()
+	defer at.peakPnLCacheMutex.Unlock()
+
+	posKey := symbol + "_" + side
+	peak, exists := at.peakPnLCache[posKey]
+	if !exists {
+		peak = currentPnLPct
+	}
+	if !exists || currentPnLPct > peak {
+		at.peakPnLCache[posKey] = currentPnLPct
+	}
+	return peak
+}
+
+// prunePeakPnLCache 清理已不存在持仓的峰值缓存（避免旧峰值被同币种同方向的新持仓沿用）
+func (at *AutoTrader) prunePeakPnLCache(activeKeys map[string]bool) {
+	at.peakPnLCacheMutex.Lock()
+	defer at.peakPnLCacheMutex.Unlock()
+
+	for key := range at.peakPnLCache {
+		if !activeKeys[key] {
+			delete(at.peakPnLCache, key)
+		}
+	}
+}
+
 // ClearPeakPnLCache 清除指定持仓的峰值缓存
 func (at *AutoTrader) ClearPeakPnLCache(symbol, side string) {
 	at.peakPnLCacheMutex.Lock()
@@ -2681,7 +3421,7 @@ func (at *AutoTrader) ReloadAIModelConfig(modelConfig *config.AIModelConfig) err
 		return fmt.Errorf("模型配置为空")
 	}
 
-	log.Printf("🔄 [%s] 重新加载AI模型配置...", at.name)
+	at.log().Infof("🔄 [%s] 重新加载AI模型配置...", at.name)
 
 	// 更新AI模型相关配置
 	at.config.CustomModelName = modelConfig.CustomModelName
@@ -2692,15 +3432,15 @@ func (at *AutoTrader) ReloadAIModelConfig(modelConfig *config.AIModelConfig) err
 	case "deepseek":
 		at.config.DeepSeekKey = modelConfig.APIKey
 		at.config.CustomAPIKey = modelConfig.APIKey
-		log.Printf("✓ [%s] DeepSeek配置已更新: Model=%s, BaseURL=%s",
+		at.log().Infof("✓ [%s] DeepSeek配置已更新: Model=%s, BaseURL=%s",
 			at.name, at.config.CustomModelName, at.config.CustomAPIURL)
 	case "qwen":
 		at.config.QwenKey = modelConfig.APIKey
-		log.Printf("✓ [%s] Qwen配置已更新: Model=%s",
+		at.log().Infof("✓ [%s] Qwen配置已更新: Model=%s",
 			at.name, at.config.CustomModelName)
 	case "custom":
 		at.config.CustomAPIKey = modelConfig.APIKey
-		log.Printf("✓ [%s] 自定义AI配置已更新: URL=%s, Model=%s",
+		at.log().Infof("✓ [%s] 自定义AI配置已更新: URL=%s, Model=%s",
 			at.name, at.config.CustomAPIURL, at.config.CustomModelName)
 	default:
 		return fmt.Errorf("不支持的AI provider: %s", modelConfig.Provider)
@@ -2711,7 +3451,7 @@ func (at *AutoTrader) ReloadAIModelConfig(modelConfig *config.AIModelConfig) err
 		return fmt.Errorf("重新初始化MCP客户端失败: %w", err)
 	}
 
-	log.Printf("✅ [%s] AI模型配置热更新完成", at.name)
+	at.log().Infof("✅ [%s] AI模型配置热更新完成", at.name)
 	return nil
 }
 
@@ -2740,7 +3480,7 @@ func (at *AutoTrader) reinitializeMCPClient() error {
 	// 使用统一的 SetAPIKey 方法重新初始化
 	at.mcpClient.SetAPIKey(apiKey, at.config.CustomAPIURL, at.config.CustomModelName)
 
-	log.Printf("🔧 [MCP] AI模型配置已重新初始化: Model=%s, Provider=%s, CustomURL=%s",
+	at.log().Infof("🔧 [MCP] AI模型配置已重新初始化: Model=%s, Provider=%s, CustomURL=%s",
 		at.config.CustomModelName, at.config.AIModel, at.config.CustomAPIURL)
 
 	return nil
@@ -2776,7 +3516,7 @@ func (at *AutoTrader) syncAutoClosedPositions() error {
 		for _, pos := range exchangePositions {
 			symbol, _ := pos["symbol"].(string)
 			side, _ := pos["side"].(string)
-			key := symbol + "_" + side
+			key := symbol + "_" + strings.ToUpper(side) // 數據庫中方向為大寫（LONG/SHORT）
 			exchangeKeys[key] = true
 		}
 
@@ -2789,20 +3529,24 @@ func (at *AutoTrader) syncAutoClosedPositions() error {
 					continue
 				}
 				symbol, side := parts[0], parts[1]
+				// 限价开仓单挂单中尚未成交，交易所没有持仓是正常的
+				if at.hasPendingEntry(symbol, strings.ToLower(side)) {
+					continue
+				}
 
-				log.Printf("⚠️ 檢測到交易所自動平倉: %s %s（數據庫顯示開倉但交易所已平）", symbol, side)
+				at.log().Warnf("⚠️ 檢測到交易所自動平倉: %s %s（數據庫顯示開倉但交易所已平）", symbol, side)
 
 				// 獲取當前價格
 				marketData, err := market.Get(symbol, at.timeframes)
 				if err != nil {
-					log.Printf("⚠️ 獲取 %s 市場數據失敗: %v", symbol, err)
+					at.log().Warnf("⚠️ 獲取 %s 市場數據失敗: %v", symbol, err)
 					continue
 				}
 
 				// 從數據庫獲取開倉信息
 				entryPrice, quantity, err := db.GetLastOpenTrade(at.config.ID, symbol, strings.ToUpper(side))
 				if err != nil {
-					log.Printf("⚠️ 獲取 %s 開倉信息失敗: %v", symbol, err)
+					at.log().Warnf("⚠️ 獲取 %s 開倉信息失敗: %v", symbol, err)
 					continue
 				}
 
@@ -2817,16 +3561,16 @@ func (at *AutoTrader) syncAutoClosedPositions() error {
 				}
 
 				// 記錄自動平倉事件
-				if err := db.RecordTrade(
+				if err := at.recordTradeWithRetry(db,
 					at.config.ID, at.userID, symbol,
 					strings.ToUpper(side), "AUTO_CLOSE",
 					quantity, marketData.CurrentPrice,
 					"交易所自動平倉（止損/止盈/強平）",
 					0, 0, pnl, pnlPct,
 				); err != nil {
-					log.Printf("⚠️ 記錄自動平倉失敗: %v", err)
+					at.log().Warnf("⚠️ 記錄自動平倉失敗: %v", err)
 				} else {
-					log.Printf("✅ 已補記錄自動平倉: %s %s, PnL: %.2f USDT (%.2f%%)",
+					at.log().Infof("✅ 已補記錄自動平倉: %s %s, PnL: %.2f USDT (%.2f%%)",
 						symbol, strings.ToUpper(side), pnl, pnlPct)
 				}
 			}
@@ -103,7 +103,6 @@ func (s *AutoTraderTestSuite) SetupTest() {
 		peakEquity:            s.config.InitialBalance,
 		startTime:             time.Now(),
 		callCount:             0,
-		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
 		lastPositions:         make(map[string]decision.PositionInfo),
 		positionStopLoss:      make(map[string]float64),
@@ -257,7 +256,7 @@ func (s *AutoTraderTestSuite) TestPeakPnLCache() {
 // ============================================================
 
 func (s *AutoTraderTestSuite) TestGetStatus() {
-	s.autoTrader.isRunning = true
+	s.autoTrader.isRunning.Store(true)
 	s.autoTrader.callCount = 15
 
 	status := s.autoTrader.GetStatus()
@@ -407,6 +406,49 @@ func (s *AutoTraderTestSuite) TestBuildTradingContext() {
 	s.Equal(5, ctx.AltcoinLeverage)
 }
 
+// TestRejectionFeedbackInNextContext 测试执行失败的决策出现在下一周期的上下文中（只反馈一次）
+func (s *AutoTraderTestSuite) TestRejectionFeedbackInNextContext() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+
+	s.autoTrader.config.RejectionFeedback = true
+	s.autoTrader.config.MaxTradesPerDay = 1
+	s.autoTrader.dailyTradeCount = 1
+	s.autoTrader.lastResetTime = time.Now()
+	defer func() {
+		s.autoTrader.config.RejectionFeedback = false
+		s.autoTrader.config.MaxTradesPerDay = 0
+		s.autoTrader.dailyTradeCount = 0
+	}()
+
+	// 守卫拒绝和交易所拒单都会被记录
+	open := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 10, StopLoss: 48000.0, TakeProfit: 52000.0}
+	err := s.autoTrader.executeOpenLongWithRecord(open, &logger.DecisionAction{})
+	s.Error(err)
+	s.autoTrader.captureRejectionFeedback(open, err)
+	update := &decision.Decision{Action: "update_stop_loss", Symbol: "ETHUSDT", NewStopLoss: 3100}
+	s.autoTrader.captureRejectionFeedback(update, fmt.Errorf("<APIError> code=-2021, msg=Order would immediately trigger."))
+
+	ctx, err := s.autoTrader.buildTradingContext()
+	s.NoError(err)
+	s.Require().Len(ctx.RecentRejections, 2)
+	s.Equal("BTCUSDT", ctx.RecentRejections[0].Symbol)
+	s.Equal(RejectDailyTradeLimit, ctx.RecentRejections[0].Code)
+	s.Equal("update_stop_loss", ctx.RecentRejections[1].Action)
+	s.Contains(ctx.RecentRejections[1].Reason, "code=-2021")
+
+	// 已反馈过的失败不再重复出现
+	ctx, err = s.autoTrader.buildTradingContext()
+	s.NoError(err)
+	s.Empty(ctx.RecentRejections)
+
+	// 关闭反馈后不记录
+	s.autoTrader.config.RejectionFeedback = false
+	s.autoTrader.captureRejectionFeedback(update, fmt.Errorf("insufficient margin"))
+	s.Empty(s.autoTrader.rejectionFeedback.drain())
+}
+
 // ============================================================
 // 层次 9: 交易执行测试
 // ============================================================
@@ -418,6 +460,7 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 		action        string
 		expectedOrder int64
 		existingSide  string
+		haltedReason  string
 		availBalance  float64
 		expectedErr   string
 		executeFn     func(*decision.Decision, *logger.DecisionAction) error
@@ -478,6 +521,26 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 				return s.autoTrader.executeOpenShortWithRecord(d, a)
 			},
 		},
+		{
+			name:         "多仓_币种暂停交易",
+			action:       "open_long",
+			haltedReason: "交易对状态为 BREAK（暂停交易）",
+			availBalance: 8000.0,
+			expectedErr:  "当前不可交易",
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeOpenLongWithRecord(d, a)
+			},
+		},
+		{
+			name:         "空仓_币种暂停交易",
+			action:       "open_short",
+			haltedReason: "交易对状态为 SETTLING（暂停交易）",
+			availBalance: 8000.0,
+			expectedErr:  "当前不可交易",
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeOpenShortWithRecord(d, a)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -493,6 +556,9 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 			} else {
 				s.mockTrader.positions = []map[string]interface{}{}
 			}
+			if tt.haltedReason != "" {
+				s.mockTrader.haltedSymbols = map[string]string{"BTCUSDT": tt.haltedReason}
+			}
 
 			decision := &decision.Decision{
 				Action:          tt.action,
@@ -524,10 +590,613 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 			// 恢复默认状态
 			s.mockTrader.balance["availableBalance"] = 8000.0
 			s.mockTrader.positions = []map[string]interface{}{}
+			s.mockTrader.haltedSymbols = nil
 		})
 	}
 }
 
+// TestCheckSymbolTradable 测试暂停交易的币种被跳过，正常币种继续交易
+func (s *AutoTraderTestSuite) TestCheckSymbolTradable() {
+	s.mockTrader.haltedSymbols = map[string]string{"LUNAUSDT": "交易对状态为 BREAK（暂停交易）"}
+	defer func() { s.mockTrader.haltedSymbols = nil }()
+
+	err := s.autoTrader.checkSymbolTradable("LUNAUSDT")
+	s.Error(err)
+	s.Contains(err.Error(), "暂停交易")
+	s.Equal("交易对状态为 BREAK（暂停交易）", s.autoTrader.GetHaltedSymbols()["LUNAUSDT"])
+
+	s.NoError(s.autoTrader.checkSymbolTradable("BTCUSDT"))
+	s.NotContains(s.autoTrader.GetHaltedSymbols(), "BTCUSDT")
+	s.False(s.autoTrader.IsExchangeInMaintenance())
+
+	// 恢复交易后从暂停列表移除
+	s.mockTrader.haltedSymbols = nil
+	s.NoError(s.autoTrader.checkSymbolTradable("LUNAUSDT"))
+	s.Empty(s.autoTrader.GetHaltedSymbols())
+
+	// 交易所维护：所有币种均跳过，并反映到状态中
+	s.mockTrader.maintenance = true
+	defer func() { s.mockTrader.maintenance = false }()
+	err = s.autoTrader.checkSymbolTradable("BTCUSDT")
+	s.Error(err)
+	s.Contains(err.Error(), "维护")
+	s.True(s.autoTrader.IsExchangeInMaintenance())
+	s.Equal(true, s.autoTrader.GetStatus()["exchange_maintenance"])
+}
+
+// TestGlobalSymbolPositionCap 测试全实例币种持仓上限：两个交易员同时开同一币种
+func (s *AutoTraderTestSuite) TestGlobalSymbolPositionCap() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+
+	registry := NewSymbolPositionRegistry(1)
+	s.autoTrader.SetSymbolRegistry(registry)
+	defer s.autoTrader.SetSymbolRegistry(nil)
+
+	// 第二个交易员：独立账户，共享同一登记表
+	otherMock := &MockTrader{
+		balance:   map[string]interface{}{"totalWalletBalance": 10000.0, "availableBalance": 8000.0, "totalUnrealizedProfit": 0.0},
+		positions: []map[string]interface{}{},
+	}
+	other := &AutoTrader{
+		id:                    "other_trader",
+		name:                  "Other Trader",
+		config:                s.config,
+		trader:                otherMock,
+		lastPositions:         make(map[string]decision.PositionInfo),
+		positionFirstSeenTime: make(map[string]int64),
+		positionStopLoss:      make(map[string]float64),
+		positionTakeProfit:    make(map[string]float64),
+		database:              s.mockDB,
+	}
+	other.SetSymbolRegistry(registry)
+
+	openLong := func() *decision.Decision {
+		return &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 10, StopLoss: 48000.0, TakeProfit: 52000.0}
+	}
+
+	// 第一个交易员开仓成功并占用名额
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(openLong(), &logger.DecisionAction{}))
+	s.Equal(1, registry.Count("BTCUSDT"))
+
+	// 第二个交易员同币种开仓被拒绝，其他币种不受影响
+	err := other.executeOpenLongWithRecord(openLong(), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "全局持仓交易员数已达上限")
+	s.Equal(1, registry.Count("BTCUSDT"))
+
+	// 开仓失败时释放名额
+	s.mockTrader.balance["availableBalance"] = 0.0
+	ethOpen := openLong()
+	ethOpen.Symbol = "ETHUSDT"
+	s.Error(s.autoTrader.executeOpenLongWithRecord(ethOpen, &logger.DecisionAction{}))
+	s.Equal(0, registry.Count("ETHUSDT"))
+	s.mockTrader.balance["availableBalance"] = 8000.0
+
+	// 第一个交易员平仓后，第二个交易员可以开仓
+	s.NoError(s.autoTrader.executeCloseLongWithRecord(&decision.Decision{Action: "close_long", Symbol: "BTCUSDT"}, &logger.DecisionAction{}))
+	s.Equal(0, registry.Count("BTCUSDT"))
+	s.NoError(other.executeOpenLongWithRecord(openLong(), &logger.DecisionAction{}))
+	s.Equal(1, registry.Count("BTCUSDT"))
+
+	// 上限在每次开仓前从 limitSource 重新读取（修改系统配置后无需重新加载交易员）
+	limit := 1
+	registry.SetLimitSource(func() int { return limit })
+	s.Error(s.autoTrader.executeOpenLongWithRecord(openLong(), &logger.DecisionAction{}))
+
+	// 上限为0表示不限制
+	limit = 0
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(openLong(), &logger.DecisionAction{}))
+	s.Equal(2, registry.Count("BTCUSDT"))
+}
+
+// TestMaxTradesPerDay 测试每日开仓上限：达到上限后停止开仓，平仓不受影响，每日重置后恢复
+func (s *AutoTraderTestSuite) TestMaxTradesPerDay() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+
+	s.autoTrader.config.MaxTradesPerDay = 2
+	s.autoTrader.dailyTradeCount = 0
+	s.autoTrader.lastResetTime = time.Now()
+	defer func() {
+		s.autoTrader.config.MaxTradesPerDay = 0
+		s.autoTrader.dailyTradeCount = 0
+	}()
+
+	open := func(symbol string) *decision.Decision {
+		return &decision.Decision{Action: "open_long", Symbol: symbol, PositionSizeUSD: 1000.0, Leverage: 10, StopLoss: 48000.0, TakeProfit: 52000.0}
+	}
+
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(open("BTCUSDT"), &logger.DecisionAction{}))
+	s.NoError(s.autoTrader.executeOpenShortWithRecord(&decision.Decision{Action: "open_short", Symbol: "ETHUSDT", PositionSizeUSD: 1000.0, Leverage: 10, StopLoss: 52000.0, TakeProfit: 48000.0}, &logger.DecisionAction{}))
+	s.Equal(2, s.autoTrader.GetDailyTradeCount())
+
+	// 达到上限后拒绝开仓，并记录原因
+	err := s.autoTrader.executeOpenLongWithRecord(open("SOLUSDT"), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "今日开仓次数已达上限 (2/2)")
+	s.Equal(RejectDailyTradeLimit, RejectionCode(err))
+	s.Equal(2, s.autoTrader.GetDailyTradeCount())
+
+	// 平仓不受影响
+	s.NoError(s.autoTrader.executeCloseLongWithRecord(&decision.Decision{Action: "close_long", Symbol: "BTCUSDT"}, &logger.DecisionAction{}))
+
+	// 计数随状态持久化，重启后恢复
+	restored := &AutoTrader{name: "restored"}
+	restored.restoreStateJSON(s.autoTrader.buildStateJSON())
+	s.Equal(2, restored.dailyTradeCount)
+
+	// 跨天后重置计数，可以继续开仓
+	s.autoTrader.lastResetTime = time.Now().AddDate(0, 0, -1)
+	s.autoTrader.maybeResetDailyMetrics()
+	s.Equal(0, s.autoTrader.GetDailyTradeCount())
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(open("SOLUSDT"), &logger.DecisionAction{}))
+	s.Equal(1, s.autoTrader.GetDailyTradeCount())
+}
+
+// TestMaxExposureMultiple 测试多个持仓时总敞口超过账户净值的上限倍数后拒绝开仓
+func (s *AutoTraderTestSuite) TestMaxExposureMultiple() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+
+	// 净值 = 10000 + 100 = 10100，上限 3 倍 = 30300；现有敞口 = 0.2×50000 + 5×3000 = 25000
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.2, "markPrice": 50000.0},
+		{"symbol": "ETHUSDT", "side": "short", "positionAmt": -5.0, "markPrice": 3000.0},
+	}
+	s.autoTrader.config.MaxExposureMultiple = 3
+	defer func() {
+		s.autoTrader.config.MaxExposureMultiple = 0
+		s.mockTrader.positions = []map[string]interface{}{}
+	}()
+
+	open := func(sizeUSD float64, leverage int) *decision.Decision {
+		return &decision.Decision{Action: "open_long", Symbol: "SOLUSDT", PositionSizeUSD: sizeUSD, Leverage: leverage, StopLoss: 95.0, TakeProfit: 110.0}
+	}
+
+	// 提高单笔杠杆也无法绕过总敞口上限
+	err := s.autoTrader.executeOpenLongWithRecord(open(6000, 20), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "超过上限 3.00 倍")
+	s.Equal(RejectExposureLimit, RejectionCode(err))
+
+	// 开仓后仍在上限内则放行
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(open(5000, 10), &logger.DecisionAction{}))
+
+	// 关闭上限后不再检查
+	s.autoTrader.config.MaxExposureMultiple = 0
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(open(6000, 20), &logger.DecisionAction{}))
+}
+
+// TestPositionLimits 测试持仓数量和单笔仓位上限：无论AI输出什么，超出上限的开仓都会被拒绝
+func (s *AutoTraderTestSuite) TestPositionLimits() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.01, "markPrice": 50000.0},
+		{"symbol": "ETHUSDT", "side": "short", "positionAmt": -0.1, "markPrice": 3000.0},
+		{"symbol": "BNBUSDT", "side": "long", "positionAmt": 1.0, "markPrice": 600.0},
+	}
+	s.autoTrader.config.MaxPositions = 3
+	s.autoTrader.config.MaxPositionSizeUSD = 500
+	defer func() {
+		s.autoTrader.config.MaxPositions = 0
+		s.autoTrader.config.MaxPositionSizeUSD = 0
+		s.mockTrader.positions = []map[string]interface{}{}
+	}()
+
+	open := func(action string, sizeUSD float64) *decision.Decision {
+		return &decision.Decision{Action: action, Symbol: "SOLUSDT", PositionSizeUSD: sizeUSD, Leverage: 5, StopLoss: 95.0, TakeProfit: 110.0}
+	}
+
+	// 已有3个持仓，第4个开仓被拒绝（多空都一样）
+	orders := s.mockTrader.orderCalls
+	for _, action := range []string{"open_long", "open_short"} {
+		d := open(action, 200)
+		if action == "open_short" {
+			d.StopLoss, d.TakeProfit = 105.0, 90.0
+		}
+		err := s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{})
+		s.Error(err)
+		s.Contains(err.Error(), "持仓数量上限 3")
+		s.Equal(RejectPositionLimit, RejectionCode(err))
+	}
+	s.Equal(orders, s.mockTrader.orderCalls, "超出上限时不应下单")
+
+	// 平掉一个后可以开仓，但单笔仓位不能超过上限
+	s.mockTrader.positions = s.mockTrader.positions[:2]
+	err := s.autoTrader.executeDecisionWithRecord(open("open_long", 800), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "超过单笔上限 500.00 USDT")
+	s.NoError(s.autoTrader.executeDecisionWithRecord(open("open_long", 500), &logger.DecisionAction{}))
+}
+
+// TestTrailingStop 测试本地模拟的追踪止损：止损线随价格上移、不随回落后退，回撤越过止损线时平仓
+func (s *AutoTraderTestSuite) TestTrailingStop() {
+	price := 100.0
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: price}, nil
+	})
+	setPrice := func(p float64) {
+		price = p
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 1.0, "entryPrice": 100.0, "markPrice": p, "leverage": 5.0},
+		}
+	}
+	setPrice(100)
+	defer func() {
+		s.mockTrader.positions = []map[string]interface{}{}
+		s.autoTrader.clearTrailingStop("BTCUSDT", "long")
+		delete(s.autoTrader.positionStopLoss, "BTCUSDT_long")
+	}()
+
+	d := &decision.Decision{Action: "set_trailing_stop", Symbol: "BTCUSDT", TrailingDistancePct: 2}
+	s.NoError(s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{}))
+	s.InDelta(98.0, s.mockTrader.lastStopLoss, 1e-9, "初始止损线为当前价回撤2%")
+
+	// 重复设置相同的追踪止损直接跳过
+	record := &logger.DecisionAction{}
+	s.NoError(s.autoTrader.executeDecisionWithRecord(d, record))
+	s.True(record.Skipped)
+
+	// 价格上涨，止损线随之上移
+	setPrice(110)
+	s.False(s.autoTrader.ratchetTrailingStop("BTCUSDT", "long", 110))
+	s.InDelta(107.8, s.mockTrader.lastStopLoss, 1e-9)
+
+	// 决策周期占用周期锁时暂不移动止损单，之后的检查中价格未再创新高也会重试
+	s.autoTrader.cycleMutex.Lock()
+	setPrice(111)
+	s.False(s.autoTrader.ratchetTrailingStop("BTCUSDT", "long", 111))
+	s.InDelta(107.8, s.mockTrader.lastStopLoss, 1e-9, "周期锁被占用时不应移动止损单")
+	s.autoTrader.cycleMutex.Unlock()
+	setPrice(110.5)
+	s.False(s.autoTrader.ratchetTrailingStop("BTCUSDT", "long", 110.5))
+	s.InDelta(108.78, s.mockTrader.lastStopLoss, 1e-9, "止损单未移动到止损线时应重试")
+
+	// 价格回落但未越过止损线，止损不后退
+	calls := s.mockTrader.setStopLossCalls
+	setPrice(109)
+	s.False(s.autoTrader.ratchetTrailingStop("BTCUSDT", "long", 109))
+	s.Equal(calls, s.mockTrader.setStopLossCalls)
+
+	// 状态随 state_json 持久化
+	restored := &AutoTrader{name: "restored"}
+	restored.restoreStateJSON(s.autoTrader.buildStateJSON())
+	state, ok := restored.getTrailingStop("BTCUSDT_long")
+	s.True(ok)
+	s.Equal(111.0, state.ExtremePrice)
+	s.InDelta(108.78, state.PlacedStopPrice, 1e-9)
+
+	// 从最高点回撤超过2%：兜底平仓并只清除该方向的追踪状态
+	s.autoTrader.setTrailingStop("BTCUSDT_short", TrailingStop{DistancePct: 2, ExtremePrice: 90, StopPrice: 91.8})
+	defer s.autoTrader.clearTrailingStop("BTCUSDT", "short")
+	orders := s.mockTrader.orderCalls
+	setPrice(108.5)
+	s.True(s.autoTrader.ratchetTrailingStop("BTCUSDT", "long", 108.5))
+	s.Equal(orders+1, s.mockTrader.orderCalls)
+	_, ok = s.autoTrader.getTrailingStop("BTCUSDT_long")
+	s.False(ok)
+	_, ok = s.autoTrader.getTrailingStop("BTCUSDT_short")
+	s.True(ok, "不应清除另一方向的追踪止损")
+
+	// 双向持仓时无法确定方向，拒绝设置
+	s.mockTrader.positions = append(s.mockTrader.positions,
+		map[string]interface{}{"symbol": "BTCUSDT", "side": "short", "positionAmt": -1.0, "entryPrice": 100.0, "markPrice": 108.5, "leverage": 5.0})
+	err := s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "双向持仓")
+
+	// 回撤比例超出范围被拒绝
+	s.Error(s.autoTrader.executeDecisionWithRecord(&decision.Decision{Action: "set_trailing_stop", Symbol: "BTCUSDT", TrailingDistancePct: 30}, &logger.DecisionAction{}))
+}
+
+// TestRespectSignalBias 测试开仓方向与信号源方向偏好的一致性检查
+func (s *AutoTraderTestSuite) TestRespectSignalBias() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+
+	s.autoTrader.signalBias = candidateSignalBias([]decision.CandidateCoin{
+		{Symbol: "SOLUSDT", Sources: []string{"ai500"}, Bias: "long"},
+		{Symbol: "DOGEUSDT", Sources: []string{"oi_top"}, Bias: "short"},
+		{Symbol: "XRPUSDT", Sources: []string{"ai500"}},
+	})
+	s.autoTrader.config.RespectSignalBias = true
+	defer func() {
+		s.autoTrader.config.RespectSignalBias = false
+		s.autoTrader.signalBias = nil
+	}()
+
+	openLong := func(symbol string) *decision.Decision {
+		return &decision.Decision{Action: "open_long", Symbol: symbol, PositionSizeUSD: 1000, Leverage: 5, StopLoss: 95.0, TakeProfit: 110.0}
+	}
+	openShort := func(symbol string) *decision.Decision {
+		return &decision.Decision{Action: "open_short", Symbol: symbol, PositionSizeUSD: 1000, Leverage: 5, StopLoss: 105.0, TakeProfit: 90.0}
+	}
+
+	// 偏多币种拒绝开空，偏空币种拒绝开多
+	err := s.autoTrader.executeOpenShortWithRecord(openShort("SOLUSDT"), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "信号源方向偏多")
+	s.Equal(RejectSignalBias, RejectionCode(err))
+
+	err = s.autoTrader.executeOpenLongWithRecord(openLong("DOGEUSDT"), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "信号源方向偏空")
+	s.Equal(RejectSignalBias, RejectionCode(err))
+
+	// 与方向偏好一致、或没有偏好的币种放行
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(openLong("SOLUSDT"), &logger.DecisionAction{}))
+	s.NoError(s.autoTrader.executeOpenShortWithRecord(openShort("DOGEUSDT"), &logger.DecisionAction{}))
+	s.NoError(s.autoTrader.executeOpenShortWithRecord(openShort("XRPUSDT"), &logger.DecisionAction{}))
+
+	// 关闭后不再检查
+	s.autoTrader.config.RespectSignalBias = false
+	s.NoError(s.autoTrader.executeOpenShortWithRecord(openShort("SOLUSDT"), &logger.DecisionAction{}))
+}
+
+// TestOpenClampsLeverageToExchangeMax 测试开仓前把杠杆截断到交易所对该币种的上限
+func (s *AutoTraderTestSuite) TestOpenClampsLeverageToExchangeMax() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+	s.mockTrader.maxLeverage = map[string]int{"DOGEUSDT": 3}
+	defer func() { s.mockTrader.maxLeverage = nil }()
+
+	d := &decision.Decision{Action: "open_long", Symbol: "DOGEUSDT", PositionSizeUSD: 300, Leverage: 10, StopLoss: 95.0, TakeProfit: 110.0}
+	actionRecord := &logger.DecisionAction{Leverage: d.Leverage}
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(d, actionRecord))
+	s.Equal(3, d.Leverage)
+	s.Equal(3, actionRecord.Leverage)
+
+	// 未超过上限的币种保持原杠杆
+	d = &decision.Decision{Action: "open_short", Symbol: "SOLUSDT", PositionSizeUSD: 300, Leverage: 10, StopLoss: 105.0, TakeProfit: 90.0}
+	actionRecord = &logger.DecisionAction{Leverage: d.Leverage}
+	s.NoError(s.autoTrader.executeOpenShortWithRecord(d, actionRecord))
+	s.Equal(10, actionRecord.Leverage)
+}
+
+// TestDryRunPlacesNoOrders 测试模拟运行模式下所有执行动作都只记录、不向交易所下单
+func (s *AutoTraderTestSuite) TestDryRunPlacesNoOrders() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "SOLUSDT", "side": "long", "positionAmt": 10.0, "entryPrice": 90.0, "markPrice": 100.0},
+	}
+	s.autoTrader.config.DryRun = true
+	defer func() {
+		s.autoTrader.config.DryRun = false
+		s.mockTrader.positions = []map[string]interface{}{}
+	}()
+
+	orderCalls := s.mockTrader.orderCalls
+	stopLossCalls := s.mockTrader.setStopLossCalls
+
+	decisions := []*decision.Decision{
+		{Action: "open_long", Symbol: "ETHUSDT", PositionSizeUSD: 1000, Leverage: 5, StopLoss: 95.0, TakeProfit: 110.0},
+		{Action: "open_short", Symbol: "XRPUSDT", PositionSizeUSD: 1000, Leverage: 5, StopLoss: 105.0, TakeProfit: 90.0},
+		{Action: "update_stop_loss", Symbol: "SOLUSDT", NewStopLoss: 96.0},
+		{Action: "update_take_profit", Symbol: "SOLUSDT", NewTakeProfit: 120.0},
+		{Action: "partial_close", Symbol: "SOLUSDT", ClosePercentage: 50.0},
+		{Action: "close_long", Symbol: "SOLUSDT"},
+	}
+	for _, d := range decisions {
+		actionRecord := &logger.DecisionAction{Action: d.Action, Symbol: d.Symbol}
+		s.NoError(s.autoTrader.executeDecisionWithRecord(d, actionRecord), d.Action)
+		s.True(actionRecord.DryRun, d.Action)
+	}
+
+	// 没有任何下单、止损调用，也没有占用开仓名额或跟踪止损价
+	s.Equal(orderCalls, s.mockTrader.orderCalls)
+	s.Equal(stopLossCalls, s.mockTrader.setStopLossCalls)
+	s.NotContains(s.autoTrader.positionStopLoss, "SOLUSDT_long")
+	s.NotContains(s.autoTrader.positionFirstSeenTime, "ETHUSDT_long")
+
+	// 关闭模拟运行后正常下单
+	s.autoTrader.config.DryRun = false
+	actionRecord := &logger.DecisionAction{}
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(decisions[0], actionRecord))
+	s.False(actionRecord.DryRun)
+	s.Equal(orderCalls+1, s.mockTrader.orderCalls)
+}
+
+// TestRejectNonCandidateOpens 测试启用仅交易候选币种后，拒绝对候选列表之外的币种开仓
+func (s *AutoTraderTestSuite) TestRejectNonCandidateOpens() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+
+	s.autoTrader.candidateSymbols = candidateSymbolSet([]decision.CandidateCoin{
+		{Symbol: "SOLUSDT", Sources: []string{"ai500"}},
+		{Symbol: "XRPUSDT", Sources: []string{"oi_top"}},
+	})
+	defer func() {
+		s.autoTrader.config.RejectNonCandidates = false
+		s.autoTrader.candidateSymbols = nil
+	}()
+
+	openLong := func(symbol string) *decision.Decision {
+		return &decision.Decision{Action: "open_long", Symbol: symbol, PositionSizeUSD: 1000, Leverage: 5, StopLoss: 95.0, TakeProfit: 110.0}
+	}
+
+	// 默认允许（兼容旧行为）
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(openLong("DOGEUSDT"), &logger.DecisionAction{}))
+
+	// 启用后候选列表之外的币种拒绝开仓，不下单
+	s.autoTrader.config.RejectNonCandidates = true
+	orderCalls := s.mockTrader.orderCalls
+	err := s.autoTrader.executeOpenLongWithRecord(openLong("PEPEUSDT"), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "不在本周期候选币种中")
+	s.Equal(RejectNonCandidate, RejectionCode(err))
+	short := &decision.Decision{Action: "open_short", Symbol: "PEPEUSDT", PositionSizeUSD: 1000, Leverage: 5, StopLoss: 105.0, TakeProfit: 90.0}
+	s.Equal(RejectNonCandidate, RejectionCode(s.autoTrader.executeOpenShortWithRecord(short, &logger.DecisionAction{})))
+	s.Equal(orderCalls, s.mockTrader.orderCalls, "候选范围外的开仓不应下单")
+
+	// 候选币种正常开仓
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(openLong("SOLUSDT"), &logger.DecisionAction{}))
+}
+
+// fillingMockTrader 开仓后持仓真实出现的交易所（用于开仓确认测试）
+type fillingMockTrader struct {
+	*MockTrader
+}
+
+func (m *fillingMockTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	order, err := m.MockTrader.OpenLong(symbol, quantity, leverage)
+	if err == nil {
+		m.positions = append(m.positions, map[string]interface{}{"symbol": symbol, "side": "long", "positionAmt": quantity})
+	}
+	return order, err
+}
+
+// TestOpenVerificationRejectsPhantomOpen 测试开仓确认：交易所返回成功但持仓未出现（异步拒单）时不记为成功
+func (s *AutoTraderTestSuite) TestOpenVerificationRejectsPhantomOpen() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+	s.autoTrader.config.OpenVerifyDelay = time.Millisecond
+	originalTrader := s.autoTrader.trader
+	defer func() {
+		s.autoTrader.config.OpenVerifyDelay = 0
+		s.autoTrader.trader = originalTrader
+		s.mockTrader.positions = nil
+	}()
+
+	openLong := func(symbol string) *decision.Decision {
+		return &decision.Decision{Action: "open_long", Symbol: symbol, PositionSizeUSD: 1000, Leverage: 5, StopLoss: 95.0, TakeProfit: 110.0}
+	}
+
+	// 下单返回成功但持仓始终没有出现：判定开仓未生效，不计入当日开仓次数、不设置止损止盈
+	s.mockTrader.positions = nil
+	tradesBefore := s.autoTrader.GetDailyTradeCount()
+	stopCalls := s.mockTrader.setStopLossCalls
+	err := s.autoTrader.executeOpenLongWithRecord(openLong("ETHUSDT"), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "开仓未生效")
+	s.Equal(tradesBefore, s.autoTrader.GetDailyTradeCount())
+	s.Equal(stopCalls, s.mockTrader.setStopLossCalls)
+
+	// 持仓确实出现时正常记为成功
+	s.autoTrader.trader = &fillingMockTrader{MockTrader: s.mockTrader}
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(openLong("SOLUSDT"), &logger.DecisionAction{}))
+	s.Equal(tradesBefore+1, s.autoTrader.GetDailyTradeCount())
+}
+
+// restingEntryMockTrader 限价开仓单挂单不成交的测试 trader（挂单列表可由测试修改）
+type restingEntryMockTrader struct {
+	*orderTypeMockTrader
+	orders []decision.OpenOrderInfo
+}
+
+func (m *restingEntryMockTrader) OpenLongWithOrder(symbol string, quantity float64, leverage int, orderType string, limitPrice float64) (map[string]interface{}, error) {
+	order, err := m.orderTypeMockTrader.OpenLongWithOrder(symbol, quantity, leverage, orderType, limitPrice)
+	if err == nil {
+		m.orders = append(m.orders, decision.OpenOrderInfo{Symbol: symbol, OrderID: order["orderId"].(int64), Type: "LIMIT", Side: "BUY", PositionSide: "LONG", Price: limitPrice})
+	}
+	return order, err
+}
+
+func (m *restingEntryMockTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	return m.orders, nil
+}
+
+// TestRestingLimitEntryRecordedOnFill 测试限价开仓单挂单未成交时只登记挂单，成交后才记录开仓、设置止损止盈并计入开仓次数
+func (s *AutoTraderTestSuite) TestRestingLimitEntryRecordedOnFill() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+	mock := &restingEntryMockTrader{orderTypeMockTrader: &orderTypeMockTrader{MockTrader: s.mockTrader}}
+	originalTrader := s.autoTrader.trader
+	s.autoTrader.trader = mock
+	defer func() {
+		s.autoTrader.trader = originalTrader
+		s.autoTrader.pendingEntries = nil
+		s.mockTrader.positions = nil
+	}()
+
+	openLimit := func(symbol string) *decision.Decision {
+		return &decision.Decision{Action: "open_long", Symbol: symbol, PositionSizeUSD: 1000, Leverage: 5, StopLoss: 95.0, TakeProfit: 110.0, OrderType: OrderTypeLimit, LimitPrice: 99.0}
+	}
+
+	// 挂单未成交：不计入开仓次数、不设置止损止盈，登记为挂单中；同向重复开仓被拒绝
+	tradesBefore := s.autoTrader.GetDailyTradeCount()
+	stopCalls := s.mockTrader.setStopLossCalls
+	record := &logger.DecisionAction{}
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(openLimit("ETHUSDT"), record))
+	s.Equal(int64(123456), record.OrderID)
+	s.Equal(tradesBefore, s.autoTrader.GetDailyTradeCount())
+	s.Equal(stopCalls, s.mockTrader.setStopLossCalls)
+	s.True(s.autoTrader.hasPendingEntry("ETHUSDT", "long"))
+	s.Equal(RejectPositionExists, RejectionCode(s.autoTrader.executeOpenLongWithRecord(openLimit("ETHUSDT"), &logger.DecisionAction{})))
+
+	// 仍在挂单中：保持登记
+	s.Empty(s.autoTrader.resolvePendingEntries())
+	s.True(s.autoTrader.hasPendingEntry("ETHUSDT", "long"))
+
+	// 成交后补记开仓并设置止损止盈
+	mock.orders = nil
+	s.mockTrader.positions = []map[string]interface{}{{"symbol": "ETHUSDT", "side": "long", "positionAmt": 10.0, "entryPrice": 99.0}}
+	s.Len(s.autoTrader.resolvePendingEntries(), 1)
+	s.False(s.autoTrader.hasPendingEntry("ETHUSDT", "long"))
+	s.Equal(tradesBefore+1, s.autoTrader.GetDailyTradeCount())
+	s.Equal(stopCalls+1, s.mockTrader.setStopLossCalls)
+	s.Equal(95.0, s.autoTrader.positionStopLoss["ETHUSDT_long"])
+
+	// 挂单消失（被撤销或过期）且没有持仓：移除登记，不记录开仓
+	s.mockTrader.positions = nil
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(openLimit("SOLUSDT"), &logger.DecisionAction{}))
+	mock.orders = nil
+	s.Len(s.autoTrader.resolvePendingEntries(), 1)
+	s.False(s.autoTrader.hasPendingEntry("SOLUSDT", "long"))
+	s.Equal(tradesBefore+1, s.autoTrader.GetDailyTradeCount())
+}
+
+// TestUnfundedAccountSuppressesOpens 测试未入金检测：暂停开仓，资金到账后自动恢复
+func (s *AutoTraderTestSuite) TestUnfundedAccountSuppressesOpens() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+	defer s.autoTrader.checkFunding(8000, 0)
+
+	// 默认阈值 1 USDT：余额为0且无持仓时视为未入金
+	s.True(s.autoTrader.checkFunding(0.3, 0))
+	s.True(s.autoTrader.IsUnfunded())
+
+	orderCalls := s.mockTrader.orderCalls
+	d := &decision.Decision{Action: "open_long", Symbol: "ETHUSDT", PositionSizeUSD: 1000, Leverage: 5, StopLoss: 95.0, TakeProfit: 110.0}
+	err := s.autoTrader.executeOpenLongWithRecord(d, &logger.DecisionAction{})
+	s.Error(err)
+	s.Equal(RejectUnfunded, RejectionCode(err))
+	d.Action = "open_short"
+	d.StopLoss, d.TakeProfit = 105.0, 90.0
+	s.Equal(RejectUnfunded, RejectionCode(s.autoTrader.executeOpenShortWithRecord(d, &logger.DecisionAction{})))
+	s.Equal(orderCalls, s.mockTrader.orderCalls, "未入金时不应下单")
+
+	// 有持仓时可用余额低只是保证金占满，不算未入金
+	s.False(s.autoTrader.checkFunding(0, 1))
+
+	// 自定义阈值
+	s.autoTrader.config.UnfundedThreshold = 50
+	defer func() { s.autoTrader.config.UnfundedThreshold = 0 }()
+	s.True(s.autoTrader.checkFunding(20, 0))
+
+	// 资金到账后自动恢复开仓
+	s.False(s.autoTrader.checkFunding(500, 0))
+	s.False(s.autoTrader.IsUnfunded())
+	d.Action = "open_long"
+	d.StopLoss, d.TakeProfit = 95.0, 110.0
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(d, &logger.DecisionAction{}))
+	s.Equal(orderCalls+1, s.mockTrader.orderCalls)
+}
+
 // TestExecuteClosePosition 测试平仓操作（多空通用）
 func (s *AutoTraderTestSuite) TestExecuteClosePosition() {
 	tests := []struct {
@@ -737,6 +1406,67 @@ func (s *AutoTraderTestSuite) TestExecuteUpdateStopOrTakeProfit() {
 	}
 }
 
+// TestLargeOpenRequiresPriceConfirmation 测试大额开仓在只有一个数据源时被拒绝，小额开仓不受影响
+func (s *AutoTraderTestSuite) TestLargeOpenRequiresPriceConfirmation() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	restore := useFakePriceVerifier(&fakePriceVerifier{prices: map[string]float64{"binance": 50000}})
+	defer restore()
+
+	s.autoTrader.config.StrictPriceCheckNotional = 3000
+	defer func() { s.autoTrader.config.StrictPriceCheckNotional = 0 }()
+
+	large := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 5000.0, Leverage: 10, StopLoss: 48000.0, TakeProfit: 52000.0}
+	err := s.autoTrader.executeOpenLongWithRecord(large, &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "严格校验阈值")
+
+	small := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 10, StopLoss: 48000.0, TakeProfit: 52000.0}
+	s.NoError(s.autoTrader.executeOpenLongWithRecord(small, &logger.DecisionAction{}))
+}
+
+// TestSkipRedundantStopLossUpdate 测试相同止损价的重复调整被跳过，价格变化时正常调整
+func (s *AutoTraderTestSuite) TestSkipRedundantStopLossUpdate() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 52000.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1},
+	}
+	defer func() {
+		s.mockTrader.positions = []map[string]interface{}{}
+		delete(s.autoTrader.positionStopLoss, "BTCUSDT_long")
+	}()
+
+	update := func(price float64) *logger.DecisionAction {
+		actionRecord := &logger.DecisionAction{Action: "update_stop_loss", Symbol: "BTCUSDT"}
+		err := s.autoTrader.executeUpdateStopLossWithRecord(&decision.Decision{Action: "update_stop_loss", Symbol: "BTCUSDT", NewStopLoss: price}, actionRecord)
+		s.NoError(err)
+		return actionRecord
+	}
+
+	calls := s.mockTrader.setStopLossCalls
+	s.False(update(51000.0).Skipped)
+	s.Equal(calls+1, s.mockTrader.setStopLossCalls)
+
+	// 相同价格（以及容差内的价格）不再调用交易所
+	s.True(update(51000.0).Skipped)
+	s.True(update(51002.0).Skipped)
+	s.Equal(calls+1, s.mockTrader.setStopLossCalls)
+
+	// 价格真正变化时正常调整
+	s.False(update(51200.0).Skipped)
+	s.Equal(calls+2, s.mockTrader.setStopLossCalls)
+	s.Equal(51200.0, s.autoTrader.positionStopLoss["BTCUSDT_long"])
+
+	// 关闭去重后每次都调整
+	s.autoTrader.config.StopUpdateTolerancePct = -1
+	defer func() { s.autoTrader.config.StopUpdateTolerancePct = 0 }()
+	s.False(update(51200.0).Skipped)
+	s.Equal(calls+3, s.mockTrader.setStopLossCalls)
+}
+
 func (s *AutoTraderTestSuite) TestExecutePartialCloseWithRecord() {
 	s.Run("成功部分平仓", func() {
 		// 设置持仓
@@ -1004,6 +1734,14 @@ type MockTrader struct {
 	shouldFailOpenLong   bool
 	shouldFailCloseLong  bool
 	shouldFailCloseShort bool
+	haltedSymbols        map[string]string // 暂停交易的币种 (symbol -> 原因)
+	maintenance          bool              // 模拟交易所维护
+	setStopLossCalls     int               // SetStopLoss 调用次数
+	lastStopLoss         float64           // 最近一次 SetStopLoss 的止损价
+	userTrades           []UserTrade       // GetUserTrades 返回的成交记录
+	userTradesCalls      int               // GetUserTrades 调用次数
+	orderCalls           int               // 开仓/平仓下单调用次数
+	maxLeverage          map[string]int    // GetMaxLeverage 返回的杠杆上限（未设置返回0=不限制）
 }
 
 func (m *MockTrader) GetBalance() (map[string]interface{}, error) {
@@ -1031,6 +1769,7 @@ func (m *MockTrader) GetPositions() ([]map[string]interface{}, error) {
 }
 
 func (m *MockTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	m.orderCalls++
 	if m.shouldFailOpenLong {
 		return nil, errors.New("failed to open long")
 	}
@@ -1041,6 +1780,7 @@ func (m *MockTrader) OpenLong(symbol string, quantity float64, leverage int) (ma
 }
 
 func (m *MockTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	m.orderCalls++
 	return map[string]interface{}{
 		"orderId": int64(123457),
 		"symbol":  symbol,
@@ -1048,6 +1788,7 @@ func (m *MockTrader) OpenShort(symbol string, quantity float64, leverage int) (m
 }
 
 func (m *MockTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	m.orderCalls++
 	if m.shouldFailCloseLong {
 		return nil, errors.New("failed to close long")
 	}
@@ -1058,6 +1799,7 @@ func (m *MockTrader) CloseLong(symbol string, quantity float64) (map[string]inte
 }
 
 func (m *MockTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	m.orderCalls++
 	if m.shouldFailCloseShort {
 		return nil, errors.New("failed to close short")
 	}
@@ -1080,6 +1822,8 @@ func (m *MockTrader) GetMarketPrice(symbol string) (float64, error) {
 }
 
 func (m *MockTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	m.setStopLossCalls++
+	m.lastStopLoss = stopPrice
 	return nil
 }
 
@@ -1111,6 +1855,25 @@ func (m *MockTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, err
 	return []decision.OpenOrderInfo{}, nil
 }
 
+func (m *MockTrader) GetSymbolTradingStatus(symbol string) (*SymbolTradingStatus, error) {
+	if m.maintenance {
+		return &SymbolTradingStatus{Symbol: symbol, Status: "BREAK", Reason: "交易所维护中", Maintenance: true}, nil
+	}
+	if reason, ok := m.haltedSymbols[symbol]; ok {
+		return &SymbolTradingStatus{Symbol: symbol, Status: "BREAK", Reason: reason}, nil
+	}
+	return &SymbolTradingStatus{Symbol: symbol, Status: "TRADING", Tradeable: true}, nil
+}
+
+func (m *MockTrader) GetMaxLeverage(symbol string) (int, error) {
+	return m.maxLeverage[symbol], nil
+}
+
+func (m *MockTrader) GetUserTrades(symbol string, startTime, endTime time.Time) ([]UserTrade, error) {
+	m.userTradesCalls++
+	return m.userTrades, nil
+}
+
 // ============================================================
 // 测试套件入口
 // ============================================================
@@ -1422,7 +2185,7 @@ func (s *AutoTraderTestSuite) TestEnforceRiskLimits_DailyLossTrigger() {
 	reason, triggered := at.enforceRiskLimits(930)
 	s.True(triggered, "应该触发日亏损限制")
 	s.Contains(reason, "最大亏损")
-	s.True(time.Until(at.stopUntil) > 0, "stopUntil 应该更新为未来时间")
+	s.True(time.Until(at.getStopUntil()) > 0, "stopUntil 应该更新为未来时间")
 }
 
 func (s *AutoTraderTestSuite) TestEnforceRiskLimits_DrawdownTrigger() {
@@ -1437,7 +2200,27 @@ func (s *AutoTraderTestSuite) TestEnforceRiskLimits_DrawdownTrigger() {
 	reason, triggered := at.enforceRiskLimits(1000)
 	s.True(triggered, "应该触发回撤限制")
 	s.Contains(reason, "账户回撤")
-	s.True(time.Until(at.stopUntil) > 0, "stopUntil 应该被设定")
+	s.True(time.Until(at.getStopUntil()) > 0, "stopUntil 应该被设定")
+}
+
+func (s *AutoTraderTestSuite) TestEnforceRiskLimits_ReportsLimitSource() {
+	at := s.autoTrader
+	at.config.MaxDailyLoss = 0
+	at.config.MaxDrawdown = 10
+	at.config.MaxDrawdownSource = RiskLimitSourceTrader
+	at.dailyPnLBase = 1200
+	at.peakEquity = 1200
+	at.needsDailyBaseline = false
+
+	reason, triggered := at.enforceRiskLimits(1000)
+	s.True(triggered, "应该触发交易员级回撤限制")
+	s.Contains(reason, "交易员级")
+
+	at.config.MaxDrawdownSource = RiskLimitSourceSystem
+	at.setStopUntil(time.Time{})
+	reason, triggered = at.enforceRiskLimits(1000)
+	s.True(triggered)
+	s.Contains(reason, "系统级")
 }
 
 func (s *AutoTraderTestSuite) TestEnforceRiskLimits_BaselineSync() {
@@ -1452,3 +2235,91 @@ func (s *AutoTraderTestSuite) TestEnforceRiskLimits_BaselineSync() {
 	s.Equal(0.0, at.dailyPnL, "同步基准后日盈亏应为0")
 	s.Equal("", reason)
 }
+
+func (s *AutoTraderTestSuite) TestAIQualityAutoPause() {
+	at := s.autoTrader
+	at.setStopUntil(time.Time{})
+	at.aiQualityResults = nil
+
+	// 未启用时不统计
+	s.False(at.recordAIQuality(true))
+	_, samples := at.GetAIQuality()
+	s.Equal(0, samples)
+
+	at.config.AIQualityWindow = 4
+	at.config.AIQualityMaxFailurePct = 50
+	at.config.AIQualityPause = 10 * time.Minute
+	defer func() {
+		at.config.AIQualityWindow = 0
+		at.config.AIQualityMaxFailurePct = 0
+		at.config.AIQualityPause = 0
+		at.setStopUntil(time.Time{})
+	}()
+
+	// 窗口未填满前不触发
+	s.False(at.recordAIQuality(true))
+	s.False(at.recordAIQuality(true))
+	s.False(at.recordAIQuality(false))
+	rate, samples := at.GetAIQuality()
+	s.InDelta(66.67, rate, 0.01)
+	s.Equal(3, samples)
+
+	// 窗口填满且失败率达到阈值：自动暂停并清空窗口
+	s.True(at.recordAIQuality(false))
+	s.True(time.Until(at.getStopUntil()) > 9*time.Minute, "应暂停到冷却结束")
+	_, samples = at.GetAIQuality()
+	s.Equal(0, samples)
+	s.Equal(0, at.GetStatus()["ai_quality_samples"])
+
+	// 滑动窗口只保留最近 N 次，旧的失败被挤出后不触发
+	s.True(at.ResumeTrading(), "手动解除前应处于暂停状态")
+	s.True(at.getStopUntil().IsZero())
+	for _, failed := range []bool{true, false, false, false, true} {
+		s.False(at.recordAIQuality(failed))
+	}
+	rate, samples = at.GetAIQuality()
+	s.Equal(25.0, rate)
+	s.Equal(4, samples)
+	s.False(at.ResumeTrading(), "未暂停时解除应返回 false")
+}
+
+func (s *AutoTraderTestSuite) TestEquityAlerts_ThresholdAndHysteresis() {
+	at := s.autoTrader
+	at.config.MaxDailyLoss = 0
+	at.config.MaxDrawdown = 0
+	at.config.AlertDrawdownPct = 5
+	at.config.AlertDailyLossPct = 3
+	at.dailyPnLBase = 1000
+	at.peakEquity = 1000
+	at.needsDailyBaseline = false
+	at.equityAlertActive = nil
+
+	check := func(equity float64) []string {
+		at.updatePnLMetrics(equity)
+		return at.checkEquityAlerts(equity)
+	}
+
+	// 未越过阈值不预警
+	s.Empty(check(990))
+
+	// 当日亏损 4% 越过 3% 阈值，回撤 4% 未越过 5%
+	s.Equal([]string{equityAlertDailyLoss}, check(960))
+
+	// 继续下跌：回撤预警首次触发，日亏损预警不重复推送
+	s.Equal([]string{equityAlertDrawdown}, check(940))
+	s.Empty(check(930), "已触发的预警不应重复推送")
+
+	// 回升到阈值附近但未低于重新布防线（阈值的 80%），日亏损预警仍不推送
+	s.Empty(check(975))
+	s.Empty(check(965), "未回落到重新布防线以下不应再次推送")
+
+	// 回落到重新布防线以下后，再次越过阈值会重新推送
+	s.Empty(check(990))
+	s.Empty(at.equityAlertActive)
+	s.Equal([]string{equityAlertDailyLoss}, check(960))
+
+	// 预警只通知，不触发风控暂停
+	reason, triggered := at.enforceRiskLimits(950)
+	s.False(triggered, "预警不应暂停交易")
+	s.Equal("", reason)
+}
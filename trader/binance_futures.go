@@ -6,8 +6,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"math"
+	"net/http"
 	"nofx/decision"
 	"nofx/hook"
+	"nofx/netproxy"
 	"strconv"
 	"strings"
 	"sync"
@@ -65,11 +68,26 @@ type FuturesTrader struct {
 	orderStrategy       string  // Order strategy: "market_only", "conservative_hybrid", "limit_only"
 	limitPriceOffset    float64 // Limit order price offset percentage (e.g., -0.03 for -0.03%)
 	limitTimeoutSeconds int     // Timeout in seconds before converting to market order
+
+	// 交易对最大杠杆缓存
+	maxLeverage maxLeverageCache
+	// 交易对状态缓存（开仓前检查）
+	symbolStatus symbolStatusCache
 }
 
 // NewFuturesTrader 创建合约交易器
-func NewFuturesTrader(apiKey, secretKey string, userId string, orderStrategy string, limitPriceOffset float64, limitTimeoutSeconds int) *FuturesTrader {
+// testnet 为 true 时连接币安合约测试网；transport 为出站代理传输（nil 使用默认传输）
+func NewFuturesTrader(apiKey, secretKey string, testnet bool, userId string, orderStrategy string, limitPriceOffset float64, limitTimeoutSeconds int, transport *http.Transport) *FuturesTrader {
 	client := futures.NewClient(apiKey, secretKey)
+	if testnet {
+		client.BaseURL = futures.BaseApiTestnetUrl
+		log.Printf("🧪 币安合约使用测试网: %s", client.BaseURL)
+	}
+	var base http.RoundTripper
+	if transport != nil {
+		base = transport
+	}
+	client.HTTPClient = &http.Client{Transport: netproxy.LimitTransport(base, &exchangeCallLimiter)}
 
 	hookRes := hook.HookExec[hook.NewBinanceTraderResult](hook.NEW_BINANCE_TRADER, userId, client)
 	if hookRes != nil && hookRes.GetResult() != nil {
@@ -366,12 +384,12 @@ func (t *FuturesTrader) GetCurrentPrice(symbol string) (float64, error) {
 	return price, nil
 }
 
-// FormatPrice 格式化价格到交易所要求的精度
-func (t *FuturesTrader) FormatPrice(symbol string, price float64) (string, error) {
+// GetPriceTick 获取交易对的价格步进值（PRICE_FILTER.tickSize）
+func (t *FuturesTrader) GetPriceTick(symbol string) (float64, error) {
 	// 获取交易对信息
 	info, err := t.client.NewExchangeInfoService().Do(context.Background())
 	if err != nil {
-		return "", fmt.Errorf("获取交易对信息失败: %w", err)
+		return 0, fmt.Errorf("获取交易对信息失败: %w", err)
 	}
 
 	// 查找对应的symbol信息
@@ -380,29 +398,38 @@ func (t *FuturesTrader) FormatPrice(symbol string, price float64) (string, error
 			// 找到价格精度过滤器
 			for _, filter := range s.Filters {
 				if filter["filterType"] == "PRICE_FILTER" {
-					tickSizeStr := filter["tickSize"].(string)
+					tickSizeStr, _ := filter["tickSize"].(string)
 					tickSize, err := strconv.ParseFloat(tickSizeStr, 64)
 					if err != nil {
-						return "", fmt.Errorf("解析tickSize失败: %w", err)
-					}
-
-					// 计算精度
-					precision := 0
-					temp := tickSize
-					for temp < 1 {
-						temp *= 10
-						precision++
+						return 0, fmt.Errorf("解析tickSize失败: %w", err)
 					}
-
-					// 格式化价格
-					format := fmt.Sprintf("%%.%df", precision)
-					return fmt.Sprintf(format, price), nil
+					return tickSize, nil
 				}
 			}
 		}
 	}
 
-	return "", fmt.Errorf("未找到 %s 的价格精度信息", symbol)
+	return 0, fmt.Errorf("未找到 %s 的价格精度信息", symbol)
+}
+
+// FormatPrice 格式化价格到交易所要求的精度
+func (t *FuturesTrader) FormatPrice(symbol string, price float64) (string, error) {
+	tickSize, err := t.GetPriceTick(symbol)
+	if err != nil {
+		return "", err
+	}
+
+	// 计算精度
+	precision := 0
+	temp := tickSize
+	for temp < 1 {
+		temp *= 10
+		precision++
+	}
+
+	// 格式化价格
+	format := fmt.Sprintf("%%.%df", precision)
+	return fmt.Sprintf(format, price), nil
 }
 
 // QueryOrderStatus 查询订单状态
@@ -513,8 +540,34 @@ func (t *FuturesTrader) monitorAndConvertLimitOrder(
 	}
 }
 
+// strategyForOrderType 把决策指定的订单类型映射为下单策略（未指定时使用交易员配置）
+// limit 沿用 conservative_hybrid 的超时转市价行为，其余配置按 limit_only 挂单
+func (t *FuturesTrader) strategyForOrderType(orderType string) string {
+	switch orderType {
+	case OrderTypeMarket:
+		return "market_only"
+	case OrderTypeLimit:
+		if t.orderStrategy == "conservative_hybrid" {
+			return t.orderStrategy
+		}
+		return "limit_only"
+	default:
+		return t.orderStrategy
+	}
+}
+
 // OpenLong 开多仓
 func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openLong(symbol, quantity, leverage, t.orderStrategy, 0)
+}
+
+// OpenLongWithOrder 按决策指定的订单类型开多仓（limitPrice 仅限价单使用）
+func (t *FuturesTrader) OpenLongWithOrder(symbol string, quantity float64, leverage int, orderType string, limitPrice float64) (map[string]interface{}, error) {
+	return t.openLong(symbol, quantity, leverage, t.strategyForOrderType(orderType), limitPrice)
+}
+
+// openLong 按指定订单策略开多仓，limitPrice > 0 时使用该限价，否则按 limitPriceOffset 计算
+func (t *FuturesTrader) openLong(symbol string, quantity float64, leverage int, strategy string, limitPrice float64) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -546,7 +599,7 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 
 	// 根据订单策略创建订单
 	var order *futures.CreateOrderResponse
-	if t.orderStrategy == "market_only" {
+	if strategy == "market_only" {
 		// 纯市价单策略
 		log.Printf("📋 [%s] 使用市价单策略", symbol)
 		order, err = t.client.NewCreateOrderService().
@@ -565,8 +618,10 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 		}
 
 		// 计算限价：多仓使用 currentPrice * (1 + offset)
-		// offset 为负数（如 -0.03），所以实际价格会低于市价
-		limitPrice := currentPrice * (1 + t.limitPriceOffset/100)
+		// offset 为负数（如 -0.03），所以实际价格会低于市价；AI 指定了限价时直接使用
+		if limitPrice <= 0 {
+			limitPrice = currentPrice * (1 + t.limitPriceOffset/100)
+		}
 		limitPriceStr, formatErr := t.FormatPrice(symbol, limitPrice)
 		if formatErr != nil {
 			return nil, fmt.Errorf("格式化限价失败: %w", formatErr)
@@ -589,7 +644,7 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 		if err != nil {
 			log.Printf("⚠️ 限价单创建失败: %v", err)
 			// 如果是 conservative_hybrid 策略，失败后可以降级到市价单
-			if t.orderStrategy == "conservative_hybrid" {
+			if strategy == "conservative_hybrid" {
 				log.Printf("📋 [%s] 限价单失败，降级为市价单", symbol)
 				order, err = t.client.NewCreateOrderService().
 					Symbol(symbol).
@@ -605,7 +660,7 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 			log.Printf("✓ 限价单创建成功: %s OrderID=%d", symbol, order.OrderID)
 
 			// 如果是 conservative_hybrid 策略，启动监控并在超时时转换为市价单
-			if t.orderStrategy == "conservative_hybrid" {
+			if strategy == "conservative_hybrid" {
 				result, converted, monitorErr := t.monitorAndConvertLimitOrder(
 					symbol,
 					order.OrderID,
@@ -651,6 +706,16 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 
 // OpenShort 开空仓
 func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openShort(symbol, quantity, leverage, t.orderStrategy, 0)
+}
+
+// OpenShortWithOrder 按决策指定的订单类型开空仓（limitPrice 仅限价单使用）
+func (t *FuturesTrader) OpenShortWithOrder(symbol string, quantity float64, leverage int, orderType string, limitPrice float64) (map[string]interface{}, error) {
+	return t.openShort(symbol, quantity, leverage, t.strategyForOrderType(orderType), limitPrice)
+}
+
+// openShort 按指定订单策略开空仓，limitPrice > 0 时使用该限价，否则按 limitPriceOffset 计算
+func (t *FuturesTrader) openShort(symbol string, quantity float64, leverage int, strategy string, limitPrice float64) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -682,7 +747,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 
 	// 根据订单策略创建订单
 	var order *futures.CreateOrderResponse
-	if t.orderStrategy == "market_only" {
+	if strategy == "market_only" {
 		// 纯市价单策略
 		log.Printf("📋 [%s] 使用市价单策略", symbol)
 		order, err = t.client.NewCreateOrderService().
@@ -701,8 +766,10 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 		}
 
 		// 计算限价：空仓使用 currentPrice * (1 - offset)
-		// offset 为负数（如 -0.03），所以 (1 - (-0.03)) = 1.03，实际价格会高于市价
-		limitPrice := currentPrice * (1 - t.limitPriceOffset/100)
+		// offset 为负数（如 -0.03），所以 (1 - (-0.03)) = 1.03，实际价格会高于市价；AI 指定了限价时直接使用
+		if limitPrice <= 0 {
+			limitPrice = currentPrice * (1 - t.limitPriceOffset/100)
+		}
 		limitPriceStr, formatErr := t.FormatPrice(symbol, limitPrice)
 		if formatErr != nil {
 			return nil, fmt.Errorf("格式化限价失败: %w", formatErr)
@@ -725,7 +792,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 		if err != nil {
 			log.Printf("⚠️ 限价单创建失败: %v", err)
 			// 如果是 conservative_hybrid 策略，失败后可以降级到市价单
-			if t.orderStrategy == "conservative_hybrid" {
+			if strategy == "conservative_hybrid" {
 				log.Printf("📋 [%s] 限价单失败，降级为市价单", symbol)
 				order, err = t.client.NewCreateOrderService().
 					Symbol(symbol).
@@ -741,7 +808,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 			log.Printf("✓ 限价单创建成功: %s OrderID=%d", symbol, order.OrderID)
 
 			// 如果是 conservative_hybrid 策略，启动监控并在超时时转换为市价单
-			if t.orderStrategy == "conservative_hybrid" {
+			if strategy == "conservative_hybrid" {
 				result, converted, monitorErr := t.monitorAndConvertLimitOrder(
 					symbol,
 					order.OrderID,
@@ -928,8 +995,8 @@ func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
 	for _, order := range orders {
 		orderType := order.Type
 
-		// 只取消止损订单（不取消止盈订单）
-		if orderType == futures.OrderTypeStopMarket || orderType == futures.OrderTypeStop {
+		// 只取消止损订单（不取消止盈订单），追踪止损单也属于止损
+		if orderType == futures.OrderTypeStopMarket || orderType == futures.OrderTypeStop || orderType == futures.OrderTypeTrailingStopMarket {
 			_, err := t.client.NewCancelOrderService().
 				Symbol(symbol).
 				OrderID(order.OrderID).
@@ -1045,7 +1112,8 @@ func (t *FuturesTrader) CancelStopOrders(symbol string) error {
 		if orderType == futures.OrderTypeStopMarket ||
 			orderType == futures.OrderTypeTakeProfitMarket ||
 			orderType == futures.OrderTypeStop ||
-			orderType == futures.OrderTypeTakeProfit {
+			orderType == futures.OrderTypeTakeProfit ||
+			orderType == futures.OrderTypeTrailingStopMarket {
 
 			_, err := t.client.NewCancelOrderService().
 				Symbol(symbol).
@@ -1140,6 +1208,54 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 	return nil
 }
 
+// 币安追踪止损单回调比例范围（%），超出范围时由 AutoTrader 本地模拟
+const (
+	binanceMinCallbackRate = 0.1
+	binanceMaxCallbackRate = 5.0
+)
+
+// SetTrailingStop 设置原生追踪止损单（TRAILING_STOP_MARKET），从设置时的价格开始追踪
+// callbackRatePct 为回调比例（%），币安只支持一位小数
+func (t *FuturesTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRatePct float64) error {
+	callbackRate := math.Round(callbackRatePct*10) / 10
+	if callbackRate < binanceMinCallbackRate || callbackRate > binanceMaxCallbackRate {
+		return fmt.Errorf("币安追踪止损回调比例必须在 %.1f%%-%.1f%% 之间: %.2f%%", binanceMinCallbackRate, binanceMaxCallbackRate, callbackRatePct)
+	}
+
+	var side futures.SideType
+	var posSide futures.PositionSideType
+	if positionSide == "LONG" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeLong
+	} else {
+		side = futures.SideTypeBuy
+		posSide = futures.PositionSideTypeShort
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeTrailingStopMarket).
+		CallbackRate(fmt.Sprintf("%.1f", callbackRate)).
+		Quantity(quantityStr).
+		WorkingType(futures.WorkingTypeContractPrice).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("设置追踪止损失败: %w", err)
+	}
+
+	t.InvalidatePositionsCache()
+
+	log.Printf("  追踪止损设置: 回调 %.1f%%", callbackRate)
+	return nil
+}
+
 // SetTakeProfit 设置止盈单
 func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
 	var side futures.SideType
@@ -1335,6 +1451,8 @@ func (t *FuturesTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo,
 			Quantity:     quantity,
 			Price:        price,
 			StopPrice:    stopPrice,
+			ReduceOnly:   order.ReduceOnly || order.ClosePosition,
+			CreatedAt:    order.Time,
 		}
 		result = append(result, orderInfo)
 	}
@@ -1343,6 +1461,120 @@ func (t *FuturesTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo,
 	return result, nil
 }
 
+// GetSymbolTradingStatus 获取交易对交易状态（基于 exchangeInfo 的 status 字段，全部交易对一次拉取后短时缓存）
+func (t *FuturesTrader) GetSymbolTradingStatus(symbol string) (*SymbolTradingStatus, error) {
+	return t.symbolStatus.lookup(symbol, func() (map[string]string, error) {
+		exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("获取交易规则失败: %w", err)
+		}
+
+		statuses := make(map[string]string, len(exchangeInfo.Symbols))
+		for _, s := range exchangeInfo.Symbols {
+			statuses[s.Symbol] = s.Status
+		}
+		return statuses, nil
+	})
+}
+
+// GetMaxLeverage 获取交易对允许的最大杠杆（取第一档杠杆分层，全部交易对一次拉取后缓存）
+func (t *FuturesTrader) GetMaxLeverage(symbol string) (int, error) {
+	return t.maxLeverage.lookup(symbol, func() (map[string]int, error) {
+		brackets, err := t.client.NewGetLeverageBracketService().Do(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("获取杠杆分层失败: %w", err)
+		}
+		values := make(map[string]int, len(brackets))
+		for _, b := range brackets {
+			values[b.Symbol] = maxInitialLeverage(b.Brackets)
+		}
+		return values, nil
+	})
+}
+
+// maxInitialLeverage 杠杆分层中的最高杠杆（名义价值最低一档）
+func maxInitialLeverage(brackets []futures.Bracket) int {
+	maxLeverage := 0
+	for _, b := range brackets {
+		if b.InitialLeverage > maxLeverage {
+			maxLeverage = b.InitialLeverage
+		}
+	}
+	return maxLeverage
+}
+
+// GetUserTrades 获取账户成交记录（Binance 要求指定交易对，单次查询不超过7天）
+func (t *FuturesTrader) GetUserTrades(symbol string, startTime, endTime time.Time) ([]UserTrade, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("查询成交记录必须指定交易对")
+	}
+
+	return collectUserTrades(startTime, endTime, userTradesPageLimit, func(startMs, endMs int64) ([]UserTrade, error) {
+		trades, err := t.client.NewListAccountTradeService().
+			Symbol(symbol).
+			StartTime(startMs).
+			EndTime(endMs).
+			Limit(userTradesPageLimit).
+			Do(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("获取成交记录失败: %w", err)
+		}
+
+		page := make([]UserTrade, 0, len(trades))
+		for _, trade := range trades {
+			price, _ := strconv.ParseFloat(trade.Price, 64)
+			quantity, _ := strconv.ParseFloat(trade.Quantity, 64)
+			realizedPnL, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+			fee, _ := strconv.ParseFloat(trade.Commission, 64)
+			page = append(page, UserTrade{
+				ID:           trade.ID,
+				OrderID:      trade.OrderID,
+				Symbol:       trade.Symbol,
+				Side:         string(trade.Side),
+				PositionSide: string(trade.PositionSide),
+				Price:        price,
+				Quantity:     quantity,
+				RealizedPnL:  realizedPnL,
+				Fee:          fee,
+				FeeAsset:     trade.CommissionAsset,
+				Maker:        trade.Maker,
+				Time:         trade.Time,
+			})
+		}
+		return page, nil
+	})
+}
+
+// buildSymbolTradingStatus 根据 Binance 风格的 exchangeInfo 状态表构建交易状态（Binance、Aster 共用）
+// 若没有任何交易对处于 TRADING 状态，视为交易所整体维护
+func buildSymbolTradingStatus(symbol string, statuses map[string]string) *SymbolTradingStatus {
+	maintenance := len(statuses) > 0
+	for _, status := range statuses {
+		if status == "TRADING" {
+			maintenance = false
+			break
+		}
+	}
+
+	result := &SymbolTradingStatus{Symbol: symbol, Maintenance: maintenance}
+	status, ok := statuses[symbol]
+	switch {
+	case maintenance:
+		result.Status = status
+		result.Reason = "交易所维护中"
+	case !ok:
+		result.Status = "UNKNOWN"
+		result.Reason = "交易对不存在或已下架"
+	case status != "TRADING":
+		result.Status = status
+		result.Reason = fmt.Sprintf("交易对状态为 %s（暂停交易）", status)
+	default:
+		result.Status = status
+		result.Tradeable = true
+	}
+	return result
+}
+
 // 辅助函数
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && stringContains(s, substr)
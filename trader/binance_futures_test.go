@@ -355,6 +355,7 @@ func (s *BinanceFuturesTestSuite) Cleanup() {
 // TestFuturesTrader_InterfaceCompliance 测试接口兼容性
 func TestFuturesTrader_InterfaceCompliance(t *testing.T) {
 	var _ Trader = (*FuturesTrader)(nil)
+	var _ trailingStopPlacer = (*FuturesTrader)(nil)
 }
 
 // TestFuturesTrader_CommonInterface 使用测试套件运行所有通用接口测试
@@ -399,7 +400,7 @@ func TestNewFuturesTrader(t *testing.T) {
 	defer mockServer.Close()
 
 	// 测试成功创建
-	trader := NewFuturesTrader("test_api_key", "test_secret_key", "test_user", "market_only", -0.03, 60)
+	trader := NewFuturesTrader("test_api_key", "test_secret_key", false, "test_user", "market_only", -0.03, 60, nil)
 
 	// 修改 client 使用 mock server
 	trader.client.BaseURL = mockServer.URL
@@ -410,6 +411,15 @@ func TestNewFuturesTrader(t *testing.T) {
 	assert.Equal(t, 15*time.Second, trader.cacheDuration)
 }
 
+// TestNewFuturesTraderTestnet 测试网交易器使用币安合约测试网地址
+func TestNewFuturesTraderTestnet(t *testing.T) {
+	mainnet := NewFuturesTrader("test_api_key", "test_secret_key", false, "test_user", "market_only", -0.03, 60, nil)
+	assert.NotEqual(t, futures.BaseApiTestnetUrl, mainnet.client.BaseURL)
+
+	testnet := NewFuturesTrader("test_api_key", "test_secret_key", true, "test_user", "market_only", -0.03, 60, nil)
+	assert.Equal(t, futures.BaseApiTestnetUrl, testnet.client.BaseURL)
+}
+
 // TestCalculatePositionSize 测试仓位计算
 func TestCalculatePositionSize(t *testing.T) {
 	trader := &FuturesTrader{}
@@ -786,3 +796,28 @@ func TestGetOpenOrders_ParseFloatValues(t *testing.T) {
 		assert.True(t, hasValidPrice, "价格或止损价至少有一个应该大于0")
 	}
 }
+
+func TestBuildSymbolTradingStatus(t *testing.T) {
+	statuses := map[string]string{
+		"BTCUSDT":  "TRADING",
+		"LUNAUSDT": "BREAK",
+	}
+
+	active := buildSymbolTradingStatus("BTCUSDT", statuses)
+	assert.True(t, active.Tradeable)
+	assert.False(t, active.Maintenance)
+
+	halted := buildSymbolTradingStatus("LUNAUSDT", statuses)
+	assert.False(t, halted.Tradeable)
+	assert.Equal(t, "BREAK", halted.Status)
+	assert.Contains(t, halted.Reason, "暂停交易")
+
+	missing := buildSymbolTradingStatus("FOOUSDT", statuses)
+	assert.False(t, missing.Tradeable)
+	assert.Equal(t, "UNKNOWN", missing.Status)
+
+	// 没有任何交易对处于 TRADING 状态，视为交易所维护
+	maintenance := buildSymbolTradingStatus("BTCUSDT", map[string]string{"BTCUSDT": "BREAK", "ETHUSDT": "BREAK"})
+	assert.False(t, maintenance.Tradeable)
+	assert.True(t, maintenance.Maintenance)
+}
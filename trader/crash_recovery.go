@@ -0,0 +1,218 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// 崩溃恢复发现的不一致类型
+const (
+	RecoveryMissingOnExchange   = "missing_on_exchange"   // 数据库记录为持仓中，交易所已无持仓（停机期间被平仓）
+	RecoveryUntrackedOnExchange = "untracked_on_exchange" // 交易所有持仓，数据库没有开仓记录（开仓后未来得及落库）
+	RecoveryQuantityMismatch    = "quantity_mismatch"     // 持仓数量与数据库记录不一致
+	RecoveryStopLossMismatch    = "stop_loss_mismatch"    // 交易所止损单价格与记录不一致（以交易所为准）
+	RecoveryStopLossMissing     = "stop_loss_missing"     // 记录有止损，交易所没有止损单
+	RecoveryTakeProfitMismatch  = "take_profit_mismatch"  // 交易所止盈单价格与记录不一致（以交易所为准）
+	RecoveryTakeProfitMissing   = "take_profit_missing"   // 记录有止盈，交易所没有止盈单
+)
+
+// recoveryQuantityTolerance 持仓数量允许的相对误差（交易所步进取整）
+const recoveryQuantityTolerance = 0.01
+
+// RecoveryDiscrepancy 崩溃恢复时数据库状态与交易所实际状态的不一致
+type RecoveryDiscrepancy struct {
+	Kind   string `json:"kind"`
+	Symbol string `json:"symbol"`
+	Side   string `json:"side"`
+	Detail string `json:"detail"`
+}
+
+// RecoveryReport 崩溃恢复结果
+type RecoveryReport struct {
+	Positions         int                   `json:"positions"`           // 交易所实际持仓数
+	StopLossRebuilt   int                   `json:"stop_loss_rebuilt"`   // 从挂单重建的止损数
+	TakeProfitRebuilt int                   `json:"take_profit_rebuilt"` // 从挂单重建的止盈数
+	Discrepancies     []RecoveryDiscrepancy `json:"discrepancies"`
+}
+
+// RecoverAfterCrash 非正常退出后、启动交易前的恢复检查
+// 以交易所为准核对数据库中的持仓记录，并从交易所挂单重建 positionStopLoss/positionTakeProfit；
+// 已不存在的持仓清除内存中的止损止盈（否则兜底止损会误认为已有保护）。
+// 停机期间被平仓的持仓只记录不一致，平仓记录由首个周期的 syncAutoClosedPositions 补写
+func (at *AutoTrader) RecoverAfterCrash() (*RecoveryReport, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易所持仓失败: %w", err)
+	}
+	orders, err := at.trader.GetOpenOrders("")
+	if err != nil {
+		at.log().Warnf("⚠️ [%s] 崩溃恢复：获取挂单失败，保留已记录的止损止盈: %v", at.name, err)
+		orders = nil
+	}
+
+	// 数据库中的未平仓记录（key 统一为 symbol_小写方向）
+	dbPositions := make(map[string]map[string]interface{})
+	if db, ok := at.database.(interface {
+		GetOpenPositionsFromHistory(string) (map[string]map[string]interface{}, error)
+	}); ok {
+		records, err := db.GetOpenPositionsFromHistory(at.id)
+		if err != nil {
+			return nil, fmt.Errorf("获取数据库持仓记录失败: %w", err)
+		}
+		for _, pos := range records {
+			symbol, _ := pos["symbol"].(string)
+			side, _ := pos["side"].(string)
+			dbPositions[symbol+"_"+strings.ToLower(side)] = pos
+		}
+	}
+
+	report := &RecoveryReport{Discrepancies: make([]RecoveryDiscrepancy, 0)}
+	addDiscrepancy := func(kind, symbol, side, format string, args ...interface{}) {
+		report.Discrepancies = append(report.Discrepancies, RecoveryDiscrepancy{
+			Kind: kind, Symbol: symbol, Side: side, Detail: fmt.Sprintf(format, args...),
+		})
+	}
+
+	onExchange := make(map[string]bool)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		quantity, _ := pos["positionAmt"].(float64)
+		quantity = math.Abs(quantity)
+		if symbol == "" || quantity == 0 {
+			continue
+		}
+		side = strings.ToLower(side)
+		positionSide := strings.ToUpper(side)
+		posKey := symbol + "_" + side
+		onExchange[posKey] = true
+		report.Positions++
+
+		dbPos, tracked := dbPositions[posKey]
+		if !tracked {
+			addDiscrepancy(RecoveryUntrackedOnExchange, symbol, side, "交易所持仓 %.4f，数据库无开仓记录", quantity)
+			if _, exists := at.positionFirstSeenTime[posKey]; !exists {
+				at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+			}
+		} else if dbQty, _ := dbPos["quantity"].(float64); dbQty > 0 && math.Abs(dbQty-quantity)/dbQty > recoveryQuantityTolerance {
+			addDiscrepancy(RecoveryQuantityMismatch, symbol, side, "数据库 %.4f，交易所 %.4f", dbQty, quantity)
+		}
+
+		if orders == nil {
+			continue
+		}
+
+		recordedSL := at.positionStopLoss[posKey]
+		if stopPrice, ok := exchangeCloseOrderPrice(orders, symbol, positionSide, "STOP_MARKET", "STOP"); ok {
+			if recordedSL > 0 && !pricesClose(recordedSL, stopPrice) {
+				addDiscrepancy(RecoveryStopLossMismatch, symbol, side, "记录 %.4f，交易所 %.4f", recordedSL, stopPrice)
+			}
+			at.positionStopLoss[posKey] = stopPrice
+			report.StopLossRebuilt++
+		} else if recordedSL > 0 {
+			addDiscrepancy(RecoveryStopLossMissing, symbol, side, "记录止损 %.4f，交易所无止损单", recordedSL)
+			delete(at.positionStopLoss, posKey)
+		}
+
+		recordedTP := at.positionTakeProfit[posKey]
+		if tpPrice, ok := exchangeCloseOrderPrice(orders, symbol, positionSide, "TAKE_PROFIT_MARKET", "TAKE_PROFIT"); ok {
+			if recordedTP > 0 && !pricesClose(recordedTP, tpPrice) {
+				addDiscrepancy(RecoveryTakeProfitMismatch, symbol, side, "记录 %.4f，交易所 %.4f", recordedTP, tpPrice)
+			}
+			at.positionTakeProfit[posKey] = tpPrice
+			report.TakeProfitRebuilt++
+		} else if recordedTP > 0 {
+			addDiscrepancy(RecoveryTakeProfitMissing, symbol, side, "记录止盈 %.4f，交易所无止盈单", recordedTP)
+			delete(at.positionTakeProfit, posKey)
+		}
+	}
+
+	for posKey, dbPos := range dbPositions {
+		if onExchange[posKey] {
+			continue
+		}
+		symbol, _ := dbPos["symbol"].(string)
+		dbQty, _ := dbPos["quantity"].(float64)
+		addDiscrepancy(RecoveryMissingOnExchange, symbol, strings.TrimPrefix(posKey, symbol+"_"), "数据库持仓 %.4f，交易所已无持仓", dbQty)
+	}
+	for posKey := range at.positionStopLoss {
+		if !onExchange[posKey] {
+			delete(at.positionStopLoss, posKey)
+		}
+	}
+	for posKey := range at.positionTakeProfit {
+		if !onExchange[posKey] {
+			delete(at.positionTakeProfit, posKey)
+		}
+	}
+
+	at.logRecoveryReport(report)
+	return report, nil
+}
+
+// exchangeCloseOrderPrice 查找持仓对应的平仓条件单（止损或止盈）的触发价
+func exchangeCloseOrderPrice(orders []decision.OpenOrderInfo, symbol, positionSide string, types ...string) (float64, bool) {
+	closeSide := "SELL"
+	if positionSide == "SHORT" {
+		closeSide = "BUY"
+	}
+	for _, order := range orders {
+		if order.Symbol != symbol || order.StopPrice <= 0 {
+			continue
+		}
+		matchedType := false
+		for _, t := range types {
+			if order.Type == t {
+				matchedType = true
+				break
+			}
+		}
+		if !matchedType {
+			continue
+		}
+		switch strings.ToUpper(order.PositionSide) {
+		case positionSide:
+			return order.StopPrice, true
+		case "", "BOTH":
+			if strings.ToUpper(order.Side) == closeSide {
+				return order.StopPrice, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// pricesClose 两个价格是否在 0.01% 以内（忽略交易所按步进取整造成的差异）
+func pricesClose(a, b float64) bool {
+	return math.Abs(a-b) <= math.Max(a, b)*0.0001
+}
+
+// logRecoveryReport 打印恢复结果，并写入决策日志便于事后排查
+func (at *AutoTrader) logRecoveryReport(report *RecoveryReport) {
+	summary := fmt.Sprintf("🩺 崩溃恢复：交易所持仓 %d 个，重建止损 %d 个、止盈 %d 个，发现 %d 处不一致",
+		report.Positions, report.StopLossRebuilt, report.TakeProfitRebuilt, len(report.Discrepancies))
+	at.log().Infof("[%s] %s", at.name, summary)
+
+	record := &logger.DecisionRecord{
+		Exchange:     at.config.Exchange,
+		ExecutionLog: []string{summary},
+		Success:      len(report.Discrepancies) == 0,
+	}
+	for _, d := range report.Discrepancies {
+		line := fmt.Sprintf("⚠️ %s %s [%s]: %s", d.Symbol, d.Side, d.Kind, d.Detail)
+		at.log().Infof("[%s]    └─ %s", at.name, line)
+		record.ExecutionLog = append(record.ExecutionLog, line)
+	}
+	if len(report.Discrepancies) > 0 {
+		record.ErrorMessage = fmt.Sprintf("崩溃恢复发现 %d 处数据库与交易所不一致", len(report.Discrepancies))
+	}
+	if at.decisionLogger != nil {
+		if err := at.decisionLogger.LogDecision(record); err != nil {
+			at.log().Warnf("⚠ 保存崩溃恢复记录失败: %v", err)
+		}
+	}
+}
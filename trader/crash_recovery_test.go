@@ -0,0 +1,95 @@
+package trader
+
+import (
+	"nofx/decision"
+	"testing"
+)
+
+// fakeHistoryStore 模拟 trade_history 中的未平仓记录（side 为大写，与数据库一致）
+type fakeHistoryStore struct {
+	positions map[string]map[string]interface{}
+}
+
+func (f *fakeHistoryStore) GetOpenPositionsFromHistory(traderID string) (map[string]map[string]interface{}, error) {
+	return f.positions, nil
+}
+
+// TestRecoverAfterCrash 模拟崩溃后的状态：数据库、内存止损止盈与交易所实际持仓/挂单不一致
+func TestRecoverAfterCrash(t *testing.T) {
+	mock := &safetyStopMockTrader{
+		MockTrader: &MockTrader{positions: []map[string]interface{}{
+			// 有记录，止损被移动过（崩溃前未保存），止盈一致
+			{"symbol": "BTCUSDT", "side": "long", "entryPrice": 50000.0, "markPrice": 50500.0, "positionAmt": 0.1},
+			// 开仓后崩溃，未落库
+			{"symbol": "ETHUSDT", "side": "short", "entryPrice": 3000.0, "markPrice": 2990.0, "positionAmt": -2.0},
+			// 数量与记录不一致，交易所止损单已不存在
+			{"symbol": "SOLUSDT", "side": "long", "entryPrice": 100.0, "markPrice": 101.0, "positionAmt": 5.0},
+		}},
+		openOrders: []decision.OpenOrderInfo{
+			{Symbol: "BTCUSDT", Side: "SELL", PositionSide: "LONG", Type: "STOP_MARKET", StopPrice: 49500},
+			{Symbol: "BTCUSDT", Side: "SELL", PositionSide: "LONG", Type: "TAKE_PROFIT_MARKET", StopPrice: 55000},
+			{Symbol: "ETHUSDT", Side: "BUY", PositionSide: "BOTH", Type: "STOP_MARKET", StopPrice: 3100},
+		},
+	}
+	store := &fakeHistoryStore{positions: map[string]map[string]interface{}{
+		"BTCUSDT_LONG":  {"symbol": "BTCUSDT", "side": "LONG", "quantity": 0.1, "stop_loss": 48000.0, "take_profit": 55000.0},
+		"SOLUSDT_LONG":  {"symbol": "SOLUSDT", "side": "LONG", "quantity": 10.0, "stop_loss": 95.0},
+		"DOGEUSDT_LONG": {"symbol": "DOGEUSDT", "side": "LONG", "quantity": 1000.0, "stop_loss": 0.1},
+	}}
+	at := &AutoTrader{
+		id:                    "t1",
+		name:                  "recover",
+		trader:                mock,
+		database:              store,
+		positionFirstSeenTime: make(map[string]int64),
+		positionStopLoss:      map[string]float64{"BTCUSDT_long": 48000, "SOLUSDT_long": 95, "DOGEUSDT_long": 0.1},
+		positionTakeProfit:    map[string]float64{"BTCUSDT_long": 55000},
+	}
+
+	report, err := at.RecoverAfterCrash()
+	if err != nil {
+		t.Fatalf("崩溃恢复失败: %v", err)
+	}
+
+	if report.Positions != 3 || report.StopLossRebuilt != 2 || report.TakeProfitRebuilt != 1 {
+		t.Errorf("恢复统计不正确: %+v", report)
+	}
+
+	// 以交易所挂单为准重建止损止盈
+	if at.positionStopLoss["BTCUSDT_long"] != 49500 || at.positionStopLoss["ETHUSDT_short"] != 3100 {
+		t.Errorf("止损未按交易所挂单重建: %v", at.positionStopLoss)
+	}
+	if at.positionTakeProfit["BTCUSDT_long"] != 55000 {
+		t.Errorf("止盈应保留: %v", at.positionTakeProfit)
+	}
+	// 交易所没有止损单的持仓清除记录，让兜底止损重新保护；已不存在的持仓清除记录
+	if _, ok := at.positionStopLoss["SOLUSDT_long"]; ok {
+		t.Error("交易所没有止损单时应清除内存止损")
+	}
+	if _, ok := at.positionStopLoss["DOGEUSDT_long"]; ok {
+		t.Error("已不存在的持仓应清除内存止损")
+	}
+	if at.positionFirstSeenTime["ETHUSDT_short"] == 0 {
+		t.Error("未落库的持仓应记录首次出现时间")
+	}
+
+	kinds := make(map[string]string)
+	for _, d := range report.Discrepancies {
+		kinds[d.Symbol+"/"+d.Kind] = d.Detail
+	}
+	expected := []string{
+		"BTCUSDT/" + RecoveryStopLossMismatch,
+		"ETHUSDT/" + RecoveryUntrackedOnExchange,
+		"SOLUSDT/" + RecoveryQuantityMismatch,
+		"SOLUSDT/" + RecoveryStopLossMissing,
+		"DOGEUSDT/" + RecoveryMissingOnExchange,
+	}
+	if len(report.Discrepancies) != len(expected) {
+		t.Errorf("不一致数量应为 %d，实际 %d: %v", len(expected), len(report.Discrepancies), report.Discrepancies)
+	}
+	for _, key := range expected {
+		if _, ok := kinds[key]; !ok {
+			t.Errorf("缺少不一致记录 %s: %v", key, report.Discrepancies)
+		}
+	}
+}
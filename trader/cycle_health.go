@@ -0,0 +1,180 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/webhook"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 交易员健康状态（列表/状态接口的 health 字段）
+const (
+	HealthOK       = "ok"       // 最近一个决策周期成功
+	HealthDegraded = "degraded" // 连续失败，但未达到 failing 阈值
+	HealthFailing  = "failing"  // 连续失败达到阈值，或已因连续失败自动暂停
+)
+
+// 健康状态阈值：连续失败的决策周期数
+const (
+	healthDegradedErrors = 1
+	healthFailingErrors  = 3
+)
+
+// maxLastErrorLen last_error 的最大长度（交易所错误可能带很长的响应体）
+const maxLastErrorLen = 300
+
+// cycleHealth 连续失败的决策周期统计
+type cycleHealth struct {
+	mu                sync.Mutex
+	consecutiveErrors int
+	lastError         string
+	lastErrorTime     time.Time
+	pauseReason       string // 因连续失败自动暂停的原因（空=未暂停）
+}
+
+// CycleHealth 决策周期健康状态快照
+type CycleHealth struct {
+	Health            string
+	Reason            string // 失败原因摘要（如 "invalid API key"），健康时为空
+	ConsecutiveErrors int
+	LastError         string
+	LastErrorTime     time.Time
+	AutoPaused        bool
+	AutoPauseReason   string
+}
+
+// recordCycleResult 记录一次决策周期的结果：成功时清零连续失败次数（保留最后一次错误供排查），
+// 失败时累加，达到 MaxConsecutiveErrors 时返回 true，由主循环停止交易员
+func (at *AutoTrader) recordCycleResult(err error) bool {
+	h := &at.cycleHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err == nil {
+		h.consecutiveErrors = 0
+		return false
+	}
+
+	h.consecutiveErrors++
+	h.lastError = truncateError(err.Error())
+	h.lastErrorTime = time.Now()
+
+	limit := at.config.MaxConsecutiveErrors
+	if limit <= 0 || h.consecutiveErrors < limit {
+		return false
+	}
+	h.pauseReason = fmt.Sprintf("连续 %d 个决策周期失败（%s）", h.consecutiveErrors, classifyCycleError(h.lastError))
+	return true
+}
+
+// haltOnErrors 连续失败达到阈值后停止交易员（与风控暂停不同，不会自动恢复，需通过启动接口重新启动）
+// 由主循环调用：Run 返回后 monitorWg 归零，不能调用 Stop（会等待自身退出）
+func (at *AutoTrader) haltOnErrors() {
+	// 与 Stop 共用 CAS：失败周期内 Stop 已执行时直接返回，避免重复关闭 stopMonitorCh
+	if !at.isRunning.CompareAndSwap(true, false) {
+		return
+	}
+
+	health := at.GetCycleHealth()
+	at.log().Errorf("⛔ [%s] %s，已自动暂停交易员，修复后请重新启动。最后错误: %s",
+		at.name, health.AutoPauseReason, health.LastError)
+
+	close(at.stopMonitorCh)
+	at.flushPersistQueue()
+
+	if db, ok := at.database.(interface {
+		UpdateTraderStatus(userID, id string, isRunning bool) error
+	}); ok {
+		if err := db.UpdateTraderStatus(at.userID, at.id, false); err != nil {
+			at.log().Warnf("⚠️ 更新交易员运行状态失败: %v", err)
+		}
+	}
+
+	at.emitWebhook(webhook.EventRiskStop, map[string]interface{}{
+		"reason":             health.AutoPauseReason,
+		"trigger":            "consecutive_errors",
+		"consecutive_errors": health.ConsecutiveErrors,
+		"last_error":         health.LastError,
+	})
+}
+
+// resetCycleHealth 重新启动时清零连续失败次数和自动暂停原因（保留最后一次错误供排查）
+func (at *AutoTrader) resetCycleHealth() {
+	h := &at.cycleHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.consecutiveErrors = 0
+	h.pauseReason = ""
+}
+
+// GetCycleHealth 获取决策周期健康状态
+func (at *AutoTrader) GetCycleHealth() CycleHealth {
+	h := &at.cycleHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snapshot := CycleHealth{
+		Health:            cycleHealthState(h.consecutiveErrors, h.pauseReason != ""),
+		ConsecutiveErrors: h.consecutiveErrors,
+		LastError:         h.lastError,
+		LastErrorTime:     h.lastErrorTime,
+		AutoPaused:        h.pauseReason != "",
+		AutoPauseReason:   h.pauseReason,
+	}
+	if snapshot.Health != HealthOK {
+		snapshot.Reason = classifyCycleError(h.lastError)
+	}
+	return snapshot
+}
+
+// cycleHealthState 根据连续失败次数推导健康状态
+func cycleHealthState(consecutiveErrors int, autoPaused bool) string {
+	switch {
+	case autoPaused || consecutiveErrors >= healthFailingErrors:
+		return HealthFailing
+	case consecutiveErrors >= healthDegradedErrors:
+		return HealthDegraded
+	default:
+		return HealthOK
+	}
+}
+
+// classifyCycleError 把常见的决策周期错误归类为简短原因，无法归类时返回截断后的原始错误
+func classifyCycleError(msg string) string {
+	lower := strings.ToLower(msg)
+	containsAny := func(keywords ...string) bool {
+		for _, k := range keywords {
+			if strings.Contains(lower, k) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case containsAny("code=-2015", "code=-2014", "code=-2008", "code=-1022",
+		"invalid api-key", "api-key format invalid", "invalid api key", "api key is invalid",
+		"signature for this request is not valid", "unauthorized", "status code 401"):
+		return "invalid API key"
+	case containsAny("code=-1003", "too many requests", "rate limit", "status code 429"):
+		return "exchange rate limited"
+	case containsAny("timeout", "deadline exceeded", "connection refused", "connection reset", "no such host", "eof"):
+		return "network error"
+	case strings.Contains(msg, "获取AI决策失败"):
+		return "AI decision failed"
+	case strings.Contains(msg, "构建交易上下文失败"):
+		return "exchange request failed"
+	default:
+		return msg
+	}
+}
+
+// truncateError 截断过长的错误信息
+func truncateError(msg string) string {
+	msg = strings.TrimSpace(msg)
+	if len([]rune(msg)) <= maxLastErrorLen {
+		return msg
+	}
+	return string([]rune(msg)[:maxLastErrorLen]) + "..."
+}
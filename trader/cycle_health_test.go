@@ -0,0 +1,134 @@
+package trader
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// statusRecorder 记录 UpdateTraderStatus 调用的测试数据库
+type statusRecorder struct {
+	calls   int
+	running bool
+}
+
+func (r *statusRecorder) UpdateTraderStatus(userID, id string, isRunning bool) error {
+	r.calls++
+	r.running = isRunning
+	return nil
+}
+
+// TestCycleHealthProgression 测试连续失败时健康状态从 ok 变为 degraded/failing，成功后恢复
+func TestCycleHealthProgression(t *testing.T) {
+	at := &AutoTrader{name: "health"}
+	keyErr := errors.New("构建交易上下文失败: 获取账户余额失败: <APIError> code=-2015, msg=Invalid API-key, IP, or permissions for action.")
+
+	if h := at.GetCycleHealth(); h.Health != HealthOK || h.Reason != "" {
+		t.Fatalf("初始状态应为 ok, 实际 %+v", h)
+	}
+
+	want := []string{HealthDegraded, HealthDegraded, HealthFailing, HealthFailing}
+	for i, state := range want {
+		if at.recordCycleResult(keyErr) {
+			t.Fatal("未设置 MaxConsecutiveErrors 时不应自动暂停")
+		}
+		h := at.GetCycleHealth()
+		if h.Health != state || h.ConsecutiveErrors != i+1 {
+			t.Errorf("第 %d 次失败后 health=%s consecutive=%d, 期望 %s/%d", i+1, h.Health, h.ConsecutiveErrors, state, i+1)
+		}
+	}
+	if h := at.GetCycleHealth(); h.Reason != "invalid API key" || h.LastErrorTime.IsZero() || !strings.Contains(h.LastError, "-2015") {
+		t.Errorf("应记录最后一次错误并归类为 invalid API key: %+v", h)
+	}
+
+	// 成功后清零连续失败次数，保留最后一次错误供排查
+	at.recordCycleResult(nil)
+	h := at.GetCycleHealth()
+	if h.Health != HealthOK || h.ConsecutiveErrors != 0 || h.LastError == "" {
+		t.Errorf("成功后应恢复 ok 并保留 last_error: %+v", h)
+	}
+
+	status := at.GetStatus()
+	for _, key := range []string{"health", "health_reason", "consecutive_errors", "last_error", "last_error_time", "auto_paused"} {
+		if _, ok := status[key]; !ok {
+			t.Errorf("GetStatus 缺少字段 %s", key)
+		}
+	}
+}
+
+// TestCycleErrorsAutoPause 测试连续失败达到阈值后停止交易员并更新数据库状态，重新启动时清除暂停状态
+func TestCycleErrorsAutoPause(t *testing.T) {
+	db := &statusRecorder{running: true}
+	at := &AutoTrader{
+		id:            "t1",
+		name:          "auto-pause",
+		config:        AutoTraderConfig{MaxConsecutiveErrors: 2},
+		database:      db,
+		stopMonitorCh: make(chan struct{}),
+	}
+	at.isRunning.Store(true)
+	aiErr := errors.New("获取AI决策失败: 调用AI API失败: context deadline exceeded")
+
+	if at.recordCycleResult(aiErr) {
+		t.Fatal("第 1 次失败不应暂停")
+	}
+	if !at.recordCycleResult(aiErr) {
+		t.Fatal("达到阈值时应暂停")
+	}
+	at.haltOnErrors()
+
+	if at.isRunning.Load() {
+		t.Error("自动暂停后交易员应停止运行")
+	}
+	select {
+	case <-at.stopMonitorCh:
+	default:
+		t.Error("自动暂停后应通知监控协程退出")
+	}
+	if db.calls != 1 || db.running {
+		t.Errorf("应把数据库运行状态更新为停止, calls=%d running=%v", db.calls, db.running)
+	}
+
+	// 已停止（如失败周期内 Stop 已执行）时再次暂停不应重复关闭 stopMonitorCh
+	at.haltOnErrors()
+	if db.calls != 1 {
+		t.Errorf("已停止时不应重复更新数据库状态, calls=%d", db.calls)
+	}
+
+	h := at.GetCycleHealth()
+	if h.Health != HealthFailing || !h.AutoPaused || !strings.Contains(h.AutoPauseReason, "连续 2 个决策周期失败") {
+		t.Errorf("自动暂停后应为 failing 并说明原因: %+v", h)
+	}
+	if h.Reason != "network error" {
+		t.Errorf("超时错误应归类为 network error, 实际 %q", h.Reason)
+	}
+
+	// 通过启动接口重新启动时清除暂停状态
+	at.resetCycleHealth()
+	if h := at.GetCycleHealth(); h.Health != HealthOK || h.AutoPaused {
+		t.Errorf("重新启动后应恢复 ok: %+v", h)
+	}
+}
+
+// TestClassifyCycleError 测试常见错误的归类
+func TestClassifyCycleError(t *testing.T) {
+	cases := map[string]string{
+		"<APIError> code=-2014, msg=API-key format invalid.": "invalid API key",
+		"bybit: API key is invalid":                          "invalid API key",
+		"<APIError> code=-1003, msg=Too many requests":       "exchange rate limited",
+		"dial tcp: lookup fapi.binance.com: no such host":    "network error",
+		"获取AI决策失败: 解析AI响应失败":                                 "AI decision failed",
+		"构建交易上下文失败: 获取持仓失败: unexpected response":             "exchange request failed",
+		"something unexpected":                               "something unexpected",
+	}
+	for msg, want := range cases {
+		if got := classifyCycleError(msg); got != want {
+			t.Errorf("classifyCycleError(%q) = %q, 期望 %q", msg, got, want)
+		}
+	}
+
+	long := strings.Repeat("x", maxLastErrorLen+50)
+	if got := truncateError(long); len([]rune(got)) != maxLastErrorLen+3 {
+		t.Errorf("过长的错误应截断, 实际长度 %d", len([]rune(got)))
+	}
+}
@@ -0,0 +1,54 @@
+package trader
+
+import (
+	"nofx/config"
+	"nofx/webhook"
+	"time"
+)
+
+// dailyReportStore 每日报告读写接口（数据库以鸭子类型注入）
+type dailyReportStore interface {
+	SummarizeTrades(traderID string, start, end time.Time) (*config.DailyReport, error)
+	SaveDailyReport(report *config.DailyReport) error
+}
+
+// generateDailyReport 汇总交易员某一天的开平仓、已实现盈亏、胜率和期末净值，保存并推送通知
+// 在跨日重置盈亏基线之前调用，此时 dailyPnLBase/dailyPnL 仍是该交易日的数据
+func (at *AutoTrader) generateDailyReport(day time.Time) *config.DailyReport {
+	store, ok := at.database.(dailyReportStore)
+	if !ok {
+		return nil
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	report, err := store.SummarizeTrades(at.id, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		at.log().Warnf("⚠️ [%s] 统计每日交易失败: %v", at.name, err)
+		return nil
+	}
+	report.UserID = at.userID
+	report.Date = start.Format("2006-01-02")
+	if at.dailyPnLBase > 0 {
+		report.StartingEquity = at.dailyPnLBase
+		report.EndingEquity = at.dailyPnLBase + at.dailyPnL
+	}
+
+	if err := store.SaveDailyReport(report); err != nil {
+		at.log().Warnf("⚠️ [%s] 保存每日报告失败: %v", at.name, err)
+	}
+	at.log().Infof("📰 [%s] %s 日报：开仓 %d | 平仓 %d | 胜率 %.1f%% | 已实现盈亏 %+.2f | 期末净值 %.2f",
+		at.name, report.Date, report.OpenedTrades, report.ClosedTrades, report.WinRate, report.RealizedPnL, report.EndingEquity)
+
+	at.emitWebhook(webhook.EventDailyReport, map[string]interface{}{
+		"date":            report.Date,
+		"opened_trades":   report.OpenedTrades,
+		"closed_trades":   report.ClosedTrades,
+		"winning_trades":  report.WinningTrades,
+		"losing_trades":   report.LosingTrades,
+		"win_rate":        report.WinRate,
+		"realized_pnl":    report.RealizedPnL,
+		"starting_equity": report.StartingEquity,
+		"ending_equity":   report.EndingEquity,
+	})
+	return report
+}
@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"nofx/config"
+	"testing"
+	"time"
+)
+
+type fakeDailyReportStore struct {
+	start, end time.Time
+	saved      []*config.DailyReport
+}
+
+func (f *fakeDailyReportStore) SummarizeTrades(traderID string, start, end time.Time) (*config.DailyReport, error) {
+	f.start, f.end = start, end
+	return &config.DailyReport{TraderID: traderID, OpenedTrades: 3, ClosedTrades: 2, WinningTrades: 1, LosingTrades: 1, WinRate: 50, RealizedPnL: 12.5}, nil
+}
+
+func (f *fakeDailyReportStore) SaveDailyReport(report *config.DailyReport) error {
+	f.saved = append(f.saved, report)
+	return nil
+}
+
+// TestGenerateDailyReport 测试日报按自然日统计并带上当日净值基线
+func TestGenerateDailyReport(t *testing.T) {
+	store := &fakeDailyReportStore{}
+	at := &AutoTrader{id: "t1", userID: "u1", name: "report", database: store}
+	at.dailyPnLBase = 1000
+	at.dailyPnL = -20
+
+	day := time.Date(2026, 3, 5, 17, 30, 0, 0, time.UTC)
+	report := at.generateDailyReport(day)
+	if report == nil || len(store.saved) != 1 {
+		t.Fatalf("应生成并保存一份日报: %+v", store.saved)
+	}
+	if !store.start.Equal(time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)) || store.end.Sub(store.start) != 24*time.Hour {
+		t.Errorf("统计区间应为当天 0 点起的 24 小时: %v ~ %v", store.start, store.end)
+	}
+	if report.Date != "2026-03-05" || report.UserID != "u1" {
+		t.Errorf("日期或用户不正确: %+v", report)
+	}
+	if report.StartingEquity != 1000 || report.EndingEquity != 980 {
+		t.Errorf("期初/期末净值不正确: %.2f / %.2f", report.StartingEquity, report.EndingEquity)
+	}
+
+	// 数据库不支持日报时跳过
+	if (&AutoTrader{name: "nodb"}).generateDailyReport(day) != nil {
+		t.Error("无日报存储时不应生成报告")
+	}
+}
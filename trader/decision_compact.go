@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"time"
+)
+
+// decisionCompactInterval 决策记录压缩检查间隔
+const decisionCompactInterval = time.Hour
+
+// startDecisionCompactWorker 启动决策记录压缩和保留清理协程（启动时先执行一次，之后每小时检查）
+func (at *AutoTrader) startDecisionCompactWorker() {
+	if (at.config.DecisionCompactAfter <= 0 && !at.config.DecisionRetention.Enabled()) || at.decisionLogger == nil {
+		return
+	}
+
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(decisionCompactInterval)
+		defer ticker.Stop()
+
+		for {
+			at.applyDecisionRetention()
+			at.compactDecisionRecords()
+			select {
+			case <-ticker.C:
+			case <-at.stopMonitorCh:
+				return
+			}
+		}
+	}()
+}
+
+// applyDecisionRetention 归档或删除超过保留期限/条数的决策记录
+func (at *AutoTrader) applyDecisionRetention() {
+	if _, err := at.decisionLogger.ApplyRetention(); err != nil {
+		at.log().Warnf("⚠️ [%s] 清理过期决策记录失败: %v", at.name, err)
+	}
+}
+
+// compactDecisionRecords 压缩旧决策记录中的提示词和思维链
+func (at *AutoTrader) compactDecisionRecords() {
+	if at.config.DecisionCompactAfter <= 0 {
+		return
+	}
+	if _, err := at.decisionLogger.CompactOldRecords(at.config.DecisionCompactAfter, at.config.DecisionCompactMode); err != nil {
+		at.log().Warnf("⚠️ [%s] 压缩决策记录失败: %v", at.name, err)
+	}
+}
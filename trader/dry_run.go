@@ -0,0 +1,20 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/logger"
+)
+
+// dryRunSkip 模拟运行模式下拦截本应发往交易所的订单：只打印意图并把动作标记为 DryRun
+// 返回 true 表示调用方应直接返回，不再调用交易所；actionRecord 可为 nil（如回撤紧急平仓）
+func (at *AutoTrader) dryRunSkip(actionRecord *logger.DecisionAction, format string, args ...interface{}) bool {
+	if !at.config.DryRun {
+		return false
+	}
+
+	at.log().Infof("  🧪 [模拟运行] %s（未下单）", fmt.Sprintf(format, args...))
+	if actionRecord != nil {
+		actionRecord.DryRun = true
+	}
+	return true
+}
@@ -0,0 +1,320 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// dryRunLockTimeout 决策预演等待周期锁的最长时间（正常决策周期执行中时超时返回）
+const dryRunLockTimeout = 10 * time.Second
+
+// ErrDryRunBusy 正常决策周期执行中，决策预演等待周期锁超时
+var ErrDryRunBusy = errors.New("交易员正在执行决策周期，请稍后重试")
+
+// 决策预演专用的校验项（其余校验项与 rejected_decisions 的拒绝原因代码一致）
+const (
+	DryRunCheckPositionMissing = "position_missing" // 平仓/调整止盈止损的目标持仓不存在
+	DryRunCheckOutsideWindow   = "outside_window"   // 交易时间窗口外不开新仓
+	DryRunCheckMarketData      = "market_data"      // 无法获取当前价格
+)
+
+// DryRunCheck 决策预演中单项校验的结果
+type DryRunCheck struct {
+	Check   string `json:"check"` // 校验项（拒绝原因代码）
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// DryRunDecision 决策预演中单个决策及其校验结果
+type DryRunDecision struct {
+	decision.Decision
+	WouldExecute bool          `json:"would_execute"` // 所有校验通过，正常周期中会发往交易所
+	Checks       []DryRunCheck `json:"checks"`
+}
+
+// DryRunResult 决策预演结果
+type DryRunResult struct {
+	TraderID       string           `json:"trader_id"`
+	Timestamp      time.Time        `json:"timestamp"`
+	AIModel        string           `json:"ai_model"`
+	PromptTemplate string           `json:"prompt_template"`
+	SystemPrompt   string           `json:"system_prompt"`
+	InputPrompt    string           `json:"input_prompt"`
+	CoTTrace       string           `json:"cot_trace"`
+	Decisions      []DryRunDecision `json:"decisions"`
+	Error          string           `json:"error,omitempty"` // AI调用或决策解析失败（提示词和思维链仍返回）
+	DurationMs     int64            `json:"duration_ms"`
+}
+
+// RunDryRun 决策预演：使用真实交易上下文和当前提示词调用AI，校验每个决策但不执行任何订单
+// 运行中或已停止的交易员均可使用；不修改持仓跟踪/快照等交易员状态，结果写入一条 dry_run 决策记录
+func (at *AutoTrader) RunDryRun() (*DryRunResult, error) {
+	start := time.Now()
+
+	// 构建上下文时持有周期锁，避免与正常决策周期并发读写持仓跟踪数据
+	if !at.tryLockCycle(dryRunLockTimeout) {
+		return nil, ErrDryRunBusy
+	}
+	ctx, err := at.collectTradingContext(true)
+	model := at.peekModel()
+	at.cycleMutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("构建交易上下文失败: %w", err)
+	}
+
+	at.log().Infof("🧪 [%s] 决策预演：正在请求AI分析（不执行订单）... [模板: %s]", at.name, at.systemPromptTemplate)
+	model.applyPrompt(ctx)
+	fullDecision, err := decision.GetFullDecisionWithCustomPrompt(ctx, model.client, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+
+	result := &DryRunResult{
+		TraderID:       at.id,
+		Timestamp:      start,
+		AIModel:        model.label,
+		PromptTemplate: at.systemPromptTemplate,
+		Decisions:      []DryRunDecision{},
+	}
+	if fullDecision != nil {
+		result.SystemPrompt = fullDecision.SystemPrompt
+		result.InputPrompt = fullDecision.UserPrompt
+		result.CoTTrace = fullDecision.CoTTrace
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("获取AI决策失败: %v", err)
+	} else {
+		signalBias := candidateSignalBias(ctx.CandidateCoins)
+		candidates := candidateSymbolSet(ctx.CandidateCoins)
+		for _, d := range sortDecisionsByPriority(fullDecision.Decisions) {
+			result.Decisions = append(result.Decisions, at.validateDryRunDecision(d, ctx, signalBias, candidates))
+		}
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	at.logDryRun(ctx, fullDecision, result)
+	return result, nil
+}
+
+// validateDryRunDecision 只读校验单个决策：复用执行路径中的风控检查，但不下单、不登记持仓名额
+func (at *AutoTrader) validateDryRunDecision(d decision.Decision, ctx *decision.Context, signalBias map[string]string, candidates map[string]bool) DryRunDecision {
+	result := DryRunDecision{Decision: d, Checks: []DryRunCheck{}}
+	check := func(name string, err error) {
+		c := DryRunCheck{Check: name, Passed: err == nil}
+		if err != nil {
+			c.Message = err.Error()
+		}
+		result.Checks = append(result.Checks, c)
+	}
+
+	switch d.Action {
+	case "open_long", "open_short":
+		side := strings.TrimPrefix(d.Action, "open_")
+		if at.outsideTradingWindow() {
+			check(DryRunCheckOutsideWindow, fmt.Errorf("🕒 交易时间窗口外（%s），不开新仓", at.config.TradingWindow.String()))
+		}
+		check(RejectBlacklisted, at.checkSymbolBlacklist(d.Symbol))
+		check(RejectPositionLimit, at.checkPositionLimits(&d))
+		check(RejectUnfunded, at.checkFundedForOpen())
+		check(RejectDailyTradeLimit, at.checkDailyTradeLimit())
+		check(RejectSignalBias, at.checkSignalBiasIn(signalBias, d.Symbol, side))
+		check(RejectNonCandidate, at.checkCandidateSymbolIn(candidates, d.Symbol))
+
+		var duplicate error
+		if findContextPosition(ctx, d.Symbol, side) != nil {
+			duplicate = fmt.Errorf("❌ %s 已有%s仓，拒绝开仓以防止仓位叠加超限", d.Symbol, sideName(side))
+		}
+		check(RejectPositionExists, duplicate)
+
+		leverage := d.Leverage
+		if leverage <= 0 {
+			leverage = 1
+		}
+		leverage = at.clampLeverage(d.Symbol, leverage)
+		requiredMargin := d.PositionSizeUSD / float64(leverage)
+		estimatedFee := d.PositionSizeUSD * 0.0004
+		var margin error
+		if requiredMargin+estimatedFee > ctx.Account.AvailableBalance {
+			margin = fmt.Errorf("❌ 保证金不足: 需要 %.2f USDT（保证金 %.2f + 手续费 %.2f），可用 %.2f USDT",
+				requiredMargin+estimatedFee, requiredMargin, estimatedFee, ctx.Account.AvailableBalance)
+		}
+		check(RejectInsufficientMargin, margin)
+
+		if balance, err := at.trader.GetBalance(); err == nil {
+			check(RejectExposureLimit, at.checkExposureLimit(d.Symbol, d.PositionSizeUSD, balance))
+		}
+
+		price, err := dryRunCurrentPrice(ctx, d.Symbol, at.timeframes)
+		if err != nil {
+			check(DryRunCheckMarketData, err)
+			break
+		}
+		_, err = at.entryPriceFor(&d, side, price)
+		check(RejectInvalidLimitPrice, err)
+		check(RejectInvalidStops, openStopsError(d, side, price))
+
+	case "close_long", "close_short", "partial_close", "update_stop_loss", "update_take_profit", "set_trailing_stop":
+		side := ""
+		if strings.HasPrefix(d.Action, "close_") {
+			side = strings.TrimPrefix(d.Action, "close_")
+		}
+		pos := findContextPosition(ctx, d.Symbol, side)
+		if pos == nil {
+			check(DryRunCheckPositionMissing, fmt.Errorf("持仓不存在: %s", d.Symbol))
+			break
+		}
+		check(DryRunCheckPositionMissing, nil)
+
+		if d.Action == "close_long" || d.Action == "close_short" || d.Action == "partial_close" {
+			check(RejectPositionLocked, at.checkPositionLocked(d.Action, d.Symbol))
+		}
+
+		if d.Action == "update_stop_loss" || d.Action == "update_take_profit" {
+			check(RejectInvalidStops, updateStopsError(d, pos.Side, pos.MarkPrice))
+		}
+	}
+
+	result.WouldExecute = d.Action != "hold" && d.Action != "wait"
+	for _, c := range result.Checks {
+		if !c.Passed {
+			result.WouldExecute = false
+			break
+		}
+	}
+	return result
+}
+
+// findContextPosition 在交易上下文中查找持仓（side 为空时匹配任意方向）
+func findContextPosition(ctx *decision.Context, symbol, side string) *decision.PositionInfo {
+	for i := range ctx.Positions {
+		pos := &ctx.Positions[i]
+		if pos.Symbol == symbol && (side == "" || pos.Side == side) {
+			return pos
+		}
+	}
+	return nil
+}
+
+// dryRunCurrentPrice 当前价格：优先使用决策时获取的市场数据
+func dryRunCurrentPrice(ctx *decision.Context, symbol string, timeframes []string) (float64, error) {
+	if data, ok := ctx.MarketDataMap[symbol]; ok && data != nil && data.CurrentPrice > 0 {
+		return data.CurrentPrice, nil
+	}
+	data, err := market.Get(symbol, timeframes)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s 当前价格失败: %w", symbol, err)
+	}
+	return data.CurrentPrice, nil
+}
+
+// openStopsError 开仓止损/止盈价格合理性（与开仓执行路径的校验一致）
+func openStopsError(d decision.Decision, side string, price float64) error {
+	if d.StopLoss <= 0 || d.TakeProfit <= 0 {
+		return fmt.Errorf("❌ 止损价 %.4f 和止盈价 %.4f 必须大于 0", d.StopLoss, d.TakeProfit)
+	}
+	if side == "long" && (d.StopLoss >= price || d.TakeProfit <= price) {
+		return fmt.Errorf("❌ 多单止损价 %.4f 必须低于当前价 %.4f，止盈价 %.4f 必须高于当前价", d.StopLoss, price, d.TakeProfit)
+	}
+	if side == "short" && (d.StopLoss <= price || d.TakeProfit >= price) {
+		return fmt.Errorf("❌ 空单止损价 %.4f 必须高于当前价 %.4f，止盈价 %.4f 必须低于当前价", d.StopLoss, price, d.TakeProfit)
+	}
+	return nil
+}
+
+// updateStopsError 调整止损/止盈价格合理性（价格会立即触发时交易所会拒绝）
+func updateStopsError(d decision.Decision, side string, price float64) error {
+	if d.Action == "update_stop_loss" {
+		if d.NewStopLoss <= 0 {
+			return fmt.Errorf("❌ 新止损价 %.4f 必须大于 0", d.NewStopLoss)
+		}
+		if side == "long" && d.NewStopLoss >= price {
+			return fmt.Errorf("多单止损必须低于当前价格 (当前: %.4f, 止损: %.4f)", price, d.NewStopLoss)
+		}
+		if side == "short" && d.NewStopLoss <= price {
+			return fmt.Errorf("空单止损必须高于当前价格 (当前: %.4f, 止损: %.4f)", price, d.NewStopLoss)
+		}
+		return nil
+	}
+	if d.NewTakeProfit <= 0 {
+		return fmt.Errorf("❌ 新止盈价 %.4f 必须大于 0", d.NewTakeProfit)
+	}
+	if side == "long" && d.NewTakeProfit <= price {
+		return fmt.Errorf("多单止盈必须高于当前价格 (当前: %.4f, 止盈: %.4f)", price, d.NewTakeProfit)
+	}
+	if side == "short" && d.NewTakeProfit >= price {
+		return fmt.Errorf("空单止盈必须低于当前价格 (当前: %.4f, 止盈: %.4f)", price, d.NewTakeProfit)
+	}
+	return nil
+}
+
+// sideName 持仓方向的中文名称
+func sideName(side string) string {
+	if side == "short" {
+		return "空"
+	}
+	return "多"
+}
+
+// logDryRun 写入 dry_run 决策记录：不包含执行动作（不影响历史表现分析），校验结果写入执行日志
+func (at *AutoTrader) logDryRun(ctx *decision.Context, fullDecision *decision.FullDecision, result *DryRunResult) {
+	record := &logger.DecisionRecord{
+		Exchange:       at.config.Exchange,
+		DryRun:         true,
+		Success:        result.Error == "",
+		ErrorMessage:   result.Error,
+		AIModel:        result.AIModel,
+		PromptTemplate: result.PromptTemplate,
+		SystemPrompt:   result.SystemPrompt,
+		InputPrompt:    result.InputPrompt,
+		CoTTrace:       result.CoTTrace,
+		ExecutionLog:   []string{"🧪 决策预演（dry run）：未执行任何订单"},
+		AccountState: logger.AccountSnapshot{
+			TotalBalance:          ctx.Account.TotalEquity - ctx.Account.UnrealizedPnL,
+			AvailableBalance:      ctx.Account.AvailableBalance,
+			TotalUnrealizedProfit: ctx.Account.UnrealizedPnL,
+			PositionCount:         ctx.Account.PositionCount,
+			MarginUsedPct:         ctx.Account.MarginUsedPct,
+			InitialBalance:        at.initialBalance,
+		},
+	}
+	for _, coin := range ctx.CandidateCoins {
+		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
+	}
+	if fullDecision != nil {
+		record.PromptVersion = fullDecision.PromptVersion
+		record.AIRequestDurationMs = fullDecision.AIRequestDurationMs
+		if usage := fullDecision.Usage; usage != nil {
+			record.PromptTokens = usage.PromptTokens
+			record.CompletionTokens = usage.CompletionTokens
+			record.TotalTokens = usage.TotalTokens
+			record.AICost = usage.Cost
+		}
+		if len(fullDecision.Decisions) > 0 {
+			decisionJSON, _ := json.MarshalIndent(fullDecision.Decisions, "", "  ")
+			record.DecisionJSON = string(decisionJSON)
+		}
+	}
+	for _, d := range result.Decisions {
+		status := "✓ 校验通过"
+		if !d.WouldExecute {
+			status = "⏭ 不执行"
+		}
+		var failed []string
+		for _, c := range d.Checks {
+			if !c.Passed {
+				failed = append(failed, fmt.Sprintf("%s: %s", c.Check, c.Message))
+			}
+		}
+		if len(failed) > 0 {
+			status = "❌ " + strings.Join(failed, "; ")
+		}
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧪 %s %s %s", d.Symbol, d.Action, status))
+	}
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		at.log().Warnf("⚠ 保存决策预演记录失败: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/market"
+	"testing"
+	"time"
+)
+
+// dryRunFailedChecks 决策预演中未通过的校验项
+func dryRunFailedChecks(r DryRunDecision) map[string]bool {
+	failed := make(map[string]bool)
+	for _, c := range r.Checks {
+		if !c.Passed {
+			failed[c.Check] = true
+		}
+	}
+	return failed
+}
+
+// TestValidateDryRunDecision 测试决策预演的只读校验：重复持仓、保证金、止损方向、目标持仓不存在
+func TestValidateDryRunDecision(t *testing.T) {
+	mockTrader := &MockTrader{}
+	at := &AutoTrader{name: "dry-run", trader: mockTrader, lastResetTime: time.Now()}
+	ctx := &decision.Context{
+		Account: decision.AccountInfo{AvailableBalance: 100},
+		Positions: []decision.PositionInfo{
+			{Symbol: "BTCUSDT", Side: "long", MarkPrice: 60000},
+		},
+		MarketDataMap: map[string]*market.Data{
+			"BTCUSDT": {Symbol: "BTCUSDT", CurrentPrice: 60000},
+			"ETHUSDT": {Symbol: "ETHUSDT", CurrentPrice: 3000},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		d      decision.Decision
+		failed string
+	}{
+		{"重复持仓", decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100, StopLoss: 59000, TakeProfit: 62000}, RejectPositionExists},
+		{"保证金不足", decision.Decision{Symbol: "ETHUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 5000, StopLoss: 3100, TakeProfit: 2900}, RejectInsufficientMargin},
+		{"开仓止损方向错误", decision.Decision{Symbol: "ETHUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100, StopLoss: 3100, TakeProfit: 3200}, RejectInvalidStops},
+		{"调整止损方向错误", decision.Decision{Symbol: "BTCUSDT", Action: "update_stop_loss", NewStopLoss: 61000}, RejectInvalidStops},
+		{"平仓目标不存在", decision.Decision{Symbol: "BTCUSDT", Action: "close_short"}, DryRunCheckPositionMissing},
+	}
+	for _, tc := range cases {
+		result := at.validateDryRunDecision(tc.d, ctx, nil, nil)
+		if result.WouldExecute || !dryRunFailedChecks(result)[tc.failed] {
+			t.Errorf("%s: 应未通过 %s 校验，实际 %+v", tc.name, tc.failed, result.Checks)
+		}
+	}
+
+	ok := at.validateDryRunDecision(decision.Decision{Symbol: "ETHUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100, StopLoss: 2900, TakeProfit: 3200}, ctx, nil, nil)
+	if !ok.WouldExecute {
+		t.Errorf("合法开仓应通过所有校验，实际 %+v", ok.Checks)
+	}
+	if mockTrader.orderCalls != 0 {
+		t.Errorf("决策预演不应下单，实际 %d 次", mockTrader.orderCalls)
+	}
+}
+
+// TestRejectionFeedbackPeek 测试决策预演读取失败反馈不会消耗下一周期的反馈
+func TestRejectionFeedbackPeek(t *testing.T) {
+	f := &rejectionFeedback{}
+	f.add(pendingRejection{symbol: "BTCUSDT", action: "open_long", code: RejectInsufficientMargin, at: time.Now()})
+
+	if got := f.peek(); len(got) != 1 {
+		t.Fatalf("peek 应返回 1 条，实际 %d", len(got))
+	}
+	if got := f.drain(); len(got) != 1 {
+		t.Fatalf("peek 后 drain 仍应返回 1 条，实际 %d", len(got))
+	}
+	if got := f.peek(); len(got) != 0 {
+		t.Errorf("drain 后应已清空，实际 %d", len(got))
+	}
+}
@@ -0,0 +1,179 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/netproxy"
+	"strings"
+)
+
+// redactSecret 脱敏密钥，只保留末4位用于确认是哪一个密钥
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+// redactURLQuery 隐藏URL查询参数（信号源地址通常在参数中携带 auth token）
+func redactURLQuery(rawURL string) string {
+	if idx := strings.Index(rawURL, "?"); idx >= 0 {
+		return rawURL[:idx] + "?****"
+	}
+	return rawURL
+}
+
+// activePromptTemplate 返回实际生效的提示词模板名称（模板不存在时与决策引擎一样回退到 default）
+func (at *AutoTrader) activePromptTemplate() (string, bool) {
+	name := at.systemPromptTemplate
+	if name == "" {
+		name = "default"
+	}
+	if _, err := decision.GetPromptTemplate(name); err != nil {
+		return "default", true
+	}
+	return name, false
+}
+
+// GetEffectiveConfig 获取运行中交易器实际使用的配置（已应用默认值和热更新，密钥已脱敏）
+func (at *AutoTrader) GetEffectiveConfig() map[string]interface{} {
+	cfg := at.config
+	activeTemplate, templateFallback := at.activePromptTemplate()
+
+	modelPool := make([]string, 0, len(at.modelPool))
+	for _, m := range at.modelPool {
+		modelPool = append(modelPool, m.label)
+	}
+
+	timeframes := at.timeframes
+	if timeframes == nil {
+		timeframes = []string{}
+	}
+	indicators := cfg.Indicators
+	if indicators == nil {
+		indicators = []string{}
+	}
+
+	return map[string]interface{}{
+		"trader_id": at.id,
+		"name":      at.name,
+		"exchange":  at.exchange,
+
+		// AI
+		"ai_model":          at.aiModel,
+		"custom_api_url":    cfg.CustomAPIURL,
+		"custom_model_name": cfg.CustomModelName,
+		"deepseek_key":      redactSecret(cfg.DeepSeekKey),
+		"qwen_key":          redactSecret(cfg.QwenKey),
+		"custom_api_key":    redactSecret(cfg.CustomAPIKey),
+		"model_pool":        modelPool,
+		"model_pool_mode":   at.modelPoolMode,
+		"fallback_model":    at.fallbackModelLabel(),
+
+		// 交易所凭证
+		"binance_api_key":         redactSecret(cfg.BinanceAPIKey),
+		"binance_secret_key":      redactSecret(cfg.BinanceSecretKey),
+		"binance_testnet":         cfg.BinanceTestnet,
+		"hyperliquid_private_key": redactSecret(cfg.HyperliquidPrivateKey),
+		"hyperliquid_wallet_addr": cfg.HyperliquidWalletAddr,
+		"hyperliquid_testnet":     cfg.HyperliquidTestnet,
+		"aster_user":              cfg.AsterUser,
+		"aster_signer":            cfg.AsterSigner,
+		"aster_private_key":       redactSecret(cfg.AsterPrivateKey),
+		"okx_api_key":             redactSecret(cfg.OKXAPIKey),
+		"okx_secret_key":          redactSecret(cfg.OKXSecretKey),
+		"okx_passphrase":          redactSecret(cfg.OKXPassphrase),
+		"okx_testnet":             cfg.OKXTestnet,
+		"outbound_proxy":          netproxy.Redact(cfg.OutboundProxy),
+
+		// 交易参数
+		"scan_interval":          cfg.ScanInterval.String(),
+		"scan_interval_minutes":  int(cfg.ScanInterval.Minutes()),
+		"scan_jitter_pct":        cfg.ScanJitterPct,
+		"initial_balance":        at.initialBalance,
+		"btc_eth_leverage":       cfg.BTCETHLeverage,
+		"altcoin_leverage":       cfg.AltcoinLeverage,
+		"taker_fee_rate":         cfg.TakerFeeRate,
+		"maker_fee_rate":         cfg.MakerFeeRate,
+		"is_cross_margin":        cfg.IsCrossMargin,
+		"order_strategy":         cfg.OrderStrategy,
+		"limit_price_offset":     cfg.LimitPriceOffset,
+		"limit_timeout_seconds":  cfg.LimitTimeoutSeconds,
+		"timeframes":             timeframes,
+		"kline_limit":            cfg.KlineLimit,
+		"indicators":             indicators,
+		"max_trades_per_day":     cfg.MaxTradesPerDay,
+		"max_exposure_multiple":  cfg.MaxExposureMultiple,
+		"respect_signal_bias":    cfg.RespectSignalBias,
+		"dry_run":                cfg.DryRun,
+		"log_level":              cfg.LogLevel,
+		"alert_drawdown_pct":     cfg.AlertDrawdownPct,
+		"alert_daily_loss_pct":   cfg.AlertDailyLossPct,
+		"sl_tp_tolerance_pct":    at.stopUpdateTolerancePct(),
+		"strict_price_check_usd": cfg.StrictPriceCheckNotional,
+		"hold_cache_pct":         cfg.HoldCachePct,
+
+		// 风控
+		"max_daily_loss":             cfg.MaxDailyLoss,
+		"max_drawdown":               cfg.MaxDrawdown,
+		"safety_stop_pct":            cfg.SafetyStopPct,
+		"stop_trading_time":          cfg.StopTradingTime.String(),
+		"risk_limit_sources":         riskLimitSources(cfg),
+		"ai_quality_window":          cfg.AIQualityWindow,
+		"ai_quality_max_failure_pct": cfg.AIQualityMaxFailurePct,
+		"ai_quality_pause_minutes":   int(cfg.AIQualityPause.Minutes()),
+		"max_consecutive_errors":     cfg.MaxConsecutiveErrors,
+		"daily_report":               cfg.DailyReport,
+		"unfunded_threshold":         cfg.UnfundedThreshold,
+		"max_ai_calls_per_day":       cfg.MaxAICallsPerDay,
+		"reject_non_candidates":      cfg.RejectNonCandidates,
+		"open_verify_delay_ms":       int(cfg.OpenVerifyDelay.Milliseconds()),
+		"trading_window":             cfg.TradingWindow.String(),
+		"flatten_on_window_close":    cfg.FlattenOnWindowClose,
+		"max_positions":              cfg.MaxPositions,
+		"max_position_size_usd":      cfg.MaxPositionSizeUSD,
+		"portfolio_group":            portfolioGroupName(at.portfolio.Load()),
+		"symbol_cap_enforced":        at.symbolRegistry != nil,
+
+		// 币种
+		"default_coins":       cfg.DefaultCoins,
+		"trading_coins":       at.tradingCoins,
+		"blacklisted_symbols": cfg.BlacklistedSymbols,
+		"use_coin_pool":       at.useCoinPool,
+		"use_oi_top":          at.useOITop,
+		"coin_pool_api_url":   redactURLQuery(at.coinPoolAPIURL),
+		"oi_top_api_url":      redactURLQuery(at.oiTopAPIURL),
+
+		// 提示词
+		"system_prompt_template":   at.systemPromptTemplate,
+		"active_prompt_template":   activeTemplate,
+		"prompt_template_fallback": templateFallback,
+		"custom_prompt":            at.customPrompt,
+		"override_base_prompt":     at.overrideBasePrompt,
+
+		// 日志与持久化
+		"decision_compact_after": cfg.DecisionCompactAfter.String(),
+		"decision_compact_mode":  cfg.DecisionCompactMode,
+		"decision_retention":     cfg.DecisionRetention,
+		"persist_max_retries":    defaultPersistMaxRetries,
+		"persist_retry_interval": defaultPersistRetryInterval.String(),
+		"dead_letter_dir":        defaultDeadLetterDir,
+	}
+}
+
+// riskLimitSources 各风控阈值的来源（trader=交易员级覆盖，system=系统配置）
+func riskLimitSources(cfg AutoTraderConfig) map[string]string {
+	source := func(s string) string {
+		if s == RiskLimitSourceTrader {
+			return RiskLimitSourceTrader
+		}
+		return RiskLimitSourceSystem
+	}
+	return map[string]string{
+		"max_daily_loss":    source(cfg.MaxDailyLossSource),
+		"max_drawdown":      source(cfg.MaxDrawdownSource),
+		"stop_trading_time": source(cfg.StopTradingTimeSource),
+	}
+}
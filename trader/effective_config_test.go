@@ -0,0 +1,55 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGetEffectiveConfigRedactsSecrets 测试运行配置中的密钥已脱敏，并返回解析后的配置
+func TestGetEffectiveConfigRedactsSecrets(t *testing.T) {
+	at := &AutoTrader{
+		id:      "trader_1",
+		name:    "effective",
+		aiModel: "deepseek",
+		config: AutoTraderConfig{
+			DeepSeekKey:      "sk-deepseek-1234567890abcd",
+			BinanceAPIKey:    "binance-api-key-WXYZ",
+			BinanceSecretKey: "binance-secret-key-9876",
+			CustomAPIKey:     "short",
+			BTCETHLeverage:   5,
+			AltcoinLeverage:  3,
+			ScanInterval:     3 * time.Minute,
+		},
+		timeframes:           []string{"15m", "4h"},
+		systemPromptTemplate: "template_that_does_not_exist",
+		coinPoolAPIURL:       "https://example.com/api/ai500/list?auth=secret-token",
+	}
+
+	cfg := at.GetEffectiveConfig()
+
+	for _, key := range []string{"deepseek_key", "binance_api_key", "binance_secret_key", "custom_api_key"} {
+		value, _ := cfg[key].(string)
+		if !strings.HasPrefix(value, "****") {
+			t.Errorf("%s 应脱敏, 实际 %q", key, value)
+		}
+	}
+	if cfg["deepseek_key"] != "****abcd" || cfg["custom_api_key"] != "****" {
+		t.Errorf("脱敏格式不正确: %v / %v", cfg["deepseek_key"], cfg["custom_api_key"])
+	}
+	if strings.Contains(cfg["coin_pool_api_url"].(string), "secret-token") {
+		t.Error("信号源地址中的 token 应被隐藏")
+	}
+
+	if tfs, ok := cfg["timeframes"].([]string); !ok || len(tfs) != 2 || tfs[1] != "4h" {
+		t.Errorf("时间线应为解析后的切片: %v", cfg["timeframes"])
+	}
+	if cfg["scan_interval_minutes"] != 3 || cfg["btc_eth_leverage"] != 5 {
+		t.Errorf("扫描间隔/杠杆不正确: %v / %v", cfg["scan_interval_minutes"], cfg["btc_eth_leverage"])
+	}
+
+	// 模板不存在时与决策引擎一样回退到 default
+	if cfg["active_prompt_template"] != "default" || cfg["prompt_template_fallback"] != true {
+		t.Errorf("不存在的模板应回退到 default: %v", cfg["active_prompt_template"])
+	}
+}
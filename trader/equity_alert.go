@@ -0,0 +1,76 @@
+package trader
+
+import (
+	"nofx/webhook"
+)
+
+// 净值预警类型
+const (
+	equityAlertDrawdown  = "drawdown"   // 净值较峰值回撤
+	equityAlertDailyLoss = "daily_loss" // 当日亏损
+)
+
+// equityAlertRearmRatio 预警触发后，指标回落到阈值的该比例以下才重新布防（滞回，避免在阈值附近反复推送）
+const equityAlertRearmRatio = 0.8
+
+// checkEquityAlerts 检查净值预警阈值，越过阈值时推送通知但不暂停交易，给硬性风控之前留出提前量
+// 同一类预警触发后需回落到重新布防线以下才会再次推送，返回本次触发的预警类型
+func (at *AutoTrader) checkEquityAlerts(currentEquity float64) []string {
+	var fired []string
+
+	if threshold := at.config.AlertDrawdownPct; threshold > 0 && at.peakEquity > 0 {
+		drawdownPct := (at.peakEquity - currentEquity) / at.peakEquity * 100
+		if at.evaluateEquityAlert(equityAlertDrawdown, drawdownPct, threshold) {
+			at.log().Infof("🔔 [%s] 净值预警：较峰值回撤 %.2f%% ≥ %.2f%% (峰值 %.2f → 当前 %.2f)",
+				at.name, drawdownPct, threshold, at.peakEquity, currentEquity)
+			at.emitWebhook(webhook.EventEquityAlert, map[string]interface{}{
+				"kind":           equityAlertDrawdown,
+				"threshold_pct":  threshold,
+				"value_pct":      drawdownPct,
+				"peak_equity":    at.peakEquity,
+				"current_equity": currentEquity,
+			})
+			fired = append(fired, equityAlertDrawdown)
+		}
+	}
+
+	if threshold := at.config.AlertDailyLossPct; threshold > 0 && at.dailyPnLBase > 0 {
+		lossPct := -at.dailyPnL / at.dailyPnLBase * 100
+		if at.evaluateEquityAlert(equityAlertDailyLoss, lossPct, threshold) {
+			at.log().Infof("🔔 [%s] 净值预警：当日亏损 %.2f%% ≥ %.2f%% (盈亏 %.2f / 基准 %.2f USDT)",
+				at.name, lossPct, threshold, at.dailyPnL, at.dailyPnLBase)
+			at.emitWebhook(webhook.EventEquityAlert, map[string]interface{}{
+				"kind":           equityAlertDailyLoss,
+				"threshold_pct":  threshold,
+				"value_pct":      lossPct,
+				"daily_pnl":      at.dailyPnL,
+				"current_equity": currentEquity,
+			})
+			fired = append(fired, equityAlertDailyLoss)
+		}
+	}
+
+	return fired
+}
+
+// evaluateEquityAlert 按滞回规则更新预警状态：未触发且越过阈值时返回 true；
+// 已触发时只在指标回落到阈值 × equityAlertRearmRatio 以下后重新布防
+func (at *AutoTrader) evaluateEquityAlert(kind string, value, threshold float64) bool {
+	if at.equityAlertActive == nil {
+		at.equityAlertActive = make(map[string]bool)
+	}
+
+	if at.equityAlertActive[kind] {
+		if value < threshold*equityAlertRearmRatio {
+			delete(at.equityAlertActive, kind)
+			at.log().Infof("🔔 [%s] 净值预警 %s 已恢复 (%.2f%%)，重新布防", at.name, kind, value)
+		}
+		return false
+	}
+
+	if value >= threshold {
+		at.equityAlertActive[kind] = true
+		return true
+	}
+	return false
+}
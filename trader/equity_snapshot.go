@@ -0,0 +1,38 @@
+package trader
+
+import (
+	"nofx/config"
+	"nofx/decision"
+	"time"
+)
+
+// equitySnapshotRecorder 净值快照写入接口（数据库以鸭子类型注入）
+type equitySnapshotRecorder interface {
+	SaveEquitySnapshot(snapshot *config.EquitySnapshot) error
+}
+
+// recordEquitySnapshot 每个决策周期写入一条净值快照，供收益率曲线查询
+func (at *AutoTrader) recordEquitySnapshot(account decision.AccountInfo) {
+	db, ok := at.database.(equitySnapshotRecorder)
+	if !ok {
+		return
+	}
+
+	snapshot := &config.EquitySnapshot{
+		TraderID:         at.id,
+		Timestamp:        time.Now(),
+		TotalEquity:      account.TotalEquity,
+		AvailableBalance: account.AvailableBalance,
+		UnrealizedPnL:    account.UnrealizedPnL,
+		PositionCount:    account.PositionCount,
+		MarginUsedPct:    account.MarginUsedPct,
+		InitialBalance:   at.initialBalance,
+		CycleNumber:      at.callCount,
+	}
+	if at.initialBalance > 0 {
+		snapshot.TotalPnL = account.TotalEquity - at.initialBalance
+	}
+	if err := db.SaveEquitySnapshot(snapshot); err != nil {
+		at.log().Warnf("⚠️ [%s] 保存净值快照失败: %v", at.name, err)
+	}
+}
@@ -0,0 +1,47 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxExposureMultipleLimit 总敞口倍数配置的上限
+const MaxExposureMultipleLimit = 100.0
+
+// accountExposure 计算当前总敞口（各持仓名义价值之和，数量×标记价格）和账户净值（钱包余额+未实现盈亏）
+func accountExposure(positions []map[string]interface{}, balance map[string]interface{}) (exposure, equity float64) {
+	for _, pos := range positions {
+		quantity, _ := pos["positionAmt"].(float64)
+		markPrice, _ := pos["markPrice"].(float64)
+		exposure += math.Abs(quantity) * markPrice
+	}
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+	return exposure, wallet + unrealized
+}
+
+// checkExposureLimit 检查开仓后的总敞口是否超过账户净值的 MaxExposureMultiple 倍（0=不限制）
+// 名义价值已包含杠杆效果，AI 无法通过提高单笔杠杆绕过该上限；无法获取持仓时拒绝开仓
+func (at *AutoTrader) checkExposureLimit(symbol string, addNotional float64, balance map[string]interface{}) error {
+	limit := at.config.MaxExposureMultiple
+	if limit <= 0 {
+		return nil
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return fmt.Errorf("❌ %s 无法获取持仓计算总敞口，拒绝开仓: %w", symbol, err)
+	}
+	exposure, equity := accountExposure(positions, balance)
+	if equity <= 0 {
+		return fmt.Errorf("❌ %s 账户净值 %.2f USDT 无效，无法计算总敞口，拒绝开仓", symbol, equity)
+	}
+
+	after := (exposure + addNotional) / equity
+	if after > limit {
+		return fmt.Errorf("❌ %s 开仓后总敞口 %.2f USDT 为账户净值 %.2f USDT 的 %.2f 倍，超过上限 %.2f 倍（当前 %.2f 倍），拒绝开仓",
+			symbol, exposure+addNotional, equity, after, limit, exposure/equity)
+	}
+	at.log().Infof("  📊 总敞口检查通过: 开仓后 %.2f 倍（上限 %.2f 倍）", after, limit)
+	return nil
+}
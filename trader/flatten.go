@@ -0,0 +1,146 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// FlattenResult 停止交易员时平仓的结果
+type FlattenResult struct {
+	Closed   int      `json:"closed"`             // 成功平仓的持仓数
+	Failures []string `json:"failures,omitempty"` // 平仓或撤单失败的说明
+}
+
+// StopAndFlatten 停止交易员并平掉本交易员的全部持仓、撤销这些币种的挂单，平仓以 MANUAL_CLOSE 记入交易历史
+// 先停止决策循环和监控协程再平仓，避免平仓过程中新的决策周期重新开仓
+func (at *AutoTrader) StopAndFlatten() FlattenResult {
+	at.Stop()
+	at.cycleMutex.Lock()
+	defer at.cycleMutex.Unlock()
+	return at.closeAllOnStop()
+}
+
+// closeAllOnStop 平掉本交易员的持仓并撤销这些币种的挂单
+// 多个交易员可能共用同一个交易所账户，只处理交易历史中属于本交易员的持仓，不影响其他交易员的持仓和止损单
+func (at *AutoTrader) closeAllOnStop() FlattenResult {
+	var result FlattenResult
+
+	owned, err := at.ownedPositionKeys()
+	if err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("获取本交易员持仓记录失败: %v", err))
+		return result
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("获取持仓失败: %v", err))
+		return result
+	}
+
+	symbols := make(map[string]bool)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		quantity, _ := pos["positionAmt"].(float64)
+		quantity = math.Abs(quantity)
+		if symbol == "" || quantity == 0 {
+			continue
+		}
+		if owned != nil && !owned[symbol+"_"+strings.ToUpper(side)] {
+			continue // 其他交易员的持仓
+		}
+		symbols[symbol] = true
+
+		if err := at.manualClosePosition(pos, symbol, side, quantity); err != nil {
+			at.log().Errorf("  ❌ [%s] 停止时平仓失败 %s %s: %v", at.name, symbol, side, err)
+			result.Failures = append(result.Failures, fmt.Sprintf("%s %s: %v", symbol, side, err))
+			continue
+		}
+		if !at.config.DryRun {
+			result.Closed++
+		}
+	}
+
+	// 撤销本交易员持仓币种的剩余挂单（止损止盈单），其他币种的挂单可能属于其他交易员
+	if !at.config.DryRun {
+		for symbol := range symbols {
+			if err := at.trader.CancelAllOrders(symbol); err != nil {
+				result.Failures = append(result.Failures, fmt.Sprintf("%s 撤单失败: %v", symbol, err))
+			}
+		}
+	}
+
+	at.log().Infof("🧹 [%s] 停止时平仓完成: 平仓 %d 个，失败 %d 个", at.name, result.Closed, len(result.Failures))
+	return result
+}
+
+// ownedPositionKeys 交易历史中本交易员未平仓的持仓（SYMBOL_SIDE，方向大写）
+// 数据库不支持查询时返回 nil（视为账户上的持仓都属于本交易员）
+func (at *AutoTrader) ownedPositionKeys() (map[string]bool, error) {
+	db, ok := at.database.(interface {
+		GetOpenPositions(string) ([]string, error)
+	})
+	if !ok {
+		at.log().Warnf("  ⚠️ [%s] 无法查询本交易员的持仓记录，将平掉账户上的全部持仓", at.name)
+		return nil, nil
+	}
+	keys, err := db.GetOpenPositions(at.config.ID)
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		owned[key] = true
+	}
+	return owned, nil
+}
+
+// manualClosePosition 平掉单个持仓并记录 MANUAL_CLOSE 交易
+func (at *AutoTrader) manualClosePosition(pos map[string]interface{}, symbol, side string, quantity float64) error {
+	entryPrice, _ := pos["entryPrice"].(float64)
+	markPrice, _ := pos["markPrice"].(float64)
+	const reason = "停止交易员时平仓"
+
+	if at.dryRunSkip(nil, "停止时平仓 %s %s 数量 %.4f", symbol, side, quantity) {
+		return nil
+	}
+
+	var order map[string]interface{}
+	var err error
+	switch side {
+	case "long":
+		order, err = at.trader.CloseLong(symbol, 0) // 0 = 全部平仓
+	case "short":
+		order, err = at.trader.CloseShort(symbol, 0)
+	default:
+		return fmt.Errorf("未知的持仓方向: %s", side)
+	}
+	if err != nil {
+		return err
+	}
+	at.log().Infof("  ✅ [%s] 停止时平仓 %s %s 成功，订单ID: %v", at.name, symbol, side, order["orderId"])
+
+	at.ClearPeakPnLCache(symbol, side)
+	at.releaseSymbolSlot(symbol, side)
+	at.emitCloseEvent(symbol, side, quantity, entryPrice, markPrice, false, reason)
+
+	if db, ok := at.database.(tradeRecorder); ok {
+		pnl := (markPrice - entryPrice) * quantity
+		if side == "short" {
+			pnl = -pnl
+		}
+		pnlPct := 0.0
+		if entryPrice > 0 {
+			pnlPct = pnl / (entryPrice * quantity) * 100
+		}
+		if err := at.recordTradeWithRetry(db,
+			at.config.ID, at.userID, symbol, strings.ToUpper(side), "MANUAL_CLOSE",
+			quantity, markPrice, reason,
+			0, 0, pnl, pnlPct,
+		); err != nil {
+			at.log().Warnf("  ⚠️ 記錄平倉到數據庫失敗: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package trader
+
+import (
+	"sort"
+	"testing"
+)
+
+// flattenRecorder 记录平仓交易的模拟数据库
+type flattenRecorder struct {
+	open    []string // 交易历史中本交易员未平仓的持仓（SYMBOL_SIDE）
+	actions []string
+	pnls    []float64
+}
+
+func (f *flattenRecorder) GetOpenPositions(traderID string) ([]string, error) {
+	return f.open, nil
+}
+
+func (f *flattenRecorder) RecordTrade(traderID, userID, symbol, side, action string, quantity, price float64, reason string, stopLoss, takeProfit, pnl, pnlPercent float64) error {
+	f.actions = append(f.actions, symbol+"_"+side+"_"+action)
+	f.pnls = append(f.pnls, pnl)
+	return nil
+}
+
+// flattenMockTrader 记录按币种撤单和平仓的模拟交易所
+type flattenMockTrader struct {
+	*MockTrader
+	closed    []string
+	cancelled []string
+}
+
+func (m *flattenMockTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	m.closed = append(m.closed, symbol+"_long")
+	return m.MockTrader.CloseLong(symbol, quantity)
+}
+
+func (m *flattenMockTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	m.closed = append(m.closed, symbol+"_short")
+	return m.MockTrader.CloseShort(symbol, quantity)
+}
+
+func (m *flattenMockTrader) CancelAllOrders(symbol string) error {
+	m.cancelled = append(m.cancelled, symbol)
+	return nil
+}
+
+func newFlattenMock() *flattenMockTrader {
+	return &flattenMockTrader{MockTrader: &MockTrader{
+		positions: []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 51000.0},
+			{"symbol": "ETHUSDT", "side": "short", "positionAmt": -2.0, "entryPrice": 3000.0, "markPrice": 3100.0},
+			// 共用交易所账户的其他交易员的持仓
+			{"symbol": "SOLUSDT", "side": "long", "positionAmt": 10.0, "entryPrice": 100.0, "markPrice": 110.0},
+		},
+		shouldFailCloseShort: true,
+	}}
+}
+
+// TestStopAndFlatten 测试停止时只平掉本交易员的持仓、只撤销这些币种的挂单并记录 MANUAL_CLOSE
+func TestStopAndFlatten(t *testing.T) {
+	mock := newFlattenMock()
+	db := &flattenRecorder{open: []string{"BTCUSDT_LONG", "ETHUSDT_SHORT"}}
+	at := &AutoTrader{name: "flatten", trader: mock, database: db, stopMonitorCh: make(chan struct{})}
+
+	result := at.StopAndFlatten()
+	if result.Closed != 1 || len(result.Failures) != 1 {
+		t.Fatalf("期望平仓 1 个、失败 1 个, 实际 %+v", result)
+	}
+	if len(db.actions) != 1 || db.actions[0] != "BTCUSDT_LONG_MANUAL_CLOSE" {
+		t.Errorf("期望记录 BTCUSDT_LONG_MANUAL_CLOSE, 实际 %v", db.actions)
+	}
+	if db.pnls[0] != 100 {
+		t.Errorf("期望盈亏 100, 实际 %.2f", db.pnls[0])
+	}
+	for _, c := range mock.closed {
+		if c == "SOLUSDT_long" {
+			t.Error("不应平掉其他交易员的持仓")
+		}
+	}
+	sort.Strings(mock.cancelled)
+	if len(mock.cancelled) != 2 || mock.cancelled[0] != "BTCUSDT" || mock.cancelled[1] != "ETHUSDT" {
+		t.Errorf("只应撤销本交易员持仓币种的挂单, 实际 %v", mock.cancelled)
+	}
+}
+
+// TestStopAndFlattenDryRun 测试模拟运行时不下单、不计入平仓数量
+func TestStopAndFlattenDryRun(t *testing.T) {
+	mock := newFlattenMock()
+	db := &flattenRecorder{open: []string{"BTCUSDT_LONG"}}
+	at := &AutoTrader{name: "flatten", trader: mock, database: db, config: AutoTraderConfig{DryRun: true}, stopMonitorCh: make(chan struct{})}
+
+	result := at.StopAndFlatten()
+	if result.Closed != 0 || len(result.Failures) != 0 {
+		t.Errorf("模拟运行不应计入平仓数量, 实际 %+v", result)
+	}
+	if len(mock.closed) != 0 || len(mock.cancelled) != 0 || len(db.actions) != 0 {
+		t.Errorf("模拟运行不应下单或撤单: closed=%v cancelled=%v actions=%v", mock.closed, mock.cancelled, db.actions)
+	}
+}
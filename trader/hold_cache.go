@@ -0,0 +1,107 @@
+package trader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"nofx/decision"
+	"sort"
+	"strings"
+)
+
+// MaxHoldCachePct 持有决策缓存阈值上限（价格变动百分比）
+const MaxHoldCachePct = 5.0
+
+// holdDecisionCache 上一次全部为 hold/wait 的AI决策及调用时的市场快照
+type holdDecisionCache struct {
+	fingerprint string             // 持仓、挂单和候选币种的内容哈希
+	prices      map[string]float64 // AI调用时各币种的价格（命中缓存时不更新，避免价格缓慢漂移后一直复用）
+	decision    *decision.FullDecision
+	model       string
+}
+
+// contextFingerprint 计算交易上下文中结构性内容的哈希，持仓、挂单或候选币种变化时缓存失效
+func contextFingerprint(ctx *decision.Context) string {
+	parts := make([]string, 0, len(ctx.Positions)+len(ctx.OpenOrders)+len(ctx.CandidateCoins))
+	for _, pos := range ctx.Positions {
+		parts = append(parts, fmt.Sprintf("pos:%s:%s:%g:%d:%g:%g", pos.Symbol, pos.Side, pos.Quantity, pos.Leverage, pos.StopLoss, pos.TakeProfit))
+	}
+	for _, order := range ctx.OpenOrders {
+		parts = append(parts, fmt.Sprintf("order:%s:%s:%s:%g:%g:%g", order.Symbol, order.Type, order.PositionSide, order.Quantity, order.Price, order.StopPrice))
+	}
+	for _, coin := range ctx.CandidateCoins {
+		parts = append(parts, "coin:"+coin.Symbol)
+	}
+	sort.Strings(parts)
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// contextPrices 提取上下文中各币种的当前价格
+func contextPrices(ctx *decision.Context) map[string]float64 {
+	prices := make(map[string]float64, len(ctx.MarketDataMap))
+	for symbol, data := range ctx.MarketDataMap {
+		if data != nil && data.CurrentPrice > 0 {
+			prices[symbol] = data.CurrentPrice
+		}
+	}
+	return prices
+}
+
+// isAllHold 判断决策是否全部为 hold/wait（没有任何需要执行的动作）
+func isAllHold(decisions []decision.Decision) bool {
+	for _, d := range decisions {
+		if d.Action != "hold" && d.Action != "wait" {
+			return false
+		}
+	}
+	return true
+}
+
+// cachedHoldDecision 上下文结构未变且所有币种价格变动都在 HoldCachePct 以内时，返回可复用的持有决策
+func (at *AutoTrader) cachedHoldDecision(ctx *decision.Context) (*decision.FullDecision, string, bool) {
+	threshold := at.config.HoldCachePct
+	cache := at.holdCache
+	if threshold <= 0 || cache == nil {
+		return nil, "", false
+	}
+	if contextFingerprint(ctx) != cache.fingerprint {
+		return nil, "", false
+	}
+
+	prices := contextPrices(ctx)
+	if len(prices) != len(cache.prices) {
+		return nil, "", false
+	}
+	for symbol, cachedPrice := range cache.prices {
+		price, ok := prices[symbol]
+		if !ok || math.Abs(price-cachedPrice)/cachedPrice*100 > threshold {
+			return nil, "", false
+		}
+	}
+
+	// 复用的决策没有新的AI调用耗时和 token 用量
+	reused := *cache.decision
+	reused.AIRequestDurationMs = 0
+	reused.Usage = nil
+	return &reused, cache.model, true
+}
+
+// updateHoldCache AI返回全部持有时缓存决策和市场快照，否则清空缓存
+func (at *AutoTrader) updateHoldCache(ctx *decision.Context, fullDecision *decision.FullDecision, model string, err error) {
+	if at.config.HoldCachePct <= 0 {
+		return
+	}
+	if err != nil || fullDecision == nil || !isAllHold(fullDecision.Decisions) {
+		at.holdCache = nil
+		return
+	}
+	at.holdCache = &holdDecisionCache{
+		fingerprint: contextFingerprint(ctx),
+		prices:      contextPrices(ctx),
+		decision:    fullDecision,
+		model:       model,
+	}
+}
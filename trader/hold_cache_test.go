@@ -0,0 +1,64 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/market"
+	"testing"
+)
+
+func holdCacheContext(btcPrice float64, positions ...decision.PositionInfo) *decision.Context {
+	return &decision.Context{
+		Positions:      positions,
+		CandidateCoins: []decision.CandidateCoin{{Symbol: "BTCUSDT"}, {Symbol: "ETHUSDT"}},
+		MarketDataMap: map[string]*market.Data{
+			"BTCUSDT": {Symbol: "BTCUSDT", CurrentPrice: btcPrice},
+			"ETHUSDT": {Symbol: "ETHUSDT", CurrentPrice: 3000},
+		},
+	}
+}
+
+// TestHoldDecisionCache 测试价格变动阈值内复用持有决策，超出阈值或持仓变化时重新调用AI
+func TestHoldDecisionCache(t *testing.T) {
+	at := &AutoTrader{name: "cache"}
+	at.config.HoldCachePct = 0.5
+
+	holds := &decision.FullDecision{
+		Decisions:           []decision.Decision{{Symbol: "BTCUSDT", Action: "hold"}, {Symbol: "ETHUSDT", Action: "wait"}},
+		AIRequestDurationMs: 1200,
+	}
+	at.updateHoldCache(holdCacheContext(50000), holds, "deepseek", nil)
+
+	// 阈值内（0.4%）：命中缓存
+	cached, model, ok := at.cachedHoldDecision(holdCacheContext(50200))
+	if !ok || model != "deepseek" || len(cached.Decisions) != 2 {
+		t.Fatalf("价格变动在阈值内应命中缓存: ok=%v model=%s", ok, model)
+	}
+	if cached.AIRequestDurationMs != 0 {
+		t.Errorf("复用的决策不应带有AI调用耗时: %d", cached.AIRequestDurationMs)
+	}
+
+	// 超出阈值（0.6%）：未命中
+	if _, _, ok := at.cachedHoldDecision(holdCacheContext(50300)); ok {
+		t.Error("价格变动超过阈值不应命中缓存")
+	}
+
+	// 持仓变化：未命中
+	if _, _, ok := at.cachedHoldDecision(holdCacheContext(50000, decision.PositionInfo{Symbol: "BTCUSDT", Side: "long", Quantity: 0.1})); ok {
+		t.Error("持仓变化后不应命中缓存")
+	}
+
+	// 上次决策包含动作时清空缓存
+	at.updateHoldCache(holdCacheContext(50000), &decision.FullDecision{
+		Decisions: []decision.Decision{{Symbol: "BTCUSDT", Action: "open_long"}},
+	}, "deepseek", nil)
+	if _, _, ok := at.cachedHoldDecision(holdCacheContext(50000)); ok {
+		t.Error("非全部持有的决策不应被缓存")
+	}
+
+	// 关闭缓存（阈值为 0）
+	at.updateHoldCache(holdCacheContext(50000), holds, "deepseek", nil)
+	at.config.HoldCachePct = 0
+	if _, _, ok := at.cachedHoldDecision(holdCacheContext(50000)); ok {
+		t.Error("阈值为0时不应使用缓存")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -684,6 +685,15 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 	return nil
 }
 
+// CancelOrder 按订单ID取消挂单
+func (t *HyperliquidTrader) CancelOrder(symbol string, orderID int64) error {
+	coin := convertSymbolToHyperliquid(symbol)
+	if _, err := t.exchange.Cancel(t.ctx, coin, orderID); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+	return nil
+}
+
 // CancelStopOrders 取消该币种的止盈/止损单（用于调整止盈止损位置）
 func (t *HyperliquidTrader) CancelStopOrders(symbol string) error {
 	coin := convertSymbolToHyperliquid(symbol)
@@ -922,6 +932,137 @@ func absFloat(x float64) float64 {
 	return x
 }
 
+// GetSymbolTradingStatus 获取交易对交易状态（基于 meta 中的 isDelisted 标记）
+func (t *HyperliquidTrader) GetSymbolTradingStatus(symbol string) (*SymbolTradingStatus, error) {
+	coin := convertSymbolToHyperliquid(symbol)
+
+	meta, err := t.exchange.Info().Meta(t.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取meta信息失败: %w", err)
+	}
+
+	// 顺便刷新缓存的 meta
+	t.metaMutex.Lock()
+	t.meta = meta
+	t.metaMutex.Unlock()
+
+	result := &SymbolTradingStatus{Symbol: symbol, Status: "UNKNOWN", Reason: "交易对不存在"}
+	for _, asset := range meta.Universe {
+		if asset.Name != coin {
+			continue
+		}
+		if asset.IsDelisted {
+			result.Status = "DELISTED"
+			result.Reason = "交易对已下架"
+		} else {
+			result.Status = "TRADING"
+			result.Reason = ""
+			result.Tradeable = true
+		}
+		break
+	}
+	return result, nil
+}
+
+// GetMaxLeverage 获取交易对允许的最大杠杆（读取缓存的 meta，找不到时刷新一次）
+func (t *HyperliquidTrader) GetMaxLeverage(symbol string) (int, error) {
+	coin := convertSymbolToHyperliquid(symbol)
+
+	t.metaMutex.RLock()
+	maxLeverage := metaMaxLeverage(t.meta, coin)
+	t.metaMutex.RUnlock()
+	if maxLeverage > 0 {
+		return maxLeverage, nil
+	}
+
+	meta, err := t.exchange.Info().Meta(t.ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取meta信息失败: %w", err)
+	}
+	t.metaMutex.Lock()
+	t.meta = meta
+	t.metaMutex.Unlock()
+	return metaMaxLeverage(meta, coin), nil
+}
+
+// metaMaxLeverage 从 meta 中查找币种的最大杠杆，找不到返回 0
+func metaMaxLeverage(meta *hyperliquid.Meta, coin string) int {
+	if meta == nil {
+		return 0
+	}
+	for _, asset := range meta.Universe {
+		if asset.Name == coin {
+			return asset.MaxLeverage
+		}
+	}
+	return 0
+}
+
+// hyperliquidFillsPageLimit userFillsByTime 单次最多返回的成交数
+const hyperliquidFillsPageLimit = 2000
+
+// GetUserTrades 获取账户成交记录（symbol 为空时返回所有币种）
+func (t *HyperliquidTrader) GetUserTrades(symbol string, startTime, endTime time.Time) ([]UserTrade, error) {
+	// userFillsByTime 不支持按币种过滤：翻页依赖原始页大小，拉取完成后再过滤
+	trades, err := collectUserTrades(startTime, endTime, hyperliquidFillsPageLimit, func(startMs, endMs int64) ([]UserTrade, error) {
+		fills, err := t.exchange.Info().UserFillsByTime(t.ctx, t.walletAddr, startMs, &endMs)
+		if err != nil {
+			return nil, fmt.Errorf("获取成交记录失败: %w", err)
+		}
+
+		page := make([]UserTrade, 0, len(fills))
+		for _, fill := range fills {
+			page = append(page, convertHyperliquidFill(fill))
+		}
+		return page, nil
+	})
+	if err != nil || symbol == "" {
+		return trades, err
+	}
+
+	filtered := make([]UserTrade, 0, len(trades))
+	for _, trade := range trades {
+		if trade.Symbol == symbol {
+			filtered = append(filtered, trade)
+		}
+	}
+	return filtered, nil
+}
+
+// convertHyperliquidFill 转换 Hyperliquid 成交记录（side: B=买入, A=卖出；dir 如 "Open Long"、"Close Short"）
+func convertHyperliquidFill(fill hyperliquid.Fill) UserTrade {
+	price, _ := strconv.ParseFloat(fill.Price, 64)
+	quantity, _ := strconv.ParseFloat(fill.Size, 64)
+	realizedPnL, _ := strconv.ParseFloat(fill.ClosedPnl, 64)
+	fee, _ := strconv.ParseFloat(fill.Fee, 64)
+
+	side := "SELL"
+	if fill.Side == "B" {
+		side = "BUY"
+	}
+	positionSide := "BOTH"
+	if strings.HasSuffix(fill.Dir, "Long") {
+		positionSide = "LONG"
+	} else if strings.HasSuffix(fill.Dir, "Short") {
+		positionSide = "SHORT"
+	}
+
+	return UserTrade{
+		ID:           fill.Tid,
+		OrderID:      fill.Oid,
+		Symbol:       convertHyperliquidToSymbol(fill.Coin),
+		Side:         side,
+		PositionSide: positionSide,
+		Price:        price,
+		Quantity:     quantity,
+		RealizedPnL:  realizedPnL,
+		Fee:          fee,
+		FeeAsset:     fill.FeeToken,
+		Maker:        !fill.Crossed,
+		Time:         fill.Time,
+	}
+}
+
 // GetOpenOrders retrieves open orders for AI decision context
 func (t *HyperliquidTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
 	// 獲取所有未成交訂單
@@ -956,6 +1097,7 @@ func (t *HyperliquidTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderIn
 			Quantity:     order.Size,                  // Size 已經是 float64
 			Price:        order.LimitPx,               // LimitPx 已經是 float64
 			StopPrice:    0,                           // OpenOrder 不包含觸發價格信息
+			CreatedAt:    order.Timestamp,
 		}
 		result = append(result, orderInfo)
 	}
@@ -1,6 +1,34 @@
 package trader
 
-import "nofx/decision"
+import (
+	"nofx/decision"
+	"time"
+)
+
+// SymbolTradingStatus 交易对交易状态
+type SymbolTradingStatus struct {
+	Symbol      string `json:"symbol"`
+	Tradeable   bool   `json:"tradeable"`   // 当前是否可下单
+	Status      string `json:"status"`      // 交易所原始状态（如 TRADING、BREAK、SETTLING、DELISTED）
+	Reason      string `json:"reason"`      // 不可交易的原因
+	Maintenance bool   `json:"maintenance"` // 交易所是否处于整体维护中
+}
+
+// UserTrade 交易所返回的成交记录（用于与 trade_history 对账）
+type UserTrade struct {
+	ID           int64   `json:"id"`            // 成交ID
+	OrderID      int64   `json:"order_id"`      // 订单ID
+	Symbol       string  `json:"symbol"`        // 交易对
+	Side         string  `json:"side"`          // BUY / SELL
+	PositionSide string  `json:"position_side"` // LONG / SHORT / BOTH
+	Price        float64 `json:"price"`         // 成交价
+	Quantity     float64 `json:"quantity"`      // 成交数量
+	RealizedPnL  float64 `json:"realized_pnl"`  // 交易所计算的已实现盈亏
+	Fee          float64 `json:"fee"`           // 手续费
+	FeeAsset     string  `json:"fee_asset"`     // 手续费币种
+	Maker        bool    `json:"maker"`         // 是否为挂单成交
+	Time         int64   `json:"time"`          // 成交时间（毫秒）
+}
 
 // Trader 交易器统一接口
 // 支持多个交易平台（币安、Hyperliquid等）
@@ -56,4 +84,13 @@ type Trader interface {
 	// GetOpenOrders retrieves open orders for AI decision context
 	// Returns all orders if symbol is empty, otherwise returns orders for the specified symbol
 	GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error)
+
+	// GetSymbolTradingStatus 获取交易对当前交易状态（暂停交易、下架或交易所维护时 Tradeable=false）
+	GetSymbolTradingStatus(symbol string) (*SymbolTradingStatus, error)
+
+	// GetUserTrades 获取交易所成交记录（按时间升序，内部处理分页）
+	GetUserTrades(symbol string, startTime, endTime time.Time) ([]UserTrade, error)
+
+	// GetMaxLeverage 获取交易对允许的最大杠杆（实现内部缓存，0 表示未知/不限制）
+	GetMaxLeverage(symbol string) (int, error)
 }
@@ -0,0 +1,90 @@
+package trader
+
+import (
+	"nofx/config"
+	"time"
+)
+
+// LeaderboardPeriodAll 创建以来（收益率基于初始余额，与 total_pnl_pct 一致）
+const LeaderboardPeriodAll = "all"
+
+// LeaderboardPeriods 排行榜统计周期 → 周期长度
+var LeaderboardPeriods = map[string]time.Duration{
+	"24h":                24 * time.Hour,
+	"7d":                 7 * 24 * time.Hour,
+	"30d":                30 * 24 * time.Hour,
+	LeaderboardPeriodAll: 0,
+}
+
+// LeaderboardPeriodStats 单个统计周期的表现
+type LeaderboardPeriodStats struct {
+	PnLPct  float64 // 周期收益率（%）=（当前净值 - 周期起点净值）/ 周期起点净值
+	WinRate float64 // 周期内平仓交易的胜率（%）
+	Trades  int     // 周期内完全平仓的交易笔数
+}
+
+// LeaderboardMetrics 排行榜衍生指标（由竞赛数据缓存统一计算，避免每次请求重复查询）
+type LeaderboardMetrics struct {
+	RunningDays float64                           // 运行天数（首条净值快照至今，没有快照时按本次启动时间）
+	Periods     map[string]LeaderboardPeriodStats // key: LeaderboardPeriods
+}
+
+// leaderboardMetricsStore 排行榜指标查询接口（数据库以鸭子类型注入）
+type leaderboardMetricsStore interface {
+	GetFirstEquitySnapshotSince(traderID string, since time.Time) (*config.EquitySnapshot, error)
+	GetTradeStats(traderID string, since time.Time) (*config.TradeStats, error)
+}
+
+// LeaderboardMetrics 基于净值快照和实际成交计算排行榜衍生指标
+// currentEquity/totalPnLPct 为本次获取的账户净值和创建以来收益率；数据库不支持时各周期收益率均取 totalPnLPct
+func (at *AutoTrader) LeaderboardMetrics(currentEquity, totalPnLPct float64, now time.Time) *LeaderboardMetrics {
+	metrics := &LeaderboardMetrics{
+		RunningDays: now.Sub(at.startTime).Hours() / 24,
+		Periods:     make(map[string]LeaderboardPeriodStats, len(LeaderboardPeriods)),
+	}
+
+	db, ok := at.database.(leaderboardMetricsStore)
+	if !ok {
+		for period := range LeaderboardPeriods {
+			metrics.Periods[period] = LeaderboardPeriodStats{PnLPct: totalPnLPct}
+		}
+		return metrics
+	}
+
+	if first, err := db.GetFirstEquitySnapshotSince(at.id, time.Time{}); err == nil && first != nil {
+		metrics.RunningDays = now.Sub(first.Timestamp).Hours() / 24
+	}
+
+	for period, length := range LeaderboardPeriods {
+		var since time.Time
+		if length > 0 {
+			since = now.Add(-length)
+		}
+
+		stats := LeaderboardPeriodStats{PnLPct: totalPnLPct}
+		if period != LeaderboardPeriodAll {
+			// 周期起点净值取周期内第一条快照（周期内才创建的交易员即为其第一条快照）
+			stats.PnLPct = 0
+			start, err := db.GetFirstEquitySnapshotSince(at.id, since)
+			if err != nil {
+				at.log().Warnf("⚠️ [%s] 查询 %s 周期起点净值失败: %v", at.name, period, err)
+			} else if start != nil && start.TotalEquity > 0 && currentEquity > 0 {
+				stats.PnLPct = (currentEquity - start.TotalEquity) / start.TotalEquity * 100
+			}
+		}
+
+		if tradeStats, err := db.GetTradeStats(at.id, since); err == nil {
+			stats.WinRate = tradeStats.WinRate
+			stats.Trades = tradeStats.Trades
+		} else {
+			at.log().Warnf("⚠️ [%s] 统计 %s 周期胜率失败: %v", at.name, period, err)
+		}
+
+		metrics.Periods[period] = stats
+	}
+
+	if metrics.RunningDays < 0 {
+		metrics.RunningDays = 0
+	}
+	return metrics
+}
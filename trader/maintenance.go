@@ -0,0 +1,42 @@
+package trader
+
+import (
+	"sync"
+	"time"
+)
+
+// MaintenanceMode 系统维护模式：开启后所有交易员跳过决策周期（不调用AI、不下单），
+// 持仓、止损止盈单和交易员运行状态都保持不变，关闭或到达自动结束时间后下一个周期自动恢复
+type MaintenanceMode struct {
+	Enabled bool
+	Message string
+	Until   time.Time // 自动结束时间（零值表示需要手动关闭）
+}
+
+// Active 维护模式在 now 时刻是否生效（已过自动结束时间视为关闭）
+func (m MaintenanceMode) Active(now time.Time) bool {
+	return m.Enabled && (m.Until.IsZero() || now.Before(m.Until))
+}
+
+var (
+	maintenanceMu   sync.RWMutex
+	maintenanceMode MaintenanceMode
+)
+
+// SetMaintenanceMode 设置全局维护模式，对所有运行中的交易员从下一个周期起生效
+func SetMaintenanceMode(m MaintenanceMode) {
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+	maintenanceMode = m
+}
+
+// CurrentMaintenanceMode 当前生效的维护模式（已过自动结束时间时返回关闭状态）
+func CurrentMaintenanceMode() MaintenanceMode {
+	maintenanceMu.RLock()
+	m := maintenanceMode
+	maintenanceMu.RUnlock()
+	if !m.Active(time.Now()) {
+		return MaintenanceMode{}
+	}
+	return m
+}
@@ -0,0 +1,39 @@
+package trader
+
+import (
+	"nofx/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMaintenanceModeSkipsCycle 测试维护模式下决策周期直接跳过（不访问交易所），到达自动结束时间后恢复
+func TestMaintenanceModeSkipsCycle(t *testing.T) {
+	defer SetMaintenanceMode(MaintenanceMode{})
+
+	decisionLogger := logger.NewDecisionLogger(t.TempDir())
+	at := &AutoTrader{name: "maintenance", decisionLogger: decisionLogger}
+
+	SetMaintenanceMode(MaintenanceMode{Enabled: true, Message: "数据库迁移"})
+	if err := at.runCycle(); err != nil {
+		t.Fatalf("维护模式下周期不应返回错误: %v", err)
+	}
+	records, err := decisionLogger.GetLatestRecords(1)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("应记录一条决策记录: %v, %d", err, len(records))
+	}
+	if records[0].Success || !strings.Contains(records[0].ErrorMessage, "maintenance mode") ||
+		!strings.Contains(records[0].ErrorMessage, "数据库迁移") {
+		t.Errorf("决策记录应标记维护模式: %+v", records[0])
+	}
+
+	// 已过自动结束时间视为关闭
+	SetMaintenanceMode(MaintenanceMode{Enabled: true, Until: time.Now().Add(-time.Minute)})
+	if CurrentMaintenanceMode().Enabled {
+		t.Error("已过自动结束时间的维护模式不应生效")
+	}
+	SetMaintenanceMode(MaintenanceMode{Enabled: true, Until: time.Now().Add(time.Hour)})
+	if !CurrentMaintenanceMode().Enabled {
+		t.Error("自动结束时间之前维护模式应生效")
+	}
+}
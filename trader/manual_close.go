@@ -0,0 +1,345 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"nofx/logger"
+)
+
+// manualCloseLockTimeout 手动平仓等待周期锁的最长时间（决策周期执行中时超时返回）
+const manualCloseLockTimeout = 10 * time.Second
+
+// minPartialRemainingValue 部分平仓后剩余仓位的最小价值（USDT），低于该值改为全部平仓（与 AI partial_close 一致）
+const minPartialRemainingValue = 10.0
+
+var (
+	// ErrClosePercentInvalid 平仓百分比超出 1-100
+	ErrClosePercentInvalid = errors.New("平仓百分比必须在 1-100 之间")
+	// ErrPositionNotFound 交易所上没有该币种该方向的持仓
+	ErrPositionNotFound = errors.New("持仓不存在或已平仓")
+	// ErrTraderBusy 决策周期执行中，等待周期锁超时
+	ErrTraderBusy = errors.New("交易员正在执行决策周期，请稍后重试")
+)
+
+// ClosePositionResult 手动按比例平仓的结果
+type ClosePositionResult struct {
+	Symbol            string   `json:"symbol"`
+	Side              string   `json:"side"`
+	Percentage        float64  `json:"percentage"`         // 实际平仓比例（剩余仓位过小时改为 100）
+	Action            string   `json:"action"`             // 交易历史中的动作：PARTIAL_CLOSE / CLOSE
+	ClosedQuantity    float64  `json:"closed_quantity"`    // 本次平仓数量
+	RemainingQuantity float64  `json:"remaining_quantity"` // 剩余持仓数量
+	FillPrice         float64  `json:"fill_price"`         // 成交价（交易所未返回时为标记价格）
+	RealizedPnL       float64  `json:"realized_pnl"`       // 平仓部分的已实现盈亏（USDT）
+	RealizedPnLPct    float64  `json:"realized_pnl_pct"`   // 平仓部分相对入场价的盈亏百分比
+	OrderID           int64    `json:"order_id,omitempty"`
+	StopLoss          float64  `json:"stop_loss,omitempty"`   // 剩余仓位重新挂出的止损价
+	TakeProfit        float64  `json:"take_profit,omitempty"` // 剩余仓位重新挂出的止盈价
+	DryRun            bool     `json:"dry_run,omitempty"`
+	Warnings          []string `json:"warnings,omitempty"` // 止损/止盈重新挂单失败等不影响平仓结果的问题
+}
+
+// ClosePositionPercent 手动平掉持仓的一部分（percentage 1-100，100 为全部平仓）
+// 平仓以 PARTIAL_CLOSE / CLOSE 记入交易历史；部分平仓后按剩余数量重新挂出原有的止损/止盈单
+func (at *AutoTrader) ClosePositionPercent(symbol, side string, percentage float64) (*ClosePositionResult, error) {
+	side = strings.ToLower(side)
+	if side != "long" && side != "short" {
+		return nil, fmt.Errorf("未知的持仓方向: %s", side)
+	}
+	if percentage < 1 || percentage > 100 {
+		return nil, ErrClosePercentInvalid
+	}
+
+	// 持有周期锁，避免与决策周期同时操作同一持仓
+	if !at.tryLockCycle(manualCloseLockTimeout) {
+		return nil, ErrTraderBusy
+	}
+	defer at.cycleMutex.Unlock()
+
+	pos, err := at.findPosition(symbol, side)
+	if err != nil {
+		return nil, err
+	}
+	positionAmt, _ := pos["positionAmt"].(float64)
+	entryPrice, _ := pos["entryPrice"].(float64)
+	markPrice, _ := pos["markPrice"].(float64)
+	totalQuantity := math.Abs(positionAmt)
+	positionSide := strings.ToUpper(side)
+	posKey := symbol + "_" + side
+
+	result := &ClosePositionResult{Symbol: symbol, Side: side, Percentage: percentage, FillPrice: markPrice}
+	if remaining := totalQuantity * (1 - percentage/100); percentage < 100 && remaining*markPrice <= minPartialRemainingValue {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("剩余仓位价值 %.2f USDT 不足 %.0f USDT，已改为全部平仓", remaining*markPrice, minPartialRemainingValue))
+		result.Percentage = 100
+	}
+	partial := result.Percentage < 100
+	result.ClosedQuantity = totalQuantity
+	result.Action = "CLOSE"
+	if partial {
+		result.ClosedQuantity = totalQuantity * result.Percentage / 100
+		result.RemainingQuantity = totalQuantity - result.ClosedQuantity
+		result.Action = "PARTIAL_CLOSE"
+	}
+
+	actionRecord := logger.DecisionAction{
+		Action:    "close_" + side,
+		Symbol:    symbol,
+		Quantity:  result.ClosedQuantity,
+		Price:     markPrice,
+		Timestamp: time.Now(),
+	}
+	if partial {
+		actionRecord.Action = "partial_close"
+		actionRecord.ClosePercentage = result.Percentage
+	}
+
+	// 平仓前记录本方向剩余仓位和对向持仓的止损/止盈/追踪止损（部分交易所平仓后会撤销该币种全部条件单）
+	protections := at.captureStopProtections(symbol, side, result.RemainingQuantity)
+
+	if at.dryRunSkip(&actionRecord, "手动平仓 %s %s %.0f%% 数量 %.4f", symbol, side, result.Percentage, result.ClosedQuantity) {
+		result.DryRun = true
+		result.RealizedPnL, result.RealizedPnLPct = closedPnL(side, entryPrice, markPrice, result.ClosedQuantity)
+		actionRecord.Success = true
+		at.logManualClose(result, actionRecord)
+		return result, nil
+	}
+
+	closeQuantity := result.ClosedQuantity
+	if !partial {
+		closeQuantity = 0 // 0 = 全部平仓，避免精度误差留下残仓
+	}
+	var order map[string]interface{}
+	if side == "long" {
+		order, err = at.trader.CloseLong(symbol, closeQuantity)
+	} else {
+		order, err = at.trader.CloseShort(symbol, closeQuantity)
+	}
+	if err != nil {
+		recordActionError(&actionRecord, err)
+		at.logManualClose(result, actionRecord)
+		return nil, fmt.Errorf("平仓失败: %w", err)
+	}
+
+	if orderID, ok := order["orderId"].(int64); ok {
+		result.OrderID = orderID
+		actionRecord.OrderID = orderID
+	}
+	if avgPrice, ok := order["avgPrice"].(float64); ok && avgPrice > 0 {
+		result.FillPrice = avgPrice
+		actionRecord.Price = avgPrice
+	}
+	result.RealizedPnL, result.RealizedPnLPct = closedPnL(side, entryPrice, result.FillPrice, result.ClosedQuantity)
+	actionRecord.Success = true
+	at.log().Infof("  🖐 [%s] 手动平仓 %s %s %.0f%% 成功: 平仓 %.4f，剩余 %.4f，盈亏 %.2f USDT",
+		at.name, symbol, side, result.Percentage, result.ClosedQuantity, result.RemainingQuantity, result.RealizedPnL)
+
+	reason := fmt.Sprintf("手动平仓 %.0f%%", result.Percentage)
+	at.emitCloseEvent(symbol, side, result.ClosedQuantity, entryPrice, result.FillPrice, partial, reason)
+
+	result.Warnings = at.restoreStopOrders(symbol, protections, result.Warnings)
+	if partial {
+		for _, p := range protections {
+			if p.positionSide == positionSide {
+				result.StopLoss, result.TakeProfit = p.stopLoss, p.takeProfit
+				if p.empty() {
+					result.Warnings = append(result.Warnings, "原持仓没有止损止盈单，剩余仓位没有止损保护")
+				}
+			}
+		}
+		// 下一周期读取交易所持仓前，内存快照先反映剩余数量
+		if lastPos, ok := at.lastPositions[posKey]; ok {
+			lastPos.Quantity = result.RemainingQuantity
+			at.lastPositions[posKey] = lastPos
+		}
+	} else {
+		at.ClearPeakPnLCache(symbol, side)
+		at.releaseSymbolSlot(symbol, side)
+		at.clearPositionAnnotation(symbol, side)
+		delete(at.positionStopLoss, posKey)
+		delete(at.positionTakeProfit, posKey)
+		// 已记录为手动平仓，不再被下一周期识别为被动平仓
+		delete(at.lastPositions, posKey)
+	}
+
+	if db, ok := at.database.(tradeRecorder); ok {
+		if err := at.recordTradeWithRetry(db,
+			at.config.ID, at.userID, symbol, positionSide, result.Action,
+			result.ClosedQuantity, result.FillPrice, reason,
+			result.StopLoss, result.TakeProfit, result.RealizedPnL, result.RealizedPnLPct,
+		); err != nil {
+			at.log().Warnf("  ⚠️ 記錄手動平倉到數據庫失敗: %v", err)
+		}
+	}
+
+	at.logManualClose(result, actionRecord)
+	return result, nil
+}
+
+// findPosition 查找交易所上指定币种和方向的持仓
+func (at *AutoTrader) findPosition(symbol, side string) (map[string]interface{}, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	for _, pos := range positions {
+		posSymbol, _ := pos["symbol"].(string)
+		posSide, _ := pos["side"].(string)
+		posAmt, _ := pos["positionAmt"].(float64)
+		if posSymbol == symbol && strings.ToLower(posSide) == side && posAmt != 0 {
+			return pos, nil
+		}
+	}
+	return nil, ErrPositionNotFound
+}
+
+// stopProtection 平仓前记录的持仓保护单，撤单后据此重新挂出
+type stopProtection struct {
+	positionSide string  // LONG / SHORT
+	quantity     float64 // 重新挂单的数量（本方向为剩余数量，对向为持仓数量）
+	stopLoss     float64
+	takeProfit   float64
+	trailing     bool // 交易所原生追踪止损单（撤单后需按 trailingStops 中的回撤比例重新挂出）
+}
+
+// empty 是否没有任何保护单
+func (p stopProtection) empty() bool {
+	return p.stopLoss <= 0 && p.takeProfit <= 0 && !p.trailing
+}
+
+// captureStopProtections 记录平仓后仍持有的仓位（本方向剩余部分、双向持仓时的对向持仓）当前的保护单
+// 止损/止盈价优先读取交易所条件单，获取失败时使用内存记录；原生追踪止损以 trailingStops 状态为准
+func (at *AutoTrader) captureStopProtections(symbol, side string, remaining float64) []stopProtection {
+	orders, ordersErr := at.trader.GetOpenOrders(symbol)
+	if ordersErr != nil {
+		at.log().Warnf("  ⚠️ [%s] 获取 %s 挂单失败，使用记录的止损止盈价: %v", at.name, symbol, ordersErr)
+	}
+
+	quantities := map[string]float64{side: remaining}
+	opposite := "short"
+	if side == "short" {
+		opposite = "long"
+	}
+	if pos, err := at.findPosition(symbol, opposite); err == nil {
+		positionAmt, _ := pos["positionAmt"].(float64)
+		quantities[opposite] = math.Abs(positionAmt)
+	}
+
+	var protections []stopProtection
+	for _, s := range []string{side, opposite} {
+		if quantities[s] <= 0 {
+			continue
+		}
+		posKey := symbol + "_" + s
+		p := stopProtection{
+			positionSide: strings.ToUpper(s),
+			quantity:     quantities[s],
+			stopLoss:     at.positionStopLoss[posKey],
+			takeProfit:   at.positionTakeProfit[posKey],
+		}
+		if ordersErr == nil {
+			if price, ok := exchangeCloseOrderPrice(orders, symbol, p.positionSide, "STOP_MARKET", "STOP"); ok {
+				p.stopLoss = price
+			}
+			if price, ok := exchangeCloseOrderPrice(orders, symbol, p.positionSide, "TAKE_PROFIT_MARKET", "TAKE_PROFIT"); ok {
+				p.takeProfit = price
+			}
+		}
+		if state, ok := at.getTrailingStop(posKey); ok && state.Native {
+			p.trailing = true
+		}
+		protections = append(protections, p)
+	}
+	return protections
+}
+
+// restoreStopOrders 撤销该币种原有的条件单并重新挂出仍持有仓位的保护单（reduce-only 条件单数量超过持仓会被交易所拒绝）
+// 撤单作用于该币种两个方向（含原生追踪止损），双向持仓时对向持仓的保护单也必须补挂
+func (at *AutoTrader) restoreStopOrders(symbol string, protections []stopProtection, warnings []string) []string {
+	hasOrders := false
+	for _, p := range protections {
+		if !p.empty() {
+			hasOrders = true
+		}
+	}
+	if !hasOrders {
+		return warnings
+	}
+	if err := at.trader.CancelStopOrders(symbol); err != nil {
+		at.log().Warnf("  ⚠️ [%s] 撤销 %s 原有止损止盈单失败: %v", at.name, symbol, err)
+	}
+	for i := range protections {
+		warnings = at.replaceStopProtection(symbol, &protections[i], warnings)
+	}
+	return warnings
+}
+
+// replaceStopProtection 按记录重新挂出一个方向的保护单，失败的写入 warnings 并清除对应的内存记录
+// 原生追踪止损补挂失败时改为在当前止损线挂普通止损单，由回撤监控继续本地模拟
+func (at *AutoTrader) replaceStopProtection(symbol string, p *stopProtection, warnings []string) []string {
+	posKey := symbol + "_" + strings.ToLower(p.positionSide)
+
+	if p.trailing {
+		state, _ := at.getTrailingStop(posKey)
+		err := errors.New("交易所不支持原生追踪止损")
+		if placer, ok := at.trader.(trailingStopPlacer); ok {
+			err = placer.SetTrailingStop(symbol, p.positionSide, p.quantity, state.DistancePct)
+		}
+		if err == nil {
+			p.stopLoss = state.StopPrice
+			at.positionStopLoss[posKey] = p.stopLoss
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s 重新设置交易所追踪止损失败，改为本地模拟: %v", p.positionSide, err))
+			state.Native = false
+			at.setTrailingStop(posKey, state)
+			p.trailing = false
+			p.stopLoss = at.alignStopPrice(symbol, p.positionSide, true, state.StopPrice)
+		}
+	}
+	if p.stopLoss > 0 && !p.trailing {
+		if err := at.trader.SetStopLoss(symbol, p.positionSide, p.quantity, p.stopLoss); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s 重新设置止损失败: %v", p.positionSide, err))
+			p.stopLoss = 0
+			delete(at.positionStopLoss, posKey)
+		} else {
+			at.positionStopLoss[posKey] = p.stopLoss
+			at.markTrailingStopPlaced(posKey, p.stopLoss)
+		}
+	}
+	if p.takeProfit > 0 {
+		if err := at.trader.SetTakeProfit(symbol, p.positionSide, p.quantity, p.takeProfit); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s 重新设置止盈失败: %v", p.positionSide, err))
+			p.takeProfit = 0
+			delete(at.positionTakeProfit, posKey)
+		} else {
+			at.positionTakeProfit[posKey] = p.takeProfit
+		}
+	}
+	return warnings
+}
+
+// closedPnL 平仓部分的已实现盈亏及相对入场价的百分比
+func closedPnL(side string, entryPrice, exitPrice, quantity float64) (pnl, pnlPct float64) {
+	if entryPrice <= 0 || quantity <= 0 {
+		return 0, 0
+	}
+	pnl = (exitPrice - entryPrice) * quantity
+	pnlPct = (exitPrice - entryPrice) / entryPrice * 100
+	if side == "short" {
+		pnl, pnlPct = -pnl, -pnlPct
+	}
+	return pnl, pnlPct
+}
+
+// logManualClose 将手动平仓写入决策日志，留下人工干预的记录
+func (at *AutoTrader) logManualClose(result *ClosePositionResult, action logger.DecisionAction) {
+	entry := fmt.Sprintf("🖐 手动平仓: %s %s %.0f%% 数量 %.4f 价格 %.4f 盈亏 %.2f USDT",
+		result.Symbol, result.Side, result.Percentage, result.ClosedQuantity, result.FillPrice, result.RealizedPnL)
+	if action.DryRun {
+		entry += " [模拟运行，未平仓]"
+	}
+	at.logManualAction(entry, action)
+}
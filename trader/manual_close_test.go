@@ -0,0 +1,224 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// manualCloseMockTrader 记录平仓数量和重新挂出的止损止盈数量
+type manualCloseMockTrader struct {
+	*cancelMockTrader
+	closeQuantities []float64
+	stopQuantity    float64
+	tpQuantity      float64
+	tpPrice         float64
+	stopsCancelled  bool
+	placed          []string // 按顺序记录重新挂出的保护单
+	trailingErr     error
+}
+
+func (m *manualCloseMockTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	m.closeQuantities = append(m.closeQuantities, quantity)
+	return map[string]interface{}{"orderId": int64(99), "avgPrice": 52000.0}, nil
+}
+
+func (m *manualCloseMockTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	m.stopQuantity = quantity
+	m.placed = append(m.placed, fmt.Sprintf("SL %s %.4f@%.0f", positionSide, quantity, stopPrice))
+	return m.MockTrader.SetStopLoss(symbol, positionSide, quantity, stopPrice)
+}
+
+func (m *manualCloseMockTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	m.tpQuantity, m.tpPrice = quantity, takeProfitPrice
+	m.placed = append(m.placed, fmt.Sprintf("TP %s %.4f@%.0f", positionSide, quantity, takeProfitPrice))
+	return nil
+}
+
+func (m *manualCloseMockTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRatePct float64) error {
+	if m.trailingErr != nil {
+		return m.trailingErr
+	}
+	m.placed = append(m.placed, fmt.Sprintf("TRAILING %s %.4f@%.1f%%", positionSide, quantity, callbackRatePct))
+	return nil
+}
+
+func (m *manualCloseMockTrader) CancelStopOrders(symbol string) error {
+	m.stopsCancelled = true
+	return nil
+}
+
+// manualCloseRecorder 记录交易历史的模拟数据库
+type manualCloseRecorder struct {
+	actions    []string
+	quantities []float64
+	pnls       []float64
+}
+
+func (r *manualCloseRecorder) RecordTrade(traderID, userID, symbol, side, action string, quantity, price float64, reason string, stopLoss, takeProfit, pnl, pnlPercent float64) error {
+	r.actions = append(r.actions, symbol+"_"+side+"_"+action)
+	r.quantities = append(r.quantities, quantity)
+	r.pnls = append(r.pnls, pnl)
+	return nil
+}
+
+// TestClosePositionPercent 测试手动部分平仓：按比例下单、记录 PARTIAL_CLOSE 盈亏、按剩余数量重新挂止损止盈；100% 记录 CLOSE
+func TestClosePositionPercent(t *testing.T) {
+	newTrader := func() (*AutoTrader, *manualCloseMockTrader, *manualCloseRecorder) {
+		mock := &manualCloseMockTrader{cancelMockTrader: &cancelMockTrader{
+			MockTrader: &MockTrader{positions: []map[string]interface{}{
+				{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.2, "entryPrice": 50000.0, "markPrice": 51900.0},
+			}},
+			orders: []decision.OpenOrderInfo{
+				{Symbol: "BTCUSDT", Type: "STOP_MARKET", Side: "SELL", PositionSide: "LONG", Quantity: 0.2, StopPrice: 48000},
+				{Symbol: "BTCUSDT", Type: "TAKE_PROFIT_MARKET", Side: "SELL", PositionSide: "LONG", Quantity: 0.2, StopPrice: 56000},
+			},
+		}}
+		db := &manualCloseRecorder{}
+		at := &AutoTrader{
+			name:               "manual-close",
+			trader:             mock,
+			database:           db,
+			decisionLogger:     logger.NewDecisionLogger(t.TempDir()),
+			positionStopLoss:   map[string]float64{},
+			positionTakeProfit: map[string]float64{},
+			lastPositions: map[string]decision.PositionInfo{
+				"BTCUSDT_long": {Symbol: "BTCUSDT", Side: "long", Quantity: 0.2, EntryPrice: 50000},
+			},
+		}
+		return at, mock, db
+	}
+
+	at, mock, db := newTrader()
+	if _, err := at.ClosePositionPercent("BTCUSDT", "long", 0); err != ErrClosePercentInvalid {
+		t.Errorf("0%% 应返回 ErrClosePercentInvalid, 实际 %v", err)
+	}
+	if _, err := at.ClosePositionPercent("BTCUSDT", "short", 50); err != ErrPositionNotFound {
+		t.Errorf("没有空仓时应返回 ErrPositionNotFound, 实际 %v", err)
+	}
+
+	result, err := at.ClosePositionPercent("BTCUSDT", "long", 50)
+	if err != nil {
+		t.Fatalf("部分平仓失败: %v", err)
+	}
+	if len(mock.closeQuantities) != 1 || math.Abs(mock.closeQuantities[0]-0.1) > 1e-9 {
+		t.Errorf("应平仓 0.1, 实际 %v", mock.closeQuantities)
+	}
+	if result.Action != "PARTIAL_CLOSE" || result.FillPrice != 52000 || math.Abs(result.RealizedPnL-200) > 1e-6 || math.Abs(result.RemainingQuantity-0.1) > 1e-9 {
+		t.Errorf("部分平仓结果不正确: %+v", result)
+	}
+	if len(db.actions) != 1 || db.actions[0] != "BTCUSDT_LONG_PARTIAL_CLOSE" || math.Abs(db.pnls[0]-200) > 1e-6 {
+		t.Errorf("应记录 PARTIAL_CLOSE 及平仓部分的盈亏, 实际 %v %v", db.actions, db.pnls)
+	}
+	if !mock.stopsCancelled || math.Abs(mock.stopQuantity-0.1) > 1e-9 || mock.lastStopLoss != 48000 || math.Abs(mock.tpQuantity-0.1) > 1e-9 || mock.tpPrice != 56000 {
+		t.Errorf("止损止盈应按剩余数量重新挂出: cancelled=%v sl=%.4f@%.0f tp=%.4f@%.0f", mock.stopsCancelled, mock.stopQuantity, mock.lastStopLoss, mock.tpQuantity, mock.tpPrice)
+	}
+	if result.StopLoss != 48000 || result.TakeProfit != 56000 {
+		t.Errorf("结果应返回重新挂出的止损止盈价: %+v", result)
+	}
+	if qty := at.lastPositions["BTCUSDT_long"].Quantity; math.Abs(qty-0.1) > 1e-9 {
+		t.Errorf("持仓快照应更新为剩余数量, 实际 %.4f", qty)
+	}
+	records, _ := at.decisionLogger.GetLatestRecords(10)
+	if len(records) != 1 || records[0].Decisions[0].Action != "partial_close" || !strings.Contains(records[0].ExecutionLog[0], "手动平仓") {
+		t.Errorf("应记录手动平仓决策日志: %+v", records)
+	}
+
+	// 100%：全部平仓（数量传 0），记录 CLOSE，不再挂止损止盈，持仓快照移除
+	at, mock, db = newTrader()
+	result, err = at.ClosePositionPercent("BTCUSDT", "long", 100)
+	if err != nil {
+		t.Fatalf("全部平仓失败: %v", err)
+	}
+	if mock.closeQuantities[0] != 0 || result.Action != "CLOSE" || db.actions[0] != "BTCUSDT_LONG_CLOSE" || math.Abs(db.quantities[0]-0.2) > 1e-9 {
+		t.Errorf("全部平仓结果不正确: %+v %v", result, db.actions)
+	}
+	if mock.stopsCancelled || mock.setStopLossCalls != 0 {
+		t.Error("全部平仓不应重新挂止损止盈")
+	}
+	if _, ok := at.lastPositions["BTCUSDT_long"]; ok {
+		t.Error("全部平仓后持仓快照应移除，避免下一周期识别为被动平仓")
+	}
+
+	// 剩余仓位价值过小时改为全部平仓
+	at, mock, db = newTrader()
+	result, err = at.ClosePositionPercent("BTCUSDT", "long", 99.99)
+	if err != nil || result.Percentage != 100 || mock.closeQuantities[0] != 0 || len(result.Warnings) == 0 {
+		t.Errorf("剩余仓位过小时应改为全部平仓: %+v %v", result, err)
+	}
+}
+
+// TestClosePositionPercentHedged 测试双向持仓时平仓撤单后补挂对向持仓的止损，并重新挂出原生追踪止损
+func TestClosePositionPercentHedged(t *testing.T) {
+	newTrader := func() (*AutoTrader, *manualCloseMockTrader) {
+		mock := &manualCloseMockTrader{cancelMockTrader: &cancelMockTrader{
+			MockTrader: &MockTrader{positions: []map[string]interface{}{
+				{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.2, "entryPrice": 50000.0, "markPrice": 51900.0},
+				{"symbol": "BTCUSDT", "side": "short", "positionAmt": -0.5, "entryPrice": 53000.0, "markPrice": 51900.0},
+			}},
+			orders: []decision.OpenOrderInfo{
+				{Symbol: "BTCUSDT", Type: "TRAILING_STOP_MARKET", Side: "SELL", PositionSide: "LONG", Quantity: 0.2},
+				{Symbol: "BTCUSDT", Type: "STOP_MARKET", Side: "BUY", PositionSide: "SHORT", Quantity: 0.5, StopPrice: 55000},
+			},
+		}}
+		at := &AutoTrader{
+			name:               "manual-close-hedged",
+			trader:             mock,
+			database:           &manualCloseRecorder{},
+			decisionLogger:     logger.NewDecisionLogger(t.TempDir()),
+			positionStopLoss:   map[string]float64{"BTCUSDT_long": 50960},
+			positionTakeProfit: map[string]float64{},
+			lastPositions:      map[string]decision.PositionInfo{},
+			trailingStops: map[string]TrailingStop{
+				"BTCUSDT_long": {DistancePct: 2, ExtremePrice: 52000, StopPrice: 50960, Native: true},
+			},
+		}
+		return at, mock
+	}
+
+	at, mock := newTrader()
+	result, err := at.ClosePositionPercent("BTCUSDT", "long", 50)
+	if err != nil {
+		t.Fatalf("部分平仓失败: %v", err)
+	}
+	want := []string{"TRAILING LONG 0.1000@2.0%", "SL SHORT 0.5000@55000"}
+	if fmt.Sprint(mock.placed) != fmt.Sprint(want) {
+		t.Errorf("应按剩余数量重新挂出追踪止损并补挂空仓止损: 期望 %v, 实际 %v", want, mock.placed)
+	}
+	if result.StopLoss != 50960 || len(result.Warnings) != 0 {
+		t.Errorf("结果应返回追踪止损线且没有警告: %+v", result)
+	}
+	if state := at.GetTrailingStops()["BTCUSDT_long"]; !state.Native {
+		t.Error("追踪止损重新挂出成功后应保持原生状态")
+	}
+
+	// 追踪止损补挂失败：改为在止损线挂普通止损单并转为本地模拟，结果中给出警告
+	at, mock = newTrader()
+	mock.trailingErr = errors.New("rejected")
+	result, err = at.ClosePositionPercent("BTCUSDT", "long", 50)
+	if err != nil {
+		t.Fatalf("部分平仓失败: %v", err)
+	}
+	want = []string{"SL LONG 0.1000@50960", "SL SHORT 0.5000@55000"}
+	if fmt.Sprint(mock.placed) != fmt.Sprint(want) || len(result.Warnings) != 1 {
+		t.Errorf("追踪止损失败时应挂普通止损并警告: %v %v", mock.placed, result.Warnings)
+	}
+	if state := at.GetTrailingStops()["BTCUSDT_long"]; state.Native {
+		t.Error("追踪止损补挂失败后应转为本地模拟")
+	}
+
+	// 全部平仓多单：平仓撤单后对向空仓的止损仍需补挂
+	at, mock = newTrader()
+	if _, err := at.ClosePositionPercent("BTCUSDT", "long", 100); err != nil {
+		t.Fatalf("全部平仓失败: %v", err)
+	}
+	want = []string{"SL SHORT 0.5000@55000"}
+	if !mock.stopsCancelled || fmt.Sprint(mock.placed) != fmt.Sprint(want) {
+		t.Errorf("全部平仓后应只补挂空仓止损: %v", mock.placed)
+	}
+}
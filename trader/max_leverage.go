@@ -0,0 +1,64 @@
+package trader
+
+import (
+	"sync"
+	"time"
+)
+
+// maxLeverageCacheTTL 交易所杠杆上限缓存有效期（杠杆分层很少变化）
+const maxLeverageCacheTTL = 6 * time.Hour
+
+// maxLeverageCache 交易所各交易对最大杠杆缓存（symbol -> 最大杠杆），零值可直接使用
+type maxLeverageCache struct {
+	mu        sync.RWMutex
+	values    map[string]int
+	fetchedAt time.Time
+}
+
+// get 读取缓存的最大杠杆，缓存为空或已过期时 fresh=false
+func (c *maxLeverageCache) get(symbol string) (maxLeverage int, found bool, fresh bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.values == nil || time.Since(c.fetchedAt) > maxLeverageCacheTTL {
+		return 0, false, false
+	}
+	maxLeverage, found = c.values[symbol]
+	return maxLeverage, found, true
+}
+
+// store 用交易所返回的全部交易对杠杆上限替换缓存
+func (c *maxLeverageCache) store(values map[string]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values = values
+	c.fetchedAt = time.Now()
+}
+
+// lookup 从缓存读取最大杠杆，缓存失效时调用 fetch 拉取全部交易对后再读取；交易对不存在时返回 0（不限制）
+func (c *maxLeverageCache) lookup(symbol string, fetch func() (map[string]int, error)) (int, error) {
+	if maxLeverage, _, fresh := c.get(symbol); fresh {
+		return maxLeverage, nil
+	}
+	values, err := fetch()
+	if err != nil {
+		return 0, err
+	}
+	c.store(values)
+	return values[symbol], nil
+}
+
+// clampLeverage 将杠杆限制在交易所允许的最大值以内，超出时记录日志
+// 查询失败或交易所未返回上限时保持原杠杆，由交易所自行校验
+func (at *AutoTrader) clampLeverage(symbol string, leverage int) int {
+	maxLeverage, err := at.trader.GetMaxLeverage(symbol)
+	if err != nil {
+		at.log().Warnf("  ⚠️ 获取 %s 最大杠杆失败，按 %dx 下单: %v", symbol, leverage, err)
+		return leverage
+	}
+	if maxLeverage <= 0 || leverage <= maxLeverage {
+		return leverage
+	}
+
+	at.log().Infof("  🎚️ %s 杠杆 %dx 超过交易所上限 %dx，已调整为 %dx", symbol, leverage, maxLeverage, maxLeverage)
+	return maxLeverage
+}
@@ -0,0 +1,82 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// TestClampLeverage 测试杠杆按交易所上限截断
+func TestClampLeverage(t *testing.T) {
+	mock := &MockTrader{maxLeverage: map[string]int{"DOGEUSDT": 3, "BTCUSDT": 125}}
+	at := &AutoTrader{trader: mock}
+
+	tests := []struct {
+		name     string
+		symbol   string
+		leverage int
+		want     int
+	}{
+		{"超过上限时截断", "DOGEUSDT", 10, 3},
+		{"等于上限保持不变", "DOGEUSDT", 3, 3},
+		{"低于上限保持不变", "BTCUSDT", 20, 20},
+		{"交易所未返回上限时不限制", "XRPUSDT", 50, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := at.clampLeverage(tt.symbol, tt.leverage); got != tt.want {
+				t.Errorf("clampLeverage(%s, %d) = %d, 期望 %d", tt.symbol, tt.leverage, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMaxLeverageCache 测试杠杆上限缓存：有效期内只拉取一次，拉取失败不写入缓存
+func TestMaxLeverageCache(t *testing.T) {
+	var cache maxLeverageCache
+	fetches := 0
+	fetch := func() (map[string]int, error) {
+		fetches++
+		return map[string]int{"BTCUSDT": 125, "DOGEUSDT": 3}, nil
+	}
+
+	if _, err := cache.lookup("BTCUSDT", func() (map[string]int, error) { return nil, errors.New("网络错误") }); err == nil {
+		t.Fatal("拉取失败时应返回错误")
+	}
+
+	for _, tc := range []struct {
+		symbol string
+		want   int
+	}{{"BTCUSDT", 125}, {"DOGEUSDT", 3}, {"UNKNOWNUSDT", 0}} {
+		got, err := cache.lookup(tc.symbol, fetch)
+		if err != nil || got != tc.want {
+			t.Errorf("lookup(%s) = %d, %v，期望 %d", tc.symbol, got, err, tc.want)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("缓存有效期内应只拉取 1 次，实际 %d 次", fetches)
+	}
+
+	// 缓存过期后重新拉取
+	cache.fetchedAt = time.Now().Add(-maxLeverageCacheTTL - time.Minute)
+	if _, err := cache.lookup("BTCUSDT", fetch); err != nil || fetches != 2 {
+		t.Errorf("缓存过期后应重新拉取，实际拉取 %d 次, err=%v", fetches, err)
+	}
+}
+
+// TestMaxInitialLeverage 测试从杠杆分层中取最高杠杆
+func TestMaxInitialLeverage(t *testing.T) {
+	brackets := []futures.Bracket{
+		{Bracket: 1, InitialLeverage: 75},
+		{Bracket: 2, InitialLeverage: 50},
+		{Bracket: 3, InitialLeverage: 20},
+	}
+	if got := maxInitialLeverage(brackets); got != 75 {
+		t.Errorf("期望 75，实际 %d", got)
+	}
+	if got := maxInitialLeverage(nil); got != 0 {
+		t.Errorf("无分层时期望 0，实际 %d", got)
+	}
+}
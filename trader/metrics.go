@@ -0,0 +1,24 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/metrics"
+)
+
+// publishMetrics 每个周期用账户快照更新 Prometheus 交易指标
+func (at *AutoTrader) publishMetrics(account decision.AccountInfo) {
+	drawdownPct := 0.0
+	if peak := at.peakEquity; peak > 0 && account.TotalEquity < peak {
+		drawdownPct = (peak - account.TotalEquity) / peak * 100
+	}
+
+	metrics.SetTrader(metrics.TraderGauges{
+		TraderID:      at.id,
+		UserID:        at.userID,
+		Equity:        account.TotalEquity,
+		TotalPnL:      account.TotalPnL,
+		TotalPnLPct:   account.TotalPnLPct,
+		PositionCount: account.PositionCount,
+		DrawdownPct:   drawdownPct,
+	})
+}
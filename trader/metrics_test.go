@@ -0,0 +1,32 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/metrics"
+	"strings"
+	"testing"
+)
+
+// TestPublishMetrics 测试交易周期的账户快照写入 Prometheus 指标（回撤按峰值净值计算）
+func TestPublishMetrics(t *testing.T) {
+	at := &AutoTrader{id: "metrics_trader", userID: "metrics_user", peakEquity: 1200}
+	defer metrics.RemoveTrader(at.id)
+
+	at.publishMetrics(decision.AccountInfo{TotalEquity: 1080, TotalPnL: 80, TotalPnLPct: 8, PositionCount: 3})
+
+	var sb strings.Builder
+	if err := metrics.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	body := sb.String()
+	for _, want := range []string{
+		`nofx_trader_equity_usdt{trader_id="metrics_trader",user_id="metrics_user"} 1080`,
+		`nofx_trader_pnl_percent{trader_id="metrics_trader",user_id="metrics_user"} 8`,
+		`nofx_trader_open_positions{trader_id="metrics_trader",user_id="metrics_user"} 3`,
+		`nofx_trader_drawdown_percent{trader_id="metrics_trader",user_id="metrics_user"} 10`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("缺少指标行 %q\n%s", want, body)
+		}
+	}
+}
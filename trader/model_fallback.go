@@ -0,0 +1,58 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// fullDecisionFunc 请求AI决策（测试中替换为模拟实现）
+var fullDecisionFunc = decision.GetFullDecisionWithCustomPrompt
+
+// initFallbackModel 创建备用模型的客户端（entry 为 nil 时不启用）
+func (at *AutoTrader) initFallbackModel(entry *ModelPoolEntry) {
+	if entry == nil {
+		return
+	}
+	client := newAIClient(entry.Provider, entry.APIKey, entry.CustomAPIURL, entry.CustomModelName)
+	if at.outboundTransport != nil {
+		client.SetTransport(at.outboundTransport)
+	}
+	at.fallbackModel = &pooledModel{
+		label:        modelLabel(entry.ModelID, entry.CustomModelName),
+		client:       client,
+		promptPrefix: entry.SystemPromptPrefix,
+		promptSuffix: entry.SystemPromptSuffix,
+	}
+	at.log().Infof("🛟 [%s] 启用备用AI模型: %s", at.name, at.fallbackModel.label)
+}
+
+// fallbackModelLabel 备用模型标识（未启用时为空）
+func (at *AutoTrader) fallbackModelLabel() string {
+	if at.fallbackModel == nil {
+		return ""
+	}
+	return at.fallbackModel.label
+}
+
+// callModel 使用本周期选择的模型获取决策，并在决策记录中标注实际产生决策的模型（model_used）
+// 主模型调用失败、超时或响应无法解析且配置了备用模型时，改用备用模型重试一次
+func (at *AutoTrader) callModel(ctx *decision.Context, model pooledModel, record *logger.DecisionRecord) (*decision.FullDecision, error) {
+	record.ModelUsed = model.label
+	model.applyPrompt(ctx)
+	fullDecision, err := fullDecisionFunc(ctx, model.client, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+
+	fallback := at.fallbackModel
+	if err == nil || fallback == nil || fallback.label == model.label || !errors.Is(err, decision.ErrAIUnavailable) {
+		return fullDecision, err
+	}
+
+	at.log().Infof("🛟 [%s] 主模型 %s 未返回可用决策，改用备用模型 %s 重试: %v", at.name, model.label, fallback.label, err)
+	record.ExecutionLog = append(record.ExecutionLog,
+		fmt.Sprintf("🛟 主模型 %s 失败，改用备用模型 %s: %v", model.label, fallback.label, err))
+	record.FallbackUsed = true
+	record.ModelUsed = fallback.label
+	fallback.applyPrompt(ctx)
+	return fullDecisionFunc(ctx, fallback.client, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+}
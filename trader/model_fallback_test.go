@@ -0,0 +1,82 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+	"testing"
+)
+
+// stubFullDecision 替换AI决策请求：按客户端返回预设结果，并记录调用顺序和当时的 prompt 前缀
+func stubFullDecision(t *testing.T, results map[mcp.AIClient]error) *[]string {
+	t.Helper()
+	original := fullDecisionFunc
+	t.Cleanup(func() { fullDecisionFunc = original })
+
+	var prefixes []string
+	fullDecisionFunc = func(ctx *decision.Context, client mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*decision.FullDecision, error) {
+		prefixes = append(prefixes, ctx.SystemPromptPrefix)
+		if err := results[client]; err != nil {
+			return nil, err
+		}
+		return &decision.FullDecision{CoTTrace: ctx.SystemPromptPrefix}, nil
+	}
+	return &prefixes
+}
+
+// TestCallModelFallback 测试主模型失败时改用备用模型重试一次，并在决策记录中标注实际使用的模型
+func TestCallModelFallback(t *testing.T) {
+	aiErr := fmt.Errorf("调用AI API失败: 超时: %w", decision.ErrAIUnavailable)
+	marketErr := errors.New("获取市场数据失败: 连接被拒绝")
+
+	tests := []struct {
+		name         string
+		primaryErr   error
+		fallbackErr  error
+		withFallback bool
+		wantCalls    int
+		wantErr      bool
+		wantUsed     string
+		wantFallback bool
+	}{
+		{name: "主模型成功", withFallback: true, wantCalls: 1, wantUsed: "deepseek"},
+		{name: "主模型失败_备用成功", primaryErr: aiErr, withFallback: true, wantCalls: 2, wantUsed: "qwen", wantFallback: true},
+		{name: "主模型失败_备用也失败", primaryErr: aiErr, fallbackErr: aiErr, withFallback: true, wantCalls: 2, wantErr: true, wantUsed: "qwen", wantFallback: true},
+		{name: "未配置备用模型", primaryErr: aiErr, wantCalls: 1, wantErr: true, wantUsed: "deepseek"},
+		{name: "与模型无关的失败不重试", primaryErr: marketErr, withFallback: true, wantCalls: 1, wantErr: true, wantUsed: "deepseek"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := pooledModel{label: "deepseek", client: mcp.NewDeepSeekClient(), promptPrefix: "主模型前缀"}
+			at := &AutoTrader{name: "fallback"}
+			results := map[mcp.AIClient]error{primary.client: tt.primaryErr}
+			if tt.withFallback {
+				at.initFallbackModel(&ModelPoolEntry{ModelID: "qwen", Provider: "qwen", APIKey: "sk-2", SystemPromptPrefix: "备用模型前缀"})
+				results[at.fallbackModel.client] = tt.fallbackErr
+			}
+			prefixes := stubFullDecision(t, results)
+
+			record := &logger.DecisionRecord{}
+			fullDecision, err := at.callModel(&decision.Context{}, primary, record)
+
+			if len(*prefixes) != tt.wantCalls {
+				t.Fatalf("AI调用次数 = %d, 期望 %d", len(*prefixes), tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, 期望出错: %v", err, tt.wantErr)
+			}
+			if record.ModelUsed != tt.wantUsed || record.FallbackUsed != tt.wantFallback {
+				t.Errorf("model_used = %s, fallback_used = %v, 期望 %s, %v", record.ModelUsed, record.FallbackUsed, tt.wantUsed, tt.wantFallback)
+			}
+			if tt.wantFallback && (*prefixes)[1] != "备用模型前缀" {
+				t.Errorf("备用模型应使用自己的 prompt 前缀, 实际 %q", (*prefixes)[1])
+			}
+			if !tt.wantErr && tt.wantFallback && fullDecision.CoTTrace != "备用模型前缀" {
+				t.Errorf("应返回备用模型的决策")
+			}
+		})
+	}
+}
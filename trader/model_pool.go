@@ -0,0 +1,145 @@
+package trader
+
+import (
+	"fmt"
+	"math/rand"
+	"nofx/decision"
+	"nofx/mcp"
+	"strings"
+)
+
+// 模型池选择方式
+const (
+	ModelPoolRoundRobin = "round_robin" // 按顺序轮换
+	ModelPoolRandom     = "random"      // 每个周期随机选择
+)
+
+// ModelPoolEntry 模型池中的一个AI模型配置
+type ModelPoolEntry struct {
+	ModelID         string // 模型ID（如 "deepseek"），写入决策记录用于对比
+	Provider        string
+	APIKey          string
+	CustomAPIURL    string
+	CustomModelName string
+
+	// 模型专属 System Prompt 前缀/后缀（为空表示不添加）
+	SystemPromptPrefix string
+	SystemPromptSuffix string
+}
+
+// pooledModel 已初始化客户端的模型池成员
+type pooledModel struct {
+	label        string
+	client       mcp.AIClient
+	promptPrefix string // 模型专属 System Prompt 前缀
+	promptSuffix string // 模型专属 System Prompt 后缀
+}
+
+// applyPrompt 把模型专属的 System Prompt 前缀/后缀写入决策上下文
+func (m pooledModel) applyPrompt(ctx *decision.Context) {
+	ctx.SystemPromptPrefix = m.promptPrefix
+	ctx.SystemPromptSuffix = m.promptSuffix
+}
+
+// ParseModelPool 解析逗号分隔的模型ID列表（去空格、去重，保持顺序）
+func ParseModelPool(raw string) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range strings.Split(raw, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// IsValidModelPoolMode 检查模型池选择方式是否合法（空值视为默认的轮换）
+func IsValidModelPoolMode(mode string) bool {
+	return mode == "" || mode == ModelPoolRoundRobin || mode == ModelPoolRandom
+}
+
+// newAIClient 按 provider 创建AI客户端（与 NewAutoTrader 的初始化规则一致）
+func newAIClient(provider, apiKey, customURL, customModel string) mcp.AIClient {
+	var client mcp.AIClient
+	switch provider {
+	case "qwen":
+		client = mcp.NewQwenClient()
+	case "custom":
+		client = mcp.New()
+	default:
+		client = mcp.NewDeepSeekClient()
+	}
+	client.SetAPIKey(apiKey, customURL, customModel)
+	return client
+}
+
+// modelLabel 决策记录中的模型标识：模型ID，配置了自定义模型名称时附加在后面
+func modelLabel(modelID, customModel string) string {
+	if customModel == "" {
+		return modelID
+	}
+	return fmt.Sprintf("%s/%s", modelID, customModel)
+}
+
+// initModelPool 为模型池中的每个模型创建独立客户端
+// 池中少于2个模型时不启用，继续使用交易员绑定的模型
+func (at *AutoTrader) initModelPool(entries []ModelPoolEntry, mode string) {
+	if len(entries) < 2 {
+		return
+	}
+	if mode == "" {
+		mode = ModelPoolRoundRobin
+	}
+
+	at.modelPool = make([]pooledModel, 0, len(entries))
+	labels := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		label := modelLabel(entry.ModelID, entry.CustomModelName)
+		client := newAIClient(entry.Provider, entry.APIKey, entry.CustomAPIURL, entry.CustomModelName)
+		if at.outboundTransport != nil {
+			client.SetTransport(at.outboundTransport)
+		}
+		at.modelPool = append(at.modelPool, pooledModel{
+			label:        label,
+			client:       client,
+			promptPrefix: entry.SystemPromptPrefix,
+			promptSuffix: entry.SystemPromptSuffix,
+		})
+		labels = append(labels, label)
+	}
+	at.modelPoolMode = mode
+	at.log().Infof("🎲 [%s] 启用模型池 (%s): %s", at.name, mode, strings.Join(labels, ", "))
+}
+
+// nextModel 选择本周期使用的AI模型（客户端、模型标识和专属 prompt 前缀/后缀）
+// 未启用模型池时返回交易员绑定的模型；周期由 cycleMutex 串行执行，无需额外加锁
+func (at *AutoTrader) nextModel() pooledModel {
+	if len(at.modelPool) == 0 {
+		return pooledModel{
+			label:        modelLabel(at.aiModel, at.config.CustomModelName),
+			client:       at.mcpClient,
+			promptPrefix: at.config.SystemPromptPrefix,
+			promptSuffix: at.config.SystemPromptSuffix,
+		}
+	}
+
+	var picked pooledModel
+	if at.modelPoolMode == ModelPoolRandom {
+		picked = at.modelPool[rand.Intn(len(at.modelPool))]
+	} else {
+		picked = at.modelPool[at.modelPoolIndex%len(at.modelPool)]
+		at.modelPoolIndex++
+	}
+	return picked
+}
+
+// peekModel 本周期将使用的AI模型（不推进模型池轮换位置）
+func (at *AutoTrader) peekModel() pooledModel {
+	if len(at.modelPool) == 0 || at.modelPoolMode == ModelPoolRandom {
+		return at.nextModel()
+	}
+	return at.modelPool[at.modelPoolIndex%len(at.modelPool)]
+}
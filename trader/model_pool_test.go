@@ -0,0 +1,115 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/mcp"
+	"testing"
+)
+
+func testModelPoolEntries() []ModelPoolEntry {
+	return []ModelPoolEntry{
+		{ModelID: "deepseek", Provider: "deepseek", APIKey: "sk-1"},
+		{ModelID: "qwen", Provider: "qwen", APIKey: "sk-2"},
+		{ModelID: "custom", Provider: "custom", APIKey: "sk-3", CustomAPIURL: "https://api.example.com/v1", CustomModelName: "gpt-4o"},
+	}
+}
+
+// TestModelPoolRoundRobin 测试轮换模式下每个周期依次使用池中的模型
+func TestModelPoolRoundRobin(t *testing.T) {
+	at := &AutoTrader{name: "pool"}
+	at.initModelPool(testModelPoolEntries(), ModelPoolRoundRobin)
+
+	expected := []string{"deepseek", "qwen", "custom/gpt-4o", "deepseek", "qwen", "custom/gpt-4o"}
+	clients := make([]mcp.AIClient, 0, len(expected))
+	for i, want := range expected {
+		picked := at.nextModel()
+		client, label := picked.client, picked.label
+		if label != want {
+			t.Errorf("第 %d 个周期使用模型 %s, 期望 %s", i+1, label, want)
+		}
+		clients = append(clients, client)
+	}
+
+	// 同一模型在不同周期复用同一个客户端
+	for i := 0; i < 3; i++ {
+		if clients[i] != clients[i+3] {
+			t.Errorf("模型 %s 应复用同一个客户端", expected[i])
+		}
+		if clients[i] == clients[(i+1)%3] {
+			t.Errorf("不同模型不应共用客户端")
+		}
+	}
+}
+
+// TestModelPoolRandom 测试随机模式只会选择池中的模型
+func TestModelPoolRandom(t *testing.T) {
+	at := &AutoTrader{name: "pool"}
+	at.initModelPool(testModelPoolEntries(), ModelPoolRandom)
+
+	allowed := map[string]bool{"deepseek": true, "qwen": true, "custom/gpt-4o": true}
+	for i := 0; i < 20; i++ {
+		if picked := at.nextModel(); !allowed[picked.label] {
+			t.Fatalf("随机选择了池外的模型: %s", picked.label)
+		}
+	}
+}
+
+// TestModelPoolDisabled 测试池中少于2个模型时使用交易员绑定的模型
+func TestModelPoolDisabled(t *testing.T) {
+	bound := mcp.NewDeepSeekClient()
+	at := &AutoTrader{name: "single", aiModel: "deepseek", mcpClient: bound}
+	at.initModelPool(testModelPoolEntries()[:1], ModelPoolRoundRobin)
+
+	for i := 0; i < 3; i++ {
+		picked := at.nextModel()
+		if picked.client != bound || picked.label != "deepseek" {
+			t.Errorf("未启用模型池时应使用绑定模型, 实际 %s", picked.label)
+		}
+	}
+}
+
+// TestModelPromptAffixes 测试模型专属 prompt 前缀/后缀只作用于对应的模型
+func TestModelPromptAffixes(t *testing.T) {
+	entries := testModelPoolEntries()
+	entries[0].SystemPromptPrefix = "DeepSeek 专用前缀"
+	entries[1].SystemPromptSuffix = "Qwen 专用后缀"
+
+	at := &AutoTrader{name: "pool"}
+	at.initModelPool(entries, ModelPoolRoundRobin)
+
+	expected := []struct{ label, prefix, suffix string }{
+		{"deepseek", "DeepSeek 专用前缀", ""},
+		{"qwen", "", "Qwen 专用后缀"},
+		{"custom/gpt-4o", "", ""},
+	}
+	for _, want := range expected {
+		ctx := &decision.Context{}
+		picked := at.nextModel()
+		picked.applyPrompt(ctx)
+		if picked.label != want.label || ctx.SystemPromptPrefix != want.prefix || ctx.SystemPromptSuffix != want.suffix {
+			t.Errorf("模型 %s 的前缀/后缀不正确: %q / %q", picked.label, ctx.SystemPromptPrefix, ctx.SystemPromptSuffix)
+		}
+	}
+
+	// 未启用模型池时使用绑定模型的配置
+	single := &AutoTrader{name: "single", aiModel: "qwen", config: AutoTraderConfig{SystemPromptPrefix: "绑定模型前缀"}}
+	ctx := &decision.Context{}
+	single.nextModel().applyPrompt(ctx)
+	if ctx.SystemPromptPrefix != "绑定模型前缀" || ctx.SystemPromptSuffix != "" {
+		t.Errorf("绑定模型的前缀/后缀不正确: %q / %q", ctx.SystemPromptPrefix, ctx.SystemPromptSuffix)
+	}
+}
+
+// TestParseModelPool 测试模型池解析与选择方式校验
+func TestParseModelPool(t *testing.T) {
+	ids := ParseModelPool(" deepseek, qwen,,deepseek ,custom ")
+	if len(ids) != 3 || ids[0] != "deepseek" || ids[1] != "qwen" || ids[2] != "custom" {
+		t.Errorf("解析结果不正确: %v", ids)
+	}
+	if len(ParseModelPool("")) != 0 {
+		t.Error("空字符串应解析为空列表")
+	}
+	if !IsValidModelPoolMode("") || !IsValidModelPoolMode(ModelPoolRandom) || IsValidModelPoolMode("weighted") {
+		t.Error("选择方式校验不正确")
+	}
+}
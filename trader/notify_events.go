@@ -0,0 +1,35 @@
+package trader
+
+import (
+	"nofx/config"
+	"nofx/notify"
+	"nofx/webhook"
+)
+
+// notificationSource 用户通知配置读取接口（数据库以鸭子类型注入）
+type notificationSource interface {
+	GetUserNotification(userID string) (*config.UserNotification, error)
+}
+
+// emitNotification 异步推送事件到用户配置的 Telegram 机器人（未配置时不发送）
+// 发送由 notify.DefaultNotifier 排队、限速并重试，不阻塞交易流程
+func (at *AutoTrader) emitNotification(event *webhook.Event) {
+	db, ok := at.database.(notificationSource)
+	if !ok {
+		return
+	}
+
+	go func() {
+		n, err := db.GetUserNotification(at.userID)
+		if err != nil {
+			at.log().Warnf("⚠️ [%s] 读取通知配置失败: %v", at.name, err)
+			return
+		}
+		if n == nil {
+			return
+		}
+
+		cfg := &notify.TelegramConfig{BotToken: n.TelegramBotToken, ChatID: n.TelegramChatID, Events: n.Events, Enabled: n.Enabled}
+		notify.DefaultNotifier.Enqueue(cfg, event.Type, notify.FormatEvent(event))
+	}()
+}
@@ -0,0 +1,921 @@
+package trader
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"nofx/decision"
+	"nofx/netproxy"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// okxInstrumentsTTL 合约规格缓存有效期（合约面值、下单精度很少变化）
+const okxInstrumentsTTL = 6 * time.Hour
+
+// okxFillsPageLimit OKX 成交明细单页最大条数
+const okxFillsPageLimit = 100
+
+// okxInstrument OKX 永续合约规格
+type okxInstrument struct {
+	CtVal    float64 // 合约面值（每张合约对应的币数量）
+	LotSz    float64 // 下单数量精度（张）
+	MinSz    float64 // 最小下单数量（张）
+	TickSz   float64 // 价格精度
+	MaxLever int     // 最大杠杆
+	State    string  // live / suspend / preopen 等
+}
+
+// OKXTrader OKX 永续合约交易器（USDT 本位，数量在内部与“张”互相换算）
+type OKXTrader struct {
+	apiKey     string
+	secretKey  string
+	passphrase string
+	testnet    bool // 模拟盘：请求头带 x-simulated-trading: 1
+	baseURL    string
+	client     *http.Client
+	marginMode string // cross / isolated
+
+	mu            sync.RWMutex
+	instruments   map[string]okxInstrument // instId -> 合约规格
+	instrumentsAt time.Time
+	posModeOnce   sync.Once
+	netMode       bool // 账户为单向持仓模式（无法切换到双向持仓时下单不带 posSide）
+
+	// 交易对最大杠杆缓存
+	maxLeverage maxLeverageCache
+}
+
+// NewOKXTrader 创建 OKX 交易器
+// passphrase: 创建 API Key 时设置的口令；testnet=true 使用 OKX 模拟盘
+// transport: 出站代理传输（nil 表示直连）
+func NewOKXTrader(apiKey, secretKey, passphrase string, testnet bool, transport *http.Transport) (*OKXTrader, error) {
+	if apiKey == "" || secretKey == "" || passphrase == "" {
+		return nil, fmt.Errorf("OKX 需要 API Key、Secret Key 和 Passphrase")
+	}
+	if transport == nil {
+		transport = &http.Transport{}
+	} else {
+		transport = transport.Clone()
+	}
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	transport.ResponseHeaderTimeout = 10 * time.Second
+	transport.IdleConnTimeout = 90 * time.Second
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: netproxy.LimitTransport(transport, &exchangeCallLimiter),
+	}
+	return &OKXTrader{
+		apiKey:      apiKey,
+		secretKey:   secretKey,
+		passphrase:  passphrase,
+		testnet:     testnet,
+		baseURL:     "https://www.okx.com",
+		client:      client,
+		marginMode:  "cross",
+		instruments: make(map[string]okxInstrument),
+	}, nil
+}
+
+// okxInstID 内部交易对转换为 OKX 永续合约 ID（BTCUSDT -> BTC-USDT-SWAP）
+func okxInstID(symbol string) string {
+	s := strings.ToUpper(symbol)
+	if strings.HasSuffix(s, "-SWAP") {
+		return s
+	}
+	for _, quote := range []string{"USDT", "USDC", "USD"} {
+		if strings.HasSuffix(s, quote) && len(s) > len(quote) {
+			return s[:len(s)-len(quote)] + "-" + quote + "-SWAP"
+		}
+	}
+	return s
+}
+
+// okxSymbol OKX 永续合约 ID 转换为内部交易对（BTC-USDT-SWAP -> BTCUSDT）
+func okxSymbol(instID string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(instID, "-SWAP"), "-", "")
+}
+
+// okxFloat 解析 OKX 返回的数字字符串（空字符串为0）
+func okxFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// okxOrderID 将 OKX 的字符串订单ID转换为 int64
+func okxOrderID(id string) int64 {
+	v, _ := strconv.ParseInt(id, 10, 64)
+	return v
+}
+
+// formatOKXStep 按步进值的小数位数格式化数字
+func formatOKXStep(value, step float64) string {
+	decimals := 0
+	if s := strconv.FormatFloat(step, 'f', -1, 64); strings.Contains(s, ".") {
+		decimals = len(s) - strings.Index(s, ".") - 1
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
+
+// sign 生成 OKX 请求签名：Base64(HMAC-SHA256(timestamp + method + requestPath + body))
+func (t *OKXTrader) sign(timestamp, method, requestPath, body string) string {
+	mac := hmac.New(sha256.New, []byte(t.secretKey))
+	mac.Write([]byte(timestamp + method + requestPath + body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// request 发送签名请求，返回响应中的 data 字段
+func (t *OKXTrader) request(method, path string, query url.Values, payload interface{}) (json.RawMessage, error) {
+	requestPath := path
+	if len(query) > 0 {
+		requestPath += "?" + query.Encode()
+	}
+
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("序列化请求失败: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, t.baseURL+requestPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OK-ACCESS-KEY", t.apiKey)
+	req.Header.Set("OK-ACCESS-SIGN", t.sign(timestamp, method, requestPath, string(body)))
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", t.passphrase)
+	if t.testnet {
+		req.Header.Set("x-simulated-trading", "1")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var envelope struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+	if envelope.Code != "0" {
+		// 下单类接口的具体原因在 data[].sMsg 中
+		var items []struct {
+			SCode string `json:"sCode"`
+			SMsg  string `json:"sMsg"`
+		}
+		if json.Unmarshal(envelope.Data, &items) == nil {
+			for _, item := range items {
+				if item.SCode != "" && item.SCode != "0" {
+					return nil, fmt.Errorf("OKX错误 %s: %s", item.SCode, item.SMsg)
+				}
+			}
+		}
+		return nil, fmt.Errorf("OKX错误 %s: %s", envelope.Code, envelope.Msg)
+	}
+	return envelope.Data, nil
+}
+
+// fetchInstruments 拉取全部 USDT 永续合约规格
+func (t *OKXTrader) fetchInstruments() (map[string]okxInstrument, error) {
+	data, err := t.request("GET", "/api/v5/public/instruments", url.Values{"instType": {"SWAP"}}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取合约规格失败: %w", err)
+	}
+	var items []struct {
+		InstID string `json:"instId"`
+		CtVal  string `json:"ctVal"`
+		LotSz  string `json:"lotSz"`
+		MinSz  string `json:"minSz"`
+		TickSz string `json:"tickSz"`
+		Lever  string `json:"lever"`
+		State  string `json:"state"`
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("解析合约规格失败: %w", err)
+	}
+
+	instruments := make(map[string]okxInstrument, len(items))
+	for _, item := range items {
+		lever, _ := strconv.Atoi(item.Lever)
+		instruments[item.InstID] = okxInstrument{
+			CtVal:    okxFloat(item.CtVal),
+			LotSz:    okxFloat(item.LotSz),
+			MinSz:    okxFloat(item.MinSz),
+			TickSz:   okxFloat(item.TickSz),
+			MaxLever: lever,
+			State:    item.State,
+		}
+	}
+	return instruments, nil
+}
+
+// getInstrument 获取合约规格（带缓存）
+func (t *OKXTrader) getInstrument(instID string) (okxInstrument, error) {
+	t.mu.RLock()
+	inst, ok := t.instruments[instID]
+	fresh := time.Since(t.instrumentsAt) < okxInstrumentsTTL
+	t.mu.RUnlock()
+	if ok && fresh {
+		return inst, nil
+	}
+
+	instruments, err := t.fetchInstruments()
+	if err != nil {
+		return okxInstrument{}, err
+	}
+	t.mu.Lock()
+	t.instruments = instruments
+	t.instrumentsAt = time.Now()
+	t.mu.Unlock()
+
+	inst, ok = instruments[instID]
+	if !ok || inst.CtVal <= 0 {
+		return okxInstrument{}, fmt.Errorf("未找到 OKX 合约 %s", instID)
+	}
+	return inst, nil
+}
+
+// toContracts 币数量换算为合约张数（按下单精度向下取整）
+func (t *OKXTrader) toContracts(symbol string, quantity float64) (string, error) {
+	inst, err := t.getInstrument(okxInstID(symbol))
+	if err != nil {
+		return "", err
+	}
+	contracts := quantity / inst.CtVal
+	if inst.LotSz > 0 {
+		contracts = math.Floor(contracts/inst.LotSz+1e-9) * inst.LotSz
+	}
+	if contracts <= 0 || contracts < inst.MinSz {
+		return "", fmt.Errorf("%s 数量 %.8f 不足最小下单量 %g 张（每张 %g）", symbol, quantity, inst.MinSz, inst.CtVal)
+	}
+	return formatOKXStep(contracts, inst.LotSz), nil
+}
+
+// ensurePositionMode 首次交易前尝试切换为双向持仓模式，失败时按单向持仓下单
+func (t *OKXTrader) ensurePositionMode() {
+	t.posModeOnce.Do(func() {
+		data, err := t.request("GET", "/api/v5/account/config", nil, nil)
+		if err != nil {
+			log.Printf("  ⚠️ 获取 OKX 账户配置失败，默认按双向持仓下单: %v", err)
+			return
+		}
+		var configs []struct {
+			PosMode string `json:"posMode"`
+		}
+		if json.Unmarshal(data, &configs) != nil || len(configs) == 0 || configs[0].PosMode != "net_mode" {
+			return
+		}
+		if _, err := t.request("POST", "/api/v5/account/set-position-mode", nil, map[string]string{"posMode": "long_short_mode"}); err != nil {
+			log.Printf("  ⚠️ OKX 切换双向持仓失败，按单向持仓下单: %v", err)
+			t.netMode = true
+			return
+		}
+		log.Printf("  ✓ OKX 账户已切换为双向持仓模式")
+	})
+}
+
+// GetBalance 获取账户余额（USDT）
+func (t *OKXTrader) GetBalance() (map[string]interface{}, error) {
+	data, err := t.request("GET", "/api/v5/account/balance", url.Values{"ccy": {"USDT"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []struct {
+		Details []struct {
+			Ccy     string `json:"ccy"`
+			Eq      string `json:"eq"`
+			AvailEq string `json:"availEq"`
+			AvailBa string `json:"availBal"`
+			Upl     string `json:"upl"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("解析余额失败: %w", err)
+	}
+
+	equity, available, unrealized := 0.0, 0.0, 0.0
+	if len(accounts) > 0 {
+		for _, d := range accounts[0].Details {
+			if d.Ccy != "USDT" {
+				continue
+			}
+			equity = okxFloat(d.Eq)
+			unrealized = okxFloat(d.Upl)
+			// 单币种保证金账户没有 availEq，使用 availBal
+			available = okxFloat(d.AvailEq)
+			if d.AvailEq == "" {
+				available = okxFloat(d.AvailBa)
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"totalWalletBalance":    equity - unrealized, // 钱包余额（不含未实现盈亏）
+		"availableBalance":      available,
+		"totalUnrealizedProfit": unrealized,
+	}, nil
+}
+
+// okxPosition OKX 持仓原始数据
+type okxPosition struct {
+	InstID  string `json:"instId"`
+	PosSide string `json:"posSide"` // long / short / net
+	Pos     string `json:"pos"`     // 张数（单向持仓时空仓为负）
+	AvgPx   string `json:"avgPx"`
+	MarkPx  string `json:"markPx"`
+	Upl     string `json:"upl"`
+	Lever   string `json:"lever"`
+	LiqPx   string `json:"liqPx"`
+}
+
+// side 持仓方向 long / short
+func (p okxPosition) side() string {
+	if p.PosSide == "short" || (p.PosSide == "net" && okxFloat(p.Pos) < 0) {
+		return "short"
+	}
+	return "long"
+}
+
+func (t *OKXTrader) fetchPositions() ([]okxPosition, error) {
+	data, err := t.request("GET", "/api/v5/account/positions", url.Values{"instType": {"SWAP"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var positions []okxPosition
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, fmt.Errorf("解析持仓失败: %w", err)
+	}
+	return positions, nil
+}
+
+// GetPositions 获取持仓信息（数量换算为币数量，空仓为负，与币安一致）
+func (t *OKXTrader) GetPositions() ([]map[string]interface{}, error) {
+	positions, err := t.fetchPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+	for _, pos := range positions {
+		contracts := math.Abs(okxFloat(pos.Pos))
+		if contracts == 0 {
+			continue
+		}
+		inst, err := t.getInstrument(pos.InstID)
+		if err != nil {
+			log.Printf("⚠️ %v", err)
+			continue
+		}
+
+		side := pos.side()
+		amount := contracts * inst.CtVal
+		if side == "short" {
+			amount = -amount
+		}
+		result = append(result, map[string]interface{}{
+			"symbol":           okxSymbol(pos.InstID),
+			"side":             side,
+			"positionAmt":      amount,
+			"entryPrice":       okxFloat(pos.AvgPx),
+			"markPrice":        okxFloat(pos.MarkPx),
+			"unRealizedProfit": okxFloat(pos.Upl),
+			"leverage":         okxFloat(pos.Lever),
+			"liquidationPrice": okxFloat(pos.LiqPx),
+		})
+	}
+	return result, nil
+}
+
+// placeOrder 下市价单；reduce=true 表示平仓
+func (t *OKXTrader) placeOrder(symbol, side, posSide string, quantity float64, reduce bool) (map[string]interface{}, error) {
+	t.ensurePositionMode()
+
+	sz, err := t.toContracts(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	order := map[string]interface{}{
+		"instId":  okxInstID(symbol),
+		"tdMode":  t.marginMode,
+		"side":    side,
+		"ordType": "market",
+		"sz":      sz,
+	}
+	if t.netMode {
+		if reduce {
+			order["reduceOnly"] = true
+		}
+	} else {
+		order["posSide"] = posSide
+	}
+
+	data, err := t.request("POST", "/api/v5/trade/order", nil, order)
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		OrdID string `json:"ordId"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil || len(results) == 0 {
+		return nil, fmt.Errorf("解析下单结果失败: %s", string(data))
+	}
+
+	return map[string]interface{}{
+		"orderId": okxOrderID(results[0].OrdID),
+		"symbol":  symbol,
+		"status":  "NEW",
+	}, nil
+}
+
+// OpenLong 开多仓
+func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	// 开仓前先取消所有挂单，防止残留挂单导致仓位叠加
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	result, err := t.placeOrder(symbol, "buy", "long", quantity, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✓ 开多仓成功: %s 数量: %.8f", symbol, quantity)
+	return result, nil
+}
+
+// OpenShort 开空仓
+func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	// 开仓前先取消所有挂单，防止残留挂单导致仓位叠加
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	result, err := t.placeOrder(symbol, "sell", "short", quantity, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✓ 开空仓成功: %s 数量: %.8f", symbol, quantity)
+	return result, nil
+}
+
+// positionQuantity 查询指定方向的持仓数量（币数量）
+func (t *OKXTrader) positionQuantity(symbol, side string) (float64, error) {
+	positions, err := t.GetPositions()
+	if err != nil {
+		return 0, err
+	}
+	for _, pos := range positions {
+		if pos["symbol"] == symbol && pos["side"] == side {
+			return math.Abs(pos["positionAmt"].(float64)), nil
+		}
+	}
+	return 0, nil
+}
+
+// closePosition 平仓（quantity=0 表示全部平仓），平仓后取消该币种的止盈止损单
+func (t *OKXTrader) closePosition(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	if quantity == 0 {
+		qty, err := t.positionQuantity(symbol, side)
+		if err != nil {
+			return nil, err
+		}
+		if qty == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, map[string]string{"long": "多", "short": "空"}[side])
+		}
+		quantity = qty
+		log.Printf("  📊 获取到持仓数量: %.8f", quantity)
+	}
+
+	orderSide := "sell"
+	if side == "short" {
+		orderSide = "buy"
+	}
+	result, err := t.placeOrder(symbol, orderSide, side, quantity, true)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✓ 平%s仓成功: %s 数量: %.8f", map[string]string{"long": "多", "short": "空"}[side], symbol, quantity)
+
+	if err := t.CancelStopOrders(symbol); err != nil {
+		log.Printf("  ⚠ 平仓后取消止盈止损单失败，请手动检查 %s 的挂单: %v", symbol, err)
+	}
+	return result, nil
+}
+
+// CloseLong 平多仓（quantity=0表示全部平仓）
+func (t *OKXTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, "long", quantity)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *OKXTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, "short", quantity)
+}
+
+// SetMarginMode 设置仓位模式（OKX 在每笔订单上指定 tdMode，这里只记录）
+func (t *OKXTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	if isCrossMargin {
+		t.marginMode = "cross"
+	} else {
+		t.marginMode = "isolated"
+	}
+	return nil
+}
+
+// SetLeverage 设置杠杆倍数（逐仓双向持仓时多空分别设置）
+func (t *OKXTrader) SetLeverage(symbol string, leverage int) error {
+	t.ensurePositionMode()
+
+	params := map[string]string{
+		"instId":  okxInstID(symbol),
+		"lever":   strconv.Itoa(leverage),
+		"mgnMode": t.marginMode,
+	}
+	if t.marginMode == "isolated" && !t.netMode {
+		for _, posSide := range []string{"long", "short"} {
+			params["posSide"] = posSide
+			if _, err := t.request("POST", "/api/v5/account/set-leverage", nil, params); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err := t.request("POST", "/api/v5/account/set-leverage", nil, params)
+	return err
+}
+
+// GetMarketPrice 获取最新成交价
+func (t *OKXTrader) GetMarketPrice(symbol string) (float64, error) {
+	data, err := t.request("GET", "/api/v5/market/ticker", url.Values{"instId": {okxInstID(symbol)}}, nil)
+	if err != nil {
+		return 0, err
+	}
+	var tickers []struct {
+		Last string `json:"last"`
+	}
+	if err := json.Unmarshal(data, &tickers); err != nil || len(tickers) == 0 {
+		return 0, fmt.Errorf("无法获取 %s 价格", symbol)
+	}
+	return strconv.ParseFloat(tickers[0].Last, 64)
+}
+
+// placeStop 下止损/止盈条件单（触发后市价平仓）
+func (t *OKXTrader) placeStop(symbol, positionSide string, quantity, triggerPrice float64, stopLoss bool) error {
+	t.ensurePositionMode()
+
+	inst, err := t.getInstrument(okxInstID(symbol))
+	if err != nil {
+		return err
+	}
+	sz, err := t.toContracts(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	side, posSide := "sell", "long"
+	if positionSide == "SHORT" {
+		side, posSide = "buy", "short"
+	}
+	order := map[string]interface{}{
+		"instId":     okxInstID(symbol),
+		"tdMode":     t.marginMode,
+		"side":       side,
+		"ordType":    "conditional",
+		"sz":         sz,
+		"reduceOnly": true,
+	}
+	if !t.netMode {
+		order["posSide"] = posSide
+	}
+	price := formatOKXStep(math.Round(triggerPrice/inst.TickSz)*inst.TickSz, inst.TickSz)
+	if stopLoss {
+		order["slTriggerPx"] = price
+		order["slOrdPx"] = "-1" // -1 表示触发后市价成交
+	} else {
+		order["tpTriggerPx"] = price
+		order["tpOrdPx"] = "-1"
+	}
+
+	_, err = t.request("POST", "/api/v5/trade/order-algo", nil, order)
+	return err
+}
+
+// SetStopLoss 设置止损单
+func (t *OKXTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return t.placeStop(symbol, positionSide, quantity, stopPrice, true)
+}
+
+// SetTakeProfit 设置止盈单
+func (t *OKXTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return t.placeStop(symbol, positionSide, quantity, takeProfitPrice, false)
+}
+
+// okxAlgoOrder OKX 条件单（止盈止损）
+type okxAlgoOrder struct {
+	AlgoID      string `json:"algoId"`
+	InstID      string `json:"instId"`
+	Side        string `json:"side"`
+	PosSide     string `json:"posSide"`
+	Sz          string `json:"sz"`
+	SlTriggerPx string `json:"slTriggerPx"`
+	TpTriggerPx string `json:"tpTriggerPx"`
+}
+
+// okxPendingOrder OKX 普通挂单
+type okxPendingOrder struct {
+	OrdID   string `json:"ordId"`
+	InstID  string `json:"instId"`
+	OrdType string `json:"ordType"`
+	Side    string `json:"side"`
+	PosSide string `json:"posSide"`
+	Sz      string `json:"sz"`
+	Px      string `json:"px"`
+}
+
+func (t *OKXTrader) pendingAlgoOrders(symbol string) ([]okxAlgoOrder, error) {
+	query := url.Values{"ordType": {"conditional"}, "instType": {"SWAP"}}
+	if symbol != "" {
+		query.Set("instId", okxInstID(symbol))
+	}
+	data, err := t.request("GET", "/api/v5/trade/orders-algo-pending", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取止盈止损单失败: %w", err)
+	}
+	var orders []okxAlgoOrder
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return nil, fmt.Errorf("解析止盈止损单失败: %w", err)
+	}
+	return orders, nil
+}
+
+func (t *OKXTrader) pendingOrders(symbol string) ([]okxPendingOrder, error) {
+	query := url.Values{"instType": {"SWAP"}}
+	if symbol != "" {
+		query.Set("instId", okxInstID(symbol))
+	}
+	data, err := t.request("GET", "/api/v5/trade/orders-pending", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+	var orders []okxPendingOrder
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+	return orders, nil
+}
+
+// cancelAlgoOrders 取消符合条件的止盈止损单
+func (t *OKXTrader) cancelAlgoOrders(symbol string, match func(okxAlgoOrder) bool) error {
+	orders, err := t.pendingAlgoOrders(symbol)
+	if err != nil {
+		return err
+	}
+	var cancels []map[string]string
+	for _, order := range orders {
+		if match(order) {
+			cancels = append(cancels, map[string]string{"algoId": order.AlgoID, "instId": order.InstID})
+		}
+	}
+	if len(cancels) == 0 {
+		return nil
+	}
+	if _, err := t.request("POST", "/api/v5/trade/cancel-algos", nil, cancels); err != nil {
+		return fmt.Errorf("取消止盈止损单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的 %d 个止盈/止损单", symbol, len(cancels))
+	return nil
+}
+
+// CancelStopLossOrders 仅取消止损单（不影响止盈单）
+func (t *OKXTrader) CancelStopLossOrders(symbol string) error {
+	return t.cancelAlgoOrders(symbol, func(o okxAlgoOrder) bool { return o.SlTriggerPx != "" })
+}
+
+// CancelTakeProfitOrders 仅取消止盈单（不影响止损单）
+func (t *OKXTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.cancelAlgoOrders(symbol, func(o okxAlgoOrder) bool { return o.TpTriggerPx != "" })
+}
+
+// CancelStopOrders 取消该币种的止盈/止损单
+func (t *OKXTrader) CancelStopOrders(symbol string) error {
+	return t.cancelAlgoOrders(symbol, func(okxAlgoOrder) bool { return true })
+}
+
+// CancelAllOrders 取消该币种的所有挂单（普通挂单和止盈止损单）
+func (t *OKXTrader) CancelAllOrders(symbol string) error {
+	orders, err := t.pendingOrders(symbol)
+	if err != nil {
+		return err
+	}
+	if len(orders) > 0 {
+		cancels := make([]map[string]string, 0, len(orders))
+		for _, order := range orders {
+			cancels = append(cancels, map[string]string{"ordId": order.OrdID, "instId": order.InstID})
+		}
+		if _, err := t.request("POST", "/api/v5/trade/cancel-batch-orders", nil, cancels); err != nil {
+			return fmt.Errorf("取消挂单失败: %w", err)
+		}
+	}
+	return t.CancelStopOrders(symbol)
+}
+
+// FormatQuantity 按合约面值和下单精度格式化数量（返回币数量）
+func (t *OKXTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	inst, err := t.getInstrument(okxInstID(symbol))
+	if err != nil {
+		return "", err
+	}
+	contracts := quantity / inst.CtVal
+	if inst.LotSz > 0 {
+		contracts = math.Floor(contracts/inst.LotSz+1e-9) * inst.LotSz
+	}
+	return formatOKXStep(contracts*inst.CtVal, inst.LotSz*inst.CtVal), nil
+}
+
+// GetOpenOrders 获取挂单（普通挂单和止盈止损单），symbol 为空时返回全部
+func (t *OKXTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	orders, err := t.pendingOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	algos, err := t.pendingAlgoOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	positionSide := func(posSide string) string {
+		if posSide == "net" || posSide == "" {
+			return "BOTH"
+		}
+		return strings.ToUpper(posSide)
+	}
+	baseQuantity := func(instID, sz string) float64 {
+		inst, err := t.getInstrument(instID)
+		if err != nil {
+			return okxFloat(sz)
+		}
+		return okxFloat(sz) * inst.CtVal
+	}
+
+	result := []decision.OpenOrderInfo{}
+	for _, o := range orders {
+		result = append(result, decision.OpenOrderInfo{
+			Symbol:       okxSymbol(o.InstID),
+			OrderID:      okxOrderID(o.OrdID),
+			Type:         strings.ToUpper(o.OrdType),
+			Side:         strings.ToUpper(o.Side),
+			PositionSide: positionSide(o.PosSide),
+			Quantity:     baseQuantity(o.InstID, o.Sz),
+			Price:        okxFloat(o.Px),
+		})
+	}
+	for _, o := range algos {
+		info := decision.OpenOrderInfo{
+			Symbol:       okxSymbol(o.InstID),
+			OrderID:      okxOrderID(o.AlgoID),
+			Side:         strings.ToUpper(o.Side),
+			PositionSide: positionSide(o.PosSide),
+			Quantity:     baseQuantity(o.InstID, o.Sz),
+		}
+		if o.SlTriggerPx != "" {
+			info.Type = "STOP_MARKET"
+			info.StopPrice = okxFloat(o.SlTriggerPx)
+		} else {
+			info.Type = "TAKE_PROFIT_MARKET"
+			info.StopPrice = okxFloat(o.TpTriggerPx)
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// GetSymbolTradingStatus 获取交易对交易状态（基于合约规格的 state 字段，live 为可交易）
+func (t *OKXTrader) GetSymbolTradingStatus(symbol string) (*SymbolTradingStatus, error) {
+	instruments, err := t.fetchInstruments()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(instruments))
+	for instID, inst := range instruments {
+		status := strings.ToUpper(inst.State)
+		if inst.State == "live" {
+			status = "TRADING"
+		}
+		statuses[okxSymbol(instID)] = status
+	}
+	return buildSymbolTradingStatus(symbol, statuses), nil
+}
+
+// GetMaxLeverage 获取交易对允许的最大杠杆（来自合约规格，全部交易对一次拉取后缓存）
+func (t *OKXTrader) GetMaxLeverage(symbol string) (int, error) {
+	return t.maxLeverage.lookup(symbol, func() (map[string]int, error) {
+		instruments, err := t.fetchInstruments()
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]int, len(instruments))
+		for instID, inst := range instruments {
+			values[okxSymbol(instID)] = inst.MaxLever
+		}
+		return values, nil
+	})
+}
+
+// GetUserTrades 获取成交明细（近三个月，symbol 为空时返回全部永续合约成交）
+// OKX 按时间倒序分页（after=billId），每个时间窗口内翻页取全后再统一排序
+func (t *OKXTrader) GetUserTrades(symbol string, startTime, endTime time.Time) ([]UserTrade, error) {
+	return collectUserTrades(startTime, endTime, math.MaxInt32, func(startMs, endMs int64) ([]UserTrade, error) {
+		query := url.Values{
+			"instType": {"SWAP"},
+			"begin":    {strconv.FormatInt(startMs, 10)},
+			"end":      {strconv.FormatInt(endMs, 10)},
+			"limit":    {strconv.Itoa(okxFillsPageLimit)},
+		}
+		if symbol != "" {
+			query.Set("instId", okxInstID(symbol))
+		}
+
+		var trades []UserTrade
+		for len(trades) < userTradesMaxResults {
+			data, err := t.request("GET", "/api/v5/trade/fills-history", query, nil)
+			if err != nil {
+				return nil, fmt.Errorf("获取成交记录失败: %w", err)
+			}
+			var fills []struct {
+				BillID   string `json:"billId"`
+				OrdID    string `json:"ordId"`
+				InstID   string `json:"instId"`
+				Side     string `json:"side"`
+				PosSide  string `json:"posSide"`
+				FillPx   string `json:"fillPx"`
+				FillSz   string `json:"fillSz"`
+				FillPnl  string `json:"fillPnl"`
+				Fee      string `json:"fee"`
+				FeeCcy   string `json:"feeCcy"`
+				ExecType string `json:"execType"`
+				Ts       string `json:"ts"`
+			}
+			if err := json.Unmarshal(data, &fills); err != nil {
+				return nil, fmt.Errorf("解析成交记录失败: %w", err)
+			}
+
+			for _, fill := range fills {
+				quantity := okxFloat(fill.FillSz)
+				if inst, err := t.getInstrument(fill.InstID); err == nil {
+					quantity *= inst.CtVal
+				}
+				positionSide := strings.ToUpper(fill.PosSide)
+				if fill.PosSide == "net" || fill.PosSide == "" {
+					positionSide = "BOTH"
+				}
+				ts, _ := strconv.ParseInt(fill.Ts, 10, 64)
+				trades = append(trades, UserTrade{
+					ID:           okxOrderID(fill.BillID),
+					OrderID:      okxOrderID(fill.OrdID),
+					Symbol:       okxSymbol(fill.InstID),
+					Side:         strings.ToUpper(fill.Side),
+					PositionSide: positionSide,
+					Price:        okxFloat(fill.FillPx),
+					Quantity:     quantity,
+					RealizedPnL:  okxFloat(fill.FillPnl),
+					Fee:          -okxFloat(fill.Fee), // OKX 手续费为负数表示扣除
+					FeeAsset:     fill.FeeCcy,
+					Maker:        fill.ExecType == "M",
+					Time:         ts,
+				})
+			}
+			if len(fills) < okxFillsPageLimit {
+				break
+			}
+			query.Set("after", fills[len(fills)-1].BillID)
+		}
+		return trades, nil
+	})
+}
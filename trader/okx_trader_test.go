@@ -0,0 +1,126 @@
+package trader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+var _ Trader = (*OKXTrader)(nil)
+
+// TestOKXSymbolMapping 测试交易对与 OKX 永续合约ID互相转换
+func TestOKXSymbolMapping(t *testing.T) {
+	cases := map[string]string{
+		"BTCUSDT":      "BTC-USDT-SWAP",
+		"ethusdt":      "ETH-USDT-SWAP",
+		"SOLUSDC":      "SOL-USDC-SWAP",
+		"1000PEPEUSDT": "1000PEPE-USDT-SWAP",
+	}
+	for symbol, instID := range cases {
+		if got := okxInstID(symbol); got != instID {
+			t.Errorf("okxInstID(%s) = %s, 期望 %s", symbol, got, instID)
+		}
+	}
+	if got := okxSymbol("BTC-USDT-SWAP"); got != "BTCUSDT" {
+		t.Errorf("okxSymbol 应返回 BTCUSDT, 实际 %s", got)
+	}
+	if _, err := NewOKXTrader("key", "secret", "", false, nil); err == nil {
+		t.Error("缺少 Passphrase 时应返回错误")
+	}
+}
+
+// newOKXTestTrader 创建指向 mock 服务器的 OKX 交易器
+// 合约面值 BTC=0.01，下单精度 0.01 张；账户已是双向持仓模式
+func newOKXTestTrader(t *testing.T, orders *[]map[string]interface{}) *OKXTrader {
+	server := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 校验签名
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(r.Header.Get("OK-ACCESS-TIMESTAMP") + r.Method + r.URL.RequestURI() + string(body)))
+		if r.Header.Get("OK-ACCESS-SIGN") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) ||
+			r.Header.Get("OK-ACCESS-PASSPHRASE") != "pass" || r.Header.Get("x-simulated-trading") != "1" {
+			w.Write([]byte(`{"code":"50113","msg":"Invalid Sign","data":[]}`))
+			return
+		}
+
+		var data string
+		switch r.URL.Path {
+		case "/api/v5/public/instruments":
+			data = `[{"instId":"BTC-USDT-SWAP","ctVal":"0.01","lotSz":"0.01","minSz":"0.01","tickSz":"0.1","lever":"100","state":"live"}]`
+		case "/api/v5/account/balance":
+			data = `[{"details":[{"ccy":"USDT","eq":"1100","availEq":"900","upl":"100"}]}]`
+		case "/api/v5/account/positions":
+			data = `[{"instId":"BTC-USDT-SWAP","posSide":"short","pos":"5","avgPx":"60000","markPx":"59000","upl":"50","lever":"10","liqPx":"70000"}]`
+		case "/api/v5/account/config":
+			data = `[{"posMode":"long_short_mode"}]`
+		case "/api/v5/trade/order":
+			var order map[string]interface{}
+			json.Unmarshal(body, &order)
+			*orders = append(*orders, order)
+			data = `[{"ordId":"123456789","sCode":"0","sMsg":""}]`
+		default:
+			data = `[]`
+		}
+		w.Write([]byte(`{"code":"0","msg":"","data":` + data + `}`))
+	}))
+
+	trader, err := NewOKXTrader("key", "secret", "pass", true, nil)
+	if err != nil {
+		t.Fatalf("创建 OKX 交易器失败: %v", err)
+	}
+	trader.baseURL = server.URL
+	return trader
+}
+
+// TestOKXTraderAccountAndOrders 测试余额、持仓的合约张数换算以及下单返回的订单ID
+func TestOKXTraderAccountAndOrders(t *testing.T) {
+	var orders []map[string]interface{}
+	trader := newOKXTestTrader(t, &orders)
+
+	balance, err := trader.GetBalance()
+	if err != nil {
+		t.Fatalf("获取余额失败: %v", err)
+	}
+	if balance["totalWalletBalance"] != 1000.0 || balance["availableBalance"] != 900.0 || balance["totalUnrealizedProfit"] != 100.0 {
+		t.Errorf("余额解析错误: %+v", balance)
+	}
+
+	positions, err := trader.GetPositions()
+	if err != nil {
+		t.Fatalf("获取持仓失败: %v", err)
+	}
+	if len(positions) != 1 {
+		t.Fatalf("应有1个持仓, 实际 %d", len(positions))
+	}
+	pos := positions[0]
+	if pos["symbol"] != "BTCUSDT" || pos["side"] != "short" || pos["positionAmt"] != -0.05 {
+		t.Errorf("持仓解析错误（5张×0.01应为-0.05 BTC空仓）: %+v", pos)
+	}
+
+	result, err := trader.OpenLong("BTCUSDT", 0.123, 10)
+	if err != nil {
+		t.Fatalf("开多仓失败: %v", err)
+	}
+	if id, ok := result["orderId"].(int64); !ok || id != 123456789 {
+		t.Errorf("订单ID应为 int64(123456789), 实际 %#v", result["orderId"])
+	}
+	if len(orders) != 1 {
+		t.Fatalf("应下1笔订单, 实际 %d", len(orders))
+	}
+	order := orders[0]
+	if order["instId"] != "BTC-USDT-SWAP" || order["side"] != "buy" || order["posSide"] != "long" || order["sz"] != "12.30" {
+		t.Errorf("下单参数错误（0.123 BTC 应为 12.30 张）: %+v", order)
+	}
+
+	if _, err := trader.CloseShort("BTCUSDT", 0); err != nil {
+		t.Fatalf("平空仓失败: %v", err)
+	}
+	closeOrder := orders[len(orders)-1]
+	if closeOrder["side"] != "buy" || closeOrder["posSide"] != "short" || closeOrder["sz"] != "5.00" {
+		t.Errorf("全部平空应买入5张: %+v", closeOrder)
+	}
+}
@@ -0,0 +1,99 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+var (
+	// ErrCancelOrderUnsupported 交易所不支持按订单ID撤单
+	ErrCancelOrderUnsupported = errors.New("该交易所不支持按订单ID撤单")
+	// ErrOpenOrderNotFound 挂单不存在（可能已成交或已被撤销）
+	ErrOpenOrderNotFound = errors.New("挂单不存在或已成交")
+)
+
+// GetOpenOrders 获取交易所上的未成交挂单（symbol 为空时返回全部）
+func (at *AutoTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	return at.trader.GetOpenOrders(symbol)
+}
+
+// CancelOpenOrder 手动撤销一个挂单，并在决策日志中记录人工干预
+func (at *AutoTrader) CancelOpenOrder(symbol string, orderID int64) error {
+	canceler, ok := at.trader.(orderCanceler)
+	if !ok {
+		return ErrCancelOrderUnsupported
+	}
+
+	orders, err := at.trader.GetOpenOrders(symbol)
+	if err != nil {
+		return fmt.Errorf("获取挂单失败: %w", err)
+	}
+	var target *decision.OpenOrderInfo
+	for i := range orders {
+		if orders[i].OrderID == orderID {
+			target = &orders[i]
+			break
+		}
+	}
+	if target == nil {
+		return ErrOpenOrderNotFound
+	}
+
+	actionRecord := logger.DecisionAction{
+		Action:    "cancel_order",
+		Symbol:    symbol,
+		Quantity:  target.Quantity,
+		Price:     target.Price,
+		OrderID:   orderID,
+		Timestamp: time.Now(),
+	}
+	if target.Price == 0 {
+		actionRecord.Price = target.StopPrice
+	}
+
+	if !at.dryRunSkip(&actionRecord, "手动撤销 %s 挂单 %d", symbol, orderID) {
+		if err = canceler.CancelOrder(symbol, orderID); err != nil {
+			recordActionError(&actionRecord, err)
+		}
+	}
+	actionRecord.Success = err == nil
+	at.logManualCancel(target, actionRecord)
+
+	if err != nil {
+		return err
+	}
+	at.log().Infof("  🖐 [%s] 已手动撤销 %s %s %s 挂单 (订单ID: %d)", at.name, symbol, target.Type, target.Side, orderID)
+	return nil
+}
+
+// logManualCancel 将手动撤单写入决策日志，留下人工干预的记录
+func (at *AutoTrader) logManualCancel(order *decision.OpenOrderInfo, action logger.DecisionAction) {
+	entry := fmt.Sprintf("🖐 手动撤单: %s %s %s 数量 %.4f 价格 %.4f 触发价 %.4f (订单ID: %d)",
+		order.Symbol, order.Type, order.Side, order.Quantity, order.Price, order.StopPrice, order.OrderID)
+	if action.DryRun {
+		entry += " [模拟运行，未撤单]"
+	}
+	at.logManualAction(entry, action)
+}
+
+// logManualAction 把一次人工干预（手动撤单、手动平仓）写入决策日志，失败时在执行日志中附上错误
+func (at *AutoTrader) logManualAction(entry string, action logger.DecisionAction) {
+	if !action.DryRun && !action.Success {
+		entry += " ❌ " + action.Error
+	}
+
+	record := &logger.DecisionRecord{
+		Exchange:     at.config.Exchange,
+		Decisions:    []logger.DecisionAction{action},
+		ExecutionLog: []string{entry},
+		Success:      action.Success,
+		ErrorMessage: action.Error,
+	}
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		at.log().Warnf("⚠ 保存人工干预记录失败: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// TestCancelOpenOrder 测试手动撤单：只撤指定挂单并记入决策日志，挂单不存在或交易所不支持时返回对应错误
+func TestCancelOpenOrder(t *testing.T) {
+	orders := []decision.OpenOrderInfo{
+		{Symbol: "BTCUSDT", OrderID: 7, Type: "STOP_MARKET", Side: "SELL", Quantity: 0.1, StopPrice: 48000, ReduceOnly: true},
+	}
+	mock := &cancelMockTrader{MockTrader: &MockTrader{}, orders: orders}
+	decisionLogger := logger.NewDecisionLogger(t.TempDir())
+	at := &AutoTrader{name: "manual-cancel", trader: mock, decisionLogger: decisionLogger}
+
+	if err := at.CancelOpenOrder("BTCUSDT", 8); err != ErrOpenOrderNotFound {
+		t.Errorf("挂单不存在时应返回 ErrOpenOrderNotFound, 实际 %v", err)
+	}
+	if err := at.CancelOpenOrder("BTCUSDT", 7); err != nil {
+		t.Fatalf("撤单失败: %v", err)
+	}
+	if len(mock.cancelled) != 1 || mock.cancelled[0] != 7 || mock.clearedAll {
+		t.Errorf("应只撤销订单 7, 实际 %v clearedAll=%v", mock.cancelled, mock.clearedAll)
+	}
+
+	records, err := decisionLogger.GetLatestRecords(10)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("应记录 1 条手动撤单日志, 实际 %d, %v", len(records), err)
+	}
+	record := records[0]
+	if len(record.Decisions) != 1 || record.Decisions[0].OrderID != 7 || record.Decisions[0].Price != 48000 || !record.Success {
+		t.Errorf("撤单记录不正确: %+v", record.Decisions)
+	}
+	if len(record.ExecutionLog) != 1 || !strings.Contains(record.ExecutionLog[0], "手动撤单") {
+		t.Errorf("执行日志应记录手动撤单, 实际 %v", record.ExecutionLog)
+	}
+
+	at.trader = &MockTrader{}
+	if err := at.CancelOpenOrder("BTCUSDT", 7); err != ErrCancelOrderUnsupported {
+		t.Errorf("不支持撤单的交易所应返回 ErrCancelOrderUnsupported, 实际 %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+	"time"
+)
+
+// confirmOpen 启用开仓确认（OpenVerifyDelay>0）时，下单后等待片刻重新读取持仓和挂单，
+// 确认开仓确实生效，避免交易所异步拒单时仍记录为开仓成功
+// side 为 long/short；mayRest 表示本次可能是未立即成交的限价单，此时即使未启用确认也会检查一次。
+// 返回 resting=true 表示限价单仍在挂单中尚未成交（调用方不应记录为已开仓）；
+// 无法读取持仓或挂单时不阻断（只记录警告），可能挂单的按挂单中处理，等下个周期再确认
+func (at *AutoTrader) confirmOpen(symbol, side string, mayRest bool) (resting bool, err error) {
+	delay := at.config.OpenVerifyDelay
+	if delay <= 0 && !mayRest {
+		return false, nil
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		at.log().Warnf("  ⚠️ [%s] 开仓确认读取持仓失败，跳过确认: %v", at.name, err)
+		return mayRest, nil
+	}
+	for _, pos := range positions {
+		if pos["symbol"] != symbol || pos["side"] != side {
+			continue
+		}
+		if amt, ok := pos["positionAmt"].(float64); !ok || math.Abs(amt) > 0 {
+			at.log().Infof("  ✓ [%s] 开仓确认: %s %s 持仓已生效", at.name, symbol, side)
+			return false, nil
+		}
+	}
+
+	orders, err := at.trader.GetOpenOrders(symbol)
+	if err != nil {
+		at.log().Warnf("  ⚠️ [%s] 开仓确认读取挂单失败，跳过确认: %v", at.name, err)
+		return mayRest, nil
+	}
+	for _, order := range orders {
+		if isEntryOrder(order, side) {
+			at.log().Infof("  ✓ [%s] 开仓确认: %s %s 限价单挂单中（订单ID %d），成交前不记录开仓", at.name, symbol, side, order.OrderID)
+			return true, nil
+		}
+	}
+
+	if delay <= 0 {
+		// 未启用确认时不判定拒单：交易所可能尚未同步挂单，交给下个周期的 resolvePendingEntries 处理
+		return true, nil
+	}
+	return false, fmt.Errorf("❌ 开仓未生效：下单 %v 后未检测到 %s %s 持仓或挂单，订单可能已被交易所拒绝", delay, symbol, side)
+}
+
+// isEntryOrder 是否为对应方向的开仓挂单（排除止损/止盈单）
+func isEntryOrder(order decision.OpenOrderInfo, side string) bool {
+	return order.EntrySide() == side
+}
@@ -478,7 +478,7 @@ func TestOrderStrategy_ConservativeHybrid_Timeout(t *testing.T) {
 	}))
 	defer mockServer.Close()
 
-	trader := NewFuturesTrader("test_key", "test_secret", "test_user", "conservative_hybrid", -0.03, 3) // 3秒超時
+	trader := NewFuturesTrader("test_key", "test_secret", false, "test_user", "conservative_hybrid", -0.03, 3, nil) // 3秒超時
 	trader.client.BaseURL = mockServer.URL
 	trader.client.HTTPClient = mockServer.Client()
 
@@ -732,7 +732,7 @@ func TestOrderStrategy_TimeoutSettings(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			trader := NewFuturesTrader("test_key", "test_secret", "test_user", tc.strategy, -0.03, tc.timeoutSeconds)
+			trader := NewFuturesTrader("test_key", "test_secret", false, "test_user", tc.strategy, -0.03, tc.timeoutSeconds, nil)
 
 			if trader.orderStrategy != tc.strategy {
 				t.Errorf("策略設置錯誤: 預期 %s, 實際 %s", tc.strategy, trader.orderStrategy)
@@ -788,7 +788,7 @@ func TestOrderStrategy_ParameterValidation(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			trader := NewFuturesTrader("test_key", "test_secret", "test_user", tc.strategy, tc.offset, tc.timeout)
+			trader := NewFuturesTrader("test_key", "test_secret", false, "test_user", tc.strategy, tc.offset, tc.timeout, nil)
 
 			if trader == nil && !tc.shouldErr {
 				t.Error("預期創建成功，但返回 nil")
@@ -941,7 +941,7 @@ func TestOrderStrategy_DatabaseIntegration(t *testing.T) {
 				cfg.strategy, cfg.offset, cfg.timeout)
 
 			// 驗證配置可以被正確設置
-			trader := NewFuturesTrader("key", "secret", "user", cfg.strategy, cfg.offset, cfg.timeout)
+			trader := NewFuturesTrader("key", "secret", false, "user", cfg.strategy, cfg.offset, cfg.timeout, nil)
 
 			if trader.orderStrategy != cfg.strategy {
 				t.Errorf("策略不匹配: 預期 %s, 實際 %s", cfg.strategy, trader.orderStrategy)
@@ -972,7 +972,7 @@ func BenchmarkOrderStrategy_MarketOrder(b *testing.B) {
 	}))
 	defer mockServer.Close()
 
-	trader := NewFuturesTrader("key", "secret", "user", "market_only", -0.03, 60)
+	trader := NewFuturesTrader("key", "secret", false, "user", "market_only", -0.03, 60, nil)
 	trader.client.BaseURL = mockServer.URL
 	trader.client.HTTPClient = mockServer.Client()
 
@@ -0,0 +1,153 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// 决策可指定的开仓订单类型
+const (
+	OrderTypeMarket = "market" // 市价单，立即成交（适合紧急入场）
+	OrderTypeLimit  = "limit"  // 限价单，按 AI 给出的价格挂单（适合耐心入场）
+)
+
+// maxLimitPriceDeviation AI 给出的限价偏离当前价的最大比例（5%），超出视为价格错误
+const maxLimitPriceDeviation = 0.05
+
+// orderTypeTrader 支持按决策指定订单类型开仓的交易所（未实现的交易所使用默认下单策略）
+// 限价单按 limitPrice 以 GTC 挂单，未成交时保留在交易所，可通过 cancel_order 撤销
+type orderTypeTrader interface {
+	OpenLongWithOrder(symbol string, quantity float64, leverage int, orderType string, limitPrice float64) (map[string]interface{}, error)
+	OpenShortWithOrder(symbol string, quantity float64, leverage int, orderType string, limitPrice float64) (map[string]interface{}, error)
+}
+
+// orderCanceler 支持按订单ID撤单的交易所（用于 cancel_order 决策）
+type orderCanceler interface {
+	CancelOrder(symbol string, orderID int64) error
+}
+
+// validateLimitEntry 校验 AI 给出的限价：必须接近当前价，且位于止损和止盈之间
+func validateLimitEntry(d *decision.Decision, side string, currentPrice float64) error {
+	if d.LimitPrice <= 0 {
+		return fmt.Errorf("❌ %s 限价单必须提供 limit_price", d.Symbol)
+	}
+
+	deviation := math.Abs(d.LimitPrice-currentPrice) / currentPrice
+	if deviation > maxLimitPriceDeviation {
+		return fmt.Errorf("❌ %s 限价 %.4f 偏离当前价 %.4f 达 %.2f%%（上限 %.0f%%），拒绝开仓",
+			d.Symbol, d.LimitPrice, currentPrice, deviation*100, maxLimitPriceDeviation*100)
+	}
+
+	if side == "long" && (d.LimitPrice <= d.StopLoss || d.LimitPrice >= d.TakeProfit) {
+		return fmt.Errorf("❌ %s 多单限价 %.4f 必须在止损价 %.4f 和止盈价 %.4f 之间",
+			d.Symbol, d.LimitPrice, d.StopLoss, d.TakeProfit)
+	}
+	if side == "short" && (d.LimitPrice >= d.StopLoss || d.LimitPrice <= d.TakeProfit) {
+		return fmt.Errorf("❌ %s 空单限价 %.4f 必须在止盈价 %.4f 和止损价 %.4f 之间",
+			d.Symbol, d.LimitPrice, d.TakeProfit, d.StopLoss)
+	}
+	return nil
+}
+
+// entryPriceFor 返回开仓使用的入场价：限价单使用 AI 给出的限价（先校验），否则使用当前价
+// 交易所不支持按决策指定订单类型时会按默认策略下单，因此仍使用当前价
+func (at *AutoTrader) entryPriceFor(d *decision.Decision, side string, currentPrice float64) (float64, error) {
+	if d.OrderType != OrderTypeLimit {
+		return currentPrice, nil
+	}
+	if _, ok := at.trader.(orderTypeTrader); !ok {
+		return currentPrice, nil
+	}
+	if err := validateLimitEntry(d, side, currentPrice); err != nil {
+		return 0, err
+	}
+	return d.LimitPrice, nil
+}
+
+// openPosition 开仓：决策指定了订单类型时按决策下单，否则使用交易员配置的下单策略
+func (at *AutoTrader) openPosition(d *decision.Decision, side string, quantity float64) (map[string]interface{}, error) {
+	if d.OrderType != "" {
+		if t, ok := at.trader.(orderTypeTrader); ok {
+			at.log().Infof("  📋 使用 AI 指定的订单类型: %s (限价: %.4f)", d.OrderType, d.LimitPrice)
+			if side == "long" {
+				return t.OpenLongWithOrder(d.Symbol, quantity, d.Leverage, d.OrderType, d.LimitPrice)
+			}
+			return t.OpenShortWithOrder(d.Symbol, quantity, d.Leverage, d.OrderType, d.LimitPrice)
+		}
+		at.log().Warnf("  ⚠️ [%s] 交易所 %s 不支持按决策指定订单类型，使用默认下单策略", at.name, at.exchange)
+	}
+
+	if side == "long" {
+		return at.trader.OpenLong(d.Symbol, quantity, d.Leverage)
+	}
+	return at.trader.OpenShort(d.Symbol, quantity, d.Leverage)
+}
+
+// executeCancelOrderWithRecord 撤销未成交的限价开仓单：指定 order_id 时只撤该单，否则撤销该币种全部限价开仓单
+// 撤单后移除对应的挂单登记；该币种既无持仓也无开仓挂单时，清理遗留的止损止盈单并释放币种名额
+func (at *AutoTrader) executeCancelOrderWithRecord(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	at.log().Infof("  🗑️ 撤销限价开仓单: %s (订单ID: %d)", d.Symbol, d.OrderID)
+
+	canceler, ok := at.trader.(orderCanceler)
+	if !ok {
+		return fmt.Errorf("交易所 %s 不支持按订单撤单", at.exchange)
+	}
+
+	orders, err := at.trader.GetOpenOrders(d.Symbol)
+	if err != nil {
+		return fmt.Errorf("获取挂单失败: %w", err)
+	}
+	var targets []decision.OpenOrderInfo
+	remaining := 0
+	for _, order := range orders {
+		if order.EntrySide() == "" {
+			continue
+		}
+		if d.OrderID == 0 || order.OrderID == d.OrderID {
+			targets = append(targets, order)
+		} else {
+			remaining++
+		}
+	}
+	if len(targets) == 0 {
+		// 挂单可能已在两个周期之间成交或被撤销
+		at.log().Infof("  ℹ️  %s 没有对应的未成交限价开仓单，跳过撤单", d.Symbol)
+		return nil
+	}
+
+	if at.dryRunSkip(actionRecord, "撤销 %s 的 %d 个限价开仓单", d.Symbol, len(targets)) {
+		return nil
+	}
+
+	for _, order := range targets {
+		if err := canceler.CancelOrder(d.Symbol, order.OrderID); err != nil {
+			return fmt.Errorf("撤销订单 %d 失败: %w", order.OrderID, err)
+		}
+		actionRecord.OrderID = order.OrderID
+		at.dropPendingEntry(order)
+		at.log().Infof("  ✓ 已撤销限价开仓单 %s %s @ %.4f (订单ID: %d)", d.Symbol, order.EntrySide(), order.Price, order.OrderID)
+	}
+
+	if remaining > 0 {
+		return nil
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		at.log().Warnf("  ⚠️ 获取持仓失败，保留 %s 的止损止盈单: %v", d.Symbol, err)
+		return nil
+	}
+	for _, pos := range positions {
+		if pos["symbol"] == d.Symbol {
+			return nil
+		}
+	}
+	if at.symbolRegistry != nil {
+		at.symbolRegistry.Release(d.Symbol, at.id)
+	}
+	if err := at.trader.CancelAllOrders(d.Symbol); err != nil {
+		at.log().Warnf("  ⚠️ 清理 %s 遗留的止损止盈单失败: %v", d.Symbol, err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package trader
+
+import (
+	"encoding/json"
+	"net/http"
+	"nofx/decision"
+	"nofx/logger"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// orderTypeMockTrader 记录按决策指定订单类型的开仓调用
+type orderTypeMockTrader struct {
+	*MockTrader
+	orderType  string
+	limitPrice float64
+}
+
+func (m *orderTypeMockTrader) OpenLongWithOrder(symbol string, quantity float64, leverage int, orderType string, limitPrice float64) (map[string]interface{}, error) {
+	m.orderType, m.limitPrice = orderType, limitPrice
+	return m.OpenLong(symbol, quantity, leverage)
+}
+
+func (m *orderTypeMockTrader) OpenShortWithOrder(symbol string, quantity float64, leverage int, orderType string, limitPrice float64) (map[string]interface{}, error) {
+	m.orderType, m.limitPrice = orderType, limitPrice
+	return m.OpenShort(symbol, quantity, leverage)
+}
+
+// cancelMockTrader 返回固定挂单并记录撤单的测试 trader
+type cancelMockTrader struct {
+	*MockTrader
+	orders     []decision.OpenOrderInfo
+	cancelled  []int64
+	clearedAll bool
+}
+
+func (m *cancelMockTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	return m.orders, nil
+}
+
+func (m *cancelMockTrader) CancelOrder(symbol string, orderID int64) error {
+	m.cancelled = append(m.cancelled, orderID)
+	return nil
+}
+
+func (m *cancelMockTrader) CancelAllOrders(symbol string) error {
+	m.clearedAll = true
+	return nil
+}
+
+// TestPerDecisionOrderType 测试 AI 按决策指定市价/限价开仓
+func TestPerDecisionOrderType(t *testing.T) {
+	mock := &orderTypeMockTrader{MockTrader: &MockTrader{}}
+	at := &AutoTrader{name: "order-type", trader: mock}
+
+	// 市价单：按当前价计算入场价，透传订单类型
+	market := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, StopLoss: 48000, TakeProfit: 55000, OrderType: OrderTypeMarket}
+	if price, err := at.entryPriceFor(market, "long", 50000); err != nil || price != 50000 {
+		t.Fatalf("市价单入场价应为当前价: price=%.2f err=%v", price, err)
+	}
+	if _, err := at.openPosition(market, "long", 0.1); err != nil || mock.orderType != OrderTypeMarket {
+		t.Errorf("市价单未按决策下单: type=%s err=%v", mock.orderType, err)
+	}
+
+	// 限价单：使用 AI 给出的限价
+	limit := &decision.Decision{Symbol: "BTCUSDT", Action: "open_short", Leverage: 5, StopLoss: 52000, TakeProfit: 45000, OrderType: OrderTypeLimit, LimitPrice: 50500}
+	if price, err := at.entryPriceFor(limit, "short", 50000); err != nil || price != 50500 {
+		t.Fatalf("限价单入场价应为限价: price=%.2f err=%v", price, err)
+	}
+	if _, err := at.openPosition(limit, "short", 0.1); err != nil || mock.orderType != OrderTypeLimit || mock.limitPrice != 50500 {
+		t.Errorf("限价单未按决策下单: type=%s price=%.2f err=%v", mock.orderType, mock.limitPrice, err)
+	}
+
+	// 限价偏离当前价过大或不在止损止盈之间时拒绝
+	limit.LimitPrice = 44000
+	if _, err := at.entryPriceFor(limit, "short", 50000); err == nil {
+		t.Error("偏离当前价超过5%的限价应被拒绝")
+	}
+	limit.LimitPrice = 49000
+	limit.TakeProfit = 49500
+	if _, err := at.entryPriceFor(limit, "short", 50000); err == nil {
+		t.Error("空单限价低于止盈价应被拒绝")
+	}
+
+	// 交易所不支持时使用默认策略和当前价
+	fallback := &AutoTrader{name: "fallback", trader: &MockTrader{}}
+	if price, err := fallback.entryPriceFor(limit, "short", 50000); err != nil || price != 50000 {
+		t.Errorf("不支持订单类型的交易所应使用当前价: price=%.2f err=%v", price, err)
+	}
+}
+
+// TestFuturesTraderPerDecisionOrderType 测试币安按决策覆盖默认下单策略
+func TestFuturesTraderPerDecisionOrderType(t *testing.T) {
+	var orderType, orderPrice string
+	mockServer := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/time":
+			json.NewEncoder(w).Encode(map[string]interface{}{"serverTime": time.Now().UnixMilli()})
+		case "/fapi/v1/leverage":
+			json.NewEncoder(w).Encode(map[string]interface{}{"leverage": 10, "symbol": "BTCUSDT"})
+		case "/fapi/v1/allOpenOrders", "/fapi/v1/positionSide/dual":
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "msg": "success"})
+		case "/fapi/v1/exchangeInfo":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"symbols": []map[string]interface{}{{
+					"symbol": "BTCUSDT",
+					"filters": []map[string]interface{}{
+						{"filterType": "LOT_SIZE", "stepSize": "0.001"},
+						{"filterType": "PRICE_FILTER", "tickSize": "0.1"},
+					},
+				}},
+			})
+		case "/fapi/v1/ticker/price", "/fapi/v2/ticker/price":
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"symbol": "BTCUSDT", "price": "50000.0"}})
+		case "/fapi/v1/order":
+			r.ParseForm()
+			orderType, orderPrice = r.Form.Get("type"), r.Form.Get("price")
+			json.NewEncoder(w).Encode(&futures.CreateOrderResponse{OrderID: 1, Symbol: "BTCUSDT", Status: "NEW", Type: futures.OrderType(orderType)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	client := futures.NewClient("test_key", "test_secret")
+	client.BaseURL = mockServer.URL
+	client.HTTPClient = mockServer.Client()
+
+	// 默认 limit_only，决策指定市价单
+	trader := newFuturesTraderWithClient(client, "limit_only", -0.03, 60)
+	if _, err := trader.OpenLongWithOrder("BTCUSDT", 0.01, 10, OrderTypeMarket, 0); err != nil {
+		t.Fatalf("市价开多失败: %v", err)
+	}
+	if orderType != "MARKET" {
+		t.Errorf("决策指定市价单，实际下单类型 %s", orderType)
+	}
+
+	// 默认 market_only，决策指定限价单，使用 AI 给出的限价
+	trader = newFuturesTraderWithClient(client, "market_only", -0.03, 60)
+	if _, err := trader.OpenShortWithOrder("BTCUSDT", 0.01, 10, OrderTypeLimit, 50250); err != nil {
+		t.Fatalf("限价开空失败: %v", err)
+	}
+	if orderType != "LIMIT" || orderPrice != "50250.0" {
+		t.Errorf("决策指定限价单，实际下单类型 %s 价格 %s", orderType, orderPrice)
+	}
+}
+
+// TestCancelOrderDecision 测试 cancel_order 撤销未成交的限价开仓单
+func TestCancelOrderDecision(t *testing.T) {
+	orders := []decision.OpenOrderInfo{
+		{Symbol: "BTCUSDT", OrderID: 1, Type: "LIMIT", Side: "BUY", PositionSide: "LONG", Price: 49000},
+		{Symbol: "BTCUSDT", OrderID: 2, Type: "LIMIT", Side: "SELL", PositionSide: "SHORT", Price: 51000},
+		{Symbol: "BTCUSDT", OrderID: 3, Type: "STOP_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 48000},
+	}
+
+	// 指定 order_id：只撤该单，仍有其他开仓挂单时保留止损止盈单
+	mock := &cancelMockTrader{MockTrader: &MockTrader{}, orders: orders}
+	at := &AutoTrader{name: "cancel", trader: mock, config: AutoTraderConfig{}}
+	at.trackPendingEntry(&decision.Decision{Symbol: "BTCUSDT"}, "long", 1, 0.1, 49000)
+	at.trackPendingEntry(&decision.Decision{Symbol: "BTCUSDT"}, "short", 2, 0.1, 51000)
+	record := &logger.DecisionAction{}
+	if err := at.executeCancelOrderWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "cancel_order", OrderID: 2}, record); err != nil {
+		t.Fatalf("撤单失败: %v", err)
+	}
+	if len(mock.cancelled) != 1 || mock.cancelled[0] != 2 || record.OrderID != 2 || mock.clearedAll {
+		t.Errorf("应只撤销订单 2, 实际 %v clearedAll=%v", mock.cancelled, mock.clearedAll)
+	}
+	if at.hasPendingEntry("BTCUSDT", "short") || !at.hasPendingEntry("BTCUSDT", "long") {
+		t.Error("撤单后应只移除订单 2 的挂单登记")
+	}
+
+	// 未指定 order_id：撤销全部开仓挂单（不撤止损单），无持仓时清理遗留的止损止盈单
+	mock = &cancelMockTrader{MockTrader: &MockTrader{}, orders: orders}
+	at.trader = mock
+	if err := at.executeCancelOrderWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "cancel_order"}, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("撤单失败: %v", err)
+	}
+	if len(mock.cancelled) != 2 || !mock.clearedAll {
+		t.Errorf("应撤销 2 个开仓挂单并清理止损止盈单, 实际 %v clearedAll=%v", mock.cancelled, mock.clearedAll)
+	}
+	if len(at.pendingEntries) != 0 {
+		t.Errorf("撤销全部开仓挂单后应清空挂单登记, 剩余 %d", len(at.pendingEntries))
+	}
+
+	// 交易所不支持按订单撤单
+	at.trader = &MockTrader{}
+	if err := at.executeCancelOrderWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "cancel_order"}, &logger.DecisionAction{}); err == nil {
+		t.Error("不支持撤单的交易所应返回错误")
+	}
+}
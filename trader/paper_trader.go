@@ -0,0 +1,602 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPaperBalance 模拟盘未指定初始资金时使用的资金（USDT）
+const DefaultPaperBalance = 10000.0
+
+// defaultPaperStateDir 模拟盘账户状态文件目录（每个交易员一个 JSON 文件，重启后继续）
+const defaultPaperStateDir = "paper_accounts"
+
+// paperMaxTrades 模拟盘保留的成交记录条数
+const paperMaxTrades = 1000
+
+// paperPosition 模拟持仓
+type paperPosition struct {
+	Symbol            string  `json:"symbol"`
+	Side              string  `json:"side"` // long / short
+	Quantity          float64 `json:"quantity"`
+	EntryPrice        float64 `json:"entry_price"`
+	Leverage          int     `json:"leverage"`
+	StopLoss          float64 `json:"stop_loss"`
+	TakeProfit        float64 `json:"take_profit"`
+	StopLossOrderID   int64   `json:"stop_loss_order_id"`
+	TakeProfitOrderID int64   `json:"take_profit_order_id"`
+}
+
+// paperState 模拟盘账户状态（持久化到文件）
+type paperState struct {
+	WalletBalance float64                   `json:"wallet_balance"` // 钱包余额（已扣除手续费、计入已实现盈亏）
+	NextOrderID   int64                     `json:"next_order_id"`
+	Positions     map[string]*paperPosition `json:"positions"` // key: symbol_side
+	Leverage      map[string]int            `json:"leverage"`
+	Trades        []UserTrade               `json:"trades"`
+}
+
+// PaperTrader 模拟盘交易器：使用实时行情价格模拟成交、手续费和止损止盈触发，不向任何交易所下单
+type PaperTrader struct {
+	mu           sync.Mutex
+	state        paperState
+	statePath    string // 为空表示不持久化
+	takerFeeRate float64
+	makerFeeRate float64
+	useMaker     bool // 只挂限价单的策略按 Maker 费率计算开平仓手续费
+	priceFunc    func(symbol string) (float64, error)
+}
+
+// NewPaperTrader 创建模拟盘交易器；statePath 存在时恢复之前的模拟账户
+func NewPaperTrader(statePath string, initialBalance, takerFeeRate, makerFeeRate float64, orderStrategy string) *PaperTrader {
+	if initialBalance <= 0 {
+		initialBalance = DefaultPaperBalance
+	}
+	if takerFeeRate <= 0 {
+		takerFeeRate = 0.0004
+	}
+	if makerFeeRate <= 0 {
+		makerFeeRate = 0.0002
+	}
+
+	t := &PaperTrader{
+		state: paperState{
+			WalletBalance: initialBalance,
+			NextOrderID:   1,
+			Positions:     make(map[string]*paperPosition),
+			Leverage:      make(map[string]int),
+		},
+		statePath:    statePath,
+		takerFeeRate: takerFeeRate,
+		makerFeeRate: makerFeeRate,
+		useMaker:     orderStrategy == "limit_only",
+		priceFunc:    market.NewAPIClient().GetCurrentPrice,
+	}
+
+	if statePath != "" {
+		if err := t.load(); err != nil {
+			log.Printf("⚠️ 读取模拟盘账户失败，使用初始资金 %.2f USDT 重新开始: %v", initialBalance, err)
+		}
+	}
+	return t
+}
+
+// load 从状态文件恢复模拟账户（文件不存在时保持初始状态）
+func (t *PaperTrader) load() error {
+	data, err := os.ReadFile(t.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state paperState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("解析模拟盘状态失败: %w", err)
+	}
+	if state.Positions == nil {
+		state.Positions = make(map[string]*paperPosition)
+	}
+	if state.Leverage == nil {
+		state.Leverage = make(map[string]int)
+	}
+	if state.NextOrderID <= 0 {
+		state.NextOrderID = 1
+	}
+	t.state = state
+	log.Printf("✅ 恢复模拟盘账户: 钱包 %.2f USDT, %d 个持仓", state.WalletBalance, len(state.Positions))
+	return nil
+}
+
+// save 持久化模拟账户（先写临时文件再替换，避免写一半）
+func (t *PaperTrader) save() {
+	if t.statePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		log.Printf("⚠️ 序列化模拟盘状态失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(t.statePath), 0755); err != nil {
+		log.Printf("⚠️ 创建模拟盘状态目录失败: %v", err)
+		return
+	}
+	tmp := t.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("⚠️ 写入模拟盘状态失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, t.statePath); err != nil {
+		log.Printf("⚠️ 保存模拟盘状态失败: %v", err)
+	}
+}
+
+func paperKey(symbol, side string) string {
+	return symbol + "_" + side
+}
+
+func (t *PaperTrader) nextOrderID() int64 {
+	id := t.state.NextOrderID
+	t.state.NextOrderID++
+	return id
+}
+
+// orderFeeRate 开平仓手续费率（止损止盈触发按 Taker 计算）
+func (t *PaperTrader) orderFeeRate() float64 {
+	if t.useMaker {
+		return t.makerFeeRate
+	}
+	return t.takerFeeRate
+}
+
+// markPrices 获取所有持仓币种的最新价格，获取失败时使用开仓价（不触发止损止盈）
+func (t *PaperTrader) markPrices() map[string]float64 {
+	prices := make(map[string]float64)
+	for _, pos := range t.state.Positions {
+		if _, ok := prices[pos.Symbol]; ok {
+			continue
+		}
+		price, err := t.priceFunc(pos.Symbol)
+		if err != nil || price <= 0 {
+			log.Printf("⚠️ 模拟盘获取 %s 价格失败，使用开仓价: %v", pos.Symbol, err)
+			continue
+		}
+		prices[pos.Symbol] = price
+	}
+	return prices
+}
+
+// triggerStops 标记价格穿过止损/止盈价时按触发价模拟成交
+func (t *PaperTrader) triggerStops(prices map[string]float64) {
+	changed := false
+	for key, pos := range t.state.Positions {
+		price, ok := prices[pos.Symbol]
+		if !ok {
+			continue
+		}
+		var fillPrice float64
+		var reason string
+		if pos.Side == "long" {
+			if pos.StopLoss > 0 && price <= pos.StopLoss {
+				fillPrice, reason = pos.StopLoss, "止损"
+			} else if pos.TakeProfit > 0 && price >= pos.TakeProfit {
+				fillPrice, reason = pos.TakeProfit, "止盈"
+			}
+		} else {
+			if pos.StopLoss > 0 && price >= pos.StopLoss {
+				fillPrice, reason = pos.StopLoss, "止损"
+			} else if pos.TakeProfit > 0 && price <= pos.TakeProfit {
+				fillPrice, reason = pos.TakeProfit, "止盈"
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		orderID := pos.StopLossOrderID
+		if reason == "止盈" {
+			orderID = pos.TakeProfitOrderID
+		}
+		pnl := t.settle(pos, pos.Quantity, fillPrice, t.takerFeeRate, orderID)
+		delete(t.state.Positions, key)
+		changed = true
+		log.Printf("📄 模拟盘 %s %s 触发%s @ %.4f，盈亏 %.2f USDT", pos.Symbol, pos.Side, reason, fillPrice, pnl)
+	}
+	if changed {
+		t.save()
+	}
+}
+
+// settle 按成交价平掉 quantity 数量，更新钱包余额并记录成交，返回已实现盈亏（未扣手续费）
+func (t *PaperTrader) settle(pos *paperPosition, quantity, price, feeRate float64, orderID int64) float64 {
+	pnl := (price - pos.EntryPrice) * quantity
+	side, positionSide := "SELL", "LONG"
+	if pos.Side == "short" {
+		pnl = -pnl
+		side, positionSide = "BUY", "SHORT"
+	}
+	fee := price * quantity * feeRate
+	t.state.WalletBalance += pnl - fee
+	t.recordTrade(orderID, pos.Symbol, side, positionSide, price, quantity, pnl, fee)
+	return pnl
+}
+
+func (t *PaperTrader) recordTrade(orderID int64, symbol, side, positionSide string, price, quantity, realizedPnL, fee float64) {
+	t.state.Trades = append(t.state.Trades, UserTrade{
+		ID:           t.nextOrderID(),
+		OrderID:      orderID,
+		Symbol:       symbol,
+		Side:         side,
+		PositionSide: positionSide,
+		Price:        price,
+		Quantity:     quantity,
+		RealizedPnL:  realizedPnL,
+		Fee:          fee,
+		FeeAsset:     "USDT",
+		Maker:        t.useMaker,
+		Time:         time.Now().UnixMilli(),
+	})
+	if len(t.state.Trades) > paperMaxTrades {
+		t.state.Trades = t.state.Trades[len(t.state.Trades)-paperMaxTrades:]
+	}
+}
+
+// unrealized 计算持仓未实现盈亏和占用保证金
+func (t *PaperTrader) unrealized(prices map[string]float64) (pnl, margin float64) {
+	for _, pos := range t.state.Positions {
+		mark := pos.EntryPrice
+		if price, ok := prices[pos.Symbol]; ok {
+			mark = price
+		}
+		if pos.Side == "long" {
+			pnl += (mark - pos.EntryPrice) * pos.Quantity
+		} else {
+			pnl += (pos.EntryPrice - mark) * pos.Quantity
+		}
+		margin += pos.EntryPrice * pos.Quantity / float64(pos.Leverage)
+	}
+	return pnl, margin
+}
+
+// GetBalance 获取模拟账户余额
+func (t *PaperTrader) GetBalance() (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prices := t.markPrices()
+	t.triggerStops(prices)
+	unrealizedPnL, margin := t.unrealized(prices)
+
+	return map[string]interface{}{
+		"totalWalletBalance":    t.state.WalletBalance,
+		"availableBalance":      t.state.WalletBalance + unrealizedPnL - margin,
+		"totalUnrealizedProfit": unrealizedPnL,
+	}, nil
+}
+
+// GetPositions 获取模拟持仓（空仓数量为负，与币安一致）
+func (t *PaperTrader) GetPositions() ([]map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prices := t.markPrices()
+	t.triggerStops(prices)
+
+	keys := make([]string, 0, len(t.state.Positions))
+	for key := range t.state.Positions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		pos := t.state.Positions[key]
+		mark := pos.EntryPrice
+		if price, ok := prices[pos.Symbol]; ok {
+			mark = price
+		}
+		amount := pos.Quantity
+		pnl := (mark - pos.EntryPrice) * pos.Quantity
+		liquidation := pos.EntryPrice * (1 - 1/float64(pos.Leverage))
+		if pos.Side == "short" {
+			amount = -amount
+			pnl = -pnl
+			liquidation = pos.EntryPrice * (1 + 1/float64(pos.Leverage))
+		}
+		result = append(result, map[string]interface{}{
+			"symbol":           pos.Symbol,
+			"side":             pos.Side,
+			"positionAmt":      amount,
+			"entryPrice":       pos.EntryPrice,
+			"markPrice":        mark,
+			"unRealizedProfit": pnl,
+			"leverage":         float64(pos.Leverage),
+			"liquidationPrice": liquidation,
+		})
+	}
+	return result, nil
+}
+
+// open 按当前价格模拟开仓
+func (t *PaperTrader) open(symbol, side string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("开仓数量必须大于0")
+	}
+	price, err := t.priceFunc(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 价格失败: %w", symbol, err)
+	}
+	if price <= 0 {
+		return nil, fmt.Errorf("%s 价格无效: %.8f", symbol, price)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if leverage <= 0 {
+		leverage = t.state.Leverage[symbol]
+	}
+	if leverage <= 0 {
+		leverage = 1
+	}
+
+	prices := t.markPrices()
+	prices[symbol] = price
+	t.triggerStops(prices)
+	unrealizedPnL, margin := t.unrealized(prices)
+	available := t.state.WalletBalance + unrealizedPnL - margin
+
+	notional := price * quantity
+	fee := notional * t.orderFeeRate()
+	required := notional/float64(leverage) + fee
+	if required > available {
+		return nil, fmt.Errorf("模拟盘保证金不足: 需要 %.2f USDT，可用 %.2f USDT", required, available)
+	}
+
+	orderID := t.nextOrderID()
+	key := paperKey(symbol, side)
+	if pos, ok := t.state.Positions[key]; ok {
+		// 同方向加仓：按数量加权平均开仓价
+		total := pos.Quantity + quantity
+		pos.EntryPrice = (pos.EntryPrice*pos.Quantity + price*quantity) / total
+		pos.Quantity = total
+		pos.Leverage = leverage
+	} else {
+		t.state.Positions[key] = &paperPosition{Symbol: symbol, Side: side, Quantity: quantity, EntryPrice: price, Leverage: leverage}
+	}
+	t.state.Leverage[symbol] = leverage
+	t.state.WalletBalance -= fee
+
+	tradeSide, positionSide := "BUY", "LONG"
+	if side == "short" {
+		tradeSide, positionSide = "SELL", "SHORT"
+	}
+	t.recordTrade(orderID, symbol, tradeSide, positionSide, price, quantity, 0, fee)
+	t.save()
+
+	log.Printf("📄 模拟盘开%s %s 数量 %.6f @ %.4f，杠杆 %dx，手续费 %.4f", map[string]string{"long": "多", "short": "空"}[side], symbol, quantity, price, leverage, fee)
+	return map[string]interface{}{
+		"orderId":     orderID,
+		"symbol":      symbol,
+		"status":      "FILLED",
+		"avgPrice":    price,
+		"executedQty": quantity,
+	}, nil
+}
+
+// close 按当前价格模拟平仓（quantity=0 表示全部平仓）
+func (t *PaperTrader) close(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	price, err := t.priceFunc(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 价格失败: %w", symbol, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := paperKey(symbol, side)
+	pos, ok := t.state.Positions[key]
+	if !ok {
+		return nil, fmt.Errorf("没有找到 %s 的%s持仓", symbol, map[string]string{"long": "多", "short": "空"}[side])
+	}
+	if quantity <= 0 || quantity >= pos.Quantity {
+		quantity = pos.Quantity
+	}
+
+	orderID := t.nextOrderID()
+	pnl := t.settle(pos, quantity, price, t.orderFeeRate(), orderID)
+	if quantity >= pos.Quantity {
+		delete(t.state.Positions, key)
+	} else {
+		pos.Quantity -= quantity
+	}
+	t.save()
+
+	log.Printf("📄 模拟盘平仓 %s %s 数量 %.6f @ %.4f，盈亏 %.2f USDT", symbol, side, quantity, price, pnl)
+	return map[string]interface{}{
+		"orderId":     orderID,
+		"symbol":      symbol,
+		"status":      "FILLED",
+		"avgPrice":    price,
+		"executedQty": quantity,
+		"realizedPnl": pnl,
+	}, nil
+}
+
+// OpenLong 模拟开多仓
+func (t *PaperTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.open(symbol, "long", quantity, leverage)
+}
+
+// OpenShort 模拟开空仓
+func (t *PaperTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.open(symbol, "short", quantity, leverage)
+}
+
+// CloseLong 模拟平多仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.close(symbol, "long", quantity)
+}
+
+// CloseShort 模拟平空仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.close(symbol, "short", quantity)
+}
+
+// SetLeverage 记录币种杠杆（开仓未指定杠杆时使用）
+func (t *PaperTrader) SetLeverage(symbol string, leverage int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Leverage[symbol] = leverage
+	return nil
+}
+
+// SetMarginMode 模拟盘统一按全仓计算，忽略仓位模式
+func (t *PaperTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	return nil
+}
+
+// GetMarketPrice 获取实时行情价格
+func (t *PaperTrader) GetMarketPrice(symbol string) (float64, error) {
+	return t.priceFunc(symbol)
+}
+
+// setStop 设置止损或止盈价格
+func (t *PaperTrader) setStop(symbol, positionSide string, price float64, stopLoss bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pos, ok := t.state.Positions[paperKey(symbol, strings.ToLower(positionSide))]
+	if !ok {
+		return fmt.Errorf("没有找到 %s %s 持仓，无法设置止损止盈", symbol, positionSide)
+	}
+	if stopLoss {
+		pos.StopLoss = price
+		pos.StopLossOrderID = t.nextOrderID()
+	} else {
+		pos.TakeProfit = price
+		pos.TakeProfitOrderID = t.nextOrderID()
+	}
+	t.save()
+	return nil
+}
+
+// SetStopLoss 设置模拟止损单
+func (t *PaperTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return t.setStop(symbol, positionSide, stopPrice, true)
+}
+
+// SetTakeProfit 设置模拟止盈单
+func (t *PaperTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return t.setStop(symbol, positionSide, takeProfitPrice, false)
+}
+
+// clearStops 取消币种的止损和/或止盈单
+func (t *PaperTrader) clearStops(symbol string, stopLoss, takeProfit bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, pos := range t.state.Positions {
+		if pos.Symbol != symbol {
+			continue
+		}
+		if stopLoss {
+			pos.StopLoss, pos.StopLossOrderID = 0, 0
+		}
+		if takeProfit {
+			pos.TakeProfit, pos.TakeProfitOrderID = 0, 0
+		}
+	}
+	t.save()
+	return nil
+}
+
+// CancelStopLossOrders 取消模拟止损单
+func (t *PaperTrader) CancelStopLossOrders(symbol string) error {
+	return t.clearStops(symbol, true, false)
+}
+
+// CancelTakeProfitOrders 取消模拟止盈单
+func (t *PaperTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.clearStops(symbol, false, true)
+}
+
+// CancelAllOrders 取消币种的所有模拟挂单
+func (t *PaperTrader) CancelAllOrders(symbol string) error {
+	return t.clearStops(symbol, true, true)
+}
+
+// CancelStopOrders 取消币种的模拟止盈止损单
+func (t *PaperTrader) CancelStopOrders(symbol string) error {
+	return t.clearStops(symbol, true, true)
+}
+
+// FormatQuantity 模拟盘不限制数量精度，保留6位小数
+func (t *PaperTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return strconv.FormatFloat(math.Floor(quantity*1e6)/1e6, 'f', -1, 64), nil
+}
+
+// GetOpenOrders 返回模拟止损/止盈单（symbol 为空时返回全部）
+func (t *PaperTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	orders := []decision.OpenOrderInfo{}
+	for _, pos := range t.state.Positions {
+		if symbol != "" && pos.Symbol != symbol {
+			continue
+		}
+		side, positionSide := "SELL", "LONG"
+		if pos.Side == "short" {
+			side, positionSide = "BUY", "SHORT"
+		}
+		if pos.StopLoss > 0 {
+			orders = append(orders, decision.OpenOrderInfo{Symbol: pos.Symbol, OrderID: pos.StopLossOrderID, Type: "STOP_MARKET", Side: side, PositionSide: positionSide, Quantity: pos.Quantity, StopPrice: pos.StopLoss})
+		}
+		if pos.TakeProfit > 0 {
+			orders = append(orders, decision.OpenOrderInfo{Symbol: pos.Symbol, OrderID: pos.TakeProfitOrderID, Type: "TAKE_PROFIT_MARKET", Side: side, PositionSide: positionSide, Quantity: pos.Quantity, StopPrice: pos.TakeProfit})
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderID < orders[j].OrderID })
+	return orders, nil
+}
+
+// GetSymbolTradingStatus 模拟盘所有币种始终可交易
+func (t *PaperTrader) GetSymbolTradingStatus(symbol string) (*SymbolTradingStatus, error) {
+	return &SymbolTradingStatus{Symbol: symbol, Status: "TRADING", Tradeable: true}, nil
+}
+
+// GetUserTrades 返回模拟成交记录（symbol 为空时返回全部，按时间升序）
+func (t *PaperTrader) GetUserTrades(symbol string, startTime, endTime time.Time) ([]UserTrade, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	trades := []UserTrade{}
+	for _, trade := range t.state.Trades {
+		if symbol != "" && trade.Symbol != symbol {
+			continue
+		}
+		if trade.Time < startTime.UnixMilli() || (!endTime.IsZero() && trade.Time > endTime.UnixMilli()) {
+			continue
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+// GetMaxLeverage 模拟盘不限制杠杆
+func (t *PaperTrader) GetMaxLeverage(symbol string) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,152 @@
+package trader
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestPaperTrader(statePath string, prices map[string]float64) *PaperTrader {
+	pt := NewPaperTrader(statePath, 1000, 0.001, 0.0005, "market_only")
+	pt.priceFunc = func(symbol string) (float64, error) { return prices[symbol], nil }
+	return pt
+}
+
+func paperBalance(t *testing.T, pt *PaperTrader) map[string]interface{} {
+	t.Helper()
+	balance, err := pt.GetBalance()
+	if err != nil {
+		t.Fatalf("获取模拟余额失败: %v", err)
+	}
+	return balance
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+// TestPaperTraderOpenCloseWithFees 测试模拟开平仓按实时价格成交并扣除手续费
+func TestPaperTraderOpenCloseWithFees(t *testing.T) {
+	prices := map[string]float64{"BTCUSDT": 100}
+	pt := newTestPaperTrader("", prices)
+
+	if _, err := pt.OpenLong("BTCUSDT", 2, 5); err != nil {
+		t.Fatalf("开多失败: %v", err)
+	}
+	// 开仓手续费 100*2*0.001=0.2
+	if wallet := paperBalance(t, pt)["totalWalletBalance"].(float64); !almostEqual(wallet, 999.8) {
+		t.Errorf("开仓后钱包余额应为 999.8, 实际 %.4f", wallet)
+	}
+
+	prices["BTCUSDT"] = 110
+	positions, _ := pt.GetPositions()
+	if len(positions) != 1 || positions[0]["side"] != "long" || !almostEqual(positions[0]["unRealizedProfit"].(float64), 20) {
+		t.Fatalf("持仓未实现盈亏应为 20, 实际 %v", positions)
+	}
+	balance := paperBalance(t, pt)
+	// 可用 = 钱包 + 未实现盈亏 - 保证金(100*2/5=40)
+	if available := balance["availableBalance"].(float64); !almostEqual(available, 999.8+20-40) {
+		t.Errorf("可用余额应为 979.8, 实际 %.4f", available)
+	}
+
+	if _, err := pt.CloseLong("BTCUSDT", 0); err != nil {
+		t.Fatalf("平多失败: %v", err)
+	}
+	// 平仓盈利 20，手续费 110*2*0.001=0.22
+	if wallet := paperBalance(t, pt)["totalWalletBalance"].(float64); !almostEqual(wallet, 999.8+20-0.22) {
+		t.Errorf("平仓后钱包余额应为 1019.58, 实际 %.4f", wallet)
+	}
+	if positions, _ := pt.GetPositions(); len(positions) != 0 {
+		t.Errorf("平仓后不应有持仓, 实际 %v", positions)
+	}
+
+	// 空单数量为负，价格上涨时亏损
+	if _, err := pt.OpenShort("BTCUSDT", 1, 10); err != nil {
+		t.Fatalf("开空失败: %v", err)
+	}
+	prices["BTCUSDT"] = 115
+	positions, _ = pt.GetPositions()
+	if positions[0]["positionAmt"].(float64) != -1 || !almostEqual(positions[0]["unRealizedProfit"].(float64), -5) {
+		t.Errorf("空单数量应为-1、未实现盈亏应为-5, 实际 %v", positions[0])
+	}
+
+	if trades, _ := pt.GetUserTrades("BTCUSDT", time.Time{}, time.Time{}); len(trades) != 3 {
+		t.Errorf("应记录3笔模拟成交, 实际 %d", len(trades))
+	}
+}
+
+// TestPaperTraderStopLossTrigger 测试价格穿过止损价时按止损价平仓，止盈单随之消失
+func TestPaperTraderStopLossTrigger(t *testing.T) {
+	prices := map[string]float64{"ETHUSDT": 200}
+	pt := newTestPaperTrader("", prices)
+
+	if _, err := pt.OpenLong("ETHUSDT", 1, 2); err != nil {
+		t.Fatalf("开多失败: %v", err)
+	}
+	if err := pt.SetStopLoss("ETHUSDT", "LONG", 1, 190); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+	if err := pt.SetTakeProfit("ETHUSDT", "LONG", 1, 220); err != nil {
+		t.Fatalf("设置止盈失败: %v", err)
+	}
+	if orders, _ := pt.GetOpenOrders("ETHUSDT"); len(orders) != 2 || orders[0].Type != "STOP_MARKET" || orders[1].Type != "TAKE_PROFIT_MARKET" {
+		t.Fatalf("应有止损和止盈两个挂单, 实际 %+v", orders)
+	}
+
+	prices["ETHUSDT"] = 185
+	if positions, _ := pt.GetPositions(); len(positions) != 0 {
+		t.Fatalf("跌破止损价后应已平仓, 实际 %v", positions)
+	}
+	// 开仓手续费 0.2，止损按触发价 190 成交亏损 10，手续费 0.19
+	if wallet := paperBalance(t, pt)["totalWalletBalance"].(float64); !almostEqual(wallet, 1000-0.2-10-0.19) {
+		t.Errorf("止损后钱包余额应为 989.61, 实际 %.4f", wallet)
+	}
+	if orders, _ := pt.GetOpenOrders(""); len(orders) != 0 {
+		t.Errorf("平仓后不应再有挂单, 实际 %+v", orders)
+	}
+}
+
+// TestPaperTraderInsufficientMargin 测试保证金不足时拒绝开仓
+func TestPaperTraderInsufficientMargin(t *testing.T) {
+	pt := newTestPaperTrader("", map[string]float64{"BTCUSDT": 100})
+
+	if _, err := pt.OpenLong("BTCUSDT", 50, 5); err == nil {
+		t.Fatal("需要 1000 USDT 保证金加手续费，应拒绝开仓")
+	}
+	if _, err := pt.OpenLong("BTCUSDT", 40, 5); err != nil {
+		t.Fatalf("保证金足够时应允许开仓: %v", err)
+	}
+	if _, err := pt.CloseShort("BTCUSDT", 0); err == nil {
+		t.Error("没有空单时平空应返回错误")
+	}
+}
+
+// TestPaperTraderPersistence 测试模拟账户重启后恢复持仓和余额
+func TestPaperTraderPersistence(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "paper.json")
+	prices := map[string]float64{"SOLUSDT": 50}
+
+	pt := newTestPaperTrader(statePath, prices)
+	if _, err := pt.OpenShort("SOLUSDT", 4, 3); err != nil {
+		t.Fatalf("开空失败: %v", err)
+	}
+	if err := pt.SetStopLoss("SOLUSDT", "SHORT", 4, 55); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+
+	restored := newTestPaperTrader(statePath, prices)
+	positions, _ := restored.GetPositions()
+	if len(positions) != 1 || positions[0]["positionAmt"].(float64) != -4 || positions[0]["leverage"].(float64) != 3 {
+		t.Fatalf("重启后应恢复空单持仓, 实际 %v", positions)
+	}
+	if wallet := paperBalance(t, restored)["totalWalletBalance"].(float64); !almostEqual(wallet, 1000-0.2) {
+		t.Errorf("重启后钱包余额应为 999.8, 实际 %.4f", wallet)
+	}
+
+	// 恢复后的止损单仍然生效
+	prices["SOLUSDT"] = 56
+	if positions, _ := restored.GetPositions(); len(positions) != 0 {
+		t.Errorf("涨破止损价后空单应已平仓, 实际 %v", positions)
+	}
+}
@@ -0,0 +1,145 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+	"time"
+)
+
+// pendingEntry 已挂出但尚未成交的限价开仓单
+// 成交确认前不写入开仓记录、不设置止损止盈、不计入当日开仓次数，成交后由 resolvePendingEntries 补记
+type pendingEntry struct {
+	symbol     string
+	side       string // long/short
+	orderID    int64
+	quantity   float64
+	limitPrice float64
+	leverage   int
+	stopLoss   float64
+	takeProfit float64
+	reasoning  string
+	placedAt   time.Time
+}
+
+// mayRestUnfilled 本次开仓是否可能以限价单挂单而未立即成交
+// AI 指定限价单（conservative_hybrid 会在下单调用内超时转市价，返回时已成交）或默认策略为 limit_only
+func (at *AutoTrader) mayRestUnfilled(d *decision.Decision) bool {
+	strategy := at.config.OrderStrategy
+	if _, ok := at.trader.(orderTypeTrader); ok {
+		switch d.OrderType {
+		case OrderTypeMarket:
+			return false
+		case OrderTypeLimit:
+			return strategy != "conservative_hybrid"
+		}
+	}
+	return strategy == "limit_only"
+}
+
+// trackPendingEntry 登记挂单中的限价开仓单（币种名额保留到成交、撤单或挂单消失）
+func (at *AutoTrader) trackPendingEntry(d *decision.Decision, side string, orderID int64, quantity, limitPrice float64) {
+	if at.pendingEntries == nil {
+		at.pendingEntries = make(map[string]*pendingEntry)
+	}
+	at.pendingEntries[d.Symbol+"_"+side] = &pendingEntry{
+		symbol:     d.Symbol,
+		side:       side,
+		orderID:    orderID,
+		quantity:   quantity,
+		limitPrice: limitPrice,
+		leverage:   d.Leverage,
+		stopLoss:   d.StopLoss,
+		takeProfit: d.TakeProfit,
+		reasoning:  d.Reasoning,
+		placedAt:   time.Now(),
+	}
+	at.log().Infof("  📋 限价开仓单挂单中: %s %s 数量 %.4f @ %.4f (订单ID: %d)，成交后再记录开仓并设置止盈止损",
+		d.Symbol, side, quantity, limitPrice, orderID)
+}
+
+// hasPendingEntry 该币种该方向是否有挂单中的限价开仓单
+func (at *AutoTrader) hasPendingEntry(symbol, side string) bool {
+	_, ok := at.pendingEntries[symbol+"_"+side]
+	return ok
+}
+
+// resolvePendingEntries 每周期检查挂单中的限价开仓单：已成交的补记开仓并设置止盈止损，
+// 已不在交易所（被撤销或过期）的移除并释放币种名额。返回写入决策记录的日志
+func (at *AutoTrader) resolvePendingEntries() []string {
+	if len(at.pendingEntries) == 0 {
+		return nil
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		at.log().Warnf("⚠️ [%s] 检查限价开仓单：获取持仓失败: %v", at.name, err)
+		return nil
+	}
+
+	var notes []string
+	for key, entry := range at.pendingEntries {
+		if quantity, entryPrice, ok := filledPosition(positions, entry.symbol, entry.side); ok {
+			delete(at.pendingEntries, key)
+			if entryPrice <= 0 {
+				entryPrice = entry.limitPrice
+			}
+			note := fmt.Sprintf("限价开仓单已成交: %s %s 数量 %.4f @ %.4f (订单ID: %d)", entry.symbol, entry.side, quantity, entryPrice, entry.orderID)
+			at.log().Infof("✅ [%s] %s", at.name, note)
+			at.recordOpenedPosition(entry.symbol, entry.side, quantity, entryPrice, entry.leverage, entry.stopLoss, entry.takeProfit, entry.reasoning)
+			notes = append(notes, note)
+			continue
+		}
+
+		orders, err := at.trader.GetOpenOrders(entry.symbol)
+		if err != nil {
+			at.log().Warnf("⚠️ [%s] 检查限价开仓单 %s 失败: %v", at.name, entry.symbol, err)
+			continue
+		}
+		if entry.resting(orders) {
+			continue
+		}
+		delete(at.pendingEntries, key)
+		at.releaseSymbolSlot(entry.symbol, entry.side)
+		note := fmt.Sprintf("限价开仓单未成交且已不在交易所（撤销或过期）: %s %s (订单ID: %d)", entry.symbol, entry.side, entry.orderID)
+		at.log().Infof("🗑️ [%s] %s", at.name, note)
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+// dropPendingEntry 撤销限价开仓单后移除对应的挂单登记
+func (at *AutoTrader) dropPendingEntry(order decision.OpenOrderInfo) {
+	key := order.Symbol + "_" + order.EntrySide()
+	if entry, ok := at.pendingEntries[key]; ok && (entry.orderID == 0 || entry.orderID == order.OrderID) {
+		delete(at.pendingEntries, key)
+	}
+}
+
+// resting 限价开仓单是否仍在交易所挂单中
+func (e *pendingEntry) resting(orders []decision.OpenOrderInfo) bool {
+	for _, order := range orders {
+		if e.orderID != 0 && order.OrderID == e.orderID {
+			return true
+		}
+		if e.orderID == 0 && isEntryOrder(order, e.side) {
+			return true
+		}
+	}
+	return false
+}
+
+// filledPosition 交易所上该币种该方向的持仓数量和均价（没有持仓时 ok=false）
+func filledPosition(positions []map[string]interface{}, symbol, side string) (quantity, entryPrice float64, ok bool) {
+	for _, pos := range positions {
+		if pos["symbol"] != symbol || pos["side"] != side {
+			continue
+		}
+		amt, _ := pos["positionAmt"].(float64)
+		if amt == 0 {
+			continue
+		}
+		entryPrice, _ = pos["entryPrice"].(float64)
+		return math.Abs(amt), entryPrice, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,288 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 持久化重试配置（所有交易员共用，写入失败的交易记录/状态按此重试）
+const (
+	defaultPersistMaxRetries    = 5
+	defaultPersistRetryInterval = 30 * time.Second
+	defaultDeadLetterDir        = "dead_letters"
+)
+
+// 持久化操作类型
+const (
+	persistKindTrade       = "trade"
+	persistKindTraderState = "trader_state"
+)
+
+// tradeRecorder 交易记录写入接口（数据库以鸭子类型注入）
+type tradeRecorder interface {
+	RecordTrade(traderID, userID, symbol, side, action string, quantity, price float64, reason string, stopLoss, takeProfit, pnl, pnlPercent float64) error
+}
+
+// traderStateSaver 交易员状态写入接口
+type traderStateSaver interface {
+	SaveTraderState(traderID, userID string, callCount int, peakEquity float64, lastResetTime int64, stateJSON string) error
+}
+
+// tradeRecordPayload 交易记录参数（用于重试和死信文件）
+type tradeRecordPayload struct {
+	TraderID   string  `json:"trader_id"`
+	UserID     string  `json:"user_id"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`
+	Action     string  `json:"action"`
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"`
+	Reason     string  `json:"reason"`
+	StopLoss   float64 `json:"stop_loss"`
+	TakeProfit float64 `json:"take_profit"`
+	PnL        float64 `json:"pnl"`
+	PnLPercent float64 `json:"pnl_percent"`
+}
+
+// traderStatePayload 交易员状态参数（用于重试和死信文件）
+type traderStatePayload struct {
+	TraderID      string  `json:"trader_id"`
+	UserID        string  `json:"user_id"`
+	CallCount     int     `json:"call_count"`
+	PeakEquity    float64 `json:"peak_equity"`
+	LastResetTime int64   `json:"last_reset_time"`
+	StateJSON     string  `json:"state_json"`
+}
+
+// persistOp 一次失败的持久化写入
+type persistOp struct {
+	Kind          string       `json:"kind"`
+	Payload       interface{}  `json:"payload"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"last_error"`
+	FirstFailedAt time.Time    `json:"first_failed_at"`
+	exec          func() error // 重新执行写入
+}
+
+// persistRetryQueue 持久化写入重试队列
+// 写入失败的交易记录/状态先放入内存队列定期重试，超过最大次数后写入本地死信文件，供人工恢复
+type persistRetryQueue struct {
+	mu             sync.Mutex
+	ops            []*persistOp
+	maxRetries     int
+	deadLetterPath string
+}
+
+// newPersistRetryQueue 创建重试队列
+func newPersistRetryQueue(maxRetries int, deadLetterPath string) *persistRetryQueue {
+	if maxRetries <= 0 {
+		maxRetries = defaultPersistMaxRetries
+	}
+	return &persistRetryQueue{
+		maxRetries:     maxRetries,
+		deadLetterPath: deadLetterPath,
+	}
+}
+
+// enqueue 加入队列（状态写入只保留最新一条，旧状态无需补写）
+func (q *persistRetryQueue) enqueue(op *persistOp) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if op.Kind == persistKindTraderState {
+		for i, existing := range q.ops {
+			if existing.Kind == persistKindTraderState {
+				op.Attempts = existing.Attempts
+				op.FirstFailedAt = existing.FirstFailedAt
+				q.ops[i] = op
+				return
+			}
+		}
+	}
+	q.ops = append(q.ops, op)
+}
+
+// Len 队列中待重试的写入数量
+func (q *persistRetryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ops)
+}
+
+// retry 对队列中每个写入重试一次，成功的移出队列，超过最大次数的写入死信文件
+// 返回本次重试成功的数量
+func (q *persistRetryQueue) retry() int {
+	q.mu.Lock()
+	pending := q.ops
+	q.ops = nil
+	q.mu.Unlock()
+
+	succeeded := 0
+	var remaining []*persistOp
+	for _, op := range pending {
+		if err := op.exec(); err != nil {
+			op.Attempts++
+			op.LastError = err.Error()
+			if op.Attempts >= q.maxRetries {
+				q.deadLetter(op)
+				continue
+			}
+			remaining = append(remaining, op)
+			continue
+		}
+		succeeded++
+		log.Printf("✅ 持久化重试成功: %s (第 %d 次重试)", op.Kind, op.Attempts+1)
+	}
+
+	if len(remaining) > 0 {
+		q.mu.Lock()
+		// 重试期间可能有新的失败写入，保持先后顺序
+		q.ops = append(remaining, q.ops...)
+		q.mu.Unlock()
+	}
+	return succeeded
+}
+
+// flush 停止时清空队列：用尽剩余重试次数，仍失败的写入死信文件
+func (q *persistRetryQueue) flush() {
+	for i := 0; i < q.maxRetries && q.Len() > 0; i++ {
+		q.retry()
+	}
+
+	q.mu.Lock()
+	pending := q.ops
+	q.ops = nil
+	q.mu.Unlock()
+
+	for _, op := range pending {
+		q.deadLetter(op)
+	}
+}
+
+// deadLetter 写入死信文件（JSON Lines，每行一条）
+func (q *persistRetryQueue) deadLetter(op *persistOp) {
+	log.Printf("❌ 持久化写入重试 %d 次仍失败，写入死信文件 %s: %s", op.Attempts, q.deadLetterPath, op.LastError)
+
+	if err := appendDeadLetter(q.deadLetterPath, op); err != nil {
+		payload, _ := json.Marshal(op)
+		log.Printf("❌ 写入死信文件失败: %v，丢失的记录: %s", err, payload)
+	}
+}
+
+// appendDeadLetter 追加一条死信记录
+func appendDeadLetter(path string, op *persistOp) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建死信目录失败: %w", err)
+	}
+
+	line, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("序列化死信记录失败: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("打开死信文件失败: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入死信文件失败: %w", err)
+	}
+	return nil
+}
+
+// recordTradeWithRetry 写入交易记录，失败时加入重试队列
+// 返回首次写入的错误，调用方保留原有日志
+func (at *AutoTrader) recordTradeWithRetry(db tradeRecorder, traderID, userID, symbol, side, action string, quantity, price float64, reason string, stopLoss, takeProfit, pnl, pnlPercent float64) error {
+	payload := tradeRecordPayload{
+		TraderID: traderID, UserID: userID, Symbol: symbol, Side: side, Action: action,
+		Quantity: quantity, Price: price, Reason: reason,
+		StopLoss: stopLoss, TakeProfit: takeProfit, PnL: pnl, PnLPercent: pnlPercent,
+	}
+	exec := func() error {
+		return db.RecordTrade(payload.TraderID, payload.UserID, payload.Symbol, payload.Side, payload.Action,
+			payload.Quantity, payload.Price, payload.Reason,
+			payload.StopLoss, payload.TakeProfit, payload.PnL, payload.PnLPercent)
+	}
+
+	err := exec()
+	if err != nil && at.persistQueue != nil {
+		at.persistQueue.enqueue(&persistOp{
+			Kind:          persistKindTrade,
+			Payload:       payload,
+			LastError:     err.Error(),
+			FirstFailedAt: time.Now(),
+			exec:          exec,
+		})
+		at.log().Infof("  🔁 交易记录 %s %s %s 写入失败，已加入重试队列", symbol, side, action)
+	}
+	return err
+}
+
+// saveTraderStateWithRetry 写入交易员状态，失败时加入重试队列
+func (at *AutoTrader) saveTraderStateWithRetry(db traderStateSaver, payload traderStatePayload) error {
+	exec := func() error {
+		return db.SaveTraderState(payload.TraderID, payload.UserID, payload.CallCount, payload.PeakEquity, payload.LastResetTime, payload.StateJSON)
+	}
+
+	err := exec()
+	if err != nil && at.persistQueue != nil {
+		at.persistQueue.enqueue(&persistOp{
+			Kind:          persistKindTraderState,
+			Payload:       payload,
+			LastError:     err.Error(),
+			FirstFailedAt: time.Now(),
+			exec:          exec,
+		})
+	}
+	return err
+}
+
+// startPersistRetryWorker 启动持久化重试协程
+func (at *AutoTrader) startPersistRetryWorker() {
+	if at.persistQueue == nil {
+		return
+	}
+
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(defaultPersistRetryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if at.persistQueue.Len() > 0 {
+					at.persistQueue.retry()
+				}
+			case <-at.stopMonitorCh:
+				return
+			}
+		}
+	}()
+}
+
+// flushPersistQueue 停止时清空持久化重试队列
+func (at *AutoTrader) flushPersistQueue() {
+	if at.persistQueue == nil || at.persistQueue.Len() == 0 {
+		return
+	}
+	at.log().Infof("🔁 [%s] 停止前清空持久化重试队列（%d 条待写入）", at.name, at.persistQueue.Len())
+	at.persistQueue.flush()
+}
+
+// GetPendingPersistCount 获取待重试的持久化写入数量
+func (at *AutoTrader) GetPendingPersistCount() int {
+	if at.persistQueue == nil {
+		return 0
+	}
+	return at.persistQueue.Len()
+}
@@ -0,0 +1,132 @@
+package trader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// flakyRecorder 前 failTimes 次写入失败的模拟数据库
+type flakyRecorder struct {
+	failTimes   int
+	tradeCalls  int
+	stateCalls  int
+	lastTrade   string
+	lastCallCnt int
+}
+
+func (f *flakyRecorder) RecordTrade(traderID, userID, symbol, side, action string, quantity, price float64, reason string, stopLoss, takeProfit, pnl, pnlPercent float64) error {
+	f.tradeCalls++
+	if f.tradeCalls <= f.failTimes {
+		return errors.New("database is locked")
+	}
+	f.lastTrade = symbol + "_" + side + "_" + action
+	return nil
+}
+
+func (f *flakyRecorder) SaveTraderState(traderID, userID string, callCount int, peakEquity float64, lastResetTime int64, stateJSON string) error {
+	f.stateCalls++
+	if f.stateCalls <= f.failTimes {
+		return errors.New("database is locked")
+	}
+	f.lastCallCnt = callCount
+	return nil
+}
+
+// TestRecordTradeRetrySucceeds 测试临时写入失败会被重试并成功
+func TestRecordTradeRetrySucceeds(t *testing.T) {
+	deadLetterPath := filepath.Join(t.TempDir(), "trader.jsonl")
+	at := &AutoTrader{name: "test", persistQueue: newPersistRetryQueue(3, deadLetterPath)}
+	db := &flakyRecorder{failTimes: 2}
+
+	err := at.recordTradeWithRetry(db, "trader", "user", "BTCUSDT", "LONG", "CLOSE", 0.1, 50000, "test", 0, 0, 10, 2)
+	if err == nil {
+		t.Fatal("首次写入应返回错误")
+	}
+	if at.GetPendingPersistCount() != 1 {
+		t.Fatalf("失败写入应加入重试队列，实际 %d", at.GetPendingPersistCount())
+	}
+
+	// 第一次重试仍失败，保留在队列
+	if n := at.persistQueue.retry(); n != 0 {
+		t.Fatalf("第一次重试应失败，成功数 %d", n)
+	}
+	if at.GetPendingPersistCount() != 1 {
+		t.Fatal("重试失败后应保留在队列")
+	}
+
+	// 第二次重试成功
+	if n := at.persistQueue.retry(); n != 1 {
+		t.Fatalf("第二次重试应成功，成功数 %d", n)
+	}
+	if at.GetPendingPersistCount() != 0 {
+		t.Error("重试成功后应移出队列")
+	}
+	if db.lastTrade != "BTCUSDT_LONG_CLOSE" {
+		t.Errorf("重试写入的记录不正确: %s", db.lastTrade)
+	}
+	if _, err := os.Stat(deadLetterPath); !os.IsNotExist(err) {
+		t.Error("重试成功不应写入死信文件")
+	}
+}
+
+// TestPersistRetryDeadLetter 测试超过最大重试次数后写入死信文件
+func TestPersistRetryDeadLetter(t *testing.T) {
+	deadLetterPath := filepath.Join(t.TempDir(), "dead", "trader.jsonl")
+	at := &AutoTrader{name: "test", persistQueue: newPersistRetryQueue(2, deadLetterPath)}
+	db := &flakyRecorder{failTimes: 100}
+
+	at.recordTradeWithRetry(db, "trader", "user", "ETHUSDT", "SHORT", "OPEN", 1, 3000, "test", 3100, 2800, 0, 0)
+	at.persistQueue.retry()
+	at.persistQueue.retry()
+
+	if at.GetPendingPersistCount() != 0 {
+		t.Fatalf("超过最大重试次数后应移出队列，实际 %d", at.GetPendingPersistCount())
+	}
+
+	f, err := os.Open(deadLetterPath)
+	if err != nil {
+		t.Fatalf("应写入死信文件: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatal("死信文件为空")
+	}
+	var entry struct {
+		Kind     string             `json:"kind"`
+		Attempts int                `json:"attempts"`
+		Payload  tradeRecordPayload `json:"payload"`
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+		t.Fatalf("死信记录格式错误: %v", err)
+	}
+	if entry.Kind != persistKindTrade || entry.Payload.Symbol != "ETHUSDT" || entry.Payload.Action != "OPEN" || entry.Attempts != 2 {
+		t.Errorf("死信记录内容不正确: %+v", entry)
+	}
+}
+
+// TestPersistQueueFlushOnStop 测试停止时清空队列，状态写入只保留最新一条
+func TestPersistQueueFlushOnStop(t *testing.T) {
+	deadLetterPath := filepath.Join(t.TempDir(), "trader.jsonl")
+	at := &AutoTrader{name: "test", persistQueue: newPersistRetryQueue(5, deadLetterPath)}
+	db := &flakyRecorder{failTimes: 3}
+
+	at.saveTraderStateWithRetry(db, traderStatePayload{TraderID: "trader", CallCount: 1})
+	at.saveTraderStateWithRetry(db, traderStatePayload{TraderID: "trader", CallCount: 2})
+	if at.GetPendingPersistCount() != 1 {
+		t.Fatalf("状态写入应只保留最新一条，实际 %d", at.GetPendingPersistCount())
+	}
+
+	at.flushPersistQueue()
+	if at.GetPendingPersistCount() != 0 {
+		t.Error("flush 后队列应为空")
+	}
+	if db.lastCallCnt != 2 {
+		t.Errorf("应写入最新状态，实际 callCount=%d", db.lastCallCnt)
+	}
+}
@@ -0,0 +1,320 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"sort"
+	"sync"
+	"time"
+)
+
+// portfolioLockTimeout 组长等待成员周期锁的最长时间
+const portfolioLockTimeout = 30 * time.Second
+
+// PortfolioGroup 组合模式分组
+// 同一用户显式分组、同一交易所的多个交易员共用一次AI调用：
+// 组长合并所有成员账户后统一决策，再把决策分配给各成员执行，其他成员不再单独调用AI
+type PortfolioGroup struct {
+	name     string
+	exchange string
+	mu       sync.RWMutex
+	members  map[string]*AutoTrader
+}
+
+// NewPortfolioGroup 创建组合分组
+func NewPortfolioGroup(name, exchange string) *PortfolioGroup {
+	return &PortfolioGroup{
+		name:     name,
+		exchange: exchange,
+		members:  make(map[string]*AutoTrader),
+	}
+}
+
+// Name 分组名称
+func (g *PortfolioGroup) Name() string {
+	return g.name
+}
+
+// portfolioGroupName 分组名称（未分组返回空字符串）
+func portfolioGroupName(g *PortfolioGroup) string {
+	if g == nil {
+		return ""
+	}
+	return g.name
+}
+
+// Join 加入分组（只允许同一交易所的交易员加入）
+func (g *PortfolioGroup) Join(at *AutoTrader) error {
+	if at.exchange != g.exchange {
+		return fmt.Errorf("组合 %s 仅支持交易所 %s，交易员 %s 使用 %s", g.name, g.exchange, at.name, at.exchange)
+	}
+
+	g.mu.Lock()
+	g.members[at.id] = at
+	g.mu.Unlock()
+
+	at.portfolio.Store(g)
+	log.Printf("🧩 交易员 %s 已加入组合 [%s]", at.name, g.name)
+	return nil
+}
+
+// Leave 离开分组
+func (g *PortfolioGroup) Leave(traderID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if at, ok := g.members[traderID]; ok {
+		at.portfolio.CompareAndSwap(g, nil)
+		delete(g.members, traderID)
+	}
+}
+
+// Size 成员数量
+func (g *PortfolioGroup) Size() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.members)
+}
+
+// Members 获取成员列表（按ID排序）
+func (g *PortfolioGroup) Members() []*AutoTrader {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	members := make([]*AutoTrader, 0, len(g.members))
+	for _, at := range g.members {
+		members = append(members, at)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].id < members[j].id })
+	return members
+}
+
+// activeMembers 获取运行中的成员（按ID排序）
+func (g *PortfolioGroup) activeMembers() []*AutoTrader {
+	var active []*AutoTrader
+	for _, at := range g.Members() {
+		if at.isRunning.Load() {
+			active = append(active, at)
+		}
+	}
+	return active
+}
+
+// IsLeader 判断交易员是否为当前组长
+// 组长为本周期能够完成决策的运行中成员里ID最小的一个：风控暂停、AI预算用完或不在交易时间窗口内的成员
+// 会在自己的周期中提前返回，由它做组长会让整个分组都拿不到决策；没有成员满足条件时退回运行中ID最小的成员
+func (g *PortfolioGroup) IsLeader(at *AutoTrader) bool {
+	active := g.activeMembers()
+	if len(active) == 0 {
+		return true
+	}
+	for _, member := range active {
+		if member.canLeadPortfolio() {
+			return member.id == at.id
+		}
+	}
+	return active[0].id == at.id
+}
+
+// canLeadPortfolio 本周期是否能为分组调用AI决策（与 runCycle 中提前返回的条件一致）
+func (at *AutoTrader) canLeadPortfolio() bool {
+	return at.portfolioSkipReason() == "" && !at.outsideTradingWindow()
+}
+
+// portfolioSkipReason 成员不参与本轮组合决策的原因（与自身周期中跳过AI决策的条件一致，空字符串表示参与）
+func (at *AutoTrader) portfolioSkipReason() string {
+	if time.Now().Before(at.getStopUntil()) {
+		return "风控暂停中"
+	}
+	if at.aiBudgetExhausted() {
+		return "今日AI调用预算已用完"
+	}
+	return ""
+}
+
+// GetPortfolioGroup 获取交易员所属的组合分组（未分组返回nil）
+func (at *AutoTrader) GetPortfolioGroup() *PortfolioGroup {
+	return at.portfolio.Load()
+}
+
+// requestDecision 请求AI决策：组合模式（成员多于1个）走组合决策，否则独立决策
+// 返回完整决策以及本交易员需要执行的决策列表
+func (at *AutoTrader) requestDecision(ctx *decision.Context, record *logger.DecisionRecord) (*decision.FullDecision, []decision.Decision, error) {
+	// 只读取一次分组：Leave 可能在周期执行中并发清空
+	if group := at.portfolio.Load(); group != nil && group.Size() > 1 {
+		return at.runPortfolioDecision(group, ctx, record)
+	}
+
+	// ♻️ 市场几乎没动且上次决策全部持有：复用上次结果，跳过AI调用
+	if cached, model, ok := at.cachedHoldDecision(ctx); ok {
+		record.AIModel = model
+		record.CachedDecision = true
+		record.ExecutionLog = append(record.ExecutionLog, "♻️ 复用上一次持有决策（未调用AI）")
+		at.log().Infof("♻️ [%s] 价格变动未超过 %.2f%% 且持仓未变，复用上一次持有决策", at.name, at.config.HoldCachePct)
+		return cached, cached.Decisions, nil
+	}
+
+	model := at.nextModel()
+	record.AIModel = model.label
+	fullDecision, err := at.callModel(ctx, model, record)
+	at.updateHoldCache(ctx, fullDecision, record.ModelUsed, err)
+	if fullDecision == nil {
+		return nil, nil, err
+	}
+	return fullDecision, fullDecision.Decisions, err
+}
+
+// runPortfolioDecision 组长执行组合决策：合并成员上下文 → 一次AI调用 → 分配并执行成员决策
+// 返回组长自身需要执行的决策；其他成员的决策在各自的周期锁内执行并写入各自的决策日志
+func (at *AutoTrader) runPortfolioDecision(group *PortfolioGroup, ctx *decision.Context, record *logger.DecisionRecord) (*decision.FullDecision, []decision.Decision, error) {
+	members := []decision.PortfolioMember{{TraderID: at.id, Context: ctx}}
+	others := make(map[string]*AutoTrader)
+
+	for _, member := range group.activeMembers() {
+		if member.id == at.id {
+			continue
+		}
+		// 风控暂停中、AI预算用完的成员不参与本轮组合决策
+		if reason := member.portfolioSkipReason(); reason != "" {
+			at.log().Infof("🧩 组合成员 %s %s，跳过", member.name, reason)
+			continue
+		}
+
+		if !member.tryLockCycle(portfolioLockTimeout) {
+			at.log().Warnf("⚠️ 组合成员 %s 周期锁等待超时，跳过", member.name)
+			continue
+		}
+		memberCtx, err := member.buildTradingContext()
+		member.cycleMutex.Unlock()
+		if err != nil {
+			at.log().Warnf("⚠️ 组合成员 %s 构建交易上下文失败，跳过: %v", member.name, err)
+			continue
+		}
+		members = append(members, decision.PortfolioMember{TraderID: member.id, Context: memberCtx})
+		others[member.id] = member
+	}
+
+	mergedCtx, err := decision.BuildPortfolioContext(members)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	at.log().Infof("🧩 组合模式 [%s]：合并 %d 个交易员 | 组合净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
+		group.Name(), len(members), mergedCtx.Account.TotalEquity, mergedCtx.Account.AvailableBalance, mergedCtx.Account.PositionCount)
+	record.ExecutionLog = append(record.ExecutionLog,
+		fmt.Sprintf("组合模式 [%s]：%d 个交易员共用一次AI调用", group.Name(), len(members)))
+
+	model := at.nextModel()
+	record.AIModel = model.label
+	fullDecision, err := at.callModel(mergedCtx, model, record)
+	// 成员共用本次AI调用，同样计入各自的每日AI预算（组长的调用由 runCycle 计入）
+	for _, member := range others {
+		member.recordAICall()
+	}
+	if err != nil {
+		return fullDecision, nil, err
+	}
+
+	assigned, rejected := decision.AssignPortfolioDecisions(fullDecision.Decisions, members)
+	for _, reason := range rejected {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧩 未分配: %s", reason))
+	}
+
+	for id, member := range others {
+		if len(assigned[id]) == 0 {
+			continue
+		}
+		member.executePortfolioDecisions(group.Name(), at.name, fullDecision, assigned[id])
+	}
+
+	return fullDecision, assigned[at.id], nil
+}
+
+// executePortfolioDecisions 成员执行组长分配的决策，并写入自己的决策日志
+// 执行前按成员自身周期的规则过滤：风控暂停中不执行；交易窗口外或未入金时只执行平仓和调整止盈止损
+func (at *AutoTrader) executePortfolioDecisions(groupName, leaderName string, fullDecision *decision.FullDecision, decisions []decision.Decision) {
+	if !at.tryLockCycle(portfolioLockTimeout) {
+		at.log().Warnf("⚠️ [%s] 周期锁等待超时，放弃执行本轮组合决策", at.name)
+		return
+	}
+	defer at.cycleMutex.Unlock()
+
+	record := &logger.DecisionRecord{
+		Exchange:     at.config.Exchange,
+		ExecutionLog: []string{fmt.Sprintf("组合模式 [%s]：执行组长 %s 分配的决策", groupName, leaderName)},
+		Success:      true,
+		SystemPrompt: fullDecision.SystemPrompt,
+		InputPrompt:  fullDecision.UserPrompt,
+		CoTTrace:     fullDecision.CoTTrace,
+	}
+	if decisionJSON, err := json.MarshalIndent(decisions, "", "  "); err == nil {
+		record.DecisionJSON = string(decisionJSON)
+	}
+
+	// 组长构建上下文后成员可能刚触发风控暂停
+	if stopUntil := at.getStopUntil(); time.Now().Before(stopUntil) {
+		at.log().Infof("⏸ [%s] 风险控制暂停中，放弃执行本轮组合决策", at.name)
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", time.Until(stopUntil).Minutes())
+		if err := at.decisionLogger.LogDecision(record); err != nil {
+			at.log().Warnf("⚠ [%s] 保存组合决策记录失败: %v", at.name, err)
+		}
+		return
+	}
+	if at.outsideTradingWindow() {
+		decisions = at.dropOpenDecisions(decisions, record)
+	}
+	if at.IsUnfunded() {
+		decisions = at.dropOpenDecisionsFor(decisions, record, "account unfunded")
+	}
+
+	for _, d := range sortDecisionsByPriority(decisions) {
+		actionRecord := logger.DecisionAction{
+			Action:    d.Action,
+			Symbol:    d.Symbol,
+			Leverage:  d.Leverage,
+			Timestamp: time.Now(),
+		}
+
+		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
+			at.log().Errorf("❌ [%s] 执行组合决策失败 (%s %s): %v", at.name, d.Symbol, d.Action, err)
+			recordActionError(&actionRecord, err)
+			at.recordRejection(&d, err)
+			at.captureRejectionFeedback(&d, err)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+		} else if actionRecord.Skipped {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏭ %s %s 跳过: 与当前挂单价格相同", d.Symbol, d.Action))
+		} else if actionRecord.DryRun {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧪 %s %s 模拟运行: 未向交易所下单", d.Symbol, d.Action))
+		} else {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+
+	at.appendSafetyStops(record)
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		at.log().Warnf("⚠ [%s] 保存组合决策记录失败: %v", at.name, err)
+	}
+}
+
+// tryLockCycle 在超时时间内尝试获取周期锁（避免组长交接时互相等待导致死锁）
+func (at *AutoTrader) tryLockCycle(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if at.cycleMutex.TryLock() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
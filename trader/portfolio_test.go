@@ -0,0 +1,161 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestPortfolioGroupMembership 测试组合分组的加入/退出与组长选择
+func TestPortfolioGroupMembership(t *testing.T) {
+	group := NewPortfolioGroup("core", "binance")
+
+	a := &AutoTrader{id: "trader_b", name: "B", exchange: "binance"}
+	b := &AutoTrader{id: "trader_a", name: "A", exchange: "binance"}
+	other := &AutoTrader{id: "trader_c", name: "C", exchange: "hyperliquid"}
+	a.isRunning.Store(true)
+	other.isRunning.Store(true)
+
+	if err := group.Join(a); err != nil {
+		t.Fatalf("加入失败: %v", err)
+	}
+	if err := group.Join(b); err != nil {
+		t.Fatalf("加入失败: %v", err)
+	}
+	if err := group.Join(other); err == nil {
+		t.Error("不同交易所的交易员不应允许加入")
+	}
+	if other.GetPortfolioGroup() != nil {
+		t.Error("加入失败的交易员不应绑定分组")
+	}
+
+	if group.Size() != 2 {
+		t.Fatalf("成员数量 = %d, 期望 2", group.Size())
+	}
+
+	// 只有运行中的成员才能成为组长
+	if !group.IsLeader(a) || group.IsLeader(b) {
+		t.Error("组长应为运行中ID最小的成员 trader_b")
+	}
+	b.isRunning.Store(true)
+	if !group.IsLeader(b) || group.IsLeader(a) {
+		t.Error("trader_a 启动后应成为组长")
+	}
+
+	// 本周期无法决策（风控暂停 / AI预算用完）的成员不做组长，由下一个成员接任
+	b.setStopUntil(time.Now().Add(time.Hour))
+	if !group.IsLeader(a) || group.IsLeader(b) {
+		t.Error("风控暂停中的成员不应成为组长")
+	}
+	b.setStopUntil(time.Time{})
+	b.config.MaxAICallsPerDay = 1
+	b.recordAICall()
+	if !group.IsLeader(a) || group.IsLeader(b) {
+		t.Error("AI预算用完的成员不应成为组长")
+	}
+	// 没有成员能决策时退回运行中ID最小的成员
+	a.setStopUntil(time.Now().Add(time.Hour))
+	if !group.IsLeader(b) || group.IsLeader(a) {
+		t.Error("没有可决策的成员时应退回运行中ID最小的成员")
+	}
+	a.setStopUntil(time.Time{})
+	b.resetDailyAICalls()
+
+	group.Leave(b.id)
+	if group.Size() != 1 || b.GetPortfolioGroup() != nil {
+		t.Error("退出分组后应解除绑定")
+	}
+	if !group.IsLeader(a) {
+		t.Error("剩余成员应成为组长")
+	}
+}
+
+// TestPortfolioGroupConcurrentAccess 测试成员退出、启停、风控暂停与组长读取分组状态并发执行（配合 go test -race）
+func TestPortfolioGroupConcurrentAccess(t *testing.T) {
+	group := NewPortfolioGroup("core", "binance")
+	leader := &AutoTrader{id: "trader_a", name: "A", exchange: "binance"}
+	member := &AutoTrader{id: "trader_b", name: "B", exchange: "binance"}
+	leader.isRunning.Store(true)
+	for _, at := range []*AutoTrader{leader, member} {
+		if err := group.Join(at); err != nil {
+			t.Fatalf("加入失败: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			member.isRunning.Store(i%2 == 0)
+			member.setStopUntil(time.Now().Add(time.Minute))
+			group.Leave(member.id)
+			_ = group.Join(member)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if g := leader.GetPortfolioGroup(); g != nil {
+				for _, at := range g.activeMembers() {
+					_ = time.Now().Before(at.getStopUntil())
+				}
+				g.IsLeader(leader)
+			}
+			_ = member.GetPortfolioGroup()
+		}
+	}()
+	wg.Wait()
+
+	if member.GetPortfolioGroup() != group || group.Size() != 2 {
+		t.Error("重新加入后应绑定分组")
+	}
+}
+
+// TestExecutePortfolioDecisionsMemberGuards 测试成员执行组长分配的决策前应用自身周期的规则
+func TestExecutePortfolioDecisionsMemberGuards(t *testing.T) {
+	opens := []decision.Decision{
+		{Symbol: "BTCUSDT", Action: "open_long"},
+		{Symbol: "ETHUSDT", Action: "open_short"},
+	}
+	full := &decision.FullDecision{}
+	latest := func(at *AutoTrader) *logger.DecisionRecord {
+		records, err := at.decisionLogger.GetLatestRecords(1)
+		if err != nil || len(records) != 1 {
+			t.Fatalf("应写入成员自己的决策记录: %v", err)
+		}
+		return records[0]
+	}
+
+	// 风控暂停中：不执行任何决策
+	paused := &AutoTrader{name: "paused", decisionLogger: logger.NewDecisionLogger(t.TempDir())}
+	paused.setStopUntil(time.Now().Add(time.Hour))
+	paused.executePortfolioDecisions("core", "leader", full, opens)
+	if r := latest(paused); r.Success || len(r.Decisions) != 0 || !strings.Contains(r.ErrorMessage, "风险控制暂停中") {
+		t.Errorf("风控暂停中的成员不应执行组合决策: %+v", r)
+	}
+
+	// 交易窗口外：丢弃开仓决策
+	hour := time.Now().UTC().Hour()
+	window, err := ParseTradingWindow(fmt.Sprintf("%02d:00-%02d:00", (hour+2)%24, (hour+3)%24), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := &AutoTrader{name: "outside", config: AutoTraderConfig{TradingWindow: window}, decisionLogger: logger.NewDecisionLogger(t.TempDir())}
+	outside.executePortfolioDecisions("core", "leader", full, opens)
+	if r := latest(outside); len(r.Decisions) != 0 || !strings.Contains(strings.Join(r.ExecutionLog, "\n"), "outside trading window") {
+		t.Errorf("交易窗口外的成员不应执行开仓决策: %+v", r)
+	}
+
+	// 未入金：丢弃开仓决策
+	unfunded := &AutoTrader{name: "unfunded", decisionLogger: logger.NewDecisionLogger(t.TempDir())}
+	unfunded.checkFunding(0, 0)
+	unfunded.executePortfolioDecisions("core", "leader", full, opens)
+	if r := latest(unfunded); len(r.Decisions) != 0 || !strings.Contains(strings.Join(r.ExecutionLog, "\n"), "account unfunded") {
+		t.Errorf("未入金的成员不应执行开仓决策: %+v", r)
+	}
+}
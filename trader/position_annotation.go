@@ -0,0 +1,133 @@
+package trader
+
+import (
+	"nofx/config"
+)
+
+// positionAnnotationStore 持仓备注读写接口（数据库以鸭子类型注入）
+type positionAnnotationStore interface {
+	SavePositionAnnotation(a *config.PositionAnnotation) error
+	GetPositionAnnotations(traderID string) ([]*config.PositionAnnotation, error)
+	DeletePositionAnnotation(traderID, symbol, side string) error
+}
+
+// PositionAnnotation 持仓的人工备注和锁定状态
+// 备注和锁定状态会写入AI提示词；锁定后执行层拒绝 close_long/close_short/partial_close
+type PositionAnnotation struct {
+	Symbol string `json:"symbol"`
+	Side   string `json:"side"`
+	Note   string `json:"note"`
+	Locked bool   `json:"locked"`
+}
+
+// loadPositionAnnotations 从数据库恢复持仓备注
+func (at *AutoTrader) loadPositionAnnotations() {
+	db, ok := at.database.(positionAnnotationStore)
+	if !ok {
+		return
+	}
+	saved, err := db.GetPositionAnnotations(at.id)
+	if err != nil {
+		at.log().Warnf("⚠️ [%s] 加载持仓备注失败: %v", at.name, err)
+		return
+	}
+
+	at.annotationsMutex.Lock()
+	defer at.annotationsMutex.Unlock()
+	if at.annotations == nil {
+		at.annotations = make(map[string]PositionAnnotation)
+	}
+	for _, a := range saved {
+		at.annotations[a.Symbol+"_"+a.Side] = PositionAnnotation{Symbol: a.Symbol, Side: a.Side, Note: a.Note, Locked: a.Locked}
+	}
+}
+
+// AnnotatePosition 为当前持仓设置人工备注和锁定状态（备注为空且未锁定时清除）
+func (at *AutoTrader) AnnotatePosition(symbol, side, note string, locked bool) (*PositionAnnotation, error) {
+	if _, err := at.findPosition(symbol, side); err != nil {
+		return nil, err
+	}
+
+	if db, ok := at.database.(positionAnnotationStore); ok {
+		if err := db.SavePositionAnnotation(&config.PositionAnnotation{
+			TraderID: at.id, Symbol: symbol, Side: side, Note: note, Locked: locked,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	annotation := PositionAnnotation{Symbol: symbol, Side: side, Note: note, Locked: locked}
+	posKey := symbol + "_" + side
+	at.annotationsMutex.Lock()
+	if at.annotations == nil {
+		at.annotations = make(map[string]PositionAnnotation)
+	}
+	if note == "" && !locked {
+		delete(at.annotations, posKey)
+	} else {
+		at.annotations[posKey] = annotation
+	}
+	at.annotationsMutex.Unlock()
+
+	at.log().Infof("📝 [%s] 更新 %s %s 持仓备注（锁定: %v）: %s", at.name, symbol, side, locked, note)
+	return &annotation, nil
+}
+
+// getPositionAnnotation 获取持仓的备注和锁定状态
+func (at *AutoTrader) getPositionAnnotation(posKey string) PositionAnnotation {
+	at.annotationsMutex.RLock()
+	defer at.annotationsMutex.RUnlock()
+	return at.annotations[posKey]
+}
+
+// clearPositionAnnotation 持仓完全平仓后删除其备注
+func (at *AutoTrader) clearPositionAnnotation(symbol, side string) {
+	posKey := symbol + "_" + side
+	at.annotationsMutex.Lock()
+	_, exists := at.annotations[posKey]
+	delete(at.annotations, posKey)
+	at.annotationsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	if db, ok := at.database.(positionAnnotationStore); ok {
+		if err := db.DeletePositionAnnotation(at.id, symbol, side); err != nil {
+			at.log().Warnf("⚠️ [%s] %v", at.name, err)
+		}
+	}
+}
+
+// prunePositionAnnotations 清理已不存在持仓的备注（交易所侧止损、强平、紧急平仓等）
+func (at *AutoTrader) prunePositionAnnotations(activeKeys map[string]bool) {
+	at.annotationsMutex.RLock()
+	var stale []PositionAnnotation
+	for key, a := range at.annotations {
+		if !activeKeys[key] {
+			stale = append(stale, a)
+		}
+	}
+	at.annotationsMutex.RUnlock()
+
+	for _, a := range stale {
+		at.clearPositionAnnotation(a.Symbol, a.Side)
+	}
+}
+
+// checkPositionLocked 检查平仓类决策的目标持仓是否被人工锁定
+// partial_close 不指定方向，任一方向被锁定即拒绝
+func (at *AutoTrader) checkPositionLocked(action, symbol string) error {
+	sides := []string{"long", "short"}
+	switch action {
+	case "close_long":
+		sides = []string{"long"}
+	case "close_short":
+		sides = []string{"short"}
+	}
+	for _, side := range sides {
+		if at.getPositionAnnotation(symbol + "_" + side).Locked {
+			return rejectDecisionf(RejectPositionLocked, "POSITION_LOCKED_DETAIL", symbol, side)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+
+	"nofx/config"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// annotationStore 记录持仓备注的模拟数据库
+type annotationStore struct {
+	saved map[string]config.PositionAnnotation
+}
+
+func (s *annotationStore) SavePositionAnnotation(a *config.PositionAnnotation) error {
+	s.saved[a.Symbol+"_"+a.Side] = *a
+	return nil
+}
+
+func (s *annotationStore) GetPositionAnnotations(traderID string) ([]*config.PositionAnnotation, error) {
+	var result []*config.PositionAnnotation
+	for _, a := range s.saved {
+		a := a
+		result = append(result, &a)
+	}
+	return result, nil
+}
+
+func (s *annotationStore) DeletePositionAnnotation(traderID, symbol, side string) error {
+	delete(s.saved, symbol+"_"+side)
+	return nil
+}
+
+// TestPositionLockRejectsClose 测试锁定的持仓拒绝 AI 的平仓决策且不下单，持仓消失后备注自动清除
+func TestPositionLockRejectsClose(t *testing.T) {
+	mock := &MockTrader{positions: []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 51000.0},
+	}}
+	store := &annotationStore{saved: map[string]config.PositionAnnotation{}}
+	at := &AutoTrader{name: "lock", id: "lock", trader: mock, database: store}
+
+	if _, err := at.AnnotatePosition("ETHUSDT", "long", "不存在", true); !errors.Is(err, ErrPositionNotFound) {
+		t.Errorf("没有持仓时应返回 ErrPositionNotFound, 实际 %v", err)
+	}
+	if _, err := at.AnnotatePosition("BTCUSDT", "long", "长线底仓", true); err != nil {
+		t.Fatalf("设置备注失败: %v", err)
+	}
+	if a := store.saved["BTCUSDT_long"]; !a.Locked || a.Note != "长线底仓" {
+		t.Errorf("备注应写入数据库: %+v", a)
+	}
+
+	for _, action := range []string{"close_long", "partial_close"} {
+		record := &logger.DecisionAction{}
+		err := at.executeDecisionWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: action, ClosePercentage: 50}, record)
+		if RejectionCode(err) != RejectPositionLocked {
+			t.Errorf("%s 锁定的持仓应被拒绝, 实际错误: %v", action, err)
+		}
+	}
+	if err := at.checkPositionLocked("close_short", "BTCUSDT"); err != nil {
+		t.Errorf("未锁定的空仓不应被拒绝: %v", err)
+	}
+	if mock.orderCalls != 0 {
+		t.Errorf("锁定的持仓不应发送任何订单, 实际 %d 次", mock.orderCalls)
+	}
+
+	// 重启后从数据库恢复
+	restored := &AutoTrader{name: "lock", id: "lock", trader: mock, database: store}
+	restored.loadPositionAnnotations()
+	if !restored.getPositionAnnotation("BTCUSDT_long").Locked {
+		t.Error("重启后应恢复锁定状态")
+	}
+
+	// 持仓完全平仓后清除
+	restored.prunePositionAnnotations(map[string]bool{})
+	if restored.getPositionAnnotation("BTCUSDT_long").Locked || len(store.saved) != 0 {
+		t.Errorf("持仓消失后备注应被清除, 数据库剩余 %v", store.saved)
+	}
+}
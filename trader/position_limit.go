@@ -0,0 +1,27 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+)
+
+// checkPositionLimits 检查开仓决策是否超过交易员的仓位上限（MaxPositions / MaxPositionSizeUSD，0=不限制）
+// 持仓数量以交易所实际持仓为准，无法获取持仓时拒绝开仓
+func (at *AutoTrader) checkPositionLimits(d *decision.Decision) error {
+	if limit := at.config.MaxPositionSizeUSD; limit > 0 && d.PositionSizeUSD > limit {
+		return fmt.Errorf("🚧 %s 仓位 %.2f USDT 超过单笔上限 %.2f USDT，拒绝开仓", d.Symbol, d.PositionSizeUSD, limit)
+	}
+
+	limit := at.config.MaxPositions
+	if limit <= 0 {
+		return nil
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return fmt.Errorf("🚧 %s 无法获取持仓检查持仓数量上限，拒绝开仓: %w", d.Symbol, err)
+	}
+	if len(positions) >= limit {
+		return fmt.Errorf("🚧 当前已持仓 %d 个，达到持仓数量上限 %d，拒绝开仓 %s", len(positions), limit, d.Symbol)
+	}
+	return nil
+}
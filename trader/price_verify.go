@@ -0,0 +1,95 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"nofx/market"
+)
+
+// priceConsistencyMaxDeviation 多数据源价格允许的最大偏差（2%）
+const priceConsistencyMaxDeviation = 0.02
+
+// priceVerifier 多数据源价格一致性校验（由 market.DataSourceManager 实现）
+type priceVerifier interface {
+	VerifyPriceConsistency(symbol string, maxDeviation float64) (bool, map[string]float64, error)
+}
+
+// currentPriceVerifier 返回当前可用的价格校验器，未启用多数据源时返回 nil（测试中可替换）
+// 测试网交易员使用测试网数据源，保证校验价格与下单环境一致
+var currentPriceVerifier = func(testnet bool) priceVerifier {
+	if testnet {
+		if dsm := market.GetTestnetDSManager(); dsm != nil {
+			return dsm
+		}
+		return nil
+	}
+	if market.WSMonitorCli == nil {
+		return nil
+	}
+	if dsm := market.WSMonitorCli.GetDSManager(); dsm != nil {
+		return dsm
+	}
+	return nil
+}
+
+// marketRESTFallbackActive 行情是否处于 REST 轮询降级模式（测试中可替换）
+var marketRESTFallbackActive = market.RESTFallbackActive
+
+// isTestnet 当前交易所是否连接测试网（OKX 模拟盘使用实盘行情，按主网处理）
+func (at *AutoTrader) isTestnet() bool {
+	switch at.config.Exchange {
+	case "binance":
+		return at.config.BinanceTestnet
+	case "hyperliquid":
+		return at.config.HyperliquidTestnet
+	}
+	return false
+}
+
+// verifyEntryPrice 开仓前的多数据源价格一致性验证（防止单交易所价格异常导致误判）
+// 名义价值达到 StrictPriceCheckNotional 时进入严格模式：必须有至少两个健康数据源且价格一致，否则拒绝开仓；
+// 小额开仓在数据源不足时降级放行
+func (at *AutoTrader) verifyEntryPrice(symbol string, notional float64) error {
+	threshold := at.config.StrictPriceCheckNotional
+	strict := threshold > 0 && notional >= threshold
+
+	verifier := currentPriceVerifier(at.isTestnet())
+	if verifier == nil {
+		if strict {
+			return fmt.Errorf("❌ %s 开仓金额 %.2f USDT 达到严格校验阈值 %.2f USDT，但未启用多数据源价格校验，拒绝开仓",
+				symbol, notional, threshold)
+		}
+		return nil
+	}
+
+	consistent, prices, err := verifier.VerifyPriceConsistency(symbol, priceConsistencyMaxDeviation)
+	if err != nil {
+		// WebSocket 行情中断、降级为单一来源的 REST 轮询：无法做多源校验，只记录日志，不阻止所有开仓
+		if errors.Is(err, market.ErrInsufficientPriceSources) && marketRESTFallbackActive() {
+			at.log().Warnf("⚠️  %s 行情处于 REST 轮询降级模式，数据源不足以验证价格一致性，跳过校验继续交易", symbol)
+			return nil
+		}
+		if strict {
+			return fmt.Errorf("❌ %s 开仓金额 %.2f USDT 达到严格校验阈值 %.2f USDT，需要至少两个健康数据源确认价格，拒绝开仓: %w",
+				symbol, notional, threshold, err)
+		}
+		at.log().Warnf("⚠️  %s 价格验证失败（数据源不足），继续交易: %v", symbol, err)
+		return nil
+	}
+
+	if !consistent {
+		priceDetails := ""
+		for source, price := range prices {
+			priceDetails += fmt.Sprintf("%s: %.2f, ", source, price)
+		}
+		return fmt.Errorf("❌ 价格异常：%s 在多个数据源间偏差过大（>2%%），拒绝开仓以防止误判。价格: %s",
+			symbol, priceDetails)
+	}
+
+	if strict {
+		at.log().Infof("✅ %s 价格验证通过（大额开仓严格校验，%d 个数据源一致）", symbol, len(prices))
+	} else {
+		at.log().Infof("✅ %s 价格验证通过（多数据源一致性检查）", symbol)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package trader
+
+import (
+	"nofx/market"
+	"strings"
+	"testing"
+)
+
+// fakePriceVerifier 模拟多数据源价格校验
+type fakePriceVerifier struct {
+	prices map[string]float64
+}
+
+func (f *fakePriceVerifier) VerifyPriceConsistency(symbol string, maxDeviation float64) (bool, map[string]float64, error) {
+	if len(f.prices) < 2 {
+		return true, f.prices, market.ErrInsufficientPriceSources
+	}
+	var sum float64
+	for _, p := range f.prices {
+		sum += p
+	}
+	avg := sum / float64(len(f.prices))
+	for _, p := range f.prices {
+		if (p-avg)/avg > maxDeviation || (avg-p)/avg > maxDeviation {
+			return false, f.prices, nil
+		}
+	}
+	return true, f.prices, nil
+}
+
+// useFakePriceVerifier 替换价格校验器，返回恢复函数
+func useFakePriceVerifier(v priceVerifier) func() {
+	original := currentPriceVerifier
+	currentPriceVerifier = func(bool) priceVerifier { return v }
+	return func() { currentPriceVerifier = original }
+}
+
+// TestVerifyEntryPriceStrictForLargeOpen 测试大额开仓要求至少两个数据源一致，小额开仓降级放行
+func TestVerifyEntryPriceStrictForLargeOpen(t *testing.T) {
+	at := &AutoTrader{name: "strict", config: AutoTraderConfig{StrictPriceCheckNotional: 5000}}
+
+	single := &fakePriceVerifier{prices: map[string]float64{"binance": 50000}}
+	agree := &fakePriceVerifier{prices: map[string]float64{"binance": 50000, "okx": 50050}}
+	disagree := &fakePriceVerifier{prices: map[string]float64{"binance": 50000, "okx": 56000}}
+
+	tests := []struct {
+		name       string
+		verifier   priceVerifier
+		notional   float64
+		wantErrSub string
+	}{
+		{name: "小额开仓_数据源不足_降级放行", verifier: single, notional: 1000},
+		{name: "大额开仓_数据源不足_拒绝", verifier: single, notional: 10000, wantErrSub: "需要至少两个健康数据源"},
+		{name: "大额开仓_两个数据源一致_通过", verifier: agree, notional: 10000},
+		{name: "大额开仓_价格偏差过大_拒绝", verifier: disagree, notional: 10000, wantErrSub: "偏差过大"},
+		{name: "小额开仓_价格偏差过大_拒绝", verifier: disagree, notional: 1000, wantErrSub: "偏差过大"},
+		{name: "大额开仓_未启用多数据源_拒绝", verifier: nil, notional: 5000, wantErrSub: "未启用多数据源价格校验"},
+		{name: "小额开仓_未启用多数据源_放行", verifier: nil, notional: 4999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore := useFakePriceVerifier(tt.verifier)
+			defer restore()
+
+			err := at.verifyEntryPrice("BTCUSDT", tt.notional)
+			if tt.wantErrSub == "" {
+				if err != nil {
+					t.Errorf("不应拒绝开仓: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrSub) {
+				t.Errorf("错误应包含 %q, 实际 %v", tt.wantErrSub, err)
+			}
+		})
+	}
+
+	// 阈值为0时不启用严格模式
+	at.config.StrictPriceCheckNotional = 0
+	restore := useFakePriceVerifier(single)
+	defer restore()
+	if err := at.verifyEntryPrice("BTCUSDT", 1_000_000); err != nil {
+		t.Errorf("未启用严格模式时数据源不足应放行: %v", err)
+	}
+}
+
+// TestVerifyEntryPriceDuringRESTFallback 测试行情降级为 REST 轮询时，数据源不足只记录日志不拒绝开仓，价格偏差仍然拒绝
+func TestVerifyEntryPriceDuringRESTFallback(t *testing.T) {
+	at := &AutoTrader{name: "fallback", config: AutoTraderConfig{StrictPriceCheckNotional: 5000}}
+
+	originalFallback := marketRESTFallbackActive
+	marketRESTFallbackActive = func() bool { return true }
+	defer func() { marketRESTFallbackActive = originalFallback }()
+
+	restore := useFakePriceVerifier(&fakePriceVerifier{prices: map[string]float64{"binance": 50000}})
+	if err := at.verifyEntryPrice("BTCUSDT", 10000); err != nil {
+		t.Errorf("REST 轮询降级时数据源不足不应拒绝大额开仓: %v", err)
+	}
+	restore()
+
+	restore = useFakePriceVerifier(&fakePriceVerifier{prices: map[string]float64{"binance": 50000, "okx": 56000}})
+	defer restore()
+	if err := at.verifyEntryPrice("BTCUSDT", 1000); err == nil || !strings.Contains(err.Error(), "偏差过大") {
+		t.Errorf("REST 轮询降级时价格偏差过大仍应拒绝, 实际 %v", err)
+	}
+}
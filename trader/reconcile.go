@@ -0,0 +1,91 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// externalOpenReason 对账接管外部持仓时补记开仓记录的原因
+const externalOpenReason = "对账接管：交易所持仓没有开仓记录（手动或外部下单）"
+
+// reconcileExternalPositions 对比交易所持仓与 trade_history 未平仓记录，接管交易所上没有开仓记录的持仓
+// 以交易所持仓均价补记一条 EXTERNAL_OPEN 开仓记录，之后的平仓配对和盈亏计算与普通持仓一致；
+// 数据库有记录但交易所已没有的持仓由 syncAutoClosedPositions 按自动平仓处理。返回写入决策记录的对账日志
+func (at *AutoTrader) reconcileExternalPositions() []string {
+	db, ok := at.database.(interface {
+		GetOpenPositionsFromHistory(string) (map[string]map[string]interface{}, error)
+		RecordTrade(string, string, string, string, string, float64, float64, string, float64, float64, float64, float64) error
+	})
+	if !ok {
+		return nil
+	}
+	// 还有交易记录等待重试写入时数据库持仓不完整，跳过本轮对账，避免把自己的开仓当成外部持仓
+	if at.persistQueue != nil && at.persistQueue.Len() > 0 {
+		at.log().Infof("⏭ [%s] 持久化重试队列未清空，跳过本轮持仓对账", at.name)
+		return nil
+	}
+
+	records, err := db.GetOpenPositionsFromHistory(at.config.ID)
+	if err != nil {
+		at.log().Warnf("⚠️ [%s] 持仓对账：获取数据库持仓失败: %v", at.name, err)
+		return nil
+	}
+	tracked := make(map[string]bool, len(records))
+	for _, pos := range records {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		tracked[symbol+"_"+strings.ToLower(side)] = true
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		at.log().Warnf("⚠️ [%s] 持仓对账：获取交易所持仓失败: %v", at.name, err)
+		return nil
+	}
+
+	var notes []string
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		quantity, _ := pos["positionAmt"].(float64)
+		entryPrice, _ := pos["entryPrice"].(float64)
+		quantity = math.Abs(quantity)
+		side = strings.ToLower(side)
+		posKey := symbol + "_" + side
+		if symbol == "" || quantity == 0 || tracked[posKey] {
+			continue
+		}
+		// 自己挂出的限价开仓单部分成交，由 resolvePendingEntries 记录，不作为外部持仓接管
+		if at.hasPendingEntry(symbol, side) {
+			continue
+		}
+		// 同一交易所账户上的其他交易员已登记该币种，视为其持仓，不接管
+		if at.symbolRegistry != nil && at.symbolRegistry.HeldByOther(symbol, at.id) {
+			continue
+		}
+		if entryPrice <= 0 {
+			at.log().Warnf("⚠️ [%s] 外部持仓 %s %s 缺少入场价，暂不接管", at.name, symbol, side)
+			continue
+		}
+
+		if err := at.recordTradeWithRetry(db,
+			at.config.ID, at.userID, symbol,
+			strings.ToUpper(side), "EXTERNAL_OPEN",
+			quantity, entryPrice,
+			externalOpenReason,
+			0, 0, 0, 0,
+		); err != nil {
+			at.log().Warnf("⚠️ [%s] 记录外部持仓 %s %s 失败: %v", at.name, symbol, side, err)
+		}
+		if at.externalPositions == nil {
+			at.externalPositions = make(map[string]bool)
+		}
+		at.externalPositions[posKey] = true
+
+		note := fmt.Sprintf("持仓对账：接管外部持仓 %s %s 数量 %.4f @ %.4f", symbol, strings.ToUpper(side), quantity, entryPrice)
+		at.log().Infof("🧷 [%s] %s", at.name, note)
+		notes = append(notes, note)
+	}
+	return notes
+}
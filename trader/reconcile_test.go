@@ -0,0 +1,62 @@
+package trader
+
+import (
+	"testing"
+)
+
+// fakeReconcileStore 在未平仓记录基础上记录补写的交易
+type fakeReconcileStore struct {
+	fakeHistoryStore
+	trades []tradeRecordPayload
+}
+
+func (f *fakeReconcileStore) RecordTrade(traderID, userID, symbol, side, action string, quantity, price float64, reason string, stopLoss, takeProfit, pnl, pnlPercent float64) error {
+	f.trades = append(f.trades, tradeRecordPayload{
+		TraderID: traderID, UserID: userID, Symbol: symbol, Side: side, Action: action,
+		Quantity: quantity, Price: price, Reason: reason,
+	})
+	return nil
+}
+
+// TestReconcileExternalPositions 测试交易所上没有开仓记录的持仓按交易所均价接管，已记录和其他交易员的持仓不动
+func TestReconcileExternalPositions(t *testing.T) {
+	mock := &MockTrader{positions: []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "entryPrice": 50000.0, "positionAmt": 0.1},
+		{"symbol": "ETHUSDT", "side": "short", "entryPrice": 3000.0, "positionAmt": -2.0},
+		{"symbol": "SOLUSDT", "side": "long", "entryPrice": 100.0, "positionAmt": 5.0},
+		{"symbol": "XRPUSDT", "side": "long", "entryPrice": 0.5, "positionAmt": 0.0},
+	}}
+	store := &fakeReconcileStore{fakeHistoryStore: fakeHistoryStore{positions: map[string]map[string]interface{}{
+		"BTCUSDT_LONG": {"symbol": "BTCUSDT", "side": "LONG", "quantity": 0.1},
+	}}}
+	registry := NewSymbolPositionRegistry(0)
+	registry.Sync("other", []string{"SOLUSDT"})
+
+	at := &AutoTrader{
+		id:             "t1",
+		name:           "reconcile",
+		config:         AutoTraderConfig{ID: "t1"},
+		userID:         "u1",
+		trader:         mock,
+		database:       store,
+		symbolRegistry: registry,
+	}
+
+	notes := at.reconcileExternalPositions()
+	if len(notes) != 1 || len(store.trades) != 1 {
+		t.Fatalf("应只接管 ETHUSDT 空仓, 日志 %v, 记录 %+v", notes, store.trades)
+	}
+	trade := store.trades[0]
+	if trade.Symbol != "ETHUSDT" || trade.Side != "SHORT" || trade.Action != "EXTERNAL_OPEN" || trade.Quantity != 2 || trade.Price != 3000 {
+		t.Errorf("补记的开仓记录不正确: %+v", trade)
+	}
+	if !at.externalPositions["ETHUSDT_short"] || at.externalPositions["BTCUSDT_long"] {
+		t.Errorf("外部持仓标记不正确: %v", at.externalPositions)
+	}
+
+	// 已接管的持仓写入数据库后不再重复接管
+	store.positions["ETHUSDT_SHORT"] = map[string]interface{}{"symbol": "ETHUSDT", "side": "SHORT", "quantity": 2.0, "external": true}
+	if notes := at.reconcileExternalPositions(); len(notes) != 0 || len(store.trades) != 1 {
+		t.Errorf("重复接管: 日志 %v, 记录 %+v", notes, store.trades)
+	}
+}
@@ -0,0 +1,107 @@
+package trader
+
+import (
+	"errors"
+	"nofx/decision"
+	"nofx/i18n"
+	"nofx/logger"
+	"strings"
+)
+
+// 决策被拒绝的原因代码（写入 rejected_decisions，用于统计和调参）
+const (
+	RejectDailyTradeLimit    = "daily_trade_limit"   // 每日开仓次数已达上限
+	RejectPositionExists     = "position_exists"     // 已有同币种同方向持仓
+	RejectSymbolHalted       = "symbol_halted"       // 币种暂停交易或交易所维护
+	RejectSymbolCap          = "symbol_cap"          // 全实例币种持仓上限
+	RejectPriceCheck         = "price_check"         // 多数据源价格校验未通过
+	RejectInsufficientMargin = "insufficient_margin" // 保证金不足
+	RejectInvalidStops       = "invalid_stops"       // 止损/止盈价格不合理
+	RejectInvalidLimitPrice  = "invalid_limit_price" // AI 给出的限价不合理
+	RejectExposureLimit      = "exposure_limit"      // 总敞口超过账户净值的上限倍数
+	RejectSignalBias         = "signal_bias"         // 开仓方向与信号源方向偏好相反
+	RejectUnfunded           = "unfunded"            // 账户未入金（无持仓且可用余额接近0）
+	RejectNonCandidate       = "non_candidate"       // 开仓币种不在本周期候选列表中
+	RejectPositionLimit      = "position_limit"      // 持仓数量或单笔仓位超过交易员上限
+	RejectBlacklisted        = "blacklisted"         // 开仓币种在黑名单中
+	RejectPositionLocked     = "position_locked"     // 持仓已被人工锁定，拒绝平仓
+)
+
+// ErrorCodeExecutionFailed 非守卫拒绝的执行失败（如交易所下单失败）的错误码
+const ErrorCodeExecutionFailed = "EXECUTION_FAILED"
+
+// DecisionRejection 守卫检查拒绝执行决策的错误，携带结构化原因代码
+// Key/Args 非空时错误信息由 i18n 消息模板生成，API 可按请求语言重新生成
+type DecisionRejection struct {
+	Code string
+	Err  error
+	Key  string
+	Args []interface{}
+}
+
+func (r *DecisionRejection) Error() string { return r.Err.Error() }
+
+func (r *DecisionRejection) Unwrap() error { return r.Err }
+
+// rejectDecision 把守卫检查的错误包装为带原因代码的拒绝（错误信息保持不变）
+func rejectDecision(code string, err error) error {
+	return &DecisionRejection{Code: code, Err: err}
+}
+
+// rejectDecisionf 按 i18n 消息模板生成带原因代码的拒绝（错误信息使用中文模板，同时写入日志和AI反馈）
+func rejectDecisionf(code, key string, args ...interface{}) error {
+	return &DecisionRejection{
+		Code: code,
+		Err:  errors.New(i18n.Message(i18n.LangZH, key, args...)),
+		Key:  key,
+		Args: args,
+	}
+}
+
+// ErrorCode 返回决策执行错误的稳定错误码：守卫拒绝为大写的原因代码（如 INSUFFICIENT_MARGIN），其他为 EXECUTION_FAILED
+func ErrorCode(err error) string {
+	if code := RejectionCode(err); code != "" {
+		return strings.ToUpper(code)
+	}
+	return ErrorCodeExecutionFailed
+}
+
+// recordActionError 把执行失败写入决策动作记录：错误信息、错误码及可本地化的消息模板
+func recordActionError(actionRecord *logger.DecisionAction, err error) {
+	actionRecord.Error = err.Error()
+	actionRecord.ErrorCode = ErrorCode(err)
+	var rejection *DecisionRejection
+	if errors.As(err, &rejection) && rejection.Key != "" {
+		actionRecord.ErrorKey = rejection.Key
+		actionRecord.ErrorArgs = rejection.Args
+	}
+}
+
+// RejectionCode 返回错误对应的拒绝原因代码，非守卫拒绝返回空字符串
+func RejectionCode(err error) string {
+	var rejection *DecisionRejection
+	if errors.As(err, &rejection) {
+		return rejection.Code
+	}
+	return ""
+}
+
+// rejectionRecorder 拒绝记录写入接口（数据库以鸭子类型注入）
+type rejectionRecorder interface {
+	RecordRejectedDecision(traderID, userID, symbol, action, reasonCode, reason string) error
+}
+
+// recordRejection 记录被守卫检查拒绝的决策，非守卫拒绝（如交易所下单失败）不记录
+func (at *AutoTrader) recordRejection(d *decision.Decision, err error) {
+	code := RejectionCode(err)
+	if code == "" || !at.config.RecordRejections {
+		return
+	}
+	db, ok := at.database.(rejectionRecorder)
+	if !ok {
+		return
+	}
+	if err := db.RecordRejectedDecision(at.id, at.userID, d.Symbol, d.Action, code, err.Error()); err != nil {
+		at.log().Warnf("⚠️ [%s] 记录被拒绝的决策失败: %v", at.name, err)
+	}
+}
@@ -0,0 +1,93 @@
+package trader
+
+import (
+	"nofx/decision"
+	"sync"
+	"time"
+)
+
+// maxRejectionFeedback 每个周期最多反馈给AI的失败决策条数（只保留最新的）
+const maxRejectionFeedback = 10
+
+// maxRejectionReasonLen 反馈给AI的错误信息最大长度（交易所错误可能包含很长的响应体）
+const maxRejectionReasonLen = 300
+
+// pendingRejection 等待反馈给AI的失败决策
+type pendingRejection struct {
+	symbol string
+	action string
+	code   string
+	reason string
+	at     time.Time
+}
+
+// rejectionFeedback 执行失败的决策缓冲区：本周期写入，下一周期构建上下文时取出
+type rejectionFeedback struct {
+	mu      sync.Mutex
+	pending []pendingRejection
+}
+
+// add 记录一条失败决策，超过上限时丢弃最旧的
+func (f *rejectionFeedback) add(r pendingRejection) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pending = append(f.pending, r)
+	if len(f.pending) > maxRejectionFeedback {
+		f.pending = f.pending[len(f.pending)-maxRejectionFeedback:]
+	}
+}
+
+// drain 取出并清空所有待反馈的失败决策（每条只反馈一次）
+func (f *rejectionFeedback) drain() []decision.OrderRejection {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := f.pendingLocked()
+	f.pending = nil
+	return result
+}
+
+// peek 读取待反馈的失败决策但不清空（决策预演使用，不消耗下一周期的反馈）
+func (f *rejectionFeedback) peek() []decision.OrderRejection {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.pendingLocked()
+}
+
+// pendingLocked 转换待反馈的失败决策（调用方需持有 f.mu）
+func (f *rejectionFeedback) pendingLocked() []decision.OrderRejection {
+	if len(f.pending) == 0 {
+		return nil
+	}
+	result := make([]decision.OrderRejection, 0, len(f.pending))
+	for _, r := range f.pending {
+		result = append(result, decision.OrderRejection{
+			Symbol:     r.symbol,
+			Action:     r.action,
+			Code:       r.code,
+			Reason:     r.reason,
+			MinutesAgo: int(time.Since(r.at).Minutes()),
+		})
+	}
+	return result
+}
+
+// captureRejectionFeedback 记录执行失败的决策，下一周期写入AI上下文（RejectionFeedback 关闭时不记录）
+func (at *AutoTrader) captureRejectionFeedback(d *decision.Decision, err error) {
+	if err == nil || !at.config.RejectionFeedback {
+		return
+	}
+	reason := err.Error()
+	if runes := []rune(reason); len(runes) > maxRejectionReasonLen {
+		reason = string(runes[:maxRejectionReasonLen]) + "..."
+	}
+	at.rejectionFeedback.add(pendingRejection{
+		symbol: d.Symbol,
+		action: d.Action,
+		code:   RejectionCode(err),
+		reason: reason,
+		at:     time.Now(),
+	})
+}
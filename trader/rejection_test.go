@@ -0,0 +1,76 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"testing"
+)
+
+type fakeRejectionRecorder struct {
+	codes []string
+}
+
+func (f *fakeRejectionRecorder) RecordRejectedDecision(traderID, userID, symbol, action, reasonCode, reason string) error {
+	f.codes = append(f.codes, reasonCode)
+	return nil
+}
+
+// TestRejectionCode 测试拒绝原因代码的包装与提取（错误信息保持不变）
+func TestRejectionCode(t *testing.T) {
+	base := errors.New("❌ 保证金不足")
+	err := rejectDecision(RejectInsufficientMargin, base)
+	if err.Error() != base.Error() || !errors.Is(err, base) {
+		t.Errorf("包装后错误信息或错误链不正确: %v", err)
+	}
+	if code := RejectionCode(fmt.Errorf("开仓失败: %w", err)); code != RejectInsufficientMargin {
+		t.Errorf("应能从包装链中取出原因代码, 实际 %q", code)
+	}
+	if code := RejectionCode(base); code != "" {
+		t.Errorf("非守卫拒绝不应有原因代码, 实际 %q", code)
+	}
+}
+
+// TestRecordActionError 测试决策动作记录的错误码：模板拒绝保留模板和参数，非守卫错误为 EXECUTION_FAILED
+func TestRecordActionError(t *testing.T) {
+	var action logger.DecisionAction
+	recordActionError(&action, rejectDecisionf(RejectInsufficientMargin, "INSUFFICIENT_MARGIN_DETAIL", 110.0, 100.0, 10.0, 50.0))
+	if want := "❌ 保证金不足: 需要 110.00 USDT（保证金 100.00 + 手续费 10.00），可用 50.00 USDT"; action.Error != want {
+		t.Errorf("错误信息应与原中文提示一致: %q", action.Error)
+	}
+	if action.ErrorCode != "INSUFFICIENT_MARGIN" || action.ErrorKey != "INSUFFICIENT_MARGIN_DETAIL" || len(action.ErrorArgs) != 4 {
+		t.Errorf("错误码或模板不正确: %+v", action)
+	}
+
+	action = logger.DecisionAction{}
+	recordActionError(&action, rejectDecision(RejectPriceCheck, errors.New("价格异常")))
+	if action.ErrorCode != "PRICE_CHECK" || action.ErrorKey != "" {
+		t.Errorf("未使用模板的拒绝只应有错误码: %+v", action)
+	}
+
+	action = logger.DecisionAction{}
+	recordActionError(&action, errors.New("下单失败"))
+	if action.ErrorCode != ErrorCodeExecutionFailed || action.Error != "下单失败" {
+		t.Errorf("非守卫错误应为 EXECUTION_FAILED: %+v", action)
+	}
+}
+
+// TestRecordRejection 测试只记录守卫拒绝，且受开关控制
+func TestRecordRejection(t *testing.T) {
+	recorder := &fakeRejectionRecorder{}
+	at := &AutoTrader{id: "t1", name: "reject", database: recorder}
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long"}
+
+	at.recordRejection(d, rejectDecision(RejectPriceCheck, errors.New("价格异常")))
+	if len(recorder.codes) != 0 {
+		t.Fatalf("未开启记录时不应写入: %v", recorder.codes)
+	}
+
+	at.config.RecordRejections = true
+	at.recordRejection(d, rejectDecision(RejectPriceCheck, errors.New("价格异常")))
+	at.recordRejection(d, errors.New("交易所下单失败"))
+	if len(recorder.codes) != 1 || recorder.codes[0] != RejectPriceCheck {
+		t.Errorf("应只记录守卫拒绝: %v", recorder.codes)
+	}
+}
@@ -0,0 +1,22 @@
+package trader
+
+import (
+	"nofx/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// log 交易员的结构化 logger：附带 trader_id/user_id/component/cycle 字段，
+// 写入 stdout 的同时保存到该交易员的运行日志缓冲区（GET /api/traders/:id/logs）
+func (at *AutoTrader) log() *logrus.Entry {
+	entry := at.runtimeLog
+	if entry == nil {
+		entry = logger.ForTrader(at.id, at.userID, "")
+	}
+	return entry.WithField("cycle", at.logCycle.Load())
+}
+
+// Logger 供 TraderManager 记录与该交易员相关的日志（启动、运行错误等）
+func (at *AutoTrader) Logger() *logrus.Entry {
+	return at.log().WithField("component", "manager")
+}
@@ -0,0 +1,135 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// ActionAutoSafetyStop 兜底止损在决策记录中的动作名
+const ActionAutoSafetyStop = "auto_safety_stop"
+
+// hasExchangeStopLoss 判断交易所挂单中是否已有该持仓方向的止损单
+// 单向持仓模式（BOTH）下按订单方向判断：多单止损为 SELL，空单止损为 BUY
+func hasExchangeStopLoss(orders []decision.OpenOrderInfo, symbol, positionSide string) bool {
+	closeSide := "SELL"
+	if positionSide == "SHORT" {
+		closeSide = "BUY"
+	}
+	for _, order := range orders {
+		if order.Symbol != symbol || (order.Type != "STOP_MARKET" && order.Type != "STOP") {
+			continue
+		}
+		switch strings.ToUpper(order.PositionSide) {
+		case positionSide:
+			return true
+		case "", "BOTH":
+			if strings.ToUpper(order.Side) == closeSide {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// safetyStopPrice 计算兜底止损价：距入场价 pct%；若该价格已被当前价越过（会立即触发），改为距当前价 pct%
+func safetyStopPrice(positionSide string, entryPrice, markPrice, pct float64) float64 {
+	if positionSide == "LONG" {
+		stop := entryPrice * (1 - pct/100)
+		if markPrice > 0 && stop >= markPrice {
+			stop = markPrice * (1 - pct/100)
+		}
+		return stop
+	}
+	stop := entryPrice * (1 + pct/100)
+	if markPrice > 0 && stop <= markPrice {
+		stop = markPrice * (1 + pct/100)
+	}
+	return stop
+}
+
+// ensureSafetyStops 每个周期执行完决策后检查持仓保护：没有止损单且未跟踪到止损价（AI 未设置或设置失败）的持仓，
+// 按 SafetyStopPct 自动设置兜底止损，返回记录到决策日志的动作
+// 无法获取挂单时跳过本周期检查，避免在不确定的情况下重复挂单
+func (at *AutoTrader) ensureSafetyStops() []logger.DecisionAction {
+	pct := at.config.SafetyStopPct
+	if pct <= 0 {
+		return nil
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		at.log().Warnf("⚠️ [%s] 兜底止损检查：获取持仓失败: %v", at.name, err)
+		return nil
+	}
+	if len(positions) == 0 {
+		return nil
+	}
+
+	orders, err := at.trader.GetOpenOrders("")
+	if err != nil {
+		at.log().Warnf("⚠️ [%s] 兜底止损检查：获取挂单失败，本周期跳过: %v", at.name, err)
+		return nil
+	}
+
+	var actions []logger.DecisionAction
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		entryPrice, _ := pos["entryPrice"].(float64)
+		markPrice, _ := pos["markPrice"].(float64)
+		quantity, _ := pos["positionAmt"].(float64)
+		quantity = math.Abs(quantity)
+		if symbol == "" || quantity == 0 || entryPrice <= 0 {
+			continue
+		}
+
+		positionSide := strings.ToUpper(side)
+		posKey := symbol + "_" + strings.ToLower(side)
+		if at.positionStopLoss[posKey] > 0 || hasExchangeStopLoss(orders, symbol, positionSide) {
+			continue
+		}
+
+		stopPrice := at.alignStopPrice(symbol, positionSide, true, safetyStopPrice(positionSide, entryPrice, markPrice, pct))
+		action := logger.DecisionAction{
+			Action:    ActionAutoSafetyStop,
+			Symbol:    symbol,
+			Quantity:  quantity,
+			Price:     stopPrice,
+			Timestamp: time.Now(),
+		}
+
+		at.log().Infof("🛡️ [%s] %s %s 没有止损保护，自动设置兜底止损 %.4f（入场价 %.4f，距离 %.2f%%）",
+			at.name, symbol, positionSide, stopPrice, entryPrice, pct)
+		if at.dryRunSkip(&action, "兜底止损 %s %s → %.4f", symbol, positionSide, stopPrice) {
+			action.Success = true
+		} else if err := at.trader.SetStopLoss(symbol, positionSide, quantity, stopPrice); err != nil {
+			at.log().Errorf("❌ [%s] %s %s 设置兜底止损失败: %v", at.name, symbol, positionSide, err)
+			action.Error = fmt.Sprintf("设置兜底止损失败: %v", err)
+		} else {
+			action.Success = true
+			at.positionStopLoss[posKey] = stopPrice
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// appendSafetyStops 执行兜底止损检查并写入决策记录
+func (at *AutoTrader) appendSafetyStops(record *logger.DecisionRecord) {
+	for _, action := range at.ensureSafetyStops() {
+		record.Decisions = append(record.Decisions, action)
+		if action.Success {
+			if action.DryRun {
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧪 %s %s: 持仓无止损，模拟运行未下单（兜底止损 %.4f）", action.Symbol, action.Action, action.Price))
+				continue
+			}
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡️ %s %s: 持仓无止损，已设置兜底止损 %.4f", action.Symbol, action.Action, action.Price))
+		} else {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %s", action.Symbol, action.Action, action.Error))
+		}
+	}
+}
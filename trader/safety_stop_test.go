@@ -0,0 +1,96 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"testing"
+)
+
+// safetyStopMockTrader 记录兜底止损调用的模拟交易器
+type safetyStopMockTrader struct {
+	*MockTrader
+	openOrders []decision.OpenOrderInfo
+	stops      map[string]float64
+}
+
+func (m *safetyStopMockTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	return m.openOrders, nil
+}
+
+func (m *safetyStopMockTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	m.stops[symbol+"_"+positionSide] = stopPrice
+	return nil
+}
+
+// TestEnsureSafetyStops 测试没有止损保护的持仓会自动设置兜底止损，已有止损的持仓不重复设置
+func TestEnsureSafetyStops(t *testing.T) {
+	mock := &safetyStopMockTrader{
+		MockTrader: &MockTrader{positions: []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "entryPrice": 50000.0, "markPrice": 50500.0, "positionAmt": 0.1},
+			{"symbol": "ETHUSDT", "side": "short", "entryPrice": 3000.0, "markPrice": 3200.0, "positionAmt": -2.0},
+			{"symbol": "SOLUSDT", "side": "long", "entryPrice": 100.0, "markPrice": 101.0, "positionAmt": 10.0},
+			{"symbol": "BNBUSDT", "side": "short", "entryPrice": 600.0, "markPrice": 590.0, "positionAmt": -1.0},
+		}},
+		openOrders: []decision.OpenOrderInfo{
+			{Symbol: "SOLUSDT", Side: "SELL", PositionSide: "LONG", Type: "STOP_MARKET", StopPrice: 95},
+		},
+		stops: make(map[string]float64),
+	}
+	at := &AutoTrader{
+		name:             "safety",
+		trader:           mock,
+		config:           AutoTraderConfig{SafetyStopPct: 5},
+		positionStopLoss: map[string]float64{"BNBUSDT_short": 620},
+	}
+
+	actions := at.ensureSafetyStops()
+
+	if len(actions) != 2 || len(mock.stops) != 2 {
+		t.Fatalf("期望只为 BTC 和 ETH 设置兜底止损，实际动作 %d 个，止损 %v", len(actions), mock.stops)
+	}
+	if got := mock.stops["BTCUSDT_LONG"]; math.Abs(got-47500) > 1e-9 {
+		t.Errorf("BTC 多单兜底止损期望 47500，实际 %.4f", got)
+	}
+	// 空单入场价的 5% 止损（3150）已被当前价越过，应改为距当前价 5%
+	if got := mock.stops["ETHUSDT_SHORT"]; math.Abs(got-3360) > 1e-9 {
+		t.Errorf("ETH 空单兜底止损期望 3360，实际 %.4f", got)
+	}
+	for _, action := range actions {
+		if action.Action != ActionAutoSafetyStop || !action.Success {
+			t.Errorf("兜底止损动作记录不正确: %+v", action)
+		}
+	}
+	if at.positionStopLoss["BTCUSDT_long"] != 47500 {
+		t.Errorf("兜底止损应记录到持仓止损跟踪，实际 %v", at.positionStopLoss)
+	}
+
+	// 已跟踪止损后下一周期不再重复设置
+	mock.stops = make(map[string]float64)
+	if actions := at.ensureSafetyStops(); len(actions) != 0 {
+		t.Errorf("已设置兜底止损的持仓不应重复设置，实际 %d 个动作", len(actions))
+	}
+
+	// 比例为 0 时关闭兜底止损
+	at.config.SafetyStopPct = 0
+	at.positionStopLoss = make(map[string]float64)
+	if actions := at.ensureSafetyStops(); len(actions) != 0 || len(mock.stops) != 0 {
+		t.Errorf("SafetyStopPct=0 时不应设置兜底止损")
+	}
+}
+
+// TestHasExchangeStopLoss 测试单向持仓模式下按订单方向识别止损单
+func TestHasExchangeStopLoss(t *testing.T) {
+	orders := []decision.OpenOrderInfo{
+		{Symbol: "BTCUSDT", Side: "SELL", PositionSide: "BOTH", Type: "STOP_MARKET"},
+		{Symbol: "ETHUSDT", Side: "SELL", PositionSide: "LONG", Type: "TAKE_PROFIT_MARKET"},
+	}
+	if !hasExchangeStopLoss(orders, "BTCUSDT", "LONG") {
+		t.Error("单向持仓的 SELL 止损单应视为多单止损")
+	}
+	if hasExchangeStopLoss(orders, "BTCUSDT", "SHORT") {
+		t.Error("SELL 止损单不应视为空单止损")
+	}
+	if hasExchangeStopLoss(orders, "ETHUSDT", "LONG") {
+		t.Error("止盈单不应视为止损")
+	}
+}
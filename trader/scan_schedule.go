@@ -0,0 +1,44 @@
+package trader
+
+import (
+	"math/rand"
+	"nofx/netproxy"
+	"time"
+)
+
+// maxScanJitterPct 扫描间隔抖动上限（百分比），避免间隔被抖动到接近 0
+const maxScanJitterPct = 50
+
+// exchangeCallLimiter 所有交易员共用的交易所 REST 请求并发限制（默认不限制）
+// 覆盖 Binance/Aster/OKX 交易器；Hyperliquid SDK 不支持自定义 HTTP 客户端，不受限制
+var exchangeCallLimiter netproxy.Limiter
+
+// SetMaxConcurrentExchangeCalls 设置同时进行的交易所请求数上限（<=0 表示不限制），对已创建的交易器立即生效
+func SetMaxConcurrentExchangeCalls(n int) {
+	exchangeCallLimiter.SetLimit(n)
+}
+
+// SetFirstCycleDelay 设置首次决策周期的延迟（Run 之前调用），多个交易员同时启动时错开首次扫描
+func (at *AutoTrader) SetFirstCycleDelay(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	at.firstCycleDelay = d
+}
+
+// nextScanInterval 下一次扫描的间隔：ScanInterval 加上 ±ScanJitterPct% 的随机抖动
+func (at *AutoTrader) nextScanInterval() time.Duration {
+	return jitterInterval(at.config.ScanInterval, at.config.ScanJitterPct, rand.Float64())
+}
+
+// jitterInterval 按 r∈[0,1) 在 [base×(1-pct%), base×(1+pct%)) 内取值，pct 超出 [0,50] 时截断
+func jitterInterval(base time.Duration, pct float64, r float64) time.Duration {
+	if pct <= 0 || base <= 0 {
+		return base
+	}
+	if pct > maxScanJitterPct {
+		pct = maxScanJitterPct
+	}
+	factor := 1 + (2*r-1)*pct/100
+	return time.Duration(float64(base) * factor)
+}
@@ -0,0 +1,28 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+// TestJitterInterval 测试扫描间隔抖动范围
+func TestJitterInterval(t *testing.T) {
+	base := 3 * time.Minute
+	cases := []struct {
+		pct  float64
+		r    float64
+		want time.Duration
+	}{
+		{0, 0.9, base},                // 不抖动
+		{10, 0, 162 * time.Second},    // 下限 -10%
+		{10, 0.5, base},               // 中点
+		{10, 0.75, 189 * time.Second}, // +5%
+		{80, 0, 90 * time.Second},     // 超过上限按 ±50% 截断
+		{-5, 0.2, base},               // 负数视为不抖动
+	}
+	for _, tc := range cases {
+		if got := jitterInterval(base, tc.pct, tc.r); got != tc.want {
+			t.Errorf("jitterInterval(%v, %v, %v) = %v, 期望 %v", base, tc.pct, tc.r, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+)
+
+// candidateSignalBias 提取候选币种的信号方向偏好 (symbol -> long/short)，没有偏好的币种不记录
+func candidateSignalBias(coins []decision.CandidateCoin) map[string]string {
+	biases := make(map[string]string)
+	for _, coin := range coins {
+		if coin.Bias == "long" || coin.Bias == "short" {
+			biases[coin.Symbol] = coin.Bias
+		}
+	}
+	return biases
+}
+
+// checkSignalBias 启用 RespectSignalBias 时，拒绝与信号源方向偏好相反的开仓
+// side 为开仓方向（long/short）；币种不在本周期候选列表或没有偏好时放行
+func (at *AutoTrader) checkSignalBias(symbol, side string) error {
+	return at.checkSignalBiasIn(at.signalBias, symbol, side)
+}
+
+// checkSignalBiasIn 按指定的信号方向偏好检查开仓方向（决策预演使用本次构建的偏好，不依赖上一周期）
+func (at *AutoTrader) checkSignalBiasIn(signalBias map[string]string, symbol, side string) error {
+	if !at.config.RespectSignalBias {
+		return nil
+	}
+	bias, ok := signalBias[symbol]
+	if !ok || bias == side {
+		return nil
+	}
+	biasName, sideName := "偏空", "开多"
+	if bias == "long" {
+		biasName, sideName = "偏多", "开空"
+	}
+	return fmt.Errorf("❌ %s 信号源方向%s，拒绝%s（已启用遵循信号方向）", symbol, biasName, sideName)
+}
+
+// candidateSymbolSet 本周期候选币种集合
+func candidateSymbolSet(coins []decision.CandidateCoin) map[string]bool {
+	symbols := make(map[string]bool, len(coins))
+	for _, coin := range coins {
+		symbols[coin.Symbol] = true
+	}
+	return symbols
+}
+
+// checkCandidateSymbol 启用 RejectNonCandidates 时，拒绝对本周期候选列表之外的币种开仓（AI 幻觉或超出交易范围）
+// 尚未构建过交易上下文（候选集合为 nil）时放行
+func (at *AutoTrader) checkCandidateSymbol(symbol string) error {
+	return at.checkCandidateSymbolIn(at.candidateSymbols, symbol)
+}
+
+// checkCandidateSymbolIn 按指定的候选集合检查开仓币种
+func (at *AutoTrader) checkCandidateSymbolIn(candidates map[string]bool, symbol string) error {
+	if !at.config.RejectNonCandidates || candidates == nil {
+		return nil
+	}
+	if candidates[symbol] {
+		return nil
+	}
+	return fmt.Errorf("❌ %s 不在本周期候选币种中，拒绝开仓（已启用仅交易候选币种）", symbol)
+}
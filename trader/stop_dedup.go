@@ -0,0 +1,38 @@
+package trader
+
+import (
+	"math"
+	"nofx/logger"
+)
+
+// defaultStopUpdateTolerancePct 止损/止盈去重的默认容差（百分比）
+const defaultStopUpdateTolerancePct = 0.01
+
+// stopUpdateTolerancePct 返回止损/止盈去重容差（百分比），负数表示关闭去重
+func (at *AutoTrader) stopUpdateTolerancePct() float64 {
+	if at.config.StopUpdateTolerancePct == 0 {
+		return defaultStopUpdateTolerancePct
+	}
+	return at.config.StopUpdateTolerancePct
+}
+
+// isSameStopPrice 判断目标价格与当前价格的偏差是否在容差内
+func isSameStopPrice(current, target, tolerancePct float64) bool {
+	if current <= 0 || target <= 0 || tolerancePct < 0 {
+		return false
+	}
+	return math.Abs(target-current)/current*100 <= tolerancePct
+}
+
+// skipRedundantStopUpdate 新的止损/止盈价与当前跟踪值相同时跳过，避免每个周期重复撤单挂单
+// tracked 为 positionStopLoss 或 positionTakeProfit，命中时把动作标记为跳过（no-op）
+func (at *AutoTrader) skipRedundantStopUpdate(tracked map[string]float64, posKey, label string, target float64, actionRecord *logger.DecisionAction) bool {
+	current, exists := tracked[posKey]
+	if !exists || !isSameStopPrice(current, target, at.stopUpdateTolerancePct()) {
+		return false
+	}
+
+	at.log().Infof("  ⏭ %s未变化 (当前: %.4f, 目标: %.4f)，跳过重复调整", label, current, target)
+	actionRecord.Skipped = true
+	return true
+}
@@ -0,0 +1,67 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/stream"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CycleSummary 推送给实时订阅者的决策周期摘要（不含提示词和思维链等大文本）
+type CycleSummary struct {
+	CycleNumber    int                     `json:"cycle_number"`
+	Timestamp      time.Time               `json:"timestamp"`
+	Success        bool                    `json:"success"`
+	ErrorMessage   string                  `json:"error_message,omitempty"`
+	AIModel        string                  `json:"ai_model,omitempty"`
+	CachedDecision bool                    `json:"cached_decision,omitempty"`
+	Decisions      []logger.DecisionAction `json:"decisions"`
+	ExecutionLog   []string                `json:"execution_log"`
+}
+
+// publishCycle 决策周期结束后推送决策摘要、账户快照，持仓变化时推送持仓
+func (at *AutoTrader) publishCycle(record *logger.DecisionRecord) {
+	now := time.Now()
+	stream.Publish(stream.Event{
+		Type:     stream.EventDecision,
+		TraderID: at.id,
+		Time:     now,
+		Data: CycleSummary{
+			CycleNumber:    record.CycleNumber,
+			Timestamp:      record.Timestamp,
+			Success:        record.Success,
+			ErrorMessage:   record.ErrorMessage,
+			AIModel:        record.AIModel,
+			CachedDecision: record.CachedDecision,
+			Decisions:      record.Decisions,
+			ExecutionLog:   record.ExecutionLog,
+		},
+	})
+
+	// 周期在获取账户前就结束时（如风控暂停）没有账户快照
+	if record.AccountState.TotalBalance == 0 && record.AccountState.PositionCount == 0 {
+		return
+	}
+	stream.Publish(stream.Event{Type: stream.EventAccount, TraderID: at.id, Time: now, Data: record.AccountState})
+
+	if signature := positionsSignature(record.Positions); signature != at.streamedPositions {
+		at.streamedPositions = signature
+		positions := record.Positions
+		if positions == nil {
+			positions = []logger.PositionSnapshot{}
+		}
+		stream.Publish(stream.Event{Type: stream.EventPositions, TraderID: at.id, Time: now, Data: positions})
+	}
+}
+
+// positionsSignature 持仓签名（币种、方向、数量），用于判断持仓是否变化
+func positionsSignature(positions []logger.PositionSnapshot) string {
+	parts := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		parts = append(parts, fmt.Sprintf("%s:%s:%g", pos.Symbol, pos.Side, pos.PositionAmt))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "|")
+}
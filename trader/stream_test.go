@@ -0,0 +1,47 @@
+package trader
+
+import (
+	"nofx/logger"
+	"nofx/stream"
+	"testing"
+)
+
+// TestPublishCyclePositionsOnlyOnChange 测试每个周期推送决策和账户，持仓只在变化时推送
+func TestPublishCyclePositionsOnlyOnChange(t *testing.T) {
+	at := &AutoTrader{id: "stream_test"}
+	sub := stream.Subscribe([]string{"stream_test"}, 16)
+	defer sub.Close()
+
+	record := &logger.DecisionRecord{
+		CycleNumber:  3,
+		Success:      true,
+		AccountState: logger.AccountSnapshot{TotalBalance: 1000, PositionCount: 1},
+		Positions:    []logger.PositionSnapshot{{Symbol: "BTCUSDT", Side: "long", PositionAmt: 0.1}},
+	}
+	at.publishCycle(record)
+	at.publishCycle(record)
+
+	var types []string
+	for len(sub.Events()) > 0 {
+		types = append(types, (<-sub.Events()).Type)
+	}
+	want := []string{stream.EventDecision, stream.EventAccount, stream.EventPositions, stream.EventDecision, stream.EventAccount}
+	if len(types) != len(want) {
+		t.Fatalf("推送的事件应为 %v, 实际 %v", want, types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("推送的事件应为 %v, 实际 %v", want, types)
+		}
+	}
+
+	// 平仓后推送空持仓列表
+	record.Positions = nil
+	at.publishCycle(record)
+	<-sub.Events()
+	<-sub.Events()
+	ev := <-sub.Events()
+	if positions, ok := ev.Data.([]logger.PositionSnapshot); ev.Type != stream.EventPositions || !ok || len(positions) != 0 {
+		t.Errorf("平仓后应推送空持仓列表, 实际 %+v", ev)
+	}
+}
@@ -0,0 +1,64 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"strings"
+)
+
+// ParseSymbolList 解析逗号分隔的币种列表（统一为大写 USDT 交易对，忽略空项和重复项）
+func ParseSymbolList(list string) []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		symbol := normalizeSymbol(item)
+		if seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// isBlacklisted 币种是否在黑名单中（交易员黑名单 + 全局黑名单）
+func (at *AutoTrader) isBlacklisted(symbol string) bool {
+	symbol = normalizeSymbol(symbol)
+	for _, blocked := range at.config.BlacklistedSymbols {
+		if blocked == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSymbolBlacklist 拒绝对黑名单币种开仓（不依赖AI是否遵守提示词）
+func (at *AutoTrader) checkSymbolBlacklist(symbol string) error {
+	if !at.isBlacklisted(symbol) {
+		return nil
+	}
+	return fmt.Errorf("⛔ %s 在禁止交易的币种黑名单中，拒绝开仓", symbol)
+}
+
+// filterBlacklistedCoins 从候选币种中移除黑名单币种，避免出现在提示词中
+func (at *AutoTrader) filterBlacklistedCoins(coins []decision.CandidateCoin) []decision.CandidateCoin {
+	if len(at.config.BlacklistedSymbols) == 0 {
+		return coins
+	}
+	kept := make([]decision.CandidateCoin, 0, len(coins))
+	var removed []string
+	for _, coin := range coins {
+		if at.isBlacklisted(coin.Symbol) {
+			removed = append(removed, coin.Symbol)
+			continue
+		}
+		kept = append(kept, coin)
+	}
+	if len(removed) > 0 {
+		at.log().Infof("⛔ [%s] 候选币种中移除黑名单币种: %v", at.name, removed)
+	}
+	return kept
+}
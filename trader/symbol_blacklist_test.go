@@ -0,0 +1,45 @@
+package trader
+
+import (
+	"testing"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+func TestParseSymbolList(t *testing.T) {
+	got := ParseSymbolList(" pepeusdt, ,BTCUSDT,pepe,")
+	if len(got) != 2 || got[0] != "PEPEUSDT" || got[1] != "BTCUSDT" {
+		t.Errorf("期望 [PEPEUSDT BTCUSDT], 实际 %v", got)
+	}
+}
+
+// TestSymbolBlacklist 测试黑名单币种从候选中移除，且AI仍输出时拒绝开仓、不下单
+func TestSymbolBlacklist(t *testing.T) {
+	mock := &MockTrader{}
+	at := &AutoTrader{
+		name:         "blacklist",
+		trader:       mock,
+		tradingCoins: []string{"BTCUSDT", "PEPEUSDT"},
+		config:       AutoTraderConfig{BlacklistedSymbols: ParseSymbolList("PEPEUSDT")},
+	}
+
+	coins, err := at.getCandidateCoins()
+	if err != nil {
+		t.Fatalf("获取候选币种失败: %v", err)
+	}
+	if len(coins) != 1 || coins[0].Symbol != "BTCUSDT" {
+		t.Errorf("黑名单币种应从候选中移除, 实际 %v", coins)
+	}
+
+	for _, action := range []string{"open_long", "open_short"} {
+		record := &logger.DecisionAction{}
+		err := at.executeDecisionWithRecord(&decision.Decision{Symbol: "PEPEUSDT", Action: action, Leverage: 5, PositionSizeUSD: 100}, record)
+		if RejectionCode(err) != RejectBlacklisted {
+			t.Errorf("%s 黑名单币种应被拒绝, 实际错误: %v", action, err)
+		}
+	}
+	if mock.orderCalls != 0 {
+		t.Errorf("黑名单币种不应发送任何订单, 实际 %d 次", mock.orderCalls)
+	}
+}
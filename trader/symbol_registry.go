@@ -0,0 +1,211 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"sort"
+	"sync"
+)
+
+// SymbolPositionRegistry 全实例的币种持仓登记表
+// 记录每个币种当前有哪些交易员持仓，用于限制同一币种同时持仓的交易员数量，避免系统性集中风险
+type SymbolPositionRegistry struct {
+	mu           sync.Mutex
+	maxPerSymbol int                        // 每个币种最多允许的持仓交易员数（0=不限制）
+	limitSource  func() int                 // 上限的读取函数（每次登记开仓前重新读取，nil 表示使用 maxPerSymbol）
+	holders      map[string]map[string]bool // symbol -> traderID 集合
+}
+
+// NewSymbolPositionRegistry 创建币种持仓登记表
+func NewSymbolPositionRegistry(maxPerSymbol int) *SymbolPositionRegistry {
+	return &SymbolPositionRegistry{
+		maxPerSymbol: maxPerSymbol,
+		holders:      make(map[string]map[string]bool),
+	}
+}
+
+// SetLimit 设置每个币种最多允许的持仓交易员数（0=不限制）
+func (r *SymbolPositionRegistry) SetLimit(maxPerSymbol int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if maxPerSymbol < 0 {
+		maxPerSymbol = 0
+	}
+	r.maxPerSymbol = maxPerSymbol
+}
+
+// SetLimitSource 设置上限的读取函数：每次登记开仓前重新读取，修改系统配置后无需重启即可生效
+func (r *SymbolPositionRegistry) SetLimitSource(source func() int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limitSource = source
+}
+
+// refreshLimit 登记开仓前从 limitSource 刷新上限（在锁外读取，避免持锁查询数据库）
+func (r *SymbolPositionRegistry) refreshLimit() {
+	r.mu.Lock()
+	source := r.limitSource
+	r.mu.Unlock()
+	if source != nil {
+		r.SetLimit(source())
+	}
+}
+
+// Limit 获取每个币种最多允许的持仓交易员数
+func (r *SymbolPositionRegistry) Limit() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maxPerSymbol
+}
+
+// Acquire 为交易员登记币种持仓名额
+// 已持有该币种时直接通过（acquired=false）；超过上限时返回错误
+func (r *SymbolPositionRegistry) Acquire(symbol, traderID string) (acquired bool, err error) {
+	r.refreshLimit()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	holders := r.holders[symbol]
+	if holders[traderID] {
+		return false, nil
+	}
+	if r.maxPerSymbol > 0 && len(holders) >= r.maxPerSymbol {
+		return false, fmt.Errorf("🌐 %s 全局持仓交易员数已达上限 (%d/%d)，跳过开仓", symbol, len(holders), r.maxPerSymbol)
+	}
+
+	if holders == nil {
+		holders = make(map[string]bool)
+		r.holders[symbol] = holders
+	}
+	holders[traderID] = true
+	return true, nil
+}
+
+// Release 释放交易员在某币种的持仓名额
+func (r *SymbolPositionRegistry) Release(symbol, traderID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if holders, ok := r.holders[symbol]; ok {
+		delete(holders, traderID)
+		if len(holders) == 0 {
+			delete(r.holders, symbol)
+		}
+	}
+}
+
+// Sync 用交易员的实际持仓覆盖其登记（修正止损/强平等交易所侧平仓造成的偏差）
+func (r *SymbolPositionRegistry) Sync(traderID string, symbols []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeLocked(traderID)
+	for _, symbol := range symbols {
+		if r.holders[symbol] == nil {
+			r.holders[symbol] = make(map[string]bool)
+		}
+		r.holders[symbol][traderID] = true
+	}
+}
+
+// RemoveTrader 移除交易员的全部登记
+func (r *SymbolPositionRegistry) RemoveTrader(traderID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeLocked(traderID)
+}
+
+func (r *SymbolPositionRegistry) removeLocked(traderID string) {
+	for symbol, holders := range r.holders {
+		delete(holders, traderID)
+		if len(holders) == 0 {
+			delete(r.holders, symbol)
+		}
+	}
+}
+
+// Count 获取某币种当前持仓的交易员数
+func (r *SymbolPositionRegistry) Count(symbol string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.holders[symbol])
+}
+
+// HeldByOther 是否有其他交易员登记持有该币种
+func (r *SymbolPositionRegistry) HeldByOther(symbol, traderID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for holder := range r.holders[symbol] {
+		if holder != traderID {
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshot 获取各币种持仓交易员数（symbol -> count）
+func (r *SymbolPositionRegistry) Snapshot() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int, len(r.holders))
+	for symbol, holders := range r.holders {
+		counts[symbol] = len(holders)
+	}
+	return counts
+}
+
+// SetSymbolRegistry 设置全实例币种持仓登记表（由 TraderManager 注入）
+func (at *AutoTrader) SetSymbolRegistry(registry *SymbolPositionRegistry) {
+	at.symbolRegistry = registry
+}
+
+// acquireSymbolSlot 开仓前登记全局币种名额；未注入登记表时不限制
+// 返回是否为本次新登记（开仓失败时需要释放）
+func (at *AutoTrader) acquireSymbolSlot(symbol string) (bool, error) {
+	if at.symbolRegistry == nil {
+		return false, nil
+	}
+	return at.symbolRegistry.Acquire(symbol, at.id)
+}
+
+// releaseSymbolSlot 平仓后释放全局币种名额（同币种另一方向仍有持仓或挂单中的限价开仓单时保留）
+func (at *AutoTrader) releaseSymbolSlot(symbol, closedSide string) {
+	if at.symbolRegistry == nil {
+		return
+	}
+	otherSide := "short"
+	if closedSide == "short" {
+		otherSide = "long"
+	}
+	if _, ok := at.lastPositions[symbol+"_"+otherSide]; ok {
+		return
+	}
+	if at.hasPendingEntry(symbol, otherSide) {
+		return
+	}
+	at.symbolRegistry.Release(symbol, at.id)
+}
+
+// syncSymbolSlots 用当前持仓和挂单中的限价开仓单同步全局币种登记
+func (at *AutoTrader) syncSymbolSlots(positions []decision.PositionInfo) {
+	if at.symbolRegistry == nil {
+		return
+	}
+	seen := make(map[string]bool)
+	symbols := make([]string, 0, len(positions)+len(at.pendingEntries))
+	for _, pos := range positions {
+		if !seen[pos.Symbol] {
+			seen[pos.Symbol] = true
+			symbols = append(symbols, pos.Symbol)
+		}
+	}
+	for _, entry := range at.pendingEntries {
+		if !seen[entry.symbol] {
+			seen[entry.symbol] = true
+			symbols = append(symbols, entry.symbol)
+		}
+	}
+	sort.Strings(symbols)
+	at.symbolRegistry.Sync(at.id, symbols)
+}
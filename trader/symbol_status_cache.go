@@ -0,0 +1,38 @@
+package trader
+
+import (
+	"sync"
+	"time"
+)
+
+// symbolStatusCacheTTL 交易对状态缓存有效期（开仓前检查使用，需尽快发现暂停交易的交易对）
+const symbolStatusCacheTTL = time.Minute
+
+// symbolStatusCache exchangeInfo 中各交易对状态的缓存（symbol -> status），零值可直接使用
+// 一次拉取包含全部交易对，避免每次开仓都请求完整的 exchangeInfo
+type symbolStatusCache struct {
+	mu        sync.RWMutex
+	statuses  map[string]string
+	fetchedAt time.Time
+}
+
+// lookup 从缓存构建交易对交易状态，缓存失效时调用 fetch 拉取全部交易对状态后再构建
+func (c *symbolStatusCache) lookup(symbol string, fetch func() (map[string]string, error)) (*SymbolTradingStatus, error) {
+	c.mu.RLock()
+	statuses := c.statuses
+	fresh := statuses != nil && time.Since(c.fetchedAt) <= symbolStatusCacheTTL
+	c.mu.RUnlock()
+	if fresh {
+		return buildSymbolTradingStatus(symbol, statuses), nil
+	}
+
+	statuses, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.statuses = statuses
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return buildSymbolTradingStatus(symbol, statuses), nil
+}
@@ -0,0 +1,36 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSymbolStatusCache 测试交易对状态缓存：有效期内只拉取一次 exchangeInfo，过期后重新拉取
+func TestSymbolStatusCache(t *testing.T) {
+	var cache symbolStatusCache
+	fetches := 0
+	fetch := func() (map[string]string, error) {
+		fetches++
+		return map[string]string{"BTCUSDT": "TRADING", "LUNAUSDT": "BREAK"}, nil
+	}
+
+	if _, err := cache.lookup("BTCUSDT", func() (map[string]string, error) { return nil, errors.New("网络错误") }); err == nil {
+		t.Fatal("拉取失败时应返回错误")
+	}
+
+	if status, err := cache.lookup("BTCUSDT", fetch); err != nil || !status.Tradeable {
+		t.Errorf("BTCUSDT 应可交易: %+v, %v", status, err)
+	}
+	if status, err := cache.lookup("LUNAUSDT", fetch); err != nil || status.Tradeable || status.Status != "BREAK" {
+		t.Errorf("LUNAUSDT 应暂停交易: %+v, %v", status, err)
+	}
+	if fetches != 1 {
+		t.Errorf("缓存有效期内应只拉取 1 次，实际 %d 次", fetches)
+	}
+
+	cache.fetchedAt = time.Now().Add(-symbolStatusCacheTTL - time.Second)
+	if _, err := cache.lookup("BTCUSDT", fetch); err != nil || fetches != 2 {
+		t.Errorf("缓存过期后应重新拉取，实际拉取 %d 次, err=%v", fetches, err)
+	}
+}
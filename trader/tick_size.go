@@ -0,0 +1,75 @@
+package trader
+
+import (
+	"math"
+)
+
+// tickEpsilon 判断价格是否已对齐 tick 时容忍的浮点误差（以 tick 个数计）
+const tickEpsilon = 1e-9
+
+// priceTickProvider 能提供交易对价格步进值的交易所（未实现的交易所不做止盈止损对齐）
+type priceTickProvider interface {
+	GetPriceTick(symbol string) (float64, error)
+}
+
+// roundToTickSize 将价格/数量四舍五入到tick size/step size的整数倍
+func roundToTickSize(value float64, tickSize float64) float64 {
+	if tickSize <= 0 {
+		return value
+	}
+	// 计算有多少个tick size
+	steps := value / tickSize
+	// 四舍五入到最近的整数
+	roundedSteps := math.Round(steps)
+	// 乘回tick size
+	return roundedSteps * tickSize
+}
+
+// roundToTickDirection 将价格按指定方向取整到tick size的整数倍（up=true 向上取整，否则向下取整）
+// 已经在 tick 上的价格（仅有浮点误差）保持不变
+func roundToTickDirection(value float64, tickSize float64, up bool) float64 {
+	if tickSize <= 0 {
+		return value
+	}
+	steps := value / tickSize
+	if math.Abs(steps-math.Round(steps)) < tickEpsilon {
+		return math.Round(steps) * tickSize
+	}
+	if up {
+		return math.Ceil(steps) * tickSize
+	}
+	return math.Floor(steps) * tickSize
+}
+
+// roundStopToTick 将止损/止盈价对齐到 tick，取整方向朝向当前价（保证不比 AI 给出的价格更宽松）：
+// 多单止损向上、止盈向下；空单止损向下、止盈向上
+func roundStopToTick(price, tickSize float64, positionSide string, isStopLoss bool) float64 {
+	isLong := positionSide == "LONG"
+	up := isLong == isStopLoss
+	return roundToTickDirection(price, tickSize, up)
+}
+
+// alignStopPrice 设置止损/止盈前按交易对的价格步进值对齐价格，获取步进值失败时使用原价格
+func (at *AutoTrader) alignStopPrice(symbol, positionSide string, isStopLoss bool, price float64) float64 {
+	provider, ok := at.trader.(priceTickProvider)
+	if !ok || price <= 0 {
+		return price
+	}
+	tickSize, err := provider.GetPriceTick(symbol)
+	if err != nil || tickSize <= 0 {
+		if err != nil {
+			at.log().Warnf("  ⚠️ 获取 %s 价格步进值失败，止盈止损价格不做对齐: %v", symbol, err)
+		}
+		return price
+	}
+
+	aligned := roundStopToTick(price, tickSize, positionSide, isStopLoss)
+	if aligned != price {
+		label := "止盈"
+		if isStopLoss {
+			label = "止损"
+		}
+		at.log().Infof("  📏 %s %s %s价按 tick %g 对齐: %.8g → %.8g", symbol, positionSide, label, tickSize, price, aligned)
+	}
+	return aligned
+}
@@ -0,0 +1,71 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// tickMockTrader 提供固定价格步进值的模拟交易所
+type tickMockTrader struct {
+	*MockTrader
+	tickSize float64
+	err      error
+}
+
+func (m *tickMockTrader) GetPriceTick(symbol string) (float64, error) {
+	return m.tickSize, m.err
+}
+
+// TestRoundStopToTick 测试止损止盈按粗粒度 tick 朝当前价方向取整
+func TestRoundStopToTick(t *testing.T) {
+	tests := []struct {
+		name         string
+		price        float64
+		tickSize     float64
+		positionSide string
+		isStopLoss   bool
+		want         float64
+	}{
+		{"多单止损向上取整", 95123.37, 10, "LONG", true, 95130},
+		{"多单止盈向下取整", 98765.43, 10, "LONG", false, 98760},
+		{"空单止损向下取整", 95123.37, 10, "SHORT", true, 95120},
+		{"空单止盈向上取整", 91234.56, 10, "SHORT", false, 91240},
+		{"已对齐的价格保持不变", 0.3, 0.1, "LONG", true, 0.3},
+		{"半个tick的粗粒度", 2.26, 0.5, "SHORT", false, 2.5},
+		{"无tick信息时不处理", 123.456, 0, "LONG", true, 123.456},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundStopToTick(tt.price, tt.tickSize, tt.positionSide, tt.isStopLoss)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("roundStopToTick(%v, %v, %s, %v) = %v, want %v",
+					tt.price, tt.tickSize, tt.positionSide, tt.isStopLoss, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAlignStopPrice 测试设置止损止盈前使用交易所的 tick 对齐价格
+func TestAlignStopPrice(t *testing.T) {
+	mock := &tickMockTrader{MockTrader: &MockTrader{}, tickSize: 10}
+	at := &AutoTrader{name: "tick", trader: mock}
+
+	if got := at.alignStopPrice("BTCUSDT", "LONG", true, 95123.37); math.Abs(got-95130) > 1e-9 {
+		t.Errorf("多单止损应向上对齐到 95130，实际 %v", got)
+	}
+	if got := at.alignStopPrice("BTCUSDT", "SHORT", false, 91234.56); math.Abs(got-91240) > 1e-9 {
+		t.Errorf("空单止盈应向上对齐到 91240，实际 %v", got)
+	}
+
+	// 获取 tick 失败或交易所不提供 tick 时保持原价
+	mock.err = fmt.Errorf("exchangeInfo 不可用")
+	if got := at.alignStopPrice("BTCUSDT", "LONG", true, 95123.37); got != 95123.37 {
+		t.Errorf("获取 tick 失败时应使用原价，实际 %v", got)
+	}
+	plain := &AutoTrader{name: "plain", trader: &MockTrader{}}
+	if got := plain.alignStopPrice("BTCUSDT", "LONG", true, 95123.37); got != 95123.37 {
+		t.Errorf("不支持 tick 的交易所应使用原价，实际 %v", got)
+	}
+}
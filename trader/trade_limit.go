@@ -0,0 +1,35 @@
+package trader
+
+import (
+	"fmt"
+)
+
+// checkDailyTradeLimit 检查当日开仓次数是否已达上限（MaxTradesPerDay=0 表示不限制）
+func (at *AutoTrader) checkDailyTradeLimit() error {
+	limit := at.config.MaxTradesPerDay
+	if limit <= 0 {
+		return nil
+	}
+	if at.dailyTradeCount >= limit {
+		return fmt.Errorf("🔢 今日开仓次数已达上限 (%d/%d)，跳过开仓", at.dailyTradeCount, limit)
+	}
+	return nil
+}
+
+// recordDailyTrade 开仓成功后累加当日开仓次数
+func (at *AutoTrader) recordDailyTrade() {
+	at.dailyTradeCount++
+	if limit := at.config.MaxTradesPerDay; limit > 0 && at.dailyTradeCount >= limit {
+		at.log().Infof("🔢 [%s] 今日开仓次数已达上限 (%d/%d)，今日剩余时间只执行平仓和风控", at.name, at.dailyTradeCount, limit)
+	}
+}
+
+// resetDailyTradeCount 每日重置开仓次数
+func (at *AutoTrader) resetDailyTradeCount() {
+	at.dailyTradeCount = 0
+}
+
+// GetDailyTradeCount 获取当日已开仓次数
+func (at *AutoTrader) GetDailyTradeCount() int {
+	return at.dailyTradeCount
+}
@@ -0,0 +1,37 @@
+package trader
+
+import (
+	"nofx/config"
+	"nofx/decision"
+	"time"
+)
+
+// tradeStatsWindow 写入决策上下文的交易统计范围
+const tradeStatsWindow = 30 * 24 * time.Hour
+
+// tradeStatsSummary 基于 trade_history 实际成交统计近30天表现（数据库不支持或查询失败时返回 nil）
+func (at *AutoTrader) tradeStatsSummary() *decision.TradeStatsSummary {
+	db, ok := at.database.(interface {
+		GetTradeStats(traderID string, since time.Time) (*config.TradeStats, error)
+	})
+	if !ok {
+		return nil
+	}
+	stats, err := db.GetTradeStats(at.config.ID, time.Now().Add(-tradeStatsWindow))
+	if err != nil {
+		at.log().Warnf("⚠️  [%s] 统计实际成交表现失败: %v", at.name, err)
+		return nil
+	}
+	return &decision.TradeStatsSummary{
+		Period:               "近30天",
+		Trades:               stats.Trades,
+		Wins:                 stats.Wins,
+		Losses:               stats.Losses,
+		WinRate:              stats.WinRate,
+		RealizedPnL:          stats.RealizedPnL,
+		AvgWin:               stats.AvgWin,
+		AvgLoss:              stats.AvgLoss,
+		ProfitFactor:         stats.ProfitFactor,
+		MaxConsecutiveLosses: stats.MaxConsecutiveLosses,
+	}
+}
@@ -0,0 +1,69 @@
+package trader
+
+import (
+	"encoding/json"
+	"nofx/decision"
+)
+
+// traderExtraState trader_state.state_json 中保存的扩展运行状态
+type traderExtraState struct {
+	DailyTradeCount int                              `json:"daily_trade_count,omitempty"` // 当日已开仓次数
+	DailyAICalls    int                              `json:"daily_ai_calls,omitempty"`    // 当日已调用AI次数（AI调用预算）
+	LastPositions   map[string]decision.PositionInfo `json:"last_positions,omitempty"`    // 上一周期持仓快照（重启后继续检测被动平仓）
+	PeakPnL         map[string]float64               `json:"peak_pnl,omitempty"`          // 持仓最高收益百分比（重启后回撤平仓不重置峰值）
+	TrailingStops   map[string]TrailingStop          `json:"trailing_stops,omitempty"`    // 追踪止损状态（重启后继续从已记录的最优价追踪）
+}
+
+// buildStateJSON 序列化扩展运行状态
+func (at *AutoTrader) buildStateJSON() string {
+	data, err := json.Marshal(traderExtraState{
+		DailyTradeCount: at.dailyTradeCount,
+		DailyAICalls:    int(at.dailyAICallCount.Load()),
+		LastPositions:   at.lastPositions,
+		PeakPnL:         at.GetPeakPnLCache(),
+		TrailingStops:   at.GetTrailingStops(),
+	})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// restoreStateJSON 从 state_json 恢复扩展运行状态
+// 当日计数依赖 lastResetTime：跨天后由 maybeResetDailyMetrics 清零
+// 持仓快照恢复后，停机期间被止损/止盈的持仓会在首个周期被识别为被动平仓并按快照计算盈亏
+// 峰值收益恢复后，已不存在的持仓的峰值会在首个周期构建上下文时清理
+func (at *AutoTrader) restoreStateJSON(stateJSON string) {
+	if stateJSON == "" || stateJSON == "{}" {
+		return
+	}
+
+	var state traderExtraState
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		at.log().Warnf("⚠️ [%s] 解析扩展状态失败，忽略: %v", at.name, err)
+		return
+	}
+	at.dailyTradeCount = state.DailyTradeCount
+	at.dailyAICallCount.Store(int64(state.DailyAICalls))
+	if len(state.LastPositions) > 0 {
+		at.lastPositions = state.LastPositions
+		at.log().Infof("✅ [%s] 恢复持仓快照: %d 个持仓", at.name, len(state.LastPositions))
+	}
+	if len(state.PeakPnL) > 0 {
+		at.peakPnLCacheMutex.Lock()
+		if at.peakPnLCache == nil {
+			at.peakPnLCache = make(map[string]float64)
+		}
+		for key, peak := range state.PeakPnL {
+			at.peakPnLCache[key] = peak
+		}
+		at.peakPnLCacheMutex.Unlock()
+		at.log().Infof("✅ [%s] 恢复峰值收益缓存: %d 个持仓", at.name, len(state.PeakPnL))
+	}
+	for key, trailing := range state.TrailingStops {
+		at.setTrailingStop(key, trailing)
+	}
+	if len(state.TrailingStops) > 0 {
+		at.log().Infof("✅ [%s] 恢复追踪止损: %d 个持仓", at.name, len(state.TrailingStops))
+	}
+}
@@ -0,0 +1,70 @@
+package trader
+
+import (
+	"nofx/decision"
+	"testing"
+)
+
+// TestLastPositionsSurviveRestart 测试持仓快照随状态持久化，重启后仍能识别停机期间的被动平仓
+func TestLastPositionsSurviveRestart(t *testing.T) {
+	at := &AutoTrader{name: "before", lastPositions: make(map[string]decision.PositionInfo)}
+	at.updatePositionSnapshot([]decision.PositionInfo{
+		{Symbol: "BTCUSDT", Side: "long", EntryPrice: 50000, MarkPrice: 51000, Quantity: 0.1, Leverage: 5, StopLoss: 48000, TakeProfit: 51200},
+		{Symbol: "ETHUSDT", Side: "short", EntryPrice: 3000, MarkPrice: 2900, Quantity: 1, Leverage: 5},
+	})
+
+	restored := &AutoTrader{name: "after", lastPositions: make(map[string]decision.PositionInfo)}
+	restored.restoreStateJSON(at.buildStateJSON())
+	if len(restored.lastPositions) != 2 {
+		t.Fatalf("重启后应恢复2个持仓快照, 实际 %d", len(restored.lastPositions))
+	}
+
+	// 停机期间 BTC 多仓被止盈，ETH 空仓仍在
+	closed := restored.detectClosedPositions([]decision.PositionInfo{{Symbol: "ETHUSDT", Side: "short"}})
+	if len(closed) != 1 || closed[0].Symbol != "BTCUSDT" {
+		t.Fatalf("应检测到 BTCUSDT 被动平仓, 实际 %+v", closed)
+	}
+
+	pos := closed[0]
+	if pnl := pos.Quantity * (pos.MarkPrice - pos.EntryPrice); pnl != 100 {
+		t.Errorf("按恢复的快照计算盈亏应为 100, 实际 %.2f", pnl)
+	}
+	if price, reason := restored.inferCloseDetails(pos); reason != "take_profit" || price != 51200 {
+		t.Errorf("平仓原因推断不正确: price=%.2f reason=%s", price, reason)
+	}
+
+	// 旧版本状态（无快照）不影响现有快照
+	restored.restoreStateJSON(`{"daily_trade_count":1}`)
+	if len(restored.lastPositions) != 2 || restored.dailyTradeCount != 1 {
+		t.Errorf("旧格式状态恢复不正确: positions=%d count=%d", len(restored.lastPositions), restored.dailyTradeCount)
+	}
+}
+
+// TestPeakPnLSurvivesRestart 测试峰值收益随状态持久化，重启后回撤平仓不会从当前收益重新计峰
+func TestPeakPnLSurvivesRestart(t *testing.T) {
+	at := &AutoTrader{name: "before", peakPnLCache: make(map[string]float64)}
+	at.observePeakPnL("BTCUSDT", "long", 20)
+	at.observePeakPnL("BTCUSDT", "long", 12)
+	at.observePeakPnL("ETHUSDT", "short", 8)
+
+	restored := &AutoTrader{name: "after", peakPnLCache: make(map[string]float64)}
+	restored.restoreStateJSON(at.buildStateJSON())
+	if peak := restored.GetPeakPnLCache()["BTCUSDT_long"]; peak != 20 {
+		t.Fatalf("重启后 BTC 峰值收益应为 20%%, 实际 %.2f", peak)
+	}
+
+	// 重启后首次检查：收益 11% 相对恢复的峰值 20% 回撤 45%，应触发回撤平仓而不是把 11% 当作新峰值
+	peak := restored.observePeakPnL("BTCUSDT", "long", 11)
+	if peak != 20 {
+		t.Fatalf("重启后峰值被重置: %.2f", peak)
+	}
+	if drawdown := (peak - 11) / peak * 100; drawdown < 40 {
+		t.Errorf("回撤应超过 40%%, 实际 %.2f%%", drawdown)
+	}
+
+	// 停机期间已平仓的持仓，其峰值在首个周期被清理
+	restored.prunePeakPnLCache(map[string]bool{"BTCUSDT_long": true})
+	if _, ok := restored.GetPeakPnLCache()["ETHUSDT_short"]; ok {
+		t.Error("已不存在持仓的峰值应被清理")
+	}
+}
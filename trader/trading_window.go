@@ -0,0 +1,180 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// TradingWindow 交易时间窗口（UTC），窗口外不开新仓
+// nil 表示全天候交易
+type TradingWindow struct {
+	spec     string
+	ranges   [][2]int // 每段的 [开始分钟, 结束分钟)，结束早于开始表示跨零点
+	weekends bool     // 周六、周日是否交易
+}
+
+// ParseTradingWindow 解析交易时间窗口
+// activeHours 格式 "13:00-21:00"，多段用逗号分隔，支持跨零点（如 "22:00-02:00"），为空表示全天
+// 全天且周末也交易时返回 nil（不限制）
+func ParseTradingWindow(activeHours string, weekendTrading bool) (*TradingWindow, error) {
+	activeHours = strings.TrimSpace(activeHours)
+	if activeHours == "" && weekendTrading {
+		return nil, nil
+	}
+
+	w := &TradingWindow{spec: activeHours, weekends: weekendTrading}
+	if activeHours == "" {
+		return w, nil
+	}
+	for _, part := range strings.Split(activeHours, ",") {
+		bounds := strings.Split(strings.TrimSpace(part), "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("交易时间窗口格式错误: %q（应为 HH:MM-HH:MM）", part)
+		}
+		start, err := parseClock(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("交易时间窗口 %q 的开始和结束时间不能相同", part)
+		}
+		w.ranges = append(w.ranges, [2]int{start, end})
+	}
+	return w, nil
+}
+
+// parseClock 解析 HH:MM（允许 24:00 表示当天结束）
+func parseClock(s string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &hour, &minute); err != nil {
+		return 0, fmt.Errorf("时间格式错误: %q（应为 HH:MM）", s)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("时间超出范围: %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// Allows 判断指定时间是否在交易窗口内
+func (w *TradingWindow) Allows(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	t = t.UTC()
+	if !w.weekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return false
+	}
+	if len(w.ranges) == 0 {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, r := range w.ranges {
+		if r[0] < r[1] && minute >= r[0] && minute < r[1] {
+			return true
+		}
+		if r[0] > r[1] && (minute >= r[0] || minute < r[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// String 窗口描述（用于日志和决策记录）
+func (w *TradingWindow) String() string {
+	if w == nil {
+		return "全天"
+	}
+	desc := "UTC " + w.spec
+	if w.spec == "" {
+		desc = "UTC 全天"
+	}
+	if !w.weekends {
+		desc += "，周末休市"
+	}
+	return desc
+}
+
+// outsideTradingWindow 当前是否在交易时间窗口外
+func (at *AutoTrader) outsideTradingWindow() bool {
+	return !at.config.TradingWindow.Allows(time.Now())
+}
+
+// skipOutsideWindow 交易窗口外的周期处理，返回 true 表示本周期已结束
+// 没有持仓时跳过AI调用；启用 FlattenOnWindowClose 时平掉所有持仓；
+// 其余情况继续调用AI，但只执行平仓和调整止盈止损（由 dropOpenDecisions 过滤开仓）
+func (at *AutoTrader) skipOutsideWindow(positions []decision.PositionInfo, record *logger.DecisionRecord) bool {
+	window := at.config.TradingWindow.String()
+
+	if len(positions) > 0 && !at.config.FlattenOnWindowClose {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🕒 outside trading window (%s)：只允许平仓和调整止盈止损", window))
+		return false
+	}
+
+	if len(positions) == 0 {
+		at.log().Infof("🕒 [%s] 不在交易时间窗口内（%s），跳过本周期", at.name, window)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🕒 outside trading window (%s)：跳过AI决策", window))
+	} else {
+		at.log().Infof("🕒 [%s] 交易时间窗口已关闭（%s），平掉全部 %d 个持仓", at.name, window, len(positions))
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🕒 outside trading window (%s)：窗口关闭，平掉所有持仓", window))
+		at.flattenPositions(positions, record)
+	}
+
+	at.updatePositionSnapshot(positions)
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		at.log().Warnf("⚠ 保存决策记录失败: %v", err)
+	}
+	at.saveTraderState()
+	return true
+}
+
+// flattenPositions 市价平掉所有持仓
+func (at *AutoTrader) flattenPositions(positions []decision.PositionInfo, record *logger.DecisionRecord) {
+	for _, pos := range positions {
+		d := decision.Decision{
+			Symbol:    pos.Symbol,
+			Action:    "close_" + pos.Side,
+			Reasoning: "交易时间窗口关闭",
+		}
+		actionRecord := logger.DecisionAction{
+			Action:    d.Action,
+			Symbol:    d.Symbol,
+			Timestamp: time.Now(),
+		}
+		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
+			at.log().Errorf("❌ 窗口关闭平仓失败 (%s %s): %v", d.Symbol, d.Action, err)
+			recordActionError(&actionRecord, err)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+		} else {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+}
+
+// dropOpenDecisions 交易窗口外丢弃开仓决策
+func (at *AutoTrader) dropOpenDecisions(decisions []decision.Decision, record *logger.DecisionRecord) []decision.Decision {
+	return at.dropOpenDecisionsFor(decisions, record, "outside trading window")
+}
+
+// dropOpenDecisionsFor 丢弃开仓决策并在执行日志中注明原因，只保留平仓和调整止盈止损
+func (at *AutoTrader) dropOpenDecisionsFor(decisions []decision.Decision, record *logger.DecisionRecord, reason string) []decision.Decision {
+	kept := make([]decision.Decision, 0, len(decisions))
+	for _, d := range decisions {
+		if d.Action == "open_long" || d.Action == "open_short" {
+			at.log().Infof("⏭ [%s] %s，忽略开仓决策 %s %s", at.name, reason, d.Symbol, d.Action)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏭ %s %s 跳过: %s", d.Symbol, d.Action, reason))
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
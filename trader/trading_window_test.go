@@ -0,0 +1,78 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"testing"
+	"time"
+)
+
+// TestTradingWindowAllows 测试交易时间窗口（含跨零点、多段和周末休市）
+func TestTradingWindowAllows(t *testing.T) {
+	at := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	window, err := ParseTradingWindow("13:00-21:00, 22:00-02:00", false)
+	if err != nil {
+		t.Fatalf("解析交易窗口失败: %v", err)
+	}
+	cases := []struct {
+		time string
+		want bool
+	}{
+		{"2026-10-16T12:59:00Z", false}, // 周五，窗口开始前
+		{"2026-10-16T13:00:00Z", true},
+		{"2026-10-16T20:59:00Z", true},
+		{"2026-10-16T21:00:00Z", false}, // 结束时间不含
+		{"2026-10-16T23:30:00Z", true},  // 跨零点窗口
+		{"2026-10-15T01:30:00Z", true},
+		{"2026-10-17T14:00:00Z", false},      // 周六休市
+		{"2026-10-16T15:00:00+08:00", false}, // 按 UTC 判断（UTC 07:00）
+	}
+	for _, c := range cases {
+		if got := window.Allows(at(c.time)); got != c.want {
+			t.Errorf("%s 是否可交易: 期望 %v, 实际 %v", c.time, c.want, got)
+		}
+	}
+
+	// 全天且周末交易时不限制
+	if w, err := ParseTradingWindow("", true); err != nil || w != nil || !w.Allows(at("2026-10-17T03:00:00Z")) {
+		t.Errorf("未配置窗口时应不限制: %v %v", w, err)
+	}
+	// 只关闭周末
+	weekdays, _ := ParseTradingWindow("", false)
+	if weekdays.Allows(at("2026-10-18T10:00:00Z")) || !weekdays.Allows(at("2026-10-19T10:00:00Z")) {
+		t.Error("仅周末休市时周日不可交易、周一全天可交易")
+	}
+
+	for _, invalid := range []string{"13:00", "25:00-26:00", "10:00-10:00", "9-17"} {
+		if _, err := ParseTradingWindow(invalid, true); err == nil {
+			t.Errorf("无效窗口 %q 应返回错误", invalid)
+		}
+	}
+}
+
+// TestDropOpenDecisionsOutsideWindow 测试交易窗口外只保留平仓和调整止损决策，并在决策记录中说明
+func TestDropOpenDecisionsOutsideWindow(t *testing.T) {
+	at := &AutoTrader{name: "window"}
+	record := &logger.DecisionRecord{}
+
+	kept := at.dropOpenDecisions([]decision.Decision{
+		{Symbol: "BTCUSDT", Action: "open_long"},
+		{Symbol: "ETHUSDT", Action: "close_short"},
+		{Symbol: "SOLUSDT", Action: "update_stop_loss"},
+		{Symbol: "BNBUSDT", Action: "open_short"},
+	}, record)
+
+	if len(kept) != 2 || kept[0].Action != "close_short" || kept[1].Action != "update_stop_loss" {
+		t.Errorf("应只保留平仓和调整止损决策, 实际 %+v", kept)
+	}
+	if len(record.ExecutionLog) != 2 {
+		t.Errorf("被忽略的开仓决策应写入执行日志, 实际 %v", record.ExecutionLog)
+	}
+}
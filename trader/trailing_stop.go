@@ -0,0 +1,262 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// TrailingStop 持仓追踪止损状态
+// 止损线 = 最优价 × (1 ∓ 回撤比例)，只朝有利方向移动
+type TrailingStop struct {
+	DistancePct     float64 `json:"distance_pct"`     // 从最优价回撤多少百分比触发
+	ActivationPrice float64 `json:"activation_price"` // 设置追踪止损时的价格
+	ExtremePrice    float64 `json:"extreme_price"`    // 多单为最高价、空单为最低价
+	StopPrice       float64 `json:"stop_price"`       // 当前止损线
+	Native          bool    `json:"native,omitempty"` // 交易所原生追踪止损单（否则由回撤监控移动普通止损单模拟）
+	// PlacedStopPrice 本地模拟时交易所止损单实际所在的价格；与止损线不一致时回撤监控会重试移动
+	PlacedStopPrice float64 `json:"placed_stop_price,omitempty"`
+}
+
+// trailingStopPlacer 支持原生追踪止损单的交易所（未实现的交易所由 AutoTrader 本地模拟）
+type trailingStopPlacer interface {
+	SetTrailingStop(symbol string, positionSide string, quantity, callbackRatePct float64) error
+}
+
+// trailingStopLevel 按最优价和回撤比例计算止损线
+func trailingStopLevel(side string, extreme, distancePct float64) float64 {
+	if side == "long" {
+		return extreme * (1 - distancePct/100)
+	}
+	return extreme * (1 + distancePct/100)
+}
+
+// moreFavorable 价格 a 是否比 b 对持仓更有利（多单更高、空单更低）
+func moreFavorable(side string, a, b float64) bool {
+	if side == "long" {
+		return a > b
+	}
+	return a < b
+}
+
+// executeSetTrailingStopWithRecord 为持仓设置追踪止损
+// 交易所支持时下原生追踪止损单，否则先按当前止损线挂普通止损单，由回撤监控随价格移动
+func (at *AutoTrader) executeSetTrailingStopWithRecord(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	at.log().Infof("  🪢 设置追踪止损: %s 回撤 %.2f%%", d.Symbol, d.TrailingDistancePct)
+
+	if d.TrailingDistancePct < decision.MinTrailingDistancePct || d.TrailingDistancePct > decision.MaxTrailingDistancePct {
+		return fmt.Errorf("追踪止损回撤比例必须在 %.1f%%-%.0f%% 之间: %.2f%%",
+			decision.MinTrailingDistancePct, decision.MaxTrailingDistancePct, d.TrailingDistancePct)
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return fmt.Errorf("获取持仓失败: %w", err)
+	}
+	var side string
+	var quantity float64
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		posAmt, _ := pos["positionAmt"].(float64)
+		if symbol != d.Symbol || posAmt == 0 {
+			continue
+		}
+		// 决策不带方向，且移动止损单会撤销两个方向的止损，双向持仓时无法只作用于其中一个方向
+		if side != "" {
+			return fmt.Errorf("%s 存在双向持仓，无法确定追踪止损的方向", d.Symbol)
+		}
+		side, _ = pos["side"].(string)
+		side = strings.ToLower(side)
+		quantity = math.Abs(posAmt)
+	}
+	if side == "" {
+		return fmt.Errorf("持仓不存在: %s", d.Symbol)
+	}
+
+	marketData, err := market.Get(d.Symbol, at.timeframes)
+	if err != nil {
+		return err
+	}
+	price := marketData.CurrentPrice
+	actionRecord.Price = price
+
+	// 重复设置时保留已记录的最优价，避免价格回落后重新设置导致止损线后退
+	posKey := d.Symbol + "_" + side
+	state := TrailingStop{DistancePct: d.TrailingDistancePct, ActivationPrice: price, ExtremePrice: price}
+	if existing, ok := at.getTrailingStop(posKey); ok {
+		if existing.DistancePct == d.TrailingDistancePct {
+			at.log().Infof("  ⏭ %s 追踪止损未变化（回撤 %.2f%%，止损线 %.4f），跳过重复设置", d.Symbol, existing.DistancePct, existing.StopPrice)
+			actionRecord.Skipped = true
+			return nil
+		}
+		state.ActivationPrice = existing.ActivationPrice
+		if moreFavorable(side, existing.ExtremePrice, price) {
+			state.ExtremePrice = existing.ExtremePrice
+		}
+	}
+	state.StopPrice = at.alignStopPrice(d.Symbol, strings.ToUpper(side), true, trailingStopLevel(side, state.ExtremePrice, state.DistancePct))
+
+	if at.dryRunSkip(actionRecord, "设置追踪止损 %s %s 回撤 %.2f%% 止损线 %.4f", d.Symbol, side, state.DistancePct, state.StopPrice) {
+		return nil
+	}
+
+	if placer, ok := at.trader.(trailingStopPlacer); ok {
+		if err := at.trader.CancelStopLossOrders(d.Symbol); err != nil {
+			return fmt.Errorf("取消旧止损单失败，中止设置追踪止损: %w", err)
+		}
+		delete(at.positionStopLoss, posKey)
+		if err := placer.SetTrailingStop(d.Symbol, strings.ToUpper(side), quantity, state.DistancePct); err != nil {
+			at.log().Warnf("  ⚠️ 交易所追踪止损单设置失败，改为本地模拟: %v", err)
+		} else {
+			state.Native = true
+			at.positionStopLoss[posKey] = state.StopPrice
+		}
+	}
+	if !state.Native {
+		if err := at.moveTrailingStop(d.Symbol, state.StopPrice); err != nil {
+			return err
+		}
+		state.PlacedStopPrice = state.StopPrice
+	}
+
+	at.setTrailingStop(posKey, state)
+	at.log().Infof("  ✓ 追踪止损已设置: %s %s 回撤 %.2f%% | 最优价 %.4f | 止损线 %.4f (原生: %v)",
+		d.Symbol, side, state.DistancePct, state.ExtremePrice, state.StopPrice, state.Native)
+	return nil
+}
+
+// moveTrailingStop 本地模拟追踪止损：把普通止损单移动到新的止损线（复用调整止损的校验、去重和止盈恢复逻辑）
+func (at *AutoTrader) moveTrailingStop(symbol string, stopPrice float64) error {
+	return at.executeUpdateStopLossWithRecord(&decision.Decision{
+		Symbol:      symbol,
+		Action:      "update_stop_loss",
+		NewStopLoss: stopPrice,
+		Reasoning:   "追踪止损",
+	}, &logger.DecisionAction{Action: "update_stop_loss", Symbol: symbol, Timestamp: time.Now()})
+}
+
+// ratchetTrailingStop 由回撤监控调用：价格创出有利新极值时上移（空单下移）止损线
+// 本地模拟的追踪止损会同步移动普通止损单，上次未能移动（周期锁被占用或下单失败）的在之后的检查中重试；
+// 价格已越过止损线（止损单未成交或被撤销）时兜底平仓
+// 返回 true 表示持仓已被平仓
+func (at *AutoTrader) ratchetTrailingStop(symbol, side string, markPrice float64) bool {
+	posKey := symbol + "_" + side
+
+	at.trailingStopsMutex.Lock()
+	state, ok := at.trailingStops[posKey]
+	if !ok || markPrice <= 0 {
+		at.trailingStopsMutex.Unlock()
+		return false
+	}
+	improved := moreFavorable(side, markPrice, state.ExtremePrice)
+	if improved {
+		state.ExtremePrice = markPrice
+		state.StopPrice = trailingStopLevel(side, markPrice, state.DistancePct)
+		at.trailingStops[posKey] = state
+	}
+	triggered := !state.Native && !moreFavorable(side, markPrice, state.StopPrice)
+	at.trailingStopsMutex.Unlock()
+
+	if triggered {
+		at.log().Infof("🪢 追踪止损触发: %s %s | 当前价 %.4f 越过止损线 %.4f（最优价 %.4f，回撤 %.2f%%）",
+			symbol, side, markPrice, state.StopPrice, state.ExtremePrice, state.DistancePct)
+		if err := at.emergencyClosePosition(symbol, side); err != nil {
+			at.log().Errorf("❌ 追踪止损平仓失败 (%s %s): %v", symbol, side, err)
+			return false
+		}
+		at.clearTrailingStop(symbol, side)
+		at.ClearPeakPnLCache(symbol, side)
+		return true
+	}
+	if state.Native {
+		return false
+	}
+	stopPrice := at.alignStopPrice(symbol, strings.ToUpper(side), true, state.StopPrice)
+	if stopPrice == state.PlacedStopPrice {
+		return false
+	}
+
+	// 决策周期执行中时留到下一次检查，避免与周期并发修改止损单
+	if !at.cycleMutex.TryLock() {
+		return false
+	}
+	defer at.cycleMutex.Unlock()
+
+	at.log().Infof("🪢 追踪止损上移: %s %s | 最优价 %.4f → 止损线 %.4f", symbol, side, state.ExtremePrice, stopPrice)
+	if err := at.moveTrailingStop(symbol, stopPrice); err != nil {
+		at.log().Errorf("❌ 移动追踪止损失败 (%s %s): %v", symbol, side, err)
+		return false
+	}
+	at.markTrailingStopPlaced(posKey, stopPrice)
+	return false
+}
+
+// getTrailingStop 获取持仓的追踪止损状态
+func (at *AutoTrader) getTrailingStop(posKey string) (TrailingStop, bool) {
+	at.trailingStopsMutex.RLock()
+	defer at.trailingStopsMutex.RUnlock()
+	state, ok := at.trailingStops[posKey]
+	return state, ok
+}
+
+// setTrailingStop 保存持仓的追踪止损状态
+func (at *AutoTrader) setTrailingStop(posKey string, state TrailingStop) {
+	at.trailingStopsMutex.Lock()
+	defer at.trailingStopsMutex.Unlock()
+	if at.trailingStops == nil {
+		at.trailingStops = make(map[string]TrailingStop)
+	}
+	at.trailingStops[posKey] = state
+}
+
+// stopLossSide 调整止损后按内存记录找出被调整的持仓方向（止损价已对齐；多单止损低于现价、空单高于现价，两个方向不会相同）
+func (at *AutoTrader) stopLossSide(symbol string, stopPrice float64) string {
+	if at.positionStopLoss[symbol+"_short"] == stopPrice {
+		return "short"
+	}
+	return "long"
+}
+
+// markTrailingStopPlaced 记录本地模拟追踪止损在交易所的止损单价格（状态已清除时忽略）
+func (at *AutoTrader) markTrailingStopPlaced(posKey string, stopPrice float64) {
+	at.trailingStopsMutex.Lock()
+	defer at.trailingStopsMutex.Unlock()
+	if state, ok := at.trailingStops[posKey]; ok {
+		state.PlacedStopPrice = stopPrice
+		at.trailingStops[posKey] = state
+	}
+}
+
+// clearTrailingStop 清除币种指定方向的追踪止损状态
+func (at *AutoTrader) clearTrailingStop(symbol, side string) {
+	at.trailingStopsMutex.Lock()
+	defer at.trailingStopsMutex.Unlock()
+	delete(at.trailingStops, symbol+"_"+strings.ToLower(side))
+}
+
+// pruneTrailingStops 清理已不存在持仓的追踪止损状态
+func (at *AutoTrader) pruneTrailingStops(activeKeys map[string]bool) {
+	at.trailingStopsMutex.Lock()
+	defer at.trailingStopsMutex.Unlock()
+	for key := range at.trailingStops {
+		if !activeKeys[key] {
+			delete(at.trailingStops, key)
+		}
+	}
+}
+
+// GetTrailingStops 获取所有追踪止损状态的副本
+func (at *AutoTrader) GetTrailingStops() map[string]TrailingStop {
+	at.trailingStopsMutex.RLock()
+	defer at.trailingStopsMutex.RUnlock()
+
+	stops := make(map[string]TrailingStop, len(at.trailingStops))
+	for k, v := range at.trailingStops {
+		stops[k] = v
+	}
+	return stops
+}
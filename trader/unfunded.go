@@ -0,0 +1,52 @@
+package trader
+
+import (
+	"fmt"
+)
+
+// defaultUnfundedThreshold 可用余额低于该值（USDT）且无持仓时视为账户未入金
+const defaultUnfundedThreshold = 1.0
+
+// unfundedThreshold 生效的未入金判定阈值（配置为0时使用默认值）
+func (at *AutoTrader) unfundedThreshold() float64 {
+	if at.config.UnfundedThreshold > 0 {
+		return at.config.UnfundedThreshold
+	}
+	return defaultUnfundedThreshold
+}
+
+// checkFunding 根据本周期的可用余额和持仓数更新未入金状态，返回当前是否未入金
+// 有持仓时可用余额低只是保证金占满，不算未入金；状态变化时才打印日志，避免每周期刷屏
+func (at *AutoTrader) checkFunding(availableBalance float64, positionCount int) bool {
+	threshold := at.unfundedThreshold()
+	unfunded := positionCount == 0 && availableBalance <= threshold
+
+	at.tradingStatusMutex.Lock()
+	changed := unfunded != at.unfunded
+	at.unfunded = unfunded
+	at.tradingStatusMutex.Unlock()
+
+	if changed {
+		if unfunded {
+			at.log().Infof("💸 [%s] 账户未入金（可用余额 %.2f ≤ %.2f USDT），暂停开仓，资金到账后自动恢复", at.name, availableBalance, threshold)
+		} else {
+			at.log().Infof("💰 [%s] 检测到资金到账（可用余额 %.2f USDT），恢复交易", at.name, availableBalance)
+		}
+	}
+	return unfunded
+}
+
+// IsUnfunded 账户是否处于未入金状态（最近一次周期检查的结果）
+func (at *AutoTrader) IsUnfunded() bool {
+	at.tradingStatusMutex.RLock()
+	defer at.tradingStatusMutex.RUnlock()
+	return at.unfunded
+}
+
+// checkFundedForOpen 未入金时拒绝开仓（组合模式下组长代成员执行时同样生效）
+func (at *AutoTrader) checkFundedForOpen() error {
+	if at.IsUnfunded() {
+		return fmt.Errorf("❌ 账户未入金（可用余额不超过 %.2f USDT），暂停开仓直到资金到账", at.unfundedThreshold())
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package trader
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	userTradesPageLimit  = 1000               // Binance/Aster 单页最多返回条数
+	userTradesMaxWindow  = 7 * 24 * time.Hour // Binance/Aster 单次查询的最大时间跨度
+	userTradesMaxResults = 5000               // 单次对账最多返回的成交数，防止大范围查询拖垮交易所限频
+	exchangeFillsTTL     = 30 * time.Second   // 成交记录缓存时间
+)
+
+// collectUserTrades 按时间窗口分段并翻页拉取成交记录
+// 每页满额时从该页最后一条成交时间继续（同一毫秒的成交按ID去重），结果按时间升序
+func collectUserTrades(startTime, endTime time.Time, pageLimit int, fetch func(startMs, endMs int64) ([]UserTrade, error)) ([]UserTrade, error) {
+	if !startTime.Before(endTime) {
+		return nil, fmt.Errorf("开始时间必须早于结束时间")
+	}
+
+	result := make([]UserTrade, 0)
+	seen := make(map[int64]bool)
+	for windowStart := startTime; windowStart.Before(endTime); {
+		windowEnd := windowStart.Add(userTradesMaxWindow)
+		if windowEnd.After(endTime) {
+			windowEnd = endTime
+		}
+
+		from := windowStart.UnixMilli()
+		for {
+			page, err := fetch(from, windowEnd.UnixMilli())
+			if err != nil {
+				return nil, err
+			}
+			for _, trade := range page {
+				if seen[trade.ID] {
+					continue
+				}
+				seen[trade.ID] = true
+				result = append(result, trade)
+			}
+			if len(result) >= userTradesMaxResults {
+				sortUserTrades(result)
+				return result[:userTradesMaxResults], nil
+			}
+			if len(page) < pageLimit {
+				break
+			}
+			last := page[len(page)-1].Time
+			if last <= from {
+				break // 同一毫秒内成交超过一页，无法继续翻页
+			}
+			from = last
+		}
+		windowStart = windowEnd
+	}
+
+	sortUserTrades(result)
+	return result, nil
+}
+
+func sortUserTrades(trades []UserTrade) {
+	sort.SliceStable(trades, func(i, j int) bool {
+		if trades[i].Time == trades[j].Time {
+			return trades[i].ID < trades[j].ID
+		}
+		return trades[i].Time < trades[j].Time
+	})
+}
+
+// exchangeFillsEntry 成交记录缓存项
+type exchangeFillsEntry struct {
+	trades    []UserTrade
+	fetchedAt time.Time
+}
+
+// exchangeFillsCache 成交记录短时缓存（对账页面频繁刷新时避免触发交易所限频）
+type exchangeFillsCache struct {
+	mu      sync.Mutex
+	entries map[string]exchangeFillsEntry
+}
+
+// GetExchangeFills 获取交易所实际成交记录（用于与 trade_history 对账），相同查询30秒内使用缓存
+func (at *AutoTrader) GetExchangeFills(symbol string, startTime, endTime time.Time) ([]UserTrade, error) {
+	key := fmt.Sprintf("%s|%d|%d", symbol, startTime.Unix(), endTime.Unix())
+
+	at.fillsCache.mu.Lock()
+	if entry, ok := at.fillsCache.entries[key]; ok && time.Since(entry.fetchedAt) < exchangeFillsTTL {
+		at.fillsCache.mu.Unlock()
+		return entry.trades, nil
+	}
+	at.fillsCache.mu.Unlock()
+
+	trades, err := at.trader.GetUserTrades(symbol, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	at.fillsCache.mu.Lock()
+	defer at.fillsCache.mu.Unlock()
+	if at.fillsCache.entries == nil {
+		at.fillsCache.entries = make(map[string]exchangeFillsEntry)
+	}
+	// 清理过期缓存，避免不同查询条件无限累积
+	for k, entry := range at.fillsCache.entries {
+		if time.Since(entry.fetchedAt) >= exchangeFillsTTL {
+			delete(at.fillsCache.entries, k)
+		}
+	}
+	at.fillsCache.entries[key] = exchangeFillsEntry{trades: trades, fetchedAt: time.Now()}
+	return trades, nil
+}
@@ -0,0 +1,77 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCollectUserTradesPagination 测试成交记录按7天窗口分段、满页继续翻页并按ID去重
+func TestCollectUserTradesPagination(t *testing.T) {
+	start := time.UnixMilli(0)
+	end := start.Add(10 * 24 * time.Hour)
+
+	// 模拟交易所：第一个窗口内有3笔成交（每页2条），第二个窗口1笔
+	all := []UserTrade{
+		{ID: 1, Time: 1000},
+		{ID: 2, Time: 2000},
+		{ID: 3, Time: 2000},
+		{ID: 4, Time: start.Add(8 * 24 * time.Hour).UnixMilli()},
+	}
+	var calls int
+	trades, err := collectUserTrades(start, end, 2, func(startMs, endMs int64) ([]UserTrade, error) {
+		calls++
+		if endMs-startMs > userTradesMaxWindow.Milliseconds() {
+			t.Fatalf("单次查询跨度超过7天: %d ms", endMs-startMs)
+		}
+		page := []UserTrade{}
+		for _, trade := range all {
+			if trade.Time >= startMs && trade.Time <= endMs && len(page) < 2 {
+				page = append(page, trade)
+			}
+		}
+		return page, nil
+	})
+	if err != nil {
+		t.Fatalf("拉取成交记录失败: %v", err)
+	}
+
+	if len(trades) != 4 {
+		t.Fatalf("应返回4笔不重复成交, 实际 %d: %+v", len(trades), trades)
+	}
+	for i, trade := range trades {
+		if trade.ID != int64(i+1) {
+			t.Errorf("成交应按时间升序: %+v", trades)
+			break
+		}
+	}
+	if calls < 3 {
+		t.Errorf("应分窗口并翻页查询, 实际调用 %d 次", calls)
+	}
+
+	if _, err := collectUserTrades(end, start, 2, nil); err == nil {
+		t.Error("开始时间晚于结束时间应返回错误")
+	}
+}
+
+// TestGetExchangeFillsCache 测试相同查询在缓存期内不重复请求交易所
+func TestGetExchangeFillsCache(t *testing.T) {
+	mock := &MockTrader{userTrades: []UserTrade{{ID: 1, Symbol: "BTCUSDT", Price: 50000, Quantity: 0.1}}}
+	at := &AutoTrader{name: "fills", trader: mock}
+	from, to := time.Now().Add(-time.Hour), time.Now()
+
+	for i := 0; i < 3; i++ {
+		fills, err := at.GetExchangeFills("BTCUSDT", from, to)
+		if err != nil || len(fills) != 1 {
+			t.Fatalf("获取成交记录失败: fills=%v err=%v", fills, err)
+		}
+	}
+	if mock.userTradesCalls != 1 {
+		t.Errorf("缓存期内应只请求一次交易所, 实际 %d 次", mock.userTradesCalls)
+	}
+
+	// 不同查询条件不共用缓存
+	at.GetExchangeFills("ETHUSDT", from, to)
+	if mock.userTradesCalls != 2 {
+		t.Errorf("不同交易对应重新请求, 实际 %d 次", mock.userTradesCalls)
+	}
+}
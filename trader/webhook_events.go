@@ -0,0 +1,103 @@
+package trader
+
+import (
+	"nofx/config"
+	"nofx/decision"
+	"nofx/notify"
+	"nofx/webhook"
+)
+
+// reasoningExcerptRunes 事件中AI理由摘要的最大字符数
+const reasoningExcerptRunes = 300
+
+// webhookSource 用户 Webhook 配置读取接口（数据库以鸭子类型注入）
+type webhookSource interface {
+	GetUserWebhook(userID string) (*config.UserWebhook, error)
+}
+
+// emitWebhook 异步推送交易事件到用户配置的回调地址，并同步推送到用户配置的 Telegram
+// 读取配置与投递都在独立协程中完成，失败只记录日志，不影响交易流程
+func (at *AutoTrader) emitWebhook(eventType string, data map[string]interface{}) {
+	if at.userID == "" {
+		return
+	}
+
+	event := webhook.NewEvent(eventType, at.id, at.userID, data)
+	event.Data["trader_name"] = at.name
+	at.emitNotification(event)
+
+	db, ok := at.database.(webhookSource)
+	if !ok {
+		return
+	}
+	go func() {
+		hook, err := db.GetUserWebhook(at.userID)
+		if err != nil {
+			at.log().Warnf("⚠️ [%s] 读取Webhook配置失败: %v", at.name, err)
+			return
+		}
+		if hook == nil {
+			return
+		}
+
+		cfg := &webhook.Config{URL: hook.URL, Secret: hook.Secret, Events: hook.Events, Enabled: hook.Enabled}
+		if !cfg.Subscribes(eventType) {
+			return
+		}
+		if err := webhook.DefaultDispatcher.Deliver(cfg, event); err != nil {
+			at.log().Warnf("⚠️ [%s] Webhook 投递失败 [%s %s]: %v", at.name, event.Type, event.ID, err)
+		}
+	}()
+}
+
+// emitOpenEvent 推送开仓事件（reasoning 为AI理由，只保留摘要）
+func (at *AutoTrader) emitOpenEvent(symbol, side string, quantity, price float64, leverage int, stopLoss, takeProfit float64, reasoning string) {
+	at.emitWebhook(webhook.EventOpen, map[string]interface{}{
+		"symbol":      symbol,
+		"side":        side,
+		"quantity":    quantity,
+		"price":       price,
+		"leverage":    leverage,
+		"stop_loss":   stopLoss,
+		"take_profit": takeProfit,
+		"reasoning":   notify.Excerpt(reasoning, reasoningExcerptRunes),
+	})
+}
+
+// emitCloseEvent 推送平仓事件（partial=true 表示部分平仓）
+func (at *AutoTrader) emitCloseEvent(symbol, side string, quantity, entryPrice, exitPrice float64, partial bool, reason string) {
+	data := closeEventData(symbol, side, quantity, entryPrice, exitPrice, partial, notify.Excerpt(reason, reasoningExcerptRunes))
+	if pos, ok := at.lastPositions[symbol+"_"+side]; ok && pos.Leverage > 0 {
+		data["leverage"] = pos.Leverage
+	}
+	at.emitWebhook(webhook.EventClose, data)
+}
+
+// emitPassiveCloseEvent 推送被动平仓事件（止损/止盈/强平等由交易所完成的平仓，reason 为推断的原因）
+func (at *AutoTrader) emitPassiveCloseEvent(pos decision.PositionInfo, exitPrice float64, reason string) {
+	data := closeEventData(pos.Symbol, pos.Side, pos.Quantity, pos.EntryPrice, exitPrice, false, reason)
+	data["leverage"] = pos.Leverage
+	data["passive"] = true
+	at.emitWebhook(webhook.EventClose, data)
+}
+
+// closeEventData 构建平仓事件数据（按开仓价和平仓价估算盈亏）
+func closeEventData(symbol, side string, quantity, entryPrice, exitPrice float64, partial bool, reason string) map[string]interface{} {
+	pnl := 0.0
+	if entryPrice > 0 && quantity > 0 {
+		pnl = (exitPrice - entryPrice) * quantity
+		if side == "short" {
+			pnl = -pnl
+		}
+	}
+	return map[string]interface{}{
+		"symbol":      symbol,
+		"side":        side,
+		"quantity":    quantity,
+		"entry_price": entryPrice,
+		"exit_price":  exitPrice,
+		"pnl":         pnl,
+		"partial":     partial,
+		"reason":      reason,
+	}
+}
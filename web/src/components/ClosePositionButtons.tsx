@@ -0,0 +1,80 @@
+import { useState } from 'react'
+import { api } from '../lib/api'
+import { confirmToast, notify } from '../lib/notify'
+import { t, type Language } from '../i18n/translations'
+import type { Position } from '../types'
+
+interface ClosePositionButtonsProps {
+  traderId: string
+  position: Position
+  language: Language
+  onClosed: () => void
+}
+
+const CLOSE_PERCENTAGES = [25, 50, 100]
+
+// 持仓行内的手动按比例平仓按钮
+export function ClosePositionButtons({
+  traderId,
+  position,
+  language,
+  onClosed,
+}: ClosePositionButtonsProps) {
+  const [closing, setClosing] = useState(false)
+
+  const handleClose = async (percentage: number) => {
+    const confirmed = await confirmToast(
+      t('closePositionConfirm', language, {
+        symbol: position.symbol,
+        side: t(position.side === 'long' ? 'long' : 'short', language),
+        percentage,
+      }),
+      { okText: t('closePosition', language) }
+    )
+    if (!confirmed) return
+
+    setClosing(true)
+    try {
+      const result = await api.closePosition(
+        traderId,
+        position.symbol,
+        position.side,
+        percentage
+      )
+      notify.success(
+        t('positionClosed', language, {
+          symbol: result.symbol,
+          quantity: result.closed_quantity.toFixed(4),
+          price: result.fill_price.toFixed(4),
+          pnl: result.realized_pnl.toFixed(2),
+        })
+      )
+      result.warnings?.forEach((warning) => notify.warning(warning))
+      onClosed()
+    } catch (err) {
+      notify.error(err instanceof Error ? err.message : String(err))
+    } finally {
+      setClosing(false)
+    }
+  }
+
+  return (
+    <div className="flex items-center gap-1 justify-end">
+      {CLOSE_PERCENTAGES.map((percentage) => (
+        <button
+          key={percentage}
+          onClick={() => handleClose(percentage)}
+          disabled={closing}
+          className="px-2 py-1 rounded text-xs font-semibold disabled:opacity-50"
+          style={{
+            background: 'rgba(246, 70, 93, 0.1)',
+            color: '#F6465D',
+          }}
+          title={t('closePosition', language)}
+        >
+          {percentage}%
+        </button>
+      ))}
+    </div>
+  )
+}
@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react'
+import React, { useState, useEffect, useRef } from 'react'
 import { useNavigate } from 'react-router-dom'
 import { useAuth } from '../contexts/AuthContext'
 import { useLanguage } from '../contexts/LanguageContext'
@@ -10,7 +10,7 @@ import { useSystemConfig } from '../hooks/useSystemConfig'
 
 export function LoginPage() {
   const { language } = useLanguage()
-  const { login, loginAdmin, verifyOTP } = useAuth()
+  const { login, loginAdmin, verifyOTP, verifyEmail } = useAuth()
   const navigate = useNavigate()
   const [step, setStep] = useState<'login' | 'otp'>('login')
   const [email, setEmail] = useState('')
@@ -27,6 +27,23 @@ export function LoginPage() {
   const [expiredToastId, setExpiredToastId] = useState<string | number | null>(
     null
   )
+  const verifyEmailHandled = useRef(false)
+
+  // 邮箱验证链接：/login?verify_email=<token>（令牌一次性，只提交一次）
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.search)
+    const verifyToken = params.get('verify_email')
+    if (!verifyToken || verifyEmailHandled.current) return
+    verifyEmailHandled.current = true
+    window.history.replaceState({}, '', window.location.pathname)
+    verifyEmail(verifyToken).then((result) => {
+      if (result.success) {
+        toast.success(result.message || '邮箱验证成功，请登录')
+      } else {
+        toast.error(result.message || '邮箱验证失败')
+      }
+    })
+  }, [verifyEmail])
 
   // Show notification if user was redirected here due to 401
   useEffect(() => {
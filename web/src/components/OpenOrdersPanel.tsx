@@ -0,0 +1,151 @@
+import { useState } from 'react'
+import useSWR from 'swr'
+import { ListOrdered, X } from 'lucide-react'
+import { api } from '../lib/api'
+import { confirmToast, notify } from '../lib/notify'
+import { t, type Language } from '../i18n/translations'
+import type { OpenOrder } from '../types'
+
+interface OpenOrdersPanelProps {
+  traderId: string
+  language: Language
+}
+
+// 交易所未成交挂单（限价单、止损止盈单），支持手动撤单
+export function OpenOrdersPanel({ traderId, language }: OpenOrdersPanelProps) {
+  const [cancelling, setCancelling] = useState<number | null>(null)
+  const { data: orders, mutate } = useSWR<OpenOrder[]>(
+    `orders-${traderId}`,
+    () => api.getOpenOrders(traderId),
+    {
+      refreshInterval: 15000,
+      revalidateOnFocus: false,
+      dedupingInterval: 10000,
+    }
+  )
+
+  const handleCancel = async (order: OpenOrder) => {
+    const confirmed = await confirmToast(
+      t('cancelOrderConfirm', language, {
+        symbol: order.symbol,
+        type: order.type,
+        orderId: order.order_id,
+      }),
+      { okText: t('cancelOrder', language) }
+    )
+    if (!confirmed) return
+
+    setCancelling(order.order_id)
+    try {
+      await api.cancelOpenOrder(traderId, order.symbol, order.order_id)
+      notify.success(t('orderCancelled', language))
+      await mutate()
+    } catch (err) {
+      notify.error(err instanceof Error ? err.message : String(err))
+    } finally {
+      setCancelling(null)
+    }
+  }
+
+  return (
+    <div className="binance-card p-6 animate-slide-in">
+      <div className="flex items-center justify-between mb-5">
+        <h2
+          className="text-xl font-bold flex items-center gap-2"
+          style={{ color: '#EAECEF' }}
+        >
+          <ListOrdered className="w-5 h-5" style={{ color: '#F0B90B' }} />
+          {t('openOrders', language)}
+        </h2>
+      </div>
+      {orders && orders.length > 0 ? (
+        <div className="overflow-x-auto">
+          <table className="w-full text-sm">
+            <thead className="text-left border-b border-gray-800">
+              <tr>
+                <th className="pb-3 font-semibold text-gray-400">
+                  {t('symbol', language)}
+                </th>
+                <th className="pb-3 font-semibold text-gray-400">
+                  {t('side', language)}
+                </th>
+                <th className="pb-3 font-semibold text-gray-400">
+                  {t('orderType', language)}
+                </th>
+                <th className="pb-3 font-semibold text-gray-400">
+                  {t('orderPrice', language)}
+                </th>
+                <th className="pb-3 font-semibold text-gray-400">
+                  {t('quantity', language)}
+                </th>
+                <th className="pb-3 font-semibold text-gray-400">
+                  {t('orderCreatedAt', language)}
+                </th>
+                <th className="pb-3" />
+              </tr>
+            </thead>
+            <tbody>
+              {orders.map((order) => (
+                <tr
+                  key={order.order_id}
+                  className="border-b border-gray-800 last:border-0"
+                >
+                  <td className="py-3 font-mono font-semibold">
+                    {order.symbol}
+                  </td>
+                  <td
+                    className="py-3 text-xs font-bold"
+                    style={{
+                      color: order.side === 'BUY' ? '#0ECB81' : '#F6465D',
+                    }}
+                  >
+                    {order.side}
+                  </td>
+                  <td className="py-3 text-xs" style={{ color: '#EAECEF' }}>
+                    {order.type}
+                    {order.reduce_only && (
+                      <span className="ml-2" style={{ color: '#848E9C' }}>
+                        {t('reduceOnly', language)}
+                      </span>
+                    )}
+                  </td>
+                  <td className="py-3 font-mono" style={{ color: '#EAECEF' }}>
+                    {order.stop_price > 0
+                      ? `${t('triggerPrice', language)} ${order.stop_price.toFixed(4)}`
+                      : order.price.toFixed(4)}
+                  </td>
+                  <td className="py-3 font-mono" style={{ color: '#EAECEF' }}>
+                    {order.quantity.toFixed(4)}
+                  </td>
+                  <td className="py-3 text-xs" style={{ color: '#848E9C' }}>
+                    {order.created_at
+                      ? new Date(order.created_at).toLocaleString()
+                      : '-'}
+                  </td>
+                  <td className="py-3 text-right">
+                    <button
+                      onClick={() => handleCancel(order)}
+                      disabled={cancelling === order.order_id}
+                      className="px-2 py-1 rounded text-xs font-semibold flex items-center gap-1 ml-auto disabled:opacity-50"
+                      style={{
+                        background: 'rgba(246, 70, 93, 0.1)',
+                        color: '#F6465D',
+                      }}
+                    >
+                      <X className="w-3 h-3" />
+                      {t('cancelOrder', language)}
+                    </button>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      ) : (
+        <div className="text-center py-8 text-sm" style={{ color: '#848E9C' }}>
+          {t('noOpenOrders', language)}
+        </div>
+      )}
+    </div>
+  )
+}
@@ -0,0 +1,88 @@
+import { useState } from 'react'
+import { Lock, StickyNote, Unlock } from 'lucide-react'
+import { api } from '../lib/api'
+import { notify } from '../lib/notify'
+import { t, type Language } from '../i18n/translations'
+import type { Position } from '../types'
+
+interface PositionAnnotationControlProps {
+  traderId: string
+  position: Position
+  language: Language
+  onChanged: () => void
+}
+
+// 持仓行内的备注编辑和锁定开关（锁定后AI的平仓决策会被拒绝）
+export function PositionAnnotationControl({
+  traderId,
+  position,
+  language,
+  onChanged,
+}: PositionAnnotationControlProps) {
+  const [editing, setEditing] = useState(false)
+  const [note, setNote] = useState(position.note ?? '')
+  const [saving, setSaving] = useState(false)
+  const locked = position.locked ?? false
+
+  const save = async (nextNote: string, nextLocked: boolean) => {
+    setSaving(true)
+    try {
+      await api.annotatePosition(
+        traderId,
+        position.symbol,
+        position.side,
+        nextNote.trim(),
+        nextLocked
+      )
+      setEditing(false)
+      onChanged()
+    } catch (err) {
+      notify.error(err instanceof Error ? err.message : String(err))
+    } finally {
+      setSaving(false)
+    }
+  }
+
+  return (
+    <div className="flex items-center gap-1 justify-end">
+      {editing && (
+        <input
+          autoFocus
+          value={note}
+          maxLength={200}
+          disabled={saving}
+          placeholder={t('positionNotePlaceholder', language)}
+          onChange={(e) => setNote(e.target.value)}
+          onKeyDown={(e) => {
+            if (e.key === 'Enter') save(note, locked)
+            if (e.key === 'Escape') setEditing(false)
+          }}
+          className="px-2 py-1 rounded text-xs w-48"
+          style={{ background: '#0B0E11', border: '1px solid #2B3139' }}
+        />
+      )}
+      <button
+        onClick={() => (editing ? save(note, locked) : setEditing(true))}
+        disabled={saving}
+        className="p-1 rounded disabled:opacity-50"
+        style={{ color: position.note ? '#F0B90B' : '#848E9C' }}
+        title={t('positionNote', language)}
+      >
+        <StickyNote className="w-4 h-4" />
+      </button>
+      <button
+        onClick={() => save(position.note ?? '', !locked)}
+        disabled={saving}
+        className="p-1 rounded disabled:opacity-50"
+        style={{ color: locked ? '#F0B90B' : '#848E9C' }}
+        title={t(locked ? 'unlockPosition' : 'lockPosition', language)}
+      >
+        {locked ? (
+          <Lock className="w-4 h-4" />
+        ) : (
+          <Unlock className="w-4 h-4" />
+        )}
+      </button>
+    </div>
+  )
+}
@@ -71,6 +71,9 @@ export function RegisterPage() {
     const result = await register(email, password, betaCode.trim() || undefined)
 
     if (result.success && result.userID) {
+      if (result.emailVerificationSent && result.message) {
+        toast.success(result.message)
+      }
       setUserID(result.userID)
       setOtpSecret(result.otpSecret || '')
       setQrCodeURL(result.qrCodeURL || '')
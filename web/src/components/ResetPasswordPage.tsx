@@ -10,7 +10,13 @@ import { toast } from 'sonner'
 
 export function ResetPasswordPage() {
   const { language } = useLanguage()
-  const { resetPassword } = useAuth()
+  const { resetPassword, requestPasswordReset, resetPasswordWithToken } =
+    useAuth()
+  // 邮件重置链接：/reset-password?token=<token>，无需邮箱和 OTP
+  const [resetToken] = useState(
+    () => new URLSearchParams(window.location.search).get('token') || ''
+  )
+  const [sendingEmail, setSendingEmail] = useState(false)
   const [email, setEmail] = useState('')
   const [newPassword, setNewPassword] = useState('')
   const [confirmPassword, setConfirmPassword] = useState('')
@@ -35,7 +41,9 @@ export function ResetPasswordPage() {
 
     setLoading(true)
 
-    const result = await resetPassword(email, newPassword, otpCode)
+    const result = resetToken
+      ? await resetPasswordWithToken(resetToken, newPassword)
+      : await resetPassword(email, newPassword, otpCode)
 
     if (result.success) {
       setSuccess(true)
@@ -54,6 +62,20 @@ export function ResetPasswordPage() {
     setLoading(false)
   }
 
+  const handleSendResetEmail = async () => {
+    setError('')
+    setSendingEmail(true)
+    const result = await requestPasswordReset(email)
+    if (result.success) {
+      toast.success(result.message || '重置邮件已发送')
+    } else {
+      const msg = result.message || t('resetPasswordFailed', language)
+      setError(msg)
+      toast.error(msg)
+    }
+    setSendingEmail(false)
+  }
+
   return (
     <div className="min-h-screen" style={{ background: '#0B0E11' }}>
       <Header simple />
@@ -88,7 +110,9 @@ export function ResetPasswordPage() {
               {t('resetPasswordTitle', language)}
             </h1>
             <p className="text-sm mt-2" style={{ color: '#848E9C' }}>
-              使用邮箱和 Google Authenticator 重置密码
+              {resetToken
+                ? '请设置新密码'
+                : '使用邮箱和 Google Authenticator 重置密码'}
             </p>
           </div>
 
@@ -112,21 +136,34 @@ export function ResetPasswordPage() {
               </div>
             ) : (
               <form onSubmit={handleResetPassword} className="space-y-4">
-                <div>
-                  <label
-                    className="block text-sm font-semibold mb-2"
-                    style={{ color: '#EAECEF' }}
-                  >
-                    {t('email', language)}
-                  </label>
-                  <Input
-                    type="email"
-                    value={email}
-                    onChange={(e) => setEmail(e.target.value)}
-                    placeholder={t('emailPlaceholder', language)}
-                    required
-                  />
-                </div>
+                {!resetToken && (
+                  <div>
+                    <label
+                      className="block text-sm font-semibold mb-2"
+                      style={{ color: '#EAECEF' }}
+                    >
+                      {t('email', language)}
+                    </label>
+                    <Input
+                      type="email"
+                      value={email}
+                      onChange={(e) => setEmail(e.target.value)}
+                      placeholder={t('emailPlaceholder', language)}
+                      required
+                    />
+                    <button
+                      type="button"
+                      onClick={handleSendResetEmail}
+                      disabled={sendingEmail || !email}
+                      className="mt-2 text-xs hover:underline disabled:opacity-50"
+                      style={{ color: '#F0B90B' }}
+                    >
+                      {sendingEmail
+                        ? t('loading', language)
+                        : '没有 Authenticator？发送重置链接到邮箱'}
+                    </button>
+                  </div>
+                )}
 
                 <div>
                   <label
@@ -230,36 +267,40 @@ export function ResetPasswordPage() {
                   />
                 </div>
 
-                <div>
-                  <label
-                    className="block text-sm font-semibold mb-2"
-                    style={{ color: '#EAECEF' }}
-                  >
-                    {t('otpCode', language)}
-                  </label>
-                  <div className="text-center mb-3">
-                    <div className="text-3xl">📱</div>
-                    <p className="text-xs mt-1" style={{ color: '#848E9C' }}>
-                      打开 Google Authenticator 获取6位验证码
-                    </p>
+                {!resetToken && (
+                  <div>
+                    <label
+                      className="block text-sm font-semibold mb-2"
+                      style={{ color: '#EAECEF' }}
+                    >
+                      {t('otpCode', language)}
+                    </label>
+                    <div className="text-center mb-3">
+                      <div className="text-3xl">📱</div>
+                      <p className="text-xs mt-1" style={{ color: '#848E9C' }}>
+                        打开 Google Authenticator 获取6位验证码
+                      </p>
+                    </div>
+                    <input
+                      type="text"
+                      value={otpCode}
+                      onChange={(e) =>
+                        setOtpCode(
+                          e.target.value.replace(/\D/g, '').slice(0, 6)
+                        )
+                      }
+                      className="w-full px-3 py-2 rounded text-center text-2xl font-mono"
+                      style={{
+                        background: '#0B0E11',
+                        border: '1px solid #2B3139',
+                        color: '#EAECEF',
+                      }}
+                      placeholder={t('otpPlaceholder', language)}
+                      maxLength={6}
+                      required
+                    />
                   </div>
-                  <input
-                    type="text"
-                    value={otpCode}
-                    onChange={(e) =>
-                      setOtpCode(e.target.value.replace(/\D/g, '').slice(0, 6))
-                    }
-                    className="w-full px-3 py-2 rounded text-center text-2xl font-mono"
-                    style={{
-                      background: '#0B0E11',
-                      border: '1px solid #2B3139',
-                      color: '#EAECEF',
-                    }}
-                    placeholder={t('otpPlaceholder', language)}
-                    maxLength={6}
-                    required
-                  />
-                </div>
+                )}
 
                 {error && (
                   <div
@@ -275,7 +316,11 @@ export function ResetPasswordPage() {
 
                 <button
                   type="submit"
-                  disabled={loading || otpCode.length !== 6 || !passwordValid}
+                  disabled={
+                    loading ||
+                    (!resetToken && otpCode.length !== 6) ||
+                    !passwordValid
+                  }
                   className="w-full px-4 py-2 rounded text-sm font-semibold transition-all hover:scale-105 disabled:opacity-50"
                   style={{ background: '#F0B90B', color: '#000' }}
                 >
@@ -115,6 +115,29 @@ export function TradersGrid({
                   ? t('running', language)
                   : t('stopped', language)}
               </div>
+              {/* 决策周期健康状态：连续失败时显示原因，悬停查看最后一次错误 */}
+              {trader.health && trader.health !== 'ok' && (
+                <div
+                  className="mt-1 px-2 py-0.5 rounded text-xs font-semibold max-w-[200px] truncate"
+                  title={trader.auto_pause_reason || trader.last_error}
+                  style={
+                    trader.health === 'failing'
+                      ? {
+                          background: 'rgba(246, 70, 93, 0.1)',
+                          color: '#F6465D',
+                        }
+                      : {
+                          background: 'rgba(240, 185, 11, 0.1)',
+                          color: '#F0B90B',
+                        }
+                  }
+                >
+                  {trader.health === 'failing'
+                    ? t('healthFailing', language)
+                    : t('healthDegraded', language)}
+                  {trader.health_reason ? `: ${trader.health_reason}` : ''}
+                </div>
+              )}
             </div>
 
             {/* Actions: 禁止换行,超出横向滚动 */}
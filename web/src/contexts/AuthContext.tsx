@@ -33,6 +33,7 @@ interface AuthContextType {
     userID?: string
     otpSecret?: string
     qrCodeURL?: string
+    emailVerificationSent?: boolean
   }>
   verifyOTP: (
     userID: string,
@@ -47,6 +48,16 @@ interface AuthContextType {
     newPassword: string,
     otpCode: string
   ) => Promise<{ success: boolean; message?: string }>
+  verifyEmail: (
+    token: string
+  ) => Promise<{ success: boolean; message?: string }>
+  requestPasswordReset: (
+    email: string
+  ) => Promise<{ success: boolean; message?: string }>
+  resetPasswordWithToken: (
+    token: string,
+    newPassword: string
+  ) => Promise<{ success: boolean; message?: string }>
   logout: () => void
   isLoading: boolean
 }
@@ -127,6 +138,25 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
             message: data.message,
           }
         }
+        // 已验证邮箱且未绑定 Authenticator 的账户，密码登录直接返回 token
+        if (data.access_token) {
+          reset401Flag()
+          const userInfo = { id: data.user_id, email: data.email }
+          setToken(data.access_token)
+          setUser(userInfo)
+          localStorage.setItem('auth_token', data.access_token)
+          localStorage.setItem('auth_user', JSON.stringify(userInfo))
+
+          const returnUrl = sessionStorage.getItem('returnUrl')
+          if (returnUrl) {
+            sessionStorage.removeItem('returnUrl')
+            window.history.pushState({}, '', returnUrl)
+          } else {
+            window.history.pushState({}, '', '/traders')
+          }
+          window.dispatchEvent(new PopStateEvent('popstate'))
+          return { success: true, message: data.message }
+        }
       } else {
         return { success: false, message: data.error }
       }
@@ -209,6 +239,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
           userID: data.user_id,
           otpSecret: data.otp_secret,
           qrCodeURL: data.qr_code_url,
+          emailVerificationSent: data.email_verification_sent === true,
           message: data.message,
         }
       } else {
@@ -337,6 +368,47 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     }
   }
 
+  // 邮件链接相关接口（需要后端配置 SMTP）
+  const postEmailFlow = async (
+    path: string,
+    body: Record<string, string>,
+    fallback: string
+  ) => {
+    try {
+      const response = await fetch(path, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify(body),
+      })
+      const data = await response.json()
+      if (response.ok) {
+        return { success: true, message: data.message }
+      }
+      return { success: false, message: data.error }
+    } catch (error) {
+      return { success: false, message: fallback }
+    }
+  }
+
+  const verifyEmail = (token: string) =>
+    postEmailFlow('/api/verify-email', { token }, '邮箱验证失败，请重试')
+
+  const requestPasswordReset = (email: string) =>
+    postEmailFlow(
+      '/api/request-password-reset',
+      { email },
+      '发送重置邮件失败，请重试'
+    )
+
+  const resetPasswordWithToken = (token: string, newPassword: string) =>
+    postEmailFlow(
+      '/api/reset-password-with-token',
+      { token, new_password: newPassword },
+      '密码重置失败，请重试'
+    )
+
   const logout = () => {
     const savedToken = localStorage.getItem('auth_token')
     if (savedToken) {
@@ -364,6 +436,9 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
         verifyOTP,
         completeRegistration,
         resetPassword,
+        verifyEmail,
+        requestPasswordReset,
+        resetPasswordWithToken,
         logout,
         isLoading,
       }}
@@ -11,6 +11,8 @@ export const translations = {
     competition: 'Competition',
     running: 'RUNNING',
     stopped: 'STOPPED',
+    healthDegraded: 'DEGRADED',
+    healthFailing: 'FAILING',
     strategy: 'Strategy',
     adminMode: 'Admin Mode',
     logout: 'Logout',
@@ -52,12 +54,35 @@ export const translations = {
     noPositions: 'No Positions',
     noActivePositions: 'No active trading positions',
 
+    // Open Orders
+    openOrders: 'Open Orders',
+    orderType: 'Type',
+    orderPrice: 'Price',
+    triggerPrice: 'Trigger',
+    reduceOnly: 'Reduce Only',
+    orderCreatedAt: 'Created',
+    cancelOrder: 'Cancel',
+    cancelOrderConfirm:
+      'Cancel {type} order #{orderId} on {symbol}? This takes effect on the exchange immediately.',
+    orderCancelled: 'Order cancelled',
+    noOpenOrders: 'No open orders',
+    closePosition: 'Close',
+    closePositionConfirm:
+      'Close {percentage}% of the {side} position on {symbol}? A market order is sent to the exchange immediately.',
+    positionClosed:
+      'Closed {quantity} {symbol} at {price}, realized PnL {pnl} USDT',
+    positionNote: 'Note',
+    positionNotePlaceholder: 'Note for the AI (empty to clear)',
+    lockPosition: 'Lock: the AI cannot close this position',
+    unlockPosition: 'Unlock: allow the AI to close this position',
+
     // Recent Decisions
     recentDecisions: 'Recent Decisions',
     lastCycles: 'Last {count} trading cycles',
     noDecisionsYet: 'No Decisions Yet',
     aiDecisionsWillAppear: 'AI trading decisions will appear here',
     cycle: 'Cycle',
+    fallbackModelUsed: 'fallback model',
     success: 'Success',
     failed: 'Failed',
     inputPrompt: 'Input Prompt',
@@ -891,6 +916,8 @@ export const translations = {
     competition: '竞赛',
     running: '运行中',
     stopped: '已停止',
+    healthDegraded: '异常',
+    healthFailing: '故障',
     strategy: '策略',
     adminMode: '管理员模式',
     logout: '退出',
@@ -932,12 +959,35 @@ export const translations = {
     noPositions: '无持仓',
     noActivePositions: '当前没有活跃的交易持仓',
 
+    // Open Orders
+    openOrders: '当前挂单',
+    orderType: '类型',
+    orderPrice: '价格',
+    triggerPrice: '触发价',
+    reduceOnly: '只减仓',
+    orderCreatedAt: '下单时间',
+    cancelOrder: '撤单',
+    cancelOrderConfirm:
+      '确定撤销 {symbol} 的 {type} 挂单 #{orderId} 吗？撤单会立即在交易所生效。',
+    orderCancelled: '挂单已撤销',
+    noOpenOrders: '当前没有挂单',
+    closePosition: '平仓',
+    closePositionConfirm:
+      '确定以市价平掉 {symbol} {side} 仓位的 {percentage}% 吗？会立即向交易所下单。',
+    positionClosed:
+      '已平仓 {symbol} {quantity}，成交价 {price}，已实现盈亏 {pnl} USDT',
+    positionNote: '备注',
+    positionNotePlaceholder: '给 AI 的备注（留空清除）',
+    lockPosition: '锁定：AI 不能平掉该持仓',
+    unlockPosition: '解锁：允许 AI 平掉该持仓',
+
     // Recent Decisions
     recentDecisions: '最近决策',
     lastCycles: '最近 {count} 个交易周期',
     noDecisionsYet: '暂无决策',
     aiDecisionsWillAppear: 'AI交易决策将显示在这里',
     cycle: '周期',
+    fallbackModelUsed: '备用模型',
     success: '成功',
     failed: '失败',
     inputPrompt: '输入提示',
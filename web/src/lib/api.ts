@@ -2,6 +2,9 @@ import type {
   SystemStatus,
   AccountInfo,
   Position,
+  OpenOrder,
+  ClosePositionResult,
+  PositionAnnotation,
   DecisionRecord,
   Statistics,
   TraderInfo,
@@ -9,6 +12,8 @@ import type {
   AIModel,
   Exchange,
   CreateTraderRequest,
+  CreateTraderFromTemplateRequest,
+  TraderTemplate,
   UpdateModelConfigRequest,
   UpdateExchangeConfigRequest,
   CompetitionData,
@@ -23,6 +28,8 @@ function getAuthHeaders(): Record<string, string> {
   const token = localStorage.getItem('auth_token')
   const headers: Record<string, string> = {
     'Content-Type': 'application/json',
+    // 后端按界面语言返回本地化的错误信息（与 LanguageContext 的默认值一致）
+    'Accept-Language': localStorage.getItem('language') === 'zh' ? 'zh' : 'en',
   }
 
   if (token) {
@@ -40,9 +47,20 @@ export const api = {
     return res.json()
   },
 
-  // 获取公开的交易员列表（无需认证）
-  async getPublicTraders(): Promise<any[]> {
-    const res = await httpClient.get(`${API_BASE}/traders`)
+  // 获取公开的交易员排行榜（无需认证，支持统计周期、排序和分页）
+  async getPublicTraders(params?: {
+    sort?: 'total_pnl_pct' | 'total_equity' | 'win_rate' | 'running_days'
+    order?: 'asc' | 'desc'
+    period?: '24h' | '7d' | '30d' | 'all'
+    page?: number
+    page_size?: number
+  }): Promise<any> {
+    const query = new URLSearchParams()
+    Object.entries(params || {}).forEach(([key, value]) => {
+      if (value !== undefined) query.set(key, String(value))
+    })
+    const qs = query.toString()
+    const res = await httpClient.get(`${API_BASE}/traders${qs ? `?${qs}` : ''}`)
     if (!res.ok) throw new Error('获取公开trader列表失败')
     return res.json()
   },
@@ -57,6 +75,52 @@ export const api = {
     return res.json()
   },
 
+  // 交易员配置模板
+  async getTraderTemplates(): Promise<TraderTemplate[]> {
+    const res = await httpClient.get(
+      `${API_BASE}/trader-templates`,
+      getAuthHeaders()
+    )
+    if (!res.ok) throw new Error('获取交易员模板失败')
+    const data = await res.json()
+    return data.templates
+  },
+
+  async saveTraderTemplate(
+    traderId: string,
+    name: string,
+    description = ''
+  ): Promise<TraderTemplate> {
+    const res = await httpClient.post(
+      `${API_BASE}/trader-templates`,
+      { trader_id: traderId, name, description },
+      getAuthHeaders()
+    )
+    if (!res.ok) throw new Error('保存交易员模板失败')
+    return res.json()
+  },
+
+  async deleteTraderTemplate(templateId: string): Promise<void> {
+    const res = await httpClient.delete(
+      `${API_BASE}/trader-templates/${templateId}`,
+      getAuthHeaders()
+    )
+    if (!res.ok) throw new Error('删除交易员模板失败')
+  },
+
+  async createTraderFromTemplate(
+    templateId: string,
+    request: CreateTraderFromTemplateRequest
+  ): Promise<TraderInfo> {
+    const res = await httpClient.post(
+      `${API_BASE}/traders/from-template/${templateId}`,
+      request,
+      getAuthHeaders()
+    )
+    if (!res.ok) throw new Error('从模板创建交易员失败')
+    return res.json()
+  },
+
   async deleteTrader(traderId: string): Promise<void> {
     const res = await httpClient.delete(
       `${API_BASE}/traders/${traderId}`,
@@ -289,6 +353,71 @@ export const api = {
     return res.json()
   },
 
+  // 获取交易所未成交挂单（支持trader_id）
+  async getOpenOrders(traderId: string): Promise<OpenOrder[]> {
+    const res = await httpClient.get(
+      `${API_BASE}/orders?trader_id=${traderId}`,
+      getAuthHeaders()
+    )
+    if (!res.ok) throw new Error('获取挂单列表失败')
+    return res.json()
+  },
+
+  // 手动撤销挂单
+  async cancelOpenOrder(
+    traderId: string,
+    symbol: string,
+    orderId: number
+  ): Promise<void> {
+    const res = await httpClient.delete(
+      `${API_BASE}/orders/${orderId}?trader_id=${traderId}&symbol=${encodeURIComponent(symbol)}`,
+      getAuthHeaders()
+    )
+    if (!res.ok) {
+      const data = await res.json().catch(() => ({}))
+      throw new Error(data.error || '撤单失败')
+    }
+  },
+
+  // 手动按比例平仓（percentage 1-100，100 为全部平仓）
+  async closePosition(
+    traderId: string,
+    symbol: string,
+    side: string,
+    percentage: number
+  ): Promise<ClosePositionResult> {
+    const res = await httpClient.post(
+      `${API_BASE}/positions/close`,
+      { trader_id: traderId, symbol, side, percentage },
+      getAuthHeaders()
+    )
+    if (!res.ok) {
+      const data = await res.json().catch(() => ({}))
+      throw new Error(data.error || '平仓失败')
+    }
+    return res.json()
+  },
+
+  // 设置持仓备注和锁定状态（锁定后AI的平仓决策会被拒绝）
+  async annotatePosition(
+    traderId: string,
+    symbol: string,
+    side: string,
+    note: string,
+    locked: boolean
+  ): Promise<PositionAnnotation> {
+    const res = await httpClient.post(
+      `${API_BASE}/positions/annotate`,
+      { trader_id: traderId, symbol, side, note, locked },
+      getAuthHeaders()
+    )
+    if (!res.ok) {
+      const data = await res.json().catch(() => ({}))
+      throw new Error(data.error || '保存持仓备注失败')
+    }
+    return res.json()
+  },
+
   // 获取决策日志（支持trader_id）
   async getDecisions(traderId?: string): Promise<DecisionRecord[]> {
     const url = traderId
@@ -4,6 +4,9 @@ import useSWR from 'swr'
 import { api } from '../lib/api'
 import { EquityChart } from '../components/EquityChart'
 import AILearning from '../components/AILearning'
+import { OpenOrdersPanel } from '../components/OpenOrdersPanel'
+import { ClosePositionButtons } from '../components/ClosePositionButtons'
+import { PositionAnnotationControl } from '../components/PositionAnnotationControl'
 import { useLanguage } from '../contexts/LanguageContext'
 import { useAuth } from '../contexts/AuthContext'
 import { t, type Language } from '../i18n/translations'
@@ -112,7 +115,7 @@ export default function TraderDashboard() {
     }
   )
 
-  const { data: positions } = useSWR<Position[]>(
+  const { data: positions, mutate: mutatePositions } = useSWR<Position[]>(
     user && token && selectedTraderId ? `positions-${selectedTraderId}` : null,
     () => api.getPositions(selectedTraderId),
     {
@@ -485,6 +488,7 @@ export default function TraderDashboard() {
                       <th className="pb-3 font-semibold text-gray-400">
                         {t('liqPrice', language)}
                       </th>
+                      <th className="pb-3" />
                     </tr>
                   </thead>
                   <tbody>
@@ -495,6 +499,15 @@ export default function TraderDashboard() {
                       >
                         <td className="py-3 font-mono font-semibold">
                           {pos.symbol}
+                          {pos.note && (
+                            <div
+                              className="text-xs font-sans font-normal truncate max-w-[12rem]"
+                              style={{ color: '#848E9C' }}
+                              title={pos.note}
+                            >
+                              {pos.note}
+                            </div>
+                          )}
                         </td>
                         <td className="py-3">
                           <span
@@ -566,6 +579,23 @@ export default function TraderDashboard() {
                         >
                           {pos.liquidation_price.toFixed(4)}
                         </td>
+                        <td className="py-3">
+                          <div className="flex items-center gap-2 justify-end">
+                            <PositionAnnotationControl
+                              key={`${pos.symbol}_${pos.side}_${pos.note ?? ''}`}
+                              traderId={selectedTrader.trader_id}
+                              position={pos}
+                              language={language}
+                              onChanged={() => mutatePositions()}
+                            />
+                            <ClosePositionButtons
+                              traderId={selectedTrader.trader_id}
+                              position={pos}
+                              language={language}
+                              onClosed={() => mutatePositions()}
+                            />
+                          </div>
+                        </td>
                       </tr>
                     ))}
                   </tbody>
@@ -585,6 +615,12 @@ export default function TraderDashboard() {
               </div>
             )}
           </div>
+
+          {/* Open Orders */}
+          <OpenOrdersPanel
+            traderId={selectedTrader.trader_id}
+            language={language}
+          />
         </div>
 
         {/* 右侧：Recent Decisions */}
@@ -760,6 +796,16 @@ function DecisionCard({
           <div className="text-xs" style={{ color: '#848E9C' }}>
             {new Date(decision.timestamp).toLocaleString()}
           </div>
+          {decision.model_used && (
+            <div className="text-xs" style={{ color: '#848E9C' }}>
+              {decision.model_used}
+              {decision.fallback_used && (
+                <span className="ml-1" style={{ color: '#F0B90B' }}>
+                  ({t('fallbackModelUsed', language)})
+                </span>
+              )}
+            </div>
+          )}
         </div>
         <div
           className="px-3 py-1 rounded text-xs font-bold"
@@ -866,6 +912,7 @@ function DecisionCard({
                 }
               >
                 {action.action}
+                {action.close_percentage ? ` ${action.close_percentage}%` : ''}
               </span>
               {action.leverage > 0 && (
                 <span style={{ color: '#F0B90B' }}>{action.leverage}x</span>
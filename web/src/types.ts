@@ -38,6 +38,45 @@ export interface Position {
   unrealized_pnl_pct: number
   liquidation_price: number
   margin_used: number
+  note?: string
+  locked?: boolean
+}
+
+export interface OpenOrder {
+  symbol: string
+  order_id: number
+  type: string
+  side: string
+  position_side: string
+  quantity: number
+  price: number
+  stop_price: number
+  reduce_only: boolean
+  created_at: number
+}
+
+export interface ClosePositionResult {
+  symbol: string
+  side: string
+  percentage: number
+  action: string
+  closed_quantity: number
+  remaining_quantity: number
+  fill_price: number
+  realized_pnl: number
+  realized_pnl_pct: number
+  order_id?: number
+  stop_loss?: number
+  take_profit?: number
+  dry_run?: boolean
+  warnings?: string[]
+}
+
+export interface PositionAnnotation {
+  symbol: string
+  side: string
+  note: string
+  locked: boolean
 }
 
 export interface DecisionAction {
@@ -50,6 +89,7 @@ export interface DecisionAction {
   timestamp: string
   success: boolean
   error?: string
+  close_percentage?: number
 }
 
 export interface AccountSnapshot {
@@ -73,6 +113,9 @@ export interface DecisionRecord {
   execution_log: string[]
   success: boolean
   error_message?: string
+  ai_model?: string
+  model_used?: string
+  fallback_used?: boolean
 }
 
 export interface Statistics {
@@ -81,6 +124,7 @@ export interface Statistics {
   failed_cycles: number
   total_open_positions: number
   total_close_positions: number
+  fallback_cycles?: number
 }
 
 // AI Trading相关类型
@@ -107,6 +151,13 @@ export interface TraderInfo {
   limit_price_offset?: number
   limit_timeout_seconds?: number
   timeframes?: string
+  health?: 'ok' | 'degraded' | 'failing' // 决策周期健康状态
+  health_reason?: string // 失败原因摘要（如 invalid API key）
+  consecutive_errors?: number
+  last_error?: string
+  last_error_time?: string
+  auto_paused?: boolean // 连续失败达到阈值后自动暂停
+  auto_pause_reason?: string
 }
 
 export interface AIModel {
@@ -158,6 +209,24 @@ export interface CreateTraderRequest {
   limit_timeout_seconds?: number // 限价单超时时间 (默认 300 = 5分钟)
 }
 
+// 交易员配置模板（config 不含名称、初始余额和交易所/模型绑定）
+export interface TraderTemplate {
+  id: string
+  name: string
+  description: string
+  config: Partial<CreateTraderRequest>
+  is_system: boolean
+  source_trader_id: string
+  created_at: string
+}
+
+export interface CreateTraderFromTemplateRequest {
+  name: string
+  ai_model_id: string
+  exchange_id: string
+  initial_balance?: number // 可选：不填时由后端从交易所查询
+}
+
 export interface UpdateModelConfigRequest {
   models: {
     [key: string]: {
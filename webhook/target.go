@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget 回调地址指向本机、内网、链路本地或未指定地址（防止 SSRF）
+var ErrForbiddenTarget = errors.New("回调地址不能指向本机或内网地址")
+
+// lookupIPAddr 解析回调地址的主机名（测试中可替换）
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// forbiddenIP 是否为不允许投递的地址：回环、私有网段、链路本地（含云元数据 169.254.169.254）、未指定、组播
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// ValidateURL 校验回调地址：必须是 http/https，且主机解析出的所有地址都不能是本机或内网地址
+// 保存时调用；投递时由 safeDialControl 按实际连接的 IP 再校验一次，避免 DNS 重绑定绕过
+func ValidateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("无效的回调地址: %s", raw)
+	}
+
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if forbiddenIP(ip) {
+			return ErrForbiddenTarget
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("无法解析回调地址 %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if forbiddenIP(addr.IP) {
+			return ErrForbiddenTarget
+		}
+	}
+	return nil
+}
+
+// safeDialControl 建立连接前检查实际连接的 IP（覆盖 DNS 重绑定和重定向到内网的情况）
+func safeDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || forbiddenIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+	}
+	return nil
+}
+
+// newSafeClient 默认投递使用的 HTTP 客户端：拒绝连接本机和内网地址
+// 不走环境变量代理，否则连接检查只能看到代理的地址
+func newSafeClient() *http.Client {
+	dialer := &net.Dialer{Timeout: defaultTimeout, Control: safeDialControl}
+	return &http.Client{
+		Timeout: defaultTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: defaultTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
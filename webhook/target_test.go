@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestValidateURL 测试保存回调地址时拒绝本机、内网、链路本地和未指定地址
+func TestValidateURL(t *testing.T) {
+	orig := lookupIPAddr
+	defer func() { lookupIPAddr = orig }()
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "hooks.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		case "rebind.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("10.1.2.3")}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	for _, raw := range []string{"https://hooks.example.com/nofx", "http://93.184.216.34:8080/hook"} {
+		if err := ValidateURL(raw); err != nil {
+			t.Errorf("%s 应允许, 实际 %v", raw, err)
+		}
+	}
+
+	forbidden := []string{
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+		"http://172.16.0.1/hook",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"https://rebind.example.com/hook",
+	}
+	for _, raw := range forbidden {
+		if err := ValidateURL(raw); !errors.Is(err, ErrForbiddenTarget) {
+			t.Errorf("%s 应拒绝, 实际 %v", raw, err)
+		}
+	}
+
+	for _, raw := range []string{"ftp://hooks.example.com", "not a url", "https://unknown.example.com/hook"} {
+		if err := ValidateURL(raw); err == nil || errors.Is(err, ErrForbiddenTarget) {
+			t.Errorf("%s 应返回地址无效, 实际 %v", raw, err)
+		}
+	}
+}
+
+// TestDefaultDispatcherRejectsPrivateTargets 测试投递时按实际连接的 IP 拒绝本机地址（防止 DNS 重绑定）
+func TestDefaultDispatcherRejectsPrivateTargets(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil, 0, time.Millisecond)
+	err := d.Deliver(&Config{URL: server.URL, Secret: "s", Enabled: true}, NewEvent(EventOpen, "t", "u", nil))
+	if !errors.Is(err, ErrForbiddenTarget) {
+		t.Errorf("投递到本机地址应被拒绝, 实际 %v", err)
+	}
+	if called {
+		t.Error("不应连接到本机地址")
+	}
+}
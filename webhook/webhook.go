@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 交易事件类型
+const (
+	EventOpen        = "open"         // 开仓成功
+	EventClose       = "close"        // 平仓成功（含部分平仓）
+	EventRiskStop    = "risk_stop"    // 触发风控暂停
+	EventEquityAlert = "equity_alert" // 净值预警（不暂停交易）
+	EventDailyReport = "daily_report" // 每日绩效报告
+	EventAIBudget    = "ai_budget"    // 当日AI调用预算用完
+)
+
+// AllEvents 支持订阅的全部事件
+var AllEvents = []string{EventOpen, EventClose, EventRiskStop, EventEquityAlert, EventDailyReport, EventAIBudget}
+
+// 请求头
+const (
+	HeaderSignature = "X-NOFX-Signature" // sha256=<hex>
+	HeaderTimestamp = "X-NOFX-Timestamp" // Unix 秒
+	HeaderEvent     = "X-NOFX-Event"
+	HeaderEventID   = "X-NOFX-Event-ID"
+)
+
+// 默认投递配置
+const (
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = 2 * time.Second
+	defaultTimeout        = 10 * time.Second
+)
+
+// Event 推送给用户回调地址的事件
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	TraderID  string                 `json:"trader_id"`
+	UserID    string                 `json:"user_id"`
+	Timestamp int64                  `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Config 用户的 Webhook 配置
+type Config struct {
+	URL     string
+	Secret  string
+	Events  []string // 订阅的事件，为空表示全部
+	Enabled bool
+}
+
+// Subscribes 是否订阅了该事件
+func (c *Config) Subscribes(eventType string) bool {
+	if c == nil || !c.Enabled || c.URL == "" {
+		return false
+	}
+	if len(c.Events) == 0 {
+		return true
+	}
+	for _, e := range c.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidEvent 是否为支持的事件类型
+func IsValidEvent(eventType string) bool {
+	for _, e := range AllEvents {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEvent 创建事件
+func NewEvent(eventType, traderID, userID string, data map[string]interface{}) *Event {
+	now := time.Now()
+	return &Event{
+		ID:        fmt.Sprintf("evt_%d_%s", now.UnixNano(), randomHex(4)),
+		Type:      eventType,
+		TraderID:  traderID,
+		UserID:    userID,
+		Timestamp: now.Unix(),
+		Data:      data,
+	}
+}
+
+// GenerateSecret 生成签名密钥
+func GenerateSecret() string {
+	return "whsec_" + randomHex(24)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, "<timestamp>.<body>") 的十六进制
+// 时间戳参与签名，接收方可据此拒绝重放请求
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名（signature 可带 "sha256=" 前缀），供接收方参考实现
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	signature = strings.TrimPrefix(signature, "sha256=")
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// Dispatcher 异步投递 Webhook，失败按指数退避重试，不阻塞交易流程
+type Dispatcher struct {
+	client         *http.Client
+	maxRetries     int
+	initialBackoff time.Duration
+}
+
+// NewDispatcher 创建投递器（client 为 nil 时使用拒绝本机和内网地址的默认客户端）
+func NewDispatcher(client *http.Client, maxRetries int, initialBackoff time.Duration) *Dispatcher {
+	if client == nil {
+		client = newSafeClient()
+	}
+	if maxRetries < 0 {
+		maxRetries = defaultMaxRetries
+	}
+	if initialBackoff <= 0 {
+		initialBackoff = defaultInitialBackoff
+	}
+	return &Dispatcher{client: client, maxRetries: maxRetries, initialBackoff: initialBackoff}
+}
+
+// DefaultDispatcher 默认投递器
+var DefaultDispatcher = NewDispatcher(nil, defaultMaxRetries, defaultInitialBackoff)
+
+// Dispatch 异步投递事件（未订阅该事件时直接忽略）
+func (d *Dispatcher) Dispatch(cfg *Config, event *Event) {
+	if !cfg.Subscribes(event.Type) {
+		return
+	}
+	go func() {
+		if err := d.Deliver(cfg, event); err != nil {
+			log.Printf("⚠️ Webhook 投递失败 [%s %s]: %v", event.Type, event.ID, err)
+		}
+	}()
+}
+
+// Deliver 同步投递事件，失败时按指数退避重试
+func (d *Dispatcher) Deliver(cfg *Config, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %w", err)
+	}
+
+	backoff := d.initialBackoff
+	var lastErr error
+	for attempt := 0; attempt <= d.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if lastErr = d.post(cfg, event, body); lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("重试 %d 次后仍失败: %w", d.maxRetries, lastErr)
+}
+
+// post 发送一次请求（每次重试重新签名，时间戳保持最新）
+func (d *Dispatcher) post(cfg *Config, event *Event, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "NOFX-Webhook/1.0")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderEventID, event.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(cfg.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("回调地址返回 HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
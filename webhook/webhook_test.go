@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// verifyRequest 接收方校验签名的示例：读取原始请求体，用时间戳头和共享密钥重新计算签名
+func verifyRequest(r *http.Request, secret string, tolerance time.Duration) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, false
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	// 拒绝过期请求，防止重放
+	if time.Since(time.Unix(timestamp, 0)) > tolerance {
+		return nil, false
+	}
+	return body, Verify(secret, timestamp, body, r.Header.Get(HeaderSignature))
+}
+
+// TestDeliverSignedEvent 测试投递的请求可被接收方校验签名
+func TestDeliverSignedEvent(t *testing.T) {
+	secret := GenerateSecret()
+	received := make(chan Event, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := verifyRequest(r, secret, 5*time.Minute)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event Event
+		json.Unmarshal(body, &event)
+		received <- event
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(server.Client(), 0, time.Millisecond)
+	cfg := &Config{URL: server.URL, Secret: secret, Enabled: true}
+	event := NewEvent(EventOpen, "trader_1", "user_1", map[string]interface{}{"symbol": "BTCUSDT", "side": "long"})
+
+	if err := d.Deliver(cfg, event); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+	got := <-received
+	if got.ID != event.ID || got.Type != EventOpen || got.Data["symbol"] != "BTCUSDT" {
+		t.Errorf("收到的事件不正确: %+v", got)
+	}
+
+	// 密钥不一致时接收方应拒绝
+	wrong := &Config{URL: server.URL, Secret: "wrong", Enabled: true}
+	if err := d.Deliver(wrong, event); err == nil {
+		t.Error("错误密钥签名的请求应被拒绝")
+	}
+}
+
+// TestDeliverRetry 测试失败后按退避重试
+func TestDeliverRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(server.Client(), 3, time.Millisecond)
+	cfg := &Config{URL: server.URL, Secret: "s", Enabled: true}
+	if err := d.Deliver(cfg, NewEvent(EventClose, "t", "u", nil)); err != nil {
+		t.Fatalf("重试后应成功: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("请求次数 = %d, 期望 3", calls)
+	}
+
+	atomic.StoreInt32(&calls, -100)
+	d = NewDispatcher(server.Client(), 1, time.Millisecond)
+	if err := d.Deliver(cfg, NewEvent(EventClose, "t", "u", nil)); err == nil {
+		t.Error("超过重试次数应返回错误")
+	}
+	if calls != -98 {
+		t.Errorf("请求次数 = %d, 期望 2", calls+100)
+	}
+}
+
+// TestConfigSubscribes 测试事件订阅过滤
+func TestConfigSubscribes(t *testing.T) {
+	cfg := &Config{URL: "https://example.com/hook", Enabled: true, Events: []string{EventRiskStop}}
+	if !cfg.Subscribes(EventRiskStop) || cfg.Subscribes(EventOpen) {
+		t.Error("应只订阅 risk_stop")
+	}
+	cfg.Events = nil
+	if !cfg.Subscribes(EventOpen) {
+		t.Error("未指定事件时应订阅全部")
+	}
+	cfg.Enabled = false
+	if cfg.Subscribes(EventOpen) {
+		t.Error("禁用后不应投递")
+	}
+	var nilCfg *Config
+	if nilCfg.Subscribes(EventOpen) {
+		t.Error("nil 配置不应投递")
+	}
+}